@@ -0,0 +1,200 @@
+// Package addresspool derives deposit addresses ahead of time, so creating a
+// payment claims a ready address instead of waiting on key derivation.
+package addresspool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+)
+
+// Store is the subset of *repository.Store the refiller finds short pools
+// and adds addresses through.
+type Store interface {
+	ListAccountsBelowLowWater(ctx context.Context, lowWater int64) ([]repository.ListAccountsBelowLowWaterRow, error)
+	AllocateWalletIndexes(ctx context.Context, arg repository.AllocateWalletIndexesParams) (int64, error)
+	CreatePoolAddresses(ctx context.Context, arg repository.CreatePoolAddressesParams) (int64, error)
+}
+
+// Refiller tops up the address pool of every account whose unreserved
+// addresses fell below LowWater, back up to Target. Addresses are derived by
+// at most Workers goroutines and inserted BatchSize at a time.
+type Refiller struct {
+	store       Store
+	wallets     payments.WalletDeriver
+	testWallets payments.WalletDeriver
+	logger      *slog.Logger
+
+	cfg config.AddressPoolConfig
+}
+
+// Option customises a Refiller.
+type Option func(*Refiller)
+
+// WithLogger replaces slog.Default.
+func WithLogger(l *slog.Logger) Option {
+	return func(r *Refiller) { r.logger = l }
+}
+
+// WithTestWallets derives the addresses of test clients' accounts from w.
+// Without it those accounts are left to derive on demand.
+func WithTestWallets(w payments.WalletDeriver) Option {
+	return func(r *Refiller) { r.testWallets = w }
+}
+
+// New builds a refiller using the payments.addressPool section of cfg;
+// wallets derives the addresses of live accounts.
+func New(store Store, wallets payments.WalletDeriver, cfg *config.Config, opts ...Option) *Refiller {
+	r := &Refiller{
+		store:   store,
+		wallets: wallets,
+		logger:  slog.Default(),
+		cfg:     cfg.Payments.AddressPool,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run refills every interval until ctx is done.
+func (r *Refiller) Run(ctx context.Context) error {
+	r.logger.Info("address pool refiller started", "interval", r.cfg.Interval.Std(),
+		"low_water", r.cfg.LowWater, "target", r.cfg.Target)
+	for {
+		if _, err := r.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			r.logger.ErrorContext(ctx, "address pool refill failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			r.logger.Info("address pool refiller stopped")
+			return nil
+		case <-time.After(r.cfg.Interval.Std()):
+		}
+	}
+}
+
+// RunOnce refills the pools that are below the low-water mark now and
+// returns how many addresses it added. An account that fails does not stop
+// the others; their errors are joined.
+func (r *Refiller) RunOnce(ctx context.Context) (int, error) {
+	accounts, err := r.store.ListAccountsBelowLowWater(ctx, int64(r.cfg.LowWater))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list accounts below low water: %w", err)
+	}
+
+	var (
+		added int
+		errs  []error
+	)
+	for _, account := range accounts {
+		wallets := r.wallets
+		if account.Mode == config.ModeTest {
+			if wallets = r.testWallets; wallets == nil {
+				continue
+			}
+		}
+		n, err := r.refill(ctx, account, wallets)
+		added += n
+		if err != nil {
+			if ctx.Err() != nil {
+				return added, err
+			}
+			errs = append(errs, fmt.Errorf("account %s: %w", account.AccountID, err))
+		}
+	}
+	if added > 0 || len(errs) > 0 {
+		r.logger.InfoContext(ctx, "address pool refilled", "accounts", len(accounts), "addresses", added, "failed", len(errs))
+	}
+	return added, errors.Join(errs...)
+}
+
+// refill tops up the pool of account, a batch at a time. Indexes are taken
+// from the counter payments derive from, so a pooled address never matches
+// one derived on demand; the indexes of a batch that fails are skipped.
+func (r *Refiller) refill(ctx context.Context, account repository.ListAccountsBelowLowWaterRow, wallets payments.WalletDeriver) (int, error) {
+	added := 0
+	for missing := r.cfg.Target - int(account.Unreserved); missing > 0; {
+		n := min(missing, r.cfg.BatchSize)
+		first, err := r.store.AllocateWalletIndexes(ctx, repository.AllocateWalletIndexesParams{
+			Name:  payments.WalletCounter(account.Mode),
+			Count: int64(n),
+		})
+		if err != nil {
+			return added, fmt.Errorf("failed to allocate wallet indexes: %w", err)
+		}
+		indexes, addresses, err := r.derive(ctx, wallets, first, n)
+		if err != nil {
+			return added, err
+		}
+		if _, err := r.store.CreatePoolAddresses(ctx, repository.CreatePoolAddressesParams{
+			AccountID:      account.AccountID,
+			AddressIndexes: indexes,
+			Addresses:      addresses,
+		}); err != nil {
+			return added, fmt.Errorf("failed to insert pooled addresses: %w", err)
+		}
+		added += n
+		missing -= n
+	}
+	return added, nil
+}
+
+// derive derives the n addresses from index first on at most cfg.Workers
+// goroutines.
+func (r *Refiller) derive(ctx context.Context, wallets payments.WalletDeriver, first int64, n int) ([]int64, []string, error) {
+	if first < 0 || first+int64(n)-1 > math.MaxUint32 {
+		return nil, nil, fmt.Errorf("wallet indexes %d to %d are out of range", first, first+int64(n)-1)
+	}
+	indexes := make([]int64, n)
+	addresses := make([]string, n)
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, r.cfg.Workers)
+		firstErr error
+	)
+	for i := range n {
+		indexes[i] = first + int64(i)
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, nil, ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			address, err := wallets.DeriveWallet(uint32(indexes[i]))
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to derive wallet %d: %w", indexes[i], err)
+				}
+				mu.Unlock()
+				return
+			}
+			addresses[i] = address
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+	return indexes, addresses, nil
+}
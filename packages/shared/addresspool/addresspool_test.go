@@ -0,0 +1,165 @@
+package addresspool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// stubWallets derives prefix followed by the index, tracking how many
+// derivations run at once.
+type stubWallets struct {
+	prefix  string
+	failAt  int64
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+func (w *stubWallets) DeriveWallet(index uint32) (string, error) {
+	n := w.running.Add(1)
+	defer w.running.Add(-1)
+	for {
+		peak := w.peak.Load()
+		if n <= peak || w.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	if w.failAt > 0 && int64(index) == w.failAt {
+		return "", errors.New("derivation failed")
+	}
+	return fmt.Sprintf("%s%d", w.prefix, index), nil
+}
+
+func testConfig() *config.Config {
+	cfg := &config.Config{Payments: config.PaymentsConfig{AddressPool: config.AddressPoolConfig{
+		Enabled:   true,
+		LowWater:  5,
+		Target:    12,
+		BatchSize: 5,
+		Workers:   3,
+		Interval:  config.Duration(time.Minute),
+	}}}
+	return cfg
+}
+
+func newAccount(t *testing.T, store *repository.FakeStore, mode string) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+	client, err := store.CreateClient(ctx, repository.CreateClientParams{Name: "merchant " + mode, ApiKey: uuid.NewString(), Mode: mode})
+	require.NoError(t, err)
+	account, err := store.CreateAccount(ctx, repository.CreateAccountParams{ClientID: client.ID, Name: "main"})
+	require.NoError(t, err)
+	return account.ID
+}
+
+func poolOf(store *repository.FakeStore, accountID uuid.UUID) []repository.AddressPool {
+	var pool []repository.AddressPool
+	for _, a := range store.AddressPool() {
+		if a.AccountID == accountID {
+			pool = append(pool, a)
+		}
+	}
+	return pool
+}
+
+func newRefiller(store Store, wallets *stubWallets, opts ...Option) *Refiller {
+	opts = append([]Option{WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, opts...)
+	return New(store, wallets, testConfig(), opts...)
+}
+
+func TestRefiller_RunOnce(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewFakeStore()
+	accountID := newAccount(t, store, config.ModeLive)
+	wallets := &stubWallets{prefix: "TWallet"}
+	r := newRefiller(store, wallets)
+
+	added, err := r.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 12, added, "an empty pool is filled up to the target")
+
+	pool := poolOf(store, accountID)
+	require.Len(t, pool, 12)
+	for i, a := range pool {
+		assert.Equal(t, int64(i), a.AddressIndex)
+		assert.Equal(t, fmt.Sprintf("TWallet%d", i), a.Address)
+		assert.False(t, a.ReservedByPayment != nil)
+	}
+	assert.LessOrEqual(t, wallets.peak.Load(), int32(3), "derivations are bounded by the worker count")
+
+	added, err = r.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, added, "a pool at its target is left alone")
+}
+
+func TestRefiller_RunOnceTopsUpBelowLowWater(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewFakeStore()
+	accountID := newAccount(t, store, config.ModeLive)
+	r := newRefiller(store, &stubWallets{prefix: "TWallet"})
+	_, err := r.RunOnce(ctx)
+	require.NoError(t, err)
+
+	for range 7 {
+		_, err := store.ReserveAddress(ctx, repository.ReserveAddressParams{PaymentID: uuid.New(), AccountID: accountID})
+		require.NoError(t, err)
+	}
+	added, err := r.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, added, "5 unreserved addresses are not below the low-water mark")
+
+	_, err = store.ReserveAddress(ctx, repository.ReserveAddressParams{PaymentID: uuid.New(), AccountID: accountID})
+	require.NoError(t, err)
+	added, err = r.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 8, added)
+
+	pool := poolOf(store, accountID)
+	require.Len(t, pool, 20)
+	assert.Equal(t, int64(19), pool[19].AddressIndex, "new addresses continue from the counter")
+}
+
+func TestRefiller_RunOnceTestAccounts(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewFakeStore()
+	liveID := newAccount(t, store, config.ModeLive)
+	testID := newAccount(t, store, config.ModeTest)
+
+	_, err := newRefiller(store, &stubWallets{prefix: "TWallet"}).RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Len(t, poolOf(store, liveID), 12)
+	assert.Empty(t, poolOf(store, testID), "test accounts need test wallets")
+
+	_, err = newRefiller(store, &stubWallets{prefix: "TWallet"}, WithTestWallets(&stubWallets{prefix: "TTest"})).RunOnce(ctx)
+	require.NoError(t, err)
+	pool := poolOf(store, testID)
+	require.Len(t, pool, 12)
+	assert.Equal(t, "TTest0", pool[0].Address, "test addresses use the test counter and wallets")
+}
+
+func TestRefiller_RunOnceAccountFailure(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewFakeStore()
+	first := newAccount(t, store, config.ModeLive)
+	second := newAccount(t, store, config.ModeLive)
+
+	added, err := newRefiller(store, &stubWallets{prefix: "TWallet", failAt: 7}).RunOnce(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to derive wallet 7: derivation failed")
+
+	total := len(poolOf(store, first)) + len(poolOf(store, second))
+	assert.Equal(t, added, total)
+	assert.Equal(t, 5+12, total, "the failing batch is dropped and the other account still filled")
+}
@@ -0,0 +1,403 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+)
+
+// accountRequest is the body of POST /v1/accounts and PUT
+// /v1/accounts/{id}. A PUT replaces every setting, so one left out is
+// cleared.
+type accountRequest struct {
+	Name string `json:"name"`
+	// DefaultExpirySeconds is the expiry of payments that do not set one.
+	DefaultExpirySeconds *int64 `json:"default_expiry_seconds"`
+	// DefaultWebhookEndpointID is the endpoint the webhooks of payments that
+	// do not name one go to; empty means every endpoint of the client.
+	DefaultWebhookEndpointID string `json:"default_webhook_endpoint_id"`
+	// Metadata is a JSON object the merchant keeps with the account.
+	Metadata json.RawMessage `json:"metadata"`
+	// AddressStrategy is how the payments of the account are told apart:
+	// unique_wallet, the default, or amount_suffix.
+	AddressStrategy string `json:"address_strategy"`
+}
+
+// accountPatch is the body of PATCH /v1/accounts/{id}; fields left out keep
+// their value.
+type accountPatch struct {
+	Name            *string `json:"name"`
+	AddressStrategy *string `json:"address_strategy"`
+}
+
+// accountSettings is a validated accountRequest.
+type accountSettings struct {
+	name              string
+	expirySeconds     *int64
+	webhookEndpointID *uuid.UUID
+	metadata          []byte
+	addressStrategy   *string
+}
+
+// accountRecord is an account as its merchant sees it.
+type accountRecord struct {
+	ID              uuid.UUID `json:"id"`
+	Name            string    `json:"name"`
+	AddressStrategy string    `json:"address_strategy"`
+	CreatedAt       string    `json:"created_at"`
+
+	// DepositWallet is the wallet the payments of an amount_suffix account
+	// are paid into, once the first of them was created.
+	DepositWallet *string `json:"deposit_wallet,omitempty"`
+
+	// The settings are left out while unset.
+	DefaultExpirySeconds     *int64          `json:"default_expiry_seconds,omitempty"`
+	DefaultWebhookEndpointID *uuid.UUID      `json:"default_webhook_endpoint_id,omitempty"`
+	Metadata                 json.RawMessage `json:"metadata,omitempty"`
+
+	// DeletedAt is only set on a soft deleted account.
+	DeletedAt string `json:"deleted_at,omitempty"`
+}
+
+func newAccountRecord(a repository.Account) accountRecord {
+	rec := accountRecord{
+		ID:                   a.ID,
+		Name:                 a.Name,
+		AddressStrategy:      a.AddressStrategy,
+		CreatedAt:            formatTime(a.CreatedAt),
+		DepositWallet:        a.DepositWallet,
+		DefaultExpirySeconds: a.DefaultExpirySeconds,
+		Metadata:             a.Metadata,
+	}
+	if rec.AddressStrategy == "" {
+		rec.AddressStrategy = payments.StrategyUniqueWallet
+	}
+	if a.DefaultWebhookEndpointID != nil {
+		id := uuid.UUID(*a.DefaultWebhookEndpointID)
+		rec.DefaultWebhookEndpointID = &id
+	}
+	if a.DeletedAt.Valid {
+		rec.DeletedAt = formatTime(a.DeletedAt)
+	}
+	return rec
+}
+
+type accountAuditLog struct {
+	AccountID uuid.UUID `json:"account_id"`
+	ClientID  uuid.UUID `json:"client_id"`
+	Name      string    `json:"name"`
+}
+
+// createAccount handles POST /v1/accounts, creating an account under the
+// authenticated client and auditing it in the same transaction.
+func (s *Server) createAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	client := clientFrom(ctx)
+	settings, ok := s.decodeAccount(w, r)
+	if !ok {
+		return
+	}
+
+	var account repository.Account
+	err := s.store.ExecTx(ctx, func(q repository.Querier) error {
+		var err error
+		account, err = q.CreateAccount(ctx, repository.CreateAccountParams{
+			ClientID:                 client.ID,
+			Name:                     settings.name,
+			DefaultExpirySeconds:     settings.expirySeconds,
+			DefaultWebhookEndpointID: settings.webhookEndpointID,
+			Metadata:                 settings.metadata,
+			AddressStrategy:          settings.addressStrategy,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to insert account: %w", err)
+		}
+		return audit(ctx, q, EventAccountCreated, fmt.Sprintf("account %q created", settings.name),
+			accountAuditLog{AccountID: account.ID, ClientID: client.ID, Name: settings.name})
+	})
+	if err != nil {
+		s.internalError(w, r, "failed to create account", err, "client_id", client.ID)
+		return
+	}
+	s.logger.InfoContext(ctx, "account created", "account_id", account.ID, "client_id", client.ID)
+
+	writeJSON(w, http.StatusCreated, newAccountRecord(account))
+}
+
+// getAccount handles GET /v1/accounts/{id}. Accounts of other clients are
+// reported as not found, and so are deleted ones unless include_deleted=true
+// is passed.
+func (s *Server) getAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, codeAccountNotFound, "account not found"))
+		return
+	}
+	var includeDeleted bool
+	if v := r.URL.Query().Get("include_deleted"); v != "" {
+		if includeDeleted, err = strconv.ParseBool(v); err != nil {
+			apierror.Write(w, r, &apierror.Error{
+				HTTPStatus: http.StatusBadRequest,
+				Code:       codeValidationFailed,
+				Message:    "request has invalid parameters",
+				Fields:     map[string]string{"include_deleted": "must be true or false"},
+			})
+			return
+		}
+	}
+	account, err := s.tenant(ctx).GetAccountByIDAndClientID(ctx, repository.GetAccountByIDAndClientIDParams{
+		ID:             id,
+		IncludeDeleted: includeDeleted,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, codeAccountNotFound, "account not found"))
+		return
+	}
+	if err != nil {
+		s.internalError(w, r, "failed to load account", err, "account_id", id)
+		return
+	}
+	writeJSON(w, http.StatusOK, newAccountRecord(account))
+}
+
+// replaceAccount handles PUT /v1/accounts/{id}, replacing the name and
+// settings of an account of the client and auditing it in the same
+// transaction. Payments already created keep the settings they were given.
+func (s *Server) replaceAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	client := clientFrom(ctx)
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, codeAccountNotFound, "account not found"))
+		return
+	}
+	settings, ok := s.decodeAccount(w, r)
+	if !ok {
+		return
+	}
+
+	var account repository.Account
+	err = s.store.ExecTx(ctx, func(q repository.Querier) error {
+		var err error
+		account, err = q.ReplaceAccount(ctx, repository.ReplaceAccountParams{
+			Name:                     settings.name,
+			DefaultExpirySeconds:     settings.expirySeconds,
+			DefaultWebhookEndpointID: settings.webhookEndpointID,
+			Metadata:                 settings.metadata,
+			AddressStrategy:          settings.addressStrategy,
+			ID:                       id,
+			ClientID:                 client.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update account: %w", err)
+		}
+		return audit(ctx, q, EventAccountUpdated, fmt.Sprintf("account %q updated", settings.name),
+			accountAuditLog{AccountID: account.ID, ClientID: client.ID, Name: settings.name})
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, codeAccountNotFound, "account not found"))
+		return
+	}
+	if err != nil {
+		s.internalError(w, r, "failed to update account", err, "account_id", id)
+		return
+	}
+	s.logger.InfoContext(ctx, "account updated", "account_id", account.ID, "client_id", client.ID)
+
+	writeJSON(w, http.StatusOK, newAccountRecord(account))
+}
+
+// updateAccount handles PATCH /v1/accounts/{id}, changing the fields given of
+// an account of the client and auditing it in the same transaction. Accounts
+// of other clients are reported as not found.
+func (s *Server) updateAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	client := clientFrom(ctx)
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, codeAccountNotFound, "account not found"))
+		return
+	}
+	var req accountPatch
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+	fields := make(map[string]string)
+	if req.Name != nil {
+		if msg := validateName(*req.Name); msg != "" {
+			fields["name"] = msg
+		}
+		name := strings.TrimSpace(*req.Name)
+		req.Name = &name
+	}
+	if req.AddressStrategy != nil {
+		if msg := validateAddressStrategy(*req.AddressStrategy); msg != "" {
+			fields["address_strategy"] = msg
+		}
+	}
+	if len(fields) > 0 {
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusBadRequest,
+			Code:       codeValidationFailed,
+			Message:    "request has invalid fields",
+			Fields:     fields,
+		})
+		return
+	}
+
+	var account repository.Account
+	err = s.store.ExecTx(ctx, func(q repository.Querier) error {
+		var err error
+		account, err = q.UpdateAccount(ctx, repository.UpdateAccountParams{
+			Name:            req.Name,
+			AddressStrategy: req.AddressStrategy,
+			ID:              id,
+			ClientID:        client.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update account: %w", err)
+		}
+		return audit(ctx, q, EventAccountUpdated, fmt.Sprintf("account %q updated", account.Name),
+			accountAuditLog{AccountID: account.ID, ClientID: client.ID, Name: account.Name})
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, codeAccountNotFound, "account not found"))
+		return
+	}
+	if err != nil {
+		s.internalError(w, r, "failed to update account", err, "account_id", id)
+		return
+	}
+	s.logger.InfoContext(ctx, "account updated", "account_id", account.ID, "client_id", client.ID)
+
+	writeJSON(w, http.StatusOK, newAccountRecord(account))
+}
+
+// deleteAccount handles DELETE /v1/accounts/{id}, soft deleting an account of
+// the client and auditing it in the same transaction. An account with a
+// PENDING or DETECTED payment is refused with a 409; its payments keep
+// settling either way.
+func (s *Server) deleteAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	client := clientFrom(ctx)
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, codeAccountNotFound, "account not found"))
+		return
+	}
+
+	var account repository.Account
+	err = s.store.ExecTx(ctx, func(q repository.Querier) error {
+		var err error
+		account, err = q.SoftDeleteAccount(ctx, repository.SoftDeleteAccountParams{ID: id, ClientID: client.ID})
+		if err != nil {
+			return fmt.Errorf("failed to delete account: %w", err)
+		}
+		return audit(ctx, q, EventAccountDeleted, fmt.Sprintf("account %q deleted", account.Name),
+			accountAuditLog{AccountID: account.ID, ClientID: client.ID, Name: account.Name})
+	})
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, codeAccountNotFound, "account not found"))
+		return
+	case errors.Is(err, repository.ErrAccountHasOpenPayments):
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusConflict,
+			Code:       codeAccountHasOpenPayments,
+			Message:    "account has pending or detected payments",
+		})
+		return
+	case err != nil:
+		s.internalError(w, r, "failed to delete account", err, "account_id", id)
+		return
+	}
+	s.logger.InfoContext(ctx, "account deleted", "account_id", account.ID, "client_id", client.ID)
+
+	writeJSON(w, http.StatusOK, newAccountRecord(account))
+}
+
+// decodeAccount decodes and validates an accountRequest, writing a 400 when
+// it is not valid. The default webhook endpoint must be an active endpoint of
+// the client.
+func (s *Server) decodeAccount(w http.ResponseWriter, r *http.Request) (accountSettings, bool) {
+	var req accountRequest
+	if !s.decodeJSON(w, r, &req) {
+		return accountSettings{}, false
+	}
+	settings := accountSettings{name: strings.TrimSpace(req.Name), expirySeconds: req.DefaultExpirySeconds}
+	fields := make(map[string]string)
+	if msg := validateName(req.Name); msg != "" {
+		fields["name"] = msg
+	}
+	if e := req.DefaultExpirySeconds; e != nil && (*e < int64(payments.MinExpiry.Seconds()) || *e > int64(payments.MaxExpiry.Seconds())) {
+		fields["default_expiry_seconds"] = fmt.Sprintf("must be between %d and %d seconds",
+			int64(payments.MinExpiry.Seconds()), int64(payments.MaxExpiry.Seconds()))
+	}
+	if req.DefaultWebhookEndpointID != "" {
+		if id, err := uuid.Parse(req.DefaultWebhookEndpointID); err != nil {
+			fields["default_webhook_endpoint_id"] = "must be a UUID"
+		} else {
+			settings.webhookEndpointID = repository.UUIDPtr(id)
+		}
+	}
+	var msg string
+	if settings.metadata, msg = payments.CompactMetadata(req.Metadata); msg != "" {
+		fields["metadata"] = msg
+	}
+	if req.AddressStrategy != "" {
+		if msg := validateAddressStrategy(req.AddressStrategy); msg != "" {
+			fields["address_strategy"] = msg
+		}
+		settings.addressStrategy = &req.AddressStrategy
+	}
+	if len(fields) == 0 && settings.webhookEndpointID != nil {
+		msg, err := s.checkWebhookEndpoint(r, *settings.webhookEndpointID)
+		if err != nil {
+			s.internalError(w, r, "failed to load webhook endpoint", err, "endpoint_id", req.DefaultWebhookEndpointID)
+			return accountSettings{}, false
+		}
+		if msg != "" {
+			fields["default_webhook_endpoint_id"] = msg
+		}
+	}
+	if len(fields) > 0 {
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusBadRequest,
+			Code:       codeValidationFailed,
+			Message:    "request has invalid fields",
+			Fields:     fields,
+		})
+		return accountSettings{}, false
+	}
+	return settings, true
+}
+
+// validateAddressStrategy returns what is wrong with strategy as the
+// address_strategy of an account.
+func validateAddressStrategy(strategy string) string {
+	if !slices.Contains(payments.AddressStrategies, strategy) {
+		return "must be one of " + strings.Join(payments.AddressStrategies, ", ")
+	}
+	return ""
+}
+
+// checkWebhookEndpoint returns what is wrong with id as a webhook endpoint of
+// the authenticated client.
+func (s *Server) checkWebhookEndpoint(r *http.Request, id uuid.UUID) (string, error) {
+	_, err := s.tenant(r.Context()).GetActiveWebhookEndpoint(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "must be an active webhook endpoint", nil
+	}
+	return "", err
+}
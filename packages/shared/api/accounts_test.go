@@ -0,0 +1,465 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+)
+
+// addEndpoint stores a webhook endpoint of clientID.
+func addEndpoint(t *testing.T, store *repository.FakeStore, clientID uuid.UUID, active bool) uuid.UUID {
+	t.Helper()
+	e, err := store.AddWebhookEndpoint(repository.WebhookEndpoint{ClientID: clientID, Url: "https://shop.example/hook", IsActive: active})
+	require.NoError(t, err)
+	return e.ID
+}
+
+// storedAccount returns the account id as stored, deleted ones included.
+func storedAccount(t *testing.T, store *repository.FakeStore, id uuid.UUID) repository.Account {
+	t.Helper()
+	a, err := store.GetAccountByIDAndClientID(context.Background(), repository.GetAccountByIDAndClientIDParams{
+		ID:             id,
+		ClientID:       testClient.ID,
+		IncludeDeleted: true,
+	})
+	require.NoError(t, err)
+	return a
+}
+
+// otherClientAccount stores a client other than testClient with one
+// account, and returns the account.
+func otherClientAccount(t *testing.T, store *repository.FakeStore) repository.Account {
+	t.Helper()
+	ctx := context.Background()
+	other, err := store.CreateClient(ctx, repository.CreateClientParams{Name: "other", ApiKey: "sk_other", Mode: "live"})
+	require.NoError(t, err)
+	a, err := store.CreateAccount(ctx, repository.CreateAccountParams{ClientID: other.ID, Name: "theirs"})
+	require.NoError(t, err)
+	return a
+}
+
+func TestCreateAccount(t *testing.T) {
+	s, store := newFakeServer(t)
+
+	status, resp := do(t, s, http.MethodPost, "/v1/accounts", `{"name":"EU store"}`, nil)
+
+	require.Equal(t, http.StatusCreated, status)
+	id := uuid.MustParse(resp["id"].(string))
+	assert.Equal(t, map[string]any{
+		"id":               id.String(),
+		"name":             "EU store",
+		"address_strategy": "unique_wallet",
+		"created_at":       "2026-03-01T12:00:00Z",
+	}, resp)
+	assert.Equal(t, "EU store", storedAccount(t, store, id).Name)
+	assertAudit(t, store, EventAccountCreated, "client:"+testClient.ID.String(),
+		accountAuditLog{AccountID: id, ClientID: testClient.ID, Name: "EU store"})
+}
+
+func TestCreateAccount_ValidationErrors(t *testing.T) {
+	s, store := newFakeServer(t)
+
+	status, resp := do(t, s, http.MethodPost, "/v1/accounts", `{"name":""}`, nil)
+
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "is required", resp["fields"].(map[string]any)["name"])
+	n, err := store.CountAccountsByClientID(context.Background(), testClient.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n, "only testAccount")
+}
+
+func TestCreateAccount_RequiresAPIKey(t *testing.T) {
+	s, _ := newFakeServer(t)
+
+	status, _ := do(t, s, http.MethodPost, "/v1/accounts", `{"name":"x"}`, http.Header{})
+
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestCreateAccount_TransactionFails(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.txErr = errors.New("connection reset")
+
+	status, resp := do(t, s, http.MethodPost, "/v1/accounts", `{"name":"x"}`, nil)
+
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, apierror.CodeInternal, resp["code"])
+}
+
+func TestCreateAccount_Settings(t *testing.T) {
+	s, store := newFakeServer(t)
+	endpointID := addEndpoint(t, store, testClient.ID, true)
+
+	status, resp := do(t, s, http.MethodPost, "/v1/accounts", `{"name":"EU store","default_expiry_seconds":600,
+		"default_webhook_endpoint_id":"`+endpointID.String()+`","metadata":{ "region": "eu", "tags": ["a", "b"] }}`, nil)
+
+	require.Equal(t, http.StatusCreated, status)
+	id := uuid.MustParse(resp["id"].(string))
+	assert.Equal(t, map[string]any{
+		"id":                          id.String(),
+		"name":                        "EU store",
+		"address_strategy":            "unique_wallet",
+		"created_at":                  "2026-03-01T12:00:00Z",
+		"default_expiry_seconds":      float64(600),
+		"default_webhook_endpoint_id": endpointID.String(),
+		"metadata":                    map[string]any{"region": "eu", "tags": []any{"a", "b"}},
+	}, resp)
+	account := storedAccount(t, store, id)
+	assert.Equal(t, `{"region":"eu","tags":["a","b"]}`, string(account.Metadata), "metadata is stored compacted")
+}
+
+func TestCreateAccount_InvalidSettings(t *testing.T) {
+	testCases := []struct {
+		name  string
+		body  string
+		field string
+		want  string
+	}{
+		{"expiry too short", `{"name":"x","default_expiry_seconds":30}`, "default_expiry_seconds", "must be between 60 and 86400 seconds"},
+		{"expiry too long", `{"name":"x","default_expiry_seconds":86401}`, "default_expiry_seconds", "must be between 60 and 86400 seconds"},
+		{"malformed endpoint", `{"name":"x","default_webhook_endpoint_id":"hook"}`, "default_webhook_endpoint_id", "must be a UUID"},
+		{"unknown endpoint", `{"name":"x","default_webhook_endpoint_id":"` + uuid.NewString() + `"}`, "default_webhook_endpoint_id", "must be an active webhook endpoint"},
+		{"metadata not an object", `{"name":"x","metadata":["a"]}`, "metadata", "must be an object"},
+		{"metadata too large", `{"name":"x","metadata":{"note":"` + strings.Repeat("a", payments.MaxMetadataBytes) + `"}}`, "metadata", "must be at most 8192 bytes"},
+		{"unknown address strategy", `{"name":"x","address_strategy":"round_robin"}`, "address_strategy", "must be one of unique_wallet, amount_suffix"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, _ := newFakeServer(t)
+
+			status, resp := do(t, s, http.MethodPost, "/v1/accounts", tc.body, nil)
+
+			assert.Equal(t, http.StatusBadRequest, status)
+			assert.Equal(t, map[string]any{tc.field: tc.want}, resp["fields"])
+		})
+	}
+}
+
+func TestCreateAccount_InactiveWebhookEndpoint(t *testing.T) {
+	testCases := []struct {
+		name     string
+		clientID uuid.UUID
+		active   bool
+	}{
+		{"inactive", testClient.ID, false},
+		{"other client's", uuid.Nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store := newFakeServer(t)
+			clientID := tc.clientID
+			if clientID == uuid.Nil {
+				clientID = otherClientAccount(t, store).ClientID
+			}
+			endpointID := addEndpoint(t, store, clientID, tc.active)
+
+			status, resp := do(t, s, http.MethodPost, "/v1/accounts",
+				`{"name":"x","default_webhook_endpoint_id":"`+endpointID.String()+`"}`, nil)
+
+			assert.Equal(t, http.StatusBadRequest, status)
+			assert.Equal(t, "must be an active webhook endpoint", resp["fields"].(map[string]any)["default_webhook_endpoint_id"])
+			assert.Empty(t, store.Logs(), "no account was created")
+		})
+	}
+}
+
+func TestGetAccount(t *testing.T) {
+	s, store := newFakeServer(t)
+	expiry := int64(900)
+	account, err := store.CreateAccount(context.Background(), repository.CreateAccountParams{
+		ClientID:             testClient.ID,
+		Name:                 "EU store",
+		DefaultExpirySeconds: &expiry,
+		Metadata:             []byte(`{"region":"eu"}`),
+	})
+	require.NoError(t, err)
+
+	status, resp := do(t, s, http.MethodGet, "/v1/accounts/"+account.ID.String(), "", nil)
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]any{
+		"id":                     account.ID.String(),
+		"name":                   "EU store",
+		"address_strategy":       "unique_wallet",
+		"created_at":             "2026-03-01T12:00:00Z",
+		"default_expiry_seconds": float64(900),
+		"metadata":               map[string]any{"region": "eu"},
+	}, resp)
+}
+
+func TestGetAccount_NotFound(t *testing.T) {
+	testCases := []struct {
+		name string
+		id   func(*testing.T, *repository.FakeStore) string
+	}{
+		{"malformed id", func(*testing.T, *repository.FakeStore) string { return "acct" }},
+		{"unknown id", func(*testing.T, *repository.FakeStore) string { return uuid.NewString() }},
+		{"other client's account", func(t *testing.T, store *repository.FakeStore) string {
+			return otherClientAccount(t, store).ID.String()
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store := newFakeServer(t)
+
+			status, resp := do(t, s, http.MethodGet, "/v1/accounts/"+tc.id(t, store), "", nil)
+
+			assert.Equal(t, http.StatusNotFound, status)
+			assert.Equal(t, codeAccountNotFound, resp["code"])
+		})
+	}
+}
+
+func TestGetAccount_IncludeDeleted(t *testing.T) {
+	s, store := newFakeServer(t)
+	_, err := store.SoftDeleteAccount(context.Background(), repository.SoftDeleteAccountParams{ID: testAccount.ID, ClientID: testClient.ID})
+	require.NoError(t, err)
+
+	status, _ := do(t, s, http.MethodGet, "/v1/accounts/"+testAccount.ID.String(), "", nil)
+	assert.Equal(t, http.StatusNotFound, status, "deleted accounts are hidden by default")
+
+	status, resp := do(t, s, http.MethodGet, "/v1/accounts/"+testAccount.ID.String()+"?include_deleted=true", "", nil)
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "2026-03-01T12:00:00Z", resp["deleted_at"])
+}
+
+func TestGetAccount_InvalidIncludeDeleted(t *testing.T) {
+	s, _ := newFakeServer(t)
+
+	status, resp := do(t, s, http.MethodGet, "/v1/accounts/"+testAccount.ID.String()+"?include_deleted=maybe", "", nil)
+
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, map[string]any{"include_deleted": "must be true or false"}, resp["fields"])
+}
+
+func TestDeleteAccount(t *testing.T) {
+	s, store := newFakeServer(t)
+
+	status, resp := do(t, s, http.MethodDelete, "/v1/accounts/"+testAccount.ID.String(), "", nil)
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "2026-03-01T12:00:00Z", resp["deleted_at"])
+	assert.True(t, storedAccount(t, store, testAccount.ID).DeletedAt.Valid)
+	assertAudit(t, store, EventAccountDeleted, "client:"+testClient.ID.String(),
+		accountAuditLog{AccountID: testAccount.ID, ClientID: testClient.ID, Name: "main"})
+}
+
+func TestDeleteAccount_Refused(t *testing.T) {
+	ctx := context.Background()
+	testCases := []struct {
+		name     string
+		setup    func(*testing.T, *repository.FakeStore) string
+		wantCode int
+		wantBody string
+	}{
+		{"open payments", func(t *testing.T, store *repository.FakeStore) string {
+			_, err := store.CreatePayment(ctx, repository.CreatePaymentParams{
+				ClientID: testClient.ID, AccountID: testAccount.ID, UniqueWallet: "TWallet7", Token: "USDT", Mode: "live",
+			})
+			require.NoError(t, err)
+			return testAccount.ID.String()
+		}, http.StatusConflict, codeAccountHasOpenPayments},
+		{"already deleted", func(t *testing.T, store *repository.FakeStore) string {
+			_, err := store.SoftDeleteAccount(ctx, repository.SoftDeleteAccountParams{ID: testAccount.ID, ClientID: testClient.ID})
+			require.NoError(t, err)
+			return testAccount.ID.String()
+		}, http.StatusNotFound, codeAccountNotFound},
+		{"other client's account", func(t *testing.T, store *repository.FakeStore) string {
+			return otherClientAccount(t, store).ID.String()
+		}, http.StatusNotFound, codeAccountNotFound},
+		{"malformed id", func(*testing.T, *repository.FakeStore) string { return "acct" }, http.StatusNotFound, codeAccountNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store := newFakeServer(t)
+			id := tc.setup(t, store)
+
+			status, resp := do(t, s, http.MethodDelete, "/v1/accounts/"+id, "", nil)
+
+			assert.Equal(t, tc.wantCode, status)
+			assert.Equal(t, tc.wantBody, resp["code"])
+			assert.Empty(t, store.Logs())
+		})
+	}
+}
+
+func TestReplaceAccount(t *testing.T) {
+	s, store := newFakeServer(t)
+	_, err := store.ReplaceAccount(context.Background(), repository.ReplaceAccountParams{
+		Name:     "main",
+		Metadata: []byte(`{"region":"us"}`),
+		ID:       testAccount.ID,
+		ClientID: testClient.ID,
+	})
+	require.NoError(t, err)
+
+	status, resp := do(t, s, http.MethodPut, "/v1/accounts/"+testAccount.ID.String(),
+		`{"name":"EU store","default_expiry_seconds":300}`, nil)
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "EU store", resp["name"])
+	assert.Equal(t, float64(300), resp["default_expiry_seconds"])
+	assert.NotContains(t, resp, "metadata", "settings left out of a PUT are cleared")
+	assert.Nil(t, storedAccount(t, store, testAccount.ID).Metadata)
+	assertAudit(t, store, EventAccountUpdated, "client:"+testClient.ID.String(),
+		accountAuditLog{AccountID: testAccount.ID, ClientID: testClient.ID, Name: "EU store"})
+}
+
+func TestReplaceAccount_NotFound(t *testing.T) {
+	s, store := newFakeServer(t)
+	other := otherClientAccount(t, store)
+
+	status, resp := do(t, s, http.MethodPut, "/v1/accounts/"+other.ID.String(), `{"name":"x"}`, nil)
+
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, codeAccountNotFound, resp["code"])
+	assert.Equal(t, "theirs", other.Name)
+	assert.Empty(t, store.Logs())
+}
+
+func TestUpdateAccount(t *testing.T) {
+	s, store := newFakeServer(t)
+	expiry := int64(300)
+	_, err := store.ReplaceAccount(context.Background(), repository.ReplaceAccountParams{
+		Name:                 "main",
+		DefaultExpirySeconds: &expiry,
+		ID:                   testAccount.ID,
+		ClientID:             testClient.ID,
+	})
+	require.NoError(t, err)
+
+	status, resp := do(t, s, http.MethodPatch, "/v1/accounts/"+testAccount.ID.String(), `{"name":"  EU store "}`, nil)
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "EU store", resp["name"])
+	assert.Equal(t, float64(300), resp["default_expiry_seconds"], "settings not in the patch are kept")
+	assertAudit(t, store, EventAccountUpdated, "client:"+testClient.ID.String(),
+		accountAuditLog{AccountID: testAccount.ID, ClientID: testClient.ID, Name: "EU store"})
+}
+
+func TestUpdateAccount_KeepsOmittedName(t *testing.T) {
+	s, store := newFakeServer(t)
+
+	status, resp := do(t, s, http.MethodPatch, "/v1/accounts/"+testAccount.ID.String(), `{}`, nil)
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "main", resp["name"])
+	assertAudit(t, store, EventAccountUpdated, "client:"+testClient.ID.String(),
+		accountAuditLog{AccountID: testAccount.ID, ClientID: testClient.ID, Name: "main"})
+}
+
+func TestUpdateAccount_InvalidName(t *testing.T) {
+	testCases := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"empty", `{"name":""}`, "is required"},
+		{"blank", `{"name":"   "}`, "is required"},
+		{"too long", `{"name":"` + strings.Repeat("a", maxNameLength+1) + `"}`, "must be at most 200 bytes"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store := newFakeServer(t)
+
+			status, resp := do(t, s, http.MethodPatch, "/v1/accounts/"+testAccount.ID.String(), tc.body, nil)
+
+			assert.Equal(t, http.StatusBadRequest, status)
+			assert.Equal(t, map[string]any{"name": tc.want}, resp["fields"])
+			assert.Equal(t, "main", storedAccount(t, store, testAccount.ID).Name)
+		})
+	}
+}
+
+func TestAccount_AddressStrategy(t *testing.T) {
+	s, store := newFakeServer(t)
+
+	status, resp := do(t, s, http.MethodPost, "/v1/accounts", `{"name":"EU store","address_strategy":"amount_suffix"}`, nil)
+
+	require.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "amount_suffix", resp["address_strategy"])
+	id := resp["id"].(string)
+	assert.NotContains(t, resp, "deposit_wallet", "the deposit wallet is derived with the first payment")
+
+	wallet, index := "TWallet4", int64(4)
+	_, err := store.SetAccountDepositWallet(context.Background(), repository.SetAccountDepositWalletParams{
+		DepositWallet:      wallet,
+		DepositWalletIndex: index,
+		ID:                 uuid.MustParse(id),
+	})
+	require.NoError(t, err)
+	status, resp = do(t, s, http.MethodGet, "/v1/accounts/"+id, "", nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "TWallet4", resp["deposit_wallet"])
+
+	status, resp = do(t, s, http.MethodPatch, "/v1/accounts/"+id, `{"address_strategy":"unique_wallet"}`, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "unique_wallet", resp["address_strategy"])
+	assert.Equal(t, "EU store", resp["name"], "fields not in the patch are kept")
+	assert.Equal(t, "TWallet4", resp["deposit_wallet"], "the deposit wallet stays with the account")
+
+	status, resp = do(t, s, http.MethodPatch, "/v1/accounts/"+id, `{"address_strategy":"round_robin"}`, nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, map[string]any{"address_strategy": "must be one of unique_wallet, amount_suffix"}, resp["fields"])
+
+	status, resp = do(t, s, http.MethodPut, "/v1/accounts/"+id, `{"name":"EU store","address_strategy":"amount_suffix"}`, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "amount_suffix", resp["address_strategy"])
+	status, resp = do(t, s, http.MethodPut, "/v1/accounts/"+id, `{"name":"EU store"}`, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "unique_wallet", resp["address_strategy"], "a PUT without a strategy sets the default")
+}
+
+func TestUpdateAccount_OtherClient(t *testing.T) {
+	s, store := newFakeServer(t)
+	// The account belongs to another client, so the update scoped to this
+	// client matches nothing.
+	other := otherClientAccount(t, store)
+
+	status, resp := do(t, s, http.MethodPatch, "/v1/accounts/"+other.ID.String(), `{"name":"mine now"}`, nil)
+
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, codeAccountNotFound, resp["code"])
+	assert.Empty(t, store.Logs())
+}
+
+func TestAccountLifecycle(t *testing.T) {
+	s, store := newFakeServer(t)
+
+	status, resp := do(t, s, http.MethodPost, "/v1/accounts", `{"name":"EU store"}`, nil)
+	require.Equal(t, http.StatusCreated, status)
+	path := "/v1/accounts/" + resp["id"].(string)
+
+	status, _ = do(t, s, http.MethodPatch, path, `{"name":"EU shop"}`, nil)
+	require.Equal(t, http.StatusOK, status)
+	status, resp = do(t, s, http.MethodGet, path, "", nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "EU shop", resp["name"])
+
+	status, _ = do(t, s, http.MethodDelete, path, "", nil)
+	require.Equal(t, http.StatusOK, status)
+	status, _ = do(t, s, http.MethodPatch, path, `{"name":"back"}`, nil)
+	assert.Equal(t, http.StatusNotFound, status, "a deleted account cannot be changed")
+
+	var events []string
+	for _, l := range store.Logs() {
+		events = append(events, l.EventType)
+	}
+	assert.Equal(t, []string{EventAccountCreated, EventAccountUpdated, EventAccountDeleted}, events)
+}
@@ -0,0 +1,387 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/requestid"
+)
+
+// Audit events written by the admin, account and webhook endpoint routes.
+const (
+	EventClientCreated        = "CLIENT_CREATED"
+	EventClientDeactivated    = "CLIENT_DEACTIVATED"
+	EventClientKeyRotated     = "CLIENT_KEY_ROTATED"
+	EventAccountCreated       = "ACCOUNT_CREATED"
+	EventAccountUpdated       = "ACCOUNT_UPDATED"
+	EventAccountDeleted       = "ACCOUNT_DELETED"
+	EventWebhookSecretRotated = "WEBHOOK_SECRET_ROTATED"
+)
+
+const (
+	// adminActorHeader optionally names the operator behind an admin
+	// request, so the audit log can tell operators sharing the token apart.
+	adminActorHeader = "X-Admin-Actor"
+	maxActorLength   = 64
+
+	// apiKeyPrefix starts every generated live client API key, and
+	// testAPIKeyPrefix every test one, so a test key is told apart at a
+	// glance.
+	apiKeyPrefix     = "sk_"
+	testAPIKeyPrefix = "tpg_test_"
+
+	defaultClientPageSize = 50
+	maxClientPageSize     = 200
+
+	maxNameLength = 200
+)
+
+type actorKey struct{}
+
+// actorFrom returns the principal adminAuth or authenticate stored in ctx.
+func actorFrom(ctx context.Context) string {
+	a, _ := ctx.Value(actorKey{}).(string)
+	return a
+}
+
+// clientActor is the audit principal of a merchant acting with its API key.
+func clientActor(id uuid.UUID) string {
+	return "client:" + id.String()
+}
+
+// validActor reports whether s may name an operator: 1 to 64 letters,
+// digits or '.', '_', '-', '@'.
+func validActor(s string) bool {
+	if len(s) == 0 || len(s) > maxActorLength {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '_', c == '-', c == '@':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// adminAuth lets through requests bearing the admin token and records the
+// acting principal, "admin" or "admin:<X-Admin-Actor>", in the context. The
+// token is compared by hash in constant time, and merchant API keys are
+// never looked up, so a merchant key gets the same 401 as any wrong token.
+func (s *Server) adminAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := apiKey(r)
+		got := sha256.Sum256([]byte(key))
+		if key == "" || subtle.ConstantTimeCompare(got[:], s.adminToken[:]) != 1 {
+			apierror.Write(w, r, apierror.New(http.StatusUnauthorized, codeUnauthorized, "invalid admin token"))
+			return
+		}
+		actor := "admin"
+		if name := r.Header.Get(adminActorHeader); name != "" {
+			if !validActor(name) {
+				apierror.Write(w, r, &apierror.Error{
+					HTTPStatus: http.StatusBadRequest,
+					Code:       codeValidationFailed,
+					Message:    "request has invalid fields",
+					Fields:     map[string]string{adminActorHeader: "must be 1 to 64 letters, digits or . _ - @"},
+				})
+				return
+			}
+			actor += ":" + name
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), actorKey{}, actor)))
+	})
+}
+
+// NewAPIKey returns a random API key for a client in mode.
+func NewAPIKey(mode string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	prefix := apiKeyPrefix
+	if mode == config.ModeTest {
+		prefix = testAPIKeyPrefix
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// audit logs event in the same transaction as the change it records, with
+// the acting principal and request ID of ctx.
+func audit(ctx context.Context, q repository.Querier, event, msg string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit data: %w", err)
+	}
+	actor := actorFrom(ctx)
+	if err := q.CreateLog(ctx, repository.CreateLogParams{
+		EventType: event,
+		Message:   &msg,
+		RawData:   raw,
+		RequestID: requestid.Ptr(ctx),
+		Actor:     &actor,
+	}); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+type createClientRequest struct {
+	Name string `json:"name"`
+	// Mode is live or test; empty means live.
+	Mode string `json:"mode"`
+}
+
+// clientRecord is a client as the admin API shows it. APIKey is only set
+// when the key was just created, as it cannot be shown again.
+type clientRecord struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Mode      string    `json:"mode"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt string    `json:"created_at"`
+	APIKey    string    `json:"api_key,omitempty"`
+}
+
+type clientPage struct {
+	Clients []clientRecord `json:"clients"`
+	// NextCursor is passed as ?cursor= to get the next page; it is left out
+	// on the last one.
+	NextCursor *uuid.UUID `json:"next_cursor,omitempty"`
+}
+
+type clientAuditLog struct {
+	ClientID uuid.UUID `json:"client_id"`
+	Name     string    `json:"name,omitempty"`
+	Mode     string    `json:"mode,omitempty"`
+}
+
+func newClientRecord(c repository.Client) clientRecord {
+	return clientRecord{
+		ID:        c.ID,
+		Name:      c.Name,
+		Mode:      clientMode(c),
+		IsActive:  c.IsActive == nil || *c.IsActive,
+		CreatedAt: formatTime(c.CreatedAt),
+	}
+}
+
+// clientMode returns the mode of c; clients created before modes are live.
+func clientMode(c repository.Client) string {
+	if c.Mode == "" {
+		return config.ModeLive
+	}
+	return c.Mode
+}
+
+// livemode reports whether a payment in mode is a live one.
+func livemode(mode string) bool {
+	return mode != config.ModeTest
+}
+
+// validateName returns what is wrong with the name of a new client or
+// account.
+func validateName(name string) string {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return "is required"
+	case len(name) > maxNameLength:
+		return fmt.Sprintf("must be at most %d bytes", maxNameLength)
+	}
+	return ""
+}
+
+// createClient handles POST /admin/clients. The response carries the new
+// client's API key, the only time it is shown. Test clients can only be
+// created while test mode is on.
+func (s *Server) createClient(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req createClientRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+	fields := make(map[string]string)
+	if msg := validateName(req.Name); msg != "" {
+		fields["name"] = msg
+	}
+	switch req.Mode {
+	case "":
+		req.Mode = config.ModeLive
+	case config.ModeLive:
+	case config.ModeTest:
+		if s.testWallets == nil {
+			fields["mode"] = "test mode is not enabled"
+		}
+	default:
+		fields["mode"] = "must be live or test"
+	}
+	if len(fields) > 0 {
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusBadRequest,
+			Code:       codeValidationFailed,
+			Message:    "request has invalid fields",
+			Fields:     fields,
+		})
+		return
+	}
+	name, mode := strings.TrimSpace(req.Name), req.Mode
+	key, err := NewAPIKey(mode)
+	if err != nil {
+		s.internalError(w, r, "failed to create client", err)
+		return
+	}
+
+	var client repository.Client
+	err = s.store.ExecTx(ctx, func(q repository.Querier) error {
+		client, err = q.CreateClient(ctx, repository.CreateClientParams{Name: name, ApiKey: key, Mode: mode})
+		if err != nil {
+			return fmt.Errorf("failed to insert client: %w", err)
+		}
+		return audit(ctx, q, EventClientCreated, fmt.Sprintf("client %q created", name),
+			clientAuditLog{ClientID: client.ID, Name: name, Mode: mode})
+	})
+	if err != nil {
+		s.internalError(w, r, "failed to create client", err)
+		return
+	}
+	s.logger.InfoContext(ctx, "client created", "client_id", client.ID, "mode", mode, "actor", actorFrom(ctx))
+
+	rec := newClientRecord(client)
+	rec.APIKey = key
+	writeJSON(w, http.StatusCreated, rec)
+}
+
+// deactivateClient handles POST /admin/clients/{id}/deactivate. The
+// client's key stops working at once; its data is kept.
+func (s *Server) deactivateClient(w http.ResponseWriter, r *http.Request) {
+	s.updateClient(w, r, EventClientDeactivated, "client deactivated", nil,
+		func(ctx context.Context, q repository.Querier, previous repository.Client) (repository.Client, error) {
+			return q.DeactivateClient(ctx, previous.ID)
+		})
+}
+
+// rotateClientKey handles POST /admin/clients/{id}/rotate-key. The old key
+// stops working at once; the new one is in the response and shown only
+// there. The new key keeps the prefix of the client's mode.
+func (s *Server) rotateClientKey(w http.ResponseWriter, r *http.Request) {
+	var key string
+	s.updateClient(w, r, EventClientKeyRotated, "client API key rotated", &key,
+		func(ctx context.Context, q repository.Querier, previous repository.Client) (repository.Client, error) {
+			var err error
+			if key, err = NewAPIKey(previous.Mode); err != nil {
+				return repository.Client{}, err
+			}
+			return q.RotateClientAPIKey(ctx, repository.RotateClientAPIKeyParams{ApiKey: key, ID: previous.ID})
+		})
+}
+
+// updateClient applies update to the client named by the id path value, as
+// it was before, and audits it as event, in one transaction, then evicts the
+// client's cached lookup. key, when set by update, is returned as the
+// client's new API key.
+func (s *Server) updateClient(w http.ResponseWriter, r *http.Request, event, msg string, key *string,
+	update func(context.Context, repository.Querier, repository.Client) (repository.Client, error)) {
+	ctx := r.Context()
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, codeClientNotFound, "client not found"))
+		return
+	}
+
+	var previous, client repository.Client
+	err = s.store.ExecTx(ctx, func(q repository.Querier) error {
+		// The cache is keyed by the API key in force before the update.
+		if previous, err = q.GetClientByID(ctx, id); err != nil {
+			return err
+		}
+		client, err = update(ctx, q, previous)
+		if err != nil {
+			return err
+		}
+		return audit(ctx, q, event, msg, clientAuditLog{ClientID: id})
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, codeClientNotFound, "client not found"))
+		return
+	}
+	if err != nil {
+		s.internalError(w, r, "failed to update client", err, "client_id", id, "event", event)
+		return
+	}
+	s.logger.InfoContext(ctx, msg, "client_id", id, "actor", actorFrom(ctx))
+	s.evictClient(ctx, id, previous.ApiKey)
+
+	rec := newClientRecord(client)
+	if key != nil {
+		rec.APIKey = *key
+	}
+	writeJSON(w, http.StatusOK, rec)
+}
+
+// listClients handles GET /admin/clients?limit=&cursor=, paging through
+// clients in id order.
+func (s *Server) listClients(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	fields := make(map[string]string)
+	limit := defaultClientPageSize
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxClientPageSize {
+			fields["limit"] = fmt.Sprintf("must be between 1 and %d", maxClientPageSize)
+		}
+		limit = n
+	}
+	var cursor uuid.UUID
+	if v := query.Get("cursor"); v != "" {
+		var err error
+		if cursor, err = uuid.Parse(v); err != nil {
+			fields["cursor"] = "must be a next_cursor from an earlier page"
+		}
+	}
+	if len(fields) > 0 {
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusBadRequest,
+			Code:       codeValidationFailed,
+			Message:    "request has invalid parameters",
+			Fields:     fields,
+		})
+		return
+	}
+
+	// One extra row tells whether there is a next page.
+	clients, err := s.store.ListClients(r.Context(), repository.ListClientsParams{
+		AfterID: cursor,
+		Limit:   int32(limit + 1),
+	})
+	if err != nil {
+		s.internalError(w, r, "failed to list clients", err)
+		return
+	}
+
+	page := clientPage{Clients: make([]clientRecord, 0, min(len(clients), limit))}
+	if len(clients) > limit {
+		clients = clients[:limit]
+		next := clients[limit-1].ID
+		page.NextCursor = &next
+	}
+	for _, c := range clients {
+		page.Clients = append(page.Clients, newClientRecord(c))
+	}
+	writeJSON(w, http.StatusOK, page)
+}
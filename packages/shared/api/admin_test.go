@@ -0,0 +1,335 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+const testAdminToken = "admin-token-0123456789abcdef0123456789"
+
+func adminHeader(actor string) http.Header {
+	h := http.Header{"Authorization": {"Bearer " + testAdminToken}}
+	if actor != "" {
+		h.Set(adminActorHeader, actor)
+	}
+	return h
+}
+
+func newAdminServer(t *testing.T) (*Server, *mockStore) {
+	t.Helper()
+	s, store, _ := newTestServer(t, WithAdminToken(testAdminToken))
+	return s, store
+}
+
+func storedClient(id uuid.UUID, active bool) repository.Client {
+	return repository.Client{
+		ID:        id,
+		Name:      "shop",
+		ApiKey:    "sk_stored",
+		IsActive:  &active,
+		CreatedAt: pgtype.Timestamptz{Time: t0, Valid: true},
+	}
+}
+
+// expectAudit expects one audit log of event by actor about data.
+func expectAudit(store *mockStore, event, actor string, data any) {
+	want, _ := json.Marshal(data)
+	store.On("CreateLog", mock.Anything, mock.MatchedBy(func(arg repository.CreateLogParams) bool {
+		return arg.EventType == event &&
+			arg.PaymentID == nil &&
+			arg.Actor != nil && *arg.Actor == actor &&
+			arg.RequestID != nil &&
+			string(arg.RawData) == string(want)
+	})).Return(nil).Once()
+}
+
+// assertAudit asserts that the last log of store is an audit log of event
+// by actor about data.
+func assertAudit(t *testing.T, store *repository.FakeStore, event, actor string, data any) {
+	t.Helper()
+	logs := store.Logs()
+	require.NotEmpty(t, logs, "no audit log written")
+	last := logs[len(logs)-1]
+	want, err := json.Marshal(data)
+	require.NoError(t, err)
+	assert.Equal(t, event, last.EventType)
+	assert.False(t, last.PaymentID != nil)
+	require.NotNil(t, last.Actor)
+	assert.Equal(t, actor, *last.Actor)
+	assert.NotNil(t, last.RequestID)
+	assert.JSONEq(t, string(want), string(last.RawData))
+}
+
+// newFakeAdminServer returns a server with the admin API enabled over a
+// FakeStore, and a client created through the store.
+func newFakeAdminServer(t *testing.T, mode string, opts ...Option) (*Server, *repository.FakeStore, repository.Client) {
+	t.Helper()
+	s, store := newFakeServer(t, append([]Option{WithAdminToken(testAdminToken)}, opts...)...)
+	c, err := store.CreateClient(context.Background(), repository.CreateClientParams{Name: "shop", ApiKey: "sk_stored", Mode: mode})
+	require.NoError(t, err)
+	return s, store, c
+}
+
+func TestCreateClient(t *testing.T) {
+	s, store := newFakeServer(t, WithAdminToken(testAdminToken))
+
+	status, resp := do(t, s, http.MethodPost, "/admin/clients", `{"name":" Acme "}`, adminHeader("alice"))
+
+	require.Equal(t, http.StatusCreated, status)
+	id := uuid.MustParse(resp["id"].(string))
+	assert.Equal(t, true, resp["is_active"])
+	assert.Equal(t, config.ModeLive, resp["mode"])
+	assert.Equal(t, "2026-03-01T12:00:00Z", resp["created_at"])
+	key := resp["api_key"].(string)
+	assert.True(t, strings.HasPrefix(key, apiKeyPrefix))
+	assert.Len(t, key, len(apiKeyPrefix)+43, "32 random bytes")
+	stored, err := store.GetClientByAPIKey(context.Background(), key)
+	require.NoError(t, err, "the returned key authenticates the client")
+	assert.Equal(t, id, stored.ID)
+	assert.Equal(t, "Acme", stored.Name)
+	assertAudit(t, store, EventClientCreated, "admin:alice", clientAuditLog{ClientID: id, Name: "Acme", Mode: config.ModeLive})
+}
+
+func TestCreateClient_TestMode(t *testing.T) {
+	s, store := newFakeServer(t, WithAdminToken(testAdminToken), WithTestWallets(testWallets))
+
+	status, resp := do(t, s, http.MethodPost, "/admin/clients", `{"name":"Sandbox","mode":"test"}`, adminHeader(""))
+
+	require.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, config.ModeTest, resp["mode"])
+	key := resp["api_key"].(string)
+	assert.True(t, strings.HasPrefix(key, testAPIKeyPrefix))
+	assert.Len(t, key, len(testAPIKeyPrefix)+43)
+	assertAudit(t, store, EventClientCreated, "admin",
+		clientAuditLog{ClientID: uuid.MustParse(resp["id"].(string)), Name: "Sandbox", Mode: config.ModeTest})
+}
+
+func TestCreateClient_InvalidMode(t *testing.T) {
+	testCases := []struct {
+		name, body, want string
+	}{
+		{"test mode off", `{"name":"Sandbox","mode":"test"}`, "test mode is not enabled"},
+		{"unknown mode", `{"name":"Sandbox","mode":"staging"}`, "must be live or test"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, _ := newAdminServer(t)
+
+			status, resp := do(t, s, http.MethodPost, "/admin/clients", tc.body, adminHeader(""))
+
+			assert.Equal(t, http.StatusBadRequest, status)
+			assert.Equal(t, tc.want, resp["fields"].(map[string]any)["mode"])
+		})
+	}
+}
+
+func TestCreateClient_ValidationErrors(t *testing.T) {
+	s, _ := newAdminServer(t)
+
+	for _, body := range []string{`{"name":"  "}`, `{}`, `{"name":"` + strings.Repeat("x", maxNameLength+1) + `"}`} {
+		status, resp := do(t, s, http.MethodPost, "/admin/clients", body, adminHeader(""))
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, codeValidationFailed, resp["code"])
+		assert.Contains(t, resp["fields"], "name")
+	}
+
+	status, resp := do(t, s, http.MethodPost, "/admin/clients", `{`, adminHeader(""))
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, codeInvalidJSON, resp["code"])
+}
+
+func TestCreateClient_AuditFailureFails(t *testing.T) {
+	s, store := newAdminServer(t)
+	store.On("CreateClient", mock.Anything, mock.Anything).Return(storedClient(uuid.New(), true), nil)
+	store.On("CreateLog", mock.Anything, mock.Anything).Return(errors.New("boom"))
+
+	status, resp := do(t, s, http.MethodPost, "/admin/clients", `{"name":"Acme"}`, adminHeader(""))
+
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Nil(t, resp["api_key"], "a client whose creation was not audited is not handed out")
+}
+
+func TestDeactivateClient(t *testing.T) {
+	s, store, c := newFakeAdminServer(t, config.ModeLive)
+
+	status, resp := do(t, s, http.MethodPost, "/admin/clients/"+c.ID.String()+"/deactivate", "", adminHeader(""))
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, false, resp["is_active"])
+	assert.NotContains(t, resp, "api_key")
+	_, err := store.GetClientByAPIKey(context.Background(), "sk_stored")
+	assert.ErrorIs(t, err, pgx.ErrNoRows, "a deactivated client no longer authenticates")
+	assertAudit(t, store, EventClientDeactivated, "admin", clientAuditLog{ClientID: c.ID})
+}
+
+func TestDeactivateClient_NotFound(t *testing.T) {
+	s, store := newFakeServer(t, WithAdminToken(testAdminToken))
+
+	for _, path := range []string{"/admin/clients/" + uuid.NewString() + "/deactivate", "/admin/clients/nope/deactivate"} {
+		status, resp := do(t, s, http.MethodPost, path, "", adminHeader(""))
+
+		assert.Equal(t, http.StatusNotFound, status)
+		assert.Equal(t, codeClientNotFound, resp["code"])
+	}
+	assert.Empty(t, store.Logs())
+}
+
+func TestRotateClientKey(t *testing.T) {
+	s, store, c := newFakeAdminServer(t, config.ModeLive)
+
+	status, resp := do(t, s, http.MethodPost, "/admin/clients/"+c.ID.String()+"/rotate-key", "", adminHeader("ops-bot"))
+
+	require.Equal(t, http.StatusOK, status)
+	key := resp["api_key"].(string)
+	assert.True(t, strings.HasPrefix(key, apiKeyPrefix))
+	ctx := context.Background()
+	_, err := store.GetClientByAPIKey(ctx, "sk_stored")
+	assert.ErrorIs(t, err, pgx.ErrNoRows, "the old key stops working")
+	rotated, err := store.GetClientByAPIKey(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, c.ID, rotated.ID)
+	assertAudit(t, store, EventClientKeyRotated, "admin:ops-bot", clientAuditLog{ClientID: c.ID})
+}
+
+func TestRotateClientKey_TestClient(t *testing.T) {
+	s, store, c := newFakeAdminServer(t, config.ModeTest)
+
+	status, resp := do(t, s, http.MethodPost, "/admin/clients/"+c.ID.String()+"/rotate-key", "", adminHeader(""))
+
+	require.Equal(t, http.StatusOK, status)
+	assert.True(t, strings.HasPrefix(resp["api_key"].(string), testAPIKeyPrefix), "a test client keeps a test key")
+	assertAudit(t, store, EventClientKeyRotated, "admin", clientAuditLog{ClientID: c.ID})
+}
+
+func TestListClients(t *testing.T) {
+	s, store := newFakeServer(t, WithAdminToken(testAdminToken))
+	ctx := context.Background()
+	for _, key := range []string{"sk_a", "sk_b", "sk_c"} {
+		c, err := store.CreateClient(ctx, repository.CreateClientParams{Name: "shop", ApiKey: key, Mode: config.ModeLive})
+		require.NoError(t, err)
+		if key == "sk_b" {
+			_, err = store.DeactivateClient(ctx, c.ID)
+			require.NoError(t, err)
+		}
+	}
+	all, err := store.ListClients(ctx, repository.ListClientsParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, all, 4, "testClient and the three above")
+
+	status, resp := do(t, s, http.MethodGet, "/admin/clients?limit=2&cursor="+all[0].ID.String(), "", adminHeader(""))
+
+	require.Equal(t, http.StatusOK, status)
+	clients := resp["clients"].([]any)
+	require.Len(t, clients, 2)
+	for i, c := range clients {
+		assert.Equal(t, all[i+1].ID.String(), c.(map[string]any)["id"])
+		assert.Equal(t, *all[i+1].IsActive, c.(map[string]any)["is_active"])
+		assert.NotContains(t, c, "api_key", "listed keys are never shown")
+	}
+	assert.Equal(t, all[2].ID.String(), resp["next_cursor"])
+
+	status, resp = do(t, s, http.MethodGet, "/admin/clients?cursor="+all[2].ID.String(), "", adminHeader(""))
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Len(t, resp["clients"], 1)
+	assert.NotContains(t, resp, "next_cursor", "the last page has no cursor")
+}
+
+func TestListClients_InvalidParams(t *testing.T) {
+	s, _ := newAdminServer(t)
+
+	status, resp := do(t, s, http.MethodGet, "/admin/clients?limit=0&cursor=x", "", adminHeader(""))
+
+	assert.Equal(t, http.StatusBadRequest, status)
+	fields := resp["fields"].(map[string]any)
+	assert.Contains(t, fields, "limit")
+	assert.Contains(t, fields, "cursor")
+}
+
+func TestAdminRoutes_Authorization(t *testing.T) {
+	routes := []struct{ method, path string }{
+		{http.MethodPost, "/admin/clients"},
+		{http.MethodGet, "/admin/clients"},
+		{http.MethodGet, "/admin/logs"},
+		{http.MethodGet, "/admin/invoices"},
+		{http.MethodPost, "/admin/clients/" + testClient.ID.String() + "/deactivate"},
+		{http.MethodPost, "/admin/clients/" + testClient.ID.String() + "/rotate-key"},
+	}
+	headers := map[string]http.Header{
+		"merchant key as bearer":    {"Authorization": {"Bearer " + testAPIKey}},
+		"merchant key as X-API-Key": {"X-Api-Key": {testAPIKey}},
+		"no credentials":            {},
+		"wrong token":               {"Authorization": {"Bearer " + testAdminToken + "x"}},
+		"admin token prefix":        {"Authorization": {"Bearer " + testAdminToken[:10]}},
+	}
+
+	for _, route := range routes {
+		for name, header := range headers {
+			t.Run(route.method+" "+route.path+"/"+name, func(t *testing.T) {
+				// The mock store fails the test on any call: no key is
+				// looked up and nothing is written.
+				s, _ := newAdminServer(t)
+
+				status, resp := do(t, s, route.method, route.path, `{"name":"x"}`, header)
+
+				assert.Equal(t, http.StatusUnauthorized, status)
+				assert.Equal(t, codeUnauthorized, resp["code"])
+			})
+		}
+	}
+}
+
+func TestAdminRoutes_InvalidActor(t *testing.T) {
+	s, _ := newAdminServer(t)
+
+	status, resp := do(t, s, http.MethodGet, "/admin/clients", "", adminHeader("alice smith"))
+
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, resp["fields"], adminActorHeader)
+}
+
+func TestAdminRoutes_DisabledWithoutToken(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithAdminToken("")}} {
+		s, _, _ := newTestServer(t, opts...)
+		req := httptest.NewRequest(http.MethodGet, "/admin/clients", nil)
+		req.Header.Set("Authorization", "Bearer ")
+		rec := httptest.NewRecorder()
+
+		s.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestAdminToken_NotAMerchantKey(t *testing.T) {
+	s, store := newAdminServer(t)
+	store.On("GetClientByAPIKey", mock.Anything, testAdminToken).Return(repository.Client{}, pgx.ErrNoRows)
+
+	status, _ := do(t, s, http.MethodPost, "/v1/accounts", `{"name":"x"}`, adminHeader(""))
+
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestValidActor(t *testing.T) {
+	assert.True(t, validActor("alice@example.com"))
+	assert.True(t, validActor("ops_bot-2"))
+	assert.False(t, validActor(""))
+	assert.False(t, validActor("alice smith"))
+	assert.False(t, validActor("admin\nforged"))
+	assert.False(t, validActor(strings.Repeat("a", maxActorLength+1)))
+}
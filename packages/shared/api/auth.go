@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/cache"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/logging"
+)
+
+// apiKeyHeader carries the client's API key when the Authorization header
+// does not.
+const apiKeyHeader = "X-API-Key"
+
+// clientCachePrefix namespaces the client cache entries, keyed by the
+// SHA-256 of the API key so the cache never holds a usable key.
+const clientCachePrefix = "tpg:client:"
+
+// cachedClient is what the client cache keeps of a client. Mode picks the
+// wallets of its payments, so an entry without one, cached by an older
+// release, is read again from the database.
+type cachedClient struct {
+	ID     uuid.UUID `json:"id"`
+	Active bool      `json:"active"`
+	Mode   string    `json:"mode"`
+}
+
+type clientKey struct{}
+
+// clientFrom returns the client authenticate stored in ctx.
+func clientFrom(ctx context.Context) repository.Client {
+	c, _ := ctx.Value(clientKey{}).(repository.Client)
+	return c
+}
+
+// apiKey reads the key from "Authorization: Bearer <key>", or from
+// X-API-Key.
+func apiKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scheme, key, ok := strings.Cut(auth, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(key)
+		}
+		return ""
+	}
+	return r.Header.Get(apiKeyHeader)
+}
+
+// authenticate looks up the active client owning the request's API key and
+// passes it to next in the context, along with "client:<id>" as the acting
+// principal for audit logs; requests without one get a 401.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := apiKey(r)
+		if key == "" {
+			apierror.Write(w, r, apierror.New(http.StatusUnauthorized, codeUnauthorized, "missing API key"))
+			return
+		}
+		client, err := s.lookupClient(r.Context(), key)
+		if errors.Is(err, pgx.ErrNoRows) {
+			apierror.Write(w, r, apierror.New(http.StatusUnauthorized, codeUnauthorized, "invalid API key"))
+			return
+		}
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "failed to authenticate client", "error", err)
+			apierror.Write(w, r, apierror.Internal())
+			return
+		}
+		if info := infoFrom(r.Context()); info != nil {
+			info.clientID = client.ID
+		}
+		ctx := context.WithValue(r.Context(), clientKey{}, client)
+		ctx = context.WithValue(ctx, actorKey{}, clientActor(client.ID))
+		ctx = logging.WithClientID(ctx, client.ID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ClientCacheKey is the key the client owning apiKey is cached under. Tools
+// that change clients outside the API evict it so the change applies at once.
+func ClientCacheKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return clientCachePrefix + hex.EncodeToString(sum[:])
+}
+
+// lookupClient returns the active client owning key, reading through the
+// client cache when there is one. A cached client carries only its ID,
+// active flag and mode. Cache failures are logged and fall back to the
+// database.
+func (s *Server) lookupClient(ctx context.Context, key string) (repository.Client, error) {
+	if s.clients == nil {
+		return s.store.GetClientByAPIKey(ctx, key)
+	}
+	cacheKey := ClientCacheKey(key)
+	raw, err := s.clients.Get(ctx, cacheKey)
+	if err == nil {
+		var c cachedClient
+		switch err := json.Unmarshal(raw, &c); {
+		case err != nil:
+			s.logger.WarnContext(ctx, "discarding malformed client cache entry")
+		case !c.Active:
+			return repository.Client{}, pgx.ErrNoRows
+		case c.Mode != "":
+			return repository.Client{ID: c.ID, IsActive: &c.Active, Mode: c.Mode}, nil
+		}
+	} else if !errors.Is(err, cache.ErrMiss) {
+		s.logger.WarnContext(ctx, "failed to read client cache", "error", err)
+	}
+
+	client, err := s.store.GetClientByAPIKey(ctx, key)
+	if err != nil {
+		return repository.Client{}, err
+	}
+	raw, err = json.Marshal(cachedClient{ID: client.ID, Active: true, Mode: clientMode(client)})
+	if err == nil {
+		err = s.clients.Set(ctx, cacheKey, raw, s.clientTTL)
+	}
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to cache client", "client_id", client.ID, "error", err)
+	}
+	return client, nil
+}
+
+// evictClient drops the cached lookup of apiKey. An entry that outlives a
+// failed eviction expires with the cache TTL.
+func (s *Server) evictClient(ctx context.Context, clientID uuid.UUID, apiKey string) {
+	if s.clients == nil {
+		return
+	}
+	if err := s.clients.Delete(ctx, ClientCacheKey(apiKey)); err != nil {
+		s.logger.ErrorContext(ctx, "failed to evict cached client", "client_id", clientID, "error", err)
+	}
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/cache"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+const testClientTTL = time.Minute
+
+func newCachedServer(t *testing.T, opts ...Option) (*Server, *mockStore, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	opts = append([]Option{WithAdminToken(testAdminToken), WithClientCache(cache.NewRedis(client), testClientTTL)}, opts...)
+	s, store, _ := newTestServer(t, opts...)
+	return s, store, mr
+}
+
+// authenticated sends a request with key through authenticate and returns
+// the status and the client handed to the handler.
+func authenticated(t *testing.T, s *Server, key string) (int, repository.Client) {
+	t.Helper()
+	var got repository.Client
+	h := s.authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = clientFrom(r.Context())
+		writeJSON(w, http.StatusOK, map[string]string{})
+	}))
+	status, _ := do(t, h, http.MethodGet, "/", "", http.Header{"Authorization": {"Bearer " + key}})
+	return status, got
+}
+
+func TestAuthenticate_CacheHit(t *testing.T) {
+	s, store, mr := newCachedServer(t)
+	store.On("GetClientByAPIKey", mock.Anything, testAPIKey).Return(testClient, nil).Once()
+
+	status, got := authenticated(t, s, testAPIKey)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, testClient, got, "a miss hands over the stored client")
+
+	status, got = authenticated(t, s, testAPIKey)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, testClient.ID, got.ID, "the hit skips the database")
+	assert.True(t, *got.IsActive)
+	assert.Equal(t, config.ModeLive, got.Mode)
+
+	keys := mr.Keys()
+	require.Len(t, keys, 1)
+	assert.Equal(t, ClientCacheKey(testAPIKey), keys[0])
+	assert.NotContains(t, keys[0], testAPIKey)
+	value, err := mr.Get(keys[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"`+testClient.ID.String()+`","active":true,"mode":"live"}`, value, "only the ID, active flag and mode")
+	assert.Equal(t, testClientTTL, mr.TTL(keys[0]))
+}
+
+func TestAuthenticate_CachedEntryWithoutMode(t *testing.T) {
+	s, store, mr := newCachedServer(t)
+	require.NoError(t, mr.Set(ClientCacheKey(testAPIKey), `{"id":"`+testClient.ID.String()+`","active":true}`))
+	test := testClient
+	test.Mode = config.ModeTest
+	store.On("GetClientByAPIKey", mock.Anything, testAPIKey).Return(test, nil).Once()
+
+	status, got := authenticated(t, s, testAPIKey)
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, config.ModeTest, got.Mode, "an entry of an older release is not taken for a live client")
+	value, err := mr.Get(ClientCacheKey(testAPIKey))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"`+testClient.ID.String()+`","active":true,"mode":"test"}`, value)
+}
+
+func TestAuthenticate_CacheMissUnknownKey(t *testing.T) {
+	s, store, mr := newCachedServer(t)
+	store.On("GetClientByAPIKey", mock.Anything, "sk_unknown").Return(repository.Client{}, pgx.ErrNoRows).Twice()
+
+	for range 2 {
+		status, _ := authenticated(t, s, "sk_unknown")
+		assert.Equal(t, http.StatusUnauthorized, status)
+	}
+	assert.Empty(t, mr.Keys(), "unknown keys are not cached")
+}
+
+func TestAuthenticate_CacheExpiry(t *testing.T) {
+	s, store, mr := newCachedServer(t)
+	store.On("GetClientByAPIKey", mock.Anything, testAPIKey).Return(testClient, nil).Twice()
+
+	status, _ := authenticated(t, s, testAPIKey)
+	require.Equal(t, http.StatusOK, status)
+	mr.FastForward(testClientTTL)
+
+	status, _ = authenticated(t, s, testAPIKey)
+	assert.Equal(t, http.StatusOK, status, "an expired entry is read again from the database")
+}
+
+func TestAuthenticate_CachedInactiveClient(t *testing.T) {
+	s, _, mr := newCachedServer(t)
+	require.NoError(t, mr.Set(ClientCacheKey(testAPIKey), `{"id":"`+testClient.ID.String()+`","active":false}`))
+
+	status, _ := authenticated(t, s, testAPIKey)
+
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestAuthenticate_CacheDownFallsBack(t *testing.T) {
+	s, store, mr := newCachedServer(t)
+	mr.Close()
+	store.On("GetClientByAPIKey", mock.Anything, testAPIKey).Return(testClient, nil).Once()
+
+	status, got := authenticated(t, s, testAPIKey)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, testClient, got)
+}
+
+func TestRotateClientKey_EvictsCachedClient(t *testing.T) {
+	s, store, mr := newCachedServer(t)
+	id := uuid.New()
+	old := storedClient(id, true)
+	store.On("GetClientByAPIKey", mock.Anything, old.ApiKey).Return(old, nil).Once()
+	status, _ := authenticated(t, s, old.ApiKey)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, mr.Keys(), 1)
+
+	store.On("GetClientByID", mock.Anything, id).Return(old, nil).Once()
+	store.On("RotateClientAPIKey", mock.Anything, mock.Anything).Return(storedClient(id, true), nil)
+	expectAudit(store, EventClientKeyRotated, "admin", clientAuditLog{ClientID: id})
+	status, _ = do(t, s, http.MethodPost, "/admin/clients/"+id.String()+"/rotate-key", "", adminHeader(""))
+	require.Equal(t, http.StatusOK, status)
+	assert.Empty(t, mr.Keys(), "the old key's entry is evicted")
+
+	store.On("GetClientByAPIKey", mock.Anything, old.ApiKey).Return(repository.Client{}, pgx.ErrNoRows).Once()
+	status, _ = authenticated(t, s, old.ApiKey)
+	assert.Equal(t, http.StatusUnauthorized, status, "the old key stops working at once")
+}
+
+func TestDeactivateClient_EvictsCachedClient(t *testing.T) {
+	s, store, mr := newCachedServer(t)
+	id := uuid.New()
+	active := storedClient(id, true)
+	require.NoError(t, mr.Set(ClientCacheKey(active.ApiKey), `{"id":"`+id.String()+`","active":true,"mode":"live"}`))
+
+	store.On("GetClientByID", mock.Anything, id).Return(active, nil).Once()
+	store.On("DeactivateClient", mock.Anything, id).Return(storedClient(id, false), nil)
+	expectAudit(store, EventClientDeactivated, "admin", clientAuditLog{ClientID: id})
+	status, _ := do(t, s, http.MethodPost, "/admin/clients/"+id.String()+"/deactivate", "", adminHeader(""))
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Empty(t, mr.Keys())
+}
+
+func TestDeactivateClient_CachedNotFound(t *testing.T) {
+	s, store, _ := newCachedServer(t)
+	id := uuid.New()
+	store.On("GetClientByID", mock.Anything, id).Return(repository.Client{}, pgx.ErrNoRows).Once()
+
+	status, resp := do(t, s, http.MethodPost, "/admin/clients/"+id.String()+"/deactivate", "", adminHeader(""))
+
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, codeClientNotFound, resp["code"])
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/outbox"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/requestid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/webhooks"
+)
+
+const (
+	// EventCancelled is logged when a merchant cancels a payment.
+	EventCancelled = "PAYMENT_CANCELLED"
+	// EventPaymentCancelled notifies the merchant that a payment was
+	// cancelled.
+	EventPaymentCancelled = webhooks.EventPaymentCancelled
+)
+
+type cancelledLog struct {
+	PreviousStatus string `json:"previous_status"`
+}
+
+// cancelPayment handles POST /v1/payments/{id}/cancel. A payment can be
+// cancelled while it is PENDING and no transfer to it has been seen; once a
+// transfer is detected the customer has paid, and cancelling would strand
+// the funds. The cancellation, its log and its outbox event are written in
+// one transaction.
+func (s *Server) cancelPayment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	client := clientFrom(ctx)
+	tenant := s.tenant(ctx)
+	payment, ok := s.lookupPayment(w, r, tenant.GetPayment)
+	if !ok {
+		return
+	}
+	if !checkCancellable(w, r, payment) {
+		return
+	}
+
+	cancelled, err := outbox.Transition(ctx, s.store, s.changes, EventPaymentCancelled, s.now(), func(q repository.Querier) (repository.Payment, error) {
+		current, err := repository.NewTenantQueries(q, client.ID).GetPayment(ctx, payment.ID)
+		if err != nil {
+			return repository.Payment{}, fmt.Errorf("failed to load payment: %w", err)
+		}
+		cancelled, err := q.CancelPayment(ctx, repository.CancelPaymentParams{
+			ID:         payment.ID,
+			FromStatus: payment.Status,
+			Version:    current.Version,
+		})
+		if err != nil {
+			return repository.Payment{}, err
+		}
+		raw, err := json.Marshal(cancelledLog{PreviousStatus: payment.Status})
+		if err != nil {
+			return repository.Payment{}, fmt.Errorf("failed to encode log data: %w", err)
+		}
+		msg := fmt.Sprintf("cancelled while %s", payment.Status)
+		actor := actorFrom(ctx)
+		if err := q.CreateLog(ctx, repository.CreateLogParams{
+			PaymentID: repository.UUIDPtr(payment.ID),
+			EventType: EventCancelled,
+			Message:   &msg,
+			RawData:   raw,
+			RequestID: requestid.Ptr(ctx),
+			Actor:     &actor,
+		}); err != nil {
+			return repository.Payment{}, fmt.Errorf("failed to write %s log: %w", EventCancelled, err)
+		}
+		return cancelled, nil
+	})
+	if errors.Is(err, repository.ErrPaymentStatusChanged) {
+		// The payment moved on after it was read, e.g. it was confirmed or a
+		// transfer to it was recorded. Report what it is now.
+		current, err := tenant.GetPayment(ctx, payment.ID)
+		if err != nil {
+			s.internalError(w, r, "failed to reload payment", err, "payment_id", payment.ID)
+			return
+		}
+		if checkCancellable(w, r, current) {
+			apierror.Write(w, r, apierror.New(http.StatusConflict, codePaymentHasTransfer, "a transfer to the payment has been recorded"))
+		}
+		return
+	}
+	if err != nil {
+		s.internalError(w, r, "failed to cancel payment", err, "payment_id", payment.ID)
+		return
+	}
+	s.logger.InfoContext(ctx, "payment cancelled", "payment_id", payment.ID, "client_id", client.ID,
+		"previous_status", payment.Status)
+	writeJSON(w, http.StatusOK, s.paymentRecord(cancelled))
+}
+
+// checkCancellable writes a 409 and returns false unless payment may be
+// cancelled as far as its status tells.
+func checkCancellable(w http.ResponseWriter, r *http.Request, payment repository.Payment) bool {
+	switch {
+	case payment.Status == repository.PaymentDetected:
+		apierror.Write(w, r, apierror.New(http.StatusConflict, codePaymentHasTransfer, "a transfer to the payment has been detected"))
+	case !repository.CanTransitionPayment(payment.Status, repository.PaymentCancelled):
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusConflict,
+			Code:       codePaymentNotCancellable,
+			Message:    fmt.Sprintf("a %s payment cannot be cancelled", payment.Status),
+		})
+	default:
+		return true
+	}
+	return false
+}
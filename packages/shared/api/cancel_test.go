@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/events"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+var cancelPath = "/v1/payments/" + testPaymentID.String() + "/cancel"
+
+func paymentIn(status string) repository.Payment {
+	p := storedPayment()
+	p.Status = status
+	p.ConfirmedAt = pgtype.Timestamptz{}
+	return p
+}
+
+func expectCancel(store *mockStore, from string, err error) {
+	cancelled := paymentIn(repository.PaymentCancelled)
+	if err != nil {
+		cancelled = repository.Payment{}
+	}
+	store.On("CancelPayment", mock.Anything, repository.CancelPaymentParams{ID: testPaymentID, FromStatus: from}).
+		Return(cancelled, err).Once()
+}
+
+func TestCancelPayment(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(paymentIn(repository.PaymentPending), nil)
+	expectCancel(store, repository.PaymentPending, nil)
+	store.On("CreateLog", mock.Anything, mock.MatchedBy(func(arg repository.CreateLogParams) bool {
+		var data cancelledLog
+		return *arg.PaymentID == testPaymentID &&
+			arg.EventType == EventCancelled &&
+			*arg.Actor == clientActor(testClient.ID) &&
+			json.Unmarshal(arg.RawData, &data) == nil && data.PreviousStatus == repository.PaymentPending
+	})).Return(nil).Once()
+	store.On("CreateOutboxEvent", mock.Anything, mock.MatchedBy(func(arg repository.CreateOutboxEventParams) bool {
+		var event struct {
+			ID   uuid.UUID `json:"id"`
+			Type string    `json:"type"`
+			Data struct {
+				Status string `json:"status"`
+			} `json:"data"`
+		}
+		return arg.EventType == EventPaymentCancelled &&
+			arg.AggregateID == testPaymentID &&
+			arg.RequestID != nil &&
+			json.Unmarshal(arg.Payload, &event) == nil &&
+			event.ID == arg.ID &&
+			event.Type == EventPaymentCancelled && event.Data.Status == repository.PaymentCancelled
+	})).Return(nil).Once()
+
+	status, resp := do(t, s, http.MethodPost, cancelPath, "", nil)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, testPaymentID.String(), resp["id"])
+	assert.Equal(t, repository.PaymentCancelled, resp["status"])
+}
+
+func TestCancelPayment_PublishesTransition(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	transitions := events.NewTypedBus()
+	s.changes = transitions
+	ch, cancel := transitions.Subscribe(EventPaymentCancelled)
+	defer cancel()
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(paymentIn(repository.PaymentPending), nil)
+	expectCancel(store, repository.PaymentPending, nil)
+	store.On("CreateLog", mock.Anything, mock.Anything).Return(nil).Once()
+	store.On("CreateOutboxEvent", mock.Anything, mock.Anything).Return(nil).Once()
+
+	status, _ := do(t, s, http.MethodPost, cancelPath, "", nil)
+
+	assert.Equal(t, http.StatusOK, status)
+	require.Len(t, ch, 1)
+	e := <-ch
+	assert.Equal(t, testPaymentID, e.PaymentID)
+	assert.Equal(t, repository.PaymentCancelled, e.Status)
+}
+
+func TestCancelPayment_DetectedTransfer(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(paymentIn(repository.PaymentDetected), nil)
+
+	status, resp := do(t, s, http.MethodPost, cancelPath, "", nil)
+
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, codePaymentHasTransfer, resp["code"])
+	store.AssertNotCalled(t, "CancelPayment", mock.Anything, mock.Anything)
+}
+
+func TestCancelPayment_NotCancellable(t *testing.T) {
+	for _, from := range []string{
+		repository.PaymentUnderpaid,
+		repository.PaymentConfirmed,
+		repository.PaymentExpired,
+		repository.PaymentReview,
+		repository.PaymentCancelled,
+	} {
+		t.Run(from, func(t *testing.T) {
+			s, store, _ := newTestServer(t)
+			expectClient(store)
+			store.On("GetPayment", mock.Anything, testPaymentID).Return(paymentIn(from), nil)
+
+			status, resp := do(t, s, http.MethodPost, cancelPath, "", nil)
+
+			assert.Equal(t, http.StatusConflict, status)
+			assert.Equal(t, codePaymentNotCancellable, resp["code"])
+			store.AssertNotCalled(t, "CancelPayment", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCancelPayment_LosesRace(t *testing.T) {
+	testCases := []struct {
+		name    string
+		current string
+		code    string
+	}{
+		{"confirmed meanwhile", repository.PaymentConfirmed, codePaymentNotCancellable},
+		{"detected meanwhile", repository.PaymentDetected, codePaymentHasTransfer},
+		{"transfer recorded meanwhile", repository.PaymentPending, codePaymentHasTransfer},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store, _ := newTestServer(t)
+			expectClient(store)
+			// Read by the handler, then again for its version in the
+			// transaction.
+			store.On("GetPayment", mock.Anything, testPaymentID).Return(paymentIn(repository.PaymentPending), nil).Twice()
+			expectCancel(store, repository.PaymentPending, repository.ErrPaymentStatusChanged)
+			store.On("GetPayment", mock.Anything, testPaymentID).Return(paymentIn(tc.current), nil).Once()
+
+			status, resp := do(t, s, http.MethodPost, cancelPath, "", nil)
+
+			assert.Equal(t, http.StatusConflict, status)
+			assert.Equal(t, tc.code, resp["code"])
+			store.AssertNotCalled(t, "CreateLog", mock.Anything, mock.Anything)
+			store.AssertNotCalled(t, "CreateOutboxEvent", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCancelPayment_RetriesVersionConflict(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(paymentIn(repository.PaymentPending), nil).Twice()
+	expectCancel(store, repository.PaymentPending, repository.ErrVersionConflict)
+	bumped := paymentIn(repository.PaymentPending)
+	bumped.Version = 1
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(bumped, nil).Once()
+	store.On("CancelPayment", mock.Anything, repository.CancelPaymentParams{ID: testPaymentID, FromStatus: repository.PaymentPending, Version: 1}).
+		Return(paymentIn(repository.PaymentCancelled), nil).Once()
+	store.On("CreateLog", mock.Anything, mock.Anything).Return(nil).Once()
+	store.On("CreateOutboxEvent", mock.Anything, mock.Anything).Return(nil).Once()
+
+	status, resp := do(t, s, http.MethodPost, cancelPath, "", nil)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, repository.PaymentCancelled, resp["status"])
+}
+
+func TestCancelPayment_OtherClient(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	p := paymentIn(repository.PaymentPending)
+	p.ClientID = uuid.New()
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(p, nil)
+
+	status, resp := do(t, s, http.MethodPost, cancelPath, "", nil)
+
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, codePaymentNotFound, resp["code"])
+}
+
+func TestCancelPayment_TransactionFails(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(paymentIn(repository.PaymentPending), nil)
+	store.txErr = errors.New("connection reset")
+
+	status, resp := do(t, s, http.MethodPost, cancelPath, "", nil)
+
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, apierror.CodeInternal, resp["code"])
+}
+
+func TestCancelPayment_RequiresAPIKey(t *testing.T) {
+	s, _, _ := newTestServer(t)
+
+	status, _ := do(t, s, http.MethodPost, cancelPath, "", http.Header{})
+
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
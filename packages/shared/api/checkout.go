@@ -0,0 +1,170 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+)
+
+// checkoutNotFound is shown for a bad token as for an unknown payment.
+const checkoutNotFound = "This payment link is invalid or has expired. Ask the merchant for a new one."
+
+// qrSize is the width and height of the checkout page's QR code, in pixels.
+const qrSize = 240
+
+//go:embed templates/checkout.html
+var checkoutFS embed.FS
+
+var checkoutTemplates = template.Must(template.ParseFS(checkoutFS, "templates/checkout.html"))
+
+// checkoutView is what the checkout page shows. Like publicPayment, it holds
+// nothing identifying the merchant.
+type checkoutView struct {
+	Title     string
+	Status    string
+	Amount    string
+	Token     string
+	Wallet    string
+	ExpiresAt string
+	// QRCode is a data URL of a PNG encoding the payment's tron: URI.
+	QRCode template.URL
+	// Terminal is set once the status can no longer change; the page then
+	// stops following it.
+	Terminal bool
+	// StatusURL is polled for the status when StreamURL, the status
+	// stream, is not served.
+	StatusURL string
+	StreamURL string
+	// Nonce allows the page's inline script and style.
+	Nonce string
+}
+
+// messageView is a page with nothing but a message, for a payment the
+// checkout cannot show.
+type messageView struct {
+	Title string
+	Text  string
+	Nonce string
+}
+
+// checkoutTitles are the page titles of the statuses that have their own.
+var checkoutTitles = map[string]string{
+	repository.PaymentDetected:  "Transfer detected",
+	repository.PaymentConfirmed: "Payment received",
+	repository.PaymentExpired:   "Payment expired",
+	repository.PaymentCancelled: "Payment cancelled",
+	repository.PaymentReview:    "Payment under review",
+}
+
+// newCheckoutView renders payment for the checkout page. token is the status
+// token the page was opened with, which its status requests reuse.
+func (s *Server) newCheckoutView(payment repository.Payment, token string) (checkoutView, error) {
+	amount := payments.FormatAmount(repository.NumericToDecimal(payment.Amount), payment.Token)
+	v := checkoutView{
+		Title:     checkoutTitles[payment.Status],
+		Status:    payment.Status,
+		Amount:    amount,
+		Token:     payment.Token,
+		Wallet:    payment.UniqueWallet,
+		ExpiresAt: formatTime(payment.ExpiresAt),
+		Terminal:  repository.IsTerminalPaymentStatus(payment.Status),
+	}
+	if v.Title == "" {
+		v.Title = fmt.Sprintf("Pay %s %s", amount, payment.Token)
+	}
+	if v.Terminal {
+		return v, nil
+	}
+
+	png, err := s.paymentQR(payment, qrSize)
+	if err != nil {
+		return checkoutView{}, err
+	}
+	// A data URL the page builds itself, so it is safe to trust.
+	v.QRCode = template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(png))
+
+	query := "?token=" + url.QueryEscape(token)
+	v.StatusURL = "/v1/public/payments/" + payment.ID.String() + query
+	if s.bus != nil {
+		v.StreamURL = "/v1/public/payments/" + payment.ID.String() + "/events" + query
+	}
+	return v, nil
+}
+
+// checkout handles GET /checkout/{id}?token=: a page from which the payer
+// pays, following the payment's status live. It authenticates like
+// getPublicPayment, so a bad token cannot be told from an unknown payment.
+func (s *Server) checkout(w http.ResponseWriter, r *http.Request) {
+	nonce := newNonce()
+	token := r.URL.Query().Get("token")
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil || s.tokens.Verify(id, token, s.now()) != nil {
+		s.checkoutMessage(w, r, http.StatusNotFound, nonce, "Payment not found", checkoutNotFound)
+		return
+	}
+	payment, err := s.publicPayment(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		s.checkoutMessage(w, r, http.StatusNotFound, nonce, "Payment not found", checkoutNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to load payment", "payment_id", id, "error", err)
+		s.checkoutMessage(w, r, http.StatusInternalServerError, nonce, "Something went wrong",
+			"The payment could not be loaded. Try again in a moment.")
+		return
+	}
+
+	v, err := s.newCheckoutView(payment, token)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to render checkout page", "payment_id", id, "error", err)
+		s.checkoutMessage(w, r, http.StatusInternalServerError, nonce, "Something went wrong",
+			"The payment could not be shown. Try again in a moment.")
+		return
+	}
+	v.Nonce = nonce
+	s.writePage(w, r, http.StatusOK, nonce, "checkout", v)
+}
+
+func (s *Server) checkoutMessage(w http.ResponseWriter, r *http.Request, status int, nonce, title, text string) {
+	s.writePage(w, r, status, nonce, "message", messageView{Title: title, Text: text, Nonce: nonce})
+}
+
+// writePage renders the checkout template name with data. The page's URL
+// carries the status token, so it is neither cached nor sent on as a
+// referrer.
+func (s *Server) writePage(w http.ResponseWriter, r *http.Request, status int, nonce, name string, data any) {
+	var buf bytes.Buffer
+	if err := checkoutTemplates.ExecuteTemplate(&buf, name, data); err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to render checkout page", "template", name, "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	h := w.Header()
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("Cache-Control", "no-store")
+	h.Set("Referrer-Policy", "no-referrer")
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Content-Security-Policy", fmt.Sprintf(
+		"default-src 'none'; img-src data:; connect-src 'self'; script-src 'nonce-%[1]s'; style-src 'nonce-%[1]s'; base-uri 'none'; form-action 'none'",
+		nonce))
+	w.WriteHeader(status)
+	_, _ = buf.WriteTo(w)
+}
+
+// newNonce returns a random CSP nonce.
+func newNonce() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/events"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// depositWallet is a valid address, for payment URIs.
+const depositWallet = "TJRabPrwbZy45sbavfcjinPJC18kjpRTv8"
+
+// checkoutPayment is storedPayment in status, carrying the merchant's
+// identifiers the page must not show.
+func checkoutPayment(status string) repository.Payment {
+	p := paymentIn(status)
+	p.UniqueWallet = depositWallet
+	reference := "order-8841"
+	p.OrderReference = &reference
+	p.Metadata = []byte(`{"customer":"cus_secret"}`)
+	return p
+}
+
+func renderCheckout(t *testing.T, v checkoutView) string {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, checkoutTemplates.ExecuteTemplate(&buf, "checkout", v))
+	return buf.String()
+}
+
+func TestCheckoutTemplate_Open(t *testing.T) {
+	s, _, _ := newTestServer(t, WithEventBus(events.NewMemoryBus()))
+	v, err := s.newCheckoutView(checkoutPayment(repository.PaymentPending), "1.sig")
+	require.NoError(t, err)
+	v.Nonce = "n0nce"
+
+	page := renderCheckout(t, v)
+
+	assert.Contains(t, page, "<title>Pay 102.669405 USDT</title>")
+	assert.Contains(t, page, "<code>"+depositWallet+"</code>")
+	assert.Contains(t, page, `data-expires-at="2026-03-01T12:30:00Z"`)
+	assert.Contains(t, page, `data-stream-url="/v1/public/payments/`+testPaymentID.String()+`/events?token=1.sig"`)
+	assert.Contains(t, page, `data-status-url="/v1/public/payments/`+testPaymentID.String()+`?token=1.sig"`)
+	assert.Contains(t, page, `<script nonce="n0nce">`)
+	assert.Contains(t, page, `src="data:image/png;base64,`, "the QR code is not sanitised away")
+	assert.NotContains(t, page, "ZgotmplZ")
+}
+
+func TestCheckoutTemplate_WithoutStream(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	v, err := s.newCheckoutView(checkoutPayment(repository.PaymentPending), "1.sig")
+	require.NoError(t, err)
+
+	page := renderCheckout(t, v)
+
+	assert.NotContains(t, page, "data-stream-url", "the page falls back to polling")
+	assert.Contains(t, page, "data-status-url")
+}
+
+func TestCheckoutTemplate_Terminal(t *testing.T) {
+	s, _, _ := newTestServer(t, WithEventBus(events.NewMemoryBus()))
+	v, err := s.newCheckoutView(checkoutPayment(repository.PaymentConfirmed), "1.sig")
+	require.NoError(t, err)
+
+	page := renderCheckout(t, v)
+
+	assert.Contains(t, page, "<h1>Payment received</h1>")
+	assert.NotContains(t, page, "<script", "a settled payment is not followed")
+	assert.NotContains(t, page, "data-stream-url")
+	assert.NotContains(t, page, "<img", "nothing invites another transfer")
+}
+
+func TestCheckoutTemplate_EscapesValues(t *testing.T) {
+	s, _, _ := newTestServer(t, WithEventBus(events.NewMemoryBus()))
+	v, err := s.newCheckoutView(checkoutPayment(repository.PaymentPending), `"><b>`)
+	require.NoError(t, err)
+	v.Wallet = `T<script>alert(1)</script>`
+
+	page := renderCheckout(t, v)
+
+	assert.NotContains(t, page, "<script>alert(1)")
+	assert.NotContains(t, page, `"><b>`)
+}
+
+func TestNewCheckoutView_QRCode(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	v, err := s.newCheckoutView(checkoutPayment(repository.PaymentUnderpaid), "1.sig")
+	require.NoError(t, err)
+
+	data, ok := strings.CutPrefix(string(v.QRCode), "data:image/png;base64,")
+	require.True(t, ok)
+	raw, err := base64.StdEncoding.DecodeString(data)
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(raw))
+	require.NoError(t, err)
+	assert.Equal(t, qrSize, img.Bounds().Dx())
+}
+
+func TestCheckout(t *testing.T) {
+	testCases := []struct {
+		status string
+		want   []string
+		open   bool
+	}{
+		{repository.PaymentPending, []string{"Send exactly this amount"}, true},
+		{repository.PaymentDetected, []string{"<h1>Transfer detected</h1>", "waiting to be confirmed"}, true},
+		{repository.PaymentUnderpaid, []string{"Send the rest to the same address"}, true},
+		{repository.PaymentConfirmed, []string{"<h1>Payment received</h1>", "102.669405 USDT"}, false},
+		{repository.PaymentExpired, []string{"<h1>Payment expired</h1>", "Do not send funds"}, false},
+		{repository.PaymentCancelled, []string{"<h1>Payment cancelled</h1>"}, false},
+		{repository.PaymentReview, []string{"<h1>Payment under review</h1>"}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.status, func(t *testing.T) {
+			tokens := NewStatusTokens("secret", time.Hour)
+			s, store, _ := newTestServer(t, WithStatusTokens(tokens), WithEventBus(events.NewMemoryBus()))
+			store.On("GetPayment", mock.Anything, testPaymentID).Return(checkoutPayment(tc.status), nil)
+			token := tokens.Issue(testPaymentID, t0.Add(30*time.Minute))
+			rec := httptest.NewRecorder()
+
+			s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/checkout/"+testPaymentID.String()+"?token="+token, nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+			assert.Equal(t, "no-referrer", rec.Header().Get("Referrer-Policy"), "the URL carries the token")
+			assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+			page := rec.Body.String()
+			assert.Contains(t, page, `data-status="`+tc.status+`"`)
+			for _, want := range tc.want {
+				assert.Contains(t, page, want)
+			}
+			assert.Equal(t, tc.open, strings.Contains(page, depositWallet), "the address is only shown while it can be paid")
+			assert.Equal(t, tc.open, strings.Contains(page, "data-stream-url"))
+			for _, secret := range []string{testClient.ID.String(), testAccount.ID.String(), "order-8841", "cus_secret"} {
+				assert.NotContains(t, page, secret, "no merchant identifiers")
+			}
+
+			csp := rec.Header().Get("Content-Security-Policy")
+			assert.Contains(t, csp, "default-src 'none'")
+			if tc.open {
+				_, after, _ := strings.Cut(csp, "script-src 'nonce-")
+				nonce, _, _ := strings.Cut(after, "'")
+				assert.Contains(t, page, `<script nonce="`+nonce+`">`)
+			}
+		})
+	}
+}
+
+func TestCheckout_InvalidToken(t *testing.T) {
+	tokens := NewStatusTokens("secret", time.Hour)
+	valid := tokens.Issue(testPaymentID, t0.Add(30*time.Minute))
+
+	for name, path := range map[string]string{
+		"missing token": "/checkout/" + testPaymentID.String(),
+		"forged token":  "/checkout/" + testPaymentID.String() + "?token=" + NewStatusTokens("guess", time.Hour).Issue(testPaymentID, t0),
+		"malformed id":  "/checkout/pay_1?token=" + valid,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, _, _ := newTestServer(t, WithStatusTokens(tokens))
+			rec := httptest.NewRecorder()
+
+			s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+			assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+			assert.Contains(t, rec.Body.String(), "<h1>Payment not found</h1>")
+		})
+	}
+}
+
+func TestCheckout_UnknownPayment(t *testing.T) {
+	tokens := NewStatusTokens("secret", time.Hour)
+	s, store, _ := newTestServer(t, WithStatusTokens(tokens))
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(repository.Payment{}, pgx.ErrNoRows)
+	rec := httptest.NewRecorder()
+
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet,
+		"/checkout/"+testPaymentID.String()+"?token="+tokens.Issue(testPaymentID, t0.Add(30*time.Minute)), nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), "<h1>Payment not found</h1>")
+}
+
+func TestCheckout_StoreError(t *testing.T) {
+	tokens := NewStatusTokens("secret", time.Hour)
+	s, store, _ := newTestServer(t, WithStatusTokens(tokens))
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(repository.Payment{}, errors.New("connection reset"))
+	rec := httptest.NewRecorder()
+
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet,
+		"/checkout/"+testPaymentID.String()+"?token="+tokens.Issue(testPaymentID, t0.Add(30*time.Minute)), nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "<h1>Something went wrong</h1>")
+	assert.NotContains(t, rec.Body.String(), "connection reset")
+}
+
+func TestCheckout_DisabledWithoutTokens(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	rec := httptest.NewRecorder()
+
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/checkout/"+testPaymentID.String()+"?token=x", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+)
+
+// maxJSONDepth bounds how deeply a request body may nest objects and arrays.
+// Every request the API takes is a flat object but for account metadata.
+const maxJSONDepth = 8
+
+// limitBody refuses requests declaring a body larger than maxBodyBytes and
+// cuts off those that send one without declaring it, so no route reads more.
+func (s *Server) limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > s.maxBodyBytes {
+			writeTooLarge(w, r, s.maxBodyBytes)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxBodyBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// decodeJSON decodes the body of r, a single JSON object, into dst. The
+// body must be valid UTF-8, at most maxBodyBytes long and maxJSONDepth deep,
+// and may only hold fields dst has. Otherwise decodeJSON writes the error
+// response and returns false.
+//
+// Every handler reading a body goes through it.
+func (s *Server) decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBodyBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeTooLarge(w, r, tooLarge.Limit)
+		return false
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, codeInvalidJSON, "failed to read request body"))
+		return false
+	}
+	if !utf8.Valid(body) {
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, codeInvalidJSON, "request body must be valid UTF-8"))
+		return false
+	}
+	if jsonDepth(body) > maxJSONDepth {
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusBadRequest,
+			Code:       codeInvalidJSON,
+			Message:    fmt.Sprintf("request body must not nest deeper than %d levels", maxJSONDepth),
+		})
+		return false
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		apierror.Write(w, r, decodeError(err))
+		return false
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, codeInvalidJSON, "request body must hold a single JSON object"))
+		return false
+	}
+	return true
+}
+
+// decodeError describes why a body did not decode.
+func decodeError(err error) *apierror.Error {
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return &apierror.Error{
+			HTTPStatus: http.StatusBadRequest,
+			Code:       codeInvalidJSON,
+			Message:    "request body has a field of the wrong type",
+			Fields:     map[string]string{typeErr.Field: "must be a " + jsonType(typeErr.Type)},
+		}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no error type for unknown fields.
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &apierror.Error{
+			HTTPStatus: http.StatusBadRequest,
+			Code:       codeInvalidJSON,
+			Message:    "request body has unknown fields",
+			Fields:     map[string]string{field: "is not a known field"},
+		}
+	}
+	return apierror.New(http.StatusBadRequest, codeInvalidJSON, "request body must be a JSON object")
+}
+
+// jsonType names the JSON type values of t decode from.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "object"
+}
+
+// jsonDepth returns how deeply body nests objects and arrays, without
+// decoding it.
+func jsonDepth(body []byte) int {
+	depth, deepest := 0, 0
+	inString, escaped := false, false
+	for _, c := range body {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{' || c == '[':
+			depth++
+			deepest = max(deepest, depth)
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
+	return deepest
+}
+
+func writeTooLarge(w http.ResponseWriter, r *http.Request, limit int64) {
+	apierror.Write(w, r, &apierror.Error{
+		HTTPStatus: http.StatusRequestEntityTooLarge,
+		Code:       codeRequestTooLarge,
+		Message:    fmt.Sprintf("request body must be at most %d bytes", limit),
+	})
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitBody_DeclaredLength(t *testing.T) {
+	s, _, wallets := newTestServer(t)
+	body := `{"account_id":"` + testAccount.ID.String() + `","amount":"5","pad":"` + strings.Repeat("x", 64<<10) + `"}`
+
+	status, resp := do(t, s, http.MethodPost, "/v1/payments", body, nil)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status, "refused before the API key is looked up")
+	assert.Equal(t, codeRequestTooLarge, resp["code"])
+	assert.Equal(t, "request body must be at most 65536 bytes", resp["detail"])
+	assert.Empty(t, wallets.indexes)
+}
+
+func TestLimitBody_UndeclaredLength(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	s.maxBodyBytes = 100
+	expectClient(store)
+
+	// No Content-Length, as with a chunked upload.
+	req := httptest.NewRequest(http.MethodPost, "/v1/accounts", io.MultiReader(strings.NewReader(`{"name":"`+strings.Repeat("x", 200)+`"}`)))
+	req.ContentLength = -1
+	req.Header.Set("Authorization", "Bearer "+testAPIKey)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Contains(t, rec.Body.String(), "request body must be at most 100 bytes")
+}
+
+func TestLimitBody_IdempotentRequest(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	req := httptest.NewRequest(http.MethodPost, "/v1/payments", io.MultiReader(strings.NewReader(strings.Repeat(" ", 65<<10))))
+	req.ContentLength = -1
+	req.Header.Set("Authorization", "Bearer "+testAPIKey)
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	rec := httptest.NewRecorder()
+
+	s.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, "the key is never claimed")
+}
+
+func TestDecodeJSON_Rejects(t *testing.T) {
+	account := testAccount.ID.String()
+	testCases := []struct {
+		name    string
+		body    string
+		message string
+		fields  map[string]any
+	}{
+		{"unknown field", `{"account_id":"` + account + `","amount":"5","amout":"5"}`,
+			"request body has unknown fields", map[string]any{"amout": "is not a known field"}},
+		{"wrong type", `{"account_id":"` + account + `","amount":"5","expires_in":"60"}`,
+			"request body has a field of the wrong type", map[string]any{"expires_in": "must be a number"}},
+		{"invalid UTF-8", "{\"account_id\":\"" + account + "\",\"amount\":\"5\",\"token\":\"US\xffDT\"}",
+			"request body must be valid UTF-8", nil},
+		{"trailing data", `{"account_id":"` + account + `","amount":"5"}{"amount":"6"}`,
+			"request body must hold a single JSON object", nil},
+		{"too deep", `{"account_id":` + strings.Repeat("[", 9) + strings.Repeat("]", 9) + `}`,
+			"request body must not nest deeper than 8 levels", nil},
+		{"not an object", `[1]`, "request body must be a JSON object", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store, wallets := newTestServer(t)
+			expectClient(store)
+
+			status, resp := do(t, s, http.MethodPost, "/v1/payments", tc.body, nil)
+
+			assert.Equal(t, http.StatusBadRequest, status)
+			assert.Equal(t, codeInvalidJSON, resp["code"])
+			assert.Equal(t, tc.message, resp["detail"])
+			if tc.fields != nil {
+				assert.Equal(t, tc.fields, resp["fields"])
+			}
+			assert.Empty(t, wallets.indexes)
+		})
+	}
+}
+
+func TestDecodeJSON_AdminRoutes(t *testing.T) {
+	s, _ := newAdminServer(t)
+
+	status, resp := do(t, s, http.MethodPost, "/admin/clients", `{"name":"Acme","api_key":"sk_mine"}`, adminHeader(""))
+
+	require.Equal(t, http.StatusBadRequest, status, "a client cannot pick its own key")
+	assert.Equal(t, map[string]any{"api_key": "is not a known field"}, resp["fields"])
+}
+
+func TestJSONDepth(t *testing.T) {
+	testCases := map[string]int{
+		``:                         0,
+		`"x"`:                      0,
+		`{}`:                       1,
+		`{"a":[{"b":1}],"c":[]}`:   3,
+		`{"a":"[[[{{{"}`:           1,
+		`{"a":"\"[[["}`:            1,
+		`[[],[[]],[]]`:             3,
+		`{"a":"\\","b":[[["c"]]]}`: 4,
+	}
+	for body, want := range testCases {
+		assert.Equal(t, want, jsonDepth([]byte(body)), body)
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Error codes of the failures particular to an endpoint, returned as the
+// code of its problem response. See package apierror for the shared ones.
+const (
+	codeUnauthorized     = "unauthorized"
+	codeInvalidJSON      = "invalid_json"
+	codeRequestTooLarge  = "request_too_large"
+	codeValidationFailed = "validation_failed"
+	codeAccountNotFound  = "account_not_found"
+	codePaymentNotFound  = "payment_not_found"
+	codeClientNotFound   = "client_not_found"
+	codeTestModeDisabled = "test_mode_disabled"
+
+	codeDerivationHalted = "wallet_derivation_halted"
+
+	codePaymentNotCancellable = "payment_not_cancellable"
+	codePaymentHasTransfer    = "payment_has_transfer"
+	codePaymentNotPending     = "payment_not_pending"
+	codeWalletAttemptsUsed    = "wallet_attempts_exhausted"
+
+	codeAccountHasOpenPayments = "account_has_open_payments"
+	codeTooManyOpenPayments    = "too_many_open_payments"
+	codeSuffixesExhausted      = "amount_suffixes_exhausted"
+	codePaymentWalletShared    = "payment_wallet_shared"
+
+	codeWebhookDeliveryNotFound = "webhook_delivery_not_found"
+	codeWebhookEndpointInactive = "webhook_endpoint_inactive"
+	codeWebhookEndpointNotFound = "webhook_endpoint_not_found"
+
+	codeInvalidIdempotencyKey    = "invalid_idempotency_key"
+	codeIdempotencyKeyReused     = "idempotency_key_reused"
+	codeIdempotencyKeyInProgress = "idempotency_key_in_progress"
+	codeIdempotencyKeyFailed     = "idempotency_key_failed"
+)
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,255 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+)
+
+// exportPageSize is how many payments an export reads per query. The
+// response is flushed after each page, so memory stays bounded however
+// many payments match.
+const exportPageSize = 500
+
+// Export formats.
+const (
+	exportCSV   = "csv"
+	exportJSONL = "jsonl"
+)
+
+// exportStatuses are the statuses an export filters on.
+var exportStatuses = []string{
+	repository.PaymentPending,
+	repository.PaymentDetected,
+	repository.PaymentUnderpaid,
+	repository.PaymentConfirmed,
+	repository.PaymentExpired,
+	repository.PaymentReview,
+	repository.PaymentCancelled,
+}
+
+// exportColumns names the CSV columns, in the order of exportRecord.
+var exportColumns = []string{"id", "account", "amount", "token", "status", "wallet", "created_at", "confirmed_at", "tx_hash"}
+
+// exportRecord is one exported payment. TxHash is its first deposit.
+type exportRecord struct {
+	ID          uuid.UUID `json:"id"`
+	Account     string    `json:"account"`
+	Amount      string    `json:"amount"`
+	Token       string    `json:"token"`
+	Status      string    `json:"status"`
+	Wallet      string    `json:"wallet"`
+	CreatedAt   string    `json:"created_at"`
+	ConfirmedAt *string   `json:"confirmed_at"`
+	TxHash      *string   `json:"tx_hash"`
+}
+
+func newExportRecord(p repository.ListPaymentExportRow) exportRecord {
+	return exportRecord{
+		ID:          p.ID,
+		Account:     p.AccountName,
+		Amount:      payments.FormatAmount(repository.NumericToDecimal(p.Amount), p.Token),
+		Token:       p.Token,
+		Status:      p.Status,
+		Wallet:      p.UniqueWallet,
+		CreatedAt:   formatTime(p.CreatedAt),
+		ConfirmedAt: optionalTime(p.ConfirmedAt),
+		TxHash:      p.TxHash,
+	}
+}
+
+// exportEncoder writes exported payments in one format.
+type exportEncoder interface {
+	begin() error
+	write(exportRecord) error
+	// flush pushes what was written so far to the response.
+	flush() error
+}
+
+type csvExport struct {
+	w  *csv.Writer
+	rc *http.ResponseController
+}
+
+func (e *csvExport) begin() error {
+	return e.w.Write(exportColumns)
+}
+
+func (e *csvExport) write(rec exportRecord) error {
+	return e.w.Write([]string{
+		rec.ID.String(), rec.Account, rec.Amount, rec.Token, rec.Status, rec.Wallet,
+		rec.CreatedAt, deref(rec.ConfirmedAt), deref(rec.TxHash),
+	})
+}
+
+func (e *csvExport) flush() error {
+	e.w.Flush()
+	if err := e.w.Error(); err != nil {
+		return err
+	}
+	return e.rc.Flush()
+}
+
+type jsonlExport struct {
+	enc *json.Encoder
+	rc  *http.ResponseController
+}
+
+func (e *jsonlExport) begin() error { return nil }
+
+func (e *jsonlExport) write(rec exportRecord) error {
+	return e.enc.Encode(rec)
+}
+
+func (e *jsonlExport) flush() error {
+	return e.rc.Flush()
+}
+
+// exportPayments handles GET /v1/exports/payments?from=&to=&status=&format=,
+// streaming the client's payments created in [from, to), oldest first, as
+// CSV or JSON lines. from and to are RFC 3339 times or dates, taken as
+// midnight UTC.
+//
+// Payments are read a page at a time. Once the first page is sent the status
+// can no longer change, so a later failure aborts the response rather than
+// leave a truncated export looking complete.
+func (s *Server) exportPayments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	client := clientFrom(ctx)
+
+	q := r.URL.Query()
+	fields := map[string]string{}
+	from, err := parseExportTime(q.Get("from"))
+	if err != nil {
+		fields["from"] = err.Error()
+	}
+	to, err := parseExportTime(q.Get("to"))
+	if err != nil {
+		fields["to"] = err.Error()
+	}
+	if len(fields) == 0 && !to.After(from) {
+		fields["to"] = "must be after from"
+	}
+	var status *string
+	if v := q.Get("status"); v != "" {
+		if !slices.Contains(exportStatuses, v) {
+			fields["status"] = fmt.Sprintf("must be one of %v", exportStatuses)
+		}
+		status = &v
+	}
+	format := q.Get("format")
+	if format == "" {
+		format = exportCSV
+	}
+	if format != exportCSV && format != exportJSONL {
+		fields["format"] = "must be csv or jsonl"
+	}
+	if len(fields) > 0 {
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusBadRequest,
+			Code:       codeValidationFailed,
+			Message:    "request has invalid query parameters",
+			Fields:     fields,
+		})
+		return
+	}
+
+	tenant := s.tenant(ctx)
+	arg := repository.ListPaymentExportParams{
+		CreatedFrom:    pgtype.Timestamptz{Time: from, Valid: true},
+		CreatedTo:      pgtype.Timestamptz{Time: to, Valid: true},
+		Status:         status,
+		AfterCreatedAt: pgtype.Timestamptz{Time: from, Valid: true},
+		Limit:          exportPageSize,
+	}
+	rc := http.NewResponseController(w)
+	var enc exportEncoder
+	exported := 0
+	for {
+		page, err := tenant.ListPaymentExport(ctx, arg)
+		if err != nil && enc == nil {
+			s.internalError(w, r, "failed to list payments to export", err)
+			return
+		}
+		if err != nil {
+			s.abortExport(r, exported, err)
+		}
+		if enc == nil {
+			enc = s.beginExport(w, rc, format, from, to)
+			if err := enc.begin(); err != nil {
+				s.abortExport(r, exported, err)
+			}
+		}
+		// The server's write timeout would cut a large export short.
+		_ = rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+		for _, p := range page {
+			if err := enc.write(newExportRecord(p)); err != nil {
+				s.abortExport(r, exported, err)
+			}
+		}
+		if err := enc.flush(); err != nil {
+			s.abortExport(r, exported, err)
+		}
+		exported += len(page)
+		if len(page) < exportPageSize {
+			break
+		}
+		last := page[len(page)-1]
+		arg.AfterCreatedAt, arg.AfterID = last.CreatedAt, last.ID
+	}
+	s.logger.InfoContext(ctx, "payments exported", "client_id", client.ID, "format", format, "payments", exported)
+}
+
+// beginExport sends the headers of an export and returns its encoder.
+func (s *Server) beginExport(w http.ResponseWriter, rc *http.ResponseController, format string, from, to time.Time) exportEncoder {
+	name := fmt.Sprintf("payments-%s-%s.%s", from.Format("20060102"), to.Format("20060102"), format)
+	h := w.Header()
+	h.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	h.Set("Cache-Control", "no-store")
+	h.Set("X-Accel-Buffering", "no")
+	if format == exportJSONL {
+		h.Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		return &jsonlExport{enc: json.NewEncoder(w), rc: rc}
+	}
+	h.Set("Content-Type", "text/csv; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	return &csvExport{w: csv.NewWriter(w), rc: rc}
+}
+
+// abortExport ends an export that has already started sending.
+func (s *Server) abortExport(r *http.Request, exported int, err error) {
+	s.logger.ErrorContext(r.Context(), "payment export failed", "payments", exported, "error", err)
+	panic(http.ErrAbortHandler)
+}
+
+// parseExportTime parses an RFC 3339 time, or a date as midnight UTC.
+func parseExportTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("is required")
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.New("must be an RFC 3339 time or a YYYY-MM-DD date")
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+var exportFrom = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// exportRow is the i-th payment of a month of test payments, one a minute.
+func exportRow(i int) repository.ListPaymentExportRow {
+	return repository.ListPaymentExportRow{
+		ID:           uuid.NewSHA1(uuid.Nil, []byte(fmt.Sprint(i))),
+		AccountName:  "main",
+		Amount:       pgtype.Numeric{Int: big.NewInt(int64(1000 + i)), Exp: -2, Valid: true},
+		Token:        "USDT",
+		Status:       repository.PaymentPending,
+		UniqueWallet: fmt.Sprintf("TWallet%d", i),
+		CreatedAt:    pgtype.Timestamptz{Time: exportFrom.Add(time.Duration(i) * time.Minute), Valid: true},
+	}
+}
+
+// expectExportPages serves n payments a page at a time, resuming after the
+// key each query passes, and returns the queries made.
+func expectExportPages(store *mockStore, n int) *[]repository.ListPaymentExportParams {
+	var queries []repository.ListPaymentExportParams
+	index := make(map[uuid.UUID]int, n)
+	for i := range n {
+		index[exportRow(i).ID] = i
+	}
+	call := store.On("ListPaymentExport", mock.Anything, mock.Anything)
+	call.Run(func(args mock.Arguments) {
+		arg := args.Get(1).(repository.ListPaymentExportParams)
+		queries = append(queries, arg)
+		start := 0
+		if arg.AfterID != uuid.Nil {
+			start = index[arg.AfterID] + 1
+		}
+		var page []repository.ListPaymentExportRow
+		for i := start; i < n && len(page) < int(arg.Limit); i++ {
+			page = append(page, exportRow(i))
+		}
+		call.ReturnArguments = mock.Arguments{page, nil}
+	})
+	return &queries
+}
+
+func exportRequest(query string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/v1/exports/payments?"+query, nil)
+	req.Header.Set("Authorization", "Bearer "+testAPIKey)
+	return req
+}
+
+func TestExportPayments_CSV(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	confirmed := pgtype.Timestamptz{Time: exportFrom.Add(time.Hour), Valid: true}
+	txHash := "f00d"
+	rows := []repository.ListPaymentExportRow{exportRow(0), exportRow(1), exportRow(2)}
+	rows[0].AccountName = "Acme, Inc."
+	rows[1].AccountName = `The "Best" Shop`
+	rows[1].Status, rows[1].ConfirmedAt, rows[1].TxHash = repository.PaymentConfirmed, confirmed, &txHash
+	rows[2].AccountName = "line\nbreak"
+	store.On("ListPaymentExport", mock.Anything, repository.ListPaymentExportParams{
+		ClientID:       testClient.ID,
+		CreatedFrom:    pgtype.Timestamptz{Time: exportFrom, Valid: true},
+		CreatedTo:      pgtype.Timestamptz{Time: exportFrom.AddDate(0, 1, 0), Valid: true},
+		AfterCreatedAt: pgtype.Timestamptz{Time: exportFrom, Valid: true},
+		Limit:          exportPageSize,
+	}).Return(rows, nil).Once()
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, exportRequest("from=2026-01-01&to=2026-02-01"))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="payments-20260101-20260201.csv"`, rec.Header().Get("Content-Disposition"))
+	body := rec.Body.String()
+	assert.Contains(t, body, `,"Acme, Inc.",`)
+	assert.Contains(t, body, `,"The ""Best"" Shop",`)
+	assert.Contains(t, body, ",\"line\nbreak\",")
+
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, exportColumns, records[0])
+	assert.Equal(t, []string{
+		rows[0].ID.String(), "Acme, Inc.", "10.000000", "USDT", "PENDING", "TWallet0", "2026-01-01T00:00:00Z", "", "",
+	}, records[1])
+	assert.Equal(t, []string{
+		rows[1].ID.String(), `The "Best" Shop`, "10.010000", "USDT", "CONFIRMED", "TWallet1", "2026-01-01T00:01:00Z", "2026-01-01T01:00:00Z", "f00d",
+	}, records[2])
+	assert.Equal(t, "line\nbreak", records[3][1])
+}
+
+func TestExportPayments_StreamsInPages(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	const n = 10_250
+	queries := expectExportPages(store, n)
+
+	rec := newFlushRecorder()
+	s.ServeHTTP(rec, exportRequest("from=2026-01-01T00:00:00Z&to=2026-02-01T00:00:00Z&status=PENDING"))
+
+	require.Equal(t, http.StatusOK, rec.status)
+	require.Len(t, *queries, 21, "20 full pages and a short last one")
+	for i, q := range *queries {
+		assert.EqualValues(t, exportPageSize, q.Limit)
+		require.NotNil(t, q.Status)
+		assert.Equal(t, repository.PaymentPending, *q.Status)
+		if i > 0 {
+			last := exportRow(i*exportPageSize - 1)
+			assert.Equal(t, last.ID, q.AfterID, "page %d resumes after the last row of the page before", i)
+			assert.Equal(t, last.CreatedAt, q.AfterCreatedAt)
+		}
+	}
+
+	chunks := rec.chunks()
+	assert.Len(t, chunks, 21, "every page is flushed as it is read")
+	for _, c := range chunks[:20] {
+		assert.LessOrEqual(t, strings.Count(c, "\n"), exportPageSize+1)
+	}
+	records, err := csv.NewReader(strings.NewReader(strings.Join(chunks, ""))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, n+1)
+	for i, r := range records[1:] {
+		if r[0] != exportRow(i).ID.String() {
+			t.Fatalf("row %d is %s, want payment %d", i, r[0], i)
+		}
+	}
+}
+
+func TestExportPayments_ExactPages(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	queries := expectExportPages(store, 2*exportPageSize)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, exportRequest("from=2026-01-01&to=2026-02-01"))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, *queries, 3, "a full last page needs one more query to know it was the last")
+	assert.Equal(t, 2*exportPageSize+1, strings.Count(rec.Body.String(), "\n"))
+}
+
+func TestExportPayments_JSONL(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	row := exportRow(0)
+	row.AccountName = `Acme, "Inc."`
+	store.On("ListPaymentExport", mock.Anything, mock.Anything).Return([]repository.ListPaymentExportRow{row}, nil).Once()
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, exportRequest("from=2026-01-01&to=2026-02-01&format=jsonl"))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="payments-20260101-20260201.jsonl"`, rec.Header().Get("Content-Disposition"))
+	sc := bufio.NewScanner(rec.Body)
+	require.True(t, sc.Scan())
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(sc.Bytes(), &got))
+	assert.Equal(t, map[string]any{
+		"id":           row.ID.String(),
+		"account":      `Acme, "Inc."`,
+		"amount":       "10.000000",
+		"token":        "USDT",
+		"status":       "PENDING",
+		"wallet":       "TWallet0",
+		"created_at":   "2026-01-01T00:00:00Z",
+		"confirmed_at": nil,
+		"tx_hash":      nil,
+	}, got)
+	assert.False(t, sc.Scan(), "one line per payment")
+}
+
+func TestExportPayments_Empty(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("ListPaymentExport", mock.Anything, mock.Anything).Return([]repository.ListPaymentExportRow(nil), nil).Once()
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, exportRequest("from=2026-01-01&to=2026-02-01"))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, strings.Join(exportColumns, ",")+"\n", rec.Body.String())
+}
+
+func TestExportPayments_Validation(t *testing.T) {
+	testCases := []struct {
+		name   string
+		query  string
+		fields map[string]any
+	}{
+		{"missing range", "", map[string]any{"from": "is required", "to": "is required"}},
+		{"bad time", "from=yesterday&to=2026-02-01", map[string]any{"from": "must be an RFC 3339 time or a YYYY-MM-DD date"}},
+		{"empty range", "from=2026-02-01&to=2026-02-01", map[string]any{"to": "must be after from"}},
+		{"unknown status", "from=2026-01-01&to=2026-02-01&status=PAID", map[string]any{
+			"status": "must be one of [PENDING DETECTED UNDERPAID CONFIRMED EXPIRED REVIEW CANCELLED]",
+		}},
+		{"unknown format", "from=2026-01-01&to=2026-02-01&format=xlsx", map[string]any{"format": "must be csv or jsonl"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store, _ := newTestServer(t)
+			expectClient(store)
+
+			code, resp := do(t, s, http.MethodGet, "/v1/exports/payments?"+tc.query, "", nil)
+
+			assert.Equal(t, http.StatusBadRequest, code)
+			assert.Equal(t, codeValidationFailed, resp["code"])
+			assert.Equal(t, tc.fields, resp["fields"])
+		})
+	}
+}
+
+func TestExportPayments_Unauthenticated(t *testing.T) {
+	s, _, _ := newTestServer(t)
+
+	code, _ := do(t, s, http.MethodGet, "/v1/exports/payments?from=2026-01-01&to=2026-02-01", "", http.Header{})
+
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestExportPayments_FailsBeforeStreaming(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("ListPaymentExport", mock.Anything, mock.Anything).Return(nil, errors.New("connection refused")).Once()
+
+	code, resp := do(t, s, http.MethodGet, "/v1/exports/payments?from=2026-01-01&to=2026-02-01", "", nil)
+
+	assert.Equal(t, http.StatusInternalServerError, code)
+	assert.Equal(t, apierror.CodeInternal, resp["code"])
+}
+
+func TestExportPayments_AbortsMidStream(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	page := make([]repository.ListPaymentExportRow, exportPageSize)
+	for i := range page {
+		page[i] = exportRow(i)
+	}
+	store.On("ListPaymentExport", mock.Anything, mock.MatchedBy(func(arg repository.ListPaymentExportParams) bool {
+		return arg.AfterID == uuid.Nil
+	})).Return(page, nil).Once()
+	store.On("ListPaymentExport", mock.Anything, mock.Anything).Return(nil, context.DeadlineExceeded).Once()
+
+	rec := newFlushRecorder()
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		s.ServeHTTP(rec, exportRequest("from=2026-01-01&to=2026-02-01"))
+	}, "the connection is cut, so the client cannot mistake the export for complete")
+	assert.Equal(t, http.StatusOK, rec.status)
+	require.Len(t, rec.chunks(), 1)
+}
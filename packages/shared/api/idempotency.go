@@ -0,0 +1,328 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+const (
+	// IdempotencyKeyHeader names the header a client sets to make a POST
+	// safe to retry.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// ReplayedHeader is set on responses replayed from an earlier request.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// inProgressRetryAfter is the Retry-After, in seconds, sent while
+	// another request holds the key.
+	inProgressRetryAfter = 1
+	// claimAttempts bounds how often a key released by a failed request
+	// between our claim and lookup is claimed again.
+	claimAttempts = 3
+	// staleClaimAfter is how long a claim may go unrenewed before a retry of
+	// the same request claims the key again. A running request renews its
+	// claim every claimRenewInterval, so a stale claim was left by a process
+	// that died before it could settle the key.
+	staleClaimAfter    = 2 * time.Minute
+	claimRenewInterval = staleClaimAfter / 4
+	// keyWriteAttempts bounds how often completing or failing a key is
+	// tried, keyWriteRetryDelay apart.
+	keyWriteAttempts   = 3
+	keyWriteRetryDelay = 100 * time.Millisecond
+
+	keyStatusCompleted = "COMPLETED"
+	keyStatusFailed    = "FAILED"
+)
+
+// idempotent makes POSTs carrying an Idempotency-Key header safe to retry.
+// The first request claims the key for the client and runs next; a
+// successful response is stored with the key as it is completed. A retry
+// with the same method, path and body gets that response back, a reuse of
+// the key for a different request gets a 409, and so does a retry while the
+// first request is still running. A failed request releases the key. A
+// successful one whose response cannot be stored marks the key FAILED, which
+// answers every retry with a 409. A key left held by a process that died is
+// claimed again by a retry once its claim is stale.
+//
+// It must run after authenticate, since keys are scoped to the client.
+func (s *Server) idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			apierror.Write(w, r, &apierror.Error{
+				HTTPStatus: http.StatusBadRequest,
+				Code:       codeInvalidIdempotencyKey,
+				Message:    "Idempotency-Key must be at most " + strconv.Itoa(maxIdempotencyKeyLength) + " characters",
+			})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBodyBytes))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeTooLarge(w, r, tooLarge.Limit)
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, codeInvalidJSON, "failed to read request body"))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := r.Context()
+		client := clientFrom(ctx)
+		hash := requestHash(r, body)
+		for range claimAttempts {
+			_, err := s.store.ClaimIdempotencyKey(ctx, repository.ClaimIdempotencyKeyParams{
+				ClientID:       client.ID,
+				IdempotencyKey: key,
+				RequestHash:    hash,
+				StaleAfter:     pgtype.Interval{Microseconds: staleClaimAfter.Microseconds(), Valid: true},
+			})
+			if err == nil {
+				s.runIdempotent(w, r, key, next)
+				return
+			}
+			if !errors.Is(err, pgx.ErrNoRows) {
+				s.internalError(w, r, "failed to claim idempotency key", err, "client_id", client.ID)
+				return
+			}
+
+			held, err := s.store.GetIdempotencyKey(ctx, repository.GetIdempotencyKeyParams{
+				ClientID:       client.ID,
+				IdempotencyKey: key,
+			})
+			if errors.Is(err, pgx.ErrNoRows) {
+				// Released by a failed request since the claim; try again.
+				continue
+			}
+			if err != nil {
+				s.internalError(w, r, "failed to load idempotency key", err, "client_id", client.ID)
+				return
+			}
+			replay(w, r, held, hash)
+			return
+		}
+		writeInProgress(w, r)
+	})
+}
+
+// runIdempotent runs next for the request that claimed key, renewing the
+// claim meanwhile, and settles the key with its outcome. The key is settled
+// even when the client has gone away meanwhile, since its retry depends on
+// it.
+func (s *Server) runIdempotent(w http.ResponseWriter, r *http.Request, key string, next http.Handler) {
+	ctx := context.WithoutCancel(r.Context())
+	client := clientFrom(ctx)
+	rec := &bufferedResponse{header: make(http.Header), status: http.StatusOK}
+	settled := false
+	defer func() {
+		if settled {
+			return
+		}
+		// A panicking handler must not leave the key held forever.
+		s.release(r, key)
+	}()
+	stopRenewing := s.renewClaim(ctx, key)
+	defer stopRenewing()
+	next.ServeHTTP(rec, r)
+	stopRenewing()
+
+	if rec.status >= 200 && rec.status < 300 {
+		status := int32(rec.status)
+		headers, err := json.Marshal(rec.header)
+		if err == nil {
+			err = retryKeyWrite(func() error {
+				return s.store.CompleteIdempotencyKey(ctx, repository.CompleteIdempotencyKeyParams{
+					ClientID:        client.ID,
+					IdempotencyKey:  key,
+					ResponseStatus:  &status,
+					ResponseBody:    rec.body.Bytes(),
+					ResponseHeaders: headers,
+				})
+			})
+		}
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to complete idempotency key", "client_id", client.ID, "error", err)
+			s.fail(ctx, key)
+		}
+	} else {
+		s.release(r, key)
+	}
+	settled = true
+	rec.flush(w)
+}
+
+// renewClaim renews the claim on key every claimRenewInterval until the
+// returned function is called, so a request running for longer than
+// staleClaimAfter is not taken for one whose process died. Calling the
+// function again does nothing.
+func (s *Server) renewClaim(ctx context.Context, key string) func() {
+	client := clientFrom(ctx)
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(claimRenewInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := s.store.RenewIdempotencyKey(ctx, repository.RenewIdempotencyKeyParams{
+					ClientID:       client.ID,
+					IdempotencyKey: key,
+				})
+				if err != nil {
+					s.logger.WarnContext(ctx, "failed to renew idempotency key", "client_id", client.ID, "error", err)
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}
+}
+
+// fail marks key FAILED after its request succeeded but its response could
+// not be stored. The payment exists, so no retry may run the request again.
+func (s *Server) fail(ctx context.Context, key string) {
+	client := clientFrom(ctx)
+	err := retryKeyWrite(func() error {
+		return s.store.FailIdempotencyKey(ctx, repository.FailIdempotencyKeyParams{
+			ClientID:       client.ID,
+			IdempotencyKey: key,
+		})
+	})
+	if err != nil {
+		// Left IN_PROGRESS, the key is claimed again once stale.
+		s.logger.ErrorContext(ctx, "failed to mark idempotency key failed", "client_id", client.ID, "error", err)
+	}
+}
+
+// retryKeyWrite runs write until it succeeds, at most keyWriteAttempts times.
+func retryKeyWrite(write func() error) error {
+	var err error
+	for attempt := range keyWriteAttempts {
+		if attempt > 0 {
+			time.Sleep(keyWriteRetryDelay)
+		}
+		if err = write(); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (s *Server) release(r *http.Request, key string) {
+	ctx := context.WithoutCancel(r.Context())
+	client := clientFrom(ctx)
+	err := s.store.ReleaseIdempotencyKey(ctx, repository.ReleaseIdempotencyKeyParams{
+		ClientID:       client.ID,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to release idempotency key", "client_id", client.ID, "error", err)
+	}
+}
+
+// replay answers a request whose key is already held.
+func replay(w http.ResponseWriter, r *http.Request, held repository.IdempotencyKey, hash string) {
+	switch {
+	case held.RequestHash != hash:
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusConflict,
+			Code:       codeIdempotencyKeyReused,
+			Message:    "Idempotency-Key was already used for a different request",
+		})
+	case held.Status == keyStatusFailed:
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusConflict,
+			Code:       codeIdempotencyKeyFailed,
+			Message:    "the request with this Idempotency-Key succeeded but its response was not stored; look up its result instead of retrying",
+		})
+	case held.Status != keyStatusCompleted || held.ResponseStatus == nil:
+		writeInProgress(w, r)
+	default:
+		// Keys completed before headers were stored have none.
+		var headers http.Header
+		if len(held.ResponseHeaders) > 0 && json.Unmarshal(held.ResponseHeaders, &headers) == nil {
+			for k, v := range headers {
+				w.Header()[k] = v
+			}
+		}
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.Header().Set(ReplayedHeader, "true")
+		w.WriteHeader(int(*held.ResponseStatus))
+		_, _ = w.Write(held.ResponseBody)
+	}
+}
+
+func writeInProgress(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", strconv.Itoa(inProgressRetryAfter))
+	apierror.Write(w, r, &apierror.Error{
+		HTTPStatus: http.StatusConflict,
+		Code:       codeIdempotencyKeyInProgress,
+		Message:    "a request with this Idempotency-Key is still in progress; retry after it completes",
+	})
+}
+
+// requestHash identifies a request by method, path and body.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// bufferedResponse holds a handler's response until its idempotency key is
+// settled.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+	wrote  bool
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if !b.wrote {
+		b.status, b.wrote = status, true
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) flush(w http.ResponseWriter) {
+	for k, v := range b.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(b.status)
+	_, _ = w.Write(b.body.Bytes())
+}
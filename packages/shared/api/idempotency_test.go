@@ -0,0 +1,489 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// memKeys keeps idempotency keys in memory with the semantics of the
+// queries: a claim only succeeds for an absent key or a stale claim of the
+// same request. Like the database, it fails calls on a cancelled context.
+type memKeys struct {
+	mu          sync.Mutex
+	keys        map[string]repository.IdempotencyKey
+	completeErr error
+	// failCompletes fails that many completions before they succeed.
+	failCompletes int
+	completes     int
+}
+
+func newMemKeys() *memKeys {
+	return &memKeys{keys: make(map[string]repository.IdempotencyKey)}
+}
+
+func (m *memKeys) ClaimIdempotencyKey(_ context.Context, arg repository.ClaimIdempotencyKeyParams) (repository.IdempotencyKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := arg.ClientID.String() + "/" + arg.IdempotencyKey
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	if k, ok := m.keys[id]; ok {
+		staleBefore := now.Time.Add(-time.Duration(arg.StaleAfter.Microseconds) * time.Microsecond)
+		stale := k.Status == "IN_PROGRESS" && k.RequestHash == arg.RequestHash && k.ClaimedAt.Time.Before(staleBefore)
+		if !stale {
+			return repository.IdempotencyKey{}, pgx.ErrNoRows
+		}
+		k.ClaimedAt = now
+		m.keys[id] = k
+		return k, nil
+	}
+	k := repository.IdempotencyKey{
+		ClientID:       arg.ClientID,
+		IdempotencyKey: arg.IdempotencyKey,
+		RequestHash:    arg.RequestHash,
+		Status:         "IN_PROGRESS",
+		ClaimedAt:      now,
+	}
+	m.keys[id] = k
+	return k, nil
+}
+
+func (m *memKeys) GetIdempotencyKey(_ context.Context, arg repository.GetIdempotencyKeyParams) (repository.IdempotencyKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, ok := m.keys[arg.ClientID.String()+"/"+arg.IdempotencyKey]
+	if !ok {
+		return repository.IdempotencyKey{}, pgx.ErrNoRows
+	}
+	return k, nil
+}
+
+func (m *memKeys) CompleteIdempotencyKey(ctx context.Context, arg repository.CompleteIdempotencyKeyParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.completes++
+	if m.completeErr != nil {
+		return m.completeErr
+	}
+	if m.completes <= m.failCompletes {
+		return errors.New("connection reset")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	id := arg.ClientID.String() + "/" + arg.IdempotencyKey
+	k := m.keys[id]
+	if k.Status == "IN_PROGRESS" {
+		k.Status, k.ResponseStatus, k.ResponseBody = "COMPLETED", arg.ResponseStatus, arg.ResponseBody
+		k.ResponseHeaders = arg.ResponseHeaders
+		m.keys[id] = k
+	}
+	return nil
+}
+
+func (m *memKeys) FailIdempotencyKey(ctx context.Context, arg repository.FailIdempotencyKeyParams) error {
+	return m.update(ctx, arg.ClientID.String()+"/"+arg.IdempotencyKey, func(k *repository.IdempotencyKey) { k.Status = "FAILED" })
+}
+
+func (m *memKeys) RenewIdempotencyKey(ctx context.Context, arg repository.RenewIdempotencyKeyParams) error {
+	return m.update(ctx, arg.ClientID.String()+"/"+arg.IdempotencyKey, func(k *repository.IdempotencyKey) {
+		k.ClaimedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	})
+}
+
+// update applies set to the key with id if it is IN_PROGRESS.
+func (m *memKeys) update(ctx context.Context, id string, set func(*repository.IdempotencyKey)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if k, ok := m.keys[id]; ok && k.Status == "IN_PROGRESS" {
+		set(&k)
+		m.keys[id] = k
+	}
+	return nil
+}
+
+func (m *memKeys) ReleaseIdempotencyKey(ctx context.Context, arg repository.ReleaseIdempotencyKeyParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	id := arg.ClientID.String() + "/" + arg.IdempotencyKey
+	if m.keys[id].Status == "IN_PROGRESS" {
+		delete(m.keys, id)
+	}
+	return nil
+}
+
+// keyedStore serves idempotency keys from memory and the rest from the mock.
+type keyedStore struct {
+	*mockStore
+	*memKeys
+}
+
+// newKeyedServer wraps handler in the idempotency middleware, with requests
+// already authenticated as testClient.
+func newKeyedServer(t *testing.T, handler http.HandlerFunc) (http.Handler, *memKeys) {
+	t.Helper()
+	s, store, _ := newTestServer(t)
+	keys := newMemKeys()
+	s.store = keyedStore{mockStore: store, memKeys: keys}
+	h := s.idempotent(handler)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientKey{}, testClient)))
+	}), keys
+}
+
+func post(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/payments", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// counting answers 201 with a body numbering its calls.
+func counting(calls *atomic.Int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		n := calls.Add(1)
+		writeJSON(w, http.StatusCreated, map[string]int32{"call": n})
+	}
+}
+
+func TestIdempotent_ReplaysCompletedRequest(t *testing.T) {
+	var calls atomic.Int32
+	h, _ := newKeyedServer(t, counting(&calls))
+
+	first := post(h, "order-1", `{"amount":"5"}`)
+	second := post(h, "order-1", `{"amount":"5"}`)
+
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.JSONEq(t, `{"call":1}`, second.Body.String())
+	assert.Equal(t, "true", second.Header().Get(ReplayedHeader))
+	assert.Empty(t, first.Header().Get(ReplayedHeader))
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestIdempotent_ReplaysResponseHeaders(t *testing.T) {
+	var calls atomic.Int32
+	h, _ := newKeyedServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/v1/payments/p1")
+		counting(&calls)(w, r)
+	})
+
+	first := post(h, "order-1", `{"amount":"5"}`)
+	second := post(h, "order-1", `{"amount":"5"}`)
+
+	assert.Equal(t, "/v1/payments/p1", first.Header().Get("Location"))
+	assert.Equal(t, "/v1/payments/p1", second.Header().Get("Location"))
+	assert.Equal(t, "application/json", second.Header().Get("Content-Type"))
+	assert.Equal(t, "true", second.Header().Get(ReplayedHeader))
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestIdempotent_ReplaysKeyWithoutStoredHeaders(t *testing.T) {
+	h, keys := newKeyedServer(t, nil)
+	status := int32(http.StatusCreated)
+	hash := requestHash(httptest.NewRequest(http.MethodPost, "/v1/payments", nil), []byte(`{}`))
+	keys.keys[testClient.ID.String()+"/order-1"] = repository.IdempotencyKey{
+		ClientID:       testClient.ID,
+		IdempotencyKey: "order-1",
+		RequestHash:    hash,
+		Status:         "COMPLETED",
+		ResponseStatus: &status,
+		ResponseBody:   []byte(`{"call":1}`),
+	}
+
+	rec := post(h, "order-1", `{}`)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"call":1}`, rec.Body.String())
+}
+
+func TestIdempotent_WithoutKey(t *testing.T) {
+	var calls atomic.Int32
+	h, keys := newKeyedServer(t, counting(&calls))
+
+	post(h, "", `{}`)
+	post(h, "", `{}`)
+
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Empty(t, keys.keys)
+}
+
+func TestIdempotent_KeysAreScopedToRequestBody(t *testing.T) {
+	var calls atomic.Int32
+	h, _ := newKeyedServer(t, counting(&calls))
+	post(h, "order-1", `{"amount":"5"}`)
+
+	rec := post(h, "order-1", `{"amount":"6"}`)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), codeIdempotencyKeyReused)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestIdempotent_HandlerSeesBody(t *testing.T) {
+	var got []byte
+	h, _ := newKeyedServer(t, func(w http.ResponseWriter, r *http.Request) {
+		got, _ = io.ReadAll(r.Body)
+		writeJSON(w, http.StatusCreated, map[string]string{})
+	})
+
+	post(h, "order-1", `{"amount":"5"}`)
+
+	assert.Equal(t, `{"amount":"5"}`, string(got))
+}
+
+func TestIdempotent_FailedRequestReleasesKey(t *testing.T) {
+	fail := true
+	h, keys := newKeyedServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, codeValidationFailed, "invalid payment"))
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]string{"id": "p1"})
+	})
+
+	rec := post(h, "order-1", `{"amount":"5"}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Empty(t, keys.keys, "a failed request does not hold the key")
+
+	fail = false
+	rec = post(h, "order-1", `{"amount":"5"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Empty(t, rec.Header().Get(ReplayedHeader))
+}
+
+func TestIdempotent_PanicReleasesKey(t *testing.T) {
+	h, keys := newKeyedServer(t, func(http.ResponseWriter, *http.Request) { panic("boom") })
+
+	assert.Panics(t, func() { post(h, "order-1", `{}`) })
+	assert.Empty(t, keys.keys)
+}
+
+func TestIdempotent_CompletionRetried(t *testing.T) {
+	var calls atomic.Int32
+	h, keys := newKeyedServer(t, counting(&calls))
+	keys.failCompletes = 1
+
+	first := post(h, "order-1", `{}`)
+	second := post(h, "order-1", `{}`)
+
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, 2, keys.completes)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, "true", second.Header().Get(ReplayedHeader))
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestIdempotent_CompletionFailureFailsKey(t *testing.T) {
+	var calls atomic.Int32
+	h, keys := newKeyedServer(t, counting(&calls))
+	keys.completeErr = errors.New("connection reset")
+	id := testClient.ID.String() + "/order-1"
+
+	first := post(h, "order-1", `{}`)
+	second := post(h, "order-1", `{}`)
+
+	assert.Equal(t, http.StatusCreated, first.Code, "the payment was created, so the client still gets it")
+	assert.Equal(t, keyWriteAttempts, keys.completes)
+	assert.Equal(t, "FAILED", keys.keys[id].Status)
+	assert.Equal(t, http.StatusConflict, second.Code, "a retry must not create a second payment")
+	assert.Contains(t, second.Body.String(), codeIdempotencyKeyFailed)
+
+	// Long after, the key is still not claimed again.
+	k := keys.keys[id]
+	k.ClaimedAt = pgtype.Timestamptz{Time: time.Now().Add(-staleClaimAfter - time.Second), Valid: true}
+	keys.keys[id] = k
+	third := post(h, "order-1", `{}`)
+	assert.Equal(t, http.StatusConflict, third.Code)
+	assert.Contains(t, third.Body.String(), codeIdempotencyKeyFailed)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestIdempotent_SettlesKeyAfterClientGoesAway(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		held   bool
+	}{
+		{"completed", http.StatusCreated, true},
+		{"released", http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			h, keys := newKeyedServer(t, func(w http.ResponseWriter, _ *http.Request) {
+				cancel()
+				writeJSON(w, tt.status, map[string]string{})
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/payments", strings.NewReader(`{}`)).WithContext(ctx)
+			req.Header.Set(IdempotencyKeyHeader, "order-1")
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			k, ok := keys.keys[testClient.ID.String()+"/order-1"]
+			assert.Equal(t, tt.held, ok)
+			if tt.held {
+				assert.Equal(t, "COMPLETED", k.Status, "a retry gets the response rather than a 409")
+			}
+		})
+	}
+}
+
+func TestIdempotent_ReclaimsStaleKey(t *testing.T) {
+	var calls atomic.Int32
+	h, keys := newKeyedServer(t, counting(&calls))
+	id := testClient.ID.String() + "/order-1"
+	hash := requestHash(httptest.NewRequest(http.MethodPost, "/v1/payments", nil), []byte(`{}`))
+	// Left behind by a process that died while serving the request.
+	keys.keys[id] = repository.IdempotencyKey{
+		ClientID:       testClient.ID,
+		IdempotencyKey: "order-1",
+		RequestHash:    hash,
+		Status:         "IN_PROGRESS",
+		ClaimedAt:      pgtype.Timestamptz{Time: time.Now().Add(-staleClaimAfter - time.Second), Valid: true},
+	}
+
+	reused := post(h, "order-1", `{"amount":"6"}`)
+	retried := post(h, "order-1", `{}`)
+
+	assert.Equal(t, http.StatusConflict, reused.Code, "only a retry of the same request reclaims the key")
+	assert.Contains(t, reused.Body.String(), codeIdempotencyKeyReused)
+	assert.Equal(t, http.StatusCreated, retried.Code)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, "COMPLETED", keys.keys[id].Status)
+}
+
+func TestIdempotent_FreshClaimIsNotReclaimed(t *testing.T) {
+	var calls atomic.Int32
+	h, keys := newKeyedServer(t, counting(&calls))
+	hash := requestHash(httptest.NewRequest(http.MethodPost, "/v1/payments", nil), []byte(`{}`))
+	keys.keys[testClient.ID.String()+"/order-1"] = repository.IdempotencyKey{
+		ClientID:       testClient.ID,
+		IdempotencyKey: "order-1",
+		RequestHash:    hash,
+		Status:         "IN_PROGRESS",
+		ClaimedAt:      pgtype.Timestamptz{Time: time.Now().Add(-staleClaimAfter + time.Second), Valid: true},
+	}
+
+	rec := post(h, "order-1", `{}`)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), codeIdempotencyKeyInProgress)
+	assert.Zero(t, calls.Load())
+}
+
+func TestIdempotent_KeyTooLong(t *testing.T) {
+	var calls atomic.Int32
+	h, _ := newKeyedServer(t, counting(&calls))
+
+	rec := post(h, strings.Repeat("k", maxIdempotencyKeyLength+1), `{}`)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), codeInvalidIdempotencyKey)
+	assert.Zero(t, calls.Load())
+}
+
+func TestIdempotent_ConcurrentRequestInProgress(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var calls atomic.Int32
+	h, _ := newKeyedServer(t, func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		counting(&calls)(w, r)
+	})
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- post(h, "order-1", `{}`) }()
+	<-started
+
+	racing := post(h, "order-1", `{}`)
+	assert.Equal(t, http.StatusConflict, racing.Code)
+	assert.Contains(t, racing.Body.String(), codeIdempotencyKeyInProgress)
+	assert.Equal(t, "1", racing.Header().Get("Retry-After"))
+
+	close(release)
+	assert.Equal(t, http.StatusCreated, (<-first).Code)
+	assert.Equal(t, http.StatusCreated, post(h, "order-1", `{}`).Code, "replayed once complete")
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestIdempotent_ConcurrentDuplicatesRunOnce(t *testing.T) {
+	const requests = 20
+	var calls atomic.Int32
+	h, _ := newKeyedServer(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		counting(&calls)(w, r)
+	})
+
+	var wg sync.WaitGroup
+	codes := make([]int, requests)
+	bodies := make([]string, requests)
+	start := make(chan struct{})
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			rec := post(h, "order-1", `{"amount":"5"}`)
+			codes[i], bodies[i] = rec.Code, rec.Body.String()
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load(), "only one request creates a payment")
+	for i, code := range codes {
+		switch code {
+		case http.StatusCreated:
+			assert.JSONEq(t, `{"call":1}`, bodies[i])
+		case http.StatusConflict:
+			assert.Contains(t, bodies[i], codeIdempotencyKeyInProgress)
+		default:
+			t.Errorf("request %d: unexpected status %d", i, code)
+		}
+	}
+}
+
+func TestCreatePayment_RetryWithIdempotencyKey(t *testing.T) {
+	s, store, wallets := newTestServer(t)
+	s.store = keyedStore{mockStore: store, memKeys: newMemKeys()}
+	store.On("GetClientByAPIKey", mock.Anything, testAPIKey).Return(testClient, nil)
+	expectAccount(store, nil)
+	expectInsert(store, "25", t0.Add(30*time.Minute))
+	body := `{"account_id":"22222222-2222-2222-2222-222222222222","amount":"25"}`
+	header := http.Header{"Authorization": {"Bearer " + testAPIKey}, IdempotencyKeyHeader: {"checkout-42"}}
+
+	status, first := do(t, s, http.MethodPost, "/v1/payments", body, header)
+	require.Equal(t, http.StatusCreated, status)
+	status, retried := do(t, s, http.MethodPost, "/v1/payments", body, header)
+
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, first, retried, "the retry gets the original payment")
+	assert.Equal(t, []uint32{7}, wallets.indexes, "one deposit wallet")
+	store.AssertNumberOfCalls(t, "CreatePayment", 1)
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/fees"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+)
+
+// Pages of GET /admin/invoices.
+const (
+	defaultInvoicePageSize = 50
+	maxInvoicePageSize     = 200
+)
+
+// invoiceMonthLayout is how invoice months are written, e.g. 2026-03.
+const invoiceMonthLayout = "2006-01"
+
+type invoiceRecord struct {
+	ID        uuid.UUID           `json:"id"`
+	ClientID  uuid.UUID           `json:"client_id"`
+	Month     string              `json:"month"`
+	Payments  int64               `json:"payments"`
+	Lines     []invoiceLineRecord `json:"lines"`
+	Totals    []invoiceTotal      `json:"totals"`
+	CreatedAt string              `json:"created_at"`
+}
+
+// invoiceLineRecord is an invoice line. Rate is a percentage for a PERCENT
+// line and a fee per payment for a MINIMUM one.
+type invoiceLineRecord struct {
+	Token    string `json:"token"`
+	Kind     string `json:"kind"`
+	Payments int64  `json:"payments"`
+	Amount   string `json:"amount"`
+	Rate     string `json:"rate"`
+	Fee      string `json:"fee"`
+}
+
+type invoiceTotal struct {
+	Token string `json:"token"`
+	Fee   string `json:"fee"`
+}
+
+type invoicePage struct {
+	Invoices []invoiceRecord `json:"invoices"`
+	// NextOffset is passed as ?offset= to get the next page; it is left out
+	// on the last one.
+	NextOffset *int64 `json:"next_offset,omitempty"`
+}
+
+func newInvoiceRecord(inv *fees.Invoice) invoiceRecord {
+	rec := invoiceRecord{
+		ID:        inv.ID,
+		ClientID:  inv.ClientID,
+		Month:     inv.Month.UTC().Format(invoiceMonthLayout),
+		Payments:  inv.Payments,
+		Lines:     make([]invoiceLineRecord, 0, len(inv.Lines)),
+		Totals:    []invoiceTotal{},
+		CreatedAt: inv.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	for _, l := range inv.Lines {
+		rec.Lines = append(rec.Lines, invoiceLineRecord{
+			Token:    l.Token,
+			Kind:     l.Kind,
+			Payments: l.Payments,
+			Amount:   payments.FormatAmount(l.Amount, l.Token),
+			Rate:     l.Rate.String(),
+			Fee:      fees.FormatFee(l.Fee, l.Token),
+		})
+	}
+	for _, t := range inv.Totals() {
+		rec.Totals = append(rec.Totals, invoiceTotal{Token: t.Token, Fee: fees.FormatFee(t.Fee, t.Token)})
+	}
+	return rec
+}
+
+// listInvoices handles GET
+// /admin/invoices?client_id=&month=&limit=&offset=, paging through the
+// invoices of every client, newest month first. month is written as
+// 2026-03. Every filter is optional.
+func (s *Server) listInvoices(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	fields := make(map[string]string)
+	var arg repository.ListInvoicesParams
+	if v := query.Get("client_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			fields["client_id"] = "must be a client id"
+		}
+		arg.ClientID = repository.UUIDPtr(id)
+	}
+	if v := query.Get("month"); v != "" {
+		month, err := time.Parse(invoiceMonthLayout, v)
+		if err != nil {
+			fields["month"] = "must be a month such as 2026-03"
+		}
+		arg.Month = pgtype.Date{Time: month, Valid: err == nil}
+	}
+	limit := defaultInvoicePageSize
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxInvoicePageSize {
+			fields["limit"] = fmt.Sprintf("must be between 1 and %d", maxInvoicePageSize)
+		}
+		limit = n
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			fields["offset"] = "must be a next_offset from an earlier page"
+		}
+		arg.Offset = int32(n)
+	}
+	if len(fields) > 0 {
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusBadRequest,
+			Code:       codeValidationFailed,
+			Message:    "request has invalid parameters",
+			Fields:     fields,
+		})
+		return
+	}
+
+	// One extra row tells whether there is a next page.
+	arg.Limit = int32(limit + 1)
+	invoices, err := s.store.ListInvoices(r.Context(), arg)
+	if err != nil {
+		s.internalError(w, r, "failed to list invoices", err)
+		return
+	}
+
+	page := invoicePage{Invoices: make([]invoiceRecord, 0, min(len(invoices), limit))}
+	if len(invoices) > limit {
+		invoices = invoices[:limit]
+		next := int64(arg.Offset) + int64(limit)
+		page.NextOffset = &next
+	}
+	if len(invoices) == 0 {
+		writeJSON(w, http.StatusOK, page)
+		return
+	}
+	ids := make([]uuid.UUID, len(invoices))
+	for i, inv := range invoices {
+		ids[i] = inv.ID
+	}
+	lines, err := s.store.ListInvoiceLines(r.Context(), ids)
+	if err != nil {
+		s.internalError(w, r, "failed to list invoice lines", err)
+		return
+	}
+	for _, inv := range invoices {
+		page.Invoices = append(page.Invoices, newInvoiceRecord(fees.InvoiceFromRecords(inv, lines)))
+	}
+	writeJSON(w, http.StatusOK, page)
+}
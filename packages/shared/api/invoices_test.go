@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+func storedInvoice(month time.Month) repository.Invoice {
+	return repository.Invoice{
+		ID:        uuid.New(),
+		ClientID:  testClient.ID,
+		Month:     pgtype.Date{Time: time.Date(2026, month, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+		Payments:  3,
+		CreatedAt: pgtype.Timestamptz{Time: t0, Valid: true},
+	}
+}
+
+func numeric(s string) pgtype.Numeric {
+	d := decimal.RequireFromString(s)
+	return pgtype.Numeric{Int: d.Coefficient(), Exp: d.Exponent(), Valid: true}
+}
+
+func TestListInvoices(t *testing.T) {
+	s, store := newAdminServer(t)
+	march, february := storedInvoice(time.March), storedInvoice(time.February)
+	store.On("ListInvoices", mock.Anything, repository.ListInvoicesParams{Limit: 2}).
+		Return([]repository.Invoice{march, february}, nil)
+	store.On("ListInvoiceLines", mock.Anything, []uuid.UUID{march.ID}).Return([]repository.InvoiceLine{
+		{InvoiceID: march.ID, Token: "USDT", Kind: "PERCENT", Payments: 2, Amount: numeric("200"), Rate: numeric("1.5"), Fee: numeric("3")},
+		{InvoiceID: march.ID, Token: "USDT", Kind: "MINIMUM", Payments: 1, Amount: numeric("5"), Rate: numeric("0.25"), Fee: numeric("0.25")},
+	}, nil)
+
+	status, resp := do(t, s, http.MethodGet, "/admin/invoices?limit=1", "", adminHeader(""))
+
+	require.Equal(t, http.StatusOK, status)
+	invoices := resp["invoices"].([]any)
+	require.Len(t, invoices, 1)
+	inv := invoices[0].(map[string]any)
+	assert.Equal(t, march.ID.String(), inv["id"])
+	assert.Equal(t, "2026-03", inv["month"])
+	assert.Equal(t, float64(3), inv["payments"])
+	lines := inv["lines"].([]any)
+	require.Len(t, lines, 2)
+	assert.Equal(t, map[string]any{
+		"token": "USDT", "kind": "PERCENT", "payments": float64(2), "amount": "200.000000", "rate": "1.5", "fee": "3.00",
+	}, lines[0])
+	assert.Equal(t, []any{map[string]any{"token": "USDT", "fee": "3.25"}}, inv["totals"])
+	assert.Equal(t, float64(1), resp["next_offset"])
+}
+
+func TestListInvoices_Filters(t *testing.T) {
+	s, store := newAdminServer(t)
+	clientID := uuid.New()
+	store.On("ListInvoices", mock.Anything, mock.MatchedBy(func(arg repository.ListInvoicesParams) bool {
+		return arg.ClientID != nil && *arg.ClientID == clientID &&
+			arg.Month.Valid && arg.Month.Time.Format("2006-01-02") == "2026-02-01" &&
+			arg.Limit == defaultInvoicePageSize+1 &&
+			arg.Offset == 50
+	})).Return([]repository.Invoice(nil), nil).Once()
+
+	status, resp := do(t, s, http.MethodGet, "/admin/invoices?client_id="+clientID.String()+"&month=2026-02&offset=50", "", adminHeader(""))
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []any{}, resp["invoices"])
+	assert.NotContains(t, resp, "next_offset")
+	store.AssertNotCalled(t, "ListInvoiceLines", mock.Anything, mock.Anything)
+}
+
+func TestListInvoices_InvalidParams(t *testing.T) {
+	s, _ := newAdminServer(t)
+
+	status, resp := do(t, s, http.MethodGet, "/admin/invoices?client_id=x&month=2026-3-1&limit=0&offset=x", "", adminHeader(""))
+
+	assert.Equal(t, http.StatusBadRequest, status)
+	fields := resp["fields"].(map[string]any)
+	assert.Equal(t, "must be a month such as 2026-03", fields["month"])
+	for _, name := range []string{"client_id", "limit", "offset"} {
+		assert.Contains(t, fields, name)
+	}
+}
+
+func TestListInvoices_StoreError(t *testing.T) {
+	s, store := newAdminServer(t)
+	inv := storedInvoice(time.March)
+	store.On("ListInvoices", mock.Anything, mock.Anything).Return([]repository.Invoice{inv}, nil)
+	store.On("ListInvoiceLines", mock.Anything, mock.Anything).Return([]repository.InvoiceLine(nil), assert.AnError)
+
+	status, _ := do(t, s, http.MethodGet, "/admin/invoices", "", adminHeader(""))
+
+	assert.Equal(t, http.StatusInternalServerError, status)
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/logging"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/requestid"
+)
+
+// redacted replaces the value of a credential header in logs.
+const redacted = logging.RedactedValue
+
+// secretHeaders are never logged as sent.
+var secretHeaders = []string{"Authorization", apiKeyHeader, "Cookie"}
+
+// requestInfo collects what the handlers learn about a request, for the
+// access log written once it completes.
+type requestInfo struct {
+	clientID uuid.UUID
+}
+
+type requestInfoKey struct{}
+
+func infoFrom(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(*requestInfo)
+	return info
+}
+
+// logRequests gives every request an ID, taken from X-Request-ID when the
+// caller sent a usable one, puts it in the context and the response
+// headers, traces the request, and logs it once it completes. Bodies are
+// never logged; headers only at debug level, with credentials scrubbed.
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx, span := s.startSpan(r)
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		w.Header().Set(requestid.Header, id)
+
+		info := &requestInfo{}
+		ctx = context.WithValue(requestid.NewContext(ctx, id), requestInfoKey{}, info)
+		rec := &statusRecorder{ResponseWriter: w}
+		r = r.WithContext(ctx)
+		next.ServeHTTP(rec, r)
+		latency := time.Since(start)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		s.metrics.ObserveRequest(route(r), r.Method, status, latency)
+		s.endSpan(ctx, span, r, status, info)
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int64("bytes", rec.bytes),
+			slog.Duration("latency", latency),
+			slog.String("remote_addr", r.RemoteAddr),
+		}
+		if info.clientID != uuid.Nil {
+			attrs = append(attrs, slog.String("client_id", info.clientID.String()))
+		}
+		if sc := span.SpanContext(); sc.IsValid() {
+			attrs = append(attrs, slog.String("trace_id", sc.TraceID().String()))
+		}
+		if s.logger.Enabled(ctx, slog.LevelDebug) {
+			attrs = append(attrs, slog.Any("headers", scrubHeaders(r.Header)))
+		}
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		s.logger.LogAttrs(ctx, level, "request completed", attrs...)
+	})
+}
+
+// route returns the pattern the mux matched r with, without its method, or
+// "unmatched" for requests no route took.
+func route(r *http.Request) string {
+	if r.Pattern == "" {
+		return "unmatched"
+	}
+	if _, path, ok := strings.Cut(r.Pattern, " "); ok {
+		return path
+	}
+	return r.Pattern
+}
+
+// scrubHeaders returns a copy of h with credential values replaced.
+func scrubHeaders(h http.Header) http.Header {
+	scrubbed := h.Clone()
+	for _, name := range secretHeaders {
+		name = http.CanonicalHeaderKey(name)
+		if _, ok := scrubbed[name]; ok {
+			scrubbed[name] = []string{redacted}
+		}
+	}
+	return scrubbed
+}
+
+// statusRecorder remembers the status and size of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/requestid"
+)
+
+// capture returns a logger writing JSON at level to a buffer, and a function
+// decoding the records logged so far.
+func capture(level slog.Level) (*slog.Logger, func(t *testing.T) []map[string]any) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: level}))
+	return logger, func(t *testing.T) []map[string]any {
+		t.Helper()
+		var records []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var r map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &r), line)
+			records = append(records, r)
+		}
+		return records
+	}
+}
+
+func record(t *testing.T, records []map[string]any, msg string) map[string]any {
+	t.Helper()
+	for _, r := range records {
+		if r["msg"] == msg {
+			return r
+		}
+	}
+	require.Failf(t, "record not logged", "%q", msg)
+	return nil
+}
+
+func TestLogRequests(t *testing.T) {
+	logger, records := capture(slog.LevelInfo)
+	s, store, _ := newTestServer(t, WithLogger(logger))
+	expectClient(store)
+	expectAccount(store, nil)
+	expectInsert(store, "25.5", t0.Add(30*time.Minute))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/payments",
+		strings.NewReader(`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"25.5"}`))
+	req.Header.Set("Authorization", "Bearer "+testAPIKey)
+	req.Header.Set(requestid.Header, "req-abc123")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "req-abc123", rec.Header().Get(requestid.Header), "the caller's ID is echoed")
+
+	access := record(t, records(t), "request completed")
+	assert.Equal(t, "INFO", access["level"])
+	assert.Equal(t, "req-abc123", access["request_id"])
+	assert.Equal(t, "POST", access["method"])
+	assert.Equal(t, "/v1/payments", access["path"])
+	assert.Equal(t, float64(http.StatusCreated), access["status"])
+	assert.Equal(t, float64(rec.Body.Len()), access["bytes"])
+	assert.Contains(t, access, "latency")
+	assert.Equal(t, testClient.ID.String(), access["client_id"])
+	assert.NotContains(t, access, "headers", "headers are only logged at debug level")
+
+	created := record(t, records(t), "payment created")
+	assert.Equal(t, "req-abc123", created["request_id"], "handler logs carry the ID")
+
+	for _, c := range store.Calls {
+		if c.Method == "CreateLog" {
+			arg := c.Arguments.Get(1).(repository.CreateLogParams)
+			require.NotNil(t, arg.RequestID)
+			assert.Equal(t, "req-abc123", *arg.RequestID, "the log row carries the ID")
+		}
+	}
+}
+
+func TestLogRequests_NeverLogsBodiesOrKeys(t *testing.T) {
+	logger, records := capture(slog.LevelDebug)
+	s, store, _ := newTestServer(t, WithLogger(logger))
+	store.On("GetClientByAPIKey", mock.Anything, "sk_live_secret").Return(repository.Client{}, pgx.ErrNoRows)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/payments", strings.NewReader(`{"amount":"987.654"}`))
+	req.Header.Set(apiKeyHeader, "sk_live_secret")
+	req.Header.Set("Cookie", "session=s3cr3t")
+	req.Header.Set("User-Agent", "shop/1.0")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	all := records(t)
+	raw, err := json.Marshal(all)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "sk_live_secret")
+	assert.NotContains(t, string(raw), "s3cr3t")
+	assert.NotContains(t, string(raw), "987.654")
+
+	access := record(t, all, "request completed")
+	headers := access["headers"].(map[string]any)
+	assert.Equal(t, []any{redacted}, headers["X-Api-Key"])
+	assert.Equal(t, []any{redacted}, headers["Cookie"])
+	assert.Equal(t, []any{"shop/1.0"}, headers["User-Agent"])
+	assert.NotContains(t, access, "client_id", "the key matched no client")
+	assert.Equal(t, float64(http.StatusUnauthorized), access["status"])
+}
+
+func TestLogRequests_GeneratesID(t *testing.T) {
+	testCases := []struct {
+		name     string
+		incoming string
+	}{
+		{"none sent", ""},
+		{"unsafe characters", "bad id\nwith spaces"},
+		{"too long", strings.Repeat("a", 200)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, _, _ := newTestServer(t)
+			req := httptest.NewRequest(http.MethodGet, "/v1/unknown", nil)
+			if tc.incoming != "" {
+				req.Header.Set(requestid.Header, tc.incoming)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(requestid.Header)
+			assert.True(t, requestid.Valid(id), "%q", id)
+			assert.NotEqual(t, tc.incoming, id)
+		})
+	}
+}
+
+func TestLogRequests_ServerErrorsLoggedAsErrors(t *testing.T) {
+	logger, records := capture(slog.LevelInfo)
+	s, store, _ := newTestServer(t, WithLogger(logger))
+	store.On("GetClientByAPIKey", mock.Anything, testAPIKey).Return(repository.Client{}, assert.AnError)
+
+	status, _ := do(t, s, http.MethodGet, "/v1/payments/"+testPaymentID.String(), "", nil)
+
+	require.Equal(t, http.StatusInternalServerError, status)
+	all := records(t)
+	assert.Equal(t, "ERROR", record(t, all, "request completed")["level"])
+	failure := record(t, all, "failed to authenticate client")
+	assert.NotEmpty(t, failure["request_id"])
+	assert.Equal(t, record(t, all, "request completed")["request_id"], failure["request_id"])
+}
+
+func TestStatusRecorder_ImplicitOK(t *testing.T) {
+	rec := &statusRecorder{ResponseWriter: httptest.NewRecorder()}
+
+	_, err := rec.Write([]byte("hello"))
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.status)
+	assert.Equal(t, int64(5), rec.bytes)
+}
+
+type request struct {
+	route, method string
+	status        int
+}
+
+type recordingMetrics struct {
+	requests []request
+}
+
+func (m *recordingMetrics) ObserveRequest(route, method string, status int, d time.Duration) {
+	m.requests = append(m.requests, request{route, method, status})
+}
+
+func TestLogRequests_Metrics(t *testing.T) {
+	m := &recordingMetrics{}
+	s, store, _ := newTestServer(t, WithMetrics(m))
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(repository.Payment{}, pgx.ErrNoRows)
+
+	do(t, s, http.MethodGet, "/v1/payments/"+testPaymentID.String(), "", nil)
+	do(t, s, http.MethodPost, "/v1/payments", `{`, nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/nope/"+testPaymentID.String(), nil))
+
+	assert.Equal(t, []request{
+		{"/v1/payments/{id}", "GET", http.StatusNotFound},
+		{"/v1/payments", "POST", http.StatusBadRequest},
+		{"unmatched", "GET", http.StatusNotFound},
+	}, m.requests, "routes are labelled by pattern, never by raw path")
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// Pages of GET /admin/logs.
+const (
+	defaultLogPageSize = 50
+	maxLogPageSize     = 200
+)
+
+// logRecord is a log as an operator sees it. RawData is the JSON the writer
+// recorded, returned as is rather than as base64 bytes.
+type logRecord struct {
+	ID        uuid.UUID       `json:"id"`
+	PaymentID *uuid.UUID      `json:"payment_id"`
+	EventType string          `json:"event_type"`
+	Message   *string         `json:"message"`
+	RawData   json.RawMessage `json:"raw_data"`
+	CreatedAt string          `json:"created_at"`
+	RequestID *string         `json:"request_id"`
+	Actor     *string         `json:"actor"`
+}
+
+type logPage struct {
+	Logs []logRecord `json:"logs"`
+	// NextOffset is passed as ?offset= to get the next page; it is left out
+	// on the last one.
+	NextOffset *int64 `json:"next_offset,omitempty"`
+}
+
+func newLogRecord(l repository.Log) logRecord {
+	return logRecord{
+		ID:        l.ID,
+		PaymentID: l.PaymentID,
+		EventType: l.EventType,
+		Message:   l.Message,
+		RawData:   l.RawData,
+		CreatedAt: formatTime(l.CreatedAt),
+		RequestID: l.RequestID,
+		Actor:     l.Actor,
+	}
+}
+
+// listLogs handles GET
+// /admin/logs?event_type=&payment_id=&from=&to=&limit=&offset=, paging
+// through the logs of every client created in [from, to), newest first.
+// Every filter is optional.
+func (s *Server) listLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	fields := make(map[string]string)
+	var arg repository.ListLogsParams
+	if v := query.Get("event_type"); v != "" {
+		arg.EventType = &v
+	}
+	if v := query.Get("payment_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			fields["payment_id"] = "must be a payment id"
+		}
+		arg.PaymentID = repository.UUIDPtr(id)
+	}
+	for name, bound := range map[string]*pgtype.Timestamptz{"from": &arg.From, "to": &arg.To} {
+		v := query.Get(name)
+		if v == "" {
+			continue
+		}
+		t, err := parseExportTime(v)
+		if err != nil {
+			fields[name] = err.Error()
+			continue
+		}
+		*bound = pgtype.Timestamptz{Time: t, Valid: true}
+	}
+	if arg.From.Valid && arg.To.Valid && !arg.To.Time.After(arg.From.Time) {
+		fields["to"] = "must be after from"
+	}
+	limit := defaultLogPageSize
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLogPageSize {
+			fields["limit"] = fmt.Sprintf("must be between 1 and %d", maxLogPageSize)
+		}
+		limit = n
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			fields["offset"] = "must be a next_offset from an earlier page"
+		}
+		arg.Offset = int32(n)
+	}
+	if len(fields) > 0 {
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusBadRequest,
+			Code:       codeValidationFailed,
+			Message:    "request has invalid parameters",
+			Fields:     fields,
+		})
+		return
+	}
+
+	// One extra row tells whether there is a next page.
+	arg.Limit = int32(limit + 1)
+	logs, err := s.store.ListLogs(r.Context(), arg)
+	if err != nil {
+		s.internalError(w, r, "failed to list logs", err)
+		return
+	}
+
+	page := logPage{Logs: make([]logRecord, 0, min(len(logs), limit))}
+	if len(logs) > limit {
+		logs = logs[:limit]
+		next := int64(arg.Offset) + int64(limit)
+		page.NextOffset = &next
+	}
+	for _, l := range logs {
+		page.Logs = append(page.Logs, newLogRecord(l))
+	}
+	writeJSON(w, http.StatusOK, page)
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+func TestListLogs(t *testing.T) {
+	s, store := newFakeServer(t, WithAdminToken(testAdminToken))
+	ctx := context.Background()
+	for i := range 3 {
+		require.NoError(t, store.CreateLog(ctx, repository.CreateLogParams{
+			EventType: "WEBHOOK_SENT",
+			RawData:   []byte(`{"attempt":` + strconv.Itoa(i) + `}`),
+		}))
+	}
+	require.NoError(t, store.CreateLog(ctx, repository.CreateLogParams{EventType: "CLIENT_CREATED"}))
+	webhooks := "WEBHOOK_SENT"
+	want, err := store.ListLogs(ctx, repository.ListLogsParams{EventType: &webhooks, Limit: 10})
+	require.NoError(t, err)
+
+	status, resp := do(t, s, http.MethodGet, "/admin/logs?event_type=WEBHOOK_SENT&limit=2", "", adminHeader(""))
+
+	require.Equal(t, http.StatusOK, status)
+	logs := resp["logs"].([]any)
+	require.Len(t, logs, 2)
+	for i, l := range logs {
+		l := l.(map[string]any)
+		assert.Equal(t, want[i].ID.String(), l["id"])
+		assert.Equal(t, "WEBHOOK_SENT", l["event_type"])
+		assert.Equal(t, "2026-03-01T12:00:00Z", l["created_at"])
+		assert.IsType(t, map[string]any{}, l["raw_data"], "raw data is JSON, not base64")
+	}
+	assert.Equal(t, float64(2), resp["next_offset"])
+
+	status, resp = do(t, s, http.MethodGet, "/admin/logs?event_type=WEBHOOK_SENT&limit=2&offset=2", "", adminHeader(""))
+
+	require.Equal(t, http.StatusOK, status)
+	logs = resp["logs"].([]any)
+	require.Len(t, logs, 1)
+	assert.Equal(t, want[2].ID.String(), logs[0].(map[string]any)["id"])
+	assert.NotContains(t, resp, "next_offset", "the last page has no offset")
+}
+
+func TestListLogs_Empty(t *testing.T) {
+	s, _ := newFakeServer(t, WithAdminToken(testAdminToken))
+
+	status, resp := do(t, s, http.MethodGet, "/admin/logs", "", adminHeader(""))
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []any{}, resp["logs"])
+}
+
+func TestListLogs_Filters(t *testing.T) {
+	s, store := newAdminServer(t)
+	paymentID := uuid.New()
+	store.On("ListLogs", mock.Anything, mock.MatchedBy(func(arg repository.ListLogsParams) bool {
+		return arg.EventType == nil &&
+			arg.PaymentID != nil && *arg.PaymentID == paymentID &&
+			arg.From == pgtype.Timestamptz{Time: t0, Valid: true} &&
+			arg.To == pgtype.Timestamptz{Time: t0.Add(24 * time.Hour), Valid: true} &&
+			arg.Limit == defaultLogPageSize+1 &&
+			arg.Offset == 0
+	})).Return([]repository.Log(nil), nil).Once()
+
+	status, _ := do(t, s, http.MethodGet, "/admin/logs?payment_id="+paymentID.String()+"&from=2026-03-01T12:00:00Z&to=2026-03-02T12:00:00Z", "", adminHeader(""))
+
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestListLogs_InvalidParams(t *testing.T) {
+	s, _ := newAdminServer(t)
+
+	status, resp := do(t, s, http.MethodGet, "/admin/logs?payment_id=x&from=yesterday&limit=201&offset=-1", "", adminHeader(""))
+
+	assert.Equal(t, http.StatusBadRequest, status)
+	fields := resp["fields"].(map[string]any)
+	for _, name := range []string{"payment_id", "from", "limit", "offset"} {
+		assert.Contains(t, fields, name)
+	}
+
+	status, resp = do(t, s, http.MethodGet, "/admin/logs?from=2026-03-02&to=2026-03-01", "", adminHeader(""))
+
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "must be after from", resp["fields"].(map[string]any)["to"])
+}
+
+func TestListLogs_StoreError(t *testing.T) {
+	s, store := newAdminServer(t)
+	store.On("ListLogs", mock.Anything, mock.Anything).Return([]repository.Log(nil), assert.AnError)
+
+	status, _ := do(t, s, http.MethodGet, "/admin/logs", "", adminHeader(""))
+
+	assert.Equal(t, http.StatusInternalServerError, status)
+}
@@ -0,0 +1,360 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+)
+
+type createPaymentRequest struct {
+	AccountID string `json:"account_id"`
+	// Token is one of the supported tokens; empty means the first of them.
+	Token string `json:"token"`
+	// Amount is a decimal string in Token units, so no precision is lost to
+	// JSON numbers.
+	Amount string `json:"amount"`
+	// ExpiresIn is in seconds; nil means the account's default, or the
+	// configured one.
+	ExpiresIn *int64 `json:"expires_in"`
+	// WebhookEndpointID names the one endpoint the payment's webhooks go to.
+	WebhookEndpointID string `json:"webhook_endpoint_id"`
+	// OrderReference is the merchant's own id for the payment, unique among
+	// its payments.
+	OrderReference string `json:"order_reference"`
+	// Metadata is a JSON object of at most 8 KB echoed with the payment.
+	Metadata json.RawMessage `json:"metadata"`
+}
+
+type paymentResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Wallet    string    `json:"wallet"`
+	Token     string    `json:"token"`
+	Amount    string    `json:"amount"`
+	ExpiresAt string    `json:"expires_at"`
+	Status    string    `json:"status"`
+	// Livemode is false for the payments of test clients.
+	Livemode         bool                       `json:"livemode"`
+	WalletActivation *payments.WalletActivation `json:"wallet_activation,omitempty"`
+	// StatusToken lets a checkout page poll GET /v1/public/payments/{id}.
+	StatusToken    string          `json:"status_token,omitempty"`
+	OrderReference *string         `json:"order_reference,omitempty"`
+	Metadata       json.RawMessage `json:"metadata,omitempty"`
+}
+
+// paymentRecord is a payment as its merchant sees it.
+type paymentRecord struct {
+	ID           uuid.UUID `json:"id"`
+	AccountID    uuid.UUID `json:"account_id"`
+	Wallet       string    `json:"wallet"`
+	Token        string    `json:"token"`
+	Amount       string    `json:"amount"`
+	Status       string    `json:"status"`
+	ExpiresAt    string    `json:"expires_at"`
+	ConfirmedAt  *string   `json:"confirmed_at"`
+	AttemptCount int32     `json:"attempt_count"`
+	Livemode     bool      `json:"livemode"`
+	CreatedAt    string    `json:"created_at"`
+	FiatAmount   *string   `json:"fiat_amount,omitempty"`
+	FiatCurrency *string   `json:"fiat_currency,omitempty"`
+	ExchangeRate *string   `json:"exchange_rate,omitempty"`
+	RateAt       *string   `json:"rate_at,omitempty"`
+	StatusToken  string    `json:"status_token,omitempty"`
+	// QRPNGBase64 is a PNG QR code of the payment's tron: URI, set for
+	// include_qr=true while the payment can be paid.
+	QRPNGBase64 string `json:"qr_png_base64,omitempty"`
+
+	OrderReference *string         `json:"order_reference,omitempty"`
+	Metadata       json.RawMessage `json:"metadata,omitempty"`
+}
+
+// publicPayment is what a status token holder may see of a payment.
+type publicPayment struct {
+	Status    string `json:"status"`
+	Token     string `json:"token"`
+	Amount    string `json:"amount"`
+	Wallet    string `json:"wallet"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// createPayment handles POST /v1/payments: it gives the payment the next
+// deposit wallet and records the payment, its first attempt and an
+// ADDRESS_GENERATED log in one transaction.
+func (s *Server) createPayment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	client := clientFrom(ctx)
+
+	var req createPaymentRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+	p, fields := payments.Request(req).Validate(s.payments)
+	if len(fields) > 0 {
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusBadRequest,
+			Code:       codeValidationFailed,
+			Message:    "request has invalid fields",
+			Fields:     fields,
+		})
+		return
+	}
+
+	account, err := s.tenant(ctx).GetAccountByIDAndClientID(ctx, repository.GetAccountByIDAndClientIDParams{
+		ID: p.AccountID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, codeAccountNotFound, "account not found"))
+		return
+	}
+	if err != nil {
+		s.internalError(w, r, "failed to load account", err, "account_id", p.AccountID)
+		return
+	}
+	if p.WebhookEndpointID != nil {
+		msg, err := s.checkWebhookEndpoint(r, *p.WebhookEndpointID)
+		if err != nil {
+			s.internalError(w, r, "failed to load webhook endpoint", err, "endpoint_id", req.WebhookEndpointID)
+			return
+		}
+		if msg != "" {
+			apierror.Write(w, r, &apierror.Error{
+				HTTPStatus: http.StatusBadRequest,
+				Code:       codeValidationFailed,
+				Message:    "request has invalid fields",
+				Fields:     map[string]string{"webhook_endpoint_id": msg},
+			})
+			return
+		}
+	}
+	p = payments.Request(req).WithAccountDefaults(p, account)
+
+	wallets, ok := s.walletsFor(client.Mode)
+	if !ok {
+		apierror.Write(w, r, apierror.New(http.StatusForbidden, codeTestModeDisabled, "test mode is not enabled"))
+		return
+	}
+	p.Mode = clientMode(client)
+	payment, err := payments.Create(ctx, s.store, wallets, client.ID, p, s.now())
+	if errors.Is(err, payments.ErrAccountNotFound) {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, codeAccountNotFound, "account not found"))
+		return
+	}
+	if errors.Is(err, payments.ErrTooManyOpenPayments) {
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusTooManyRequests,
+			Code:       codeTooManyOpenPayments,
+			Message:    fmt.Sprintf("account has %d open payments, the most allowed", p.MaxActive),
+		})
+		return
+	}
+	if errors.Is(err, payments.ErrSuffixesExhausted) {
+		apierror.Write(w, r, apierror.New(http.StatusConflict, codeSuffixesExhausted,
+			"every amount suffix of the account is held by a recent payment"))
+		return
+	}
+	if errors.Is(err, payments.ErrAmountTooPrecise) {
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusBadRequest,
+			Code:       codeValidationFailed,
+			Message:    "request has invalid fields",
+			Fields: map[string]string{"amount": fmt.Sprintf("must have at most %d decimal places for an %s account",
+				payments.AmountDecimals-payments.SuffixDigits, payments.StrategyAmountSuffix)},
+		})
+		return
+	}
+	if errors.Is(err, repository.ErrDuplicateOrderReference) {
+		apierror.Write(w, r, err)
+		return
+	}
+	if errors.Is(err, payments.ErrDerivationHalted) {
+		s.derivationHalted(w, r)
+		return
+	}
+	if err != nil {
+		s.internalError(w, r, "failed to create payment", err, "account_id", p.AccountID)
+		return
+	}
+	s.logger.InfoContext(ctx, "payment created", "payment_id", payment.ID, "client_id", client.ID,
+		"account_id", p.AccountID, "wallet", payment.UniqueWallet)
+
+	resp := paymentResponse{
+		ID:        payment.ID,
+		Wallet:    payment.UniqueWallet,
+		Token:     p.Token,
+		Amount:    payments.FormatAmount(repository.NumericToDecimal(payment.Amount), p.Token),
+		ExpiresAt: formatTime(payment.ExpiresAt),
+		Status:    payment.Status,
+		Livemode:  livemode(payment.Mode),
+
+		OrderReference: payment.OrderReference,
+		Metadata:       payment.Metadata,
+	}
+	if s.tokens != nil {
+		resp.StatusToken = s.tokens.Issue(payment.ID, payment.ExpiresAt.Time)
+	}
+	// The checker asks the live network, which knows nothing of testnet
+	// wallets.
+	if s.activation != nil && resp.Livemode {
+		a, err := payments.CheckWalletActivation(ctx, s.activation, payment.UniqueWallet, p.Token)
+		if err != nil {
+			s.logger.WarnContext(ctx, "failed to check wallet activation", "payment_id", payment.ID, "error", err)
+		}
+		resp.WalletActivation = &a
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// getPayment handles GET /v1/payments/{id}. Payments of other clients are
+// reported as not found. include_qr=true adds a QR code of the payment URI.
+func (s *Server) getPayment(w http.ResponseWriter, r *http.Request) {
+	withQR, ok := includeQR(w, r)
+	if !ok {
+		return
+	}
+	payment, ok := s.lookupPayment(w, r, s.tenant(r.Context()).GetPayment)
+	if !ok {
+		return
+	}
+	s.writePaymentRecord(w, r, payment, withQR)
+}
+
+// getPaymentByOrderReference handles GET
+// /v1/payments/by-order-reference/{reference}, finding the client's payment
+// by the reference it was created with. It takes include_qr like getPayment.
+func (s *Server) getPaymentByOrderReference(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ref := r.PathValue("reference")
+	withQR, ok := includeQR(w, r)
+	if !ok {
+		return
+	}
+	payment, err := s.tenant(ctx).GetPaymentByOrderReference(ctx, ref)
+	if errors.Is(err, pgx.ErrNoRows) {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, codePaymentNotFound, "payment not found"))
+		return
+	}
+	if err != nil {
+		s.internalError(w, r, "failed to load payment", err, "order_reference", ref)
+		return
+	}
+	s.writePaymentRecord(w, r, payment, withQR)
+}
+
+// writePaymentRecord writes payment's record, with its QR code when withQR
+// is set.
+func (s *Server) writePaymentRecord(w http.ResponseWriter, r *http.Request, payment repository.Payment, withQR bool) {
+	rec := s.paymentRecord(payment)
+	if withQR {
+		if err := s.addQR(&rec, payment); err != nil {
+			s.internalError(w, r, "failed to render payment QR code", err, "payment_id", payment.ID)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, rec)
+}
+
+// paymentRecord shows payment to its merchant.
+func (s *Server) paymentRecord(payment repository.Payment) paymentRecord {
+	rec := paymentRecord{
+		ID:          payment.ID,
+		AccountID:   payment.AccountID,
+		Wallet:      payment.UniqueWallet,
+		Token:       payment.Token,
+		Amount:      payments.FormatAmount(repository.NumericToDecimal(payment.Amount), payment.Token),
+		Status:      payment.Status,
+		ExpiresAt:   formatTime(payment.ExpiresAt),
+		ConfirmedAt: optionalTime(payment.ConfirmedAt),
+		CreatedAt:   formatTime(payment.CreatedAt),
+		RateAt:      optionalTime(payment.RateAt),
+		Livemode:    livemode(payment.Mode),
+
+		OrderReference: payment.OrderReference,
+		Metadata:       payment.Metadata,
+	}
+	if payment.AttemptCount != nil {
+		rec.AttemptCount = *payment.AttemptCount
+	}
+	if payment.FiatAmount.Valid {
+		fiat := repository.NumericToDecimal(payment.FiatAmount).String()
+		rate := repository.NumericToDecimal(payment.ExchangeRate).String()
+		rec.FiatAmount, rec.FiatCurrency, rec.ExchangeRate = &fiat, payment.FiatCurrency, &rate
+	}
+	if s.tokens != nil {
+		rec.StatusToken = s.tokens.Issue(payment.ID, payment.ExpiresAt.Time)
+	}
+	return rec
+}
+
+// getPublicPayment handles GET /v1/public/payments/{id}?token=. A bad or
+// expired token gets the same 404 as an unknown payment, so the route cannot
+// be used to find out which payment ids exist.
+func (s *Server) getPublicPayment(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil || s.tokens.Verify(id, r.URL.Query().Get("token"), s.now()) != nil {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, codePaymentNotFound, "payment not found"))
+		return
+	}
+	payment, ok := s.lookupPayment(w, r, s.publicPayment)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, publicPayment{
+		Status:    payment.Status,
+		Token:     payment.Token,
+		Amount:    payments.FormatAmount(repository.NumericToDecimal(payment.Amount), payment.Token),
+		Wallet:    payment.UniqueWallet,
+		ExpiresAt: formatTime(payment.ExpiresAt),
+	})
+}
+
+// lookupPayment loads the payment named by the id path value with get,
+// writing a 404 when there is none. Client routes pass the tenant's GetPayment
+// and the public routes publicPayment.
+func (s *Server) lookupPayment(w http.ResponseWriter, r *http.Request,
+	get func(context.Context, uuid.UUID) (repository.Payment, error),
+) (repository.Payment, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, codePaymentNotFound, "payment not found"))
+		return repository.Payment{}, false
+	}
+	payment, err := get(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, codePaymentNotFound, "payment not found"))
+		return repository.Payment{}, false
+	}
+	if err != nil {
+		s.internalError(w, r, "failed to load payment", err, "payment_id", id)
+		return repository.Payment{}, false
+	}
+	return payment, true
+}
+
+// internalError logs an error the handler did not expect and answers with
+// the problem apierror.From maps it to: a 4xx for a repository error it
+// knows, otherwise a 500 that does not reveal err.
+func (s *Server) internalError(w http.ResponseWriter, r *http.Request, msg string, err error, args ...any) {
+	s.logger.ErrorContext(r.Context(), msg, append(args, "error", err)...)
+	apierror.Write(w, r, err)
+}
+
+func formatTime(t pgtype.Timestamptz) string {
+	return t.Time.UTC().Format(time.RFC3339)
+}
+
+func optionalTime(t pgtype.Timestamptz) *string {
+	if !t.Valid {
+		return nil
+	}
+	s := formatTime(t)
+	return &s
+}
@@ -0,0 +1,873 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+)
+
+var testPaymentID = uuid.MustParse("33333333-3333-3333-3333-333333333333")
+
+// fakeActivation reports every address as activated, or fails.
+type fakeActivation struct {
+	activated bool
+	err       error
+}
+
+func (f fakeActivation) IsAddressActivated(_ context.Context, _ string) (bool, error) {
+	return f.activated, f.err
+}
+
+func expectClient(store *mockStore) {
+	store.On("GetClientByAPIKey", mock.Anything, testAPIKey).Return(testClient, nil)
+}
+
+func expectAccount(store *mockStore, err error) {
+	store.On("GetAccountByIDAndClientID", mock.Anything, repository.GetAccountByIDAndClientIDParams{
+		ID:       testAccount.ID,
+		ClientID: testClient.ID,
+	}).Return(testAccount, err)
+}
+
+// expectInsert expects a USDT payment of amount to be created at wallet
+// index 7.
+func expectInsert(store *mockStore, amount string, expiresAt time.Time) {
+	expectInsertToken(store, config.TokenUSDT, amount, expiresAt)
+}
+
+func expectInsertToken(store *mockStore, token, amount string, expiresAt time.Time) {
+	index := int64(7)
+	store.On("NextWalletIndex", mock.Anything, "deposit").Return(int64(7), nil)
+	store.On("CreatePayment", mock.Anything, mock.MatchedBy(func(arg repository.CreatePaymentParams) bool {
+		return arg.ClientID == testClient.ID &&
+			arg.AccountID == testAccount.ID &&
+			numericToString(arg.Amount) == amount &&
+			arg.UniqueWallet == "TWallet7" &&
+			arg.ExpiresAt.Time.Equal(expiresAt) &&
+			arg.WalletIndex != nil && *arg.WalletIndex == 7 &&
+			arg.Token == token &&
+			arg.Mode == config.ModeLive
+	})).Return(repository.Payment{
+		ID:           testPaymentID,
+		ClientID:     testClient.ID,
+		AccountID:    testAccount.ID,
+		UniqueWallet: "TWallet7",
+		Token:        token,
+		Amount:       repository.DecimalToNumeric(decimal.RequireFromString(amount)),
+		Status:       "PENDING",
+		ExpiresAt:    pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	}, nil)
+	store.On("CreatePaymentAttempt", mock.Anything, repository.CreatePaymentAttemptParams{
+		AttemptNumber:   1,
+		PaymentID:       testPaymentID,
+		GeneratedWallet: "TWallet7",
+		WalletIndex:     &index,
+	}).Return(repository.PaymentAttempt{}, nil)
+	store.On("CreateLog", mock.Anything, mock.MatchedBy(func(arg repository.CreateLogParams) bool {
+		var raw payments.AddressGeneratedLog
+		return *arg.PaymentID == testPaymentID &&
+			arg.EventType == payments.EventAddressGenerated &&
+			json.Unmarshal(arg.RawData, &raw) == nil &&
+			raw == payments.AddressGeneratedLog{Wallet: "TWallet7", WalletIndex: 7, Attempt: 1}
+	})).Return(nil)
+}
+
+func numericToString(n pgtype.Numeric) string {
+	return decimal.NewFromBigInt(n.Int, n.Exp).String()
+}
+
+func TestCreatePayment(t *testing.T) {
+	s, store, wallets := newTestServer(t)
+	expectClient(store)
+	expectAccount(store, nil)
+	expectInsert(store, "25.5", t0.Add(30*time.Minute))
+
+	status, resp := do(t, s, http.MethodPost, "/v1/payments",
+		`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"25.5"}`, nil)
+
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, map[string]any{
+		"id":         testPaymentID.String(),
+		"wallet":     "TWallet7",
+		"token":      "USDT",
+		"amount":     "25.500000",
+		"expires_at": "2026-03-01T12:30:00Z",
+		"status":     "PENDING",
+		"livemode":   true,
+	}, resp)
+	assert.Equal(t, []uint32{7}, wallets.indexes)
+}
+
+func TestCreatePayment_TestClient(t *testing.T) {
+	s, store, wallets := newTestServer(t, WithTestWallets(testWallets), WithActivationChecker(fakeActivation{activated: true}))
+	test := testClient
+	test.Mode = config.ModeTest
+	store.On("GetClientByAPIKey", mock.Anything, testAPIKey).Return(test, nil)
+	expectAccount(store, nil)
+	store.On("NextWalletIndex", mock.Anything, "deposit_test").Return(int64(3), nil)
+	store.On("CreatePayment", mock.Anything, mock.MatchedBy(func(arg repository.CreatePaymentParams) bool {
+		return arg.UniqueWallet == "TTest3" && arg.Mode == config.ModeTest
+	})).Return(repository.Payment{ID: testPaymentID, UniqueWallet: "TTest3", Status: "PENDING", Mode: config.ModeTest}, nil)
+	store.On("CreatePaymentAttempt", mock.Anything, mock.Anything).Return(repository.PaymentAttempt{}, nil)
+	store.On("CreateLog", mock.Anything, mock.Anything).Return(nil)
+
+	status, resp := do(t, s, http.MethodPost, "/v1/payments",
+		`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"5"}`, nil)
+
+	require.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "TTest3", resp["wallet"])
+	assert.Equal(t, false, resp["livemode"])
+	assert.NotContains(t, resp, "wallet_activation", "the live network is not asked about testnet wallets")
+	assert.Empty(t, wallets.indexes, "no live wallet is handed out")
+}
+
+func TestCreatePayment_CachedTestClient(t *testing.T) {
+	s, store, _ := newCachedServer(t, WithTestWallets(testWallets), WithActivationChecker(fakeActivation{activated: true}))
+	test := testClient
+	test.Mode = config.ModeTest
+	store.On("GetClientByAPIKey", mock.Anything, testAPIKey).Return(test, nil).Once()
+	expectAccount(store, nil)
+	store.On("NextWalletIndex", mock.Anything, "deposit_test").Return(int64(3), nil).Twice()
+	store.On("CreatePayment", mock.Anything, mock.MatchedBy(func(arg repository.CreatePaymentParams) bool {
+		return arg.UniqueWallet == "TTest3" && arg.Mode == config.ModeTest
+	})).Return(repository.Payment{ID: testPaymentID, UniqueWallet: "TTest3", Status: "PENDING", Mode: config.ModeTest}, nil).Twice()
+	store.On("CreatePaymentAttempt", mock.Anything, mock.Anything).Return(repository.PaymentAttempt{}, nil)
+	store.On("CreateLog", mock.Anything, mock.Anything).Return(nil)
+
+	for _, served := range []string{"the database", "the cache"} {
+		status, resp := do(t, s, http.MethodPost, "/v1/payments",
+			`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"5"}`, nil)
+
+		require.Equal(t, http.StatusCreated, status, served)
+		assert.Equal(t, "TTest3", resp["wallet"], "a test client served from %s gets test wallets", served)
+		assert.Equal(t, false, resp["livemode"], served)
+	}
+}
+
+func TestCreatePayment_TestModeDisabled(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	test := testClient
+	test.Mode = config.ModeTest
+	store.On("GetClientByAPIKey", mock.Anything, testAPIKey).Return(test, nil)
+	expectAccount(store, nil)
+
+	status, resp := do(t, s, http.MethodPost, "/v1/payments",
+		`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"5"}`, nil)
+
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, codeTestModeDisabled, resp["code"])
+}
+
+func TestCreatePayment_ExpiresIn(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	expectAccount(store, nil)
+	expectInsert(store, "10", t0.Add(2*time.Hour))
+
+	status, resp := do(t, s, http.MethodPost, "/v1/payments",
+		`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"10","expires_in":7200}`, nil)
+
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "2026-03-01T14:00:00Z", resp["expires_at"])
+}
+
+func TestCreatePayment_AccountDefaults(t *testing.T) {
+	accountEndpoint, requestEndpoint := uuid.New(), uuid.New()
+	expiry := int64(600)
+	account := testAccount
+	account.DefaultExpirySeconds = &expiry
+	account.DefaultWebhookEndpointID = repository.UUIDPtr(accountEndpoint)
+
+	testCases := []struct {
+		name         string
+		body         string
+		wantExpiry   time.Time
+		wantEndpoint uuid.UUID
+	}{
+		{"account defaults", `{"account_id":"22222222-2222-2222-2222-222222222222","amount":"5"}`,
+			t0.Add(10 * time.Minute), accountEndpoint},
+		{"request overrides", `{"account_id":"22222222-2222-2222-2222-222222222222","amount":"5","expires_in":120,
+			"webhook_endpoint_id":"` + requestEndpoint.String() + `"}`, t0.Add(2 * time.Minute), requestEndpoint},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store, _ := newTestServer(t)
+			expectClient(store)
+			store.On("GetAccountByIDAndClientID", mock.Anything, mock.Anything).Return(account, nil)
+			if tc.wantEndpoint == requestEndpoint {
+				store.On("GetActiveWebhookEndpoint", mock.Anything, repository.GetActiveWebhookEndpointParams{ID: requestEndpoint, ClientID: testClient.ID}).
+					Return(repository.WebhookEndpoint{ID: requestEndpoint}, nil)
+			}
+			store.On("NextWalletIndex", mock.Anything, "deposit").Return(int64(7), nil)
+			store.On("CreatePayment", mock.Anything, mock.MatchedBy(func(arg repository.CreatePaymentParams) bool {
+				return arg.ExpiresAt.Time.Equal(tc.wantExpiry) && repository.SameUUID(arg.WebhookEndpointID, &tc.wantEndpoint)
+			})).Return(repository.Payment{ID: testPaymentID, ExpiresAt: pgtype.Timestamptz{Time: tc.wantExpiry, Valid: true}}, nil)
+			store.On("CreatePaymentAttempt", mock.Anything, mock.Anything).Return(repository.PaymentAttempt{}, nil)
+			store.On("CreateLog", mock.Anything, mock.Anything).Return(nil)
+
+			status, resp := do(t, s, http.MethodPost, "/v1/payments", tc.body, nil)
+
+			require.Equal(t, http.StatusCreated, status)
+			assert.Equal(t, tc.wantExpiry.Format(time.RFC3339), resp["expires_at"])
+		})
+	}
+}
+
+func TestCreatePayment_InactiveWebhookEndpoint(t *testing.T) {
+	s, store, wallets := newTestServer(t)
+	expectClient(store)
+	expectAccount(store, nil)
+	store.On("GetActiveWebhookEndpoint", mock.Anything, mock.Anything).Return(repository.WebhookEndpoint{}, pgx.ErrNoRows)
+
+	status, resp := do(t, s, http.MethodPost, "/v1/payments",
+		`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"5","webhook_endpoint_id":"`+uuid.NewString()+`"}`, nil)
+
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, map[string]any{"webhook_endpoint_id": "must be an active webhook endpoint"}, resp["fields"])
+	assert.Empty(t, wallets.indexes)
+}
+
+func TestCreatePayment_Token(t *testing.T) {
+	testCases := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{"TRX", `"TRX"`, config.TokenTRX},
+		{"USDT", `"USDT"`, config.TokenUSDT},
+		{"case insensitive", `"trx"`, config.TokenTRX},
+		{"defaults to the first supported token", `""`, config.TokenUSDT},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store, _ := newTestServer(t)
+			expectClient(store)
+			expectAccount(store, nil)
+			expectInsertToken(store, tc.want, "25.5", t0.Add(30*time.Minute))
+
+			status, resp := do(t, s, http.MethodPost, "/v1/payments",
+				`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"25.5","token":`+tc.token+`}`, nil)
+
+			assert.Equal(t, http.StatusCreated, status)
+			assert.Equal(t, tc.want, resp["token"])
+			assert.Equal(t, "25.500000", resp["amount"])
+		})
+	}
+}
+
+func TestCreatePayment_UnsupportedToken(t *testing.T) {
+	s, store, wallets := newTestServer(t)
+	s.payments.SupportedTokens = []string{config.TokenUSDT}
+	expectClient(store)
+
+	status, resp := do(t, s, http.MethodPost, "/v1/payments",
+		`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"25.5","token":"TRX"}`, nil)
+
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, map[string]any{"token": "must be one of USDT"}, resp["fields"])
+	assert.Empty(t, wallets.indexes)
+}
+
+func TestCreatePayment_ValidationErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		body   string
+		fields map[string]any
+	}{
+		{
+			name:   "missing fields",
+			body:   `{}`,
+			fields: map[string]any{"account_id": "is required", "amount": "is required"},
+		},
+		{
+			name: "all fields invalid",
+			body: `{"account_id":"acc-1","amount":"ten","expires_in":30}`,
+			fields: map[string]any{
+				"account_id": "must be a UUID",
+				"amount":     "must be a decimal string",
+				"expires_in": "must be between 60 and 86400 seconds",
+			},
+		},
+		{
+			name:   "zero amount",
+			body:   `{"account_id":"22222222-2222-2222-2222-222222222222","amount":"0"}`,
+			fields: map[string]any{"amount": "must be positive"},
+		},
+		{
+			name:   "too precise",
+			body:   `{"account_id":"22222222-2222-2222-2222-222222222222","amount":"1.0000001"}`,
+			fields: map[string]any{"amount": "must have at most 6 decimal places"},
+		},
+		{
+			name:   "below minimum",
+			body:   `{"account_id":"22222222-2222-2222-2222-222222222222","amount":"0.5"}`,
+			fields: map[string]any{"amount": "must be at least 1"},
+		},
+		{
+			name:   "above maximum",
+			body:   `{"account_id":"22222222-2222-2222-2222-222222222222","amount":"10000.01"}`,
+			fields: map[string]any{"amount": "must be at most 10000"},
+		},
+		{
+			name:   "unknown token",
+			body:   `{"account_id":"22222222-2222-2222-2222-222222222222","amount":"5","token":"DOGE"}`,
+			fields: map[string]any{"token": "must be one of USDT, TRX"},
+		},
+		{
+			name:   "unknown token and bad amount",
+			body:   `{"account_id":"22222222-2222-2222-2222-222222222222","amount":"1.0000001","token":"DOGE"}`,
+			fields: map[string]any{"token": "must be one of USDT, TRX", "amount": "must have at most 6 decimal places"},
+		},
+		{
+			name: "bad order reference and metadata",
+			body: `{"account_id":"22222222-2222-2222-2222-222222222222","amount":"5","order_reference":"` + strings.Repeat("x", 201) + `","metadata":[1]}`,
+			fields: map[string]any{
+				"order_reference": "must be at most 200 bytes",
+				"metadata":        "must be an object",
+			},
+		},
+		{
+			name:   "padded order reference",
+			body:   `{"account_id":"22222222-2222-2222-2222-222222222222","amount":"5","order_reference":" order-1"}`,
+			fields: map[string]any{"order_reference": "must not start or end with whitespace"},
+		},
+		{
+			name:   "metadata too large",
+			body:   `{"account_id":"22222222-2222-2222-2222-222222222222","amount":"5","metadata":{"note":"` + strings.Repeat("a", payments.MaxMetadataBytes) + `"}}`,
+			fields: map[string]any{"metadata": "must be at most 8192 bytes"},
+		},
+		{
+			name:   "expiry too long",
+			body:   `{"account_id":"22222222-2222-2222-2222-222222222222","amount":"5","expires_in":86401}`,
+			fields: map[string]any{"expires_in": "must be between 60 and 86400 seconds"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store, wallets := newTestServer(t)
+			expectClient(store)
+
+			status, resp := do(t, s, http.MethodPost, "/v1/payments", tc.body, nil)
+
+			assert.Equal(t, http.StatusBadRequest, status)
+			assert.Equal(t, codeValidationFailed, resp["code"])
+			assert.Equal(t, tc.fields, resp["fields"])
+			assert.Empty(t, wallets.indexes)
+		})
+	}
+}
+
+func TestCreatePayment_InvalidJSON(t *testing.T) {
+	for _, body := range []string{``, `[1]`, `{"amount":5}`} {
+		s, store, _ := newTestServer(t)
+		expectClient(store)
+
+		status, resp := do(t, s, http.MethodPost, "/v1/payments", body, nil)
+
+		assert.Equal(t, http.StatusBadRequest, status, body)
+		assert.Equal(t, codeInvalidJSON, resp["code"], body)
+	}
+}
+
+func TestCreatePayment_UnknownAccount(t *testing.T) {
+	s, store, wallets := newTestServer(t)
+	expectClient(store)
+	expectAccount(store, pgx.ErrNoRows)
+
+	status, resp := do(t, s, http.MethodPost, "/v1/payments",
+		`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"25"}`, nil)
+
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, codeAccountNotFound, resp["code"])
+	assert.Empty(t, wallets.indexes)
+}
+
+func TestCreatePayment_AccountDeletedMeanwhile(t *testing.T) {
+	s, store, wallets := newTestServer(t)
+	expectClient(store)
+	params := repository.GetAccountByIDAndClientIDParams{ID: testAccount.ID, ClientID: testClient.ID}
+	store.On("GetAccountByIDAndClientID", mock.Anything, params).Return(testAccount, nil).Once()
+	store.On("GetAccountByIDAndClientID", mock.Anything, params).Return(repository.Account{}, pgx.ErrNoRows).Once()
+
+	status, resp := do(t, s, http.MethodPost, "/v1/payments",
+		`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"25"}`, nil)
+
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, codeAccountNotFound, resp["code"])
+	assert.Empty(t, wallets.indexes)
+	store.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything)
+}
+
+func TestCreatePayment_MaxActivePerAccount(t *testing.T) {
+	t.Run("under the limit", func(t *testing.T) {
+		s, store, wallets := newTestServer(t)
+		s.payments.MaxActivePerAccount = 2
+		expectClient(store)
+		expectAccount(store, nil)
+		store.On("CountPendingPaymentsByAccountID", mock.Anything, testAccount.ID).Return(int64(1), nil)
+		expectInsert(store, "25", t0.Add(30*time.Minute))
+
+		status, _ := do(t, s, http.MethodPost, "/v1/payments",
+			`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"25"}`, nil)
+
+		assert.Equal(t, http.StatusCreated, status)
+		assert.Equal(t, []uint32{7}, wallets.indexes)
+	})
+
+	t.Run("at the limit", func(t *testing.T) {
+		s, store, wallets := newTestServer(t)
+		s.payments.MaxActivePerAccount = 2
+		expectClient(store)
+		expectAccount(store, nil)
+		store.On("CountPendingPaymentsByAccountID", mock.Anything, testAccount.ID).Return(int64(2), nil)
+
+		status, resp := do(t, s, http.MethodPost, "/v1/payments",
+			`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"25"}`, nil)
+
+		assert.Equal(t, http.StatusTooManyRequests, status)
+		assert.Equal(t, codeTooManyOpenPayments, resp["code"])
+		assert.Empty(t, wallets.indexes)
+		store.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything)
+	})
+}
+
+func TestCreatePayment_TransactionFails(t *testing.T) {
+	testCases := []struct {
+		name  string
+		setup func(*mockStore, *stubWallets)
+	}{
+		{"commit fails", func(store *mockStore, _ *stubWallets) {
+			store.txErr = errors.New("failed to commit transaction: conflict")
+		}},
+		{"index allocation fails", func(store *mockStore, _ *stubWallets) {
+			store.On("NextWalletIndex", mock.Anything, "deposit").Return(int64(0), errors.New("boom"))
+		}},
+		{"index out of range", func(store *mockStore, _ *stubWallets) {
+			store.On("NextWalletIndex", mock.Anything, "deposit").Return(int64(1)<<32, nil)
+		}},
+		{"derivation fails", func(store *mockStore, wallets *stubWallets) {
+			store.On("NextWalletIndex", mock.Anything, "deposit").Return(int64(7), nil)
+			wallets.err = errors.New("bad mnemonic")
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store, wallets := newTestServer(t)
+			expectClient(store)
+			expectAccount(store, nil)
+			tc.setup(store, wallets)
+
+			status, resp := do(t, s, http.MethodPost, "/v1/payments",
+				`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"25"}`, nil)
+
+			assert.Equal(t, http.StatusInternalServerError, status)
+			assert.Equal(t, apierror.CodeInternal, resp["code"])
+			assert.Equal(t, "internal error", resp["detail"], "the cause is not leaked")
+		})
+	}
+}
+
+func TestCreatePayment_DerivationHalted(t *testing.T) {
+	s, store, wallets := newTestServer(t)
+	expectClient(store)
+	expectAccount(store, nil)
+	store.On("NextWalletIndex", mock.Anything, "deposit").Return(int64(7), nil)
+	wallets.err = payments.ErrDerivationHalted
+
+	status, resp := do(t, s, http.MethodPost, "/v1/payments",
+		`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"25"}`, nil)
+
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, codeDerivationHalted, resp["code"])
+	store.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything)
+}
+
+func TestCreatePayment_DuplicateWallet(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	expectAccount(store, nil)
+	store.On("NextWalletIndex", mock.Anything, "deposit").Return(int64(7), nil)
+	store.On("CreatePayment", mock.Anything, mock.Anything).
+		Return(repository.Payment{}, repository.ErrDuplicateWallet)
+
+	status, resp := do(t, s, http.MethodPost, "/v1/payments",
+		`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"25"}`, nil)
+
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, apierror.CodeDuplicateWallet, resp["code"])
+}
+
+// expectSuffixAccount expects testAccount to be loaded as an amount_suffix
+// account whose deposit wallet is TWallet1.
+func expectSuffixAccount(store *mockStore) {
+	wallet, index := "TWallet1", int64(1)
+	account := testAccount
+	account.AddressStrategy = payments.StrategyAmountSuffix
+	account.DepositWallet, account.DepositWalletIndex = &wallet, &index
+	store.On("GetAccountByIDAndClientID", mock.Anything, repository.GetAccountByIDAndClientIDParams{
+		ID:       testAccount.ID,
+		ClientID: testClient.ID,
+	}).Return(account, nil)
+}
+
+func TestCreatePayment_AmountSuffix(t *testing.T) {
+	s, store, wallets := newTestServer(t)
+	expectClient(store)
+	expectSuffixAccount(store)
+	store.On("ReserveAmountSuffix", mock.Anything, mock.MatchedBy(func(arg repository.ReserveAmountSuffixParams) bool {
+		return arg.AccountID == testAccount.ID && arg.Now.Time.Equal(t0)
+	})).Return(int32(3), nil)
+	store.On("CreatePayment", mock.Anything, mock.MatchedBy(func(arg repository.CreatePaymentParams) bool {
+		return numericToString(arg.Amount) == "25.500003" && arg.UniqueWallet == "TWallet1" && arg.WalletIndex == nil
+	})).Return(repository.Payment{
+		ID:           testPaymentID,
+		UniqueWallet: "TWallet1",
+		Token:        config.TokenUSDT,
+		Amount:       repository.DecimalToNumeric(decimal.RequireFromString("25.500003")),
+		Status:       "PENDING",
+		ExpiresAt:    pgtype.Timestamptz{Time: t0.Add(30 * time.Minute), Valid: true},
+	}, nil)
+	store.On("CreatePaymentAttempt", mock.Anything, mock.Anything).Return(repository.PaymentAttempt{}, nil)
+	store.On("CreateLog", mock.Anything, mock.Anything).Return(nil)
+
+	status, resp := do(t, s, http.MethodPost, "/v1/payments",
+		`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"25.5"}`, nil)
+
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "TWallet1", resp["wallet"])
+	assert.Equal(t, "25.500003", resp["amount"], "the amount to pay carries the suffix")
+	assert.Empty(t, wallets.indexes, "the deposit wallet is derived once")
+}
+
+func TestCreatePayment_AmountSuffixErrors(t *testing.T) {
+	t.Run("too precise", func(t *testing.T) {
+		s, store, _ := newTestServer(t)
+		expectClient(store)
+		expectSuffixAccount(store)
+
+		status, resp := do(t, s, http.MethodPost, "/v1/payments",
+			`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"25.0001"}`, nil)
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, codeValidationFailed, resp["code"])
+		assert.Equal(t, map[string]any{"amount": "must have at most 3 decimal places for an amount_suffix account"}, resp["fields"])
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		s, store, _ := newTestServer(t)
+		expectClient(store)
+		expectSuffixAccount(store)
+		store.On("ReserveAmountSuffix", mock.Anything, mock.Anything).Return(int32(0), pgx.ErrNoRows)
+
+		status, resp := do(t, s, http.MethodPost, "/v1/payments",
+			`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"25"}`, nil)
+
+		assert.Equal(t, http.StatusConflict, status)
+		assert.Equal(t, codeSuffixesExhausted, resp["code"])
+		store.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything)
+	})
+}
+
+func TestCreatePayment_WalletActivation(t *testing.T) {
+	testCases := []struct {
+		name       string
+		checker    fakeActivation
+		activation map[string]any
+	}{
+		{"activated", fakeActivation{activated: true}, map[string]any{"activated": true}},
+		{"not activated", fakeActivation{}, map[string]any{
+			"activated": false,
+			"warning":   "deposit address is not activated; a USDT transfer to it costs the sender about twice the usual energy",
+		}},
+		{"node unreachable", fakeActivation{err: errors.New("timeout")}, map[string]any{"activated": nil}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store, _ := newTestServer(t, WithActivationChecker(tc.checker))
+			expectClient(store)
+			expectAccount(store, nil)
+			expectInsert(store, "25", t0.Add(30*time.Minute))
+
+			status, resp := do(t, s, http.MethodPost, "/v1/payments",
+				`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"25"}`, nil)
+
+			require.Equal(t, http.StatusCreated, status)
+			assert.Equal(t, tc.activation, resp["wallet_activation"])
+		})
+	}
+}
+
+func TestCreatePayment_WalletActivationTRX(t *testing.T) {
+	s, store, _ := newTestServer(t, WithActivationChecker(fakeActivation{}))
+	expectClient(store)
+	expectAccount(store, nil)
+	expectInsertToken(store, config.TokenTRX, "25", t0.Add(30*time.Minute))
+
+	status, resp := do(t, s, http.MethodPost, "/v1/payments",
+		`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"25","token":"TRX"}`, nil)
+
+	require.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, map[string]any{
+		"activated": false,
+		"warning":   "deposit address is not activated; the sender pays an extra 1.1 TRX to activate it",
+	}, resp["wallet_activation"], "the warning is about the payment's token")
+}
+
+func storedPayment() repository.Payment {
+	attempts := int32(2)
+	currency := "USD"
+	return repository.Payment{
+		ID:           testPaymentID,
+		ClientID:     testClient.ID,
+		AccountID:    testAccount.ID,
+		Amount:       repository.DecimalToNumeric(decimal.RequireFromString("102.669405")),
+		UniqueWallet: "TWallet7",
+		Token:        "USDT",
+		Status:       "CONFIRMED",
+		ExpiresAt:    pgtype.Timestamptz{Time: t0.Add(30 * time.Minute), Valid: true},
+		ConfirmedAt:  pgtype.Timestamptz{Time: t0.Add(10 * time.Minute), Valid: true},
+		AttemptCount: &attempts,
+		CreatedAt:    pgtype.Timestamptz{Time: t0, Valid: true},
+		FiatAmount:   repository.DecimalToNumeric(decimal.RequireFromString("25.00")),
+		FiatCurrency: &currency,
+		ExchangeRate: repository.DecimalToNumeric(decimal.RequireFromString("0.2435")),
+		RateAt:       pgtype.Timestamptz{Time: t0.Add(-time.Minute), Valid: true},
+	}
+}
+
+func TestCreatePayment_IssuesStatusToken(t *testing.T) {
+	tokens := NewStatusTokens("secret", time.Hour)
+	s, store, _ := newTestServer(t, WithStatusTokens(tokens))
+	expectClient(store)
+	expectAccount(store, nil)
+	expectInsert(store, "25", t0.Add(30*time.Minute))
+
+	status, resp := do(t, s, http.MethodPost, "/v1/payments",
+		`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"25"}`, nil)
+
+	require.Equal(t, http.StatusCreated, status)
+	token, _ := resp["status_token"].(string)
+	assert.NoError(t, tokens.Verify(testPaymentID, token, t0.Add(30*time.Minute)))
+}
+
+func TestPaymentOrderReference(t *testing.T) {
+	s, store := newFakeServer(t)
+	fallback := repository.NewMockQuerier(t)
+	fallback.On("CreatePaymentAttempt", mock.Anything, mock.Anything).Return(repository.PaymentAttempt{}, nil)
+	store.Querier = fallback
+	body := `{"account_id":"` + testAccount.ID.String() + `","amount":"25","order_reference":"order-1001","metadata":{ "sku": "A-1" }}`
+
+	status, created := do(t, s, http.MethodPost, "/v1/payments", body, nil)
+	require.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "order-1001", created["order_reference"])
+	assert.Equal(t, map[string]any{"sku": "A-1"}, created["metadata"])
+
+	status, resp := do(t, s, http.MethodPost, "/v1/payments", body, nil)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, apierror.CodeDuplicateOrderReference, resp["code"])
+
+	status, got := do(t, s, http.MethodGet, "/v1/payments/"+created["id"].(string), "", nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "order-1001", got["order_reference"])
+	assert.Equal(t, map[string]any{"sku": "A-1"}, got["metadata"])
+
+	status, found := do(t, s, http.MethodGet, "/v1/payments/by-order-reference/order-1001", "", nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, got, found)
+
+	status, resp = do(t, s, http.MethodGet, "/v1/payments/by-order-reference/order-1002", "", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, codePaymentNotFound, resp["code"])
+}
+
+func TestGetPaymentByOrderReference_OtherClient(t *testing.T) {
+	s, store := newFakeServer(t)
+	other := otherClientAccount(t, store)
+	ref := "order-1001"
+	_, err := store.CreatePayment(context.Background(), repository.CreatePaymentParams{
+		ClientID: other.ClientID, AccountID: other.ID, UniqueWallet: "TWallet9", Token: "USDT", Mode: "live", OrderReference: &ref,
+	})
+	require.NoError(t, err)
+
+	status, resp := do(t, s, http.MethodGet, "/v1/payments/by-order-reference/order-1001", "", nil)
+
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, codePaymentNotFound, resp["code"])
+}
+
+func TestGetPayment(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(storedPayment(), nil)
+
+	status, resp := do(t, s, http.MethodGet, "/v1/payments/"+testPaymentID.String(), "", nil)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]any{
+		"id":            testPaymentID.String(),
+		"account_id":    testAccount.ID.String(),
+		"wallet":        "TWallet7",
+		"token":         "USDT",
+		"amount":        "102.669405",
+		"status":        "CONFIRMED",
+		"expires_at":    "2026-03-01T12:30:00Z",
+		"confirmed_at":  "2026-03-01T12:10:00Z",
+		"attempt_count": float64(2),
+		"livemode":      true,
+		"created_at":    "2026-03-01T12:00:00Z",
+		"fiat_amount":   "25",
+		"fiat_currency": "USD",
+		"exchange_rate": "0.2435",
+		"rate_at":       "2026-03-01T11:59:00Z",
+	}, resp)
+}
+
+func TestGetPayment_Pending(t *testing.T) {
+	tokens := NewStatusTokens("secret", time.Hour)
+	s, store, _ := newTestServer(t, WithStatusTokens(tokens))
+	expectClient(store)
+	payment := storedPayment()
+	payment.Status, payment.ConfirmedAt = "PENDING", pgtype.Timestamptz{}
+	payment.FiatAmount, payment.FiatCurrency, payment.ExchangeRate, payment.RateAt =
+		pgtype.Numeric{}, nil, pgtype.Numeric{}, pgtype.Timestamptz{}
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(payment, nil)
+
+	status, resp := do(t, s, http.MethodGet, "/v1/payments/"+testPaymentID.String(), "", nil)
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Nil(t, resp["confirmed_at"])
+	assert.NotContains(t, resp, "fiat_amount")
+	assert.Equal(t, tokens.Issue(testPaymentID, payment.ExpiresAt.Time), resp["status_token"])
+}
+
+func TestGetPayment_NotFound(t *testing.T) {
+	otherClient := storedPayment()
+	otherClient.ClientID = uuid.New()
+
+	testCases := []struct {
+		name  string
+		id    string
+		setup func(*mockStore)
+	}{
+		{"unknown id", testPaymentID.String(), func(store *mockStore) {
+			store.On("GetPayment", mock.Anything, testPaymentID).Return(repository.Payment{}, pgx.ErrNoRows)
+		}},
+		{"other client's payment", testPaymentID.String(), func(store *mockStore) {
+			store.On("GetPayment", mock.Anything, testPaymentID).Return(otherClient, nil)
+		}},
+		{"malformed id", "pay_1", func(*mockStore) {}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store, _ := newTestServer(t)
+			expectClient(store)
+			tc.setup(store)
+
+			status, resp := do(t, s, http.MethodGet, "/v1/payments/"+tc.id, "", nil)
+
+			assert.Equal(t, http.StatusNotFound, status)
+			assert.Equal(t, codePaymentNotFound, resp["code"])
+		})
+	}
+}
+
+func TestGetPayment_QueryTimeout(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).
+		Return(repository.Payment{}, fmt.Errorf("%w: timeout: context deadline exceeded", repository.ErrQueryTimeout))
+
+	status, resp := do(t, s, http.MethodGet, "/v1/payments/"+testPaymentID.String(), "", nil)
+
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, apierror.CodeQueryTimeout, resp["code"])
+}
+
+func TestGetPayment_RequiresAPIKey(t *testing.T) {
+	s, _, _ := newTestServer(t)
+
+	status, _ := do(t, s, http.MethodGet, "/v1/payments/"+testPaymentID.String(), "", http.Header{})
+
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestGetPublicPayment(t *testing.T) {
+	tokens := NewStatusTokens("secret", time.Hour)
+	s, store, _ := newTestServer(t, WithStatusTokens(tokens))
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(storedPayment(), nil)
+	token := tokens.Issue(testPaymentID, t0.Add(30*time.Minute))
+
+	status, resp := do(t, s, http.MethodGet, "/v1/public/payments/"+testPaymentID.String()+"?token="+token, "", http.Header{})
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]any{
+		"status":     "CONFIRMED",
+		"token":      "USDT",
+		"amount":     "102.669405",
+		"wallet":     "TWallet7",
+		"expires_at": "2026-03-01T12:30:00Z",
+	}, resp, "only the checkout fields are exposed")
+}
+
+func TestGetPublicPayment_InvalidToken(t *testing.T) {
+	tokens := NewStatusTokens("secret", time.Hour)
+	valid := tokens.Issue(testPaymentID, t0.Add(30*time.Minute))
+
+	testCases := []struct {
+		name  string
+		id    string
+		token string
+		now   time.Time
+	}{
+		{"missing token", testPaymentID.String(), "", t0},
+		{"token for another payment", uuid.New().String(), valid, t0},
+		{"forged token", testPaymentID.String(), NewStatusTokens("guess", time.Hour).Issue(testPaymentID, t0), t0},
+		{"expired token", testPaymentID.String(), valid, t0.Add(90 * time.Minute)},
+		{"malformed id", "pay_1", valid, t0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, _, _ := newTestServer(t, WithStatusTokens(tokens))
+			s.now = func() time.Time { return tc.now }
+
+			status, resp := do(t, s, http.MethodGet, "/v1/public/payments/"+tc.id+"?token="+tc.token, "", http.Header{})
+
+			assert.Equal(t, http.StatusNotFound, status, "404, not 401, so ids cannot be probed")
+			assert.Equal(t, codePaymentNotFound, resp["code"])
+		})
+	}
+}
+
+func TestGetPublicPayment_DisabledWithoutTokens(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	rec := httptest.NewRecorder()
+
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/public/payments/"+testPaymentID.String()+"?token=x", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet/qr"
+)
+
+// paymentURI returns the tron: URI a wallet app scans to pay payment. An
+// underpaid payment's URI has no amount: the payer sends the rest, which
+// the payment does not record.
+func (s *Server) paymentURI(payment repository.Payment) (string, error) {
+	token := payment.Token
+	if token == config.TokenUSDT {
+		token = s.usdtContract
+		if payment.Mode == config.ModeTest {
+			token = s.testUSDTContract
+		}
+	}
+	amount := payment.Amount
+	if payment.Status == repository.PaymentUnderpaid {
+		amount = pgtype.Numeric{}
+	}
+	uri, err := wallet.PaymentURI(payment.UniqueWallet, amount, token)
+	if err != nil {
+		return "", fmt.Errorf("failed to build payment URI: %w", err)
+	}
+	return uri, nil
+}
+
+// paymentQR returns a PNG QR code of payment's URI, size pixels wide.
+func (s *Server) paymentQR(payment repository.Payment, size int) ([]byte, error) {
+	uri, err := s.paymentURI(payment)
+	if err != nil {
+		return nil, err
+	}
+	return qr.PNG(uri, qr.WithSize(size))
+}
+
+// addQR sets rec's QR code, for a payment that can still be paid.
+func (s *Server) addQR(rec *paymentRecord, payment repository.Payment) error {
+	if repository.IsTerminalPaymentStatus(payment.Status) {
+		return nil
+	}
+	png, err := s.paymentQR(payment, qr.DefaultSize)
+	if err != nil {
+		return err
+	}
+	rec.QRPNGBase64 = base64.StdEncoding.EncodeToString(png)
+	return nil
+}
+
+// includeQR reads the include_qr query parameter, writing a 400 when it is
+// not a boolean.
+func includeQR(w http.ResponseWriter, r *http.Request) (include, ok bool) {
+	v := r.URL.Query().Get("include_qr")
+	if v == "" {
+		return false, true
+	}
+	include, err := strconv.ParseBool(v)
+	if err != nil {
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusBadRequest,
+			Code:       codeValidationFailed,
+			Message:    "request has invalid parameters",
+			Fields:     map[string]string{"include_qr": "must be true or false"},
+		})
+		return false, false
+	}
+	return include, true
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/png"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet/qr"
+)
+
+func TestPaymentURI(t *testing.T) {
+	const shastaUSDT = "TG3XXyExBkPp9nzdajDZsozEu4BkaSJozs"
+	cfg := testConfig()
+	cfg.TestMode.Tron.USDTContract = shastaUSDT
+	s := New(&mockStore{}, &stubWallets{}, cfg)
+
+	trx := checkoutPayment(repository.PaymentPending)
+	trx.Token = config.TokenTRX
+	test := checkoutPayment(repository.PaymentPending)
+	test.Mode = config.ModeTest
+
+	testCases := []struct {
+		name    string
+		payment repository.Payment
+		want    string
+	}{
+		{"USDT", checkoutPayment(repository.PaymentPending),
+			"tron:" + depositWallet + "?amount=102.669405&token=" + testUSDTContract},
+		{"TRX", trx, "tron:" + depositWallet + "?amount=102.669405"},
+		{"test mode", test, "tron:" + depositWallet + "?amount=102.669405&token=" + shastaUSDT},
+		{"underpaid", checkoutPayment(repository.PaymentUnderpaid), "tron:" + depositWallet + "?token=" + testUSDTContract},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			uri, err := s.paymentURI(tc.payment)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, uri)
+		})
+	}
+}
+
+func TestGetPayment_IncludeQR(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(checkoutPayment(repository.PaymentPending), nil)
+
+	status, resp := do(t, s, http.MethodGet, "/v1/payments/"+testPaymentID.String()+"?include_qr=true", "", nil)
+
+	require.Equal(t, http.StatusOK, status)
+	raw, err := base64.StdEncoding.DecodeString(resp["qr_png_base64"].(string))
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(raw))
+	require.NoError(t, err)
+	assert.Equal(t, qr.DefaultSize, img.Bounds().Dx())
+}
+
+func TestGetPayment_QROmitted(t *testing.T) {
+	testCases := map[string]struct {
+		query  string
+		status string
+	}{
+		"not asked for":    {"", repository.PaymentPending},
+		"asked not to":     {"?include_qr=false", repository.PaymentPending},
+		"terminal payment": {"?include_qr=true", repository.PaymentConfirmed},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s, store, _ := newTestServer(t)
+			expectClient(store)
+			store.On("GetPayment", mock.Anything, testPaymentID).Return(checkoutPayment(tc.status), nil)
+
+			status, resp := do(t, s, http.MethodGet, "/v1/payments/"+testPaymentID.String()+tc.query, "", nil)
+
+			require.Equal(t, http.StatusOK, status)
+			assert.NotContains(t, resp, "qr_png_base64")
+		})
+	}
+}
+
+func TestGetPaymentByOrderReference_IncludeQR(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("GetPaymentByOrderReference", mock.Anything, repository.GetPaymentByOrderReferenceParams{
+		ClientID: testClient.ID, OrderReference: "order-8841",
+	}).Return(checkoutPayment(repository.PaymentDetected), nil)
+
+	status, resp := do(t, s, http.MethodGet, "/v1/payments/by-order-reference/order-8841?include_qr=1", "", nil)
+
+	require.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, resp["qr_png_base64"])
+}
+
+func TestGetPayment_InvalidIncludeQR(t *testing.T) {
+	for _, path := range []string{
+		"/v1/payments/" + testPaymentID.String() + "?include_qr=maybe",
+		"/v1/payments/by-order-reference/order-8841?include_qr=maybe",
+	} {
+		s, store, _ := newTestServer(t)
+		expectClient(store)
+
+		status, resp := do(t, s, http.MethodGet, path, "", nil)
+
+		assert.Equal(t, http.StatusBadRequest, status, path)
+		assert.Equal(t, codeValidationFailed, resp["code"])
+		assert.Equal(t, map[string]any{"include_qr": "must be true or false"}, resp["fields"])
+	}
+}
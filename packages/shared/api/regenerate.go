@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/requestid"
+)
+
+// EventWalletRegenerated is logged when a payment is given a new deposit
+// wallet.
+const EventWalletRegenerated = "WALLET_REGENERATED"
+
+// errWalletShared aborts the regeneration of a payment paid into the deposit
+// wallet of an amount_suffix account.
+var errWalletShared = errors.New("payment wallet is shared")
+
+type walletRegeneratedLog struct {
+	PreviousWallet string `json:"previous_wallet"`
+	Wallet         string `json:"wallet"`
+	WalletIndex    int64  `json:"wallet_index"`
+	Attempt        int32  `json:"attempt"`
+}
+
+// regenerateWallet handles POST /v1/payments/{id}/regenerate: it gives a
+// PENDING payment the next deposit wallet, up to payments.maxWalletAttempts
+// wallets per payment. The earlier wallets stay recorded as payment
+// attempts, and the watcher keeps crediting transfers to them until the
+// payment expires. The new wallet, its attempt and a WALLET_REGENERATED log
+// are written in one transaction. A payment paid into the deposit wallet of
+// an amount_suffix account is told apart by its amount, not its wallet, and
+// is never given another.
+func (s *Server) regenerateWallet(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	client := clientFrom(ctx)
+	payment, ok := s.lookupPayment(w, r, s.tenant(ctx).GetPayment)
+	if !ok {
+		return
+	}
+	if !s.checkRegenerable(w, r, payment) {
+		return
+	}
+
+	wallets, ok := s.walletsFor(payment.Mode)
+	if !ok {
+		apierror.Write(w, r, apierror.New(http.StatusForbidden, codeTestModeDisabled, "test mode is not enabled"))
+		return
+	}
+
+	var attempts int32
+	if payment.AttemptCount != nil {
+		attempts = *payment.AttemptCount
+	}
+	// A payment created before attempts were counted has had one wallet.
+	attempt := max(attempts, 1) + 1
+	var updated repository.Payment
+	err := repository.RetryTx(ctx, s.store, func(q repository.Querier) error {
+		current, err := repository.NewTenantQueries(q, client.ID).GetPayment(ctx, payment.ID)
+		if err != nil {
+			return fmt.Errorf("failed to load payment: %w", err)
+		}
+		shared, err := q.ListDepositWallets(ctx, []string{current.UniqueWallet})
+		if err != nil {
+			return fmt.Errorf("failed to look up deposit wallet: %w", err)
+		}
+		if len(shared) > 0 {
+			return errWalletShared
+		}
+		wallet, index, err := payments.AllocateWallet(ctx, q, wallets, payment.Mode)
+		if err != nil {
+			return err
+		}
+		// Compared with the count read, so of two concurrent regenerations
+		// only one moves the payment on.
+		updated, err = q.UpdatePaymentWallet(ctx, repository.UpdatePaymentWalletParams{
+			UniqueWallet: wallet,
+			WalletIndex:  &index,
+			ID:           payment.ID,
+			AttemptCount: attempts,
+			Version:      current.Version,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := q.CreatePaymentAttempt(ctx, repository.CreatePaymentAttemptParams{
+			AttemptNumber:   attempt,
+			PaymentID:       payment.ID,
+			GeneratedWallet: wallet,
+			WalletIndex:     &index,
+		}); err != nil {
+			return fmt.Errorf("failed to insert payment attempt: %w", err)
+		}
+		updated.AttemptCount = &attempt
+
+		raw, err := json.Marshal(walletRegeneratedLog{
+			PreviousWallet: payment.UniqueWallet,
+			Wallet:         wallet,
+			WalletIndex:    index,
+			Attempt:        attempt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode log data: %w", err)
+		}
+		msg := fmt.Sprintf("deposit wallet %s generated at index %d, replacing %s", wallet, index, payment.UniqueWallet)
+		actor := actorFrom(ctx)
+		if err := q.CreateLog(ctx, repository.CreateLogParams{
+			PaymentID: repository.UUIDPtr(payment.ID),
+			EventType: EventWalletRegenerated,
+			Message:   &msg,
+			RawData:   raw,
+			RequestID: requestid.Ptr(ctx),
+			Actor:     &actor,
+		}); err != nil {
+			return fmt.Errorf("failed to write %s log: %w", EventWalletRegenerated, err)
+		}
+		return nil
+	})
+	if errors.Is(err, repository.ErrPaymentStatusChanged) {
+		// The payment moved on after it was read, or another request gave it
+		// a wallet first.
+		apierror.Write(w, r, apierror.New(http.StatusConflict, codePaymentNotPending, "the payment changed while its wallet was regenerated"))
+		return
+	}
+	if errors.Is(err, errWalletShared) {
+		apierror.Write(w, r, apierror.New(http.StatusConflict, codePaymentWalletShared,
+			"the payment is paid into the deposit wallet its account's other payments share"))
+		return
+	}
+	if errors.Is(err, payments.ErrDerivationHalted) {
+		s.derivationHalted(w, r)
+		return
+	}
+	if err != nil {
+		s.internalError(w, r, "failed to regenerate wallet", err, "payment_id", payment.ID)
+		return
+	}
+	s.logger.InfoContext(ctx, "wallet regenerated", "payment_id", payment.ID, "client_id", client.ID,
+		"previous_wallet", payment.UniqueWallet, "wallet", updated.UniqueWallet, "attempt", attempt)
+	writeJSON(w, http.StatusOK, s.paymentRecord(updated))
+}
+
+// checkRegenerable writes a 409 and returns false unless payment may be
+// given another wallet.
+func (s *Server) checkRegenerable(w http.ResponseWriter, r *http.Request, payment repository.Payment) bool {
+	switch {
+	case payment.Status != repository.PaymentPending:
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusConflict,
+			Code:       codePaymentNotPending,
+			Message:    fmt.Sprintf("a %s payment cannot be given another wallet", payment.Status),
+		})
+	case !s.now().Before(payment.ExpiresAt.Time):
+		apierror.Write(w, r, apierror.New(http.StatusConflict, codePaymentNotPending, "the payment has expired"))
+	case payment.AttemptCount != nil && int(*payment.AttemptCount) >= s.payments.MaxWalletAttempts:
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusConflict,
+			Code:       codeWalletAttemptsUsed,
+			Message:    fmt.Sprintf("the payment has had %d of %d wallets", *payment.AttemptCount, s.payments.MaxWalletAttempts),
+		})
+	default:
+		return true
+	}
+	return false
+}
+
+// derivationHalted writes the 503 of a request that needed a new deposit
+// wallet while the API serves read-only.
+func (s *Server) derivationHalted(w http.ResponseWriter, r *http.Request) {
+	apierror.Write(w, r, apierror.New(http.StatusServiceUnavailable, codeDerivationHalted,
+		"new deposit wallets cannot be generated right now"))
+}
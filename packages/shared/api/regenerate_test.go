@@ -0,0 +1,285 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+)
+
+var regeneratePath = "/v1/payments/" + testPaymentID.String() + "/regenerate"
+
+// pendingPayment is a PENDING payment that has had attempts wallets.
+func pendingPayment(attempts int32) repository.Payment {
+	p := paymentIn(repository.PaymentPending)
+	p.AttemptCount = &attempts
+	return p
+}
+
+// expectOwnWallet expects TWallet7 to be looked up and found to be no
+// account's deposit wallet.
+func expectOwnWallet(store *mockStore) {
+	store.On("ListDepositWallets", mock.Anything, []string{"TWallet7"}).Return([]string{}, nil)
+}
+
+// expectRegenerate expects the payment to move from TWallet7 to the wallet
+// at index 8, as its attempt after attempts; a payment created before
+// attempts were counted has had one.
+func expectRegenerate(store *mockStore, attempts int32) {
+	index := int64(8)
+	updated := pendingPayment(attempts)
+	updated.UniqueWallet, updated.WalletIndex = "TWallet8", &index
+	expectOwnWallet(store)
+	store.On("NextWalletIndex", mock.Anything, "deposit").Return(index, nil).Once()
+	store.On("UpdatePaymentWallet", mock.Anything, repository.UpdatePaymentWalletParams{
+		UniqueWallet: "TWallet8",
+		WalletIndex:  &index,
+		ID:           testPaymentID,
+		AttemptCount: attempts,
+	}).Return(updated, nil).Once()
+	store.On("CreatePaymentAttempt", mock.Anything, repository.CreatePaymentAttemptParams{
+		AttemptNumber:   max(attempts, 1) + 1,
+		PaymentID:       testPaymentID,
+		GeneratedWallet: "TWallet8",
+		WalletIndex:     &index,
+	}).Return(repository.PaymentAttempt{}, nil).Once()
+}
+
+func TestRegenerateWallet(t *testing.T) {
+	s, store, wallets := newTestServer(t)
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(pendingPayment(1), nil)
+	expectRegenerate(store, 1)
+	store.On("CreateLog", mock.Anything, mock.MatchedBy(func(arg repository.CreateLogParams) bool {
+		var data walletRegeneratedLog
+		return *arg.PaymentID == testPaymentID &&
+			arg.EventType == EventWalletRegenerated &&
+			*arg.Actor == clientActor(testClient.ID) &&
+			json.Unmarshal(arg.RawData, &data) == nil &&
+			data == walletRegeneratedLog{PreviousWallet: "TWallet7", Wallet: "TWallet8", WalletIndex: 8, Attempt: 2}
+	})).Return(nil).Once()
+
+	status, resp := do(t, s, http.MethodPost, regeneratePath, "", nil)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "TWallet8", resp["wallet"])
+	assert.Equal(t, float64(2), resp["attempt_count"])
+	assert.Equal(t, repository.PaymentPending, resp["status"])
+	assert.Equal(t, []uint32{8}, wallets.indexes)
+}
+
+func TestRegenerateWallet_UpToLimit(t *testing.T) {
+	testCases := []struct {
+		name     string
+		limit    int
+		attempts int32
+		allowed  bool
+	}{
+		{"first regeneration", 3, 1, true},
+		{"last regeneration", 3, 2, true},
+		{"limit reached", 3, 3, false},
+		{"past a lowered limit", 2, 3, false},
+		{"single wallet allowed", 1, 1, false},
+		{"raised limit", 5, 4, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store, _ := newTestServer(t)
+			s.payments.MaxWalletAttempts = tc.limit
+			expectClient(store)
+			store.On("GetPayment", mock.Anything, testPaymentID).Return(pendingPayment(tc.attempts), nil)
+			if tc.allowed {
+				expectRegenerate(store, tc.attempts)
+				store.On("CreateLog", mock.Anything, mock.Anything).Return(nil).Once()
+			}
+
+			status, resp := do(t, s, http.MethodPost, regeneratePath, "", nil)
+
+			if tc.allowed {
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, "TWallet8", resp["wallet"])
+				return
+			}
+			assert.Equal(t, http.StatusConflict, status)
+			assert.Equal(t, codeWalletAttemptsUsed, resp["code"])
+			store.AssertNotCalled(t, "NextWalletIndex", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestRegenerateWallet_LegacyPaymentCountsFirstWallet(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(pendingPayment(0), nil)
+	expectRegenerate(store, 0)
+	store.On("CreateLog", mock.Anything, mock.Anything).Return(nil).Once()
+
+	status, resp := do(t, s, http.MethodPost, regeneratePath, "", nil)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, float64(2), resp["attempt_count"], "the wallet it was created with was its first")
+}
+
+func TestRegenerateWallet_NotPending(t *testing.T) {
+	for _, from := range []string{
+		repository.PaymentDetected,
+		repository.PaymentUnderpaid,
+		repository.PaymentConfirmed,
+		repository.PaymentExpired,
+		repository.PaymentReview,
+		repository.PaymentCancelled,
+	} {
+		t.Run(from, func(t *testing.T) {
+			s, store, _ := newTestServer(t)
+			expectClient(store)
+			store.On("GetPayment", mock.Anything, testPaymentID).Return(paymentIn(from), nil)
+
+			status, resp := do(t, s, http.MethodPost, regeneratePath, "", nil)
+
+			assert.Equal(t, http.StatusConflict, status)
+			assert.Equal(t, codePaymentNotPending, resp["code"])
+			store.AssertNotCalled(t, "NextWalletIndex", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestRegenerateWallet_Expired(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	s.now = func() time.Time { return t0.Add(30 * time.Minute) }
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(pendingPayment(1), nil)
+
+	status, resp := do(t, s, http.MethodPost, regeneratePath, "", nil)
+
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, codePaymentNotPending, resp["code"])
+	store.AssertNotCalled(t, "NextWalletIndex", mock.Anything, mock.Anything)
+}
+
+func TestRegenerateWallet_LosesRace(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(pendingPayment(1), nil)
+	expectOwnWallet(store)
+	store.On("NextWalletIndex", mock.Anything, "deposit").Return(int64(8), nil)
+	// Another regeneration, or a transfer, got to the payment first.
+	store.On("UpdatePaymentWallet", mock.Anything, mock.Anything).
+		Return(repository.Payment{}, repository.ErrPaymentStatusChanged)
+
+	status, resp := do(t, s, http.MethodPost, regeneratePath, "", nil)
+
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, codePaymentNotPending, resp["code"])
+	store.AssertNotCalled(t, "CreatePaymentAttempt", mock.Anything, mock.Anything)
+	store.AssertNotCalled(t, "CreateLog", mock.Anything, mock.Anything)
+}
+
+func TestRegenerateWallet_Failures(t *testing.T) {
+	testCases := []struct {
+		name  string
+		setup func(*mockStore, *stubWallets)
+	}{
+		{"transaction fails", func(store *mockStore, _ *stubWallets) {
+			store.txErr = errors.New("connection reset")
+		}},
+		{"derivation fails", func(store *mockStore, wallets *stubWallets) {
+			expectOwnWallet(store)
+			store.On("NextWalletIndex", mock.Anything, "deposit").Return(int64(8), nil)
+			wallets.err = errors.New("bad mnemonic")
+		}},
+		{"attempt insert fails", func(store *mockStore, _ *stubWallets) {
+			expectOwnWallet(store)
+			store.On("NextWalletIndex", mock.Anything, "deposit").Return(int64(8), nil)
+			store.On("UpdatePaymentWallet", mock.Anything, mock.Anything).Return(pendingPayment(1), nil)
+			store.On("CreatePaymentAttempt", mock.Anything, mock.Anything).
+				Return(repository.PaymentAttempt{}, errors.New("connection reset"))
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store, wallets := newTestServer(t)
+			expectClient(store)
+			store.On("GetPayment", mock.Anything, testPaymentID).Return(pendingPayment(1), nil)
+			tc.setup(store, wallets)
+
+			status, resp := do(t, s, http.MethodPost, regeneratePath, "", nil)
+
+			assert.Equal(t, http.StatusInternalServerError, status)
+			assert.Equal(t, apierror.CodeInternal, resp["code"])
+		})
+	}
+}
+
+func TestRegenerateWallet_DerivationHalted(t *testing.T) {
+	s, store, wallets := newTestServer(t)
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(pendingPayment(1), nil)
+	expectOwnWallet(store)
+	store.On("NextWalletIndex", mock.Anything, "deposit").Return(int64(8), nil)
+	wallets.err = payments.ErrDerivationHalted
+
+	status, resp := do(t, s, http.MethodPost, regeneratePath, "", nil)
+
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, codeDerivationHalted, resp["code"])
+	store.AssertNotCalled(t, "UpdatePaymentWallet", mock.Anything, mock.Anything)
+}
+
+func TestRegenerateWallet_DuplicateWallet(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(pendingPayment(1), nil)
+	expectOwnWallet(store)
+	store.On("NextWalletIndex", mock.Anything, "deposit").Return(int64(8), nil)
+	store.On("UpdatePaymentWallet", mock.Anything, mock.Anything).
+		Return(repository.Payment{}, repository.ErrDuplicateWallet)
+
+	status, resp := do(t, s, http.MethodPost, regeneratePath, "", nil)
+
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, apierror.CodeDuplicateWallet, resp["code"])
+}
+
+func TestRegenerateWallet_SharedWallet(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(pendingPayment(1), nil)
+	store.On("ListDepositWallets", mock.Anything, []string{"TWallet7"}).Return([]string{"TWallet7"}, nil)
+
+	status, resp := do(t, s, http.MethodPost, regeneratePath, "", nil)
+
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, codePaymentWalletShared, resp["code"])
+	store.AssertNotCalled(t, "NextWalletIndex", mock.Anything, mock.Anything)
+}
+
+func TestRegenerateWallet_OtherClient(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	p := pendingPayment(1)
+	p.ClientID = uuid.New()
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(p, nil)
+
+	status, resp := do(t, s, http.MethodPost, regeneratePath, "", nil)
+
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, codePaymentNotFound, resp["code"])
+}
+
+func TestRegenerateWallet_RequiresAPIKey(t *testing.T) {
+	s, _, _ := newTestServer(t)
+
+	status, _ := do(t, s, http.MethodPost, regeneratePath, "", http.Header{})
+
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+// Shapes of a search query, and what a result was matched by.
+const (
+	searchPaymentID      = "payment_id"
+	searchWallet         = "wallet"
+	searchTxHash         = "tx_hash"
+	searchOrderReference = "order_reference"
+)
+
+// searchLimit bounds the payments a wallet or transaction lookup returns.
+const searchLimit = 20
+
+// searchResponse is the body of GET /v1/search.
+type searchResponse struct {
+	Query string `json:"query"`
+	// Kind is the shape the query was read as.
+	Kind    string         `json:"kind"`
+	Results []searchResult `json:"results"`
+}
+
+type searchResult struct {
+	MatchedBy string        `json:"matched_by"`
+	Payment   paymentRecord `json:"payment"`
+}
+
+// queryKind tells what q looks like: a payment id, a TRON address, a
+// transaction hash, or failing those an order reference.
+func queryKind(q string) string {
+	if _, err := uuid.Parse(q); err == nil {
+		return searchPaymentID
+	}
+	if wallet.ValidateAddress(q) == nil {
+		return searchWallet
+	}
+	if len(q) == 64 {
+		if _, err := hex.DecodeString(q); err == nil {
+			return searchTxHash
+		}
+	}
+	return searchOrderReference
+}
+
+// search handles GET /v1/search?q=, finding the client's payments by
+// whatever identifier support was given: a payment ID, a deposit wallet
+// address from any attempt, the hash of a transaction paying into or
+// sweeping out of one, or an order reference. Merchants pick their own
+// order references, which may look like any of the others, so every query
+// is tried as one too.
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenant := s.tenant(ctx)
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusBadRequest,
+			Code:       codeValidationFailed,
+			Message:    "request has invalid parameters",
+			Fields:     map[string]string{"q": "is required"},
+		})
+		return
+	}
+
+	kind := queryKind(q)
+	var matched []repository.Payment
+	var err error
+	switch kind {
+	case searchPaymentID:
+		var p repository.Payment
+		p, err = tenant.GetPayment(ctx, uuid.MustParse(q))
+		if err == nil {
+			matched = append(matched, p)
+		}
+	case searchWallet:
+		matched, err = tenant.SearchPaymentsByWallet(ctx, repository.SearchPaymentsByWalletParams{
+			Wallet: q,
+			Limit:  searchLimit,
+		})
+	case searchTxHash:
+		matched, err = tenant.SearchPaymentsByTxHash(ctx, repository.SearchPaymentsByTxHashParams{
+			TxHash: strings.ToLower(q),
+			Limit:  searchLimit,
+		})
+	}
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		s.internalError(w, r, "failed to search payments", err, "kind", kind)
+		return
+	}
+
+	resp := searchResponse{Query: q, Kind: kind, Results: make([]searchResult, 0, len(matched)+1)}
+	seen := make(map[uuid.UUID]bool, len(matched)+1)
+	for _, p := range matched {
+		seen[p.ID] = true
+		resp.Results = append(resp.Results, searchResult{MatchedBy: kind, Payment: s.paymentRecord(p)})
+	}
+
+	p, err := tenant.GetPaymentByOrderReference(ctx, q)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		s.internalError(w, r, "failed to search payments", err, "kind", searchOrderReference)
+		return
+	case !seen[p.ID]:
+		resp.Results = append(resp.Results, searchResult{MatchedBy: searchOrderReference, Payment: s.paymentRecord(p)})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+const searchTx = "4d1ad8bb4a4e5f6a6a2b3f0c5e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d"
+
+func TestQueryKind(t *testing.T) {
+	testCases := []struct {
+		query string
+		want  string
+	}{
+		{testPaymentID.String(), searchPaymentID},
+		{"33333333333333333333333333333333", searchPaymentID},
+		{depositWallet, searchWallet},
+		{searchTx, searchTxHash},
+		{"4D1AD8BB4A4E5F6A6A2B3F0C5E8D7C6B5A4F3E2D1C0B9A8F7E6D5C4B3A2F1E0D", searchTxHash},
+		{"order-8841", searchOrderReference},
+		{"TJRabPrwbZy45sbavfcjinPJC18kjpRTv9", searchOrderReference},
+		{searchTx[:63] + "g", searchOrderReference},
+		{searchTx[:62], searchOrderReference},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			assert.Equal(t, tc.want, queryKind(tc.query))
+		})
+	}
+}
+
+func searchFor(t *testing.T, s *Server, q string) (int, map[string]any) {
+	t.Helper()
+	return do(t, s, http.MethodGet, "/v1/search?q="+url.QueryEscape(q), "", nil)
+}
+
+// noOrderReference expects q to match no order reference.
+func noOrderReference(store *mockStore, q string) {
+	store.On("GetPaymentByOrderReference", mock.Anything, repository.GetPaymentByOrderReferenceParams{
+		ClientID: testClient.ID, OrderReference: q,
+	}).Return(repository.Payment{}, pgx.ErrNoRows)
+}
+
+func resultIDs(t *testing.T, resp map[string]any) map[string]string {
+	t.Helper()
+	ids := make(map[string]string)
+	results, ok := resp["results"].([]any)
+	require.True(t, ok, "results is a list")
+	for _, r := range results {
+		result := r.(map[string]any)
+		ids[result["payment"].(map[string]any)["id"].(string)] = result["matched_by"].(string)
+	}
+	return ids
+}
+
+func TestSearch_PaymentID(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(storedPayment(), nil)
+	noOrderReference(store, testPaymentID.String())
+
+	status, resp := searchFor(t, s, " "+testPaymentID.String()+" ")
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, testPaymentID.String(), resp["query"])
+	assert.Equal(t, searchPaymentID, resp["kind"])
+	assert.Equal(t, map[string]string{testPaymentID.String(): searchPaymentID}, resultIDs(t, resp))
+}
+
+func TestSearch_PaymentIDOfOtherClient(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	other := storedPayment()
+	other.ClientID = uuid.New()
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(other, nil)
+	noOrderReference(store, testPaymentID.String())
+
+	status, resp := searchFor(t, s, testPaymentID.String())
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Empty(t, resultIDs(t, resp))
+}
+
+func TestSearch_UnknownPaymentID(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(repository.Payment{}, pgx.ErrNoRows)
+	noOrderReference(store, testPaymentID.String())
+
+	status, resp := searchFor(t, s, testPaymentID.String())
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []any{}, resp["results"])
+}
+
+func TestSearch_Wallet(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	older := storedPayment()
+	older.ID = uuid.New()
+	store.On("SearchPaymentsByWallet", mock.Anything, repository.SearchPaymentsByWalletParams{
+		ClientID: testClient.ID, Wallet: depositWallet, Limit: searchLimit,
+	}).Return([]repository.Payment{storedPayment(), older}, nil)
+	noOrderReference(store, depositWallet)
+
+	status, resp := searchFor(t, s, depositWallet)
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, searchWallet, resp["kind"])
+	assert.Equal(t, map[string]string{
+		testPaymentID.String(): searchWallet,
+		older.ID.String():      searchWallet,
+	}, resultIDs(t, resp))
+}
+
+func TestSearch_TxHash(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	upper := "4D1AD8BB4A4E5F6A6A2B3F0C5E8D7C6B5A4F3E2D1C0B9A8F7E6D5C4B3A2F1E0D"
+	store.On("SearchPaymentsByTxHash", mock.Anything, repository.SearchPaymentsByTxHashParams{
+		ClientID: testClient.ID, TxHash: searchTx, Limit: searchLimit,
+	}).Return([]repository.Payment{storedPayment()}, nil)
+	noOrderReference(store, upper)
+
+	status, resp := searchFor(t, s, upper)
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, searchTxHash, resp["kind"])
+	assert.Equal(t, map[string]string{testPaymentID.String(): searchTxHash}, resultIDs(t, resp), "hashes are stored in lower case")
+}
+
+func TestSearch_OrderReference(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("GetPaymentByOrderReference", mock.Anything, repository.GetPaymentByOrderReferenceParams{
+		ClientID: testClient.ID, OrderReference: "order-8841",
+	}).Return(storedPayment(), nil)
+
+	status, resp := searchFor(t, s, "order-8841")
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, searchOrderReference, resp["kind"])
+	assert.Equal(t, map[string]string{testPaymentID.String(): searchOrderReference}, resultIDs(t, resp))
+}
+
+func TestSearch_OrderReferenceShapedLikeID(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	byReference := storedPayment()
+	byReference.ID = uuid.New()
+	ref := uuid.NewString()
+	store.On("GetPayment", mock.Anything, uuid.MustParse(ref)).Return(repository.Payment{}, pgx.ErrNoRows)
+	store.On("GetPaymentByOrderReference", mock.Anything, repository.GetPaymentByOrderReferenceParams{
+		ClientID: testClient.ID, OrderReference: ref,
+	}).Return(byReference, nil)
+
+	status, resp := searchFor(t, s, ref)
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, searchPaymentID, resp["kind"])
+	assert.Equal(t, map[string]string{byReference.ID.String(): searchOrderReference}, resultIDs(t, resp))
+}
+
+func TestSearch_DeduplicatesOrderReference(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(storedPayment(), nil)
+	store.On("GetPaymentByOrderReference", mock.Anything, mock.Anything).Return(storedPayment(), nil)
+
+	status, resp := searchFor(t, s, testPaymentID.String())
+
+	require.Equal(t, http.StatusOK, status)
+	assert.Len(t, resp["results"], 1)
+}
+
+func TestSearch_MissingQuery(t *testing.T) {
+	for _, path := range []string{"/v1/search", "/v1/search?q=%20%20"} {
+		s, store, _ := newTestServer(t)
+		expectClient(store)
+
+		status, resp := do(t, s, http.MethodGet, path, "", nil)
+
+		assert.Equal(t, http.StatusBadRequest, status, path)
+		assert.Equal(t, codeValidationFailed, resp["code"])
+		assert.Equal(t, map[string]any{"q": "is required"}, resp["fields"])
+	}
+}
+
+func TestSearch_StoreError(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("SearchPaymentsByWallet", mock.Anything, mock.Anything).Return(nil, errors.New("connection reset"))
+
+	status, resp := searchFor(t, s, depositWallet)
+
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, apierror.CodeInternal, resp["code"])
+}
@@ -0,0 +1,360 @@
+// Package api serves the merchant HTTP API.
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/cache"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/events"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/logging"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Server timeouts.
+const (
+	readHeaderTimeout = 5 * time.Second
+	readTimeout       = 15 * time.Second
+	writeTimeout      = 30 * time.Second
+	shutdownTimeout   = 10 * time.Second
+)
+
+// Store is the subset of *repository.Store the API reads and writes through.
+// Handlers reach the client-scoped queries of repository.TenantQuerier only
+// through Server.tenant, so none can forget the client_id predicate.
+type Store interface {
+	repository.TenantQuerier
+	globalStore
+}
+
+// globalStore is the part of Store not scoped to a client: API keys,
+// idempotency keys, the admin routes and transactions. Handlers writing in a
+// transaction scope their reads with repository.NewTenantQueries.
+type globalStore interface {
+	GetClientByAPIKey(ctx context.Context, apiKey string) (repository.Client, error)
+	ListClients(ctx context.Context, arg repository.ListClientsParams) ([]repository.Client, error)
+	ListLogs(ctx context.Context, arg repository.ListLogsParams) ([]repository.Log, error)
+	ListInvoices(ctx context.Context, arg repository.ListInvoicesParams) ([]repository.Invoice, error)
+	ListInvoiceLines(ctx context.Context, invoiceIds []uuid.UUID) ([]repository.InvoiceLine, error)
+	ClaimIdempotencyKey(ctx context.Context, arg repository.ClaimIdempotencyKeyParams) (repository.IdempotencyKey, error)
+	GetIdempotencyKey(ctx context.Context, arg repository.GetIdempotencyKeyParams) (repository.IdempotencyKey, error)
+	CompleteIdempotencyKey(ctx context.Context, arg repository.CompleteIdempotencyKeyParams) error
+	FailIdempotencyKey(ctx context.Context, arg repository.FailIdempotencyKeyParams) error
+	RenewIdempotencyKey(ctx context.Context, arg repository.RenewIdempotencyKeyParams) error
+	ReleaseIdempotencyKey(ctx context.Context, arg repository.ReleaseIdempotencyKeyParams) error
+	ExecTx(ctx context.Context, fn func(repository.Querier) error) error
+}
+
+// WalletDeriver derives the address of the deposit wallet at index.
+type WalletDeriver interface {
+	DeriveWallet(index uint32) (string, error)
+}
+
+// WalletDeriverFunc adapts a function to WalletDeriver.
+type WalletDeriverFunc func(index uint32) (string, error)
+
+// DeriveWallet calls f.
+func (f WalletDeriverFunc) DeriveWallet(index uint32) (string, error) {
+	return f(index)
+}
+
+// MnemonicWallets derives deposit wallets from mnemonic, the same way the
+// sweeper derives their keys.
+func MnemonicWallets(mnemonic string) WalletDeriverFunc {
+	return func(index uint32) (string, error) {
+		address, _, err := wallet.DeriveTronAddressFromMnemonic(mnemonic, index)
+		if err != nil {
+			return "", fmt.Errorf("failed to derive wallet %d: %w", index, err)
+		}
+		return address, nil
+	}
+}
+
+// Metrics records API requests. *metrics.Metrics implements it.
+type Metrics interface {
+	ObserveRequest(route, method string, status int, d time.Duration)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveRequest(string, string, int, time.Duration) {}
+
+// Server handles the /v1 routes and, given an admin token, the /admin
+// routes.
+type Server struct {
+	store      globalStore
+	tenants    repository.TenantQuerier
+	wallets    WalletDeriver
+	activation payments.ActivationChecker
+	tokens     *StatusTokens
+	bus        events.Bus
+	changes    events.EventPublisher
+	adminToken *[sha256.Size]byte
+	logger     *slog.Logger
+	metrics    Metrics
+	tracer     trace.Tracer
+	payments   config.PaymentsConfig
+	now        func() time.Time
+	mux        *http.ServeMux
+	handler    http.Handler
+
+	// usdtContract and testUSDTContract are the USDT contracts of the live
+	// and test networks, for payment URIs.
+	usdtContract     string
+	testUSDTContract string
+
+	// maxBodyBytes bounds the body of every request.
+	maxBodyBytes int64
+	// rotationOverlap is how long the secret a webhook endpoint rotated
+	// away from still signs its deliveries; see webhooks.rotationOverlap.
+	rotationOverlap time.Duration
+
+	// testWallets derives the wallets of test clients; nil leaves test
+	// mode off.
+	testWallets WalletDeriver
+
+	// clients caches API key lookups for clientTTL when set.
+	clients   cache.Cache
+	clientTTL time.Duration
+	// summaries caches GET /v1/summary when set.
+	summaries cache.Cache
+
+	// streamHeartbeat and streamMaxDuration time the status streams.
+	streamHeartbeat   time.Duration
+	streamMaxDuration time.Duration
+	// streams is cancelled by CloseStreams.
+	streams      context.Context
+	closeStreams context.CancelFunc
+}
+
+// Option customises a Server.
+type Option func(*Server)
+
+// WithLogger replaces slog.Default. Records logged while serving a request
+// get its request_id.
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Server) { s.logger = l }
+}
+
+// WithMetrics records every request in m.
+func WithMetrics(m Metrics) Option {
+	return func(s *Server) { s.metrics = m }
+}
+
+// WithActivationChecker reports whether each new deposit wallet is
+// activated in the payment response. Without it the response leaves the
+// status out.
+func WithActivationChecker(c payments.ActivationChecker) Option {
+	return func(s *Server) { s.activation = c }
+}
+
+// WithTestWallets derives the deposit wallets of test clients with w, which
+// must use the test mnemonic. Without it test clients cannot be created and
+// their payments are refused.
+func WithTestWallets(w WalletDeriver) Option {
+	return func(s *Server) { s.testWallets = w }
+}
+
+// WithStatusTokens issues a status token with every payment and serves
+// GET /v1/public/payments/{id} and the checkout page GET /checkout/{id} to
+// holders of one. Without it the public routes are not registered.
+func WithStatusTokens(t *StatusTokens) Option {
+	return func(s *Server) { s.tokens = t }
+}
+
+// WithEventBus serves GET /v1/public/payments/{id}/events, streaming the
+// status changes published to bus. The route also needs WithStatusTokens.
+func WithEventBus(bus events.Bus) Option {
+	return func(s *Server) { s.bus = bus }
+}
+
+// WithTransitionEvents publishes the status changes the server makes, such
+// as cancelling a payment, to p once they commit.
+func WithTransitionEvents(p events.EventPublisher) Option {
+	return func(s *Server) { s.changes = p }
+}
+
+// WithClientCache caches the client behind each API key in c for ttl, so
+// authenticating a request skips the database. Deactivating a client or
+// rotating its key evicts the entry; ttl bounds how long another replica
+// keeps honouring it.
+func WithClientCache(c cache.Cache, ttl time.Duration) Option {
+	return func(s *Server) {
+		s.clients = c
+		s.clientTTL = ttl
+	}
+}
+
+// WithSummaryCache caches each client's GET /v1/summary response in c for
+// 30 seconds, so a dashboard polling it does not query the database on
+// every load.
+func WithSummaryCache(c cache.Cache) Option {
+	return func(s *Server) { s.summaries = c }
+}
+
+// WithAdminToken serves the /admin routes to requests bearing token. Without
+// it, or with an empty token, the admin routes are not registered.
+func WithAdminToken(token string) Option {
+	return func(s *Server) {
+		if token == "" {
+			return
+		}
+		sum := sha256.Sum256([]byte(token))
+		s.adminToken = &sum
+	}
+}
+
+// New builds a server using the api, payments and webhooks sections of cfg.
+func New(store Store, wallets WalletDeriver, cfg *config.Config, opts ...Option) *Server {
+	s := &Server{
+		store:    store,
+		tenants:  store,
+		wallets:  wallets,
+		logger:   slog.Default(),
+		metrics:  nopMetrics{},
+		tracer:   noop.NewTracerProvider().Tracer(tracerName),
+		payments: cfg.Payments,
+		now:      time.Now,
+		mux:      http.NewServeMux(),
+
+		usdtContract:     cfg.Tron.USDTContract,
+		testUSDTContract: cfg.TestMode.Tron.USDTContract,
+
+		maxBodyBytes:    cfg.API.MaxBodyBytes,
+		rotationOverlap: cfg.Webhooks.RotationOverlap.Std(),
+
+		streamHeartbeat:   streamHeartbeat,
+		streamMaxDuration: streamMaxDuration,
+	}
+	s.streams, s.closeStreams = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.maxBodyBytes <= 0 {
+		s.maxBodyBytes = config.DefaultMaxBodyBytes
+	}
+	s.logger = slog.New(logging.NewHandler(s.logger.Handler()))
+	s.handler = s.logRequests(s.limitBody(s.mux))
+	s.mux.Handle("POST /v1/payments", s.authenticate(s.idempotent(http.HandlerFunc(s.createPayment))))
+	s.mux.Handle("GET /v1/payments/{id}", s.authenticate(http.HandlerFunc(s.getPayment)))
+	s.mux.Handle("GET /v1/payments/by-order-reference/{reference}", s.authenticate(http.HandlerFunc(s.getPaymentByOrderReference)))
+	s.mux.Handle("POST /v1/payments/{id}/cancel", s.authenticate(http.HandlerFunc(s.cancelPayment)))
+	s.mux.Handle("POST /v1/payments/{id}/regenerate", s.authenticate(s.idempotent(http.HandlerFunc(s.regenerateWallet))))
+	s.mux.Handle("POST /v1/accounts", s.authenticate(http.HandlerFunc(s.createAccount)))
+	s.mux.Handle("GET /v1/accounts/{id}", s.authenticate(http.HandlerFunc(s.getAccount)))
+	s.mux.Handle("PUT /v1/accounts/{id}", s.authenticate(http.HandlerFunc(s.replaceAccount)))
+	s.mux.Handle("PATCH /v1/accounts/{id}", s.authenticate(http.HandlerFunc(s.updateAccount)))
+	s.mux.Handle("DELETE /v1/accounts/{id}", s.authenticate(http.HandlerFunc(s.deleteAccount)))
+	s.mux.Handle("GET /v1/exports/payments", s.authenticate(http.HandlerFunc(s.exportPayments)))
+	s.mux.Handle("GET /v1/summary", s.authenticate(http.HandlerFunc(s.getSummary)))
+	s.mux.Handle("GET /v1/stats/funnel", s.authenticate(http.HandlerFunc(s.getFunnel)))
+	s.mux.Handle("GET /v1/search", s.authenticate(http.HandlerFunc(s.search)))
+	s.mux.Handle("GET /v1/webhook-deliveries", s.authenticate(http.HandlerFunc(s.listWebhookDeliveries)))
+	s.mux.Handle("GET /v1/webhook-deliveries/{id}", s.authenticate(http.HandlerFunc(s.getWebhookDelivery)))
+	s.mux.Handle("POST /v1/webhook-deliveries/{id}/replay",
+		s.authenticate(s.idempotent(http.HandlerFunc(s.replayWebhookDelivery))))
+	s.mux.Handle("POST /v1/webhook-endpoints/{id}/rotate-secret", s.authenticate(http.HandlerFunc(s.rotateWebhookSecret)))
+	if s.tokens != nil {
+		s.mux.HandleFunc("GET /v1/public/payments/{id}", s.getPublicPayment)
+		s.mux.HandleFunc("GET /checkout/{id}", s.checkout)
+	}
+	if s.tokens != nil && s.bus != nil {
+		s.mux.HandleFunc("GET /v1/public/payments/{id}/events", s.streamPublicPayment)
+	}
+	if s.adminToken != nil {
+		s.mux.Handle("POST /admin/clients", s.adminAuth(http.HandlerFunc(s.createClient)))
+		s.mux.Handle("GET /admin/clients", s.adminAuth(http.HandlerFunc(s.listClients)))
+		s.mux.Handle("POST /admin/clients/{id}/deactivate", s.adminAuth(http.HandlerFunc(s.deactivateClient)))
+		s.mux.Handle("POST /admin/clients/{id}/rotate-key", s.adminAuth(http.HandlerFunc(s.rotateClientKey)))
+		s.mux.Handle("POST /admin/clients/{client_id}/webhook-endpoints/{id}/rotate-secret",
+			s.adminAuth(http.HandlerFunc(s.adminRotateWebhookSecret)))
+		s.mux.Handle("GET /admin/logs", s.adminAuth(http.HandlerFunc(s.listLogs)))
+		s.mux.Handle("GET /admin/invoices", s.adminAuth(http.HandlerFunc(s.listInvoices)))
+	}
+	return s
+}
+
+// tenant returns the queries of the authenticated client.
+func (s *Server) tenant(ctx context.Context) *repository.TenantQueries {
+	return repository.NewTenantQueries(s.tenants, clientFrom(ctx).ID)
+}
+
+// publicPayment loads a payment for the public routes, which authenticate
+// with a status token for it rather than as a client. It is the only read
+// not scoped to a client.
+func (s *Server) publicPayment(ctx context.Context, id uuid.UUID) (repository.Payment, error) {
+	return s.tenants.GetPayment(ctx, id)
+}
+
+// walletsFor returns the deriver of the wallets of mode, or false when the
+// mode is not served.
+func (s *Server) walletsFor(mode string) (WalletDeriver, bool) {
+	if mode == config.ModeTest {
+		return s.testWallets, s.testWallets != nil
+	}
+	return s.wallets, true
+}
+
+// CloseStreams ends the open status streams. http.Server.Shutdown waits for
+// them, so register it with RegisterOnShutdown.
+func (s *Server) CloseStreams() {
+	s.closeStreams()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// NewHTTPServer returns an http.Server for h with the API's timeouts.
+func NewHTTPServer(h http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           h,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+	}
+}
+
+// Serve serves h on l until ctx is done, then gives in-flight requests
+// shutdownTimeout to finish.
+func Serve(ctx context.Context, l net.Listener, h http.Handler) error {
+	srv := NewHTTPServer(h)
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(l) }()
+
+	select {
+	case err := <-errc:
+		return fmt.Errorf("api server failed: %w", err)
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down api server: %w", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("api server failed: %w", err)
+	}
+	return nil
+}
+
+// ListenAndServe serves h on port until ctx is done.
+func ListenAndServe(ctx context.Context, port int, h http.Handler) error {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d: %w", port, err)
+	}
+	return Serve(ctx, l, h)
+}
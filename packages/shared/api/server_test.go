@@ -0,0 +1,229 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+const testAPIKey = "sk_test_123"
+
+var (
+	t0          = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	testClient  = repository.Client{ID: uuid.MustParse("11111111-1111-1111-1111-111111111111"), Name: "shop"}
+	testAccount = repository.Account{ID: uuid.MustParse("22222222-2222-2222-2222-222222222222"), ClientID: testClient.ID}
+)
+
+// mockStore is a Store whose transactions run directly on its MockQuerier.
+type mockStore struct {
+	*repository.MockQuerier
+	txErr error
+}
+
+func (m *mockStore) ExecTx(_ context.Context, fn func(repository.Querier) error) error {
+	if m.txErr != nil {
+		return m.txErr
+	}
+	return fn(m.MockQuerier)
+}
+
+// stubWallets derives "T<index>" and records the indexes asked for.
+type stubWallets struct {
+	indexes []uint32
+	err     error
+}
+
+func (s *stubWallets) DeriveWallet(index uint32) (string, error) {
+	s.indexes = append(s.indexes, index)
+	if s.err != nil {
+		return "", s.err
+	}
+	return fmt.Sprintf("TWallet%d", index), nil
+}
+
+// testWallets derives "TTest<index>", standing in for the test mnemonic.
+var testWallets = WalletDeriverFunc(func(index uint32) (string, error) {
+	return fmt.Sprintf("TTest%d", index), nil
+})
+
+// testUSDTContract is the mainnet USDT contract.
+const testUSDTContract = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+
+func testConfig() *config.Config {
+	return &config.Config{
+		Payments: config.PaymentsConfig{
+			DefaultExpiry: config.Duration(30 * time.Minute),
+			MinAmount:     "1",
+			MaxAmount:     "10000",
+
+			SupportedTokens:   []string{config.TokenUSDT, config.TokenTRX},
+			MaxWalletAttempts: config.DefaultMaxWalletAttempts,
+		},
+		Tron: config.TronConfig{USDTContract: testUSDTContract},
+	}
+}
+
+func newTestServer(t *testing.T, opts ...Option) (*Server, *mockStore, *stubWallets) {
+	t.Helper()
+	store := &mockStore{MockQuerier: repository.NewMockQuerier(t)}
+	wallets := &stubWallets{}
+	opts = append([]Option{WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, opts...)
+	s := New(store, wallets, testConfig(), opts...)
+	s.now = func() time.Time { return t0 }
+	return s, store, wallets
+}
+
+// newFakeServer returns a Server backed by a FakeStore holding testClient,
+// whose key is testAPIKey, and its account testAccount, named "main".
+func newFakeServer(t *testing.T, opts ...Option) (*Server, *repository.FakeStore) {
+	t.Helper()
+	ctx := context.Background()
+	store := repository.NewFakeStore(repository.WithFakeClock(func() time.Time { return t0 }))
+	_, err := store.UpsertClient(ctx, repository.UpsertClientParams{ID: testClient.ID, Name: testClient.Name, ApiKey: testAPIKey})
+	require.NoError(t, err)
+	_, err = store.UpsertAccount(ctx, repository.UpsertAccountParams{ID: testAccount.ID, ClientID: testClient.ID, Name: "main"})
+	require.NoError(t, err)
+	opts = append([]Option{WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, opts...)
+	s := New(store, &stubWallets{}, testConfig(), opts...)
+	s.now = func() time.Time { return t0 }
+	return s, store
+}
+
+// do sends method path with body, authenticated with testAPIKey, and
+// decodes the JSON response, a problem document when the request failed.
+func do(t *testing.T, h http.Handler, method, path, body string, header http.Header) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	if header == nil {
+		header = http.Header{"Authorization": {"Bearer " + testAPIKey}}
+	}
+	req.Header = header
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code >= http.StatusBadRequest {
+		assert.Equal(t, apierror.ContentType, rec.Header().Get("Content-Type"))
+	} else {
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	}
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec.Code, resp
+}
+
+func TestAuthenticate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		header http.Header
+		found  bool
+	}{
+		{"bearer token", http.Header{"Authorization": {"Bearer " + testAPIKey}}, true},
+		{"lowercase scheme", http.Header{"Authorization": {"bearer " + testAPIKey}}, true},
+		{"api key header", http.Header{"X-Api-Key": {testAPIKey}}, true},
+		{"unknown key", http.Header{"X-Api-Key": {"sk_unknown"}}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store, _ := newTestServer(t)
+			key := apiKey(&http.Request{Header: tc.header})
+			if tc.found {
+				store.On("GetClientByAPIKey", mock.Anything, key).Return(testClient, nil)
+			} else {
+				store.On("GetClientByAPIKey", mock.Anything, key).Return(repository.Client{}, pgx.ErrNoRows)
+			}
+
+			var got repository.Client
+			h := s.authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = clientFrom(r.Context())
+				writeJSON(w, http.StatusOK, map[string]string{})
+			}))
+			status, resp := do(t, h, http.MethodGet, "/", "", tc.header)
+
+			if tc.found {
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, testClient, got)
+				return
+			}
+			assert.Equal(t, http.StatusUnauthorized, status)
+			assert.Equal(t, codeUnauthorized, resp["code"])
+		})
+	}
+}
+
+func TestAuthenticate_MissingKey(t *testing.T) {
+	for _, header := range []http.Header{
+		{},
+		{"Authorization": {"Basic dXNlcjpwYXNz"}},
+		{"Authorization": {"Bearer "}},
+	} {
+		s, _, _ := newTestServer(t)
+
+		status, resp := do(t, s, http.MethodPost, "/v1/payments", `{}`, header)
+
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, "missing API key", resp["detail"])
+	}
+}
+
+func TestAuthenticate_StoreError(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	store.On("GetClientByAPIKey", mock.Anything, testAPIKey).Return(repository.Client{}, errors.New("connection reset"))
+
+	status, resp := do(t, s, http.MethodPost, "/v1/payments", `{}`, nil)
+
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, apierror.CodeInternal, resp["code"])
+}
+
+func TestMnemonicWallets(t *testing.T) {
+	const mnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	wallets := MnemonicWallets(mnemonic)
+
+	first, err := wallets.DeriveWallet(0)
+	require.NoError(t, err)
+	second, err := wallets.DeriveWallet(1)
+	require.NoError(t, err)
+
+	assert.Regexp(t, `^T[1-9A-HJ-NP-Za-km-z]{33}$`, first)
+	assert.NotEqual(t, first, second)
+}
+
+func TestServe_StopsWithContext(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Serve(ctx, l, s) }()
+
+	resp, err := http.Post("http://"+l.Addr().String()+"/v1/payments", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return after cancel")
+	}
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+)
+
+// funnelBucket is one bucket of GET /v1/stats/funnel.
+type funnelBucket struct {
+	Start string `json:"start"`
+	payments.FunnelCounts
+}
+
+// funnelResponse is the body of GET /v1/stats/funnel.
+type funnelResponse struct {
+	Bucket  string                `json:"bucket"`
+	From    string                `json:"from"`
+	To      string                `json:"to"`
+	Buckets []funnelBucket        `json:"buckets"`
+	Total   payments.FunnelCounts `json:"total"`
+}
+
+// getFunnel handles GET /v1/stats/funnel?from=&to=&bucket=: of the client's
+// payments created in [from, to), how many a transfer was seen for, were
+// confirmed before they expired, and expired, per UTC hour or day. from and
+// to are RFC 3339 times or dates, taken as midnight UTC; bucket is hour or
+// day, the default.
+func (s *Server) getFunnel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := r.URL.Query()
+	fields := map[string]string{}
+	from, err := parseExportTime(q.Get("from"))
+	if err != nil {
+		fields["from"] = err.Error()
+	}
+	to, err := parseExportTime(q.Get("to"))
+	if err != nil {
+		fields["to"] = err.Error()
+	}
+	bucket := q.Get("bucket")
+	if bucket == "" {
+		bucket = payments.FunnelDay
+	}
+	width, ok := payments.FunnelWidth(bucket)
+	if !ok {
+		fields["bucket"] = "must be hour or day"
+	}
+	if len(fields) == 0 {
+		if err := payments.CheckFunnelRange(width, from, to); err != nil {
+			fields["to"] = err.Error()
+		}
+	}
+	if len(fields) > 0 {
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusBadRequest,
+			Code:       codeValidationFailed,
+			Message:    "request has invalid query parameters",
+			Fields:     fields,
+		})
+		return
+	}
+
+	// The tenant scopes the query to the client whatever client it is given.
+	funnel, err := payments.GetFunnel(ctx, s.tenant(ctx), nil, bucket, from, to)
+	if err != nil {
+		s.internalError(w, r, "failed to read payment funnel", err)
+		return
+	}
+	resp := funnelResponse{
+		Bucket:  bucket,
+		From:    from.Format(time.RFC3339),
+		To:      to.Format(time.RFC3339),
+		Buckets: make([]funnelBucket, 0, len(funnel.Buckets)),
+		Total:   funnel.Total,
+	}
+	for _, b := range funnel.Buckets {
+		resp.Buckets = append(resp.Buckets, funnelBucket{Start: b.Start.Format(time.RFC3339), FunnelCounts: b.FunnelCounts})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
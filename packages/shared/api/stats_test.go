@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+func TestGetFunnel(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	from := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 3)
+	store.On("GetPaymentFunnel", mock.Anything, repository.GetPaymentFunnelParams{
+		Bucket:      "day",
+		CreatedFrom: pgtype.Timestamptz{Time: from, Valid: true},
+		CreatedTo:   pgtype.Timestamptz{Time: to, Valid: true},
+		ClientID:    &testClient.ID,
+	}).Return([]repository.GetPaymentFunnelRow{
+		{Bucket: pgtype.Timestamp{Time: from, Valid: true}, Created: 10, Detected: 8, Confirmed: 7, Expired: 2},
+		{Bucket: pgtype.Timestamp{Time: from.AddDate(0, 0, 2), Valid: true}, Created: 3, Detected: 1, Confirmed: 1, Expired: 1},
+	}, nil).Once()
+
+	code, resp := do(t, s, http.MethodGet, "/v1/stats/funnel?from=2026-02-01&to=2026-02-04", "", nil)
+
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "day", resp["bucket"])
+	assert.Equal(t, "2026-02-01T00:00:00Z", resp["from"])
+	assert.Equal(t, "2026-02-04T00:00:00Z", resp["to"])
+	assert.Equal(t, []any{
+		map[string]any{"start": "2026-02-01T00:00:00Z", "created": float64(10), "detected": float64(8), "confirmed": float64(7), "expired": float64(2)},
+		map[string]any{"start": "2026-02-02T00:00:00Z", "created": float64(0), "detected": float64(0), "confirmed": float64(0), "expired": float64(0)},
+		map[string]any{"start": "2026-02-03T00:00:00Z", "created": float64(3), "detected": float64(1), "confirmed": float64(1), "expired": float64(1)},
+	}, resp["buckets"])
+	assert.Equal(t, map[string]any{"created": float64(13), "detected": float64(9), "confirmed": float64(8), "expired": float64(3)},
+		resp["total"])
+}
+
+func TestGetFunnel_Hourly(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("GetPaymentFunnel", mock.Anything, mock.MatchedBy(func(arg repository.GetPaymentFunnelParams) bool {
+		return arg.Bucket == "hour" && *arg.ClientID == testClient.ID
+	})).Return([]repository.GetPaymentFunnelRow{}, nil).Once()
+
+	code, resp := do(t, s, http.MethodGet,
+		"/v1/stats/funnel?bucket=hour&from=2026-02-01T10:30:00Z&to=2026-02-01T12:00:00Z", "", nil)
+
+	require.Equal(t, http.StatusOK, code)
+	buckets := resp["buckets"].([]any)
+	require.Len(t, buckets, 2)
+	assert.Equal(t, "2026-02-01T10:00:00Z", buckets[0].(map[string]any)["start"])
+}
+
+func TestGetFunnel_Validation(t *testing.T) {
+	testCases := []struct {
+		name  string
+		query string
+		field string
+	}{
+		{"missing from", "to=2026-02-04", "from"},
+		{"bad to", "from=2026-02-01&to=tomorrow", "to"},
+		{"unknown bucket", "from=2026-02-01&to=2026-02-04&bucket=week", "bucket"},
+		{"backwards", "from=2026-02-04&to=2026-02-01", "to"},
+		{"too many buckets", "from=2026-01-01&to=2026-03-01&bucket=hour", "to"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store, _ := newTestServer(t)
+			expectClient(store)
+
+			code, resp := do(t, s, http.MethodGet, "/v1/stats/funnel?"+tc.query, "", nil)
+
+			require.Equal(t, http.StatusBadRequest, code)
+			assert.Equal(t, codeValidationFailed, resp["code"])
+			assert.Contains(t, resp["fields"], tc.field)
+		})
+	}
+}
+
+func TestGetFunnel_StoreError(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("GetPaymentFunnel", mock.Anything, mock.Anything).Return(nil, assert.AnError).Once()
+
+	code, _ := do(t, s, http.MethodGet, "/v1/stats/funnel?from=2026-02-01&to=2026-02-04", "", nil)
+
+	assert.Equal(t, http.StatusInternalServerError, code)
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+)
+
+// Status stream timing.
+const (
+	// streamHeartbeat is how often an idle stream sends a comment, so
+	// proxies do not close it.
+	streamHeartbeat = 15 * time.Second
+	// streamMaxDuration caps a stream. EventSource reconnects after
+	// streamRetry and is sent the current status again.
+	streamMaxDuration = 10 * time.Minute
+	streamRetry       = 3 * time.Second
+)
+
+type statusEvent struct {
+	Status string `json:"status"`
+}
+
+// statusStream writes server-sent events, flushing each one.
+type statusStream struct {
+	w    http.ResponseWriter
+	rc   *http.ResponseController
+	id   int
+	last string
+}
+
+// send writes a status event unless status is the one last sent.
+func (s *statusStream) send(status string) error {
+	if status == s.last {
+		return nil
+	}
+	data, err := json.Marshal(statusEvent{Status: status})
+	if err != nil {
+		return err
+	}
+	s.id++
+	s.last = status
+	return s.write(fmt.Sprintf("id: %d\nevent: status\ndata: %s\n\n", s.id, data))
+}
+
+func (s *statusStream) heartbeat() error {
+	return s.write(": heartbeat\n\n")
+}
+
+func (s *statusStream) write(frame string) error {
+	if _, err := fmt.Fprint(s.w, frame); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// streamPublicPayment serves GET /v1/public/payments/{id}/events: the
+// payment's current status, then every change to it, as server-sent events.
+// It authenticates like getPublicPayment.
+func (s *Server) streamPublicPayment(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil || s.tokens.Verify(id, r.URL.Query().Get("token"), s.now()) != nil {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, codePaymentNotFound, "payment not found"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.streamMaxDuration)
+	defer cancel()
+	stopOnClose := context.AfterFunc(s.streams, cancel)
+	defer stopOnClose()
+	// Subscribed before the payment is read, so no change falls between the
+	// two.
+	events, unsubscribe, err := s.bus.Subscribe(ctx, id)
+	if err != nil {
+		s.internalError(w, r, "failed to follow payment", err, "payment_id", id)
+		return
+	}
+	defer unsubscribe()
+	payment, ok := s.lookupPayment(w, r, s.publicPayment)
+	if !ok {
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// The server's write timeout would cut the stream short.
+	_ = rc.SetWriteDeadline(time.Now().Add(s.streamMaxDuration + writeTimeout))
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	stream := &statusStream{w: w, rc: rc}
+	if err := stream.write(fmt.Sprintf("retry: %d\n\n", streamRetry.Milliseconds())); err != nil {
+		return
+	}
+	if err := stream.send(payment.Status); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(s.streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			err = stream.send(e.Status)
+		case <-heartbeat.C:
+			err = stream.heartbeat()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
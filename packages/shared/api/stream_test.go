@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/events"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// flushRecorder is a ResponseWriter that records what was written between
+// flushes, safe to read while the handler is still streaming.
+type flushRecorder struct {
+	mu      sync.Mutex
+	header  http.Header
+	status  int
+	pending strings.Builder
+	flushed []string
+}
+
+func newFlushRecorder() *flushRecorder {
+	return &flushRecorder{header: http.Header{}}
+}
+
+func (f *flushRecorder) Header() http.Header { return f.header }
+
+func (f *flushRecorder) WriteHeader(status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.status == 0 {
+		f.status = status
+	}
+}
+
+func (f *flushRecorder) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.status == 0 {
+		f.status = http.StatusOK
+	}
+	return f.pending.Write(p)
+}
+
+func (f *flushRecorder) Flush() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flushed = append(f.flushed, f.pending.String())
+	f.pending.Reset()
+}
+
+func (f *flushRecorder) chunks() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.flushed...)
+}
+
+// stream serves a status stream for testPaymentID until the returned cancel
+// is called, which waits for the handler to return.
+func stream(t *testing.T, s *Server, token string) (*flushRecorder, func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/v1/public/payments/"+testPaymentID.String()+"/events?token="+token, nil).WithContext(ctx)
+	rec := newFlushRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.ServeHTTP(rec, req)
+	}()
+	return rec, func() {
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("stream did not end")
+		}
+	}
+}
+
+func newStreamServer(t *testing.T, status string) (*Server, *events.MemoryBus, string) {
+	t.Helper()
+	tokens := NewStatusTokens("secret", time.Hour)
+	bus := events.NewMemoryBus()
+	s, store, _ := newTestServer(t, WithStatusTokens(tokens), WithEventBus(bus))
+	payment := storedPayment()
+	payment.Status = status
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(payment, nil)
+	return s, bus, tokens.Issue(testPaymentID, t0.Add(30*time.Minute))
+}
+
+func waitChunks(t *testing.T, rec *flushRecorder, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool { return len(rec.chunks()) >= n }, time.Second, time.Millisecond)
+}
+
+func TestStreamPublicPayment(t *testing.T) {
+	s, bus, token := newStreamServer(t, repository.PaymentPending)
+	rec, stop := stream(t, s, token)
+	waitChunks(t, rec, 2)
+
+	for _, status := range []string{repository.PaymentDetected, repository.PaymentDetected, repository.PaymentConfirmed} {
+		require.NoError(t, bus.Publish(context.Background(), events.Event{PaymentID: testPaymentID, Status: status, At: t0}))
+	}
+	waitChunks(t, rec, 4)
+	stop()
+
+	assert.Equal(t, http.StatusOK, rec.status)
+	assert.Equal(t, "text/event-stream", rec.header.Get("Content-Type"))
+	assert.Equal(t, "no-cache", rec.header.Get("Cache-Control"))
+	assert.Equal(t, []string{
+		"retry: 3000\n\n",
+		"id: 1\nevent: status\ndata: {\"status\":\"PENDING\"}\n\n",
+		"id: 2\nevent: status\ndata: {\"status\":\"DETECTED\"}\n\n",
+		"id: 3\nevent: status\ndata: {\"status\":\"CONFIRMED\"}\n\n",
+	}, rec.chunks(), "current status first, then changes in order, repeats dropped")
+	assert.Empty(t, bus.Watched(), "unsubscribed once the client left")
+}
+
+func TestStreamPublicPayment_Heartbeat(t *testing.T) {
+	s, _, token := newStreamServer(t, repository.PaymentPending)
+	s.streamHeartbeat = 5 * time.Millisecond
+	rec, stop := stream(t, s, token)
+	defer stop()
+
+	waitChunks(t, rec, 3)
+
+	assert.Equal(t, ": heartbeat\n\n", rec.chunks()[2])
+}
+
+func TestStreamPublicPayment_MaxDuration(t *testing.T) {
+	s, bus, token := newStreamServer(t, repository.PaymentPending)
+	s.streamMaxDuration = 20 * time.Millisecond
+	rec := newFlushRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/public/payments/"+testPaymentID.String()+"/events?token="+token, nil)
+
+	s.ServeHTTP(rec, req)
+
+	assert.Len(t, rec.chunks(), 2, "the stream ends by itself")
+	assert.Empty(t, bus.Watched())
+}
+
+func TestStreamPublicPayment_CloseStreams(t *testing.T) {
+	s, bus, token := newStreamServer(t, repository.PaymentPending)
+	rec := newFlushRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/public/payments/"+testPaymentID.String()+"/events?token="+token, nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.ServeHTTP(rec, req)
+	}()
+	waitChunks(t, rec, 2)
+
+	s.CloseStreams()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stream outlived CloseStreams")
+	}
+	assert.Empty(t, bus.Watched())
+}
+
+func TestStreamPublicPayment_InvalidToken(t *testing.T) {
+	tokens := NewStatusTokens("secret", time.Hour)
+	s, _, _ := newTestServer(t, WithStatusTokens(tokens), WithEventBus(events.NewMemoryBus()))
+	forged := NewStatusTokens("guess", time.Hour).Issue(testPaymentID, t0)
+
+	status, resp := do(t, s, http.MethodGet, "/v1/public/payments/"+testPaymentID.String()+"/events?token="+forged, "", http.Header{})
+
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, codePaymentNotFound, resp["code"])
+}
+
+func TestStreamPublicPayment_NotFound(t *testing.T) {
+	tokens := NewStatusTokens("secret", time.Hour)
+	bus := events.NewMemoryBus()
+	s, store, _ := newTestServer(t, WithStatusTokens(tokens), WithEventBus(bus))
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(repository.Payment{}, pgx.ErrNoRows)
+	token := tokens.Issue(testPaymentID, t0.Add(30*time.Minute))
+
+	status, _ := do(t, s, http.MethodGet, "/v1/public/payments/"+testPaymentID.String()+"/events?token="+token, "", http.Header{})
+
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Empty(t, bus.Watched())
+}
+
+func TestStreamPublicPayment_DisabledWithoutBus(t *testing.T) {
+	tokens := NewStatusTokens("secret", time.Hour)
+	s, _, _ := newTestServer(t, WithStatusTokens(tokens))
+	rec := httptest.NewRecorder()
+
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/public/payments/"+testPaymentID.String()+"/events?token="+tokens.Issue(testPaymentID, t0), nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
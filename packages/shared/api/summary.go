@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/cache"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+)
+
+const (
+	// summaryCachePrefix namespaces the cached summaries, keyed by client.
+	summaryCachePrefix = "tpg:summary:"
+	// summaryCacheTTL is how stale a summary may be. A dashboard polling it
+	// costs at most three queries per client this often.
+	summaryCacheTTL = 30 * time.Second
+	// recentPaymentsLimit is how many payments the summary lists.
+	recentPaymentsLimit = 10
+)
+
+// summaryPeriod is what happened over one period of the summary.
+type summaryPeriod struct {
+	// Payments counts the payments created in the period by status.
+	Payments map[string]int64 `json:"payments"`
+	// ConfirmedVolume sums the payments confirmed in the period by token.
+	ConfirmedVolume map[string]string `json:"confirmed_volume"`
+}
+
+// summaryResponse is the body of GET /v1/summary. Periods are whole UTC
+// days, today included.
+type summaryResponse struct {
+	Today          summaryPeriod   `json:"today"`
+	Last7Days      summaryPeriod   `json:"last_7_days"`
+	Last30Days     summaryPeriod   `json:"last_30_days"`
+	RecentPayments []paymentRecord `json:"recent_payments"`
+	GeneratedAt    string          `json:"generated_at"`
+}
+
+// summaryCacheKey is the key the summary of clientID is cached under.
+func summaryCacheKey(clientID uuid.UUID) string {
+	return summaryCachePrefix + clientID.String()
+}
+
+// getSummary handles GET /v1/summary, the figures a merchant's dashboard
+// header shows. The response is cached for summaryCacheTTL per client when
+// the server has a summary cache.
+func (s *Server) getSummary(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	client := clientFrom(ctx)
+	key := summaryCacheKey(client.ID)
+	if s.summaries != nil {
+		raw, err := s.summaries.Get(ctx, key)
+		if err == nil {
+			if json.Valid(raw) {
+				writeJSON(w, http.StatusOK, json.RawMessage(raw))
+				return
+			}
+			s.logger.WarnContext(ctx, "discarding malformed summary cache entry")
+		} else if !errors.Is(err, cache.ErrMiss) {
+			s.logger.WarnContext(ctx, "failed to read summary cache", "error", err)
+		}
+	}
+
+	summary, err := s.summarize(ctx, s.tenant(ctx))
+	if err != nil {
+		s.internalError(w, r, "failed to summarize payments", err)
+		return
+	}
+	raw, err := json.Marshal(summary)
+	if err != nil {
+		s.internalError(w, r, "failed to encode summary", err)
+		return
+	}
+	if s.summaries != nil {
+		if err := s.summaries.Set(ctx, key, raw, summaryCacheTTL); err != nil {
+			s.logger.WarnContext(ctx, "failed to cache summary", "error", err)
+		}
+	}
+	writeJSON(w, http.StatusOK, json.RawMessage(raw))
+}
+
+// summarize builds the summary of tenant's client with one query for the
+// status counts, one for the confirmed volume and one for the recent
+// payments.
+func (s *Server) summarize(ctx context.Context, tenant *repository.TenantQueries) (summaryResponse, error) {
+	now := s.now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	starts := [3]time.Time{today, today.AddDate(0, 0, -6), today.AddDate(0, 0, -29)}
+
+	counts, err := tenant.CountClientPaymentsByStatus(ctx, repository.CountClientPaymentsByStatusParams{
+		TodayFrom: pgtype.Timestamptz{Time: starts[0], Valid: true},
+		WeekFrom:  pgtype.Timestamptz{Time: starts[1], Valid: true},
+		MonthFrom: pgtype.Timestamptz{Time: starts[2], Valid: true},
+	})
+	if err != nil {
+		return summaryResponse{}, err
+	}
+	volume, err := tenant.GetDailyConfirmedVolume(ctx, repository.GetDailyConfirmedVolumeParams{
+		ConfirmedFrom: pgtype.Timestamptz{Time: starts[2], Valid: true},
+	})
+	if err != nil {
+		return summaryResponse{}, err
+	}
+	recent, err := tenant.ListRecentClientPayments(ctx, repository.ListRecentClientPaymentsParams{
+		Limit: recentPaymentsLimit,
+	})
+	if err != nil {
+		return summaryResponse{}, err
+	}
+
+	var periods [3]summaryPeriod
+	var sums [3]map[string]decimal.Decimal
+	for i := range periods {
+		periods[i] = summaryPeriod{Payments: make(map[string]int64), ConfirmedVolume: make(map[string]string)}
+		sums[i] = make(map[string]decimal.Decimal)
+	}
+	for _, c := range counts {
+		for i, n := range [3]int64{c.TodayCount, c.WeekCount, c.MonthCount} {
+			if n > 0 {
+				periods[i].Payments[c.Status] = n
+			}
+		}
+	}
+	for _, v := range volume {
+		for i, start := range starts {
+			if !v.Day.Time.Before(start) {
+				sums[i][v.Token] = sums[i][v.Token].Add(repository.NumericToDecimal(v.Volume))
+			}
+		}
+	}
+	for i := range periods {
+		for token, sum := range sums[i] {
+			periods[i].ConfirmedVolume[token] = payments.FormatAmount(sum, token)
+		}
+	}
+
+	summary := summaryResponse{
+		Today:          periods[0],
+		Last7Days:      periods[1],
+		Last30Days:     periods[2],
+		RecentPayments: make([]paymentRecord, 0, len(recent)),
+		GeneratedAt:    now.Format(time.RFC3339),
+	}
+	for _, p := range recent {
+		summary.RecentPayments = append(summary.RecentPayments, s.paymentRecord(p))
+	}
+	return summary, nil
+}
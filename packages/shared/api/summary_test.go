@@ -0,0 +1,151 @@
+package api
+
+import (
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/cache"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// expectSummary sets up the three summary queries, each answering once.
+func expectSummary(store *mockStore) {
+	today := time.Date(t0.Year(), t0.Month(), t0.Day(), 0, 0, 0, 0, time.UTC)
+	store.On("CountClientPaymentsByStatus", mock.Anything, repository.CountClientPaymentsByStatusParams{
+		TodayFrom: pgtype.Timestamptz{Time: today, Valid: true},
+		WeekFrom:  pgtype.Timestamptz{Time: today.AddDate(0, 0, -6), Valid: true},
+		ClientID:  testClient.ID,
+		MonthFrom: pgtype.Timestamptz{Time: today.AddDate(0, 0, -29), Valid: true},
+	}).Return([]repository.CountClientPaymentsByStatusRow{
+		{Status: repository.PaymentConfirmed, TodayCount: 1, WeekCount: 3, MonthCount: 7},
+		{Status: repository.PaymentExpired, TodayCount: 0, WeekCount: 0, MonthCount: 2},
+	}, nil).Once()
+	store.On("GetDailyConfirmedVolume", mock.Anything, repository.GetDailyConfirmedVolumeParams{
+		ClientID:      testClient.ID,
+		ConfirmedFrom: pgtype.Timestamptz{Time: today.AddDate(0, 0, -29), Valid: true},
+	}).Return([]repository.GetDailyConfirmedVolumeRow{
+		{Day: pgtype.Date{Time: today.AddDate(0, 0, -20), Valid: true}, Token: "USDT", Volume: pgtype.Numeric{Int: big.NewInt(10000), Exp: -2, Valid: true}},
+		{Day: pgtype.Date{Time: today.AddDate(0, 0, -2), Valid: true}, Token: "USDT", Volume: pgtype.Numeric{Int: big.NewInt(2550), Exp: -2, Valid: true}},
+		{Day: pgtype.Date{Time: today, Valid: true}, Token: "TRX", Volume: pgtype.Numeric{Int: big.NewInt(5), Valid: true}},
+	}, nil).Once()
+	store.On("ListRecentClientPayments", mock.Anything, repository.ListRecentClientPaymentsParams{
+		ClientID: testClient.ID,
+		Limit:    recentPaymentsLimit,
+	}).Return([]repository.Payment{{
+		ID:        testPaymentID,
+		ClientID:  testClient.ID,
+		Amount:    pgtype.Numeric{Int: big.NewInt(2550), Exp: -2, Valid: true},
+		Status:    repository.PaymentConfirmed,
+		Token:     "USDT",
+		Mode:      "live",
+		CreatedAt: pgtype.Timestamptz{Time: t0, Valid: true},
+	}}, nil).Once()
+}
+
+func TestGetSummary(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	expectSummary(store)
+
+	code, resp := do(t, s, http.MethodGet, "/v1/summary", "", nil)
+
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]any{
+		"payments":         map[string]any{"CONFIRMED": float64(1)},
+		"confirmed_volume": map[string]any{"TRX": "5.000000"},
+	}, resp["today"])
+	assert.Equal(t, map[string]any{
+		"payments":         map[string]any{"CONFIRMED": float64(3)},
+		"confirmed_volume": map[string]any{"TRX": "5.000000", "USDT": "25.500000"},
+	}, resp["last_7_days"])
+	assert.Equal(t, map[string]any{
+		"payments":         map[string]any{"CONFIRMED": float64(7), "EXPIRED": float64(2)},
+		"confirmed_volume": map[string]any{"TRX": "5.000000", "USDT": "125.500000"},
+	}, resp["last_30_days"])
+	recent := resp["recent_payments"].([]any)
+	require.Len(t, recent, 1)
+	assert.Equal(t, testPaymentID.String(), recent[0].(map[string]any)["id"])
+	assert.Equal(t, t0.Format(time.RFC3339), resp["generated_at"])
+}
+
+func TestGetSummary_Empty(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("CountClientPaymentsByStatus", mock.Anything, mock.Anything).Return(nil, nil)
+	store.On("GetDailyConfirmedVolume", mock.Anything, mock.Anything).Return(nil, nil)
+	store.On("ListRecentClientPayments", mock.Anything, mock.Anything).Return(nil, nil)
+
+	code, resp := do(t, s, http.MethodGet, "/v1/summary", "", nil)
+
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]any{"payments": map[string]any{}, "confirmed_volume": map[string]any{}}, resp["today"])
+	assert.Equal(t, []any{}, resp["recent_payments"])
+}
+
+func TestGetSummary_StoreError(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	store.On("CountClientPaymentsByStatus", mock.Anything, mock.Anything).Return(nil, assert.AnError)
+
+	code, _ := do(t, s, http.MethodGet, "/v1/summary", "", nil)
+
+	assert.Equal(t, http.StatusInternalServerError, code)
+	store.AssertNotCalled(t, "ListRecentClientPayments", mock.Anything, mock.Anything)
+}
+
+func newSummaryCachedServer(t *testing.T) (*Server, *mockStore, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	s, store, _ := newTestServer(t, WithSummaryCache(cache.NewRedis(client)))
+	return s, store, mr
+}
+
+func TestGetSummary_Cached(t *testing.T) {
+	s, store, mr := newSummaryCachedServer(t)
+	expectClient(store)
+	expectSummary(store)
+
+	code, first := do(t, s, http.MethodGet, "/v1/summary", "", nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, mr.Exists(summaryCacheKey(testClient.ID)))
+	assert.Equal(t, summaryCacheTTL, mr.TTL(summaryCacheKey(testClient.ID)))
+
+	// The second load is answered from the cache: each query was expected
+	// only once.
+	code, second := do(t, s, http.MethodGet, "/v1/summary", "", nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, first, second)
+
+	// Another client's summary is its own.
+	assert.False(t, mr.Exists(summaryCacheKey(uuid.New())))
+
+	// Once the entry expires the summary is built again.
+	mr.FastForward(summaryCacheTTL)
+	expectSummary(store)
+	code, _ = do(t, s, http.MethodGet, "/v1/summary", "", nil)
+	require.Equal(t, http.StatusOK, code)
+	store.AssertNumberOfCalls(t, "CountClientPaymentsByStatus", 2)
+}
+
+func TestGetSummary_CacheDown(t *testing.T) {
+	s, store, mr := newSummaryCachedServer(t)
+	mr.Close()
+	expectClient(store)
+	expectSummary(store)
+
+	code, resp := do(t, s, http.MethodGet, "/v1/summary", "", nil)
+
+	require.Equal(t, http.StatusOK, code, "a failing cache falls back to the database")
+	assert.Len(t, resp["recent_payments"], 1)
+}
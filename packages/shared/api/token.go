@@ -0,0 +1,65 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidToken is returned by StatusTokens.Verify for a token that was not
+// issued for the payment or has expired.
+var ErrInvalidToken = errors.New("invalid status token")
+
+// StatusTokens issues and checks the tokens that let a checkout page poll
+// one payment's status without the merchant's API key. A token is
+//
+//	<expiry unix seconds>.<base64url HMAC-SHA256(secret, "<payment id>.<expiry>")>
+//
+// so it carries its own expiry and needs no storage.
+type StatusTokens struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewStatusTokens signs tokens with secret that stay valid for ttl after
+// their payment expires.
+func NewStatusTokens(secret string, ttl time.Duration) *StatusTokens {
+	return &StatusTokens{secret: []byte(secret), ttl: ttl}
+}
+
+// Issue returns a token for payment id, which expires at paymentExpiry.
+func (t *StatusTokens) Issue(id uuid.UUID, paymentExpiry time.Time) string {
+	exp := strconv.FormatInt(paymentExpiry.Add(t.ttl).Unix(), 10)
+	return exp + "." + base64.RawURLEncoding.EncodeToString(t.sign(id, exp))
+}
+
+// Verify checks that token was issued for payment id and has not expired at
+// now.
+func (t *StatusTokens) Verify(id uuid.UUID, token string, now time.Time) error {
+	exp, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalidToken
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, t.sign(id, exp)) {
+		return ErrInvalidToken
+	}
+	// The expiry is signed, so it only needs parsing once the MAC matches.
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || !now.Before(time.Unix(unix, 0)) {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+func (t *StatusTokens) sign(id uuid.UUID, exp string) []byte {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(id.String() + "." + exp))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusTokens(t *testing.T) {
+	tokens := NewStatusTokens("secret", time.Hour)
+	token := tokens.Issue(testPaymentID, t0)
+
+	assert.NoError(t, tokens.Verify(testPaymentID, token, t0))
+	assert.NoError(t, tokens.Verify(testPaymentID, token, t0.Add(time.Hour-time.Second)),
+		"valid for the ttl after the payment expires")
+	assert.Equal(t, token, tokens.Issue(testPaymentID, t0), "tokens are deterministic")
+}
+
+func TestStatusTokens_Rejects(t *testing.T) {
+	tokens := NewStatusTokens("secret", time.Hour)
+	token := tokens.Issue(testPaymentID, t0)
+	exp, sig, _ := strings.Cut(token, ".")
+
+	testCases := []struct {
+		name  string
+		id    uuid.UUID
+		token string
+		now   time.Time
+	}{
+		{"expired", testPaymentID, token, t0.Add(time.Hour)},
+		{"other payment", uuid.New(), token, t0},
+		{"other secret", testPaymentID, NewStatusTokens("other", time.Hour).Issue(testPaymentID, t0), t0},
+		{"extended expiry", testPaymentID, "9999999999." + sig, t0},
+		{"tampered signature", testPaymentID, exp + "." + sig[:len(sig)-2] + "AA", t0},
+		{"bad encoding", testPaymentID, exp + ".!!!", t0},
+		{"no separator", testPaymentID, sig, t0},
+		{"empty", testPaymentID, "", t0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ErrorIs(t, tokens.Verify(tc.id, tc.token, tc.now), ErrInvalidToken)
+		})
+	}
+}
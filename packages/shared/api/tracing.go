@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/requestid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans the API records.
+const tracerName = "github.com/yaninyzwitty/tron-payment-gateway/packages/shared/api"
+
+// WithTracerProvider records a server span for every request, continuing
+// the trace of a caller that sent a traceparent header. Everything the
+// request does, down to its queries, hangs off that span.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(s *Server) { s.tracer = tp.Tracer(tracerName) }
+}
+
+// startSpan starts the server span of r. It is named after the method only
+// until endSpan learns the route.
+func (s *Server) startSpan(r *http.Request) (context.Context, trace.Span) {
+	ctx := tracing.Propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	return s.tracer.Start(ctx, r.Method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.path", r.URL.Path),
+		))
+}
+
+// endSpan names span after the route r matched and records how the request
+// went. Only server errors mark the span failed; a 4xx is the caller's.
+func (s *Server) endSpan(ctx context.Context, span trace.Span, r *http.Request, status int, info *requestInfo) {
+	defer span.End()
+	if !span.IsRecording() {
+		return
+	}
+	span.SetName(r.Method + " " + route(r))
+	span.SetAttributes(
+		attribute.String("http.route", route(r)),
+		attribute.Int("http.response.status_code", status),
+		attribute.String("request_id", requestid.FromContext(ctx)),
+	)
+	if info.clientID != uuid.Nil {
+		span.SetAttributes(attribute.String("client_id", info.clientID.String()))
+	}
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/requestid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// callerTraceParent is the trace context a caller sends with its request.
+const callerTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// zeroDB is a pool on which every statement succeeds and every row scans as
+// zero values, enough to drive a request through a real repository.Store.
+type zeroDB struct{}
+
+func (zeroDB) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (zeroDB) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return nil, pgx.ErrTxClosed
+}
+
+func (zeroDB) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	return zeroRow{}
+}
+
+func (zeroDB) Begin(context.Context) (pgx.Tx, error) {
+	return &zeroTx{}, nil
+}
+
+type zeroRow struct{}
+
+func (zeroRow) Scan(...any) error { return nil }
+
+// zeroTx is a transaction on zeroDB. Methods it does not implement panic
+// through the nil pgx.Tx.
+type zeroTx struct {
+	pgx.Tx
+}
+
+func (*zeroTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return zeroDB{}.Exec(ctx, sql, args...)
+}
+
+func (*zeroTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return zeroDB{}.Query(ctx, sql, args...)
+}
+
+func (*zeroTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return zeroDB{}.QueryRow(ctx, sql, args...)
+}
+
+func (*zeroTx) Commit(context.Context) error   { return nil }
+func (*zeroTx) Rollback(context.Context) error { return nil }
+
+func TestTracing_CreatePaymentSpanHierarchy(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	store := repository.NewStore(zeroDB{}, repository.WithTracerProvider(tp))
+	s := New(store, &stubWallets{}, testConfig(),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithTracerProvider(tp))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/payments",
+		strings.NewReader(`{"account_id":"22222222-2222-2222-2222-222222222222","amount":"25.5"}`))
+	req.Header.Set("Authorization", "Bearer "+testAPIKey)
+	req.Header.Set("traceparent", callerTraceParent)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	spans := rec.Ended()
+	byName := make(map[string]sdktrace.ReadOnlySpan, len(spans))
+	var accountReads []sdktrace.ReadOnlySpan
+	for _, span := range spans {
+		byName[span.Name()] = span
+		if span.Name() == "db.GetAccountByIDAndClientID" {
+			accountReads = append(accountReads, span)
+		}
+	}
+	server := byName["POST /v1/payments"]
+	require.NotNil(t, server, "spans: %v", spanNames(spans))
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String(), "the caller's trace continues")
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+
+	tx := byName["db.transaction"]
+	require.NotNil(t, tx)
+	assert.Equal(t, server.SpanContext().SpanID(), tx.Parent().SpanID())
+
+	require.Contains(t, byName, "db.GetClientByAPIKey")
+	assert.Equal(t, server.SpanContext().SpanID(), byName["db.GetClientByAPIKey"].Parent().SpanID())
+	// The handler reads the account for its defaults, and Create reads it
+	// again in the transaction.
+	require.Len(t, accountReads, 2)
+	assert.Equal(t, server.SpanContext().SpanID(), accountReads[0].Parent().SpanID())
+	assert.Equal(t, tx.SpanContext().SpanID(), accountReads[1].Parent().SpanID())
+	for _, name := range []string{"db.NextWalletIndex", "db.CreatePayment", "db.CreatePaymentAttempt", "db.CreateLog"} {
+		require.Contains(t, byName, name)
+		assert.Equal(t, tx.SpanContext().SpanID(), byName[name].Parent().SpanID(), name)
+	}
+	for _, span := range spans {
+		assert.Equal(t, server.SpanContext().TraceID(), span.SpanContext().TraceID(), span.Name())
+	}
+}
+
+func TestTracing_ServerSpanAttributes(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	s, store, _ := newTestServer(t, WithTracerProvider(tp))
+	expectClient(store)
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(repository.Payment{}, errors.New("connection reset"))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/payments/"+testPaymentID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+testAPIKey)
+	req.Header.Set(requestid.Header, "req-trace-1")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /v1/payments/{id}", span.Name())
+	assert.Equal(t, codes.Error, span.Status().Code)
+	attrs := span.Attributes()
+	assert.Contains(t, attrs, attribute.String("http.route", "/v1/payments/{id}"))
+	assert.Contains(t, attrs, attribute.Int("http.response.status_code", http.StatusInternalServerError))
+	assert.Contains(t, attrs, attribute.String("request_id", "req-trace-1"))
+	assert.Contains(t, attrs, attribute.String("client_id", testClient.ID.String()))
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := make([]string, len(spans))
+	for i, s := range spans {
+		names[i] = s.Name()
+	}
+	return names
+}
@@ -0,0 +1,361 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/requestid"
+)
+
+// Pages of GET /v1/webhook-deliveries.
+const (
+	defaultDeliveryPageSize = 50
+	maxDeliveryPageSize     = 200
+)
+
+// deliveryStatuses are the statuses ?status= may filter by.
+var deliveryStatuses = []string{"PENDING", "DELIVERED", "FAILED"}
+
+// webhookDeliveryRecord is a webhook delivery as its merchant sees it.
+// Payload, the last response and the attempt history are only shown for a
+// single delivery.
+type webhookDeliveryRecord struct {
+	ID         uuid.UUID  `json:"id"`
+	EndpointID uuid.UUID  `json:"endpoint_id"`
+	URL        string     `json:"url"`
+	PaymentID  *uuid.UUID `json:"payment_id"`
+	EventType  string     `json:"event_type"`
+	Status     string     `json:"status"`
+	// Attempts, LastStatusCode, LastError and LastLatencyMs are the history
+	// of the delivery: how often it was sent and how the last attempt went.
+	Attempts       int32   `json:"attempts"`
+	LastStatusCode *int32  `json:"last_status_code"`
+	LastError      *string `json:"last_error"`
+	LastLatencyMs  *int32  `json:"last_latency_ms"`
+	// NextAttemptAt is set while the delivery is PENDING.
+	NextAttemptAt *string `json:"next_attempt_at"`
+	DeliveredAt   *string `json:"delivered_at"`
+	CreatedAt     string  `json:"created_at"`
+	RequestID     *string `json:"request_id"`
+	// ReplayOf is the delivery this one replays.
+	ReplayOf *uuid.UUID `json:"replay_of"`
+
+	Payload          json.RawMessage `json:"payload,omitempty"`
+	LastResponseBody *string         `json:"last_response_body,omitempty"`
+	// LastResponseHeaders holds the allow-listed headers of the last
+	// response.
+	LastResponseHeaders json.RawMessage `json:"last_response_headers,omitempty"`
+	// AttemptHistory lists every attempt, oldest first, with its response.
+	AttemptHistory json.RawMessage `json:"attempt_history,omitempty"`
+}
+
+type webhookDeliveryPage struct {
+	Deliveries []webhookDeliveryRecord `json:"deliveries"`
+	// NextCursor is passed as ?cursor= to get the next page; it is left out
+	// on the last one.
+	NextCursor *uuid.UUID `json:"next_cursor,omitempty"`
+}
+
+func newWebhookDeliveryRecord(d repository.ListWebhookDeliveriesRow) webhookDeliveryRecord {
+	rec := webhookDeliveryRecord{
+		ID:             d.ID,
+		EndpointID:     d.EndpointID,
+		URL:            d.Url,
+		PaymentID:      d.PaymentID,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		LastLatencyMs:  d.LastLatencyMs,
+		DeliveredAt:    optionalTime(d.DeliveredAt),
+		CreatedAt:      formatTime(d.CreatedAt),
+		RequestID:      d.RequestID,
+		ReplayOf:       d.ReplayOf,
+	}
+	if d.Status == "PENDING" {
+		rec.NextAttemptAt = optionalTime(d.NextAttemptAt)
+	}
+	return rec
+}
+
+// listWebhookDeliveries handles GET
+// /v1/webhook-deliveries?payment_id=&status=&limit=&cursor=, paging through
+// the deliveries to the client's endpoints, newest first.
+func (s *Server) listWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	fields := make(map[string]string)
+	var arg repository.ListWebhookDeliveriesParams
+	if v := query.Get("payment_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			fields["payment_id"] = "must be a payment id"
+		}
+		arg.PaymentID = repository.UUIDPtr(id)
+	}
+	if v := query.Get("status"); v != "" {
+		if !slices.Contains(deliveryStatuses, v) {
+			fields["status"] = "must be PENDING, DELIVERED or FAILED"
+		}
+		arg.Status = &v
+	}
+	limit := defaultDeliveryPageSize
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxDeliveryPageSize {
+			fields["limit"] = fmt.Sprintf("must be between 1 and %d", maxDeliveryPageSize)
+		}
+		limit = n
+	}
+	if v := query.Get("cursor"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			fields["cursor"] = "must be a next_cursor from an earlier page"
+		}
+		arg.BeforeID = repository.UUIDPtr(id)
+	}
+	if len(fields) > 0 {
+		apierror.Write(w, r, &apierror.Error{
+			HTTPStatus: http.StatusBadRequest,
+			Code:       codeValidationFailed,
+			Message:    "request has invalid parameters",
+			Fields:     fields,
+		})
+		return
+	}
+
+	// One extra row tells whether there is a next page.
+	arg.Limit = int32(limit + 1)
+	deliveries, err := s.tenant(r.Context()).ListWebhookDeliveries(r.Context(), arg)
+	if err != nil {
+		s.internalError(w, r, "failed to list webhook deliveries", err)
+		return
+	}
+
+	page := webhookDeliveryPage{Deliveries: make([]webhookDeliveryRecord, 0, min(len(deliveries), limit))}
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+		next := deliveries[limit-1].ID
+		page.NextCursor = &next
+	}
+	for _, d := range deliveries {
+		page.Deliveries = append(page.Deliveries, newWebhookDeliveryRecord(d))
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// getWebhookDelivery handles GET /v1/webhook-deliveries/{id}, showing what
+// was sent and how the endpoint last answered.
+func (s *Server) getWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.lookupWebhookDelivery(w, r)
+	if !ok {
+		return
+	}
+	rec := newWebhookDeliveryRecord(repository.ListWebhookDeliveriesRow{
+		ID:             d.ID,
+		EndpointID:     d.EndpointID,
+		PaymentID:      d.PaymentID,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		DeliveredAt:    d.DeliveredAt,
+		CreatedAt:      d.CreatedAt,
+		RequestID:      d.RequestID,
+		ReplayOf:       d.ReplayOf,
+		LastLatencyMs:  d.LastLatencyMs,
+		Url:            d.Url,
+	})
+	rec.Payload = d.Payload
+	rec.LastResponseBody = d.LastResponseBody
+	rec.LastResponseHeaders = d.LastResponseHeaders
+	rec.AttemptHistory = d.AttemptHistory
+	writeJSON(w, http.StatusOK, rec)
+}
+
+// replayWebhookDelivery handles POST /v1/webhook-deliveries/{id}/replay. It
+// queues a copy of the delivery, whatever became of it, as a new PENDING
+// delivery to the same endpoint; the worker signs it with the endpoint's
+// secret at the time it is sent. The original is left as it is.
+func (s *Server) replayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	client := clientFrom(ctx)
+	d, ok := s.lookupWebhookDelivery(w, r)
+	if !ok {
+		return
+	}
+	if !d.IsActive {
+		writeEndpointInactive(w, r)
+		return
+	}
+	replay, err := s.tenant(ctx).ReplayWebhookDelivery(ctx, repository.ReplayWebhookDeliveryParams{
+		RequestID: requestid.Ptr(ctx),
+		ID:        d.ID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The endpoint was deactivated since the lookup.
+		writeEndpointInactive(w, r)
+		return
+	}
+	if err != nil {
+		s.internalError(w, r, "failed to replay webhook delivery", err, "delivery_id", d.ID)
+		return
+	}
+	s.logger.InfoContext(ctx, "webhook delivery replayed", "delivery_id", d.ID, "replay_id", replay.ID,
+		"client_id", client.ID)
+
+	writeJSON(w, http.StatusCreated, newWebhookDeliveryRecord(repository.ListWebhookDeliveriesRow{
+		ID:             replay.ID,
+		EndpointID:     replay.EndpointID,
+		PaymentID:      replay.PaymentID,
+		EventType:      replay.EventType,
+		Status:         replay.Status,
+		Attempts:       replay.Attempts,
+		NextAttemptAt:  replay.NextAttemptAt,
+		LastStatusCode: replay.LastStatusCode,
+		LastError:      replay.LastError,
+		DeliveredAt:    replay.DeliveredAt,
+		CreatedAt:      replay.CreatedAt,
+		RequestID:      replay.RequestID,
+		ReplayOf:       replay.ReplayOf,
+		LastLatencyMs:  replay.LastLatencyMs,
+		Url:            d.Url,
+	}))
+}
+
+// lookupWebhookDelivery loads the delivery named in the path, answering
+// with a 404 when the client has no such delivery.
+func (s *Server) lookupWebhookDelivery(w http.ResponseWriter, r *http.Request) (repository.GetWebhookDeliveryRow, bool) {
+	notFound := apierror.New(http.StatusNotFound, codeWebhookDeliveryNotFound, "webhook delivery not found")
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apierror.Write(w, r, notFound)
+		return repository.GetWebhookDeliveryRow{}, false
+	}
+	d, err := s.tenant(r.Context()).GetWebhookDelivery(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		apierror.Write(w, r, notFound)
+		return repository.GetWebhookDeliveryRow{}, false
+	}
+	if err != nil {
+		s.internalError(w, r, "failed to load webhook delivery", err, "delivery_id", id)
+		return repository.GetWebhookDeliveryRow{}, false
+	}
+	return d, true
+}
+
+func writeEndpointInactive(w http.ResponseWriter, r *http.Request) {
+	apierror.Write(w, r, apierror.New(http.StatusConflict, codeWebhookEndpointInactive,
+		"webhook endpoint is deactivated"))
+}
+
+// webhookSecretPrefix starts every generated webhook endpoint secret.
+const webhookSecretPrefix = "whsec_"
+
+// NewWebhookSecret returns a random signing secret for a webhook endpoint.
+func NewWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// webhookSecretRecord is a webhook endpoint whose secret was just rotated.
+// Secret cannot be shown again.
+type webhookSecretRecord struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret"`
+	RotatedAt string    `json:"rotated_at"`
+	// PreviousSecretExpiresAt is when deliveries stop being signed with the
+	// secret this one replaced.
+	PreviousSecretExpiresAt string `json:"previous_secret_expires_at"`
+}
+
+type webhookEndpointAuditLog struct {
+	EndpointID uuid.UUID `json:"endpoint_id"`
+	ClientID   uuid.UUID `json:"client_id"`
+}
+
+// rotateWebhookSecret handles POST /v1/webhook-endpoints/{id}/rotate-secret.
+// Deliveries are signed with the new secret at once and, until
+// previous_secret_expires_at, with the old one as well, so the merchant can
+// switch its receiver over without rejecting any.
+func (s *Server) rotateWebhookSecret(w http.ResponseWriter, r *http.Request) {
+	s.rotateEndpointSecret(w, r, clientFrom(r.Context()).ID)
+}
+
+// adminRotateWebhookSecret handles POST
+// /admin/clients/{client_id}/webhook-endpoints/{id}/rotate-secret, rotating
+// a merchant's secret for it, as when the merchant reports it leaked.
+func (s *Server) adminRotateWebhookSecret(w http.ResponseWriter, r *http.Request) {
+	clientID, err := uuid.Parse(r.PathValue("client_id"))
+	if err != nil {
+		apierror.Write(w, r, apierror.New(http.StatusNotFound, codeClientNotFound, "client not found"))
+		return
+	}
+	s.rotateEndpointSecret(w, r, clientID)
+}
+
+// rotateEndpointSecret gives the active endpoint of client named by the id
+// path value a new secret and audits it, in one transaction.
+func (s *Server) rotateEndpointSecret(w http.ResponseWriter, r *http.Request, clientID uuid.UUID) {
+	ctx := r.Context()
+	notFound := apierror.New(http.StatusNotFound, codeWebhookEndpointNotFound, "webhook endpoint not found")
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apierror.Write(w, r, notFound)
+		return
+	}
+	secret, err := NewWebhookSecret()
+	if err != nil {
+		s.internalError(w, r, "failed to rotate webhook secret", err, "endpoint_id", id)
+		return
+	}
+
+	var endpoint repository.WebhookEndpoint
+	err = s.store.ExecTx(ctx, func(q repository.Querier) error {
+		endpoint, err = q.RotateWebhookEndpointSecret(ctx, repository.RotateWebhookEndpointSecretParams{
+			Secret:   secret,
+			ID:       id,
+			ClientID: clientID,
+		})
+		if err != nil {
+			return err
+		}
+		return audit(ctx, q, EventWebhookSecretRotated, "webhook endpoint secret rotated",
+			webhookEndpointAuditLog{EndpointID: id, ClientID: clientID})
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		apierror.Write(w, r, notFound)
+		return
+	}
+	if err != nil {
+		s.internalError(w, r, "failed to rotate webhook secret", err, "endpoint_id", id)
+		return
+	}
+	s.logger.InfoContext(ctx, "webhook endpoint secret rotated", "endpoint_id", id, "client_id", clientID,
+		"actor", actorFrom(ctx))
+
+	writeJSON(w, http.StatusOK, webhookSecretRecord{
+		ID:                      endpoint.ID,
+		URL:                     endpoint.Url,
+		Secret:                  endpoint.Secret,
+		RotatedAt:               formatTime(endpoint.RotatedAt),
+		PreviousSecretExpiresAt: formatTime(pgtype.Timestamptz{Time: endpoint.RotatedAt.Time.Add(s.rotationOverlap), Valid: true}),
+	})
+}
@@ -0,0 +1,339 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/apierror"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+var (
+	testDeliveryID = uuid.MustParse("4f3c2b1a-0000-4000-8000-0000000000d1")
+	testEndpointID = uuid.MustParse("4f3c2b1a-0000-4000-8000-0000000000e1")
+)
+
+func deliveryPath(suffix string) string {
+	return "/v1/webhook-deliveries/" + testDeliveryID.String() + suffix
+}
+
+// failedDelivery is a payment.confirmed delivery that ran out of attempts.
+func failedDelivery() repository.GetWebhookDeliveryRow {
+	code, lastErr, body, latency := int32(502), "endpoint returned 502", "bad gateway", int32(184)
+	return repository.GetWebhookDeliveryRow{
+		ID:                  testDeliveryID,
+		EndpointID:          testEndpointID,
+		PaymentID:           repository.UUIDPtr(testPaymentID),
+		EventType:           "payment.confirmed",
+		Payload:             []byte(`{"type":"payment.confirmed","data":{"status":"CONFIRMED"}}`),
+		Status:              "FAILED",
+		Attempts:            8,
+		NextAttemptAt:       pgtype.Timestamptz{Time: t0, Valid: true},
+		LastStatusCode:      &code,
+		LastError:           &lastErr,
+		CreatedAt:           pgtype.Timestamptz{Time: t0.Add(-time.Hour), Valid: true},
+		LastResponseBody:    &body,
+		LastResponseHeaders: []byte(`{"Content-Type":"text/plain","Retry-After":"120"}`),
+		LastLatencyMs:       &latency,
+		AttemptHistory:      []byte(`[{"attempt":8,"at":"2026-03-01T12:00:00Z","status_code":502,"latency_ms":184}]`),
+		Url:                 "https://shop.example/hooks",
+		IsActive:            true,
+	}
+}
+
+func expectDelivery(store *mockStore, d repository.GetWebhookDeliveryRow, err error) {
+	store.On("GetWebhookDelivery", mock.Anything, repository.GetWebhookDeliveryParams{ID: testDeliveryID, ClientID: testClient.ID}).
+		Return(d, err)
+}
+
+func TestListWebhookDeliveries(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	status, latency := "FAILED", int32(184)
+	store.On("ListWebhookDeliveries", mock.Anything, repository.ListWebhookDeliveriesParams{
+		ClientID:  testClient.ID,
+		PaymentID: repository.UUIDPtr(testPaymentID),
+		Status:    &status,
+		Limit:     defaultDeliveryPageSize + 1,
+	}).Return([]repository.ListWebhookDeliveriesRow{{
+		ID:            testDeliveryID,
+		PaymentID:     repository.UUIDPtr(testPaymentID),
+		EventType:     "payment.confirmed",
+		Status:        "FAILED",
+		Attempts:      8,
+		CreatedAt:     pgtype.Timestamptz{Time: t0, Valid: true},
+		LastLatencyMs: &latency,
+		Url:           "https://shop.example/hooks",
+	}}, nil)
+
+	code, resp := do(t, s, http.MethodGet, "/v1/webhook-deliveries?payment_id="+testPaymentID.String()+"&status=FAILED", "", nil)
+
+	assert.Equal(t, http.StatusOK, code)
+	deliveries := resp["deliveries"].([]any)
+	require.Len(t, deliveries, 1)
+	d := deliveries[0].(map[string]any)
+	assert.Equal(t, testDeliveryID.String(), d["id"])
+	assert.Equal(t, "FAILED", d["status"])
+	assert.Equal(t, float64(8), d["attempts"])
+	assert.Nil(t, d["next_attempt_at"], "a settled delivery is not due")
+	assert.Equal(t, float64(184), d["last_latency_ms"])
+	assert.NotContains(t, d, "payload", "payloads are only shown one delivery at a time")
+	assert.NotContains(t, d, "attempt_history")
+	assert.NotContains(t, resp, "next_cursor")
+}
+
+func TestListWebhookDeliveries_Pages(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	cursor := uuid.New()
+	rows := make([]repository.ListWebhookDeliveriesRow, 3)
+	for i := range rows {
+		rows[i] = repository.ListWebhookDeliveriesRow{ID: uuid.New(), Status: "PENDING"}
+	}
+	store.On("ListWebhookDeliveries", mock.Anything, repository.ListWebhookDeliveriesParams{
+		ClientID: testClient.ID,
+		BeforeID: repository.UUIDPtr(cursor),
+		Limit:    3,
+	}).Return(rows, nil)
+
+	code, resp := do(t, s, http.MethodGet, "/v1/webhook-deliveries?limit=2&cursor="+cursor.String(), "", nil)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, resp["deliveries"], 2)
+	assert.Equal(t, rows[1].ID.String(), resp["next_cursor"])
+}
+
+func TestListWebhookDeliveries_InvalidParameters(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+
+	code, resp := do(t, s, http.MethodGet, "/v1/webhook-deliveries?payment_id=abc&status=LOST&limit=0&cursor=x", "", nil)
+
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, codeValidationFailed, resp["code"])
+	fields := resp["fields"].(map[string]any)
+	for _, f := range []string{"payment_id", "status", "limit", "cursor"} {
+		assert.Contains(t, fields, f)
+	}
+	store.AssertNotCalled(t, "ListWebhookDeliveries", mock.Anything, mock.Anything)
+}
+
+func TestGetWebhookDelivery(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	expectDelivery(store, failedDelivery(), nil)
+
+	code, resp := do(t, s, http.MethodGet, deliveryPath(""), "", nil)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, testDeliveryID.String(), resp["id"])
+	assert.Equal(t, testEndpointID.String(), resp["endpoint_id"])
+	assert.Equal(t, testPaymentID.String(), resp["payment_id"])
+	assert.Equal(t, "https://shop.example/hooks", resp["url"])
+	assert.Equal(t, float64(502), resp["last_status_code"])
+	assert.Equal(t, "endpoint returned 502", resp["last_error"])
+	assert.Equal(t, "bad gateway", resp["last_response_body"])
+	assert.Equal(t, float64(184), resp["last_latency_ms"])
+	assert.Equal(t, map[string]any{"Content-Type": "text/plain", "Retry-After": "120"}, resp["last_response_headers"])
+	require.Len(t, resp["attempt_history"], 1)
+	assert.Equal(t, float64(502), resp["attempt_history"].([]any)[0].(map[string]any)["status_code"])
+	assert.Equal(t, map[string]any{"type": "payment.confirmed", "data": map[string]any{"status": "CONFIRMED"}}, resp["payload"])
+	assert.NotContains(t, resp, "secret")
+}
+
+func TestGetWebhookDelivery_NotFound(t *testing.T) {
+	testCases := []struct {
+		name string
+		path string
+	}{
+		{"unknown", deliveryPath("")},
+		{"malformed id", "/v1/webhook-deliveries/abc"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store, _ := newTestServer(t)
+			expectClient(store)
+			// Another client's delivery is not found either.
+			store.On("GetWebhookDelivery", mock.Anything, mock.Anything).
+				Return(repository.GetWebhookDeliveryRow{}, pgx.ErrNoRows).Maybe()
+
+			code, resp := do(t, s, http.MethodGet, tc.path, "", nil)
+
+			assert.Equal(t, http.StatusNotFound, code)
+			assert.Equal(t, codeWebhookDeliveryNotFound, resp["code"])
+		})
+	}
+}
+
+func TestReplayWebhookDelivery(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	expectClient(store)
+	original := failedDelivery()
+	expectDelivery(store, original, nil)
+	replayID := uuid.New()
+	store.On("ReplayWebhookDelivery", mock.Anything, mock.MatchedBy(func(arg repository.ReplayWebhookDeliveryParams) bool {
+		return arg.ID == testDeliveryID && arg.ClientID == testClient.ID && arg.RequestID != nil
+	})).Return(repository.WebhookDelivery{
+		ID:            replayID,
+		EndpointID:    original.EndpointID,
+		PaymentID:     original.PaymentID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        "PENDING",
+		NextAttemptAt: pgtype.Timestamptz{Time: t0, Valid: true},
+		CreatedAt:     pgtype.Timestamptz{Time: t0, Valid: true},
+		ReplayOf:      repository.UUIDPtr(testDeliveryID),
+	}, nil).Once()
+
+	code, resp := do(t, s, http.MethodPost, deliveryPath("/replay"), "", nil)
+
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, replayID.String(), resp["id"])
+	assert.Equal(t, "PENDING", resp["status"])
+	assert.Equal(t, float64(0), resp["attempts"], "the copy starts over")
+	assert.Nil(t, resp["last_status_code"])
+	assert.Equal(t, t0.Format(time.RFC3339), resp["next_attempt_at"])
+	assert.Equal(t, testDeliveryID.String(), resp["replay_of"])
+	assert.Equal(t, "https://shop.example/hooks", resp["url"])
+}
+
+func TestReplayWebhookDelivery_Refused(t *testing.T) {
+	inactive := failedDelivery()
+	inactive.IsActive = false
+
+	testCases := []struct {
+		name      string
+		delivery  repository.GetWebhookDeliveryRow
+		lookupErr error
+		replayErr error
+		status    int
+		code      string
+	}{
+		{"unknown delivery", repository.GetWebhookDeliveryRow{}, pgx.ErrNoRows, nil, http.StatusNotFound, codeWebhookDeliveryNotFound},
+		{"deactivated endpoint", inactive, nil, nil, http.StatusConflict, codeWebhookEndpointInactive},
+		{"deactivated since lookup", failedDelivery(), nil, pgx.ErrNoRows, http.StatusConflict, codeWebhookEndpointInactive},
+		{"lookup failure", repository.GetWebhookDeliveryRow{}, errors.New("connection reset"), nil, http.StatusInternalServerError, apierror.CodeInternal},
+		{"replay failure", failedDelivery(), nil, errors.New("connection reset"), http.StatusInternalServerError, apierror.CodeInternal},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store, _ := newTestServer(t)
+			expectClient(store)
+			expectDelivery(store, tc.delivery, tc.lookupErr)
+			if tc.lookupErr == nil && tc.delivery.IsActive {
+				store.On("ReplayWebhookDelivery", mock.Anything, mock.Anything).
+					Return(repository.WebhookDelivery{}, tc.replayErr).Once()
+			}
+
+			code, resp := do(t, s, http.MethodPost, deliveryPath("/replay"), "", nil)
+
+			assert.Equal(t, tc.status, code)
+			assert.Equal(t, tc.code, resp["code"], fmt.Sprint(resp))
+			if !tc.delivery.IsActive {
+				store.AssertNotCalled(t, "ReplayWebhookDelivery", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func rotatedEndpoint(secret string) repository.WebhookEndpoint {
+	previous := "whsec_old"
+	return repository.WebhookEndpoint{
+		ID:              testEndpointID,
+		ClientID:        testClient.ID,
+		Url:             "https://shop.example/hooks",
+		Secret:          secret,
+		IsActive:        true,
+		SecondarySecret: &previous,
+		RotatedAt:       pgtype.Timestamptz{Time: t0, Valid: true},
+	}
+}
+
+func TestRotateWebhookSecret(t *testing.T) {
+	s, store, _ := newTestServer(t)
+	s.rotationOverlap = 24 * time.Hour
+	expectClient(store)
+	var secret string
+	store.On("RotateWebhookEndpointSecret", mock.Anything, mock.MatchedBy(func(arg repository.RotateWebhookEndpointSecretParams) bool {
+		return arg.ID == testEndpointID && arg.ClientID == testClient.ID &&
+			strings.HasPrefix(arg.Secret, webhookSecretPrefix) && arg.Secret != "whsec_old"
+	})).Run(func(args mock.Arguments) {
+		secret = args.Get(1).(repository.RotateWebhookEndpointSecretParams).Secret
+	}).Return(rotatedEndpoint("whsec_new"), nil).Once()
+	expectAudit(store, EventWebhookSecretRotated, clientActor(testClient.ID),
+		webhookEndpointAuditLog{EndpointID: testEndpointID, ClientID: testClient.ID})
+
+	code, resp := do(t, s, http.MethodPost, "/v1/webhook-endpoints/"+testEndpointID.String()+"/rotate-secret", "", nil)
+
+	require.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, secret)
+	assert.Equal(t, testEndpointID.String(), resp["id"])
+	assert.Equal(t, "whsec_new", resp["secret"])
+	assert.Equal(t, t0.Format(time.RFC3339), resp["rotated_at"])
+	assert.Equal(t, t0.Add(24*time.Hour).Format(time.RFC3339), resp["previous_secret_expires_at"])
+	assert.NotContains(t, fmt.Sprint(resp), "whsec_old", "the old secret is not shown")
+}
+
+func TestRotateWebhookSecret_NotFound(t *testing.T) {
+	testCases := []struct {
+		name string
+		path string
+	}{
+		{"unknown, inactive or another client's", "/v1/webhook-endpoints/" + testEndpointID.String() + "/rotate-secret"},
+		{"malformed id", "/v1/webhook-endpoints/abc/rotate-secret"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store, _ := newTestServer(t)
+			expectClient(store)
+			store.On("RotateWebhookEndpointSecret", mock.Anything, mock.Anything).
+				Return(repository.WebhookEndpoint{}, pgx.ErrNoRows).Maybe()
+
+			code, resp := do(t, s, http.MethodPost, tc.path, "", nil)
+
+			assert.Equal(t, http.StatusNotFound, code)
+			assert.Equal(t, codeWebhookEndpointNotFound, resp["code"])
+			store.AssertNotCalled(t, "CreateLog", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestAdminRotateWebhookSecret(t *testing.T) {
+	s, store := newAdminServer(t)
+	clientID := uuid.New()
+	store.On("RotateWebhookEndpointSecret", mock.Anything, mock.MatchedBy(func(arg repository.RotateWebhookEndpointSecretParams) bool {
+		return arg.ID == testEndpointID && arg.ClientID == clientID
+	})).Return(rotatedEndpoint("whsec_new"), nil).Once()
+	expectAudit(store, EventWebhookSecretRotated, "admin:ops-bot",
+		webhookEndpointAuditLog{EndpointID: testEndpointID, ClientID: clientID})
+
+	code, resp := do(t, s, http.MethodPost,
+		"/admin/clients/"+clientID.String()+"/webhook-endpoints/"+testEndpointID.String()+"/rotate-secret", "", adminHeader("ops-bot"))
+
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "whsec_new", resp["secret"])
+}
+
+func TestNewWebhookSecret(t *testing.T) {
+	a, err := NewWebhookSecret()
+	require.NoError(t, err)
+	b, err := NewWebhookSecret()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(a, webhookSecretPrefix))
+	assert.Len(t, a, len(webhookSecretPrefix)+43, "32 random bytes")
+	assert.NotEqual(t, a, b)
+}
@@ -0,0 +1,130 @@
+// Package apierror is how the HTTP API fails a request. Handlers describe
+// the failure as an Error, or pass on a repository error for From to map, and
+// Write answers with an RFC 7807 problem document:
+//
+//	{"type": "about:blank", "title": "Not Found", "status": 404,
+//	 "detail": "payment not found", "instance": "/v1/payments/...",
+//	 "code": "payment_not_found", "request_id": "..."}
+//
+// Code is the field clients branch on; detail is for people and may change.
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/requestid"
+)
+
+// ContentType is the media type of a problem document.
+const ContentType = "application/problem+json"
+
+// Codes of the errors From maps to. Endpoints define more specific codes of
+// their own, e.g. payment_not_found.
+const (
+	CodeInternal                = "internal_error"
+	CodeNotFound                = "resource_not_found"
+	CodeDuplicateWallet         = "duplicate_wallet"
+	CodeDuplicateOrderReference = "duplicate_order_reference"
+	CodeInvalidTransition       = "invalid_transition"
+	CodeQueryTimeout            = "query_timeout"
+)
+
+// internalMessage is the detail of every 5xx response, whatever the cause.
+const internalMessage = "internal error"
+
+// Error is a failed request as the client sees it.
+type Error struct {
+	// HTTPStatus is the response status.
+	HTTPStatus int
+	// Code is a stable machine-readable name, e.g. payment_expired.
+	Code string
+	// Message says what went wrong. It is not written for a 5xx status.
+	Message string
+	// Fields maps a request field to what is wrong with it.
+	Fields map[string]string
+}
+
+// New returns an Error without field details.
+func New(status int, code, message string) *Error {
+	return &Error{HTTPStatus: status, Code: code, Message: message}
+}
+
+// Internal returns the Error of an unexpected failure.
+func Internal() *Error {
+	return New(http.StatusInternalServerError, CodeInternal, internalMessage)
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.HTTPStatus, e.Code, e.Message)
+}
+
+// From returns the Error in err's chain, or the one a repository error maps
+// to. Any other error is internal.
+func From(err error) *Error {
+	var e *Error
+	switch {
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, repository.ErrNotFound), errors.Is(err, pgx.ErrNoRows):
+		return New(http.StatusNotFound, CodeNotFound, "resource not found")
+	case errors.Is(err, repository.ErrDuplicateWallet):
+		return New(http.StatusConflict, CodeDuplicateWallet, "wallet is already assigned to an open payment")
+	case errors.Is(err, repository.ErrDuplicateOrderReference):
+		return New(http.StatusConflict, CodeDuplicateOrderReference, "order reference is already used by another payment")
+	case errors.Is(err, repository.ErrInvalidPaymentTransition):
+		return New(http.StatusConflict, CodeInvalidTransition, "payment cannot move to that status")
+	case errors.Is(err, repository.ErrQueryTimeout):
+		// The database is slow, not broken: 503 tells clients to retry.
+		return New(http.StatusServiceUnavailable, CodeQueryTimeout, "database query timed out")
+	default:
+		return Internal()
+	}
+}
+
+// Problem is the RFC 7807 body Write sends, with the error code, request ID
+// and field details as extension members.
+type Problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	Code      string            `json:"code"`
+	RequestID string            `json:"request_id,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+// NewProblem returns the problem document answering r with err. A 5xx
+// problem carries neither err's message nor its fields, so a database or
+// upstream error never reaches the client.
+func NewProblem(r *http.Request, err error) Problem {
+	e := From(err)
+	p := Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(e.HTTPStatus),
+		Status:    e.HTTPStatus,
+		Detail:    e.Message,
+		Instance:  r.URL.Path,
+		Code:      e.Code,
+		RequestID: requestid.FromContext(r.Context()),
+		Fields:    e.Fields,
+	}
+	if e.HTTPStatus >= http.StatusInternalServerError {
+		p.Detail = internalMessage
+		p.Fields = nil
+	}
+	return p
+}
+
+// Write answers r with err as a problem document.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	p := NewProblem(r, err)
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
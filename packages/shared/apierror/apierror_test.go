@@ -0,0 +1,128 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/requestid"
+)
+
+// write answers a request for path with err and returns the response.
+func write(t *testing.T, path string, err error) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	r = r.WithContext(requestid.NewContext(r.Context(), "req-1"))
+	rec := httptest.NewRecorder()
+	Write(rec, r, err)
+	return rec
+}
+
+func TestWrite_MappedErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"not found", repository.ErrNotFound,
+			`{"type":"about:blank","title":"Not Found","status":404,"detail":"resource not found","instance":"/v1/things/1","code":"resource_not_found","request_id":"req-1"}`},
+		{"no rows", fmt.Errorf("failed to load thing: %w", pgx.ErrNoRows),
+			`{"type":"about:blank","title":"Not Found","status":404,"detail":"resource not found","instance":"/v1/things/1","code":"resource_not_found","request_id":"req-1"}`},
+		{"duplicate wallet", repository.ErrDuplicateWallet,
+			`{"type":"about:blank","title":"Conflict","status":409,"detail":"wallet is already assigned to an open payment","instance":"/v1/things/1","code":"duplicate_wallet","request_id":"req-1"}`},
+		{"duplicate order reference", fmt.Errorf("failed to insert payment: %w", repository.ErrDuplicateOrderReference),
+			`{"type":"about:blank","title":"Conflict","status":409,"detail":"order reference is already used by another payment","instance":"/v1/things/1","code":"duplicate_order_reference","request_id":"req-1"}`},
+		{"invalid transition", fmt.Errorf("%w: EXPIRED to CONFIRMED", repository.ErrInvalidPaymentTransition),
+			`{"type":"about:blank","title":"Conflict","status":409,"detail":"payment cannot move to that status","instance":"/v1/things/1","code":"invalid_transition","request_id":"req-1"}`},
+		{"query timeout", fmt.Errorf("failed to get payment: %w", repository.ErrQueryTimeout),
+			`{"type":"about:blank","title":"Service Unavailable","status":503,"detail":"internal error","instance":"/v1/things/1","code":"query_timeout","request_id":"req-1"}`},
+		{"unknown", errors.New("connection reset"),
+			`{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal error","instance":"/v1/things/1","code":"internal_error","request_id":"req-1"}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := write(t, "/v1/things/1", tc.err)
+
+			assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+			var p Problem
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+			assert.Equal(t, p.Status, rec.Code)
+			assert.JSONEq(t, tc.want, rec.Body.String())
+		})
+	}
+}
+
+func TestWrite_Error(t *testing.T) {
+	err := &Error{
+		HTTPStatus: http.StatusBadRequest,
+		Code:       "validation_failed",
+		Message:    "invalid payment",
+		Fields:     map[string]string{"amount": "must be positive"},
+	}
+
+	rec := write(t, "/v1/payments", fmt.Errorf("create payment: %w", err))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "invalid payment",
+		"instance": "/v1/payments",
+		"code": "validation_failed",
+		"request_id": "req-1",
+		"fields": {"amount": "must be positive"}
+	}`, rec.Body.String())
+}
+
+func TestWrite_ServerErrorsHideDetails(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+	}{
+		{"plain error", errors.New(`pq: relation "payments" does not exist`)},
+		{"wrapped", fmt.Errorf("failed to commit: %w", errors.New("serialization failure at 10.0.0.7"))},
+		{"described 5xx", &Error{
+			HTTPStatus: http.StatusBadGateway,
+			Code:       "node_unavailable",
+			Message:    "node 10.0.0.7 refused the connection",
+			Fields:     map[string]string{"node": "10.0.0.7"},
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := write(t, "/v1/payments", tc.err)
+
+			assert.GreaterOrEqual(t, rec.Code, http.StatusInternalServerError)
+			var p Problem
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+			assert.Equal(t, "internal error", p.Detail)
+			assert.Nil(t, p.Fields)
+			assert.NotContains(t, rec.Body.String(), "10.0.0.7")
+			assert.NotContains(t, rec.Body.String(), "relation")
+		})
+	}
+}
+
+func TestWrite_WithoutRequestID(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Write(rec, httptest.NewRequest(http.MethodGet, "/v1/payments", nil), Internal())
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.NotContains(t, body, "request_id")
+}
+
+func TestError_Error(t *testing.T) {
+	assert.Equal(t, "404 payment_not_found: payment not found",
+		New(http.StatusNotFound, "payment_not_found", "payment not found").Error())
+}
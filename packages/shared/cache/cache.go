@@ -0,0 +1,82 @@
+// Package cache is a small key-value cache with per-entry expiry, used to
+// spare the database lookups that run on every request. Callers treat it as
+// best effort: a failing cache must never fail the request, only send it to
+// the database.
+package cache
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+// ErrMiss is returned by Get for keys that are absent or expired.
+var ErrMiss = errors.New("cache miss")
+
+// Cache stores opaque values under string keys.
+type Cache interface {
+	// Get returns the value stored under key, or ErrMiss.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value under key for ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes key; deleting an absent key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Redis is a Cache backed by a Redis server.
+type Redis struct {
+	client redis.Cmdable
+}
+
+// NewRedis returns a Cache storing its entries through client.
+func NewRedis(client redis.Cmdable) *Redis {
+	return &Redis{client: client}
+}
+
+// NewRedisClient returns a client for the server in cfg. It connects
+// lazily, so a server that is down only fails the first commands.
+func NewRedisClient(cfg config.RedisConfig, password string) *redis.Client {
+	opts := &redis.Options{
+		Addr:     cfg.Addr,
+		Username: cfg.Username,
+		Password: password,
+		DB:       cfg.DB,
+	}
+	if cfg.TLS {
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return redis.NewClient(opts)
+}
+
+// Get implements Cache.
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+	return value, nil
+}
+
+// Set implements Cache.
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := r.client.Set(ctx, key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Delete implements Cache.
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	if err := r.client.Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("failed to delete cache entry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+func newTestRedis(t *testing.T) (*Redis, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := NewRedisClient(config.RedisConfig{Addr: mr.Addr()}, "")
+	t.Cleanup(func() { client.Close() })
+	return NewRedis(client), mr
+}
+
+func TestRedis_SetGet(t *testing.T) {
+	c, _ := newTestRedis(t)
+	ctx := context.Background()
+
+	_, err := c.Get(ctx, "k")
+	assert.ErrorIs(t, err, ErrMiss)
+
+	require.NoError(t, c.Set(ctx, "k", []byte("v"), time.Minute))
+	got, err := c.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("v"), got)
+}
+
+func TestRedis_Expiry(t *testing.T) {
+	c, mr := newTestRedis(t)
+	ctx := context.Background()
+	require.NoError(t, c.Set(ctx, "k", []byte("v"), time.Minute))
+	assert.Equal(t, time.Minute, mr.TTL("k"))
+
+	mr.FastForward(time.Minute)
+
+	_, err := c.Get(ctx, "k")
+	assert.ErrorIs(t, err, ErrMiss)
+}
+
+func TestRedis_Delete(t *testing.T) {
+	c, _ := newTestRedis(t)
+	ctx := context.Background()
+	require.NoError(t, c.Set(ctx, "k", []byte("v"), time.Minute))
+
+	require.NoError(t, c.Delete(ctx, "k"))
+	require.NoError(t, c.Delete(ctx, "k"), "deleting twice is fine")
+
+	_, err := c.Get(ctx, "k")
+	assert.ErrorIs(t, err, ErrMiss)
+}
+
+func TestRedis_ServerDown(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	defer client.Close()
+	c := NewRedis(client)
+	mr.Close()
+	ctx := context.Background()
+
+	_, err := c.Get(ctx, "k")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrMiss, "an outage is not a miss")
+	assert.Error(t, c.Set(ctx, "k", []byte("v"), time.Minute))
+	assert.Error(t, c.Delete(ctx, "k"))
+}
+
+func TestNewRedisClient(t *testing.T) {
+	client := NewRedisClient(config.RedisConfig{Addr: "redis:6380", Username: "api", DB: 3, TLS: true}, "secret")
+	defer client.Close()
+
+	opts := client.Options()
+	assert.Equal(t, "redis:6380", opts.Addr)
+	assert.Equal(t, "api", opts.Username)
+	assert.Equal(t, "secret", opts.Password)
+	assert.Equal(t, 3, opts.DB)
+	assert.NotNil(t, opts.TLSConfig)
+}
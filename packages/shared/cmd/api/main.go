@@ -0,0 +1,263 @@
+// Command api serves the merchant HTTP API and, when grpc.port is set, the
+// internal gRPC payment service.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/addresspool"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/api"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/cache"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/db"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/events"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/health"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/lifecycle"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/locking"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/logging"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/metrics"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/rpc"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tracing"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/walletcheck"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// shutdownTimeout bounds how long in-flight queries get to finish on exit.
+const shutdownTimeout = 10 * time.Second
+
+// addressPoolLease names the lease the replica refilling the address pool
+// holds.
+const addressPoolLease = "address-pool"
+
+// serviceName names the process in traces unless tracing.serviceName does.
+const serviceName = "tron-payment-gateway-api"
+
+func main() {
+	configPath := flag.String("config", "config.yaml", "path to the config file")
+	flag.Parse()
+
+	if err := run(*configPath); err != nil {
+		slog.Error("api failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath string) error {
+	var cfg config.Config
+	if err := cfg.LoadConfigForEnv(configPath); err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	logging.Setup(cfg.Logging)
+
+	mnemonic, err := cfg.WalletMnemonic()
+	if err != nil {
+		return err
+	}
+	// Test clients get wallets of their own mnemonic, never live ones.
+	var testWallets api.WalletDeriver
+	if cfg.TestMode.Enabled {
+		testMnemonic, err := cfg.TestWalletMnemonic()
+		if err != nil {
+			return err
+		}
+		testWallets = api.MnemonicWallets(testMnemonic)
+	}
+	statusSecret, err := cfg.StatusTokenSecret()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := lifecycle.SignalContext(context.Background())
+	defer stop()
+
+	tp, shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, serviceName)
+	if err != nil {
+		return err
+	}
+	pool, err := db.ConnectWithRetry(ctx, &cfg, db.DefaultRetryOptions())
+	if err != nil {
+		return err
+	}
+	if err := db.CheckSchema(ctx, pool, cfg.DatabaseConfig.AutoMigrate); err != nil {
+		return err
+	}
+
+	runner := lifecycle.NewRunner()
+	// Components stop in reverse, so spans are flushed last.
+	runner.Add("tracing", lifecycle.OnStop(shutdownTracing))
+	runner.Add("database", lifecycle.OnStop(func(ctx context.Context) error {
+		return db.GracefulClose(ctx, pool, shutdownTimeout)
+	}))
+
+	reg := metrics.NewRegistry()
+	m := metrics.New(reg)
+	client := tron.NewClient(cfg.Tron, cfg.TronAPIKey(), tron.WithMetrics(m), tron.WithTracerProvider(tp))
+	store := repository.NewStore(pool,
+		repository.WithTracerProvider(tp),
+		repository.WithQueryTimeout(cfg.DatabaseConfig.EffectiveQueryTimeout()),
+		repository.WithMetricsRecorder(m),
+	)
+	var wallets api.WalletDeriver = api.MnemonicWallets(mnemonic)
+	halted, err := checkDerivation(ctx, &cfg, store, wallets, testWallets)
+	if err != nil {
+		return err
+	}
+	if halted {
+		// Wallets already handed out can still be read and paid; only new
+		// ones are refused, pooled ones included.
+		wallets = walletcheck.Halted()
+		if testWallets != nil {
+			testWallets = walletcheck.Halted()
+		}
+		cfg.Payments.AddressPool.Enabled = false
+	}
+	bus := events.NewMemoryBus()
+	transitions := events.NewTypedBus(events.WithDropMetrics(m))
+	opts := []api.Option{
+		api.WithActivationChecker(client),
+		api.WithMetrics(m),
+		api.WithStatusTokens(api.NewStatusTokens(statusSecret, cfg.Payments.StatusTokenTTL.Std())),
+		api.WithEventBus(bus),
+		api.WithTransitionEvents(transitions),
+		api.WithTracerProvider(tp),
+	}
+	if testWallets != nil {
+		opts = append(opts, api.WithTestWallets(testWallets))
+	}
+	if adminToken, err := cfg.AdminToken(); err != nil {
+		slog.Warn("admin API disabled", "reason", err)
+	} else {
+		opts = append(opts, api.WithAdminToken(adminToken))
+	}
+	if cfg.Redis.Enabled() {
+		rdb := cache.NewRedisClient(cfg.Redis, cfg.RedisPassword())
+		runner.Add("redis", lifecycle.OnStop(func(context.Context) error {
+			return rdb.Close()
+		}))
+		rc := cache.NewRedis(rdb)
+		opts = append(opts, api.WithClientCache(rc, cfg.Redis.ClientCacheTTL.Std()), api.WithSummaryCache(rc))
+	}
+	server := api.New(store, wallets, &cfg, opts...)
+	probes := health.NewHandler(health.WithCheck("database", func(ctx context.Context) (any, error) {
+		return nil, db.HealthCheck(ctx, pool)
+	}))
+
+	mux := http.NewServeMux()
+	mux.Handle("/v1/", server)
+	mux.Handle("/admin/", server)
+	mux.Handle("/healthz", probes)
+	mux.Handle("/readyz", probes)
+
+	if cfg.MetricsPort != 0 {
+		runner.Add("metrics server", lifecycle.Loop(func(ctx context.Context) error {
+			return metrics.ListenAndServe(ctx, cfg.MetricsPort, reg)
+		}))
+		runner.Add("pool stats reporter", lifecycle.Loop(func(ctx context.Context) error {
+			m.ReportPoolStats(ctx, pool)
+			return nil
+		}))
+		runner.Add("tron usage reporter", lifecycle.Loop(func(ctx context.Context) error {
+			m.ReportTronUsage(ctx, client)
+			return nil
+		}))
+	}
+	runner.Add("event poller", lifecycle.Loop(events.NewPoller(store, bus).Run))
+	// Changes made here reach the status streams without waiting for a poll.
+	runner.Add("event forwarder", lifecycle.Loop(func(ctx context.Context) error {
+		return transitions.Forward(ctx, bus)
+	}))
+	if poolCfg := cfg.Payments.AddressPool; poolCfg.Enabled {
+		var poolOpts []addresspool.Option
+		if testWallets != nil {
+			poolOpts = append(poolOpts, addresspool.WithTestWallets(testWallets))
+		}
+		refiller := addresspool.New(store, wallets, &cfg, poolOpts...)
+		locker := locking.New(store)
+		runner.Add("address pool refiller", lifecycle.Loop(func(ctx context.Context) error {
+			return locker.RunAsLeader(ctx, addressPoolLease, poolCfg.LeaseTTL.Std(), refiller.Run)
+		}))
+	}
+	if cfg.GRPC.Port != 0 {
+		grpcServer, err := newGRPCServer(&cfg, store, wallets, testWallets, bus, m, tp)
+		if err != nil {
+			return err
+		}
+		runner.Add("grpc server", lifecycle.Loop(func(ctx context.Context) error {
+			return grpcServer.ListenAndServe(ctx, cfg.GRPC.Port)
+		}))
+		slog.Info("grpc listening", "port", cfg.GRPC.Port)
+	}
+	httpServer := api.NewHTTPServer(mux)
+	httpServer.RegisterOnShutdown(server.CloseStreams)
+	runner.Add("api server", lifecycle.HTTPServer(httpServer, fmt.Sprintf(":%d", cfg.AppPort)))
+
+	slog.Info("api listening", "port", cfg.AppPort)
+	// Reload the config on SIGHUP or file change. The logger follows it;
+	// other sections are read at startup and their changes only logged.
+	reloader := config.NewWatcher(configPath, &cfg)
+	logging.FollowReloads(reloader)
+	runner.Add("config reloader", lifecycle.Loop(reloader.Watch))
+
+	return runner.Run(ctx)
+}
+
+// checkDerivation re-derives the newest handed-out wallets unless
+// payments.derivationCheck disables it. A mismatch fails startup, or with
+// onMismatch readOnly reports that the API must derive no new wallets.
+func checkDerivation(ctx context.Context, cfg *config.Config, store walletcheck.Store, wallets, testWallets api.WalletDeriver) (halted bool, err error) {
+	check := cfg.Payments.DerivationCheck
+	if check.Disabled {
+		return false, nil
+	}
+	var opts []walletcheck.Option
+	if testWallets != nil {
+		opts = append(opts, walletcheck.WithTestWallets(testWallets))
+	}
+	res, err := walletcheck.New(store, wallets, opts...).Check(ctx, check.Samples)
+	if err != nil {
+		return false, err
+	}
+	if err := res.Err(); err != nil {
+		if check.OnMismatch != config.OnMismatchReadOnly {
+			return false, fmt.Errorf("refusing to start: %w", err)
+		}
+		slog.Error("serving read-only: no new deposit wallets will be generated", "error", err)
+		return true, nil
+	}
+	return false, nil
+}
+
+// newGRPCServer builds the internal payment service, authenticating callers
+// with the shared secret and, when grpc.tls names a CA, client certificates.
+// testWallets, when set, serves test clients.
+func newGRPCServer(cfg *config.Config, store rpc.Store, wallets, testWallets api.WalletDeriver, bus events.Bus, m *metrics.Metrics, tp trace.TracerProvider) (*rpc.Server, error) {
+	opts := []rpc.Option{rpc.WithMetrics(m), rpc.WithEventBus(bus), rpc.WithTracerProvider(tp)}
+	if testWallets != nil {
+		opts = append(opts, rpc.WithTestWallets(testWallets))
+	}
+	secret, err := cfg.GRPCSecret()
+	switch {
+	case err == nil:
+		opts = append(opts, rpc.WithSharedSecret(secret))
+	case cfg.GRPC.TLS.Mutual():
+		slog.Info("grpc shared secret not set; callers need a client certificate", "reason", err)
+	default:
+		return nil, fmt.Errorf("grpc server has no way to authenticate callers: %w", err)
+	}
+	if cfg.GRPC.TLS.Enabled() {
+		tlsConfig, err := rpc.ServerTLS(cfg.GRPC.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, rpc.WithTLS(tlsConfig))
+	}
+	return rpc.New(store, wallets, cfg, opts...), nil
+}
@@ -0,0 +1,156 @@
+// Command reconciler checks confirmed payments against the chain on a
+// schedule and saves a report of every mismatch it finds.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/db"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/lifecycle"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/locking"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/logging"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/metrics"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/reconciler"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tracing"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+)
+
+// shutdownTimeout bounds how long in-flight queries get to finish on exit.
+const shutdownTimeout = 10 * time.Second
+
+// serviceName names the process in traces unless tracing.serviceName does.
+const serviceName = "tron-payment-gateway-reconciler"
+
+// leaseName is the lease replicas campaign for; only its holder reconciles.
+const leaseName = "reconciler"
+
+func main() {
+	configPath := flag.String("config", "config.yaml", "path to the config file")
+	once := flag.Bool("once", false, "run a single reconciliation and exit")
+	flag.Parse()
+
+	if err := run(*configPath, *once); err != nil {
+		slog.Error("reconciler failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath string, once bool) error {
+	var cfg config.Config
+	if err := cfg.LoadConfigForEnv(configPath); err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	logging.Setup(cfg.Logging)
+	if !cfg.Reconciler.Enabled {
+		return errors.New("reconciler is disabled: set reconciler.enabled")
+	}
+
+	ctx, stop := lifecycle.SignalContext(context.Background())
+	defer stop()
+
+	tp, shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, serviceName)
+	if err != nil {
+		return err
+	}
+	pool, err := db.ConnectWithRetry(ctx, &cfg, db.DefaultRetryOptions())
+	if err != nil {
+		return err
+	}
+	if err := db.CheckSchema(ctx, pool, cfg.DatabaseConfig.AutoMigrate); err != nil {
+		return err
+	}
+	closePool := func(ctx context.Context) error {
+		return db.GracefulClose(ctx, pool, shutdownTimeout)
+	}
+
+	reg := metrics.NewRegistry()
+	m := metrics.New(reg)
+	client := tron.NewClient(cfg.Tron, cfg.TronAPIKey(), tron.WithMetrics(m), tron.WithTracerProvider(tp))
+	store := repository.NewStore(pool,
+		repository.WithTracerProvider(tp),
+		repository.WithQueryTimeout(cfg.DatabaseConfig.EffectiveQueryTimeout()),
+		repository.WithMetricsRecorder(m),
+	)
+	r := reconciler.New(client, store, &cfg, reconciler.WithMetrics(m))
+	locker := locking.New(store)
+	leaseTTL := cfg.Reconciler.LeaseTTL.Std()
+	if once {
+		defer func() {
+			if err := closePool(context.Background()); err != nil {
+				slog.Warn("database pool did not drain", "error", err)
+			}
+			if err := shutdownTracing(context.Background()); err != nil {
+				slog.Warn("spans were not flushed", "error", err)
+			}
+		}()
+		return reconcileOnce(ctx, r, locker, leaseTTL)
+	}
+
+	runner := lifecycle.NewRunner()
+	// Components stop in reverse, so spans are flushed last.
+	runner.Add("tracing", lifecycle.OnStop(shutdownTracing))
+	runner.Add("database", lifecycle.OnStop(closePool))
+	if cfg.MetricsPort != 0 {
+		runner.Add("metrics server", lifecycle.Loop(func(ctx context.Context) error {
+			return metrics.ListenAndServe(ctx, cfg.MetricsPort, reg)
+		}))
+		runner.Add("pool stats reporter", lifecycle.Loop(func(ctx context.Context) error {
+			m.ReportPoolStats(ctx, pool)
+			return nil
+		}))
+		runner.Add("tron usage reporter", lifecycle.Loop(func(ctx context.Context) error {
+			m.ReportTronUsage(ctx, client)
+			return nil
+		}))
+	}
+	runner.Add("reconciler", lifecycle.Loop(func(ctx context.Context) error {
+		return locker.RunAsLeader(ctx, leaseName, leaseTTL, r.Run)
+	}))
+	// Reload the config on SIGHUP or file change. The logger follows it;
+	// other sections are read at startup and their changes only logged.
+	reloader := config.NewWatcher(configPath, &cfg)
+	logging.FollowReloads(reloader)
+	runner.Add("config reloader", lifecycle.Loop(reloader.Watch))
+
+	return runner.Run(ctx)
+}
+
+// reconcileOnce runs a single reconciliation under the lease, so two runs
+// never save overlapping reports.
+func reconcileOnce(ctx context.Context, r *reconciler.Reconciler, locker *locking.Locker, ttl time.Duration) error {
+	lease, err := locker.AcquireLeader(ctx, leaseName, ttl)
+	if errors.Is(err, locking.ErrNotLeader) {
+		return errors.New("another reconciler is running: try again once it stops")
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lease.Release(context.WithoutCancel(ctx)); err != nil {
+			slog.Warn("failed to release reconciler lease", "error", err)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-lease.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	_, err = r.RunOnce(ctx)
+	if lostErr := lease.Err(); lostErr != nil {
+		return fmt.Errorf("reconciliation stopped after losing the lease: %w", lostErr)
+	}
+	return err
+}
@@ -0,0 +1,155 @@
+// Command retention deletes logs past the retention period of their event
+// type on a schedule.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/db"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/lifecycle"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/locking"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/logging"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/metrics"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/retention"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tracing"
+)
+
+// shutdownTimeout bounds how long in-flight queries get to finish on exit.
+const shutdownTimeout = 10 * time.Second
+
+// serviceName names the process in traces unless tracing.serviceName does.
+const serviceName = "tron-payment-gateway-retention"
+
+// leaseName is the lease replicas campaign for; only its holder prunes.
+const leaseName = "retention"
+
+func main() {
+	configPath := flag.String("config", "config.yaml", "path to the config file")
+	once := flag.Bool("once", false, "prune once and exit")
+	dryRun := flag.Bool("dry-run", false, "count the logs due for pruning without deleting them (default retention.dryRun)")
+	flag.Parse()
+
+	if err := run(*configPath, *once, *dryRun); err != nil {
+		slog.Error("retention failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath string, once, dryRun bool) error {
+	var cfg config.Config
+	if err := cfg.LoadConfigForEnv(configPath); err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	logging.Setup(cfg.Logging)
+	if !cfg.Retention.Enabled {
+		return errors.New("retention is disabled: set retention.enabled")
+	}
+
+	ctx, stop := lifecycle.SignalContext(context.Background())
+	defer stop()
+
+	tp, shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, serviceName)
+	if err != nil {
+		return err
+	}
+	pool, err := db.ConnectWithRetry(ctx, &cfg, db.DefaultRetryOptions())
+	if err != nil {
+		return err
+	}
+	if err := db.CheckSchema(ctx, pool, cfg.DatabaseConfig.AutoMigrate); err != nil {
+		return err
+	}
+	closePool := func(ctx context.Context) error {
+		return db.GracefulClose(ctx, pool, shutdownTimeout)
+	}
+
+	reg := metrics.NewRegistry()
+	m := metrics.New(reg)
+	store := repository.NewStore(pool,
+		repository.WithTracerProvider(tp),
+		repository.WithQueryTimeout(cfg.DatabaseConfig.EffectiveQueryTimeout()),
+		repository.WithMetricsRecorder(m),
+	)
+	opts := []retention.Option{retention.WithMetrics(m)}
+	if dryRun {
+		opts = append(opts, retention.WithDryRun(true))
+	}
+	p := retention.New(store, &cfg, opts...)
+	locker := locking.New(store)
+	leaseTTL := cfg.Retention.LeaseTTL.Std()
+	if once {
+		defer func() {
+			if err := closePool(context.Background()); err != nil {
+				slog.Warn("database pool did not drain", "error", err)
+			}
+			if err := shutdownTracing(context.Background()); err != nil {
+				slog.Warn("spans were not flushed", "error", err)
+			}
+		}()
+		return pruneOnce(ctx, p, locker, leaseTTL)
+	}
+
+	runner := lifecycle.NewRunner()
+	// Components stop in reverse, so spans are flushed last.
+	runner.Add("tracing", lifecycle.OnStop(shutdownTracing))
+	runner.Add("database", lifecycle.OnStop(closePool))
+	if cfg.MetricsPort != 0 {
+		runner.Add("metrics server", lifecycle.Loop(func(ctx context.Context) error {
+			return metrics.ListenAndServe(ctx, cfg.MetricsPort, reg)
+		}))
+		runner.Add("pool stats reporter", lifecycle.Loop(func(ctx context.Context) error {
+			m.ReportPoolStats(ctx, pool)
+			return nil
+		}))
+	}
+	runner.Add("retention", lifecycle.Loop(func(ctx context.Context) error {
+		return locker.RunAsLeader(ctx, leaseName, leaseTTL, p.Run)
+	}))
+	// Reload the config on SIGHUP or file change. The logger follows it;
+	// other sections are read at startup and their changes only logged.
+	reloader := config.NewWatcher(configPath, &cfg)
+	logging.FollowReloads(reloader)
+	runner.Add("config reloader", lifecycle.Loop(reloader.Watch))
+
+	return runner.Run(ctx)
+}
+
+// pruneOnce prunes once under the lease, so two replicas never delete the
+// same rows side by side.
+func pruneOnce(ctx context.Context, p *retention.Pruner, locker *locking.Locker, ttl time.Duration) error {
+	lease, err := locker.AcquireLeader(ctx, leaseName, ttl)
+	if errors.Is(err, locking.ErrNotLeader) {
+		return errors.New("another retention job is running: try again once it stops")
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lease.Release(context.WithoutCancel(ctx)); err != nil {
+			slog.Warn("failed to release retention lease", "error", err)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-lease.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	_, err = p.RunOnce(ctx)
+	if lostErr := lease.Err(); lostErr != nil {
+		return fmt.Errorf("pruning stopped after losing the lease: %w", lostErr)
+	}
+	return err
+}
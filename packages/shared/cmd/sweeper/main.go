@@ -0,0 +1,161 @@
+// Command sweeper moves confirmed payments from their deposit wallets to the
+// cold wallet.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/db"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/lifecycle"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/locking"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/logging"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/metrics"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/sweeper"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tracing"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+)
+
+// shutdownTimeout bounds how long in-flight queries get to finish on exit.
+const shutdownTimeout = 10 * time.Second
+
+// serviceName names the process in traces unless tracing.serviceName does.
+const serviceName = "tron-payment-gateway-sweeper"
+
+// leaseName is the lease replicas campaign for; only its holder sweeps.
+const leaseName = "sweeper"
+
+func main() {
+	configPath := flag.String("config", "config.yaml", "path to the config file")
+	once := flag.Bool("once", false, "run a single sweep and exit")
+	flag.Parse()
+
+	if err := run(*configPath, *once); err != nil {
+		slog.Error("sweeper failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath string, once bool) error {
+	var cfg config.Config
+	if err := cfg.LoadConfigForEnv(configPath); err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	logging.Setup(cfg.Logging)
+	if !cfg.Sweeper.Enabled {
+		return errors.New("sweeper is disabled: set sweeper.enabled")
+	}
+	mnemonic, err := cfg.WalletMnemonic()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := lifecycle.SignalContext(context.Background())
+	defer stop()
+
+	tp, shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, serviceName)
+	if err != nil {
+		return err
+	}
+	pool, err := db.ConnectWithRetry(ctx, &cfg, db.DefaultRetryOptions())
+	if err != nil {
+		return err
+	}
+	if err := db.CheckSchema(ctx, pool, cfg.DatabaseConfig.AutoMigrate); err != nil {
+		return err
+	}
+	closePool := func(ctx context.Context) error {
+		return db.GracefulClose(ctx, pool, shutdownTimeout)
+	}
+
+	reg := metrics.NewRegistry()
+	m := metrics.New(reg)
+	client := tron.NewClient(cfg.Tron, cfg.TronAPIKey(), tron.WithMetrics(m), tron.WithTracerProvider(tp))
+	store := repository.NewStore(pool,
+		repository.WithTracerProvider(tp),
+		repository.WithQueryTimeout(cfg.DatabaseConfig.EffectiveQueryTimeout()),
+		repository.WithMetricsRecorder(m),
+	)
+	s := sweeper.New(client, store, sweeper.MnemonicKeys(mnemonic), &cfg)
+	locker := locking.New(store)
+	leaseTTL := cfg.Sweeper.LeaseTTL.Std()
+	if once {
+		defer func() {
+			if err := closePool(context.Background()); err != nil {
+				slog.Warn("database pool did not drain", "error", err)
+			}
+			if err := shutdownTracing(context.Background()); err != nil {
+				slog.Warn("spans were not flushed", "error", err)
+			}
+		}()
+		return sweepOnce(ctx, s, locker, leaseTTL, cfg.Sweeper.DryRun)
+	}
+
+	runner := lifecycle.NewRunner()
+	// Components stop in reverse, so spans are flushed last.
+	runner.Add("tracing", lifecycle.OnStop(shutdownTracing))
+	runner.Add("database", lifecycle.OnStop(closePool))
+	if cfg.MetricsPort != 0 {
+		runner.Add("metrics server", lifecycle.Loop(func(ctx context.Context) error {
+			return metrics.ListenAndServe(ctx, cfg.MetricsPort, reg)
+		}))
+		runner.Add("pool stats reporter", lifecycle.Loop(func(ctx context.Context) error {
+			m.ReportPoolStats(ctx, pool)
+			return nil
+		}))
+		runner.Add("tron usage reporter", lifecycle.Loop(func(ctx context.Context) error {
+			m.ReportTronUsage(ctx, client)
+			return nil
+		}))
+	}
+	runner.Add("sweeper", lifecycle.Loop(func(ctx context.Context) error {
+		return locker.RunAsLeader(ctx, leaseName, leaseTTL, s.Run)
+	}))
+	// Reload the config on SIGHUP or file change. The logger follows it;
+	// other sections are read at startup and their changes only logged.
+	reloader := config.NewWatcher(configPath, &cfg)
+	logging.FollowReloads(reloader)
+	runner.Add("config reloader", lifecycle.Loop(reloader.Watch))
+
+	return runner.Run(ctx)
+}
+
+// sweepOnce runs a single sweep under the lease, so it never overlaps a
+// running sweeper replica.
+func sweepOnce(ctx context.Context, s *sweeper.Sweeper, locker *locking.Locker, ttl time.Duration, dryRun bool) error {
+	lease, err := locker.AcquireLeader(ctx, leaseName, ttl)
+	if errors.Is(err, locking.ErrNotLeader) {
+		return errors.New("another sweeper is running: try again once it stops")
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lease.Release(context.WithoutCancel(ctx)); err != nil {
+			slog.Warn("failed to release sweeper lease", "error", err)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-lease.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	transfers, err := s.RunOnce(ctx)
+	slog.Info("sweep finished", "transfers", len(transfers), "dry_run", dryRun)
+	if lostErr := lease.Err(); lostErr != nil {
+		return fmt.Errorf("sweep stopped after losing the lease: %w", lostErr)
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/api"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+func testAccount() repository.Account {
+	return repository.Account{ID: testAccountID, ClientID: testClientID, Name: "main", CreatedAt: testCreatedAt}
+}
+
+func TestAccountCreate(t *testing.T) {
+	ta := newTestApp(t)
+	ta.store.On("GetClientByID", mock.Anything, testClientID).Return(testClient(), nil).Once()
+	ta.store.On("CreateAccount", mock.Anything, repository.CreateAccountParams{ClientID: testClientID, Name: "main"}).
+		Return(testAccount(), nil).Once()
+	expectAudit(ta.store, api.EventAccountCreated)
+
+	err := ta.run(context.Background(), []string{"account", "create", "--client", testClientID.String(), "--name", "main", "--actor", "alice", "--json"})
+	require.NoError(t, err)
+	var rec accountRecord
+	ta.decode(t, &rec)
+	assert.Equal(t, testAccountID, rec.ID)
+	assert.Equal(t, "2025-01-02T03:04:05Z", rec.CreatedAt)
+}
+
+func TestAccountCreate_UnknownClient(t *testing.T) {
+	ta := newTestApp(t)
+	ta.store.On("GetClientByID", mock.Anything, testClientID).Return(repository.Client{}, pgx.ErrNoRows).Once()
+
+	err := ta.run(context.Background(), []string{"account", "create", "--client", testClientID.String(), "--name", "main"})
+	require.ErrorIs(t, err, errClientNotFound)
+}
+
+func TestAccountCreate_Validation(t *testing.T) {
+	ta := newTestApp(t)
+	require.ErrorContains(t, ta.run(context.Background(), []string{"account", "create", "--name", "main"}), "--client is required")
+	require.ErrorContains(t, ta.run(context.Background(), []string{"account", "create", "--client", testClientID.String()}), "--name is required")
+}
+
+func TestAccountList(t *testing.T) {
+	ta := newTestApp(t)
+	ta.store.On("GetAccountsByClientID", mock.Anything, repository.GetAccountsByClientIDParams{ClientID: testClientID}).Return([]repository.GetAccountsByClientIDRow{{ID: testAccountID, ClientID: testClientID, Name: "main"}}, nil).Once()
+
+	require.NoError(t, ta.run(context.Background(), []string{"account", "list", "--client", testClientID.String()}))
+	out := ta.stdout.String()
+	assert.Contains(t, out, "CLIENT")
+	assert.Contains(t, out, testAccountID.String())
+	assert.Contains(t, out, "main")
+}
+
+func TestAccountList_JSON(t *testing.T) {
+	ta := newTestApp(t)
+	ta.store.On("GetAccountsByClientID", mock.Anything, repository.GetAccountsByClientIDParams{ClientID: testClientID}).Return([]repository.GetAccountsByClientIDRow{}, nil).Once()
+
+	require.NoError(t, ta.run(context.Background(), []string{"account", "list", "--client", testClientID.String(), "--json"}))
+	assert.JSONEq(t, "[]", ta.stdout.String())
+}
+
+func TestAccountList_IncludeDeleted(t *testing.T) {
+	ta := newTestApp(t)
+	ta.store.On("GetAccountsByClientID", mock.Anything, repository.GetAccountsByClientIDParams{ClientID: testClientID, IncludeDeleted: true}).
+		Return([]repository.GetAccountsByClientIDRow{{ID: testAccountID, ClientID: testClientID, Name: "main", DeletedAt: testCreatedAt}}, nil).Once()
+
+	require.NoError(t, ta.run(context.Background(), []string{"account", "list", "--client", testClientID.String(), "--include-deleted", "--json"}))
+	var recs []accountRecord
+	ta.decode(t, &recs)
+	require.Len(t, recs, 1)
+	assert.Equal(t, "2025-01-02T03:04:05Z", recs[0].DeletedAt)
+}
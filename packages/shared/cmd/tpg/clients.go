@@ -0,0 +1,497 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/api"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/cache"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+
+	// adminTimeout bounds a request to the admin API.
+	adminTimeout = 30 * time.Second
+)
+
+// errClientNotFound is returned for a client ID that matches no client.
+var errClientNotFound = errors.New("client not found")
+
+// clientRecord is a client as the admin API shows it. APIKey is only set
+// when the key was just created.
+type clientRecord struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Mode      string    `json:"mode"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt string    `json:"created_at"`
+	APIKey    string    `json:"api_key,omitempty"`
+}
+
+type clientPage struct {
+	Clients    []clientRecord `json:"clients"`
+	NextCursor *uuid.UUID     `json:"next_cursor,omitempty"`
+}
+
+func newClientRecord(c repository.Client) clientRecord {
+	return clientRecord{
+		ID:        c.ID,
+		Name:      c.Name,
+		Mode:      c.Mode,
+		IsActive:  c.IsActive == nil || *c.IsActive,
+		CreatedAt: formatTime(c.CreatedAt),
+	}
+}
+
+// clientAdmin manages clients, in the database or through the admin API.
+type clientAdmin interface {
+	CreateClient(ctx context.Context, name, mode string) (clientRecord, error)
+	ListClients(ctx context.Context, after uuid.UUID, limit int) (clientPage, error)
+	DeactivateClient(ctx context.Context, id uuid.UUID) (clientRecord, error)
+	RotateClientKey(ctx context.Context, id uuid.UUID) (clientRecord, error)
+}
+
+// clientFlags adds the flags choosing how the client commands reach the
+// gateway and returns the function that builds the clientAdmin they chose.
+func (a *app) clientFlags(fs *flag.FlagSet) func(context.Context) (clientAdmin, error) {
+	apiURL := fs.String("api-url", "", "admin API base URL; the token is read from "+config.DefaultAdminTokenEnv)
+	actor := fs.String("actor", os.Getenv("USER"), "operator named in the audit log")
+	return func(ctx context.Context) (clientAdmin, error) {
+		if *apiURL != "" {
+			return newAPIClients(*apiURL, os.Getenv(config.DefaultAdminTokenEnv), *actor)
+		}
+		store, err := a.database(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c, err := a.cache()
+		if err != nil {
+			return nil, err
+		}
+		return &dbClients{store: store, cache: c, actor: cliActor(*actor)}, nil
+	}
+}
+
+// cliActor is the audit principal of an operator using tpg against the
+// database, "tpg" or "tpg:<name>".
+func cliActor(name string) string {
+	if name == "" {
+		return "tpg"
+	}
+	return "tpg:" + name
+}
+
+func (a *app) clientCreate(ctx context.Context, args []string) error {
+	fs := a.flags("client create")
+	name := fs.String("name", "", "client name (required)")
+	mode := fs.String("mode", config.ModeLive, "client mode, live or test")
+	admin := a.clientFlags(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*name) == "" {
+		return errors.New("--name is required")
+	}
+	if *mode != config.ModeLive && *mode != config.ModeTest {
+		return fmt.Errorf("--mode must be live or test, got %q", *mode)
+	}
+	clients, err := admin(ctx)
+	if err != nil {
+		return err
+	}
+	rec, err := clients.CreateClient(ctx, strings.TrimSpace(*name), *mode)
+	if err != nil {
+		return err
+	}
+	return a.printClient(rec)
+}
+
+func (a *app) clientList(ctx context.Context, args []string) error {
+	fs := a.flags("client list")
+	limit := fs.Int("limit", defaultPageSize, fmt.Sprintf("clients per page, at most %d", maxPageSize))
+	after := fs.String("after", "", "list clients after this ID, the next cursor of an earlier page")
+	admin := a.clientFlags(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *limit < 1 || *limit > maxPageSize {
+		return fmt.Errorf("--limit must be between 1 and %d", maxPageSize)
+	}
+	cursor, err := optionalID("after", *after)
+	if err != nil {
+		return err
+	}
+	clients, err := admin(ctx)
+	if err != nil {
+		return err
+	}
+	page, err := clients.ListClients(ctx, cursor, *limit)
+	if err != nil {
+		return err
+	}
+	if a.json {
+		return a.printJSON(page)
+	}
+	if err := a.printClientTable(page.Clients...); err != nil {
+		return err
+	}
+	if page.NextCursor != nil {
+		fmt.Fprintf(a.stderr, "more clients: tpg client list --after %s\n", page.NextCursor)
+	}
+	return nil
+}
+
+func (a *app) clientDeactivate(ctx context.Context, args []string) error {
+	fs := a.flags("client deactivate")
+	id := fs.String("id", "", "client ID (required)")
+	yes := fs.Bool("yes", false, "confirm the client's API key stops working")
+	admin := a.clientFlags(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	clientID, err := requiredID("id", *id)
+	if err != nil {
+		return err
+	}
+	if err := confirm(*yes, "deactivating client "+clientID.String()); err != nil {
+		return err
+	}
+	clients, err := admin(ctx)
+	if err != nil {
+		return err
+	}
+	rec, err := clients.DeactivateClient(ctx, clientID)
+	if err != nil {
+		return err
+	}
+	return a.printClient(rec)
+}
+
+func (a *app) clientRotateKey(ctx context.Context, args []string) error {
+	fs := a.flags("client rotate-key")
+	id := fs.String("id", "", "client ID (required)")
+	admin := a.clientFlags(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	clientID, err := requiredID("id", *id)
+	if err != nil {
+		return err
+	}
+	clients, err := admin(ctx)
+	if err != nil {
+		return err
+	}
+	rec, err := clients.RotateClientKey(ctx, clientID)
+	if err != nil {
+		return err
+	}
+	return a.printClient(rec)
+}
+
+// printClient prints rec, with its API key when it was just created.
+func (a *app) printClient(rec clientRecord) error {
+	if a.json {
+		return a.printJSON(rec)
+	}
+	if err := a.printClientTable(rec); err != nil {
+		return err
+	}
+	if rec.APIKey != "" {
+		fmt.Fprintln(a.stderr, "The API key is shown only once; hand it to the merchant now.")
+	}
+	return nil
+}
+
+func (a *app) printClientTable(recs ...clientRecord) error {
+	header := []string{"ID", "NAME", "MODE", "ACTIVE", "CREATED"}
+	withKeys := len(recs) == 1 && recs[0].APIKey != ""
+	if withKeys {
+		header = append(header, "API KEY")
+	}
+	rows := make([][]string, 0, len(recs))
+	for _, r := range recs {
+		row := []string{r.ID.String(), r.Name, orDash(r.Mode), strconv.FormatBool(r.IsActive), r.CreatedAt}
+		if withKeys {
+			row = append(row, r.APIKey)
+		}
+		rows = append(rows, row)
+	}
+	return a.printTable(header, rows)
+}
+
+func requiredID(flag, s string) (uuid.UUID, error) {
+	if s == "" {
+		return uuid.Nil, fmt.Errorf("--%s is required", flag)
+	}
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("--%s must be a UUID: %w", flag, err)
+	}
+	return id, nil
+}
+
+func optionalID(flag, s string) (uuid.UUID, error) {
+	if s == "" {
+		return uuid.Nil, nil
+	}
+	return requiredID(flag, s)
+}
+
+// dbClients changes clients in the database the way the admin API does:
+// each change is audited in its transaction, and the API's cached lookup
+// of the client's old key is evicted after it.
+type dbClients struct {
+	store Store
+	cache cache.Cache
+	actor string
+}
+
+func (c *dbClients) CreateClient(ctx context.Context, name, mode string) (clientRecord, error) {
+	key, err := api.NewAPIKey(mode)
+	if err != nil {
+		return clientRecord{}, err
+	}
+	var client repository.Client
+	err = c.store.ExecTx(ctx, func(q repository.Querier) error {
+		client, err = q.CreateClient(ctx, repository.CreateClientParams{Name: name, ApiKey: key, Mode: mode})
+		if err != nil {
+			return fmt.Errorf("failed to insert client: %w", err)
+		}
+		return audit(ctx, q, c.actor, api.EventClientCreated, fmt.Sprintf("client %q created", name),
+			clientAuditLog{ClientID: client.ID, Name: name, Mode: mode})
+	})
+	if err != nil {
+		return clientRecord{}, fmt.Errorf("failed to create client: %w", err)
+	}
+	rec := newClientRecord(client)
+	rec.APIKey = key
+	return rec, nil
+}
+
+func (c *dbClients) ListClients(ctx context.Context, after uuid.UUID, limit int) (clientPage, error) {
+	// One extra row tells whether there is a next page.
+	clients, err := c.store.ListClients(ctx, repository.ListClientsParams{AfterID: after, Limit: int32(limit + 1)})
+	if err != nil {
+		return clientPage{}, fmt.Errorf("failed to list clients: %w", err)
+	}
+	page := clientPage{Clients: make([]clientRecord, 0, min(len(clients), limit))}
+	if len(clients) > limit {
+		clients = clients[:limit]
+		next := clients[limit-1].ID
+		page.NextCursor = &next
+	}
+	for _, client := range clients {
+		page.Clients = append(page.Clients, newClientRecord(client))
+	}
+	return page, nil
+}
+
+func (c *dbClients) DeactivateClient(ctx context.Context, id uuid.UUID) (clientRecord, error) {
+	return c.update(ctx, id, api.EventClientDeactivated, "client deactivated", nil,
+		func(q repository.Querier, _ repository.Client) (repository.Client, error) {
+			return q.DeactivateClient(ctx, id)
+		})
+}
+
+// RotateClientKey gives the client a new key with the prefix of its mode.
+func (c *dbClients) RotateClientKey(ctx context.Context, id uuid.UUID) (clientRecord, error) {
+	var key string
+	return c.update(ctx, id, api.EventClientKeyRotated, "client API key rotated", &key,
+		func(q repository.Querier, previous repository.Client) (repository.Client, error) {
+			var err error
+			if key, err = api.NewAPIKey(previous.Mode); err != nil {
+				return repository.Client{}, err
+			}
+			return q.RotateClientAPIKey(ctx, repository.RotateClientAPIKeyParams{ApiKey: key, ID: id})
+		})
+}
+
+// update applies change to client id, as it was before, and audits it as
+// event in one transaction, then evicts the cached lookup of the client's old
+// key. key, when set by change, is returned as the client's new API key.
+func (c *dbClients) update(ctx context.Context, id uuid.UUID, event, msg string, key *string,
+	change func(repository.Querier, repository.Client) (repository.Client, error)) (clientRecord, error) {
+	var previous, client repository.Client
+	err := c.store.ExecTx(ctx, func(q repository.Querier) error {
+		var err error
+		if previous, err = q.GetClientByID(ctx, id); err != nil {
+			return err
+		}
+		if client, err = change(q, previous); err != nil {
+			return err
+		}
+		return c.audit(ctx, q, event, msg, id, "")
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return clientRecord{}, fmt.Errorf("%w: %s", errClientNotFound, id)
+	}
+	if err != nil {
+		return clientRecord{}, fmt.Errorf("failed to update client %s: %w", id, err)
+	}
+	if c.cache != nil {
+		if err := c.cache.Delete(ctx, api.ClientCacheKey(previous.ApiKey)); err != nil {
+			return clientRecord{}, fmt.Errorf("client %s updated but its cached API key was not evicted: %w", id, err)
+		}
+	}
+	rec := newClientRecord(client)
+	if key != nil {
+		rec.APIKey = *key
+	}
+	return rec, nil
+}
+
+// clientAuditLog is the raw data of a client audit log, as the admin API
+// writes it.
+type clientAuditLog struct {
+	ClientID uuid.UUID `json:"client_id"`
+	Name     string    `json:"name,omitempty"`
+	Mode     string    `json:"mode,omitempty"`
+}
+
+func (c *dbClients) audit(ctx context.Context, q repository.Querier, event, msg string, id uuid.UUID, name string) error {
+	return audit(ctx, q, c.actor, event, msg, clientAuditLog{ClientID: id, Name: name})
+}
+
+// audit logs event as done by actor, in the transaction of q.
+func audit(ctx context.Context, q repository.Querier, actor, event, msg string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit data: %w", err)
+	}
+	if err := q.CreateLog(ctx, repository.CreateLogParams{
+		EventType: event,
+		Message:   &msg,
+		RawData:   raw,
+		Actor:     &actor,
+	}); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// apiClients manages clients through the admin API.
+type apiClients struct {
+	baseURL *url.URL
+	token   string
+	actor   string
+	http    *http.Client
+}
+
+func newAPIClients(baseURL, token, actor string) (*apiClients, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("--api-url must be an http or https URL, got %q", baseURL)
+	}
+	if token == "" {
+		return nil, fmt.Errorf("admin token is empty: set %s", config.DefaultAdminTokenEnv)
+	}
+	return &apiClients{baseURL: u, token: token, actor: actor, http: &http.Client{Timeout: adminTimeout}}, nil
+}
+
+func (c *apiClients) CreateClient(ctx context.Context, name, mode string) (clientRecord, error) {
+	var rec clientRecord
+	err := c.do(ctx, http.MethodPost, "/admin/clients", nil, map[string]string{"name": name, "mode": mode}, &rec)
+	return rec, err
+}
+
+func (c *apiClients) ListClients(ctx context.Context, after uuid.UUID, limit int) (clientPage, error) {
+	query := url.Values{"limit": {strconv.Itoa(limit)}}
+	if after != uuid.Nil {
+		query.Set("cursor", after.String())
+	}
+	var page clientPage
+	err := c.do(ctx, http.MethodGet, "/admin/clients", query, nil, &page)
+	return page, err
+}
+
+func (c *apiClients) DeactivateClient(ctx context.Context, id uuid.UUID) (clientRecord, error) {
+	var rec clientRecord
+	err := c.do(ctx, http.MethodPost, "/admin/clients/"+id.String()+"/deactivate", nil, nil, &rec)
+	return rec, err
+}
+
+func (c *apiClients) RotateClientKey(ctx context.Context, id uuid.UUID) (clientRecord, error) {
+	var rec clientRecord
+	err := c.do(ctx, http.MethodPost, "/admin/clients/"+id.String()+"/rotate-key", nil, nil, &rec)
+	return rec, err
+}
+
+// apiError is the problem document the API answers a failed request with.
+type apiError struct {
+	Code    string            `json:"code"`
+	Message string            `json:"detail"`
+	Fields  map[string]string `json:"fields"`
+}
+
+// do sends a request to path with body, if any, as JSON and decodes the
+// response into out. Error responses are returned as errors.
+func (c *apiClients) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	u := c.baseURL.JoinPath(path)
+	u.RawQuery = query.Encode()
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.actor != "" {
+		req.Header.Set("X-Admin-Actor", c.actor)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read %s %s response: %w", method, path, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr apiError
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Message == "" {
+			return fmt.Errorf("%s %s returned %d", method, path, resp.StatusCode)
+		}
+		if resp.StatusCode == http.StatusNotFound && apiErr.Code == "client_not_found" {
+			return errClientNotFound
+		}
+		msg := apiErr.Message
+		for _, field := range sortedKeys(apiErr.Fields) {
+			msg += fmt.Sprintf("; %s %s", field, apiErr.Fields[field])
+		}
+		return fmt.Errorf("%s %s returned %d: %s", method, path, resp.StatusCode, msg)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/api"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/cache"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+func testClient() repository.Client {
+	active := true
+	return repository.Client{ID: testClientID, Name: "acme", ApiKey: "old-key", IsActive: &active, CreatedAt: testCreatedAt}
+}
+
+func expectAudit(store *mockStore, event string) {
+	store.On("CreateLog", mock.Anything, mock.MatchedBy(func(arg repository.CreateLogParams) bool {
+		return arg.EventType == event && arg.Actor != nil && *arg.Actor == "tpg:alice"
+	})).Return(nil).Once()
+}
+
+func TestClientCreate(t *testing.T) {
+	ta := newTestApp(t)
+	ta.store.On("CreateClient", mock.Anything, mock.MatchedBy(func(arg repository.CreateClientParams) bool {
+		return arg.Name == "acme" && arg.ApiKey != ""
+	})).Return(testClient(), nil).Once()
+	expectAudit(ta.store, api.EventClientCreated)
+
+	err := ta.run(context.Background(), []string{"client", "create", "--name", " acme ", "--actor", "alice", "--json"})
+	require.NoError(t, err)
+	var rec clientRecord
+	ta.decode(t, &rec)
+	assert.Equal(t, testClientID, rec.ID)
+	assert.True(t, rec.IsActive)
+	assert.NotEmpty(t, rec.APIKey)
+	assert.NotEqual(t, "old-key", rec.APIKey)
+}
+
+func TestClientCreate_Table(t *testing.T) {
+	ta := newTestApp(t)
+	ta.store.On("CreateClient", mock.Anything, mock.Anything).Return(testClient(), nil).Once()
+	expectAudit(ta.store, api.EventClientCreated)
+
+	require.NoError(t, ta.run(context.Background(), []string{"client", "create", "--name", "acme", "--actor", "alice"}))
+	assert.Contains(t, ta.stdout.String(), "API KEY")
+	assert.Contains(t, ta.stdout.String(), testClientID.String())
+	assert.Contains(t, ta.stderr.String(), "shown only once")
+}
+
+func TestClientCreate_NameRequired(t *testing.T) {
+	ta := newTestApp(t)
+	require.ErrorContains(t, ta.run(context.Background(), []string{"client", "create", "--name", "  "}), "--name is required")
+}
+
+func TestClientList(t *testing.T) {
+	ta := newTestApp(t)
+	second := testClient()
+	second.ID = testAccountID
+	ta.store.On("ListClients", mock.Anything, repository.ListClientsParams{AfterID: testPaymentID, Limit: 2}).
+		Return([]repository.Client{testClient(), second}, nil).Once()
+
+	err := ta.run(context.Background(), []string{"client", "list", "--limit", "1", "--after", testPaymentID.String(), "--json"})
+	require.NoError(t, err)
+	var page clientPage
+	ta.decode(t, &page)
+	require.Len(t, page.Clients, 1)
+	assert.Empty(t, page.Clients[0].APIKey, "keys are never listed")
+	require.NotNil(t, page.NextCursor)
+	assert.Equal(t, testClientID, *page.NextCursor)
+}
+
+func TestClientList_Table(t *testing.T) {
+	ta := newTestApp(t)
+	ta.store.On("ListClients", mock.Anything, repository.ListClientsParams{Limit: defaultPageSize + 1}).
+		Return([]repository.Client{testClient()}, nil).Once()
+
+	require.NoError(t, ta.run(context.Background(), []string{"client", "list"}))
+	assert.Contains(t, ta.stdout.String(), "ID")
+	assert.Contains(t, ta.stdout.String(), "acme")
+	assert.NotContains(t, ta.stdout.String(), "old-key")
+	assert.Empty(t, ta.stderr.String(), "no next page")
+}
+
+func TestClientList_InvalidLimit(t *testing.T) {
+	ta := newTestApp(t)
+	require.ErrorContains(t, ta.run(context.Background(), []string{"client", "list", "--limit", "0"}), "--limit")
+}
+
+func TestClientDeactivate_RequiresYes(t *testing.T) {
+	ta := newTestApp(t)
+	err := ta.run(context.Background(), []string{"client", "deactivate", "--id", testClientID.String()})
+	require.ErrorIs(t, err, errNotConfirmed)
+}
+
+func TestClientDeactivate(t *testing.T) {
+	ta := newTestApp(t)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { rdb.Close() })
+	ta.clientCache = cache.NewRedis(rdb)
+	require.NoError(t, mr.Set(api.ClientCacheKey("old-key"), "{}"))
+
+	inactive := testClient()
+	*inactive.IsActive = false
+	ta.store.On("GetClientByID", mock.Anything, testClientID).Return(testClient(), nil).Once()
+	ta.store.On("DeactivateClient", mock.Anything, testClientID).Return(inactive, nil).Once()
+	expectAudit(ta.store, api.EventClientDeactivated)
+
+	err := ta.run(context.Background(), []string{"client", "deactivate", "--id", testClientID.String(), "--yes", "--actor", "alice", "--json"})
+	require.NoError(t, err)
+	var rec clientRecord
+	ta.decode(t, &rec)
+	assert.False(t, rec.IsActive)
+	assert.False(t, mr.Exists(api.ClientCacheKey("old-key")), "the old key's cached lookup is evicted")
+}
+
+func TestClientDeactivate_NotFound(t *testing.T) {
+	ta := newTestApp(t)
+	ta.store.On("GetClientByID", mock.Anything, testClientID).Return(repository.Client{}, pgx.ErrNoRows).Once()
+
+	err := ta.run(context.Background(), []string{"client", "deactivate", "--id", testClientID.String(), "--yes"})
+	require.ErrorIs(t, err, errClientNotFound)
+}
+
+func TestClientRotateKey(t *testing.T) {
+	ta := newTestApp(t)
+	var newKey string
+	ta.store.On("GetClientByID", mock.Anything, testClientID).Return(testClient(), nil).Once()
+	ta.store.On("RotateClientAPIKey", mock.Anything, mock.MatchedBy(func(arg repository.RotateClientAPIKeyParams) bool {
+		newKey = arg.ApiKey
+		return arg.ID == testClientID && arg.ApiKey != "old-key"
+	})).Return(testClient(), nil).Once()
+	expectAudit(ta.store, api.EventClientKeyRotated)
+
+	err := ta.run(context.Background(), []string{"client", "rotate-key", "--id", testClientID.String(), "--actor", "alice", "--json"})
+	require.NoError(t, err)
+	var rec clientRecord
+	ta.decode(t, &rec)
+	assert.Equal(t, newKey, rec.APIKey)
+}
+
+func TestClientRotateKey_InvalidID(t *testing.T) {
+	ta := newTestApp(t)
+	require.ErrorContains(t, ta.run(context.Background(), []string{"client", "rotate-key", "--id", "nope"}), "--id must be a UUID")
+}
+
+// adminAPI serves the admin client routes the way the API does, recording
+// the requests it gets.
+func adminAPI(t *testing.T) (*httptest.Server, *[]*http.Request) {
+	t.Helper()
+	var reqs []*http.Request
+	mux := http.NewServeMux()
+	write := func(w http.ResponseWriter, status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	mux.HandleFunc("GET /admin/clients", func(w http.ResponseWriter, r *http.Request) {
+		write(w, http.StatusOK, clientPage{Clients: []clientRecord{{ID: testClientID, Name: "acme", IsActive: true}}})
+	})
+	mux.HandleFunc("POST /admin/clients/{id}/rotate-key", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != testClientID.String() {
+			write(w, http.StatusNotFound, apiError{Code: "client_not_found", Message: "client not found"})
+			return
+		}
+		write(w, http.StatusOK, clientRecord{ID: testClientID, Name: "acme", IsActive: true, APIKey: "new-key"})
+	})
+	mux.HandleFunc("POST /admin/clients", func(w http.ResponseWriter, r *http.Request) {
+		write(w, http.StatusBadRequest, apiError{Code: "invalid_request", Message: "invalid client", Fields: map[string]string{"name": "is taken"}})
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqs = append(reqs, r)
+		if r.Header.Get("Authorization") != "Bearer admin-secret" {
+			write(w, http.StatusUnauthorized, apiError{Code: "unauthorized", Message: "missing or invalid admin token"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &reqs
+}
+
+func TestClientList_API(t *testing.T) {
+	t.Setenv(config.DefaultAdminTokenEnv, "admin-secret")
+	srv, reqs := adminAPI(t)
+	ta := newTestApp(t)
+
+	err := ta.run(context.Background(), []string{"client", "list", "--api-url", srv.URL, "--actor", "alice", "--limit", "10", "--json"})
+	require.NoError(t, err)
+	var page clientPage
+	ta.decode(t, &page)
+	require.Len(t, page.Clients, 1)
+	require.Len(t, *reqs, 1)
+	assert.Equal(t, "10", (*reqs)[0].URL.Query().Get("limit"))
+	assert.Equal(t, "alice", (*reqs)[0].Header.Get("X-Admin-Actor"))
+}
+
+func TestClientRotateKey_API(t *testing.T) {
+	t.Setenv(config.DefaultAdminTokenEnv, "admin-secret")
+	srv, _ := adminAPI(t)
+
+	ta := newTestApp(t)
+	require.NoError(t, ta.run(context.Background(), []string{"client", "rotate-key", "--api-url", srv.URL, "--id", testClientID.String()}))
+	assert.Contains(t, ta.stdout.String(), "new-key")
+
+	ta = newTestApp(t)
+	err := ta.run(context.Background(), []string{"client", "rotate-key", "--api-url", srv.URL, "--id", testAccountID.String()})
+	require.ErrorIs(t, err, errClientNotFound)
+}
+
+func TestClientCreate_APIError(t *testing.T) {
+	t.Setenv(config.DefaultAdminTokenEnv, "admin-secret")
+	srv, _ := adminAPI(t)
+	ta := newTestApp(t)
+
+	err := ta.run(context.Background(), []string{"client", "create", "--api-url", srv.URL, "--name", "acme"})
+	require.ErrorContains(t, err, "returned 400: invalid client; name is taken")
+}
+
+func TestClientList_APIWithoutToken(t *testing.T) {
+	t.Setenv(config.DefaultAdminTokenEnv, "")
+	ta := newTestApp(t)
+	err := ta.run(context.Background(), []string{"client", "list", "--api-url", "http://localhost:8080"})
+	require.ErrorContains(t, err, config.DefaultAdminTokenEnv)
+}
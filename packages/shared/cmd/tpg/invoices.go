@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/fees"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+)
+
+// invoiceMonthLayout is how --month is written, e.g. 2026-03.
+const invoiceMonthLayout = "2006-01"
+
+// invoiceRecord is an invoice as tpg prints it.
+type invoiceRecord struct {
+	ID        uuid.UUID           `json:"id"`
+	ClientID  uuid.UUID           `json:"client_id"`
+	Month     string              `json:"month"`
+	Payments  int64               `json:"payments"`
+	Lines     []invoiceLineRecord `json:"lines"`
+	Totals    []invoiceTotal      `json:"totals"`
+	CreatedAt string              `json:"created_at"`
+}
+
+type invoiceLineRecord struct {
+	Token    string `json:"token"`
+	Kind     string `json:"kind"`
+	Payments int64  `json:"payments"`
+	Amount   string `json:"amount"`
+	Rate     string `json:"rate"`
+	Fee      string `json:"fee"`
+}
+
+type invoiceTotal struct {
+	Token string `json:"token"`
+	Fee   string `json:"fee"`
+}
+
+func newInvoiceRecord(inv *fees.Invoice) invoiceRecord {
+	rec := invoiceRecord{
+		ID:        inv.ID,
+		ClientID:  inv.ClientID,
+		Month:     inv.Month.UTC().Format(invoiceMonthLayout),
+		Payments:  inv.Payments,
+		Lines:     []invoiceLineRecord{},
+		Totals:    []invoiceTotal{},
+		CreatedAt: inv.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	for _, l := range inv.Lines {
+		rec.Lines = append(rec.Lines, invoiceLineRecord{
+			Token:    l.Token,
+			Kind:     l.Kind,
+			Payments: l.Payments,
+			Amount:   payments.FormatAmount(l.Amount, l.Token),
+			Rate:     l.Rate.String(),
+			Fee:      fees.FormatFee(l.Fee, l.Token),
+		})
+	}
+	for _, t := range inv.Totals() {
+		rec.Totals = append(rec.Totals, invoiceTotal{Token: t.Token, Fee: fees.FormatFee(t.Fee, t.Token)})
+	}
+	return rec
+}
+
+func (a *app) invoiceGenerate(ctx context.Context, args []string) error {
+	fs := a.flags("invoice generate")
+	client := fs.String("client", "", "client ID (required)")
+	month := fs.String("month", "", "UTC month to invoice, e.g. 2026-03 (default last month)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	clientID, err := requiredID("client", *client)
+	if err != nil {
+		return err
+	}
+	start := fees.MonthStart(time.Now()).AddDate(0, -1, 0)
+	if *month != "" {
+		if start, err = time.Parse(invoiceMonthLayout, *month); err != nil {
+			return errors.New("--month must be a month such as 2026-03")
+		}
+	}
+	cfg, err := a.config()
+	if err != nil {
+		return err
+	}
+	store, err := a.database(ctx)
+	if err != nil {
+		return err
+	}
+
+	invoice, err := fees.New(store, cfg).GenerateInvoice(ctx, clientID, start)
+	if errors.Is(err, fees.ErrClientNotFound) {
+		return fmt.Errorf("client %s not found", clientID)
+	}
+	if err != nil {
+		return err
+	}
+	return a.printInvoice(newInvoiceRecord(invoice))
+}
+
+func (a *app) printInvoice(rec invoiceRecord) error {
+	if a.json {
+		return a.printJSON(rec)
+	}
+	fields := [][2]string{
+		{"Invoice", rec.ID.String()},
+		{"Client", rec.ClientID.String()},
+		{"Month", rec.Month},
+		{"Payments", strconv.FormatInt(rec.Payments, 10)},
+	}
+	for _, t := range rec.Totals {
+		fields = append(fields, [2]string{"Total " + t.Token, t.Fee})
+	}
+	if err := a.printFields(fields); err != nil {
+		return err
+	}
+	if len(rec.Lines) == 0 {
+		return nil
+	}
+	fmt.Fprintln(a.stdout)
+	rows := make([][]string, 0, len(rec.Lines))
+	for _, l := range rec.Lines {
+		rows = append(rows, []string{l.Token, l.Kind, strconv.FormatInt(l.Payments, 10), l.Amount, l.Rate, l.Fee})
+	}
+	return a.printTable([]string{"TOKEN", "KIND", "PAYMENTS", "AMOUNT", "RATE", "FEE"}, rows)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+func TestInvoiceGenerate(t *testing.T) {
+	ta := newTestApp(t)
+	ta.cfg.Fees = config.FeesConfig{Percent: "2"}
+	month := pgtype.Date{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+	invoiceID := uuid.New()
+	ta.store.On("GetClientByID", mock.Anything, testClientID).Return(repository.Client{ID: testClientID}, nil)
+	ta.store.On("GetInvoiceByClientMonth", mock.Anything, repository.GetInvoiceByClientMonthParams{ClientID: testClientID, Month: month}).
+		Return(repository.Invoice{}, pgx.ErrNoRows)
+	ta.store.On("GetMonthlyFeeBasis", mock.Anything, mock.Anything).Return([]repository.GetMonthlyFeeBasisRow{{
+		Token: "TRX", Payments: 1, Amount: numericFromString("10.000025"),
+	}}, nil)
+	ta.store.On("CreateInvoice", mock.Anything, repository.CreateInvoiceParams{ClientID: testClientID, Month: month, Payments: 1}).
+		Return(repository.Invoice{ID: invoiceID, ClientID: testClientID, Month: month, Payments: 1, CreatedAt: testCreatedAt}, nil)
+	ta.store.On("CreateInvoiceLine", mock.Anything, mock.Anything).Return(nil)
+
+	require.NoError(t, ta.run(context.Background(), []string{"invoice", "generate", "--client", testClientID.String(), "--month", "2025-01", "--json"}))
+	var rec invoiceRecord
+	ta.decode(t, &rec)
+	assert.Equal(t, invoiceID, rec.ID)
+	assert.Equal(t, "2025-01", rec.Month)
+	require.Len(t, rec.Lines, 1)
+	assert.Equal(t, invoiceLineRecord{Token: "TRX", Kind: "PERCENT", Payments: 1, Amount: "10.000025", Rate: "2", Fee: "0.200001"}, rec.Lines[0])
+	assert.Equal(t, []invoiceTotal{{Token: "TRX", Fee: "0.200001"}}, rec.Totals)
+}
+
+func TestInvoiceGenerate_Table(t *testing.T) {
+	ta := newTestApp(t)
+	saved := repository.Invoice{ID: uuid.New(), ClientID: testClientID, Month: pgtype.Date{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true}, Payments: 4}
+	ta.store.On("GetClientByID", mock.Anything, testClientID).Return(repository.Client{ID: testClientID}, nil)
+	ta.store.On("GetInvoiceByClientMonth", mock.Anything, mock.Anything).Return(saved, nil)
+	ta.store.On("ListInvoiceLines", mock.Anything, []uuid.UUID{saved.ID}).Return([]repository.InvoiceLine{{
+		InvoiceID: saved.ID, Token: "USDT", Kind: "MINIMUM", Payments: 4,
+		Amount: numericFromString("8"), Rate: numericFromString("0.1"), Fee: numericFromString("0.4"),
+	}}, nil)
+
+	require.NoError(t, ta.run(context.Background(), []string{"invoice", "generate", "--client", testClientID.String(), "--month", "2025-01"}))
+	out := ta.stdout.String()
+	assert.Regexp(t, `Total USDT:\s+0.40`, out)
+	assert.Contains(t, out, "MINIMUM")
+}
+
+func TestInvoiceGenerate_Flags(t *testing.T) {
+	ta := newTestApp(t)
+
+	err := ta.run(context.Background(), []string{"invoice", "generate"})
+	require.ErrorContains(t, err, "--client is required")
+
+	err = ta.run(context.Background(), []string{"invoice", "generate", "--client", testClientID.String(), "--month", "March"})
+	require.ErrorContains(t, err, "--month must be a month such as 2026-03")
+}
+
+func TestInvoiceGenerate_ClientNotFound(t *testing.T) {
+	ta := newTestApp(t)
+	ta.store.On("GetClientByID", mock.Anything, testClientID).Return(repository.Client{}, pgx.ErrNoRows)
+
+	err := ta.run(context.Background(), []string{"invoice", "generate", "--client", testClientID.String(), "--month", "2025-01"})
+	require.EqualError(t, err, "client "+testClientID.String()+" not found")
+}
+
+func numericFromString(s string) pgtype.Numeric {
+	d := decimal.RequireFromString(s)
+	return pgtype.Numeric{Int: d.Coefficient(), Exp: d.Exponent(), Valid: true}
+}
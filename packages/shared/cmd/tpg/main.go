@@ -0,0 +1,316 @@
+// Command tpg is the operator CLI of the gateway. It manages clients,
+// accounts and payments, reports the payment funnel, runs migrations, derives deposit wallets and checks
+// stored ones still derive, reports and resets the block watcher's progress,
+// reconciles payments against the chain, invoices clients for their fees,
+// repairs payments inconsistent with their attempts and seeds development
+// databases.
+//
+// Commands talk to the database named by --config. The client commands can
+// go through the admin API instead: pass --api-url and set ADMIN_API_TOKEN.
+// Results print as a table, or as JSON with --json.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/api"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/cache"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/db"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/lifecycle"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/reconciler"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/watcher"
+)
+
+// errNotConfirmed is returned by destructive commands run without --yes.
+var errNotConfirmed = errors.New("refusing to run without --yes")
+
+// Store is what the database commands read and write through.
+// *repository.Store implements it.
+type Store interface {
+	repository.Querier
+	ExecTx(ctx context.Context, fn func(repository.Querier) error) error
+}
+
+// command is a "<group> <name>" subcommand, or a single-word one.
+type command struct {
+	name    string
+	summary string
+	run     func(a *app, ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"client create", "create a client and print its API key", (*app).clientCreate},
+	{"client list", "list clients", (*app).clientList},
+	{"client deactivate", "deactivate a client; its API key stops working", (*app).clientDeactivate},
+	{"client rotate-key", "replace a client's API key", (*app).clientRotateKey},
+	{"account create", "create an account under a client", (*app).accountCreate},
+	{"account list", "list a client's accounts", (*app).accountList},
+	{"payment get", "show a payment", (*app).paymentGet},
+	{"payment list", "list payments, optionally by status", (*app).paymentList},
+	{"payment funnel", "count payments created per hour or day by how far they got", (*app).paymentFunnel},
+	{"migrate up", "apply pending migrations", (*app).migrateUp},
+	{"migrate down", "roll back applied migrations", (*app).migrateDown},
+	{"migrate status", "list applied and pending migrations", (*app).migrateStatus},
+	{"wallet derive", "derive deposit wallet addresses", (*app).walletDerive},
+	{"wallet verify", "check that recent payment wallets still derive from the configured mnemonics", (*app).walletVerify},
+	{"watcher status", "show how far the block watcher is behind the chain", (*app).watcherStatus},
+	{"watcher reset", "move a block watcher to another height", (*app).watcherReset},
+	{"reconcile run", "check confirmed payments against the chain and save a report", (*app).reconcileRun},
+	{"reconcile export", "write a saved reconciliation report as CSV", (*app).reconcileExport},
+	{"invoice generate", "compute and save a client's invoice for a month's fees, or show the saved one", (*app).invoiceGenerate},
+	{"repair payments", "find payments inconsistent with their attempts and transactions; --fix corrects them", (*app).repairPayments},
+	{"seed", "fill a development database with demo clients and payments", (*app).seed},
+}
+
+func main() {
+	ctx, stop := lifecycle.SignalContext(context.Background())
+	defer stop()
+
+	a := &app{stdout: os.Stdout, stderr: os.Stderr}
+	err := a.run(ctx, os.Args[1:])
+	a.close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tpg:", err)
+		os.Exit(1)
+	}
+}
+
+// app holds what a command needs. The config, database and other
+// dependencies are opened on first use; tests set them beforehand.
+type app struct {
+	stdout io.Writer
+	stderr io.Writer
+
+	// Set by the flags every command takes.
+	configPath string
+	json       bool
+
+	cfg         *config.Config
+	dbPool      *pgxpool.Pool
+	store       Store
+	migrator    migrator
+	chain       watcher.Chain
+	ledgerChain reconciler.Chain
+	wallets     api.WalletDeriver
+	testWallets api.WalletDeriver
+	// clientCache is evicted when the database commands change a client;
+	// nil when Redis is not configured.
+	clientCache cache.Cache
+	cacheLoaded bool
+
+	closers []func()
+}
+
+// run runs the command args name, e.g. ["client", "list", "--json"].
+func (a *app) run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		a.usage()
+		return errors.New("missing command")
+	}
+	for _, c := range commands {
+		words := strings.Fields(c.name)
+		if len(args) < len(words) || strings.Join(args[:len(words)], " ") != c.name {
+			continue
+		}
+		err := c.run(a, ctx, args[len(words):])
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	a.usage()
+	name := args[0]
+	if len(args) > 1 && !strings.HasPrefix(args[1], "-") {
+		name += " " + args[1]
+	}
+	return fmt.Errorf("unknown command %q", name)
+}
+
+func (a *app) usage() {
+	fmt.Fprintln(a.stderr, "usage: tpg <command> [flags]")
+	fmt.Fprintln(a.stderr)
+	w := tabwriter.NewWriter(a.stderr, 0, 0, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %s\t%s\n", c.name, c.summary)
+	}
+	_ = w.Flush()
+	fmt.Fprintln(a.stderr)
+	fmt.Fprintln(a.stderr, `Run "tpg <command> -h" for the flags of a command.`)
+}
+
+// flags returns the flag set of command name with the flags every command
+// takes.
+func (a *app) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("tpg "+name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.StringVar(&a.configPath, "config", "config.yaml", "path to the config file")
+	fs.BoolVar(&a.json, "json", false, "print JSON instead of a table")
+	return fs
+}
+
+// parse parses args into fs, which takes no positional arguments.
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	return nil
+}
+
+// confirm fails unless a destructive command was run with --yes.
+func confirm(yes bool, what string) error {
+	if !yes {
+		return fmt.Errorf("%s: %w", what, errNotConfirmed)
+	}
+	return nil
+}
+
+func (a *app) onClose(fn func()) {
+	a.closers = append(a.closers, fn)
+}
+
+// close releases what the command opened, last opened first.
+func (a *app) close() {
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		a.closers[i]()
+	}
+	a.closers = nil
+}
+
+func (a *app) config() (*config.Config, error) {
+	if a.cfg != nil {
+		return a.cfg, nil
+	}
+	var cfg config.Config
+	if err := cfg.LoadConfigForEnv(a.configPath); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	a.cfg = &cfg
+	return a.cfg, nil
+}
+
+// database returns the store of the configured database.
+func (a *app) database(ctx context.Context) (Store, error) {
+	if a.store != nil {
+		return a.store, nil
+	}
+	pool, err := a.pool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a.store = repository.NewStore(pool)
+	return a.store, nil
+}
+
+// cache returns the client cache of the API, or nil when there is none.
+func (a *app) cache() (cache.Cache, error) {
+	if a.cacheLoaded {
+		return a.clientCache, nil
+	}
+	cfg, err := a.config()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Redis.Enabled() {
+		rdb := cache.NewRedisClient(cfg.Redis, cfg.RedisPassword())
+		a.onClose(func() { _ = rdb.Close() })
+		a.clientCache = cache.NewRedis(rdb)
+	}
+	a.cacheLoaded = true
+	return a.clientCache, nil
+}
+
+func (a *app) tronChain() (watcher.Chain, error) {
+	if a.chain != nil {
+		return a.chain, nil
+	}
+	cfg, err := a.config()
+	if err != nil {
+		return nil, err
+	}
+	a.chain = tron.NewClient(cfg.Tron, cfg.TronAPIKey())
+	return a.chain, nil
+}
+
+// ledger returns the TRON client the reconcile commands read balances and
+// transactions through.
+func (a *app) ledger() (reconciler.Chain, error) {
+	if a.ledgerChain != nil {
+		return a.ledgerChain, nil
+	}
+	cfg, err := a.config()
+	if err != nil {
+		return nil, err
+	}
+	a.ledgerChain = tron.NewClient(cfg.Tron, cfg.TronAPIKey())
+	return a.ledgerChain, nil
+}
+
+func (a *app) walletDeriver() (api.WalletDeriver, error) {
+	if a.wallets != nil {
+		return a.wallets, nil
+	}
+	cfg, err := a.config()
+	if err != nil {
+		return nil, err
+	}
+	mnemonic, err := cfg.WalletMnemonic()
+	if err != nil {
+		return nil, err
+	}
+	a.wallets = api.MnemonicWallets(mnemonic)
+	return a.wallets, nil
+}
+
+// testWalletDeriver returns the wallets of test clients, or nil when test
+// mode is disabled.
+func (a *app) testWalletDeriver() (api.WalletDeriver, error) {
+	if a.testWallets != nil {
+		return a.testWallets, nil
+	}
+	cfg, err := a.config()
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.TestMode.Enabled {
+		return nil, nil
+	}
+	mnemonic, err := cfg.TestWalletMnemonic()
+	if err != nil {
+		return nil, err
+	}
+	a.testWallets = api.MnemonicWallets(mnemonic)
+	return a.testWallets, nil
+}
+
+// pool connects to the configured database once, without the retries the
+// services wait out a starting database with.
+func (a *app) pool(ctx context.Context) (*pgxpool.Pool, error) {
+	if a.dbPool != nil {
+		return a.dbPool, nil
+	}
+	cfg, err := a.config()
+	if err != nil {
+		return nil, err
+	}
+	pool, err := db.DbConnect(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	a.onClose(pool.Close)
+	a.dbPool = pool
+	return pool, nil
+}
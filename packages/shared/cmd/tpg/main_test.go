@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+var (
+	testClientID  = uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	testAccountID = uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	testPaymentID = uuid.MustParse("33333333-3333-3333-3333-333333333333")
+	testCreatedAt = pgtype.Timestamptz{Time: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true}
+)
+
+type mockStore struct {
+	*repository.MockQuerier
+}
+
+func (m *mockStore) ExecTx(_ context.Context, fn func(repository.Querier) error) error {
+	return fn(m.MockQuerier)
+}
+
+// testApp is an app over a mock store whose output is captured.
+type testApp struct {
+	*app
+	store  *mockStore
+	stdout *bytes.Buffer
+	stderr *bytes.Buffer
+}
+
+func newTestApp(t *testing.T) *testApp {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.BlockWatcher.MaxLag = 100
+	store := &mockStore{MockQuerier: repository.NewMockQuerier(t)}
+	ta := &testApp{store: store, stdout: &bytes.Buffer{}, stderr: &bytes.Buffer{}}
+	ta.app = &app{
+		stdout:      ta.stdout,
+		stderr:      ta.stderr,
+		cfg:         cfg,
+		store:       store,
+		cacheLoaded: true,
+	}
+	return ta
+}
+
+// decode unmarshals what the command printed as JSON.
+func (ta *testApp) decode(t *testing.T, v any) {
+	t.Helper()
+	require.NoError(t, json.Unmarshal(ta.stdout.Bytes(), v), ta.stdout.String())
+}
+
+func TestRun_UnknownCommand(t *testing.T) {
+	ta := newTestApp(t)
+	err := ta.run(context.Background(), []string{"client", "explode"})
+	require.ErrorContains(t, err, `unknown command "client explode"`)
+	assert.Contains(t, ta.stderr.String(), "client rotate-key")
+}
+
+func TestRun_MissingCommand(t *testing.T) {
+	ta := newTestApp(t)
+	require.Error(t, ta.run(context.Background(), []string{"client"}))
+	assert.Contains(t, ta.stderr.String(), "usage: tpg")
+}
+
+func TestRun_Help(t *testing.T) {
+	ta := newTestApp(t)
+	require.NoError(t, ta.run(context.Background(), []string{"payment", "list", "-h"}))
+	assert.Contains(t, ta.stderr.String(), "-status")
+}
+
+func TestRun_PositionalArguments(t *testing.T) {
+	ta := newTestApp(t)
+	err := ta.run(context.Background(), []string{"account", "list", "--client", testClientID.String(), "extra"})
+	require.ErrorContains(t, err, "unexpected arguments: extra")
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/db"
+)
+
+// migrator runs the embedded migrations against the database.
+type migrator interface {
+	// Up applies pending migrations up to and including version, or all of
+	// them when version is negative.
+	Up(ctx context.Context, version int64) error
+	// Down rolls back applied migrations newer than version.
+	Down(ctx context.Context, version int64) error
+	Status(ctx context.Context) (*db.MigrationStatus, error)
+}
+
+// poolMigrator is the migrator of a database pool.
+type poolMigrator struct {
+	pool *pgxpool.Pool
+}
+
+func (m poolMigrator) Up(ctx context.Context, version int64) error {
+	if version < 0 {
+		return db.Migrate(ctx, m.pool)
+	}
+	return db.MigrateTo(ctx, m.pool, version)
+}
+
+func (m poolMigrator) Down(ctx context.Context, version int64) error {
+	return db.MigrateDown(ctx, m.pool, version)
+}
+
+func (m poolMigrator) Status(ctx context.Context) (*db.MigrationStatus, error) {
+	return db.Status(ctx, m.pool)
+}
+
+func (a *app) migrations(ctx context.Context) (migrator, error) {
+	if a.migrator != nil {
+		return a.migrator, nil
+	}
+	pool, err := a.pool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a.migrator = poolMigrator{pool: pool}
+	return a.migrator, nil
+}
+
+// migrationRecord is a migration as migrate status prints it.
+type migrationRecord struct {
+	Version int64  `json:"version"`
+	Name    string `json:"name"`
+	Applied bool   `json:"applied"`
+}
+
+func (a *app) migrateUp(ctx context.Context, args []string) error {
+	fs := a.flags("migrate up")
+	to := fs.Int64("to", -1, "apply migrations up to and including this version; all when negative")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	m, err := a.migrations(ctx)
+	if err != nil {
+		return err
+	}
+	if err := m.Up(ctx, *to); err != nil {
+		return err
+	}
+	return a.printMigrations(ctx, m)
+}
+
+func (a *app) migrateDown(ctx context.Context, args []string) error {
+	fs := a.flags("migrate down")
+	to := fs.Int64("to", -1, "roll back migrations newer than this version, 0 for all; only the newest by default")
+	yes := fs.Bool("yes", false, "confirm the rolled back migrations' tables and data are dropped")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	m, err := a.migrations(ctx)
+	if err != nil {
+		return err
+	}
+	target := *to
+	if target < 0 {
+		st, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		if len(st.Applied) == 0 {
+			return errors.New("no migrations are applied")
+		}
+		target = 0
+		if n := len(st.Applied); n > 1 {
+			target = st.Applied[n-2].Version
+		}
+	}
+	if err := confirm(*yes, fmt.Sprintf("rolling back migrations newer than version %d", target)); err != nil {
+		return err
+	}
+	if err := m.Down(ctx, target); err != nil {
+		return err
+	}
+	return a.printMigrations(ctx, m)
+}
+
+func (a *app) migrateStatus(ctx context.Context, args []string) error {
+	fs := a.flags("migrate status")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	m, err := a.migrations(ctx)
+	if err != nil {
+		return err
+	}
+	return a.printMigrations(ctx, m)
+}
+
+// printMigrations prints every embedded migration and whether it is applied.
+func (a *app) printMigrations(ctx context.Context, m migrator) error {
+	st, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	recs := make([]migrationRecord, 0, len(st.Applied)+len(st.Pending))
+	for _, mig := range st.Applied {
+		recs = append(recs, migrationRecord{Version: mig.Version, Name: mig.Name, Applied: true})
+	}
+	for _, mig := range st.Pending {
+		recs = append(recs, migrationRecord{Version: mig.Version, Name: mig.Name})
+	}
+	if a.json {
+		return a.printJSON(recs)
+	}
+	rows := make([][]string, 0, len(recs))
+	for _, r := range recs {
+		state := "pending"
+		if r.Applied {
+			state = "applied"
+		}
+		rows = append(rows, []string{strconv.FormatInt(r.Version, 10), r.Name, state})
+	}
+	return a.printTable([]string{"VERSION", "NAME", "STATUS"}, rows)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/db"
+)
+
+// fakeMigrator keeps the applied migrations in memory.
+type fakeMigrator struct {
+	all     []db.Migration
+	applied int
+	ups     []int64
+	downs   []int64
+}
+
+func newFakeMigrator(applied int) *fakeMigrator {
+	return &fakeMigrator{
+		all: []db.Migration{
+			{Version: 1, Name: "clients"},
+			{Version: 2, Name: "accounts"},
+			{Version: 3, Name: "payments"},
+		},
+		applied: applied,
+	}
+}
+
+func (m *fakeMigrator) Up(_ context.Context, version int64) error {
+	m.ups = append(m.ups, version)
+	for m.applied < len(m.all) && (version < 0 || m.all[m.applied].Version <= version) {
+		m.applied++
+	}
+	return nil
+}
+
+func (m *fakeMigrator) Down(_ context.Context, version int64) error {
+	m.downs = append(m.downs, version)
+	for m.applied > 0 && m.all[m.applied-1].Version > version {
+		m.applied--
+	}
+	return nil
+}
+
+func (m *fakeMigrator) Status(context.Context) (*db.MigrationStatus, error) {
+	return &db.MigrationStatus{Applied: m.all[:m.applied], Pending: m.all[m.applied:]}, nil
+}
+
+func TestMigrateUp(t *testing.T) {
+	ta := newTestApp(t)
+	m := newFakeMigrator(1)
+	ta.migrator = m
+
+	require.NoError(t, ta.run(context.Background(), []string{"migrate", "up", "--to", "2", "--json"}))
+	assert.Equal(t, []int64{2}, m.ups)
+	var recs []migrationRecord
+	ta.decode(t, &recs)
+	assert.Equal(t, []migrationRecord{
+		{Version: 1, Name: "clients", Applied: true},
+		{Version: 2, Name: "accounts", Applied: true},
+		{Version: 3, Name: "payments"},
+	}, recs)
+}
+
+func TestMigrateUp_All(t *testing.T) {
+	ta := newTestApp(t)
+	m := newFakeMigrator(0)
+	ta.migrator = m
+
+	require.NoError(t, ta.run(context.Background(), []string{"migrate", "up"}))
+	assert.Equal(t, []int64{-1}, m.ups)
+	assert.Equal(t, 3, m.applied)
+}
+
+func TestMigrateDown_RequiresYes(t *testing.T) {
+	ta := newTestApp(t)
+	m := newFakeMigrator(3)
+	ta.migrator = m
+
+	err := ta.run(context.Background(), []string{"migrate", "down"})
+	require.ErrorIs(t, err, errNotConfirmed)
+	assert.Empty(t, m.downs)
+}
+
+func TestMigrateDown_Newest(t *testing.T) {
+	ta := newTestApp(t)
+	m := newFakeMigrator(3)
+	ta.migrator = m
+
+	require.NoError(t, ta.run(context.Background(), []string{"migrate", "down", "--yes"}))
+	assert.Equal(t, []int64{2}, m.downs, "only the newest migration is rolled back")
+	assert.Equal(t, 2, m.applied)
+}
+
+func TestMigrateDown_To(t *testing.T) {
+	ta := newTestApp(t)
+	m := newFakeMigrator(3)
+	ta.migrator = m
+
+	require.NoError(t, ta.run(context.Background(), []string{"migrate", "down", "--to", "0", "--yes"}))
+	assert.Equal(t, []int64{0}, m.downs)
+	assert.Equal(t, 0, m.applied)
+}
+
+func TestMigrateDown_NothingApplied(t *testing.T) {
+	ta := newTestApp(t)
+	ta.migrator = newFakeMigrator(0)
+
+	require.ErrorContains(t, ta.run(context.Background(), []string{"migrate", "down", "--yes"}), "no migrations are applied")
+}
+
+func TestMigrateStatus(t *testing.T) {
+	ta := newTestApp(t)
+	ta.migrator = newFakeMigrator(2)
+
+	require.NoError(t, ta.run(context.Background(), []string{"migrate", "status"}))
+	out := ta.stdout.String()
+	assert.Regexp(t, `2\s+accounts\s+applied`, out)
+	assert.Regexp(t, `3\s+payments\s+pending`, out)
+}
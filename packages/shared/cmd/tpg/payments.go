@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+)
+
+// paymentStatuses are the statuses payment list filters on.
+var paymentStatuses = []string{
+	repository.PaymentPending,
+	repository.PaymentDetected,
+	repository.PaymentUnderpaid,
+	repository.PaymentConfirmed,
+	repository.PaymentExpired,
+	repository.PaymentReview,
+	repository.PaymentCancelled,
+}
+
+// paymentRecord is a payment as tpg prints it. Unlike the merchant API it
+// shows the owning client and the wallet's derivation index.
+type paymentRecord struct {
+	ID           uuid.UUID `json:"id"`
+	ClientID     uuid.UUID `json:"client_id"`
+	AccountID    uuid.UUID `json:"account_id"`
+	Token        string    `json:"token"`
+	Amount       string    `json:"amount"`
+	Status       string    `json:"status"`
+	Wallet       string    `json:"wallet"`
+	WalletIndex  *int64    `json:"wallet_index,omitempty"`
+	AttemptCount int32     `json:"attempt_count"`
+	FiatAmount   *string   `json:"fiat_amount,omitempty"`
+	FiatCurrency *string   `json:"fiat_currency,omitempty"`
+	ExpiresAt    string    `json:"expires_at"`
+	ConfirmedAt  string    `json:"confirmed_at,omitempty"`
+	CreatedAt    string    `json:"created_at"`
+}
+
+type paymentPage struct {
+	Payments   []paymentRecord `json:"payments"`
+	NextCursor *uuid.UUID      `json:"next_cursor,omitempty"`
+}
+
+func newPaymentRecord(p repository.Payment) paymentRecord {
+	rec := paymentRecord{
+		ID:           p.ID,
+		ClientID:     p.ClientID,
+		AccountID:    p.AccountID,
+		Token:        p.Token,
+		Amount:       payments.FormatAmount(repository.NumericToDecimal(p.Amount), p.Token),
+		Status:       p.Status,
+		Wallet:       p.UniqueWallet,
+		WalletIndex:  p.WalletIndex,
+		FiatCurrency: p.FiatCurrency,
+		ExpiresAt:    formatTime(p.ExpiresAt),
+		ConfirmedAt:  formatTime(p.ConfirmedAt),
+		CreatedAt:    formatTime(p.CreatedAt),
+	}
+	if p.AttemptCount != nil {
+		rec.AttemptCount = *p.AttemptCount
+	}
+	if p.FiatAmount.Valid {
+		fiat := repository.NumericToDecimal(p.FiatAmount).String()
+		rec.FiatAmount = &fiat
+	}
+	return rec
+}
+
+func (a *app) paymentGet(ctx context.Context, args []string) error {
+	fs := a.flags("payment get")
+	id := fs.String("id", "", "payment ID (required)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	paymentID, err := requiredID("id", *id)
+	if err != nil {
+		return err
+	}
+	store, err := a.database(ctx)
+	if err != nil {
+		return err
+	}
+	payment, err := store.GetPayment(ctx, paymentID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("payment %s not found", paymentID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load payment %s: %w", paymentID, err)
+	}
+
+	rec := newPaymentRecord(payment)
+	if a.json {
+		return a.printJSON(rec)
+	}
+	fields := [][2]string{
+		{"ID", rec.ID.String()},
+		{"Client", rec.ClientID.String()},
+		{"Account", rec.AccountID.String()},
+		{"Status", rec.Status},
+		{"Amount", rec.Amount + " " + rec.Token},
+		{"Wallet", rec.Wallet},
+		{"Wallet index", orDash(optionalInt(rec.WalletIndex))},
+		{"Attempts", strconv.Itoa(int(rec.AttemptCount))},
+	}
+	if rec.FiatAmount != nil && rec.FiatCurrency != nil {
+		fields = append(fields, [2]string{"Fiat amount", *rec.FiatAmount + " " + *rec.FiatCurrency})
+	}
+	fields = append(fields,
+		[2]string{"Created", rec.CreatedAt},
+		[2]string{"Expires", rec.ExpiresAt},
+		[2]string{"Confirmed", orDash(rec.ConfirmedAt)},
+	)
+	return a.printFields(fields)
+}
+
+func (a *app) paymentList(ctx context.Context, args []string) error {
+	fs := a.flags("payment list")
+	status := fs.String("status", "", "only list payments in this status: "+strings.Join(paymentStatuses, ", "))
+	limit := fs.Int("limit", defaultPageSize, fmt.Sprintf("payments per page, at most %d", maxPageSize))
+	after := fs.String("after", "", "list payments after this ID, the next cursor of an earlier page")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *limit < 1 || *limit > maxPageSize {
+		return fmt.Errorf("--limit must be between 1 and %d", maxPageSize)
+	}
+	cursor, err := optionalID("after", *after)
+	if err != nil {
+		return err
+	}
+	arg := repository.ListPaymentsParams{AfterID: cursor, Limit: int32(*limit + 1)}
+	if *status != "" {
+		s := strings.ToUpper(*status)
+		if !slices.Contains(paymentStatuses, s) {
+			return fmt.Errorf("--status must be one of %s", strings.Join(paymentStatuses, ", "))
+		}
+		arg.Status = &s
+	}
+	store, err := a.database(ctx)
+	if err != nil {
+		return err
+	}
+	// One extra row tells whether there is a next page.
+	list, err := store.ListPayments(ctx, arg)
+	if err != nil {
+		return fmt.Errorf("failed to list payments: %w", err)
+	}
+
+	page := paymentPage{Payments: make([]paymentRecord, 0, min(len(list), *limit))}
+	if len(list) > *limit {
+		list = list[:*limit]
+		next := list[*limit-1].ID
+		page.NextCursor = &next
+	}
+	for _, p := range list {
+		page.Payments = append(page.Payments, newPaymentRecord(p))
+	}
+	if a.json {
+		return a.printJSON(page)
+	}
+	rows := make([][]string, 0, len(page.Payments))
+	for _, p := range page.Payments {
+		rows = append(rows, []string{p.ID.String(), p.ClientID.String(), p.Status, p.Amount, p.Token, p.Wallet, p.CreatedAt})
+	}
+	if err := a.printTable([]string{"ID", "CLIENT", "STATUS", "AMOUNT", "TOKEN", "WALLET", "CREATED"}, rows); err != nil {
+		return err
+	}
+	if page.NextCursor != nil {
+		fmt.Fprintf(a.stderr, "more payments: tpg payment list --after %s\n", page.NextCursor)
+	}
+	return nil
+}
+
+// funnelRecord is a funnel bucket as tpg prints it.
+type funnelRecord struct {
+	Start string `json:"start"`
+	payments.FunnelCounts
+}
+
+type funnelReport struct {
+	Bucket  string                `json:"bucket"`
+	From    string                `json:"from"`
+	To      string                `json:"to"`
+	Buckets []funnelRecord        `json:"buckets"`
+	Total   payments.FunnelCounts `json:"total"`
+}
+
+func (a *app) paymentFunnel(ctx context.Context, args []string) error {
+	fs := a.flags("payment funnel")
+	bucket := fs.String("bucket", payments.FunnelDay, "count by hour or day, in UTC")
+	from := fs.String("from", "", "count payments created from this RFC 3339 time or date (default 7 days before --to)")
+	to := fs.String("to", "", "count payments created before this RFC 3339 time or date (default now)")
+	client := fs.String("client", "", "only count this client's payments")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	width, ok := payments.FunnelWidth(*bucket)
+	if !ok {
+		return errors.New("--bucket must be hour or day")
+	}
+	end := time.Now().UTC()
+	if *to != "" {
+		t, err := parseTime("to", *to)
+		if err != nil {
+			return err
+		}
+		end = t
+	}
+	start := end.AddDate(0, 0, -7)
+	if *from != "" {
+		t, err := parseTime("from", *from)
+		if err != nil {
+			return err
+		}
+		start = t
+	}
+	if err := payments.CheckFunnelRange(width, start, end); err != nil {
+		return err
+	}
+	clientID, err := optionalID("client", *client)
+	if err != nil {
+		return err
+	}
+	var scope *uuid.UUID
+	if clientID != uuid.Nil {
+		scope = &clientID
+	}
+	store, err := a.database(ctx)
+	if err != nil {
+		return err
+	}
+	funnel, err := payments.GetFunnel(ctx, store, scope, *bucket, start, end)
+	if err != nil {
+		return err
+	}
+
+	report := funnelReport{
+		Bucket:  *bucket,
+		From:    start.Format(time.RFC3339),
+		To:      end.Format(time.RFC3339),
+		Buckets: make([]funnelRecord, 0, len(funnel.Buckets)),
+		Total:   funnel.Total,
+	}
+	for _, b := range funnel.Buckets {
+		report.Buckets = append(report.Buckets, funnelRecord{Start: b.Start.Format(time.RFC3339), FunnelCounts: b.FunnelCounts})
+	}
+	if a.json {
+		return a.printJSON(report)
+	}
+	rows := make([][]string, 0, len(report.Buckets)+1)
+	for _, b := range report.Buckets {
+		rows = append(rows, funnelRow(b.Start, b.FunnelCounts))
+	}
+	rows = append(rows, funnelRow("TOTAL", report.Total))
+	return a.printTable([]string{"BUCKET", "CREATED", "DETECTED", "CONFIRMED", "EXPIRED"}, rows)
+}
+
+func funnelRow(label string, c payments.FunnelCounts) []string {
+	return []string{
+		label,
+		strconv.FormatInt(c.Created, 10),
+		strconv.FormatInt(c.Detected, 10),
+		strconv.FormatInt(c.Confirmed, 10),
+		strconv.FormatInt(c.Expired, 10),
+	}
+}
+
+// parseTime parses the value of --flag, an RFC 3339 time or a date taken as
+// midnight UTC.
+func parseTime(flag, s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("--%s must be an RFC 3339 time or a YYYY-MM-DD date", flag)
+}
+
+func optionalInt(n *int64) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatInt(*n, 10)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+)
+
+func testPayment() repository.Payment {
+	index := int64(7)
+	return repository.Payment{
+		ID:           testPaymentID,
+		ClientID:     testClientID,
+		AccountID:    testAccountID,
+		Amount:       pgtype.Numeric{Int: big.NewInt(2550), Exp: -2, Valid: true},
+		UniqueWallet: "TWallet7",
+		Status:       repository.PaymentPending,
+		ExpiresAt:    testCreatedAt,
+		CreatedAt:    testCreatedAt,
+		WalletIndex:  &index,
+		Token:        config.TokenUSDT,
+	}
+}
+
+func TestPaymentGet(t *testing.T) {
+	ta := newTestApp(t)
+	ta.store.On("GetPayment", mock.Anything, testPaymentID).Return(testPayment(), nil).Once()
+
+	require.NoError(t, ta.run(context.Background(), []string{"payment", "get", "--id", testPaymentID.String(), "--json"}))
+	var rec paymentRecord
+	ta.decode(t, &rec)
+	assert.Equal(t, "25.500000", rec.Amount)
+	assert.Equal(t, "TWallet7", rec.Wallet)
+	require.NotNil(t, rec.WalletIndex)
+	assert.EqualValues(t, 7, *rec.WalletIndex)
+	assert.Empty(t, rec.ConfirmedAt)
+}
+
+func TestPaymentGet_Table(t *testing.T) {
+	ta := newTestApp(t)
+	ta.store.On("GetPayment", mock.Anything, testPaymentID).Return(testPayment(), nil).Once()
+
+	require.NoError(t, ta.run(context.Background(), []string{"payment", "get", "--id", testPaymentID.String()}))
+	out := ta.stdout.String()
+	assert.Contains(t, out, "Status:")
+	assert.Contains(t, out, "25.500000 USDT")
+	assert.Regexp(t, `Confirmed:\s+-`, out)
+}
+
+func TestPaymentGet_NotFound(t *testing.T) {
+	ta := newTestApp(t)
+	ta.store.On("GetPayment", mock.Anything, testPaymentID).Return(repository.Payment{}, pgx.ErrNoRows).Once()
+
+	err := ta.run(context.Background(), []string{"payment", "get", "--id", testPaymentID.String()})
+	require.ErrorContains(t, err, "not found")
+}
+
+func TestPaymentList_Status(t *testing.T) {
+	ta := newTestApp(t)
+	status := repository.PaymentConfirmed
+	ta.store.On("ListPayments", mock.Anything, repository.ListPaymentsParams{Status: &status, Limit: 2}).
+		Return([]repository.Payment{testPayment(), testPayment()}, nil).Once()
+
+	require.NoError(t, ta.run(context.Background(), []string{"payment", "list", "--status", "confirmed", "--limit", "1", "--json"}))
+	var page paymentPage
+	ta.decode(t, &page)
+	require.Len(t, page.Payments, 1)
+	require.NotNil(t, page.NextCursor)
+	assert.Equal(t, testPaymentID, *page.NextCursor)
+}
+
+func TestPaymentList_Table(t *testing.T) {
+	ta := newTestApp(t)
+	ta.store.On("ListPayments", mock.Anything, repository.ListPaymentsParams{AfterID: testClientID, Limit: defaultPageSize + 1}).
+		Return([]repository.Payment{testPayment()}, nil).Once()
+
+	require.NoError(t, ta.run(context.Background(), []string{"payment", "list", "--after", testClientID.String()}))
+	out := ta.stdout.String()
+	assert.Contains(t, out, "STATUS")
+	assert.Contains(t, out, "TWallet7")
+	assert.Empty(t, ta.stderr.String())
+}
+
+func TestPaymentList_InvalidStatus(t *testing.T) {
+	ta := newTestApp(t)
+	err := ta.run(context.Background(), []string{"payment", "list", "--status", "paid"})
+	require.ErrorContains(t, err, "--status must be one of")
+}
+
+func TestPaymentFunnel(t *testing.T) {
+	ta := newTestApp(t)
+	from := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	ta.store.On("GetPaymentFunnel", mock.Anything, repository.GetPaymentFunnelParams{
+		Bucket:      payments.FunnelDay,
+		CreatedFrom: pgtype.Timestamptz{Time: from, Valid: true},
+		CreatedTo:   pgtype.Timestamptz{Time: from.AddDate(0, 0, 2), Valid: true},
+		ClientID:    &testClientID,
+	}).Return([]repository.GetPaymentFunnelRow{
+		{Bucket: pgtype.Timestamp{Time: from, Valid: true}, Created: 5, Detected: 4, Confirmed: 3, Expired: 1},
+	}, nil).Once()
+
+	require.NoError(t, ta.run(context.Background(), []string{"payment", "funnel",
+		"--from", "2026-02-01", "--to", "2026-02-03", "--client", testClientID.String(), "--json"}))
+	var report funnelReport
+	ta.decode(t, &report)
+	require.Len(t, report.Buckets, 2)
+	assert.Equal(t, "2026-02-01T00:00:00Z", report.Buckets[0].Start)
+	assert.Equal(t, int64(4), report.Buckets[0].Detected)
+	assert.Equal(t, payments.FunnelCounts{}, report.Buckets[1].FunnelCounts)
+	assert.Equal(t, payments.FunnelCounts{Created: 5, Detected: 4, Confirmed: 3, Expired: 1}, report.Total)
+}
+
+func TestPaymentFunnel_EveryClientTable(t *testing.T) {
+	ta := newTestApp(t)
+	ta.store.On("GetPaymentFunnel", mock.Anything, mock.MatchedBy(func(arg repository.GetPaymentFunnelParams) bool {
+		return arg.ClientID == nil && arg.Bucket == payments.FunnelHour
+	})).Return([]repository.GetPaymentFunnelRow{}, nil).Once()
+
+	require.NoError(t, ta.run(context.Background(), []string{"payment", "funnel",
+		"--bucket", "hour", "--from", "2026-02-01T10:00:00Z", "--to", "2026-02-01T12:00:00Z"}))
+	out := ta.stdout.String()
+	assert.Contains(t, out, "BUCKET")
+	assert.Contains(t, out, "2026-02-01T11:00:00Z")
+	assert.Regexp(t, `TOTAL\s+0\s+0\s+0\s+0`, out)
+}
+
+func TestPaymentFunnel_InvalidFlags(t *testing.T) {
+	ta := newTestApp(t)
+	err := ta.run(context.Background(), []string{"payment", "funnel", "--bucket", "week"})
+	require.ErrorContains(t, err, "--bucket must be hour or day")
+	err = ta.run(context.Background(), []string{"payment", "funnel", "--from", "yesterday"})
+	require.ErrorContains(t, err, "--from must be")
+	err = ta.run(context.Background(), []string{"payment", "funnel", "--from", "2026-02-03", "--to", "2026-02-01"})
+	require.ErrorContains(t, err, "to must be after from")
+}
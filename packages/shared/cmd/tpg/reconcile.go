@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/reconciler"
+)
+
+// reportRecord is a reconciliation report as tpg prints it.
+type reportRecord struct {
+	ID              uuid.UUID        `json:"id"`
+	WindowStart     string           `json:"window_start"`
+	WindowEnd       string           `json:"window_end"`
+	PaymentsChecked int              `json:"payments_checked"`
+	Mismatches      []mismatchRecord `json:"mismatches"`
+	CreatedAt       string           `json:"created_at"`
+}
+
+type mismatchRecord struct {
+	PaymentID uuid.UUID `json:"payment_id"`
+	Kind      string    `json:"kind"`
+	Wallet    string    `json:"wallet"`
+	Token     string    `json:"token"`
+	TxHash    string    `json:"tx_hash,omitempty"`
+	Expected  string    `json:"expected"`
+	Actual    string    `json:"actual"`
+	Detail    string    `json:"detail"`
+}
+
+func newReportRecord(r *reconciler.Report) reportRecord {
+	rec := reportRecord{
+		ID:              r.ID,
+		WindowStart:     r.WindowStart.UTC().Format(time.RFC3339),
+		WindowEnd:       r.WindowEnd.UTC().Format(time.RFC3339),
+		PaymentsChecked: r.PaymentsChecked,
+		Mismatches:      []mismatchRecord{},
+		CreatedAt:       r.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	for _, m := range r.Mismatches {
+		rec.Mismatches = append(rec.Mismatches, mismatchRecord{
+			PaymentID: m.PaymentID,
+			Kind:      m.Kind,
+			Wallet:    m.Wallet,
+			Token:     m.Token,
+			TxHash:    m.TxHash,
+			Expected:  m.Expected.String(),
+			Actual:    m.Actual.String(),
+			Detail:    m.Detail,
+		})
+	}
+	return rec
+}
+
+func (a *app) reconcileRun(ctx context.Context, args []string) error {
+	fs := a.flags("reconcile run")
+	window := fs.Duration("window", 0, "how far back to look for confirmed payments (default reconciler.window)")
+	csvPath := fs.String("csv", "", `also write the report as CSV to this file, or to stdout instead of the table with "-"`)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *window < 0 {
+		return errors.New("--window must not be negative")
+	}
+	cfg, err := a.config()
+	if err != nil {
+		return err
+	}
+	if *window == 0 {
+		*window = cfg.Reconciler.Window.Std()
+	}
+	store, err := a.database(ctx)
+	if err != nil {
+		return err
+	}
+	chain, err := a.ledger()
+	if err != nil {
+		return err
+	}
+
+	// Mismatches are printed below; the reconciler's log is only for errors.
+	logger := slog.New(slog.NewTextHandler(a.stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	r := reconciler.New(chain, store, cfg, reconciler.WithLogger(logger))
+	until := time.Now().UTC()
+	report, err := r.Reconcile(ctx, until.Add(-*window), until)
+	if err != nil {
+		return err
+	}
+	if *csvPath != "" {
+		if err := a.writeCSV(report, *csvPath); err != nil {
+			return err
+		}
+		if *csvPath == "-" {
+			return nil
+		}
+	}
+	return a.printReport(report)
+}
+
+func (a *app) reconcileExport(ctx context.Context, args []string) error {
+	fs := a.flags("reconcile export")
+	id := fs.String("id", "", "report ID (default the latest report)")
+	out := fs.String("out", "-", `file to write the CSV to, "-" for stdout`)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	reportID, err := optionalID("id", *id)
+	if err != nil {
+		return err
+	}
+	store, err := a.database(ctx)
+	if err != nil {
+		return err
+	}
+
+	var saved repository.ReconciliationReport
+	if reportID == uuid.Nil {
+		saved, err = store.GetLatestReconciliationReport(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("no reconciliation report yet: run tpg reconcile run")
+		}
+	} else {
+		saved, err = store.GetReconciliationReport(ctx, reportID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("reconciliation report %s not found", reportID)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load reconciliation report: %w", err)
+	}
+	mismatches, err := store.ListReconciliationMismatches(ctx, saved.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list mismatches of report %s: %w", saved.ID, err)
+	}
+	return a.writeCSV(reconciler.ReportFromRecords(saved, mismatches), *out)
+}
+
+// writeCSV writes report to path, or to stdout when path is "-".
+func (a *app) writeCSV(report *reconciler.Report, path string) error {
+	if path == "-" {
+		return report.WriteCSV(a.stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := report.WriteCSV(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func (a *app) printReport(report *reconciler.Report) error {
+	rec := newReportRecord(report)
+	if a.json {
+		return a.printJSON(rec)
+	}
+	if err := a.printFields([][2]string{
+		{"Report", rec.ID.String()},
+		{"Window", rec.WindowStart + " - " + rec.WindowEnd},
+		{"Payments checked", strconv.Itoa(rec.PaymentsChecked)},
+		{"Mismatches", strconv.Itoa(len(rec.Mismatches))},
+	}); err != nil {
+		return err
+	}
+	if len(rec.Mismatches) == 0 {
+		return nil
+	}
+	fmt.Fprintln(a.stdout)
+	rows := make([][]string, 0, len(rec.Mismatches))
+	for _, m := range rec.Mismatches {
+		rows = append(rows, []string{m.PaymentID.String(), m.Kind, m.Token, m.Wallet, orDash(m.TxHash), m.Expected, m.Actual, m.Detail})
+	}
+	return a.printTable([]string{"PAYMENT", "KIND", "TOKEN", "WALLET", "TX", "EXPECTED", "ACTUAL", "DETAIL"}, rows)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/reconciler"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+)
+
+var testReportID = uuid.MustParse("44444444-4444-4444-4444-444444444444")
+
+// emptyLedger is a chain holding nothing.
+type emptyLedger struct{}
+
+func (emptyLedger) GetTRXBalance(context.Context, string) (int64, error) { return 0, nil }
+
+func (emptyLedger) GetTRC20Balance(context.Context, string, string) (*big.Int, error) {
+	return new(big.Int), nil
+}
+
+func (emptyLedger) GetTransactionByID(context.Context, string) (*tron.Transaction, error) {
+	return nil, tron.ErrTransactionNotFound
+}
+
+func (emptyLedger) GetTransactionInfoByID(context.Context, string) (*tron.TransactionInfo, error) {
+	return nil, tron.ErrTransactionNotFound
+}
+
+// expectReconcile stubs a run over one confirmed payment with no recorded
+// deposit, and returns the window it was asked for.
+func expectReconcile(ta *testApp) *repository.ListReconciliationPaymentsParams {
+	ta.ledgerChain = emptyLedger{}
+	var window repository.ListReconciliationPaymentsParams
+	ta.store.On("ListReconciliationPayments", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { window = args.Get(1).(repository.ListReconciliationPaymentsParams) }).
+		Return([]repository.ListReconciliationPaymentsRow{{
+			ID: testPaymentID, Token: "USDT", UniqueWallet: "TDeposit", Amount: numeric("12.5"), ConfirmedAt: testCreatedAt,
+		}}, nil).Once()
+	ta.store.On("ListOpenSweeps", mock.Anything).Return([]repository.Sweep(nil), nil).Once()
+	ta.store.On("ListPaymentTransactions", mock.Anything, testPaymentID).Return([]repository.Transaction(nil), nil).Once()
+	ta.store.On("CreateReconciliationReport", mock.Anything, mock.MatchedBy(func(p repository.CreateReconciliationReportParams) bool {
+		return p.PaymentsChecked == 1 && p.Mismatches == 1
+	})).Return(repository.ReconciliationReport{ID: testReportID, CreatedAt: testCreatedAt}, nil).Once()
+	ta.store.On("CreateReconciliationMismatch", mock.Anything, mock.MatchedBy(func(p repository.CreateReconciliationMismatchParams) bool {
+		return p.ReportID == testReportID && p.PaymentID == testPaymentID && p.Kind == reconciler.KindMissingTransfer
+	})).Return(nil).Once()
+	return &window
+}
+
+func TestReconcileRun(t *testing.T) {
+	ta := newTestApp(t)
+	window := expectReconcile(ta)
+
+	require.NoError(t, ta.run(context.Background(), []string{"reconcile", "run", "--window", "2h", "--json"}))
+	assert.Equal(t, 2*time.Hour, window.Until.Time.Sub(window.Since.Time))
+	var rec reportRecord
+	ta.decode(t, &rec)
+	assert.Equal(t, testReportID, rec.ID)
+	assert.Equal(t, 1, rec.PaymentsChecked)
+	assert.Equal(t, []mismatchRecord{{
+		PaymentID: testPaymentID,
+		Kind:      reconciler.KindMissingTransfer,
+		Wallet:    "TDeposit",
+		Token:     "USDT",
+		Expected:  "12.5",
+		Actual:    "0",
+		Detail:    "confirmed payment has no recorded deposit",
+	}}, rec.Mismatches)
+}
+
+func TestReconcileRun_DefaultWindow(t *testing.T) {
+	ta := newTestApp(t)
+	ta.cfg.Reconciler.Window = config.Duration(6 * time.Hour)
+	window := expectReconcile(ta)
+
+	require.NoError(t, ta.run(context.Background(), []string{"reconcile", "run"}))
+	assert.Equal(t, 6*time.Hour, window.Until.Time.Sub(window.Since.Time))
+	out := ta.stdout.String()
+	assert.Regexp(t, `Mismatches:\s+1`, out)
+	assert.Contains(t, out, "MISSING_TRANSFER")
+}
+
+func TestReconcileRun_CSV(t *testing.T) {
+	ta := newTestApp(t)
+	expectReconcile(ta)
+
+	require.NoError(t, ta.run(context.Background(), []string{"reconcile", "run", "--window", "1h", "--csv", "-"}))
+	lines := strings.Split(strings.TrimSpace(ta.stdout.String()), "\n")
+	require.Len(t, lines, 2, "the CSV replaces the table")
+	assert.True(t, strings.HasPrefix(lines[0], "report_id,"))
+	assert.Contains(t, lines[1], testReportID.String()+",")
+	assert.Contains(t, lines[1], ",12.500000,0.000000,-12.500000,")
+}
+
+func TestReconcileExport_Latest(t *testing.T) {
+	ta := newTestApp(t)
+	txHash := "f00d"
+	ta.store.On("GetLatestReconciliationReport", mock.Anything).
+		Return(repository.ReconciliationReport{ID: testReportID, WindowStart: testCreatedAt, WindowEnd: testCreatedAt, PaymentsChecked: 3, Mismatches: 1}, nil).Once()
+	ta.store.On("ListReconciliationMismatches", mock.Anything, testReportID).
+		Return([]repository.ReconciliationMismatch{{
+			ReportID: testReportID, PaymentID: testPaymentID, Kind: reconciler.KindSweepShortfall, Wallet: "TDeposit", Token: "TRX",
+			TxHash: &txHash, Expected: numeric("30"), Actual: numeric("20"), Detail: "transferred less",
+		}}, nil).Once()
+	out := filepath.Join(t.TempDir(), "report.csv")
+
+	require.NoError(t, ta.run(context.Background(), []string{"reconcile", "export", "--out", out}))
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"report_id,window_start,window_end,payment_id,kind,wallet,token,tx_hash,expected,actual,difference,detail",
+		testReportID.String() + ",2025-01-02T03:04:05Z,2025-01-02T03:04:05Z," + testPaymentID.String() +
+			",SWEEP_SHORTFALL,TDeposit,TRX,f00d,30.000000,20.000000,-10.000000,transferred less",
+		"",
+	}, "\n"), string(data))
+	assert.Empty(t, ta.stdout.String())
+}
+
+func TestReconcileExport_ByID(t *testing.T) {
+	ta := newTestApp(t)
+	ta.store.On("GetReconciliationReport", mock.Anything, testReportID).
+		Return(repository.ReconciliationReport{ID: testReportID}, nil).Once()
+	ta.store.On("ListReconciliationMismatches", mock.Anything, testReportID).
+		Return([]repository.ReconciliationMismatch(nil), nil).Once()
+
+	require.NoError(t, ta.run(context.Background(), []string{"reconcile", "export", "--id", testReportID.String()}))
+	assert.True(t, strings.HasPrefix(ta.stdout.String(), "report_id,"))
+}
+
+func TestReconcileExport_NotFound(t *testing.T) {
+	ta := newTestApp(t)
+	ta.store.On("GetLatestReconciliationReport", mock.Anything).
+		Return(repository.ReconciliationReport{}, pgx.ErrNoRows).Once()
+
+	require.ErrorContains(t, ta.run(context.Background(), []string{"reconcile", "export"}), "no reconciliation report yet")
+}
+
+func numeric(s string) pgtype.Numeric {
+	var n pgtype.Numeric
+	if err := n.Scan(s); err != nil {
+		panic(err)
+	}
+	return n
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/repair"
+)
+
+func (a *app) repairPayments(ctx context.Context, args []string) error {
+	fs := a.flags("repair payments")
+	fix := fs.Bool("fix", false, "correct what can be corrected; without it nothing is changed")
+	actor := fs.String("actor", os.Getenv("USER"), "operator named in the repair logs")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	store, err := a.database(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Issues are printed below, so the repairer's log would repeat them.
+	r := repair.New(store, repair.WithActor(cliActor(*actor)), repair.WithLogger(slog.New(slog.DiscardHandler)))
+	report, err := r.Scan(ctx)
+	if err != nil {
+		return err
+	}
+	if *fix {
+		if err := r.Fix(ctx, report); err != nil {
+			return err
+		}
+	}
+	if err := a.printRepairReport(report, *fix); err != nil {
+		return err
+	}
+	if _, failed, _ := report.Counts(); failed > 0 {
+		return fmt.Errorf("%d repairs failed", failed)
+	}
+	return nil
+}
+
+func (a *app) printRepairReport(report *repair.Report, fixed bool) error {
+	if a.json {
+		return a.printJSON(report)
+	}
+	repaired, failed, manual := report.Counts()
+	fields := [][2]string{{"Issues", strconv.Itoa(len(report.Issues))}}
+	if fixed {
+		fields = append(fields, [2]string{"Repaired", strconv.Itoa(repaired)}, [2]string{"Failed", strconv.Itoa(failed)})
+	}
+	fields = append(fields, [2]string{"Not fixable", strconv.Itoa(manual)})
+	if err := a.printFields(fields); err != nil {
+		return err
+	}
+	if len(report.Issues) == 0 {
+		return nil
+	}
+	fmt.Fprintln(a.stdout)
+	rows := make([][]string, 0, len(report.Issues))
+	for _, issue := range report.Issues {
+		rows = append(rows, []string{issue.PaymentID.String(), issue.Kind, orDash(issue.Stored), orDash(issue.Expected),
+			repairStatus(issue), issue.Detail})
+	}
+	return a.printTable([]string{"PAYMENT", "KIND", "STORED", "EXPECTED", "STATUS", "DETAIL"}, rows)
+}
+
+// repairStatus says what became of issue, or in a dry run what --fix would
+// do.
+func repairStatus(issue repair.Issue) string {
+	switch {
+	case !issue.Fixable:
+		return "manual"
+	case issue.Repaired:
+		return "repaired"
+	case issue.Error != "":
+		return "failed: " + issue.Error
+	default:
+		return "fixable"
+	}
+}
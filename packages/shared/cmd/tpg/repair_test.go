@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/repair"
+)
+
+var driftedPayment = uuid.MustParse("55555555-5555-5555-5555-555555555555")
+
+// expectDrift seeds one payment whose attempt_count lags its attempts.
+func expectDrift(ta *testApp) {
+	one := int32(1)
+	ta.store.On("ListAttemptCountDrift", mock.Anything).Return([]repository.ListAttemptCountDriftRow{
+		{ID: driftedPayment, AttemptCount: &one, Attempts: 2},
+	}, nil)
+	ta.store.On("ListWalletDrift", mock.Anything).Return(nil, nil)
+	ta.store.On("ListConfirmedWithoutDeposit", mock.Anything).Return([]repository.ListConfirmedWithoutDepositRow{
+		{ID: testPaymentID, UniqueWallet: "TPaid", Token: "USDT"},
+	}, nil)
+}
+
+func TestRepairPayments_DryRun(t *testing.T) {
+	ta := newTestApp(t)
+	expectDrift(ta)
+
+	require.NoError(t, ta.run(context.Background(), []string{"repair", "payments"}))
+
+	out := ta.stdout.String()
+	assert.Contains(t, out, "Issues:       2")
+	assert.NotContains(t, out, "Repaired:")
+	assert.Contains(t, out, driftedPayment.String())
+	assert.Contains(t, out, "fixable")
+	assert.Contains(t, out, "manual")
+	ta.store.AssertNotCalled(t, "SyncAttemptCount", mock.Anything, mock.Anything)
+}
+
+func TestRepairPayments_Fix(t *testing.T) {
+	ta := newTestApp(t)
+	expectDrift(ta)
+	two := int32(2)
+	ta.store.On("SyncAttemptCount", mock.Anything, driftedPayment).Return(&two, nil).Once()
+	ta.store.On("CreateLog", mock.Anything, mock.MatchedBy(func(arg repository.CreateLogParams) bool {
+		return *arg.PaymentID == driftedPayment && arg.EventType == repair.EventPaymentRepaired && *arg.Actor == "tpg:ops"
+	})).Return(nil).Once()
+
+	require.NoError(t, ta.run(context.Background(), []string{"repair", "payments", "--fix", "--actor", "ops", "--json"}))
+
+	var report repair.Report
+	ta.decode(t, &report)
+	require.Len(t, report.Issues, 2)
+	assert.True(t, report.Issues[0].Repaired)
+	assert.Equal(t, "2", report.Issues[0].Expected)
+	assert.False(t, report.Issues[1].Fixable)
+}
+
+func TestRepairPayments_FixFails(t *testing.T) {
+	ta := newTestApp(t)
+	expectDrift(ta)
+	ta.store.On("SyncAttemptCount", mock.Anything, driftedPayment).Return(nil, errors.New("connection reset"))
+
+	err := ta.run(context.Background(), []string{"repair", "payments", "--fix"})
+
+	require.EqualError(t, err, "1 repairs failed")
+	assert.Contains(t, ta.stdout.String(), "failed: failed to update attempt count: connection reset")
+}
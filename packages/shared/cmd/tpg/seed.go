@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/api"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/watcher"
+)
+
+const (
+	// devMnemonic is the BIP-39 test mnemonic. Its keys are public, so the
+	// wallets seeded from it must never receive real funds.
+	devMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+	// seedActor is the actor of the logs seed writes.
+	seedActor = "tpg:seed"
+
+	maxSeedClients  = 100
+	maxSeedAccounts = 20
+	maxSeedPayments = 100
+)
+
+// seedNamespace derives the IDs of seeded rows, so seeding again updates
+// them instead of adding more.
+var seedNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://github.com/yaninyzwitty/tron-payment-gateway/seed"))
+
+// seedStatuses is the order seeded payments cycle through.
+var seedStatuses = []string{repository.PaymentPending, repository.PaymentConfirmed, repository.PaymentExpired}
+
+var seedAccountNames = []string{"Main", "Subscriptions", "Donations", "Marketplace", "Refunds"}
+
+// seededClient is a client as seed reports it, with the API key to try the
+// API with.
+type seededClient struct {
+	ID       uuid.UUID   `json:"id"`
+	Name     string      `json:"name"`
+	APIKey   string      `json:"api_key"`
+	Accounts []uuid.UUID `json:"accounts"`
+	Payments int         `json:"payments"`
+}
+
+type seedResult struct {
+	Wiped    bool           `json:"wiped"`
+	Clients  []seededClient `json:"clients"`
+	Payments map[string]int `json:"payments"`
+}
+
+func (a *app) seed(ctx context.Context, args []string) error {
+	fs := a.flags("seed")
+	clients := fs.Int("clients", 3, fmt.Sprintf("clients to seed, at most %d", maxSeedClients))
+	accounts := fs.Int("accounts", 2, fmt.Sprintf("accounts per client, at most %d", maxSeedAccounts))
+	perAccount := fs.Int("payments", 6, fmt.Sprintf("payments per account, at most %d", maxSeedPayments))
+	mnemonic := fs.String("mnemonic", devMnemonic, "mnemonic the deposit wallets are derived from")
+	wipe := fs.Bool("wipe", false, "delete every client, account, payment and log before seeding")
+	yes := fs.Bool("yes", false, "confirm --wipe")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := seedCounts(*clients, *accounts, *perAccount); err != nil {
+		return err
+	}
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(config.EnvProfileVar))) {
+	case "prod", "production":
+		return fmt.Errorf("refusing to seed with %s=%s", config.EnvProfileVar, os.Getenv(config.EnvProfileVar))
+	}
+	if *wipe {
+		if err := confirm(*yes, "wiping the database"); err != nil {
+			return err
+		}
+	}
+	store, err := a.database(ctx)
+	if err != nil {
+		return err
+	}
+
+	s := seeder{wallets: api.MnemonicWallets(*mnemonic), now: time.Now().UTC()}
+	res := seedResult{Wiped: *wipe, Payments: make(map[string]int)}
+	if *wipe {
+		if err := store.WipeData(ctx); err != nil {
+			return fmt.Errorf("failed to wipe database: %w", err)
+		}
+	}
+	for i := 1; i <= *clients; i++ {
+		var client seededClient
+		// Each client is seeded whole or not at all.
+		err := store.ExecTx(ctx, func(q repository.Querier) error {
+			var err error
+			client, err = s.client(ctx, q, i, *accounts, *perAccount, res.Payments)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to seed client %d: %w", i, err)
+		}
+		res.Clients = append(res.Clients, client)
+	}
+
+	if a.json {
+		return a.printJSON(res)
+	}
+	rows := make([][]string, 0, len(res.Clients))
+	for _, c := range res.Clients {
+		rows = append(rows, []string{c.ID.String(), c.Name, c.APIKey, strconv.Itoa(len(c.Accounts)), strconv.Itoa(c.Payments)})
+	}
+	if err := a.printTable([]string{"CLIENT", "NAME", "API KEY", "ACCOUNTS", "PAYMENTS"}, rows); err != nil {
+		return err
+	}
+	var counts []string
+	for _, status := range sortedKeys(res.Payments) {
+		counts = append(counts, fmt.Sprintf("%d %s", res.Payments[status], status))
+	}
+	fmt.Fprintf(a.stderr, "seeded payments: %s\n", strings.Join(counts, ", "))
+	return nil
+}
+
+func seedCounts(clients, accounts, perAccount int) error {
+	switch {
+	case clients < 1 || clients > maxSeedClients:
+		return fmt.Errorf("--clients must be between 1 and %d", maxSeedClients)
+	case accounts < 1 || accounts > maxSeedAccounts:
+		return fmt.Errorf("--accounts must be between 1 and %d", maxSeedAccounts)
+	case perAccount < 0 || perAccount > maxSeedPayments:
+		return fmt.Errorf("--payments must be between 0 and %d", maxSeedPayments)
+	}
+	return nil
+}
+
+// seeder writes the rows of one seed run. Every row's ID, and everything
+// about it but its timestamps, follows from its position, so a second run
+// finds and updates the rows of the first.
+type seeder struct {
+	wallets api.WalletDeriver
+	now     time.Time
+}
+
+// seedID is the ID of the row at path, e.g. "client/1/account/2".
+func seedID(path string) uuid.UUID {
+	return uuid.NewSHA1(seedNamespace, []byte(path))
+}
+
+// seedKey is the API key of client n. Seeded keys are derivable by anyone,
+// which is fine for the databases seed runs against.
+func seedKey(n int) string {
+	sum := sha256.Sum256([]byte("seed/client/" + strconv.Itoa(n)))
+	return "sk_dev_" + hex.EncodeToString(sum[:12])
+}
+
+// seedAmount is a repeatable, plausible amount for the payment at path: a
+// few dollars to a few hundred for USDT, whole TRX otherwise.
+func seedAmount(path, token string) decimal.Decimal {
+	sum := sha256.Sum256([]byte(path))
+	n := int64(binary.BigEndian.Uint32(sum[:4]))
+	if token == config.TokenTRX {
+		return decimal.NewFromInt(10 + n%4990)
+	}
+	return decimal.New(500+n%49500, -2)
+}
+
+func (s seeder) client(ctx context.Context, q repository.Querier, n, accounts, perAccount int, counts map[string]int) (seededClient, error) {
+	path := fmt.Sprintf("client/%d", n)
+	client, err := q.UpsertClient(ctx, repository.UpsertClientParams{
+		ID:     seedID(path),
+		Name:   fmt.Sprintf("Demo Client %d", n),
+		ApiKey: seedKey(n),
+	})
+	if err != nil {
+		return seededClient{}, fmt.Errorf("failed to upsert client: %w", err)
+	}
+	out := seededClient{ID: client.ID, Name: client.Name, APIKey: client.ApiKey, Accounts: []uuid.UUID{}}
+
+	for j := 1; j <= accounts; j++ {
+		accountPath := fmt.Sprintf("%s/account/%d", path, j)
+		name := seedAccountNames[(j-1)%len(seedAccountNames)]
+		if j > len(seedAccountNames) {
+			name += " " + strconv.Itoa(j)
+		}
+		account, err := q.UpsertAccount(ctx, repository.UpsertAccountParams{ID: seedID(accountPath), ClientID: client.ID, Name: name})
+		if err != nil {
+			return seededClient{}, fmt.Errorf("failed to upsert account %d: %w", j, err)
+		}
+		out.Accounts = append(out.Accounts, account.ID)
+
+		for k := 1; k <= perAccount; k++ {
+			status, err := s.payment(ctx, q, account, fmt.Sprintf("%s/payment/%d", accountPath, k), k)
+			if err != nil {
+				return seededClient{}, fmt.Errorf("failed to seed payment %d of account %d: %w", k, j, err)
+			}
+			counts[status]++
+			out.Payments++
+		}
+	}
+	return out, nil
+}
+
+// payment seeds the k-th payment of account, its first attempt and its
+// logs, and returns its status. A payment seeded before keeps its wallet.
+func (s seeder) payment(ctx context.Context, q repository.Querier, account repository.Account, path string, k int) (string, error) {
+	id := seedID(path)
+	status := seedStatuses[(k-1)%len(seedStatuses)]
+	token := config.TokenUSDT
+	if k%4 == 0 {
+		token = config.TokenTRX
+	}
+	// Older payments were made further in the past.
+	age := time.Duration(k) * 3 * time.Hour
+
+	var wallet string
+	var index int64
+	existing, err := q.GetPayment(ctx, id)
+	switch {
+	case err == nil && existing.WalletIndex != nil:
+		wallet, index = existing.UniqueWallet, *existing.WalletIndex
+	case err == nil || errors.Is(err, pgx.ErrNoRows):
+		if wallet, index, err = payments.AllocateWallet(ctx, q, s.wallets, config.ModeLive); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("failed to look up payment: %w", err)
+	}
+
+	amount := seedAmount(path, token)
+	attempts := int32(1)
+	arg := repository.UpsertPaymentParams{
+		ID:           id,
+		ClientID:     account.ClientID,
+		AccountID:    account.ID,
+		Amount:       pgtype.Numeric{Int: amount.Coefficient(), Exp: amount.Exponent(), Valid: true},
+		UniqueWallet: wallet,
+		Status:       status,
+		AttemptCount: &attempts,
+		WalletIndex:  &index,
+		Token:        token,
+	}
+	switch status {
+	case repository.PaymentPending:
+		arg.ExpiresAt = timestamptz(s.now.Add(30 * time.Minute))
+	case repository.PaymentConfirmed:
+		arg.ExpiresAt = timestamptz(s.now.Add(-age + 30*time.Minute))
+		arg.ConfirmedAt = timestamptz(s.now.Add(-age + 5*time.Minute))
+	case repository.PaymentExpired:
+		arg.ExpiresAt = timestamptz(s.now.Add(-age + 30*time.Minute))
+	}
+	payment, err := q.UpsertPayment(ctx, arg)
+	if err != nil {
+		return "", fmt.Errorf("failed to upsert payment: %w", err)
+	}
+
+	if _, err := q.UpsertPaymentAttempt(ctx, repository.UpsertPaymentAttemptParams{
+		ID:              seedID(path + "/attempt/1"),
+		PaymentID:       payment.ID,
+		AttemptNumber:   attempts,
+		GeneratedWallet: wallet,
+		WalletIndex:     &index,
+	}); err != nil {
+		return "", fmt.Errorf("failed to upsert payment attempt: %w", err)
+	}
+
+	if err := seedLog(ctx, q, path+"/log/address", payment.ID, payments.EventAddressGenerated,
+		fmt.Sprintf("deposit wallet %s generated at index %d", wallet, index),
+		payments.AddressGeneratedLog{Wallet: wallet, WalletIndex: index, Attempt: attempts}); err != nil {
+		return "", err
+	}
+	if status == repository.PaymentExpired {
+		if err := seedLog(ctx, q, path+"/log/expired", payment.ID, watcher.EventExpired,
+			"expired while "+repository.PaymentPending,
+			map[string]any{"previous_status": repository.PaymentPending, "expires_at": arg.ExpiresAt.Time}); err != nil {
+			return "", err
+		}
+	}
+	return status, nil
+}
+
+func seedLog(ctx context.Context, q repository.Querier, path string, paymentID uuid.UUID, event, msg string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode log data: %w", err)
+	}
+	actor := seedActor
+	if err := q.UpsertLog(ctx, repository.UpsertLogParams{
+		ID:        seedID(path),
+		PaymentID: repository.UUIDPtr(paymentID),
+		EventType: event,
+		Message:   &msg,
+		RawData:   raw,
+		Actor:     &actor,
+	}); err != nil {
+		return fmt.Errorf("failed to upsert %s log: %w", event, err)
+	}
+	return nil
+}
+
+func timestamptz(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
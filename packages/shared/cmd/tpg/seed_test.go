@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/api"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/watcher"
+)
+
+// expectSeedWrites makes every upsert of a seed run succeed, echoing its
+// row back, and returns the payments it upserted.
+func expectSeedWrites(store *mockStore) *[]repository.UpsertPaymentParams {
+	var upserted []repository.UpsertPaymentParams
+	client := store.On("UpsertClient", mock.Anything, mock.Anything)
+	client.Run(func(args mock.Arguments) {
+		arg := args.Get(1).(repository.UpsertClientParams)
+		client.ReturnArguments = mock.Arguments{repository.Client{ID: arg.ID, Name: arg.Name, ApiKey: arg.ApiKey}, nil}
+	})
+	account := store.On("UpsertAccount", mock.Anything, mock.Anything)
+	account.Run(func(args mock.Arguments) {
+		arg := args.Get(1).(repository.UpsertAccountParams)
+		account.ReturnArguments = mock.Arguments{repository.Account{ID: arg.ID, ClientID: arg.ClientID, Name: arg.Name}, nil}
+	})
+	payment := store.On("UpsertPayment", mock.Anything, mock.Anything)
+	payment.Run(func(args mock.Arguments) {
+		arg := args.Get(1).(repository.UpsertPaymentParams)
+		upserted = append(upserted, arg)
+		payment.ReturnArguments = mock.Arguments{repository.Payment{ID: arg.ID, Status: arg.Status, UniqueWallet: arg.UniqueWallet, WalletIndex: arg.WalletIndex}, nil}
+	})
+	store.On("UpsertPaymentAttempt", mock.Anything, mock.Anything).Return(repository.PaymentAttempt{}, nil)
+	store.On("UpsertLog", mock.Anything, mock.Anything).Return(nil)
+	return &upserted
+}
+
+func TestSeed(t *testing.T) {
+	ta := newTestApp(t)
+	upserted := expectSeedWrites(ta.store)
+	ta.store.On("GetPayment", mock.Anything, mock.Anything).Return(repository.Payment{}, pgx.ErrNoRows)
+	next := int64(40)
+	index := ta.store.On("NextWalletIndex", mock.Anything, "deposit")
+	index.Run(func(mock.Arguments) {
+		index.ReturnArguments = mock.Arguments{next, nil}
+		next++
+	})
+
+	require.NoError(t, ta.run(context.Background(), []string{"seed", "--clients", "2", "--accounts", "2", "--payments", "4", "--json"}))
+
+	var res seedResult
+	ta.decode(t, &res)
+	require.Len(t, res.Clients, 2)
+	assert.Equal(t, seedID("client/1"), res.Clients[0].ID)
+	assert.Equal(t, seedKey(1), res.Clients[0].APIKey)
+	assert.NotEqual(t, res.Clients[0].APIKey, res.Clients[1].APIKey)
+	assert.Len(t, res.Clients[0].Accounts, 2)
+	assert.Equal(t, 8, res.Clients[0].Payments)
+	assert.Equal(t, map[string]int{
+		repository.PaymentPending:   8,
+		repository.PaymentConfirmed: 4,
+		repository.PaymentExpired:   4,
+	}, res.Payments)
+	assert.False(t, res.Wiped)
+
+	require.Len(t, *upserted, 16)
+	first := (*upserted)[0]
+	assert.Equal(t, seedID("client/1/account/1/payment/1"), first.ID)
+	assert.EqualValues(t, 40, *first.WalletIndex)
+	want, err := api.MnemonicWallets(devMnemonic).DeriveWallet(40)
+	require.NoError(t, err)
+	assert.Equal(t, want, first.UniqueWallet)
+	assert.Equal(t, config.TokenTRX, (*upserted)[3].Token, "every fourth payment is in TRX")
+	for _, p := range *upserted {
+		switch p.Status {
+		case repository.PaymentConfirmed:
+			assert.True(t, p.ConfirmedAt.Valid)
+		default:
+			assert.False(t, p.ConfirmedAt.Valid)
+		}
+		assert.True(t, p.Amount.Valid)
+	}
+
+	ta.store.AssertNumberOfCalls(t, "UpsertPaymentAttempt", 16)
+	// An ADDRESS_GENERATED log for every payment and a PAYMENT_EXPIRED one
+	// for every expired payment.
+	ta.store.AssertNumberOfCalls(t, "UpsertLog", 16+4)
+	ta.store.AssertCalled(t, "UpsertLog", mock.Anything, mock.MatchedBy(func(arg repository.UpsertLogParams) bool {
+		return arg.EventType == watcher.EventExpired && arg.ID == seedID("client/1/account/1/payment/3/log/expired")
+	}))
+	ta.store.AssertCalled(t, "UpsertLog", mock.Anything, mock.MatchedBy(func(arg repository.UpsertLogParams) bool {
+		return arg.EventType == payments.EventAddressGenerated && *arg.Actor == seedActor
+	}))
+	ta.store.AssertNotCalled(t, "WipeData", mock.Anything)
+}
+
+func TestSeed_Rerun(t *testing.T) {
+	ta := newTestApp(t)
+	upserted := expectSeedWrites(ta.store)
+	index := int64(7)
+	ta.store.On("GetPayment", mock.Anything, mock.Anything).Return(repository.Payment{UniqueWallet: "TSeeded", WalletIndex: &index}, nil)
+
+	require.NoError(t, ta.run(context.Background(), []string{"seed", "--clients", "1", "--accounts", "1", "--payments", "3"}))
+
+	ta.store.AssertNotCalled(t, "NextWalletIndex", mock.Anything, mock.Anything)
+	require.Len(t, *upserted, 3)
+	for _, p := range *upserted {
+		assert.Equal(t, "TSeeded", p.UniqueWallet, "a seeded payment keeps its wallet")
+		assert.Equal(t, &index, p.WalletIndex)
+	}
+	assert.Contains(t, ta.stdout.String(), seedKey(1))
+	assert.Contains(t, ta.stderr.String(), "seeded payments: 1 CONFIRMED, 1 EXPIRED, 1 PENDING")
+}
+
+func TestSeed_Wipe(t *testing.T) {
+	ta := newTestApp(t)
+	expectSeedWrites(ta.store)
+	ta.store.On("WipeData", mock.Anything).Return(nil).Once()
+	ta.store.On("GetPayment", mock.Anything, mock.Anything).Return(repository.Payment{}, pgx.ErrNoRows)
+	ta.store.On("NextWalletIndex", mock.Anything, "deposit").Return(int64(0), nil)
+
+	require.NoError(t, ta.run(context.Background(), []string{"seed", "--clients", "1", "--accounts", "1", "--payments", "1", "--wipe", "--yes", "--json"}))
+	var res seedResult
+	ta.decode(t, &res)
+	assert.True(t, res.Wiped)
+}
+
+func TestSeed_WipeRequiresYes(t *testing.T) {
+	ta := newTestApp(t)
+	require.ErrorIs(t, ta.run(context.Background(), []string{"seed", "--wipe"}), errNotConfirmed)
+}
+
+func TestSeed_RefusesProduction(t *testing.T) {
+	t.Setenv(config.EnvProfileVar, "production")
+	ta := newTestApp(t)
+	require.ErrorContains(t, ta.run(context.Background(), []string{"seed"}), "refusing to seed")
+}
+
+func TestSeed_Counts(t *testing.T) {
+	ta := newTestApp(t)
+	require.ErrorContains(t, ta.run(context.Background(), []string{"seed", "--clients", "0"}), "--clients")
+	require.ErrorContains(t, ta.run(context.Background(), []string{"seed", "--accounts", "21"}), "--accounts")
+	require.ErrorContains(t, ta.run(context.Background(), []string{"seed", "--payments", "-1"}), "--payments")
+}
+
+func TestSeedAmount(t *testing.T) {
+	usdt := seedAmount("client/1/account/1/payment/1", config.TokenUSDT)
+	assert.True(t, usdt.Equal(seedAmount("client/1/account/1/payment/1", config.TokenUSDT)), "amounts are repeatable")
+	assert.True(t, usdt.GreaterThanOrEqual(decimalFromString(t, "5")) && usdt.LessThan(decimalFromString(t, "500")), usdt.String())
+	assert.LessOrEqual(t, -usdt.Exponent(), int32(2))
+
+	trx := seedAmount("client/1/account/1/payment/4", config.TokenTRX)
+	assert.True(t, trx.IsInteger())
+	assert.True(t, trx.GreaterThanOrEqual(decimalFromString(t, "10")), trx.String())
+}
+
+func decimalFromString(t *testing.T, s string) decimal.Decimal {
+	t.Helper()
+	d, err := decimal.NewFromString(s)
+	require.NoError(t, err)
+	return d
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/walletcheck"
+)
+
+// maxDeriveRange bounds how many wallets one wallet derive prints.
+const maxDeriveRange = 1000
+
+// walletRecord is a derived deposit wallet. Its private key is never
+// printed.
+type walletRecord struct {
+	Index   uint32 `json:"index"`
+	Address string `json:"address"`
+}
+
+func (a *app) walletDerive(ctx context.Context, args []string) error {
+	fs := a.flags("wallet derive")
+	index := fs.Int64("index", -1, "derivation index of the wallet")
+	span := fs.String("range", "", fmt.Sprintf("inclusive range of indexes, e.g. 0-9, at most %d wallets", maxDeriveRange))
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	first, last, err := deriveRange(*index, *span)
+	if err != nil {
+		return err
+	}
+	wallets, err := a.walletDeriver()
+	if err != nil {
+		return err
+	}
+	recs := make([]walletRecord, 0, last-first+1)
+	for i := first; i <= last; i++ {
+		addr, err := wallets.DeriveWallet(uint32(i))
+		if err != nil {
+			return fmt.Errorf("failed to derive wallet %d: %w", i, err)
+		}
+		recs = append(recs, walletRecord{Index: uint32(i), Address: addr})
+	}
+	if a.json {
+		return a.printJSON(recs)
+	}
+	rows := make([][]string, 0, len(recs))
+	for _, r := range recs {
+		rows = append(rows, []string{strconv.FormatUint(uint64(r.Index), 10), r.Address})
+	}
+	return a.printTable([]string{"INDEX", "ADDRESS"}, rows)
+}
+
+// walletVerify re-derives the wallets of the newest payment attempts, as the
+// API does when it starts, and fails when any differs from the stored one.
+func (a *app) walletVerify(ctx context.Context, args []string) error {
+	fs := a.flags("wallet verify")
+	samples := fs.Int("samples", 0, "how many of the newest payment attempts to check (default payments.derivationCheck.samples)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *samples < 0 || *samples > config.MaxDerivationCheckSamples {
+		return fmt.Errorf("--samples must be between 1 and %d", config.MaxDerivationCheckSamples)
+	}
+	cfg, err := a.config()
+	if err != nil {
+		return err
+	}
+	if *samples == 0 {
+		*samples = cfg.Payments.DerivationCheck.Samples
+	}
+	store, err := a.database(ctx)
+	if err != nil {
+		return err
+	}
+	wallets, err := a.walletDeriver()
+	if err != nil {
+		return err
+	}
+	testWallets, err := a.testWalletDeriver()
+	if err != nil {
+		return err
+	}
+
+	// Mismatches are printed below, so the checker's log would repeat them.
+	opts := []walletcheck.Option{walletcheck.WithLogger(slog.New(slog.DiscardHandler))}
+	if testWallets != nil {
+		opts = append(opts, walletcheck.WithTestWallets(testWallets))
+	}
+	res, err := walletcheck.New(store, wallets, opts...).Check(ctx, *samples)
+	if err != nil {
+		return err
+	}
+	if err := a.printVerification(res); err != nil {
+		return err
+	}
+	return res.Err()
+}
+
+func (a *app) printVerification(res walletcheck.Result) error {
+	if a.json {
+		if res.Mismatches == nil {
+			res.Mismatches = []walletcheck.Mismatch{}
+		}
+		return a.printJSON(res)
+	}
+	fmt.Fprintf(a.stdout, "checked %d wallets, skipped %d, %d mismatched\n", res.Checked, res.Skipped, len(res.Mismatches))
+	if len(res.Mismatches) == 0 {
+		return nil
+	}
+	rows := make([][]string, 0, len(res.Mismatches))
+	for _, m := range res.Mismatches {
+		derived := m.Derived
+		if m.Error != "" {
+			derived = "error: " + m.Error
+		}
+		rows = append(rows, []string{m.Mode, strconv.FormatInt(m.Index, 10), m.PaymentID.String(),
+			strconv.Itoa(int(m.Attempt)), m.Stored, derived})
+	}
+	return a.printTable([]string{"MODE", "INDEX", "PAYMENT", "ATTEMPT", "STORED", "DERIVED"}, rows)
+}
+
+// deriveRange returns the first and last index wallet derive was asked for,
+// through exactly one of --index and --range.
+func deriveRange(index int64, span string) (first, last int64, err error) {
+	switch {
+	case index >= 0 && span != "":
+		return 0, 0, errors.New("--index and --range are mutually exclusive")
+	case index >= 0:
+		first, last = index, index
+	case span != "":
+		lo, hi, ok := strings.Cut(span, "-")
+		if !ok {
+			return 0, 0, fmt.Errorf("--range must look like 0-9, got %q", span)
+		}
+		if first, err = strconv.ParseInt(strings.TrimSpace(lo), 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("--range must look like 0-9, got %q", span)
+		}
+		if last, err = strconv.ParseInt(strings.TrimSpace(hi), 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("--range must look like 0-9, got %q", span)
+		}
+		if first < 0 || last < first {
+			return 0, 0, fmt.Errorf("--range %q is empty", span)
+		}
+		if last-first+1 > maxDeriveRange {
+			return 0, 0, fmt.Errorf("--range covers more than %d wallets", maxDeriveRange)
+		}
+	default:
+		return 0, 0, errors.New("--index or --range is required")
+	}
+	if last > math.MaxUint32 {
+		return 0, 0, fmt.Errorf("derivation indexes stop at %d", uint32(math.MaxUint32))
+	}
+	return first, last, nil
+}
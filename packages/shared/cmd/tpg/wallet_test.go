@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/api"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/walletcheck"
+)
+
+// stubWallets derives "T<index>".
+var stubWallets = api.WalletDeriverFunc(func(index uint32) (string, error) {
+	return fmt.Sprintf("T%d", index), nil
+})
+
+func TestWalletDerive_Index(t *testing.T) {
+	ta := newTestApp(t)
+	ta.wallets = stubWallets
+
+	require.NoError(t, ta.run(context.Background(), []string{"wallet", "derive", "--index", "5", "--json"}))
+	var recs []walletRecord
+	ta.decode(t, &recs)
+	assert.Equal(t, []walletRecord{{Index: 5, Address: "T5"}}, recs)
+}
+
+func TestWalletDerive_Range(t *testing.T) {
+	ta := newTestApp(t)
+	ta.wallets = stubWallets
+
+	require.NoError(t, ta.run(context.Background(), []string{"wallet", "derive", "--range", "2-4"}))
+	out := ta.stdout.String()
+	assert.Contains(t, out, "ADDRESS")
+	for _, addr := range []string{"T2", "T3", "T4"} {
+		assert.Contains(t, out, addr)
+	}
+	assert.NotContains(t, out, "T5")
+}
+
+func TestWalletDerive_Error(t *testing.T) {
+	ta := newTestApp(t)
+	ta.wallets = api.WalletDeriverFunc(func(uint32) (string, error) { return "", errors.New("bad seed") })
+
+	require.ErrorContains(t, ta.run(context.Background(), []string{"wallet", "derive", "--index", "0"}), "failed to derive wallet 0: bad seed")
+}
+
+func TestWalletVerify_Match(t *testing.T) {
+	ta := newTestApp(t)
+	ta.wallets = stubWallets
+	ta.cfg.Payments.DerivationCheck.Samples = 20
+	ta.store.On("ListRecentAttemptWallets", mock.Anything, int32(20)).Return([]repository.ListRecentAttemptWalletsRow{
+		{PaymentID: uuid.New(), AttemptNumber: 1, GeneratedWallet: "T8", WalletIndex: 8, Mode: "live"},
+		{PaymentID: uuid.New(), AttemptNumber: 1, GeneratedWallet: "TTest", WalletIndex: 2, Mode: "test"},
+	}, nil)
+
+	require.NoError(t, ta.run(context.Background(), []string{"wallet", "verify"}))
+	assert.Equal(t, "checked 1 wallets, skipped 1, 0 mismatched\n", ta.stdout.String())
+}
+
+func TestWalletVerify_Mismatch(t *testing.T) {
+	ta := newTestApp(t)
+	ta.wallets = stubWallets
+	paymentID := uuid.New()
+	ta.store.On("ListRecentAttemptWallets", mock.Anything, int32(3)).Return([]repository.ListRecentAttemptWalletsRow{
+		{PaymentID: uuid.New(), AttemptNumber: 1, GeneratedWallet: "T9", WalletIndex: 9, Mode: "live"},
+		{PaymentID: paymentID, AttemptNumber: 2, GeneratedWallet: "TCorrupted", WalletIndex: 8, Mode: "live"},
+	}, nil)
+
+	err := ta.run(context.Background(), []string{"wallet", "verify", "--samples", "3", "--json"})
+
+	require.ErrorIs(t, err, walletcheck.ErrMismatch)
+	assert.ErrorContains(t, err, "live wallet index 8 of payment "+paymentID.String())
+	var res walletcheck.Result
+	ta.decode(t, &res)
+	assert.Equal(t, 2, res.Checked)
+	require.Len(t, res.Mismatches, 1)
+	assert.Equal(t, walletcheck.Mismatch{
+		PaymentID: paymentID,
+		Attempt:   2,
+		Mode:      "live",
+		Index:     8,
+		Stored:    "TCorrupted",
+		Derived:   "T8",
+	}, res.Mismatches[0])
+}
+
+func TestWalletVerify_Samples(t *testing.T) {
+	ta := newTestApp(t)
+	require.ErrorContains(t, ta.run(context.Background(), []string{"wallet", "verify", "--samples", "1001"}), "--samples must be between 1 and 1000")
+}
+
+func TestDeriveRange(t *testing.T) {
+	tests := []struct {
+		name        string
+		index       int64
+		span        string
+		first, last int64
+		wantErr     string
+	}{
+		{name: "index", index: 3, first: 3, last: 3},
+		{name: "range", index: -1, span: "10-19", first: 10, last: 19},
+		{name: "single", index: -1, span: "4-4", first: 4, last: 4},
+		{name: "neither", index: -1, wantErr: "--index or --range is required"},
+		{name: "both", index: 1, span: "1-2", wantErr: "mutually exclusive"},
+		{name: "malformed", index: -1, span: "7", wantErr: "must look like"},
+		{name: "reversed", index: -1, span: "5-2", wantErr: "is empty"},
+		{name: "too wide", index: -1, span: "0-1000", wantErr: "more than 1000"},
+		{name: "past uint32", index: 1 << 32, wantErr: "stop at"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, last, err := deriveRange(tt.index, tt.span)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.first, first)
+			assert.Equal(t, tt.last, last)
+		})
+	}
+}
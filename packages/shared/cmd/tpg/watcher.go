@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/watcher"
+)
+
+// watcherRecord is the progress of a block watcher.
+type watcherRecord struct {
+	Name string `json:"name"`
+	watcher.LagStatus
+	Ready bool `json:"ready"`
+}
+
+func (a *app) watcherStatus(ctx context.Context, args []string) error {
+	fs := a.flags("watcher status")
+	name := fs.String("name", watcher.DefaultName, "name of the watcher, as in its watcher_state row")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	cfg, err := a.config()
+	if err != nil {
+		return err
+	}
+	store, err := a.database(ctx)
+	if err != nil {
+		return err
+	}
+	chain, err := a.tronChain()
+	if err != nil {
+		return err
+	}
+
+	w := watcher.New(chain, store, cfg, watcher.WithName(*name))
+	// A lagging watcher still reports how far behind it is.
+	status, err := w.Ready(ctx)
+	if err != nil && !errors.Is(err, watcher.ErrLagging) {
+		return err
+	}
+	lag, _ := status.(watcher.LagStatus)
+	rec := watcherRecord{Name: *name, LagStatus: lag, Ready: err == nil}
+	if a.json {
+		return a.printJSON(rec)
+	}
+	return a.printFields([][2]string{
+		{"Name", rec.Name},
+		{"Chain height", strconv.FormatInt(rec.ChainHeight, 10)},
+		{"Processed height", strconv.FormatInt(rec.ProcessedHeight, 10)},
+		{"Lag", strconv.FormatInt(rec.Lag, 10)},
+		{"Max lag", strconv.FormatInt(rec.MaxLag, 10)},
+		{"Ready", strconv.FormatBool(rec.Ready)},
+	})
+}
+
+// watcherResetRecord is where a watcher was moved to.
+type watcherResetRecord struct {
+	Name            string `json:"name"`
+	ProcessedHeight int64  `json:"processed_height"`
+}
+
+// watcherReset moves a watcher's persisted height, e.g. to rescan blocks it
+// missed or to skip ones it is stuck on. Its remembered blocks are dropped,
+// so it cannot detect a reorg below the new height. A running watcher
+// notices the move at its next save and carries on from the new height.
+func (a *app) watcherReset(ctx context.Context, args []string) error {
+	fs := a.flags("watcher reset")
+	name := fs.String("name", watcher.DefaultName, "name of the watcher, as in its watcher_state row")
+	height := fs.Int64("height", -1, "last block to treat as scanned; the watcher resumes after it (required)")
+	yes := fs.Bool("yes", false, "confirm blocks are rescanned or skipped")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *height < 0 {
+		return errors.New("--height is required")
+	}
+	if err := confirm(*yes, fmt.Sprintf("moving watcher %s to height %d", *name, *height)); err != nil {
+		return err
+	}
+	store, err := a.database(ctx)
+	if err != nil {
+		return err
+	}
+	err = store.ResetWatcherState(ctx, repository.ResetWatcherStateParams{Name: *name, LastHeight: *height})
+	if err != nil {
+		return fmt.Errorf("failed to reset watcher state: %w", err)
+	}
+	rec := watcherResetRecord{Name: *name, ProcessedHeight: *height}
+	if a.json {
+		return a.printJSON(rec)
+	}
+	return a.printFields([][2]string{
+		{"Name", rec.Name},
+		{"Processed height", strconv.FormatInt(rec.ProcessedHeight, 10)},
+	})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/watcher"
+)
+
+// stubChain reports a fixed chain height.
+type stubChain struct {
+	watcher.Chain
+	height int64
+}
+
+func (c stubChain) GetChainHeight(context.Context) (int64, error) {
+	return c.height, nil
+}
+
+func TestWatcherStatus(t *testing.T) {
+	ta := newTestApp(t)
+	ta.chain = stubChain{height: 1050}
+	ta.store.On("GetWatcherState", mock.Anything, watcher.DefaultName).
+		Return(repository.GetWatcherStateRow{LastHeight: 1000}, nil).Once()
+
+	require.NoError(t, ta.run(context.Background(), []string{"watcher", "status", "--json"}))
+	var rec watcherRecord
+	ta.decode(t, &rec)
+	assert.Equal(t, watcherRecord{
+		Name:      watcher.DefaultName,
+		LagStatus: watcher.LagStatus{ChainHeight: 1050, ProcessedHeight: 1000, Lag: 50, MaxLag: 100},
+		Ready:     true,
+	}, rec)
+}
+
+func TestWatcherStatus_Lagging(t *testing.T) {
+	ta := newTestApp(t)
+	ta.chain = stubChain{height: 1500}
+	ta.store.On("GetWatcherState", mock.Anything, "usdt").
+		Return(repository.GetWatcherStateRow{LastHeight: 1000}, nil).Once()
+
+	require.NoError(t, ta.run(context.Background(), []string{"watcher", "status", "--name", "usdt"}))
+	out := ta.stdout.String()
+	assert.Regexp(t, `Lag:\s+500`, out)
+	assert.Regexp(t, `Ready:\s+false`, out)
+}
+
+func TestWatcherStatus_NeverRun(t *testing.T) {
+	ta := newTestApp(t)
+	ta.chain = stubChain{height: 1500}
+	ta.store.On("GetWatcherState", mock.Anything, watcher.DefaultName).
+		Return(repository.GetWatcherStateRow{}, pgx.ErrNoRows).Once()
+
+	require.ErrorContains(t, ta.run(context.Background(), []string{"watcher", "status"}), "has not processed a block yet")
+}
+
+func TestWatcherReset(t *testing.T) {
+	ta := newTestApp(t)
+	ta.store.On("ResetWatcherState", mock.Anything, repository.ResetWatcherStateParams{Name: "usdt", LastHeight: 900}).
+		Return(nil).Once()
+
+	require.NoError(t, ta.run(context.Background(), []string{"watcher", "reset", "--name", "usdt", "--height", "900", "--yes", "--json"}))
+	var rec watcherResetRecord
+	ta.decode(t, &rec)
+	assert.Equal(t, watcherResetRecord{Name: "usdt", ProcessedHeight: 900}, rec)
+}
+
+func TestWatcherReset_Refused(t *testing.T) {
+	ta := newTestApp(t)
+
+	require.ErrorIs(t, ta.run(context.Background(), []string{"watcher", "reset", "--height", "900"}), errNotConfirmed)
+	require.ErrorContains(t, ta.run(context.Background(), []string{"watcher", "reset", "--yes"}), "--height is required")
+}
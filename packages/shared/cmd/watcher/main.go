@@ -0,0 +1,144 @@
+// Command watcher scans TRON blocks for transfers to payment wallets.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/db"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/events"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/health"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/lifecycle"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/logging"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/metrics"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tracing"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/watcher"
+)
+
+// shutdownTimeout bounds how long in-flight queries get to finish on exit.
+const shutdownTimeout = 10 * time.Second
+
+// serviceName names the process in traces unless tracing.serviceName does.
+const serviceName = "tron-payment-gateway-watcher"
+
+// testWatcherName keys the height of the test mode watcher.
+const testWatcherName = "tron_test"
+
+func main() {
+	configPath := flag.String("config", "config.yaml", "path to the config file")
+	mode := flag.String("mode", config.ModeLive, "payments to watch: live, or test for the testnet of the testMode section")
+	flag.Parse()
+
+	if err := run(*configPath, *mode); err != nil {
+		slog.Error("watcher failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath, mode string) error {
+	var loaded config.Config
+	if err := loaded.LoadConfigForEnv(configPath); err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	logging.Setup(loaded.Logging)
+	// A test watcher reads the testnet and keeps its own height.
+	cfg, name := loaded, watcher.DefaultName
+	switch mode {
+	case config.ModeLive:
+	case config.ModeTest:
+		if !loaded.TestMode.Enabled {
+			return errors.New("--mode test needs testMode.enabled")
+		}
+		cfg, name = *loaded.ForTestMode(), testWatcherName
+	default:
+		return fmt.Errorf("--mode must be live or test, got %q", mode)
+	}
+
+	ctx, stop := lifecycle.SignalContext(context.Background())
+	defer stop()
+
+	tp, shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, serviceName)
+	if err != nil {
+		return err
+	}
+	pool, err := db.ConnectWithRetry(ctx, &cfg, db.DefaultRetryOptions())
+	if err != nil {
+		return err
+	}
+	if err := db.CheckSchema(ctx, pool, cfg.DatabaseConfig.AutoMigrate); err != nil {
+		return err
+	}
+
+	runner := lifecycle.NewRunner()
+	// Components stop in reverse, so spans are flushed last.
+	runner.Add("tracing", lifecycle.OnStop(shutdownTracing))
+	runner.Add("database", lifecycle.OnStop(func(ctx context.Context) error {
+		return db.GracefulClose(ctx, pool, shutdownTimeout)
+	}))
+
+	reg := metrics.NewRegistry()
+	m := metrics.New(reg)
+	client := tron.NewClient(cfg.Tron, cfg.TronAPIKey(), tron.WithMetrics(m), tron.WithTracerProvider(tp))
+	store := repository.NewStore(pool,
+		repository.WithTracerProvider(tp),
+		repository.WithQueryTimeout(cfg.DatabaseConfig.EffectiveQueryTimeout()),
+		repository.WithMetricsRecorder(m),
+	)
+	transitions := events.NewTypedBus(events.WithDropMetrics(m))
+	tracker := watcher.NewConfirmationTracker(client, store, &cfg,
+		watcher.WithSolidity(client.Confirmed()), watcher.WithTrackerMetrics(m), watcher.WithTrackerEvents(transitions))
+
+	background := func(name string, run func(context.Context) error) {
+		runner.Add(name, lifecycle.Loop(run))
+	}
+	background("confirmation tracker", tracker.Run)
+	background("payment expirer", watcher.NewExpirer(store, &cfg,
+		watcher.WithExpirerMetrics(m), watcher.WithExpirerEvents(transitions)).Run)
+	if cfg.BlockWatcher.ZeroConf.Enabled {
+		background("pending pool detector", watcher.NewDetector(client, store, &cfg,
+			watcher.WithDetectorMetrics(m), watcher.WithDetectorEvents(transitions)).Run)
+	}
+
+	w := watcher.New(client, store, &cfg, watcher.WithName(name), watcher.WithTracker(tracker), watcher.WithMetrics(m))
+	if cfg.HealthPort != 0 {
+		probes := health.NewHandler(
+			health.WithCheck("database", func(ctx context.Context) (any, error) {
+				return nil, db.HealthCheck(ctx, pool)
+			}),
+			health.WithCheck("chain_lag", w.Ready),
+		)
+		background("health server", func(ctx context.Context) error {
+			return health.ListenAndServe(ctx, cfg.HealthPort, probes)
+		})
+	}
+	if cfg.MetricsPort != 0 {
+		background("metrics server", func(ctx context.Context) error {
+			return metrics.ListenAndServe(ctx, cfg.MetricsPort, reg)
+		})
+		background("pool stats reporter", func(ctx context.Context) error {
+			m.ReportPoolStats(ctx, pool)
+			return nil
+		})
+		background("tron usage reporter", func(ctx context.Context) error {
+			m.ReportTronUsage(ctx, client)
+			return nil
+		})
+	}
+	background("block watcher", w.Run)
+
+	// Reload the config on SIGHUP or file change. The logger follows it;
+	// other sections are read at startup and their changes only logged.
+	reloader := config.NewWatcher(configPath, &loaded)
+	logging.FollowReloads(reloader)
+	runner.Add("config reloader", lifecycle.Loop(reloader.Watch))
+
+	return runner.Run(ctx)
+}
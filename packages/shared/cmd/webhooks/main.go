@@ -0,0 +1,132 @@
+// Command webhooks relays payment events from the outbox, publishing them to
+// Kafka when configured, and delivers them to merchant endpoints.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/db"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/events"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/health"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/lifecycle"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/logging"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/metrics"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/outbox"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tracing"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/webhooks"
+)
+
+// shutdownTimeout bounds how long in-flight queries get to finish on exit.
+const shutdownTimeout = 10 * time.Second
+
+// serviceName names the process in traces unless tracing.serviceName does.
+const serviceName = "tron-payment-gateway-webhooks"
+
+func main() {
+	configPath := flag.String("config", "config.yaml", "path to the config file")
+	flag.Parse()
+
+	if err := run(*configPath); err != nil {
+		slog.Error("webhook worker failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath string) error {
+	var cfg config.Config
+	if err := cfg.LoadConfigForEnv(configPath); err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	logging.Setup(cfg.Logging)
+
+	kafkaPassword, err := cfg.KafkaPassword()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := lifecycle.SignalContext(context.Background())
+	defer stop()
+
+	tp, shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, serviceName)
+	if err != nil {
+		return err
+	}
+	pool, err := db.ConnectWithRetry(ctx, &cfg, db.DefaultRetryOptions())
+	if err != nil {
+		return err
+	}
+	if err := db.CheckSchema(ctx, pool, cfg.DatabaseConfig.AutoMigrate); err != nil {
+		return err
+	}
+
+	runner := lifecycle.NewRunner()
+	// Components stop in reverse, so spans are flushed last.
+	runner.Add("tracing", lifecycle.OnStop(shutdownTracing))
+	runner.Add("database", lifecycle.OnStop(func(ctx context.Context) error {
+		return db.GracefulClose(ctx, pool, shutdownTimeout)
+	}))
+
+	reg := metrics.NewRegistry()
+	m := metrics.New(reg)
+	store := repository.NewStore(pool,
+		repository.WithTracerProvider(tp),
+		repository.WithQueryTimeout(cfg.DatabaseConfig.EffectiveQueryTimeout()),
+		repository.WithMetricsRecorder(m),
+	)
+	worker := webhooks.NewWorker(store, &cfg, webhooks.WithMetrics(m))
+
+	var publisher events.Publisher = events.NopPublisher{}
+	if cfg.Kafka.Enabled() {
+		kafka, err := events.NewKafkaPublisher(cfg.Kafka, kafkaPassword)
+		if err != nil {
+			return err
+		}
+		runner.Add("kafka", lifecycle.OnStop(func(context.Context) error {
+			kafka.Close()
+			return nil
+		}))
+		publisher = kafka
+	}
+	relay := outbox.NewRelay(store, &cfg,
+		outbox.WithHandler(outbox.Publish(publisher)), outbox.WithTracerProvider(tp))
+
+	background := func(name string, run func(context.Context) error) {
+		runner.Add(name, lifecycle.Loop(run))
+	}
+	if cfg.HealthPort != 0 {
+		probes := health.NewHandler(
+			health.WithCheck("database", func(ctx context.Context) (any, error) {
+				return nil, db.HealthCheck(ctx, pool)
+			}),
+		)
+		background("health server", func(ctx context.Context) error {
+			return health.ListenAndServe(ctx, cfg.HealthPort, probes)
+		})
+	}
+	if cfg.MetricsPort != 0 {
+		background("metrics server", func(ctx context.Context) error {
+			return metrics.ListenAndServe(ctx, cfg.MetricsPort, reg)
+		})
+		background("pool stats reporter", func(ctx context.Context) error {
+			m.ReportPoolStats(ctx, pool)
+			return nil
+		})
+	}
+	background("outbox relay", relay.Run)
+	background("webhook worker", worker.Run)
+
+	// Reload the config on SIGHUP or file change. The logger follows it;
+	// other sections are read at startup and their changes only logged.
+	reloader := config.NewWatcher(configPath, &cfg)
+	logging.FollowReloads(reloader)
+	runner.Add("config reloader", lifecycle.Loop(reloader.Watch))
+
+	return runner.Run(ctx)
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Defaults applied to an unset payments.addressPool section.
+const (
+	DefaultAddressPoolLowWater  = 20
+	DefaultAddressPoolTarget    = 100
+	DefaultAddressPoolBatchSize = 50
+	DefaultAddressPoolWorkers   = 4
+	DefaultAddressPoolInterval  = Duration(30 * time.Second)
+	DefaultAddressPoolLeaseTTL  = Duration(30 * time.Second)
+)
+
+// Bounds on AddressPoolConfig.BatchSize and AddressPoolConfig.Workers.
+const (
+	MaxAddressPoolBatchSize = 1000
+	MaxAddressPoolWorkers   = 64
+)
+
+// AddressPoolConfig tunes the worker that derives deposit addresses ahead of
+// time, so creating a payment does not wait on key derivation.
+type AddressPoolConfig struct {
+	// Enabled turns the worker on and makes payment creation claim its
+	// address from the pool, deriving one on demand only when the pool is
+	// empty.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// LowWater is the count of unreserved addresses below which an
+	// account's pool is refilled.
+	LowWater int `yaml:"lowWater" json:"lowWater"`
+	// Target is the count of unreserved addresses a refill tops a pool up to.
+	Target int `yaml:"target" json:"target"`
+	// BatchSize is the most addresses derived and inserted at once.
+	BatchSize int `yaml:"batchSize" json:"batchSize"`
+	// Workers bounds how many addresses are derived concurrently.
+	Workers int `yaml:"workers" json:"workers"`
+	// Interval is the pause between runs of the worker.
+	Interval Duration `yaml:"interval" json:"interval"`
+	// LeaseTTL bounds how long a leader's lease lasts without renewal; only
+	// the replica holding the lease refills.
+	LeaseTTL Duration `yaml:"leaseTTL" json:"leaseTTL"`
+}
+
+func (a *AddressPoolConfig) applyDefaults() {
+	if a.LowWater == 0 {
+		a.LowWater = DefaultAddressPoolLowWater
+	}
+	if a.Target == 0 {
+		a.Target = max(DefaultAddressPoolTarget, a.LowWater)
+	}
+	if a.BatchSize == 0 {
+		a.BatchSize = DefaultAddressPoolBatchSize
+	}
+	if a.Workers == 0 {
+		a.Workers = DefaultAddressPoolWorkers
+	}
+	if a.Interval == 0 {
+		a.Interval = DefaultAddressPoolInterval
+	}
+	if a.LeaseTTL == 0 {
+		a.LeaseTTL = DefaultAddressPoolLeaseTTL
+	}
+}
+
+func (a AddressPoolConfig) validate() []error {
+	var errs []error
+
+	if a.LowWater < 1 {
+		errs = append(errs, fmt.Errorf("payments.addressPool.lowWater must be at least 1, got %d", a.LowWater))
+	}
+	if a.Target < a.LowWater {
+		errs = append(errs, fmt.Errorf("payments.addressPool.target (%d) must not be below lowWater (%d)", a.Target, a.LowWater))
+	}
+	if a.BatchSize < 1 || a.BatchSize > MaxAddressPoolBatchSize {
+		errs = append(errs, fmt.Errorf("payments.addressPool.batchSize must be between 1 and %d, got %d", MaxAddressPoolBatchSize, a.BatchSize))
+	}
+	if a.Workers < 1 || a.Workers > MaxAddressPoolWorkers {
+		errs = append(errs, fmt.Errorf("payments.addressPool.workers must be between 1 and %d, got %d", MaxAddressPoolWorkers, a.Workers))
+	}
+	if a.Interval <= 0 {
+		errs = append(errs, fmt.Errorf("payments.addressPool.interval must be positive, got %s", a.Interval.Std()))
+	}
+	if a.LeaseTTL < Duration(time.Second) {
+		errs = append(errs, fmt.Errorf("payments.addressPool.leaseTTL must be at least 1s, got %s", a.LeaseTTL.Std()))
+	}
+	return errs
+}
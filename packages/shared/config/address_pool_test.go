@@ -0,0 +1,97 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_LoadConfig_AddressPoolSection(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := `
+appPort: 8080
+database:
+  user: root
+  host: localhost
+  database: tpg
+  maxConnections: 10
+payments:
+  addressPool:
+    enabled: true
+    lowWater: 10
+    target: 40
+    batchSize: 20
+    workers: 8
+    interval: 1m
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath))
+
+	a := cfg.Payments.AddressPool
+	assert.True(t, a.Enabled)
+	assert.Equal(t, 10, a.LowWater)
+	assert.Equal(t, 40, a.Target)
+	assert.Equal(t, 20, a.BatchSize)
+	assert.Equal(t, 8, a.Workers)
+	assert.Equal(t, time.Minute, a.Interval.Std())
+	assert.Equal(t, DefaultAddressPoolLeaseTTL, a.LeaseTTL)
+}
+
+func TestConfig_AddressPoolDefaults(t *testing.T) {
+	cfg := validConfig()
+
+	a := cfg.Payments.AddressPool
+	assert.False(t, a.Enabled)
+	assert.Equal(t, DefaultAddressPoolLowWater, a.LowWater)
+	assert.Equal(t, DefaultAddressPoolTarget, a.Target)
+	assert.Equal(t, DefaultAddressPoolBatchSize, a.BatchSize)
+	assert.Equal(t, DefaultAddressPoolWorkers, a.Workers)
+	assert.Equal(t, DefaultAddressPoolInterval, a.Interval)
+	assert.Equal(t, DefaultAddressPoolLeaseTTL, a.LeaseTTL)
+}
+
+func TestConfig_AddressPoolDefaultTargetFollowsLowWater(t *testing.T) {
+	a := AddressPoolConfig{LowWater: 500}
+	a.applyDefaults()
+
+	assert.Equal(t, 500, a.Target, "an unset target must not fall below a raised low-water mark")
+}
+
+func TestAddressPoolConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mutate  func(*AddressPoolConfig)
+		wantErr string
+	}{
+		{"valid", func(*AddressPoolConfig) {}, ""},
+		{"target equals low water", func(a *AddressPoolConfig) { a.Target = a.LowWater }, ""},
+		{"negative low water", func(a *AddressPoolConfig) { a.LowWater = -1 }, "payments.addressPool.lowWater must be at least 1, got -1"},
+		{"target below low water", func(a *AddressPoolConfig) { a.LowWater, a.Target = 50, 10 }, "payments.addressPool.target (10) must not be below lowWater (50)"},
+		{"batch too large", func(a *AddressPoolConfig) { a.BatchSize = MaxAddressPoolBatchSize + 1 }, "payments.addressPool.batchSize must be between 1 and 1000"},
+		{"too many workers", func(a *AddressPoolConfig) { a.Workers = MaxAddressPoolWorkers + 1 }, "payments.addressPool.workers must be between 1 and 64"},
+		{"negative workers", func(a *AddressPoolConfig) { a.Workers = -2 }, "payments.addressPool.workers must be between 1 and 64, got -2"},
+		{"negative interval", func(a *AddressPoolConfig) { a.Interval = Duration(-time.Second) }, "payments.addressPool.interval must be positive"},
+		{"short lease", func(a *AddressPoolConfig) { a.LeaseTTL = Duration(500 * time.Millisecond) }, "payments.addressPool.leaseTTL must be at least 1s, got 500ms"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.mutate(&cfg.Payments.AddressPool)
+
+			err := cfg.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
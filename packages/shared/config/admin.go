@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// DefaultAdminTokenEnv is read when AdminConfig.TokenEnv is empty.
+const DefaultAdminTokenEnv = "ADMIN_API_TOKEN"
+
+// MinAdminTokenLength is the shortest admin token AdminToken accepts.
+const MinAdminTokenLength = 32
+
+// AdminConfig configures the admin API used to manage clients.
+type AdminConfig struct {
+	// TokenEnv names the environment variable holding the bearer token of
+	// the admin API, ADMIN_API_TOKEN when unset. The token is never read
+	// from the file; without it the admin routes are not served.
+	TokenEnv string `yaml:"tokenEnv" json:"tokenEnv"`
+
+	// token is populated from TokenEnv by Hydrate.
+	token string
+}
+
+// TokenEnvName returns the environment variable the admin token is read from.
+func (a AdminConfig) TokenEnvName() string {
+	if a.TokenEnv != "" {
+		return a.TokenEnv
+	}
+	return DefaultAdminTokenEnv
+}
+
+// AdminToken returns the bearer token of the admin API.
+func (c *Config) AdminToken() (string, error) {
+	token := c.Admin.token
+	if token == "" {
+		return "", fmt.Errorf("admin token is empty: set %s", c.Admin.TokenEnvName())
+	}
+	if len(token) < MinAdminTokenLength {
+		return "", fmt.Errorf("admin token in %s must be at least %d characters", c.Admin.TokenEnvName(), MinAdminTokenLength)
+	}
+	return token, nil
+}
+
+func (a *AdminConfig) hydrate() {
+	if v, ok := os.LookupEnv(a.TokenEnvName()); ok {
+		a.token = v
+	}
+}
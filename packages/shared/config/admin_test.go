@@ -0,0 +1,40 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_AdminToken(t *testing.T) {
+	cfg := validConfig()
+	assert.Equal(t, DefaultAdminTokenEnv, cfg.Admin.TokenEnvName())
+	_, err := cfg.AdminToken()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), DefaultAdminTokenEnv)
+
+	token := strings.Repeat("a", MinAdminTokenLength)
+	t.Setenv("OPS_TOKEN", token)
+	cfg.Admin.TokenEnv = "OPS_TOKEN"
+	cfg.Hydrate()
+
+	got, err := cfg.AdminToken()
+	require.NoError(t, err)
+	assert.Equal(t, token, got)
+
+	redacted := cfg.Redacted()
+	_, err = redacted.AdminToken()
+	assert.Error(t, err, "redacting drops the token")
+}
+
+func TestConfig_AdminTokenTooShort(t *testing.T) {
+	t.Setenv(DefaultAdminTokenEnv, "short")
+	cfg := validConfig()
+	cfg.Hydrate()
+
+	_, err := cfg.AdminToken()
+
+	assert.EqualError(t, err, "admin token in ADMIN_API_TOKEN must be at least 32 characters")
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Defaults and bounds of the payments.amountSuffix section.
+const (
+	DefaultAmountSuffixHold = Duration(time.Hour)
+	MaxAmountSuffixHold     = Duration(7 * 24 * time.Hour)
+)
+
+// AmountSuffixConfig tunes the amount_suffix address strategy, which sends
+// the payments of an account to one deposit wallet and tells them apart by
+// a suffix added to their amounts.
+type AmountSuffixConfig struct {
+	// Hold is how long a suffix stays reserved after its payment expires,
+	// so a transfer mined late is not credited to the next payment given
+	// the suffix.
+	Hold Duration `yaml:"hold" json:"hold"`
+}
+
+func (a *AmountSuffixConfig) applyDefaults() {
+	if a.Hold == 0 {
+		a.Hold = DefaultAmountSuffixHold
+	}
+}
+
+func (a AmountSuffixConfig) validate() []error {
+	if a.Hold < 0 || a.Hold > MaxAmountSuffixHold {
+		return []error{fmt.Errorf("payments.amountSuffix.hold must be between 0s and %s, got %s", MaxAmountSuffixHold.Std(), a.Hold.Std())}
+	}
+	return nil
+}
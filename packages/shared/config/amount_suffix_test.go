@@ -0,0 +1,66 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_LoadConfig_AmountSuffixSection(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := `
+appPort: 8080
+database:
+  user: root
+  host: localhost
+  database: tpg
+  maxConnections: 10
+payments:
+  amountSuffix:
+    hold: 30m
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath))
+
+	assert.Equal(t, 30*time.Minute, cfg.Payments.AmountSuffix.Hold.Std())
+}
+
+func TestConfig_AmountSuffixDefaults(t *testing.T) {
+	cfg := validConfig()
+
+	assert.Equal(t, DefaultAmountSuffixHold, cfg.Payments.AmountSuffix.Hold)
+}
+
+func TestAmountSuffixConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		hold    Duration
+		wantErr string
+	}{
+		{"valid", Duration(time.Hour), ""},
+		{"longest hold", MaxAmountSuffixHold, ""},
+		{"negative hold", Duration(-time.Second), "payments.amountSuffix.hold must be between 0s and 168h0m0s, got -1s"},
+		{"hold too long", MaxAmountSuffixHold + Duration(time.Second), "payments.amountSuffix.hold must be between 0s and 168h0m0s"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Payments.AmountSuffix.Hold = tc.hold
+
+			err := cfg.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package config
+
+import "fmt"
+
+// DefaultMaxBodyBytes bounds a request body when api.maxBodyBytes is unset.
+const DefaultMaxBodyBytes = 64 << 10
+
+// MaxMaxBodyBytes caps APIConfig.MaxBodyBytes. No request the API takes
+// comes close.
+const MaxMaxBodyBytes = 10 << 20
+
+// APIConfig tunes the merchant HTTP API.
+type APIConfig struct {
+	// MaxBodyBytes bounds the body of a request; a larger one gets a 413.
+	MaxBodyBytes int64 `yaml:"maxBodyBytes" json:"maxBodyBytes"`
+}
+
+func (a *APIConfig) applyDefaults() {
+	if a.MaxBodyBytes == 0 {
+		a.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+}
+
+func (a APIConfig) validate() []error {
+	if a.MaxBodyBytes < 1 || a.MaxBodyBytes > MaxMaxBodyBytes {
+		return []error{fmt.Errorf("api.maxBodyBytes must be between 1 and %d, got %d", MaxMaxBodyBytes, a.MaxBodyBytes)}
+	}
+	return nil
+}
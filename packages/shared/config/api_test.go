@@ -0,0 +1,42 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_APIDefaults(t *testing.T) {
+	cfg := validConfig()
+
+	assert.Equal(t, int64(DefaultMaxBodyBytes), cfg.API.MaxBodyBytes)
+}
+
+func TestAPIConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mutate  func(*APIConfig)
+		wantErr string
+	}{
+		{"valid", func(*APIConfig) {}, ""},
+		{"largest body", func(a *APIConfig) { a.MaxBodyBytes = MaxMaxBodyBytes }, ""},
+		{"negative body", func(a *APIConfig) { a.MaxBodyBytes = -1 }, "api.maxBodyBytes must be between 1 and 10485760, got -1"},
+		{"body too large", func(a *APIConfig) { a.MaxBodyBytes = MaxMaxBodyBytes + 1 }, "api.maxBodyBytes must be between 1 and 10485760"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.mutate(&cfg.API)
+
+			err := cfg.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Defaults applied to an unset blockWatcher section.
+const (
+	DefaultBlockPollInterval = Duration(3 * time.Second)
+	DefaultBlockBatchSize    = 100
+	// DefaultZeroConfWindow covers a checkout page left open for a while.
+	DefaultZeroConfWindow       = Duration(30 * time.Minute)
+	DefaultZeroConfPollInterval = Duration(time.Second)
+	// DefaultReorgDepth covers the 19 blocks after which TRON solidifies a
+	// block and it can no longer be reorged.
+	DefaultReorgDepth = 20
+	// DefaultMaxLag is about five minutes of blocks.
+	DefaultMaxLag = 100
+)
+
+// MaxReorgDepth caps BlockWatcherConfig.ReorgDepth; the remembered blocks
+// are saved with the height after every block.
+const MaxReorgDepth = 1000
+
+// MaxBlockBatchSize caps BlockWatcherConfig.BatchSize so a long catch-up
+// cannot hold a single poll open for minutes.
+const MaxBlockBatchSize = 1000
+
+// BlockWatcherConfig tunes the worker that scans new blocks for transfers to
+// payment wallets.
+type BlockWatcherConfig struct {
+	// PollInterval is how long the watcher waits once it has caught up with
+	// the chain head. TRON produces a block every 3 seconds.
+	PollInterval Duration `yaml:"pollInterval" json:"pollInterval"`
+	// BatchSize is the most blocks processed per poll while catching up.
+	BatchSize int `yaml:"batchSize" json:"batchSize"`
+	// StartHeight is the first block scanned when no height has been
+	// persisted yet; 0 starts at the current chain head.
+	StartHeight int64 `yaml:"startHeight" json:"startHeight"`
+	// ReorgDepth is how many of the last scanned blocks the watcher
+	// remembers to detect a reorg and find the fork point. A reorg deeper
+	// than this is still detected, but transfers below the remembered blocks
+	// are not re-checked.
+	ReorgDepth int `yaml:"reorgDepth" json:"reorgDepth"`
+	// MaxLag is how many blocks the watcher may fall behind the chain head
+	// before its readiness probe fails.
+	MaxLag int64 `yaml:"maxLag" json:"maxLag"`
+	// ZeroConf tunes the fast path that spots transfers while they are still
+	// in the pending pool.
+	ZeroConf ZeroConfConfig `yaml:"zeroConf" json:"zeroConf"`
+}
+
+// ZeroConfConfig tunes unconfirmed transfer detection. A payment with a
+// transfer in the pending pool is marked DETECTED so a checkout page can
+// react within seconds; it is still only confirmed from blocks.
+type ZeroConfConfig struct {
+	// Enabled turns detection on; the rest of the section is only validated
+	// when it is set.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Window limits detection to payments created this recently, which
+	// keeps the pending pool polls cheap.
+	Window Duration `yaml:"window" json:"window"`
+	// PollInterval is the pause between pending pool polls.
+	PollInterval Duration `yaml:"pollInterval" json:"pollInterval"`
+}
+
+func (b *BlockWatcherConfig) applyDefaults() {
+	if b.PollInterval == 0 {
+		b.PollInterval = DefaultBlockPollInterval
+	}
+	if b.BatchSize == 0 {
+		b.BatchSize = DefaultBlockBatchSize
+	}
+	if b.ReorgDepth == 0 {
+		b.ReorgDepth = DefaultReorgDepth
+	}
+	if b.MaxLag == 0 {
+		b.MaxLag = DefaultMaxLag
+	}
+	if b.ZeroConf.Window == 0 {
+		b.ZeroConf.Window = DefaultZeroConfWindow
+	}
+	if b.ZeroConf.PollInterval == 0 {
+		b.ZeroConf.PollInterval = DefaultZeroConfPollInterval
+	}
+}
+
+func (b BlockWatcherConfig) validate() []error {
+	var errs []error
+
+	if b.PollInterval <= 0 {
+		errs = append(errs, fmt.Errorf("blockWatcher.pollInterval must be positive, got %s", b.PollInterval.Std()))
+	}
+	if b.BatchSize < 1 || b.BatchSize > MaxBlockBatchSize {
+		errs = append(errs, fmt.Errorf("blockWatcher.batchSize must be between 1 and %d, got %d", MaxBlockBatchSize, b.BatchSize))
+	}
+	if b.StartHeight < 0 {
+		errs = append(errs, fmt.Errorf("blockWatcher.startHeight must not be negative, got %d", b.StartHeight))
+	}
+	if b.ReorgDepth < 1 || b.ReorgDepth > MaxReorgDepth {
+		errs = append(errs, fmt.Errorf("blockWatcher.reorgDepth must be between 1 and %d, got %d", MaxReorgDepth, b.ReorgDepth))
+	}
+	if b.MaxLag < 1 {
+		errs = append(errs, fmt.Errorf("blockWatcher.maxLag must be at least 1, got %d", b.MaxLag))
+	}
+	if b.ZeroConf.Enabled {
+		if b.ZeroConf.Window <= 0 {
+			errs = append(errs, fmt.Errorf("blockWatcher.zeroConf.window must be positive, got %s", b.ZeroConf.Window.Std()))
+		}
+		if b.ZeroConf.PollInterval <= 0 {
+			errs = append(errs, fmt.Errorf("blockWatcher.zeroConf.pollInterval must be positive, got %s", b.ZeroConf.PollInterval.Std()))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_LoadConfig_BlockWatcherSection(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yaml := `
+appPort: 8080
+database:
+  user: root
+  host: localhost
+  database: tpg
+  maxConnections: 10
+blockWatcher:
+  pollInterval: 1s
+  batchSize: 250
+  startHeight: 60000000
+  reorgDepth: 40
+  maxLag: 30
+  zeroConf:
+    enabled: true
+    window: 10m
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath))
+
+	assert.Equal(t, time.Second, cfg.BlockWatcher.PollInterval.Std())
+	assert.Equal(t, 250, cfg.BlockWatcher.BatchSize)
+	assert.Equal(t, int64(60000000), cfg.BlockWatcher.StartHeight)
+	assert.Equal(t, 40, cfg.BlockWatcher.ReorgDepth)
+	assert.Equal(t, int64(30), cfg.BlockWatcher.MaxLag)
+	assert.True(t, cfg.BlockWatcher.ZeroConf.Enabled)
+	assert.Equal(t, 10*time.Minute, cfg.BlockWatcher.ZeroConf.Window.Std())
+	assert.Equal(t, DefaultZeroConfPollInterval, cfg.BlockWatcher.ZeroConf.PollInterval)
+}
+
+func TestConfig_LoadConfig_BlockWatcherDefaults(t *testing.T) {
+	cfg := validConfig()
+
+	assert.Equal(t, DefaultBlockPollInterval, cfg.BlockWatcher.PollInterval)
+	assert.Equal(t, DefaultBlockBatchSize, cfg.BlockWatcher.BatchSize)
+	assert.Zero(t, cfg.BlockWatcher.StartHeight)
+	assert.Equal(t, DefaultReorgDepth, cfg.BlockWatcher.ReorgDepth)
+	assert.Equal(t, int64(DefaultMaxLag), cfg.BlockWatcher.MaxLag)
+	assert.False(t, cfg.BlockWatcher.ZeroConf.Enabled)
+	assert.Equal(t, DefaultZeroConfWindow, cfg.BlockWatcher.ZeroConf.Window)
+}
+
+func TestBlockWatcherConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mutate  func(*BlockWatcherConfig)
+		wantErr string
+	}{
+		{"valid", func(*BlockWatcherConfig) {}, ""},
+		{"negative poll interval", func(b *BlockWatcherConfig) { b.PollInterval = Duration(-time.Second) }, "blockWatcher.pollInterval must be positive"},
+		{"batch too large", func(b *BlockWatcherConfig) { b.BatchSize = MaxBlockBatchSize + 1 }, "blockWatcher.batchSize must be between 1 and 1000"},
+		{"negative batch", func(b *BlockWatcherConfig) { b.BatchSize = -1 }, "blockWatcher.batchSize must be between 1 and 1000"},
+		{"negative start height", func(b *BlockWatcherConfig) { b.StartHeight = -5 }, "blockWatcher.startHeight must not be negative"},
+		{"reorg depth too large", func(b *BlockWatcherConfig) { b.ReorgDepth = MaxReorgDepth + 1 }, "blockWatcher.reorgDepth must be between 1 and 1000"},
+		{"negative reorg depth", func(b *BlockWatcherConfig) { b.ReorgDepth = -1 }, "blockWatcher.reorgDepth must be between 1 and 1000"},
+		{"negative max lag", func(b *BlockWatcherConfig) { b.MaxLag = -1 }, "blockWatcher.maxLag must be at least 1"},
+		{"zero conf disabled ignores window", func(b *BlockWatcherConfig) { b.ZeroConf.Window = -1 }, ""},
+		{"zero conf negative window", func(b *BlockWatcherConfig) {
+			b.ZeroConf.Enabled, b.ZeroConf.Window = true, Duration(-time.Minute)
+		}, "blockWatcher.zeroConf.window must be positive"},
+		{"zero conf negative poll interval", func(b *BlockWatcherConfig) {
+			b.ZeroConf.Enabled, b.ZeroConf.PollInterval = true, Duration(-time.Second)
+		}, "blockWatcher.zeroConf.pollInterval must be positive"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.mutate(&cfg.BlockWatcher)
+
+			err := cfg.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
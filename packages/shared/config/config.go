@@ -1,36 +1,349 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Debug          bool           `yaml:"debug"`
-	AppPort        int            `yaml:"appPort"`
-	DatabaseConfig DatabaseConfig `yaml:"database"`
+	Debug   bool `yaml:"debug" json:"debug"`
+	AppPort int  `yaml:"appPort" json:"appPort"`
+	// HealthPort serves the /healthz and /readyz probes of the workers; 0
+	// turns the listener off.
+	HealthPort int `yaml:"healthPort" json:"healthPort"`
+	// MetricsPort serves Prometheus metrics at /metrics, apart from the API
+	// and the probes; 0 turns the listener off.
+	MetricsPort    int                `yaml:"metricsPort" json:"metricsPort"`
+	DatabaseConfig DatabaseConfig     `yaml:"database" json:"database"`
+	API            APIConfig          `yaml:"api" json:"api"`
+	Tron           TronConfig         `yaml:"tron" json:"tron"`
+	TestMode       TestModeConfig     `yaml:"testMode" json:"testMode"`
+	Payments       PaymentsConfig     `yaml:"payments" json:"payments"`
+	BlockWatcher   BlockWatcherConfig `yaml:"blockWatcher" json:"blockWatcher"`
+	Sweeper        SweeperConfig      `yaml:"sweeper" json:"sweeper"`
+	Reconciler     ReconcilerConfig   `yaml:"reconciler" json:"reconciler"`
+	Retention      RetentionConfig    `yaml:"retention" json:"retention"`
+	Rates          RatesConfig        `yaml:"rates" json:"rates"`
+	Fees           FeesConfig         `yaml:"fees" json:"fees"`
+	Webhooks       WebhooksConfig     `yaml:"webhooks" json:"webhooks"`
+	Outbox         OutboxConfig       `yaml:"outbox" json:"outbox"`
+	Admin          AdminConfig        `yaml:"admin" json:"admin"`
+	GRPC           GRPCConfig         `yaml:"grpc" json:"grpc"`
+	Redis          RedisConfig        `yaml:"redis" json:"redis"`
+	Kafka          KafkaConfig        `yaml:"kafka" json:"kafka"`
+	Tracing        TracingConfig      `yaml:"tracing" json:"tracing"`
+	Logging        LoggingConfig      `yaml:"logging" json:"logging"`
+
+	// mode is set by ForTestMode.
+	mode string
 }
 
 type DatabaseConfig struct {
-	User           string `yaml:"user"`
-	Password       string `yaml:"password"`
-	Host           string `yaml:"host"`
-	Port           int    `yaml:"port"`
-	Database       string `yaml:"database"`
-	MaxConnections int    `yaml:"maxConnections"`
+	User string `yaml:"user" json:"user"`
+	// Password is a fallback for local setups; prefer PasswordEnv.
+	Password string `yaml:"password" json:"password" secret:"true"`
+	// PasswordEnv names the environment variable holding the password,
+	// DATABASE_PASSWORD when unset.
+	PasswordEnv string `yaml:"passwordEnv" json:"passwordEnv"`
+	Host        string `yaml:"host" json:"host"`
+	// Port defaults to DefaultDatabasePort when unset.
+	Port           int    `yaml:"port" json:"port"`
+	Database       string `yaml:"database" json:"database"`
+	MaxConnections int    `yaml:"maxConnections" json:"maxConnections"`
+	MinConnections int    `yaml:"minConnections" json:"minConnections"`
+	// Pool durations fall back to the db package defaults when unset.
+	MaxConnLifetime   Duration `yaml:"maxConnLifetime" json:"maxConnLifetime"`
+	MaxConnIdleTime   Duration `yaml:"maxConnIdleTime" json:"maxConnIdleTime"`
+	HealthCheckPeriod Duration `yaml:"healthCheckPeriod" json:"healthCheckPeriod"`
+	// QueryTimeout bounds each query the repository makes, defaulting to
+	// DefaultQueryTimeout when unset.
+	QueryTimeout Duration `yaml:"queryTimeout" json:"queryTimeout"`
+	// StatementTimeout is set as the session's statement_timeout so the
+	// server gives up on a statement even if the client stops waiting,
+	// defaulting to DefaultStatementTimeout when unset.
+	StatementTimeout Duration `yaml:"statementTimeout" json:"statementTimeout"`
+	SSLMode          string   `yaml:"sslMode" json:"sslMode"`
+	SSLRootCert      string   `yaml:"sslRootCert" json:"sslRootCert"`
+	SSLCert          string   `yaml:"sslCert" json:"sslCert"`
+	SSLKey           string   `yaml:"sslKey" json:"sslKey"`
+	// AutoMigrate lets a service apply pending migrations when it starts
+	// instead of refusing to. Only for single-replica development setups;
+	// see db.CheckSchema.
+	AutoMigrate bool `yaml:"autoMigrate" json:"autoMigrate"`
+
+	// password is populated from PasswordEnv by Hydrate.
+	password string
+}
+
+// DefaultPasswordEnv is read when DatabaseConfig.PasswordEnv is empty.
+const DefaultPasswordEnv = "DATABASE_PASSWORD"
+
+// DefaultDatabasePort is the CockroachDB SQL port, used when Port is unset.
+const DefaultDatabasePort = 26257
+
+// DefaultQueryTimeout is used when DatabaseConfig.QueryTimeout is unset.
+const DefaultQueryTimeout = Duration(5 * time.Second)
+
+// DefaultStatementTimeout is used when DatabaseConfig.StatementTimeout is
+// unset. It is longer than DefaultQueryTimeout, so a query overriding its
+// timeout for a slow report still has room to run.
+const DefaultStatementTimeout = Duration(30 * time.Second)
+
+// Supported values for DatabaseConfig.SSLMode.
+const (
+	SSLModeDisable    = "disable"
+	SSLModeRequire    = "require"
+	SSLModeVerifyCA   = "verify-ca"
+	SSLModeVerifyFull = "verify-full"
+)
+
+// EffectiveSSLMode returns the configured sslmode, defaulting to verify-full
+// when unset, or an error for values other than the supported modes.
+func (d DatabaseConfig) EffectiveSSLMode() (string, error) {
+	switch d.SSLMode {
+	case "":
+		return SSLModeVerifyFull, nil
+	case SSLModeDisable, SSLModeRequire, SSLModeVerifyCA, SSLModeVerifyFull:
+		return d.SSLMode, nil
+	default:
+		return "", fmt.Errorf("invalid sslMode %q: must be one of disable, require, verify-ca, verify-full", d.SSLMode)
+	}
+}
+
+// EffectivePort returns Port, or DefaultDatabasePort when it is unset.
+func (d DatabaseConfig) EffectivePort() int {
+	if d.Port == 0 {
+		return DefaultDatabasePort
+	}
+	return d.Port
+}
+
+// EffectiveQueryTimeout returns QueryTimeout, or DefaultQueryTimeout when
+// it is unset.
+func (d DatabaseConfig) EffectiveQueryTimeout() time.Duration {
+	if d.QueryTimeout == 0 {
+		return DefaultQueryTimeout.Std()
+	}
+	return d.QueryTimeout.Std()
+}
+
+// EffectiveStatementTimeout returns StatementTimeout, or
+// DefaultStatementTimeout when it is unset.
+func (d DatabaseConfig) EffectiveStatementTimeout() time.Duration {
+	if d.StatementTimeout == 0 {
+		return DefaultStatementTimeout.Std()
+	}
+	return d.StatementTimeout.Std()
+}
+
+// PasswordEnvName returns the environment variable the password is read from.
+func (d DatabaseConfig) PasswordEnvName() string {
+	if d.PasswordEnv != "" {
+		return d.PasswordEnv
+	}
+	return DefaultPasswordEnv
+}
+
+// Hydrate fills in values that come from the environment rather than the
+// config file. LoadConfig calls it; configs built in code should too.
+func (c *Config) Hydrate() {
+	if v, ok := os.LookupEnv(c.DatabaseConfig.PasswordEnvName()); ok {
+		c.DatabaseConfig.password = v
+	}
+	c.Tron.hydrate()
+	c.TestMode.hydrate()
+	c.Payments.hydrate()
+	c.Sweeper.hydrate()
+	c.Rates.hydrate()
+	c.Admin.hydrate()
+	c.GRPC.hydrate()
+	c.Redis.hydrate()
+	c.Kafka.hydrate()
+}
+
+// ApplyDefaults fills in unset values of sections that have sensible
+// defaults. LoadConfig calls it before Validate.
+func (c *Config) ApplyDefaults() {
+	c.API.applyDefaults()
+	c.Tron.applyDefaults()
+	c.TestMode.applyDefaults()
+	c.Payments.applyDefaults()
+	c.BlockWatcher.applyDefaults()
+	c.Sweeper.applyDefaults()
+	c.Reconciler.applyDefaults()
+	c.Retention.applyDefaults()
+	c.Rates.applyDefaults()
+	c.Webhooks.applyDefaults()
+	c.Outbox.applyDefaults()
+	c.Redis.applyDefaults()
+	c.Kafka.applyDefaults()
+	c.Tracing.applyDefaults()
+	c.Logging.applyDefaults(c.Debug)
+}
+
+// DatabasePassword returns the password from the environment, falling back to
+// the password key in the file. An empty password is only accepted for
+// insecure local setups (sslMode disable with debug enabled).
+func (c *Config) DatabasePassword() (string, error) {
+	password := c.DatabaseConfig.password
+	if password == "" {
+		password = c.DatabaseConfig.Password
+	}
+	if password != "" {
+		return password, nil
+	}
+	if c.Debug && c.DatabaseConfig.SSLMode == SSLModeDisable {
+		return "", nil
+	}
+	return "", fmt.Errorf("database password is empty: set %s", c.DatabaseConfig.PasswordEnvName())
+}
+
+// LoadOption tweaks LoadConfig.
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	skipValidation bool
+}
+
+// SkipValidation loads the file without calling Validate, for tooling that
+// only needs part of the config.
+func SkipValidation() LoadOption {
+	return func(o *loadOptions) { o.skipValidation = true }
 }
 
-func (c *Config) LoadConfig(path string) error {
+// LoadConfig reads the YAML file at path, expands ${VAR} references in string
+// values, fills in environment values and validates the result.
+func (c *Config) LoadConfig(path string, opts ...LoadOption) error {
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	f, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("failed to read config %w", err)
 	}
 
-	if err := yaml.Unmarshal(f, c); err != nil {
+	return c.load(f, o)
+}
+
+// load decodes data into c and runs the same post-processing for every
+// loader: env expansion, hydration, defaults and validation.
+func (c *Config) load(data []byte, o loadOptions) error {
+	if err := yaml.Unmarshal(data, c); err != nil {
 		return fmt.Errorf("failed to parse config %w", err)
 	}
 
-	return nil
+	if err := expandEnv(c, os.LookupEnv); err != nil {
+		return err
+	}
+
+	c.Hydrate()
+	c.ApplyDefaults()
+
+	if o.skipValidation {
+		return nil
+	}
+	return c.Validate()
+}
+
+// Validate checks the config for values that would only fail later, deep in
+// the driver or server. Every violation is reported, not just the first.
+func (c *Config) Validate() error {
+	var errs []error
+	addf := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if !validPort(c.AppPort) {
+		addf("appPort must be between 1 and 65535, got %d", c.AppPort)
+	}
+	if c.HealthPort != 0 && !validPort(c.HealthPort) {
+		addf("healthPort must be between 1 and 65535, got %d", c.HealthPort)
+	}
+	if c.MetricsPort != 0 {
+		switch {
+		case !validPort(c.MetricsPort):
+			addf("metricsPort must be between 1 and 65535, got %d", c.MetricsPort)
+		case c.MetricsPort == c.AppPort || c.MetricsPort == c.HealthPort:
+			addf("metricsPort %d must differ from appPort and healthPort", c.MetricsPort)
+		}
+	}
+
+	if c.GRPC.Port != 0 && (c.GRPC.Port == c.AppPort || c.GRPC.Port == c.HealthPort || c.GRPC.Port == c.MetricsPort) {
+		addf("grpc.port %d must differ from appPort, healthPort and metricsPort", c.GRPC.Port)
+	}
+
+	db := c.DatabaseConfig
+	if db.Host == "" {
+		addf("database.host is required")
+	}
+	if db.User == "" {
+		addf("database.user is required")
+	}
+	if db.Database == "" {
+		addf("database.database is required")
+	}
+	if db.Port != 0 && !validPort(db.Port) {
+		addf("database.port must be between 1 and 65535, got %d", db.Port)
+	}
+	if db.MaxConnections <= 0 {
+		addf("database.maxConnections must be positive, got %d", db.MaxConnections)
+	}
+	if db.MinConnections < 0 {
+		addf("database.minConnections must not be negative, got %d", db.MinConnections)
+	} else if db.MaxConnections > 0 && db.MinConnections > db.MaxConnections {
+		addf("database.minConnections (%d) must not exceed maxConnections (%d)", db.MinConnections, db.MaxConnections)
+	}
+	for _, d := range []struct {
+		field string
+		value Duration
+	}{
+		{"database.maxConnLifetime", db.MaxConnLifetime},
+		{"database.maxConnIdleTime", db.MaxConnIdleTime},
+		{"database.healthCheckPeriod", db.HealthCheckPeriod},
+		{"database.queryTimeout", db.QueryTimeout},
+		{"database.statementTimeout", db.StatementTimeout},
+	} {
+		if d.value < 0 {
+			addf("%s must not be negative, got %s", d.field, d.value.Std())
+		}
+	}
+	if db.QueryTimeout >= 0 && db.StatementTimeout >= 0 && db.EffectiveStatementTimeout() < db.EffectiveQueryTimeout() {
+		addf("database.statementTimeout (%s) must not be shorter than queryTimeout (%s)", db.EffectiveStatementTimeout(), db.EffectiveQueryTimeout())
+	}
+	if _, err := db.EffectiveSSLMode(); err != nil {
+		addf("database: %w", err)
+	}
+
+	errs = append(errs, c.API.validate()...)
+	errs = append(errs, c.Tron.validate()...)
+	errs = append(errs, c.TestMode.validate()...)
+	errs = append(errs, c.Payments.validate()...)
+	errs = append(errs, c.BlockWatcher.validate()...)
+	errs = append(errs, c.Sweeper.validate()...)
+	errs = append(errs, c.Reconciler.validate()...)
+	errs = append(errs, c.Retention.validate()...)
+	errs = append(errs, c.Rates.validate()...)
+	errs = append(errs, c.Fees.validate()...)
+	errs = append(errs, c.Webhooks.validate()...)
+	errs = append(errs, c.Outbox.validate()...)
+	errs = append(errs, c.GRPC.validate()...)
+	errs = append(errs, c.Redis.validate()...)
+	errs = append(errs, c.Kafka.validate()...)
+	errs = append(errs, c.Tracing.validate()...)
+	errs = append(errs, c.Logging.validate()...)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+}
+
+func validPort(port int) bool {
+	return port >= 1 && port <= 65535
 }
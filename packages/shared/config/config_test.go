@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -60,7 +62,7 @@ database:
 	require.NoError(t, err)
 
 	var cfg Config
-	err = cfg.LoadConfig(configPath)
+	err = cfg.LoadConfig(configPath, SkipValidation())
 
 	assert.NoError(t, err)
 	assert.False(t, cfg.Debug)
@@ -246,7 +248,7 @@ func TestConfig_LoadConfig_EmptyFile(t *testing.T) {
 	require.NoError(t, err)
 
 	var cfg Config
-	err = cfg.LoadConfig(configPath)
+	err = cfg.LoadConfig(configPath, SkipValidation())
 
 	// Empty YAML should parse successfully with zero values
 	assert.NoError(t, err)
@@ -267,7 +269,7 @@ appPort: 5000
 	require.NoError(t, err)
 
 	var cfg Config
-	err = cfg.LoadConfig(configPath)
+	err = cfg.LoadConfig(configPath, SkipValidation())
 
 	assert.NoError(t, err)
 	assert.True(t, cfg.Debug)
@@ -369,8 +371,8 @@ database:
 	var cfg Config
 	err = cfg.LoadConfig(configPath)
 
-	assert.NoError(t, err)
-	assert.Equal(t, -1, cfg.AppPort)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "appPort must be between 1 and 65535, got -1")
 }
 
 func TestConfig_LoadConfig_NegativeMaxConnections(t *testing.T) {
@@ -393,8 +395,8 @@ database:
 	var cfg Config
 	err = cfg.LoadConfig(configPath)
 
-	assert.NoError(t, err)
-	assert.Equal(t, -5, cfg.DatabaseConfig.MaxConnections)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "database.maxConnections must be positive, got -5")
 }
 
 func TestConfig_LoadConfig_ZeroValues(t *testing.T) {
@@ -417,6 +419,13 @@ database:
 	var cfg Config
 	err = cfg.LoadConfig(configPath)
 
+	// Zero values are rejected by default, with every violation listed.
+	require.Error(t, err)
+	for _, want := range []string{"appPort", "database.host", "database.user", "database.database", "database.maxConnections"} {
+		assert.Contains(t, err.Error(), want)
+	}
+
+	err = cfg.LoadConfig(configPath, SkipValidation())
 	assert.NoError(t, err)
 	assert.False(t, cfg.Debug)
 	assert.Equal(t, 0, cfg.AppPort)
@@ -463,4 +472,276 @@ func TestConfig_StructFields(t *testing.T) {
 	assert.Equal(t, "host", cfg.DatabaseConfig.Host)
 	assert.Equal(t, "db", cfg.DatabaseConfig.Database)
 	assert.Equal(t, 50, cfg.DatabaseConfig.MaxConnections)
-}
\ No newline at end of file
+}
+func TestDatabaseConfig_EffectiveSSLMode(t *testing.T) {
+	testCases := []struct {
+		sslMode string
+		want    string
+		wantErr bool
+	}{
+		{"", SSLModeVerifyFull, false},
+		{"disable", SSLModeDisable, false},
+		{"require", SSLModeRequire, false},
+		{"verify-ca", SSLModeVerifyCA, false},
+		{"verify-full", SSLModeVerifyFull, false},
+		{"prefer", "", true},
+		{"VERIFY-FULL", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.sslMode, func(t *testing.T) {
+			got, err := DatabaseConfig{SSLMode: tc.sslMode}.EffectiveSSLMode()
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestConfig_LoadConfig_SSLSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yaml := `
+database:
+  host: localhost
+  sslMode: verify-ca
+  sslRootCert: /certs/ca.crt
+  sslCert: /certs/client.crt
+  sslKey: /certs/client.key
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath, SkipValidation()))
+
+	assert.Equal(t, "verify-ca", cfg.DatabaseConfig.SSLMode)
+	assert.Equal(t, "/certs/ca.crt", cfg.DatabaseConfig.SSLRootCert)
+	assert.Equal(t, "/certs/client.crt", cfg.DatabaseConfig.SSLCert)
+	assert.Equal(t, "/certs/client.key", cfg.DatabaseConfig.SSLKey)
+}
+
+func TestConfig_LoadConfig_PasswordFromDefaultEnv(t *testing.T) {
+	t.Setenv(DefaultPasswordEnv, "from-env")
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yaml := `
+database:
+  user: root
+  host: localhost
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath, SkipValidation()))
+
+	password, err := cfg.DatabasePassword()
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", password)
+}
+
+func TestConfig_LoadConfig_PasswordFromCustomEnv(t *testing.T) {
+	t.Setenv("TPG_DB_PASS", "custom-secret")
+	t.Setenv(DefaultPasswordEnv, "ignored")
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yaml := `
+database:
+  passwordEnv: TPG_DB_PASS
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath, SkipValidation()))
+
+	password, err := cfg.DatabasePassword()
+	require.NoError(t, err)
+	assert.Equal(t, "custom-secret", password)
+	assert.Equal(t, "TPG_DB_PASS", cfg.DatabaseConfig.PasswordEnvName())
+}
+
+func TestConfig_DatabasePassword_EnvOverridesFile(t *testing.T) {
+	t.Setenv(DefaultPasswordEnv, "env-pass")
+	cfg := Config{DatabaseConfig: DatabaseConfig{Password: "file-pass"}}
+	cfg.Hydrate()
+
+	password, err := cfg.DatabasePassword()
+	require.NoError(t, err)
+	assert.Equal(t, "env-pass", password)
+}
+
+func TestConfig_DatabasePassword_FallsBackToFile(t *testing.T) {
+	cfg := Config{DatabaseConfig: DatabaseConfig{PasswordEnv: "TPG_UNSET_PASSWORD_VAR", Password: "file-pass"}}
+	cfg.Hydrate()
+
+	password, err := cfg.DatabasePassword()
+	require.NoError(t, err)
+	assert.Equal(t, "file-pass", password)
+}
+
+func TestConfig_DatabasePassword_EmptyIsError(t *testing.T) {
+	t.Setenv(DefaultPasswordEnv, "")
+
+	testCases := []struct {
+		name    string
+		debug   bool
+		sslMode string
+		wantErr bool
+	}{
+		{"production defaults", false, "", true},
+		{"debug with tls", true, SSLModeVerifyFull, true},
+		{"insecure without debug", false, SSLModeDisable, true},
+		{"insecure debug", true, SSLModeDisable, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Config{Debug: tc.debug, DatabaseConfig: DatabaseConfig{SSLMode: tc.sslMode}}
+			cfg.Hydrate()
+
+			password, err := cfg.DatabasePassword()
+			if tc.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), DefaultPasswordEnv)
+				return
+			}
+			require.NoError(t, err)
+			assert.Empty(t, password)
+		})
+	}
+}
+
+func TestConfig_LoadConfig_PoolSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yaml := `
+database:
+  maxConnections: 50
+  minConnections: 5
+  maxConnLifetime: 10m
+  maxConnIdleTime: 2m
+  healthCheckPeriod: 30s
+  queryTimeout: 2s
+  statementTimeout: 10s
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath, SkipValidation()))
+
+	assert.Equal(t, 5, cfg.DatabaseConfig.MinConnections)
+	assert.Equal(t, 10*time.Minute, cfg.DatabaseConfig.MaxConnLifetime.Std())
+	assert.Equal(t, 2*time.Minute, cfg.DatabaseConfig.MaxConnIdleTime.Std())
+	assert.Equal(t, 30*time.Second, cfg.DatabaseConfig.HealthCheckPeriod.Std())
+	assert.Equal(t, 2*time.Second, cfg.DatabaseConfig.EffectiveQueryTimeout())
+	assert.Equal(t, 10*time.Second, cfg.DatabaseConfig.EffectiveStatementTimeout())
+}
+
+func TestDatabaseConfig_EffectiveTimeouts(t *testing.T) {
+	var db DatabaseConfig
+	assert.Equal(t, DefaultQueryTimeout.Std(), db.EffectiveQueryTimeout())
+	assert.Equal(t, DefaultStatementTimeout.Std(), db.EffectiveStatementTimeout())
+}
+
+func validConfig() Config {
+	cfg := Config{
+		AppPort: 8080,
+		DatabaseConfig: DatabaseConfig{
+			User:           "root",
+			Host:           "localhost",
+			Database:       "tpg",
+			MaxConnections: 10,
+		},
+	}
+	cfg.ApplyDefaults()
+	return cfg
+}
+
+func TestConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mutate  func(*Config)
+		wantErr string
+	}{
+		{"valid", func(*Config) {}, ""},
+		{"port zero", func(c *Config) { c.AppPort = 0 }, "appPort must be between 1 and 65535"},
+		{"port too high", func(c *Config) { c.AppPort = 65536 }, "appPort must be between 1 and 65535"},
+		{"health port unset", func(c *Config) { c.HealthPort = 0 }, ""},
+		{"health port too high", func(c *Config) { c.HealthPort = 70000 }, "healthPort must be between 1 and 65535"},
+		{"metrics port", func(c *Config) { c.MetricsPort = 9090 }, ""},
+		{"metrics port too high", func(c *Config) { c.MetricsPort = 70000 }, "metricsPort must be between 1 and 65535"},
+		{"metrics port shared with api", func(c *Config) { c.MetricsPort = 8080 }, "metricsPort 8080 must differ from appPort and healthPort"},
+		{"database port out of range", func(c *Config) { c.DatabaseConfig.Port = 70000 }, "database.port must be between 1 and 65535"},
+		{"missing host", func(c *Config) { c.DatabaseConfig.Host = "" }, "database.host is required"},
+		{"missing user", func(c *Config) { c.DatabaseConfig.User = "" }, "database.user is required"},
+		{"missing database", func(c *Config) { c.DatabaseConfig.Database = "" }, "database.database is required"},
+		{"zero max connections", func(c *Config) { c.DatabaseConfig.MaxConnections = 0 }, "database.maxConnections must be positive"},
+		{"negative min connections", func(c *Config) { c.DatabaseConfig.MinConnections = -1 }, "database.minConnections must not be negative"},
+		{"min above max", func(c *Config) { c.DatabaseConfig.MinConnections = 20 }, "must not exceed maxConnections"},
+		{"negative duration", func(c *Config) { c.DatabaseConfig.HealthCheckPeriod = Duration(-time.Second) }, "database.healthCheckPeriod must not be negative"},
+		{"negative query timeout", func(c *Config) { c.DatabaseConfig.QueryTimeout = Duration(-time.Second) }, "database.queryTimeout must not be negative"},
+		{"statement timeout below default query timeout", func(c *Config) { c.DatabaseConfig.StatementTimeout = Duration(time.Second) }, "database.statementTimeout (1s) must not be shorter than queryTimeout (5s)"},
+		{"longer query timeout", func(c *Config) { c.DatabaseConfig.QueryTimeout = Duration(20 * time.Second) }, ""},
+		{"bad ssl mode", func(c *Config) { c.DatabaseConfig.SSLMode = "prefer" }, "database: invalid sslMode \"prefer\""},
+		{"grpc port", func(c *Config) { c.GRPC.Port = 9443 }, ""},
+		{"grpc port too high", func(c *Config) { c.GRPC.Port = 70000 }, "grpc.port must be between 1 and 65535"},
+		{"grpc port shared with api", func(c *Config) { c.GRPC.Port = 8080 }, "grpc.port 8080 must differ from appPort, healthPort and metricsPort"},
+		{"grpc cert without key", func(c *Config) { c.GRPC.TLS.CertFile = "server.pem" }, "grpc.tls.certFile and grpc.tls.keyFile must be set together"},
+		{"redis", func(c *Config) { c.Redis = RedisConfig{Addr: "redis:6379", DB: 2} }, ""},
+		{"redis negative db", func(c *Config) { c.Redis.DB = -1 }, "redis.db must not be negative"},
+		{"redis negative ttl", func(c *Config) { c.Redis.ClientCacheTTL = Duration(-time.Second) }, "redis.clientCacheTTL must not be negative"},
+		{"kafka", func(c *Config) { c.Kafka.Brokers = []string{"kafka:9092"} }, ""},
+		{"kafka empty broker", func(c *Config) { c.Kafka.Brokers = []string{"kafka:9092", ""} }, "kafka.brokers[1] must not be empty"},
+		{"kafka sasl", func(c *Config) {
+			c.Kafka = KafkaConfig{Brokers: []string{"kafka:9092"}, SASL: KafkaSASLConfig{Mechanism: KafkaSASLScramSHA512, Username: "gateway"}}
+		}, ""},
+		{"kafka bad sasl mechanism", func(c *Config) {
+			c.Kafka = KafkaConfig{Brokers: []string{"kafka:9092"}, SASL: KafkaSASLConfig{Mechanism: "GSSAPI", Username: "gateway"}}
+		}, `kafka.sasl.mechanism must be one of [PLAIN SCRAM-SHA-256 SCRAM-SHA-512], got "GSSAPI"`},
+		{"kafka sasl without username", func(c *Config) {
+			c.Kafka = KafkaConfig{Brokers: []string{"kafka:9092"}, SASL: KafkaSASLConfig{Mechanism: KafkaSASLPlain}}
+		}, "kafka.sasl.username is required with mechanism PLAIN"},
+		{"kafka off ignores sasl", func(c *Config) { c.Kafka.SASL.Mechanism = "GSSAPI" }, ""},
+		{"tracing", func(c *Config) { c.Tracing = TracingConfig{Endpoint: "http://otel-collector:4318", SampleRatio: 0.25} }, ""},
+		{"tracing endpoint without scheme", func(c *Config) { c.Tracing.Endpoint = "otel-collector:4318" }, `tracing.endpoint must be an http or https URL, got "otel-collector:4318"`},
+		{"tracing ratio above one", func(c *Config) { c.Tracing.SampleRatio = 1.5 }, "tracing.sampleRatio must be between 0 and 1, got 1.5"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.mutate(&cfg)
+
+			err := cfg.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
+
+func TestConfig_Validate_CollectsAllViolations(t *testing.T) {
+	cfg := Config{AppPort: -1, DatabaseConfig: DatabaseConfig{MaxConnections: -5}}
+	cfg.ApplyDefaults()
+
+	err := cfg.Validate()
+
+	require.Error(t, err)
+	lines := strings.Split(err.Error(), "\n")
+	assert.Len(t, lines, 5)
+	assert.True(t, strings.HasPrefix(lines[0], "invalid config: "))
+}
+
+func TestDatabaseConfig_EffectivePort(t *testing.T) {
+	assert.Equal(t, DefaultDatabasePort, DatabaseConfig{}.EffectivePort())
+	assert.Equal(t, 5432, DatabaseConfig{Port: 5432}.EffectivePort())
+}
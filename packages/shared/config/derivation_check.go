@@ -0,0 +1,54 @@
+package config
+
+import "fmt"
+
+// What the API does when the startup derivation check finds a mismatch.
+const (
+	// OnMismatchRefuse stops the process before it serves a request.
+	OnMismatchRefuse = "refuse"
+	// OnMismatchReadOnly serves requests but derives no new deposit wallet,
+	// so payments can be read but not created or given another wallet.
+	OnMismatchReadOnly = "readOnly"
+)
+
+// DefaultDerivationCheckSamples is used when payments.derivationCheck.samples
+// is unset.
+const DefaultDerivationCheckSamples = 20
+
+// MaxDerivationCheckSamples bounds DerivationCheckConfig.Samples, so the
+// check cannot hold up startup for long.
+const MaxDerivationCheckSamples = 1000
+
+// DerivationCheckConfig tunes the check, run as the API starts, that the
+// configured mnemonics still derive the wallets already handed out. A build
+// deriving differently would otherwise give out wallets nobody holds the
+// keys of.
+type DerivationCheckConfig struct {
+	// Disabled skips the check.
+	Disabled bool `yaml:"disabled" json:"disabled"`
+	// Samples is how many of the newest payment attempts are re-derived.
+	Samples int `yaml:"samples" json:"samples"`
+	// OnMismatch is refuse or readOnly; refuse when unset.
+	OnMismatch string `yaml:"onMismatch" json:"onMismatch"`
+}
+
+func (d *DerivationCheckConfig) applyDefaults() {
+	if d.Samples == 0 {
+		d.Samples = DefaultDerivationCheckSamples
+	}
+	if d.OnMismatch == "" {
+		d.OnMismatch = OnMismatchRefuse
+	}
+}
+
+func (d DerivationCheckConfig) validate() []error {
+	var errs []error
+
+	if d.Samples < 1 || d.Samples > MaxDerivationCheckSamples {
+		errs = append(errs, fmt.Errorf("payments.derivationCheck.samples must be between 1 and %d, got %d", MaxDerivationCheckSamples, d.Samples))
+	}
+	if d.OnMismatch != OnMismatchRefuse && d.OnMismatch != OnMismatchReadOnly {
+		errs = append(errs, fmt.Errorf("payments.derivationCheck.onMismatch must be %s or %s, got %q", OnMismatchRefuse, OnMismatchReadOnly, d.OnMismatch))
+	}
+	return errs
+}
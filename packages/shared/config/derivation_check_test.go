@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_LoadConfig_DerivationCheckSection(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := `
+appPort: 8080
+database:
+  user: root
+  host: localhost
+  database: tpg
+  maxConnections: 10
+payments:
+  derivationCheck:
+    samples: 50
+    onMismatch: readOnly
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath))
+
+	d := cfg.Payments.DerivationCheck
+	assert.False(t, d.Disabled)
+	assert.Equal(t, 50, d.Samples)
+	assert.Equal(t, OnMismatchReadOnly, d.OnMismatch)
+}
+
+func TestConfig_DerivationCheckDefaults(t *testing.T) {
+	cfg := validConfig()
+
+	d := cfg.Payments.DerivationCheck
+	assert.False(t, d.Disabled)
+	assert.Equal(t, DefaultDerivationCheckSamples, d.Samples)
+	assert.Equal(t, OnMismatchRefuse, d.OnMismatch)
+}
+
+func TestDerivationCheckConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mutate  func(*DerivationCheckConfig)
+		wantErr string
+	}{
+		{"valid", func(*DerivationCheckConfig) {}, ""},
+		{"read only", func(d *DerivationCheckConfig) { d.OnMismatch = OnMismatchReadOnly }, ""},
+		{"negative samples", func(d *DerivationCheckConfig) { d.Samples = -1 }, "payments.derivationCheck.samples must be between 1 and 1000, got -1"},
+		{"too many samples", func(d *DerivationCheckConfig) { d.Samples = MaxDerivationCheckSamples + 1 }, "payments.derivationCheck.samples must be between 1 and 1000"},
+		{"unknown action", func(d *DerivationCheckConfig) { d.OnMismatch = "ignore" }, `payments.derivationCheck.onMismatch must be refuse or readOnly, got "ignore"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.mutate(&cfg.Payments.DerivationCheck)
+
+			err := cfg.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
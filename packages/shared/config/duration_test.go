@@ -0,0 +1,99 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseDuration(t *testing.T) {
+	testCases := []struct {
+		in      string
+		want    time.Duration
+		wantErr string
+	}{
+		{"30s", 30 * time.Second, ""},
+		{"5m", 5 * time.Minute, ""},
+		{"1h30m", 90 * time.Minute, ""},
+		{"45", 45 * time.Second, ""},
+		{"0", 0, ""},
+		{"", 0, ""},
+		{"soon", 0, `invalid duration "soon"`},
+		{"5 minutes", 0, `invalid duration "5 minutes"`},
+		{"-1s", 0, "must not be negative"},
+		{"-10", 0, "must not be negative"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ParseDuration(tc.in)
+			if tc.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got.Std())
+		})
+	}
+}
+
+func TestDuration_String(t *testing.T) {
+	assert.Equal(t, "30s", Duration(30*time.Second).String())
+	assert.Equal(t, "30m", Duration(30*time.Minute).String())
+	assert.Equal(t, "1h", Duration(time.Hour).String())
+	assert.Equal(t, "1h30m", Duration(90*time.Minute).String())
+	assert.Equal(t, "1m30s", Duration(90*time.Second).String())
+	assert.Equal(t, "0s", Duration(0).String())
+	assert.Equal(t, "250ms", Duration(250*time.Millisecond).String())
+}
+
+func TestDuration_YAML(t *testing.T) {
+	var v struct {
+		Timeout Duration `yaml:"timeout"`
+		Legacy  Duration `yaml:"legacy"`
+	}
+	require.NoError(t, yaml.Unmarshal([]byte("timeout: 5m\nlegacy: 30\n"), &v))
+	assert.Equal(t, 5*time.Minute, v.Timeout.Std())
+	assert.Equal(t, 30*time.Second, v.Legacy.Std())
+
+	out, err := yaml.Marshal(v)
+	require.NoError(t, err)
+	assert.Equal(t, "timeout: 5m\nlegacy: 30s\n", string(out))
+}
+
+func TestDuration_YAMLErrors(t *testing.T) {
+	var v struct {
+		Timeout Duration `yaml:"timeout"`
+	}
+
+	err := yaml.Unmarshal([]byte("timeout: -5s\n"), &v)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must not be negative")
+
+	err = yaml.Unmarshal([]byte("timeout: [1, 2]\n"), &v)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "duration must be a string")
+}
+
+func TestDuration_JSON(t *testing.T) {
+	var v struct {
+		Timeout Duration `json:"timeout"`
+		Legacy  Duration `json:"legacy"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"timeout":"90s","legacy":60}`), &v))
+	assert.Equal(t, 90*time.Second, v.Timeout.Std())
+	assert.Equal(t, time.Minute, v.Legacy.Std())
+
+	out, err := json.Marshal(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"timeout":"1m30s","legacy":"1m"}`, string(out))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"timeout":1.5}`), &v))
+	assert.Error(t, json.Unmarshal([]byte(`{"timeout":"-1m"}`), &v))
+	assert.Error(t, json.Unmarshal([]byte(`{"timeout":true}`), &v))
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// MaxFeePercentPlaces is how many decimal places a fee percentage may have.
+const MaxFeePercentPlaces = 6
+
+// feeDecimals is the unit each token's fees are rounded to: a cent of USDT,
+// which merchants are billed in, and a sun of TRX.
+var feeDecimals = map[string]int32{
+	TokenTRX:  6,
+	TokenUSDT: 2,
+}
+
+// FeeDecimals returns how many decimal places the fees of token are rounded
+// to, ignoring case, or false for a token the gateway does not know.
+func FeeDecimals(token string) (int32, bool) {
+	d, ok := feeDecimals[strings.ToUpper(token)]
+	return d, ok
+}
+
+// FeesConfig is the schedule merchants are charged by for their confirmed
+// live payments. Rates are decimal strings, e.g. "1.5", so they stay exact.
+type FeesConfig struct {
+	// Percent of a payment's amount is charged as its fee; empty charges
+	// none.
+	Percent string `yaml:"percent" json:"percent"`
+	// Minimum is the least fee charged for a payment, in token units; empty
+	// has none.
+	Minimum string `yaml:"minimum" json:"minimum"`
+	// Tokens overrides Percent and Minimum for the payments of a token, e.g.
+	// TRX. An empty field of an override falls back to the section's.
+	Tokens map[string]TokenFeesConfig `yaml:"tokens" json:"tokens"`
+}
+
+// TokenFeesConfig overrides the fee schedule for one token.
+type TokenFeesConfig struct {
+	Percent string `yaml:"percent" json:"percent"`
+	Minimum string `yaml:"minimum" json:"minimum"`
+}
+
+// FeeSchedule is what a token's payments are charged: Percent of each
+// payment's amount, but no less than Minimum per payment.
+type FeeSchedule struct {
+	Percent decimal.Decimal
+	Minimum decimal.Decimal
+}
+
+// Schedule returns the fee schedule of token, ignoring case, with its
+// override applied.
+func (f FeesConfig) Schedule(token string) (FeeSchedule, error) {
+	places, ok := FeeDecimals(token)
+	if !ok {
+		return FeeSchedule{}, fmt.Errorf("fees: unsupported token %q", token)
+	}
+	percent, minimum := f.Percent, f.Minimum
+	percentField, minimumField := "fees.percent", "fees.minimum"
+	for name, o := range f.Tokens {
+		if !strings.EqualFold(name, token) {
+			continue
+		}
+		if o.Percent != "" {
+			percent, percentField = o.Percent, "fees.tokens."+name+".percent"
+		}
+		if o.Minimum != "" {
+			minimum, minimumField = o.Minimum, "fees.tokens."+name+".minimum"
+		}
+	}
+
+	var s FeeSchedule
+	var err error
+	if s.Percent, err = parseFeeRate(percentField, percent, MaxFeePercentPlaces); err != nil {
+		return FeeSchedule{}, err
+	}
+	if s.Percent.GreaterThan(decimal.NewFromInt(100)) {
+		return FeeSchedule{}, fmt.Errorf("%s must be between 0 and 100, got %q", percentField, percent)
+	}
+	if s.Minimum, err = parseFeeRate(minimumField, minimum, places); err != nil {
+		return FeeSchedule{}, err
+	}
+	return s, nil
+}
+
+// parseFeeRate parses a non-negative rate of at most places decimal places;
+// an empty one is zero.
+func parseFeeRate(field, value string, places int32) (decimal.Decimal, error) {
+	if value == "" {
+		return decimal.Zero, nil
+	}
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("%s must be a decimal such as \"1.5\", got %q", field, value)
+	}
+	if d.IsNegative() {
+		return decimal.Zero, fmt.Errorf("%s must not be negative, got %q", field, value)
+	}
+	if !d.Equal(d.Truncate(places)) {
+		return decimal.Zero, fmt.Errorf("%s must have at most %d decimal places, got %q", field, places, value)
+	}
+	return d, nil
+}
+
+func (f FeesConfig) validate() []error {
+	var errs []error
+	for name := range f.Tokens {
+		if !slices.Contains(KnownTokens, strings.ToUpper(name)) {
+			errs = append(errs, fmt.Errorf("fees.tokens: unsupported token %q, must be one of %s",
+				name, strings.Join(KnownTokens, ", ")))
+		}
+	}
+	seen := make(map[string]bool)
+	for _, token := range KnownTokens {
+		_, err := f.Schedule(token)
+		// A bad rate of the section is reported once, not per token.
+		if err != nil && !seen[err.Error()] {
+			seen[err.Error()] = true
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_LoadConfig_FeesSection(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := `
+appPort: 8080
+database:
+  user: root
+  host: localhost
+  database: tpg
+  maxConnections: 10
+fees:
+  percent: "1.5"
+  minimum: "0.5"
+  tokens:
+    TRX:
+      minimum: "2.000001"
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath))
+
+	usdt, err := cfg.Fees.Schedule("usdt")
+	require.NoError(t, err)
+	assert.Equal(t, "1.5", usdt.Percent.String())
+	assert.Equal(t, "0.5", usdt.Minimum.String())
+	trx, err := cfg.Fees.Schedule("TRX")
+	require.NoError(t, err)
+	assert.Equal(t, "1.5", trx.Percent.String(), "an override without a percent keeps the section's")
+	assert.Equal(t, "2.000001", trx.Minimum.String())
+}
+
+func TestFeesConfig_Schedule_Unset(t *testing.T) {
+	s, err := FeesConfig{}.Schedule(TokenUSDT)
+
+	require.NoError(t, err)
+	assert.True(t, s.Percent.Equal(decimal.Zero))
+	assert.True(t, s.Minimum.Equal(decimal.Zero))
+
+	_, err = FeesConfig{}.Schedule("BTC")
+	assert.EqualError(t, err, `fees: unsupported token "BTC"`)
+}
+
+func TestFeeDecimals(t *testing.T) {
+	usdt, ok := FeeDecimals("usdt")
+	assert.True(t, ok)
+	assert.Equal(t, int32(2), usdt, "USDT fees are billed to the cent")
+	trx, ok := FeeDecimals(TokenTRX)
+	assert.True(t, ok)
+	assert.Equal(t, int32(6), trx, "TRX fees are billed to the sun")
+	_, ok = FeeDecimals("BTC")
+	assert.False(t, ok)
+}
+
+func TestFeesConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		fees    FeesConfig
+		wantErr string
+	}{
+		{"unset", FeesConfig{}, ""},
+		{"valid", FeesConfig{Percent: "2.25", Minimum: "0.1", Tokens: map[string]TokenFeesConfig{"trx": {Percent: "3"}}}, ""},
+		{"whole percent", FeesConfig{Percent: "100"}, ""},
+		{"percent not a number", FeesConfig{Percent: "1,5"}, `fees.percent must be a decimal such as "1.5", got "1,5"`},
+		{"negative percent", FeesConfig{Percent: "-1"}, `fees.percent must not be negative, got "-1"`},
+		{"percent over 100", FeesConfig{Percent: "100.5"}, `fees.percent must be between 0 and 100, got "100.5"`},
+		{"percent too precise", FeesConfig{Percent: "0.0000001"}, "fees.percent must have at most 6 decimal places"},
+		{"minimum under a cent", FeesConfig{Minimum: "0.005"}, `fees.minimum must have at most 2 decimal places, got "0.005"`},
+		{"trx minimum to the sun", FeesConfig{Tokens: map[string]TokenFeesConfig{"TRX": {Minimum: "0.000001"}}}, ""},
+		{"trx minimum under a sun", FeesConfig{Tokens: map[string]TokenFeesConfig{"TRX": {Minimum: "0.0000001"}}}, "fees.tokens.TRX.minimum must have at most 6 decimal places"},
+		{"unknown token", FeesConfig{Tokens: map[string]TokenFeesConfig{"BTC": {Percent: "1"}}}, `fees.tokens: unsupported token "BTC"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Fees = tc.fees
+
+			err := cfg.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
+
+func TestFeesConfig_Validate_ReportsSectionOnce(t *testing.T) {
+	err := FeesConfig{Percent: "x"}.validate()
+
+	assert.Len(t, err, 1, "the section's percent is every token's but is reported once")
+}
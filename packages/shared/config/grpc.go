@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// DefaultGRPCSecretEnv is read when GRPCConfig.SecretEnv is empty.
+const DefaultGRPCSecretEnv = "GRPC_SHARED_SECRET"
+
+// MinGRPCSecretLength is the shortest shared secret GRPCSecret accepts.
+const MinGRPCSecretLength = 32
+
+// GRPCConfig configures the internal gRPC payment service. Callers
+// authenticate with the shared secret, or with a client certificate when TLS
+// names a CA.
+type GRPCConfig struct {
+	// Port serves the gRPC API from the api binary; 0 turns it off.
+	Port int `yaml:"port" json:"port"`
+	// Target is the address internal binaries dial, e.g. "api:9443".
+	Target string `yaml:"target" json:"target"`
+	// SecretEnv names the environment variable holding the shared secret,
+	// GRPC_SHARED_SECRET when unset. The secret is never read from the
+	// file.
+	SecretEnv string        `yaml:"secretEnv" json:"secretEnv"`
+	TLS       GRPCTLSConfig `yaml:"tls" json:"tls"`
+
+	// secret is populated from SecretEnv by Hydrate.
+	secret string
+}
+
+// GRPCTLSConfig holds PEM file paths. CertFile and KeyFile are the server's
+// certificate, or the client's when dialling. With CAFile too, the server
+// requires callers to present a certificate signed by it, and callers verify
+// the server against it.
+type GRPCTLSConfig struct {
+	CertFile string `yaml:"certFile" json:"certFile"`
+	KeyFile  string `yaml:"keyFile" json:"keyFile"`
+	CAFile   string `yaml:"caFile" json:"caFile"`
+}
+
+// Enabled reports whether TLS is configured.
+func (t GRPCTLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != "" || t.CAFile != ""
+}
+
+// Mutual reports whether callers authenticate with a certificate.
+func (t GRPCTLSConfig) Mutual() bool {
+	return t.CAFile != "" && t.CertFile != "" && t.KeyFile != ""
+}
+
+// SecretEnvName returns the environment variable the shared secret is read
+// from.
+func (g GRPCConfig) SecretEnvName() string {
+	if g.SecretEnv != "" {
+		return g.SecretEnv
+	}
+	return DefaultGRPCSecretEnv
+}
+
+// GRPCSecret returns the shared secret of the gRPC API.
+func (c *Config) GRPCSecret() (string, error) {
+	secret := c.GRPC.secret
+	if secret == "" {
+		return "", fmt.Errorf("grpc shared secret is empty: set %s", c.GRPC.SecretEnvName())
+	}
+	if len(secret) < MinGRPCSecretLength {
+		return "", fmt.Errorf("grpc shared secret in %s must be at least %d characters", c.GRPC.SecretEnvName(), MinGRPCSecretLength)
+	}
+	return secret, nil
+}
+
+func (g *GRPCConfig) hydrate() {
+	if v, ok := os.LookupEnv(g.SecretEnvName()); ok {
+		g.secret = v
+	}
+}
+
+func (g GRPCConfig) validate() []error {
+	var errs []error
+
+	if g.Port != 0 && !validPort(g.Port) {
+		errs = append(errs, fmt.Errorf("grpc.port must be between 1 and 65535, got %d", g.Port))
+	}
+	if g.TLS.Enabled() && (g.TLS.CertFile == "") != (g.TLS.KeyFile == "") {
+		errs = append(errs, fmt.Errorf("grpc.tls.certFile and grpc.tls.keyFile must be set together"))
+	}
+	return errs
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_GRPCSecret(t *testing.T) {
+	cfg := validConfig()
+	assert.Equal(t, DefaultGRPCSecretEnv, cfg.GRPC.SecretEnvName())
+	_, err := cfg.GRPCSecret()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), DefaultGRPCSecretEnv)
+
+	secret := strings.Repeat("s", MinGRPCSecretLength)
+	t.Setenv("INTERNAL_SECRET", secret)
+	cfg.GRPC.SecretEnv = "INTERNAL_SECRET"
+	cfg.Hydrate()
+
+	got, err := cfg.GRPCSecret()
+	require.NoError(t, err)
+	assert.Equal(t, secret, got)
+
+	redacted := cfg.Redacted()
+	_, err = redacted.GRPCSecret()
+	assert.Error(t, err, "redacting drops the secret")
+}
+
+func TestConfig_GRPCSecretTooShort(t *testing.T) {
+	t.Setenv(DefaultGRPCSecretEnv, "short")
+	cfg := validConfig()
+	cfg.Hydrate()
+
+	_, err := cfg.GRPCSecret()
+
+	assert.EqualError(t, err, "grpc shared secret in GRPC_SHARED_SECRET must be at least 32 characters")
+}
+
+func TestGRPCTLSConfig(t *testing.T) {
+	var tls GRPCTLSConfig
+	assert.False(t, tls.Enabled())
+	assert.False(t, tls.Mutual())
+
+	tls = GRPCTLSConfig{CertFile: "server.pem", KeyFile: "server-key.pem"}
+	assert.True(t, tls.Enabled())
+	assert.False(t, tls.Mutual(), "without a CA callers are not asked for a certificate")
+
+	tls.CAFile = "ca.pem"
+	assert.True(t, tls.Mutual())
+}
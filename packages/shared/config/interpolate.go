@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// envPattern matches $${...} escapes and ${VAR} / ${VAR:-default} references.
+var envPattern = regexp.MustCompile(`\$\$\{[^}]*\}|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandEnv replaces ${VAR} and ${VAR:-default} in every string field
+// reachable from v, which must be a pointer. Like the shell, the default is
+// used when VAR is unset or empty; $${VAR} is left as the literal ${VAR}.
+// References with no value and no default are reported together.
+func expandEnv(v any, lookup func(string) (string, bool)) error {
+	var missing []string
+	expandValue(reflect.ValueOf(v), lookup, &missing)
+	if len(missing) == 0 {
+		return nil
+	}
+	slices.Sort(missing)
+	missing = slices.Compact(missing)
+	return fmt.Errorf("unresolved environment variables in config: %s", strings.Join(missing, ", "))
+}
+
+func expandValue(v reflect.Value, lookup func(string) (string, bool), missing *[]string) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			expandValue(v.Elem(), lookup, missing)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				expandValue(v.Field(i), lookup, missing)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			expandValue(v.Index(i), lookup, missing)
+		}
+	case reflect.Map:
+		// Map values are not addressable, so only string values are rewritten.
+		if v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		for _, k := range v.MapKeys() {
+			v.SetMapIndex(k, reflect.ValueOf(expandString(v.MapIndex(k).String(), lookup, missing)).Convert(v.Type().Elem()))
+		}
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(expandString(v.String(), lookup, missing))
+		}
+	}
+}
+
+func expandString(s string, lookup func(string) (string, bool), missing *[]string) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+	return envPattern.ReplaceAllStringFunc(s, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		groups := envPattern.FindStringSubmatch(match)
+		name, hasDefault, def := groups[1], groups[2] != "", groups[3]
+		if value, ok := lookup(name); ok && value != "" {
+			return value
+		}
+		if hasDefault {
+			return def
+		}
+		*missing = append(*missing, name)
+		return match
+	})
+}
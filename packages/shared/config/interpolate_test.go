@@ -0,0 +1,126 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mapLookup(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+func TestExpandString(t *testing.T) {
+	env := mapLookup(map[string]string{"HOST": "db.internal", "EMPTY": "", "PORT": "26257"})
+
+	testCases := []struct {
+		in          string
+		want        string
+		wantMissing []string
+	}{
+		{"plain", "plain", nil},
+		{"${HOST}", "db.internal", nil},
+		{"${HOST}:${PORT}", "db.internal:26257", nil},
+		{"${UNSET:-fallback}", "fallback", nil},
+		{"${EMPTY:-fallback}", "fallback", nil},
+		{"${UNSET:-}", "", nil},
+		{"${HOST:-fallback}", "db.internal", nil},
+		{"$${HOST}", "${HOST}", nil},
+		{"$${UNSET} and ${HOST}", "${UNSET} and db.internal", nil},
+		{"${UNSET}", "${UNSET}", []string{"UNSET"}},
+		{"${EMPTY}", "${EMPTY}", []string{"EMPTY"}},
+		{"$HOST", "$HOST", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			var missing []string
+			got := expandString(tc.in, env, &missing)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantMissing, missing)
+		})
+	}
+}
+
+func TestExpandEnv_NestedStructs(t *testing.T) {
+	cfg := Config{
+		DatabaseConfig: DatabaseConfig{Host: "${DB_HOST}", User: "${DB_USER:-root}"},
+		Tron:           TronConfig{APIKey: "${TRONGRID_KEY}"},
+		Payments:       PaymentsConfig{SupportedTokens: []string{"${TOKEN:-USDT}"}},
+	}
+
+	err := expandEnv(&cfg, mapLookup(map[string]string{"DB_HOST": "crdb", "TRONGRID_KEY": "k-123"}))
+
+	require.NoError(t, err)
+	assert.Equal(t, "crdb", cfg.DatabaseConfig.Host)
+	assert.Equal(t, "root", cfg.DatabaseConfig.User)
+	assert.Equal(t, "k-123", cfg.Tron.APIKey)
+	assert.Equal(t, []string{"USDT"}, cfg.Payments.SupportedTokens)
+}
+
+func TestExpandEnv_ListsAllMissing(t *testing.T) {
+	cfg := Config{
+		DatabaseConfig: DatabaseConfig{Host: "${DB_HOST}", Database: "${DB_NAME}"},
+		Tron:           TronConfig{APIKey: "${TRONGRID_KEY}", FullNodeURL: "${DB_HOST}"},
+	}
+
+	err := expandEnv(&cfg, mapLookup(nil))
+
+	require.Error(t, err)
+	assert.Equal(t, "unresolved environment variables in config: DB_HOST, DB_NAME, TRONGRID_KEY", err.Error())
+}
+
+func TestExpandEnv_StringMap(t *testing.T) {
+	v := struct{ Headers map[string]string }{Headers: map[string]string{"Authorization": "Bearer ${TOKEN}"}}
+
+	require.NoError(t, expandEnv(&v, mapLookup(map[string]string{"TOKEN": "abc"})))
+	assert.Equal(t, "Bearer abc", v.Headers["Authorization"])
+}
+
+func TestConfig_LoadConfig_Interpolation(t *testing.T) {
+	t.Setenv("TPG_TEST_DB_HOST", "crdb.internal")
+	t.Setenv("TPG_TEST_TRONGRID_KEY", "secret-key")
+	t.Setenv(DefaultTronAPIKeyEnv, "")
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yaml := `
+appPort: 8080
+database:
+  user: ${TPG_TEST_DB_USER:-root}
+  password: $${not-a-var}
+  host: ${TPG_TEST_DB_HOST}
+  database: tpg
+  maxConnections: 10
+tron:
+  apiKey: ${TPG_TEST_TRONGRID_KEY}
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath))
+
+	assert.Equal(t, "root", cfg.DatabaseConfig.User)
+	assert.Equal(t, "${not-a-var}", cfg.DatabaseConfig.Password)
+	assert.Equal(t, "crdb.internal", cfg.DatabaseConfig.Host)
+	assert.Equal(t, "secret-key", cfg.TronAPIKey())
+}
+
+func TestConfig_LoadConfig_InterpolationMissing(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	require.NoError(t, os.WriteFile(configPath, []byte("database:\n  host: ${TPG_TEST_UNSET_HOST}\n"), 0644))
+
+	var cfg Config
+	err := cfg.LoadConfig(configPath, SkipValidation())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "TPG_TEST_UNSET_HOST")
+}
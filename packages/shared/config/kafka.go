@@ -0,0 +1,110 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+)
+
+// DefaultKafkaPasswordEnv is read when KafkaSASLConfig.PasswordEnv is empty.
+const DefaultKafkaPasswordEnv = "KAFKA_PASSWORD"
+
+// DefaultKafkaTopic is applied to an unset topic.
+const DefaultKafkaTopic = "payment-events"
+
+// SASL mechanisms a Kafka cluster may require.
+const (
+	KafkaSASLPlain       = "PLAIN"
+	KafkaSASLScramSHA256 = "SCRAM-SHA-256"
+	KafkaSASLScramSHA512 = "SCRAM-SHA-512"
+)
+
+var kafkaSASLMechanisms = []string{KafkaSASLPlain, KafkaSASLScramSHA256, KafkaSASLScramSHA512}
+
+// KafkaConfig configures the optional stream of payment lifecycle events.
+// With no brokers the outbox relay publishes nothing.
+type KafkaConfig struct {
+	// Brokers are the host:port seed brokers of the cluster.
+	Brokers []string `yaml:"brokers" json:"brokers"`
+	// Topic receives every event, keyed by payment ID.
+	Topic    string `yaml:"topic" json:"topic"`
+	ClientID string `yaml:"clientId" json:"clientId"`
+	// TLS connects to the brokers over TLS, verified against the system
+	// roots.
+	TLS  bool            `yaml:"tls" json:"tls"`
+	SASL KafkaSASLConfig `yaml:"sasl" json:"sasl"`
+}
+
+// KafkaSASLConfig authenticates to the brokers. An empty Mechanism turns
+// SASL off.
+type KafkaSASLConfig struct {
+	// Mechanism is PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512.
+	Mechanism string `yaml:"mechanism" json:"mechanism"`
+	Username  string `yaml:"username" json:"username"`
+	// PasswordEnv names the environment variable holding the password,
+	// KAFKA_PASSWORD when unset. The password is never read from the file.
+	PasswordEnv string `yaml:"passwordEnv" json:"passwordEnv"`
+
+	// password is populated from PasswordEnv by Hydrate.
+	password string
+}
+
+// Enabled reports whether a Kafka cluster is configured.
+func (k KafkaConfig) Enabled() bool {
+	return len(k.Brokers) > 0
+}
+
+// PasswordEnvName returns the environment variable the password is read from.
+func (s KafkaSASLConfig) PasswordEnvName() string {
+	if s.PasswordEnv != "" {
+		return s.PasswordEnv
+	}
+	return DefaultKafkaPasswordEnv
+}
+
+// KafkaPassword returns the SASL password from the environment. It is only
+// required, and only read, when a SASL mechanism is set.
+func (c *Config) KafkaPassword() (string, error) {
+	if c.Kafka.SASL.Mechanism == "" {
+		return "", nil
+	}
+	if c.Kafka.SASL.password == "" {
+		return "", fmt.Errorf("kafka sasl password is empty: set %s", c.Kafka.SASL.PasswordEnvName())
+	}
+	return c.Kafka.SASL.password, nil
+}
+
+func (k *KafkaConfig) hydrate() {
+	if v, ok := os.LookupEnv(k.SASL.PasswordEnvName()); ok {
+		k.SASL.password = v
+	}
+}
+
+func (k *KafkaConfig) applyDefaults() {
+	if k.Topic == "" {
+		k.Topic = DefaultKafkaTopic
+	}
+}
+
+func (k KafkaConfig) validate() []error {
+	if !k.Enabled() {
+		return nil
+	}
+	var errs []error
+
+	for i, b := range k.Brokers {
+		if b == "" {
+			errs = append(errs, fmt.Errorf("kafka.brokers[%d] must not be empty", i))
+		}
+	}
+	if k.SASL.Mechanism == "" {
+		return errs
+	}
+	if !slices.Contains(kafkaSASLMechanisms, k.SASL.Mechanism) {
+		errs = append(errs, fmt.Errorf("kafka.sasl.mechanism must be one of %v, got %q", kafkaSASLMechanisms, k.SASL.Mechanism))
+	}
+	if k.SASL.Username == "" {
+		errs = append(errs, fmt.Errorf("kafka.sasl.username is required with mechanism %s", k.SASL.Mechanism))
+	}
+	return errs
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_KafkaPassword(t *testing.T) {
+	cfg := validConfig()
+	assert.False(t, cfg.Kafka.Enabled())
+	assert.Equal(t, DefaultKafkaTopic, cfg.Kafka.Topic)
+	password, err := cfg.KafkaPassword()
+	require.NoError(t, err, "no password is needed without SASL")
+	assert.Empty(t, password)
+
+	cfg.Kafka = KafkaConfig{
+		Brokers: []string{"kafka:9092"},
+		SASL:    KafkaSASLConfig{Mechanism: KafkaSASLPlain, Username: "gateway", PasswordEnv: "EVENTS_PASSWORD"},
+	}
+	_, err = cfg.KafkaPassword()
+	assert.EqualError(t, err, "kafka sasl password is empty: set EVENTS_PASSWORD")
+
+	t.Setenv("EVENTS_PASSWORD", "hunter2")
+	cfg.Hydrate()
+
+	assert.True(t, cfg.Kafka.Enabled())
+	password, err = cfg.KafkaPassword()
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", password)
+	redacted := cfg.Redacted()
+	_, err = redacted.KafkaPassword()
+	assert.Error(t, err, "redacting drops the password")
+}
+
+func TestKafkaConfig_PasswordEnvName(t *testing.T) {
+	assert.Equal(t, DefaultKafkaPasswordEnv, KafkaSASLConfig{}.PasswordEnvName())
+	assert.Equal(t, "EVENTS_PASSWORD", KafkaSASLConfig{PasswordEnv: "EVENTS_PASSWORD"}.PasswordEnvName())
+}
+
+func TestKafkaConfig_ApplyDefaults(t *testing.T) {
+	k := KafkaConfig{Topic: "payments"}
+	k.applyDefaults()
+	assert.Equal(t, "payments", k.Topic, "a set topic is kept")
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Log levels and formats accepted by LoggingConfig.
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
+// LoggingConfig configures the process logger every binary installs at
+// startup.
+type LoggingConfig struct {
+	// Level is the minimum level logged: debug, info, warn or error. Unset
+	// logs info and up, or debug and up when debug is on.
+	Level string `yaml:"level" json:"level"`
+	// Format is json, the default, or text for reading logs on a terminal.
+	Format string `yaml:"format" json:"format"`
+	// AddSource adds the file and line of the logging call to each record.
+	AddSource bool `yaml:"addSource" json:"addSource"`
+}
+
+func (l *LoggingConfig) applyDefaults(debug bool) {
+	if l.Level == "" {
+		l.Level = LogLevelInfo
+		if debug {
+			l.Level = LogLevelDebug
+		}
+	}
+	if l.Format == "" {
+		l.Format = LogFormatJSON
+	}
+}
+
+func (l LoggingConfig) validate() []error {
+	var errs []error
+
+	if l.Level != "" && !slices.Contains([]string{LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError}, l.Level) {
+		errs = append(errs, fmt.Errorf("logging.level must be debug, info, warn or error, got %q", l.Level))
+	}
+	if l.Format != "" && l.Format != LogFormatJSON && l.Format != LogFormatText {
+		errs = append(errs, fmt.Errorf("logging.format must be json or text, got %q", l.Format))
+	}
+	return errs
+}
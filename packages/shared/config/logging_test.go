@@ -0,0 +1,30 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggingConfig_ApplyDefaults(t *testing.T) {
+	var l LoggingConfig
+	l.applyDefaults(false)
+	assert.Equal(t, LoggingConfig{Level: LogLevelInfo, Format: LogFormatJSON}, l)
+
+	l = LoggingConfig{}
+	l.applyDefaults(true)
+	assert.Equal(t, LogLevelDebug, l.Level, "debug lowers the default level")
+
+	l = LoggingConfig{Level: LogLevelWarn, Format: LogFormatText}
+	l.applyDefaults(true)
+	assert.Equal(t, LoggingConfig{Level: LogLevelWarn, Format: LogFormatText}, l, "set values are kept")
+}
+
+func TestLoggingConfig_Validate(t *testing.T) {
+	assert.Empty(t, LoggingConfig{Level: LogLevelError, Format: LogFormatText}.validate())
+
+	errs := LoggingConfig{Level: "verbose", Format: "logfmt"}.validate()
+	assert.Len(t, errs, 2)
+	assert.ErrorContains(t, errs[0], "logging.level")
+	assert.ErrorContains(t, errs[1], "logging.format")
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Defaults applied to an unset outbox section.
+const (
+	DefaultOutboxPollInterval = Duration(time.Second)
+	DefaultOutboxBatchSize    = 100
+)
+
+// MaxOutboxBatchSize caps how many events one relay transaction claims.
+const MaxOutboxBatchSize = 1000
+
+// OutboxConfig configures the relay handing committed payment events on to
+// webhook deliveries.
+type OutboxConfig struct {
+	// PollInterval is the wait after a pass that found fewer than BatchSize
+	// events; a full batch is followed by the next one at once.
+	PollInterval Duration `yaml:"pollInterval" json:"pollInterval"`
+	BatchSize    int      `yaml:"batchSize" json:"batchSize"`
+}
+
+func (o *OutboxConfig) applyDefaults() {
+	if o.PollInterval == 0 {
+		o.PollInterval = DefaultOutboxPollInterval
+	}
+	if o.BatchSize == 0 {
+		o.BatchSize = DefaultOutboxBatchSize
+	}
+}
+
+func (o OutboxConfig) validate() []error {
+	var errs []error
+
+	if o.PollInterval <= 0 {
+		errs = append(errs, fmt.Errorf("outbox.pollInterval must be positive, got %s", o.PollInterval.Std()))
+	}
+	if o.BatchSize < 1 || o.BatchSize > MaxOutboxBatchSize {
+		errs = append(errs, fmt.Errorf("outbox.batchSize must be between 1 and %d, got %d", MaxOutboxBatchSize, o.BatchSize))
+	}
+	return errs
+}
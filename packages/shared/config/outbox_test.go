@@ -0,0 +1,44 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_OutboxDefaults(t *testing.T) {
+	cfg := validConfig()
+
+	assert.Equal(t, DefaultOutboxPollInterval, cfg.Outbox.PollInterval)
+	assert.Equal(t, DefaultOutboxBatchSize, cfg.Outbox.BatchSize)
+}
+
+func TestOutboxConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mutate  func(*OutboxConfig)
+		wantErr string
+	}{
+		{"valid", func(*OutboxConfig) {}, ""},
+		{"negative poll interval", func(o *OutboxConfig) { o.PollInterval = Duration(-time.Second) }, "outbox.pollInterval must be positive"},
+		{"negative batch", func(o *OutboxConfig) { o.BatchSize = -1 }, "outbox.batchSize must be between 1 and 1000"},
+		{"batch too large", func(o *OutboxConfig) { o.BatchSize = MaxOutboxBatchSize + 1 }, "outbox.batchSize must be between 1 and 1000"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.mutate(&cfg.Outbox)
+
+			err := cfg.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvProfileVar selects the overlay used by LoadConfigForEnv, e.g. staging.
+const EnvProfileVar = "TPG_ENV"
+
+// LoadConfigWithOverlays reads base and deep-merges each overlay onto it in
+// order before decoding: mappings are merged key by key, while scalars and
+// sequences in the overlay replace the base value. Because merging happens on
+// the parsed documents, any key present in an overlay wins, including zero
+// values such as debug: false. The merged result then goes through the same
+// env expansion, defaults and validation as LoadConfig.
+func (c *Config) LoadConfigWithOverlays(base string, overlays ...string) error {
+	merged, err := readYAMLMap(base)
+	if err != nil {
+		return err
+	}
+	for _, path := range overlays {
+		overlay, err := readYAMLMap(path)
+		if err != nil {
+			return err
+		}
+		merged = mergeMaps(merged, overlay)
+	}
+
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return fmt.Errorf("failed to merge config overlays: %w", err)
+	}
+	return c.load(data, loadOptions{})
+}
+
+// LoadConfigForEnv loads path and, when TPG_ENV is set, the profile overlay
+// next to it: config.yaml plus config.staging.yaml for TPG_ENV=staging.
+func (c *Config) LoadConfigForEnv(path string) error {
+	env := strings.TrimSpace(os.Getenv(EnvProfileVar))
+	if env == "" {
+		return c.LoadConfig(path)
+	}
+	return c.LoadConfigWithOverlays(path, OverlayPath(path, env))
+}
+
+// OverlayPath returns the profile file for env alongside path.
+func OverlayPath(path, env string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + env + ext
+}
+
+func readYAMLMap(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %w", err)
+	}
+	m := map[string]any{}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// mergeMaps merges overlay into base and returns base.
+func mergeMaps(base, overlay map[string]any) map[string]any {
+	for k, ov := range overlay {
+		if bm, ok := base[k].(map[string]any); ok {
+			if om, ok := ov.(map[string]any); ok {
+				base[k] = mergeMaps(bm, om)
+				continue
+			}
+		}
+		base[k] = ov
+	}
+	return base
+}
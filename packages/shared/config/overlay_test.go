@@ -0,0 +1,161 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const overlayBaseYAML = `
+debug: true
+appPort: 8080
+database:
+  user: root
+  host: localhost
+  database: tpg
+  maxConnections: 10
+  minConnections: 2
+payments:
+  supportedTokens: [USDT, TRX]
+`
+
+func writeConfigFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestMergeMaps(t *testing.T) {
+	base := map[string]any{
+		"debug":   true,
+		"appPort": 8080,
+		"database": map[string]any{
+			"host":           "localhost",
+			"maxConnections": 10,
+		},
+		"tokens": []any{"USDT", "TRX"},
+	}
+	overlay := map[string]any{
+		"debug": false,
+		"database": map[string]any{
+			"host": "crdb.prod",
+		},
+		"tokens": []any{"USDT"},
+		"extra":  "new",
+	}
+
+	merged := mergeMaps(base, overlay)
+
+	assert.Equal(t, false, merged["debug"], "explicit zero value in overlay must win")
+	assert.Equal(t, 8080, merged["appPort"])
+	assert.Equal(t, map[string]any{"host": "crdb.prod", "maxConnections": 10}, merged["database"])
+	assert.Equal(t, []any{"USDT"}, merged["tokens"], "slices are replaced, not appended")
+	assert.Equal(t, "new", merged["extra"])
+}
+
+func TestMergeMaps_ScalarReplacesMap(t *testing.T) {
+	merged := mergeMaps(
+		map[string]any{"database": map[string]any{"host": "a"}},
+		map[string]any{"database": nil},
+	)
+	assert.Nil(t, merged["database"])
+}
+
+func TestConfig_LoadConfigWithOverlays(t *testing.T) {
+	dir := t.TempDir()
+	base := writeConfigFile(t, dir, "config.yaml", overlayBaseYAML)
+	prod := writeConfigFile(t, dir, "config.prod.yaml", `
+debug: false
+database:
+  host: crdb.prod
+  maxConnections: 50
+payments:
+  supportedTokens: [USDT]
+`)
+	local := writeConfigFile(t, dir, "config.local.yaml", `
+appPort: 9090
+`)
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfigWithOverlays(base, prod, local))
+
+	assert.False(t, cfg.Debug)
+	assert.Equal(t, 9090, cfg.AppPort)
+	assert.Equal(t, "crdb.prod", cfg.DatabaseConfig.Host)
+	assert.Equal(t, "root", cfg.DatabaseConfig.User)
+	assert.Equal(t, 50, cfg.DatabaseConfig.MaxConnections)
+	assert.Equal(t, 2, cfg.DatabaseConfig.MinConnections)
+	assert.Equal(t, []string{"USDT"}, cfg.Payments.SupportedTokens)
+}
+
+func TestConfig_LoadConfigWithOverlays_ValidatesMergedResult(t *testing.T) {
+	dir := t.TempDir()
+	base := writeConfigFile(t, dir, "config.yaml", overlayBaseYAML)
+	bad := writeConfigFile(t, dir, "config.bad.yaml", "appPort: 0\n")
+
+	var cfg Config
+	err := cfg.LoadConfigWithOverlays(base, bad)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "appPort must be between 1 and 65535")
+}
+
+func TestConfig_LoadConfigWithOverlays_MissingOverlay(t *testing.T) {
+	dir := t.TempDir()
+	base := writeConfigFile(t, dir, "config.yaml", overlayBaseYAML)
+
+	var cfg Config
+	err := cfg.LoadConfigWithOverlays(base, filepath.Join(dir, "config.nope.yaml"))
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read config")
+}
+
+func TestConfig_LoadConfigWithOverlays_ExpandsEnv(t *testing.T) {
+	t.Setenv("TPG_TEST_OVERLAY_HOST", "crdb.staging")
+	dir := t.TempDir()
+	base := writeConfigFile(t, dir, "config.yaml", overlayBaseYAML)
+	staging := writeConfigFile(t, dir, "config.staging.yaml", "database:\n  host: ${TPG_TEST_OVERLAY_HOST}\n")
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfigWithOverlays(base, staging))
+
+	assert.Equal(t, "crdb.staging", cfg.DatabaseConfig.Host)
+}
+
+func TestConfig_LoadConfigForEnv(t *testing.T) {
+	dir := t.TempDir()
+	base := writeConfigFile(t, dir, "config.yaml", overlayBaseYAML)
+	writeConfigFile(t, dir, "config.staging.yaml", "database:\n  host: crdb.staging\n")
+
+	t.Run("no profile", func(t *testing.T) {
+		t.Setenv(EnvProfileVar, "")
+		var cfg Config
+		require.NoError(t, cfg.LoadConfigForEnv(base))
+		assert.Equal(t, "localhost", cfg.DatabaseConfig.Host)
+	})
+
+	t.Run("staging profile", func(t *testing.T) {
+		t.Setenv(EnvProfileVar, "staging")
+		var cfg Config
+		require.NoError(t, cfg.LoadConfigForEnv(base))
+		assert.Equal(t, "crdb.staging", cfg.DatabaseConfig.Host)
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		t.Setenv(EnvProfileVar, "qa")
+		var cfg Config
+		err := cfg.LoadConfigForEnv(base)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "config.qa.yaml")
+	})
+}
+
+func TestOverlayPath(t *testing.T) {
+	assert.Equal(t, "/etc/tpg/config.staging.yaml", OverlayPath("/etc/tpg/config.yaml", "staging"))
+	assert.Equal(t, "config.prod", OverlayPath("config", "prod"))
+}
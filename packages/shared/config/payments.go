@@ -0,0 +1,223 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Tokens the gateway knows how to watch for.
+const (
+	TokenTRX  = "TRX"
+	TokenUSDT = "USDT"
+)
+
+// KnownTokens lists every value accepted in PaymentsConfig.SupportedTokens.
+var KnownTokens = []string{TokenTRX, TokenUSDT}
+
+// tokenDecimals is the precision of each known token's amounts. TRX is
+// counted in sun and USDT in its TRC-20 contract's base units; both have 6
+// decimals, but they are distinct units and are looked up per token.
+var tokenDecimals = map[string]int32{
+	TokenTRX:  6,
+	TokenUSDT: 6,
+}
+
+// TokenDecimals returns how many decimal places amounts of token have,
+// ignoring case, or false for a token the gateway does not know.
+func TokenDecimals(token string) (int32, bool) {
+	d, ok := tokenDecimals[strings.ToUpper(token)]
+	return d, ok
+}
+
+// Defaults applied to an unset payments section.
+const (
+	DefaultPaymentExpiry       = Duration(30 * time.Minute)
+	DefaultMaxActivePerAccount = 100
+	DefaultStatusTokenTTL      = Duration(24 * time.Hour)
+	DefaultMaxWalletAttempts   = 3
+)
+
+// DefaultStatusTokenSecretEnv is read when PaymentsConfig.StatusTokenSecretEnv
+// is empty.
+const DefaultStatusTokenSecretEnv = "PAYMENT_STATUS_TOKEN_SECRET"
+
+// MaxUnderpaymentTolerancePercent caps how short a transfer may fall of the
+// requested amount and still settle the payment.
+const MaxUnderpaymentTolerancePercent = 5.0
+
+// PaymentsConfig holds the defaults CreatePayment falls back on when a request
+// does not override them.
+type PaymentsConfig struct {
+	DefaultExpiry Duration `yaml:"defaultExpiry" json:"defaultExpiry"`
+	// MaxActivePerAccount bounds the PENDING and DETECTED payments of an
+	// account; creating one more is refused until one settles.
+	MaxActivePerAccount int `yaml:"maxActivePerAccount" json:"maxActivePerAccount"`
+	// UnderpaymentTolerancePercent is between 0 and 5; see
+	// UnderpaymentToleranceBps for the value used in amount comparisons.
+	UnderpaymentTolerancePercent float64  `yaml:"underpaymentTolerancePercent" json:"underpaymentTolerancePercent"`
+	SupportedTokens              []string `yaml:"supportedTokens" json:"supportedTokens"`
+	// MinAmount and MaxAmount are decimal strings in token units, e.g.
+	// "1.5". Empty means unbounded.
+	MinAmount string `yaml:"minAmount" json:"minAmount"`
+	MaxAmount string `yaml:"maxAmount" json:"maxAmount"`
+	// StatusTokenTTL is how long after its payment expires a public status
+	// token keeps working, so a checkout page can still show the outcome.
+	StatusTokenTTL Duration `yaml:"statusTokenTTL" json:"statusTokenTTL"`
+	// StatusTokenSecretEnv names the environment variable holding the key
+	// public status tokens are signed with, PAYMENT_STATUS_TOKEN_SECRET when
+	// unset. The key is never read from the file.
+	StatusTokenSecretEnv string `yaml:"statusTokenSecretEnv" json:"statusTokenSecretEnv"`
+	// MaxWalletAttempts bounds how many deposit wallets a payment may be
+	// given, counting the first, when a merchant regenerates its wallet.
+	MaxWalletAttempts int `yaml:"maxWalletAttempts" json:"maxWalletAttempts"`
+	// AddressPool pre-generates deposit addresses per account.
+	AddressPool AddressPoolConfig `yaml:"addressPool" json:"addressPool"`
+	// AmountSuffix tunes the payments of accounts that share one deposit
+	// wallet.
+	AmountSuffix AmountSuffixConfig `yaml:"amountSuffix" json:"amountSuffix"`
+	// DerivationCheck re-derives recent wallets as the API starts.
+	DerivationCheck DerivationCheckConfig `yaml:"derivationCheck" json:"derivationCheck"`
+
+	// statusTokenSecret is populated from StatusTokenSecretEnv by Hydrate.
+	statusTokenSecret string
+}
+
+// StatusTokenSecretEnvName returns the environment variable the status token
+// key is read from.
+func (p PaymentsConfig) StatusTokenSecretEnvName() string {
+	if p.StatusTokenSecretEnv != "" {
+		return p.StatusTokenSecretEnv
+	}
+	return DefaultStatusTokenSecretEnv
+}
+
+// StatusTokenSecret returns the key public status tokens are signed with.
+func (c *Config) StatusTokenSecret() (string, error) {
+	if c.Payments.statusTokenSecret == "" {
+		return "", fmt.Errorf("status token secret is empty: set %s", c.Payments.StatusTokenSecretEnvName())
+	}
+	return c.Payments.statusTokenSecret, nil
+}
+
+// UnderpaymentToleranceBps returns the tolerance in basis points, so amount
+// checks can stay in integer arithmetic.
+func (p PaymentsConfig) UnderpaymentToleranceBps() int64 {
+	return int64(math.Round(p.UnderpaymentTolerancePercent * 100))
+}
+
+// SupportsToken reports whether token is enabled, ignoring case.
+func (p PaymentsConfig) SupportsToken(token string) bool {
+	return slices.ContainsFunc(p.SupportedTokens, func(t string) bool {
+		return strings.EqualFold(t, token)
+	})
+}
+
+// DefaultToken returns the token of a payment created without one: the
+// first of SupportedTokens, or USDT when none are set.
+func (p PaymentsConfig) DefaultToken() string {
+	if len(p.SupportedTokens) == 0 {
+		return TokenUSDT
+	}
+	return strings.ToUpper(p.SupportedTokens[0])
+}
+
+// AmountLimits returns the parsed MinAmount and MaxAmount; a nil bound means
+// unbounded.
+func (p PaymentsConfig) AmountLimits() (minAmount, maxAmount *decimal.Decimal, err error) {
+	if minAmount, err = parseAmount(p.MinAmount); err != nil {
+		return nil, nil, fmt.Errorf("payments.minAmount %w", err)
+	}
+	if maxAmount, err = parseAmount(p.MaxAmount); err != nil {
+		return nil, nil, fmt.Errorf("payments.maxAmount %w", err)
+	}
+	return minAmount, maxAmount, nil
+}
+
+func (p *PaymentsConfig) hydrate() {
+	if v, ok := os.LookupEnv(p.StatusTokenSecretEnvName()); ok {
+		p.statusTokenSecret = v
+	}
+}
+
+func (p *PaymentsConfig) applyDefaults() {
+	if p.DefaultExpiry == 0 {
+		p.DefaultExpiry = DefaultPaymentExpiry
+	}
+	if p.MaxActivePerAccount == 0 {
+		p.MaxActivePerAccount = DefaultMaxActivePerAccount
+	}
+	if len(p.SupportedTokens) == 0 {
+		p.SupportedTokens = []string{TokenUSDT}
+	}
+	if p.StatusTokenTTL == 0 {
+		p.StatusTokenTTL = DefaultStatusTokenTTL
+	}
+	if p.MaxWalletAttempts == 0 {
+		p.MaxWalletAttempts = DefaultMaxWalletAttempts
+	}
+	p.AddressPool.applyDefaults()
+	p.AmountSuffix.applyDefaults()
+	p.DerivationCheck.applyDefaults()
+}
+
+func (p PaymentsConfig) validate() []error {
+	var errs []error
+
+	if p.DefaultExpiry <= 0 {
+		errs = append(errs, fmt.Errorf("payments.defaultExpiry must be positive, got %s", p.DefaultExpiry.Std()))
+	}
+	if p.StatusTokenTTL <= 0 {
+		errs = append(errs, fmt.Errorf("payments.statusTokenTTL must be positive, got %s", p.StatusTokenTTL.Std()))
+	}
+	if p.MaxActivePerAccount < 1 {
+		errs = append(errs, fmt.Errorf("payments.maxActivePerAccount must be at least 1, got %d", p.MaxActivePerAccount))
+	}
+	if p.MaxWalletAttempts < 1 {
+		errs = append(errs, fmt.Errorf("payments.maxWalletAttempts must be at least 1, got %d", p.MaxWalletAttempts))
+	}
+	if p.UnderpaymentTolerancePercent < 0 || p.UnderpaymentTolerancePercent > MaxUnderpaymentTolerancePercent {
+		errs = append(errs, fmt.Errorf("payments.underpaymentTolerancePercent must be between 0 and %g, got %g",
+			MaxUnderpaymentTolerancePercent, p.UnderpaymentTolerancePercent))
+	}
+	if len(p.SupportedTokens) == 0 {
+		errs = append(errs, fmt.Errorf("payments.supportedTokens must list at least one token"))
+	}
+	for _, token := range p.SupportedTokens {
+		if !slices.Contains(KnownTokens, strings.ToUpper(token)) {
+			errs = append(errs, fmt.Errorf("payments.supportedTokens: unsupported token %q, must be one of %s",
+				token, strings.Join(KnownTokens, ", ")))
+		}
+	}
+
+	minAmount, maxAmount, err := p.AmountLimits()
+	switch {
+	case err != nil:
+		errs = append(errs, err)
+	case minAmount != nil && maxAmount != nil && minAmount.GreaterThan(*maxAmount):
+		errs = append(errs, fmt.Errorf("payments.minAmount (%s) must not exceed maxAmount (%s)", minAmount, maxAmount))
+	}
+
+	errs = append(errs, p.AddressPool.validate()...)
+	errs = append(errs, p.AmountSuffix.validate()...)
+	return append(errs, p.DerivationCheck.validate()...)
+}
+
+func parseAmount(value string) (*decimal.Decimal, error) {
+	if value == "" {
+		return nil, nil
+	}
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return nil, fmt.Errorf("must be a decimal such as \"10.5\", got %q", value)
+	}
+	if !d.IsPositive() {
+		return nil, fmt.Errorf("must be positive, got %q", value)
+	}
+	return &d, nil
+}
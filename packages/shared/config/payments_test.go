@@ -0,0 +1,165 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_LoadConfig_PaymentsSection(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yaml := `
+appPort: 8080
+database:
+  user: root
+  host: localhost
+  database: tpg
+  maxConnections: 10
+payments:
+  defaultExpiry: 45m
+  maxActivePerAccount: 20
+  underpaymentTolerancePercent: 0.5
+  supportedTokens: [USDT, trx]
+  minAmount: "1.5"
+  maxAmount: "10000"
+  maxWalletAttempts: 5
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath))
+
+	p := cfg.Payments
+	assert.Equal(t, 45*time.Minute, p.DefaultExpiry.Std())
+	assert.Equal(t, 20, p.MaxActivePerAccount)
+	assert.Equal(t, 5, p.MaxWalletAttempts)
+	assert.Equal(t, int64(50), p.UnderpaymentToleranceBps())
+	assert.True(t, p.SupportsToken("TRX"))
+	assert.True(t, p.SupportsToken("usdt"))
+	assert.Equal(t, TokenUSDT, p.DefaultToken())
+
+	minAmount, maxAmount, err := p.AmountLimits()
+	require.NoError(t, err)
+	assert.Equal(t, "1.5", minAmount.String())
+	assert.Equal(t, "10000", maxAmount.String())
+}
+
+func TestConfig_LoadConfig_PaymentsDefaults(t *testing.T) {
+	cfg := validConfig()
+
+	assert.Equal(t, DefaultPaymentExpiry, cfg.Payments.DefaultExpiry)
+	assert.Equal(t, DefaultMaxActivePerAccount, cfg.Payments.MaxActivePerAccount)
+	assert.Equal(t, []string{TokenUSDT}, cfg.Payments.SupportedTokens)
+	assert.Equal(t, TokenUSDT, cfg.Payments.DefaultToken())
+	assert.Equal(t, int64(0), cfg.Payments.UnderpaymentToleranceBps())
+	assert.Equal(t, DefaultStatusTokenTTL, cfg.Payments.StatusTokenTTL)
+	assert.Equal(t, DefaultMaxWalletAttempts, cfg.Payments.MaxWalletAttempts)
+	assert.Equal(t, DefaultStatusTokenSecretEnv, cfg.Payments.StatusTokenSecretEnvName())
+
+	minAmount, maxAmount, err := cfg.Payments.AmountLimits()
+	require.NoError(t, err)
+	assert.Nil(t, minAmount)
+	assert.Nil(t, maxAmount)
+}
+
+func TestConfig_LoadConfig_PaymentsBadExpiry(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	require.NoError(t, os.WriteFile(configPath, []byte("payments:\n  defaultExpiry: soon\n"), 0644))
+
+	var cfg Config
+	err := cfg.LoadConfig(configPath, SkipValidation())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse config")
+	assert.Contains(t, err.Error(), `invalid duration "soon"`)
+}
+
+func TestPaymentsConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mutate  func(*PaymentsConfig)
+		wantErr string
+	}{
+		{"valid", func(*PaymentsConfig) {}, ""},
+		{"zero expiry", func(p *PaymentsConfig) { p.DefaultExpiry = 0 }, "payments.defaultExpiry must be positive"},
+		{"negative expiry", func(p *PaymentsConfig) { p.DefaultExpiry = Duration(-time.Minute) }, "payments.defaultExpiry must be positive"},
+		{"zero status token ttl", func(p *PaymentsConfig) { p.StatusTokenTTL = 0 }, "payments.statusTokenTTL must be positive"},
+		{"negative max active", func(p *PaymentsConfig) { p.MaxActivePerAccount = -1 }, "payments.maxActivePerAccount must be at least 1"},
+		{"negative max wallet attempts", func(p *PaymentsConfig) { p.MaxWalletAttempts = -1 }, "payments.maxWalletAttempts must be at least 1"},
+		{"tolerance above 5%", func(p *PaymentsConfig) { p.UnderpaymentTolerancePercent = 5.01 }, "payments.underpaymentTolerancePercent must be between 0 and 5"},
+		{"negative tolerance", func(p *PaymentsConfig) { p.UnderpaymentTolerancePercent = -1 }, "payments.underpaymentTolerancePercent must be between 0 and 5"},
+		{"tolerance at 5%", func(p *PaymentsConfig) { p.UnderpaymentTolerancePercent = 5 }, ""},
+		{"unsupported token", func(p *PaymentsConfig) { p.SupportedTokens = []string{"USDT", "DOGE"} }, `unsupported token "DOGE"`},
+		{"bad min amount", func(p *PaymentsConfig) { p.MinAmount = "one" }, "payments.minAmount must be a decimal"},
+		{"zero max amount", func(p *PaymentsConfig) { p.MaxAmount = "0" }, "payments.maxAmount must be positive"},
+		{"min above max", func(p *PaymentsConfig) { p.MinAmount, p.MaxAmount = "100", "10" }, "payments.minAmount (100) must not exceed maxAmount (10)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.mutate(&cfg.Payments)
+
+			err := cfg.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
+
+func TestPaymentsConfig_UnderpaymentToleranceBps(t *testing.T) {
+	assert.Equal(t, int64(0), PaymentsConfig{}.UnderpaymentToleranceBps())
+	assert.Equal(t, int64(25), PaymentsConfig{UnderpaymentTolerancePercent: 0.25}.UnderpaymentToleranceBps())
+	assert.Equal(t, int64(500), PaymentsConfig{UnderpaymentTolerancePercent: 5}.UnderpaymentToleranceBps())
+	// Float noise must not cost a basis point.
+	assert.Equal(t, int64(29), PaymentsConfig{UnderpaymentTolerancePercent: 0.29}.UnderpaymentToleranceBps())
+}
+
+func TestPaymentsConfig_DefaultToken(t *testing.T) {
+	assert.Equal(t, TokenTRX, PaymentsConfig{SupportedTokens: []string{"trx", "USDT"}}.DefaultToken())
+	assert.Equal(t, TokenUSDT, PaymentsConfig{}.DefaultToken())
+}
+
+func TestTokenDecimals(t *testing.T) {
+	for _, token := range KnownTokens {
+		d, ok := TokenDecimals(token)
+		assert.True(t, ok, token)
+		assert.Equal(t, int32(6), d, token)
+	}
+	d, ok := TokenDecimals("usdt")
+	assert.True(t, ok)
+	assert.Equal(t, int32(6), d)
+	_, ok = TokenDecimals("DOGE")
+	assert.False(t, ok)
+}
+
+func TestConfig_StatusTokenSecret(t *testing.T) {
+	cfg := validConfig()
+	_, err := cfg.StatusTokenSecret()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), DefaultStatusTokenSecretEnv)
+
+	t.Setenv("CHECKOUT_SECRET", "s3cret")
+	cfg.Payments.StatusTokenSecretEnv = "CHECKOUT_SECRET"
+	cfg.Hydrate()
+
+	secret, err := cfg.StatusTokenSecret()
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", secret)
+
+	redacted := cfg.Redacted()
+	_, err = redacted.StatusTokenSecret()
+	assert.Error(t, err, "redacting drops the secret")
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+)
+
+// DefaultCoinGeckoURL is the public CoinGecko API.
+const DefaultCoinGeckoURL = "https://api.coingecko.com/api/v3"
+
+// DefaultRatesAPIKeyEnv is read when RatesConfig.APIKeyEnv is empty.
+const DefaultRatesAPIKeyEnv = "COINGECKO_API_KEY"
+
+// Defaults applied to an unset rates section.
+const (
+	DefaultRateCacheTTL       = Duration(time.Minute)
+	DefaultRateMaxAge         = Duration(10 * time.Minute)
+	DefaultRateRequestTimeout = Duration(10 * time.Second)
+)
+
+// RatesConfig configures the exchange rates fiat-denominated invoices are
+// converted with.
+type RatesConfig struct {
+	// BaseURL is the CoinGecko API root, DefaultCoinGeckoURL when unset.
+	BaseURL string `yaml:"baseURL" json:"baseURL"`
+	// APIKey is a fallback for local setups; prefer APIKeyEnv. The public
+	// API works without a key at a lower rate limit.
+	APIKey string `yaml:"apiKey" json:"apiKey" secret:"true"`
+	// APIKeyEnv names the environment variable holding the CoinGecko key,
+	// COINGECKO_API_KEY when unset.
+	APIKeyEnv string `yaml:"apiKeyEnv" json:"apiKeyEnv"`
+	// CacheTTL is how long a fetched rate is used before it is fetched again.
+	CacheTTL Duration `yaml:"cacheTTL" json:"cacheTTL"`
+	// MaxAge is the oldest rate an invoice may be priced at. While the
+	// provider is failing, cached rates are used up to this age; past it
+	// invoice creation fails.
+	MaxAge         Duration `yaml:"maxAge" json:"maxAge"`
+	RequestTimeout Duration `yaml:"requestTimeout" json:"requestTimeout"`
+
+	// apiKey is populated from APIKeyEnv by Hydrate.
+	apiKey string
+}
+
+// APIKeyEnvName returns the environment variable the API key is read from.
+func (r RatesConfig) APIKeyEnvName() string {
+	if r.APIKeyEnv != "" {
+		return r.APIKeyEnv
+	}
+	return DefaultRatesAPIKeyEnv
+}
+
+// RatesAPIKey returns the CoinGecko key from the environment, falling back to
+// the apiKey key in the file.
+func (c *Config) RatesAPIKey() string {
+	if c.Rates.apiKey != "" {
+		return c.Rates.apiKey
+	}
+	return c.Rates.APIKey
+}
+
+func (r *RatesConfig) hydrate() {
+	if v, ok := os.LookupEnv(r.APIKeyEnvName()); ok {
+		r.apiKey = v
+	}
+}
+
+func (r *RatesConfig) applyDefaults() {
+	if r.BaseURL == "" {
+		r.BaseURL = DefaultCoinGeckoURL
+	}
+	if r.CacheTTL == 0 {
+		r.CacheTTL = DefaultRateCacheTTL
+	}
+	if r.MaxAge == 0 {
+		r.MaxAge = DefaultRateMaxAge
+	}
+	if r.RequestTimeout == 0 {
+		r.RequestTimeout = DefaultRateRequestTimeout
+	}
+}
+
+func (r RatesConfig) validate() []error {
+	var errs []error
+
+	if u, err := url.Parse(r.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("rates.baseURL must be an http(s) URL, got %q", r.BaseURL))
+	}
+	if r.CacheTTL <= 0 {
+		errs = append(errs, fmt.Errorf("rates.cacheTTL must be positive, got %s", r.CacheTTL.Std()))
+	}
+	if r.MaxAge < r.CacheTTL {
+		errs = append(errs, fmt.Errorf("rates.maxAge (%s) must not be shorter than cacheTTL (%s)", r.MaxAge.Std(), r.CacheTTL.Std()))
+	}
+	if r.RequestTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("rates.requestTimeout must be positive, got %s", r.RequestTimeout.Std()))
+	}
+
+	return errs
+}
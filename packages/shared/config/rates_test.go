@@ -0,0 +1,81 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_LoadConfig_RatesSection(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yaml := `
+appPort: 8080
+database:
+  user: root
+  host: localhost
+  database: tpg
+  maxConnections: 10
+rates:
+  baseURL: https://pro-api.coingecko.com/api/v3
+  apiKeyEnv: TEST_RATES_KEY
+  cacheTTL: 30s
+  maxAge: 5m
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+	t.Setenv("TEST_RATES_KEY", "env-rates-key")
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath))
+
+	assert.Equal(t, "https://pro-api.coingecko.com/api/v3", cfg.Rates.BaseURL)
+	assert.Equal(t, 30*time.Second, cfg.Rates.CacheTTL.Std())
+	assert.Equal(t, 5*time.Minute, cfg.Rates.MaxAge.Std())
+	assert.Equal(t, DefaultRateRequestTimeout, cfg.Rates.RequestTimeout)
+	assert.Equal(t, "env-rates-key", cfg.RatesAPIKey())
+	redacted := cfg.Redacted()
+	assert.Empty(t, redacted.RatesAPIKey(), "keys from the environment are dropped")
+}
+
+func TestConfig_RatesDefaults(t *testing.T) {
+	cfg := validConfig()
+
+	assert.Equal(t, DefaultCoinGeckoURL, cfg.Rates.BaseURL)
+	assert.Equal(t, DefaultRateCacheTTL, cfg.Rates.CacheTTL)
+	assert.Equal(t, DefaultRateMaxAge, cfg.Rates.MaxAge)
+	assert.Equal(t, DefaultRatesAPIKeyEnv, cfg.Rates.APIKeyEnvName())
+}
+
+func TestRatesConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mutate  func(*RatesConfig)
+		wantErr string
+	}{
+		{"valid", func(*RatesConfig) {}, ""},
+		{"bad url", func(r *RatesConfig) { r.BaseURL = "coingecko" }, "rates.baseURL must be an http(s) URL"},
+		{"negative ttl", func(r *RatesConfig) { r.CacheTTL = Duration(-time.Second) }, "rates.cacheTTL must be positive"},
+		{"max age below ttl", func(r *RatesConfig) { r.MaxAge = Duration(time.Second) }, "rates.maxAge (1s) must not be shorter than cacheTTL (1m0s)"},
+		{"negative timeout", func(r *RatesConfig) { r.RequestTimeout = Duration(-time.Second) }, "rates.requestTimeout must be positive"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.mutate(&cfg.Rates)
+
+			err := cfg.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
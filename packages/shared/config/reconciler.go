@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Defaults applied to an unset reconciler section.
+const (
+	DefaultReconcileInterval = Duration(time.Hour)
+	DefaultReconcileWindow   = Duration(24 * time.Hour)
+	DefaultReconcileLeaseTTL = Duration(30 * time.Second)
+	// DefaultReconcileTolerance is the difference, in token units, below
+	// which two amounts of a token missing from ReconcilerConfig.Tolerance
+	// are equal.
+	DefaultReconcileTolerance = "0"
+	// DefaultReconcileTRXTolerance covers the bandwidth a TRX sweep burns out
+	// of the swept balance.
+	DefaultReconcileTRXTolerance = "1"
+)
+
+// ReconcilerConfig tunes the job that checks confirmed payments against the
+// chain: their deposits, the balances of their deposit wallets and their
+// sweeps to the cold wallet.
+type ReconcilerConfig struct {
+	// Enabled turns the worker on. tpg reconcile runs either way.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Interval is the pause between runs of the worker.
+	Interval Duration `yaml:"interval" json:"interval"`
+	// Window is how far back a run looks for confirmed payments.
+	Window Duration `yaml:"window" json:"window"`
+	// Tolerance maps a token to the difference, as a decimal string in token
+	// units, up to which amounts still match.
+	Tolerance map[string]string `yaml:"tolerance" json:"tolerance"`
+	// LeaseTTL bounds how long a leader's lease lasts without renewal; only
+	// the replica holding the lease reconciles.
+	LeaseTTL Duration `yaml:"leaseTTL" json:"leaseTTL"`
+}
+
+// ReconcileTolerance returns the difference up to which two amounts of token
+// match.
+func (r ReconcilerConfig) ReconcileTolerance(token string) decimal.Decimal {
+	for t, v := range r.Tolerance {
+		if strings.EqualFold(t, token) {
+			if d, err := decimal.NewFromString(v); err == nil {
+				return d
+			}
+		}
+	}
+	if strings.EqualFold(token, TokenTRX) {
+		return decimal.RequireFromString(DefaultReconcileTRXTolerance)
+	}
+	return decimal.RequireFromString(DefaultReconcileTolerance)
+}
+
+func (r *ReconcilerConfig) applyDefaults() {
+	if r.Interval == 0 {
+		r.Interval = DefaultReconcileInterval
+	}
+	if r.Window == 0 {
+		r.Window = DefaultReconcileWindow
+	}
+	if r.LeaseTTL == 0 {
+		r.LeaseTTL = DefaultReconcileLeaseTTL
+	}
+}
+
+func (r ReconcilerConfig) validate() []error {
+	var errs []error
+
+	if r.Interval <= 0 {
+		errs = append(errs, fmt.Errorf("reconciler.interval must be positive, got %s", r.Interval.Std()))
+	}
+	if r.Window <= 0 {
+		errs = append(errs, fmt.Errorf("reconciler.window must be positive, got %s", r.Window.Std()))
+	}
+	for token, v := range r.Tolerance {
+		if !slices.Contains(KnownTokens, strings.ToUpper(token)) {
+			errs = append(errs, fmt.Errorf("reconciler.tolerance: unsupported token %q, must be one of %s",
+				token, strings.Join(KnownTokens, ", ")))
+			continue
+		}
+		if d, err := decimal.NewFromString(v); err != nil || d.IsNegative() {
+			errs = append(errs, fmt.Errorf("reconciler.tolerance.%s must be a non-negative decimal, got %q", token, v))
+		}
+	}
+	if r.LeaseTTL < Duration(time.Second) {
+		errs = append(errs, fmt.Errorf("reconciler.leaseTTL must be at least 1s, got %s", r.LeaseTTL.Std()))
+	}
+	return errs
+}
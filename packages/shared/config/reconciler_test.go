@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_LoadConfig_ReconcilerSection(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := `
+appPort: 8080
+database:
+  user: root
+  host: localhost
+  database: tpg
+  maxConnections: 10
+reconciler:
+  enabled: true
+  interval: 30m
+  window: 72h
+  tolerance:
+    trx: "0.5"
+  leaseTTL: 1m
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath))
+
+	r := cfg.Reconciler
+	assert.True(t, r.Enabled)
+	assert.Equal(t, 30*time.Minute, r.Interval.Std())
+	assert.Equal(t, 72*time.Hour, r.Window.Std())
+	assert.True(t, decimal.RequireFromString("0.5").Equal(r.ReconcileTolerance(TokenTRX)))
+	assert.True(t, r.ReconcileTolerance(TokenUSDT).IsZero())
+	assert.Equal(t, time.Minute, r.LeaseTTL.Std())
+}
+
+func TestConfig_ReconcilerDefaults(t *testing.T) {
+	cfg := validConfig()
+
+	r := cfg.Reconciler
+	assert.False(t, r.Enabled)
+	assert.Equal(t, DefaultReconcileInterval, r.Interval)
+	assert.Equal(t, DefaultReconcileWindow, r.Window)
+	assert.Equal(t, DefaultReconcileLeaseTTL, r.LeaseTTL)
+	assert.True(t, decimal.RequireFromString(DefaultReconcileTRXTolerance).Equal(r.ReconcileTolerance("trx")))
+	assert.True(t, decimal.RequireFromString(DefaultReconcileTolerance).Equal(r.ReconcileTolerance(TokenUSDT)))
+}
+
+func TestReconcilerConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mutate  func(*ReconcilerConfig)
+		wantErr string
+	}{
+		{"valid", func(*ReconcilerConfig) {}, ""},
+		{"zero tolerance", func(r *ReconcilerConfig) { r.Tolerance = map[string]string{"USDT": "0"} }, ""},
+		{"negative interval", func(r *ReconcilerConfig) { r.Interval = Duration(-time.Second) }, "reconciler.interval must be positive"},
+		{"negative window", func(r *ReconcilerConfig) { r.Window = Duration(-time.Hour) }, "reconciler.window must be positive"},
+		{"unknown token", func(r *ReconcilerConfig) { r.Tolerance = map[string]string{"BTC": "1"} }, `unsupported token "BTC"`},
+		{"negative tolerance", func(r *ReconcilerConfig) { r.Tolerance = map[string]string{"TRX": "-1"} }, "reconciler.tolerance.TRX must be a non-negative decimal"},
+		{"empty tolerance", func(r *ReconcilerConfig) { r.Tolerance = map[string]string{"USDT": ""} }, "reconciler.tolerance.USDT must be a non-negative decimal"},
+		{"short lease", func(r *ReconcilerConfig) { r.LeaseTTL = Duration(500 * time.Millisecond) }, "reconciler.leaseTTL must be at least 1s, got 500ms"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.mutate(&cfg.Reconciler)
+
+			err := cfg.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// RedactedValue replaces secret fields in Redacted output.
+const RedactedValue = "••••"
+
+// Redacted returns a deep copy of c that is safe to log: every non-empty
+// string field tagged secret:"true" is replaced with RedactedValue, and
+// secrets read from the environment are dropped. New secret fields must carry
+// the tag.
+func (c *Config) Redacted() Config {
+	cp := *c
+	cp.DatabaseConfig.password = ""
+	cp.Tron.apiKey = ""
+	cp.Sweeper.mnemonic = ""
+	cp.TestMode.Tron.apiKey = ""
+	cp.TestMode.mnemonic = ""
+	cp.Payments.statusTokenSecret = ""
+	cp.Rates.apiKey = ""
+	cp.Admin.token = ""
+	cp.GRPC.secret = ""
+	cp.Redis.password = ""
+	cp.Kafka.SASL.password = ""
+	cp.Payments.SupportedTokens = slices.Clone(c.Payments.SupportedTokens)
+	cp.Sweeper.MinAmount = maps.Clone(c.Sweeper.MinAmount)
+	cp.Tron.Endpoints = slices.Clone(c.Tron.Endpoints)
+	cp.TestMode.Tron.Endpoints = slices.Clone(c.TestMode.Tron.Endpoints)
+	cp.Kafka.Brokers = slices.Clone(c.Kafka.Brokers)
+	for i := range cp.Tron.Endpoints {
+		cp.Tron.Endpoints[i].apiKey = ""
+	}
+	for i := range cp.TestMode.Tron.Endpoints {
+		cp.TestMode.Tron.Endpoints[i].apiKey = ""
+	}
+
+	redactValue(reflect.ValueOf(&cp).Elem())
+	return cp
+}
+
+// DumpJSON writes the redacted config as indented JSON, for startup logging.
+func (c *Config) DumpJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(c.Redacted()); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	return nil
+}
+
+func redactValue(v reflect.Value) {
+	if v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			redactValue(v.Index(i))
+		}
+		return
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field, fv := t.Field(i), v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Tag.Get("secret") == "true" && fv.Kind() == reflect.String {
+			if fv.String() != "" {
+				fv.SetString(RedactedValue)
+			}
+			continue
+		}
+		redactValue(fv)
+	}
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func secretConfig(t *testing.T) Config {
+	t.Setenv(DefaultPasswordEnv, "env-db-pass")
+	t.Setenv(DefaultTronAPIKeyEnv, "env-tron-key")
+
+	cfg := validConfig()
+	cfg.DatabaseConfig.Password = "file-db-pass"
+	cfg.Tron.APIKey = "file-tron-key"
+	cfg.Hydrate()
+	return cfg
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	cfg := secretConfig(t)
+
+	redacted := cfg.Redacted()
+
+	assert.Equal(t, RedactedValue, redacted.DatabaseConfig.Password)
+	assert.Equal(t, RedactedValue, redacted.Tron.APIKey)
+	assert.Empty(t, redacted.DatabaseConfig.password)
+	assert.Empty(t, redacted.Tron.apiKey)
+	assert.Equal(t, cfg.DatabaseConfig.Host, redacted.DatabaseConfig.Host)
+
+	// The original is untouched.
+	assert.Equal(t, "file-db-pass", cfg.DatabaseConfig.Password)
+	password, err := cfg.DatabasePassword()
+	require.NoError(t, err)
+	assert.Equal(t, "env-db-pass", password)
+
+	// Slices are copied, not shared.
+	redacted.Payments.SupportedTokens[0] = "changed"
+	assert.Equal(t, TokenUSDT, cfg.Payments.SupportedTokens[0])
+}
+
+func TestConfig_Redacted_EndpointKeys(t *testing.T) {
+	t.Setenv("TPG_NODE_KEY", "env-node-key")
+	cfg := validConfig()
+	cfg.Tron.Endpoints = []TronEndpoint{
+		{URL: "https://a.example.com", APIKey: "file-node-key"},
+		{URL: "https://b.example.com", APIKeyEnv: "TPG_NODE_KEY"},
+	}
+	cfg.Hydrate()
+
+	redacted := cfg.Redacted()
+
+	assert.Equal(t, RedactedValue, redacted.Tron.Endpoints[0].APIKey)
+	assert.Empty(t, redacted.Tron.Endpoints[1].Key())
+	assert.Equal(t, "file-node-key", cfg.Tron.Endpoints[0].APIKey, "redacting must not touch the original")
+	assert.Equal(t, "env-node-key", cfg.Tron.Endpoints[1].Key())
+}
+
+func TestConfig_Redacted_EmptySecretsStayEmpty(t *testing.T) {
+	cfg := validConfig()
+
+	redacted := cfg.Redacted()
+
+	assert.Empty(t, redacted.DatabaseConfig.Password)
+	assert.Empty(t, redacted.Tron.APIKey)
+}
+
+func TestConfig_DumpJSON(t *testing.T) {
+	cfg := secretConfig(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, cfg.DumpJSON(&buf))
+
+	out := buf.String()
+	for _, secret := range []string{"file-db-pass", "env-db-pass", "file-tron-key", "env-tron-key"} {
+		assert.NotContains(t, out, secret)
+	}
+
+	var dumped map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &dumped))
+	assert.Equal(t, RedactedValue, dumped["database"].(map[string]any)["password"])
+	assert.Equal(t, "localhost", dumped["database"].(map[string]any)["host"])
+	assert.Equal(t, "30m", dumped["payments"].(map[string]any)["defaultExpiry"])
+}
+
+// TestConfig_SecretFieldsTagged fails when a field that looks like a
+// credential is added without secret:"true". Fields ending in Env only name
+// the variable that holds the secret and are exempt.
+func TestConfig_SecretFieldsTagged(t *testing.T) {
+	var check func(path string, typ reflect.Type)
+	check = func(path string, typ reflect.Type) {
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := path + field.Name
+			if field.Type.Kind() == reflect.Struct {
+				check(name+".", field.Type)
+				continue
+			}
+
+			lower := strings.ToLower(field.Name)
+			looksSecret := strings.Contains(lower, "password") ||
+				strings.Contains(lower, "secret") ||
+				strings.Contains(lower, "apikey")
+			if looksSecret && !strings.HasSuffix(field.Name, "Env") {
+				assert.Equal(t, "true", field.Tag.Get("secret"), "%s must be tagged secret:\"true\"", name)
+			}
+		}
+	}
+
+	check("Config.", reflect.TypeOf(Config{}))
+}
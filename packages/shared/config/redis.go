@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultRedisPasswordEnv is read when RedisConfig.PasswordEnv is empty.
+const DefaultRedisPasswordEnv = "REDIS_PASSWORD"
+
+// DefaultRedisClientCacheTTL is applied to an unset clientCacheTTL. It bounds
+// how long a replica that missed an invalidation keeps accepting a rotated
+// or deactivated key.
+const DefaultRedisClientCacheTTL = Duration(time.Minute)
+
+// RedisConfig configures the optional Redis cache in front of client API key
+// lookups. With Addr unset the API reads clients straight from the
+// database.
+type RedisConfig struct {
+	// Addr is the host:port of the Redis server.
+	Addr     string `yaml:"addr" json:"addr"`
+	Username string `yaml:"username" json:"username"`
+	// PasswordEnv names the environment variable holding the password,
+	// REDIS_PASSWORD when unset. The password is never read from the file.
+	PasswordEnv string `yaml:"passwordEnv" json:"passwordEnv"`
+	DB          int    `yaml:"db" json:"db"`
+	// TLS connects to Addr over TLS, verified against the system roots.
+	TLS bool `yaml:"tls" json:"tls"`
+	// ClientCacheTTL is how long a client looked up by API key is cached.
+	ClientCacheTTL Duration `yaml:"clientCacheTTL" json:"clientCacheTTL"`
+
+	// password is populated from PasswordEnv by Hydrate.
+	password string
+}
+
+// Enabled reports whether a Redis server is configured.
+func (r RedisConfig) Enabled() bool {
+	return r.Addr != ""
+}
+
+// PasswordEnvName returns the environment variable the password is read from.
+func (r RedisConfig) PasswordEnvName() string {
+	if r.PasswordEnv != "" {
+		return r.PasswordEnv
+	}
+	return DefaultRedisPasswordEnv
+}
+
+// RedisPassword returns the Redis password from the environment. An empty
+// password is allowed for servers without AUTH.
+func (c *Config) RedisPassword() string {
+	return c.Redis.password
+}
+
+func (r *RedisConfig) hydrate() {
+	if v, ok := os.LookupEnv(r.PasswordEnvName()); ok {
+		r.password = v
+	}
+}
+
+func (r *RedisConfig) applyDefaults() {
+	if r.ClientCacheTTL == 0 {
+		r.ClientCacheTTL = DefaultRedisClientCacheTTL
+	}
+}
+
+func (r RedisConfig) validate() []error {
+	var errs []error
+
+	if r.DB < 0 {
+		errs = append(errs, fmt.Errorf("redis.db must not be negative, got %d", r.DB))
+	}
+	if r.ClientCacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("redis.clientCacheTTL must not be negative, got %s", r.ClientCacheTTL.Std()))
+	}
+	return errs
+}
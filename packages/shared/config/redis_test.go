@@ -0,0 +1,34 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_RedisPassword(t *testing.T) {
+	cfg := validConfig()
+	assert.False(t, cfg.Redis.Enabled())
+	assert.Equal(t, DefaultRedisPasswordEnv, cfg.Redis.PasswordEnvName())
+	assert.Empty(t, cfg.RedisPassword(), "a server without AUTH needs no password")
+
+	t.Setenv("CACHE_PASSWORD", "hunter2")
+	cfg.Redis = RedisConfig{Addr: "redis:6379", PasswordEnv: "CACHE_PASSWORD"}
+	cfg.Hydrate()
+
+	assert.True(t, cfg.Redis.Enabled())
+	assert.Equal(t, "hunter2", cfg.RedisPassword())
+	redacted := cfg.Redacted()
+	assert.Empty(t, redacted.RedisPassword(), "redacting drops the password")
+}
+
+func TestRedisConfig_ApplyDefaults(t *testing.T) {
+	var r RedisConfig
+	r.applyDefaults()
+	assert.Equal(t, DefaultRedisClientCacheTTL, r.ClientCacheTTL)
+
+	r.ClientCacheTTL = Duration(5 * time.Second)
+	r.applyDefaults()
+	assert.Equal(t, Duration(5*time.Second), r.ClientCacheTTL, "a set TTL is kept")
+}
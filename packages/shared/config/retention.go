@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Defaults applied to an unset retention section.
+const (
+	DefaultRetentionInterval   = Duration(time.Hour)
+	DefaultRetentionBatchSize  = 1000
+	DefaultRetentionBatchPause = Duration(500 * time.Millisecond)
+	DefaultRetentionLeaseTTL   = Duration(30 * time.Second)
+)
+
+// MaxRetentionBatchSize caps RetentionConfig.BatchSize. Larger deletes make
+// for transactions CockroachDB is likely to retry or abort.
+const MaxRetentionBatchSize = 10_000
+
+// RetentionConfig tunes the job that prunes old rows from the logs table.
+type RetentionConfig struct {
+	// Enabled turns the worker on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Interval is the pause between runs of the worker.
+	Interval Duration `yaml:"interval" json:"interval"`
+	// Periods maps an event type to how long its logs are kept. A period of
+	// 0 keeps them forever.
+	Periods map[string]Duration `yaml:"periods" json:"periods"`
+	// Default is how long logs of event types missing from Periods are kept;
+	// 0, the default, keeps them forever.
+	Default Duration `yaml:"default" json:"default"`
+	// BatchSize is the most logs deleted per statement.
+	BatchSize int `yaml:"batchSize" json:"batchSize"`
+	// BatchPause is the sleep between two deletes, leaving the cluster room
+	// for other work.
+	BatchPause Duration `yaml:"batchPause" json:"batchPause"`
+	// DryRun counts the logs a run would delete without deleting them.
+	DryRun bool `yaml:"dryRun" json:"dryRun"`
+	// LeaseTTL bounds how long a leader's lease lasts without renewal; only
+	// the replica holding the lease prunes.
+	LeaseTTL Duration `yaml:"leaseTTL" json:"leaseTTL"`
+}
+
+// Period returns how long logs of eventType are kept, 0 meaning forever.
+func (r RetentionConfig) Period(eventType string) time.Duration {
+	if p, ok := r.Periods[eventType]; ok {
+		return p.Std()
+	}
+	return r.Default.Std()
+}
+
+func (r *RetentionConfig) applyDefaults() {
+	if r.Interval == 0 {
+		r.Interval = DefaultRetentionInterval
+	}
+	if r.BatchSize == 0 {
+		r.BatchSize = DefaultRetentionBatchSize
+	}
+	if r.BatchPause == 0 {
+		r.BatchPause = DefaultRetentionBatchPause
+	}
+	if r.LeaseTTL == 0 {
+		r.LeaseTTL = DefaultRetentionLeaseTTL
+	}
+}
+
+func (r RetentionConfig) validate() []error {
+	var errs []error
+
+	if r.Interval <= 0 {
+		errs = append(errs, fmt.Errorf("retention.interval must be positive, got %s", r.Interval.Std()))
+	}
+	eventTypes := make([]string, 0, len(r.Periods))
+	for eventType := range r.Periods {
+		eventTypes = append(eventTypes, eventType)
+	}
+	sort.Strings(eventTypes)
+	for _, eventType := range eventTypes {
+		if eventType == "" {
+			errs = append(errs, fmt.Errorf("retention.periods: event type must not be empty"))
+			continue
+		}
+		if p := r.Periods[eventType]; p < 0 {
+			errs = append(errs, fmt.Errorf("retention.periods.%s must not be negative, got %s", eventType, p.Std()))
+		}
+	}
+	if r.Default < 0 {
+		errs = append(errs, fmt.Errorf("retention.default must not be negative, got %s", r.Default.Std()))
+	}
+	if r.BatchSize < 1 || r.BatchSize > MaxRetentionBatchSize {
+		errs = append(errs, fmt.Errorf("retention.batchSize must be between 1 and %d, got %d", MaxRetentionBatchSize, r.BatchSize))
+	}
+	if r.BatchPause < 0 {
+		errs = append(errs, fmt.Errorf("retention.batchPause must not be negative, got %s", r.BatchPause.Std()))
+	}
+	if r.LeaseTTL < Duration(time.Second) {
+		errs = append(errs, fmt.Errorf("retention.leaseTTL must be at least 1s, got %s", r.LeaseTTL.Std()))
+	}
+	return errs
+}
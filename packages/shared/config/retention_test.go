@@ -0,0 +1,98 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_LoadConfig_RetentionSection(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := `
+appPort: 8080
+database:
+  user: root
+  host: localhost
+  database: tpg
+  maxConnections: 10
+retention:
+  enabled: true
+  interval: 6h
+  periods:
+    WEBHOOK_SENT: 2160h
+    TX_DETECTED: 720h
+    ERROR: 0
+  default: 8760h
+  batchSize: 500
+  batchPause: 1s
+  dryRun: true
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath))
+
+	r := cfg.Retention
+	assert.True(t, r.Enabled)
+	assert.Equal(t, 6*time.Hour, r.Interval.Std())
+	assert.Equal(t, 90*24*time.Hour, r.Period("WEBHOOK_SENT"))
+	assert.Equal(t, 30*24*time.Hour, r.Period("TX_DETECTED"))
+	assert.Zero(t, r.Period("ERROR"), "a zero period keeps the logs forever")
+	assert.Equal(t, 365*24*time.Hour, r.Period("ADDRESS_GENERATED"), "unlisted types use the default")
+	assert.Equal(t, 500, r.BatchSize)
+	assert.Equal(t, time.Second, r.BatchPause.Std())
+	assert.True(t, r.DryRun)
+	assert.Equal(t, DefaultRetentionLeaseTTL, r.LeaseTTL)
+}
+
+func TestConfig_RetentionDefaults(t *testing.T) {
+	cfg := validConfig()
+
+	r := cfg.Retention
+	assert.False(t, r.Enabled)
+	assert.Equal(t, DefaultRetentionInterval, r.Interval)
+	assert.Equal(t, DefaultRetentionBatchSize, r.BatchSize)
+	assert.Equal(t, DefaultRetentionBatchPause, r.BatchPause)
+	assert.Equal(t, DefaultRetentionLeaseTTL, r.LeaseTTL)
+	assert.Zero(t, r.Period("TX_DETECTED"), "logs are kept forever unless configured otherwise")
+}
+
+func TestRetentionConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mutate  func(*RetentionConfig)
+		wantErr string
+	}{
+		{"valid", func(*RetentionConfig) {}, ""},
+		{"periods", func(r *RetentionConfig) {
+			r.Periods = map[string]Duration{"TX_DETECTED": Duration(time.Hour), "ERROR": 0}
+		}, ""},
+		{"negative interval", func(r *RetentionConfig) { r.Interval = Duration(-time.Second) }, "retention.interval must be positive"},
+		{"empty event type", func(r *RetentionConfig) { r.Periods = map[string]Duration{"": Duration(time.Hour)} }, "retention.periods: event type must not be empty"},
+		{"negative period", func(r *RetentionConfig) { r.Periods = map[string]Duration{"TX_DETECTED": Duration(-time.Hour)} }, "retention.periods.TX_DETECTED must not be negative"},
+		{"negative default", func(r *RetentionConfig) { r.Default = Duration(-time.Hour) }, "retention.default must not be negative"},
+		{"batch too large", func(r *RetentionConfig) { r.BatchSize = MaxRetentionBatchSize + 1 }, "retention.batchSize must be between 1 and 10000"},
+		{"negative batch", func(r *RetentionConfig) { r.BatchSize = -1 }, "retention.batchSize must be between 1 and 10000, got -1"},
+		{"negative pause", func(r *RetentionConfig) { r.BatchPause = Duration(-time.Millisecond) }, "retention.batchPause must not be negative"},
+		{"short lease", func(r *RetentionConfig) { r.LeaseTTL = Duration(500 * time.Millisecond) }, "retention.leaseTTL must be at least 1s, got 500ms"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.mutate(&cfg.Retention)
+
+			err := cfg.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
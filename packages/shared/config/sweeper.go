@@ -0,0 +1,174 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+// DefaultWalletMnemonicEnv is read when SweeperConfig.MnemonicEnv is empty.
+const DefaultWalletMnemonicEnv = "WALLET_MNEMONIC"
+
+// Defaults applied to an unset sweeper section.
+const (
+	DefaultSweepInterval  = Duration(10 * time.Minute)
+	DefaultSweepBatchSize = 50
+	// DefaultSweepRecheckInterval is how long a wallet left alone by a run
+	// waits before it is looked at again.
+	DefaultSweepRecheckInterval = Duration(time.Hour)
+	// DefaultSweepMinAmount is the smallest balance worth sweeping, in token
+	// units. Below it the fees eat too much of the amount.
+	DefaultSweepMinAmount = "10"
+	// DefaultSweepMaxFeeLimitSun caps the fee limit of a TRC20 sweep at 100
+	// TRX.
+	DefaultSweepMaxFeeLimitSun = 100_000_000
+	// DefaultEnergyPriceSun is the mainnet energy price when it was last
+	// raised; a higher price than the chain's only makes fee limits roomier.
+	DefaultEnergyPriceSun = 420
+	// DefaultSweepLeaseTTL is how long a sweeper replica that stopped
+	// renewing its leadership blocks the others.
+	DefaultSweepLeaseTTL = Duration(30 * time.Second)
+)
+
+// MaxSweepBatchSize caps SweeperConfig.BatchSize.
+const MaxSweepBatchSize = 500
+
+// SweeperConfig tunes the worker that moves confirmed payments from their
+// deposit wallets to the cold wallet.
+type SweeperConfig struct {
+	// Enabled turns the sweeper on; the rest of the section is only
+	// validated when it is set.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// ColdWallet is the base58 address every sweep pays.
+	ColdWallet string `yaml:"coldWallet" json:"coldWallet"`
+	// Interval is the pause between sweep runs.
+	Interval Duration `yaml:"interval" json:"interval"`
+	// BatchSize is the most payments swept per run.
+	BatchSize int `yaml:"batchSize" json:"batchSize"`
+	// RecheckInterval is how long a wallet whose balance was below the
+	// threshold, or could not pay the fees, stays out of the batches. It
+	// also caps the backoff of a wallet whose sweeps keep failing.
+	RecheckInterval Duration `yaml:"recheckInterval" json:"recheckInterval"`
+	// MinAmount maps a token to the smallest balance swept, as a decimal
+	// string in token units. Tokens missing from it use
+	// DefaultSweepMinAmount.
+	MinAmount map[string]string `yaml:"minAmount" json:"minAmount"`
+	// MaxFeeLimitSun caps the fee limit of a TRC20 sweep.
+	MaxFeeLimitSun int64 `yaml:"maxFeeLimitSun" json:"maxFeeLimitSun"`
+	// EnergyPriceSun is the price of one unit of energy burned in place of
+	// staked energy.
+	EnergyPriceSun int64 `yaml:"energyPriceSun" json:"energyPriceSun"`
+	// DryRun logs what would be swept without signing or broadcasting.
+	DryRun bool `yaml:"dryRun" json:"dryRun"`
+	// LeaseTTL bounds how long a leader's lease lasts without renewal; only
+	// the replica holding the lease sweeps.
+	LeaseTTL Duration `yaml:"leaseTTL" json:"leaseTTL"`
+	// MnemonicEnv names the environment variable holding the mnemonic the
+	// deposit wallets are derived from, WALLET_MNEMONIC when unset. The
+	// mnemonic is never read from the file.
+	MnemonicEnv string `yaml:"mnemonicEnv" json:"mnemonicEnv"`
+
+	// mnemonic is populated from MnemonicEnv by Hydrate.
+	mnemonic string
+}
+
+// MnemonicEnvName returns the environment variable the mnemonic is read from.
+func (s SweeperConfig) MnemonicEnvName() string {
+	if s.MnemonicEnv != "" {
+		return s.MnemonicEnv
+	}
+	return DefaultWalletMnemonicEnv
+}
+
+// WalletMnemonic returns the mnemonic deposit wallets are derived from.
+func (c *Config) WalletMnemonic() (string, error) {
+	if c.Sweeper.mnemonic == "" {
+		return "", fmt.Errorf("wallet mnemonic is empty: set %s", c.Sweeper.MnemonicEnvName())
+	}
+	return c.Sweeper.mnemonic, nil
+}
+
+// MinSweepAmount returns the smallest balance of token worth sweeping.
+func (s SweeperConfig) MinSweepAmount(token string) decimal.Decimal {
+	for t, v := range s.MinAmount {
+		if strings.EqualFold(t, token) {
+			if d, err := decimal.NewFromString(v); err == nil {
+				return d
+			}
+		}
+	}
+	return decimal.RequireFromString(DefaultSweepMinAmount)
+}
+
+func (s *SweeperConfig) hydrate() {
+	if v, ok := os.LookupEnv(s.MnemonicEnvName()); ok {
+		s.mnemonic = v
+	}
+}
+
+func (s *SweeperConfig) applyDefaults() {
+	if s.Interval == 0 {
+		s.Interval = DefaultSweepInterval
+	}
+	if s.BatchSize == 0 {
+		s.BatchSize = DefaultSweepBatchSize
+	}
+	if s.RecheckInterval == 0 {
+		s.RecheckInterval = DefaultSweepRecheckInterval
+	}
+	if s.MaxFeeLimitSun == 0 {
+		s.MaxFeeLimitSun = DefaultSweepMaxFeeLimitSun
+	}
+	if s.EnergyPriceSun == 0 {
+		s.EnergyPriceSun = DefaultEnergyPriceSun
+	}
+	if s.LeaseTTL == 0 {
+		s.LeaseTTL = DefaultSweepLeaseTTL
+	}
+}
+
+func (s SweeperConfig) validate() []error {
+	if !s.Enabled {
+		return nil
+	}
+	var errs []error
+
+	if err := wallet.ValidateAddress(s.ColdWallet); err != nil {
+		errs = append(errs, fmt.Errorf("sweeper.coldWallet: %w", err))
+	}
+	if s.Interval <= 0 {
+		errs = append(errs, fmt.Errorf("sweeper.interval must be positive, got %s", s.Interval.Std()))
+	}
+	if s.BatchSize < 1 || s.BatchSize > MaxSweepBatchSize {
+		errs = append(errs, fmt.Errorf("sweeper.batchSize must be between 1 and %d, got %d", MaxSweepBatchSize, s.BatchSize))
+	}
+	if s.RecheckInterval <= 0 {
+		errs = append(errs, fmt.Errorf("sweeper.recheckInterval must be positive, got %s", s.RecheckInterval.Std()))
+	}
+	for token, v := range s.MinAmount {
+		if !slices.Contains(KnownTokens, strings.ToUpper(token)) {
+			errs = append(errs, fmt.Errorf("sweeper.minAmount: unsupported token %q, must be one of %s",
+				token, strings.Join(KnownTokens, ", ")))
+			continue
+		}
+		if _, err := parseAmount(v); err != nil || v == "" {
+			errs = append(errs, fmt.Errorf("sweeper.minAmount.%s must be a positive decimal, got %q", token, v))
+		}
+	}
+	if s.MaxFeeLimitSun <= 0 {
+		errs = append(errs, fmt.Errorf("sweeper.maxFeeLimitSun must be positive, got %d", s.MaxFeeLimitSun))
+	}
+	if s.EnergyPriceSun <= 0 {
+		errs = append(errs, fmt.Errorf("sweeper.energyPriceSun must be positive, got %d", s.EnergyPriceSun))
+	}
+	if s.LeaseTTL < Duration(time.Second) {
+		errs = append(errs, fmt.Errorf("sweeper.leaseTTL must be at least 1s, got %s", s.LeaseTTL.Std()))
+	}
+
+	return errs
+}
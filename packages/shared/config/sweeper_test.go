@@ -0,0 +1,138 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testColdWallet = "TKTX96CBxr5kvhjsDHcqoiPWZageGxoTW3"
+
+func TestConfig_LoadConfig_SweeperSection(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yaml := `
+appPort: 8080
+database:
+  user: root
+  host: localhost
+  database: tpg
+  maxConnections: 10
+sweeper:
+  enabled: true
+  coldWallet: TKTX96CBxr5kvhjsDHcqoiPWZageGxoTW3
+  interval: 5m
+  batchSize: 20
+  recheckInterval: 30m
+  minAmount:
+    USDT: "25.5"
+  maxFeeLimitSun: 50000000
+  energyPriceSun: 210
+  dryRun: true
+  leaseTTL: 45s
+  mnemonicEnv: TEST_SWEEPER_MNEMONIC
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+	t.Setenv("TEST_SWEEPER_MNEMONIC", "test mnemonic")
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath))
+
+	s := cfg.Sweeper
+	assert.True(t, s.Enabled)
+	assert.Equal(t, testColdWallet, s.ColdWallet)
+	assert.Equal(t, 5*time.Minute, s.Interval.Std())
+	assert.Equal(t, 20, s.BatchSize)
+	assert.Equal(t, 30*time.Minute, s.RecheckInterval.Std())
+	assert.True(t, decimal.RequireFromString("25.5").Equal(s.MinSweepAmount("usdt")))
+	assert.True(t, decimal.RequireFromString(DefaultSweepMinAmount).Equal(s.MinSweepAmount(TokenTRX)))
+	assert.Equal(t, int64(50000000), s.MaxFeeLimitSun)
+	assert.Equal(t, int64(210), s.EnergyPriceSun)
+	assert.True(t, s.DryRun)
+	assert.Equal(t, 45*time.Second, s.LeaseTTL.Std())
+
+	mnemonic, err := cfg.WalletMnemonic()
+	require.NoError(t, err)
+	assert.Equal(t, "test mnemonic", mnemonic)
+}
+
+func TestConfig_LoadConfig_SweeperDefaults(t *testing.T) {
+	cfg := validConfig()
+
+	assert.False(t, cfg.Sweeper.Enabled)
+	assert.Equal(t, DefaultSweepInterval, cfg.Sweeper.Interval)
+	assert.Equal(t, DefaultSweepBatchSize, cfg.Sweeper.BatchSize)
+	assert.Equal(t, DefaultSweepRecheckInterval, cfg.Sweeper.RecheckInterval)
+	assert.Equal(t, int64(DefaultSweepMaxFeeLimitSun), cfg.Sweeper.MaxFeeLimitSun)
+	assert.Equal(t, int64(DefaultEnergyPriceSun), cfg.Sweeper.EnergyPriceSun)
+	assert.Equal(t, DefaultSweepLeaseTTL, cfg.Sweeper.LeaseTTL)
+	assert.Equal(t, DefaultWalletMnemonicEnv, cfg.Sweeper.MnemonicEnvName())
+}
+
+func TestConfig_WalletMnemonic_Missing(t *testing.T) {
+	cfg := validConfig()
+
+	_, err := cfg.WalletMnemonic()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), DefaultWalletMnemonicEnv)
+}
+
+func TestConfig_Redacted_DropsMnemonic(t *testing.T) {
+	t.Setenv(DefaultWalletMnemonicEnv, "secret words")
+	cfg := validConfig()
+	cfg.Hydrate()
+
+	redacted := cfg.Redacted()
+
+	_, err := redacted.WalletMnemonic()
+	assert.Error(t, err)
+	mnemonic, err := cfg.WalletMnemonic()
+	require.NoError(t, err)
+	assert.Equal(t, "secret words", mnemonic, "redacting must not touch the original")
+}
+
+func TestSweeperConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mutate  func(*SweeperConfig)
+		wantErr string
+	}{
+		{"valid", func(*SweeperConfig) {}, ""},
+		{"disabled skips validation", func(s *SweeperConfig) { s.Enabled = false; s.ColdWallet = "" }, ""},
+		{"missing cold wallet", func(s *SweeperConfig) { s.ColdWallet = "" }, "sweeper.coldWallet"},
+		{"invalid cold wallet", func(s *SweeperConfig) { s.ColdWallet = "TNotAnAddress" }, "sweeper.coldWallet"},
+		{"negative interval", func(s *SweeperConfig) { s.Interval = Duration(-time.Second) }, "sweeper.interval must be positive"},
+		{"batch too large", func(s *SweeperConfig) { s.BatchSize = MaxSweepBatchSize + 1 }, "sweeper.batchSize must be between 1 and 500"},
+		{"negative recheck interval", func(s *SweeperConfig) { s.RecheckInterval = Duration(-time.Minute) }, "sweeper.recheckInterval must be positive"},
+		{"unknown token", func(s *SweeperConfig) { s.MinAmount = map[string]string{"BTC": "1"} }, `unsupported token "BTC"`},
+		{"zero minimum", func(s *SweeperConfig) { s.MinAmount = map[string]string{"TRX": "0"} }, "sweeper.minAmount.TRX must be a positive decimal"},
+		{"empty minimum", func(s *SweeperConfig) { s.MinAmount = map[string]string{"USDT": ""} }, "sweeper.minAmount.USDT must be a positive decimal"},
+		{"negative fee limit", func(s *SweeperConfig) { s.MaxFeeLimitSun = -1 }, "sweeper.maxFeeLimitSun must be positive"},
+		{"negative energy price", func(s *SweeperConfig) { s.EnergyPriceSun = -1 }, "sweeper.energyPriceSun must be positive"},
+		{"short lease", func(s *SweeperConfig) { s.LeaseTTL = Duration(500 * time.Millisecond) }, "sweeper.leaseTTL must be at least 1s, got 500ms"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Sweeper.Enabled = true
+			cfg.Sweeper.ColdWallet = testColdWallet
+			tc.mutate(&cfg.Sweeper)
+
+			err := cfg.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// Client modes. Live clients take real payments on the tron network; test
+// clients take payments on the testnet of the testMode section.
+const (
+	ModeLive = "live"
+	ModeTest = "test"
+)
+
+// DefaultTestWalletMnemonicEnv is read when TestModeConfig.MnemonicEnv is
+// empty.
+const DefaultTestWalletMnemonicEnv = "WALLET_TEST_MNEMONIC"
+
+// TestModeConfig sets up test mode, in which test clients integrate against
+// a testnet without touching live wallets or live data.
+type TestModeConfig struct {
+	// Enabled lets test clients be created and watched; the rest of the
+	// section is only validated when it is set.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Tron is the testnet test payments are made on. Network defaults to
+	// shasta, and mainnet is refused.
+	Tron TronConfig `yaml:"tron" json:"tron"`
+	// MnemonicEnv names the environment variable holding the mnemonic the
+	// test deposit wallets are derived from, WALLET_TEST_MNEMONIC when unset.
+	// It must differ from the live mnemonic.
+	MnemonicEnv string `yaml:"mnemonicEnv" json:"mnemonicEnv"`
+
+	// mnemonic is populated from MnemonicEnv by Hydrate.
+	mnemonic string
+}
+
+// MnemonicEnvName returns the environment variable the test mnemonic is read
+// from.
+func (t TestModeConfig) MnemonicEnvName() string {
+	if t.MnemonicEnv != "" {
+		return t.MnemonicEnv
+	}
+	return DefaultTestWalletMnemonicEnv
+}
+
+// TestWalletMnemonic returns the mnemonic test deposit wallets are derived
+// from, or an error when it is unset or the same as the live one.
+func (c *Config) TestWalletMnemonic() (string, error) {
+	m := c.TestMode.mnemonic
+	if m == "" {
+		return "", fmt.Errorf("test wallet mnemonic is empty: set %s", c.TestMode.MnemonicEnvName())
+	}
+	if m == c.Sweeper.mnemonic {
+		return "", fmt.Errorf("test wallet mnemonic in %s must differ from the live one", c.TestMode.MnemonicEnvName())
+	}
+	return m, nil
+}
+
+// Mode returns the client mode the config serves: ModeTest for a config
+// returned by ForTestMode, ModeLive otherwise.
+func (c *Config) Mode() string {
+	if c.mode == "" {
+		return ModeLive
+	}
+	return c.mode
+}
+
+// ForTestMode returns a copy of c for the workers serving test clients: its
+// tron section is the testnet's and Mode reports ModeTest.
+func (c *Config) ForTestMode() *Config {
+	t := *c
+	t.Tron = c.TestMode.Tron
+	t.mode = ModeTest
+	return &t
+}
+
+func (t *TestModeConfig) hydrate() {
+	t.Tron.hydrate()
+	if v, ok := os.LookupEnv(t.MnemonicEnvName()); ok {
+		t.mnemonic = v
+	}
+}
+
+func (t *TestModeConfig) applyDefaults() {
+	if t.Tron.Network == "" {
+		t.Tron.Network = TronShasta
+	}
+	t.Tron.applyDefaults()
+}
+
+func (t TestModeConfig) validate() []error {
+	if !t.Enabled {
+		return nil
+	}
+	var errs []error
+
+	if t.Tron.Network == TronMainnet {
+		errs = append(errs, fmt.Errorf("testMode.tron.network must be a testnet, got %q", t.Tron.Network))
+	}
+	for _, err := range t.Tron.validate() {
+		errs = append(errs, fmt.Errorf("testMode.%w", err))
+	}
+
+	return errs
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_LoadConfig_TestModeSection(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yaml := `
+appPort: 8080
+database:
+  user: root
+  host: localhost
+  database: tpg
+  maxConnections: 10
+testMode:
+  enabled: true
+  tron:
+    confirmationsRequired: 1
+    apiKeyEnv: TEST_TESTNET_API_KEY
+  mnemonicEnv: TEST_TESTNET_MNEMONIC
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+	t.Setenv("TEST_TESTNET_API_KEY", "testnet-key")
+	t.Setenv("TEST_TESTNET_MNEMONIC", "testnet words")
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath))
+
+	tm := cfg.TestMode
+	assert.True(t, tm.Enabled)
+	assert.Equal(t, TronShasta, tm.Tron.Network)
+	assert.Equal(t, "https://api.shasta.trongrid.io", tm.Tron.FullNodeURL)
+	assert.Equal(t, usdtContracts[TronShasta], tm.Tron.USDTContract)
+	assert.Equal(t, 1, tm.Tron.ConfirmationsRequired)
+	assert.Equal(t, TronMainnet, cfg.Tron.Network, "the live section keeps its own defaults")
+
+	mnemonic, err := cfg.TestWalletMnemonic()
+	require.NoError(t, err)
+	assert.Equal(t, "testnet words", mnemonic)
+}
+
+func TestConfig_ForTestMode(t *testing.T) {
+	t.Setenv("TEST_TESTNET_API_KEY", "testnet-key")
+	cfg := validConfig()
+	cfg.TestMode.Tron.APIKeyEnv = "TEST_TESTNET_API_KEY"
+	cfg.Hydrate()
+
+	test := cfg.ForTestMode()
+
+	assert.Equal(t, ModeLive, cfg.Mode())
+	assert.Equal(t, ModeTest, test.Mode())
+	assert.Equal(t, TronShasta, test.Tron.Network)
+	assert.Equal(t, "testnet-key", test.TronAPIKey())
+	assert.Equal(t, TronMainnet, cfg.Tron.Network, "the original is untouched")
+	assert.Equal(t, cfg.DatabaseConfig, test.DatabaseConfig)
+}
+
+func TestConfig_TestWalletMnemonic(t *testing.T) {
+	t.Run("missing", func(t *testing.T) {
+		cfg := validConfig()
+
+		_, err := cfg.TestWalletMnemonic()
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), DefaultTestWalletMnemonicEnv)
+	})
+
+	t.Run("same as live", func(t *testing.T) {
+		t.Setenv(DefaultWalletMnemonicEnv, "shared words")
+		t.Setenv(DefaultTestWalletMnemonicEnv, "shared words")
+		cfg := validConfig()
+		cfg.Hydrate()
+
+		_, err := cfg.TestWalletMnemonic()
+
+		require.ErrorContains(t, err, "must differ from the live one")
+	})
+}
+
+func TestConfig_Redacted_DropsTestModeSecrets(t *testing.T) {
+	t.Setenv(DefaultTestWalletMnemonicEnv, "secret words")
+	cfg := validConfig()
+	cfg.Hydrate()
+
+	redacted := cfg.Redacted()
+
+	_, err := redacted.TestWalletMnemonic()
+	assert.Error(t, err)
+	_, err = cfg.TestWalletMnemonic()
+	assert.NoError(t, err, "redacting must not touch the original")
+}
+
+func TestTestModeConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mutate  func(*TestModeConfig)
+		wantErr string
+	}{
+		{"valid", func(*TestModeConfig) {}, ""},
+		{"nile", func(m *TestModeConfig) { m.Tron.Network = TronNile }, ""},
+		{"disabled skips validation", func(m *TestModeConfig) { m.Enabled = false; m.Tron.Network = TronMainnet }, ""},
+		{"mainnet", func(m *TestModeConfig) { m.Tron.Network = TronMainnet }, `testMode.tron.network must be a testnet, got "mainnet"`},
+		{"invalid node url", func(m *TestModeConfig) { m.Tron.FullNodeURL = "ftp://node" }, "testMode.tron.fullNodeURL"},
+		{"zero confirmations", func(m *TestModeConfig) { m.Tron.ConfirmationsRequired = 0 }, "testMode.tron.confirmationsRequired must be at least 1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.TestMode.Enabled = true
+			tc.mutate(&cfg.TestMode)
+
+			err := cfg.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// DefaultTracingSampleRatio is applied to an unset sampleRatio.
+const DefaultTracingSampleRatio = 1.0
+
+// TracingConfig configures OpenTelemetry tracing. With no endpoint nothing
+// is exported and spans cost next to nothing.
+type TracingConfig struct {
+	// Endpoint is the OTLP/HTTP collector URL, e.g.
+	// "http://otel-collector:4318"; traces go to its /v1/traces path.
+	Endpoint string `yaml:"endpoint" json:"endpoint"`
+	// SampleRatio is the share of traces started here that are recorded,
+	// between 0 and 1; unset records all of them. Traces a caller started
+	// follow the caller's decision.
+	SampleRatio float64 `yaml:"sampleRatio" json:"sampleRatio"`
+	// ServiceName names this process in traces. Each binary falls back to
+	// a name of its own.
+	ServiceName string `yaml:"serviceName" json:"serviceName"`
+}
+
+// Enabled reports whether a collector is configured.
+func (t TracingConfig) Enabled() bool {
+	return t.Endpoint != ""
+}
+
+func (t *TracingConfig) applyDefaults() {
+	if t.SampleRatio == 0 {
+		t.SampleRatio = DefaultTracingSampleRatio
+	}
+}
+
+func (t TracingConfig) validate() []error {
+	var errs []error
+
+	if t.Endpoint != "" {
+		if u, err := url.Parse(t.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("tracing.endpoint must be an http or https URL, got %q", t.Endpoint))
+		}
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing.sampleRatio must be between 0 and 1, got %g", t.SampleRatio))
+	}
+	return errs
+}
@@ -0,0 +1,19 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracingConfig_ApplyDefaults(t *testing.T) {
+	var tr TracingConfig
+	assert.False(t, tr.Enabled())
+	tr.applyDefaults()
+	assert.Equal(t, DefaultTracingSampleRatio, tr.SampleRatio)
+
+	tr = TracingConfig{Endpoint: "https://otel.example.com", SampleRatio: 0.1}
+	tr.applyDefaults()
+	assert.True(t, tr.Enabled())
+	assert.Equal(t, 0.1, tr.SampleRatio, "a set ratio is kept")
+}
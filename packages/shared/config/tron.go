@@ -0,0 +1,272 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+// Supported values for TronConfig.Network.
+const (
+	TronMainnet = "mainnet"
+	TronShasta  = "shasta"
+	TronNile    = "nile"
+)
+
+// DefaultTronAPIKeyEnv is read when TronConfig.APIKeyEnv is empty.
+const DefaultTronAPIKeyEnv = "TRONGRID_API_KEY"
+
+// Defaults applied to an unset tron section.
+const (
+	DefaultTronRequestTimeout        = Duration(10 * time.Second)
+	DefaultTronConfirmationsRequired = 19
+	// DefaultTronRequestsPerSecond stays under TronGrid's per-key quota.
+	DefaultTronRequestsPerSecond = 10
+	DefaultTronBurst             = 20
+	DefaultTronMaxAttempts       = 3
+	DefaultTronRetryBaseDelay    = Duration(500 * time.Millisecond)
+	DefaultTronRetryMaxDelay     = Duration(10 * time.Second)
+)
+
+// tronGridURLs are the public TronGrid endpoints per network. TronGrid serves
+// the full node, solidity node and event APIs from the same host.
+var tronGridURLs = map[string]string{
+	TronMainnet: "https://api.trongrid.io",
+	TronShasta:  "https://api.shasta.trongrid.io",
+	TronNile:    "https://nile.trongrid.io",
+}
+
+// usdtContracts are the Tether USD TRC20 contracts per network.
+var usdtContracts = map[string]string{
+	TronMainnet: "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t",
+	TronShasta:  "TG3XXyExBkPp9nzdajDZsozEu4BkaSJozs",
+	TronNile:    "TXYZopYRdj2D9XRtbG411XZZ3kM5VkAeBf",
+}
+
+// TronConfig selects the TRON network and the node endpoints the gateway
+// talks to.
+type TronConfig struct {
+	// Network is one of mainnet, shasta or nile; mainnet when unset.
+	Network string `yaml:"network" json:"network"`
+	// Node URLs default to the TronGrid endpoints for Network.
+	FullNodeURL     string `yaml:"fullNodeURL" json:"fullNodeURL"`
+	SolidityNodeURL string `yaml:"solidityNodeURL" json:"solidityNodeURL"`
+	EventServerURL  string `yaml:"eventServerURL" json:"eventServerURL"`
+	// APIKey is a fallback for local setups; prefer APIKeyEnv.
+	APIKey string `yaml:"apiKey" json:"apiKey" secret:"true"`
+	// APIKeyEnv names the environment variable holding the TronGrid key,
+	// TRONGRID_API_KEY when unset.
+	APIKeyEnv string `yaml:"apiKeyEnv" json:"apiKeyEnv"`
+	// RequestTimeout bounds every HTTP call to the node.
+	RequestTimeout Duration `yaml:"requestTimeout" json:"requestTimeout"`
+	// ConfirmationsRequired is how many blocks must follow a transfer before
+	// a payment is treated as confirmed.
+	ConfirmationsRequired int `yaml:"confirmationsRequired" json:"confirmationsRequired"`
+	// USDTContract is the token contract whose transfers credit USDT
+	// payments, the official Tether contract for Network when unset.
+	USDTContract string `yaml:"usdtContract" json:"usdtContract"`
+	// Endpoints lists the full nodes requests are spread across, best
+	// first. When set it replaces FullNodeURL.
+	Endpoints []TronEndpoint `yaml:"endpoints" json:"endpoints"`
+	// RateLimit paces the requests sent to each endpoint.
+	RateLimit TronRateLimitConfig `yaml:"rateLimit" json:"rateLimit"`
+	// Retry governs how reads that fail with a 429, 5xx or timeout are
+	// retried.
+	Retry TronRetryConfig `yaml:"retry" json:"retry"`
+	// DailyBudget caps the node requests a process sends per UTC day, retries
+	// included. Once it is spent, only critical calls such as block polling
+	// and broadcasts go out. Zero sets no budget.
+	DailyBudget int64 `yaml:"dailyBudget" json:"dailyBudget"`
+
+	// apiKey is populated from APIKeyEnv by Hydrate.
+	apiKey string
+}
+
+// TronEndpoint is one full node the client may route requests to.
+type TronEndpoint struct {
+	URL string `yaml:"url" json:"url"`
+	// APIKey is a fallback for local setups; prefer APIKeyEnv. Endpoints
+	// without a key use the tron section's.
+	APIKey string `yaml:"apiKey" json:"apiKey" secret:"true"`
+	// APIKeyEnv names the environment variable holding this endpoint's key.
+	APIKeyEnv string `yaml:"apiKeyEnv" json:"apiKeyEnv"`
+
+	// apiKey is populated from APIKeyEnv by Hydrate.
+	apiKey string
+}
+
+// TronRateLimitConfig is a token bucket applied per endpoint, since quotas
+// are enforced per API key.
+type TronRateLimitConfig struct {
+	RequestsPerSecond float64 `yaml:"requestsPerSecond" json:"requestsPerSecond"`
+	// Burst is how many requests may go out at once after an idle spell.
+	Burst int `yaml:"burst" json:"burst"`
+}
+
+// TronRetryConfig bounds the retries of a read. Broadcasts are never
+// retried.
+type TronRetryConfig struct {
+	// MaxAttempts counts the first attempt, so 1 disables retries.
+	MaxAttempts int `yaml:"maxAttempts" json:"maxAttempts"`
+	// BaseDelay is the first backoff; it doubles with every retry up to
+	// MaxDelay. A Retry-After longer than MaxDelay ends the retries.
+	BaseDelay Duration `yaml:"baseDelay" json:"baseDelay"`
+	MaxDelay  Duration `yaml:"maxDelay" json:"maxDelay"`
+}
+
+// Key returns the endpoint's API key from the environment, falling back to
+// the apiKey key in the file. It is empty when the endpoint has none.
+func (e TronEndpoint) Key() string {
+	if e.apiKey != "" {
+		return e.apiKey
+	}
+	return e.APIKey
+}
+
+// FullNodeEndpoints returns the full nodes to use: Endpoints, or
+// FullNodeURL on its own when Endpoints is empty.
+func (t TronConfig) FullNodeEndpoints() []TronEndpoint {
+	if len(t.Endpoints) > 0 {
+		return t.Endpoints
+	}
+	return []TronEndpoint{{URL: t.FullNodeURL}}
+}
+
+// APIKeyEnvName returns the environment variable the API key is read from.
+func (t TronConfig) APIKeyEnvName() string {
+	if t.APIKeyEnv != "" {
+		return t.APIKeyEnv
+	}
+	return DefaultTronAPIKeyEnv
+}
+
+// TronAPIKey returns the TronGrid key from the environment, falling back to
+// the apiKey key in the file. An empty key is allowed; TronGrid serves
+// unauthenticated requests at a lower rate limit.
+func (c *Config) TronAPIKey() string {
+	if c.Tron.apiKey != "" {
+		return c.Tron.apiKey
+	}
+	return c.Tron.APIKey
+}
+
+func (t *TronConfig) hydrate() {
+	if v, ok := os.LookupEnv(t.APIKeyEnvName()); ok {
+		t.apiKey = v
+	}
+	for i, e := range t.Endpoints {
+		if e.APIKeyEnv == "" {
+			continue
+		}
+		if v, ok := os.LookupEnv(e.APIKeyEnv); ok {
+			t.Endpoints[i].apiKey = v
+		}
+	}
+}
+
+func (t *TronConfig) applyDefaults() {
+	if t.Network == "" {
+		t.Network = TronMainnet
+	}
+	if base, ok := tronGridURLs[t.Network]; ok {
+		if t.FullNodeURL == "" {
+			t.FullNodeURL = base
+		}
+		if t.SolidityNodeURL == "" {
+			t.SolidityNodeURL = base
+		}
+		if t.EventServerURL == "" {
+			t.EventServerURL = base
+		}
+	}
+	if t.RequestTimeout == 0 {
+		t.RequestTimeout = DefaultTronRequestTimeout
+	}
+	if t.ConfirmationsRequired == 0 {
+		t.ConfirmationsRequired = DefaultTronConfirmationsRequired
+	}
+	if t.USDTContract == "" {
+		t.USDTContract = usdtContracts[t.Network]
+	}
+	if t.RateLimit.RequestsPerSecond == 0 {
+		t.RateLimit.RequestsPerSecond = DefaultTronRequestsPerSecond
+	}
+	if t.RateLimit.Burst == 0 {
+		t.RateLimit.Burst = DefaultTronBurst
+	}
+	if t.Retry.MaxAttempts == 0 {
+		t.Retry.MaxAttempts = DefaultTronMaxAttempts
+	}
+	if t.Retry.BaseDelay == 0 {
+		t.Retry.BaseDelay = DefaultTronRetryBaseDelay
+	}
+	if t.Retry.MaxDelay == 0 {
+		t.Retry.MaxDelay = DefaultTronRetryMaxDelay
+	}
+}
+
+func (t TronConfig) validate() []error {
+	var errs []error
+
+	if _, ok := tronGridURLs[t.Network]; !ok {
+		errs = append(errs, fmt.Errorf("tron.network must be one of mainnet, shasta, nile, got %q", t.Network))
+	}
+	for _, u := range []struct{ field, value string }{
+		{"tron.fullNodeURL", t.FullNodeURL},
+		{"tron.solidityNodeURL", t.SolidityNodeURL},
+		{"tron.eventServerURL", t.EventServerURL},
+	} {
+		if err := validHTTPURL(u.value); err != nil {
+			errs = append(errs, fmt.Errorf("%s %w", u.field, err))
+		}
+	}
+	for i, e := range t.Endpoints {
+		if err := validHTTPURL(e.URL); err != nil {
+			errs = append(errs, fmt.Errorf("tron.endpoints[%d].url %w", i, err))
+		}
+	}
+	if t.RequestTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("tron.requestTimeout must be positive, got %s", t.RequestTimeout.Std()))
+	}
+	if t.ConfirmationsRequired < 1 {
+		errs = append(errs, fmt.Errorf("tron.confirmationsRequired must be at least 1, got %d", t.ConfirmationsRequired))
+	}
+	if err := wallet.ValidateAddress(t.USDTContract); err != nil {
+		errs = append(errs, fmt.Errorf("tron.usdtContract: %w", err))
+	}
+	if t.RateLimit.RequestsPerSecond <= 0 {
+		errs = append(errs, fmt.Errorf("tron.rateLimit.requestsPerSecond must be positive, got %g", t.RateLimit.RequestsPerSecond))
+	}
+	if t.RateLimit.Burst < 1 {
+		errs = append(errs, fmt.Errorf("tron.rateLimit.burst must be at least 1, got %d", t.RateLimit.Burst))
+	}
+	if t.Retry.MaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("tron.retry.maxAttempts must be at least 1, got %d", t.Retry.MaxAttempts))
+	}
+	if t.Retry.BaseDelay <= 0 {
+		errs = append(errs, fmt.Errorf("tron.retry.baseDelay must be positive, got %s", t.Retry.BaseDelay.Std()))
+	}
+	if t.Retry.MaxDelay < t.Retry.BaseDelay {
+		errs = append(errs, fmt.Errorf("tron.retry.maxDelay must be at least baseDelay, got %s", t.Retry.MaxDelay.Std()))
+	}
+	if t.DailyBudget < 0 {
+		errs = append(errs, fmt.Errorf("tron.dailyBudget must not be negative, got %d", t.DailyBudget))
+	}
+
+	return errs
+}
+
+// validHTTPURL requires an absolute http or https URL with a host.
+func validHTTPURL(value string) error {
+	if value == "" {
+		return fmt.Errorf("is required")
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("must be an absolute http(s) URL, got %q", value)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+func TestConfig_LoadConfig_TronSection(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yaml := `
+appPort: 8080
+database:
+  user: root
+  host: localhost
+  database: tpg
+  maxConnections: 10
+tron:
+  network: nile
+  fullNodeURL: https://nile.example.com
+  solidityNodeURL: https://nile-solidity.example.com
+  requestTimeout: 5s
+  confirmationsRequired: 20
+  rateLimit:
+    requestsPerSecond: 2.5
+    burst: 5
+  retry:
+    maxAttempts: 4
+    baseDelay: 250ms
+    maxDelay: 2s
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath))
+
+	assert.Equal(t, TronNile, cfg.Tron.Network)
+	assert.Equal(t, "https://nile.example.com", cfg.Tron.FullNodeURL)
+	assert.Equal(t, "https://nile-solidity.example.com", cfg.Tron.SolidityNodeURL)
+	assert.Equal(t, "https://nile.trongrid.io", cfg.Tron.EventServerURL)
+	assert.Equal(t, 5*time.Second, cfg.Tron.RequestTimeout.Std())
+	assert.Equal(t, 20, cfg.Tron.ConfirmationsRequired)
+	assert.Equal(t, TronRateLimitConfig{RequestsPerSecond: 2.5, Burst: 5}, cfg.Tron.RateLimit)
+	assert.Equal(t, TronRetryConfig{MaxAttempts: 4, BaseDelay: Duration(250 * time.Millisecond), MaxDelay: Duration(2 * time.Second)}, cfg.Tron.Retry)
+}
+
+func TestConfig_LoadConfig_TronDefaults(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yaml := `
+appPort: 8080
+database:
+  user: root
+  host: localhost
+  database: tpg
+  maxConnections: 10
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath))
+
+	assert.Equal(t, TronMainnet, cfg.Tron.Network)
+	assert.Equal(t, "https://api.trongrid.io", cfg.Tron.FullNodeURL)
+	assert.Equal(t, "https://api.trongrid.io", cfg.Tron.SolidityNodeURL)
+	assert.Equal(t, "https://api.trongrid.io", cfg.Tron.EventServerURL)
+	assert.Equal(t, DefaultTronRequestTimeout, cfg.Tron.RequestTimeout)
+	assert.Equal(t, DefaultTronConfirmationsRequired, cfg.Tron.ConfirmationsRequired)
+	assert.Equal(t, "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", cfg.Tron.USDTContract)
+	assert.Equal(t, float64(DefaultTronRequestsPerSecond), cfg.Tron.RateLimit.RequestsPerSecond)
+	assert.Equal(t, DefaultTronBurst, cfg.Tron.RateLimit.Burst)
+	assert.Equal(t, DefaultTronMaxAttempts, cfg.Tron.Retry.MaxAttempts)
+	assert.Equal(t, DefaultTronRetryBaseDelay, cfg.Tron.Retry.BaseDelay)
+	assert.Equal(t, DefaultTronRetryMaxDelay, cfg.Tron.Retry.MaxDelay)
+}
+
+func TestTronConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mutate  func(*TronConfig)
+		wantErr string
+	}{
+		{"valid", func(*TronConfig) {}, ""},
+		{"unknown network", func(tc *TronConfig) { tc.Network = "testnet" }, `tron.network must be one of mainnet, shasta, nile, got "testnet"`},
+		{"relative url", func(tc *TronConfig) { tc.FullNodeURL = "/wallet" }, "tron.fullNodeURL must be an absolute http(s) URL"},
+		{"bad scheme", func(tc *TronConfig) { tc.SolidityNodeURL = "grpc://node:50051" }, "tron.solidityNodeURL must be an absolute http(s) URL"},
+		{"unparseable url", func(tc *TronConfig) { tc.EventServerURL = "http://[::1" }, "tron.eventServerURL must be an absolute http(s) URL"},
+		{"zero timeout", func(tc *TronConfig) { tc.RequestTimeout = 0 }, "tron.requestTimeout must be positive"},
+		{"negative timeout", func(tc *TronConfig) { tc.RequestTimeout = Duration(-time.Second) }, "tron.requestTimeout must be positive"},
+		{"negative confirmations", func(tc *TronConfig) { tc.ConfirmationsRequired = -1 }, "tron.confirmationsRequired must be at least 1"},
+		{"bad usdt contract", func(tc *TronConfig) { tc.USDTContract = "0xdeadbeef" }, "tron.usdtContract: invalid tron address"},
+		{"zero rps", func(tc *TronConfig) { tc.RateLimit.RequestsPerSecond = -1 }, "tron.rateLimit.requestsPerSecond must be positive"},
+		{"zero burst", func(tc *TronConfig) { tc.RateLimit.Burst = -1 }, "tron.rateLimit.burst must be at least 1"},
+		{"zero attempts", func(tc *TronConfig) { tc.Retry.MaxAttempts = -1 }, "tron.retry.maxAttempts must be at least 1"},
+		{"negative base delay", func(tc *TronConfig) { tc.Retry.BaseDelay = Duration(-time.Second) }, "tron.retry.baseDelay must be positive"},
+		{"max below base", func(tc *TronConfig) { tc.Retry.MaxDelay = Duration(time.Millisecond) }, "tron.retry.maxDelay must be at least baseDelay"},
+		{"negative daily budget", func(tc *TronConfig) { tc.DailyBudget = -1 }, "tron.dailyBudget must not be negative"},
+		{"bad endpoint url", func(tc *TronConfig) {
+			tc.Endpoints = []TronEndpoint{{URL: "https://a.example.com"}, {URL: "node:8090"}}
+		}, "tron.endpoints[1].url must be an absolute http(s) URL"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.mutate(&cfg.Tron)
+
+			err := cfg.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
+
+func TestTronConfig_DefaultsPerNetwork(t *testing.T) {
+	for network, want := range map[string]string{
+		TronMainnet: "https://api.trongrid.io",
+		TronShasta:  "https://api.shasta.trongrid.io",
+		TronNile:    "https://nile.trongrid.io",
+	} {
+		tc := TronConfig{Network: network}
+		tc.applyDefaults()
+		assert.Equal(t, want, tc.FullNodeURL, network)
+		assert.NoError(t, wallet.ValidateAddress(tc.USDTContract), network)
+	}
+}
+
+func TestConfig_TronAPIKey(t *testing.T) {
+	t.Run("from default env", func(t *testing.T) {
+		t.Setenv(DefaultTronAPIKeyEnv, "env-key")
+		cfg := Config{Tron: TronConfig{APIKey: "file-key"}}
+		cfg.Hydrate()
+		assert.Equal(t, "env-key", cfg.TronAPIKey())
+	})
+
+	t.Run("from custom env", func(t *testing.T) {
+		t.Setenv("TPG_TRON_KEY", "custom-key")
+		cfg := Config{Tron: TronConfig{APIKeyEnv: "TPG_TRON_KEY"}}
+		cfg.Hydrate()
+		assert.Equal(t, "custom-key", cfg.TronAPIKey())
+	})
+
+	t.Run("falls back to file", func(t *testing.T) {
+		cfg := Config{Tron: TronConfig{APIKeyEnv: "TPG_UNSET_TRON_KEY", APIKey: "file-key"}}
+		cfg.Hydrate()
+		assert.Equal(t, "file-key", cfg.TronAPIKey())
+	})
+}
+
+func TestConfig_LoadConfig_TronEndpoints(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yaml := `
+appPort: 8080
+database:
+  user: root
+  host: localhost
+  database: tpg
+  maxConnections: 10
+tron:
+  endpoints:
+    - url: https://api.trongrid.io
+      apiKeyEnv: TPG_TRONGRID_KEY
+    - url: https://tron.example.com
+      apiKey: file-key
+    - url: http://localhost:8090
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+	t.Setenv("TPG_TRONGRID_KEY", "env-key")
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath))
+
+	endpoints := cfg.Tron.FullNodeEndpoints()
+	require.Len(t, endpoints, 3)
+	assert.Equal(t, "https://api.trongrid.io", endpoints[0].URL)
+	assert.Equal(t, "env-key", endpoints[0].Key())
+	assert.Equal(t, "file-key", endpoints[1].Key())
+	assert.Empty(t, endpoints[2].Key())
+}
+
+func TestTronConfig_FullNodeEndpoints_Default(t *testing.T) {
+	tc := TronConfig{FullNodeURL: "https://nile.example.com"}
+
+	assert.Equal(t, []TronEndpoint{{URL: "https://nile.example.com"}}, tc.FullNodeEndpoints())
+}
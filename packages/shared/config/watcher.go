@@ -0,0 +1,250 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Names of the sections callbacks registered with OnChange are keyed by.
+// Every top-level key of the config file is a section; these are the ones
+// something in the tree can apply without a restart.
+const (
+	SectionDebug        = "debug"
+	SectionLogging      = "logging"
+	SectionTron         = "tron"
+	SectionPayments     = "payments"
+	SectionBlockWatcher = "blockWatcher"
+	SectionSweeper      = "sweeper"
+	SectionRates        = "rates"
+	SectionWebhooks     = "webhooks"
+)
+
+// immutableSections name the listeners and the database pool, which are
+// only set up at startup. A reload keeps their old values.
+var immutableSections = map[string]bool{
+	"appPort":     true,
+	"healthPort":  true,
+	"metricsPort": true,
+	"database":    true,
+}
+
+type section struct {
+	name      string
+	index     int
+	immutable bool
+}
+
+func (s section) get(c *Config) any {
+	return reflect.ValueOf(c).Elem().Field(s.index).Interface()
+}
+
+// keep copies the old value into the reloaded config.
+func (s section) keep(dst, src *Config) {
+	reflect.ValueOf(dst).Elem().Field(s.index).Set(reflect.ValueOf(src).Elem().Field(s.index))
+}
+
+// sections lists every top-level key of Config by its yaml name, so a new
+// section is compared on reload without being registered here.
+var sections = configSections()
+
+func configSections() []section {
+	t := reflect.TypeOf(Config{})
+	var out []section
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if !f.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		out = append(out, section{name: name, index: i, immutable: immutableSections[name]})
+	}
+	return out
+}
+
+// ChangeFunc receives the config before and after a reload.
+type ChangeFunc func(old, updated *Config)
+
+// Watcher reloads the config file, and the TPG_ENV overlay next to it, on
+// SIGHUP or file change. A reload that fails to load or validate is rejected
+// and the previous config stays active. Changes to the database section and
+// the listener ports are logged and ignored; other sections are swapped in,
+// and those nothing registered OnChange for are logged as needing a restart.
+type Watcher struct {
+	path string
+	// files are the paths a reload reads: path and its overlay, if any.
+	files []string
+
+	mu        sync.RWMutex
+	current   *Config
+	callbacks map[string][]ChangeFunc
+}
+
+// NewWatcher watches path, starting from the already loaded current config.
+func NewWatcher(path string, current *Config) *Watcher {
+	files := []string{path}
+	if env := strings.TrimSpace(os.Getenv(EnvProfileVar)); env != "" {
+		files = append(files, OverlayPath(path, env))
+	}
+	return &Watcher{path: path, files: files, current: current, callbacks: make(map[string][]ChangeFunc)}
+}
+
+// Current returns the active config. Callers must not modify it.
+func (w *Watcher) Current() *Config {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// OnChange registers fn to run after a reload that changed section.
+func (w *Watcher) OnChange(section string, fn ChangeFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callbacks[section] = append(w.callbacks[section], fn)
+}
+
+// Reload re-reads and validates the file and swaps it in. It returns the
+// names of the sections that changed.
+func (w *Watcher) Reload() ([]string, error) {
+	var next Config
+	if err := next.LoadConfigForEnv(w.path); err != nil {
+		slog.Error("config reload rejected, keeping previous config", "path", w.path, "error", err)
+		return nil, fmt.Errorf("failed to reload config: %w", err)
+	}
+
+	w.mu.Lock()
+	old := w.current
+	var changed []string
+	var fns []ChangeFunc
+	for _, s := range sections {
+		if reflect.DeepEqual(s.get(old), s.get(&next)) {
+			continue
+		}
+		if s.immutable {
+			slog.Warn("config section changed but requires a restart, ignoring", "section", s.name)
+			s.keep(&next, old)
+			continue
+		}
+		changed = append(changed, s.name)
+		if len(w.callbacks[s.name]) == 0 {
+			slog.Warn("config section changed but is only read at startup, restart to apply", "section", s.name)
+		}
+		fns = append(fns, w.callbacks[s.name]...)
+	}
+	w.current = &next
+	w.mu.Unlock()
+
+	if len(changed) > 0 {
+		slog.Info("config reloaded", "path", w.path, "changed", changed)
+	}
+	for _, fn := range fns {
+		fn(old, &next)
+	}
+	return changed, nil
+}
+
+// Run reloads on every SIGHUP until ctx is done.
+func (w *Watcher) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			_, _ = w.Reload()
+		}
+	}
+}
+
+// Watch reloads on SIGHUP and on file change until ctx is done.
+func (w *Watcher) Watch(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx)
+	}()
+	err := w.WatchFile(ctx)
+	<-done
+	return err
+}
+
+// WatchFile reloads whenever a config file changes, until ctx is done.
+// Editors and Kubernetes replace the file rather than write it: a ConfigMap
+// mount swaps the ..data symlink the file resolves through, so no event ever
+// names the file itself. The directory is watched instead, and on any event
+// in it the files are stat'ed through their links and reloaded when one of
+// them is a different file, or was modified, since the last look.
+func (w *Watcher) WatchFile(ctx context.Context) error {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer fw.Close()
+
+	if err := fw.Add(filepath.Dir(w.path)); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", w.path, err)
+	}
+	last := w.statFiles()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-fw.Events:
+			if !ok {
+				return nil
+			}
+			next := w.statFiles()
+			if !filesChanged(last, next) {
+				continue
+			}
+			last = next
+			_, _ = w.Reload()
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("config file watcher error", "error", err)
+		}
+	}
+}
+
+// statFiles stats each config file through its symlinks; a missing file is
+// nil.
+func (w *Watcher) statFiles() []os.FileInfo {
+	infos := make([]os.FileInfo, len(w.files))
+	for i, path := range w.files {
+		if info, err := os.Stat(path); err == nil {
+			infos[i] = info
+		}
+	}
+	return infos
+}
+
+func filesChanged(old, updated []os.FileInfo) bool {
+	for i := range old {
+		a, b := old[i], updated[i]
+		if a == nil || b == nil {
+			if a != b {
+				return true
+			}
+			continue
+		}
+		if !os.SameFile(a, b) || !a.ModTime().Equal(b.ModTime()) || a.Size() != b.Size() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,290 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const watcherYAML = `
+appPort: 8080
+database:
+  user: root
+  host: localhost
+  database: tpg
+  maxConnections: 10
+payments:
+  defaultExpiry: 30m
+`
+
+func newTestWatcher(t *testing.T) (*Watcher, string) {
+	t.Helper()
+	path := writeConfigFile(t, t.TempDir(), "config.yaml", watcherYAML)
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(path))
+	return NewWatcher(path, &cfg), path
+}
+
+func TestWatcher_BadReloadThenGoodReload(t *testing.T) {
+	w, path := newTestWatcher(t)
+	initial := w.Current()
+
+	var paymentsCalls, tronCalls int
+	w.OnChange(SectionPayments, func(old, updated *Config) {
+		paymentsCalls++
+		assert.Equal(t, 30*time.Minute, old.Payments.DefaultExpiry.Std())
+		assert.Equal(t, 45*time.Minute, updated.Payments.DefaultExpiry.Std())
+	})
+	w.OnChange(SectionTron, func(old, updated *Config) { tronCalls++ })
+
+	// A file that fails validation is rejected and the old config stays.
+	require.NoError(t, os.WriteFile(path, []byte(watcherYAML+"  maxActivePerAccount: -1\n"), 0644))
+	changed, err := w.Reload()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "payments.maxActivePerAccount")
+	assert.Nil(t, changed)
+	assert.Same(t, initial, w.Current())
+	assert.Zero(t, paymentsCalls)
+
+	// A good file is swapped in and only the changed section is notified.
+	good := `
+appPort: 8080
+database:
+  user: root
+  host: localhost
+  database: tpg
+  maxConnections: 10
+payments:
+  defaultExpiry: 45m
+`
+	require.NoError(t, os.WriteFile(path, []byte(good), 0644))
+	changed, err = w.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, []string{SectionPayments}, changed)
+	assert.Equal(t, 45*time.Minute, w.Current().Payments.DefaultExpiry.Std())
+	assert.Equal(t, 1, paymentsCalls)
+	assert.Zero(t, tronCalls)
+}
+
+func TestWatcher_NoChange(t *testing.T) {
+	w, _ := newTestWatcher(t)
+	called := false
+	w.OnChange(SectionPayments, func(*Config, *Config) { called = true })
+
+	changed, err := w.Reload()
+
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+	assert.False(t, called)
+}
+
+func TestWatcher_ImmutableSectionsIgnored(t *testing.T) {
+	w, path := newTestWatcher(t)
+
+	moved := `
+debug: true
+appPort: 9090
+database:
+  user: root
+  host: crdb.elsewhere
+  database: tpg
+  maxConnections: 10
+payments:
+  defaultExpiry: 30m
+`
+	require.NoError(t, os.WriteFile(path, []byte(moved), 0644))
+	changed, err := w.Reload()
+
+	require.NoError(t, err)
+	// Debug also raises the default log level.
+	assert.Equal(t, []string{SectionDebug, SectionLogging}, changed)
+	assert.True(t, w.Current().Debug)
+	assert.Equal(t, 8080, w.Current().AppPort)
+	assert.Equal(t, "localhost", w.Current().DatabaseConfig.Host)
+}
+
+func TestWatcher_RunReloadsOnSIGHUP(t *testing.T) {
+	w, path := newTestWatcher(t)
+	reloaded := make(chan struct{}, 1)
+	w.OnChange(SectionDebug, func(*Config, *Config) {
+		select {
+		case reloaded <- struct{}{}:
+		default:
+		}
+	})
+
+	// Keep SIGHUP from killing the test binary before Run subscribes.
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGHUP)
+	defer signal.Stop(guard)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	require.NoError(t, os.WriteFile(path, []byte("debug: true\n"+watcherYAML), 0644))
+
+	// Run registers its signal handler asynchronously; keep signalling until
+	// the reload lands.
+	deadline := time.After(5 * time.Second)
+	for {
+		require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGHUP))
+		select {
+		case <-reloaded:
+			assert.True(t, w.Current().Debug)
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("config not reloaded on SIGHUP")
+		}
+	}
+}
+
+func TestWatcher_WatchFile(t *testing.T) {
+	w, path := newTestWatcher(t)
+	reloaded := make(chan struct{}, 1)
+	w.OnChange(SectionDebug, func(*Config, *Config) {
+		select {
+		case reloaded <- struct{}{}:
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- w.WatchFile(ctx) }()
+	defer func() {
+		cancel()
+		assert.NoError(t, <-errCh)
+	}()
+
+	// Replace the file the way editors do: write a sibling and rename it.
+	deadline := time.After(5 * time.Second)
+	for {
+		tmp := filepath.Join(filepath.Dir(path), ".config.yaml.tmp")
+		require.NoError(t, os.WriteFile(tmp, []byte("debug: true\n"+watcherYAML), 0644))
+		require.NoError(t, os.Rename(tmp, path))
+		select {
+		case <-reloaded:
+			assert.True(t, w.Current().Debug)
+			return
+		case <-time.After(100 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("config not reloaded on file change")
+		}
+	}
+}
+
+func TestWatcher_SectionsCoverConfig(t *testing.T) {
+	var names []string
+	for _, s := range sections {
+		names = append(names, s.name)
+	}
+
+	assert.Contains(t, names, SectionLogging)
+	assert.Contains(t, names, "grpc")
+	assert.Contains(t, names, "fees")
+	assert.NotContains(t, names, "mode")
+	// Every field but the unexported mode is a section.
+	assert.Len(t, names, reflect.TypeOf(Config{}).NumField()-1)
+}
+
+func TestWatcher_ReloadsSectionWithoutCallback(t *testing.T) {
+	w, path := newTestWatcher(t)
+
+	require.NoError(t, os.WriteFile(path, []byte(watcherYAML+"fees:\n  percent: \"1.5\"\n"), 0644))
+	changed, err := w.Reload()
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fees"}, changed)
+	assert.Equal(t, "1.5", w.Current().Fees.Percent)
+}
+
+func TestWatcher_WatchFileConfigMapSwap(t *testing.T) {
+	// Lay the directory out the way the kubelet mounts a ConfigMap:
+	// config.yaml -> ..data/config.yaml, ..data -> ..<version>.
+	dir := t.TempDir()
+	version := 0
+	publish := func(contents string) {
+		version++
+		v := fmt.Sprintf("..v%d", version)
+		require.NoError(t, os.Mkdir(filepath.Join(dir, v), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, v, "config.yaml"), []byte(contents), 0644))
+		require.NoError(t, os.Symlink(v, filepath.Join(dir, "..data_tmp")))
+		require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+	}
+	publish(watcherYAML)
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.Symlink(filepath.Join("..data", "config.yaml"), path))
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(path))
+	w := NewWatcher(path, &cfg)
+	reloaded := make(chan struct{}, 1)
+	w.OnChange(SectionDebug, func(*Config, *Config) {
+		select {
+		case reloaded <- struct{}{}:
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- w.WatchFile(ctx) }()
+	defer func() {
+		cancel()
+		assert.NoError(t, <-errCh)
+	}()
+
+	// No event ever names config.yaml; only ..data is swapped.
+	deadline := time.After(5 * time.Second)
+	for {
+		publish("debug: true\n" + watcherYAML)
+		select {
+		case <-reloaded:
+			assert.True(t, w.Current().Debug)
+			return
+		case <-time.After(100 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("config not reloaded on ConfigMap update")
+		}
+	}
+}
+
+func TestWatcher_ReloadAppliesOverlay(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "config.yaml", watcherYAML)
+	t.Setenv(EnvProfileVar, "staging")
+	overlay := writeConfigFile(t, dir, "config.staging.yaml", "payments:\n  defaultExpiry: 45m\n")
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfigForEnv(path))
+	w := NewWatcher(path, &cfg)
+	assert.Equal(t, []string{path, overlay}, w.files)
+
+	require.NoError(t, os.WriteFile(overlay, []byte("payments:\n  defaultExpiry: 50m\n"), 0644))
+	changed, err := w.Reload()
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{SectionPayments}, changed)
+	assert.Equal(t, 50*time.Minute, w.Current().Payments.DefaultExpiry.Std())
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Defaults applied to an unset webhooks section.
+const (
+	DefaultWebhookPollInterval     = Duration(5 * time.Second)
+	DefaultWebhookBatchSize        = 50
+	DefaultWebhookRequestTimeout   = Duration(10 * time.Second)
+	DefaultWebhookMaxAttempts      = 6
+	DefaultWebhookConcurrency      = 16
+	DefaultWebhookMaxConnsPerHost  = 4
+	DefaultWebhookMaxIdleConns     = 100
+	DefaultWebhookRotationOverlap  = Duration(24 * time.Hour)
+	DefaultWebhookBreakerThreshold = 5
+	DefaultWebhookBreakerCooldown  = Duration(time.Minute)
+	DefaultWebhookClaimLease       = Duration(5 * time.Minute)
+)
+
+// MaxWebhookBatchSize caps how many deliveries one poll claims.
+const MaxWebhookBatchSize = 500
+
+// WebhooksConfig configures the worker delivering merchant webhooks.
+type WebhooksConfig struct {
+	PollInterval Duration `yaml:"pollInterval" json:"pollInterval"`
+	BatchSize    int      `yaml:"batchSize" json:"batchSize"`
+	// RequestTimeout bounds one delivery attempt, including reading the
+	// response.
+	RequestTimeout Duration `yaml:"requestTimeout" json:"requestTimeout"`
+	// MaxAttempts is how many times a delivery is tried before it is marked
+	// FAILED.
+	MaxAttempts int `yaml:"maxAttempts" json:"maxAttempts"`
+	// Concurrency is how many deliveries of a batch are sent at once.
+	Concurrency int `yaml:"concurrency" json:"concurrency"`
+	// MaxConnsPerHost bounds the deliveries sent to one endpoint host at
+	// once, and the connections kept open to it.
+	MaxConnsPerHost int `yaml:"maxConnsPerHost" json:"maxConnsPerHost"`
+	MaxIdleConns    int `yaml:"maxIdleConns" json:"maxIdleConns"`
+	// MaxRedirects is how many redirects a delivery follows; 0, the
+	// default, treats a redirect as a failed attempt.
+	MaxRedirects int `yaml:"maxRedirects" json:"maxRedirects"`
+	// RotationOverlap is how long after an endpoint's secret is rotated
+	// deliveries are still signed with the old one as well, giving the
+	// merchant time to switch its receiver over.
+	RotationOverlap Duration `yaml:"rotationOverlap" json:"rotationOverlap"`
+	// AllowPrivateAddresses lets deliveries reach loopback, private and
+	// link-local addresses. It is for local development only; left off, a
+	// merchant cannot point a webhook at the gateway's own network.
+	AllowPrivateAddresses bool `yaml:"allowPrivateAddresses" json:"allowPrivateAddresses"`
+	// BreakerThreshold is how many attempts in a row must fail against an
+	// endpoint host before its deliveries are held back for BreakerCooldown;
+	// after that one is let through to probe the host.
+	BreakerThreshold int      `yaml:"breakerThreshold" json:"breakerThreshold"`
+	BreakerCooldown  Duration `yaml:"breakerCooldown" json:"breakerCooldown"`
+	// ClaimLease is how long a batch claimed by one worker is hidden from
+	// the others. A delivery whose attempt a crashed worker never recorded
+	// is sent again once it lapses, so it must outlast a batch; see
+	// BatchDuration. Left unset it is DefaultWebhookClaimLease, or
+	// BatchDuration when that is longer.
+	ClaimLease Duration `yaml:"claimLease" json:"claimLease"`
+}
+
+// BatchDuration is how long a batch takes to send when every request times
+// out. That is when all its deliveries go to one host, whose lanes, at most
+// MaxConnsPerHost and Concurrency of them, each send one after another.
+func (w WebhooksConfig) BatchDuration() time.Duration {
+	lanes := max(min(w.MaxConnsPerHost, w.Concurrency), 1)
+	rounds := (w.BatchSize + lanes - 1) / lanes
+	return time.Duration(rounds) * w.RequestTimeout.Std()
+}
+
+func (w *WebhooksConfig) applyDefaults() {
+	if w.PollInterval == 0 {
+		w.PollInterval = DefaultWebhookPollInterval
+	}
+	if w.BatchSize == 0 {
+		w.BatchSize = DefaultWebhookBatchSize
+	}
+	if w.RequestTimeout == 0 {
+		w.RequestTimeout = DefaultWebhookRequestTimeout
+	}
+	if w.MaxAttempts == 0 {
+		w.MaxAttempts = DefaultWebhookMaxAttempts
+	}
+	if w.Concurrency == 0 {
+		w.Concurrency = DefaultWebhookConcurrency
+	}
+	if w.MaxConnsPerHost == 0 {
+		w.MaxConnsPerHost = DefaultWebhookMaxConnsPerHost
+	}
+	if w.MaxIdleConns == 0 {
+		w.MaxIdleConns = DefaultWebhookMaxIdleConns
+	}
+	if w.RotationOverlap == 0 {
+		w.RotationOverlap = DefaultWebhookRotationOverlap
+	}
+	if w.BreakerThreshold == 0 {
+		w.BreakerThreshold = DefaultWebhookBreakerThreshold
+	}
+	if w.BreakerCooldown == 0 {
+		w.BreakerCooldown = DefaultWebhookBreakerCooldown
+	}
+	if w.ClaimLease == 0 {
+		w.ClaimLease = max(DefaultWebhookClaimLease, Duration(w.BatchDuration()))
+	}
+}
+
+func (w WebhooksConfig) validate() []error {
+	var errs []error
+
+	if w.PollInterval <= 0 {
+		errs = append(errs, fmt.Errorf("webhooks.pollInterval must be positive, got %s", w.PollInterval.Std()))
+	}
+	if w.BatchSize < 1 || w.BatchSize > MaxWebhookBatchSize {
+		errs = append(errs, fmt.Errorf("webhooks.batchSize must be between 1 and %d, got %d", MaxWebhookBatchSize, w.BatchSize))
+	}
+	if w.RequestTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("webhooks.requestTimeout must be positive, got %s", w.RequestTimeout.Std()))
+	}
+	if w.MaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("webhooks.maxAttempts must be at least 1, got %d", w.MaxAttempts))
+	}
+	if w.Concurrency < 1 {
+		errs = append(errs, fmt.Errorf("webhooks.concurrency must be at least 1, got %d", w.Concurrency))
+	}
+	if w.MaxConnsPerHost < 1 {
+		errs = append(errs, fmt.Errorf("webhooks.maxConnsPerHost must be at least 1, got %d", w.MaxConnsPerHost))
+	}
+	if w.MaxIdleConns < 0 {
+		errs = append(errs, fmt.Errorf("webhooks.maxIdleConns must not be negative, got %d", w.MaxIdleConns))
+	}
+	if w.MaxRedirects < 0 {
+		errs = append(errs, fmt.Errorf("webhooks.maxRedirects must not be negative, got %d", w.MaxRedirects))
+	}
+	if w.RotationOverlap <= 0 {
+		errs = append(errs, fmt.Errorf("webhooks.rotationOverlap must be positive, got %s", w.RotationOverlap.Std()))
+	}
+	if w.BreakerThreshold < 1 {
+		errs = append(errs, fmt.Errorf("webhooks.breakerThreshold must be at least 1, got %d", w.BreakerThreshold))
+	}
+	if w.BreakerCooldown <= 0 {
+		errs = append(errs, fmt.Errorf("webhooks.breakerCooldown must be positive, got %s", w.BreakerCooldown.Std()))
+	}
+	if batch := w.BatchDuration(); w.ClaimLease.Std() < batch {
+		errs = append(errs, fmt.Errorf("webhooks.claimLease must be at least %s, the time a batch takes when every request times out, got %s", batch, w.ClaimLease.Std()))
+	}
+
+	return errs
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_LoadConfig_WebhooksSection(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	yaml := `
+appPort: 8080
+database:
+  user: root
+  host: localhost
+  database: tpg
+  maxConnections: 10
+webhooks:
+  pollInterval: 2s
+  batchSize: 20
+  requestTimeout: 3s
+  maxAttempts: 4
+  concurrency: 8
+  maxConnsPerHost: 2
+  maxRedirects: 1
+  rotationOverlap: 1h
+  allowPrivateAddresses: true
+  breakerThreshold: 3
+  breakerCooldown: 30s
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+
+	var cfg Config
+	require.NoError(t, cfg.LoadConfig(configPath))
+
+	w := cfg.Webhooks
+	assert.Equal(t, 2*time.Second, w.PollInterval.Std())
+	assert.Equal(t, 20, w.BatchSize)
+	assert.Equal(t, 3*time.Second, w.RequestTimeout.Std())
+	assert.Equal(t, 4, w.MaxAttempts)
+	assert.Equal(t, 8, w.Concurrency)
+	assert.Equal(t, 2, w.MaxConnsPerHost)
+	assert.Equal(t, DefaultWebhookMaxIdleConns, w.MaxIdleConns)
+	assert.Equal(t, 1, w.MaxRedirects)
+	assert.Equal(t, time.Hour, w.RotationOverlap.Std())
+	assert.True(t, w.AllowPrivateAddresses)
+	assert.Equal(t, 3, w.BreakerThreshold)
+	assert.Equal(t, 30*time.Second, w.BreakerCooldown.Std())
+}
+
+func TestConfig_WebhooksDefaults(t *testing.T) {
+	cfg := validConfig()
+
+	w := cfg.Webhooks
+	assert.Equal(t, DefaultWebhookPollInterval, w.PollInterval)
+	assert.Equal(t, DefaultWebhookBatchSize, w.BatchSize)
+	assert.Equal(t, DefaultWebhookRequestTimeout, w.RequestTimeout)
+	assert.Equal(t, DefaultWebhookMaxAttempts, w.MaxAttempts)
+	assert.Equal(t, DefaultWebhookConcurrency, w.Concurrency)
+	assert.Equal(t, DefaultWebhookMaxConnsPerHost, w.MaxConnsPerHost)
+	assert.Zero(t, w.MaxRedirects, "redirects are not followed by default")
+	assert.Equal(t, DefaultWebhookRotationOverlap, w.RotationOverlap)
+	assert.False(t, w.AllowPrivateAddresses, "private addresses are blocked by default")
+	assert.Equal(t, DefaultWebhookBreakerThreshold, w.BreakerThreshold)
+	assert.Equal(t, DefaultWebhookBreakerCooldown, w.BreakerCooldown)
+	assert.Equal(t, DefaultWebhookClaimLease, w.ClaimLease)
+}
+
+func TestConfig_WebhooksClaimLeaseCoversBatch(t *testing.T) {
+	cfg := validConfig()
+	cfg.Webhooks.BatchSize = MaxWebhookBatchSize
+	cfg.Webhooks.ClaimLease = 0
+	cfg.ApplyDefaults()
+
+	assert.Equal(t, 125*DefaultWebhookRequestTimeout.Std(), cfg.Webhooks.BatchDuration())
+	assert.Equal(t, Duration(cfg.Webhooks.BatchDuration()), cfg.Webhooks.ClaimLease, "an unset lease outlasts the batch")
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestWebhooksConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mutate  func(*WebhooksConfig)
+		wantErr string
+	}{
+		{"valid", func(*WebhooksConfig) {}, ""},
+		{"negative poll interval", func(w *WebhooksConfig) { w.PollInterval = Duration(-time.Second) }, "webhooks.pollInterval must be positive"},
+		{"batch too large", func(w *WebhooksConfig) { w.BatchSize = MaxWebhookBatchSize + 1 }, "webhooks.batchSize must be between 1 and 500"},
+		{"negative timeout", func(w *WebhooksConfig) { w.RequestTimeout = Duration(-time.Second) }, "webhooks.requestTimeout must be positive"},
+		{"negative attempts", func(w *WebhooksConfig) { w.MaxAttempts = -1 }, "webhooks.maxAttempts must be at least 1"},
+		{"no concurrency", func(w *WebhooksConfig) { w.Concurrency = -1 }, "webhooks.concurrency must be at least 1"},
+		{"negative conns per host", func(w *WebhooksConfig) { w.MaxConnsPerHost = -1 }, "webhooks.maxConnsPerHost must be at least 1"},
+		{"negative idle conns", func(w *WebhooksConfig) { w.MaxIdleConns = -1 }, "webhooks.maxIdleConns must not be negative"},
+		{"negative redirects", func(w *WebhooksConfig) { w.MaxRedirects = -1 }, "webhooks.maxRedirects must not be negative"},
+		{"negative rotation overlap", func(w *WebhooksConfig) { w.RotationOverlap = Duration(-time.Hour) }, "webhooks.rotationOverlap must be positive"},
+		{"negative breaker threshold", func(w *WebhooksConfig) { w.BreakerThreshold = -1 }, "webhooks.breakerThreshold must be at least 1"},
+		{"negative breaker cooldown", func(w *WebhooksConfig) { w.BreakerCooldown = Duration(-time.Second) }, "webhooks.breakerCooldown must be positive"},
+		{"claim lease shorter than a request", func(w *WebhooksConfig) { w.ClaimLease = Duration(time.Second) }, "webhooks.claimLease must be at least 2m10s"},
+		{"claim lease shorter than a batch", func(w *WebhooksConfig) {
+			w.BatchSize, w.MaxConnsPerHost, w.ClaimLease = MaxWebhookBatchSize, 4, Duration(5*time.Minute)
+		}, "webhooks.claimLease must be at least 20m50s"},
+		{"concurrency below conns per host", func(w *WebhooksConfig) {
+			w.Concurrency, w.ClaimLease = 1, Duration(5*time.Minute)
+		}, "webhooks.claimLease must be at least 8m20s"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.mutate(&cfg.Webhooks)
+
+			err := cfg.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
@@ -3,44 +3,152 @@ package db
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
 )
 
-func DbConnect(ctx context.Context, cfg *config.Config) (*pgxpool.Pool, error) {
-	userInfo := url.UserPassword(cfg.DatabaseConfig.User, cfg.DatabaseConfig.Password)
+func DbConnect(ctx context.Context, cfg *config.Config, opts ...Option) (*pgxpool.Pool, error) {
+	poolCfg, err := buildPoolConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		opt(poolCfg)
+	}
+	return connect(ctx, poolCfg)
+}
 
-	dbURL := url.URL{
-		Scheme:   "postgres",
-		User:     userInfo,
-		Host:     fmt.Sprintf("%s:%d", cfg.DatabaseConfig.Host, cfg.DatabaseConfig.Port),
-		Path:     cfg.DatabaseConfig.Database,
-		RawQuery: "sslmode=verify-full",
+// buildPoolConfig turns the database section into a pgxpool.Config. Errors
+// here are configuration mistakes and are never worth retrying.
+func buildPoolConfig(cfg *config.Config) (*pgxpool.Config, error) {
+	password, err := cfg.DatabasePassword()
+	if err != nil {
+		return nil, err
 	}
 
-	dsn := dbURL.String()
+	dsn, err := buildDSN(cfg.DatabaseConfig, password)
+	if err != nil {
+		return nil, err
+	}
 
 	poolCfg, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse pgx pool config: %w", err)
 	}
 
-	// Set pool parameters
-	poolCfg.MaxConns = int32(cfg.DatabaseConfig.MaxConnections)
-	poolCfg.MinConns = 2
-	poolCfg.MaxConnLifetime = time.Hour
+	if err := applyPoolSettings(poolCfg, cfg.DatabaseConfig); err != nil {
+		return nil, err
+	}
+
+	return poolCfg, nil
+}
+
+// Pool defaults used when the corresponding DatabaseConfig field is zero.
+const (
+	defaultMinConns          = 2
+	defaultMaxConnLifetime   = time.Hour
+	defaultMaxConnIdleTime   = 30 * time.Minute
+	defaultHealthCheckPeriod = time.Minute
+)
+
+func applyPoolSettings(poolCfg *pgxpool.Config, dbCfg config.DatabaseConfig) error {
+	poolCfg.MaxConns = int32(dbCfg.MaxConnections)
+
+	minConns := dbCfg.MinConnections
+	if minConns == 0 {
+		minConns = defaultMinConns
+		if dbCfg.MaxConnections > 0 && dbCfg.MaxConnections < minConns {
+			minConns = dbCfg.MaxConnections
+		}
+	}
+	if minConns < 0 {
+		return fmt.Errorf("minConnections must not be negative, got %d", minConns)
+	}
+	if dbCfg.MaxConnections > 0 && minConns > dbCfg.MaxConnections {
+		return fmt.Errorf("minConnections (%d) must not exceed maxConnections (%d)", minConns, dbCfg.MaxConnections)
+	}
+	poolCfg.MinConns = int32(minConns)
+
+	var err error
+	if poolCfg.MaxConnLifetime, err = parsePoolDuration("maxConnLifetime", dbCfg.MaxConnLifetime, defaultMaxConnLifetime); err != nil {
+		return err
+	}
+	if poolCfg.MaxConnIdleTime, err = parsePoolDuration("maxConnIdleTime", dbCfg.MaxConnIdleTime, defaultMaxConnIdleTime); err != nil {
+		return err
+	}
+	if poolCfg.HealthCheckPeriod, err = parsePoolDuration("healthCheckPeriod", dbCfg.HealthCheckPeriod, defaultHealthCheckPeriod); err != nil {
+		return err
+	}
+
+	// The repository bounds queries on the client side; statement_timeout
+	// stops the server too when a client goes away without cancelling.
+	statementTimeout, err := parsePoolDuration("statementTimeout", dbCfg.StatementTimeout, config.DefaultStatementTimeout.Std())
+	if err != nil {
+		return err
+	}
+	poolCfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(statementTimeout.Milliseconds(), 10)
+	return nil
+}
 
+func parsePoolDuration(field string, value config.Duration, def time.Duration) (time.Duration, error) {
+	if value == 0 {
+		return def, nil
+	}
+	if value < 0 {
+		return 0, fmt.Errorf("%s must be positive, got %s", field, value.Std())
+	}
+	return value.Std(), nil
+}
+
+// connect opens the pool and verifies it with a ping.
+func connect(ctx context.Context, poolCfg *pgxpool.Config) (*pgxpool.Pool, error) {
 	// Initialize pool using the parsed config
 	dbpool, err := pgxpool.NewWithConfig(ctx, poolCfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create pgx pool: %w", err)
 	}
 	if err := dbpool.Ping(ctx); err != nil {
+		dbpool.Close()
 		return nil, fmt.Errorf("database connection failed: %w", err)
 	}
 
 	return dbpool, nil
 }
+
+// buildDSN assembles the postgres URL, including the TLS query parameters.
+// Certificate paths are only passed along when TLS is in use.
+func buildDSN(dbCfg config.DatabaseConfig, password string) (string, error) {
+	sslMode, err := dbCfg.EffectiveSSLMode()
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("sslmode", sslMode)
+	if sslMode != config.SSLModeDisable {
+		if dbCfg.SSLRootCert != "" {
+			query.Set("sslrootcert", dbCfg.SSLRootCert)
+		}
+		if dbCfg.SSLCert != "" {
+			query.Set("sslcert", dbCfg.SSLCert)
+		}
+		if dbCfg.SSLKey != "" {
+			query.Set("sslkey", dbCfg.SSLKey)
+		}
+	}
+
+	dbURL := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(dbCfg.User, password),
+		Host:     net.JoinHostPort(dbCfg.Host, strconv.Itoa(dbCfg.EffectivePort())),
+		Path:     dbCfg.Database,
+		RawQuery: query.Encode(),
+	}
+
+	return dbURL.String(), nil
+}
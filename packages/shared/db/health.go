@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultHealthCheckTimeout bounds HealthCheck when ctx has no earlier deadline.
+const DefaultHealthCheckTimeout = 2 * time.Second
+
+// Pinger is the subset of *pgxpool.Pool used by HealthCheck.
+type Pinger interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// statReader is the subset of *pgxpool.Stat mapped into Stats.
+type statReader interface {
+	TotalConns() int32
+	IdleConns() int32
+	AcquiredConns() int32
+	MaxConns() int32
+	AcquireCount() int64
+	AcquireDuration() time.Duration
+}
+
+var (
+	_ Pinger     = (*pgxpool.Pool)(nil)
+	_ statReader = (*pgxpool.Stat)(nil)
+)
+
+// Stats is a plain snapshot of pool usage so callers don't depend on pgxpool.
+type Stats struct {
+	TotalConns      int32
+	IdleConns       int32
+	AcquiredConns   int32
+	MaxConns        int32
+	AcquireCount    int64
+	AcquireDuration time.Duration
+}
+
+// HealthCheck runs SELECT 1 with a bounded timeout, suitable for /readyz.
+func HealthCheck(ctx context.Context, pool Pinger) error {
+	ctx, cancel := context.WithTimeout(ctx, DefaultHealthCheckTimeout)
+	defer cancel()
+
+	var one int
+	if err := pool.QueryRow(ctx, "SELECT 1").Scan(&one); err != nil {
+		return fmt.Errorf("database health check failed: %w", err)
+	}
+	return nil
+}
+
+// PoolStats maps pgxpool.Stat into Stats.
+func PoolStats(pool *pgxpool.Pool) Stats {
+	return statsFrom(pool.Stat())
+}
+
+func statsFrom(s statReader) Stats {
+	return Stats{
+		TotalConns:      s.TotalConns(),
+		IdleConns:       s.IdleConns(),
+		AcquiredConns:   s.AcquiredConns(),
+		MaxConns:        s.MaxConns(),
+		AcquireCount:    s.AcquireCount(),
+		AcquireDuration: s.AcquireDuration(),
+	}
+}
+
+// StatsReporter samples a pool on an interval and hands each snapshot to a
+// callback, e.g. to update metrics gauges.
+type StatsReporter struct {
+	sample   func() Stats
+	interval time.Duration
+	report   func(Stats)
+}
+
+// NewStatsReporter reports snapshots taken by sample.
+func NewStatsReporter(sample func() Stats, interval time.Duration, report func(Stats)) *StatsReporter {
+	return &StatsReporter{sample: sample, interval: interval, report: report}
+}
+
+// NewPoolStatsReporter reports PoolStats(pool).
+func NewPoolStatsReporter(pool *pgxpool.Pool, interval time.Duration, report func(Stats)) *StatsReporter {
+	return NewStatsReporter(func() Stats { return PoolStats(pool) }, interval, report)
+}
+
+// Run reports once immediately and then every interval until ctx is done.
+func (r *StatsReporter) Run(ctx context.Context) {
+	if r.interval <= 0 {
+		slog.Warn("stats reporter disabled: non-positive interval", "interval", r.interval)
+		return
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	r.report(r.sample())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.report(r.sample())
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubRow struct {
+	err error
+}
+
+func (r stubRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	*dest[0].(*int) = 1
+	return nil
+}
+
+type stubPinger struct {
+	err      error
+	deadline time.Time
+}
+
+func (p *stubPinger) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	p.deadline, _ = ctx.Deadline()
+	return stubRow{err: p.err}
+}
+
+type stubStat struct{}
+
+func (stubStat) TotalConns() int32              { return 10 }
+func (stubStat) IdleConns() int32               { return 7 }
+func (stubStat) AcquiredConns() int32           { return 3 }
+func (stubStat) MaxConns() int32                { return 25 }
+func (stubStat) AcquireCount() int64            { return 1234 }
+func (stubStat) AcquireDuration() time.Duration { return 5 * time.Second }
+
+func TestHealthCheck_OK(t *testing.T) {
+	pinger := &stubPinger{}
+
+	err := HealthCheck(context.Background(), pinger)
+
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(DefaultHealthCheckTimeout), pinger.deadline, time.Second)
+}
+
+func TestHealthCheck_Error(t *testing.T) {
+	pinger := &stubPinger{err: errors.New("connection reset")}
+
+	err := HealthCheck(context.Background(), pinger)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "database health check failed")
+	assert.Contains(t, err.Error(), "connection reset")
+}
+
+func TestHealthCheck_KeepsShorterDeadline(t *testing.T) {
+	pinger := &stubPinger{}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	require.NoError(t, HealthCheck(ctx, pinger))
+	assert.WithinDuration(t, time.Now().Add(100*time.Millisecond), pinger.deadline, 100*time.Millisecond)
+}
+
+func TestStatsFrom(t *testing.T) {
+	stats := statsFrom(stubStat{})
+
+	assert.Equal(t, Stats{
+		TotalConns:      10,
+		IdleConns:       7,
+		AcquiredConns:   3,
+		MaxConns:        25,
+		AcquireCount:    1234,
+		AcquireDuration: 5 * time.Second,
+	}, stats)
+}
+
+func TestStatsReporter_ReportsUntilCancelled(t *testing.T) {
+	var reports atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+
+	reporter := NewStatsReporter(func() Stats { return statsFrom(stubStat{}) }, 10*time.Millisecond, func(s Stats) {
+		assert.Equal(t, int32(10), s.TotalConns)
+		if reports.Add(1) == 3 {
+			cancel()
+		}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		reporter.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("reporter did not stop after cancel")
+	}
+	assert.GreaterOrEqual(t, reports.Load(), int32(3))
+}
+
+func TestStatsReporter_NonPositiveInterval(t *testing.T) {
+	called := false
+	reporter := NewStatsReporter(func() Stats { return Stats{} }, 0, func(Stats) { called = true })
+
+	reporter.Run(context.Background())
+
+	assert.False(t, called)
+}
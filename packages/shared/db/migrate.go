@@ -0,0 +1,410 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const createSchemaMigrations = `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version INT8 PRIMARY KEY,
+  name STRING NOT NULL,
+  applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+
+// describeSchemaMigrations lists the columns of schema_migrations, none when
+// no migration has run yet. Its breaking column comes with migration 046.
+const describeSchemaMigrations = `SELECT column_name FROM information_schema.columns
+WHERE table_schema = current_schema() AND table_name = 'schema_migrations'`
+
+// downMarker starts the part of a migration file that reverts it.
+const downMarker = "-- migrate:down"
+
+// breakingMarker is a line of a migration's up section that marks it as
+// breaking binaries built before it, e.g. because it drops or renames a
+// column they read. It is recorded in schema_migrations, so an older binary
+// that does not embed the migration can still tell; see CheckSchema.
+const breakingMarker = "-- migrate:breaking"
+
+// ErrIrreversible is returned when a migration to roll back has no
+// "-- migrate:down" section.
+var ErrIrreversible = errors.New("migration cannot be rolled back")
+
+// Errors returned by CheckSchema.
+var (
+	// ErrSchemaBehind means the database lacks migrations the binary
+	// embeds.
+	ErrSchemaBehind = errors.New("database schema is behind this binary")
+	// ErrSchemaIncompatible means the database has a breaking migration
+	// the binary does not know.
+	ErrSchemaIncompatible = errors.New("database schema is incompatible with this binary")
+)
+
+// Migration is a single embedded .sql file. Version is parsed from the
+// numeric filename prefix, e.g. 003_payments.sql has version 3. SQL is the
+// file up to its "-- migrate:down" line, if any, and Down what follows it.
+// Breaking is set by a "-- migrate:breaking" line in the SQL.
+type Migration struct {
+	Version  int64
+	Name     string
+	SQL      string
+	Down     string
+	Breaking bool
+}
+
+// MigrationStatus splits the embedded migrations into the ones recorded in
+// schema_migrations and the ones still waiting to be applied.
+type MigrationStatus struct {
+	Applied []Migration
+	Pending []Migration
+}
+
+// migrationDB is the subset of *pgxpool.Pool the runner needs.
+type migrationDB interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+var _ migrationDB = (*pgxpool.Pool)(nil)
+
+// Migrate applies every pending embedded migration in version order.
+func Migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	return migrateTo(ctx, pool, -1)
+}
+
+// MigrateTo applies pending embedded migrations up to and including version.
+func MigrateTo(ctx context.Context, pool *pgxpool.Pool, version int64) error {
+	if version < 0 {
+		return fmt.Errorf("invalid migration version %d", version)
+	}
+	return migrateTo(ctx, pool, version)
+}
+
+// MigrateDown rolls back applied migrations newer than version, newest
+// first, each in its own transaction. Version 0 rolls back all of them.
+// Nothing is rolled back unless every one of them can be.
+func MigrateDown(ctx context.Context, pool *pgxpool.Pool, version int64) error {
+	if version < 0 {
+		return fmt.Errorf("invalid migration version %d", version)
+	}
+	return migrateDown(ctx, pool, version)
+}
+
+// Status reports which embedded migrations have been applied.
+func Status(ctx context.Context, pool *pgxpool.Pool) (*MigrationStatus, error) {
+	return status(ctx, pool)
+}
+
+// LoadMigrations returns the embedded migrations sorted by version.
+func LoadMigrations() ([]Migration, error) {
+	return loadMigrations(migrationFiles, "migrations")
+}
+
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	seen := make(map[int64]string)
+	var migrations []Migration
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		version, err := parseMigrationVersion(e.Name())
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := seen[version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, prev, e.Name())
+		}
+		seen[version] = e.Name()
+
+		body, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", e.Name(), err)
+		}
+		up, down, _ := strings.Cut(string(body), downMarker)
+		migrations = append(migrations, Migration{
+			Version:  version,
+			Name:     e.Name(),
+			SQL:      up,
+			Down:     strings.TrimSpace(down),
+			Breaking: hasLine(up, breakingMarker),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func hasLine(s, line string) bool {
+	for _, l := range strings.Split(s, "\n") {
+		if strings.TrimSpace(l) == line {
+			return true
+		}
+	}
+	return false
+}
+
+func parseMigrationVersion(name string) (int64, error) {
+	prefix, _, ok := strings.Cut(name, "_")
+	if !ok {
+		return 0, fmt.Errorf("migration %s does not follow NNN_name.sql", name)
+	}
+	version, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("migration %s has invalid version prefix %q", name, prefix)
+	}
+	return version, nil
+}
+
+// appliedMigration is a row of schema_migrations.
+type appliedMigration struct {
+	Version  int64
+	Name     string
+	Breaking bool
+}
+
+func appliedVersions(ctx context.Context, db migrationDB) (map[int64]bool, error) {
+	rows, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]bool, len(rows))
+	for _, r := range rows {
+		applied[r.Version] = true
+	}
+	return applied, nil
+}
+
+// appliedMigrations returns the rows of schema_migrations sorted by version.
+// It only reads: a database without the table has no migrations applied,
+// and one without the breaking column none that are breaking.
+func appliedMigrations(ctx context.Context, db migrationDB) ([]appliedMigration, error) {
+	columns, err := schemaMigrationColumns(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, nil
+	}
+	query := "SELECT version, name, false FROM schema_migrations ORDER BY version"
+	if columns["breaking"] {
+		query = "SELECT version, name, breaking FROM schema_migrations ORDER BY version"
+	}
+
+	rows, err := db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var applied []appliedMigration
+	for rows.Next() {
+		var m appliedMigration
+		if err := rows.Scan(&m.Version, &m.Name, &m.Breaking); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied = append(applied, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	return applied, nil
+}
+
+func schemaMigrationColumns(ctx context.Context, db migrationDB) (map[string]bool, error) {
+	rows, err := db.Query(ctx, describeSchemaMigrations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations columns: %w", err)
+		}
+		columns[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to look up schema_migrations: %w", err)
+	}
+	return columns, nil
+}
+
+func status(ctx context.Context, db migrationDB) (*MigrationStatus, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	return splitMigrations(migrations, applied), nil
+}
+
+func splitMigrations(migrations []Migration, applied map[int64]bool) *MigrationStatus {
+	st := &MigrationStatus{}
+	for _, m := range migrations {
+		if applied[m.Version] {
+			st.Applied = append(st.Applied, m)
+		} else {
+			st.Pending = append(st.Pending, m)
+		}
+	}
+	return st
+}
+
+// migrateTo applies pending migrations with version <= target (all of them
+// when target is negative), creating schema_migrations first if need be.
+func migrateTo(ctx context.Context, db migrationDB, target int64) error {
+	if _, err := db.Exec(ctx, createSchemaMigrations); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	st, err := status(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range st.Pending {
+		if target >= 0 && m.Version > target {
+			break
+		}
+		applied, err := applyMigration(ctx, db, m)
+		if err != nil {
+			return err
+		}
+		if applied {
+			slog.Info("applied migration", "version", m.Version, "name", m.Name)
+		}
+	}
+	return nil
+}
+
+// applyMigration runs a single migration in its own transaction. The version
+// row is claimed first with ON CONFLICT DO NOTHING, so when several replicas
+// start at once only the one that wins the insert executes the SQL; the
+// others see zero rows affected and skip it. A breaking migration is marked
+// so after its SQL ran, as the breaking column only exists from migration
+// 046 on; no migration before it is breaking.
+func applyMigration(ctx context.Context, db migrationDB, m Migration) (bool, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin migration %s: %w", m.Name, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	tag, err := tx.Exec(ctx,
+		"INSERT INTO schema_migrations (version, name) VALUES ($1, $2) ON CONFLICT (version) DO NOTHING",
+		m.Version, m.Name)
+	if err != nil {
+		return false, fmt.Errorf("failed to record migration %s: %w", m.Name, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, m.SQL); err != nil {
+		return false, fmt.Errorf("failed to apply migration %s: %w", m.Name, err)
+	}
+	if m.Breaking {
+		if _, err := tx.Exec(ctx, "UPDATE schema_migrations SET breaking = true WHERE version = $1", m.Version); err != nil {
+			return false, fmt.Errorf("failed to mark migration %s breaking: %w", m.Name, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		if errors.Is(err, pgx.ErrTxCommitRollback) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to commit migration %s: %w", m.Name, err)
+	}
+	return true, nil
+}
+
+// migrateDown rolls back applied migrations with version > target.
+func migrateDown(ctx context.Context, db migrationDB, target int64) error {
+	st, err := status(ctx, db)
+	if err != nil {
+		return err
+	}
+	revert, err := rollbackPlan(st.Applied, target)
+	if err != nil {
+		return err
+	}
+	for _, m := range revert {
+		reverted, err := revertMigration(ctx, db, m)
+		if err != nil {
+			return err
+		}
+		if reverted {
+			slog.Info("rolled back migration", "version", m.Version, "name", m.Name)
+		}
+	}
+	return nil
+}
+
+// rollbackPlan returns the migrations of applied, sorted by version, that
+// rolling back to target reverts, newest first.
+func rollbackPlan(applied []Migration, target int64) ([]Migration, error) {
+	var revert []Migration
+	for i := len(applied) - 1; i >= 0 && applied[i].Version > target; i-- {
+		m := applied[i]
+		if m.Down == "" {
+			return nil, fmt.Errorf("failed to roll back migration %s: %w", m.Name, ErrIrreversible)
+		}
+		revert = append(revert, m)
+	}
+	return revert, nil
+}
+
+// revertMigration runs the down section of a single migration in its own
+// transaction, deleting its version row first so that, as in
+// applyMigration, only one of several racing runners executes the SQL.
+func revertMigration(ctx context.Context, db migrationDB, m Migration) (bool, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin rollback of %s: %w", m.Name, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	tag, err := tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", m.Version)
+	if err != nil {
+		return false, fmt.Errorf("failed to unrecord migration %s: %w", m.Name, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, m.Down); err != nil {
+		return false, fmt.Errorf("failed to roll back migration %s: %w", m.Name, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		if errors.Is(err, pgx.ErrTxCommitRollback) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to commit rollback of %s: %w", m.Name, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,210 @@
+package db
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	migrations, err := LoadMigrations()
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(migrations), 5)
+
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "001_clients.sql", migrations[0].Name)
+	assert.Contains(t, migrations[0].SQL, "CREATE TABLE clients")
+
+	for i := 1; i < len(migrations); i++ {
+		assert.Less(t, migrations[i-1].Version, migrations[i].Version, "migrations must be sorted")
+	}
+	for _, m := range migrations {
+		assert.NotEmptyf(t, m.Down, "%s has no %s section", m.Name, downMarker)
+		assert.NotContains(t, m.SQL, downMarker)
+	}
+}
+
+func TestLoadMigrations_DownSection(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/001_a.sql": {Data: []byte("CREATE TABLE a (id UUID PRIMARY KEY);\n\n-- migrate:down\nDROP TABLE a;\n")},
+	}
+
+	migrations, err := loadMigrations(fsys, "m")
+	require.NoError(t, err)
+	require.Len(t, migrations, 1)
+	assert.Equal(t, "CREATE TABLE a (id UUID PRIMARY KEY);\n\n", migrations[0].SQL)
+	assert.Equal(t, "DROP TABLE a;", migrations[0].Down)
+}
+
+func TestLoadMigrations_BreakingMarker(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/001_a.sql": {Data: []byte("CREATE TABLE a (id UUID PRIMARY KEY, b STRING);\n")},
+		"m/002_b.sql": {Data: []byte("-- migrate:breaking\nALTER TABLE a DROP COLUMN b;\n\n-- migrate:down\nALTER TABLE a ADD COLUMN b STRING;\n")},
+		"m/003_c.sql": {Data: []byte("SELECT 1;\n\n-- migrate:down\n-- migrate:breaking\nSELECT 1;\n")},
+	}
+
+	migrations, err := loadMigrations(fsys, "m")
+	require.NoError(t, err)
+	require.Len(t, migrations, 3)
+	assert.False(t, migrations[0].Breaking)
+	assert.True(t, migrations[1].Breaking)
+	assert.False(t, migrations[2].Breaking, "only the up section marks a migration breaking")
+}
+
+func TestLoadMigrations_NoBreakingBeforeBreakingColumn(t *testing.T) {
+	migrations, err := LoadMigrations()
+	require.NoError(t, err)
+	for _, m := range migrations {
+		if m.Version <= 46 {
+			assert.False(t, m.Breaking, "%s is breaking, but schema_migrations records that from 046 on", m.Name)
+		}
+	}
+}
+
+func TestLoadMigrations_SortsByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/010_later.sql": {Data: []byte("SELECT 10")},
+		"m/002_b.sql":     {Data: []byte("SELECT 2")},
+		"m/001_a.sql":     {Data: []byte("SELECT 1")},
+		"m/README.md":     {Data: []byte("ignored")},
+	}
+
+	migrations, err := loadMigrations(fsys, "m")
+	require.NoError(t, err)
+	require.Len(t, migrations, 3)
+	assert.Equal(t, []int64{1, 2, 10}, []int64{migrations[0].Version, migrations[1].Version, migrations[2].Version})
+	assert.Equal(t, "SELECT 10", migrations[2].SQL)
+}
+
+func TestLoadMigrations_DuplicateVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/001_a.sql": {Data: []byte("SELECT 1")},
+		"m/01_b.sql":  {Data: []byte("SELECT 1")},
+	}
+
+	_, err := loadMigrations(fsys, "m")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "duplicate migration version 1")
+}
+
+func TestParseMigrationVersion(t *testing.T) {
+	testCases := []struct {
+		name    string
+		want    int64
+		wantErr bool
+	}{
+		{"001_clients.sql", 1, false},
+		{"042_watcher_state.sql", 42, false},
+		{"clients.sql", 0, true},
+		{"abc_clients.sql", 0, true},
+		{"000_zero.sql", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseMigrationVersion(tc.name)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestSplitMigrations(t *testing.T) {
+	migrations := []Migration{{Version: 1}, {Version: 2}, {Version: 3}}
+
+	st := splitMigrations(migrations, map[int64]bool{1: true, 3: true})
+
+	require.Len(t, st.Applied, 2)
+	require.Len(t, st.Pending, 1)
+	assert.Equal(t, int64(2), st.Pending[0].Version)
+}
+
+func TestMigrateTo_NegativeVersion(t *testing.T) {
+	err := MigrateTo(context.Background(), nil, -1)
+	assert.Error(t, err)
+}
+
+func TestMigrateDown_NegativeVersion(t *testing.T) {
+	err := MigrateDown(context.Background(), nil, -1)
+	assert.Error(t, err)
+}
+
+func TestRollbackPlan(t *testing.T) {
+	applied := []Migration{
+		{Version: 1, Name: "001_a.sql", Down: "DROP TABLE a"},
+		{Version: 2, Name: "002_b.sql"},
+		{Version: 3, Name: "003_c.sql", Down: "DROP TABLE c"},
+		{Version: 4, Name: "004_d.sql", Down: "DROP TABLE d"},
+	}
+
+	plan, err := rollbackPlan(applied, 2)
+	require.NoError(t, err)
+	require.Len(t, plan, 2)
+	assert.Equal(t, []int64{4, 3}, []int64{plan[0].Version, plan[1].Version}, "newest first")
+
+	plan, err = rollbackPlan(applied, 4)
+	require.NoError(t, err)
+	assert.Empty(t, plan)
+
+	_, err = rollbackPlan(applied, 0)
+	require.ErrorIs(t, err, ErrIrreversible)
+	assert.Contains(t, err.Error(), "002_b.sql")
+}
+
+// TestMigrate_Integration runs against a throwaway database when
+// TPG_TEST_DATABASE_URL is set, e.g. a CockroachDB started with
+// `cockroach start-single-node --insecure`.
+func TestMigrate_Integration(t *testing.T) {
+	dsn := os.Getenv("TPG_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TPG_TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	// Replicas racing on startup must all succeed.
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = Migrate(ctx, pool)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	// Re-running is a no-op.
+	require.NoError(t, Migrate(ctx, pool))
+
+	st, err := Status(ctx, pool)
+	require.NoError(t, err)
+	assert.Empty(t, st.Pending)
+
+	migrations, err := LoadMigrations()
+	require.NoError(t, err)
+	assert.Len(t, st.Applied, len(migrations))
+
+	// Every migration rolls back, and applies again afterwards.
+	require.NoError(t, MigrateDown(ctx, pool, 0))
+	st, err = Status(ctx, pool)
+	require.NoError(t, err)
+	assert.Empty(t, st.Applied)
+	require.NoError(t, Migrate(ctx, pool))
+}
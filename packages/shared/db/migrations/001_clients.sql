@@ -5,8 +5,10 @@
 CREATE TABLE clients (
   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
   name STRING NOT NULL,
-  api_key STRING UNIQUE NOT NULL,
+  api_key STRING NOT NULL UNIQUE,
   is_active BOOL DEFAULT TRUE,
   created_at TIMESTAMPTZ DEFAULT now()
 );
 
+-- migrate:down
+DROP TABLE clients;
@@ -12,3 +12,6 @@ CREATE TABLE accounts (
   created_at TIMESTAMPTZ DEFAULT now(),
   CONSTRAINT fk_accounts_client FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
 );
+
+-- migrate:down
+DROP TABLE accounts;
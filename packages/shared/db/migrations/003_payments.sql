@@ -15,5 +15,5 @@ CREATE TABLE payments (
     created_at TIMESTAMPTZ DEFAULT now()
 );
 
-
-
+-- migrate:down
+DROP TABLE payments;
@@ -12,4 +12,7 @@ CREATE TABLE payment_attempts (
 );
 
 
-CREATE INDEX idx_payment_attempts_payment_id ON payment_attempts(payment_id);
\ No newline at end of file
+CREATE INDEX idx_payment_attempts_payment_id ON payment_attempts(payment_id);
+
+-- migrate:down
+DROP TABLE payment_attempts;
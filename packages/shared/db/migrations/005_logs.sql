@@ -13,4 +13,7 @@ CREATE TABLE logs (
 
 CREATE INDEX idx_logs_payment_id_created_at ON logs(payment_id, created_at DESC) WHERE payment_id IS NOT NULL;
 CREATE INDEX idx_logs_event_type_created_at ON logs(event_type, created_at DESC);
-CREATE INDEX idx_logs_created_at ON logs(created_at DESC);
\ No newline at end of file
+CREATE INDEX idx_logs_created_at ON logs(created_at DESC);
+
+-- migrate:down
+DROP TABLE logs;
@@ -0,0 +1,10 @@
+-- A wallet may only back one PENDING payment at a time; expired and confirmed
+-- payments keep their wallet for history, so the constraint is partial.
+CREATE UNIQUE INDEX idx_payments_unique_wallet_pending ON payments(unique_wallet) WHERE status = 'PENDING';
+
+-- Lookup path for GetPaymentByUniqueWallet (latest payment first).
+CREATE INDEX idx_payments_unique_wallet_created_at ON payments(unique_wallet, created_at DESC);
+
+-- migrate:down
+DROP INDEX payments@idx_payments_unique_wallet_created_at;
+DROP INDEX IF EXISTS payments@idx_payments_unique_wallet_pending;
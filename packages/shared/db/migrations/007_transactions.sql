@@ -0,0 +1,25 @@
+-- Transactions Table (On-chain transfers credited to a payment)
+CREATE TABLE transactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    tx_hash STRING NOT NULL,
+    transfer_index INT NOT NULL DEFAULT 0, -- position of the transfer within the transaction
+    token STRING NOT NULL, -- 'TRX' or the TRC20 symbol, e.g. 'USDT'
+    contract_address STRING, -- NULL for native TRX
+    from_address STRING NOT NULL,
+    to_address STRING NOT NULL,
+    amount DECIMAL(18,6) NOT NULL,
+    block_number INT8 NOT NULL,
+    block_hash STRING NOT NULL,
+    confirmations INT NOT NULL DEFAULT 0,
+    status STRING NOT NULL DEFAULT 'DETECTED' CHECK (status IN ('DETECTED', 'CONFIRMED')),
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+-- Re-scanning a block must not credit the same transfer twice.
+CREATE UNIQUE INDEX idx_transactions_tx_hash_transfer_index ON transactions(tx_hash, transfer_index);
+CREATE INDEX idx_transactions_payment_id ON transactions(payment_id);
+CREATE INDEX idx_transactions_status_block_number ON transactions(status, block_number);
+
+-- migrate:down
+DROP TABLE transactions;
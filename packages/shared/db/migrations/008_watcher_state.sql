@@ -0,0 +1,11 @@
+-- Watcher State Table (Last block processed by each chain watcher)
+CREATE TABLE watcher_state (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name STRING NOT NULL UNIQUE,
+    last_height INT8 NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+-- migrate:down
+DROP TABLE watcher_state;
@@ -0,0 +1,21 @@
+-- Transfers that add up to less than the requested amount (after the
+-- configured tolerance) leave the payment UNDERPAID until it is topped up or
+-- expires.
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS check_status;
+ALTER TABLE payments ADD CONSTRAINT check_payments_status CHECK (status IN ('PENDING', 'UNDERPAID', 'CONFIRMED', 'EXPIRED'));
+
+-- An UNDERPAID payment still owns its wallet so top-ups are credited to it.
+DROP INDEX IF EXISTS payments@idx_payments_unique_wallet_pending;
+CREATE UNIQUE INDEX idx_payments_unique_wallet_open ON payments(unique_wallet) WHERE status IN ('PENDING', 'UNDERPAID');
+
+-- Part of a transfer beyond what the payment still needed; NULL when none.
+ALTER TABLE transactions ADD COLUMN excess_amount DECIMAL(18,6);
+
+-- migrate:down
+ALTER TABLE transactions DROP COLUMN excess_amount;
+
+DROP INDEX payments@idx_payments_unique_wallet_open;
+CREATE UNIQUE INDEX idx_payments_unique_wallet_pending ON payments(unique_wallet) WHERE status = 'PENDING';
+
+ALTER TABLE payments DROP CONSTRAINT check_payments_status;
+ALTER TABLE payments ADD CONSTRAINT check_status CHECK (status IN ('PENDING', 'CONFIRMED', 'EXPIRED'));
@@ -0,0 +1,45 @@
+-- Derivation index of unique_wallet under the gateway mnemonic, so the
+-- sweeper can derive its key again. NULL for wallets whose index was never
+-- recorded; those are not swept.
+ALTER TABLE payments ADD COLUMN wallet_index INT8;
+
+-- Deposits credit a payment; sweeps move its funds on to the cold wallet and
+-- must not count towards the amount received.
+ALTER TABLE transactions ADD COLUMN kind STRING NOT NULL DEFAULT 'DEPOSIT';
+ALTER TABLE transactions ADD CONSTRAINT check_transactions_kind CHECK (kind IN ('DEPOSIT', 'SWEEP'));
+
+-- Sweeps Table (Transfers from a deposit wallet to the cold wallet)
+CREATE TABLE sweeps (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    token STRING NOT NULL,
+    contract_address STRING, -- NULL for native TRX
+    from_address STRING NOT NULL,
+    to_address STRING NOT NULL,
+    amount DECIMAL(18,6) NOT NULL,
+    tx_id STRING NOT NULL,
+    -- The signed transaction, saved before it is broadcast so a crashed run
+    -- broadcasts the same transaction again instead of signing a new one.
+    signed_tx JSONB NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    -- SIGNED: saved, maybe broadcast; SENT: accepted by the node;
+    -- CONFIRMED: deep enough in the chain; FAILED: executed and reverted;
+    -- REJECTED and EXPIRED: never on chain, so the funds may be swept again.
+    status STRING NOT NULL DEFAULT 'SIGNED' CHECK (status IN ('SIGNED', 'SENT', 'CONFIRMED', 'FAILED', 'REJECTED', 'EXPIRED')),
+    created_at TIMESTAMPTZ DEFAULT now(),
+    updated_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE UNIQUE INDEX idx_sweeps_tx_id ON sweeps(tx_id);
+-- At most one sweep per wallet and token may be in flight, so no run can
+-- sign a second transaction for funds another may already be moving.
+CREATE UNIQUE INDEX idx_sweeps_open ON sweeps(from_address, token) WHERE status IN ('SIGNED', 'SENT');
+CREATE INDEX idx_sweeps_payment_id ON sweeps(payment_id);
+
+-- migrate:down
+DROP TABLE sweeps;
+
+ALTER TABLE transactions DROP CONSTRAINT check_transactions_kind;
+ALTER TABLE transactions DROP COLUMN kind;
+
+ALTER TABLE payments DROP COLUMN wallet_index;
@@ -0,0 +1,21 @@
+-- A transfer seen in the pending pool moves a PENDING payment to DETECTED
+-- before it is in a block. DETECTED is not final: the payment still goes on
+-- to CONFIRMED, UNDERPAID or EXPIRED.
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS check_payments_status;
+ALTER TABLE payments ADD CONSTRAINT check_payments_status CHECK (status IN ('PENDING', 'DETECTED', 'UNDERPAID', 'CONFIRMED', 'EXPIRED'));
+
+-- A DETECTED payment still owns its wallet.
+DROP INDEX IF EXISTS payments@idx_payments_unique_wallet_open;
+CREATE UNIQUE INDEX idx_payments_unique_wallet_open ON payments(unique_wallet) WHERE status IN ('PENDING', 'DETECTED', 'UNDERPAID');
+
+-- Backs the expiry sweep and the pending pool watch.
+CREATE INDEX idx_payments_status_expires_at ON payments(status, expires_at);
+
+-- migrate:down
+DROP INDEX payments@idx_payments_status_expires_at;
+
+DROP INDEX payments@idx_payments_unique_wallet_open;
+CREATE UNIQUE INDEX idx_payments_unique_wallet_open ON payments(unique_wallet) WHERE status IN ('PENDING', 'UNDERPAID');
+
+ALTER TABLE payments DROP CONSTRAINT check_payments_status;
+ALTER TABLE payments ADD CONSTRAINT check_payments_status CHECK (status IN ('PENDING', 'UNDERPAID', 'CONFIRMED', 'EXPIRED'));
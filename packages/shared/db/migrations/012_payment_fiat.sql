@@ -0,0 +1,19 @@
+-- An invoice priced in fiat keeps the amount and currency it was priced in
+-- and the rate that converted it to the token amount, so the conversion can
+-- be audited later. All four are NULL for invoices priced in a token.
+ALTER TABLE payments ADD COLUMN fiat_amount DECIMAL(18,6);
+ALTER TABLE payments ADD COLUMN fiat_currency STRING;
+-- Price of one token in fiat_currency, as quoted at rate_at.
+ALTER TABLE payments ADD COLUMN exchange_rate DECIMAL(36,18);
+ALTER TABLE payments ADD COLUMN rate_at TIMESTAMPTZ;
+ALTER TABLE payments ADD CONSTRAINT check_payments_fiat CHECK (
+    (fiat_amount IS NULL AND fiat_currency IS NULL AND exchange_rate IS NULL AND rate_at IS NULL)
+    OR (fiat_amount IS NOT NULL AND fiat_currency IS NOT NULL AND exchange_rate IS NOT NULL AND rate_at IS NOT NULL)
+);
+
+-- migrate:down
+ALTER TABLE payments DROP CONSTRAINT check_payments_fiat;
+ALTER TABLE payments DROP COLUMN rate_at;
+ALTER TABLE payments DROP COLUMN exchange_rate;
+ALTER TABLE payments DROP COLUMN fiat_currency;
+ALTER TABLE payments DROP COLUMN fiat_amount;
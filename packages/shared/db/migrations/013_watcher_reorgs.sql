@@ -0,0 +1,32 @@
+-- The watcher remembers the blocks it scanned last, oldest first, as
+-- [{"height": 1, "hash": "..."}]. A new block whose parent hash does not
+-- match the newest of them reveals a reorg, and walking back through them
+-- finds where the chains forked.
+ALTER TABLE watcher_state ADD COLUMN recent_blocks JSONB NOT NULL DEFAULT '[]';
+
+-- A transfer whose block was orphaned by a reorg is kept for the audit trail
+-- but no longer counts towards its payment. If the new chain includes it
+-- again it is recorded as a new row, so only live rows are unique.
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS check_status;
+ALTER TABLE transactions ADD CONSTRAINT check_transactions_status CHECK (status IN ('DETECTED', 'CONFIRMED', 'ORPHANED'));
+DROP INDEX IF EXISTS transactions@idx_transactions_tx_hash_transfer_index;
+CREATE UNIQUE INDEX idx_transactions_tx_hash_transfer_index ON transactions(tx_hash, transfer_index) WHERE status != 'ORPHANED';
+CREATE INDEX idx_transactions_block_hash ON transactions(block_hash);
+
+-- A confirmed payment whose transfers were orphaned is reopened as PENDING
+-- while it has time left. Past its expiry it can no longer be paid, so it
+-- waits in REVIEW for an operator.
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS check_payments_status;
+ALTER TABLE payments ADD CONSTRAINT check_payments_status CHECK (status IN ('PENDING', 'DETECTED', 'UNDERPAID', 'CONFIRMED', 'EXPIRED', 'REVIEW'));
+
+-- migrate:down
+ALTER TABLE payments DROP CONSTRAINT check_payments_status;
+ALTER TABLE payments ADD CONSTRAINT check_payments_status CHECK (status IN ('PENDING', 'DETECTED', 'UNDERPAID', 'CONFIRMED', 'EXPIRED'));
+
+DROP INDEX transactions@idx_transactions_block_hash;
+DROP INDEX transactions@idx_transactions_tx_hash_transfer_index;
+CREATE UNIQUE INDEX idx_transactions_tx_hash_transfer_index ON transactions(tx_hash, transfer_index);
+ALTER TABLE transactions DROP CONSTRAINT check_transactions_status;
+ALTER TABLE transactions ADD CONSTRAINT check_status CHECK (status IN ('DETECTED', 'CONFIRMED'));
+
+ALTER TABLE watcher_state DROP COLUMN recent_blocks;
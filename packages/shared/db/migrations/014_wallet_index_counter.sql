@@ -0,0 +1,23 @@
+-- Deposit wallets are derived from one mnemonic by index, so an index must
+-- never back two payments, whatever their client or account. The counter is
+-- bumped inside the transaction that creates the payment, so a payment that
+-- rolls back hands its index back.
+CREATE TABLE wallet_index_counters (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name STRING NOT NULL UNIQUE,
+    next_index INT8 NOT NULL DEFAULT 0 CHECK (next_index >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+-- Start past any index already handed out.
+INSERT INTO wallet_index_counters (name, next_index)
+SELECT 'deposit', COALESCE(max(wallet_index) + 1, 0) FROM payments;
+
+-- Backstop for the counter: one index, one payment.
+CREATE UNIQUE INDEX idx_payments_wallet_index ON payments(wallet_index) WHERE wallet_index IS NOT NULL;
+
+-- migrate:down
+DROP INDEX payments@idx_payments_wallet_index;
+
+DROP TABLE wallet_index_counters;
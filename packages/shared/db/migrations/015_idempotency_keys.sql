@@ -0,0 +1,20 @@
+-- A client retrying a request with the same Idempotency-Key gets the first
+-- response back instead of a second payment. The key is claimed before the
+-- handler runs and completed with its response afterwards.
+CREATE TABLE idempotency_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    client_id UUID NOT NULL REFERENCES clients(id),
+    idempotency_key STRING NOT NULL,
+    -- SHA-256 of the method, path and body, so reusing a key for a different
+    -- request is caught.
+    request_hash STRING NOT NULL,
+    status STRING NOT NULL DEFAULT 'IN_PROGRESS' CHECK (status IN ('IN_PROGRESS', 'COMPLETED')),
+    response_status INT4,
+    response_body BYTES,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT now(),
+    UNIQUE (client_id, idempotency_key)
+);
+
+-- migrate:down
+DROP TABLE idempotency_keys;
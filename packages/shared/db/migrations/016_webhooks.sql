@@ -0,0 +1,37 @@
+-- Webhook Endpoints Table (Where a client is notified of payment changes)
+CREATE TABLE webhook_endpoints (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    url STRING NOT NULL,
+    -- Key of the X-TPG-Signature HMAC, shared with the merchant.
+    secret STRING NOT NULL,
+    is_active BOOL NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX idx_webhook_endpoints_client_id ON webhook_endpoints(client_id);
+
+-- Webhook Deliveries Table (One event queued for one endpoint)
+CREATE TABLE webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    endpoint_id UUID NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+    payment_id UUID REFERENCES payments(id) ON DELETE CASCADE,
+    event_type STRING NOT NULL,
+    payload JSONB NOT NULL,
+    -- PENDING: due at next_attempt_at; DELIVERED: answered with a 2xx;
+    -- FAILED: out of attempts.
+    status STRING NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'DELIVERED', 'FAILED')),
+    attempts INT4 NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_status_code INT4,
+    last_error STRING,
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'PENDING';
+CREATE INDEX idx_webhook_deliveries_payment_id ON webhook_deliveries(payment_id);
+
+-- migrate:down
+DROP TABLE webhook_deliveries;
+DROP TABLE webhook_endpoints;
@@ -0,0 +1,13 @@
+-- The X-Request-ID of the API request that caused a log entry or webhook
+-- delivery, so a merchant's report can be traced from the request through
+-- everything it triggered. NULL for work the background services start.
+ALTER TABLE logs ADD COLUMN request_id STRING;
+ALTER TABLE webhook_deliveries ADD COLUMN request_id STRING;
+
+CREATE INDEX idx_logs_request_id ON logs(request_id) WHERE request_id IS NOT NULL;
+
+-- migrate:down
+DROP INDEX logs@idx_logs_request_id;
+
+ALTER TABLE webhook_deliveries DROP COLUMN request_id;
+ALTER TABLE logs DROP COLUMN request_id;
@@ -0,0 +1,11 @@
+-- Who made the change a log entry records: "admin", or "admin:<name>" when
+-- the operator named themselves, for the admin API, and "client:<id>" for a
+-- merchant acting on its own data. NULL for the gateway's own work.
+ALTER TABLE logs ADD COLUMN actor STRING;
+
+CREATE INDEX idx_logs_actor_created_at ON logs(actor, created_at DESC) WHERE actor IS NOT NULL;
+
+-- migrate:down
+DROP INDEX logs@idx_logs_actor_created_at;
+
+ALTER TABLE logs DROP COLUMN actor;
@@ -0,0 +1,9 @@
+-- A merchant may void a PENDING or DETECTED payment that has no transfer
+-- recorded against it. CANCELLED is terminal and frees the wallet, like
+-- EXPIRED.
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS check_payments_status;
+ALTER TABLE payments ADD CONSTRAINT check_payments_status CHECK (status IN ('PENDING', 'DETECTED', 'UNDERPAID', 'CONFIRMED', 'EXPIRED', 'REVIEW', 'CANCELLED'));
+
+-- migrate:down
+ALTER TABLE payments DROP CONSTRAINT check_payments_status;
+ALTER TABLE payments ADD CONSTRAINT check_payments_status CHECK (status IN ('PENDING', 'DETECTED', 'UNDERPAID', 'CONFIRMED', 'EXPIRED', 'REVIEW'));
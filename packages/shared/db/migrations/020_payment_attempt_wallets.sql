@@ -0,0 +1,18 @@
+-- A merchant may give a PENDING payment a new deposit wallet. The payment
+-- then points at the newest wallet, but each earlier one stays in
+-- payment_attempts with the index it was derived at, so a late transfer to
+-- it is still credited to the payment and can be swept.
+ALTER TABLE payment_attempts ADD COLUMN wallet_index INT8;
+
+UPDATE payment_attempts AS a
+SET wallet_index = p.wallet_index
+FROM payments AS p
+WHERE a.payment_id = p.id AND a.generated_wallet = p.unique_wallet;
+
+-- Lookup path for GetPaymentByWallet.
+CREATE INDEX idx_payment_attempts_generated_wallet ON payment_attempts(generated_wallet);
+
+-- migrate:down
+DROP INDEX payment_attempts@idx_payment_attempts_generated_wallet;
+
+ALTER TABLE payment_attempts DROP COLUMN wallet_index;
@@ -0,0 +1,9 @@
+-- A payment is requested in one token, and only transfers of that token are
+-- credited to it. Payments created before the column existed were priced in
+-- USDT, the only token enabled by default.
+ALTER TABLE payments ADD COLUMN token STRING NOT NULL DEFAULT 'USDT';
+ALTER TABLE payments ADD CONSTRAINT check_payments_token CHECK (token IN ('TRX', 'USDT'));
+
+-- migrate:down
+ALTER TABLE payments DROP CONSTRAINT check_payments_token;
+ALTER TABLE payments DROP COLUMN token;
@@ -0,0 +1,6 @@
+-- Internal services page through a client's payments by id
+-- (ListClientPayments).
+CREATE INDEX idx_payments_client_id_id ON payments(client_id, id);
+
+-- migrate:down
+DROP INDEX payments@idx_payments_client_id_id;
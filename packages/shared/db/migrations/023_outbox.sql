@@ -0,0 +1,20 @@
+-- Payment events are written to the outbox in the transaction that changes
+-- the payment, so a crash can never keep the change and lose the event. A
+-- relay hands committed rows on, e.g. as webhook deliveries, and marks them
+-- processed in one transaction of its own.
+CREATE TABLE outbox (
+    -- Also the id of the event in its payload, so consumers can deduplicate.
+    id UUID PRIMARY KEY,
+    event_type STRING NOT NULL,
+    -- The payment the event is about.
+    aggregate_id UUID NOT NULL,
+    payload JSONB NOT NULL,
+    request_id STRING,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    processed_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_outbox_unprocessed ON outbox(created_at, id) WHERE processed_at IS NULL;
+
+-- migrate:down
+DROP TABLE outbox;
@@ -0,0 +1,15 @@
+-- Leases elect one leader among the replicas of a singleton worker, e.g. the
+-- sweeper. The holder renews lease_until while it runs; once it lapses any
+-- replica may take over. token grows with every new holder, so a write
+-- carrying a stale token can be told from the current leader's.
+CREATE TABLE leases (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name STRING NOT NULL UNIQUE,
+    holder STRING NOT NULL,
+    token INT8 NOT NULL CHECK (token > 0),
+    lease_until TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- migrate:down
+DROP TABLE leases;
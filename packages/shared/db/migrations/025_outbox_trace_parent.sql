@@ -0,0 +1,7 @@
+-- The W3C traceparent of the span that wrote an outbox event, so the relay
+-- that hands it on later can link its own span back to the request or job
+-- that caused it. NULL when tracing was off.
+ALTER TABLE outbox ADD COLUMN trace_parent STRING;
+
+-- migrate:down
+ALTER TABLE outbox DROP COLUMN trace_parent;
@@ -0,0 +1,40 @@
+-- Reconciliation reports record where the chain disagrees with what the
+-- transactions table says a confirmed payment received and swept.
+CREATE TABLE reconciliation_reports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- Payments confirmed in [window_start, window_end) were checked.
+    window_start TIMESTAMPTZ NOT NULL,
+    window_end TIMESTAMPTZ NOT NULL,
+    payments_checked INT NOT NULL DEFAULT 0,
+    mismatches INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_reconciliation_reports_created_at ON reconciliation_reports(created_at DESC);
+
+CREATE TABLE reconciliation_mismatches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    report_id UUID NOT NULL REFERENCES reconciliation_reports(id) ON DELETE CASCADE,
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    -- MISSING_TRANSFER: a recorded deposit is not on chain as recorded, or
+    -- the payment has none; UNEXPLAINED_BALANCE: the deposit wallet holds
+    -- more than was deposited and not swept; MISSING_BALANCE: it holds less
+    -- and was never swept; SWEEP_SHORTFALL: a sweep is not on chain as
+    -- recorded, or less reached the cold wallet than left the deposit wallet.
+    kind STRING NOT NULL CHECK (kind IN ('MISSING_TRANSFER', 'UNEXPLAINED_BALANCE', 'MISSING_BALANCE', 'SWEEP_SHORTFALL')),
+    wallet STRING NOT NULL,
+    token STRING NOT NULL,
+    tx_hash STRING, -- NULL for balance mismatches
+    -- In token units: what the database accounts for, and what the chain
+    -- shows.
+    expected DECIMAL(18,6) NOT NULL,
+    actual DECIMAL(18,6) NOT NULL,
+    detail STRING NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_reconciliation_mismatches_report_id ON reconciliation_mismatches(report_id);
+
+-- migrate:down
+DROP TABLE reconciliation_mismatches;
+DROP TABLE reconciliation_reports;
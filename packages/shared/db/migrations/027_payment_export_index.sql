@@ -0,0 +1,5 @@
+-- Keyset path for ListPaymentExport: a client's payments in creation order.
+CREATE INDEX idx_payments_client_created_at ON payments(client_id, created_at, id);
+
+-- migrate:down
+DROP INDEX payments@idx_payments_client_created_at;
@@ -0,0 +1,8 @@
+-- Bound client and account names as the API does, so no other writer can
+-- store a name the API would reject.
+ALTER TABLE clients ADD CONSTRAINT clients_name_length CHECK (length(name) <= 200);
+ALTER TABLE accounts ADD CONSTRAINT accounts_name_length CHECK (length(name) <= 200);
+
+-- migrate:down
+ALTER TABLE accounts DROP CONSTRAINT accounts_name_length;
+ALTER TABLE clients DROP CONSTRAINT clients_name_length;
@@ -0,0 +1,19 @@
+-- Test clients take testnet payments to deposit wallets derived from a
+-- mnemonic of their own. The mode is copied onto each payment so the workers
+-- can keep to one mode without joining clients.
+ALTER TABLE clients ADD COLUMN mode STRING NOT NULL DEFAULT 'live' CHECK (mode IN ('live', 'test'));
+ALTER TABLE payments ADD COLUMN mode STRING NOT NULL DEFAULT 'live' CHECK (mode IN ('live', 'test'));
+
+-- Each mode counts its wallet indexes from zero, so an index is only unique
+-- within a mode.
+DROP INDEX payments@idx_payments_wallet_index;
+CREATE UNIQUE INDEX idx_payments_mode_wallet_index ON payments(mode, wallet_index) WHERE wallet_index IS NOT NULL;
+
+-- migrate:down
+-- Fails while test payments reuse a live wallet index; they have to be
+-- removed by hand first.
+DROP INDEX payments@idx_payments_mode_wallet_index;
+CREATE UNIQUE INDEX idx_payments_wallet_index ON payments(wallet_index) WHERE wallet_index IS NOT NULL;
+
+ALTER TABLE payments DROP COLUMN mode;
+ALTER TABLE clients DROP COLUMN mode;
@@ -0,0 +1,16 @@
+-- Per-account defaults for new payments, which a create-payment request can
+-- still override, and free-form merchant metadata. The API bounds the
+-- metadata; its stored JSONB text can differ in length from what was sent.
+ALTER TABLE accounts ADD COLUMN default_expiry_seconds INT8 CHECK (default_expiry_seconds BETWEEN 60 AND 86400);
+ALTER TABLE accounts ADD COLUMN default_webhook_endpoint_id UUID REFERENCES webhook_endpoints(id) ON DELETE SET NULL;
+ALTER TABLE accounts ADD COLUMN metadata JSONB;
+
+-- The endpoint a payment's webhooks go to; NULL sends them to every active
+-- endpoint of the client.
+ALTER TABLE payments ADD COLUMN webhook_endpoint_id UUID REFERENCES webhook_endpoints(id) ON DELETE SET NULL;
+
+-- migrate:down
+ALTER TABLE payments DROP COLUMN webhook_endpoint_id;
+ALTER TABLE accounts DROP COLUMN metadata;
+ALTER TABLE accounts DROP COLUMN default_webhook_endpoint_id;
+ALTER TABLE accounts DROP COLUMN default_expiry_seconds;
@@ -0,0 +1,14 @@
+-- Accounts are soft deleted so the payments made to them keep their history.
+ALTER TABLE accounts ADD COLUMN deleted_at TIMESTAMPTZ;
+
+-- Refuse hard deletes of an account that still has payments instead of
+-- cascading them away. The inline constraint of 003_payments.sql is named by
+-- the CockroachDB version that ran it.
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_account_id_fkey;
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS fk_account_id_ref_accounts;
+ALTER TABLE payments ADD CONSTRAINT fk_payments_account FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE RESTRICT;
+
+-- migrate:down
+ALTER TABLE payments DROP CONSTRAINT fk_payments_account;
+ALTER TABLE payments ADD CONSTRAINT payments_account_id_fkey FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE;
+ALTER TABLE accounts DROP COLUMN deleted_at;
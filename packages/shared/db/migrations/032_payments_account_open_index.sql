@@ -0,0 +1,6 @@
+-- Creating a payment counts the open payments of its account
+-- (CountPendingPaymentsByAccountID), and so does soft deleting the account.
+CREATE INDEX idx_payments_account_open ON payments(account_id) WHERE status IN ('PENDING', 'DETECTED');
+
+-- migrate:down
+DROP INDEX payments@idx_payments_account_open;
@@ -0,0 +1,7 @@
+-- The payload version an endpoint receives. It is fixed per endpoint so a
+-- payload change ships as a new version instead of breaking integrations
+-- built against an older one. Existing endpoints get the first version.
+ALTER TABLE webhook_endpoints ADD COLUMN api_version STRING NOT NULL DEFAULT '2026-03-01';
+
+-- migrate:down
+ALTER TABLE webhook_endpoints DROP COLUMN api_version;
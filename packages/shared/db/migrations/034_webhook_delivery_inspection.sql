@@ -0,0 +1,14 @@
+-- What merchants are shown when a webhook goes missing: the start of the
+-- last response body, and for a replayed delivery the one it was cloned
+-- from.
+ALTER TABLE webhook_deliveries ADD COLUMN last_response_body STRING;
+ALTER TABLE webhook_deliveries ADD COLUMN replay_of UUID REFERENCES webhook_deliveries(id) ON DELETE SET NULL;
+
+-- ListWebhookDeliveries pages through a payment's deliveries newest first.
+CREATE INDEX idx_webhook_deliveries_payment_created ON webhook_deliveries(payment_id, created_at DESC, id DESC);
+
+-- migrate:down
+DROP INDEX webhook_deliveries@idx_webhook_deliveries_payment_created;
+
+ALTER TABLE webhook_deliveries DROP COLUMN replay_of;
+ALTER TABLE webhook_deliveries DROP COLUMN last_response_body;
@@ -0,0 +1,10 @@
+-- Rotating an endpoint's secret keeps the one it replaces as
+-- secondary_secret. Deliveries are signed with both until the overlap
+-- window after rotated_at ends and the worker clears it, so the merchant
+-- can switch its receiver over without rejecting a delivery.
+ALTER TABLE webhook_endpoints ADD COLUMN secondary_secret STRING;
+ALTER TABLE webhook_endpoints ADD COLUMN rotated_at TIMESTAMPTZ;
+
+-- migrate:down
+ALTER TABLE webhook_endpoints DROP COLUMN rotated_at;
+ALTER TABLE webhook_endpoints DROP COLUMN secondary_secret;
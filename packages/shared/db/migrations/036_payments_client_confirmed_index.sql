@@ -0,0 +1,6 @@
+-- The dashboard summary sums a client's confirmed payments by day
+-- (GetDailyConfirmedVolume).
+CREATE INDEX idx_payments_client_confirmed_at ON payments(client_id, confirmed_at) STORING (token, amount) WHERE status = 'CONFIRMED';
+
+-- migrate:down
+DROP INDEX payments@idx_payments_client_confirmed_at;
@@ -0,0 +1,13 @@
+-- The merchant's own key for a payment, e.g. its order number, and free-form
+-- merchant metadata. A reference is unique per client when set; the API
+-- bounds the metadata as it does an account's.
+ALTER TABLE payments ADD COLUMN order_reference STRING CONSTRAINT payments_order_reference_length CHECK (length(order_reference) BETWEEN 1 AND 200);
+ALTER TABLE payments ADD COLUMN metadata JSONB;
+
+-- Lookup path for GetPaymentByOrderReference.
+CREATE UNIQUE INDEX idx_payments_client_order_reference ON payments(client_id, order_reference) WHERE order_reference IS NOT NULL;
+
+-- migrate:down
+DROP INDEX payments@idx_payments_client_order_reference;
+ALTER TABLE payments DROP COLUMN metadata;
+ALTER TABLE payments DROP COLUMN order_reference;
@@ -0,0 +1,24 @@
+-- Deposit wallets derived ahead of time, so creating a payment does not wait
+-- on key derivation. The refiller takes indexes from the mode's
+-- wallet_index_counters row, derives their addresses and adds them here; a
+-- payment claims one by setting reserved_by_payment. Claimed rows are kept,
+-- so an address is never handed out twice.
+CREATE TABLE address_pool (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    address_index INT8 NOT NULL CHECK (address_index >= 0),
+    address STRING NOT NULL,
+    reserved_by_payment UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT address_pool_address_key UNIQUE (address),
+    CONSTRAINT address_pool_account_index_key UNIQUE (account_id, address_index)
+);
+
+-- Claims and the refiller's counts only look at unreserved addresses.
+CREATE INDEX idx_address_pool_unreserved ON address_pool(account_id, address_index) WHERE reserved_by_payment IS NULL;
+
+-- One payment, one pooled address.
+CREATE UNIQUE INDEX idx_address_pool_reserved_by_payment ON address_pool(reserved_by_payment) WHERE reserved_by_payment IS NOT NULL;
+
+-- migrate:down
+DROP TABLE address_pool;
@@ -0,0 +1,6 @@
+-- Optimistic concurrency for payments: every update checks the version it
+-- read and bumps it, so of two writers holding the same row only one wins.
+ALTER TABLE payments ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+-- migrate:down
+ALTER TABLE payments DROP COLUMN version;
@@ -0,0 +1,11 @@
+-- The rest of what merchants are shown about how their endpoint answered:
+-- the allow-listed headers and latency of the last response, and a record of
+-- every attempt, capped at webhooks.maxAttempts entries by the worker.
+ALTER TABLE webhook_deliveries ADD COLUMN last_response_headers JSONB;
+ALTER TABLE webhook_deliveries ADD COLUMN last_latency_ms INT4;
+ALTER TABLE webhook_deliveries ADD COLUMN attempt_history JSONB NOT NULL DEFAULT '[]';
+
+-- migrate:down
+ALTER TABLE webhook_deliveries DROP COLUMN attempt_history;
+ALTER TABLE webhook_deliveries DROP COLUMN last_latency_ms;
+ALTER TABLE webhook_deliveries DROP COLUMN last_response_headers;
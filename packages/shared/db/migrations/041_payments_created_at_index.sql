@@ -0,0 +1,7 @@
+-- The funnel across every client (GetPaymentFunnel without a client) scans
+-- payments by creation time; the columns it counts by are stored so it needs
+-- no index join. A client's funnel uses idx_payments_client_created_at.
+CREATE INDEX idx_payments_created_at ON payments(created_at) STORING (client_id, status, expires_at, confirmed_at);
+
+-- migrate:down
+DROP INDEX payments@idx_payments_created_at;
@@ -0,0 +1,41 @@
+-- How an account tells its open payments apart. unique_wallet gives every
+-- payment a wallet of its own; amount_suffix sends them all to the
+-- account's deposit_wallet, derived at deposit_wallet_index the first time
+-- it is needed, and adds a suffix unique among the account's open payments
+-- to the last three decimals of each amount.
+ALTER TABLE accounts ADD COLUMN address_strategy STRING NOT NULL DEFAULT 'unique_wallet'
+    CHECK (address_strategy IN ('unique_wallet', 'amount_suffix'));
+ALTER TABLE accounts ADD COLUMN deposit_wallet STRING;
+ALTER TABLE accounts ADD COLUMN deposit_wallet_index INT8 CHECK (deposit_wallet_index >= 0);
+CREATE UNIQUE INDEX idx_accounts_deposit_wallet ON accounts(deposit_wallet) WHERE deposit_wallet IS NOT NULL;
+
+-- The suffix of each amount_suffix payment. A reservation holds its suffix
+-- until expires_at, a while after the payment expires so a late transfer
+-- is not credited to the payment that reuses it; an expired row is
+-- overwritten by the next reservation of its suffix.
+CREATE TABLE amount_suffix_reservations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    suffix INT4 NOT NULL CHECK (suffix BETWEEN 1 AND 999),
+    payment_id UUID NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT amount_suffix_reservations_account_id_suffix_key UNIQUE (account_id, suffix),
+    CONSTRAINT amount_suffix_reservations_payment_id_key UNIQUE (payment_id)
+);
+
+-- A payment without a wallet index shares its wallet, the deposit wallet of
+-- its account, with the account's other open payments, so only payments
+-- with a wallet of their own keep it unique.
+DROP INDEX payments@idx_payments_unique_wallet_open;
+CREATE UNIQUE INDEX idx_payments_unique_wallet_open ON payments(unique_wallet)
+    WHERE status IN ('PENDING', 'DETECTED', 'UNDERPAID') AND wallet_index IS NOT NULL;
+
+-- migrate:down
+DROP INDEX payments@idx_payments_unique_wallet_open;
+CREATE UNIQUE INDEX idx_payments_unique_wallet_open ON payments(unique_wallet) WHERE status IN ('PENDING', 'DETECTED', 'UNDERPAID');
+DROP TABLE amount_suffix_reservations;
+DROP INDEX accounts@idx_accounts_deposit_wallet;
+ALTER TABLE accounts DROP COLUMN deposit_wallet_index;
+ALTER TABLE accounts DROP COLUMN deposit_wallet;
+ALTER TABLE accounts DROP COLUMN address_strategy;
@@ -0,0 +1,36 @@
+-- A client's invoice for the fees of the live payments confirmed in one
+-- UTC month. There is one per client and month, so generating it again
+-- returns the invoice already kept rather than recomputing it under a fee
+-- schedule that may have changed since.
+CREATE TABLE invoices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    -- The first day of the month invoiced.
+    month DATE NOT NULL CHECK (extract(day FROM month) = 1),
+    payments INT8 NOT NULL DEFAULT 0 CHECK (payments >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT invoices_client_id_month_key UNIQUE (client_id, month)
+);
+
+CREATE INDEX idx_invoices_month ON invoices(month DESC);
+
+-- An invoice charges each token in up to two lines: PERCENT for the
+-- payments charged a percentage of their amount, MINIMUM for those whose
+-- percentage fell short of the minimum fee of a payment. Amounts are in
+-- token units; rate is the percentage or the minimum fee charged.
+CREATE TABLE invoice_lines (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    token STRING NOT NULL,
+    kind STRING NOT NULL CHECK (kind IN ('PERCENT', 'MINIMUM')),
+    payments INT8 NOT NULL CHECK (payments > 0),
+    amount DECIMAL(18,6) NOT NULL,
+    rate DECIMAL(18,6) NOT NULL,
+    fee DECIMAL(18,6) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT invoice_lines_invoice_id_token_kind_key UNIQUE (invoice_id, token, kind)
+);
+
+-- migrate:down
+DROP TABLE invoice_lines;
+DROP TABLE invoices;
@@ -0,0 +1,10 @@
+-- The headers of the stored response, so a replay carries e.g. the Location
+-- of the first one, and when the key was last claimed, so a key left
+-- IN_PROGRESS by a process that died mid-request can be claimed again once
+-- the claim is stale.
+ALTER TABLE idempotency_keys ADD COLUMN response_headers JSONB;
+ALTER TABLE idempotency_keys ADD COLUMN claimed_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- migrate:down
+ALTER TABLE idempotency_keys DROP COLUMN claimed_at;
+ALTER TABLE idempotency_keys DROP COLUMN response_headers;
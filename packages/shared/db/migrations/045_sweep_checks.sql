@@ -0,0 +1,16 @@
+-- Deposit wallets the sweeper looked at and left alone, because the balance
+-- was below the threshold or could not pay the fees. A wallet is not a sweep
+-- candidate again until next_check_at, so the ones left alone do not fill
+-- every batch ahead of the ones that can be swept.
+CREATE TABLE sweep_checks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    address STRING NOT NULL,
+    token STRING NOT NULL,
+    next_check_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT sweep_checks_address_token_key UNIQUE (address, token)
+);
+
+-- migrate:down
+DROP TABLE sweep_checks;
@@ -0,0 +1,7 @@
+-- Whether a migration breaks binaries built before it, so an older binary
+-- that does not embed it refuses to start; see CheckSchema. The runner
+-- added the column itself before this migration, hence IF NOT EXISTS.
+ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS breaking BOOL NOT NULL DEFAULT false;
+
+-- migrate:down
+ALTER TABLE schema_migrations DROP COLUMN breaking;
@@ -0,0 +1,13 @@
+-- A key whose request succeeded but whose response could not be stored is
+-- marked FAILED rather than left IN_PROGRESS, where a retry would claim it
+-- again once stale and create a second payment. A FAILED key is never
+-- claimed again.
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS check_status;
+ALTER TABLE idempotency_keys ADD CONSTRAINT check_idempotency_keys_status CHECK (status IN ('IN_PROGRESS', 'COMPLETED', 'FAILED'));
+
+-- migrate:down
+-- Without a response, older binaries answer these keys as in progress and
+-- still never claim them again.
+UPDATE idempotency_keys SET status = 'COMPLETED' WHERE status = 'FAILED';
+ALTER TABLE idempotency_keys DROP CONSTRAINT check_idempotency_keys_status;
+ALTER TABLE idempotency_keys ADD CONSTRAINT check_status CHECK (status IN ('IN_PROGRESS', 'COMPLETED'));
@@ -15,6 +15,48 @@ func TestMigrationFilesExist(t *testing.T) {
 		"003_payments.sql",
 		"004_payments_attempts.sql",
 		"005_logs.sql",
+		"006_payments_unique_wallet.sql",
+		"007_transactions.sql",
+		"008_watcher_state.sql",
+		"009_payment_amount_matching.sql",
+		"010_sweeps.sql",
+		"011_payment_detected.sql",
+		"012_payment_fiat.sql",
+		"013_watcher_reorgs.sql",
+		"014_wallet_index_counter.sql",
+		"015_idempotency_keys.sql",
+		"016_webhooks.sql",
+		"017_request_ids.sql",
+		"018_log_actors.sql",
+		"019_payment_cancelled.sql",
+		"020_payment_attempt_wallets.sql",
+		"021_payment_token.sql",
+		"022_payments_client_index.sql",
+		"023_outbox.sql",
+		"024_leases.sql",
+		"025_outbox_trace_parent.sql",
+		"026_reconciliation.sql",
+		"027_payment_export_index.sql",
+		"028_name_lengths.sql",
+		"029_client_mode.sql",
+		"030_account_settings.sql",
+		"031_account_soft_delete.sql",
+		"032_payments_account_open_index.sql",
+		"033_webhook_api_version.sql",
+		"034_webhook_delivery_inspection.sql",
+		"035_webhook_secret_rotation.sql",
+		"036_payments_client_confirmed_index.sql",
+		"037_payment_order_reference.sql",
+		"038_address_pool.sql",
+		"039_payment_version.sql",
+		"040_webhook_delivery_responses.sql",
+		"041_payments_created_at_index.sql",
+		"042_amount_suffix.sql",
+		"043_invoices.sql",
+		"044_idempotency_key_reclaim.sql",
+		"045_sweep_checks.sql",
+		"046_schema_migration_breaking.sql",
+		"047_idempotency_key_failed.sql",
 	}
 
 	for _, file := range expectedFiles {
@@ -52,6 +94,53 @@ func TestMigrationSequencing(t *testing.T) {
 	}
 }
 
+// Test clients migration contains required columns
+func TestClientsMigrationSchema(t *testing.T) {
+	content, err := os.ReadFile("001_clients.sql")
+	if err != nil {
+		t.Fatalf("Failed to read clients migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"CREATE TABLE clients",
+		"id UUID PRIMARY KEY DEFAULT gen_random_uuid()",
+		"name STRING NOT NULL",
+		"api_key STRING NOT NULL UNIQUE",
+		"is_active BOOL DEFAULT TRUE",
+		"created_at TIMESTAMPTZ DEFAULT now()",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Clients migration missing required element: %s", element)
+		}
+	}
+}
+
+// Test that api_key is unique, GetClientByAPIKey relies on it returning at most one row
+func TestClientsAPIKeyUnique(t *testing.T) {
+	content, err := os.ReadFile("001_clients.sql")
+	if err != nil {
+		t.Fatalf("Failed to read clients migration: %v", err)
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "api_key") {
+			if !strings.Contains(line, "UNIQUE") {
+				t.Errorf("api_key column must be UNIQUE, got: %s", line)
+			}
+			if !strings.Contains(line, "NOT NULL") {
+				t.Errorf("api_key column must be NOT NULL, got: %s", line)
+			}
+			return
+		}
+	}
+	t.Error("Clients migration missing api_key column")
+}
+
 // Test accounts migration contains required columns
 func TestAccountsMigrationSchema(t *testing.T) {
 	content, err := os.ReadFile("002_accounts.sql")
@@ -166,7 +255,351 @@ func TestLogsMigrationSchema(t *testing.T) {
 	}
 }
 
+// Test that unique_wallet is protected against concurrent pending payments and indexed for lookups
+func TestPaymentsUniqueWalletIndexes(t *testing.T) {
+	content, err := os.ReadFile("006_payments_unique_wallet.sql")
+	if err != nil {
+		t.Fatalf("Failed to read unique wallet migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"CREATE UNIQUE INDEX idx_payments_unique_wallet_pending ON payments(unique_wallet) WHERE status = 'PENDING'",
+		"CREATE INDEX idx_payments_unique_wallet_created_at ON payments(unique_wallet, created_at DESC)",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Unique wallet migration missing required element: %s", element)
+		}
+	}
+}
+
+// Test that DETECTED is a valid, non-final payment status
+func TestPaymentsDetectedStatus(t *testing.T) {
+	content, err := os.ReadFile("011_payment_detected.sql")
+	if err != nil {
+		t.Fatalf("Failed to read detected status migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"CHECK (status IN ('PENDING', 'DETECTED', 'UNDERPAID', 'CONFIRMED', 'EXPIRED'))",
+		"CREATE UNIQUE INDEX idx_payments_unique_wallet_open ON payments(unique_wallet) WHERE status IN ('PENDING', 'DETECTED', 'UNDERPAID')",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Detected status migration missing required element: %s", element)
+		}
+	}
+}
+
+// Test that fiat-priced payments keep their conversion, all or nothing
+func TestPaymentsFiatColumns(t *testing.T) {
+	content, err := os.ReadFile("012_payment_fiat.sql")
+	if err != nil {
+		t.Fatalf("Failed to read payment fiat migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ADD COLUMN fiat_amount DECIMAL(18,6)",
+		"ADD COLUMN fiat_currency STRING",
+		"ADD COLUMN exchange_rate DECIMAL(36,18)",
+		"ADD COLUMN rate_at TIMESTAMPTZ",
+		"ADD CONSTRAINT check_payments_fiat",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Payment fiat migration missing required element: %s", element)
+		}
+	}
+	if strings.Contains(migration, "NOT NULL DEFAULT") {
+		t.Error("Payment fiat columns must stay NULL for token-priced payments")
+	}
+}
+
+// Test that reorged transfers are kept but no longer block a re-credit
+func TestWatcherReorgSchema(t *testing.T) {
+	content, err := os.ReadFile("013_watcher_reorgs.sql")
+	if err != nil {
+		t.Fatalf("Failed to read watcher reorg migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE watcher_state ADD COLUMN recent_blocks JSONB NOT NULL DEFAULT '[]'",
+		"CHECK (status IN ('DETECTED', 'CONFIRMED', 'ORPHANED'))",
+		"CREATE UNIQUE INDEX idx_transactions_tx_hash_transfer_index ON transactions(tx_hash, transfer_index) WHERE status != 'ORPHANED'",
+		"CHECK (status IN ('PENDING', 'DETECTED', 'UNDERPAID', 'CONFIRMED', 'EXPIRED', 'REVIEW'))",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Watcher reorg migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestWalletIndexCounterSchema(t *testing.T) {
+	content, err := os.ReadFile("014_wallet_index_counter.sql")
+	if err != nil {
+		t.Fatalf("Failed to read wallet index counter migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"CREATE TABLE wallet_index_counters",
+		"id UUID PRIMARY KEY DEFAULT gen_random_uuid()",
+		"name STRING NOT NULL UNIQUE",
+		"next_index INT8 NOT NULL DEFAULT 0 CHECK (next_index >= 0)",
+		"created_at TIMESTAMPTZ DEFAULT now()",
+		"SELECT 'deposit', COALESCE(max(wallet_index) + 1, 0) FROM payments",
+		"CREATE UNIQUE INDEX idx_payments_wallet_index ON payments(wallet_index) WHERE wallet_index IS NOT NULL",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Wallet index counter migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestIdempotencyKeysSchema(t *testing.T) {
+	content, err := os.ReadFile("015_idempotency_keys.sql")
+	if err != nil {
+		t.Fatalf("Failed to read idempotency keys migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"CREATE TABLE idempotency_keys",
+		"id UUID PRIMARY KEY DEFAULT gen_random_uuid()",
+		"client_id UUID NOT NULL REFERENCES clients(id)",
+		"idempotency_key STRING NOT NULL",
+		"request_hash STRING NOT NULL",
+		"CHECK (status IN ('IN_PROGRESS', 'COMPLETED'))",
+		"response_body BYTES",
+		"created_at TIMESTAMPTZ DEFAULT now()",
+		"UNIQUE (client_id, idempotency_key)",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Idempotency keys migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestWebhooksSchema(t *testing.T) {
+	content, err := os.ReadFile("016_webhooks.sql")
+	if err != nil {
+		t.Fatalf("Failed to read webhooks migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"CREATE TABLE webhook_endpoints",
+		"client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE",
+		"secret STRING NOT NULL",
+		"CREATE TABLE webhook_deliveries",
+		"endpoint_id UUID NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE",
+		"payload JSONB NOT NULL",
+		"CHECK (status IN ('PENDING', 'DELIVERED', 'FAILED'))",
+		"next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now()",
+		"CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'PENDING'",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Webhooks migration missing required element: %s", element)
+		}
+	}
+}
+
 // Test that migrations don't contain dangerous operations
+func TestRequestIDsSchema(t *testing.T) {
+	content, err := os.ReadFile("017_request_ids.sql")
+	if err != nil {
+		t.Fatalf("Failed to read request IDs migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE logs ADD COLUMN request_id STRING",
+		"ALTER TABLE webhook_deliveries ADD COLUMN request_id STRING",
+		"CREATE INDEX idx_logs_request_id ON logs(request_id)",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Request IDs migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestLogActorsSchema(t *testing.T) {
+	content, err := os.ReadFile("018_log_actors.sql")
+	if err != nil {
+		t.Fatalf("Failed to read log actors migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE logs ADD COLUMN actor STRING",
+		"CREATE INDEX idx_logs_actor_created_at ON logs(actor, created_at DESC)",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Log actors migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestPaymentCancelledSchema(t *testing.T) {
+	content, err := os.ReadFile("019_payment_cancelled.sql")
+	if err != nil {
+		t.Fatalf("Failed to read payment cancelled migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE payments DROP CONSTRAINT IF EXISTS check_payments_status",
+		"'REVIEW', 'CANCELLED'",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Payment cancelled migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestPaymentAttemptWalletsSchema(t *testing.T) {
+	content, err := os.ReadFile("020_payment_attempt_wallets.sql")
+	if err != nil {
+		t.Fatalf("Failed to read payment attempt wallets migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE payment_attempts ADD COLUMN wallet_index INT8",
+		"SET wallet_index = p.wallet_index",
+		"CREATE INDEX idx_payment_attempts_generated_wallet ON payment_attempts(generated_wallet)",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Payment attempt wallets migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestPaymentTokenSchema(t *testing.T) {
+	content, err := os.ReadFile("021_payment_token.sql")
+	if err != nil {
+		t.Fatalf("Failed to read payment token migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE payments ADD COLUMN token STRING NOT NULL DEFAULT 'USDT'",
+		"check_payments_token",
+		"token IN ('TRX', 'USDT')",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Payment token migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestPaymentsClientIndexSchema(t *testing.T) {
+	content, err := os.ReadFile("022_payments_client_index.sql")
+	if err != nil {
+		t.Fatalf("Failed to read payments client index migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"CREATE INDEX idx_payments_client_id_id ON payments(client_id, id)",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Payments client index migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestOutboxSchema(t *testing.T) {
+	content, err := os.ReadFile("023_outbox.sql")
+	if err != nil {
+		t.Fatalf("Failed to read outbox migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"CREATE TABLE outbox",
+		"id UUID PRIMARY KEY",
+		"event_type STRING NOT NULL",
+		"aggregate_id UUID NOT NULL",
+		"payload JSONB NOT NULL",
+		"request_id STRING",
+		"created_at TIMESTAMPTZ NOT NULL DEFAULT now()",
+		"processed_at TIMESTAMPTZ",
+		"CREATE INDEX idx_outbox_unprocessed ON outbox(created_at, id) WHERE processed_at IS NULL",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Outbox migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestLeasesSchema(t *testing.T) {
+	content, err := os.ReadFile("024_leases.sql")
+	if err != nil {
+		t.Fatalf("Failed to read leases migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"CREATE TABLE leases",
+		"id UUID PRIMARY KEY DEFAULT gen_random_uuid()",
+		"name STRING NOT NULL UNIQUE",
+		"holder STRING NOT NULL",
+		"token INT8 NOT NULL CHECK (token > 0)",
+		"lease_until TIMESTAMPTZ NOT NULL",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Leases migration missing required element: %s", element)
+		}
+	}
+}
+
 func TestMigrationsSafety(t *testing.T) {
 	dangerousPatterns := []string{
 		"DROP DATABASE",
@@ -231,6 +664,7 @@ func TestMigrationsForeignKeys(t *testing.T) {
 		{"003_payments.sql", true, "accounts"},
 		{"004_payments_attempts.sql", true, "payments"},
 		{"005_logs.sql", true, "payments"},
+		{"007_transactions.sql", true, "payments"},
 	}
 
 	for _, tc := range testCases {
@@ -354,6 +788,11 @@ func TestMigrationDependencyOrder(t *testing.T) {
 		migrations[file] = string(content)
 	}
 
+	// Verify clients is created by the first migration
+	if !strings.Contains(migrations["001_clients.sql"], "CREATE TABLE clients") {
+		t.Error("Clients table must be created by 001_clients.sql")
+	}
+
 	// Verify clients comes before accounts
 	if !strings.Contains(migrations["002_accounts.sql"], "REFERENCES clients") {
 		t.Error("Accounts should reference clients, so clients must be created first")
@@ -373,4 +812,504 @@ func TestMigrationDependencyOrder(t *testing.T) {
 	if !strings.Contains(migrations["005_logs.sql"], "REFERENCES payments") {
 		t.Error("Logs should reference payments")
 	}
-}
\ No newline at end of file
+}
+func TestOutboxTraceParentSchema(t *testing.T) {
+	content, err := os.ReadFile("025_outbox_trace_parent.sql")
+	if err != nil {
+		t.Fatalf("Failed to read outbox trace parent migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE outbox ADD COLUMN trace_parent STRING",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Outbox trace parent migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestNameLengthsSchema(t *testing.T) {
+	content, err := os.ReadFile("028_name_lengths.sql")
+	if err != nil {
+		t.Fatalf("Failed to read name lengths migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE clients ADD CONSTRAINT clients_name_length CHECK (length(name) <= 200)",
+		"ALTER TABLE accounts ADD CONSTRAINT accounts_name_length CHECK (length(name) <= 200)",
+		"-- migrate:down",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Name lengths migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestClientModeSchema(t *testing.T) {
+	content, err := os.ReadFile("029_client_mode.sql")
+	if err != nil {
+		t.Fatalf("Failed to read client mode migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE clients ADD COLUMN mode STRING NOT NULL DEFAULT 'live' CHECK (mode IN ('live', 'test'))",
+		"ALTER TABLE payments ADD COLUMN mode STRING NOT NULL DEFAULT 'live' CHECK (mode IN ('live', 'test'))",
+		"DROP INDEX payments@idx_payments_wallet_index",
+		"CREATE UNIQUE INDEX idx_payments_mode_wallet_index ON payments(mode, wallet_index) WHERE wallet_index IS NOT NULL",
+		"-- migrate:down",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Client mode migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestAccountSettingsSchema(t *testing.T) {
+	content, err := os.ReadFile("030_account_settings.sql")
+	if err != nil {
+		t.Fatalf("Failed to read account settings migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE accounts ADD COLUMN default_expiry_seconds INT8 CHECK (default_expiry_seconds BETWEEN 60 AND 86400)",
+		"ALTER TABLE accounts ADD COLUMN default_webhook_endpoint_id UUID REFERENCES webhook_endpoints(id) ON DELETE SET NULL",
+		"ALTER TABLE accounts ADD COLUMN metadata JSONB",
+		"ALTER TABLE payments ADD COLUMN webhook_endpoint_id UUID REFERENCES webhook_endpoints(id) ON DELETE SET NULL",
+		"-- migrate:down",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Account settings migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestAccountSoftDeleteSchema(t *testing.T) {
+	content, err := os.ReadFile("031_account_soft_delete.sql")
+	if err != nil {
+		t.Fatalf("Failed to read account soft delete migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE accounts ADD COLUMN deleted_at TIMESTAMPTZ",
+		"ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_account_id_fkey",
+		"FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE RESTRICT",
+		"-- migrate:down",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Account soft delete migration missing required element: %s", element)
+		}
+	}
+
+	up := strings.Split(migration, "-- migrate:down")[0]
+	if strings.Contains(up, "ON DELETE CASCADE") {
+		t.Error("Payments must no longer cascade with their account")
+	}
+}
+
+func TestPaymentsAccountOpenIndexSchema(t *testing.T) {
+	content, err := os.ReadFile("032_payments_account_open_index.sql")
+	if err != nil {
+		t.Fatalf("Failed to read payments account open index migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"CREATE INDEX idx_payments_account_open ON payments(account_id) WHERE status IN ('PENDING', 'DETECTED')",
+		"-- migrate:down",
+		"DROP INDEX payments@idx_payments_account_open",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Payments account open index migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestWebhookAPIVersionSchema(t *testing.T) {
+	content, err := os.ReadFile("033_webhook_api_version.sql")
+	if err != nil {
+		t.Fatalf("Failed to read webhook api version migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE webhook_endpoints ADD COLUMN api_version STRING NOT NULL DEFAULT '2026-03-01'",
+		"-- migrate:down",
+		"ALTER TABLE webhook_endpoints DROP COLUMN api_version",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Webhook api version migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestWebhookDeliveryInspectionSchema(t *testing.T) {
+	content, err := os.ReadFile("034_webhook_delivery_inspection.sql")
+	if err != nil {
+		t.Fatalf("Failed to read webhook delivery inspection migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE webhook_deliveries ADD COLUMN last_response_body STRING",
+		"ALTER TABLE webhook_deliveries ADD COLUMN replay_of UUID REFERENCES webhook_deliveries(id) ON DELETE SET NULL",
+		"CREATE INDEX idx_webhook_deliveries_payment_created ON webhook_deliveries(payment_id, created_at DESC, id DESC)",
+		"-- migrate:down",
+		"DROP INDEX webhook_deliveries@idx_webhook_deliveries_payment_created",
+		"ALTER TABLE webhook_deliveries DROP COLUMN replay_of",
+		"ALTER TABLE webhook_deliveries DROP COLUMN last_response_body",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Webhook delivery inspection migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestWebhookSecretRotationSchema(t *testing.T) {
+	content, err := os.ReadFile("035_webhook_secret_rotation.sql")
+	if err != nil {
+		t.Fatalf("Failed to read webhook secret rotation migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE webhook_endpoints ADD COLUMN secondary_secret STRING",
+		"ALTER TABLE webhook_endpoints ADD COLUMN rotated_at TIMESTAMPTZ",
+		"-- migrate:down",
+		"ALTER TABLE webhook_endpoints DROP COLUMN rotated_at",
+		"ALTER TABLE webhook_endpoints DROP COLUMN secondary_secret",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Webhook secret rotation migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestPaymentsClientConfirmedIndexSchema(t *testing.T) {
+	content, err := os.ReadFile("036_payments_client_confirmed_index.sql")
+	if err != nil {
+		t.Fatalf("Failed to read payments client confirmed index migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"CREATE INDEX idx_payments_client_confirmed_at ON payments(client_id, confirmed_at) STORING (token, amount) WHERE status = 'CONFIRMED'",
+		"-- migrate:down",
+		"DROP INDEX payments@idx_payments_client_confirmed_at",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Payments client confirmed index migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestPaymentOrderReferenceSchema(t *testing.T) {
+	content, err := os.ReadFile("037_payment_order_reference.sql")
+	if err != nil {
+		t.Fatalf("Failed to read payment order reference migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE payments ADD COLUMN order_reference STRING CONSTRAINT payments_order_reference_length CHECK (length(order_reference) BETWEEN 1 AND 200)",
+		"ALTER TABLE payments ADD COLUMN metadata JSONB",
+		"CREATE UNIQUE INDEX idx_payments_client_order_reference ON payments(client_id, order_reference) WHERE order_reference IS NOT NULL",
+		"-- migrate:down",
+		"DROP INDEX payments@idx_payments_client_order_reference",
+		"ALTER TABLE payments DROP COLUMN metadata",
+		"ALTER TABLE payments DROP COLUMN order_reference",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Payment order reference migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestAddressPoolSchema(t *testing.T) {
+	content, err := os.ReadFile("038_address_pool.sql")
+	if err != nil {
+		t.Fatalf("Failed to read address pool migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"CREATE TABLE address_pool",
+		"account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE",
+		"address_index INT8 NOT NULL",
+		"address STRING NOT NULL",
+		"reserved_by_payment UUID,",
+		"id UUID PRIMARY KEY DEFAULT gen_random_uuid()",
+		"CONSTRAINT address_pool_address_key UNIQUE (address)",
+		"CONSTRAINT address_pool_account_index_key UNIQUE (account_id, address_index)",
+		"CREATE INDEX idx_address_pool_unreserved ON address_pool(account_id, address_index) WHERE reserved_by_payment IS NULL",
+		"CREATE UNIQUE INDEX idx_address_pool_reserved_by_payment ON address_pool(reserved_by_payment) WHERE reserved_by_payment IS NOT NULL",
+		"-- migrate:down",
+		"DROP TABLE address_pool",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Address pool migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestPaymentVersionSchema(t *testing.T) {
+	content, err := os.ReadFile("039_payment_version.sql")
+	if err != nil {
+		t.Fatalf("Failed to read payment version migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE payments ADD COLUMN version INT NOT NULL DEFAULT 1",
+		"-- migrate:down",
+		"ALTER TABLE payments DROP COLUMN version",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Payment version migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestWebhookDeliveryResponsesSchema(t *testing.T) {
+	content, err := os.ReadFile("040_webhook_delivery_responses.sql")
+	if err != nil {
+		t.Fatalf("Failed to read webhook delivery responses migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE webhook_deliveries ADD COLUMN last_response_headers JSONB",
+		"ALTER TABLE webhook_deliveries ADD COLUMN last_latency_ms INT4",
+		"ALTER TABLE webhook_deliveries ADD COLUMN attempt_history JSONB NOT NULL DEFAULT '[]'",
+		"-- migrate:down",
+		"ALTER TABLE webhook_deliveries DROP COLUMN attempt_history",
+		"ALTER TABLE webhook_deliveries DROP COLUMN last_latency_ms",
+		"ALTER TABLE webhook_deliveries DROP COLUMN last_response_headers",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Webhook delivery responses migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestPaymentsCreatedAtIndexSchema(t *testing.T) {
+	content, err := os.ReadFile("041_payments_created_at_index.sql")
+	if err != nil {
+		t.Fatalf("Failed to read payments created_at index migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"CREATE INDEX idx_payments_created_at ON payments(created_at) STORING (client_id, status, expires_at, confirmed_at)",
+		"-- migrate:down",
+		"DROP INDEX payments@idx_payments_created_at",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Payments created_at index migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestAmountSuffixSchema(t *testing.T) {
+	content, err := os.ReadFile("042_amount_suffix.sql")
+	if err != nil {
+		t.Fatalf("Failed to read amount suffix migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE accounts ADD COLUMN address_strategy STRING NOT NULL DEFAULT 'unique_wallet'",
+		"CHECK (address_strategy IN ('unique_wallet', 'amount_suffix'))",
+		"ALTER TABLE accounts ADD COLUMN deposit_wallet STRING",
+		"ALTER TABLE accounts ADD COLUMN deposit_wallet_index INT8",
+		"CREATE UNIQUE INDEX idx_accounts_deposit_wallet ON accounts(deposit_wallet) WHERE deposit_wallet IS NOT NULL",
+		"CREATE TABLE amount_suffix_reservations",
+		"suffix INT4 NOT NULL CHECK (suffix BETWEEN 1 AND 999)",
+		"expires_at TIMESTAMPTZ NOT NULL",
+		"CONSTRAINT amount_suffix_reservations_account_id_suffix_key UNIQUE (account_id, suffix)",
+		"CONSTRAINT amount_suffix_reservations_payment_id_key UNIQUE (payment_id)",
+		"WHERE status IN ('PENDING', 'DETECTED', 'UNDERPAID') AND wallet_index IS NOT NULL",
+		"-- migrate:down",
+		"DROP TABLE amount_suffix_reservations",
+		"ALTER TABLE accounts DROP COLUMN address_strategy",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Amount suffix migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestInvoicesSchema(t *testing.T) {
+	content, err := os.ReadFile("043_invoices.sql")
+	if err != nil {
+		t.Fatalf("Failed to read invoices migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"CREATE TABLE invoices",
+		"client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE",
+		"month DATE NOT NULL CHECK (extract(day FROM month) = 1)",
+		"CONSTRAINT invoices_client_id_month_key UNIQUE (client_id, month)",
+		"CREATE INDEX idx_invoices_month ON invoices(month DESC)",
+		"CREATE TABLE invoice_lines",
+		"invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE CASCADE",
+		"kind STRING NOT NULL CHECK (kind IN ('PERCENT', 'MINIMUM'))",
+		"fee DECIMAL(18,6) NOT NULL",
+		"CONSTRAINT invoice_lines_invoice_id_token_kind_key UNIQUE (invoice_id, token, kind)",
+		"-- migrate:down",
+		"DROP TABLE invoice_lines",
+		"DROP TABLE invoices",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Invoices migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestIdempotencyKeyReclaimSchema(t *testing.T) {
+	content, err := os.ReadFile("044_idempotency_key_reclaim.sql")
+	if err != nil {
+		t.Fatalf("Failed to read idempotency key reclaim migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE idempotency_keys ADD COLUMN response_headers JSONB",
+		"ALTER TABLE idempotency_keys ADD COLUMN claimed_at TIMESTAMPTZ NOT NULL DEFAULT now()",
+		"-- migrate:down",
+		"ALTER TABLE idempotency_keys DROP COLUMN claimed_at",
+		"ALTER TABLE idempotency_keys DROP COLUMN response_headers",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Idempotency key reclaim migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestSweepChecksSchema(t *testing.T) {
+	content, err := os.ReadFile("045_sweep_checks.sql")
+	if err != nil {
+		t.Fatalf("Failed to read sweep checks migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"CREATE TABLE sweep_checks",
+		"address STRING NOT NULL",
+		"token STRING NOT NULL",
+		"next_check_at TIMESTAMPTZ NOT NULL",
+		"CONSTRAINT sweep_checks_address_token_key UNIQUE (address, token)",
+		"-- migrate:down",
+		"DROP TABLE sweep_checks",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Sweep checks migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestSchemaMigrationBreakingSchema(t *testing.T) {
+	content, err := os.ReadFile("046_schema_migration_breaking.sql")
+	if err != nil {
+		t.Fatalf("Failed to read schema migration breaking migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS breaking BOOL NOT NULL DEFAULT false",
+		"-- migrate:down",
+		"ALTER TABLE schema_migrations DROP COLUMN breaking",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Schema migration breaking migration missing required element: %s", element)
+		}
+	}
+}
+
+func TestIdempotencyKeyFailedSchema(t *testing.T) {
+	content, err := os.ReadFile("047_idempotency_key_failed.sql")
+	if err != nil {
+		t.Fatalf("Failed to read idempotency key failed migration: %v", err)
+	}
+
+	migration := string(content)
+
+	requiredElements := []string{
+		"ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS check_status",
+		"ALTER TABLE idempotency_keys ADD CONSTRAINT check_idempotency_keys_status CHECK (status IN ('IN_PROGRESS', 'COMPLETED', 'FAILED'))",
+		"-- migrate:down",
+		"UPDATE idempotency_keys SET status = 'COMPLETED' WHERE status = 'FAILED'",
+		"ALTER TABLE idempotency_keys DROP CONSTRAINT check_idempotency_keys_status",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(migration, element) {
+			t.Errorf("Idempotency key failed migration missing required element: %s", element)
+		}
+	}
+}
@@ -17,6 +17,15 @@ func readMigration(t *testing.T, name string) string {
 	return string(data)
 }
 
+func TestMigrations_ClientsSchema(t *testing.T) {
+	s := readMigration(t, "001_clients.sql")
+	require.Contains(t, s, "CREATE TABLE clients")
+	for _, want := range []string{"name", "api_key", "is_active", "created_at"} {
+		require.Containsf(t, s, want, "expected %q in clients schema", want)
+	}
+	require.Contains(t, s, "api_key STRING NOT NULL UNIQUE", "GetClientByAPIKey depends on api_key being unique")
+}
+
 func TestMigrations_AccountsContainsAddressIndex(t *testing.T) {
 	s := readMigration(t, "002_accounts.sql")
 	require.Contains(t, s, "CREATE TABLE accounts")
@@ -45,4 +54,35 @@ func TestMigrations_LogsSchema(t *testing.T) {
 	for _, want := range []string{"event_type", "raw_data", "created_at"} {
 		require.Contains(t, s, want)
 	}
-}
\ No newline at end of file
+}
+func TestMigrations_TransactionsSchema(t *testing.T) {
+	s := readMigration(t, "007_transactions.sql")
+	require.Contains(t, s, "CREATE TABLE transactions")
+	for _, want := range []string{"payment_id", "tx_hash", "block_number", "confirmations", "status"} {
+		require.Contains(t, s, want)
+	}
+	require.Contains(t, s, "ON transactions(tx_hash, transfer_index)", "re-scanned blocks rely on this unique index")
+}
+
+func TestMigrations_WatcherStateSchema(t *testing.T) {
+	s := readMigration(t, "008_watcher_state.sql")
+	require.Contains(t, s, "CREATE TABLE watcher_state")
+	require.Contains(t, s, "name STRING NOT NULL UNIQUE", "UpsertWatcherHeight depends on name being unique")
+	require.Contains(t, s, "last_height INT8")
+}
+
+func TestMigrations_PaymentAmountMatching(t *testing.T) {
+	s := readMigration(t, "009_payment_amount_matching.sql")
+	require.Contains(t, s, "'UNDERPAID'")
+	require.Contains(t, s, "WHERE status IN ('PENDING', 'UNDERPAID')", "top-ups need the wallet to stay reserved")
+	require.Contains(t, s, "ADD COLUMN excess_amount DECIMAL(18,6)")
+}
+
+func TestMigrations_Sweeps(t *testing.T) {
+	s := readMigration(t, "010_sweeps.sql")
+	require.Contains(t, s, "ALTER TABLE payments ADD COLUMN wallet_index INT8")
+	require.Contains(t, s, "CHECK (kind IN ('DEPOSIT', 'SWEEP'))")
+	require.Contains(t, s, "CREATE TABLE sweeps")
+	require.Contains(t, s, "signed_tx JSONB NOT NULL", "a crashed run must rebroadcast, not re-sign")
+	require.Contains(t, s, "CREATE UNIQUE INDEX idx_sweeps_open ON sweeps(from_address, token) WHERE status IN ('SIGNED', 'SENT')")
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+func TestBuildPoolConfig_PoolSettings(t *testing.T) {
+	testCases := []struct {
+		name              string
+		dbCfg             config.DatabaseConfig
+		minConns          int32
+		maxConns          int32
+		maxConnLifetime   time.Duration
+		maxConnIdleTime   time.Duration
+		healthCheckPeriod time.Duration
+	}{
+		{
+			name:              "defaults",
+			dbCfg:             config.DatabaseConfig{MaxConnections: 10},
+			minConns:          2,
+			maxConns:          10,
+			maxConnLifetime:   time.Hour,
+			maxConnIdleTime:   30 * time.Minute,
+			healthCheckPeriod: time.Minute,
+		},
+		{
+			name:              "tiny worker",
+			dbCfg:             config.DatabaseConfig{MaxConnections: 1},
+			minConns:          1,
+			maxConns:          1,
+			maxConnLifetime:   time.Hour,
+			maxConnIdleTime:   30 * time.Minute,
+			healthCheckPeriod: time.Minute,
+		},
+		{
+			name: "high throughput api",
+			dbCfg: config.DatabaseConfig{
+				MaxConnections:    100,
+				MinConnections:    20,
+				MaxConnLifetime:   config.Duration(5 * time.Minute),
+				MaxConnIdleTime:   config.Duration(90 * time.Second),
+				HealthCheckPeriod: config.Duration(15 * time.Second),
+			},
+			minConns:          20,
+			maxConns:          100,
+			maxConnLifetime:   5 * time.Minute,
+			maxConnIdleTime:   90 * time.Second,
+			healthCheckPeriod: 15 * time.Second,
+		},
+		{
+			name:              "min equals max",
+			dbCfg:             config.DatabaseConfig{MaxConnections: 4, MinConnections: 4, MaxConnLifetime: config.Duration(2 * time.Hour)},
+			minConns:          4,
+			maxConns:          4,
+			maxConnLifetime:   2 * time.Hour,
+			maxConnIdleTime:   30 * time.Minute,
+			healthCheckPeriod: time.Minute,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dbCfg := tc.dbCfg
+			dbCfg.Host = "localhost"
+			dbCfg.Port = 26257
+			dbCfg.User = "root"
+			dbCfg.Password = "pass"
+
+			poolCfg, err := buildPoolConfig(&config.Config{DatabaseConfig: dbCfg})
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.minConns, poolCfg.MinConns)
+			assert.Equal(t, tc.maxConns, poolCfg.MaxConns)
+			assert.Equal(t, tc.maxConnLifetime, poolCfg.MaxConnLifetime)
+			assert.Equal(t, tc.maxConnIdleTime, poolCfg.MaxConnIdleTime)
+			assert.Equal(t, tc.healthCheckPeriod, poolCfg.HealthCheckPeriod)
+		})
+	}
+}
+
+func TestBuildPoolConfig_StatementTimeout(t *testing.T) {
+	testCases := []struct {
+		name    string
+		timeout config.Duration
+		want    string
+	}{
+		{"default", 0, "30000"},
+		{"configured", config.Duration(12 * time.Second), "12000"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dbCfg := config.DatabaseConfig{
+				Host:             "localhost",
+				User:             "root",
+				Password:         "pass",
+				MaxConnections:   5,
+				StatementTimeout: tc.timeout,
+			}
+
+			poolCfg, err := buildPoolConfig(&config.Config{DatabaseConfig: dbCfg})
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, poolCfg.ConnConfig.RuntimeParams["statement_timeout"])
+		})
+	}
+}
+
+func TestBuildPoolConfig_InvalidPoolSettings(t *testing.T) {
+	testCases := []struct {
+		name    string
+		dbCfg   config.DatabaseConfig
+		wantErr string
+	}{
+		{"min above max", config.DatabaseConfig{MaxConnections: 5, MinConnections: 6}, "must not exceed maxConnections"},
+		{"negative min", config.DatabaseConfig{MaxConnections: 5, MinConnections: -1}, "must not be negative"},
+		{"negative idle time", config.DatabaseConfig{MaxConnections: 5, MaxConnIdleTime: config.Duration(-time.Second)}, "maxConnIdleTime must be positive"},
+		{"negative health check", config.DatabaseConfig{MaxConnections: 5, HealthCheckPeriod: config.Duration(-time.Minute)}, "healthCheckPeriod must be positive"},
+		{"negative statement timeout", config.DatabaseConfig{MaxConnections: 5, StatementTimeout: config.Duration(-time.Second)}, "statementTimeout must be positive"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dbCfg := tc.dbCfg
+			dbCfg.Host = "localhost"
+			dbCfg.Port = 26257
+			dbCfg.Password = "pass"
+
+			_, err := buildPoolConfig(&config.Config{DatabaseConfig: dbCfg})
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
@@ -1,12 +1,71 @@
--- name: CreateAccount :exec
-INSERT INTO accounts (client_id, name) VALUES ($1, $2);
+-- name: CountAccountsByClientID :one
+-- Counts the accounts of the client that are not deleted.
+SELECT count(*) FROM accounts
+WHERE client_id = $1 AND deleted_at IS NULL;
+
+-- name: CreateAccount :one
+-- Creates an account of the client; a nil address_strategy gives it a
+-- wallet per payment.
+INSERT INTO accounts (client_id, name, default_expiry_seconds, default_webhook_endpoint_id, metadata, address_strategy)
+VALUES (sqlc.arg(client_id), sqlc.arg(name), sqlc.arg(default_expiry_seconds), sqlc.arg(default_webhook_endpoint_id), sqlc.arg(metadata),
+    COALESCE(sqlc.narg(address_strategy), 'unique_wallet'))
+RETURNING id, client_id, name, address_index, created_at, default_expiry_seconds, default_webhook_endpoint_id, metadata, deleted_at, address_strategy, deposit_wallet, deposit_wallet_index;
 
 -- name: GetAccountsByClientID :many
-SELECT id, client_id, name, created_at
+-- Lists the accounts of the client, leaving out soft deleted ones unless
+-- include_deleted is set.
+SELECT id, client_id, name, created_at, deleted_at
 FROM accounts
-WHERE client_id = $1;
+WHERE client_id = sqlc.arg(client_id) AND (deleted_at IS NULL OR sqlc.arg(include_deleted)::BOOL);
 
 -- name: GetAccountByIDAndClientID :one
-SELECT id, client_id, name, address_index, created_at
+-- Returns an account of the client; a soft deleted one only when
+-- include_deleted is set.
+SELECT id, client_id, name, address_index, created_at, default_expiry_seconds, default_webhook_endpoint_id, metadata, deleted_at, address_strategy, deposit_wallet, deposit_wallet_index
+FROM accounts
+WHERE id = sqlc.arg(id) AND client_id = sqlc.arg(client_id) AND (deleted_at IS NULL OR sqlc.arg(include_deleted)::BOOL);
+
+-- name: ListDepositWallets :many
+-- The wallets among wallets that are the deposit wallet of an account,
+-- shared by its amount_suffix payments.
+SELECT deposit_wallet::STRING
 FROM accounts
-WHERE id = $1 AND client_id = $2;
\ No newline at end of file
+WHERE deposit_wallet = ANY(sqlc.arg(wallets)::STRING[]);
+
+-- name: ReplaceAccount :one
+-- Replaces the name and settings of an account of the client. A nil
+-- address_strategy gives it a wallet per payment.
+UPDATE accounts
+SET name = sqlc.arg(name), default_expiry_seconds = sqlc.arg(default_expiry_seconds),
+    default_webhook_endpoint_id = sqlc.arg(default_webhook_endpoint_id), metadata = sqlc.arg(metadata),
+    address_strategy = COALESCE(sqlc.narg(address_strategy), 'unique_wallet')
+WHERE id = sqlc.arg(id) AND client_id = sqlc.arg(client_id) AND deleted_at IS NULL
+RETURNING id, client_id, name, address_index, created_at, default_expiry_seconds, default_webhook_endpoint_id, metadata, deleted_at, address_strategy, deposit_wallet, deposit_wallet_index;
+
+-- name: SoftDeleteAccount :one
+-- Marks an account of the client deleted unless one of its payments is still
+-- PENDING or DETECTED.
+UPDATE accounts
+SET deleted_at = now()
+WHERE id = sqlc.arg(id) AND client_id = sqlc.arg(client_id) AND deleted_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM payments
+    WHERE account_id = sqlc.arg(id) AND status IN ('PENDING', 'DETECTED')
+  )
+RETURNING id, client_id, name, address_index, created_at, default_expiry_seconds, default_webhook_endpoint_id, metadata, deleted_at, address_strategy, deposit_wallet, deposit_wallet_index;
+
+-- name: UpdateAccount :one
+-- Changes the fields given of an account of the client, keeping the others.
+UPDATE accounts
+SET name = COALESCE(sqlc.narg(name), name), address_strategy = COALESCE(sqlc.narg(address_strategy), address_strategy)
+WHERE id = sqlc.arg(id) AND client_id = sqlc.arg(client_id) AND deleted_at IS NULL
+RETURNING id, client_id, name, address_index, created_at, default_expiry_seconds, default_webhook_endpoint_id, metadata, deleted_at, address_strategy, deposit_wallet, deposit_wallet_index;
+
+-- name: SetAccountDepositWallet :one
+-- Gives the account the deposit wallet its amount_suffix payments pay into
+-- unless it has one already, and returns the one it has.
+UPDATE accounts
+SET deposit_wallet = COALESCE(deposit_wallet, sqlc.arg(deposit_wallet)::STRING),
+    deposit_wallet_index = COALESCE(deposit_wallet_index, sqlc.arg(deposit_wallet_index)::INT8)
+WHERE id = sqlc.arg(id)
+RETURNING deposit_wallet::STRING, deposit_wallet_index::INT8;
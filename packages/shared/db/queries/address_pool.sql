@@ -0,0 +1,33 @@
+-- name: CreatePoolAddresses :execrows
+-- Adds derived addresses to the account's pool, address_indexes[i] being
+-- the index addresses[i] was derived at.
+INSERT INTO address_pool (account_id, address_index, address)
+SELECT sqlc.arg(account_id), t.address_index, t.address
+FROM unnest(sqlc.arg(address_indexes)::INT8[], sqlc.arg(addresses)::STRING[]) AS t(address_index, address);
+
+-- name: ListAccountsBelowLowWater :many
+-- Accounts of active clients, deleted ones aside, with fewer than low_water
+-- unreserved pooled addresses, and the mode their addresses derive in.
+SELECT a.id AS account_id, c.mode, count(p.id) AS unreserved
+FROM accounts a
+JOIN clients c ON c.id = a.client_id
+LEFT JOIN address_pool p ON p.account_id = a.id AND p.reserved_by_payment IS NULL
+WHERE a.deleted_at IS NULL AND c.is_active IS NOT FALSE
+GROUP BY a.id, c.mode
+HAVING count(p.id) < sqlc.arg(low_water)::INT8
+ORDER BY a.id;
+
+-- name: ReserveAddress :one
+-- Claims the account's lowest unreserved pooled address for payment_id.
+-- Rows another transaction is claiming are skipped rather than waited on,
+-- so concurrent claims never return the same address.
+UPDATE address_pool
+SET reserved_by_payment = sqlc.arg(payment_id)
+WHERE id = (
+    SELECT id FROM address_pool
+    WHERE account_id = sqlc.arg(account_id) AND reserved_by_payment IS NULL
+    ORDER BY address_index
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+) AND reserved_by_payment IS NULL
+RETURNING id, account_id, address_index, address, reserved_by_payment, created_at;
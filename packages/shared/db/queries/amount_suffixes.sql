@@ -0,0 +1,16 @@
+-- name: ReserveAmountSuffix :one
+-- Reserves the account's lowest suffix no unexpired reservation holds for
+-- payment_id until expires_at, overwriting the expired reservation of it if
+-- there is one. It returns no row when every suffix is held.
+INSERT INTO amount_suffix_reservations (account_id, suffix, payment_id, expires_at)
+SELECT sqlc.arg(account_id), s.suffix, sqlc.arg(payment_id), sqlc.arg(expires_at)
+FROM generate_series(1, 999) AS s(suffix)
+WHERE NOT EXISTS (
+    SELECT 1 FROM amount_suffix_reservations r
+    WHERE r.account_id = sqlc.arg(account_id) AND r.suffix = s.suffix AND r.expires_at > sqlc.arg(now)
+)
+ORDER BY s.suffix
+LIMIT 1
+ON CONFLICT (account_id, suffix) DO UPDATE
+SET payment_id = excluded.payment_id, expires_at = excluded.expires_at, created_at = now()
+RETURNING suffix;
@@ -1,14 +1,36 @@
--- name: CreateClient :exec
-INSERT INTO clients (name, api_key) VALUES ($1, $2);
+-- name: CreateClient :one
+INSERT INTO clients (name, api_key, mode) VALUES ($1, $2, $3)
+RETURNING id, name, api_key, is_active, created_at, mode;
 
 -- name: GetClientByAPIKey :one
-SELECT id, name, api_key, is_active, created_at
+SELECT id, name, api_key, is_active, created_at, mode
 FROM clients
 WHERE api_key = $1 AND is_active = TRUE
 LIMIT 1;
 
+-- name: ClientExistsByAPIKey :one
+-- Reports whether any client, active or not, holds the API key.
+SELECT EXISTS (SELECT 1 FROM clients WHERE api_key = $1);
+
 -- name: GetClientByID :one
-SELECT id, name, api_key, is_active, created_at
+SELECT id, name, api_key, is_active, created_at, mode
 FROM clients
 WHERE id = $1
 LIMIT 1;
+
+-- name: DeactivateClient :one
+UPDATE clients SET is_active = FALSE
+WHERE id = $1
+RETURNING id, name, api_key, is_active, created_at, mode;
+
+-- name: RotateClientAPIKey :one
+UPDATE clients SET api_key = sqlc.arg(api_key)
+WHERE id = sqlc.arg(id)
+RETURNING id, name, api_key, is_active, created_at, mode;
+
+-- name: ListClients :many
+SELECT id, name, api_key, is_active, created_at, mode
+FROM clients
+WHERE id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg('limit');
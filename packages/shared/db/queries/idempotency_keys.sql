@@ -0,0 +1,38 @@
+-- name: ClaimIdempotencyKey :one
+-- Claims the key for a request. A key held by the same request whose claim
+-- has not been renewed for stale_after was left IN_PROGRESS by a process that
+-- died mid-request and is claimed again; any other held key is not.
+INSERT INTO idempotency_keys (client_id, idempotency_key, request_hash)
+VALUES ($1, $2, $3)
+ON CONFLICT (client_id, idempotency_key) DO UPDATE SET claimed_at = now()
+WHERE idempotency_keys.status = 'IN_PROGRESS'
+  AND idempotency_keys.request_hash = excluded.request_hash
+  AND idempotency_keys.claimed_at < now() - sqlc.arg(stale_after)::INTERVAL
+RETURNING id, client_id, idempotency_key, request_hash, status, response_status, response_body, completed_at, created_at, response_headers, claimed_at;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET status = 'COMPLETED', response_status = $3, response_body = $4, response_headers = $5, completed_at = now()
+WHERE client_id = $1 AND idempotency_key = $2 AND status = 'IN_PROGRESS';
+
+-- name: FailIdempotencyKey :exec
+-- Marks a key whose request succeeded but whose response could not be
+-- stored, so it is never claimed again.
+UPDATE idempotency_keys
+SET status = 'FAILED', completed_at = now()
+WHERE client_id = $1 AND idempotency_key = $2 AND status = 'IN_PROGRESS';
+
+-- name: GetIdempotencyKey :one
+SELECT id, client_id, idempotency_key, request_hash, status, response_status, response_body, completed_at, created_at, response_headers, claimed_at
+FROM idempotency_keys
+WHERE client_id = $1 AND idempotency_key = $2;
+
+-- name: ReleaseIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE client_id = $1 AND idempotency_key = $2 AND status = 'IN_PROGRESS';
+
+-- name: RenewIdempotencyKey :exec
+-- Renews the claim of a request still running, so it does not go stale.
+UPDATE idempotency_keys
+SET claimed_at = now()
+WHERE client_id = $1 AND idempotency_key = $2 AND status = 'IN_PROGRESS';
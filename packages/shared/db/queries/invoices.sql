@@ -0,0 +1,49 @@
+-- name: CreateInvoice :one
+-- Returns no row when the client's invoice for the month already exists.
+INSERT INTO invoices (client_id, month, payments)
+VALUES ($1, $2, $3)
+ON CONFLICT (client_id, month) DO NOTHING
+RETURNING id, client_id, month, payments, created_at;
+
+-- name: CreateInvoiceLine :exec
+INSERT INTO invoice_lines (invoice_id, token, kind, payments, amount, rate, fee)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: GetInvoiceByClientMonth :one
+SELECT id, client_id, month, payments, created_at
+FROM invoices
+WHERE client_id = $1 AND month = $2;
+
+-- name: GetMonthlyFeeBasis :many
+-- The client's live payments confirmed in [month_start, month_end) per token
+-- of the fee schedule given by tokens, percents and minimums, with how many
+-- of them, and how much of their amount, had a percentage fee under the
+-- token's minimum. Tokens left out of the schedule are left out of the sums.
+SELECT p.token,
+    count(*) AS payments,
+    sum(p.amount)::DECIMAL AS amount,
+    count(*) FILTER (WHERE p.amount * s.percent / 100 < s.minimum) AS minimum_payments,
+    COALESCE(sum(p.amount) FILTER (WHERE p.amount * s.percent / 100 < s.minimum), 0)::DECIMAL AS minimum_amount
+FROM payments p
+JOIN unnest(sqlc.arg(tokens)::STRING[], sqlc.arg(percents)::DECIMAL[], sqlc.arg(minimums)::DECIMAL[]) AS s(token, percent, minimum)
+  ON s.token = p.token
+WHERE p.client_id = sqlc.arg(client_id) AND p.status = 'CONFIRMED' AND p.mode = 'live'
+  AND p.confirmed_at >= sqlc.arg(month_start) AND p.confirmed_at < sqlc.arg(month_end)
+GROUP BY p.token
+ORDER BY p.token;
+
+-- name: ListInvoiceLines :many
+SELECT id, invoice_id, token, kind, payments, amount, rate, fee, created_at
+FROM invoice_lines
+WHERE invoice_id = ANY(sqlc.arg(invoice_ids)::UUID[])
+ORDER BY invoice_id, token, kind DESC;
+
+-- name: ListInvoices :many
+-- A page of the invoices, newest month first, of client_id and month; a NULL
+-- filter matches every invoice.
+SELECT id, client_id, month, payments, created_at
+FROM invoices
+WHERE (sqlc.narg(client_id)::UUID IS NULL OR client_id = sqlc.narg(client_id))
+  AND (sqlc.narg(month)::DATE IS NULL OR month = sqlc.narg(month))
+ORDER BY month DESC, client_id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
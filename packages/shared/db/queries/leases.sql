@@ -0,0 +1,18 @@
+-- name: AcquireLease :one
+INSERT INTO leases (name, holder, token, lease_until)
+VALUES (sqlc.arg(name), sqlc.arg(holder), 1, now() + sqlc.arg(ttl)::INTERVAL)
+ON CONFLICT (name) DO UPDATE
+SET holder = excluded.holder, token = leases.token + 1, lease_until = excluded.lease_until
+WHERE leases.lease_until <= now()
+RETURNING id, name, holder, token, lease_until, created_at;
+
+-- name: ReleaseLease :exec
+UPDATE leases
+SET lease_until = now()
+WHERE name = $1 AND token = $2;
+
+-- name: RenewLease :one
+UPDATE leases
+SET lease_until = now() + sqlc.arg(ttl)::INTERVAL
+WHERE name = sqlc.arg(name) AND token = sqlc.arg(token) AND lease_until > now()
+RETURNING id, name, holder, token, lease_until, created_at;
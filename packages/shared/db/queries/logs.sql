@@ -0,0 +1,46 @@
+-- name: CountLogsOlderThan :one
+SELECT count(*) FROM logs
+WHERE event_type = $1 AND created_at < $2;
+
+-- name: CreateLog :exec
+INSERT INTO logs (payment_id, event_type, message, raw_data, request_id, actor)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: DeleteLogsBatch :execrows
+-- Deletes the oldest logs of an event type created before a cutoff, at
+-- most limit of them, so each delete stays a small transaction.
+DELETE FROM logs
+WHERE event_type = sqlc.arg(event_type) AND created_at < sqlc.arg(created_before)
+ORDER BY created_at
+LIMIT sqlc.arg('limit');
+
+-- name: ListClientLogs :many
+-- A page of the logs of a client's payments, newest first, filtered as in
+-- ListLogs. A log of no payment belongs to no client and is never listed.
+SELECT l.id, l.payment_id, l.event_type, l.message, l.raw_data, l.created_at, l.request_id, l.actor
+FROM logs l
+JOIN payments p ON p.id = l.payment_id
+WHERE p.client_id = sqlc.arg(client_id)
+  AND (sqlc.narg(event_type)::STRING IS NULL OR l.event_type = sqlc.narg(event_type))
+  AND (sqlc.narg(payment_id)::UUID IS NULL OR l.payment_id = sqlc.narg(payment_id))
+  AND (sqlc.narg('from')::TIMESTAMPTZ IS NULL OR l.created_at >= sqlc.narg('from'))
+  AND (sqlc.narg('to')::TIMESTAMPTZ IS NULL OR l.created_at < sqlc.narg('to'))
+ORDER BY l.created_at DESC, l.id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: ListLogEventTypes :many
+SELECT DISTINCT event_type FROM logs
+ORDER BY event_type;
+
+-- name: ListLogs :many
+-- A page of the logs, newest first, of event_type and payment_id and created
+-- in [from, to); a NULL filter matches every log. idx_logs_event_type_created_at
+-- serves a filter on the event type.
+SELECT id, payment_id, event_type, message, raw_data, created_at, request_id, actor
+FROM logs
+WHERE (sqlc.narg(event_type)::STRING IS NULL OR event_type = sqlc.narg(event_type))
+  AND (sqlc.narg(payment_id)::UUID IS NULL OR payment_id = sqlc.narg(payment_id))
+  AND (sqlc.narg('from')::TIMESTAMPTZ IS NULL OR created_at >= sqlc.narg('from'))
+  AND (sqlc.narg('to')::TIMESTAMPTZ IS NULL OR created_at < sqlc.narg('to'))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
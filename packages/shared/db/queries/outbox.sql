@@ -0,0 +1,16 @@
+-- name: ClaimOutboxEvents :many
+SELECT id, event_type, aggregate_id, payload, request_id, created_at, processed_at, trace_parent
+FROM outbox
+WHERE processed_at IS NULL
+ORDER BY created_at, id
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: CreateOutboxEvent :exec
+INSERT INTO outbox (id, event_type, aggregate_id, payload, request_id, trace_parent)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: MarkOutboxEventsProcessed :exec
+UPDATE outbox
+SET processed_at = now()
+WHERE id = ANY(sqlc.arg(ids)::UUID[]) AND processed_at IS NULL;
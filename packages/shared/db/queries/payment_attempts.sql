@@ -0,0 +1,28 @@
+-- name: CreatePaymentAttempt :one
+-- The attempt count is bumped without touching the version: a new attempt
+-- is only ever recorded with CreatePayment or UpdatePaymentWallet, which
+-- set the version.
+WITH counted AS (
+    UPDATE payments SET attempt_count = sqlc.arg(attempt_number)
+    WHERE id = sqlc.arg(payment_id)
+)
+INSERT INTO payment_attempts (payment_id, attempt_number, generated_wallet, wallet_index)
+VALUES (sqlc.arg(payment_id), sqlc.arg(attempt_number), sqlc.arg(generated_wallet), sqlc.arg(wallet_index))
+RETURNING id, payment_id, attempt_number, generated_wallet, generated_at, wallet_index;
+
+-- name: ListPaymentAttempts :many
+SELECT id, payment_id, attempt_number, generated_wallet, generated_at, wallet_index
+FROM payment_attempts
+WHERE payment_id = ANY(sqlc.arg(payment_ids)::UUID[])
+ORDER BY payment_id, attempt_number;
+
+-- name: ListRecentAttemptWallets :many
+-- The newest attempts that recorded the index their wallet was derived at,
+-- with the account and mode of their payment.
+SELECT pa.payment_id, pa.attempt_number, pa.generated_wallet, pa.wallet_index::INT8 AS wallet_index,
+       p.account_id, p.mode
+FROM payment_attempts pa
+JOIN payments p ON p.id = pa.payment_id
+WHERE pa.wallet_index IS NOT NULL
+ORDER BY pa.generated_at DESC, pa.id
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,233 @@
+-- name: CreatePayment :one
+-- A payment whose wallet comes from the address pool is created with the id
+-- the pool reserved the wallet for; any other gets a random one.
+INSERT INTO payments (client_id, account_id, amount, unique_wallet, expires_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, id)
+VALUES (sqlc.arg(client_id), sqlc.arg(account_id), sqlc.arg(amount), sqlc.arg(unique_wallet), sqlc.arg(expires_at), sqlc.narg(wallet_index), sqlc.narg(fiat_amount), sqlc.narg(fiat_currency), sqlc.narg(exchange_rate), sqlc.narg(rate_at), sqlc.arg(token), sqlc.arg(mode), sqlc.narg(webhook_endpoint_id), sqlc.narg(order_reference), sqlc.narg(metadata), COALESCE(sqlc.narg(id)::UUID, gen_random_uuid()))
+RETURNING id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version;
+
+-- name: GetPayment :one
+SELECT id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version
+FROM payments
+WHERE id = $1;
+
+-- name: GetPaymentByOrderReference :one
+-- The client's payment created with the given order reference.
+SELECT id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version
+FROM payments
+WHERE client_id = sqlc.arg(client_id) AND order_reference = sqlc.arg(order_reference)::STRING;
+
+-- name: GetPaymentByUniqueWallet :one
+SELECT id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version
+FROM payments
+WHERE unique_wallet = $1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: GetPaymentByWallet :one
+SELECT id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version
+FROM payments
+WHERE (unique_wallet = sqlc.arg(wallet)
+   OR id IN (SELECT payment_id FROM payment_attempts WHERE generated_wallet = sqlc.arg(wallet)))
+  AND mode = sqlc.arg(mode)
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: GetPendingPaymentByAnyWallet :one
+-- The payment awaiting funds that was given wallet by any of its attempts,
+-- the current one included; settled payments are left to GetPaymentByWallet.
+SELECT p.id, p.client_id, p.account_id, p.amount, p.unique_wallet, p.status, p.expires_at, p.confirmed_at, p.attempt_count, p.created_at, p.wallet_index, p.fiat_amount, p.fiat_currency, p.exchange_rate, p.rate_at, p.token, p.mode, p.webhook_endpoint_id, p.order_reference, p.metadata, p.version
+FROM payment_attempts a
+JOIN payments p ON p.id = a.payment_id
+WHERE a.generated_wallet = sqlc.arg(wallet) AND p.status IN ('PENDING', 'DETECTED', 'UNDERPAID')
+ORDER BY p.created_at DESC
+LIMIT 1;
+
+-- name: GetOpenPaymentByWalletAndAmount :one
+-- The payment awaiting funds of exactly amount among those sharing wallet,
+-- the deposit wallet of an amount_suffix account, in mode. Their amounts
+-- differ by their suffixes, so at most one matches.
+SELECT id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version
+FROM payments
+WHERE unique_wallet = sqlc.arg(wallet) AND amount = sqlc.arg(amount) AND mode = sqlc.arg(mode)
+  AND wallet_index IS NULL AND status IN ('PENDING', 'DETECTED', 'UNDERPAID')
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: ConfirmPayment :one
+-- Refuses a payment whose live deposits fall short of its amount by more
+-- than tolerance_bps, whatever status it was left in.
+UPDATE payments
+SET status = 'CONFIRMED', confirmed_at = now(), version = version + 1
+WHERE id = sqlc.arg(id) AND status IN ('PENDING', 'DETECTED') AND version = sqlc.arg(version)
+  AND (
+    SELECT COALESCE(SUM(t.amount), 0) FROM transactions t
+    WHERE t.payment_id = payments.id AND t.kind = 'DEPOSIT' AND t.status != 'ORPHANED'
+  ) * 10000 >= amount * (10000 - sqlc.arg(tolerance_bps)::INT8)
+RETURNING id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version;
+
+-- name: CancelPayment :one
+UPDATE payments
+SET status = 'CANCELLED', version = version + 1
+WHERE id = sqlc.arg(id) AND status = sqlc.arg(from_status) AND version = sqlc.arg(version) AND NOT EXISTS (
+    SELECT 1 FROM transactions
+    WHERE payment_id = sqlc.arg(id) AND kind = 'DEPOSIT' AND status != 'ORPHANED'
+)
+RETURNING id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version;
+
+-- name: UpdatePaymentStatus :one
+UPDATE payments
+SET status = sqlc.arg(to_status), version = version + 1
+WHERE id = sqlc.arg(id) AND status = sqlc.arg(from_status) AND version = sqlc.arg(version)
+RETURNING id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version;
+
+-- name: UpdatePaymentWallet :one
+UPDATE payments
+SET unique_wallet = sqlc.arg(unique_wallet), wallet_index = sqlc.arg(wallet_index), version = version + 1
+WHERE id = sqlc.arg(id) AND status = 'PENDING' AND COALESCE(attempt_count, 0) = sqlc.arg(attempt_count)::INT AND version = sqlc.arg(version)
+RETURNING id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version;
+
+-- name: RevertPaymentConfirmation :one
+UPDATE payments
+SET status = sqlc.arg(to_status), confirmed_at = NULL, version = version + 1
+WHERE id = sqlc.arg(id) AND status = 'CONFIRMED' AND version = sqlc.arg(version)
+RETURNING id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version;
+
+-- name: ListRecentPendingPayments :many
+SELECT id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version
+FROM payments
+WHERE status = 'PENDING' AND created_at >= sqlc.arg(created_after) AND expires_at > now()
+  AND mode = sqlc.arg(mode)
+ORDER BY created_at;
+
+-- name: ListExpiredPayments :many
+SELECT id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version
+FROM payments
+WHERE status IN ('PENDING', 'DETECTED', 'UNDERPAID') AND expires_at <= sqlc.arg(expired_before)
+  AND mode = sqlc.arg(mode)
+ORDER BY expires_at
+LIMIT sqlc.arg('limit');
+
+-- name: ListClientPayments :many
+SELECT id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version
+FROM payments
+WHERE client_id = sqlc.arg(client_id) AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg('limit');
+
+-- name: ListPayments :many
+SELECT id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version
+FROM payments
+WHERE id > sqlc.arg(after_id) AND (sqlc.narg(status)::STRING IS NULL OR status = sqlc.narg(status))
+ORDER BY id
+LIMIT sqlc.arg('limit');
+
+-- name: ListPaymentExport :many
+-- A page of the payments a client created in [created_from, created_to),
+-- after the (created_at, id) key of the previous page.
+SELECT p.id, a.name AS account_name, p.amount, p.token, p.status, p.unique_wallet, p.created_at, p.confirmed_at,
+    (SELECT t.tx_hash FROM transactions t
+     WHERE t.payment_id = p.id AND t.kind = 'DEPOSIT' AND t.status != 'ORPHANED'
+     ORDER BY t.block_number, t.tx_hash, t.transfer_index
+     LIMIT 1) AS tx_hash
+FROM payments p
+JOIN accounts a ON a.id = p.account_id
+WHERE p.client_id = sqlc.arg(client_id)
+  AND p.created_at >= sqlc.arg(created_from) AND p.created_at < sqlc.arg(created_to)
+  AND (sqlc.narg(status)::STRING IS NULL OR p.status = sqlc.narg(status))
+  AND (p.created_at, p.id) > (sqlc.arg(after_created_at)::TIMESTAMPTZ, sqlc.arg(after_id)::UUID)
+ORDER BY p.created_at, p.id
+LIMIT sqlc.arg('limit');
+
+-- name: CountPendingPaymentsByAccountID :one
+-- Counts the payments of the account still waiting for funds.
+SELECT count(*) FROM payments
+WHERE account_id = $1 AND status IN ('PENDING', 'DETECTED');
+
+-- name: CountClientPaymentsByStatus :many
+-- Counts the client's payments created since month_from by status, and how
+-- many of them were created since week_from and today_from.
+SELECT status,
+    count(*) FILTER (WHERE created_at >= sqlc.arg(today_from)) AS today_count,
+    count(*) FILTER (WHERE created_at >= sqlc.arg(week_from)) AS week_count,
+    count(*) AS month_count
+FROM payments
+WHERE client_id = sqlc.arg(client_id) AND created_at >= sqlc.arg(month_from)
+GROUP BY status
+ORDER BY status;
+
+-- name: GetDailyConfirmedVolume :many
+-- The client's confirmed volume per UTC day and token since confirmed_from.
+SELECT (confirmed_at AT TIME ZONE 'UTC')::DATE AS day, token, sum(amount)::DECIMAL AS volume, count(*) AS payments
+FROM payments
+WHERE client_id = sqlc.arg(client_id) AND status = 'CONFIRMED' AND confirmed_at >= sqlc.arg(confirmed_from)
+GROUP BY day, token
+ORDER BY day, token;
+
+-- name: GetPaymentFunnel :many
+-- The payments created in [created_from, created_to) per UTC hour or day,
+-- as bucket is 'hour' or 'day', with how many of them a transfer was seen
+-- for, were confirmed before they expired, and expired. Every client's
+-- payments unless client_id is set.
+SELECT date_trunc(sqlc.arg(bucket)::STRING, created_at AT TIME ZONE 'UTC')::TIMESTAMP AS bucket,
+    count(*) AS created,
+    count(*) FILTER (WHERE status IN ('DETECTED', 'UNDERPAID', 'CONFIRMED')) AS detected,
+    count(*) FILTER (WHERE status = 'CONFIRMED' AND confirmed_at <= expires_at) AS confirmed,
+    count(*) FILTER (WHERE status = 'EXPIRED') AS expired
+FROM payments
+WHERE created_at >= sqlc.arg(created_from) AND created_at < sqlc.arg(created_to)
+  AND (sqlc.narg(client_id)::UUID IS NULL OR client_id = sqlc.narg(client_id))
+GROUP BY bucket
+ORDER BY bucket;
+
+-- name: ListRecentClientPayments :many
+-- The client's newest payments.
+SELECT id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version
+FROM payments
+WHERE client_id = sqlc.arg(client_id)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: PaymentExists :one
+SELECT EXISTS (SELECT 1 FROM payments WHERE id = $1 AND client_id = $2);
+
+-- name: ListPaymentStatuses :many
+SELECT id, status
+FROM payments
+WHERE id = ANY(sqlc.arg(ids)::UUID[]);
+
+-- name: AllocateWalletIndexes :one
+-- Takes count consecutive indexes from the counter at once, for the address
+-- pool, returning the first of them.
+INSERT INTO wallet_index_counters (name, next_index) VALUES (sqlc.arg(name), sqlc.arg(count)::INT8)
+ON CONFLICT (name) DO UPDATE
+SET next_index = wallet_index_counters.next_index + sqlc.arg(count)::INT8, updated_at = now()
+RETURNING next_index - sqlc.arg(count)::INT8 AS first_index;
+
+-- name: NextWalletIndex :one
+-- Each mode derives its wallets from its own mnemonic, so each has its own
+-- counter, created by its first payment.
+INSERT INTO wallet_index_counters (name, next_index) VALUES (sqlc.arg(name), 1)
+ON CONFLICT (name) DO UPDATE
+SET next_index = wallet_index_counters.next_index + 1, updated_at = now()
+RETURNING next_index - 1 AS wallet_index;
+
+-- name: SearchPaymentsByWallet :many
+-- The client's payments that handed out the wallet, as their current wallet
+-- or in an earlier attempt, newest first.
+SELECT id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version
+FROM payments
+WHERE client_id = sqlc.arg(client_id)
+  AND (unique_wallet = sqlc.arg(wallet)::STRING
+       OR id IN (SELECT payment_id FROM payment_attempts WHERE generated_wallet = sqlc.arg(wallet)::STRING))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: SearchPaymentsByTxHash :many
+-- The client's payments with a transfer, deposit or sweep, in the
+-- transaction. Orphaned transfers are left out.
+SELECT id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version
+FROM payments
+WHERE client_id = sqlc.arg(client_id)
+  AND id IN (SELECT payment_id FROM transactions WHERE tx_hash = sqlc.arg(tx_hash)::STRING AND status != 'ORPHANED')
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
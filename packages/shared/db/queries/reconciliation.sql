@@ -0,0 +1,47 @@
+-- name: CreateReconciliationMismatch :exec
+INSERT INTO reconciliation_mismatches (report_id, payment_id, kind, wallet, token, tx_hash, expected, actual, detail)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+
+-- name: CreateReconciliationReport :one
+INSERT INTO reconciliation_reports (window_start, window_end, payments_checked, mismatches)
+VALUES ($1, $2, $3, $4)
+RETURNING id, window_start, window_end, payments_checked, mismatches, created_at;
+
+-- name: GetLatestReconciliationReport :one
+SELECT id, window_start, window_end, payments_checked, mismatches, created_at
+FROM reconciliation_reports
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: GetReconciliationReport :one
+SELECT id, window_start, window_end, payments_checked, mismatches, created_at
+FROM reconciliation_reports
+WHERE id = $1;
+
+-- name: ListReconciliationMismatches :many
+SELECT id, report_id, payment_id, kind, wallet, token, tx_hash, expected, actual, detail, created_at
+FROM reconciliation_mismatches
+WHERE report_id = $1
+ORDER BY payment_id, wallet, kind, tx_hash;
+
+-- name: ListReconciliationPayments :many
+-- Confirmed live payments whose confirmation falls in [since, until).
+-- shared_wallet marks those paid to their account's deposit wallet, whose
+-- balance belongs to all of the account's payments.
+SELECT p.id, p.token, p.unique_wallet, p.amount, p.confirmed_at,
+  (p.wallet_index IS NULL AND a.deposit_wallet IS NOT NULL AND a.deposit_wallet = p.unique_wallet)::BOOL AS shared_wallet
+FROM payments p
+JOIN accounts a ON a.id = p.account_id
+WHERE p.status = 'CONFIRMED' AND p.confirmed_at >= sqlc.arg(since) AND p.confirmed_at < sqlc.arg(until)
+  AND p.mode = 'live'
+ORDER BY p.confirmed_at, p.id;
+
+-- name: GetWalletFlows :one
+-- What was deposited to and swept from a wallet in token, across all of the
+-- payments it was recorded for.
+SELECT
+  COALESCE(SUM(amount) FILTER (WHERE kind = 'DEPOSIT' AND to_address = sqlc.arg(wallet)), 0)::DECIMAL(18,6) AS deposited,
+  COALESCE(SUM(amount) FILTER (WHERE kind = 'SWEEP' AND from_address = sqlc.arg(wallet)), 0)::DECIMAL(18,6) AS swept
+FROM transactions
+WHERE token = sqlc.arg(token) AND status != 'ORPHANED'
+  AND (to_address = sqlc.arg(wallet) OR from_address = sqlc.arg(wallet));
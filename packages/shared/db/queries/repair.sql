@@ -0,0 +1,50 @@
+-- name: ListAttemptCountDrift :many
+-- Payments whose attempt_count is not the number of their attempts. One
+-- with neither predates attempt tracking and is left out.
+SELECT p.id, p.attempt_count, count(pa.id) AS attempts
+FROM payments p
+LEFT JOIN payment_attempts pa ON pa.payment_id = p.id
+GROUP BY p.id, p.attempt_count
+HAVING p.attempt_count IS DISTINCT FROM count(pa.id)
+   AND NOT (p.attempt_count IS NULL AND count(pa.id) = 0)
+ORDER BY p.id;
+
+-- name: ListWalletDrift :many
+-- Payments whose wallet is not the one their latest attempt generated.
+WITH latest AS (
+    SELECT DISTINCT ON (payment_id) payment_id, attempt_number, generated_wallet, wallet_index
+    FROM payment_attempts
+    ORDER BY payment_id, attempt_number DESC
+)
+SELECT p.id, p.unique_wallet, l.attempt_number, l.generated_wallet AS latest_wallet
+FROM payments p
+JOIN latest l ON l.payment_id = p.id
+WHERE l.generated_wallet <> p.unique_wallet
+ORDER BY p.id;
+
+-- name: ListConfirmedWithoutDeposit :many
+-- Confirmed payments without a single recorded deposit.
+SELECT p.id, p.unique_wallet, p.token
+FROM payments p
+WHERE p.status = 'CONFIRMED'
+  AND NOT EXISTS (SELECT 1 FROM transactions t WHERE t.payment_id = p.id AND t.kind = 'DEPOSIT')
+ORDER BY p.id;
+
+-- name: SyncAttemptCount :one
+-- Sets the payment's attempt_count to the number of its attempts.
+UPDATE payments
+SET attempt_count = (SELECT count(*) FROM payment_attempts WHERE payment_id = sqlc.arg(id))::INT4, version = version + 1
+WHERE id = sqlc.arg(id)
+RETURNING attempt_count;
+
+-- name: SyncPaymentWallet :one
+-- Points the payment at the wallet and index of its latest attempt.
+UPDATE payments
+SET (unique_wallet, wallet_index) = (
+    SELECT generated_wallet, wallet_index FROM payment_attempts
+    WHERE payment_id = sqlc.arg(id)
+    ORDER BY attempt_number DESC
+    LIMIT 1
+), version = version + 1
+WHERE id = sqlc.arg(id)
+RETURNING unique_wallet, wallet_index;
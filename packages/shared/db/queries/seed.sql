@@ -0,0 +1,41 @@
+-- name: UpsertClient :one
+INSERT INTO clients (id, name, api_key, is_active)
+VALUES ($1, $2, $3, TRUE)
+ON CONFLICT (id) DO UPDATE
+SET name = excluded.name, api_key = excluded.api_key, is_active = TRUE
+RETURNING id, name, api_key, is_active, created_at, mode;
+
+-- name: UpsertAccount :one
+INSERT INTO accounts (id, client_id, name)
+VALUES ($1, $2, $3)
+ON CONFLICT (id) DO UPDATE
+SET name = excluded.name, deleted_at = NULL
+RETURNING id, client_id, name, address_index, created_at;
+
+-- name: UpsertPayment :one
+INSERT INTO payments (id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, wallet_index, token)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (id) DO UPDATE
+SET amount = excluded.amount, unique_wallet = excluded.unique_wallet, status = excluded.status,
+    expires_at = excluded.expires_at, confirmed_at = excluded.confirmed_at,
+    attempt_count = excluded.attempt_count, wallet_index = excluded.wallet_index, token = excluded.token,
+    version = payments.version + 1
+RETURNING id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version;
+
+-- name: UpsertPaymentAttempt :one
+INSERT INTO payment_attempts (id, payment_id, attempt_number, generated_wallet, wallet_index)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (id) DO UPDATE
+SET generated_wallet = excluded.generated_wallet, wallet_index = excluded.wallet_index
+RETURNING id, payment_id, attempt_number, generated_wallet, generated_at, wallet_index;
+
+-- name: UpsertLog :exec
+INSERT INTO logs (id, payment_id, event_type, message, raw_data, actor)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (id) DO UPDATE
+SET event_type = excluded.event_type, message = excluded.message, raw_data = excluded.raw_data, actor = excluded.actor;
+
+-- name: WipeData :exec
+-- Referencing tables come before the tables they reference.
+TRUNCATE TABLE reconciliation_mismatches, reconciliation_reports, webhook_deliveries, webhook_endpoints,
+    outbox, logs, transactions, sweeps, payment_attempts, idempotency_keys, payments, accounts, clients;
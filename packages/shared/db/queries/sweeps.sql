@@ -0,0 +1,59 @@
+-- name: CreateSweep :one
+INSERT INTO sweeps (payment_id, token, contract_address, from_address, to_address, amount, tx_id, signed_tx, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, payment_id, token, contract_address, from_address, to_address, amount, tx_id, signed_tx, expires_at, status, created_at, updated_at;
+
+-- name: DeferSweepCandidate :exec
+-- Leaves the wallet out of the sweep candidates until next_check_at.
+INSERT INTO sweep_checks (address, token, next_check_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (address, token) DO UPDATE SET next_check_at = excluded.next_check_at, updated_at = now();
+
+-- name: ListOpenSweeps :many
+SELECT id, payment_id, token, contract_address, from_address, to_address, amount, tx_id, signed_tx, expires_at, status, created_at, updated_at
+FROM sweeps
+WHERE status IN ('SIGNED', 'SENT')
+ORDER BY created_at;
+
+-- name: ListSweepCandidates :many
+SELECT c.payment_id, c.unique_wallet, c.wallet_index, c.token
+FROM (
+  SELECT p.id AS payment_id, d.to_address AS unique_wallet, d.token, p.confirmed_at,
+    CASE WHEN d.to_address = p.unique_wallet THEN p.wallet_index ELSE a.wallet_index END AS wallet_index
+  FROM payments p
+  JOIN (SELECT DISTINCT payment_id, to_address, token FROM transactions WHERE kind = 'DEPOSIT') d ON d.payment_id = p.id
+  LEFT JOIN payment_attempts a ON a.payment_id = p.id AND a.generated_wallet = d.to_address
+  -- Test-mode deposits sit on the test network, out of the sweeper's reach.
+  WHERE p.status = 'CONFIRMED' AND p.mode = 'live'
+  UNION ALL
+  -- The payments of an amount_suffix account share its deposit wallet and
+  -- have no index of their own. The wallet is swept whole for the latest of
+  -- them confirmed, and again whenever a later one is.
+  (
+    SELECT DISTINCT ON (ac.id, d.token) p.id, ac.deposit_wallet, d.token, p.confirmed_at, ac.deposit_wallet_index
+    FROM accounts ac
+    JOIN payments p ON p.account_id = ac.id
+    JOIN (SELECT DISTINCT payment_id, to_address, token FROM transactions WHERE kind = 'DEPOSIT') d
+      ON d.payment_id = p.id AND d.to_address = ac.deposit_wallet
+    WHERE ac.deposit_wallet_index IS NOT NULL AND p.status = 'CONFIRMED' AND p.mode = 'live'
+    ORDER BY ac.id, d.token, p.confirmed_at DESC
+  )
+) c
+WHERE c.wallet_index IS NOT NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM sweeps s
+    WHERE s.payment_id = c.payment_id AND s.from_address = c.unique_wallet AND s.token = c.token
+      AND s.status NOT IN ('REJECTED', 'EXPIRED')
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM sweep_checks k
+    WHERE k.address = c.unique_wallet AND k.token = c.token AND k.next_check_at > now()
+  )
+ORDER BY c.confirmed_at
+LIMIT $1;
+
+-- name: UpdateSweepStatus :one
+UPDATE sweeps
+SET status = sqlc.arg(to_status), updated_at = now()
+WHERE id = sqlc.arg(id) AND status = sqlc.arg(from_status)
+RETURNING id, payment_id, token, contract_address, from_address, to_address, amount, tx_id, signed_tx, expires_at, status, created_at, updated_at;
@@ -0,0 +1,42 @@
+-- name: CreateTransaction :one
+INSERT INTO transactions (payment_id, tx_hash, transfer_index, token, contract_address, from_address, to_address, amount, block_number, block_hash, excess_amount)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id, payment_id, tx_hash, transfer_index, token, contract_address, from_address, to_address, amount, block_number, block_hash, confirmations, status, created_at, excess_amount, kind;
+
+-- name: ListDetectedTransactions :many
+SELECT id, payment_id, tx_hash, transfer_index, token, contract_address, from_address, to_address, amount, block_number, block_hash, confirmations, status, created_at, excess_amount, kind
+FROM transactions
+WHERE status = 'DETECTED' AND payment_id IN (SELECT id FROM payments WHERE mode = sqlc.arg(mode))
+ORDER BY block_number;
+
+-- name: ListPaymentTransactions :many
+SELECT id, payment_id, tx_hash, transfer_index, token, contract_address, from_address, to_address, amount, block_number, block_hash, confirmations, status, created_at, excess_amount, kind
+FROM transactions
+WHERE payment_id = $1 AND status != 'ORPHANED'
+ORDER BY block_number, tx_hash, transfer_index;
+
+-- name: ListTransactionsByBlockHashes :many
+SELECT id, payment_id, tx_hash, transfer_index, token, contract_address, from_address, to_address, amount, block_number, block_hash, confirmations, status, created_at, excess_amount, kind
+FROM transactions
+WHERE block_hash = ANY(sqlc.arg(block_hashes)::STRING[]) AND kind = 'DEPOSIT'
+ORDER BY block_number, tx_hash, transfer_index;
+
+-- name: UpdateTransactionConfirmations :exec
+UPDATE transactions
+SET confirmations = $2, status = $3
+WHERE id = $1 AND status != 'ORPHANED';
+
+-- name: UpdateTransactionBlock :exec
+UPDATE transactions
+SET block_number = $2, block_hash = $3, confirmations = $4
+WHERE id = $1 AND status != 'ORPHANED';
+
+-- name: SumPaymentTransfers :one
+SELECT COALESCE(SUM(amount), 0)::DECIMAL(18,6) AS total
+FROM transactions
+WHERE payment_id = $1 AND kind = 'DEPOSIT' AND status != 'ORPHANED';
+
+-- name: CreateSweepTransaction :one
+INSERT INTO transactions (payment_id, tx_hash, token, contract_address, from_address, to_address, amount, block_number, block_hash, confirmations, status, kind)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, 'CONFIRMED', 'SWEEP')
+RETURNING id, payment_id, tx_hash, transfer_index, token, contract_address, from_address, to_address, amount, block_number, block_hash, confirmations, status, created_at, excess_amount, kind;
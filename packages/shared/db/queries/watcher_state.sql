@@ -0,0 +1,26 @@
+-- name: GetWatcherHeight :one
+SELECT last_height
+FROM watcher_state
+WHERE name = $1;
+
+-- name: GetWatcherState :one
+SELECT last_height, recent_blocks
+FROM watcher_state
+WHERE name = $1;
+
+-- name: UpsertWatcherState :one
+-- Only overwrites a row still at expected_height, or inserts one when
+-- expected_height is NULL, so two replicas of a watcher cannot interleave
+-- their writes. No row is returned when the check fails.
+INSERT INTO watcher_state (name, last_height, recent_blocks)
+VALUES ($1, $2, $3)
+ON CONFLICT (name) DO UPDATE SET last_height = excluded.last_height, recent_blocks = excluded.recent_blocks, updated_at = now()
+WHERE watcher_state.last_height = sqlc.narg(expected_height)
+RETURNING last_height;
+
+-- name: ResetWatcherState :exec
+-- Sets a watcher's position unconditionally and forgets its recent blocks,
+-- for an operator recovering a stuck watcher.
+INSERT INTO watcher_state (name, last_height, recent_blocks)
+VALUES ($1, $2, '[]')
+ON CONFLICT (name) DO UPDATE SET last_height = excluded.last_height, recent_blocks = '[]', updated_at = now();
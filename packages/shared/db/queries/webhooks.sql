@@ -0,0 +1,118 @@
+-- name: ClaimWebhookDeliveries :many
+-- Claims up to limit due deliveries, oldest first, by moving their
+-- next_attempt_at to claimed_until. Workers polling at once skip each other's
+-- rows, so each delivery is sent by one of them. Recording the attempt sets
+-- next_attempt_at again; if the worker dies first, the claim lapses at
+-- claimed_until and the delivery is sent again. Each row carries its
+-- claimed_until, which the queries recording the attempt match, so a worker
+-- whose claim lapsed and was taken over cannot overwrite the new one.
+WITH due AS (
+    SELECT d.id, d.next_attempt_at
+    FROM webhook_deliveries d
+    JOIN webhook_endpoints e ON e.id = d.endpoint_id
+    WHERE d.status = 'PENDING' AND d.next_attempt_at <= now() AND e.is_active
+    ORDER BY d.next_attempt_at
+    LIMIT sqlc.arg('limit')
+    FOR UPDATE OF d SKIP LOCKED
+), claimed AS (
+    UPDATE webhook_deliveries d
+    SET next_attempt_at = sqlc.arg(claimed_until)
+    FROM due
+    WHERE d.id = due.id AND d.status = 'PENDING'
+    RETURNING d.id, d.endpoint_id, d.payment_id, d.event_type, d.payload, d.attempts, d.request_id, d.attempt_history,
+        d.next_attempt_at AS claimed_until, due.next_attempt_at AS due_at
+)
+SELECT c.id, c.endpoint_id, c.payment_id, c.event_type, c.payload, c.attempts, c.request_id, c.attempt_history, e.url,
+    e.secret, e.api_version, e.secondary_secret, e.rotated_at, c.claimed_until
+FROM claimed c
+JOIN webhook_endpoints e ON e.id = c.endpoint_id
+ORDER BY c.due_at;
+
+-- name: ClearRotatedWebhookSecrets :execrows
+-- Drops the secrets replaced at or before rotated_before, whose overlap
+-- window has ended.
+UPDATE webhook_endpoints
+SET secondary_secret = NULL
+WHERE secondary_secret IS NOT NULL AND rotated_at <= sqlc.arg(rotated_before);
+
+-- name: CreateWebhookDeliveries :exec
+INSERT INTO webhook_deliveries (endpoint_id, payment_id, event_type, payload, request_id)
+SELECT e.id, p.id, sqlc.arg(event_type), sqlc.arg(payload), sqlc.arg(request_id)
+FROM payments p
+JOIN webhook_endpoints e ON e.client_id = p.client_id
+WHERE p.id = sqlc.arg(payment_id) AND e.is_active
+  AND (p.webhook_endpoint_id IS NULL OR e.id = p.webhook_endpoint_id);
+
+-- name: DeferWebhookDelivery :exec
+-- Postpones a delivery without counting an attempt, as when its endpoint's
+-- host is being held back after failing.
+UPDATE webhook_deliveries
+SET next_attempt_at = $2
+WHERE id = $1 AND status = 'PENDING' AND next_attempt_at = sqlc.arg(claimed_until);
+
+-- name: FailWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status = 'FAILED', attempts = attempts + 1, last_status_code = $2, last_error = $3, last_response_body = $4,
+    last_response_headers = $5, last_latency_ms = $6, attempt_history = $7
+WHERE id = $1 AND status = 'PENDING' AND next_attempt_at = sqlc.arg(claimed_until);
+
+-- name: GetActiveWebhookEndpoint :one
+SELECT id, client_id, url, secret, is_active, created_at, api_version, secondary_secret, rotated_at
+FROM webhook_endpoints
+WHERE id = $1 AND client_id = $2 AND is_active;
+
+-- name: GetWebhookDelivery :one
+-- A delivery to one of the client's endpoints.
+SELECT d.id, d.endpoint_id, d.payment_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at,
+    d.last_status_code, d.last_error, d.delivered_at, d.created_at, d.request_id, d.last_response_body, d.replay_of,
+    d.last_response_headers, d.last_latency_ms, d.attempt_history, e.url, e.is_active
+FROM webhook_deliveries d
+JOIN webhook_endpoints e ON e.id = d.endpoint_id
+WHERE d.id = $1 AND e.client_id = $2;
+
+-- name: ListWebhookDeliveries :many
+-- A page of the deliveries to the client's endpoints, newest first, after
+-- before_id, the delivery the previous page ended with.
+SELECT d.id, d.endpoint_id, d.payment_id, d.event_type, d.status, d.attempts, d.next_attempt_at,
+    d.last_status_code, d.last_error, d.delivered_at, d.created_at, d.request_id, d.replay_of, d.last_latency_ms, e.url
+FROM webhook_deliveries d
+JOIN webhook_endpoints e ON e.id = d.endpoint_id
+WHERE e.client_id = sqlc.arg(client_id)
+  AND (sqlc.narg(payment_id)::UUID IS NULL OR d.payment_id = sqlc.narg(payment_id))
+  AND (sqlc.narg(status)::STRING IS NULL OR d.status = sqlc.narg(status))
+  AND (sqlc.narg(before_id)::UUID IS NULL OR (d.created_at, d.id) < (
+      SELECT b.created_at, b.id FROM webhook_deliveries b WHERE b.id = sqlc.narg(before_id)))
+ORDER BY d.created_at DESC, d.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: MarkWebhookDelivered :exec
+UPDATE webhook_deliveries
+SET status = 'DELIVERED', attempts = attempts + 1, last_status_code = $2, last_error = NULL, last_response_body = $3,
+    last_response_headers = $4, last_latency_ms = $5, attempt_history = $6, delivered_at = now()
+WHERE id = $1 AND status = 'PENDING' AND next_attempt_at = sqlc.arg(claimed_until);
+
+-- name: ReplayWebhookDelivery :one
+-- Queues a copy of a delivery to one of the client's active endpoints as a
+-- new PENDING delivery. The original is left as it is.
+INSERT INTO webhook_deliveries (endpoint_id, payment_id, event_type, payload, request_id, replay_of)
+SELECT d.endpoint_id, d.payment_id, d.event_type, d.payload, sqlc.narg(request_id), d.id
+FROM webhook_deliveries d
+JOIN webhook_endpoints e ON e.id = d.endpoint_id
+WHERE d.id = sqlc.arg(id) AND e.client_id = sqlc.arg(client_id) AND e.is_active
+RETURNING id, endpoint_id, payment_id, event_type, payload, status, attempts, next_attempt_at, last_status_code,
+    last_error, delivered_at, created_at, request_id, last_response_body, replay_of, last_response_headers,
+    last_latency_ms, attempt_history;
+
+-- name: RescheduleWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET attempts = attempts + 1, next_attempt_at = $2, last_status_code = $3, last_error = $4, last_response_body = $5,
+    last_response_headers = $6, last_latency_ms = $7, attempt_history = $8
+WHERE id = $1 AND status = 'PENDING' AND next_attempt_at = sqlc.arg(claimed_until);
+
+-- name: RotateWebhookEndpointSecret :one
+-- Makes secret the signing secret of one of the client's active endpoints,
+-- keeping the one it replaces as secondary_secret.
+UPDATE webhook_endpoints
+SET secondary_secret = secret, secret = sqlc.arg(secret), rotated_at = now()
+WHERE id = sqlc.arg(id) AND client_id = sqlc.arg(client_id) AND is_active
+RETURNING id, client_id, url, secret, is_active, created_at, api_version, secondary_secret, rotated_at;
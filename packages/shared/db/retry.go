@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+// RetryOptions controls the exponential backoff used by ConnectWithRetry.
+type RetryOptions struct {
+	// InitialDelay is the upper bound of the first wait between attempts.
+	InitialDelay time.Duration
+	// MaxDelay caps the wait between two attempts.
+	MaxDelay time.Duration
+	// MaxElapsedTime bounds the total time spent retrying. Zero retries until
+	// ctx is done.
+	MaxElapsedTime time.Duration
+}
+
+// DefaultRetryOptions suits a gateway waiting for CockroachDB to come up in
+// Kubernetes.
+func DefaultRetryOptions() RetryOptions {
+	return RetryOptions{
+		InitialDelay:   500 * time.Millisecond,
+		MaxDelay:       30 * time.Second,
+		MaxElapsedTime: 2 * time.Minute,
+	}
+}
+
+// ConnectWithRetry behaves like DbConnect but keeps retrying pool creation and
+// the initial ping with exponential backoff and jitter until it succeeds,
+// ctx is done, or opts.MaxElapsedTime passes. Invalid configuration fails
+// immediately.
+func ConnectWithRetry(ctx context.Context, cfg *config.Config, opts RetryOptions, poolOpts ...Option) (*pgxpool.Pool, error) {
+	poolCfg, err := buildPoolConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range poolOpts {
+		opt(poolCfg)
+	}
+
+	return retryConnect(ctx, opts, func(ctx context.Context) (*pgxpool.Pool, error) {
+		return connect(ctx, poolCfg)
+	})
+}
+
+func retryConnect(ctx context.Context, opts RetryOptions, connectFn func(context.Context) (*pgxpool.Pool, error)) (*pgxpool.Pool, error) {
+	if opts.InitialDelay <= 0 {
+		opts.InitialDelay = DefaultRetryOptions().InitialDelay
+	}
+	if opts.MaxDelay < opts.InitialDelay {
+		opts.MaxDelay = opts.InitialDelay
+	}
+
+	start := time.Now()
+	delay := opts.InitialDelay
+	for attempt := 1; ; attempt++ {
+		pool, err := connectFn(ctx)
+		if err == nil {
+			return pool, nil
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("database connection aborted after %d attempts: %w", attempt, ctx.Err())
+		}
+
+		wait := jitter(delay)
+		if opts.MaxElapsedTime > 0 && time.Since(start)+wait > opts.MaxElapsedTime {
+			return nil, fmt.Errorf("database connection failed after %d attempts: %w", attempt, err)
+		}
+
+		slog.Warn("database connection attempt failed", "attempt", attempt, "retry_in", wait, "error", err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("database connection aborted after %d attempts: %w", attempt, ctx.Err())
+		case <-timer.C:
+		}
+
+		delay = min(delay*2, opts.MaxDelay)
+	}
+}
+
+// jitter picks a wait in [d/2, d] so replicas restarting together spread out.
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(half+1)
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+func TestRetryConnect_SucceedsAfterFailures(t *testing.T) {
+	attempts := 0
+	want := &pgxpool.Pool{}
+
+	pool, err := retryConnect(context.Background(), RetryOptions{
+		InitialDelay:   time.Millisecond,
+		MaxDelay:       2 * time.Millisecond,
+		MaxElapsedTime: time.Second,
+	}, func(ctx context.Context) (*pgxpool.Pool, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("connection refused")
+		}
+		return want, nil
+	})
+
+	require.NoError(t, err)
+	assert.Same(t, want, pool)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestRetryConnect_GivesUpAfterMaxElapsed(t *testing.T) {
+	attempts := 0
+	start := time.Now()
+
+	pool, err := retryConnect(context.Background(), RetryOptions{
+		InitialDelay:   10 * time.Millisecond,
+		MaxDelay:       20 * time.Millisecond,
+		MaxElapsedTime: 100 * time.Millisecond,
+	}, func(ctx context.Context) (*pgxpool.Pool, error) {
+		attempts++
+		return nil, errors.New("connection refused")
+	})
+	elapsed := time.Since(start)
+
+	assert.Nil(t, pool)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "connection refused")
+	assert.Greater(t, attempts, 2)
+	assert.Less(t, elapsed, 150*time.Millisecond)
+}
+
+func TestRetryConnect_HonorsContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := retryConnect(ctx, RetryOptions{
+		InitialDelay: time.Second,
+		MaxDelay:     time.Second,
+	}, func(ctx context.Context) (*pgxpool.Pool, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestJitter_Bounds(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		d := jitter(100 * time.Millisecond)
+		assert.GreaterOrEqual(t, d, 50*time.Millisecond)
+		assert.LessOrEqual(t, d, 100*time.Millisecond)
+	}
+}
+
+func TestConnectWithRetry_ClosedPort(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+
+	cfg := &config.Config{
+		DatabaseConfig: config.DatabaseConfig{
+			User:           "root",
+			Password:       "root",
+			Host:           "127.0.0.1",
+			Port:           port,
+			Database:       "defaultdb",
+			MaxConnections: 4,
+			SSLMode:        config.SSLModeDisable,
+		},
+	}
+
+	start := time.Now()
+	pool, err := ConnectWithRetry(context.Background(), cfg, RetryOptions{
+		InitialDelay:   20 * time.Millisecond,
+		MaxDelay:       40 * time.Millisecond,
+		MaxElapsedTime: 200 * time.Millisecond,
+	})
+	elapsed := time.Since(start)
+
+	assert.Nil(t, pool)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "database connection failed after")
+	assert.GreaterOrEqual(t, elapsed, 50*time.Millisecond)
+	assert.Less(t, elapsed, 2*time.Second)
+}
+
+func TestConnectWithRetry_InvalidConfigFailsFast(t *testing.T) {
+	cfg := &config.Config{DatabaseConfig: config.DatabaseConfig{SSLMode: "bogus"}}
+
+	start := time.Now()
+	_, err := ConnectWithRetry(context.Background(), cfg, DefaultRetryOptions())
+
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SchemaVersion returns the version of the newest embedded migration, which
+// is the schema version this binary is built for.
+func SchemaVersion() (int64, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, errors.New("no migrations embedded")
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// CheckSchema compares the migrations applied to the database with the ones
+// embedded in the binary, and is meant to run before a service starts:
+//
+//   - When the database has a breaking migration the binary does not
+//     embed, it fails with ErrSchemaIncompatible; the binary is too old to
+//     run against that schema.
+//   - When the database lacks embedded migrations, it applies them if
+//     autoMigrate is set and fails with ErrSchemaBehind otherwise.
+//     autoMigrate is meant for single-replica development setups; in
+//     production `tpg migrate up` runs before the rollout.
+//   - When the database only has migrations the binary does not embed that
+//     are not breaking, e.g. while a newer release rolls out, it logs a
+//     warning and lets the service start.
+//
+// Unless it migrates, it only reads the database, so a service whose role
+// may not alter the schema can run it; a database no migration has run on
+// has every embedded migration pending.
+func CheckSchema(ctx context.Context, pool *pgxpool.Pool, autoMigrate bool) error {
+	return checkSchema(ctx, pool, autoMigrate)
+}
+
+func checkSchema(ctx context.Context, db migrationDB, autoMigrate bool) error {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+	drift := compareSchema(migrations, applied)
+	migrate, err := drift.check(autoMigrate)
+	if err != nil {
+		return err
+	}
+	if migrate {
+		slog.Info("migrating database schema", "version", drift.Current, "expected", drift.Expected, "pending", len(drift.Pending))
+		if err := migrateTo(ctx, db, -1); err != nil {
+			return err
+		}
+	}
+	if len(drift.Unknown) > 0 {
+		slog.Warn("database schema is newer than this binary",
+			"version", drift.Current, "expected", drift.Expected, "unknown_migrations", len(drift.Unknown))
+	}
+	return nil
+}
+
+// schemaDrift is how the database schema differs from the embedded
+// migrations.
+type schemaDrift struct {
+	// Expected is the newest embedded version and Current the newest
+	// applied one, 0 for an empty database.
+	Expected int64
+	Current  int64
+	// Pending are the embedded migrations not applied.
+	Pending []Migration
+	// Unknown are the applied migrations the binary does not embed.
+	Unknown []appliedMigration
+}
+
+// compareSchema compares embedded and applied migrations, both sorted by
+// version.
+func compareSchema(embedded []Migration, applied []appliedMigration) schemaDrift {
+	var d schemaDrift
+	if len(embedded) > 0 {
+		d.Expected = embedded[len(embedded)-1].Version
+	}
+	if len(applied) > 0 {
+		d.Current = applied[len(applied)-1].Version
+	}
+
+	known := make(map[int64]bool, len(embedded))
+	for _, m := range embedded {
+		known[m.Version] = true
+	}
+	done := make(map[int64]bool, len(applied))
+	for _, m := range applied {
+		done[m.Version] = true
+		if !known[m.Version] {
+			d.Unknown = append(d.Unknown, m)
+		}
+	}
+	for _, m := range embedded {
+		if !done[m.Version] {
+			d.Pending = append(d.Pending, m)
+		}
+	}
+	return d
+}
+
+// check decides whether a service may start on the schema: err refuses it,
+// and migrate asks for the pending migrations to be applied first.
+func (d schemaDrift) check(autoMigrate bool) (migrate bool, err error) {
+	for _, m := range d.Unknown {
+		if m.Breaking {
+			return false, fmt.Errorf("%w: migration %s is breaking and this binary only knows schema version %d; deploy a newer release",
+				ErrSchemaIncompatible, m.Name, d.Expected)
+		}
+	}
+	if len(d.Pending) == 0 {
+		return false, nil
+	}
+	if !autoMigrate {
+		return false, fmt.Errorf("%w: %d migrations up to version %d are not applied; run `tpg migrate up` or set database.autoMigrate",
+			ErrSchemaBehind, len(d.Pending), d.Expected)
+	}
+	return true, nil
+}
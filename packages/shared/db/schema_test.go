@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	migrations, err := LoadMigrations()
+	require.NoError(t, err)
+
+	version, err := SchemaVersion()
+
+	require.NoError(t, err)
+	assert.Equal(t, migrations[len(migrations)-1].Version, version)
+}
+
+var gateMigrations = []Migration{{Version: 1, Name: "001_a.sql"}, {Version: 2, Name: "002_b.sql"}, {Version: 3, Name: "003_c.sql"}}
+
+func TestCompareSchema(t *testing.T) {
+	applied := []appliedMigration{{Version: 1}, {Version: 3}, {Version: 5, Name: "005_e.sql"}}
+
+	d := compareSchema(gateMigrations, applied)
+
+	assert.Equal(t, int64(3), d.Expected)
+	assert.Equal(t, int64(5), d.Current)
+	require.Len(t, d.Pending, 1)
+	assert.Equal(t, int64(2), d.Pending[0].Version, "a gap counts as pending")
+	assert.Equal(t, []appliedMigration{{Version: 5, Name: "005_e.sql"}}, d.Unknown)
+}
+
+func TestSchemaDrift_Check(t *testing.T) {
+	applied := func(versions ...int64) []appliedMigration {
+		var rows []appliedMigration
+		for _, v := range versions {
+			rows = append(rows, appliedMigration{Version: v})
+		}
+		return rows
+	}
+
+	t.Run("up to date", func(t *testing.T) {
+		migrate, err := compareSchema(gateMigrations, applied(1, 2, 3)).check(false)
+		require.NoError(t, err)
+		assert.False(t, migrate)
+	})
+
+	t.Run("behind", func(t *testing.T) {
+		_, err := compareSchema(gateMigrations, applied(1)).check(false)
+		require.ErrorIs(t, err, ErrSchemaBehind)
+		assert.Contains(t, err.Error(), "2 migrations up to version 3 are not applied")
+	})
+
+	t.Run("behind with auto-migrate", func(t *testing.T) {
+		migrate, err := compareSchema(gateMigrations, nil).check(true)
+		require.NoError(t, err)
+		assert.True(t, migrate)
+	})
+
+	t.Run("ahead compatible", func(t *testing.T) {
+		migrate, err := compareSchema(gateMigrations, applied(1, 2, 3, 4)).check(false)
+		require.NoError(t, err)
+		assert.False(t, migrate)
+	})
+
+	t.Run("ahead breaking", func(t *testing.T) {
+		rows := append(applied(1, 2, 3, 4), appliedMigration{Version: 5, Name: "005_drop.sql", Breaking: true})
+		_, err := compareSchema(gateMigrations, rows).check(true)
+		require.ErrorIs(t, err, ErrSchemaIncompatible)
+		assert.Contains(t, err.Error(), "005_drop.sql")
+	})
+
+	t.Run("breaking migration the binary knows", func(t *testing.T) {
+		known := append([]Migration{}, gateMigrations...)
+		known[2].Breaking = true
+		rows := applied(1, 2)
+		rows = append(rows, appliedMigration{Version: 3, Breaking: true})
+		_, err := compareSchema(known, rows).check(false)
+		require.NoError(t, err)
+	})
+}
+
+// readOnlyDB answers queries from canned rows and fails the test on any
+// write.
+type readOnlyDB struct {
+	t    *testing.T
+	rows map[string][][]any
+}
+
+func (db readOnlyDB) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	db.t.Errorf("unexpected write: %s", sql)
+	return pgconn.CommandTag{}, nil
+}
+
+func (db readOnlyDB) Query(_ context.Context, sql string, _ ...any) (pgx.Rows, error) {
+	rows, ok := db.rows[sql]
+	if !ok && sql != describeSchemaMigrations {
+		db.t.Fatalf("unexpected query: %s", sql)
+	}
+	return &cannedRows{rows: rows}, nil
+}
+
+func (db readOnlyDB) Begin(context.Context) (pgx.Tx, error) {
+	db.t.Error("unexpected transaction")
+	return nil, pgx.ErrTxClosed
+}
+
+// cannedRows is a pgx.Rows over values in memory.
+type cannedRows struct {
+	pgx.Rows
+	rows [][]any
+	row  []any
+}
+
+func (r *cannedRows) Next() bool {
+	if len(r.rows) == 0 {
+		return false
+	}
+	r.row, r.rows = r.rows[0], r.rows[1:]
+	return true
+}
+
+func (r *cannedRows) Scan(dest ...any) error {
+	for i, d := range dest {
+		reflect.ValueOf(d).Elem().Set(reflect.ValueOf(r.row[i]))
+	}
+	return nil
+}
+
+func (r *cannedRows) Close()     {}
+func (r *cannedRows) Err() error { return nil }
+
+func TestCheckSchema_ReadsOnly(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no schema_migrations", func(t *testing.T) {
+		db := readOnlyDB{t: t}
+		applied, err := appliedMigrations(ctx, db)
+		require.NoError(t, err)
+		assert.Empty(t, applied, "no migration has run")
+		require.ErrorIs(t, checkSchema(ctx, db, false), ErrSchemaBehind)
+	})
+
+	t.Run("no breaking column", func(t *testing.T) {
+		db := readOnlyDB{t: t, rows: map[string][][]any{
+			describeSchemaMigrations: {{"version"}, {"name"}, {"applied_at"}},
+			"SELECT version, name, false FROM schema_migrations ORDER BY version": {{int64(1), "001_a.sql", false}},
+		}}
+		applied, err := appliedMigrations(ctx, db)
+		require.NoError(t, err)
+		assert.Equal(t, []appliedMigration{{Version: 1, Name: "001_a.sql"}}, applied)
+	})
+
+	t.Run("breaking column", func(t *testing.T) {
+		db := readOnlyDB{t: t, rows: map[string][][]any{
+			describeSchemaMigrations: {{"version"}, {"name"}, {"applied_at"}, {"breaking"}},
+			"SELECT version, name, breaking FROM schema_migrations ORDER BY version": {{int64(1), "001_a.sql", true}},
+		}}
+		applied, err := appliedMigrations(ctx, db)
+		require.NoError(t, err)
+		assert.Equal(t, []appliedMigration{{Version: 1, Name: "001_a.sql", Breaking: true}}, applied)
+	})
+}
+
+// TestCheckSchema_Integration runs against a throwaway database when
+// TPG_TEST_DATABASE_URL is set, like TestMigrate_Integration.
+func TestCheckSchema_Integration(t *testing.T) {
+	dsn := os.Getenv("TPG_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TPG_TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	require.NoError(t, MigrateDown(ctx, pool, 0))
+	_, err = pool.Exec(ctx, "DROP TABLE schema_migrations")
+	require.NoError(t, err)
+	require.ErrorIs(t, CheckSchema(ctx, pool, false), ErrSchemaBehind)
+	var tables int
+	require.NoError(t, pool.QueryRow(ctx, "SELECT count(*) FROM information_schema.tables WHERE table_name = 'schema_migrations'").Scan(&tables))
+	assert.Zero(t, tables, "checking the schema creates nothing")
+	require.NoError(t, CheckSchema(ctx, pool, true))
+	require.NoError(t, CheckSchema(ctx, pool, false))
+
+	version, err := SchemaVersion()
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, 'future.sql')", version+1)
+	require.NoError(t, err)
+	defer func() { _, _ = pool.Exec(ctx, "DELETE FROM schema_migrations WHERE version > $1", version) }()
+	require.NoError(t, CheckSchema(ctx, pool, false), "a newer compatible schema only warns")
+
+	_, err = pool.Exec(ctx, "INSERT INTO schema_migrations (version, name, breaking) VALUES ($1, 'future_breaking.sql', true)", version+2)
+	require.NoError(t, err)
+	require.ErrorIs(t, CheckSchema(ctx, pool, true), ErrSchemaIncompatible)
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// drainPollInterval is how often GracefulClose samples the pool while waiting
+// for acquired connections to be released.
+const drainPollInterval = 50 * time.Millisecond
+
+// GracefulClose closes pool without cutting off queries that are in flight.
+//
+// pgxpool rejects new acquisitions as soon as Close starts, so Close is kicked
+// off immediately in the background. GracefulClose then waits up to timeout
+// (or until ctx is done) for every acquired connection to be released. If
+// connections are still held when the wait ends, it returns an error with
+// their count; the pool destroys them as they come back, and exiting the
+// process drops them outright.
+func GracefulClose(ctx context.Context, pool *pgxpool.Pool, timeout time.Duration) error {
+	return gracefulClose(ctx, pool, func() int32 { return pool.Stat().AcquiredConns() }, timeout)
+}
+
+func gracefulClose(ctx context.Context, pool interface{ Close() }, acquired func() int32, timeout time.Duration) error {
+	closed := make(chan struct{})
+	go func() {
+		pool.Close()
+		close(closed)
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			slog.Info("database pool closed")
+			return nil
+		case <-ticker.C:
+			if acquired() == 0 {
+				// Close finishes destroying idle connections on its own.
+				<-closed
+				slog.Info("database pool closed")
+				return nil
+			}
+		case <-ctx.Done():
+			n := acquired()
+			if n == 0 {
+				<-closed
+				return nil
+			}
+			slog.Warn("database pool drain timed out", "acquired_conns", n, "timeout", timeout)
+			return fmt.Errorf("database pool drain timed out after %s: %d connections force-closed", timeout, n)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDrainPool mimics pgxpool: Close blocks until every acquired connection
+// has been released.
+type fakeDrainPool struct {
+	acquired atomic.Int32
+	released chan struct{}
+	closed   atomic.Bool
+}
+
+func newFakeDrainPool(held int32) *fakeDrainPool {
+	p := &fakeDrainPool{released: make(chan struct{})}
+	p.acquired.Store(held)
+	if held == 0 {
+		close(p.released)
+	}
+	return p
+}
+
+func (p *fakeDrainPool) Close() {
+	<-p.released
+	p.closed.Store(true)
+}
+
+func (p *fakeDrainPool) release() {
+	p.acquired.Store(0)
+	close(p.released)
+}
+
+func (p *fakeDrainPool) releaseAfter(d time.Duration) {
+	time.AfterFunc(d, p.release)
+}
+
+func TestGracefulClose_NoAcquiredConns(t *testing.T) {
+	pool := newFakeDrainPool(0)
+
+	err := gracefulClose(context.Background(), pool, pool.acquired.Load, time.Second)
+
+	require.NoError(t, err)
+	assert.True(t, pool.closed.Load())
+}
+
+func TestGracefulClose_ReleasedBeforeDeadline(t *testing.T) {
+	pool := newFakeDrainPool(1)
+	pool.releaseAfter(100 * time.Millisecond)
+
+	start := time.Now()
+	err := gracefulClose(context.Background(), pool, pool.acquired.Load, time.Second)
+
+	require.NoError(t, err)
+	assert.True(t, pool.closed.Load())
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestGracefulClose_ReleasedAfterDeadline(t *testing.T) {
+	pool := newFakeDrainPool(2)
+	pool.releaseAfter(500 * time.Millisecond)
+	defer func() { <-pool.released }()
+
+	start := time.Now()
+	err := gracefulClose(context.Background(), pool, pool.acquired.Load, 150*time.Millisecond)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 connections force-closed")
+	assert.False(t, pool.closed.Load())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestGracefulClose_ContextCancelled(t *testing.T) {
+	pool := newFakeDrainPool(1)
+	defer pool.release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := gracefulClose(ctx, pool, pool.acquired.Load, time.Minute)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 connections force-closed")
+}
@@ -0,0 +1,174 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Option customises the pool built by DbConnect and ConnectWithRetry.
+type Option func(*pgxpool.Config)
+
+// WithTracer installs query tracers on every pool connection. Nil tracers are
+// skipped, so callers can pass optional tracers unconditionally; with no
+// tracer left the pool runs untraced.
+func WithTracer(tracers ...pgx.QueryTracer) Option {
+	return func(poolCfg *pgxpool.Config) {
+		var active []pgx.QueryTracer
+		for _, t := range tracers {
+			if t != nil {
+				active = append(active, t)
+			}
+		}
+
+		switch len(active) {
+		case 0:
+		case 1:
+			poolCfg.ConnConfig.Tracer = active[0]
+		default:
+			poolCfg.ConnConfig.Tracer = multitracer.New(active...)
+		}
+	}
+}
+
+// DefaultMaxSQLLength is how much of a statement SlowQueryTracer logs.
+const DefaultMaxSQLLength = 512
+
+type slowQueryKey struct{}
+
+type slowQueryStart struct {
+	sql   string
+	start time.Time
+}
+
+// SlowQueryTracer logs statements that take longer than Threshold.
+type SlowQueryTracer struct {
+	Threshold    time.Duration
+	MaxSQLLength int
+	Logger       *slog.Logger
+}
+
+// NewSlowQueryTracer returns nil for a non-positive threshold so that
+// WithTracer leaves the pool untraced.
+func NewSlowQueryTracer(threshold time.Duration, logger *slog.Logger) pgx.QueryTracer {
+	if threshold <= 0 {
+		return nil
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlowQueryTracer{Threshold: threshold, MaxSQLLength: DefaultMaxSQLLength, Logger: logger}
+}
+
+func (t *SlowQueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, slowQueryKey{}, slowQueryStart{sql: data.SQL, start: time.Now()})
+}
+
+func (t *SlowQueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	q, ok := ctx.Value(slowQueryKey{}).(slowQueryStart)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(q.start)
+	if elapsed < t.Threshold {
+		return
+	}
+
+	attrs := []any{
+		"duration", elapsed,
+		"sql", truncateSQL(q.sql, t.MaxSQLLength),
+		"rows", data.CommandTag.RowsAffected(),
+	}
+	if name := queryName(q.sql); name != "" {
+		attrs = append(attrs, "query", name)
+	}
+	if data.Err != nil {
+		attrs = append(attrs, "error", data.Err)
+	}
+	t.Logger.WarnContext(ctx, "slow query", attrs...)
+}
+
+// OTelTracer records a client span per query. Only the statement text is
+// recorded; argument values never leave the process and quoted or numeric
+// literals inside the SQL are replaced with '?'.
+type OTelTracer struct {
+	tracer trace.Tracer
+}
+
+// NewOTelTracer returns nil when tp is nil so that WithTracer leaves the pool
+// untraced.
+func NewOTelTracer(tp trace.TracerProvider) pgx.QueryTracer {
+	if tp == nil {
+		return nil
+	}
+	return &OTelTracer{tracer: tp.Tracer("github.com/yaninyzwitty/tron-payment-gateway/packages/shared/db")}
+}
+
+func (t *OTelTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	spanName := "db.query"
+	if name := queryName(data.SQL); name != "" {
+		spanName = "db." + name
+	}
+
+	ctx, span := t.tracer.Start(ctx, spanName, trace.WithSpanKind(trace.SpanKindClient))
+	// Redaction is the expensive part; skip it when the span is sampled out.
+	if span.IsRecording() {
+		span.SetAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.statement", redactSQL(data.SQL)),
+		)
+	}
+	return ctx
+}
+
+func (t *OTelTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		span.End()
+		return
+	}
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	}
+	span.End()
+}
+
+// queryName extracts the sqlc query name from a "-- name: X :kind" header.
+func queryName(sql string) string {
+	rest, ok := strings.CutPrefix(sql, "-- name: ")
+	if !ok {
+		return ""
+	}
+	name, _, _ := strings.Cut(rest, " ")
+	return name
+}
+
+func truncateSQL(sql string, max int) string {
+	if max <= 0 || len(sql) <= max {
+		return sql
+	}
+	return sql[:max] + "…"
+}
+
+var (
+	stringLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numericLiteral = regexp.MustCompile(`([^$\w.])\d+(?:\.\d+)?\b`)
+)
+
+// redactSQL strips literal values from a statement. Placeholders ($1) and
+// identifiers are left alone.
+func redactSQL(sql string) string {
+	sql = stringLiteral.ReplaceAllString(sql, "'?'")
+	return numericLiteral.ReplaceAllString(sql, "${1}?")
+}
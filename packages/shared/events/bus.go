@@ -0,0 +1,123 @@
+// Package events carries payment status changes to the API's live status
+// streams.
+//
+// The watcher and the API run as separate binaries, so the in-process
+// MemoryBus only sees what is published inside the API. There a Poller reads
+// the statuses of the payments someone is streaming and publishes their
+// changes. A Bus backed by Redis or NATS can replace MemoryBus to let the
+// outbox relay publish directly, through Notifier, to every API replica.
+//
+// A TypedBus fans the transitions a process makes out by event type, to
+// whatever in that process reacts to them; outbox.Transition publishes to
+// one once its change commits.
+//
+// Separately, a Publisher streams payment lifecycle events, such as
+// payment.confirmed, to downstream consumers; KafkaPublisher writes them to
+// a Kafka topic. The outbox relay is its only producer.
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// MemoryBus drops events for it.
+const subscriberBuffer = 16
+
+// Event is a change of a payment's status.
+type Event struct {
+	PaymentID uuid.UUID `json:"payment_id"`
+	// Type is the lifecycle event of the change, such as payment.confirmed;
+	// empty when only the new status is known, as for a Poller's events.
+	Type   string    `json:"type,omitempty"`
+	Status string    `json:"status"`
+	At     time.Time `json:"at"`
+}
+
+// Bus delivers status changes to the streams following a payment.
+type Bus interface {
+	// Publish sends e to every current subscriber of its payment.
+	Publish(ctx context.Context, e Event) error
+	// Subscribe returns the events published for paymentID from now on. The
+	// channel is closed once cancel is called or ctx is done.
+	Subscribe(ctx context.Context, paymentID uuid.UUID) (events <-chan Event, cancel func(), err error)
+}
+
+type subscriber struct {
+	ch   chan Event
+	once sync.Once
+}
+
+func (s *subscriber) close() {
+	s.once.Do(func() { close(s.ch) })
+}
+
+// MemoryBus is a Bus within one process. Publish never blocks: a subscriber
+// more than 16 events behind misses the newer ones.
+type MemoryBus struct {
+	mu   sync.Mutex
+	subs map[uuid.UUID]map[*subscriber]struct{}
+}
+
+var _ Bus = (*MemoryBus)(nil)
+
+// NewMemoryBus returns a bus without subscribers.
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{subs: make(map[uuid.UUID]map[*subscriber]struct{})}
+}
+
+// Publish sends e to the subscribers of e.PaymentID.
+func (b *MemoryBus) Publish(_ context.Context, e Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs[e.PaymentID] {
+		select {
+		case s.ch <- e:
+		default:
+		}
+	}
+	return nil
+}
+
+// Subscribe follows paymentID until cancel is called or ctx is done.
+func (b *MemoryBus) Subscribe(ctx context.Context, paymentID uuid.UUID) (<-chan Event, func(), error) {
+	s := &subscriber{ch: make(chan Event, subscriberBuffer)}
+
+	b.mu.Lock()
+	if b.subs[paymentID] == nil {
+		b.subs[paymentID] = make(map[*subscriber]struct{})
+	}
+	b.subs[paymentID][s] = struct{}{}
+	b.mu.Unlock()
+
+	cancel := func() {
+		b.mu.Lock()
+		delete(b.subs[paymentID], s)
+		if len(b.subs[paymentID]) == 0 {
+			delete(b.subs, paymentID)
+		}
+		// Closed under the lock, so Publish never sends on it afterwards.
+		s.close()
+		b.mu.Unlock()
+	}
+	stop := context.AfterFunc(ctx, cancel)
+	return s.ch, func() {
+		stop()
+		cancel()
+	}, nil
+}
+
+// Watched returns the payments that have subscribers.
+func (b *MemoryBus) Watched() []uuid.UUID {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ids := make([]uuid.UUID, 0, len(b.subs))
+	for id := range b.subs {
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,106 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var t0 = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func receive(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case e, ok := <-ch:
+		require.True(t, ok, "channel closed")
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+		return Event{}
+	}
+}
+
+func TestMemoryBus_PublishesInOrderToSubscribers(t *testing.T) {
+	bus := NewMemoryBus()
+	id := uuid.New()
+	a, cancelA, err := bus.Subscribe(context.Background(), id)
+	require.NoError(t, err)
+	defer cancelA()
+	b, cancelB, err := bus.Subscribe(context.Background(), id)
+	require.NoError(t, err)
+	defer cancelB()
+
+	for _, status := range []string{"DETECTED", "CONFIRMED"} {
+		require.NoError(t, bus.Publish(context.Background(), Event{PaymentID: id, Status: status, At: t0}))
+	}
+
+	for _, ch := range []<-chan Event{a, b} {
+		assert.Equal(t, "DETECTED", receive(t, ch).Status)
+		assert.Equal(t, "CONFIRMED", receive(t, ch).Status)
+	}
+}
+
+func TestMemoryBus_OnlyTheSubscribedPayment(t *testing.T) {
+	bus := NewMemoryBus()
+	id := uuid.New()
+	ch, cancel, err := bus.Subscribe(context.Background(), id)
+	require.NoError(t, err)
+	defer cancel()
+
+	require.NoError(t, bus.Publish(context.Background(), Event{PaymentID: uuid.New(), Status: "CONFIRMED"}))
+	require.NoError(t, bus.Publish(context.Background(), Event{PaymentID: id, Status: "EXPIRED"}))
+
+	assert.Equal(t, "EXPIRED", receive(t, ch).Status)
+}
+
+func TestMemoryBus_CancelClosesAndUnwatches(t *testing.T) {
+	bus := NewMemoryBus()
+	id := uuid.New()
+	ch, cancel, err := bus.Subscribe(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{id}, bus.Watched())
+
+	cancel()
+	cancel()
+
+	_, ok := <-ch
+	assert.False(t, ok)
+	assert.Empty(t, bus.Watched())
+	assert.NoError(t, bus.Publish(context.Background(), Event{PaymentID: id}), "publishing after cancel is safe")
+}
+
+func TestMemoryBus_ContextEndsSubscription(t *testing.T) {
+	bus := NewMemoryBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, unsubscribe, err := bus.Subscribe(ctx, uuid.New())
+	require.NoError(t, err)
+	defer unsubscribe()
+
+	cancel()
+
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed when ctx was cancelled")
+	}
+	assert.Eventually(t, func() bool { return len(bus.Watched()) == 0 }, time.Second, time.Millisecond)
+}
+
+func TestMemoryBus_SlowSubscriberDoesNotBlock(t *testing.T) {
+	bus := NewMemoryBus()
+	id := uuid.New()
+	ch, cancel, err := bus.Subscribe(context.Background(), id)
+	require.NoError(t, err)
+	defer cancel()
+
+	for range subscriberBuffer + 5 {
+		require.NoError(t, bus.Publish(context.Background(), Event{PaymentID: id}))
+	}
+
+	assert.Len(t, ch, subscriberBuffer)
+}
@@ -0,0 +1,107 @@
+package events
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+// SchemaVersionHeader carries the envelope's schema version on every record,
+// so consumers can route a record before decoding it.
+const SchemaVersionHeader = "schema_version"
+
+// producer is the subset of *kgo.Client KafkaPublisher uses.
+type producer interface {
+	ProduceSync(ctx context.Context, rs ...*kgo.Record) kgo.ProduceResults
+	Close()
+}
+
+// KafkaPublisher publishes envelopes as JSON to a Kafka topic, keyed by
+// payment ID so a payment's events land on one partition in order.
+type KafkaPublisher struct {
+	client producer
+	topic  string
+}
+
+// NewKafkaPublisher connects lazily to the brokers in cfg; password is the
+// SASL password, ignored without a mechanism. Producing is idempotent and
+// acknowledged by all in-sync replicas.
+func NewKafkaPublisher(cfg config.KafkaConfig, password string) (*KafkaPublisher, error) {
+	opts, err := kafkaOptions(cfg, password)
+	if err != nil {
+		return nil, err
+	}
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka client: %w", err)
+	}
+	return &KafkaPublisher{client: client, topic: cfg.Topic}, nil
+}
+
+func kafkaOptions(cfg config.KafkaConfig, password string) ([]kgo.Opt, error) {
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(cfg.Brokers...),
+		kgo.RequiredAcks(kgo.AllISRAcks()),
+	}
+	if cfg.ClientID != "" {
+		opts = append(opts, kgo.ClientID(cfg.ClientID))
+	}
+	if cfg.TLS {
+		opts = append(opts, kgo.DialTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}))
+	}
+	if cfg.SASL.Mechanism != "" {
+		mechanism, err := saslMechanism(cfg.SASL, password)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, kgo.SASL(mechanism))
+	}
+	return opts, nil
+}
+
+func saslMechanism(cfg config.KafkaSASLConfig, password string) (sasl.Mechanism, error) {
+	switch cfg.Mechanism {
+	case config.KafkaSASLPlain:
+		return plain.Auth{User: cfg.Username, Pass: password}.AsMechanism(), nil
+	case config.KafkaSASLScramSHA256:
+		return scram.Auth{User: cfg.Username, Pass: password}.AsSha256Mechanism(), nil
+	case config.KafkaSASLScramSHA512:
+		return scram.Auth{User: cfg.Username, Pass: password}.AsSha512Mechanism(), nil
+	default:
+		return nil, fmt.Errorf("unsupported kafka sasl mechanism %q", cfg.Mechanism)
+	}
+}
+
+// Publish writes e and waits for the brokers to acknowledge it.
+func (p *KafkaPublisher) Publish(ctx context.Context, e Envelope) error {
+	value, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s envelope: %w", e.EventType, err)
+	}
+	record := &kgo.Record{
+		Topic: p.topic,
+		Key:   []byte(e.Payment.ID.String()),
+		Value: value,
+		Headers: []kgo.RecordHeader{
+			{Key: SchemaVersionHeader, Value: []byte(strconv.Itoa(e.SchemaVersion))},
+		},
+	}
+	if err := p.client.ProduceSync(ctx, record).FirstErr(); err != nil {
+		return fmt.Errorf("failed to publish %s event: %w", e.EventType, err)
+	}
+	return nil
+}
+
+// Close releases the client's connections. Publish is synchronous, so
+// nothing is left to flush.
+func (p *KafkaPublisher) Close() {
+	p.client.Close()
+}
@@ -0,0 +1,126 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// fakeProducer records what it is given and fails with err.
+type fakeProducer struct {
+	records []*kgo.Record
+	err     error
+	closed  bool
+}
+
+func (f *fakeProducer) ProduceSync(_ context.Context, rs ...*kgo.Record) kgo.ProduceResults {
+	var results kgo.ProduceResults
+	for _, r := range rs {
+		f.records = append(f.records, r)
+		results = append(results, kgo.ProduceResult{Record: r, Err: f.err})
+	}
+	return results
+}
+
+func (f *fakeProducer) Close() { f.closed = true }
+
+func TestKafkaPublisher_Publish(t *testing.T) {
+	client := &fakeProducer{}
+	p := &KafkaPublisher{client: client, topic: "payment-events"}
+	env, err := NewEnvelope(outboxEvent(t, "payment.expired", repository.Payment{ID: uuid.New(), Status: "EXPIRED"}))
+	require.NoError(t, err)
+
+	require.NoError(t, p.Publish(context.Background(), env))
+
+	require.Len(t, client.records, 1)
+	r := client.records[0]
+	assert.Equal(t, "payment-events", r.Topic)
+	assert.Equal(t, env.Payment.ID.String(), string(r.Key), "keyed by payment for per-payment ordering")
+	assert.Equal(t, []kgo.RecordHeader{{Key: SchemaVersionHeader, Value: []byte("1")}}, r.Headers)
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(r.Value, &got))
+	assert.Equal(t, float64(EnvelopeVersion), got["schema_version"])
+	assert.Equal(t, env.EventID.String(), got["event_id"])
+	assert.Equal(t, "payment.expired", got["event_type"])
+	assert.Equal(t, "2026-03-01T12:00:00Z", got["occurred_at"])
+	assert.Equal(t, "EXPIRED", got["payment"].(map[string]any)["status"])
+
+	p.Close()
+	assert.True(t, client.closed)
+}
+
+func TestKafkaPublisher_PublishError(t *testing.T) {
+	p := &KafkaPublisher{client: &fakeProducer{err: errors.New("not enough replicas")}, topic: "payment-events"}
+
+	err := p.Publish(context.Background(), Envelope{EventType: "payment.confirmed"})
+
+	assert.EqualError(t, err, "failed to publish payment.confirmed event: not enough replicas")
+}
+
+func TestKafkaOptions(t *testing.T) {
+	cfg := config.KafkaConfig{Brokers: []string{"kafka:9092"}, ClientID: "gateway", TLS: true}
+	opts, err := kafkaOptions(cfg, "")
+	require.NoError(t, err)
+	assert.Len(t, opts, 4)
+
+	for _, mechanism := range []string{config.KafkaSASLPlain, config.KafkaSASLScramSHA256, config.KafkaSASLScramSHA512} {
+		cfg.SASL = config.KafkaSASLConfig{Mechanism: mechanism, Username: "gateway"}
+		opts, err := kafkaOptions(cfg, "hunter2")
+		require.NoError(t, err, mechanism)
+		assert.Len(t, opts, 5, mechanism)
+	}
+
+	cfg.SASL.Mechanism = "GSSAPI"
+	_, err = kafkaOptions(cfg, "hunter2")
+	assert.EqualError(t, err, `unsupported kafka sasl mechanism "GSSAPI"`)
+}
+
+// TestKafkaPublisher_Integration publishes to the brokers in KAFKA_BROKERS,
+// e.g. a local Redpanda container, and reads the record back.
+func TestKafkaPublisher_Integration(t *testing.T) {
+	brokers := os.Getenv("KAFKA_BROKERS")
+	if brokers == "" {
+		t.Skip("KAFKA_BROKERS is not set")
+	}
+	cfg := config.KafkaConfig{Brokers: strings.Split(brokers, ","), Topic: "payment-events-test-" + uuid.NewString()}
+	p, err := NewKafkaPublisher(cfg, "")
+	require.NoError(t, err)
+	defer p.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	env, err := NewEnvelope(outboxEvent(t, "payment.confirmed", repository.Payment{ID: uuid.New(), Status: "CONFIRMED"}))
+	require.NoError(t, err)
+
+	consumer, err := kgo.NewClient(kgo.SeedBrokers(cfg.Brokers...),
+		kgo.ConsumeTopics(cfg.Topic), kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()))
+	require.NoError(t, err)
+	defer consumer.Close()
+	create := kmsg.NewPtrCreateTopicsRequest()
+	topic := kmsg.NewCreateTopicsRequestTopic()
+	topic.Topic, topic.NumPartitions, topic.ReplicationFactor = cfg.Topic, 1, 1
+	create.Topics = append(create.Topics, topic)
+	_, err = create.RequestWith(ctx, consumer)
+	require.NoError(t, err)
+	require.NoError(t, p.Publish(ctx, env))
+
+	fetches := consumer.PollFetches(ctx)
+	require.NoError(t, fetches.Err0())
+	records := fetches.Records()
+	require.Len(t, records, 1)
+	assert.Equal(t, env.Payment.ID.String(), string(records[0].Key))
+	var got Envelope
+	require.NoError(t, json.Unmarshal(records[0].Value, &got))
+	assert.Equal(t, env, got)
+}
@@ -0,0 +1,31 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// Notifier publishes the status of every payment it is told about.
+// Called from an outbox.Handler, it lets the status changes the watcher
+// makes, such as confirming or expiring a payment, reach the bus.
+type Notifier struct {
+	bus EventPublisher
+	now func() time.Time
+}
+
+// NewNotifier returns a Notifier publishing to bus.
+func NewNotifier(bus EventPublisher) *Notifier {
+	return &Notifier{bus: bus, now: time.Now}
+}
+
+// Notify publishes payment's current status as an event of type event.
+func (n *Notifier) Notify(ctx context.Context, event string, payment repository.Payment) error {
+	err := n.bus.Publish(ctx, Event{PaymentID: payment.ID, Type: event, Status: payment.Status, At: n.now().UTC()})
+	if err != nil {
+		return fmt.Errorf("failed to publish %s: %w", event, err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+type failingBus struct{ Bus }
+
+func (failingBus) Publish(context.Context, Event) error { return errors.New("bus down") }
+
+func TestNotifier_PublishesStatus(t *testing.T) {
+	bus := NewMemoryBus()
+	id := uuid.New()
+	ch, cancel, err := bus.Subscribe(context.Background(), id)
+	require.NoError(t, err)
+	defer cancel()
+	n := NewNotifier(bus)
+	n.now = func() time.Time { return t0 }
+
+	require.NoError(t, n.Notify(context.Background(), "PAYMENT_CONFIRMED", repository.Payment{ID: id, Status: "CONFIRMED"}))
+
+	assert.Equal(t, Event{PaymentID: id, Type: "PAYMENT_CONFIRMED", Status: "CONFIRMED", At: t0}, receive(t, ch))
+}
+
+func TestNotifier_PublishError(t *testing.T) {
+	n := NewNotifier(failingBus{})
+
+	err := n.Notify(context.Background(), "PAYMENT_EXPIRED", repository.Payment{ID: uuid.New()})
+
+	assert.EqualError(t, err, "failed to publish PAYMENT_EXPIRED: bus down")
+}
@@ -0,0 +1,108 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// DefaultPollInterval is how often a Poller reads the watched statuses.
+const DefaultPollInterval = time.Second
+
+// PollerStore is the subset of repository.Querier the Poller reads through.
+type PollerStore interface {
+	ListPaymentStatuses(ctx context.Context, ids []uuid.UUID) ([]repository.ListPaymentStatusesRow, error)
+}
+
+// Poller publishes the status changes of the payments a MemoryBus has
+// subscribers for, reading them from the database. It lets the API stream
+// changes the watcher makes in another process with one query per interval,
+// however many streams are open.
+type Poller struct {
+	store    PollerStore
+	bus      *MemoryBus
+	interval time.Duration
+	logger   *slog.Logger
+	now      func() time.Time
+	// last is the status last published per watched payment.
+	last map[uuid.UUID]string
+}
+
+// Option customises a Poller.
+type Option func(*Poller)
+
+// WithPollInterval replaces DefaultPollInterval.
+func WithPollInterval(d time.Duration) Option {
+	return func(p *Poller) { p.interval = d }
+}
+
+// WithLogger replaces slog.Default.
+func WithLogger(l *slog.Logger) Option {
+	return func(p *Poller) { p.logger = l }
+}
+
+// NewPoller returns a Poller publishing to bus.
+func NewPoller(store PollerStore, bus *MemoryBus, opts ...Option) *Poller {
+	p := &Poller{
+		store:    store,
+		bus:      bus,
+		interval: DefaultPollInterval,
+		logger:   slog.Default(),
+		now:      time.Now,
+		last:     make(map[uuid.UUID]string),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Run polls until ctx is done.
+func (p *Poller) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			p.Poll(ctx)
+		}
+	}
+}
+
+// Poll publishes the status of every watched payment that differs from the
+// one last published for it. A payment seen for the first time is published
+// too, as a stream may have read it before the change; streams drop events
+// that repeat the status they last sent.
+func (p *Poller) Poll(ctx context.Context) {
+	ids := p.bus.Watched()
+	watched := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		watched[id] = true
+	}
+	for id := range p.last {
+		if !watched[id] {
+			delete(p.last, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	rows, err := p.store.ListPaymentStatuses(ctx, ids)
+	if err != nil {
+		p.logger.ErrorContext(ctx, "failed to poll payment statuses", "payments", len(ids), "error", err)
+		return
+	}
+	for _, row := range rows {
+		if p.last[row.ID] == row.Status {
+			continue
+		}
+		p.last[row.ID] = row.Status
+		_ = p.bus.Publish(ctx, Event{PaymentID: row.ID, Status: row.Status, At: p.now().UTC()})
+	}
+}
@@ -0,0 +1,99 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// fakeStatuses answers ListPaymentStatuses from statuses, for the ids asked.
+type fakeStatuses struct {
+	statuses map[uuid.UUID]string
+	err      error
+	calls    int
+}
+
+func (f *fakeStatuses) ListPaymentStatuses(_ context.Context, ids []uuid.UUID) ([]repository.ListPaymentStatusesRow, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	var rows []repository.ListPaymentStatusesRow
+	for _, id := range ids {
+		if s, ok := f.statuses[id]; ok {
+			rows = append(rows, repository.ListPaymentStatusesRow{ID: id, Status: s})
+		}
+	}
+	return rows, nil
+}
+
+func newTestPoller(store *fakeStatuses) (*Poller, *MemoryBus) {
+	bus := NewMemoryBus()
+	p := NewPoller(store, bus, WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	p.now = func() time.Time { return t0 }
+	return p, bus
+}
+
+func TestPoller_PublishesChanges(t *testing.T) {
+	id := uuid.New()
+	store := &fakeStatuses{statuses: map[uuid.UUID]string{id: "PENDING"}}
+	p, bus := newTestPoller(store)
+	ch, cancel, err := bus.Subscribe(context.Background(), id)
+	require.NoError(t, err)
+	defer cancel()
+
+	p.Poll(context.Background())
+	assert.Equal(t, Event{PaymentID: id, Status: "PENDING", At: t0}, receive(t, ch), "first sighting is published")
+
+	p.Poll(context.Background())
+	assert.Empty(t, ch, "an unchanged status is not published again")
+
+	store.statuses[id] = "CONFIRMED"
+	p.Poll(context.Background())
+	assert.Equal(t, "CONFIRMED", receive(t, ch).Status)
+}
+
+func TestPoller_SkipsWithoutSubscribers(t *testing.T) {
+	store := &fakeStatuses{}
+	p, _ := newTestPoller(store)
+
+	p.Poll(context.Background())
+
+	assert.Zero(t, store.calls)
+}
+
+func TestPoller_ForgetsUnwatchedPayments(t *testing.T) {
+	id := uuid.New()
+	store := &fakeStatuses{statuses: map[uuid.UUID]string{id: "PENDING"}}
+	p, bus := newTestPoller(store)
+	_, cancel, err := bus.Subscribe(context.Background(), id)
+	require.NoError(t, err)
+	p.Poll(context.Background())
+
+	cancel()
+	p.Poll(context.Background())
+
+	assert.Empty(t, p.last)
+}
+
+func TestPoller_StoreError(t *testing.T) {
+	id := uuid.New()
+	store := &fakeStatuses{err: errors.New("connection reset")}
+	p, bus := newTestPoller(store)
+	ch, cancel, err := bus.Subscribe(context.Background(), id)
+	require.NoError(t, err)
+	defer cancel()
+
+	p.Poll(context.Background())
+
+	assert.Empty(t, ch)
+	assert.Equal(t, 1, store.calls)
+}
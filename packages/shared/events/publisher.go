@@ -0,0 +1,62 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/webhooks"
+)
+
+// EnvelopeVersion is the schema version of Envelope. It changes whenever a
+// field is removed, renamed or retyped; added fields keep it, so consumers
+// must ignore fields they do not know.
+const EnvelopeVersion = 1
+
+// Envelope is a payment lifecycle event as published for downstream
+// consumers such as the data warehouse.
+type Envelope struct {
+	SchemaVersion int       `json:"schema_version"`
+	EventID       uuid.UUID `json:"event_id"`
+	EventType     string    `json:"event_type"`
+	OccurredAt    time.Time `json:"occurred_at"`
+	// Payment is the payment as it was right after the event, in the shape
+	// webhooks send it.
+	Payment webhooks.PaymentData `json:"payment"`
+}
+
+// NewEnvelope wraps the outbox event e. Its payload is the webhook body, so
+// the event ID a consumer sees is the one merchants see.
+func NewEnvelope(e repository.Outbox) (Envelope, error) {
+	var body webhooks.Event
+	if err := json.Unmarshal(e.Payload, &body); err != nil {
+		return Envelope{}, fmt.Errorf("failed to decode %s payload: %w", e.EventType, err)
+	}
+	return Envelope{
+		SchemaVersion: EnvelopeVersion,
+		EventID:       body.ID,
+		EventType:     body.Type,
+		OccurredAt:    body.Created,
+		Payment:       body.Data,
+	}, nil
+}
+
+// Publisher hands lifecycle events to an external stream. The outbox relay
+// is its only caller, so every event of a committed transition is published
+// at least once; consumers drop duplicates by EventID.
+type Publisher interface {
+	Publish(ctx context.Context, e Envelope) error
+	Close()
+}
+
+// NopPublisher drops every event, for deployments without a stream.
+type NopPublisher struct{}
+
+// Publish does nothing.
+func (NopPublisher) Publish(context.Context, Envelope) error { return nil }
+
+// Close does nothing.
+func (NopPublisher) Close() {}
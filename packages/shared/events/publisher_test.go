@@ -0,0 +1,60 @@
+package events
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/webhooks"
+)
+
+func outboxEvent(t *testing.T, event string, payment repository.Payment) repository.Outbox {
+	t.Helper()
+	id := uuid.New()
+	payload, err := webhooks.NewPayload(id, event, payment, t0)
+	require.NoError(t, err)
+	return repository.Outbox{ID: id, EventType: event, AggregateID: payment.ID, Payload: payload}
+}
+
+func TestNewEnvelope(t *testing.T) {
+	payment := repository.Payment{
+		ID:           uuid.New(),
+		AccountID:    uuid.New(),
+		UniqueWallet: "TWallet7",
+		Token:        "USDT",
+		Amount:       pgtype.Numeric{Int: big.NewInt(1500), Exp: -2, Valid: true},
+		Status:       "CONFIRMED",
+		ConfirmedAt:  pgtype.Timestamptz{Time: t0, Valid: true},
+	}
+	e := outboxEvent(t, "payment.confirmed", payment)
+
+	env, err := NewEnvelope(e)
+
+	require.NoError(t, err)
+	assert.Equal(t, EnvelopeVersion, env.SchemaVersion)
+	assert.Equal(t, e.ID, env.EventID, "the event ID is the outbox and webhook event ID")
+	assert.Equal(t, "payment.confirmed", env.EventType)
+	assert.Equal(t, t0, env.OccurredAt)
+	assert.Equal(t, payment.ID, env.Payment.ID)
+	assert.Equal(t, "TWallet7", env.Payment.Wallet)
+	assert.Equal(t, "15.000000", env.Payment.Amount)
+	assert.Equal(t, "CONFIRMED", env.Payment.Status)
+}
+
+func TestNewEnvelope_BadPayload(t *testing.T) {
+	_, err := NewEnvelope(repository.Outbox{EventType: "payment.expired", Payload: []byte("{")})
+
+	assert.ErrorContains(t, err, "failed to decode payment.expired payload")
+}
+
+func TestNopPublisher(t *testing.T) {
+	var p Publisher = NopPublisher{}
+
+	assert.NoError(t, p.Publish(context.Background(), Envelope{}))
+	p.Close()
+}
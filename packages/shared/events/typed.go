@@ -0,0 +1,152 @@
+package events
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// DefaultTypedBuffer is how many events a TypedBus subscriber may fall
+// behind before the oldest ones are dropped for it.
+const DefaultTypedBuffer = 64
+
+// EventPublisher publishes events to whoever follows them. Bus and TypedBus
+// implement it; a bus spanning replicas would too, fed by the outbox relay
+// through Notifier.
+type EventPublisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// DropMetrics records the events a TypedBus drops. *metrics.Metrics
+// implements it.
+type DropMetrics interface {
+	// EventDropped records an event of eventType dropped for a subscriber
+	// that fell behind.
+	EventDropped(eventType string)
+}
+
+type nopDropMetrics struct{}
+
+func (nopDropMetrics) EventDropped(string) {}
+
+// typedSubscriber follows the event types in types, or all of them when
+// types is empty.
+type typedSubscriber struct {
+	ch    chan Event
+	types []string
+}
+
+func (s *typedSubscriber) follows(eventType string) bool {
+	return len(s.types) == 0 || slices.Contains(s.types, eventType)
+}
+
+// TypedBus fans payment events out within one process to the subscribers of
+// their type, such as payment.confirmed, so that what reacts to a transition
+// does not have to be called from where the transition happens.
+//
+// Publish never blocks: a subscriber whose buffer is full loses its oldest
+// event to make room for the new one, and the drop is recorded.
+type TypedBus struct {
+	mu      sync.Mutex
+	subs    map[*typedSubscriber]struct{}
+	buffer  int
+	metrics DropMetrics
+}
+
+var _ EventPublisher = (*TypedBus)(nil)
+
+// TypedOption customises a TypedBus.
+type TypedOption func(*TypedBus)
+
+// WithBuffer replaces DefaultTypedBuffer.
+func WithBuffer(n int) TypedOption {
+	return func(b *TypedBus) { b.buffer = n }
+}
+
+// WithDropMetrics records the events dropped for slow subscribers in m.
+func WithDropMetrics(m DropMetrics) TypedOption {
+	return func(b *TypedBus) { b.metrics = m }
+}
+
+// NewTypedBus returns a bus without subscribers.
+func NewTypedBus(opts ...TypedOption) *TypedBus {
+	b := &TypedBus{
+		subs:    make(map[*typedSubscriber]struct{}),
+		buffer:  DefaultTypedBuffer,
+		metrics: nopDropMetrics{},
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	b.buffer = max(b.buffer, 1)
+	return b
+}
+
+// Publish sends e to every subscriber of e.Type. It never fails.
+func (b *TypedBus) Publish(_ context.Context, e Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		if !s.follows(e.Type) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+			continue
+		default:
+		}
+		// Full: make room by dropping the oldest event. Sends only happen
+		// under the lock, so the retry finds room unless the subscriber
+		// drained the buffer meanwhile, which leaves room too.
+		select {
+		case old := <-s.ch:
+			b.metrics.EventDropped(old.Type)
+		default:
+		}
+		select {
+		case s.ch <- e:
+		default:
+			b.metrics.EventDropped(e.Type)
+		}
+	}
+	return nil
+}
+
+// Subscribe returns the events of eventTypes published from now on, or of
+// every type when none is given. The channel is closed once cancel is
+// called; cancel may be called more than once and while events are being
+// published.
+func (b *TypedBus) Subscribe(eventTypes ...string) (events <-chan Event, cancel func()) {
+	s := &typedSubscriber{ch: make(chan Event, b.buffer), types: slices.Clone(eventTypes)}
+
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return s.ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, s)
+			// Closed under the lock, so Publish never sends on it afterwards.
+			close(s.ch)
+			b.mu.Unlock()
+		})
+	}
+}
+
+// Forward publishes the events of eventTypes, or of every type, to p until
+// ctx is done. It lets the status streams of a Bus follow the transitions
+// made in-process without waiting for a Poller.
+func (b *TypedBus) Forward(ctx context.Context, p EventPublisher, eventTypes ...string) error {
+	events, cancel := b.Subscribe(eventTypes...)
+	defer cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e := <-events:
+			_ = p.Publish(ctx, e)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingDrops records dropped events by type.
+type countingDrops struct {
+	mu      sync.Mutex
+	dropped map[string]int
+}
+
+func (c *countingDrops) EventDropped(eventType string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dropped == nil {
+		c.dropped = make(map[string]int)
+	}
+	c.dropped[eventType]++
+}
+
+func (c *countingDrops) count(eventType string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dropped[eventType]
+}
+
+func TestTypedBus_FiltersByType(t *testing.T) {
+	bus := NewTypedBus()
+	confirmed, cancelConfirmed := bus.Subscribe("payment.confirmed")
+	defer cancelConfirmed()
+	settled, cancelSettled := bus.Subscribe("payment.confirmed", "payment.expired")
+	defer cancelSettled()
+	all, cancelAll := bus.Subscribe()
+	defer cancelAll()
+
+	id := uuid.New()
+	for _, typ := range []string{"payment.detected", "payment.expired", "payment.confirmed"} {
+		require.NoError(t, bus.Publish(context.Background(), Event{PaymentID: id, Type: typ, At: t0}))
+	}
+
+	assert.Equal(t, "payment.confirmed", receive(t, confirmed).Type)
+	assert.Empty(t, confirmed)
+	assert.Equal(t, "payment.expired", receive(t, settled).Type)
+	assert.Equal(t, "payment.confirmed", receive(t, settled).Type)
+	for _, typ := range []string{"payment.detected", "payment.expired", "payment.confirmed"} {
+		assert.Equal(t, typ, receive(t, all).Type)
+	}
+}
+
+func TestTypedBus_SlowSubscriberLosesOldest(t *testing.T) {
+	drops := &countingDrops{}
+	bus := NewTypedBus(WithBuffer(3), WithDropMetrics(drops))
+	slow, cancelSlow := bus.Subscribe()
+	defer cancelSlow()
+	fast, cancelFast := bus.Subscribe()
+	defer cancelFast()
+
+	for i := range 5 {
+		e := Event{Type: "payment.confirmed", Status: string(rune('a' + i))}
+		require.NoError(t, bus.Publish(context.Background(), e))
+		assert.Equal(t, e, receive(t, fast), "a subscriber keeping up loses nothing")
+	}
+
+	require.Len(t, slow, 3)
+	for _, want := range []string{"c", "d", "e"} {
+		assert.Equal(t, want, receive(t, slow).Status, "the newest events are kept")
+	}
+	assert.Equal(t, 2, drops.count("payment.confirmed"))
+}
+
+func TestTypedBus_CancelClosesOnce(t *testing.T) {
+	bus := NewTypedBus()
+	ch, cancel := bus.Subscribe("payment.confirmed")
+
+	cancel()
+	cancel()
+
+	_, ok := <-ch
+	assert.False(t, ok)
+	assert.NoError(t, bus.Publish(context.Background(), Event{Type: "payment.confirmed"}), "publishing after cancel is safe")
+}
+
+func TestTypedBus_UnsubscribeDuringPublish(t *testing.T) {
+	bus := NewTypedBus(WithBuffer(1))
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	var publishers sync.WaitGroup
+	for range 4 {
+		publishers.Add(1)
+		go func() {
+			defer publishers.Done()
+			for ctx.Err() == nil {
+				_ = bus.Publish(ctx, Event{Type: "payment.confirmed"})
+			}
+		}()
+	}
+
+	var subscribers sync.WaitGroup
+	for range 50 {
+		subscribers.Add(1)
+		go func() {
+			defer subscribers.Done()
+			ch, cancel := bus.Subscribe()
+			<-ch
+			cancel()
+			for range ch {
+			}
+		}()
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		subscribers.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscribers did not finish")
+	}
+	stop()
+	publishers.Wait()
+}
+
+func TestTypedBus_Forward(t *testing.T) {
+	typed := NewTypedBus()
+	bus := NewMemoryBus()
+	id := uuid.New()
+	ch, cancel, err := bus.Subscribe(context.Background(), id)
+	require.NoError(t, err)
+	defer cancel()
+
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- typed.Forward(ctx, bus, "payment.cancelled") }()
+	// Forward subscribes asynchronously; publish until the event arrives.
+	require.Eventually(t, func() bool {
+		_ = typed.Publish(context.Background(), Event{PaymentID: id, Type: "payment.cancelled", Status: "CANCELLED"})
+		return len(ch) > 0
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, "CANCELLED", receive(t, ch).Status)
+	stop()
+	assert.NoError(t, <-done)
+}
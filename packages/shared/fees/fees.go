@@ -0,0 +1,151 @@
+// Package fees computes what merchants are charged for their confirmed
+// payments and keeps it as one invoice per client and month.
+//
+// A payment is charged the percentage of its amount its token's schedule
+// sets, but no less than the schedule's minimum. An invoice charges each
+// token in up to two lines: a PERCENT line for the payments whose percentage
+// came to at least the minimum, and a MINIMUM line for the rest. Amounts and
+// fees stay decimals throughout and are never converted to floats.
+//
+// Rounding is done once per line, half away from zero, to the token's fee
+// unit (config.FeeDecimals): a cent for USDT and a sun for TRX. A PERCENT
+// line charges the percentage of the summed amounts of its payments, rounded;
+// a MINIMUM line charges the minimum per payment, which is a whole number of
+// fee units and needs no rounding. A token's total is the sum of its lines.
+package fees
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// Line kinds, as stored in invoice_lines.kind.
+const (
+	// KindPercent charges a percentage of the amounts of its payments.
+	KindPercent = "PERCENT"
+	// KindMinimum charges the minimum fee for each of its payments.
+	KindMinimum = "MINIMUM"
+)
+
+// Line charges Payments payments of Token, which received Amount in total,
+// Fee: Rate percent of Amount for a KindPercent line, Rate per payment for a
+// KindMinimum one.
+type Line struct {
+	Token    string
+	Kind     string
+	Payments int64
+	Amount   decimal.Decimal
+	Rate     decimal.Decimal
+	Fee      decimal.Decimal
+}
+
+// Total is what an invoice charges for the payments of a token.
+type Total struct {
+	Token string
+	Fee   decimal.Decimal
+}
+
+// Schedules returns the fee schedule of every token the gateway knows.
+func Schedules(cfg config.FeesConfig) (map[string]config.FeeSchedule, error) {
+	schedules := make(map[string]config.FeeSchedule, len(config.KnownTokens))
+	for _, token := range config.KnownTokens {
+		s, err := cfg.Schedule(token)
+		if err != nil {
+			return nil, err
+		}
+		schedules[token] = s
+	}
+	return schedules, nil
+}
+
+// basisParams returns schedules as the tokens, percents and minimums
+// GetMonthlyFeeBasis is given, in the order of config.KnownTokens.
+func basisParams(schedules map[string]config.FeeSchedule) (tokens []string, percents, minimums []pgtype.Numeric) {
+	for _, token := range config.KnownTokens {
+		s, ok := schedules[token]
+		if !ok {
+			continue
+		}
+		tokens = append(tokens, token)
+		percents = append(percents, repository.DecimalToNumeric(s.Percent))
+		minimums = append(minimums, repository.DecimalToNumeric(s.Minimum))
+	}
+	return tokens, percents, minimums
+}
+
+// Lines charges the payments of basis under schedules, giving a token's
+// PERCENT line before its MINIMUM line and leaving out lines without
+// payments.
+func Lines(basis []repository.GetMonthlyFeeBasisRow, schedules map[string]config.FeeSchedule) ([]Line, error) {
+	var lines []Line
+	for _, b := range basis {
+		s, ok := schedules[b.Token]
+		if !ok {
+			return nil, fmt.Errorf("no fee schedule for token %q", b.Token)
+		}
+		places, ok := config.FeeDecimals(b.Token)
+		if !ok {
+			return nil, fmt.Errorf("no fee unit for token %q", b.Token)
+		}
+		amount, minimumAmount := repository.NumericToDecimal(b.Amount), repository.NumericToDecimal(b.MinimumAmount)
+
+		if n := b.Payments - b.MinimumPayments; n > 0 {
+			charged := amount.Sub(minimumAmount)
+			lines = append(lines, Line{
+				Token:    b.Token,
+				Kind:     KindPercent,
+				Payments: n,
+				Amount:   charged,
+				Rate:     s.Percent,
+				Fee:      PercentFee(charged, s.Percent, places),
+			})
+		}
+		if b.MinimumPayments > 0 {
+			lines = append(lines, Line{
+				Token:    b.Token,
+				Kind:     KindMinimum,
+				Payments: b.MinimumPayments,
+				Amount:   minimumAmount,
+				Rate:     s.Minimum,
+				Fee:      s.Minimum.Mul(decimal.NewFromInt(b.MinimumPayments)),
+			})
+		}
+	}
+	return lines, nil
+}
+
+// PercentFee returns percent of amount rounded half away from zero to
+// places decimal places.
+func PercentFee(amount, percent decimal.Decimal, places int32) decimal.Decimal {
+	// Shifting divides by 100 exactly, so the only rounding is the last one.
+	return amount.Mul(percent).Shift(-2).Round(places)
+}
+
+// FormatFee renders fee to the fee unit of token.
+func FormatFee(fee decimal.Decimal, token string) string {
+	places, ok := config.FeeDecimals(token)
+	if !ok {
+		return fee.String()
+	}
+	return fee.StringFixed(places)
+}
+
+// Totals sums lines per token, in the order the tokens first appear.
+func Totals(lines []Line) []Total {
+	var totals []Total
+	index := make(map[string]int)
+	for _, l := range lines {
+		i, ok := index[l.Token]
+		if !ok {
+			i = len(totals)
+			index[l.Token] = i
+			totals = append(totals, Total{Token: l.Token})
+		}
+		totals[i].Fee = totals[i].Fee.Add(l.Fee)
+	}
+	return totals
+}
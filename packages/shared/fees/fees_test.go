@@ -0,0 +1,127 @@
+package fees
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+func TestPercentFee_Rounding(t *testing.T) {
+	testCases := []struct {
+		name    string
+		amount  string
+		percent string
+		places  int32
+		want    string
+	}{
+		{"usdt rounds down under half a cent", "10.83", "1.5", 2, "0.16"},
+		{"usdt rounds up from half a cent", "0.5", "1", 2, "0.01"},
+		{"usdt just under half a cent", "0.4999", "1", 2, "0"},
+		{"usdt exact", "200", "2.5", 2, "5"},
+		{"usdt sub-cent percent", "1234.567891", "0.0125", 2, "0.15"},
+		{"trx to the sun", "1.000033", "1.5", 6, "0.015"},
+		{"trx rounds up from half a sun", "0.00005", "1", 6, "0.000001"},
+		{"trx under half a sun", "0.000049", "1", 6, "0"},
+		{"zero percent", "100", "0", 2, "0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := PercentFee(decimal.RequireFromString(tc.amount), decimal.RequireFromString(tc.percent), tc.places)
+
+			assert.Equal(t, tc.want, got.String())
+		})
+	}
+}
+
+func TestLines(t *testing.T) {
+	schedules := map[string]config.FeeSchedule{
+		config.TokenUSDT: {Percent: decimal.RequireFromString("1.5"), Minimum: decimal.RequireFromString("0.25")},
+		config.TokenTRX:  {Percent: decimal.RequireFromString("2"), Minimum: decimal.RequireFromString("0.000001")},
+	}
+	basis := []repository.GetMonthlyFeeBasisRow{
+		{Token: "TRX", Payments: 2, Amount: repository.DecimalToNumeric(decimal.RequireFromString("150.123457"))},
+		// Three payments of 1000.05 in all and two of 5 whose 1.5% is 0.075,
+		// under the minimum.
+		{
+			Token: "USDT", Payments: 5, Amount: repository.DecimalToNumeric(decimal.RequireFromString("1010.05")),
+			MinimumPayments: 2, MinimumAmount: repository.DecimalToNumeric(decimal.RequireFromString("10")),
+		},
+	}
+
+	lines, err := Lines(basis, schedules)
+
+	require.NoError(t, err)
+	require.Len(t, lines, 3)
+	assert.Equal(t, "TRX", lines[0].Token)
+	assert.Equal(t, KindPercent, lines[0].Kind)
+	assert.Equal(t, int64(2), lines[0].Payments)
+	assert.Equal(t, "150.123457", lines[0].Amount.String())
+	assert.Equal(t, "3.002469", lines[0].Fee.String(), "2% of 150.123457 is 3.00246914, rounded to the sun")
+	assert.Equal(t, KindPercent, lines[1].Kind)
+	assert.Equal(t, int64(3), lines[1].Payments)
+	assert.Equal(t, "1000.05", lines[1].Amount.String())
+	assert.Equal(t, "15", lines[1].Fee.String(), "1.5% of 1000.05 is 15.00075, rounded to the cent")
+	assert.Equal(t, KindMinimum, lines[2].Kind)
+	assert.Equal(t, int64(2), lines[2].Payments)
+	assert.Equal(t, "10", lines[2].Amount.String())
+	assert.Equal(t, "0.25", lines[2].Rate.String())
+	assert.Equal(t, "0.5", lines[2].Fee.String())
+
+	totals := Totals(lines)
+	require.Len(t, totals, 2)
+	assert.Equal(t, "TRX", totals[0].Token)
+	assert.Equal(t, "3.002469", totals[0].Fee.String())
+	assert.Equal(t, "USDT", totals[1].Token)
+	assert.Equal(t, "15.5", totals[1].Fee.String())
+}
+
+func TestFormatFee(t *testing.T) {
+	assert.Equal(t, "3.10", FormatFee(decimal.RequireFromString("3.1"), "USDT"))
+	assert.Equal(t, "0.000100", FormatFee(decimal.RequireFromString("0.0001"), "TRX"))
+	assert.Equal(t, "1.5", FormatFee(decimal.RequireFromString("1.5"), "BTC"))
+}
+
+func TestLines_OnlyMinimums(t *testing.T) {
+	schedules := map[string]config.FeeSchedule{
+		config.TokenUSDT: {Percent: decimal.Zero, Minimum: decimal.RequireFromString("0.1")},
+	}
+	basis := []repository.GetMonthlyFeeBasisRow{{
+		Token: "USDT", Payments: 3, Amount: repository.DecimalToNumeric(decimal.RequireFromString("30")),
+		MinimumPayments: 3, MinimumAmount: repository.DecimalToNumeric(decimal.RequireFromString("30")),
+	}}
+
+	lines, err := Lines(basis, schedules)
+
+	require.NoError(t, err)
+	require.Len(t, lines, 1, "no PERCENT line without payments")
+	assert.Equal(t, KindMinimum, lines[0].Kind)
+	assert.Equal(t, "0.3", lines[0].Fee.String())
+}
+
+func TestLines_UnknownToken(t *testing.T) {
+	_, err := Lines([]repository.GetMonthlyFeeBasisRow{{Token: "BTC", Payments: 1}}, map[string]config.FeeSchedule{})
+
+	assert.EqualError(t, err, `no fee schedule for token "BTC"`)
+}
+
+func TestSchedules(t *testing.T) {
+	schedules, err := Schedules(config.FeesConfig{Percent: "1", Tokens: map[string]config.TokenFeesConfig{"TRX": {Percent: "2"}}})
+
+	require.NoError(t, err)
+	assert.Equal(t, "1", schedules[config.TokenUSDT].Percent.String())
+	assert.Equal(t, "2", schedules[config.TokenTRX].Percent.String())
+
+	tokens, percents, minimums := basisParams(schedules)
+	assert.Equal(t, config.KnownTokens, tokens)
+	require.Len(t, percents, 2)
+	require.Len(t, minimums, 2)
+	assert.Equal(t, "2", repository.NumericToDecimal(percents[0]).String())
+
+	_, err = Schedules(config.FeesConfig{Percent: "x"})
+	assert.Error(t, err)
+}
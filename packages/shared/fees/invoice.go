@@ -0,0 +1,194 @@
+package fees
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// ErrClientNotFound is returned by GenerateInvoice for a client that does
+// not exist.
+var ErrClientNotFound = errors.New("client not found")
+
+// ErrMonthNotOver is returned by GenerateInvoice for a month that has not
+// ended yet, whose payments may still be confirmed.
+var ErrMonthNotOver = errors.New("month is not over yet")
+
+// Store is the subset of *repository.Store invoices are generated through.
+type Store interface {
+	ExecTx(ctx context.Context, fn func(repository.Querier) error) error
+}
+
+// Invoice is what a client was charged for the live payments confirmed in
+// the UTC month starting at Month.
+type Invoice struct {
+	ID        uuid.UUID
+	ClientID  uuid.UUID
+	Month     time.Time
+	Payments  int64
+	Lines     []Line
+	CreatedAt time.Time
+}
+
+// Totals returns what the invoice charges per token.
+func (i *Invoice) Totals() []Total {
+	return Totals(i.Lines)
+}
+
+// InvoiceFromRecords rebuilds a saved invoice from its rows.
+func InvoiceFromRecords(inv repository.Invoice, lines []repository.InvoiceLine) *Invoice {
+	out := &Invoice{
+		ID:        inv.ID,
+		ClientID:  inv.ClientID,
+		Month:     inv.Month.Time,
+		Payments:  inv.Payments,
+		CreatedAt: inv.CreatedAt.Time,
+	}
+	for _, l := range lines {
+		if l.InvoiceID != inv.ID {
+			continue
+		}
+		out.Lines = append(out.Lines, Line{
+			Token:    l.Token,
+			Kind:     l.Kind,
+			Payments: l.Payments,
+			Amount:   repository.NumericToDecimal(l.Amount),
+			Rate:     repository.NumericToDecimal(l.Rate),
+			Fee:      repository.NumericToDecimal(l.Fee),
+		})
+	}
+	return out
+}
+
+// MonthStart returns the start of the UTC month t falls in.
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Invoicer generates monthly invoices under the fees section of the config.
+type Invoicer struct {
+	store Store
+	fees  config.FeesConfig
+	now   func() time.Time
+}
+
+// New builds an Invoicer using the fees section of cfg.
+func New(store Store, cfg *config.Config) *Invoicer {
+	return &Invoicer{store: store, fees: cfg.Fees, now: time.Now}
+}
+
+// GenerateInvoice returns the client's invoice for the UTC month month
+// falls in, computing and saving it the first time. Later calls return the
+// saved invoice unchanged, even if the fee schedule has changed since.
+func (i *Invoicer) GenerateInvoice(ctx context.Context, clientID uuid.UUID, month time.Time) (*Invoice, error) {
+	start := MonthStart(month)
+	end := start.AddDate(0, 1, 0)
+	if end.After(i.now()) {
+		return nil, fmt.Errorf("%w: %s ends at %s", ErrMonthNotOver, start.Format("2006-01"), end.Format(time.RFC3339))
+	}
+	schedules, err := Schedules(i.fees)
+	if err != nil {
+		return nil, err
+	}
+	key := repository.GetInvoiceByClientMonthParams{
+		ClientID: clientID,
+		Month:    pgtype.Date{Time: start, Valid: true},
+	}
+
+	var invoice *Invoice
+	err = i.store.ExecTx(ctx, func(q repository.Querier) error {
+		if _, err := q.GetClientByID(ctx, clientID); errors.Is(err, pgx.ErrNoRows) {
+			return ErrClientNotFound
+		} else if err != nil {
+			return fmt.Errorf("failed to load client %s: %w", clientID, err)
+		}
+		saved, err := q.GetInvoiceByClientMonth(ctx, key)
+		if err == nil {
+			invoice, err = loadInvoice(ctx, q, saved)
+			return err
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to load invoice: %w", err)
+		}
+
+		tokens, percents, minimums := basisParams(schedules)
+		basis, err := q.GetMonthlyFeeBasis(ctx, repository.GetMonthlyFeeBasisParams{
+			Tokens:     tokens,
+			Percents:   percents,
+			Minimums:   minimums,
+			ClientID:   clientID,
+			MonthStart: pgtype.Timestamptz{Time: start, Valid: true},
+			MonthEnd:   pgtype.Timestamptz{Time: end, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to sum confirmed payments: %w", err)
+		}
+		lines, err := Lines(basis, schedules)
+		if err != nil {
+			return err
+		}
+		var payments int64
+		for _, b := range basis {
+			payments += b.Payments
+		}
+
+		saved, err = q.CreateInvoice(ctx, repository.CreateInvoiceParams{
+			ClientID: clientID,
+			Month:    key.Month,
+			Payments: payments,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Generated concurrently since it was looked up.
+			if saved, err = q.GetInvoiceByClientMonth(ctx, key); err != nil {
+				return fmt.Errorf("failed to load invoice: %w", err)
+			}
+			invoice, err = loadInvoice(ctx, q, saved)
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save invoice: %w", err)
+		}
+		for _, l := range lines {
+			if err := q.CreateInvoiceLine(ctx, repository.CreateInvoiceLineParams{
+				InvoiceID: saved.ID,
+				Token:     l.Token,
+				Kind:      l.Kind,
+				Payments:  l.Payments,
+				Amount:    repository.DecimalToNumeric(l.Amount),
+				Rate:      repository.DecimalToNumeric(l.Rate),
+				Fee:       repository.DecimalToNumeric(l.Fee),
+			}); err != nil {
+				return fmt.Errorf("failed to save %s %s line: %w", l.Token, l.Kind, err)
+			}
+		}
+		invoice = &Invoice{
+			ID:        saved.ID,
+			ClientID:  saved.ClientID,
+			Month:     saved.Month.Time,
+			Payments:  saved.Payments,
+			Lines:     lines,
+			CreatedAt: saved.CreatedAt.Time,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+func loadInvoice(ctx context.Context, q repository.Querier, saved repository.Invoice) (*Invoice, error) {
+	lines, err := q.ListInvoiceLines(ctx, []uuid.UUID{saved.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lines of invoice %s: %w", saved.ID, err)
+	}
+	return InvoiceFromRecords(saved, lines), nil
+}
@@ -0,0 +1,163 @@
+package fees
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+var (
+	now   = time.Date(2026, 4, 2, 9, 0, 0, 0, time.UTC)
+	march = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func newTestInvoicer(t *testing.T) (*Invoicer, *repository.MockQuerier, repository.Client) {
+	t.Helper()
+	q := repository.NewMockQuerier(t)
+	store := repository.NewFakeStore(repository.WithFallback(q))
+	client, err := store.CreateClient(context.Background(), repository.CreateClientParams{Name: "shop", ApiKey: "sk_1", Mode: "live"})
+	require.NoError(t, err)
+	cfg := &config.Config{Fees: config.FeesConfig{Percent: "1.5", Minimum: "0.25"}}
+	i := New(store, cfg)
+	i.now = func() time.Time { return now }
+	return i, q, client
+}
+
+func TestGenerateInvoice(t *testing.T) {
+	i, q, client := newTestInvoicer(t)
+	ctx := context.Background()
+	month := pgtype.Date{Time: march, Valid: true}
+	invoiceID := uuid.New()
+
+	q.On("GetInvoiceByClientMonth", mock.Anything, repository.GetInvoiceByClientMonthParams{ClientID: client.ID, Month: month}).
+		Return(repository.Invoice{}, pgx.ErrNoRows).Once()
+	q.On("GetMonthlyFeeBasis", mock.Anything, mock.MatchedBy(func(arg repository.GetMonthlyFeeBasisParams) bool {
+		return arg.ClientID == client.ID &&
+			arg.MonthStart.Time.Equal(march) &&
+			arg.MonthEnd.Time.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)) &&
+			assert.ObjectsAreEqual(config.KnownTokens, arg.Tokens) &&
+			repository.NumericToDecimal(arg.Percents[1]).Equal(decimal.RequireFromString("1.5")) &&
+			repository.NumericToDecimal(arg.Minimums[1]).Equal(decimal.RequireFromString("0.25"))
+	})).Return([]repository.GetMonthlyFeeBasisRow{{
+		Token: "USDT", Payments: 3, Amount: repository.DecimalToNumeric(decimal.RequireFromString("205.5")),
+		MinimumPayments: 1, MinimumAmount: repository.DecimalToNumeric(decimal.RequireFromString("5.5")),
+	}}, nil)
+	q.On("CreateInvoice", mock.Anything, repository.CreateInvoiceParams{ClientID: client.ID, Month: month, Payments: 3}).
+		Return(repository.Invoice{ID: invoiceID, ClientID: client.ID, Month: month, Payments: 3}, nil)
+	q.On("CreateInvoiceLine", mock.Anything, mock.MatchedBy(func(arg repository.CreateInvoiceLineParams) bool {
+		return arg.InvoiceID == invoiceID && arg.Kind == KindPercent && repository.NumericToDecimal(arg.Fee).Equal(decimal.RequireFromString("3"))
+	})).Return(nil).Once()
+	q.On("CreateInvoiceLine", mock.Anything, mock.MatchedBy(func(arg repository.CreateInvoiceLineParams) bool {
+		return arg.InvoiceID == invoiceID && arg.Kind == KindMinimum && repository.NumericToDecimal(arg.Fee).Equal(decimal.RequireFromString("0.25"))
+	})).Return(nil).Once()
+
+	// Any time in the month names it.
+	invoice, err := i.GenerateInvoice(ctx, client.ID, march.Add(17*24*time.Hour))
+
+	require.NoError(t, err)
+	assert.Equal(t, invoiceID, invoice.ID)
+	assert.Equal(t, march, invoice.Month)
+	assert.Equal(t, int64(3), invoice.Payments)
+	require.Len(t, invoice.Lines, 2)
+	totals := invoice.Totals()
+	require.Len(t, totals, 1)
+	assert.Equal(t, "3.25", totals[0].Fee.String())
+}
+
+func TestGenerateInvoice_ReturnsSavedInvoice(t *testing.T) {
+	i, q, client := newTestInvoicer(t)
+	ctx := context.Background()
+	saved := repository.Invoice{ID: uuid.New(), ClientID: client.ID, Month: pgtype.Date{Time: march, Valid: true}, Payments: 1}
+	q.On("GetInvoiceByClientMonth", mock.Anything, mock.Anything).Return(saved, nil)
+	q.On("ListInvoiceLines", mock.Anything, []uuid.UUID{saved.ID}).Return([]repository.InvoiceLine{{
+		InvoiceID: saved.ID, Token: "USDT", Kind: KindPercent, Payments: 1,
+		Amount: repository.DecimalToNumeric(decimal.NewFromInt(100)),
+		Rate:   repository.DecimalToNumeric(decimal.NewFromInt(2)),
+		Fee:    repository.DecimalToNumeric(decimal.NewFromInt(2)),
+	}}, nil)
+
+	invoice, err := i.GenerateInvoice(ctx, client.ID, march)
+
+	require.NoError(t, err)
+	assert.Equal(t, saved.ID, invoice.ID)
+	require.Len(t, invoice.Lines, 1)
+	assert.Equal(t, "2", invoice.Lines[0].Fee.String(), "the saved fee, not one under today's schedule")
+	q.AssertNotCalled(t, "GetMonthlyFeeBasis", mock.Anything, mock.Anything)
+	q.AssertNotCalled(t, "CreateInvoice", mock.Anything, mock.Anything)
+}
+
+func TestGenerateInvoice_GeneratedConcurrently(t *testing.T) {
+	i, q, client := newTestInvoicer(t)
+	ctx := context.Background()
+	saved := repository.Invoice{ID: uuid.New(), ClientID: client.ID, Month: pgtype.Date{Time: march, Valid: true}}
+	q.On("GetInvoiceByClientMonth", mock.Anything, mock.Anything).Return(repository.Invoice{}, pgx.ErrNoRows).Once()
+	q.On("GetMonthlyFeeBasis", mock.Anything, mock.Anything).Return([]repository.GetMonthlyFeeBasisRow(nil), nil)
+	q.On("CreateInvoice", mock.Anything, mock.Anything).Return(repository.Invoice{}, pgx.ErrNoRows)
+	q.On("GetInvoiceByClientMonth", mock.Anything, mock.Anything).Return(saved, nil).Once()
+	q.On("ListInvoiceLines", mock.Anything, []uuid.UUID{saved.ID}).Return([]repository.InvoiceLine(nil), nil)
+
+	invoice, err := i.GenerateInvoice(ctx, client.ID, march)
+
+	require.NoError(t, err)
+	assert.Equal(t, saved.ID, invoice.ID)
+	q.AssertNotCalled(t, "CreateInvoiceLine", mock.Anything, mock.Anything)
+}
+
+func TestGenerateInvoice_MonthNotOver(t *testing.T) {
+	i, _, client := newTestInvoicer(t)
+
+	_, err := i.GenerateInvoice(context.Background(), client.ID, now)
+
+	assert.ErrorIs(t, err, ErrMonthNotOver)
+	assert.ErrorContains(t, err, "2026-04 ends at 2026-05-01T00:00:00Z")
+}
+
+func TestGenerateInvoice_ClientNotFound(t *testing.T) {
+	i, _, _ := newTestInvoicer(t)
+
+	_, err := i.GenerateInvoice(context.Background(), uuid.New(), march)
+
+	assert.ErrorIs(t, err, ErrClientNotFound)
+}
+
+func TestGenerateInvoice_SaveError(t *testing.T) {
+	i, q, client := newTestInvoicer(t)
+	q.On("GetInvoiceByClientMonth", mock.Anything, mock.Anything).Return(repository.Invoice{}, pgx.ErrNoRows)
+	q.On("GetMonthlyFeeBasis", mock.Anything, mock.Anything).Return([]repository.GetMonthlyFeeBasisRow(nil), nil)
+	q.On("CreateInvoice", mock.Anything, mock.Anything).Return(repository.Invoice{}, errors.New("connection reset"))
+
+	_, err := i.GenerateInvoice(context.Background(), client.ID, march)
+
+	assert.EqualError(t, err, "failed to save invoice: connection reset")
+}
+
+func TestInvoiceFromRecords(t *testing.T) {
+	inv := repository.Invoice{ID: uuid.New(), Month: pgtype.Date{Time: march, Valid: true}, Payments: 2}
+	lines := []repository.InvoiceLine{
+		{InvoiceID: inv.ID, Token: "TRX", Kind: KindPercent, Fee: repository.DecimalToNumeric(decimal.RequireFromString("0.000001"))},
+		{InvoiceID: uuid.New(), Token: "USDT", Kind: KindPercent},
+	}
+
+	got := InvoiceFromRecords(inv, lines)
+
+	assert.Equal(t, march, got.Month)
+	require.Len(t, got.Lines, 1, "lines of other invoices are left out")
+	assert.Equal(t, "0.000001", got.Lines[0].Fee.String())
+}
+
+func TestMonthStart(t *testing.T) {
+	assert.Equal(t, march, MonthStart(time.Date(2026, 3, 31, 23, 59, 59, 0, time.UTC)))
+	east := time.FixedZone("UTC+3", 3*60*60)
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), MonthStart(time.Date(2026, 3, 1, 1, 0, 0, 0, east)), "months are UTC")
+}
@@ -0,0 +1,177 @@
+// Package health serves the liveness and readiness probes of the gateway's
+// processes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultCheckTimeout bounds each readiness check.
+const DefaultCheckTimeout = 5 * time.Second
+
+// shutdownTimeout bounds how long Serve waits for in-flight probes on exit.
+const shutdownTimeout = 5 * time.Second
+
+// Check reports whether one dependency is ready. Details, when not nil, are
+// included in the readiness report whether or not the check passed, e.g.
+// the numbers behind a failure.
+type Check func(ctx context.Context) (details any, err error)
+
+// Result is the outcome of one check in a readiness report.
+type Result struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+	Details any    `json:"details,omitempty"`
+}
+
+// Report is the body of a /readyz response.
+type Report struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Handler serves GET /healthz, which only says the process is up, and GET
+// /readyz, which runs every check and answers 503 when any of them fails.
+type Handler struct {
+	mux     *http.ServeMux
+	checks  map[string]Check
+	timeout time.Duration
+	logger  *slog.Logger
+}
+
+// Option customises a Handler.
+type Option func(*Handler)
+
+// WithCheck adds a readiness check reported under name.
+func WithCheck(name string, check Check) Option {
+	return func(h *Handler) { h.checks[name] = check }
+}
+
+// WithTimeout changes how long each check may take.
+func WithTimeout(d time.Duration) Option {
+	return func(h *Handler) {
+		if d > 0 {
+			h.timeout = d
+		}
+	}
+}
+
+// WithLogger replaces slog.Default.
+func WithLogger(l *slog.Logger) Option {
+	return func(h *Handler) { h.logger = l }
+}
+
+// NewHandler builds a probe handler running the checks given as options.
+func NewHandler(opts ...Option) *Handler {
+	h := &Handler{
+		mux:     http.NewServeMux(),
+		checks:  make(map[string]Check),
+		timeout: DefaultCheckTimeout,
+		logger:  slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.mux.HandleFunc("GET /healthz", h.live)
+	h.mux.HandleFunc("GET /readyz", h.ready)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) live(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *Handler) ready(w http.ResponseWriter, r *http.Request) {
+	report := h.Run(r.Context())
+	status := http.StatusOK
+	if !report.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+// Run runs every check concurrently, each under its own timeout.
+func (h *Handler) Run(ctx context.Context) Report {
+	names := make([]string, 0, len(h.checks))
+	for name := range h.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]Result, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+			details, err := h.checks[name](ctx)
+			results[i] = Result{OK: err == nil, Details: details}
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Ready: true, Checks: make(map[string]Result, len(names))}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if !results[i].OK {
+			report.Ready = false
+			h.logger.Warn("readiness check failed", "check", name, "error", results[i].Error)
+		}
+	}
+	return report
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// Serve serves h on l until ctx is done, then shuts the server down.
+func Serve(ctx context.Context, l net.Listener, h http.Handler) error {
+	srv := &http.Server{Handler: h, ReadHeaderTimeout: DefaultCheckTimeout}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(l) }()
+
+	select {
+	case err := <-errc:
+		return fmt.Errorf("health server failed: %w", err)
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down health server: %w", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("health server failed: %w", err)
+	}
+	return nil
+}
+
+// ListenAndServe serves h on port until ctx is done.
+func ListenAndServe(ctx context.Context, port int, h http.Handler) error {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d: %w", port, err)
+	}
+	return Serve(ctx, l, h)
+}
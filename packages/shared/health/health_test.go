@@ -0,0 +1,127 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var quiet = WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+func ok(details any) Check {
+	return func(context.Context) (any, error) { return details, nil }
+}
+
+func failing(details any, msg string) Check {
+	return func(context.Context) (any, error) { return details, errors.New(msg) }
+}
+
+func get(t *testing.T, h http.Handler, path string) (int, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return rec.Code, body
+}
+
+func TestHandler_Live(t *testing.T) {
+	h := NewHandler(quiet, WithCheck("database", failing(nil, "down")))
+
+	status, body := get(t, h, "/healthz")
+
+	assert.Equal(t, http.StatusOK, status, "liveness does not run checks")
+	assert.Equal(t, "ok", body["status"])
+}
+
+func TestHandler_Ready(t *testing.T) {
+	h := NewHandler(quiet,
+		WithCheck("database", ok(nil)),
+		WithCheck("chain_lag", ok(map[string]int{"lag": 3})))
+
+	status, body := get(t, h, "/readyz")
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]any{
+		"ready": true,
+		"checks": map[string]any{
+			"database":  map[string]any{"ok": true},
+			"chain_lag": map[string]any{"ok": true, "details": map[string]any{"lag": float64(3)}},
+		},
+	}, body)
+}
+
+func TestHandler_NotReady(t *testing.T) {
+	h := NewHandler(quiet,
+		WithCheck("database", ok(nil)),
+		WithCheck("chain_lag", failing(map[string]int{"lag": 250}, "250 blocks behind")))
+
+	status, body := get(t, h, "/readyz")
+
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, false, body["ready"])
+	checks := body["checks"].(map[string]any)
+	assert.Equal(t, map[string]any{"ok": true}, checks["database"])
+	assert.Equal(t, map[string]any{
+		"ok": false, "error": "250 blocks behind", "details": map[string]any{"lag": float64(250)},
+	}, checks["chain_lag"])
+}
+
+func TestHandler_NoChecksIsReady(t *testing.T) {
+	status, _ := get(t, NewHandler(quiet), "/readyz")
+
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestHandler_CheckTimeout(t *testing.T) {
+	slow := func(ctx context.Context) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	h := NewHandler(quiet, WithTimeout(10*time.Millisecond), WithCheck("slow", slow), WithCheck("fast", ok(nil)))
+
+	report := h.Run(context.Background())
+
+	assert.False(t, report.Ready)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+	assert.True(t, report.Checks["fast"].OK)
+}
+
+func TestHandler_RejectsOtherMethods(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewHandler(quiet).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/readyz", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestServe_StopsWithContext(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Serve(ctx, l, NewHandler(quiet)) }()
+
+	resp, err := http.Get("http://" + l.Addr().String() + "/healthz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return after cancel")
+	}
+}
@@ -12,33 +12,80 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const createAccount = `-- name: CreateAccount :exec
-INSERT INTO accounts (client_id, name) VALUES ($1, $2)
+const countAccountsByClientID = `-- name: CountAccountsByClientID :one
+SELECT count(*) FROM accounts
+WHERE client_id = $1 AND deleted_at IS NULL
+`
+
+// Counts the accounts of the client that are not deleted.
+func (q *Queries) CountAccountsByClientID(ctx context.Context, clientID uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countAccountsByClientID, clientID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createAccount = `-- name: CreateAccount :one
+INSERT INTO accounts (client_id, name, default_expiry_seconds, default_webhook_endpoint_id, metadata, address_strategy)
+VALUES ($1, $2, $3, $4, $5,
+    COALESCE($6, 'unique_wallet'))
+RETURNING id, client_id, name, address_index, created_at, default_expiry_seconds, default_webhook_endpoint_id, metadata, deleted_at, address_strategy, deposit_wallet, deposit_wallet_index
 `
 
 type CreateAccountParams struct {
-	ClientID uuid.UUID `db:"client_id" json:"client_id"`
-	Name     string    `db:"name" json:"name"`
+	ClientID                 uuid.UUID  `db:"client_id" json:"client_id"`
+	Name                     string     `db:"name" json:"name"`
+	DefaultExpirySeconds     *int64     `db:"default_expiry_seconds" json:"default_expiry_seconds"`
+	DefaultWebhookEndpointID *uuid.UUID `db:"default_webhook_endpoint_id" json:"default_webhook_endpoint_id"`
+	Metadata                 []byte     `db:"metadata" json:"metadata"`
+	AddressStrategy          *string    `db:"address_strategy" json:"address_strategy"`
 }
 
-func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) error {
-	_, err := q.db.Exec(ctx, createAccount, arg.ClientID, arg.Name)
-	return err
+// Creates an account of the client; a nil address_strategy gives it a
+// wallet per payment.
+func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	row := q.db.QueryRow(ctx, createAccount,
+		arg.ClientID,
+		arg.Name,
+		arg.DefaultExpirySeconds,
+		arg.DefaultWebhookEndpointID,
+		arg.Metadata,
+		arg.AddressStrategy,
+	)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.ClientID,
+		&i.Name,
+		&i.AddressIndex,
+		&i.CreatedAt,
+		&i.DefaultExpirySeconds,
+		&i.DefaultWebhookEndpointID,
+		&i.Metadata,
+		&i.DeletedAt,
+		&i.AddressStrategy,
+		&i.DepositWallet,
+		&i.DepositWalletIndex,
+	)
+	return i, err
 }
 
 const getAccountByIDAndClientID = `-- name: GetAccountByIDAndClientID :one
-SELECT id, client_id, name, address_index, created_at
+SELECT id, client_id, name, address_index, created_at, default_expiry_seconds, default_webhook_endpoint_id, metadata, deleted_at, address_strategy, deposit_wallet, deposit_wallet_index
 FROM accounts
-WHERE id = $1 AND client_id = $2
+WHERE id = $1 AND client_id = $2 AND (deleted_at IS NULL OR $3::BOOL)
 `
 
 type GetAccountByIDAndClientIDParams struct {
-	ID       uuid.UUID `db:"id" json:"id"`
-	ClientID uuid.UUID `db:"client_id" json:"client_id"`
+	ID             uuid.UUID `db:"id" json:"id"`
+	ClientID       uuid.UUID `db:"client_id" json:"client_id"`
+	IncludeDeleted bool      `db:"include_deleted" json:"include_deleted"`
 }
 
+// Returns an account of the client; a soft deleted one only when
+// include_deleted is set.
 func (q *Queries) GetAccountByIDAndClientID(ctx context.Context, arg GetAccountByIDAndClientIDParams) (Account, error) {
-	row := q.db.QueryRow(ctx, getAccountByIDAndClientID, arg.ID, arg.ClientID)
+	row := q.db.QueryRow(ctx, getAccountByIDAndClientID, arg.ID, arg.ClientID, arg.IncludeDeleted)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -46,25 +93,40 @@ func (q *Queries) GetAccountByIDAndClientID(ctx context.Context, arg GetAccountB
 		&i.Name,
 		&i.AddressIndex,
 		&i.CreatedAt,
+		&i.DefaultExpirySeconds,
+		&i.DefaultWebhookEndpointID,
+		&i.Metadata,
+		&i.DeletedAt,
+		&i.AddressStrategy,
+		&i.DepositWallet,
+		&i.DepositWalletIndex,
 	)
 	return i, err
 }
 
 const getAccountsByClientID = `-- name: GetAccountsByClientID :many
-SELECT id, client_id, name, created_at
+SELECT id, client_id, name, created_at, deleted_at
 FROM accounts
-WHERE client_id = $1
+WHERE client_id = $1 AND (deleted_at IS NULL OR $2::BOOL)
 `
 
+type GetAccountsByClientIDParams struct {
+	ClientID       uuid.UUID `db:"client_id" json:"client_id"`
+	IncludeDeleted bool      `db:"include_deleted" json:"include_deleted"`
+}
+
 type GetAccountsByClientIDRow struct {
 	ID        uuid.UUID          `db:"id" json:"id"`
 	ClientID  uuid.UUID          `db:"client_id" json:"client_id"`
 	Name      string             `db:"name" json:"name"`
 	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	DeletedAt pgtype.Timestamptz `db:"deleted_at" json:"deleted_at"`
 }
 
-func (q *Queries) GetAccountsByClientID(ctx context.Context, clientID uuid.UUID) ([]GetAccountsByClientIDRow, error) {
-	rows, err := q.db.Query(ctx, getAccountsByClientID, clientID)
+// Lists the accounts of the client, leaving out soft deleted ones unless
+// include_deleted is set.
+func (q *Queries) GetAccountsByClientID(ctx context.Context, arg GetAccountsByClientIDParams) ([]GetAccountsByClientIDRow, error) {
+	rows, err := q.db.Query(ctx, getAccountsByClientID, arg.ClientID, arg.IncludeDeleted)
 	if err != nil {
 		return nil, err
 	}
@@ -77,6 +139,7 @@ func (q *Queries) GetAccountsByClientID(ctx context.Context, clientID uuid.UUID)
 			&i.ClientID,
 			&i.Name,
 			&i.CreatedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -87,3 +150,186 @@ func (q *Queries) GetAccountsByClientID(ctx context.Context, clientID uuid.UUID)
 	}
 	return items, nil
 }
+
+const listDepositWallets = `-- name: ListDepositWallets :many
+SELECT deposit_wallet::STRING
+FROM accounts
+WHERE deposit_wallet = ANY($1::STRING[])
+`
+
+// The wallets among wallets that are the deposit wallet of an account,
+// shared by its amount_suffix payments.
+func (q *Queries) ListDepositWallets(ctx context.Context, wallets []string) ([]string, error) {
+	rows, err := q.db.Query(ctx, listDepositWallets, wallets)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var deposit_wallet string
+		if err := rows.Scan(&deposit_wallet); err != nil {
+			return nil, err
+		}
+		items = append(items, deposit_wallet)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const replaceAccount = `-- name: ReplaceAccount :one
+UPDATE accounts
+SET name = $1, default_expiry_seconds = $2,
+    default_webhook_endpoint_id = $3, metadata = $4,
+    address_strategy = COALESCE($5, 'unique_wallet')
+WHERE id = $6 AND client_id = $7 AND deleted_at IS NULL
+RETURNING id, client_id, name, address_index, created_at, default_expiry_seconds, default_webhook_endpoint_id, metadata, deleted_at, address_strategy, deposit_wallet, deposit_wallet_index
+`
+
+type ReplaceAccountParams struct {
+	Name                     string     `db:"name" json:"name"`
+	DefaultExpirySeconds     *int64     `db:"default_expiry_seconds" json:"default_expiry_seconds"`
+	DefaultWebhookEndpointID *uuid.UUID `db:"default_webhook_endpoint_id" json:"default_webhook_endpoint_id"`
+	Metadata                 []byte     `db:"metadata" json:"metadata"`
+	AddressStrategy          *string    `db:"address_strategy" json:"address_strategy"`
+	ID                       uuid.UUID  `db:"id" json:"id"`
+	ClientID                 uuid.UUID  `db:"client_id" json:"client_id"`
+}
+
+// Replaces the name and settings of an account of the client. A nil
+// address_strategy gives it a wallet per payment.
+func (q *Queries) ReplaceAccount(ctx context.Context, arg ReplaceAccountParams) (Account, error) {
+	row := q.db.QueryRow(ctx, replaceAccount,
+		arg.Name,
+		arg.DefaultExpirySeconds,
+		arg.DefaultWebhookEndpointID,
+		arg.Metadata,
+		arg.AddressStrategy,
+		arg.ID,
+		arg.ClientID,
+	)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.ClientID,
+		&i.Name,
+		&i.AddressIndex,
+		&i.CreatedAt,
+		&i.DefaultExpirySeconds,
+		&i.DefaultWebhookEndpointID,
+		&i.Metadata,
+		&i.DeletedAt,
+		&i.AddressStrategy,
+		&i.DepositWallet,
+		&i.DepositWalletIndex,
+	)
+	return i, err
+}
+
+const setAccountDepositWallet = `-- name: SetAccountDepositWallet :one
+UPDATE accounts
+SET deposit_wallet = COALESCE(deposit_wallet, $1::STRING),
+    deposit_wallet_index = COALESCE(deposit_wallet_index, $2::INT8)
+WHERE id = $3
+RETURNING deposit_wallet::STRING, deposit_wallet_index::INT8
+`
+
+type SetAccountDepositWalletParams struct {
+	DepositWallet      string    `db:"deposit_wallet" json:"deposit_wallet"`
+	DepositWalletIndex int64     `db:"deposit_wallet_index" json:"deposit_wallet_index"`
+	ID                 uuid.UUID `db:"id" json:"id"`
+}
+
+type SetAccountDepositWalletRow struct {
+	DepositWallet      string `db:"deposit_wallet" json:"deposit_wallet"`
+	DepositWalletIndex int64  `db:"deposit_wallet_index" json:"deposit_wallet_index"`
+}
+
+// Gives the account the deposit wallet its amount_suffix payments pay into
+// unless it has one already, and returns the one it has.
+func (q *Queries) SetAccountDepositWallet(ctx context.Context, arg SetAccountDepositWalletParams) (SetAccountDepositWalletRow, error) {
+	row := q.db.QueryRow(ctx, setAccountDepositWallet, arg.DepositWallet, arg.DepositWalletIndex, arg.ID)
+	var i SetAccountDepositWalletRow
+	err := row.Scan(&i.DepositWallet, &i.DepositWalletIndex)
+	return i, err
+}
+
+const softDeleteAccount = `-- name: SoftDeleteAccount :one
+UPDATE accounts
+SET deleted_at = now()
+WHERE id = $1 AND client_id = $2 AND deleted_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM payments
+    WHERE account_id = $1 AND status IN ('PENDING', 'DETECTED')
+  )
+RETURNING id, client_id, name, address_index, created_at, default_expiry_seconds, default_webhook_endpoint_id, metadata, deleted_at, address_strategy, deposit_wallet, deposit_wallet_index
+`
+
+type SoftDeleteAccountParams struct {
+	ID       uuid.UUID `db:"id" json:"id"`
+	ClientID uuid.UUID `db:"client_id" json:"client_id"`
+}
+
+// Marks an account of the client deleted unless one of its payments is still
+// PENDING or DETECTED.
+func (q *Queries) SoftDeleteAccount(ctx context.Context, arg SoftDeleteAccountParams) (Account, error) {
+	row := q.db.QueryRow(ctx, softDeleteAccount, arg.ID, arg.ClientID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.ClientID,
+		&i.Name,
+		&i.AddressIndex,
+		&i.CreatedAt,
+		&i.DefaultExpirySeconds,
+		&i.DefaultWebhookEndpointID,
+		&i.Metadata,
+		&i.DeletedAt,
+		&i.AddressStrategy,
+		&i.DepositWallet,
+		&i.DepositWalletIndex,
+	)
+	return i, err
+}
+
+const updateAccount = `-- name: UpdateAccount :one
+UPDATE accounts
+SET name = COALESCE($1, name), address_strategy = COALESCE($2, address_strategy)
+WHERE id = $3 AND client_id = $4 AND deleted_at IS NULL
+RETURNING id, client_id, name, address_index, created_at, default_expiry_seconds, default_webhook_endpoint_id, metadata, deleted_at, address_strategy, deposit_wallet, deposit_wallet_index
+`
+
+type UpdateAccountParams struct {
+	Name            *string   `db:"name" json:"name"`
+	AddressStrategy *string   `db:"address_strategy" json:"address_strategy"`
+	ID              uuid.UUID `db:"id" json:"id"`
+	ClientID        uuid.UUID `db:"client_id" json:"client_id"`
+}
+
+// Changes the fields given of an account of the client, keeping the others.
+func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
+	row := q.db.QueryRow(ctx, updateAccount,
+		arg.Name,
+		arg.AddressStrategy,
+		arg.ID,
+		arg.ClientID,
+	)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.ClientID,
+		&i.Name,
+		&i.AddressIndex,
+		&i.CreatedAt,
+		&i.DefaultExpirySeconds,
+		&i.DefaultWebhookEndpointID,
+		&i.Metadata,
+		&i.DeletedAt,
+		&i.AddressStrategy,
+		&i.DepositWallet,
+		&i.DepositWalletIndex,
+	)
+	return i, err
+}
@@ -0,0 +1,177 @@
+//go:build integration
+
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// poolAddresses adds n fresh addresses to the pool of account, taking their
+// indexes from the deposit counter.
+func (f *fixture) poolAddresses(account Account, n int) []string {
+	f.t.Helper()
+	first, err := f.store.AllocateWalletIndexes(f.ctx, AllocateWalletIndexesParams{Name: "deposit", Count: int64(n)})
+	require.NoError(f.t, err)
+	indexes, addresses := make([]int64, n), make([]string, n)
+	for i := range n {
+		indexes[i], addresses[i] = first+int64(i), f.wallet()
+	}
+	added, err := f.store.CreatePoolAddresses(f.ctx, CreatePoolAddressesParams{
+		AccountID:      account.ID,
+		AddressIndexes: indexes,
+		Addresses:      addresses,
+	})
+	require.NoError(f.t, err)
+	require.EqualValues(f.t, n, added)
+	return addresses
+}
+
+func TestIntegration_AddressPool(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	account := f.account(f.client().ID)
+	addresses := f.poolAddresses(account, 2)
+
+	below, err := f.store.ListAccountsBelowLowWater(ctx, 3)
+	require.NoError(t, err)
+	assert.Contains(t, below, ListAccountsBelowLowWaterRow{AccountID: account.ID, Mode: "live", Unreserved: 2})
+
+	paymentID := uuid.New()
+	reserved, err := f.store.ReserveAddress(ctx, ReserveAddressParams{PaymentID: paymentID, AccountID: account.ID})
+	require.NoError(t, err)
+	assert.Equal(t, addresses[0], reserved.Address, "the lowest index goes first")
+	assert.Equal(t, UUIDPtr(paymentID), reserved.ReservedByPayment)
+
+	payment := f.payment(account, func(arg *CreatePaymentParams) {
+		arg.ID = reserved.ReservedByPayment
+		arg.UniqueWallet = reserved.Address
+		arg.WalletIndex = &reserved.AddressIndex
+	})
+	assert.Equal(t, paymentID, payment.ID, "the payment takes the id its wallet was reserved for")
+
+	_, err = f.store.ReserveAddress(ctx, ReserveAddressParams{PaymentID: paymentID, AccountID: account.ID})
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr, "one payment cannot reserve two addresses")
+	assert.Equal(t, "23505", pgErr.Code)
+
+	_, err = f.store.ReserveAddress(ctx, ReserveAddressParams{PaymentID: uuid.New(), AccountID: account.ID})
+	require.NoError(t, err)
+	_, err = f.store.ReserveAddress(ctx, ReserveAddressParams{PaymentID: uuid.New(), AccountID: account.ID})
+	assert.ErrorIs(t, err, pgx.ErrNoRows, "an empty pool has nothing to claim")
+
+	_, err = f.store.CreatePoolAddresses(ctx, CreatePoolAddressesParams{
+		AccountID:      account.ID,
+		AddressIndexes: []int64{reserved.AddressIndex + 1_000_000},
+		Addresses:      []string{addresses[1]},
+	})
+	require.ErrorAs(t, err, &pgErr, "an address is pooled once")
+	assert.Equal(t, "23505", pgErr.Code)
+}
+
+func TestIntegration_ReserveAddressRollsBack(t *testing.T) {
+	f := newFixture(t)
+	account := f.account(f.client().ID)
+	addresses := f.poolAddresses(account, 1)
+
+	errRollback := errors.New("rollback")
+	err := f.store.ExecTx(f.ctx, func(q Querier) error {
+		_, err := q.ReserveAddress(f.ctx, ReserveAddressParams{PaymentID: uuid.New(), AccountID: account.ID})
+		require.NoError(t, err)
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	reserved, err := f.store.ReserveAddress(f.ctx, ReserveAddressParams{PaymentID: uuid.New(), AccountID: account.ID})
+	require.NoError(t, err)
+	assert.Equal(t, addresses[0], reserved.Address, "an aborted reservation returns the address to the pool")
+}
+
+// TestIntegration_ReserveAddressConcurrent races more payments than the pool
+// holds, each reserving and inserting in one transaction as payment creation
+// does. Every pooled address must go to at most one payment, and every
+// payment must get a pooled address until the pool runs dry.
+func TestIntegration_ReserveAddressConcurrent(t *testing.T) {
+	f := newFixture(t)
+	account := f.account(f.client().ID)
+	const pooled, workers = 20, 40
+	addresses := f.poolAddresses(account, pooled)
+
+	var wg sync.WaitGroup
+	wallets := make([]string, workers)
+	errs := make([]error, workers)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wallets[i], errs[i] = createFromPool(f, account)
+		}()
+	}
+	wg.Wait()
+
+	claimed := make(map[string]bool, pooled)
+	for i, wallet := range wallets {
+		if errors.Is(errs[i], pgx.ErrNoRows) {
+			continue
+		}
+		require.NoError(t, errs[i])
+		assert.False(t, claimed[wallet], "address %s handed to two payments", wallet)
+		claimed[wallet] = true
+	}
+	assert.Len(t, claimed, pooled, "every pooled address is claimed")
+	for _, address := range addresses {
+		assert.True(t, claimed[address], "address %s never claimed", address)
+	}
+
+	var reserved int
+	require.NoError(t, f.pool.QueryRow(f.ctx,
+		`SELECT count(DISTINCT p.id) FROM address_pool a JOIN payments p ON p.id = a.reserved_by_payment AND p.unique_wallet = a.address
+		 WHERE a.account_id = $1`, account.ID).Scan(&reserved))
+	assert.Equal(t, pooled, reserved, "each reservation belongs to the payment holding its address")
+}
+
+// createFromPool reserves an address and creates a payment on it, retrying
+// the transactions CockroachDB aborts under contention. It returns
+// pgx.ErrNoRows once the pool is empty.
+func createFromPool(f *fixture, account Account) (string, error) {
+	for attempt := 0; ; attempt++ {
+		var wallet string
+		err := f.store.ExecTx(f.ctx, func(q Querier) error {
+			id := uuid.New()
+			reserved, err := q.ReserveAddress(f.ctx, ReserveAddressParams{PaymentID: id, AccountID: account.ID})
+			if err != nil {
+				return err
+			}
+			_, err = q.CreatePayment(f.ctx, CreatePaymentParams{
+				ClientID:     account.ClientID,
+				AccountID:    account.ID,
+				Amount:       numeric(2550, -2),
+				UniqueWallet: reserved.Address,
+				ExpiresAt:    timestamptz(time.Now().Add(time.Hour)),
+				WalletIndex:  &reserved.AddressIndex,
+				Token:        "USDT",
+				Mode:         "live",
+				ID:           UUIDPtr(id),
+			})
+			wallet = reserved.Address
+			return err
+		})
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "40001" && attempt < 10 {
+			continue
+		}
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("attempt %d: %w", attempt, err)
+		}
+		return wallet, err
+	}
+}
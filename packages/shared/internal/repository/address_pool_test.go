@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReserveAddressSQL(t *testing.T) {
+	assert.Contains(t, reserveAddress, "WHERE account_id = $2 AND reserved_by_payment IS NULL")
+	assert.Contains(t, reserveAddress, "ORDER BY address_index\n    LIMIT 1\n    FOR UPDATE SKIP LOCKED",
+		"concurrent claims skip each other's row instead of sharing it")
+	assert.Contains(t, reserveAddress, ") AND reserved_by_payment IS NULL\nRETURNING", "a row is only ever claimed once")
+}
+
+func TestListAccountsBelowLowWaterSQL(t *testing.T) {
+	assert.Contains(t, listAccountsBelowLowWater, "LEFT JOIN address_pool p ON p.account_id = a.id AND p.reserved_by_payment IS NULL",
+		"accounts with an empty pool are listed too")
+	assert.Contains(t, listAccountsBelowLowWater, "WHERE a.deleted_at IS NULL AND c.is_active IS NOT FALSE")
+	assert.Contains(t, listAccountsBelowLowWater, "HAVING count(p.id) < $1::INT8")
+}
+
+func TestQueries_CreatePoolAddresses(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	params := CreatePoolAddressesParams{
+		AccountID:      uuid.New(),
+		AddressIndexes: []int64{4, 5},
+		Addresses:      []string{"TWallet4", "TWallet5"},
+	}
+	mockDB.On("Exec", ctx, createPoolAddresses, []interface{}{params.AccountID, params.AddressIndexes, params.Addresses}).
+		Return(pgconn.NewCommandTag("INSERT 0 2"), nil)
+
+	added, err := queries.CreatePoolAddresses(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), added)
+	assert.Contains(t, createPoolAddresses, "FROM unnest($2::INT8[], $3::STRING[]) AS t(address_index, address)")
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_ReserveAddress(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	params := ReserveAddressParams{PaymentID: uuid.New(), AccountID: uuid.New()}
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, reserveAddress, []interface{}{params.PaymentID, params.AccountID}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 6)
+		*dest[1].(*uuid.UUID) = params.AccountID
+		*dest[2].(*int64) = 4
+		*dest[3].(*string) = "TWallet4"
+		*dest[4].(**uuid.UUID) = UUIDPtr(params.PaymentID)
+	})
+
+	reserved, err := queries.ReserveAddress(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), reserved.AddressIndex)
+	assert.Equal(t, "TWallet4", reserved.Address)
+	assert.Equal(t, UUIDPtr(params.PaymentID), reserved.ReservedByPayment)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_ListAccountsBelowLowWater(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	accountID := uuid.New()
+
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, listAccountsBelowLowWater, []interface{}{int64(20)}).Return(mockRows, nil)
+	mockRows.On("Close").Return(nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 3)
+		*dest[0].(*uuid.UUID) = accountID
+		*dest[1].(*string) = "test"
+		*dest[2].(*int64) = 3
+	})
+	mockRows.On("Err").Return(nil)
+
+	rows, err := queries.ListAccountsBelowLowWater(ctx, 20)
+
+	require.NoError(t, err)
+	assert.Equal(t, []ListAccountsBelowLowWaterRow{{AccountID: accountID, Mode: "test", Unreserved: 3}}, rows)
+	mockDB.AssertExpectations(t)
+}
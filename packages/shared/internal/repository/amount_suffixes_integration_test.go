@@ -0,0 +1,86 @@
+//go:build integration
+
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegration_AmountSuffixes(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	account := f.account(f.client().ID)
+	now := time.Now()
+	reserve := func(at time.Time) (uuid.UUID, int32, error) {
+		id := uuid.New()
+		suffix, err := f.store.ReserveAmountSuffix(ctx, ReserveAmountSuffixParams{
+			AccountID: account.ID,
+			PaymentID: id,
+			ExpiresAt: timestamptz(at.Add(time.Hour)),
+			Now:       timestamptz(at),
+		})
+		return id, suffix, err
+	}
+
+	_, first, err := reserve(now)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), first)
+	_, second, err := reserve(now)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), second)
+
+	later := now.Add(2 * time.Hour)
+	id, suffix, err := reserve(later)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), suffix, "an expired suffix is reserved again")
+	var paymentID uuid.UUID
+	require.NoError(t, f.pool.QueryRow(ctx,
+		`SELECT payment_id FROM amount_suffix_reservations WHERE account_id = $1 AND suffix = 1`, account.ID).Scan(&paymentID))
+	assert.Equal(t, id, paymentID)
+}
+
+func TestIntegration_SharedDepositWallet(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	account := f.account(f.client().ID)
+	wallet := f.wallet()
+
+	row, err := f.store.SetAccountDepositWallet(ctx, SetAccountDepositWalletParams{DepositWallet: wallet, DepositWalletIndex: 4, ID: account.ID})
+	require.NoError(t, err)
+	assert.Equal(t, wallet, row.DepositWallet)
+	row, err = f.store.SetAccountDepositWallet(ctx, SetAccountDepositWalletParams{DepositWallet: f.wallet(), DepositWalletIndex: 5, ID: account.ID})
+	require.NoError(t, err)
+	assert.Equal(t, wallet, row.DepositWallet, "a deposit wallet once set is kept")
+	shared, err := f.store.ListDepositWallets(ctx, []string{wallet, f.wallet()})
+	require.NoError(t, err)
+	assert.Equal(t, []string{wallet}, shared)
+
+	shares := func(amount int64) func(*CreatePaymentParams) {
+		return func(arg *CreatePaymentParams) {
+			arg.UniqueWallet, arg.WalletIndex, arg.Amount = wallet, nil, numeric(amount, -6)
+		}
+	}
+	f.payment(account, shares(25500001))
+	second := f.payment(account, shares(25500002))
+
+	got, err := f.store.GetOpenPaymentByWalletAndAmount(ctx, GetOpenPaymentByWalletAndAmountParams{
+		Wallet: wallet,
+		Amount: numeric(25500002, -6),
+		Mode:   "live",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, got.ID, "open payments share the wallet and are told apart by amount")
+
+	_, err = f.store.GetOpenPaymentByWalletAndAmount(ctx, GetOpenPaymentByWalletAndAmountParams{
+		Wallet: wallet,
+		Amount: numeric(25500003, -6),
+		Mode:   "live",
+	})
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReserveAmountSuffixSQL(t *testing.T) {
+	assert.Contains(t, reserveAmountSuffix, "FROM generate_series(1, 999) AS s(suffix)")
+	assert.Contains(t, reserveAmountSuffix, "WHERE r.account_id = $1 AND r.suffix = s.suffix AND r.expires_at > $4",
+		"a suffix is free once its reservation expired")
+	assert.Contains(t, reserveAmountSuffix, "ORDER BY s.suffix\nLIMIT 1", "the lowest free suffix goes first")
+	assert.Contains(t, reserveAmountSuffix, "ON CONFLICT (account_id, suffix) DO UPDATE",
+		"an expired reservation is taken over in place")
+}
+
+func TestQueries_ReserveAmountSuffix(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	params := ReserveAmountSuffixParams{
+		AccountID: uuid.New(),
+		PaymentID: uuid.New(),
+		ExpiresAt: pgtype.Timestamptz{Time: now.Add(time.Hour), Valid: true},
+		Now:       pgtype.Timestamptz{Time: now, Valid: true},
+	}
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, reserveAmountSuffix, []interface{}{params.AccountID, params.PaymentID, params.ExpiresAt, params.Now}).
+		Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 1)
+		*dest[0].(*int32) = 7
+	})
+
+	suffix, err := queries.ReserveAmountSuffix(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(7), suffix)
+	mockDB.AssertExpectations(t)
+}
@@ -11,22 +11,65 @@ import (
 	"github.com/google/uuid"
 )
 
-const createClient = `-- name: CreateClient :exec
-INSERT INTO clients (name, api_key) VALUES ($1, $2)
+const clientExistsByAPIKey = `-- name: ClientExistsByAPIKey :one
+SELECT EXISTS (SELECT 1 FROM clients WHERE api_key = $1)
+`
+
+// Reports whether any client, active or not, holds the API key.
+func (q *Queries) ClientExistsByAPIKey(ctx context.Context, apiKey string) (bool, error) {
+	row := q.db.QueryRow(ctx, clientExistsByAPIKey, apiKey)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const createClient = `-- name: CreateClient :one
+INSERT INTO clients (name, api_key, mode) VALUES ($1, $2, $3)
+RETURNING id, name, api_key, is_active, created_at, mode
 `
 
 type CreateClientParams struct {
 	Name   string `db:"name" json:"name"`
 	ApiKey string `db:"api_key" json:"api_key"`
+	Mode   string `db:"mode" json:"mode"`
 }
 
-func (q *Queries) CreateClient(ctx context.Context, arg CreateClientParams) error {
-	_, err := q.db.Exec(ctx, createClient, arg.Name, arg.ApiKey)
-	return err
+func (q *Queries) CreateClient(ctx context.Context, arg CreateClientParams) (Client, error) {
+	row := q.db.QueryRow(ctx, createClient, arg.Name, arg.ApiKey, arg.Mode)
+	var i Client
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.ApiKey,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.Mode,
+	)
+	return i, err
+}
+
+const deactivateClient = `-- name: DeactivateClient :one
+UPDATE clients SET is_active = FALSE
+WHERE id = $1
+RETURNING id, name, api_key, is_active, created_at, mode
+`
+
+func (q *Queries) DeactivateClient(ctx context.Context, id uuid.UUID) (Client, error) {
+	row := q.db.QueryRow(ctx, deactivateClient, id)
+	var i Client
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.ApiKey,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.Mode,
+	)
+	return i, err
 }
 
 const getClientByAPIKey = `-- name: GetClientByAPIKey :one
-SELECT id, name, api_key, is_active, created_at
+SELECT id, name, api_key, is_active, created_at, mode
 FROM clients
 WHERE api_key = $1 AND is_active = TRUE
 LIMIT 1
@@ -41,12 +84,13 @@ func (q *Queries) GetClientByAPIKey(ctx context.Context, apiKey string) (Client,
 		&i.ApiKey,
 		&i.IsActive,
 		&i.CreatedAt,
+		&i.Mode,
 	)
 	return i, err
 }
 
 const getClientByID = `-- name: GetClientByID :one
-SELECT id, name, api_key, is_active, created_at
+SELECT id, name, api_key, is_active, created_at, mode
 FROM clients
 WHERE id = $1
 LIMIT 1
@@ -61,6 +105,72 @@ func (q *Queries) GetClientByID(ctx context.Context, id uuid.UUID) (Client, erro
 		&i.ApiKey,
 		&i.IsActive,
 		&i.CreatedAt,
+		&i.Mode,
+	)
+	return i, err
+}
+
+const listClients = `-- name: ListClients :many
+SELECT id, name, api_key, is_active, created_at, mode
+FROM clients
+WHERE id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type ListClientsParams struct {
+	AfterID uuid.UUID `db:"after_id" json:"after_id"`
+	Limit   int32     `db:"limit" json:"limit"`
+}
+
+func (q *Queries) ListClients(ctx context.Context, arg ListClientsParams) ([]Client, error) {
+	rows, err := q.db.Query(ctx, listClients, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Client
+	for rows.Next() {
+		var i Client
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.ApiKey,
+			&i.IsActive,
+			&i.CreatedAt,
+			&i.Mode,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const rotateClientAPIKey = `-- name: RotateClientAPIKey :one
+UPDATE clients SET api_key = $1
+WHERE id = $2
+RETURNING id, name, api_key, is_active, created_at, mode
+`
+
+type RotateClientAPIKeyParams struct {
+	ApiKey string    `db:"api_key" json:"api_key"`
+	ID     uuid.UUID `db:"id" json:"id"`
+}
+
+func (q *Queries) RotateClientAPIKey(ctx context.Context, arg RotateClientAPIKeyParams) (Client, error) {
+	row := q.db.QueryRow(ctx, rotateClientAPIKey, arg.ApiKey, arg.ID)
+	var i Client
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.ApiKey,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.Mode,
 	)
 	return i, err
 }
@@ -83,10 +83,11 @@ func TestQueries_CreateClient_Success(t *testing.T) {
 		ApiKey: "test-api-key",
 	}
 
-	mockResult := new(MockResult)
-	mockDB.On("ExecContext", ctx, createClient, mock.Anything).Return(mockResult, nil)
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createClient, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil)
 
-	err := queries.CreateClient(ctx, params)
+	_, err := queries.CreateClient(ctx, params)
 
 	assert.NoError(t, err)
 	mockDB.AssertExpectations(t)
@@ -103,9 +104,11 @@ func TestQueries_CreateClient_Error(t *testing.T) {
 	}
 
 	expectedErr := errors.New("duplicate key error")
-	mockDB.On("ExecContext", ctx, createClient, mock.Anything).Return(nil, expectedErr)
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createClient, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(expectedErr)
 
-	err := queries.CreateClient(ctx, params)
+	_, err := queries.CreateClient(ctx, params)
 
 	assert.Error(t, err)
 	assert.Equal(t, expectedErr, err)
@@ -124,9 +127,11 @@ func TestQueries_CreateClient_ContextCancellation(t *testing.T) {
 		ApiKey: "test-api-key",
 	}
 
-	mockDB.On("ExecContext", ctx, createClient, mock.Anything).Return(nil, context.Canceled)
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createClient, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(context.Canceled)
 
-	err := queries.CreateClient(ctx, params)
+	_, err := queries.CreateClient(ctx, params)
 
 	assert.Error(t, err)
 	assert.Equal(t, context.Canceled, err)
@@ -143,10 +148,11 @@ func TestQueries_CreateClient_EmptyName(t *testing.T) {
 		ApiKey: "test-api-key",
 	}
 
-	mockResult := new(MockResult)
-	mockDB.On("ExecContext", ctx, createClient, mock.Anything).Return(mockResult, nil)
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createClient, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil)
 
-	err := queries.CreateClient(ctx, params)
+	_, err := queries.CreateClient(ctx, params)
 
 	assert.NoError(t, err)
 	mockDB.AssertExpectations(t)
@@ -162,10 +168,11 @@ func TestQueries_CreateClient_EmptyApiKey(t *testing.T) {
 		ApiKey: "",
 	}
 
-	mockResult := new(MockResult)
-	mockDB.On("ExecContext", ctx, createClient, mock.Anything).Return(mockResult, nil)
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createClient, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil)
 
-	err := queries.CreateClient(ctx, params)
+	_, err := queries.CreateClient(ctx, params)
 
 	assert.NoError(t, err)
 	mockDB.AssertExpectations(t)
@@ -182,10 +189,11 @@ func TestQueries_CreateClient_LongApiKey(t *testing.T) {
 		ApiKey: longKey,
 	}
 
-	mockResult := new(MockResult)
-	mockDB.On("ExecContext", ctx, createClient, mock.Anything).Return(mockResult, nil)
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createClient, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil)
 
-	err := queries.CreateClient(ctx, params)
+	_, err := queries.CreateClient(ctx, params)
 
 	assert.NoError(t, err)
 	mockDB.AssertExpectations(t)
@@ -210,7 +218,8 @@ func TestQueries_GetClientByAPIKey_Success(t *testing.T) {
 	apiKey := "test-api-key"
 
 	mockRow := new(MockRow)
-	mockDB.On("QueryRowContext", ctx, getClientByAPIKey, mock.Anything).Return(mockRow)
+	mockDB.On("QueryRow", ctx, getClientByAPIKey, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil)
 
 	_, _ = queries.GetClientByAPIKey(ctx, apiKey)
 
@@ -225,7 +234,8 @@ func TestQueries_GetClientByAPIKey_EmptyKey(t *testing.T) {
 	apiKey := ""
 
 	mockRow := new(MockRow)
-	mockDB.On("QueryRowContext", ctx, getClientByAPIKey, mock.Anything).Return(mockRow)
+	mockDB.On("QueryRow", ctx, getClientByAPIKey, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil)
 
 	_, _ = queries.GetClientByAPIKey(ctx, apiKey)
 
@@ -240,7 +250,8 @@ func TestQueries_GetClientByAPIKey_SpecialCharacters(t *testing.T) {
 	apiKey := "key-with-special-chars!@#$%"
 
 	mockRow := new(MockRow)
-	mockDB.On("QueryRowContext", ctx, getClientByAPIKey, mock.Anything).Return(mockRow)
+	mockDB.On("QueryRow", ctx, getClientByAPIKey, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil)
 
 	_, _ = queries.GetClientByAPIKey(ctx, apiKey)
 
@@ -255,7 +266,8 @@ func TestQueries_GetClientByID_Success(t *testing.T) {
 	id := uuid.New()
 
 	mockRow := new(MockRow)
-	mockDB.On("QueryRowContext", ctx, getClientByID, mock.Anything).Return(mockRow)
+	mockDB.On("QueryRow", ctx, getClientByID, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil)
 
 	_, _ = queries.GetClientByID(ctx, id)
 
@@ -270,7 +282,8 @@ func TestQueries_GetClientByID_NilUUID(t *testing.T) {
 	id := uuid.Nil
 
 	mockRow := new(MockRow)
-	mockDB.On("QueryRowContext", ctx, getClientByID, mock.Anything).Return(mockRow)
+	mockDB.On("QueryRow", ctx, getClientByID, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil)
 
 	_, _ = queries.GetClientByID(ctx, id)
 
@@ -287,7 +300,8 @@ func TestQueries_GetClientByID_ContextCancellation(t *testing.T) {
 	id := uuid.New()
 
 	mockRow := new(MockRow)
-	mockDB.On("QueryRowContext", ctx, getClientByID, mock.Anything).Return(mockRow)
+	mockDB.On("QueryRow", ctx, getClientByID, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil)
 
 	_, _ = queries.GetClientByID(ctx, id)
 
@@ -295,17 +309,17 @@ func TestQueries_GetClientByID_ContextCancellation(t *testing.T) {
 }
 
 func TestCreateClientSQL(t *testing.T) {
-	expectedSQL := "-- name: CreateClient :exec\nINSERT INTO clients (name, api_key) VALUES ($1, $2)\n"
+	expectedSQL := "-- name: CreateClient :one\nINSERT INTO clients (name, api_key, mode) VALUES ($1, $2, $3)\nRETURNING id, name, api_key, is_active, created_at, mode\n"
 	assert.Equal(t, expectedSQL, createClient)
 }
 
 func TestGetClientByAPIKeySQL(t *testing.T) {
-	expectedSQL := "-- name: GetClientByAPIKey :one\nSELECT id, name, api_key, is_active, created_at\nFROM clients\nWHERE api_key = $1 AND is_active = TRUE\nLIMIT 1\n"
+	expectedSQL := "-- name: GetClientByAPIKey :one\nSELECT id, name, api_key, is_active, created_at, mode\nFROM clients\nWHERE api_key = $1 AND is_active = TRUE\nLIMIT 1\n"
 	assert.Equal(t, expectedSQL, getClientByAPIKey)
 }
 
 func TestGetClientByIDSQL(t *testing.T) {
-	expectedSQL := "-- name: GetClientByID :one\nSELECT id, name, api_key, is_active, created_at\nFROM clients\nWHERE id = $1\nLIMIT 1\n"
+	expectedSQL := "-- name: GetClientByID :one\nSELECT id, name, api_key, is_active, created_at, mode\nFROM clients\nWHERE id = $1\nLIMIT 1\n"
 	assert.Equal(t, expectedSQL, getClientByID)
 }
 
@@ -367,3 +381,102 @@ func TestGetClientByAPIKey_ActiveClientFilter(t *testing.T) {
 	// Verify that the SQL query filters for active clients
 	assert.Contains(t, getClientByAPIKey, "is_active = TRUE")
 }
+
+func TestQueries_DeactivateClient(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	id := uuid.New()
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, deactivateClient, []interface{}{id}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 6)
+		*dest[0].(*uuid.UUID) = id
+		inactive := false
+		*dest[3].(**bool) = &inactive
+	})
+
+	client, err := queries.DeactivateClient(ctx, id)
+
+	require.NoError(t, err)
+	assert.Equal(t, id, client.ID)
+	require.NotNil(t, client.IsActive)
+	assert.False(t, *client.IsActive)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_RotateClientAPIKey(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	arg := RotateClientAPIKeyParams{ApiKey: "new-key", ID: uuid.New()}
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, rotateClientAPIKey, []interface{}{arg.ApiKey, arg.ID}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		*dest[2].(*string) = arg.ApiKey
+	})
+
+	client, err := queries.RotateClientAPIKey(ctx, arg)
+
+	require.NoError(t, err)
+	assert.Equal(t, "new-key", client.ApiKey)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_ListClients(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	id := uuid.New()
+
+	mockRows := new(MockRows)
+	arg := ListClientsParams{AfterID: uuid.Nil, Limit: 51}
+	mockDB.On("Query", ctx, listClients, []interface{}{arg.AfterID, arg.Limit}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 6)
+		*dest[0].(*uuid.UUID) = id
+		*dest[1].(*string) = "shop"
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	clients, err := queries.ListClients(ctx, arg)
+
+	require.NoError(t, err)
+	require.Len(t, clients, 1)
+	assert.Equal(t, id, clients[0].ID)
+	assert.Equal(t, "shop", clients[0].Name)
+	mockDB.AssertExpectations(t)
+}
+
+func TestListClientsSQL(t *testing.T) {
+	assert.Contains(t, listClients, "WHERE id > $1\nORDER BY id\nLIMIT $2", "ListClients pages by id")
+}
+
+func TestQueries_ClientExistsByAPIKey(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, clientExistsByAPIKey, []interface{}{"tpg_key"}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 1)
+		*dest[0].(*bool) = true
+	})
+
+	exists, err := queries.ClientExistsByAPIKey(ctx, "tpg_key")
+
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "-- name: ClientExistsByAPIKey :one\nSELECT EXISTS (SELECT 1 FROM clients WHERE api_key = $1)\n", clientExistsByAPIKey)
+	mockDB.AssertExpectations(t)
+}
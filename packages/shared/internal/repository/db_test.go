@@ -76,7 +76,7 @@ func TestQueries_WithTx_MultipleTransactions(t *testing.T) {
 	txQueries2 := queries.WithTx(tx2)
 
 	// Both should have different transactions
-	assert.NotEqual(t, txQueries1.db, txQueries2.db)
+	assert.NotSame(t, txQueries1.db, txQueries2.db)
 	assert.Equal(t, tx1, txQueries1.db)
 	assert.Equal(t, tx2, txQueries2.db)
 }
@@ -106,7 +106,7 @@ func TestNew_MultipleInstances(t *testing.T) {
 	queries1 := New(mockDB1)
 	queries2 := New(mockDB2)
 
-	assert.NotEqual(t, queries1, queries2)
+	assert.NotSame(t, queries1, queries2)
 	assert.Equal(t, mockDB1, queries1.db)
 	assert.Equal(t, mockDB2, queries2.db)
 }
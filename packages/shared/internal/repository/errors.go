@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// uniqueViolation is the SQLSTATE for unique_violation.
+const uniqueViolation = "23505"
+
+// ErrDuplicateWallet is returned when a wallet is already assigned to another
+// PENDING, DETECTED or UNDERPAID payment, or its index to any payment.
+var ErrDuplicateWallet = errors.New("wallet already assigned to an open payment")
+
+// ErrDuplicateOrderReference is returned by CreatePayment when the client
+// already has a payment with the same order reference.
+var ErrDuplicateOrderReference = errors.New("order reference already used")
+
+// ErrPaymentNotPending is returned by state transitions that only apply to a
+// PENDING or DETECTED payment when the payment has already moved on.
+var ErrPaymentNotPending = errors.New("payment is not pending")
+
+// ErrPaymentStatusChanged is returned by UpdatePaymentStatus,
+// RevertPaymentConfirmation and CancelPayment when the payment is no longer
+// in the status the caller expected.
+var ErrPaymentStatusChanged = errors.New("payment status changed")
+
+// ErrVersionConflict is returned by the payment updates when the payment was
+// updated since the caller read its version. The caller should read it
+// again and retry, e.g. with RetryTx.
+var ErrVersionConflict = errors.New("payment updated concurrently")
+
+// ErrInvalidPaymentTransition is returned by UpdatePaymentStatus,
+// RevertPaymentConfirmation and CancelPayment for a move the payment
+// lifecycle does not allow, e.g. out of a terminal status.
+var ErrInvalidPaymentTransition = errors.New("invalid payment status transition")
+
+// ErrDuplicateTransaction is returned when a transfer has already been
+// recorded, e.g. because its block was scanned twice.
+var ErrDuplicateTransaction = errors.New("transaction already recorded")
+
+// ErrSweepInProgress is returned by CreateSweep when another sweep of the
+// same wallet and token is still SIGNED or SENT.
+var ErrSweepInProgress = errors.New("sweep already in progress")
+
+// ErrSweepStatusChanged is returned by UpdateSweepStatus when the sweep is no
+// longer in the status the caller expected.
+var ErrSweepStatusChanged = errors.New("sweep status changed")
+
+// ErrNotFound is returned by UpdateAccount when the client has no such
+// account.
+var ErrNotFound = errors.New("not found")
+
+// ErrAccountHasOpenPayments is returned by SoftDeleteAccount when the account
+// still has a PENDING or DETECTED payment.
+var ErrAccountHasOpenPayments = errors.New("account has open payments")
+
+// ErrStateConflict is returned by UpsertWatcherState when the watcher's row
+// is no longer at the height the caller read, e.g. because another replica
+// of the watcher or an operator reset moved it.
+var ErrStateConflict = errors.New("watcher state changed")
+
+// ErrQueryTimeout is returned when a query runs past the Store's query
+// timeout or the session's statement_timeout. The database is slow rather
+// than broken, so the request is worth retrying later.
+var ErrQueryTimeout = errors.New("query timed out")
+
+// isUniqueViolation reports whether err is a unique violation on the named
+// constraint or index.
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
+		return false
+	}
+	return pgErr.ConstraintName == constraint || strings.Contains(pgErr.Message, constraint)
+}
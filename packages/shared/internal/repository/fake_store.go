@@ -0,0 +1,821 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// SQLSTATEs the FakeStore reports besides uniqueViolation.
+const (
+	foreignKeyViolation = "23503"
+	checkViolation      = "23514"
+)
+
+// maxNameLength is the longest client or account name the name length
+// checks allow.
+const maxNameLength = 200
+
+// maxAmountSuffix is the highest suffix amount_suffix_reservations holds.
+const maxAmountSuffix = 999
+
+// FakeStore is an in-memory Store for tests of the layers above the
+// repository. It keeps clients, accounts, webhook endpoints, payments, the
+// address pool, amount suffix reservations, wallet index counters and logs in
+// memory and enforces the unique, foreign key and check constraints of their
+// tables, failing the way the database and Store would: the Store's errors
+// where it translates them, a *pgconn.PgError or pgx.ErrNoRows otherwise.
+//
+// Queries it does not model go to the embedded Querier, e.g. a MockQuerier
+// set up for the one query a test needs; with none set they panic.
+type FakeStore struct {
+	Querier
+
+	now func() time.Time
+
+	// tx serialises ExecTx; mu guards the tables. Queries made outside a
+	// transaction see the writes of one in progress.
+	tx sync.Mutex
+	mu sync.Mutex
+
+	clients   map[uuid.UUID]Client
+	accounts  map[uuid.UUID]Account
+	endpoints map[uuid.UUID]WebhookEndpoint
+	payments  map[uuid.UUID]Payment
+	pool      []AddressPool
+	suffixes  []AmountSuffixReservation
+	counters  map[string]int64
+	logs      []Log
+}
+
+// FakeStoreOption customises a FakeStore.
+type FakeStoreOption func(*FakeStore)
+
+// WithFakeClock sets the clock the FakeStore fills in created_at, deleted_at
+// and the other now() defaults with.
+func WithFakeClock(now func() time.Time) FakeStoreOption {
+	return func(f *FakeStore) { f.now = now }
+}
+
+// WithFallback sends the queries the FakeStore does not model to q.
+func WithFallback(q Querier) FakeStoreOption {
+	return func(f *FakeStore) { f.Querier = q }
+}
+
+// NewFakeStore returns an empty FakeStore.
+func NewFakeStore(opts ...FakeStoreOption) *FakeStore {
+	f := &FakeStore{
+		now:       time.Now,
+		clients:   make(map[uuid.UUID]Client),
+		accounts:  make(map[uuid.UUID]Account),
+		endpoints: make(map[uuid.UUID]WebhookEndpoint),
+		payments:  make(map[uuid.UUID]Payment),
+		counters:  make(map[string]int64),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// ExecTx runs fn with the FakeStore, undoing every write fn made when it
+// returns an error.
+func (f *FakeStore) ExecTx(_ context.Context, fn func(Querier) error) error {
+	f.tx.Lock()
+	defer f.tx.Unlock()
+
+	f.mu.Lock()
+	clients, accounts, endpoints, payments := maps.Clone(f.clients), maps.Clone(f.accounts), maps.Clone(f.endpoints), maps.Clone(f.payments)
+	pool, suffixes, counters, logs := slices.Clone(f.pool), slices.Clone(f.suffixes), maps.Clone(f.counters), slices.Clone(f.logs)
+	f.mu.Unlock()
+
+	if err := fn(f); err != nil {
+		f.mu.Lock()
+		f.clients, f.accounts, f.endpoints, f.payments = clients, accounts, endpoints, payments
+		f.pool, f.suffixes, f.counters, f.logs = pool, suffixes, counters, logs
+		f.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// AddWebhookEndpoint stores e as a webhook endpoint, which Querier has no
+// query to create, filling in the ID and the column defaults e leaves
+// unset.
+func (f *FakeStore) AddWebhookEndpoint(e WebhookEndpoint) (WebhookEndpoint, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	if _, ok := f.endpoints[e.ID]; ok {
+		return WebhookEndpoint{}, fakeViolation(uniqueViolation, "webhook_endpoints_pkey")
+	}
+	if _, ok := f.clients[e.ClientID]; !ok {
+		return WebhookEndpoint{}, fakeViolation(foreignKeyViolation, "webhook_endpoints_client_id_fkey")
+	}
+	if !e.CreatedAt.Valid {
+		e.CreatedAt = f.timestamp()
+	}
+	if e.ApiVersion == "" {
+		e.ApiVersion = "2026-03-01"
+	}
+	f.endpoints[e.ID] = e
+	return e, nil
+}
+
+// Logs returns the logs written so far, oldest first.
+func (f *FakeStore) Logs() []Log {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.logs)
+}
+
+// AddressPool returns the pooled addresses, reserved or not, in the order
+// they were added.
+func (f *FakeStore) AddressPool() []AddressPool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.pool)
+}
+
+// AmountSuffixReservations returns the suffix reservations, expired or not,
+// in the order they were first made.
+func (f *FakeStore) AmountSuffixReservations() []AmountSuffixReservation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.suffixes)
+}
+
+func (f *FakeStore) ClientExistsByAPIKey(_ context.Context, apiKey string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.clientByAPIKey(apiKey)
+	return ok, nil
+}
+
+func (f *FakeStore) CreateClient(_ context.Context, arg CreateClientParams) (Client, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.checkClient(uuid.Nil, arg.Name, arg.ApiKey, arg.Mode); err != nil {
+		return Client{}, err
+	}
+	active := true
+	c := Client{ID: uuid.New(), Name: arg.Name, ApiKey: arg.ApiKey, IsActive: &active, CreatedAt: f.timestamp(), Mode: arg.Mode}
+	f.clients[c.ID] = c
+	return c, nil
+}
+
+func (f *FakeStore) DeactivateClient(_ context.Context, id uuid.UUID) (Client, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.clients[id]
+	if !ok {
+		return Client{}, pgx.ErrNoRows
+	}
+	active := false
+	c.IsActive = &active
+	f.clients[id] = c
+	return c, nil
+}
+
+func (f *FakeStore) GetClientByAPIKey(_ context.Context, apiKey string) (Client, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.clientByAPIKey(apiKey)
+	if !ok || c.IsActive == nil || !*c.IsActive {
+		return Client{}, pgx.ErrNoRows
+	}
+	return c, nil
+}
+
+func (f *FakeStore) GetClientByID(_ context.Context, id uuid.UUID) (Client, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.clients[id]
+	if !ok {
+		return Client{}, pgx.ErrNoRows
+	}
+	return c, nil
+}
+
+func (f *FakeStore) ListClients(_ context.Context, arg ListClientsParams) ([]Client, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var clients []Client
+	for _, c := range f.clients {
+		if bytes.Compare(c.ID[:], arg.AfterID[:]) > 0 {
+			clients = append(clients, c)
+		}
+	}
+	slices.SortFunc(clients, func(a, b Client) int { return bytes.Compare(a.ID[:], b.ID[:]) })
+	return clients[:min(len(clients), int(arg.Limit))], nil
+}
+
+func (f *FakeStore) RotateClientAPIKey(_ context.Context, arg RotateClientAPIKeyParams) (Client, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.clients[arg.ID]
+	if !ok {
+		return Client{}, pgx.ErrNoRows
+	}
+	if err := f.checkClient(c.ID, c.Name, arg.ApiKey, c.Mode); err != nil {
+		return Client{}, err
+	}
+	c.ApiKey = arg.ApiKey
+	f.clients[c.ID] = c
+	return c, nil
+}
+
+func (f *FakeStore) UpsertClient(_ context.Context, arg UpsertClientParams) (Client, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.clients[arg.ID]
+	if !ok {
+		c = Client{ID: arg.ID, CreatedAt: f.timestamp(), Mode: "live"}
+	}
+	if err := f.checkClient(c.ID, arg.Name, arg.ApiKey, c.Mode); err != nil {
+		return Client{}, err
+	}
+	active := true
+	c.Name, c.ApiKey, c.IsActive = arg.Name, arg.ApiKey, &active
+	f.clients[c.ID] = c
+	return c, nil
+}
+
+func (f *FakeStore) CountAccountsByClientID(_ context.Context, clientID uuid.UUID) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var n int64
+	for _, a := range f.accounts {
+		if a.ClientID == clientID && !a.DeletedAt.Valid {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (f *FakeStore) CreateAccount(_ context.Context, arg CreateAccountParams) (Account, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	a := Account{
+		ID:                       uuid.New(),
+		ClientID:                 arg.ClientID,
+		Name:                     arg.Name,
+		AddressIndex:             new(int32),
+		CreatedAt:                f.timestamp(),
+		DefaultExpirySeconds:     arg.DefaultExpirySeconds,
+		DefaultWebhookEndpointID: arg.DefaultWebhookEndpointID,
+		Metadata:                 arg.Metadata,
+		AddressStrategy:          orDefaultStrategy(arg.AddressStrategy),
+	}
+	if err := f.checkAccount(a); err != nil {
+		return Account{}, err
+	}
+	f.accounts[a.ID] = a
+	return a, nil
+}
+
+func (f *FakeStore) GetAccountByIDAndClientID(_ context.Context, arg GetAccountByIDAndClientIDParams) (Account, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	a, ok := f.accounts[arg.ID]
+	if !ok || a.ClientID != arg.ClientID || (a.DeletedAt.Valid && !arg.IncludeDeleted) {
+		return Account{}, pgx.ErrNoRows
+	}
+	return a, nil
+}
+
+func (f *FakeStore) GetAccountsByClientID(_ context.Context, arg GetAccountsByClientIDParams) ([]GetAccountsByClientIDRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []GetAccountsByClientIDRow
+	for _, a := range f.accounts {
+		if a.ClientID == arg.ClientID && (!a.DeletedAt.Valid || arg.IncludeDeleted) {
+			rows = append(rows, GetAccountsByClientIDRow{ID: a.ID, ClientID: a.ClientID, Name: a.Name, CreatedAt: a.CreatedAt, DeletedAt: a.DeletedAt})
+		}
+	}
+	// The query has no ORDER BY; creation order keeps tests deterministic.
+	slices.SortFunc(rows, func(a, b GetAccountsByClientIDRow) int {
+		if c := a.CreatedAt.Time.Compare(b.CreatedAt.Time); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+	return rows, nil
+}
+
+func (f *FakeStore) ReplaceAccount(_ context.Context, arg ReplaceAccountParams) (Account, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	a, ok := f.liveAccount(arg.ID, arg.ClientID)
+	if !ok {
+		return Account{}, pgx.ErrNoRows
+	}
+	a.Name = arg.Name
+	a.DefaultExpirySeconds = arg.DefaultExpirySeconds
+	a.DefaultWebhookEndpointID = arg.DefaultWebhookEndpointID
+	a.Metadata = arg.Metadata
+	a.AddressStrategy = orDefaultStrategy(arg.AddressStrategy)
+	if err := f.checkAccount(a); err != nil {
+		return Account{}, err
+	}
+	f.accounts[a.ID] = a
+	return a, nil
+}
+
+// SoftDeleteAccount returns ErrAccountHasOpenPayments and pgx.ErrNoRows as
+// Store.SoftDeleteAccount does.
+func (f *FakeStore) SoftDeleteAccount(_ context.Context, arg SoftDeleteAccountParams) (Account, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	a, ok := f.liveAccount(arg.ID, arg.ClientID)
+	if !ok {
+		return Account{}, pgx.ErrNoRows
+	}
+	if f.countOpenPayments(a.ID) > 0 {
+		return Account{}, ErrAccountHasOpenPayments
+	}
+	a.DeletedAt = f.timestamp()
+	f.accounts[a.ID] = a
+	return a, nil
+}
+
+// UpdateAccount returns ErrNotFound as Store.UpdateAccount does.
+func (f *FakeStore) UpdateAccount(_ context.Context, arg UpdateAccountParams) (Account, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	a, ok := f.liveAccount(arg.ID, arg.ClientID)
+	if !ok {
+		return Account{}, ErrNotFound
+	}
+	if arg.Name != nil {
+		a.Name = *arg.Name
+	}
+	if arg.AddressStrategy != nil {
+		a.AddressStrategy = *arg.AddressStrategy
+	}
+	if err := f.checkAccount(a); err != nil {
+		return Account{}, err
+	}
+	f.accounts[a.ID] = a
+	return a, nil
+}
+
+func (f *FakeStore) SetAccountDepositWallet(_ context.Context, arg SetAccountDepositWalletParams) (SetAccountDepositWalletRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	a, ok := f.accounts[arg.ID]
+	if !ok {
+		return SetAccountDepositWalletRow{}, pgx.ErrNoRows
+	}
+	if a.DepositWallet == nil {
+		for _, other := range f.accounts {
+			if other.DepositWallet != nil && *other.DepositWallet == arg.DepositWallet {
+				return SetAccountDepositWalletRow{}, fakeViolation(uniqueViolation, "idx_accounts_deposit_wallet")
+			}
+		}
+		a.DepositWallet, a.DepositWalletIndex = &arg.DepositWallet, &arg.DepositWalletIndex
+		f.accounts[a.ID] = a
+	}
+	return SetAccountDepositWalletRow{DepositWallet: *a.DepositWallet, DepositWalletIndex: *a.DepositWalletIndex}, nil
+}
+
+func (f *FakeStore) ListDepositWallets(_ context.Context, wallets []string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var shared []string
+	for _, a := range f.accounts {
+		if a.DepositWallet != nil && slices.Contains(wallets, *a.DepositWallet) {
+			shared = append(shared, *a.DepositWallet)
+		}
+	}
+	return shared, nil
+}
+
+// UpsertAccount returns the columns the query returns, leaving the settings
+// and deleted_at unset.
+func (f *FakeStore) UpsertAccount(_ context.Context, arg UpsertAccountParams) (Account, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	a, ok := f.accounts[arg.ID]
+	if !ok {
+		a = Account{ID: arg.ID, ClientID: arg.ClientID, AddressIndex: new(int32), CreatedAt: f.timestamp(), AddressStrategy: "unique_wallet"}
+	}
+	a.Name = arg.Name
+	a.DeletedAt = pgtype.Timestamptz{}
+	if err := f.checkAccount(a); err != nil {
+		return Account{}, err
+	}
+	f.accounts[a.ID] = a
+	return Account{ID: a.ID, ClientID: a.ClientID, Name: a.Name, AddressIndex: a.AddressIndex, CreatedAt: a.CreatedAt}, nil
+}
+
+func (f *FakeStore) GetActiveWebhookEndpoint(_ context.Context, arg GetActiveWebhookEndpointParams) (WebhookEndpoint, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, ok := f.endpoints[arg.ID]
+	if !ok || e.ClientID != arg.ClientID || !e.IsActive {
+		return WebhookEndpoint{}, pgx.ErrNoRows
+	}
+	return e, nil
+}
+
+func (f *FakeStore) CountPendingPaymentsByAccountID(_ context.Context, accountID uuid.UUID) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.countOpenPayments(accountID), nil
+}
+
+// CreatePayment returns ErrDuplicateWallet as Store.CreatePayment does.
+func (f *FakeStore) CreatePayment(_ context.Context, arg CreatePaymentParams) (Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.clients[arg.ClientID]; !ok {
+		return Payment{}, fakeViolation(foreignKeyViolation, "payments_client_id_fkey")
+	}
+	if _, ok := f.accounts[arg.AccountID]; !ok {
+		return Payment{}, fakeViolation(foreignKeyViolation, "fk_payments_account")
+	}
+	if arg.WebhookEndpointID != nil {
+		if _, ok := f.endpoints[*arg.WebhookEndpointID]; !ok {
+			return Payment{}, fakeViolation(foreignKeyViolation, "payments_webhook_endpoint_id_fkey")
+		}
+	}
+	if arg.Token != "TRX" && arg.Token != "USDT" {
+		return Payment{}, fakeViolation(checkViolation, "check_payments_token")
+	}
+	if arg.OrderReference != nil && (len(*arg.OrderReference) < 1 || len(*arg.OrderReference) > 200) {
+		return Payment{}, fakeViolation(checkViolation, "payments_order_reference_length")
+	}
+	for _, p := range f.payments {
+		if p.UniqueWallet == arg.UniqueWallet && isOpenWallet(p.Status) && p.WalletIndex != nil && arg.WalletIndex != nil {
+			return Payment{}, ErrDuplicateWallet
+		}
+		if arg.WalletIndex != nil && p.WalletIndex != nil && *p.WalletIndex == *arg.WalletIndex {
+			return Payment{}, ErrDuplicateWallet
+		}
+		if arg.OrderReference != nil && p.ClientID == arg.ClientID && p.OrderReference != nil && *p.OrderReference == *arg.OrderReference {
+			return Payment{}, ErrDuplicateOrderReference
+		}
+	}
+	id := uuid.New()
+	if arg.ID != nil {
+		if _, ok := f.payments[*arg.ID]; ok {
+			return Payment{}, fakeViolation(uniqueViolation, "payments_pkey")
+		}
+		id = *arg.ID
+	}
+	p := Payment{
+		ID:                id,
+		ClientID:          arg.ClientID,
+		AccountID:         arg.AccountID,
+		Amount:            arg.Amount,
+		UniqueWallet:      arg.UniqueWallet,
+		Status:            PaymentPending,
+		ExpiresAt:         arg.ExpiresAt,
+		AttemptCount:      new(int32),
+		CreatedAt:         f.timestamp(),
+		WalletIndex:       arg.WalletIndex,
+		FiatAmount:        arg.FiatAmount,
+		FiatCurrency:      arg.FiatCurrency,
+		ExchangeRate:      arg.ExchangeRate,
+		RateAt:            arg.RateAt,
+		Token:             arg.Token,
+		Mode:              arg.Mode,
+		WebhookEndpointID: arg.WebhookEndpointID,
+		OrderReference:    arg.OrderReference,
+		Metadata:          arg.Metadata,
+		Version:           1,
+	}
+	f.payments[p.ID] = p
+	return p, nil
+}
+
+func (f *FakeStore) GetPayment(_ context.Context, id uuid.UUID) (Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.payments[id]
+	if !ok {
+		return Payment{}, pgx.ErrNoRows
+	}
+	return p, nil
+}
+
+func (f *FakeStore) GetPaymentByOrderReference(_ context.Context, arg GetPaymentByOrderReferenceParams) (Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.payments {
+		if p.ClientID == arg.ClientID && p.OrderReference != nil && *p.OrderReference == arg.OrderReference {
+			return p, nil
+		}
+	}
+	return Payment{}, pgx.ErrNoRows
+}
+
+func (f *FakeStore) AllocateWalletIndexes(_ context.Context, arg AllocateWalletIndexesParams) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	first := f.counters[arg.Name]
+	f.counters[arg.Name] = first + arg.Count
+	return first, nil
+}
+
+func (f *FakeStore) NextWalletIndex(_ context.Context, name string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	index := f.counters[name]
+	f.counters[name] = index + 1
+	return index, nil
+}
+
+// UpdatePaymentStatus returns ErrInvalidPaymentTransition,
+// ErrVersionConflict and ErrPaymentStatusChanged as
+// Store.UpdatePaymentStatus does.
+func (f *FakeStore) UpdatePaymentStatus(_ context.Context, arg UpdatePaymentStatusParams) (Payment, error) {
+	if !CanTransitionPayment(arg.FromStatus, arg.ToStatus) {
+		return Payment{}, fmt.Errorf("%w: %s to %s", ErrInvalidPaymentTransition, arg.FromStatus, arg.ToStatus)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.payments[arg.ID]
+	if ok && p.Version != arg.Version {
+		return Payment{}, ErrVersionConflict
+	}
+	if !ok || p.Status != arg.FromStatus {
+		return Payment{}, ErrPaymentStatusChanged
+	}
+	p.Status = arg.ToStatus
+	p.Version++
+	f.payments[p.ID] = p
+	return p, nil
+}
+
+func (f *FakeStore) CreatePoolAddresses(_ context.Context, arg CreatePoolAddressesParams) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.accounts[arg.AccountID]; !ok {
+		return 0, fakeViolation(foreignKeyViolation, "address_pool_account_id_fkey")
+	}
+	if len(arg.AddressIndexes) != len(arg.Addresses) {
+		return 0, fmt.Errorf("unnest: %d indexes for %d addresses", len(arg.AddressIndexes), len(arg.Addresses))
+	}
+	added := slices.Clone(f.pool)
+	for i, address := range arg.Addresses {
+		for _, a := range added {
+			if a.Address == address {
+				return 0, fakeViolation(uniqueViolation, "address_pool_address_key")
+			}
+			if a.AccountID == arg.AccountID && a.AddressIndex == arg.AddressIndexes[i] {
+				return 0, fakeViolation(uniqueViolation, "address_pool_account_index_key")
+			}
+		}
+		added = append(added, AddressPool{
+			ID:           uuid.New(),
+			AccountID:    arg.AccountID,
+			AddressIndex: arg.AddressIndexes[i],
+			Address:      address,
+			CreatedAt:    f.timestamp(),
+		})
+	}
+	f.pool = added
+	return int64(len(arg.Addresses)), nil
+}
+
+func (f *FakeStore) ListAccountsBelowLowWater(_ context.Context, lowWater int64) ([]ListAccountsBelowLowWaterRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var rows []ListAccountsBelowLowWaterRow
+	for _, a := range f.accounts {
+		c := f.clients[a.ClientID]
+		if a.DeletedAt.Valid || (c.IsActive != nil && !*c.IsActive) {
+			continue
+		}
+		var unreserved int64
+		for _, p := range f.pool {
+			if p.AccountID == a.ID && p.ReservedByPayment == nil {
+				unreserved++
+			}
+		}
+		if unreserved < lowWater {
+			rows = append(rows, ListAccountsBelowLowWaterRow{AccountID: a.ID, Mode: c.Mode, Unreserved: unreserved})
+		}
+	}
+	slices.SortFunc(rows, func(a, b ListAccountsBelowLowWaterRow) int { return bytes.Compare(a.AccountID[:], b.AccountID[:]) })
+	return rows, nil
+}
+
+func (f *FakeStore) ReserveAddress(_ context.Context, arg ReserveAddressParams) (AddressPool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	claim := -1
+	for i, p := range f.pool {
+		if p.AccountID == arg.AccountID && p.ReservedByPayment == nil &&
+			(claim < 0 || p.AddressIndex < f.pool[claim].AddressIndex) {
+			claim = i
+		}
+	}
+	if claim < 0 {
+		return AddressPool{}, pgx.ErrNoRows
+	}
+	for _, p := range f.pool {
+		if p.ReservedByPayment != nil && *p.ReservedByPayment == arg.PaymentID {
+			return AddressPool{}, fakeViolation(uniqueViolation, "idx_address_pool_reserved_by_payment")
+		}
+	}
+	f.pool[claim].ReservedByPayment = UUIDPtr(arg.PaymentID)
+	return f.pool[claim], nil
+}
+
+// ReserveAmountSuffix reserves the lowest free suffix, as the query does,
+// overwriting an expired reservation of it.
+func (f *FakeStore) ReserveAmountSuffix(_ context.Context, arg ReserveAmountSuffixParams) (int32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.accounts[arg.AccountID]; !ok {
+		return 0, fakeViolation(foreignKeyViolation, "amount_suffix_reservations_account_id_fkey")
+	}
+	held := make(map[int32]int)
+	for i, r := range f.suffixes {
+		if r.PaymentID == arg.PaymentID {
+			return 0, fakeViolation(uniqueViolation, "amount_suffix_reservations_payment_id_key")
+		}
+		if r.AccountID == arg.AccountID {
+			held[r.Suffix] = i
+		}
+	}
+	for suffix := int32(1); suffix <= maxAmountSuffix; suffix++ {
+		i, ok := held[suffix]
+		if ok && f.suffixes[i].ExpiresAt.Time.After(arg.Now.Time) {
+			continue
+		}
+		r := AmountSuffixReservation{
+			ID:        uuid.New(),
+			AccountID: arg.AccountID,
+			Suffix:    suffix,
+			PaymentID: arg.PaymentID,
+			ExpiresAt: arg.ExpiresAt,
+			CreatedAt: f.timestamp(),
+		}
+		if ok {
+			r.ID = f.suffixes[i].ID
+			f.suffixes[i] = r
+		} else {
+			f.suffixes = append(f.suffixes, r)
+		}
+		return suffix, nil
+	}
+	return 0, pgx.ErrNoRows
+}
+
+func (f *FakeStore) CreateLog(_ context.Context, arg CreateLogParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if arg.PaymentID != nil {
+		if _, ok := f.payments[*arg.PaymentID]; !ok {
+			return fakeViolation(foreignKeyViolation, "logs_payment_id_fkey")
+		}
+	}
+	f.logs = append(f.logs, Log{
+		ID:        uuid.New(),
+		PaymentID: arg.PaymentID,
+		EventType: arg.EventType,
+		Message:   arg.Message,
+		RawData:   arg.RawData,
+		CreatedAt: f.timestamp(),
+		RequestID: arg.RequestID,
+		Actor:     arg.Actor,
+	})
+	return nil
+}
+
+func (f *FakeStore) ListLogs(_ context.Context, arg ListLogsParams) ([]Log, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var logs []Log
+	for _, l := range f.logs {
+		switch {
+		case arg.EventType != nil && l.EventType != *arg.EventType,
+			arg.PaymentID != nil && (l.PaymentID == nil || *l.PaymentID != *arg.PaymentID),
+			arg.From.Valid && l.CreatedAt.Time.Before(arg.From.Time),
+			arg.To.Valid && !l.CreatedAt.Time.Before(arg.To.Time):
+			continue
+		}
+		logs = append(logs, l)
+	}
+	slices.SortStableFunc(logs, func(a, b Log) int {
+		if c := b.CreatedAt.Time.Compare(a.CreatedAt.Time); c != 0 {
+			return c
+		}
+		return bytes.Compare(b.ID[:], a.ID[:])
+	})
+	logs = logs[min(len(logs), int(arg.Offset)):]
+	return logs[:min(len(logs), int(arg.Limit))], nil
+}
+
+// timestamp is now() as the database stores it, to the microsecond.
+func (f *FakeStore) timestamp() pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: f.now().UTC().Truncate(time.Microsecond), Valid: true}
+}
+
+func (f *FakeStore) clientByAPIKey(apiKey string) (Client, bool) {
+	for _, c := range f.clients {
+		if c.ApiKey == apiKey {
+			return c, true
+		}
+	}
+	return Client{}, false
+}
+
+// checkClient enforces the constraints of the clients table on a client
+// with id, or a new one when id is uuid.Nil.
+func (f *FakeStore) checkClient(id uuid.UUID, name, apiKey, mode string) error {
+	if len(name) > maxNameLength {
+		return fakeViolation(checkViolation, "clients_name_length")
+	}
+	if mode != "live" && mode != "test" {
+		return fakeViolation(checkViolation, "clients_mode_check")
+	}
+	if c, ok := f.clientByAPIKey(apiKey); ok && c.ID != id {
+		return fakeViolation(uniqueViolation, "clients_api_key_key")
+	}
+	return nil
+}
+
+// liveAccount returns the account of the client with id unless it is
+// deleted.
+func (f *FakeStore) liveAccount(id, clientID uuid.UUID) (Account, bool) {
+	a, ok := f.accounts[id]
+	if !ok || a.ClientID != clientID || a.DeletedAt.Valid {
+		return Account{}, false
+	}
+	return a, true
+}
+
+// checkAccount enforces the constraints of the accounts table on a.
+func (f *FakeStore) checkAccount(a Account) error {
+	if _, ok := f.clients[a.ClientID]; !ok {
+		return fakeViolation(foreignKeyViolation, "fk_accounts_client")
+	}
+	if len(a.Name) > maxNameLength {
+		return fakeViolation(checkViolation, "accounts_name_length")
+	}
+	if a.AddressStrategy != "unique_wallet" && a.AddressStrategy != "amount_suffix" {
+		return fakeViolation(checkViolation, "accounts_address_strategy_check")
+	}
+	if e := a.DefaultExpirySeconds; e != nil && (*e < 60 || *e > 86400) {
+		return fakeViolation(checkViolation, "accounts_default_expiry_seconds_check")
+	}
+	if a.DefaultWebhookEndpointID != nil {
+		if _, ok := f.endpoints[*a.DefaultWebhookEndpointID]; !ok {
+			return fakeViolation(foreignKeyViolation, "accounts_default_webhook_endpoint_id_fkey")
+		}
+	}
+	return nil
+}
+
+// countOpenPayments counts the payments of the account still waiting for
+// funds, which keep it from being deleted.
+func (f *FakeStore) countOpenPayments(accountID uuid.UUID) int64 {
+	var n int64
+	for _, p := range f.payments {
+		if p.AccountID == accountID && (p.Status == PaymentPending || p.Status == PaymentDetected) {
+			n++
+		}
+	}
+	return n
+}
+
+// isOpenWallet reports whether a payment in status holds its wallet, as
+// idx_payments_unique_wallet_open does for the payments with a wallet index.
+func isOpenWallet(status string) bool {
+	return status == PaymentPending || status == PaymentDetected || status == PaymentUnderpaid
+}
+
+// orDefaultStrategy is the address_strategy an account is given for a nil
+// one.
+func orDefaultStrategy(strategy *string) string {
+	if strategy == nil {
+		return "unique_wallet"
+	}
+	return *strategy
+}
+
+func fakeViolation(code, constraint string) error {
+	return &pgconn.PgError{
+		Code:           code,
+		ConstraintName: constraint,
+		Message:        fmt.Sprintf("violates constraint %q", constraint),
+	}
+}
+
+var _ Querier = (*FakeStore)(nil)
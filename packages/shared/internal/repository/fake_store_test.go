@@ -0,0 +1,466 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var fakeNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newFakeStore(t *testing.T) (*FakeStore, Client) {
+	t.Helper()
+	f := NewFakeStore(WithFakeClock(func() time.Time { return fakeNow }))
+	c, err := f.CreateClient(context.Background(), CreateClientParams{Name: "shop", ApiKey: "sk_1", Mode: "live"})
+	require.NoError(t, err)
+	return f, c
+}
+
+func assertViolation(t *testing.T, err error, code, constraint string) {
+	t.Helper()
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	assert.Equal(t, code, pgErr.Code)
+	assert.Equal(t, constraint, pgErr.ConstraintName)
+}
+
+func TestFakeStore_Clients(t *testing.T) {
+	ctx := context.Background()
+	f, c := newFakeStore(t)
+
+	assert.True(t, *c.IsActive)
+	assert.Equal(t, fakeNow, c.CreatedAt.Time)
+	got, err := f.GetClientByAPIKey(ctx, "sk_1")
+	require.NoError(t, err)
+	assert.Equal(t, c, got)
+
+	_, err = f.CreateClient(ctx, CreateClientParams{Name: "copycat", ApiKey: "sk_1", Mode: "live"})
+	assertViolation(t, err, uniqueViolation, "clients_api_key_key")
+	_, err = f.CreateClient(ctx, CreateClientParams{Name: "shop", ApiKey: "sk_2", Mode: "staging"})
+	assertViolation(t, err, checkViolation, "clients_mode_check")
+
+	_, err = f.DeactivateClient(ctx, c.ID)
+	require.NoError(t, err)
+	_, err = f.GetClientByAPIKey(ctx, "sk_1")
+	assert.ErrorIs(t, err, pgx.ErrNoRows, "inactive clients do not authenticate")
+	exists, err := f.ClientExistsByAPIKey(ctx, "sk_1")
+	require.NoError(t, err)
+	assert.True(t, exists, "but still hold their key")
+}
+
+func TestFakeStore_ListClients(t *testing.T) {
+	ctx := context.Background()
+	f := NewFakeStore()
+	for i := range 3 {
+		_, err := f.CreateClient(ctx, CreateClientParams{Name: "shop", ApiKey: "sk_" + strings.Repeat("x", i), Mode: "live"})
+		require.NoError(t, err)
+	}
+
+	first, err := f.ListClients(ctx, ListClientsParams{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	rest, err := f.ListClients(ctx, ListClientsParams{AfterID: first[1].ID, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+	assert.Positive(t, strings.Compare(rest[0].ID.String(), first[1].ID.String()), "pages are ordered by id")
+}
+
+func TestFakeStore_AccountConstraints(t *testing.T) {
+	ctx := context.Background()
+	f, c := newFakeStore(t)
+	short := int64(30)
+
+	_, err := f.CreateAccount(ctx, CreateAccountParams{ClientID: uuid.New(), Name: "orphan"})
+	assertViolation(t, err, foreignKeyViolation, "fk_accounts_client")
+	_, err = f.CreateAccount(ctx, CreateAccountParams{ClientID: c.ID, Name: strings.Repeat("a", 201)})
+	assertViolation(t, err, checkViolation, "accounts_name_length")
+	_, err = f.CreateAccount(ctx, CreateAccountParams{ClientID: c.ID, Name: "eu", DefaultExpirySeconds: &short})
+	assertViolation(t, err, checkViolation, "accounts_default_expiry_seconds_check")
+	_, err = f.CreateAccount(ctx, CreateAccountParams{ClientID: c.ID, Name: "eu", DefaultWebhookEndpointID: UUIDPtr(uuid.New())})
+	assertViolation(t, err, foreignKeyViolation, "accounts_default_webhook_endpoint_id_fkey")
+
+	e, err := f.AddWebhookEndpoint(WebhookEndpoint{ClientID: c.ID, Url: "https://shop.example/hook", IsActive: true})
+	require.NoError(t, err)
+	a, err := f.CreateAccount(ctx, CreateAccountParams{ClientID: c.ID, Name: "eu", DefaultWebhookEndpointID: UUIDPtr(e.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, e.ID, uuid.UUID(*a.DefaultWebhookEndpointID))
+}
+
+func TestFakeStore_AccountLifecycle(t *testing.T) {
+	ctx := context.Background()
+	f, c := newFakeStore(t)
+	other, err := f.CreateClient(ctx, CreateClientParams{Name: "other", ApiKey: "sk_other", Mode: "live"})
+	require.NoError(t, err)
+	a, err := f.CreateAccount(ctx, CreateAccountParams{ClientID: c.ID, Name: "main"})
+	require.NoError(t, err)
+
+	_, err = f.GetAccountByIDAndClientID(ctx, GetAccountByIDAndClientIDParams{ID: a.ID, ClientID: other.ID})
+	assert.ErrorIs(t, err, pgx.ErrNoRows, "accounts are scoped to their client")
+	_, err = f.UpdateAccount(ctx, UpdateAccountParams{ID: a.ID, ClientID: other.ID})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	name := "renamed"
+	a, err = f.UpdateAccount(ctx, UpdateAccountParams{Name: &name, ID: a.ID, ClientID: c.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", a.Name)
+
+	p, err := f.CreatePayment(ctx, CreatePaymentParams{ClientID: c.ID, AccountID: a.ID, UniqueWallet: "TW1", Token: "USDT", Mode: "live"})
+	require.NoError(t, err)
+	_, err = f.SoftDeleteAccount(ctx, SoftDeleteAccountParams{ID: a.ID, ClientID: c.ID})
+	assert.ErrorIs(t, err, ErrAccountHasOpenPayments)
+
+	_, err = f.UpdatePaymentStatus(ctx, UpdatePaymentStatusParams{ID: p.ID, FromStatus: PaymentPending, ToStatus: PaymentExpired, Version: p.Version})
+	require.NoError(t, err)
+	deleted, err := f.SoftDeleteAccount(ctx, SoftDeleteAccountParams{ID: a.ID, ClientID: c.ID})
+	require.NoError(t, err)
+	assert.Equal(t, fakeNow, deleted.DeletedAt.Time)
+
+	_, err = f.SoftDeleteAccount(ctx, SoftDeleteAccountParams{ID: a.ID, ClientID: c.ID})
+	assert.ErrorIs(t, err, pgx.ErrNoRows, "an account is deleted once")
+	_, err = f.GetAccountByIDAndClientID(ctx, GetAccountByIDAndClientIDParams{ID: a.ID, ClientID: c.ID})
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+	_, err = f.GetAccountByIDAndClientID(ctx, GetAccountByIDAndClientIDParams{ID: a.ID, ClientID: c.ID, IncludeDeleted: true})
+	assert.NoError(t, err)
+	n, err := f.CountAccountsByClientID(ctx, c.ID)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestFakeStore_PaymentConstraints(t *testing.T) {
+	ctx := context.Background()
+	f, c := newFakeStore(t)
+	a, err := f.CreateAccount(ctx, CreateAccountParams{ClientID: c.ID, Name: "main"})
+	require.NoError(t, err)
+	index := int64(7)
+	params := CreatePaymentParams{ClientID: c.ID, AccountID: a.ID, UniqueWallet: "TW1", WalletIndex: &index, Token: "USDT", Mode: "live"}
+
+	p, err := f.CreatePayment(ctx, params)
+	require.NoError(t, err)
+	assert.Equal(t, PaymentPending, p.Status)
+
+	_, err = f.CreatePayment(ctx, params)
+	assert.ErrorIs(t, err, ErrDuplicateWallet)
+
+	orphan := params
+	orphan.AccountID, orphan.WalletIndex = uuid.New(), nil
+	_, err = f.CreatePayment(ctx, orphan)
+	assertViolation(t, err, foreignKeyViolation, "fk_payments_account")
+
+	_, err = f.UpdatePaymentStatus(ctx, UpdatePaymentStatusParams{ID: p.ID, FromStatus: PaymentDetected, ToStatus: PaymentConfirmed, Version: p.Version})
+	assert.ErrorIs(t, err, ErrPaymentStatusChanged)
+	_, err = f.UpdatePaymentStatus(ctx, UpdatePaymentStatusParams{ID: p.ID, FromStatus: PaymentExpired, ToStatus: PaymentPending})
+	assert.ErrorIs(t, err, ErrInvalidPaymentTransition)
+}
+
+func TestFakeStore_OrderReference(t *testing.T) {
+	ctx := context.Background()
+	f, c := newFakeStore(t)
+	other, err := f.CreateClient(ctx, CreateClientParams{Name: "other", ApiKey: "sk_2", Mode: "live"})
+	require.NoError(t, err)
+	a, err := f.CreateAccount(ctx, CreateAccountParams{ClientID: c.ID, Name: "main"})
+	require.NoError(t, err)
+	b, err := f.CreateAccount(ctx, CreateAccountParams{ClientID: other.ID, Name: "main"})
+	require.NoError(t, err)
+	ref := "order-1"
+
+	p, err := f.CreatePayment(ctx, CreatePaymentParams{ClientID: c.ID, AccountID: a.ID, UniqueWallet: "TW1", Token: "TRX", Mode: "live", OrderReference: &ref, Metadata: []byte(`{"sku":"A-1"}`)})
+	require.NoError(t, err)
+
+	_, err = f.CreatePayment(ctx, CreatePaymentParams{ClientID: c.ID, AccountID: a.ID, UniqueWallet: "TW2", Token: "TRX", Mode: "live", OrderReference: &ref})
+	assert.ErrorIs(t, err, ErrDuplicateOrderReference)
+	_, err = f.CreatePayment(ctx, CreatePaymentParams{ClientID: other.ID, AccountID: b.ID, UniqueWallet: "TW3", Token: "TRX", Mode: "live", OrderReference: &ref})
+	require.NoError(t, err, "references are unique per client")
+	_, err = f.CreatePayment(ctx, CreatePaymentParams{ClientID: c.ID, AccountID: a.ID, UniqueWallet: "TW4", Token: "TRX", Mode: "live"})
+	require.NoError(t, err, "payments without a reference never collide")
+
+	got, err := f.GetPaymentByOrderReference(ctx, GetPaymentByOrderReferenceParams{ClientID: c.ID, OrderReference: ref})
+	require.NoError(t, err)
+	assert.Equal(t, p.ID, got.ID)
+	assert.JSONEq(t, `{"sku":"A-1"}`, string(got.Metadata))
+
+	_, err = f.GetPaymentByOrderReference(ctx, GetPaymentByOrderReferenceParams{ClientID: c.ID, OrderReference: "missing"})
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestFakeStore_ExecTxRollsBack(t *testing.T) {
+	ctx := context.Background()
+	f, c := newFakeStore(t)
+	boom := errors.New("boom")
+
+	err := f.ExecTx(ctx, func(q Querier) error {
+		if _, err := q.CreateAccount(ctx, CreateAccountParams{ClientID: c.ID, Name: "main"}); err != nil {
+			return err
+		}
+		if err := q.CreateLog(ctx, CreateLogParams{EventType: "ACCOUNT_CREATED"}); err != nil {
+			return err
+		}
+		return boom
+	})
+
+	assert.Equal(t, boom, err)
+	n, err := f.CountAccountsByClientID(ctx, c.ID)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Empty(t, f.Logs())
+
+	require.NoError(t, f.ExecTx(ctx, func(q Querier) error {
+		return q.CreateLog(ctx, CreateLogParams{EventType: "ACCOUNT_CREATED"})
+	}))
+	require.Len(t, f.Logs(), 1)
+	assert.Equal(t, "ACCOUNT_CREATED", f.Logs()[0].EventType)
+}
+
+func TestFakeStore_ListLogs(t *testing.T) {
+	ctx := context.Background()
+	now := fakeNow
+	f := NewFakeStore(WithFakeClock(func() time.Time { return now }))
+	c, err := f.CreateClient(ctx, CreateClientParams{Name: "shop", ApiKey: "sk_1", Mode: "live"})
+	require.NoError(t, err)
+	a, err := f.CreateAccount(ctx, CreateAccountParams{ClientID: c.ID, Name: "main"})
+	require.NoError(t, err)
+	p, err := f.CreatePayment(ctx, CreatePaymentParams{ClientID: c.ID, AccountID: a.ID, UniqueWallet: "TW1", Token: "USDT", Mode: "live"})
+	require.NoError(t, err)
+	for i, event := range []string{"PAYMENT_CREATED", "TX_DETECTED", "WEBHOOK_SENT", "WEBHOOK_SENT"} {
+		now = fakeNow.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, f.CreateLog(ctx, CreateLogParams{PaymentID: &p.ID, EventType: event}))
+	}
+	now = fakeNow.Add(4 * time.Minute)
+	require.NoError(t, f.CreateLog(ctx, CreateLogParams{EventType: "CLIENT_CREATED"}))
+
+	all, err := f.ListLogs(ctx, ListLogsParams{Limit: 10})
+	require.NoError(t, err)
+	var events []string
+	for _, l := range all {
+		events = append(events, l.EventType)
+	}
+	assert.Equal(t, []string{"CLIENT_CREATED", "WEBHOOK_SENT", "WEBHOOK_SENT", "TX_DETECTED", "PAYMENT_CREATED"}, events, "newest first")
+
+	webhooks := "WEBHOOK_SENT"
+	got, err := f.ListLogs(ctx, ListLogsParams{EventType: &webhooks, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, got, 2)
+	got, err = f.ListLogs(ctx, ListLogsParams{PaymentID: &p.ID, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, got, 4, "logs of no payment do not match a payment id")
+	got, err = f.ListLogs(ctx, ListLogsParams{
+		From:  pgtype.Timestamptz{Time: fakeNow.Add(time.Minute), Valid: true},
+		To:    pgtype.Timestamptz{Time: fakeNow.Add(3 * time.Minute), Valid: true},
+		Limit: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, got, 2, "from is inclusive and to exclusive")
+	assert.Equal(t, "WEBHOOK_SENT", got[0].EventType)
+	assert.Equal(t, "TX_DETECTED", got[1].EventType)
+
+	got, err = f.ListLogs(ctx, ListLogsParams{Limit: 2, Offset: 2})
+	require.NoError(t, err)
+	assert.Equal(t, all[2:4], got)
+	got, err = f.ListLogs(ctx, ListLogsParams{Limit: 2, Offset: 10})
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestFakeStore_Fallback(t *testing.T) {
+	q := NewMockQuerier(t)
+	f := NewFakeStore(WithFallback(q))
+	q.On("GetWatcherHeight", mock.Anything, "tron").Return(int64(42), nil)
+
+	h, err := f.GetWatcherHeight(context.Background(), "tron")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), h)
+}
+
+// nextStatus flips a payment between PENDING and UNDERPAID, a move either
+// way the lifecycle allows any number of times.
+func nextStatus(status string) string {
+	if status == PaymentPending {
+		return PaymentUnderpaid
+	}
+	return PaymentPending
+}
+
+func TestFakeStore_InterleavedUpdaters(t *testing.T) {
+	ctx := context.Background()
+	f, c := newFakeStore(t)
+	a, err := f.CreateAccount(ctx, CreateAccountParams{ClientID: c.ID, Name: "main"})
+	require.NoError(t, err)
+	p, err := f.CreatePayment(ctx, CreatePaymentParams{ClientID: c.ID, AccountID: a.ID, UniqueWallet: "TW1", Token: "USDT", Mode: "live"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), p.Version)
+
+	for round := range 6 {
+		// Both updaters read the payment before either writes, and take
+		// turns going first.
+		read := [2]Payment{}
+		for i := range read {
+			read[i], err = f.GetPayment(ctx, p.ID)
+			require.NoError(t, err)
+		}
+		var wins int
+		for i := range read {
+			u := read[(round+i)%2]
+			_, err := f.UpdatePaymentStatus(ctx, UpdatePaymentStatusParams{
+				ToStatus:   nextStatus(u.Status),
+				ID:         u.ID,
+				FromStatus: u.Status,
+				Version:    u.Version,
+			})
+			if err == nil {
+				wins++
+				continue
+			}
+			require.ErrorIs(t, err, ErrVersionConflict, "round %d", round)
+		}
+		assert.Equal(t, 1, wins, "round %d", round)
+
+		got, err := f.GetPayment(ctx, p.ID)
+		require.NoError(t, err)
+		assert.Equal(t, read[0].Version+1, got.Version, "round %d", round)
+		assert.Equal(t, nextStatus(read[0].Status), got.Status, "round %d", round)
+	}
+}
+
+func TestFakeStore_ConcurrentUpdaters(t *testing.T) {
+	ctx := context.Background()
+	f, c := newFakeStore(t)
+	a, err := f.CreateAccount(ctx, CreateAccountParams{ClientID: c.ID, Name: "main"})
+	require.NoError(t, err)
+	p, err := f.CreatePayment(ctx, CreatePaymentParams{ClientID: c.ID, AccountID: a.ID, UniqueWallet: "TW1", Token: "USDT", Mode: "live"})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	wins := make(map[int32]int)
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				u, err := f.GetPayment(ctx, p.ID)
+				if !assert.NoError(t, err) {
+					return
+				}
+				_, err = f.UpdatePaymentStatus(ctx, UpdatePaymentStatusParams{
+					ToStatus:   nextStatus(u.Status),
+					ID:         u.ID,
+					FromStatus: u.Status,
+					Version:    u.Version,
+				})
+				if err != nil {
+					assert.ErrorIs(t, err, ErrVersionConflict)
+					continue
+				}
+				mu.Lock()
+				wins[u.Version]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := f.GetPayment(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Len(t, wins, int(got.Version-1), "every version was won")
+	for version, n := range wins {
+		assert.Equal(t, 1, n, "version %d was won more than once", version)
+	}
+}
+
+func TestFakeStore_AddressPool(t *testing.T) {
+	ctx := context.Background()
+	f, c := newFakeStore(t)
+	a, err := f.CreateAccount(ctx, CreateAccountParams{ClientID: c.ID, Name: "main"})
+	require.NoError(t, err)
+
+	first, err := f.AllocateWalletIndexes(ctx, AllocateWalletIndexesParams{Name: "deposit", Count: 2})
+	require.NoError(t, err)
+	assert.Zero(t, first)
+	next, err := f.NextWalletIndex(ctx, "deposit")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), next, "the pool and payments share the counter")
+
+	added, err := f.CreatePoolAddresses(ctx, CreatePoolAddressesParams{AccountID: a.ID, AddressIndexes: []int64{1, 0}, Addresses: []string{"TW1", "TW0"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), added)
+	_, err = f.CreatePoolAddresses(ctx, CreatePoolAddressesParams{AccountID: a.ID, AddressIndexes: []int64{9}, Addresses: []string{"TW0"}})
+	assertViolation(t, err, uniqueViolation, "address_pool_address_key")
+	_, err = f.CreatePoolAddresses(ctx, CreatePoolAddressesParams{AccountID: uuid.New(), AddressIndexes: []int64{9}, Addresses: []string{"TW9"}})
+	assertViolation(t, err, foreignKeyViolation, "address_pool_account_id_fkey")
+
+	below, err := f.ListAccountsBelowLowWater(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []ListAccountsBelowLowWaterRow{{AccountID: a.ID, Mode: "live", Unreserved: 2}}, below)
+
+	paymentID := uuid.New()
+	reserved, err := f.ReserveAddress(ctx, ReserveAddressParams{PaymentID: paymentID, AccountID: a.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "TW0", reserved.Address, "the lowest index goes first")
+	_, err = f.ReserveAddress(ctx, ReserveAddressParams{PaymentID: paymentID, AccountID: a.ID})
+	assertViolation(t, err, uniqueViolation, "idx_address_pool_reserved_by_payment")
+
+	err = f.ExecTx(ctx, func(q Querier) error {
+		_, err := q.ReserveAddress(ctx, ReserveAddressParams{PaymentID: uuid.New(), AccountID: a.ID})
+		require.NoError(t, err)
+		return errors.New("boom")
+	})
+	require.Error(t, err)
+	reserved, err = f.ReserveAddress(ctx, ReserveAddressParams{PaymentID: uuid.New(), AccountID: a.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "TW1", reserved.Address, "a rolled back claim returns the address")
+
+	_, err = f.ReserveAddress(ctx, ReserveAddressParams{PaymentID: uuid.New(), AccountID: a.ID})
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestFakeStore_AmountSuffixes(t *testing.T) {
+	ctx := context.Background()
+	f, c := newFakeStore(t)
+	strategy := "amount_suffix"
+	a, err := f.CreateAccount(ctx, CreateAccountParams{ClientID: c.ID, Name: "main", AddressStrategy: &strategy})
+	require.NoError(t, err)
+	assert.Equal(t, "amount_suffix", a.AddressStrategy)
+	invalid := "round_robin"
+	_, err = f.CreateAccount(ctx, CreateAccountParams{ClientID: c.ID, Name: "other", AddressStrategy: &invalid})
+	assertViolation(t, err, checkViolation, "accounts_address_strategy_check")
+
+	row, err := f.SetAccountDepositWallet(ctx, SetAccountDepositWalletParams{DepositWallet: "TW4", DepositWalletIndex: 4, ID: a.ID})
+	require.NoError(t, err)
+	assert.Equal(t, SetAccountDepositWalletRow{DepositWallet: "TW4", DepositWalletIndex: 4}, row)
+	row, err = f.SetAccountDepositWallet(ctx, SetAccountDepositWalletParams{DepositWallet: "TW5", DepositWalletIndex: 5, ID: a.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "TW4", row.DepositWallet, "a deposit wallet once set is kept")
+	shared, err := f.ListDepositWallets(ctx, []string{"TW4", "TW5"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"TW4"}, shared)
+
+	expires := pgtype.Timestamptz{Time: fakeNow.Add(time.Hour), Valid: true}
+	now := pgtype.Timestamptz{Time: fakeNow, Valid: true}
+	first := uuid.New()
+	suffix, err := f.ReserveAmountSuffix(ctx, ReserveAmountSuffixParams{AccountID: a.ID, PaymentID: first, ExpiresAt: expires, Now: now})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), suffix)
+	_, err = f.ReserveAmountSuffix(ctx, ReserveAmountSuffixParams{AccountID: a.ID, PaymentID: first, ExpiresAt: expires, Now: now})
+	assertViolation(t, err, uniqueViolation, "amount_suffix_reservations_payment_id_key")
+	_, err = f.ReserveAmountSuffix(ctx, ReserveAmountSuffixParams{AccountID: uuid.New(), PaymentID: uuid.New(), ExpiresAt: expires, Now: now})
+	assertViolation(t, err, foreignKeyViolation, "amount_suffix_reservations_account_id_fkey")
+
+	suffix, err = f.ReserveAmountSuffix(ctx, ReserveAmountSuffixParams{AccountID: a.ID, PaymentID: uuid.New(), ExpiresAt: expires, Now: now})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), suffix)
+	suffix, err = f.ReserveAmountSuffix(ctx, ReserveAmountSuffixParams{AccountID: a.ID, PaymentID: uuid.New(), ExpiresAt: expires, Now: expires})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), suffix, "an expired suffix is reserved again")
+	assert.Len(t, f.AmountSuffixReservations(), 2)
+}
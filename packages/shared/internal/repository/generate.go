@@ -0,0 +1,9 @@
+package repository
+
+// MockQuerier is generated from the Querier interface; regenerate it after
+// changing a query with sqlc.
+//go:generate mockery --config ../../.mockery.yaml
+
+// InstrumentedQuerier's methods are generated from the Querier interface
+// too.
+//go:generate go run ./instrumentgen -in querier.go -out instrumented_querier.go
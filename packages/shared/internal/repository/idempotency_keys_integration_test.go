@@ -0,0 +1,54 @@
+//go:build integration
+
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegration_ClaimIdempotencyKeyReclaimsOnlyStaleClaims(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	client := f.client()
+	claim := func(key string) (IdempotencyKey, error) {
+		return f.store.ClaimIdempotencyKey(ctx, ClaimIdempotencyKeyParams{
+			ClientID:       client.ID,
+			IdempotencyKey: key,
+			RequestHash:    "hash",
+			StaleAfter:     pgtype.Interval{Microseconds: time.Minute.Microseconds(), Valid: true},
+		})
+	}
+	age := func(key string) {
+		_, err := f.pool.Exec(ctx, "UPDATE idempotency_keys SET claimed_at = now() - INTERVAL '2 minutes' WHERE client_id = $1 AND idempotency_key = $2", client.ID, key)
+		require.NoError(t, err)
+	}
+
+	_, err := claim("running")
+	require.NoError(t, err)
+	age("running")
+	require.NoError(t, f.store.RenewIdempotencyKey(ctx, RenewIdempotencyKeyParams{ClientID: client.ID, IdempotencyKey: "running"}))
+	_, err = claim("running")
+	assert.ErrorIs(t, err, pgx.ErrNoRows, "a renewed claim is not stale")
+
+	_, err = claim("abandoned")
+	require.NoError(t, err)
+	age("abandoned")
+	_, err = claim("abandoned")
+	assert.NoError(t, err, "a claim left by a dead process is taken again")
+
+	_, err = claim("failed")
+	require.NoError(t, err)
+	require.NoError(t, f.store.FailIdempotencyKey(ctx, FailIdempotencyKeyParams{ClientID: client.ID, IdempotencyKey: "failed"}))
+	age("failed")
+	_, err = claim("failed")
+	assert.ErrorIs(t, err, pgx.ErrNoRows, "a FAILED key is never claimed again")
+	key, err := f.store.GetIdempotencyKey(ctx, GetIdempotencyKeyParams{ClientID: client.ID, IdempotencyKey: "failed"})
+	require.NoError(t, err)
+	assert.Equal(t, "FAILED", key.Status)
+}
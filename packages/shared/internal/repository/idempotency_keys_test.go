@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueries_ClaimIdempotencyKey(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	params := ClaimIdempotencyKeyParams{
+		ClientID:       uuid.New(),
+		IdempotencyKey: "order-1",
+		RequestHash:    "abc",
+		StaleAfter:     pgtype.Interval{Microseconds: time.Minute.Microseconds(), Valid: true},
+	}
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, claimIdempotencyKey, []interface{}{params.ClientID, params.IdempotencyKey, params.RequestHash, params.StaleAfter}).
+		Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 11)
+		*dest[2].(*string) = params.IdempotencyKey
+		*dest[4].(*string) = "IN_PROGRESS"
+	})
+
+	key, err := queries.ClaimIdempotencyKey(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, "order-1", key.IdempotencyKey)
+	assert.Equal(t, "IN_PROGRESS", key.Status)
+}
+
+func TestQueries_ClaimIdempotencyKey_Held(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, claimIdempotencyKey, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+	_, err := queries.ClaimIdempotencyKey(ctx, ClaimIdempotencyKeyParams{ClientID: uuid.New(), IdempotencyKey: "order-1"})
+
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestClaimIdempotencyKeySQL(t *testing.T) {
+	assert.Contains(t, claimIdempotencyKey, "ON CONFLICT (client_id, idempotency_key) DO UPDATE SET claimed_at = now()")
+	assert.Contains(t, claimIdempotencyKey, "idempotency_keys.status = 'IN_PROGRESS'",
+		"a completed key is never claimed again")
+	assert.Contains(t, claimIdempotencyKey, "idempotency_keys.request_hash = excluded.request_hash",
+		"a stale key is only claimed again by the same request")
+	assert.Contains(t, claimIdempotencyKey, "idempotency_keys.claimed_at < now() - $4::INTERVAL",
+		"staleness is judged by the database clock that wrote claimed_at")
+}
+
+func TestQueries_CompleteIdempotencyKey(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	status := int32(201)
+	params := CompleteIdempotencyKeyParams{
+		ClientID:        uuid.New(),
+		IdempotencyKey:  "order-1",
+		ResponseStatus:  &status,
+		ResponseBody:    []byte(`{}`),
+		ResponseHeaders: []byte(`{"Location":["/v1/payments/p"]}`),
+	}
+	mockDB.On("Exec", ctx, completeIdempotencyKey,
+		[]interface{}{params.ClientID, params.IdempotencyKey, params.ResponseStatus, params.ResponseBody, params.ResponseHeaders}).Return(nil, nil)
+
+	err := queries.CompleteIdempotencyKey(ctx, params)
+
+	require.NoError(t, err)
+	assert.Contains(t, completeIdempotencyKey, "status = 'COMPLETED', response_status = $3, response_body = $4, response_headers = $5",
+		"the response is stored with the completion")
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_FailIdempotencyKey(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	params := FailIdempotencyKeyParams{ClientID: uuid.New(), IdempotencyKey: "order-1"}
+	mockDB.On("Exec", ctx, failIdempotencyKey, []interface{}{params.ClientID, params.IdempotencyKey}).Return(nil, nil)
+
+	err := queries.FailIdempotencyKey(ctx, params)
+
+	require.NoError(t, err)
+	assert.Contains(t, failIdempotencyKey, "SET status = 'FAILED'")
+	assert.Contains(t, failIdempotencyKey, "status = 'IN_PROGRESS'", "a completed key keeps its response")
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_GetIdempotencyKey(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	params := GetIdempotencyKeyParams{ClientID: uuid.New(), IdempotencyKey: "order-1"}
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, getIdempotencyKey, []interface{}{params.ClientID, params.IdempotencyKey}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 11)
+		*dest[4].(*string) = "COMPLETED"
+		status := int32(201)
+		*dest[5].(**int32) = &status
+		*dest[6].(*[]byte) = []byte(`{"id":"p"}`)
+	})
+
+	key, err := queries.GetIdempotencyKey(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, "COMPLETED", key.Status)
+	require.NotNil(t, key.ResponseStatus)
+	assert.Equal(t, int32(201), *key.ResponseStatus)
+	assert.Equal(t, `{"id":"p"}`, string(key.ResponseBody))
+}
+
+func TestQueries_ReleaseIdempotencyKey(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	params := ReleaseIdempotencyKeyParams{ClientID: uuid.New(), IdempotencyKey: "order-1"}
+	mockDB.On("Exec", ctx, releaseIdempotencyKey, []interface{}{params.ClientID, params.IdempotencyKey}).Return(nil, nil)
+
+	err := queries.ReleaseIdempotencyKey(ctx, params)
+
+	require.NoError(t, err)
+	assert.Contains(t, releaseIdempotencyKey, "status = 'IN_PROGRESS'", "completed keys are never released")
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_RenewIdempotencyKey(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	params := RenewIdempotencyKeyParams{ClientID: uuid.New(), IdempotencyKey: "order-1"}
+	mockDB.On("Exec", ctx, renewIdempotencyKey, []interface{}{params.ClientID, params.IdempotencyKey}).Return(nil, nil)
+
+	err := queries.RenewIdempotencyKey(ctx, params)
+
+	require.NoError(t, err)
+	assert.Contains(t, renewIdempotencyKey, "SET claimed_at = now()")
+	assert.Contains(t, renewIdempotencyKey, "status = 'IN_PROGRESS'")
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Query results recorded by InstrumentedQuerier.
+const (
+	QueryResultSuccess = "success"
+	QueryResultError   = "error"
+)
+
+// MetricsRecorder receives the duration of every query an
+// InstrumentedQuerier runs. *metrics.Metrics implements it.
+type MetricsRecorder interface {
+	// ObserveQuery records a call of the Querier method named method, e.g.
+	// "CreatePayment", with result QueryResultSuccess or QueryResultError.
+	ObserveQuery(method, result string, d time.Duration)
+}
+
+// InstrumentedQuerier decorates a Querier, recording how long each of its
+// methods takes and whether it failed. Finding no row is a result, not a
+// failure, as in the query spans.
+type InstrumentedQuerier struct {
+	q        Querier
+	recorder MetricsRecorder
+}
+
+// A method added to Querier without regenerating fails the build here.
+var _ Querier = (*InstrumentedQuerier)(nil)
+
+// WithMetricsRecorder records the name, duration and result of every query
+// the Store makes in r, by decorating its queries with an
+// InstrumentedQuerier.
+func WithMetricsRecorder(r MetricsRecorder) StoreOption {
+	return func(s *Store) { s.recorder = r }
+}
+
+// NewInstrumentedQuerier decorates q, recording into recorder.
+func NewInstrumentedQuerier(q Querier, recorder MetricsRecorder) *InstrumentedQuerier {
+	return &InstrumentedQuerier{q: q, recorder: recorder}
+}
+
+func (i *InstrumentedQuerier) observe(method string, start time.Time, err error) {
+	result := QueryResultSuccess
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		result = QueryResultError
+	}
+	i.recorder.ObserveQuery(method, result, time.Since(start))
+}
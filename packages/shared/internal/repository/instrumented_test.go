@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// observation is one query an InstrumentedQuerier recorded.
+type observation struct {
+	method, result string
+}
+
+type recordingRecorder struct {
+	mu   sync.Mutex
+	seen []observation
+}
+
+func (r *recordingRecorder) ObserveQuery(method, result string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seen = append(r.seen, observation{method, result})
+}
+
+// failingDB fails every statement with err.
+type failingDB struct{ err error }
+
+func (db failingDB) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, db.err
+}
+
+func (db failingDB) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return nil, db.err
+}
+
+func (db failingDB) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	return failingRow{db.err}
+}
+
+type failingRow struct{ err error }
+
+func (r failingRow) Scan(...any) error { return r.err }
+
+// TestInstrumentedQuerier_RecordsEveryMethod calls every Querier method
+// through the decorator, so a method it passes through unrecorded, or
+// records under another name, fails here.
+func TestInstrumentedQuerier_RecordsEveryMethod(t *testing.T) {
+	recorder := &recordingRecorder{}
+	q := reflect.ValueOf(NewInstrumentedQuerier(New(failingDB{errors.New("boom")}), recorder))
+	querier := reflect.TypeFor[Querier]()
+	require.Positive(t, querier.NumMethod())
+
+	for i := range querier.NumMethod() {
+		method := querier.Method(i)
+		t.Run(method.Name, func(t *testing.T) {
+			recorder.seen = nil
+			fn := q.MethodByName(method.Name)
+			args := []reflect.Value{reflect.ValueOf(context.Background())}
+			for j := 1; j < fn.Type().NumIn(); j++ {
+				args = append(args, reflect.Zero(fn.Type().In(j)))
+			}
+
+			out := fn.Call(args)
+
+			assert.EqualError(t, out[len(out)-1].Interface().(error), "boom")
+			assert.Equal(t, []observation{{method.Name, QueryResultError}}, recorder.seen)
+		})
+	}
+}
+
+func TestInstrumentedQuerier_Results(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"success", nil, QueryResultSuccess},
+		{"no rows", pgx.ErrNoRows, QueryResultSuccess},
+		{"error", errors.New("boom"), QueryResultError},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := &recordingRecorder{}
+			q := NewInstrumentedQuerier(New(failingDB{tc.err}), recorder)
+
+			_, err := q.NextWalletIndex(context.Background(), "deposit")
+
+			assert.Equal(t, tc.err, err, "the error is returned as is")
+			assert.Equal(t, []observation{{"NextWalletIndex", tc.want}}, recorder.seen)
+		})
+	}
+}
+
+func TestStore_WithMetricsRecorder(t *testing.T) {
+	ctx := context.Background()
+	tx := &recordingTx{}
+	recorder := &recordingRecorder{}
+	store := NewStore(&beginnerDB{tx: tx}, WithMetricsRecorder(recorder))
+	mockRow := new(MockRow)
+	tx.On("QueryRow", ctx, createPayment, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(&pgconn.PgError{Code: "23505", ConstraintName: "idx_payments_unique_wallet_open"})
+
+	err := store.ExecTx(ctx, func(q Querier) error {
+		_, err := q.CreatePayment(ctx, CreatePaymentParams{})
+		return err
+	})
+
+	assert.ErrorIs(t, err, ErrDuplicateWallet, "overridden methods still translate errors")
+	assert.Equal(t, []observation{{"CreatePayment", QueryResultError}}, recorder.seen)
+}
+
+func TestNewStore_UninstrumentedByDefault(t *testing.T) {
+	assert.IsType(t, &Queries{}, NewStore(new(MockDBTX)).Querier)
+	assert.IsType(t, &InstrumentedQuerier{}, NewStore(new(MockDBTX), WithMetricsRecorder(&recordingRecorder{})).Querier)
+}
@@ -0,0 +1,177 @@
+// Command instrumentgen writes instrumented_querier.go: a method of
+// InstrumentedQuerier for every method of the Querier interface sqlc
+// generates in querier.go, timing the call and recording it under the
+// method's name. It runs through go:generate in the repository package;
+// rerun it after regenerating the queries with sqlc.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// interfaceName is the interface decorated, and receiver the decorator.
+const (
+	interfaceName = "Querier"
+	receiver      = "InstrumentedQuerier"
+)
+
+func main() {
+	in := flag.String("in", "querier.go", "file declaring the Querier interface")
+	out := flag.String("out", "instrumented_querier.go", "file to write")
+	flag.Parse()
+
+	if err := run(*in, *out); err != nil {
+		slog.Error("generating the instrumented querier failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(in, out string) error {
+	src, err := os.ReadFile(in)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", in, err)
+	}
+	code, err := generate(src)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(out, code, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", out, err)
+	}
+	return nil
+}
+
+// generate returns the formatted source of the InstrumentedQuerier methods
+// for the Querier interface declared in src.
+func generate(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "querier.go", src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse querier: %w", err)
+	}
+	iface, err := findInterface(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by instrumentgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", f.Name.Name)
+	writeImports(&buf, f)
+
+	methods := iface.Methods.List
+	slices.SortFunc(methods, func(a, b *ast.Field) int { return strings.Compare(a.Names[0].Name, b.Names[0].Name) })
+	for _, m := range methods {
+		fn, ok := m.Type.(*ast.FuncType)
+		if !ok || len(m.Names) != 1 {
+			return nil, fmt.Errorf("%s embeds %s; only methods can be decorated", interfaceName, types.ExprString(m.Type))
+		}
+		if err := writeMethod(&buf, m.Names[0].Name, fn); err != nil {
+			return nil, err
+		}
+	}
+
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w", err)
+	}
+	return code, nil
+}
+
+func findInterface(f *ast.File) (*ast.InterfaceType, error) {
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if iface, ok := ts.Type.(*ast.InterfaceType); ok && ts.Name.Name == interfaceName {
+				return iface, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no %s interface found", interfaceName)
+}
+
+// writeImports imports what the querier does, which covers every parameter
+// and result type, and time for the timing.
+func writeImports(buf *bytes.Buffer, f *ast.File) {
+	var std, other []string
+	paths := []string{strconv.Quote("time")}
+	for _, imp := range f.Imports {
+		paths = append(paths, imp.Path.Value)
+	}
+	slices.Sort(paths)
+	for _, p := range slices.Compact(paths) {
+		if strings.Contains(p, ".") {
+			other = append(other, p)
+		} else {
+			std = append(std, p)
+		}
+	}
+	buf.WriteString("import (\n")
+	for _, p := range std {
+		fmt.Fprintf(buf, "\t%s\n", p)
+	}
+	if len(other) > 0 {
+		buf.WriteString("\n")
+	}
+	for _, p := range other {
+		fmt.Fprintf(buf, "\t%s\n", p)
+	}
+	buf.WriteString(")\n\n")
+}
+
+func writeMethod(buf *bytes.Buffer, name string, fn *ast.FuncType) error {
+	var params, args []string
+	for i, p := range fn.Params.List {
+		typ := types.ExprString(p.Type)
+		names := p.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{ast.NewIdent(fmt.Sprintf("p%d", i))}
+		}
+		for _, n := range names {
+			params = append(params, n.Name+" "+typ)
+			args = append(args, n.Name)
+		}
+	}
+	var results []string
+	if fn.Results != nil {
+		for _, r := range fn.Results.List {
+			for range max(len(r.Names), 1) {
+				results = append(results, types.ExprString(r.Type))
+			}
+		}
+	}
+	if len(results) == 0 || results[len(results)-1] != "error" {
+		return errors.New(name + " does not return an error")
+	}
+
+	call := fmt.Sprintf("i.q.%s(%s)", name, strings.Join(args, ", "))
+	fmt.Fprintf(buf, "func (i *%s) %s(%s) ", receiver, name, strings.Join(params, ", "))
+	switch len(results) {
+	case 1:
+		fmt.Fprintf(buf, "error {\n\tstart := time.Now()\n\terr := %s\n", call)
+		fmt.Fprintf(buf, "\ti.observe(%q, start, err)\n\treturn err\n}\n\n", name)
+	case 2:
+		fmt.Fprintf(buf, "(%s) {\n\tstart := time.Now()\n\tr, err := %s\n", strings.Join(results, ", "), call)
+		fmt.Fprintf(buf, "\ti.observe(%q, start, err)\n\treturn r, err\n}\n\n", name)
+	default:
+		return fmt.Errorf("%s returns %d values; only (T, error) and error are supported", name, len(results))
+	}
+	return nil
+}
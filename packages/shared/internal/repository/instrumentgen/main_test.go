@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGenerate_UpToDate fails when querier.go changed without rerunning go
+// generate.
+func TestGenerate_UpToDate(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("..", "querier.go"))
+	require.NoError(t, err)
+	want, err := os.ReadFile(filepath.Join("..", "instrumented_querier.go"))
+	require.NoError(t, err)
+
+	got, err := generate(src)
+
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got), "run go generate ./internal/repository")
+}
+
+func TestGenerate(t *testing.T) {
+	src := `package repository
+
+import "context"
+
+type Querier interface {
+	// Deletes a thing.
+	DeleteThing(ctx context.Context, id int64) error
+	CountThings(ctx context.Context) (int64, error)
+}
+`
+	got, err := generate([]byte(src))
+
+	require.NoError(t, err)
+	assert.Equal(t, `// Code generated by instrumentgen. DO NOT EDIT.
+
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+func (i *InstrumentedQuerier) CountThings(ctx context.Context) (int64, error) {
+	start := time.Now()
+	r, err := i.q.CountThings(ctx)
+	i.observe("CountThings", start, err)
+	return r, err
+}
+
+func (i *InstrumentedQuerier) DeleteThing(ctx context.Context, id int64) error {
+	start := time.Now()
+	err := i.q.DeleteThing(ctx, id)
+	i.observe("DeleteThing", start, err)
+	return err
+}
+`, string(got))
+}
+
+func TestGenerate_Unsupported(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{"no interface", "package repository\n", "no Querier interface found"},
+		{"no error", "package repository\ntype Querier interface{ Ping() bool }\n", "Ping does not return an error"},
+		{"three results", "package repository\ntype Querier interface{ Pair() (int, int, error) }\n", "Pair returns 3 values"},
+		{"embedded", "package repository\ntype Querier interface{ Other }\n", "Querier embeds Other"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := generate([]byte(tc.src))
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/cockroachdb"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/db"
+)
+
+// The integration suite runs the queries against a real CockroachDB:
+//
+//	go test -tags integration ./internal/repository/
+//
+// It starts a container through testcontainers, which needs Docker. With
+// TPG_TEST_DATABASE_URL set it uses that cluster instead. Either way the
+// suite migrates a database of its own and drops it when done.
+
+// cockroachImage is the CockroachDB version the suite runs against.
+const cockroachImage = "cockroachdb/cockroach:v24.3.5"
+
+// integrationPool is the migrated database every integration test shares.
+var integrationPool *pgxpool.Pool
+
+func TestMain(m *testing.M) {
+	os.Exit(runIntegration(m))
+}
+
+func runIntegration(m *testing.M) int {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	dsn := os.Getenv("TPG_TEST_DATABASE_URL")
+	if dsn == "" {
+		ctr, err := cockroachdb.Run(ctx, cockroachImage, cockroachdb.WithInsecure())
+		defer func() {
+			if err := testcontainers.TerminateContainer(ctr); err != nil {
+				fmt.Fprintln(os.Stderr, "failed to terminate CockroachDB container:", err)
+			}
+		}()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to start CockroachDB container:", err)
+			return 1
+		}
+		cfg, err := ctr.ConnectionConfig(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to read CockroachDB container address:", err)
+			return 1
+		}
+		dsn = cfg.ConnString()
+	}
+
+	pool, drop, err := createTestDatabase(ctx, dsn)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer drop()
+	if err := db.Migrate(ctx, pool); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to migrate test database:", err)
+		return 1
+	}
+	integrationPool = pool
+	return m.Run()
+}
+
+// createTestDatabase creates a database of its own on the cluster at dsn, so
+// the suite neither sees nor disturbs other data there, and returns a pool
+// on it and the function dropping it.
+func createTestDatabase(ctx context.Context, dsn string) (*pgxpool.Pool, func(), error) {
+	admin, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to test cluster: %w", err)
+	}
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	name := "tpg_repository_" + hex.EncodeToString(suffix)
+	if _, err := admin.Exec(ctx, "CREATE DATABASE "+name); err != nil {
+		admin.Close(ctx)
+		return nil, nil, fmt.Errorf("failed to create test database: %w", err)
+	}
+
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		admin.Close(ctx)
+		return nil, nil, fmt.Errorf("failed to parse test database URL: %w", err)
+	}
+	cfg.ConnConfig.Database = name
+	// Enough connections for the concurrency tests to contend for real.
+	cfg.MaxConns = 60
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		admin.Close(ctx)
+		return nil, nil, fmt.Errorf("failed to connect to test database: %w", err)
+	}
+	drop := func() {
+		pool.Close()
+		ctx := context.Background()
+		if _, err := admin.Exec(ctx, "DROP DATABASE IF EXISTS "+name+" CASCADE"); err != nil {
+			fmt.Fprintln(os.Stderr, "failed to drop test database:", err)
+		}
+		admin.Close(ctx)
+	}
+	return pool, drop, nil
+}
+
+// fixture creates the rows an integration test starts from. Every row it
+// creates is unique to the test, so tests share one database; a test that
+// scans a whole table should filter the result to the rows it created.
+type fixture struct {
+	t     *testing.T
+	ctx   context.Context
+	pool  *pgxpool.Pool
+	store *Store
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	if integrationPool == nil {
+		t.Fatal("integration database is not set up")
+	}
+	return &fixture{t: t, ctx: context.Background(), pool: integrationPool, store: NewStore(integrationPool)}
+}
+
+// conn returns a connection of its own, for tests that need two sessions
+// to contend.
+func (f *fixture) conn() *pgxpool.Conn {
+	f.t.Helper()
+	c, err := f.pool.Acquire(f.ctx)
+	require.NoError(f.t, err)
+	f.t.Cleanup(c.Release)
+	return c
+}
+
+func (f *fixture) client() Client {
+	f.t.Helper()
+	c, err := f.store.CreateClient(f.ctx, CreateClientParams{Name: "client-" + uuid.NewString(), ApiKey: "key-" + uuid.NewString()})
+	require.NoError(f.t, err)
+	return c
+}
+
+func (f *fixture) account(clientID uuid.UUID) Account {
+	f.t.Helper()
+	a, err := f.store.CreateAccount(f.ctx, CreateAccountParams{ClientID: clientID, Name: "account-" + uuid.NewString()})
+	require.NoError(f.t, err)
+	return a
+}
+
+// wallet returns an address no other payment uses.
+func (f *fixture) wallet() string {
+	id := uuid.New()
+	return "T" + hex.EncodeToString(id[:])
+}
+
+// payment creates a PENDING 25.5 USDT payment of account on a fresh wallet
+// and index, expiring in an hour; edit changes the parameters first.
+func (f *fixture) payment(account Account, edit ...func(*CreatePaymentParams)) Payment {
+	f.t.Helper()
+	index, err := f.store.NextWalletIndex(f.ctx, "deposit")
+	require.NoError(f.t, err)
+	arg := CreatePaymentParams{
+		ClientID:     account.ClientID,
+		AccountID:    account.ID,
+		Amount:       numeric(2550, -2),
+		UniqueWallet: f.wallet(),
+		ExpiresAt:    timestamptz(time.Now().Add(time.Hour)),
+		WalletIndex:  &index,
+		Token:        "USDT",
+		Mode:         "live",
+	}
+	for _, e := range edit {
+		e(&arg)
+	}
+	p, err := f.store.CreatePayment(f.ctx, arg)
+	require.NoError(f.t, err)
+	return p
+}
+
+// deposit records a transfer of p's whole amount to its wallet, as the
+// watcher would, so that p can be confirmed.
+func (f *fixture) deposit(p Payment) {
+	f.t.Helper()
+	_, err := f.store.CreateTransaction(f.ctx, CreateTransactionParams{
+		PaymentID:   p.ID,
+		TxHash:      uuid.NewString(),
+		Token:       p.Token,
+		FromAddress: f.wallet(),
+		ToAddress:   p.UniqueWallet,
+		Amount:      p.Amount,
+		BlockNumber: 1,
+		BlockHash:   "hash",
+	})
+	require.NoError(f.t, err)
+}
+
+func numeric(n int64, exp int32) pgtype.Numeric {
+	return pgtype.Numeric{Int: big.NewInt(n), Exp: exp, Valid: true}
+}
+
+func timestamptz(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
@@ -0,0 +1,97 @@
+//go:build integration
+
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegration_MonthlyFeeBasis(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	client := f.client()
+	account := f.account(client.ID)
+	confirm := func(p Payment) {
+		f.deposit(p)
+		_, err := f.store.ConfirmPayment(ctx, ConfirmPaymentParams{ID: p.ID, Version: p.Version})
+		require.NoError(t, err)
+	}
+	// 1% of 25.5 is 0.255, at least the 0.25 minimum; 1% of 10 is not.
+	confirm(f.payment(account))
+	confirm(f.payment(account))
+	confirm(f.payment(account, func(p *CreatePaymentParams) { p.Amount = numeric(10, 0) }))
+	confirm(f.payment(account, func(p *CreatePaymentParams) { p.Token = "TRX" }))
+	// Test-mode and unconfirmed payments are not charged.
+	confirm(f.payment(account, func(p *CreatePaymentParams) { p.Mode = "test" }))
+	f.payment(account)
+
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	arg := GetMonthlyFeeBasisParams{
+		Tokens:     []string{"TRX", "USDT"},
+		Percents:   []pgtype.Numeric{numeric(2, 0), numeric(1, 0)},
+		Minimums:   []pgtype.Numeric{numeric(0, 0), numeric(25, -2)},
+		ClientID:   client.ID,
+		MonthStart: timestamptz(start),
+		MonthEnd:   timestamptz(start.AddDate(0, 1, 0)),
+	}
+	rows, err := f.store.GetMonthlyFeeBasis(ctx, arg)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "TRX", rows[0].Token)
+	assert.Equal(t, int64(1), rows[0].Payments)
+	assert.Equal(t, int64(0), rows[0].MinimumPayments)
+	assert.Equal(t, "USDT", rows[1].Token)
+	assert.Equal(t, int64(3), rows[1].Payments)
+	assertNumeric(t, 61, rows[1].Amount)
+	assert.Equal(t, int64(1), rows[1].MinimumPayments)
+	assertNumeric(t, 10, rows[1].MinimumAmount)
+
+	arg.Tokens, arg.Percents, arg.Minimums = arg.Tokens[1:], arg.Percents[1:], arg.Minimums[1:]
+	rows, err = f.store.GetMonthlyFeeBasis(ctx, arg)
+	require.NoError(t, err)
+	require.Len(t, rows, 1, "tokens left out of the schedule are left out")
+}
+
+func TestIntegration_Invoices(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	client := f.client()
+	month := pgtype.Date{Time: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+
+	invoice, err := f.store.CreateInvoice(ctx, CreateInvoiceParams{ClientID: client.ID, Month: month, Payments: 2})
+	require.NoError(t, err)
+	require.NoError(t, f.store.CreateInvoiceLine(ctx, CreateInvoiceLineParams{
+		InvoiceID: invoice.ID, Token: "USDT", Kind: "PERCENT", Payments: 2,
+		Amount: numeric(200, 0), Rate: numeric(15, -1), Fee: numeric(3, 0),
+	}))
+
+	_, err = f.store.CreateInvoice(ctx, CreateInvoiceParams{ClientID: client.ID, Month: month, Payments: 5})
+	assert.ErrorIs(t, err, pgx.ErrNoRows, "one invoice per client and month")
+	got, err := f.store.GetInvoiceByClientMonth(ctx, GetInvoiceByClientMonthParams{ClientID: client.ID, Month: month})
+	require.NoError(t, err)
+	assert.Equal(t, invoice.ID, got.ID)
+	assert.Equal(t, int64(2), got.Payments)
+
+	lines, err := f.store.ListInvoiceLines(ctx, []uuid.UUID{invoice.ID})
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assertNumeric(t, 3, lines[0].Fee)
+
+	invoices, err := f.store.ListInvoices(ctx, ListInvoicesParams{ClientID: &client.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, invoices, 1)
+	assert.Equal(t, invoice.ID, invoices[0].ID)
+
+	_, err = f.store.CreateInvoice(ctx, CreateInvoiceParams{
+		ClientID: client.ID, Month: pgtype.Date{Time: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), Valid: true},
+	})
+	assert.Error(t, err, "a month starts on its first day")
+}
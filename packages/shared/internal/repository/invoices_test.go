@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueries_CreateInvoice(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	params := CreateInvoiceParams{
+		ClientID: uuid.New(),
+		Month:    pgtype.Date{Time: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+		Payments: 12,
+	}
+	invoiceID := uuid.New()
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createInvoice, []interface{}{params.ClientID, params.Month, params.Payments}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 5)
+		*dest[0].(*uuid.UUID) = invoiceID
+		*dest[3].(*int64) = params.Payments
+	})
+
+	invoice, err := queries.CreateInvoice(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, invoiceID, invoice.ID)
+	assert.Equal(t, int64(12), invoice.Payments)
+	assert.Contains(t, createInvoice, "ON CONFLICT (client_id, month) DO NOTHING")
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_CreateInvoice_Exists(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createInvoice, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+	_, err := queries.CreateInvoice(ctx, CreateInvoiceParams{ClientID: uuid.New()})
+
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestQueries_GetMonthlyFeeBasis(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	arg := GetMonthlyFeeBasisParams{
+		Tokens:     []string{"TRX", "USDT"},
+		Percents:   []pgtype.Numeric{{Int: big.NewInt(15), Exp: -1, Valid: true}, {Int: big.NewInt(1), Valid: true}},
+		Minimums:   []pgtype.Numeric{{Int: big.NewInt(0), Valid: true}, {Int: big.NewInt(25), Exp: -2, Valid: true}},
+		ClientID:   uuid.New(),
+		MonthStart: pgtype.Timestamptz{Time: start, Valid: true},
+		MonthEnd:   pgtype.Timestamptz{Time: start.AddDate(0, 1, 0), Valid: true},
+	}
+
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, getMonthlyFeeBasis, []interface{}{
+		arg.Tokens, arg.Percents, arg.Minimums, arg.ClientID, arg.MonthStart, arg.MonthEnd,
+	}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 5)
+		*dest[0].(*string) = "USDT"
+		*dest[1].(*int64) = 4
+		*dest[3].(*int64) = 1
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	got, err := queries.GetMonthlyFeeBasis(ctx, arg)
+
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "USDT", got[0].Token)
+	assert.Equal(t, int64(4), got[0].Payments)
+	assert.Equal(t, int64(1), got[0].MinimumPayments)
+	assert.Contains(t, getMonthlyFeeBasis, "p.status = 'CONFIRMED' AND p.mode = 'live'", "only live confirmed payments are charged")
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_ListInvoiceLines(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	ids := []uuid.UUID{uuid.New()}
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, listInvoiceLines, []interface{}{ids}).Return(mockRows, nil)
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	got, err := queries.ListInvoiceLines(ctx, ids)
+
+	require.NoError(t, err)
+	assert.Empty(t, got)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_ListInvoices_Error(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	mockDB.On("Query", ctx, listInvoices, mock.Anything).Return(nil, assert.AnError)
+
+	got, err := queries.ListInvoices(ctx, ListInvoicesParams{Limit: 10})
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Nil(t, got)
+}
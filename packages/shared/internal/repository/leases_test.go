@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaseSQL(t *testing.T) {
+	assert.Contains(t, acquireLease, "WHERE leases.lease_until <= now()", "only a lapsed lease changes hands")
+	assert.Contains(t, acquireLease, "token = leases.token + 1", "every new holder gets a higher token")
+	assert.Contains(t, renewLease, "WHERE name = $2 AND token = $3 AND lease_until > now()", "a lapsed lease is not renewed")
+}
+
+func TestQueries_AcquireLease(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	id := uuid.New()
+	until := time.Date(2026, 3, 1, 12, 0, 30, 0, time.UTC)
+	params := AcquireLeaseParams{
+		Name:   "sweeper",
+		Holder: "sweeper-0",
+		Ttl:    pgtype.Interval{Microseconds: 30_000_000, Valid: true},
+	}
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, acquireLease, []interface{}{params.Name, params.Holder, params.Ttl}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 6)
+		*dest[0].(*uuid.UUID) = id
+		*dest[1].(*string) = "sweeper"
+		*dest[2].(*string) = "sweeper-0"
+		*dest[3].(*int64) = 7
+		*dest[4].(*pgtype.Timestamptz) = pgtype.Timestamptz{Time: until, Valid: true}
+	})
+
+	lease, err := queries.AcquireLease(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, Lease{
+		ID:         id,
+		Name:       "sweeper",
+		Holder:     "sweeper-0",
+		Token:      7,
+		LeaseUntil: pgtype.Timestamptz{Time: until, Valid: true},
+	}, lease)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_AcquireLease_Held(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, acquireLease, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+	_, err := queries.AcquireLease(ctx, AcquireLeaseParams{Name: "sweeper", Holder: "sweeper-1"})
+
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestQueries_RenewLease(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	params := RenewLeaseParams{
+		Ttl:   pgtype.Interval{Microseconds: 30_000_000, Valid: true},
+		Name:  "sweeper",
+		Token: 7,
+	}
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, renewLease, []interface{}{params.Ttl, params.Name, params.Token}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		*dest[3].(*int64) = 7
+	})
+
+	lease, err := queries.RenewLease(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), lease.Token)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_ReleaseLease(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	mockDB.On("Exec", ctx, releaseLease, []interface{}{"sweeper", int64(7)}).Return(nil, nil)
+
+	err := queries.ReleaseLease(ctx, ReleaseLeaseParams{Name: "sweeper", Token: 7})
+
+	require.NoError(t, err)
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,80 @@
+//go:build integration
+
+package repository
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegration_DeleteLogsBatchDeletesOldestFirst(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	// An event type of its own keeps other tests' logs out of the counts.
+	eventType := "TEST_" + uuid.NewString()
+	now := time.Now()
+	for days := 1; days <= 5; days++ {
+		_, err := f.conn().Exec(ctx, "INSERT INTO logs (event_type, message, created_at) VALUES ($1, $2, $3)",
+			eventType, fmt.Sprintf("day %d", days), now.Add(-time.Duration(days)*24*time.Hour))
+		require.NoError(t, err)
+	}
+	cutoff := timestamptz(now.Add(-36 * time.Hour))
+
+	n, err := f.store.CountLogsOlderThan(ctx, CountLogsOlderThanParams{EventType: eventType, CreatedAt: cutoff})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), n, "the log of a day ago is within retention")
+
+	deleted, err := f.store.DeleteLogsBatch(ctx, DeleteLogsBatchParams{EventType: eventType, CreatedBefore: cutoff, Limit: 3})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+
+	var left []string
+	rows, err := f.conn().Query(ctx, "SELECT message FROM logs WHERE event_type = $1 ORDER BY created_at", eventType)
+	require.NoError(t, err)
+	for rows.Next() {
+		var m string
+		require.NoError(t, rows.Scan(&m))
+		left = append(left, m)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"day 2", "day 1"}, left, "the three oldest went first")
+
+	deleted, err = f.store.DeleteLogsBatch(ctx, DeleteLogsBatchParams{EventType: eventType, CreatedBefore: cutoff, Limit: 3})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	deleted, err = f.store.DeleteLogsBatch(ctx, DeleteLogsBatchParams{EventType: eventType, CreatedBefore: cutoff, Limit: 3})
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+
+	types, err := f.store.ListLogEventTypes(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, types, eventType)
+}
+
+func TestIntegration_ListClientLogsReadsOnlyTheClientsLogs(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	own := f.client()
+	other := f.client()
+	ownPayment := f.payment(f.account(own.ID))
+	otherPayment := f.payment(f.account(other.ID))
+	for _, p := range []Payment{ownPayment, otherPayment} {
+		require.NoError(t, f.store.CreateLog(ctx, CreateLogParams{PaymentID: UUIDPtr(p.ID), EventType: "PAYMENT_CREATED"}))
+	}
+	require.NoError(t, f.store.CreateLog(ctx, CreateLogParams{EventType: "CLIENT_CREATED"}))
+	tenant := NewTenantQueries(f.store, own.ID)
+
+	logs, err := tenant.ListClientLogs(ctx, ListClientLogsParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, logs, 1, "neither the other client's logs nor logs of no payment")
+	assert.Equal(t, ownPayment.ID, *logs[0].PaymentID)
+
+	logs, err = tenant.ListClientLogs(ctx, ListClientLogsParams{ClientID: other.ID, PaymentID: UUIDPtr(otherPayment.ID), Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, logs, "the other client's payment id reads nothing")
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueries_CountLogsOlderThan(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	arg := CountLogsOlderThanParams{
+		EventType: "TX_DETECTED",
+		CreatedAt: pgtype.Timestamptz{Time: time.Now().Add(-30 * 24 * time.Hour), Valid: true},
+	}
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, countLogsOlderThan, []interface{}{arg.EventType, arg.CreatedAt}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 1)
+		*dest[0].(*int64) = 4200
+	})
+
+	n, err := queries.CountLogsOlderThan(ctx, arg)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(4200), n)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_DeleteLogsBatch(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	arg := DeleteLogsBatchParams{
+		EventType:     "WEBHOOK_SENT",
+		CreatedBefore: pgtype.Timestamptz{Time: time.Now().Add(-90 * 24 * time.Hour), Valid: true},
+		Limit:         1000,
+	}
+	mockDB.On("Exec", ctx, deleteLogsBatch, []interface{}{arg.EventType, arg.CreatedBefore, arg.Limit}).
+		Return(pgconn.NewCommandTag("DELETE 1000"), nil)
+
+	n, err := queries.DeleteLogsBatch(ctx, arg)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), n)
+	assert.Contains(t, deleteLogsBatch, "ORDER BY created_at\nLIMIT $3", "each batch deletes the oldest rows")
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_DeleteLogsBatch_Error(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	mockDB.On("Exec", ctx, deleteLogsBatch, mock.Anything).Return(nil, assert.AnError)
+
+	n, err := queries.DeleteLogsBatch(ctx, DeleteLogsBatchParams{EventType: "TX_DETECTED", Limit: 10})
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Zero(t, n)
+}
+
+func TestQueries_ListLogEventTypes(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, listLogEventTypes, []interface{}(nil)).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Twice()
+	mockRows.On("Next").Return(false).Once()
+	types := []string{"ADDRESS_GENERATED", "TX_DETECTED"}
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(0).([]interface{})[0].(*string) = types[0]
+		types = types[1:]
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	got, err := queries.ListLogEventTypes(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ADDRESS_GENERATED", "TX_DETECTED"}, got)
+}
+
+func TestQueries_ListLogs(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	eventType := "WEBHOOK_SENT"
+	arg := ListLogsParams{
+		EventType: &eventType,
+		From:      pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true},
+		Limit:     51,
+		Offset:    50,
+	}
+	id := uuid.New()
+
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, listLogs, []interface{}{arg.EventType, arg.PaymentID, arg.From, arg.To, arg.Limit, arg.Offset}).
+		Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 8)
+		*dest[0].(*uuid.UUID) = id
+		*dest[2].(*string) = eventType
+		*dest[4].(*[]byte) = []byte(`{"status":200}`)
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	got, err := queries.ListLogs(ctx, arg)
+
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, id, got[0].ID)
+	assert.Equal(t, eventType, got[0].EventType)
+	assert.JSONEq(t, `{"status":200}`, string(got[0].RawData))
+	assert.Contains(t, listLogs, "ORDER BY created_at DESC, id DESC", "pages are stable under equal timestamps")
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_ListLogs_Error(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	mockDB.On("Query", ctx, listLogs, mock.Anything).Return(nil, assert.AnError)
+
+	got, err := queries.ListLogs(ctx, ListLogsParams{Limit: 10})
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Nil(t, got)
+}
@@ -0,0 +1,3314 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package repository
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	pgtype "github.com/jackc/pgx/v5/pgtype"
+
+	uuid "github.com/google/uuid"
+)
+
+// MockQuerier is an autogenerated mock type for the Querier type
+type MockQuerier struct {
+	mock.Mock
+}
+
+// AcquireLease provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) AcquireLease(ctx context.Context, arg AcquireLeaseParams) (Lease, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AcquireLease")
+	}
+
+	var r0 Lease
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, AcquireLeaseParams) (Lease, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, AcquireLeaseParams) Lease); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Lease)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, AcquireLeaseParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AllocateWalletIndexes provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) AllocateWalletIndexes(ctx context.Context, arg AllocateWalletIndexesParams) (int64, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AllocateWalletIndexes")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, AllocateWalletIndexesParams) (int64, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, AllocateWalletIndexesParams) int64); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, AllocateWalletIndexesParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CancelPayment provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) CancelPayment(ctx context.Context, arg CancelPaymentParams) (Payment, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CancelPayment")
+	}
+
+	var r0 Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, CancelPaymentParams) (Payment, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, CancelPaymentParams) Payment); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Payment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, CancelPaymentParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClaimIdempotencyKey provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ClaimIdempotencyKey(ctx context.Context, arg ClaimIdempotencyKeyParams) (IdempotencyKey, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClaimIdempotencyKey")
+	}
+
+	var r0 IdempotencyKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ClaimIdempotencyKeyParams) (IdempotencyKey, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ClaimIdempotencyKeyParams) IdempotencyKey); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(IdempotencyKey)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ClaimIdempotencyKeyParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClaimOutboxEvents provides a mock function with given fields: ctx, limit
+func (_m *MockQuerier) ClaimOutboxEvents(ctx context.Context, limit int32) ([]Outbox, error) {
+	ret := _m.Called(ctx, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClaimOutboxEvents")
+	}
+
+	var r0 []Outbox
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int32) ([]Outbox, error)); ok {
+		return rf(ctx, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int32) []Outbox); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Outbox)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int32) error); ok {
+		r1 = rf(ctx, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClaimWebhookDeliveries provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]ClaimWebhookDeliveriesRow, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClaimWebhookDeliveries")
+	}
+
+	var r0 []ClaimWebhookDeliveriesRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ClaimWebhookDeliveriesParams) ([]ClaimWebhookDeliveriesRow, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ClaimWebhookDeliveriesParams) []ClaimWebhookDeliveriesRow); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ClaimWebhookDeliveriesRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ClaimWebhookDeliveriesParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClearRotatedWebhookSecrets provides a mock function with given fields: ctx, rotatedBefore
+func (_m *MockQuerier) ClearRotatedWebhookSecrets(ctx context.Context, rotatedBefore pgtype.Timestamptz) (int64, error) {
+	ret := _m.Called(ctx, rotatedBefore)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClearRotatedWebhookSecrets")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgtype.Timestamptz) (int64, error)); ok {
+		return rf(ctx, rotatedBefore)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pgtype.Timestamptz) int64); ok {
+		r0 = rf(ctx, rotatedBefore)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pgtype.Timestamptz) error); ok {
+		r1 = rf(ctx, rotatedBefore)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClientExistsByAPIKey provides a mock function with given fields: ctx, apiKey
+func (_m *MockQuerier) ClientExistsByAPIKey(ctx context.Context, apiKey string) (bool, error) {
+	ret := _m.Called(ctx, apiKey)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClientExistsByAPIKey")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(ctx, apiKey)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, apiKey)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, apiKey)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CompleteIdempotencyKey provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) CompleteIdempotencyKey(ctx context.Context, arg CompleteIdempotencyKeyParams) error {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CompleteIdempotencyKey")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, CompleteIdempotencyKeyParams) error); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ConfirmPayment provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ConfirmPayment(ctx context.Context, arg ConfirmPaymentParams) (Payment, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConfirmPayment")
+	}
+
+	var r0 Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ConfirmPaymentParams) (Payment, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ConfirmPaymentParams) Payment); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Payment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ConfirmPaymentParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountAccountsByClientID provides a mock function with given fields: ctx, clientID
+func (_m *MockQuerier) CountAccountsByClientID(ctx context.Context, clientID uuid.UUID) (int64, error) {
+	ret := _m.Called(ctx, clientID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountAccountsByClientID")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) (int64, error)); ok {
+		return rf(ctx, clientID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) int64); ok {
+		r0 = rf(ctx, clientID)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, clientID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountClientPaymentsByStatus provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) CountClientPaymentsByStatus(ctx context.Context, arg CountClientPaymentsByStatusParams) ([]CountClientPaymentsByStatusRow, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountClientPaymentsByStatus")
+	}
+
+	var r0 []CountClientPaymentsByStatusRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, CountClientPaymentsByStatusParams) ([]CountClientPaymentsByStatusRow, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, CountClientPaymentsByStatusParams) []CountClientPaymentsByStatusRow); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]CountClientPaymentsByStatusRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, CountClientPaymentsByStatusParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountLogsOlderThan provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) CountLogsOlderThan(ctx context.Context, arg CountLogsOlderThanParams) (int64, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountLogsOlderThan")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, CountLogsOlderThanParams) (int64, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, CountLogsOlderThanParams) int64); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, CountLogsOlderThanParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountPendingPaymentsByAccountID provides a mock function with given fields: ctx, accountID
+func (_m *MockQuerier) CountPendingPaymentsByAccountID(ctx context.Context, accountID uuid.UUID) (int64, error) {
+	ret := _m.Called(ctx, accountID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountPendingPaymentsByAccountID")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) (int64, error)); ok {
+		return rf(ctx, accountID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) int64); ok {
+		r0 = rf(ctx, accountID)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, accountID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateAccount provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateAccount")
+	}
+
+	var r0 Account
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, CreateAccountParams) (Account, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, CreateAccountParams) Account); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Account)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, CreateAccountParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateClient provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) CreateClient(ctx context.Context, arg CreateClientParams) (Client, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateClient")
+	}
+
+	var r0 Client
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, CreateClientParams) (Client, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, CreateClientParams) Client); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Client)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, CreateClientParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateInvoice provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) CreateInvoice(ctx context.Context, arg CreateInvoiceParams) (Invoice, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateInvoice")
+	}
+
+	var r0 Invoice
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, CreateInvoiceParams) (Invoice, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, CreateInvoiceParams) Invoice); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Invoice)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, CreateInvoiceParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateInvoiceLine provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) CreateInvoiceLine(ctx context.Context, arg CreateInvoiceLineParams) error {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateInvoiceLine")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, CreateInvoiceLineParams) error); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateLog provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) CreateLog(ctx context.Context, arg CreateLogParams) error {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateLog")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, CreateLogParams) error); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateOutboxEvent provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) error {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateOutboxEvent")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, CreateOutboxEventParams) error); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreatePayment provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) CreatePayment(ctx context.Context, arg CreatePaymentParams) (Payment, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatePayment")
+	}
+
+	var r0 Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, CreatePaymentParams) (Payment, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, CreatePaymentParams) Payment); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Payment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, CreatePaymentParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreatePaymentAttempt provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) CreatePaymentAttempt(ctx context.Context, arg CreatePaymentAttemptParams) (PaymentAttempt, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatePaymentAttempt")
+	}
+
+	var r0 PaymentAttempt
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, CreatePaymentAttemptParams) (PaymentAttempt, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, CreatePaymentAttemptParams) PaymentAttempt); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(PaymentAttempt)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, CreatePaymentAttemptParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreatePoolAddresses provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) CreatePoolAddresses(ctx context.Context, arg CreatePoolAddressesParams) (int64, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatePoolAddresses")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, CreatePoolAddressesParams) (int64, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, CreatePoolAddressesParams) int64); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, CreatePoolAddressesParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateReconciliationMismatch provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) CreateReconciliationMismatch(ctx context.Context, arg CreateReconciliationMismatchParams) error {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateReconciliationMismatch")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, CreateReconciliationMismatchParams) error); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateReconciliationReport provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) CreateReconciliationReport(ctx context.Context, arg CreateReconciliationReportParams) (ReconciliationReport, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateReconciliationReport")
+	}
+
+	var r0 ReconciliationReport
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, CreateReconciliationReportParams) (ReconciliationReport, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, CreateReconciliationReportParams) ReconciliationReport); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(ReconciliationReport)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, CreateReconciliationReportParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateSweep provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) CreateSweep(ctx context.Context, arg CreateSweepParams) (Sweep, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateSweep")
+	}
+
+	var r0 Sweep
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, CreateSweepParams) (Sweep, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, CreateSweepParams) Sweep); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Sweep)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, CreateSweepParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateSweepTransaction provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) CreateSweepTransaction(ctx context.Context, arg CreateSweepTransactionParams) (Transaction, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateSweepTransaction")
+	}
+
+	var r0 Transaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, CreateSweepTransactionParams) (Transaction, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, CreateSweepTransactionParams) Transaction); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Transaction)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, CreateSweepTransactionParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateTransaction provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) CreateTransaction(ctx context.Context, arg CreateTransactionParams) (Transaction, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateTransaction")
+	}
+
+	var r0 Transaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, CreateTransactionParams) (Transaction, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, CreateTransactionParams) Transaction); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Transaction)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, CreateTransactionParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateWebhookDeliveries provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) CreateWebhookDeliveries(ctx context.Context, arg CreateWebhookDeliveriesParams) error {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateWebhookDeliveries")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, CreateWebhookDeliveriesParams) error); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeactivateClient provides a mock function with given fields: ctx, id
+func (_m *MockQuerier) DeactivateClient(ctx context.Context, id uuid.UUID) (Client, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeactivateClient")
+	}
+
+	var r0 Client
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) (Client, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) Client); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(Client)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeferSweepCandidate provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) DeferSweepCandidate(ctx context.Context, arg DeferSweepCandidateParams) error {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeferSweepCandidate")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, DeferSweepCandidateParams) error); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeferWebhookDelivery provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) DeferWebhookDelivery(ctx context.Context, arg DeferWebhookDeliveryParams) error {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeferWebhookDelivery")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, DeferWebhookDeliveryParams) error); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteLogsBatch provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) DeleteLogsBatch(ctx context.Context, arg DeleteLogsBatchParams) (int64, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteLogsBatch")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, DeleteLogsBatchParams) (int64, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, DeleteLogsBatchParams) int64); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, DeleteLogsBatchParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FailIdempotencyKey provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) FailIdempotencyKey(ctx context.Context, arg FailIdempotencyKeyParams) error {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FailIdempotencyKey")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, FailIdempotencyKeyParams) error); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FailWebhookDelivery provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) FailWebhookDelivery(ctx context.Context, arg FailWebhookDeliveryParams) error {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FailWebhookDelivery")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, FailWebhookDeliveryParams) error); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetAccountByIDAndClientID provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) GetAccountByIDAndClientID(ctx context.Context, arg GetAccountByIDAndClientIDParams) (Account, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAccountByIDAndClientID")
+	}
+
+	var r0 Account
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, GetAccountByIDAndClientIDParams) (Account, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, GetAccountByIDAndClientIDParams) Account); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Account)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, GetAccountByIDAndClientIDParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAccountsByClientID provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) GetAccountsByClientID(ctx context.Context, arg GetAccountsByClientIDParams) ([]GetAccountsByClientIDRow, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAccountsByClientID")
+	}
+
+	var r0 []GetAccountsByClientIDRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, GetAccountsByClientIDParams) ([]GetAccountsByClientIDRow, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, GetAccountsByClientIDParams) []GetAccountsByClientIDRow); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]GetAccountsByClientIDRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, GetAccountsByClientIDParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetActiveWebhookEndpoint provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) GetActiveWebhookEndpoint(ctx context.Context, arg GetActiveWebhookEndpointParams) (WebhookEndpoint, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetActiveWebhookEndpoint")
+	}
+
+	var r0 WebhookEndpoint
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, GetActiveWebhookEndpointParams) (WebhookEndpoint, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, GetActiveWebhookEndpointParams) WebhookEndpoint); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(WebhookEndpoint)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, GetActiveWebhookEndpointParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetClientByAPIKey provides a mock function with given fields: ctx, apiKey
+func (_m *MockQuerier) GetClientByAPIKey(ctx context.Context, apiKey string) (Client, error) {
+	ret := _m.Called(ctx, apiKey)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetClientByAPIKey")
+	}
+
+	var r0 Client
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (Client, error)); ok {
+		return rf(ctx, apiKey)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) Client); ok {
+		r0 = rf(ctx, apiKey)
+	} else {
+		r0 = ret.Get(0).(Client)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, apiKey)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetClientByID provides a mock function with given fields: ctx, id
+func (_m *MockQuerier) GetClientByID(ctx context.Context, id uuid.UUID) (Client, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetClientByID")
+	}
+
+	var r0 Client
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) (Client, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) Client); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(Client)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDailyConfirmedVolume provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) GetDailyConfirmedVolume(ctx context.Context, arg GetDailyConfirmedVolumeParams) ([]GetDailyConfirmedVolumeRow, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDailyConfirmedVolume")
+	}
+
+	var r0 []GetDailyConfirmedVolumeRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, GetDailyConfirmedVolumeParams) ([]GetDailyConfirmedVolumeRow, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, GetDailyConfirmedVolumeParams) []GetDailyConfirmedVolumeRow); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]GetDailyConfirmedVolumeRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, GetDailyConfirmedVolumeParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetIdempotencyKey provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetIdempotencyKey")
+	}
+
+	var r0 IdempotencyKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, GetIdempotencyKeyParams) (IdempotencyKey, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, GetIdempotencyKeyParams) IdempotencyKey); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(IdempotencyKey)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, GetIdempotencyKeyParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetInvoiceByClientMonth provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) GetInvoiceByClientMonth(ctx context.Context, arg GetInvoiceByClientMonthParams) (Invoice, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetInvoiceByClientMonth")
+	}
+
+	var r0 Invoice
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, GetInvoiceByClientMonthParams) (Invoice, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, GetInvoiceByClientMonthParams) Invoice); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Invoice)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, GetInvoiceByClientMonthParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLatestReconciliationReport provides a mock function with given fields: ctx
+func (_m *MockQuerier) GetLatestReconciliationReport(ctx context.Context) (ReconciliationReport, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLatestReconciliationReport")
+	}
+
+	var r0 ReconciliationReport
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (ReconciliationReport, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) ReconciliationReport); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(ReconciliationReport)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetMonthlyFeeBasis provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) GetMonthlyFeeBasis(ctx context.Context, arg GetMonthlyFeeBasisParams) ([]GetMonthlyFeeBasisRow, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMonthlyFeeBasis")
+	}
+
+	var r0 []GetMonthlyFeeBasisRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, GetMonthlyFeeBasisParams) ([]GetMonthlyFeeBasisRow, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, GetMonthlyFeeBasisParams) []GetMonthlyFeeBasisRow); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]GetMonthlyFeeBasisRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, GetMonthlyFeeBasisParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOpenPaymentByWalletAndAmount provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) GetOpenPaymentByWalletAndAmount(ctx context.Context, arg GetOpenPaymentByWalletAndAmountParams) (Payment, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOpenPaymentByWalletAndAmount")
+	}
+
+	var r0 Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, GetOpenPaymentByWalletAndAmountParams) (Payment, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, GetOpenPaymentByWalletAndAmountParams) Payment); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Payment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, GetOpenPaymentByWalletAndAmountParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPayment provides a mock function with given fields: ctx, id
+func (_m *MockQuerier) GetPayment(ctx context.Context, id uuid.UUID) (Payment, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPayment")
+	}
+
+	var r0 Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) (Payment, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) Payment); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(Payment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPaymentByOrderReference provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) GetPaymentByOrderReference(ctx context.Context, arg GetPaymentByOrderReferenceParams) (Payment, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPaymentByOrderReference")
+	}
+
+	var r0 Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, GetPaymentByOrderReferenceParams) (Payment, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, GetPaymentByOrderReferenceParams) Payment); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Payment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, GetPaymentByOrderReferenceParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPaymentByUniqueWallet provides a mock function with given fields: ctx, uniqueWallet
+func (_m *MockQuerier) GetPaymentByUniqueWallet(ctx context.Context, uniqueWallet string) (Payment, error) {
+	ret := _m.Called(ctx, uniqueWallet)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPaymentByUniqueWallet")
+	}
+
+	var r0 Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (Payment, error)); ok {
+		return rf(ctx, uniqueWallet)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) Payment); ok {
+		r0 = rf(ctx, uniqueWallet)
+	} else {
+		r0 = ret.Get(0).(Payment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, uniqueWallet)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPaymentByWallet provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) GetPaymentByWallet(ctx context.Context, arg GetPaymentByWalletParams) (Payment, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPaymentByWallet")
+	}
+
+	var r0 Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, GetPaymentByWalletParams) (Payment, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, GetPaymentByWalletParams) Payment); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Payment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, GetPaymentByWalletParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPaymentFunnel provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) GetPaymentFunnel(ctx context.Context, arg GetPaymentFunnelParams) ([]GetPaymentFunnelRow, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPaymentFunnel")
+	}
+
+	var r0 []GetPaymentFunnelRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, GetPaymentFunnelParams) ([]GetPaymentFunnelRow, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, GetPaymentFunnelParams) []GetPaymentFunnelRow); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]GetPaymentFunnelRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, GetPaymentFunnelParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPendingPaymentByAnyWallet provides a mock function with given fields: ctx, wallet
+func (_m *MockQuerier) GetPendingPaymentByAnyWallet(ctx context.Context, wallet string) (Payment, error) {
+	ret := _m.Called(ctx, wallet)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPendingPaymentByAnyWallet")
+	}
+
+	var r0 Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (Payment, error)); ok {
+		return rf(ctx, wallet)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) Payment); ok {
+		r0 = rf(ctx, wallet)
+	} else {
+		r0 = ret.Get(0).(Payment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, wallet)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetReconciliationReport provides a mock function with given fields: ctx, id
+func (_m *MockQuerier) GetReconciliationReport(ctx context.Context, id uuid.UUID) (ReconciliationReport, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetReconciliationReport")
+	}
+
+	var r0 ReconciliationReport
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) (ReconciliationReport, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) ReconciliationReport); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(ReconciliationReport)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWalletFlows provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) GetWalletFlows(ctx context.Context, arg GetWalletFlowsParams) (GetWalletFlowsRow, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWalletFlows")
+	}
+
+	var r0 GetWalletFlowsRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, GetWalletFlowsParams) (GetWalletFlowsRow, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, GetWalletFlowsParams) GetWalletFlowsRow); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(GetWalletFlowsRow)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, GetWalletFlowsParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWatcherHeight provides a mock function with given fields: ctx, name
+func (_m *MockQuerier) GetWatcherHeight(ctx context.Context, name string) (int64, error) {
+	ret := _m.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWatcherHeight")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (int64, error)); ok {
+		return rf(ctx, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) int64); ok {
+		r0 = rf(ctx, name)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWatcherState provides a mock function with given fields: ctx, name
+func (_m *MockQuerier) GetWatcherState(ctx context.Context, name string) (GetWatcherStateRow, error) {
+	ret := _m.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWatcherState")
+	}
+
+	var r0 GetWatcherStateRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (GetWatcherStateRow, error)); ok {
+		return rf(ctx, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) GetWatcherStateRow); ok {
+		r0 = rf(ctx, name)
+	} else {
+		r0 = ret.Get(0).(GetWatcherStateRow)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWebhookDelivery provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) GetWebhookDelivery(ctx context.Context, arg GetWebhookDeliveryParams) (GetWebhookDeliveryRow, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWebhookDelivery")
+	}
+
+	var r0 GetWebhookDeliveryRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, GetWebhookDeliveryParams) (GetWebhookDeliveryRow, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, GetWebhookDeliveryParams) GetWebhookDeliveryRow); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(GetWebhookDeliveryRow)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, GetWebhookDeliveryParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAccountsBelowLowWater provides a mock function with given fields: ctx, lowWater
+func (_m *MockQuerier) ListAccountsBelowLowWater(ctx context.Context, lowWater int64) ([]ListAccountsBelowLowWaterRow, error) {
+	ret := _m.Called(ctx, lowWater)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAccountsBelowLowWater")
+	}
+
+	var r0 []ListAccountsBelowLowWaterRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]ListAccountsBelowLowWaterRow, error)); ok {
+		return rf(ctx, lowWater)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []ListAccountsBelowLowWaterRow); ok {
+		r0 = rf(ctx, lowWater)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ListAccountsBelowLowWaterRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, lowWater)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAttemptCountDrift provides a mock function with given fields: ctx
+func (_m *MockQuerier) ListAttemptCountDrift(ctx context.Context) ([]ListAttemptCountDriftRow, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAttemptCountDrift")
+	}
+
+	var r0 []ListAttemptCountDriftRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]ListAttemptCountDriftRow, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []ListAttemptCountDriftRow); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ListAttemptCountDriftRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListClientLogs provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ListClientLogs(ctx context.Context, arg ListClientLogsParams) ([]Log, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListClientLogs")
+	}
+
+	var r0 []Log
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ListClientLogsParams) ([]Log, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ListClientLogsParams) []Log); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Log)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ListClientLogsParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListClientPayments provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ListClientPayments(ctx context.Context, arg ListClientPaymentsParams) ([]Payment, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListClientPayments")
+	}
+
+	var r0 []Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ListClientPaymentsParams) ([]Payment, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ListClientPaymentsParams) []Payment); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Payment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ListClientPaymentsParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListClients provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ListClients(ctx context.Context, arg ListClientsParams) ([]Client, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListClients")
+	}
+
+	var r0 []Client
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ListClientsParams) ([]Client, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ListClientsParams) []Client); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Client)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ListClientsParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListConfirmedWithoutDeposit provides a mock function with given fields: ctx
+func (_m *MockQuerier) ListConfirmedWithoutDeposit(ctx context.Context) ([]ListConfirmedWithoutDepositRow, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListConfirmedWithoutDeposit")
+	}
+
+	var r0 []ListConfirmedWithoutDepositRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]ListConfirmedWithoutDepositRow, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []ListConfirmedWithoutDepositRow); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ListConfirmedWithoutDepositRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListDepositWallets provides a mock function with given fields: ctx, wallets
+func (_m *MockQuerier) ListDepositWallets(ctx context.Context, wallets []string) ([]string, error) {
+	ret := _m.Called(ctx, wallets)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDepositWallets")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]string, error)); ok {
+		return rf(ctx, wallets)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []string); ok {
+		r0 = rf(ctx, wallets)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, wallets)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListDetectedTransactions provides a mock function with given fields: ctx, mode
+func (_m *MockQuerier) ListDetectedTransactions(ctx context.Context, mode string) ([]Transaction, error) {
+	ret := _m.Called(ctx, mode)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDetectedTransactions")
+	}
+
+	var r0 []Transaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]Transaction, error)); ok {
+		return rf(ctx, mode)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []Transaction); ok {
+		r0 = rf(ctx, mode)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Transaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, mode)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListExpiredPayments provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ListExpiredPayments(ctx context.Context, arg ListExpiredPaymentsParams) ([]Payment, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListExpiredPayments")
+	}
+
+	var r0 []Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ListExpiredPaymentsParams) ([]Payment, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ListExpiredPaymentsParams) []Payment); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Payment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ListExpiredPaymentsParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListInvoiceLines provides a mock function with given fields: ctx, invoiceIds
+func (_m *MockQuerier) ListInvoiceLines(ctx context.Context, invoiceIds []uuid.UUID) ([]InvoiceLine, error) {
+	ret := _m.Called(ctx, invoiceIds)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListInvoiceLines")
+	}
+
+	var r0 []InvoiceLine
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) ([]InvoiceLine, error)); ok {
+		return rf(ctx, invoiceIds)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) []InvoiceLine); ok {
+		r0 = rf(ctx, invoiceIds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]InvoiceLine)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []uuid.UUID) error); ok {
+		r1 = rf(ctx, invoiceIds)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListInvoices provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ListInvoices(ctx context.Context, arg ListInvoicesParams) ([]Invoice, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListInvoices")
+	}
+
+	var r0 []Invoice
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ListInvoicesParams) ([]Invoice, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ListInvoicesParams) []Invoice); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Invoice)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ListInvoicesParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListLogEventTypes provides a mock function with given fields: ctx
+func (_m *MockQuerier) ListLogEventTypes(ctx context.Context) ([]string, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListLogEventTypes")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]string, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []string); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListLogs provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ListLogs(ctx context.Context, arg ListLogsParams) ([]Log, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListLogs")
+	}
+
+	var r0 []Log
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ListLogsParams) ([]Log, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ListLogsParams) []Log); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Log)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ListLogsParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListOpenSweeps provides a mock function with given fields: ctx
+func (_m *MockQuerier) ListOpenSweeps(ctx context.Context) ([]Sweep, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListOpenSweeps")
+	}
+
+	var r0 []Sweep
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]Sweep, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []Sweep); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Sweep)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPaymentAttempts provides a mock function with given fields: ctx, paymentIds
+func (_m *MockQuerier) ListPaymentAttempts(ctx context.Context, paymentIds []uuid.UUID) ([]PaymentAttempt, error) {
+	ret := _m.Called(ctx, paymentIds)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListPaymentAttempts")
+	}
+
+	var r0 []PaymentAttempt
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) ([]PaymentAttempt, error)); ok {
+		return rf(ctx, paymentIds)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) []PaymentAttempt); ok {
+		r0 = rf(ctx, paymentIds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]PaymentAttempt)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []uuid.UUID) error); ok {
+		r1 = rf(ctx, paymentIds)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPaymentExport provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ListPaymentExport(ctx context.Context, arg ListPaymentExportParams) ([]ListPaymentExportRow, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListPaymentExport")
+	}
+
+	var r0 []ListPaymentExportRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ListPaymentExportParams) ([]ListPaymentExportRow, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ListPaymentExportParams) []ListPaymentExportRow); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ListPaymentExportRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ListPaymentExportParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPaymentStatuses provides a mock function with given fields: ctx, ids
+func (_m *MockQuerier) ListPaymentStatuses(ctx context.Context, ids []uuid.UUID) ([]ListPaymentStatusesRow, error) {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListPaymentStatuses")
+	}
+
+	var r0 []ListPaymentStatusesRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) ([]ListPaymentStatusesRow, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) []ListPaymentStatusesRow); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ListPaymentStatusesRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []uuid.UUID) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPaymentTransactions provides a mock function with given fields: ctx, paymentID
+func (_m *MockQuerier) ListPaymentTransactions(ctx context.Context, paymentID uuid.UUID) ([]Transaction, error) {
+	ret := _m.Called(ctx, paymentID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListPaymentTransactions")
+	}
+
+	var r0 []Transaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) ([]Transaction, error)); ok {
+		return rf(ctx, paymentID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []Transaction); ok {
+		r0 = rf(ctx, paymentID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Transaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, paymentID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPayments provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ListPayments(ctx context.Context, arg ListPaymentsParams) ([]Payment, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListPayments")
+	}
+
+	var r0 []Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ListPaymentsParams) ([]Payment, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ListPaymentsParams) []Payment); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Payment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ListPaymentsParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListRecentAttemptWallets provides a mock function with given fields: ctx, limit
+func (_m *MockQuerier) ListRecentAttemptWallets(ctx context.Context, limit int32) ([]ListRecentAttemptWalletsRow, error) {
+	ret := _m.Called(ctx, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListRecentAttemptWallets")
+	}
+
+	var r0 []ListRecentAttemptWalletsRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int32) ([]ListRecentAttemptWalletsRow, error)); ok {
+		return rf(ctx, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int32) []ListRecentAttemptWalletsRow); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ListRecentAttemptWalletsRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int32) error); ok {
+		r1 = rf(ctx, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListRecentClientPayments provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ListRecentClientPayments(ctx context.Context, arg ListRecentClientPaymentsParams) ([]Payment, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListRecentClientPayments")
+	}
+
+	var r0 []Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ListRecentClientPaymentsParams) ([]Payment, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ListRecentClientPaymentsParams) []Payment); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Payment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ListRecentClientPaymentsParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListRecentPendingPayments provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ListRecentPendingPayments(ctx context.Context, arg ListRecentPendingPaymentsParams) ([]Payment, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListRecentPendingPayments")
+	}
+
+	var r0 []Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ListRecentPendingPaymentsParams) ([]Payment, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ListRecentPendingPaymentsParams) []Payment); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Payment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ListRecentPendingPaymentsParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListReconciliationMismatches provides a mock function with given fields: ctx, reportID
+func (_m *MockQuerier) ListReconciliationMismatches(ctx context.Context, reportID uuid.UUID) ([]ReconciliationMismatch, error) {
+	ret := _m.Called(ctx, reportID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListReconciliationMismatches")
+	}
+
+	var r0 []ReconciliationMismatch
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) ([]ReconciliationMismatch, error)); ok {
+		return rf(ctx, reportID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []ReconciliationMismatch); ok {
+		r0 = rf(ctx, reportID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ReconciliationMismatch)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, reportID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListReconciliationPayments provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ListReconciliationPayments(ctx context.Context, arg ListReconciliationPaymentsParams) ([]ListReconciliationPaymentsRow, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListReconciliationPayments")
+	}
+
+	var r0 []ListReconciliationPaymentsRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ListReconciliationPaymentsParams) ([]ListReconciliationPaymentsRow, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ListReconciliationPaymentsParams) []ListReconciliationPaymentsRow); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ListReconciliationPaymentsRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ListReconciliationPaymentsParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListSweepCandidates provides a mock function with given fields: ctx, limit
+func (_m *MockQuerier) ListSweepCandidates(ctx context.Context, limit int32) ([]ListSweepCandidatesRow, error) {
+	ret := _m.Called(ctx, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListSweepCandidates")
+	}
+
+	var r0 []ListSweepCandidatesRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int32) ([]ListSweepCandidatesRow, error)); ok {
+		return rf(ctx, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int32) []ListSweepCandidatesRow); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ListSweepCandidatesRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int32) error); ok {
+		r1 = rf(ctx, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListTransactionsByBlockHashes provides a mock function with given fields: ctx, blockHashes
+func (_m *MockQuerier) ListTransactionsByBlockHashes(ctx context.Context, blockHashes []string) ([]Transaction, error) {
+	ret := _m.Called(ctx, blockHashes)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListTransactionsByBlockHashes")
+	}
+
+	var r0 []Transaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]Transaction, error)); ok {
+		return rf(ctx, blockHashes)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []Transaction); ok {
+		r0 = rf(ctx, blockHashes)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Transaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, blockHashes)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListWalletDrift provides a mock function with given fields: ctx
+func (_m *MockQuerier) ListWalletDrift(ctx context.Context) ([]ListWalletDriftRow, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListWalletDrift")
+	}
+
+	var r0 []ListWalletDriftRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]ListWalletDriftRow, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []ListWalletDriftRow); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ListWalletDriftRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListWebhookDeliveries provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]ListWebhookDeliveriesRow, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListWebhookDeliveries")
+	}
+
+	var r0 []ListWebhookDeliveriesRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ListWebhookDeliveriesParams) ([]ListWebhookDeliveriesRow, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ListWebhookDeliveriesParams) []ListWebhookDeliveriesRow); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ListWebhookDeliveriesRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ListWebhookDeliveriesParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkOutboxEventsProcessed provides a mock function with given fields: ctx, ids
+func (_m *MockQuerier) MarkOutboxEventsProcessed(ctx context.Context, ids []uuid.UUID) error {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkOutboxEventsProcessed")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) error); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkWebhookDelivered provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) MarkWebhookDelivered(ctx context.Context, arg MarkWebhookDeliveredParams) error {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkWebhookDelivered")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, MarkWebhookDeliveredParams) error); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NextWalletIndex provides a mock function with given fields: ctx, name
+func (_m *MockQuerier) NextWalletIndex(ctx context.Context, name string) (int64, error) {
+	ret := _m.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NextWalletIndex")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (int64, error)); ok {
+		return rf(ctx, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) int64); ok {
+		r0 = rf(ctx, name)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PaymentExists provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) PaymentExists(ctx context.Context, arg PaymentExistsParams) (bool, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PaymentExists")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, PaymentExistsParams) (bool, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, PaymentExistsParams) bool); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, PaymentExistsParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReleaseIdempotencyKey provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ReleaseIdempotencyKey(ctx context.Context, arg ReleaseIdempotencyKeyParams) error {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReleaseIdempotencyKey")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, ReleaseIdempotencyKeyParams) error); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReleaseLease provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ReleaseLease(ctx context.Context, arg ReleaseLeaseParams) error {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReleaseLease")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, ReleaseLeaseParams) error); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RenewIdempotencyKey provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) RenewIdempotencyKey(ctx context.Context, arg RenewIdempotencyKeyParams) error {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RenewIdempotencyKey")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, RenewIdempotencyKeyParams) error); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RenewLease provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) RenewLease(ctx context.Context, arg RenewLeaseParams) (Lease, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RenewLease")
+	}
+
+	var r0 Lease
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, RenewLeaseParams) (Lease, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, RenewLeaseParams) Lease); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Lease)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, RenewLeaseParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReplaceAccount provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ReplaceAccount(ctx context.Context, arg ReplaceAccountParams) (Account, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReplaceAccount")
+	}
+
+	var r0 Account
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ReplaceAccountParams) (Account, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ReplaceAccountParams) Account); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Account)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ReplaceAccountParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReplayWebhookDelivery provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ReplayWebhookDelivery(ctx context.Context, arg ReplayWebhookDeliveryParams) (WebhookDelivery, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReplayWebhookDelivery")
+	}
+
+	var r0 WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ReplayWebhookDeliveryParams) (WebhookDelivery, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ReplayWebhookDeliveryParams) WebhookDelivery); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(WebhookDelivery)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ReplayWebhookDeliveryParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RescheduleWebhookDelivery provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) RescheduleWebhookDelivery(ctx context.Context, arg RescheduleWebhookDeliveryParams) error {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RescheduleWebhookDelivery")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, RescheduleWebhookDeliveryParams) error); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReserveAddress provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ReserveAddress(ctx context.Context, arg ReserveAddressParams) (AddressPool, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReserveAddress")
+	}
+
+	var r0 AddressPool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ReserveAddressParams) (AddressPool, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ReserveAddressParams) AddressPool); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(AddressPool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ReserveAddressParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReserveAmountSuffix provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ReserveAmountSuffix(ctx context.Context, arg ReserveAmountSuffixParams) (int32, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReserveAmountSuffix")
+	}
+
+	var r0 int32
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ReserveAmountSuffixParams) (int32, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ReserveAmountSuffixParams) int32); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(int32)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ReserveAmountSuffixParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ResetWatcherState provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) ResetWatcherState(ctx context.Context, arg ResetWatcherStateParams) error {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetWatcherState")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, ResetWatcherStateParams) error); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevertPaymentConfirmation provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) RevertPaymentConfirmation(ctx context.Context, arg RevertPaymentConfirmationParams) (Payment, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevertPaymentConfirmation")
+	}
+
+	var r0 Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, RevertPaymentConfirmationParams) (Payment, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, RevertPaymentConfirmationParams) Payment); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Payment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, RevertPaymentConfirmationParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RotateClientAPIKey provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) RotateClientAPIKey(ctx context.Context, arg RotateClientAPIKeyParams) (Client, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RotateClientAPIKey")
+	}
+
+	var r0 Client
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, RotateClientAPIKeyParams) (Client, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, RotateClientAPIKeyParams) Client); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Client)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, RotateClientAPIKeyParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RotateWebhookEndpointSecret provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) RotateWebhookEndpointSecret(ctx context.Context, arg RotateWebhookEndpointSecretParams) (WebhookEndpoint, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RotateWebhookEndpointSecret")
+	}
+
+	var r0 WebhookEndpoint
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, RotateWebhookEndpointSecretParams) (WebhookEndpoint, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, RotateWebhookEndpointSecretParams) WebhookEndpoint); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(WebhookEndpoint)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, RotateWebhookEndpointSecretParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SearchPaymentsByTxHash provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) SearchPaymentsByTxHash(ctx context.Context, arg SearchPaymentsByTxHashParams) ([]Payment, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchPaymentsByTxHash")
+	}
+
+	var r0 []Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, SearchPaymentsByTxHashParams) ([]Payment, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, SearchPaymentsByTxHashParams) []Payment); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Payment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, SearchPaymentsByTxHashParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SearchPaymentsByWallet provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) SearchPaymentsByWallet(ctx context.Context, arg SearchPaymentsByWalletParams) ([]Payment, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchPaymentsByWallet")
+	}
+
+	var r0 []Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, SearchPaymentsByWalletParams) ([]Payment, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, SearchPaymentsByWalletParams) []Payment); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Payment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, SearchPaymentsByWalletParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetAccountDepositWallet provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) SetAccountDepositWallet(ctx context.Context, arg SetAccountDepositWalletParams) (SetAccountDepositWalletRow, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetAccountDepositWallet")
+	}
+
+	var r0 SetAccountDepositWalletRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, SetAccountDepositWalletParams) (SetAccountDepositWalletRow, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, SetAccountDepositWalletParams) SetAccountDepositWalletRow); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(SetAccountDepositWalletRow)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, SetAccountDepositWalletParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SoftDeleteAccount provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) SoftDeleteAccount(ctx context.Context, arg SoftDeleteAccountParams) (Account, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SoftDeleteAccount")
+	}
+
+	var r0 Account
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, SoftDeleteAccountParams) (Account, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, SoftDeleteAccountParams) Account); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Account)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, SoftDeleteAccountParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SumPaymentTransfers provides a mock function with given fields: ctx, paymentID
+func (_m *MockQuerier) SumPaymentTransfers(ctx context.Context, paymentID uuid.UUID) (pgtype.Numeric, error) {
+	ret := _m.Called(ctx, paymentID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SumPaymentTransfers")
+	}
+
+	var r0 pgtype.Numeric
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) (pgtype.Numeric, error)); ok {
+		return rf(ctx, paymentID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) pgtype.Numeric); ok {
+		r0 = rf(ctx, paymentID)
+	} else {
+		r0 = ret.Get(0).(pgtype.Numeric)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, paymentID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SyncAttemptCount provides a mock function with given fields: ctx, id
+func (_m *MockQuerier) SyncAttemptCount(ctx context.Context, id uuid.UUID) (*int32, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SyncAttemptCount")
+	}
+
+	var r0 *int32
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) (*int32, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) *int32); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*int32)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SyncPaymentWallet provides a mock function with given fields: ctx, id
+func (_m *MockQuerier) SyncPaymentWallet(ctx context.Context, id uuid.UUID) (SyncPaymentWalletRow, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SyncPaymentWallet")
+	}
+
+	var r0 SyncPaymentWalletRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) (SyncPaymentWalletRow, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) SyncPaymentWalletRow); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(SyncPaymentWalletRow)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateAccount provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateAccount")
+	}
+
+	var r0 Account
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, UpdateAccountParams) (Account, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, UpdateAccountParams) Account); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Account)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, UpdateAccountParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdatePaymentStatus provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) UpdatePaymentStatus(ctx context.Context, arg UpdatePaymentStatusParams) (Payment, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdatePaymentStatus")
+	}
+
+	var r0 Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, UpdatePaymentStatusParams) (Payment, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, UpdatePaymentStatusParams) Payment); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Payment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, UpdatePaymentStatusParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdatePaymentWallet provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) UpdatePaymentWallet(ctx context.Context, arg UpdatePaymentWalletParams) (Payment, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdatePaymentWallet")
+	}
+
+	var r0 Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, UpdatePaymentWalletParams) (Payment, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, UpdatePaymentWalletParams) Payment); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Payment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, UpdatePaymentWalletParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateSweepStatus provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) UpdateSweepStatus(ctx context.Context, arg UpdateSweepStatusParams) (Sweep, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateSweepStatus")
+	}
+
+	var r0 Sweep
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, UpdateSweepStatusParams) (Sweep, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, UpdateSweepStatusParams) Sweep); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Sweep)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, UpdateSweepStatusParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateTransactionBlock provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) UpdateTransactionBlock(ctx context.Context, arg UpdateTransactionBlockParams) error {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateTransactionBlock")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, UpdateTransactionBlockParams) error); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateTransactionConfirmations provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) UpdateTransactionConfirmations(ctx context.Context, arg UpdateTransactionConfirmationsParams) error {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateTransactionConfirmations")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, UpdateTransactionConfirmationsParams) error); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpsertAccount provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) UpsertAccount(ctx context.Context, arg UpsertAccountParams) (Account, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpsertAccount")
+	}
+
+	var r0 Account
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, UpsertAccountParams) (Account, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, UpsertAccountParams) Account); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Account)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, UpsertAccountParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpsertClient provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) UpsertClient(ctx context.Context, arg UpsertClientParams) (Client, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpsertClient")
+	}
+
+	var r0 Client
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, UpsertClientParams) (Client, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, UpsertClientParams) Client); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Client)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, UpsertClientParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpsertLog provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) UpsertLog(ctx context.Context, arg UpsertLogParams) error {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpsertLog")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, UpsertLogParams) error); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpsertPayment provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) UpsertPayment(ctx context.Context, arg UpsertPaymentParams) (Payment, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpsertPayment")
+	}
+
+	var r0 Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, UpsertPaymentParams) (Payment, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, UpsertPaymentParams) Payment); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(Payment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, UpsertPaymentParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpsertPaymentAttempt provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) UpsertPaymentAttempt(ctx context.Context, arg UpsertPaymentAttemptParams) (PaymentAttempt, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpsertPaymentAttempt")
+	}
+
+	var r0 PaymentAttempt
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, UpsertPaymentAttemptParams) (PaymentAttempt, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, UpsertPaymentAttemptParams) PaymentAttempt); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(PaymentAttempt)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, UpsertPaymentAttemptParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpsertWatcherState provides a mock function with given fields: ctx, arg
+func (_m *MockQuerier) UpsertWatcherState(ctx context.Context, arg UpsertWatcherStateParams) (int64, error) {
+	ret := _m.Called(ctx, arg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpsertWatcherState")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, UpsertWatcherStateParams) (int64, error)); ok {
+		return rf(ctx, arg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, UpsertWatcherStateParams) int64); ok {
+		r0 = rf(ctx, arg)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, UpsertWatcherStateParams) error); ok {
+		r1 = rf(ctx, arg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// WipeData provides a mock function with given fields: ctx
+func (_m *MockQuerier) WipeData(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WipeData")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewMockQuerier creates a new instance of MockQuerier. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockQuerier(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockQuerier {
+	mock := &MockQuerier{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -10,11 +10,36 @@ import (
 )
 
 type Account struct {
-	ID           uuid.UUID          `db:"id" json:"id"`
-	ClientID     uuid.UUID          `db:"client_id" json:"client_id"`
-	Name         string             `db:"name" json:"name"`
-	AddressIndex *int32             `db:"address_index" json:"address_index"`
-	CreatedAt    pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	ID                       uuid.UUID          `db:"id" json:"id"`
+	ClientID                 uuid.UUID          `db:"client_id" json:"client_id"`
+	Name                     string             `db:"name" json:"name"`
+	AddressIndex             *int32             `db:"address_index" json:"address_index"`
+	CreatedAt                pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	DefaultExpirySeconds     *int64             `db:"default_expiry_seconds" json:"default_expiry_seconds"`
+	DefaultWebhookEndpointID *uuid.UUID         `db:"default_webhook_endpoint_id" json:"default_webhook_endpoint_id"`
+	Metadata                 []byte             `db:"metadata" json:"metadata"`
+	DeletedAt                pgtype.Timestamptz `db:"deleted_at" json:"deleted_at"`
+	AddressStrategy          string             `db:"address_strategy" json:"address_strategy"`
+	DepositWallet            *string            `db:"deposit_wallet" json:"deposit_wallet"`
+	DepositWalletIndex       *int64             `db:"deposit_wallet_index" json:"deposit_wallet_index"`
+}
+
+type AddressPool struct {
+	ID                uuid.UUID          `db:"id" json:"id"`
+	AccountID         uuid.UUID          `db:"account_id" json:"account_id"`
+	AddressIndex      int64              `db:"address_index" json:"address_index"`
+	Address           string             `db:"address" json:"address"`
+	ReservedByPayment *uuid.UUID         `db:"reserved_by_payment" json:"reserved_by_payment"`
+	CreatedAt         pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type AmountSuffixReservation struct {
+	ID        uuid.UUID          `db:"id" json:"id"`
+	AccountID uuid.UUID          `db:"account_id" json:"account_id"`
+	Suffix    int32              `db:"suffix" json:"suffix"`
+	PaymentID uuid.UUID          `db:"payment_id" json:"payment_id"`
+	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
 }
 
 type Client struct {
@@ -23,28 +48,96 @@ type Client struct {
 	ApiKey    string             `db:"api_key" json:"api_key"`
 	IsActive  *bool              `db:"is_active" json:"is_active"`
 	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	Mode      string             `db:"mode" json:"mode"`
+}
+
+type IdempotencyKey struct {
+	ID              uuid.UUID          `db:"id" json:"id"`
+	ClientID        uuid.UUID          `db:"client_id" json:"client_id"`
+	IdempotencyKey  string             `db:"idempotency_key" json:"idempotency_key"`
+	RequestHash     string             `db:"request_hash" json:"request_hash"`
+	Status          string             `db:"status" json:"status"`
+	ResponseStatus  *int32             `db:"response_status" json:"response_status"`
+	ResponseBody    []byte             `db:"response_body" json:"response_body"`
+	CompletedAt     pgtype.Timestamptz `db:"completed_at" json:"completed_at"`
+	CreatedAt       pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	ResponseHeaders []byte             `db:"response_headers" json:"response_headers"`
+	ClaimedAt       pgtype.Timestamptz `db:"claimed_at" json:"claimed_at"`
+}
+
+type Invoice struct {
+	ID        uuid.UUID          `db:"id" json:"id"`
+	ClientID  uuid.UUID          `db:"client_id" json:"client_id"`
+	Month     pgtype.Date        `db:"month" json:"month"`
+	Payments  int64              `db:"payments" json:"payments"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type InvoiceLine struct {
+	ID        uuid.UUID          `db:"id" json:"id"`
+	InvoiceID uuid.UUID          `db:"invoice_id" json:"invoice_id"`
+	Token     string             `db:"token" json:"token"`
+	Kind      string             `db:"kind" json:"kind"`
+	Payments  int64              `db:"payments" json:"payments"`
+	Amount    pgtype.Numeric     `db:"amount" json:"amount"`
+	Rate      pgtype.Numeric     `db:"rate" json:"rate"`
+	Fee       pgtype.Numeric     `db:"fee" json:"fee"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type Lease struct {
+	ID         uuid.UUID          `db:"id" json:"id"`
+	Name       string             `db:"name" json:"name"`
+	Holder     string             `db:"holder" json:"holder"`
+	Token      int64              `db:"token" json:"token"`
+	LeaseUntil pgtype.Timestamptz `db:"lease_until" json:"lease_until"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"created_at"`
 }
 
 type Log struct {
 	ID        uuid.UUID          `db:"id" json:"id"`
-	PaymentID pgtype.UUID        `db:"payment_id" json:"payment_id"`
+	PaymentID *uuid.UUID         `db:"payment_id" json:"payment_id"`
 	EventType string             `db:"event_type" json:"event_type"`
 	Message   *string            `db:"message" json:"message"`
 	RawData   []byte             `db:"raw_data" json:"raw_data"`
 	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	RequestID *string            `db:"request_id" json:"request_id"`
+	Actor     *string            `db:"actor" json:"actor"`
+}
+
+type Outbox struct {
+	ID          uuid.UUID          `db:"id" json:"id"`
+	EventType   string             `db:"event_type" json:"event_type"`
+	AggregateID uuid.UUID          `db:"aggregate_id" json:"aggregate_id"`
+	Payload     []byte             `db:"payload" json:"payload"`
+	RequestID   *string            `db:"request_id" json:"request_id"`
+	CreatedAt   pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	ProcessedAt pgtype.Timestamptz `db:"processed_at" json:"processed_at"`
+	TraceParent *string            `db:"trace_parent" json:"trace_parent"`
 }
 
 type Payment struct {
-	ID           uuid.UUID          `db:"id" json:"id"`
-	ClientID     uuid.UUID          `db:"client_id" json:"client_id"`
-	AccountID    uuid.UUID          `db:"account_id" json:"account_id"`
-	Amount       pgtype.Numeric     `db:"amount" json:"amount"`
-	UniqueWallet string             `db:"unique_wallet" json:"unique_wallet"`
-	Status       string             `db:"status" json:"status"`
-	ExpiresAt    pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
-	ConfirmedAt  pgtype.Timestamptz `db:"confirmed_at" json:"confirmed_at"`
-	AttemptCount *int32             `db:"attempt_count" json:"attempt_count"`
-	CreatedAt    pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	ID                uuid.UUID          `db:"id" json:"id"`
+	ClientID          uuid.UUID          `db:"client_id" json:"client_id"`
+	AccountID         uuid.UUID          `db:"account_id" json:"account_id"`
+	Amount            pgtype.Numeric     `db:"amount" json:"amount"`
+	UniqueWallet      string             `db:"unique_wallet" json:"unique_wallet"`
+	Status            string             `db:"status" json:"status"`
+	ExpiresAt         pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+	ConfirmedAt       pgtype.Timestamptz `db:"confirmed_at" json:"confirmed_at"`
+	AttemptCount      *int32             `db:"attempt_count" json:"attempt_count"`
+	CreatedAt         pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	WalletIndex       *int64             `db:"wallet_index" json:"wallet_index"`
+	FiatAmount        pgtype.Numeric     `db:"fiat_amount" json:"fiat_amount"`
+	FiatCurrency      *string            `db:"fiat_currency" json:"fiat_currency"`
+	ExchangeRate      pgtype.Numeric     `db:"exchange_rate" json:"exchange_rate"`
+	RateAt            pgtype.Timestamptz `db:"rate_at" json:"rate_at"`
+	Token             string             `db:"token" json:"token"`
+	Mode              string             `db:"mode" json:"mode"`
+	WebhookEndpointID *uuid.UUID         `db:"webhook_endpoint_id" json:"webhook_endpoint_id"`
+	OrderReference    *string            `db:"order_reference" json:"order_reference"`
+	Metadata          []byte             `db:"metadata" json:"metadata"`
+	Version           int32              `db:"version" json:"version"`
 }
 
 type PaymentAttempt struct {
@@ -53,4 +146,122 @@ type PaymentAttempt struct {
 	AttemptNumber   int32              `db:"attempt_number" json:"attempt_number"`
 	GeneratedWallet string             `db:"generated_wallet" json:"generated_wallet"`
 	GeneratedAt     pgtype.Timestamptz `db:"generated_at" json:"generated_at"`
+	WalletIndex     *int64             `db:"wallet_index" json:"wallet_index"`
+}
+
+type ReconciliationMismatch struct {
+	ID        uuid.UUID          `db:"id" json:"id"`
+	ReportID  uuid.UUID          `db:"report_id" json:"report_id"`
+	PaymentID uuid.UUID          `db:"payment_id" json:"payment_id"`
+	Kind      string             `db:"kind" json:"kind"`
+	Wallet    string             `db:"wallet" json:"wallet"`
+	Token     string             `db:"token" json:"token"`
+	TxHash    *string            `db:"tx_hash" json:"tx_hash"`
+	Expected  pgtype.Numeric     `db:"expected" json:"expected"`
+	Actual    pgtype.Numeric     `db:"actual" json:"actual"`
+	Detail    string             `db:"detail" json:"detail"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type ReconciliationReport struct {
+	ID              uuid.UUID          `db:"id" json:"id"`
+	WindowStart     pgtype.Timestamptz `db:"window_start" json:"window_start"`
+	WindowEnd       pgtype.Timestamptz `db:"window_end" json:"window_end"`
+	PaymentsChecked int32              `db:"payments_checked" json:"payments_checked"`
+	Mismatches      int32              `db:"mismatches" json:"mismatches"`
+	CreatedAt       pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type Sweep struct {
+	ID              uuid.UUID          `db:"id" json:"id"`
+	PaymentID       uuid.UUID          `db:"payment_id" json:"payment_id"`
+	Token           string             `db:"token" json:"token"`
+	ContractAddress *string            `db:"contract_address" json:"contract_address"`
+	FromAddress     string             `db:"from_address" json:"from_address"`
+	ToAddress       string             `db:"to_address" json:"to_address"`
+	Amount          pgtype.Numeric     `db:"amount" json:"amount"`
+	TxID            string             `db:"tx_id" json:"tx_id"`
+	SignedTx        []byte             `db:"signed_tx" json:"signed_tx"`
+	ExpiresAt       pgtype.Timestamptz `db:"expires_at" json:"expires_at"`
+	Status          string             `db:"status" json:"status"`
+	CreatedAt       pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+}
+
+type SweepCheck struct {
+	ID          uuid.UUID          `db:"id" json:"id"`
+	Address     string             `db:"address" json:"address"`
+	Token       string             `db:"token" json:"token"`
+	NextCheckAt pgtype.Timestamptz `db:"next_check_at" json:"next_check_at"`
+	CreatedAt   pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+}
+
+type Transaction struct {
+	ID              uuid.UUID          `db:"id" json:"id"`
+	PaymentID       uuid.UUID          `db:"payment_id" json:"payment_id"`
+	TxHash          string             `db:"tx_hash" json:"tx_hash"`
+	TransferIndex   int32              `db:"transfer_index" json:"transfer_index"`
+	Token           string             `db:"token" json:"token"`
+	ContractAddress *string            `db:"contract_address" json:"contract_address"`
+	FromAddress     string             `db:"from_address" json:"from_address"`
+	ToAddress       string             `db:"to_address" json:"to_address"`
+	Amount          pgtype.Numeric     `db:"amount" json:"amount"`
+	BlockNumber     int64              `db:"block_number" json:"block_number"`
+	BlockHash       string             `db:"block_hash" json:"block_hash"`
+	Confirmations   int32              `db:"confirmations" json:"confirmations"`
+	Status          string             `db:"status" json:"status"`
+	CreatedAt       pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	ExcessAmount    pgtype.Numeric     `db:"excess_amount" json:"excess_amount"`
+	Kind            string             `db:"kind" json:"kind"`
+}
+
+type WalletIndexCounter struct {
+	ID        uuid.UUID          `db:"id" json:"id"`
+	Name      string             `db:"name" json:"name"`
+	NextIndex int64              `db:"next_index" json:"next_index"`
+	UpdatedAt pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"created_at"`
+}
+
+type WatcherState struct {
+	ID           uuid.UUID          `db:"id" json:"id"`
+	Name         string             `db:"name" json:"name"`
+	LastHeight   int64              `db:"last_height" json:"last_height"`
+	UpdatedAt    pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	CreatedAt    pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	RecentBlocks []byte             `db:"recent_blocks" json:"recent_blocks"`
+}
+
+type WebhookDelivery struct {
+	ID                  uuid.UUID          `db:"id" json:"id"`
+	EndpointID          uuid.UUID          `db:"endpoint_id" json:"endpoint_id"`
+	PaymentID           *uuid.UUID         `db:"payment_id" json:"payment_id"`
+	EventType           string             `db:"event_type" json:"event_type"`
+	Payload             []byte             `db:"payload" json:"payload"`
+	Status              string             `db:"status" json:"status"`
+	Attempts            int32              `db:"attempts" json:"attempts"`
+	NextAttemptAt       pgtype.Timestamptz `db:"next_attempt_at" json:"next_attempt_at"`
+	LastStatusCode      *int32             `db:"last_status_code" json:"last_status_code"`
+	LastError           *string            `db:"last_error" json:"last_error"`
+	DeliveredAt         pgtype.Timestamptz `db:"delivered_at" json:"delivered_at"`
+	CreatedAt           pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	RequestID           *string            `db:"request_id" json:"request_id"`
+	LastResponseBody    *string            `db:"last_response_body" json:"last_response_body"`
+	ReplayOf            *uuid.UUID         `db:"replay_of" json:"replay_of"`
+	LastResponseHeaders []byte             `db:"last_response_headers" json:"last_response_headers"`
+	LastLatencyMs       *int32             `db:"last_latency_ms" json:"last_latency_ms"`
+	AttemptHistory      []byte             `db:"attempt_history" json:"attempt_history"`
+}
+
+type WebhookEndpoint struct {
+	ID              uuid.UUID          `db:"id" json:"id"`
+	ClientID        uuid.UUID          `db:"client_id" json:"client_id"`
+	Url             string             `db:"url" json:"url"`
+	Secret          string             `db:"secret" json:"secret"`
+	IsActive        bool               `db:"is_active" json:"is_active"`
+	CreatedAt       pgtype.Timestamptz `db:"created_at" json:"created_at"`
+	ApiVersion      string             `db:"api_version" json:"api_version"`
+	SecondarySecret *string            `db:"secondary_secret" json:"secondary_secret"`
+	RotatedAt       pgtype.Timestamptz `db:"rotated_at" json:"rotated_at"`
 }
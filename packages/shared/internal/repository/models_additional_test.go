@@ -185,7 +185,7 @@ func TestPaymentAttempt_Sequence(t *testing.T) {
 
 func TestLog_AllFields(t *testing.T) {
 	id := uuid.New()
-	paymentID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	paymentID := UUIDPtr(uuid.New())
 	message := "Transaction confirmed"
 
 	log := Log{
@@ -198,7 +198,7 @@ func TestLog_AllFields(t *testing.T) {
 	}
 
 	assert.Equal(t, id, log.ID)
-	assert.True(t, log.PaymentID.Valid)
+	assert.True(t, log.PaymentID != nil)
 	assert.Equal(t, "TX_CONFIRMED", log.EventType)
 	assert.NotNil(t, log.Message)
 	assert.Equal(t, "Transaction confirmed", *log.Message)
@@ -233,11 +233,11 @@ func TestLog_EventTypeVariations(t *testing.T) {
 func TestLog_OptionalPaymentID(t *testing.T) {
 	log := Log{
 		ID:        uuid.New(),
-		PaymentID: pgtype.UUID{Valid: false},
+		PaymentID: nil,
 		EventType: "SYSTEM_EVENT",
 	}
 
-	assert.False(t, log.PaymentID.Valid)
+	assert.False(t, log.PaymentID != nil)
 }
 
 func TestLog_JSONDataValidation(t *testing.T) {
@@ -305,20 +305,20 @@ func TestModels_PaymentToLogsRelationship(t *testing.T) {
 	logs := []Log{
 		{
 			ID:        uuid.New(),
-			PaymentID: pgtype.UUID{Bytes: paymentID, Valid: true},
+			PaymentID: UUIDPtr(paymentID),
 			EventType: "ADDRESS_GENERATED",
 		},
 		{
 			ID:        uuid.New(),
-			PaymentID: pgtype.UUID{Bytes: paymentID, Valid: true},
+			PaymentID: UUIDPtr(paymentID),
 			EventType: "TX_CONFIRMED",
 		},
 	}
 
 	// Verify relationship
 	for _, log := range logs {
-		assert.True(t, log.PaymentID.Valid)
-		assert.Equal(t, payment.ID, log.PaymentID.Bytes)
+		assert.True(t, log.PaymentID != nil)
+		assert.Equal(t, payment.ID, uuid.UUID(*log.PaymentID))
 	}
 }
 
@@ -2,7 +2,9 @@ package repository
 
 import (
 	"encoding/json"
+	"math/big"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -15,6 +17,7 @@ import (
 func TestAccount_WithAddressIndex(t *testing.T) {
 	id := uuid.New()
 	clientID := uuid.New()
+	now := time.Now()
 	addressIndex := int32(42)
 
 	account := Account{
@@ -22,7 +25,7 @@ func TestAccount_WithAddressIndex(t *testing.T) {
 		ClientID:     clientID,
 		Name:         "Test Account",
 		AddressIndex: &addressIndex,
-		CreatedAt:    pgtype.Timestamptz{},
+		CreatedAt:    pgtype.Timestamptz{Time: now, Valid: true},
 	}
 
 	assert.Equal(t, id, account.ID)
@@ -30,6 +33,8 @@ func TestAccount_WithAddressIndex(t *testing.T) {
 	assert.Equal(t, "Test Account", account.Name)
 	assert.NotNil(t, account.AddressIndex)
 	assert.Equal(t, int32(42), *account.AddressIndex)
+	assert.True(t, account.CreatedAt.Valid)
+	assert.Equal(t, now, account.CreatedAt.Time)
 }
 
 func TestAccount_NilAddressIndex(t *testing.T) {
@@ -60,6 +65,9 @@ func TestAccount_JSONSerializationWithAddressIndex(t *testing.T) {
 	var decoded Account
 	err = json.Unmarshal(jsonData, &decoded)
 	require.NoError(t, err)
+	assert.Equal(t, account.ID, decoded.ID)
+	assert.Equal(t, account.ClientID, decoded.ClientID)
+	assert.Equal(t, account.Name, decoded.Name)
 	assert.NotNil(t, decoded.AddressIndex)
 	assert.Equal(t, int32(100), *decoded.AddressIndex)
 }
@@ -109,26 +117,30 @@ func TestPayment_Struct(t *testing.T) {
 	id := uuid.New()
 	clientID := uuid.New()
 	accountID := uuid.New()
+	now := time.Now()
 	attemptCount := int32(0)
 
 	payment := Payment{
 		ID:           id,
 		ClientID:     clientID,
 		AccountID:    accountID,
-		Amount:       pgtype.Numeric{},
+		Amount:       pgtype.Numeric{Int: big.NewInt(100000), Exp: -2, Valid: true},
 		UniqueWallet: "TXXmULCEzRo6JfxUP1LYfmzbiKUezUvUNj",
 		Status:       "PENDING",
-		ExpiresAt:    pgtype.Timestamptz{},
+		ExpiresAt:    pgtype.Timestamptz{Time: now.Add(time.Hour), Valid: true},
 		ConfirmedAt:  pgtype.Timestamptz{},
 		AttemptCount: &attemptCount,
-		CreatedAt:    pgtype.Timestamptz{},
+		CreatedAt:    pgtype.Timestamptz{Time: now, Valid: true},
 	}
 
 	assert.Equal(t, id, payment.ID)
 	assert.Equal(t, clientID, payment.ClientID)
 	assert.Equal(t, accountID, payment.AccountID)
+	assert.True(t, payment.Amount.Valid)
 	assert.Equal(t, "TXXmULCEzRo6JfxUP1LYfmzbiKUezUvUNj", payment.UniqueWallet)
 	assert.Equal(t, "PENDING", payment.Status)
+	assert.True(t, payment.ExpiresAt.Valid)
+	assert.False(t, payment.ConfirmedAt.Valid)
 	assert.NotNil(t, payment.AttemptCount)
 	assert.Equal(t, int32(0), *payment.AttemptCount)
 }
@@ -152,6 +164,9 @@ func TestPayment_JSONSerialization(t *testing.T) {
 	var decoded Payment
 	err = json.Unmarshal(jsonData, &decoded)
 	require.NoError(t, err)
+	assert.Equal(t, payment.ID, decoded.ID)
+	assert.Equal(t, payment.ClientID, decoded.ClientID)
+	assert.Equal(t, payment.AccountID, decoded.AccountID)
 	assert.Equal(t, "TTest123", decoded.UniqueWallet)
 	assert.Equal(t, "CONFIRMED", decoded.Status)
 	assert.NotNil(t, decoded.AttemptCount)
@@ -231,19 +246,22 @@ func TestPayment_ZeroAttemptCount(t *testing.T) {
 func TestPaymentAttempt_Struct(t *testing.T) {
 	id := uuid.New()
 	paymentID := uuid.New()
+	now := time.Now()
 
 	attempt := PaymentAttempt{
 		ID:              id,
 		PaymentID:       paymentID,
 		AttemptNumber:   1,
 		GeneratedWallet: "TGeneratedAddress123",
-		GeneratedAt:     pgtype.Timestamptz{},
+		GeneratedAt:     pgtype.Timestamptz{Time: now, Valid: true},
 	}
 
 	assert.Equal(t, id, attempt.ID)
 	assert.Equal(t, paymentID, attempt.PaymentID)
 	assert.Equal(t, int32(1), attempt.AttemptNumber)
 	assert.Equal(t, "TGeneratedAddress123", attempt.GeneratedWallet)
+	assert.True(t, attempt.GeneratedAt.Valid)
+	assert.Equal(t, now, attempt.GeneratedAt.Time)
 }
 
 func TestPaymentAttempt_JSONSerialization(t *testing.T) {
@@ -263,6 +281,8 @@ func TestPaymentAttempt_JSONSerialization(t *testing.T) {
 	var decoded PaymentAttempt
 	err = json.Unmarshal(jsonData, &decoded)
 	require.NoError(t, err)
+	assert.Equal(t, attempt.ID, decoded.ID)
+	assert.Equal(t, attempt.PaymentID, decoded.PaymentID)
 	assert.Equal(t, int32(2), decoded.AttemptNumber)
 	assert.Equal(t, "TWalletAddress", decoded.GeneratedWallet)
 }
@@ -296,6 +316,9 @@ func TestPaymentAttempt_MultipleAttempts(t *testing.T) {
 		assert.Equal(t, int32(i+1), attempt.AttemptNumber)
 		assert.NotEmpty(t, attempt.GeneratedWallet)
 	}
+	assert.NotEqual(t, attempts[0].ID, attempts[1].ID)
+	assert.NotEqual(t, attempts[0].GeneratedWallet, attempts[1].GeneratedWallet)
+	assert.NotEqual(t, attempts[1].GeneratedWallet, attempts[2].GeneratedWallet)
 }
 
 func TestPaymentAttempt_ZeroAttemptNumber(t *testing.T) {
@@ -324,28 +347,31 @@ func TestPaymentAttempt_NegativeAttemptNumber(t *testing.T) {
 
 func TestLog_Struct(t *testing.T) {
 	id := uuid.New()
-	paymentID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	paymentID := UUIDPtr(uuid.New())
 	message := "Transaction confirmed"
+	rawData := []byte(`{"tx_id": "abc123"}`)
 
 	log := Log{
 		ID:        id,
 		PaymentID: paymentID,
 		EventType: "TX_CONFIRMED",
 		Message:   &message,
-		RawData:   []byte(`{"tx_id": "abc123"}`),
-		CreatedAt: pgtype.Timestamptz{},
+		RawData:   rawData,
+		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
 	}
 
 	assert.Equal(t, id, log.ID)
-	assert.True(t, log.PaymentID.Valid)
+	require.NotNil(t, log.PaymentID)
+	assert.Equal(t, *paymentID, *log.PaymentID)
 	assert.Equal(t, "TX_CONFIRMED", log.EventType)
 	assert.NotNil(t, log.Message)
 	assert.Equal(t, "Transaction confirmed", *log.Message)
-	assert.NotEmpty(t, log.RawData)
+	assert.Equal(t, rawData, log.RawData)
+	assert.True(t, log.CreatedAt.Valid)
 }
 
 func TestLog_JSONSerialization(t *testing.T) {
-	paymentID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	paymentID := UUIDPtr(uuid.New())
 	message := "Webhook sent"
 
 	log := Log{
@@ -364,6 +390,7 @@ func TestLog_JSONSerialization(t *testing.T) {
 	var decoded Log
 	err = json.Unmarshal(jsonData, &decoded)
 	require.NoError(t, err)
+	assert.Equal(t, log.ID, decoded.ID)
 	assert.Equal(t, "WEBHOOK_SENT", decoded.EventType)
 }
 
@@ -415,11 +442,11 @@ func TestLog_EmptyMessage(t *testing.T) {
 func TestLog_NilPaymentID(t *testing.T) {
 	log := Log{
 		ID:        uuid.New(),
-		PaymentID: pgtype.UUID{Valid: false},
+		PaymentID: nil,
 		EventType: "SYSTEM_EVENT",
 	}
 
-	assert.False(t, log.PaymentID.Valid)
+	assert.False(t, log.PaymentID != nil)
 }
 
 func TestLog_RawDataJSONFormat(t *testing.T) {
@@ -480,6 +507,22 @@ func TestLog_LargeRawData(t *testing.T) {
 	assert.True(t, len(log.RawData) > 1000)
 }
 
+func TestLog_BinaryRawData(t *testing.T) {
+	rawData := make([]byte, 10000)
+	for i := range rawData {
+		rawData[i] = byte(i % 256)
+	}
+
+	log := Log{
+		ID:        uuid.New(),
+		EventType: "BINARY_DATA_EVENT",
+		RawData:   rawData,
+	}
+
+	assert.Equal(t, rawData, log.RawData)
+	assert.Len(t, log.RawData, 10000)
+}
+
 // Integration tests for model relationships
 
 func TestPaymentWithAttemptsRelationship(t *testing.T) {
@@ -528,20 +571,20 @@ func TestPaymentWithLogsRelationship(t *testing.T) {
 	logs := []Log{
 		{
 			ID:        uuid.New(),
-			PaymentID: pgtype.UUID{Bytes: paymentID, Valid: true},
+			PaymentID: UUIDPtr(paymentID),
 			EventType: "ADDRESS_GENERATED",
 		},
 		{
 			ID:        uuid.New(),
-			PaymentID: pgtype.UUID{Bytes: paymentID, Valid: true},
+			PaymentID: UUIDPtr(paymentID),
 			EventType: "TX_CONFIRMED",
 		},
 	}
 
 	// Verify relationship
 	for _, log := range logs {
-		assert.True(t, log.PaymentID.Valid)
-		assert.Equal(t, payment.ID, log.PaymentID.Bytes)
+		assert.True(t, log.PaymentID != nil)
+		assert.Equal(t, payment.ID, uuid.UUID(*log.PaymentID))
 	}
 }
 
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"encoding/json"
+	"math/big"
 	"testing"
 	"time"
 
@@ -396,29 +397,6 @@ func TestSQLNullTypes(t *testing.T) {
 	assert.True(t, *trueBool)
 }
 
-// Tests for Account.AddressIndex field (new field added)
-func TestAccount_WithAddressIndex(t *testing.T) {
-	id := uuid.New()
-	clientID := uuid.New()
-	now := time.Now()
-	addressIndex := int32(5)
-
-	account := Account{
-		ID:           id,
-		ClientID:     clientID,
-		Name:         "Test Account",
-		AddressIndex: &addressIndex,
-		CreatedAt:    pgtype.Timestamptz{Time: now, Valid: true},
-	}
-
-	assert.Equal(t, id, account.ID)
-	assert.Equal(t, clientID, account.ClientID)
-	assert.Equal(t, "Test Account", account.Name)
-	assert.NotNil(t, account.AddressIndex)
-	assert.Equal(t, int32(5), *account.AddressIndex)
-	assert.True(t, account.CreatedAt.Valid)
-}
-
 func TestAccount_NullAddressIndex(t *testing.T) {
 	account := Account{
 		ID:           uuid.New(),
@@ -445,95 +423,11 @@ func TestAccount_AddressIndexZero(t *testing.T) {
 	assert.Equal(t, int32(0), *account.AddressIndex)
 }
 
-func TestAccount_NegativeAddressIndex(t *testing.T) {
-	addressIndex := int32(-1)
-	account := Account{
-		ID:           uuid.New(),
-		ClientID:     uuid.New(),
-		Name:         "Test Account",
-		AddressIndex: &addressIndex,
-		CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
-	}
-
-	assert.NotNil(t, account.AddressIndex)
-	assert.Equal(t, int32(-1), *account.AddressIndex)
-}
-
-func TestAccount_LargeAddressIndex(t *testing.T) {
-	addressIndex := int32(2147483647) // max int32
-	account := Account{
-		ID:           uuid.New(),
-		ClientID:     uuid.New(),
-		Name:         "Test Account",
-		AddressIndex: &addressIndex,
-		CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
-	}
-
-	assert.NotNil(t, account.AddressIndex)
-	assert.Equal(t, int32(2147483647), *account.AddressIndex)
-}
-
-func TestAccount_JSONSerializationWithAddressIndex(t *testing.T) {
-	id := uuid.New()
-	clientID := uuid.New()
-	now := time.Now()
-	addressIndex := int32(10)
-
-	account := Account{
-		ID:           id,
-		ClientID:     clientID,
-		Name:         "Test Account",
-		AddressIndex: &addressIndex,
-		CreatedAt:    pgtype.Timestamptz{Time: now, Valid: true},
-	}
-
-	jsonData, err := json.Marshal(account)
-	require.NoError(t, err)
-	assert.NotEmpty(t, jsonData)
-
-	var decoded Account
-	err = json.Unmarshal(jsonData, &decoded)
-	require.NoError(t, err)
-
-	assert.Equal(t, account.ID, decoded.ID)
-	assert.Equal(t, account.ClientID, decoded.ClientID)
-	assert.Equal(t, account.Name, decoded.Name)
-	assert.NotNil(t, decoded.AddressIndex)
-	assert.Equal(t, int32(10), *decoded.AddressIndex)
-}
-
-// Tests for Log model
-func TestLog_Struct(t *testing.T) {
-	id := uuid.New()
-	paymentID := uuid.New()
-	now := time.Now()
-	message := "Payment initiated"
-	rawData := []byte(`{"transaction": "tx123"}`)
-
-	log := Log{
-		ID:        id,
-		PaymentID: pgtype.UUID{Bytes: paymentID, Valid: true},
-		EventType: "payment.initiated",
-		Message:   &message,
-		RawData:   rawData,
-		CreatedAt: pgtype.Timestamptz{Time: now, Valid: true},
-	}
-
-	assert.Equal(t, id, log.ID)
-	assert.True(t, log.PaymentID.Valid)
-	assert.Equal(t, paymentID, log.PaymentID.Bytes)
-	assert.Equal(t, "payment.initiated", log.EventType)
-	assert.NotNil(t, log.Message)
-	assert.Equal(t, "Payment initiated", *log.Message)
-	assert.Equal(t, rawData, log.RawData)
-	assert.True(t, log.CreatedAt.Valid)
-}
-
 func TestLog_ZeroValues(t *testing.T) {
 	var log Log
 
 	assert.Equal(t, uuid.Nil, log.ID)
-	assert.False(t, log.PaymentID.Valid)
+	assert.False(t, log.PaymentID != nil)
 	assert.Equal(t, "", log.EventType)
 	assert.Nil(t, log.Message)
 	assert.Nil(t, log.RawData)
@@ -543,20 +437,20 @@ func TestLog_ZeroValues(t *testing.T) {
 func TestLog_NullPaymentID(t *testing.T) {
 	log := Log{
 		ID:        uuid.New(),
-		PaymentID: pgtype.UUID{Valid: false},
+		PaymentID: nil,
 		EventType: "system.event",
 		Message:   stringPtr("System message"),
 		RawData:   []byte(`{}`),
 		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
 	}
 
-	assert.False(t, log.PaymentID.Valid)
+	assert.False(t, log.PaymentID != nil)
 }
 
 func TestLog_NullMessage(t *testing.T) {
 	log := Log{
 		ID:        uuid.New(),
-		PaymentID: pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		PaymentID: UUIDPtr(uuid.New()),
 		EventType: "payment.completed",
 		Message:   nil,
 		RawData:   []byte(`{}`),
@@ -566,61 +460,6 @@ func TestLog_NullMessage(t *testing.T) {
 	assert.Nil(t, log.Message)
 }
 
-func TestLog_EmptyRawData(t *testing.T) {
-	log := Log{
-		ID:        uuid.New(),
-		PaymentID: pgtype.UUID{Bytes: uuid.New(), Valid: true},
-		EventType: "payment.failed",
-		Message:   stringPtr("Payment failed"),
-		RawData:   []byte{},
-		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
-	}
-
-	assert.NotNil(t, log.RawData)
-	assert.Empty(t, log.RawData)
-}
-
-func TestLog_NilRawData(t *testing.T) {
-	log := Log{
-		ID:        uuid.New(),
-		PaymentID: pgtype.UUID{Bytes: uuid.New(), Valid: true},
-		EventType: "payment.pending",
-		Message:   stringPtr("Awaiting confirmation"),
-		RawData:   nil,
-		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
-	}
-
-	assert.Nil(t, log.RawData)
-}
-
-func TestLog_JSONSerialization(t *testing.T) {
-	id := uuid.New()
-	paymentID := uuid.New()
-	now := time.Now()
-	message := "Test message"
-	rawData := []byte(`{"key": "value"}`)
-
-	log := Log{
-		ID:        id,
-		PaymentID: pgtype.UUID{Bytes: paymentID, Valid: true},
-		EventType: "test.event",
-		Message:   &message,
-		RawData:   rawData,
-		CreatedAt: pgtype.Timestamptz{Time: now, Valid: true},
-	}
-
-	jsonData, err := json.Marshal(log)
-	require.NoError(t, err)
-	assert.NotEmpty(t, jsonData)
-
-	var decoded Log
-	err = json.Unmarshal(jsonData, &decoded)
-	require.NoError(t, err)
-
-	assert.Equal(t, log.ID, decoded.ID)
-	assert.Equal(t, log.EventType, decoded.EventType)
-}
-
 func TestLog_DifferentEventTypes(t *testing.T) {
 	eventTypes := []string{
 		"payment.created",
@@ -636,7 +475,7 @@ func TestLog_DifferentEventTypes(t *testing.T) {
 	for _, eventType := range eventTypes {
 		log := Log{
 			ID:        uuid.New(),
-			PaymentID: pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			PaymentID: UUIDPtr(uuid.New()),
 			EventType: eventType,
 			Message:   stringPtr("Test"),
 			RawData:   []byte(`{}`),
@@ -647,58 +486,6 @@ func TestLog_DifferentEventTypes(t *testing.T) {
 	}
 }
 
-func TestLog_LargeRawData(t *testing.T) {
-	largeData := make([]byte, 10000)
-	for i := range largeData {
-		largeData[i] = byte(i % 256)
-	}
-
-	log := Log{
-		ID:        uuid.New(),
-		PaymentID: pgtype.UUID{Bytes: uuid.New(), Valid: true},
-		EventType: "test.large",
-		Message:   stringPtr("Large data test"),
-		RawData:   largeData,
-		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
-	}
-
-	assert.Equal(t, largeData, log.RawData)
-	assert.Len(t, log.RawData, 10000)
-}
-
-// Tests for Payment model
-func TestPayment_Struct(t *testing.T) {
-	id := uuid.New()
-	clientID := uuid.New()
-	accountID := uuid.New()
-	now := time.Now()
-	attemptCount := int32(1)
-
-	payment := Payment{
-		ID:           id,
-		ClientID:     clientID,
-		AccountID:    accountID,
-		Amount:       pgtype.Numeric{Int: big.NewInt(100000), Exp: -2, Valid: true},
-		UniqueWallet: "TWallet123",
-		Status:       "pending",
-		ExpiresAt:    pgtype.Timestamptz{Time: now.Add(1 * time.Hour), Valid: true},
-		ConfirmedAt:  pgtype.Timestamptz{Valid: false},
-		AttemptCount: &attemptCount,
-		CreatedAt:    pgtype.Timestamptz{Time: now, Valid: true},
-	}
-
-	assert.Equal(t, id, payment.ID)
-	assert.Equal(t, clientID, payment.ClientID)
-	assert.Equal(t, accountID, payment.AccountID)
-	assert.True(t, payment.Amount.Valid)
-	assert.Equal(t, "TWallet123", payment.UniqueWallet)
-	assert.Equal(t, "pending", payment.Status)
-	assert.True(t, payment.ExpiresAt.Valid)
-	assert.False(t, payment.ConfirmedAt.Valid)
-	assert.NotNil(t, payment.AttemptCount)
-	assert.Equal(t, int32(1), *payment.AttemptCount)
-}
-
 func TestPayment_ZeroValues(t *testing.T) {
 	var payment Payment
 
@@ -758,25 +545,6 @@ func TestPayment_NullAttemptCount(t *testing.T) {
 	assert.Nil(t, payment.AttemptCount)
 }
 
-func TestPayment_ZeroAttemptCount(t *testing.T) {
-	attemptCount := int32(0)
-	payment := Payment{
-		ID:           uuid.New(),
-		ClientID:     uuid.New(),
-		AccountID:    uuid.New(),
-		Amount:       pgtype.Numeric{Int: big.NewInt(100), Exp: 0, Valid: true},
-		UniqueWallet: "TWallet",
-		Status:       "pending",
-		ExpiresAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
-		ConfirmedAt:  pgtype.Timestamptz{Valid: false},
-		AttemptCount: &attemptCount,
-		CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
-	}
-
-	assert.NotNil(t, payment.AttemptCount)
-	assert.Equal(t, int32(0), *payment.AttemptCount)
-}
-
 func TestPayment_HighAttemptCount(t *testing.T) {
 	attemptCount := int32(99)
 	payment := Payment{
@@ -834,41 +602,6 @@ func TestPayment_ExpiredPayment(t *testing.T) {
 	assert.False(t, payment.ConfirmedAt.Valid)
 }
 
-func TestPayment_JSONSerialization(t *testing.T) {
-	id := uuid.New()
-	clientID := uuid.New()
-	accountID := uuid.New()
-	now := time.Now()
-	attemptCount := int32(2)
-
-	payment := Payment{
-		ID:           id,
-		ClientID:     clientID,
-		AccountID:    accountID,
-		Amount:       pgtype.Numeric{Int: big.NewInt(100000), Exp: -2, Valid: true},
-		UniqueWallet: "TWallet123",
-		Status:       "pending",
-		ExpiresAt:    pgtype.Timestamptz{Time: now.Add(1 * time.Hour), Valid: true},
-		ConfirmedAt:  pgtype.Timestamptz{Valid: false},
-		AttemptCount: &attemptCount,
-		CreatedAt:    pgtype.Timestamptz{Time: now, Valid: true},
-	}
-
-	jsonData, err := json.Marshal(payment)
-	require.NoError(t, err)
-	assert.NotEmpty(t, jsonData)
-
-	var decoded Payment
-	err = json.Unmarshal(jsonData, &decoded)
-	require.NoError(t, err)
-
-	assert.Equal(t, payment.ID, decoded.ID)
-	assert.Equal(t, payment.ClientID, decoded.ClientID)
-	assert.Equal(t, payment.AccountID, decoded.AccountID)
-	assert.Equal(t, payment.UniqueWallet, decoded.UniqueWallet)
-	assert.Equal(t, payment.Status, decoded.Status)
-}
-
 func TestPayment_DifferentAmounts(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -921,28 +654,6 @@ func TestPayment_EmptyUniqueWallet(t *testing.T) {
 	assert.Equal(t, "", payment.UniqueWallet)
 }
 
-// Tests for PaymentAttempt model
-func TestPaymentAttempt_Struct(t *testing.T) {
-	id := uuid.New()
-	paymentID := uuid.New()
-	now := time.Now()
-
-	attempt := PaymentAttempt{
-		ID:              id,
-		PaymentID:       paymentID,
-		AttemptNumber:   1,
-		GeneratedWallet: "TWallet123",
-		GeneratedAt:     pgtype.Timestamptz{Time: now, Valid: true},
-	}
-
-	assert.Equal(t, id, attempt.ID)
-	assert.Equal(t, paymentID, attempt.PaymentID)
-	assert.Equal(t, int32(1), attempt.AttemptNumber)
-	assert.Equal(t, "TWallet123", attempt.GeneratedWallet)
-	assert.True(t, attempt.GeneratedAt.Valid)
-	assert.Equal(t, now, attempt.GeneratedAt.Time)
-}
-
 func TestPaymentAttempt_ZeroValues(t *testing.T) {
 	var attempt PaymentAttempt
 
@@ -965,50 +676,6 @@ func TestPaymentAttempt_FirstAttempt(t *testing.T) {
 	assert.Equal(t, int32(1), attempt.AttemptNumber)
 }
 
-func TestPaymentAttempt_MultipleAttempts(t *testing.T) {
-	paymentID := uuid.New()
-	baseTime := time.Now()
-
-	attempts := []PaymentAttempt{
-		{
-			ID:              uuid.New(),
-			PaymentID:       paymentID,
-			AttemptNumber:   1,
-			GeneratedWallet: "TWallet1",
-			GeneratedAt:     pgtype.Timestamptz{Time: time.Now(), Valid: true},
-		},
-		{
-			ID:              uuid.New(),
-			PaymentID:       paymentID,
-			AttemptNumber:   2,
-			GeneratedWallet: "TWallet2",
-			GeneratedAt:     pgtype.Timestamptz{Time: time.Now(), Valid: true},
-		},
-		{
-			ID:              uuid.New(),
-			PaymentID:       paymentID,
-			AttemptNumber:   3,
-			GeneratedWallet: "TWallet3",
-			GeneratedAt:     pgtype.Timestamptz{Time: time.Now(), Valid: true},
-		},
-	}
-
-	for i, attempt := range attempts {
-		assert.Equal(t, paymentID, attempt.PaymentID)
-		assert.Equal(t, int32(i+1), attempt.AttemptNumber)
-		assert.NotEqual(t, attempts[0].ID, attempts[1].ID)
-	}
-
-	// Verify attempt numbers are sequential
-	assert.Equal(t, int32(1), attempts[0].AttemptNumber)
-	assert.Equal(t, int32(2), attempts[1].AttemptNumber)
-	assert.Equal(t, int32(3), attempts[2].AttemptNumber)
-
-	// Verify unique wallets
-	assert.NotEqual(t, attempts[0].GeneratedWallet, attempts[1].GeneratedWallet)
-	assert.NotEqual(t, attempts[1].GeneratedWallet, attempts[2].GeneratedWallet)
-}
-
 func TestPaymentAttempt_HighAttemptNumber(t *testing.T) {
 	attempt := PaymentAttempt{
 		ID:              uuid.New(),
@@ -1021,33 +688,6 @@ func TestPaymentAttempt_HighAttemptNumber(t *testing.T) {
 	assert.Equal(t, int32(100), attempt.AttemptNumber)
 }
 
-func TestPaymentAttempt_JSONSerialization(t *testing.T) {
-	id := uuid.New()
-	paymentID := uuid.New()
-	now := time.Now()
-
-	attempt := PaymentAttempt{
-		ID:              id,
-		PaymentID:       paymentID,
-		AttemptNumber:   5,
-		GeneratedWallet: "TWallet123",
-		GeneratedAt:     pgtype.Timestamptz{Time: now, Valid: true},
-	}
-
-	jsonData, err := json.Marshal(attempt)
-	require.NoError(t, err)
-	assert.NotEmpty(t, jsonData)
-
-	var decoded PaymentAttempt
-	err = json.Unmarshal(jsonData, &decoded)
-	require.NoError(t, err)
-
-	assert.Equal(t, attempt.ID, decoded.ID)
-	assert.Equal(t, attempt.PaymentID, decoded.PaymentID)
-	assert.Equal(t, attempt.AttemptNumber, decoded.AttemptNumber)
-	assert.Equal(t, attempt.GeneratedWallet, decoded.GeneratedWallet)
-}
-
 func TestPaymentAttempt_EmptyGeneratedWallet(t *testing.T) {
 	attempt := PaymentAttempt{
 		ID:              uuid.New(),
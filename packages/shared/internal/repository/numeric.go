@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+)
+
+// DECIMAL columns are pgtype.Numeric; amounts are worked with as
+// decimal.Decimal. The helpers below convert between the two.
+
+// NumericToDecimal returns n as a decimal, zero when it is NULL.
+func NumericToDecimal(n pgtype.Numeric) decimal.Decimal {
+	if !n.Valid || n.Int == nil {
+		return decimal.Zero
+	}
+	return decimal.NewFromBigInt(n.Int, n.Exp)
+}
+
+// DecimalToNumeric returns d as a non-null DECIMAL column value.
+func DecimalToNumeric(d decimal.Decimal) pgtype.Numeric {
+	return pgtype.Numeric{Int: d.Coefficient(), Exp: d.Exponent(), Valid: true}
+}
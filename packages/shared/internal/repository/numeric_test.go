@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumericToDecimal(t *testing.T) {
+	testCases := []struct {
+		name string
+		n    pgtype.Numeric
+		want string
+	}{
+		{"null", pgtype.Numeric{}, "0"},
+		{"valid without digits", pgtype.Numeric{Valid: true}, "0"},
+		{"fraction", pgtype.Numeric{Int: big.NewInt(25500001), Exp: -6, Valid: true}, "25.500001"},
+		{"positive exponent", pgtype.Numeric{Int: big.NewInt(12), Exp: 3, Valid: true}, "12000"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, NumericToDecimal(tc.n).String())
+		})
+	}
+}
+
+func TestDecimalToNumeric(t *testing.T) {
+	d := decimal.RequireFromString("-10.015")
+
+	n := DecimalToNumeric(d)
+
+	assert.True(t, n.Valid)
+	assert.Equal(t, pgtype.Numeric{Int: big.NewInt(-10015), Exp: -3, Valid: true}, n)
+	assert.True(t, d.Equal(NumericToDecimal(n)), "round trip")
+}
@@ -0,0 +1,74 @@
+//go:build integration
+
+package repository
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegration_ClaimOutboxEventsSkipsLocked(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	payment := f.payment(f.account(f.client().ID))
+
+	mine := make(map[uuid.UUID]bool)
+	for range 3 {
+		id := uuid.New()
+		require.NoError(t, f.store.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+			ID:          id,
+			EventType:   "payment.created",
+			AggregateID: payment.ID,
+			Payload:     []byte(`{}`),
+		}))
+		mine[id] = true
+	}
+	claimedBy := func(events []Outbox) []uuid.UUID {
+		var ids []uuid.UUID
+		for _, e := range events {
+			if mine[e.ID] {
+				ids = append(ids, e.ID)
+			}
+		}
+		return ids
+	}
+
+	// The first relay's transaction holds the events it claimed...
+	first, err := f.conn().Begin(ctx)
+	require.NoError(t, err)
+	defer func() { _ = first.Rollback(ctx) }()
+	held, err := New(first).ClaimOutboxEvents(ctx, 1000)
+	require.NoError(t, err)
+	assert.Len(t, claimedBy(held), len(mine))
+
+	// ...so a second relay on another connection skips them instead of
+	// waiting.
+	second, err := f.conn().Begin(ctx)
+	require.NoError(t, err)
+	defer func() { _ = second.Rollback(ctx) }()
+	skipped, err := New(second).ClaimOutboxEvents(ctx, 1000)
+	require.NoError(t, err)
+	assert.Empty(t, claimedBy(skipped))
+	require.NoError(t, second.Rollback(ctx))
+
+	// Released unprocessed, the events are claimed again, and once marked
+	// processed they are not.
+	require.NoError(t, first.Rollback(ctx))
+	var ids []uuid.UUID
+	require.NoError(t, f.store.ExecTx(ctx, func(q Querier) error {
+		events, err := q.ClaimOutboxEvents(ctx, 1000)
+		if err != nil {
+			return err
+		}
+		ids = claimedBy(events)
+		return q.MarkOutboxEventsProcessed(ctx, ids)
+	}))
+	assert.Len(t, ids, len(mine))
+
+	after, err := f.store.ClaimOutboxEvents(ctx, 1000)
+	require.NoError(t, err)
+	assert.Empty(t, claimedBy(after))
+}
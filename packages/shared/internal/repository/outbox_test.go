@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimOutboxEventsSQL(t *testing.T) {
+	assert.Contains(t, claimOutboxEvents, "WHERE processed_at IS NULL")
+	assert.Contains(t, claimOutboxEvents, "ORDER BY created_at, id")
+	assert.Contains(t, claimOutboxEvents, "FOR UPDATE SKIP LOCKED", "replicas relay disjoint batches")
+}
+
+func TestQueries_ClaimOutboxEvents(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	id, paymentID := uuid.New(), uuid.New()
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, claimOutboxEvents, []interface{}{int32(100)}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 8)
+		*dest[0].(*uuid.UUID) = id
+		*dest[1].(*string) = "payment.confirmed"
+		*dest[2].(*uuid.UUID) = paymentID
+		*dest[3].(*[]byte) = []byte(`{}`)
+		*dest[5].(*pgtype.Timestamptz) = pgtype.Timestamptz{Time: created, Valid: true}
+		*dest[7].(**string) = &traceParent
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	events, err := queries.ClaimOutboxEvents(ctx, 100)
+
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, id, events[0].ID)
+	assert.Equal(t, "payment.confirmed", events[0].EventType)
+	assert.Equal(t, paymentID, events[0].AggregateID)
+	assert.Equal(t, created, events[0].CreatedAt.Time)
+	assert.False(t, events[0].ProcessedAt.Valid)
+	assert.Equal(t, &traceParent, events[0].TraceParent)
+}
+
+func TestQueries_CreateOutboxEvent(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	requestID := "req-1"
+	traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	params := CreateOutboxEventParams{
+		ID:          uuid.New(),
+		EventType:   "payment.expired",
+		AggregateID: uuid.New(),
+		Payload:     []byte(`{}`),
+		RequestID:   &requestID,
+		TraceParent: &traceParent,
+	}
+	mockDB.On("Exec", ctx, createOutboxEvent,
+		[]interface{}{params.ID, params.EventType, params.AggregateID, params.Payload, params.RequestID, params.TraceParent}).Return(nil, nil)
+
+	err := queries.CreateOutboxEvent(ctx, params)
+
+	require.NoError(t, err)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_MarkOutboxEventsProcessed(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	mockDB.On("Exec", ctx, markOutboxEventsProcessed, []interface{}{ids}).Return(nil, nil)
+
+	err := queries.MarkOutboxEventsProcessed(ctx, ids)
+
+	require.NoError(t, err)
+	assert.Contains(t, markOutboxEventsProcessed, "WHERE id = ANY($1::UUID[]) AND processed_at IS NULL")
+	mockDB.AssertExpectations(t)
+}
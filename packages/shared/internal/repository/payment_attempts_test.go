@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueries_CreatePaymentAttempt(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	index := int64(7)
+	params := CreatePaymentAttemptParams{AttemptNumber: 1, PaymentID: uuid.New(), GeneratedWallet: "TWallet", WalletIndex: &index}
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createPaymentAttempt, []interface{}{params.AttemptNumber, params.PaymentID, params.GeneratedWallet, params.WalletIndex}).
+		Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 6)
+		*dest[1].(*uuid.UUID) = params.PaymentID
+		*dest[2].(*int32) = params.AttemptNumber
+		*dest[3].(*string) = params.GeneratedWallet
+		*dest[5].(**int64) = params.WalletIndex
+	})
+
+	attempt, err := queries.CreatePaymentAttempt(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, params.PaymentID, attempt.PaymentID)
+	assert.Equal(t, int32(1), attempt.AttemptNumber)
+	assert.Equal(t, "TWallet", attempt.GeneratedWallet)
+	assert.Equal(t, &index, attempt.WalletIndex)
+}
+
+func TestCreatePaymentAttemptSQL(t *testing.T) {
+	assert.Contains(t, createPaymentAttempt, "UPDATE payments SET attempt_count = $1", "the payment counts its attempts")
+	assert.Contains(t, createPaymentAttempt, "VALUES ($2, $1, $3, $4)")
+}
+
+func TestQueries_ListPaymentAttempts(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, listPaymentAttempts, []interface{}{ids}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 6)
+		*dest[1].(*uuid.UUID) = ids[0]
+		*dest[2].(*int32) = 1
+		*dest[3].(*string) = "TFirst"
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	attempts, err := queries.ListPaymentAttempts(ctx, ids)
+
+	require.NoError(t, err)
+	require.Len(t, attempts, 1)
+	assert.Equal(t, ids[0], attempts[0].PaymentID)
+	assert.Equal(t, "TFirst", attempts[0].GeneratedWallet)
+	assert.Contains(t, listPaymentAttempts, "WHERE payment_id = ANY($1::UUID[])")
+}
+
+func TestQueries_ListRecentAttemptWallets(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	paymentID, accountID := uuid.New(), uuid.New()
+
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, listRecentAttemptWallets, []interface{}{int32(20)}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 6)
+		*dest[0].(*uuid.UUID) = paymentID
+		*dest[1].(*int32) = 2
+		*dest[2].(*string) = "TSecond"
+		*dest[3].(*int64) = 41
+		*dest[4].(*uuid.UUID) = accountID
+		*dest[5].(*string) = "test"
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	rows, err := queries.ListRecentAttemptWallets(ctx, 20)
+
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, ListRecentAttemptWalletsRow{
+		PaymentID:       paymentID,
+		AttemptNumber:   2,
+		GeneratedWallet: "TSecond",
+		WalletIndex:     41,
+		AccountID:       accountID,
+		Mode:            "test",
+	}, rows[0])
+}
+
+func TestListRecentAttemptWalletsSQL(t *testing.T) {
+	assert.Contains(t, listRecentAttemptWallets, "WHERE pa.wallet_index IS NOT NULL", "attempts without an index cannot be re-derived")
+	assert.Contains(t, listRecentAttemptWallets, "ORDER BY pa.generated_at DESC")
+	assert.Contains(t, listRecentAttemptWallets, "LIMIT $1")
+}
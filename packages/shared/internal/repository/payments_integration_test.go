@@ -0,0 +1,669 @@
+//go:build integration
+
+package repository
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegration_PaymentLifecycle(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+
+	client := f.client()
+	got, err := f.store.GetClientByAPIKey(ctx, client.ApiKey)
+	require.NoError(t, err)
+	assert.Equal(t, client.ID, got.ID)
+
+	account := f.account(client.ID)
+	byClient, err := f.store.GetAccountByIDAndClientID(ctx, GetAccountByIDAndClientIDParams{ID: account.ID, ClientID: client.ID})
+	require.NoError(t, err)
+	assert.Equal(t, account.Name, byClient.Name)
+
+	payment := f.payment(account, func(arg *CreatePaymentParams) {
+		arg.FiatAmount = numeric(2550, -2)
+		arg.FiatCurrency = ptr("EUR")
+		arg.ExchangeRate = numeric(1000150, -6)
+		arg.RateAt = timestamptz(time.Now())
+	})
+	assert.Equal(t, PaymentPending, payment.Status)
+
+	read, err := f.store.GetPayment(ctx, payment.ID)
+	require.NoError(t, err)
+	assertNumeric(t, 25.5, read.Amount)
+	assertNumeric(t, 1.00015, read.ExchangeRate)
+	require.NotNil(t, read.FiatCurrency)
+	assert.Equal(t, "EUR", *read.FiatCurrency)
+	assert.Equal(t, "USDT", read.Token)
+	assert.False(t, read.ConfirmedAt.Valid)
+
+	// A regenerated wallet still finds the payment.
+	index, err := f.store.NextWalletIndex(ctx, "deposit")
+	require.NoError(t, err)
+	wallet := f.wallet()
+	attempt, err := f.store.CreatePaymentAttempt(ctx, CreatePaymentAttemptParams{
+		AttemptNumber:   1,
+		PaymentID:       payment.ID,
+		GeneratedWallet: wallet,
+		WalletIndex:     &index,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), attempt.AttemptNumber)
+	assert.True(t, attempt.GeneratedAt.Valid)
+
+	byWallet, err := f.store.GetPaymentByWallet(ctx, GetPaymentByWalletParams{Wallet: wallet, Mode: "live"})
+	require.NoError(t, err)
+	assert.Equal(t, payment.ID, byWallet.ID)
+	require.NotNil(t, byWallet.AttemptCount)
+	assert.Equal(t, int32(1), *byWallet.AttemptCount)
+
+	attempts, err := f.store.ListPaymentAttempts(ctx, []uuid.UUID{payment.ID})
+	require.NoError(t, err)
+	require.Len(t, attempts, 1)
+	assert.Equal(t, wallet, attempts[0].GeneratedWallet)
+
+	msg := "payment created"
+	require.NoError(t, f.store.CreateLog(ctx, CreateLogParams{
+		PaymentID: UUIDPtr(payment.ID),
+		EventType: "PAYMENT_CREATED",
+		Message:   &msg,
+		RawData:   []byte(`{"amount":"25.5"}`),
+		RequestID: ptr("req-1"),
+		Actor:     ptr("tpg"),
+	}))
+	var event, raw, actor string
+	err = f.pool.QueryRow(ctx, "SELECT event_type, raw_data::STRING, actor FROM logs WHERE payment_id = $1", payment.ID).Scan(&event, &raw, &actor)
+	require.NoError(t, err)
+	assert.Equal(t, "PAYMENT_CREATED", event)
+	assert.JSONEq(t, `{"amount":"25.5"}`, raw)
+	assert.Equal(t, "tpg", actor)
+
+	list, err := f.store.ListClientPayments(ctx, ListClientPaymentsParams{ClientID: client.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, payment.ID, list[0].ID)
+}
+
+func TestIntegration_DuplicateWallet(t *testing.T) {
+	f := newFixture(t)
+	account := f.account(f.client().ID)
+	first := f.payment(account)
+
+	index, err := f.store.NextWalletIndex(f.ctx, "deposit")
+	require.NoError(t, err)
+	_, err = f.store.CreatePayment(f.ctx, CreatePaymentParams{
+		ClientID:     account.ClientID,
+		AccountID:    account.ID,
+		Amount:       numeric(1, 0),
+		UniqueWallet: first.UniqueWallet,
+		ExpiresAt:    timestamptz(time.Now().Add(time.Hour)),
+		WalletIndex:  &index,
+		Token:        "USDT",
+	})
+	require.ErrorIs(t, err, ErrDuplicateWallet)
+
+	_, err = f.store.CreatePayment(f.ctx, CreatePaymentParams{
+		ClientID:     account.ClientID,
+		AccountID:    account.ID,
+		Amount:       numeric(1, 0),
+		UniqueWallet: f.wallet(),
+		ExpiresAt:    timestamptz(time.Now().Add(time.Hour)),
+		WalletIndex:  first.WalletIndex,
+		Token:        "USDT",
+	})
+	require.ErrorIs(t, err, ErrDuplicateWallet, "an index is never reused")
+}
+
+func TestIntegration_OrderReference(t *testing.T) {
+	f := newFixture(t)
+	account := f.account(f.client().ID)
+	other := f.account(f.client().ID)
+	ref := "order-" + uuid.NewString()
+	create := func(a Account) (Payment, error) {
+		index, err := f.store.NextWalletIndex(f.ctx, "deposit")
+		require.NoError(t, err)
+		return f.store.CreatePayment(f.ctx, CreatePaymentParams{
+			ClientID:       a.ClientID,
+			AccountID:      a.ID,
+			Amount:         numeric(1, 0),
+			UniqueWallet:   f.wallet(),
+			ExpiresAt:      timestamptz(time.Now().Add(time.Hour)),
+			WalletIndex:    &index,
+			Token:          "USDT",
+			OrderReference: &ref,
+			Metadata:       []byte(`{"sku":"A-1"}`),
+		})
+	}
+
+	first, err := create(account)
+	require.NoError(t, err)
+	_, err = create(account)
+	require.ErrorIs(t, err, ErrDuplicateOrderReference)
+	_, err = create(other)
+	require.NoError(t, err, "another client may use the same reference")
+
+	got, err := f.store.GetPaymentByOrderReference(f.ctx, GetPaymentByOrderReferenceParams{ClientID: account.ClientID, OrderReference: ref})
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, got.ID)
+	assert.JSONEq(t, `{"sku":"A-1"}`, string(got.Metadata))
+}
+
+func TestIntegration_GetPendingPaymentByAnyWallet(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	account := f.account(f.client().ID)
+	payment := f.payment(account)
+
+	// Two attempts, as creating the payment and regenerating its wallet
+	// record them; the payment points at the second.
+	first := payment.UniqueWallet
+	second := f.wallet()
+	_, err := f.store.CreatePaymentAttempt(ctx, CreatePaymentAttemptParams{AttemptNumber: 1, PaymentID: payment.ID, GeneratedWallet: first, WalletIndex: payment.WalletIndex})
+	require.NoError(t, err)
+	index, err := f.store.NextWalletIndex(ctx, "deposit")
+	require.NoError(t, err)
+	_, err = f.store.CreatePaymentAttempt(ctx, CreatePaymentAttemptParams{AttemptNumber: 2, PaymentID: payment.ID, GeneratedWallet: second, WalletIndex: &index})
+	require.NoError(t, err)
+	updated, err := f.store.UpdatePaymentWallet(ctx, UpdatePaymentWalletParams{UniqueWallet: second, WalletIndex: &index, ID: payment.ID, AttemptCount: 2, Version: payment.Version})
+	require.NoError(t, err)
+
+	for _, wallet := range []string{first, second} {
+		got, err := f.store.GetPendingPaymentByAnyWallet(ctx, wallet)
+		require.NoError(t, err, wallet)
+		assert.Equal(t, payment.ID, got.ID)
+		assert.Equal(t, second, got.UniqueWallet)
+	}
+	_, err = f.store.GetPendingPaymentByAnyWallet(ctx, f.wallet())
+	assert.ErrorIs(t, err, pgx.ErrNoRows, "a wallet no attempt generated")
+
+	detected, err := f.store.UpdatePaymentStatus(ctx, UpdatePaymentStatusParams{ID: payment.ID, FromStatus: PaymentPending, ToStatus: PaymentDetected, Version: updated.Version})
+	require.NoError(t, err)
+	got, err := f.store.GetPendingPaymentByAnyWallet(ctx, first)
+	require.NoError(t, err, "a DETECTED payment still takes transfers")
+	assert.Equal(t, payment.ID, got.ID)
+
+	f.deposit(payment)
+	_, err = f.store.ConfirmPayment(ctx, ConfirmPaymentParams{ID: payment.ID, Version: detected.Version})
+	require.NoError(t, err)
+	_, err = f.store.GetPendingPaymentByAnyWallet(ctx, first)
+	assert.ErrorIs(t, err, pgx.ErrNoRows, "a settled payment is left to GetPaymentByWallet")
+	settled, err := f.store.GetPaymentByWallet(ctx, GetPaymentByWalletParams{Wallet: first, Mode: "live"})
+	require.NoError(t, err)
+	assert.Equal(t, payment.ID, settled.ID)
+}
+
+func TestIntegration_ListRecentAttemptWallets(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	account := f.account(f.client().ID)
+	payment := f.payment(account)
+
+	_, err := f.store.CreatePaymentAttempt(ctx, CreatePaymentAttemptParams{AttemptNumber: 1, PaymentID: payment.ID, GeneratedWallet: f.wallet()})
+	require.NoError(t, err)
+	index, err := f.store.NextWalletIndex(ctx, "deposit")
+	require.NoError(t, err)
+	wallet := f.wallet()
+	_, err = f.store.CreatePaymentAttempt(ctx, CreatePaymentAttemptParams{AttemptNumber: 2, PaymentID: payment.ID, GeneratedWallet: wallet, WalletIndex: &index})
+	require.NoError(t, err)
+
+	rows, err := f.store.ListRecentAttemptWallets(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, ListRecentAttemptWalletsRow{
+		PaymentID:       payment.ID,
+		AttemptNumber:   2,
+		GeneratedWallet: wallet,
+		WalletIndex:     index,
+		AccountID:       account.ID,
+		Mode:            "live",
+	}, rows[0], "the newest attempt with an index comes first")
+
+	rows, err = f.store.ListRecentAttemptWallets(ctx, 100)
+	require.NoError(t, err)
+	for _, r := range rows {
+		if r.PaymentID == payment.ID {
+			assert.Equal(t, int32(2), r.AttemptNumber, "the attempt without an index is left out")
+		}
+	}
+}
+
+func TestIntegration_StatusTransitions(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	payment := f.payment(f.account(f.client().ID))
+
+	assert.Equal(t, int32(1), payment.Version)
+	detected, err := f.store.UpdatePaymentStatus(ctx, UpdatePaymentStatusParams{ID: payment.ID, FromStatus: PaymentPending, ToStatus: PaymentDetected, Version: payment.Version})
+	require.NoError(t, err)
+	assert.Equal(t, PaymentDetected, detected.Status)
+	assert.Equal(t, int32(2), detected.Version)
+
+	_, err = f.store.UpdatePaymentStatus(ctx, UpdatePaymentStatusParams{ID: payment.ID, FromStatus: PaymentPending, ToStatus: PaymentDetected, Version: payment.Version})
+	require.ErrorIs(t, err, ErrVersionConflict, "the payment was updated since it was read")
+	_, err = f.store.UpdatePaymentStatus(ctx, UpdatePaymentStatusParams{ID: payment.ID, FromStatus: PaymentPending, ToStatus: PaymentDetected, Version: detected.Version})
+	require.ErrorIs(t, err, ErrPaymentStatusChanged, "the payment left PENDING")
+
+	f.deposit(payment)
+	confirmed, err := f.store.ConfirmPayment(ctx, ConfirmPaymentParams{ID: payment.ID, Version: detected.Version})
+	require.NoError(t, err)
+	assert.Equal(t, PaymentConfirmed, confirmed.Status)
+	assert.True(t, confirmed.ConfirmedAt.Valid)
+
+	_, err = f.store.ConfirmPayment(ctx, ConfirmPaymentParams{ID: payment.ID, Version: confirmed.Version})
+	require.ErrorIs(t, err, ErrPaymentNotPending)
+
+	reverted, err := f.store.RevertPaymentConfirmation(ctx, RevertPaymentConfirmationParams{ID: payment.ID, ToStatus: PaymentPending, Version: confirmed.Version})
+	require.NoError(t, err)
+	assert.Equal(t, PaymentPending, reverted.Status)
+	assert.False(t, reverted.ConfirmedAt.Valid)
+
+	_, err = f.store.CancelPayment(ctx, CancelPaymentParams{ID: payment.ID, FromStatus: PaymentPending, Version: confirmed.Version})
+	require.ErrorIs(t, err, ErrVersionConflict)
+	cancelled, err := f.store.CancelPayment(ctx, CancelPaymentParams{ID: payment.ID, FromStatus: PaymentPending, Version: reverted.Version})
+	require.NoError(t, err)
+	assert.Equal(t, PaymentCancelled, cancelled.Status)
+	assert.Equal(t, int32(5), cancelled.Version)
+
+	_, err = f.store.UpdatePaymentStatus(ctx, UpdatePaymentStatusParams{ID: payment.ID, FromStatus: PaymentCancelled, ToStatus: PaymentPending})
+	require.ErrorIs(t, err, ErrInvalidPaymentTransition)
+
+	_, err = f.store.UpdatePaymentStatus(ctx, UpdatePaymentStatusParams{ID: uuid.New(), FromStatus: PaymentPending, ToStatus: PaymentExpired})
+	require.ErrorIs(t, err, ErrPaymentStatusChanged, "a missing payment")
+}
+
+func TestIntegration_ConfirmPaymentRequiresDeposits(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	payment := f.payment(f.account(f.client().ID))
+	_, err := f.store.ConfirmPayment(ctx, ConfirmPaymentParams{ID: payment.ID, Version: payment.Version})
+	require.ErrorIs(t, err, ErrPaymentNotPending, "nothing received")
+
+	_, err = f.store.CreateTransaction(ctx, CreateTransactionParams{
+		PaymentID:   payment.ID,
+		TxHash:      uuid.NewString(),
+		Token:       "USDT",
+		FromAddress: f.wallet(),
+		ToAddress:   payment.UniqueWallet,
+		Amount:      numeric(2540, -2),
+		BlockNumber: 1,
+		BlockHash:   "hash",
+	})
+	require.NoError(t, err)
+	_, err = f.store.ConfirmPayment(ctx, ConfirmPaymentParams{ID: payment.ID, Version: payment.Version, ToleranceBps: 10})
+	require.ErrorIs(t, err, ErrPaymentNotPending, "25.40 of 25.50 is short by more than 0.1%")
+	confirmed, err := f.store.ConfirmPayment(ctx, ConfirmPaymentParams{ID: payment.ID, Version: payment.Version, ToleranceBps: 50})
+	require.NoError(t, err, "but within 0.5%")
+	assert.Equal(t, PaymentConfirmed, confirmed.Status)
+}
+
+func TestIntegration_ConcurrentConfirmation(t *testing.T) {
+	f := newFixture(t)
+	payment := f.payment(f.account(f.client().ID))
+	f.deposit(payment)
+
+	const workers = 10
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = f.store.ConfirmPayment(f.ctx, ConfirmPaymentParams{ID: payment.ID, Version: payment.Version})
+		}()
+	}
+	wg.Wait()
+
+	confirmed := 0
+	for _, err := range errs {
+		if err == nil {
+			confirmed++
+			continue
+		}
+		assert.ErrorIs(t, err, ErrVersionConflict, "the winner bumped the version the others read")
+	}
+	assert.Equal(t, 1, confirmed, "a payment is confirmed exactly once")
+}
+
+func TestIntegration_NextWalletIndexConcurrent(t *testing.T) {
+	f := newFixture(t)
+
+	const workers = 50
+	var wg sync.WaitGroup
+	indexes := make([]int64, workers)
+	errs := make([]error, workers)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			indexes[i], errs[i] = f.store.NextWalletIndex(f.ctx, "deposit")
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[int64]bool, workers)
+	lowest := indexes[0]
+	for i, index := range indexes {
+		require.NoError(t, errs[i])
+		assert.False(t, seen[index], "index %d handed out twice", index)
+		seen[index] = true
+		lowest = min(lowest, index)
+	}
+	// Other tests allocate too, but not while this one runs.
+	for i := range int64(workers) {
+		assert.True(t, seen[lowest+i], "index %d skipped", lowest+i)
+	}
+}
+
+func TestIntegration_ExpiryFromTwoConnections(t *testing.T) {
+	f := newFixture(t)
+	account := f.account(f.client().ID)
+
+	// Expired long before anything other tests create.
+	deadline := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	mine := make(map[uuid.UUID]bool)
+	for i := range 20 {
+		p := f.payment(account, func(arg *CreatePaymentParams) {
+			arg.ExpiresAt = timestamptz(deadline.Add(-time.Duration(i+1) * time.Minute))
+		})
+		mine[p.ID] = true
+	}
+
+	// Two expirers on their own connections list the same batch and race
+	// to move each payment; the status check lets exactly one win.
+	stores := []*Store{NewStore(f.conn()), NewStore(f.conn())}
+	expired := make([]map[uuid.UUID]bool, len(stores))
+	errs := make([]error, len(stores))
+	var wg sync.WaitGroup
+	for i, store := range stores {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			expired[i] = make(map[uuid.UUID]bool)
+			batch, err := store.ListExpiredPayments(f.ctx, ListExpiredPaymentsParams{ExpiredBefore: timestamptz(deadline), Limit: 100})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			for _, p := range batch {
+				if !mine[p.ID] {
+					continue
+				}
+				_, err := store.UpdatePaymentStatus(f.ctx, UpdatePaymentStatusParams{ID: p.ID, FromStatus: p.Status, ToStatus: PaymentExpired, Version: p.Version})
+				if errors.Is(err, ErrPaymentStatusChanged) || errors.Is(err, ErrVersionConflict) {
+					continue
+				}
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				expired[i][p.ID] = true
+			}
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, errors.Join(errs...))
+
+	for id := range mine {
+		assert.NotEqual(t, expired[0][id], expired[1][id], "payment %s expired by exactly one connection", id)
+		p, err := f.store.GetPayment(f.ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, PaymentExpired, p.Status)
+	}
+
+	again, err := f.store.ListExpiredPayments(f.ctx, ListExpiredPaymentsParams{ExpiredBefore: timestamptz(deadline), Limit: 100})
+	require.NoError(t, err)
+	for _, p := range again {
+		assert.False(t, mine[p.ID], "expired payments leave the batch")
+	}
+}
+
+// assertNumeric compares a DECIMAL by value, whatever scale it was read in.
+func assertNumeric(t *testing.T, want float64, got pgtype.Numeric) {
+	t.Helper()
+	f, err := got.Float64Value()
+	require.NoError(t, err)
+	assert.InDelta(t, want, f.Float64, 1e-9)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestIntegration_SoftDeleteAccount(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+
+	client := f.client()
+	account := f.account(client.ID)
+	kept := f.account(client.ID)
+	payment := f.payment(account)
+	arg := SoftDeleteAccountParams{ID: account.ID, ClientID: client.ID}
+
+	_, err := f.store.SoftDeleteAccount(ctx, arg)
+	require.ErrorIs(t, err, ErrAccountHasOpenPayments)
+
+	_, err = f.store.UpdatePaymentStatus(ctx, UpdatePaymentStatusParams{ID: payment.ID, FromStatus: PaymentPending, ToStatus: PaymentExpired, Version: payment.Version})
+	require.NoError(t, err)
+	deleted, err := f.store.SoftDeleteAccount(ctx, arg)
+	require.NoError(t, err)
+	assert.True(t, deleted.DeletedAt.Valid)
+
+	_, err = f.store.SoftDeleteAccount(ctx, arg)
+	assert.ErrorIs(t, err, pgx.ErrNoRows, "an account is deleted once")
+
+	_, err = f.store.GetAccountByIDAndClientID(ctx, GetAccountByIDAndClientIDParams{ID: account.ID, ClientID: client.ID})
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+	got, err := f.store.GetAccountByIDAndClientID(ctx, GetAccountByIDAndClientIDParams{ID: account.ID, ClientID: client.ID, IncludeDeleted: true})
+	require.NoError(t, err)
+	assert.Equal(t, deleted.DeletedAt.Time, got.DeletedAt.Time)
+
+	listed, err := f.store.GetAccountsByClientID(ctx, GetAccountsByClientIDParams{ClientID: client.ID})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, kept.ID, listed[0].ID)
+	listed, err = f.store.GetAccountsByClientID(ctx, GetAccountsByClientIDParams{ClientID: client.ID, IncludeDeleted: true})
+	require.NoError(t, err)
+	assert.Len(t, listed, 2)
+
+	// The payments keep their account: it cannot be hard deleted under them.
+	_, err = f.pool.Exec(ctx, "DELETE FROM accounts WHERE id = $1", account.ID)
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	assert.Equal(t, "23503", pgErr.Code)
+}
+
+func TestIntegration_CountsAndExistence(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+
+	client := f.client()
+	account := f.account(client.ID)
+	deleted := f.account(client.ID)
+	_, err := f.store.SoftDeleteAccount(ctx, SoftDeleteAccountParams{ID: deleted.ID, ClientID: client.ID})
+	require.NoError(t, err)
+	accounts, err := f.store.CountAccountsByClientID(ctx, client.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), accounts)
+
+	pending := f.payment(account)
+	expired := f.payment(account)
+	_, err = f.store.UpdatePaymentStatus(ctx, UpdatePaymentStatusParams{ID: expired.ID, FromStatus: PaymentPending, ToStatus: PaymentExpired, Version: expired.Version})
+	require.NoError(t, err)
+	open, err := f.store.CountPendingPaymentsByAccountID(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), open)
+
+	exists, err := f.store.PaymentExists(ctx, PaymentExistsParams{ID: pending.ID, ClientID: client.ID})
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = f.store.PaymentExists(ctx, PaymentExistsParams{ID: pending.ID, ClientID: f.client().ID})
+	require.NoError(t, err)
+	assert.False(t, exists, "another client's payment does not exist for it")
+
+	exists, err = f.store.ClientExistsByAPIKey(ctx, client.ApiKey)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestIntegration_ClientSummaryQueries(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	client := f.client()
+	account := f.account(client.ID)
+	first := f.payment(account)
+	confirmed := f.payment(account)
+	f.deposit(confirmed)
+	_, err := f.store.ConfirmPayment(ctx, ConfirmPaymentParams{ID: confirmed.ID, Version: confirmed.Version})
+	require.NoError(t, err)
+	trx := f.payment(account, func(arg *CreatePaymentParams) { arg.Token = "TRX" })
+	f.deposit(trx)
+	_, err = f.store.ConfirmPayment(ctx, ConfirmPaymentParams{ID: trx.ID, Version: trx.Version})
+	require.NoError(t, err)
+	// Another client's payments are left out.
+	other := f.payment(f.account(f.client().ID))
+	f.deposit(other)
+	_, err = f.store.ConfirmPayment(ctx, ConfirmPaymentParams{ID: other.ID, Version: other.Version})
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	counts, err := f.store.CountClientPaymentsByStatus(ctx, CountClientPaymentsByStatusParams{
+		TodayFrom: timestamptz(today),
+		WeekFrom:  timestamptz(today.AddDate(0, 0, -6)),
+		ClientID:  client.ID,
+		MonthFrom: timestamptz(today.AddDate(0, 0, -29)),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []CountClientPaymentsByStatusRow{
+		{Status: PaymentConfirmed, TodayCount: 2, WeekCount: 2, MonthCount: 2},
+		{Status: PaymentPending, TodayCount: 1, WeekCount: 1, MonthCount: 1},
+	}, counts)
+
+	volume, err := f.store.GetDailyConfirmedVolume(ctx, GetDailyConfirmedVolumeParams{
+		ClientID:      client.ID,
+		ConfirmedFrom: timestamptz(today.AddDate(0, 0, -29)),
+	})
+	require.NoError(t, err)
+	require.Len(t, volume, 2)
+	assert.Equal(t, "TRX", volume[0].Token)
+	assert.Equal(t, "USDT", volume[1].Token)
+	assert.Equal(t, today, volume[1].Day.Time)
+	assert.Equal(t, int64(1), volume[1].Payments)
+	assertNumeric(t, 25.5, volume[1].Volume)
+
+	recent, err := f.store.ListRecentClientPayments(ctx, ListRecentClientPaymentsParams{ClientID: client.ID, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, recent, 2)
+	assert.Equal(t, trx.ID, recent[0].ID, "newest first")
+	assert.Equal(t, confirmed.ID, recent[1].ID)
+	assert.NotEqual(t, first.ID, recent[1].ID)
+}
+
+func TestIntegration_PaymentFunnel(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	client := f.client()
+	account := f.account(client.ID)
+	f.payment(account)
+	confirmed := f.payment(account)
+	f.deposit(confirmed)
+	_, err := f.store.ConfirmPayment(ctx, ConfirmPaymentParams{ID: confirmed.ID, Version: confirmed.Version})
+	require.NoError(t, err)
+	detected := f.payment(account)
+	_, err = f.store.UpdatePaymentStatus(ctx, UpdatePaymentStatusParams{
+		ID: detected.ID, FromStatus: PaymentPending, ToStatus: PaymentDetected, Version: detected.Version,
+	})
+	require.NoError(t, err)
+	expired := f.payment(account)
+	_, err = f.store.UpdatePaymentStatus(ctx, UpdatePaymentStatusParams{
+		ID: expired.ID, FromStatus: PaymentPending, ToStatus: PaymentExpired, Version: expired.Version,
+	})
+	require.NoError(t, err)
+	// Another client's payments are left out.
+	f.payment(f.account(f.client().ID))
+
+	now := time.Now().UTC()
+	arg := GetPaymentFunnelParams{
+		Bucket:      "hour",
+		CreatedFrom: timestamptz(now.Add(-time.Hour)),
+		CreatedTo:   timestamptz(now.Add(time.Hour)),
+		ClientID:    &client.ID,
+	}
+	rows, err := f.store.GetPaymentFunnel(ctx, arg)
+	require.NoError(t, err)
+	var total GetPaymentFunnelRow
+	for _, r := range rows {
+		assert.Equal(t, r.Bucket.Time.Truncate(time.Hour), r.Bucket.Time, "buckets start on the hour")
+		total.Created += r.Created
+		total.Detected += r.Detected
+		total.Confirmed += r.Confirmed
+		total.Expired += r.Expired
+	}
+	assert.Equal(t, GetPaymentFunnelRow{Created: 4, Detected: 2, Confirmed: 1, Expired: 1}, total)
+
+	arg.Bucket = "day"
+	rows, err = f.store.GetPaymentFunnel(ctx, arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, rows)
+	assert.Equal(t, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), rows[len(rows)-1].Bucket.Time)
+
+	arg.ClientID = nil
+	rows, err = f.store.GetPaymentFunnel(ctx, arg)
+	require.NoError(t, err)
+	var all int64
+	for _, r := range rows {
+		all += r.Created
+	}
+	assert.GreaterOrEqual(t, all, int64(5), "every client's payments without a client")
+}
+
+func TestIntegration_SearchPayments(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	account := f.account(f.client().ID)
+	otherAccount := f.account(f.client().ID)
+	payment := f.payment(account)
+	wallets := f.attempts(payment, 2)
+	other := f.payment(otherAccount)
+	txHash := strings.ReplaceAll(uuid.NewString()+uuid.NewString(), "-", "")
+	for i, p := range []Payment{payment, other} {
+		_, err := f.store.CreateTransaction(ctx, CreateTransactionParams{
+			PaymentID:     p.ID,
+			TxHash:        txHash,
+			TransferIndex: int32(i),
+			Token:         "USDT",
+			FromAddress:   f.wallet(),
+			ToAddress:     p.UniqueWallet,
+			Amount:        numeric(1, 0),
+			BlockNumber:   1,
+			BlockHash:     "hash",
+		})
+		require.NoError(t, err)
+	}
+
+	for _, wallet := range wallets {
+		got, err := f.store.SearchPaymentsByWallet(ctx, SearchPaymentsByWalletParams{ClientID: account.ClientID, Wallet: wallet, Limit: 20})
+		require.NoError(t, err, wallet)
+		require.Len(t, got, 1, "the current wallet and an earlier attempt's both match")
+		assert.Equal(t, payment.ID, got[0].ID)
+	}
+	got, err := f.store.SearchPaymentsByWallet(ctx, SearchPaymentsByWalletParams{ClientID: otherAccount.ClientID, Wallet: wallets[0], Limit: 20})
+	require.NoError(t, err)
+	assert.Empty(t, got, "another client's wallet")
+
+	got, err = f.store.SearchPaymentsByTxHash(ctx, SearchPaymentsByTxHashParams{ClientID: account.ClientID, TxHash: txHash, Limit: 20})
+	require.NoError(t, err)
+	require.Len(t, got, 1, "only the caller's payment")
+	assert.Equal(t, payment.ID, got[0].ID)
+}
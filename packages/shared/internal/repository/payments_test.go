@@ -0,0 +1,725 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatePaymentSQL(t *testing.T) {
+	expectedSQL := "-- name: CreatePayment :one\nINSERT INTO payments (client_id, account_id, amount, unique_wallet, expires_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, id)\nVALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, COALESCE($16::UUID, gen_random_uuid()))\nRETURNING id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version\n"
+	assert.Equal(t, expectedSQL, createPayment)
+}
+
+func TestGetPaymentByUniqueWalletSQL(t *testing.T) {
+	expectedSQL := "-- name: GetPaymentByUniqueWallet :one\nSELECT id, client_id, account_id, amount, unique_wallet, status, expires_at, confirmed_at, attempt_count, created_at, wallet_index, fiat_amount, fiat_currency, exchange_rate, rate_at, token, mode, webhook_endpoint_id, order_reference, metadata, version\nFROM payments\nWHERE unique_wallet = $1\nORDER BY created_at DESC\nLIMIT 1\n"
+	assert.Equal(t, expectedSQL, getPaymentByUniqueWallet)
+}
+
+func TestQueries_CreatePayment_Success(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	walletIndex := int64(7)
+	currency := "USD"
+	orderRef := "order-1001"
+	params := CreatePaymentParams{
+		ClientID:       uuid.New(),
+		AccountID:      uuid.New(),
+		Amount:         pgtype.Numeric{Valid: true},
+		UniqueWallet:   "TXYZabc123",
+		ExpiresAt:      pgtype.Timestamptz{Time: time.Now().Add(5 * time.Minute), Valid: true},
+		WalletIndex:    &walletIndex,
+		FiatAmount:     pgtype.Numeric{Valid: true},
+		FiatCurrency:   &currency,
+		ExchangeRate:   pgtype.Numeric{Valid: true},
+		RateAt:         pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Token:          "TRX",
+		Mode:           "live",
+		OrderReference: &orderRef,
+		Metadata:       []byte(`{"sku":"A-1"}`),
+	}
+	paymentID := uuid.New()
+	params.ID = UUIDPtr(paymentID)
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createPayment, []interface{}{params.ClientID, params.AccountID, params.Amount, params.UniqueWallet, params.ExpiresAt, params.WalletIndex, params.FiatAmount, params.FiatCurrency, params.ExchangeRate, params.RateAt, params.Token, params.Mode, params.WebhookEndpointID, params.OrderReference, params.Metadata, params.ID}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 21)
+		*dest[0].(*uuid.UUID) = paymentID
+		*dest[4].(*string) = params.UniqueWallet
+		*dest[5].(*string) = "PENDING"
+		*dest[10].(**int64) = params.WalletIndex
+		*dest[12].(**string) = params.FiatCurrency
+		*dest[15].(*string) = params.Token
+		*dest[18].(**string) = params.OrderReference
+		*dest[19].(*[]byte) = params.Metadata
+	})
+
+	payment, err := queries.CreatePayment(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, paymentID, payment.ID)
+	assert.Equal(t, "TXYZabc123", payment.UniqueWallet)
+	assert.Equal(t, "PENDING", payment.Status)
+	assert.Equal(t, &walletIndex, payment.WalletIndex)
+	assert.Equal(t, &currency, payment.FiatCurrency)
+	assert.Equal(t, "TRX", payment.Token)
+	assert.Equal(t, &orderRef, payment.OrderReference)
+	assert.JSONEq(t, `{"sku":"A-1"}`, string(payment.Metadata))
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_GetPaymentByUniqueWallet_NotFound(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, getPaymentByUniqueWallet, []interface{}{"TMissing"}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+	_, err := queries.GetPaymentByUniqueWallet(ctx, "TMissing")
+
+	assert.True(t, errors.Is(err, pgx.ErrNoRows))
+	mockDB.AssertExpectations(t)
+}
+
+func TestMockQuerier_CreatePayment(t *testing.T) {
+	mockQuerier := new(MockQuerier)
+	ctx := context.Background()
+	params := CreatePaymentParams{ClientID: uuid.New(), AccountID: uuid.New(), UniqueWallet: "TWallet"}
+	expected := Payment{ID: uuid.New(), UniqueWallet: "TWallet", Status: "PENDING"}
+
+	mockQuerier.On("CreatePayment", ctx, params).Return(expected, nil)
+
+	payment, err := mockQuerier.CreatePayment(ctx, params)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, payment)
+	mockQuerier.AssertExpectations(t)
+}
+
+func TestConfirmPaymentSQL(t *testing.T) {
+	assert.Contains(t, confirmPayment, "WHERE id = $1 AND status IN ('PENDING', 'DETECTED') AND version = $2", "ConfirmPayment must compare-and-set on PENDING or DETECTED and the version read")
+	assert.Contains(t, confirmPayment, "version = version + 1")
+}
+
+func TestCancelPaymentSQL(t *testing.T) {
+	assert.Contains(t, cancelPayment, "SET status = 'CANCELLED', version = version + 1")
+	assert.Contains(t, cancelPayment, "WHERE id = $1 AND status = $2 AND version = $3 AND NOT EXISTS (", "CancelPayment must compare-and-set on the status and version read")
+	assert.Contains(t, cancelPayment, "WHERE payment_id = $1 AND kind = 'DEPOSIT' AND status != 'ORPHANED'", "a recorded transfer blocks cancellation")
+}
+
+func TestGetPaymentByWalletSQL(t *testing.T) {
+	assert.Contains(t, getPaymentByWallet, "WHERE (unique_wallet = $1")
+	assert.Contains(t, getPaymentByWallet, "OR id IN (SELECT payment_id FROM payment_attempts WHERE generated_wallet = $1))",
+		"a payment is found by the wallets it had before being regenerated")
+	assert.Contains(t, getPaymentByWallet, "AND mode = $2", "test and live wallets can share an address index")
+	assert.Contains(t, getPaymentByWallet, "ORDER BY created_at DESC")
+}
+
+func TestQueries_GetPaymentByWallet(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	id := uuid.New()
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, getPaymentByWallet, []interface{}{"TOld", "test"}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 21)
+		*dest[0].(*uuid.UUID) = id
+		*dest[4].(*string) = "TNew"
+	})
+
+	payment, err := queries.GetPaymentByWallet(ctx, GetPaymentByWalletParams{Wallet: "TOld", Mode: "test"})
+
+	require.NoError(t, err)
+	assert.Equal(t, id, payment.ID)
+	assert.Equal(t, "TNew", payment.UniqueWallet)
+}
+
+func TestGetPendingPaymentByAnyWalletSQL(t *testing.T) {
+	assert.Contains(t, getPendingPaymentByAnyWallet, "FROM payment_attempts a\nJOIN payments p ON p.id = a.payment_id",
+		"every wallet a payment was given, the current one included, is an attempt")
+	assert.Contains(t, getPendingPaymentByAnyWallet, "WHERE a.generated_wallet = $1 AND p.status IN ('PENDING', 'DETECTED', 'UNDERPAID')")
+	assert.Contains(t, getPendingPaymentByAnyWallet, "ORDER BY p.created_at DESC\nLIMIT 1")
+}
+
+func TestQueries_GetPendingPaymentByAnyWallet(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	id := uuid.New()
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, getPendingPaymentByAnyWallet, []interface{}{"TOld"}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 21)
+		*dest[0].(*uuid.UUID) = id
+		*dest[4].(*string) = "TNew"
+		*dest[5].(*string) = "PENDING"
+	})
+
+	payment, err := queries.GetPendingPaymentByAnyWallet(ctx, "TOld")
+
+	require.NoError(t, err)
+	assert.Equal(t, id, payment.ID)
+	assert.Equal(t, "TNew", payment.UniqueWallet)
+	mockDB.AssertExpectations(t)
+}
+
+func TestUpdatePaymentWalletSQL(t *testing.T) {
+	assert.Contains(t, updatePaymentWallet, "SET unique_wallet = $1, wallet_index = $2, version = version + 1")
+	assert.Contains(t, updatePaymentWallet, "WHERE id = $3 AND status = 'PENDING' AND COALESCE(attempt_count, 0) = $4::INT AND version = $5",
+		"UpdatePaymentWallet must compare-and-set on the attempt count and version read")
+}
+
+func TestQueries_GetPayment(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	id := uuid.New()
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, getPayment, []interface{}{id}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 21)
+		*dest[0].(*uuid.UUID) = id
+		*dest[5].(*string) = PaymentConfirmed
+	})
+
+	payment, err := queries.GetPayment(ctx, id)
+
+	require.NoError(t, err)
+	assert.Equal(t, id, payment.ID)
+	assert.Equal(t, PaymentConfirmed, payment.Status)
+}
+
+func TestQueries_GetPaymentByOrderReference(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	params := GetPaymentByOrderReferenceParams{ClientID: uuid.New(), OrderReference: "order-1001"}
+	id := uuid.New()
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, getPaymentByOrderReference, []interface{}{params.ClientID, params.OrderReference}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 21)
+		*dest[0].(*uuid.UUID) = id
+		*dest[1].(*uuid.UUID) = params.ClientID
+		*dest[18].(**string) = &params.OrderReference
+	})
+
+	payment, err := queries.GetPaymentByOrderReference(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, id, payment.ID)
+	require.NotNil(t, payment.OrderReference)
+	assert.Equal(t, "order-1001", *payment.OrderReference)
+	assert.Contains(t, getPaymentByOrderReference, "WHERE client_id = $1 AND order_reference = $2")
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_AllocateWalletIndexes(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, allocateWalletIndexes, []interface{}{"deposit", int64(50)}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 1)
+		*dest[0].(*int64) = 100
+	})
+
+	first, err := queries.AllocateWalletIndexes(ctx, AllocateWalletIndexesParams{Name: "deposit", Count: 50})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), first)
+	assert.Contains(t, allocateWalletIndexes, "SET next_index = wallet_index_counters.next_index + $2::INT8")
+	assert.Contains(t, allocateWalletIndexes, "RETURNING next_index - $2::INT8 AS first_index", "the first of the taken indexes is handed out")
+}
+
+func TestQueries_NextWalletIndex(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, nextWalletIndex, []interface{}{"deposit_test"}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 1)
+		*dest[0].(*int64) = 41
+	})
+
+	index, err := queries.NextWalletIndex(ctx, "deposit_test")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(41), index)
+	assert.Contains(t, nextWalletIndex, "ON CONFLICT (name) DO UPDATE", "a mode's counter is created by its first payment")
+	assert.Contains(t, nextWalletIndex, "SET next_index = wallet_index_counters.next_index + 1")
+	assert.Contains(t, nextWalletIndex, "RETURNING next_index - 1 AS wallet_index", "the index before the bump is handed out")
+}
+
+func TestRevertPaymentConfirmationSQL(t *testing.T) {
+	assert.Contains(t, revertPaymentConfirmation, "SET status = $1, confirmed_at = NULL, version = version + 1")
+	assert.Contains(t, revertPaymentConfirmation, "WHERE id = $2 AND status = 'CONFIRMED' AND version = $3")
+}
+
+func TestListRecentPendingPaymentsSQL(t *testing.T) {
+	assert.Contains(t, listRecentPendingPayments, "WHERE status = 'PENDING' AND created_at >= $1 AND expires_at > now()")
+}
+
+func TestListExpiredPaymentsSQL(t *testing.T) {
+	assert.Contains(t, listExpiredPayments, "WHERE status IN ('PENDING', 'DETECTED', 'UNDERPAID') AND expires_at <= $1",
+		"every non-terminal status expires")
+}
+
+func TestQueries_ListExpiredPayments(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	id := uuid.New()
+
+	mockRows := new(MockRows)
+	arg := ListExpiredPaymentsParams{ExpiredBefore: pgtype.Timestamptz{Time: time.Now(), Valid: true}, Mode: "live", Limit: 10}
+	mockDB.On("Query", ctx, listExpiredPayments, []interface{}{arg.ExpiredBefore, arg.Mode, arg.Limit}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 21)
+		*dest[0].(*uuid.UUID) = id
+		*dest[5].(*string) = PaymentDetected
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	payments, err := queries.ListExpiredPayments(ctx, arg)
+
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+	assert.Equal(t, id, payments[0].ID)
+	assert.Equal(t, PaymentDetected, payments[0].Status)
+	mockDB.AssertExpectations(t)
+}
+
+func TestListClientPaymentsSQL(t *testing.T) {
+	assert.Contains(t, listClientPayments, "WHERE client_id = $1 AND id > $2\nORDER BY id\nLIMIT $3",
+		"pages are keyed on id so the page token is the last id")
+}
+
+func TestQueries_ListClientPayments(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	id := uuid.New()
+
+	mockRows := new(MockRows)
+	arg := ListClientPaymentsParams{ClientID: uuid.New(), AfterID: uuid.New(), Limit: 51}
+	mockDB.On("Query", ctx, listClientPayments, []interface{}{arg.ClientID, arg.AfterID, arg.Limit}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 21)
+		*dest[0].(*uuid.UUID) = id
+		*dest[15].(*string) = "TRX"
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	payments, err := queries.ListClientPayments(ctx, arg)
+
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+	assert.Equal(t, id, payments[0].ID)
+	assert.Equal(t, "TRX", payments[0].Token)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_ListPayments(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	id := uuid.New()
+	status := PaymentPending
+
+	mockRows := new(MockRows)
+	arg := ListPaymentsParams{AfterID: uuid.New(), Status: &status, Limit: 20}
+	mockDB.On("Query", ctx, listPayments, []interface{}{arg.AfterID, arg.Status, arg.Limit}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 21)
+		*dest[0].(*uuid.UUID) = id
+		*dest[5].(*string) = PaymentPending
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	payments, err := queries.ListPayments(ctx, arg)
+
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+	assert.Equal(t, id, payments[0].ID)
+	assert.Equal(t, PaymentPending, payments[0].Status)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_ListPaymentExport(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	status := PaymentConfirmed
+	now := time.Now()
+	arg := ListPaymentExportParams{
+		ClientID:       uuid.New(),
+		CreatedFrom:    pgtype.Timestamptz{Time: now.Add(-time.Hour), Valid: true},
+		CreatedTo:      pgtype.Timestamptz{Time: now, Valid: true},
+		Status:         &status,
+		AfterCreatedAt: pgtype.Timestamptz{Time: now.Add(-time.Minute), Valid: true},
+		AfterID:        uuid.New(),
+		Limit:          500,
+	}
+	txHash := "f00d"
+
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, listPaymentExport, []interface{}{
+		arg.ClientID, arg.CreatedFrom, arg.CreatedTo, arg.Status, arg.AfterCreatedAt, arg.AfterID, arg.Limit,
+	}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 9)
+		*dest[1].(*string) = "Acme, Inc."
+		*dest[8].(**string) = &txHash
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	rows, err := queries.ListPaymentExport(ctx, arg)
+
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "Acme, Inc.", rows[0].AccountName)
+	assert.Equal(t, &txHash, rows[0].TxHash)
+	assert.Contains(t, listPaymentExport, "(p.created_at, p.id) > ($5::TIMESTAMPTZ, $6::UUID)")
+	assert.Contains(t, listPaymentExport, "ORDER BY p.created_at, p.id")
+	mockDB.AssertExpectations(t)
+}
+
+func TestListPaymentsSQL(t *testing.T) {
+	assert.Contains(t, listPayments, "($2::STRING IS NULL OR status = $2)", "a nil status lists every payment")
+}
+
+func TestUpdatePaymentStatusSQL(t *testing.T) {
+	assert.Contains(t, updatePaymentStatus, "SET status = $1, version = version + 1")
+	assert.Contains(t, updatePaymentStatus, "WHERE id = $2 AND status = $3 AND version = $4", "UpdatePaymentStatus must compare-and-set on the old status and version")
+}
+
+func TestQueries_ListPaymentStatuses(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	ids := []uuid.UUID{uuid.New()}
+
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, listPaymentStatuses, []interface{}{ids}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 2)
+		*dest[0].(*uuid.UUID) = ids[0]
+		*dest[1].(*string) = PaymentConfirmed
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	rows, err := queries.ListPaymentStatuses(ctx, ids)
+
+	require.NoError(t, err)
+	assert.Equal(t, []ListPaymentStatusesRow{{ID: ids[0], Status: PaymentConfirmed}}, rows)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_CountPendingPaymentsByAccountID(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	accountID := uuid.New()
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, countPendingPaymentsByAccountID, []interface{}{accountID}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 1)
+		*dest[0].(*int64) = 7
+	})
+
+	n, err := queries.CountPendingPaymentsByAccountID(ctx, accountID)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), n)
+	assert.Equal(t, "-- name: CountPendingPaymentsByAccountID :one\nSELECT count(*) FROM payments\nWHERE account_id = $1 AND status IN ('PENDING', 'DETECTED')\n", countPendingPaymentsByAccountID)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_PaymentExists(t *testing.T) {
+	for _, exists := range []bool{true, false} {
+		mockDB := new(MockDBTX)
+		queries := New(mockDB)
+		ctx := context.Background()
+		arg := PaymentExistsParams{ID: uuid.New(), ClientID: uuid.New()}
+
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, paymentExists, []interface{}{arg.ID, arg.ClientID}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			dest := args.Get(0).([]interface{})
+			require.Len(t, dest, 1)
+			*dest[0].(*bool) = exists
+		})
+
+		got, err := queries.PaymentExists(ctx, arg)
+
+		require.NoError(t, err)
+		assert.Equal(t, exists, got)
+		mockDB.AssertExpectations(t)
+	}
+	assert.Equal(t, "-- name: PaymentExists :one\nSELECT EXISTS (SELECT 1 FROM payments WHERE id = $1 AND client_id = $2)\n", paymentExists)
+}
+
+func TestQueries_CountClientPaymentsByStatus(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	today := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	arg := CountClientPaymentsByStatusParams{
+		TodayFrom: pgtype.Timestamptz{Time: today, Valid: true},
+		WeekFrom:  pgtype.Timestamptz{Time: today.AddDate(0, 0, -6), Valid: true},
+		ClientID:  uuid.New(),
+		MonthFrom: pgtype.Timestamptz{Time: today.AddDate(0, 0, -29), Valid: true},
+	}
+
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, countClientPaymentsByStatus,
+		[]interface{}{arg.TodayFrom, arg.WeekFrom, arg.ClientID, arg.MonthFrom}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 4)
+		*dest[0].(*string) = PaymentConfirmed
+		*dest[1].(*int64) = 1
+		*dest[2].(*int64) = 4
+		*dest[3].(*int64) = 9
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	rows, err := queries.CountClientPaymentsByStatus(ctx, arg)
+
+	require.NoError(t, err)
+	assert.Equal(t, []CountClientPaymentsByStatusRow{{Status: PaymentConfirmed, TodayCount: 1, WeekCount: 4, MonthCount: 9}}, rows)
+	assert.Contains(t, countClientPaymentsByStatus, "WHERE client_id = $3 AND created_at >= $4\nGROUP BY status",
+		"one pass over the client's last month of payments")
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_GetDailyConfirmedVolume(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	arg := GetDailyConfirmedVolumeParams{
+		ClientID:      uuid.New(),
+		ConfirmedFrom: pgtype.Timestamptz{Time: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+	}
+	day := time.Date(2026, 2, 14, 0, 0, 0, 0, time.UTC)
+
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, getDailyConfirmedVolume, []interface{}{arg.ClientID, arg.ConfirmedFrom}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 4)
+		*dest[0].(*pgtype.Date) = pgtype.Date{Time: day, Valid: true}
+		*dest[1].(*string) = "USDT"
+		*dest[2].(*pgtype.Numeric) = pgtype.Numeric{Int: big.NewInt(5100), Exp: -2, Valid: true}
+		*dest[3].(*int64) = 2
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	rows, err := queries.GetDailyConfirmedVolume(ctx, arg)
+
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, day, rows[0].Day.Time)
+	assert.Equal(t, "USDT", rows[0].Token)
+	assert.Equal(t, int64(2), rows[0].Payments)
+	assert.Contains(t, getDailyConfirmedVolume, "status = 'CONFIRMED' AND confirmed_at >= $2")
+	assert.Contains(t, getDailyConfirmedVolume, "(confirmed_at AT TIME ZONE 'UTC')::DATE AS day", "days are UTC days")
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_GetPaymentFunnel(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	clientID := uuid.New()
+	arg := GetPaymentFunnelParams{
+		Bucket:      "day",
+		CreatedFrom: pgtype.Timestamptz{Time: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+		CreatedTo:   pgtype.Timestamptz{Time: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+		ClientID:    &clientID,
+	}
+	day := time.Date(2026, 2, 14, 0, 0, 0, 0, time.UTC)
+
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, getPaymentFunnel, []interface{}{arg.Bucket, arg.CreatedFrom, arg.CreatedTo, arg.ClientID}).
+		Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 5)
+		*dest[0].(*pgtype.Timestamp) = pgtype.Timestamp{Time: day, Valid: true}
+		*dest[1].(*int64) = 10
+		*dest[2].(*int64) = 7
+		*dest[3].(*int64) = 6
+		*dest[4].(*int64) = 3
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	rows, err := queries.GetPaymentFunnel(ctx, arg)
+
+	require.NoError(t, err)
+	assert.Equal(t, []GetPaymentFunnelRow{{
+		Bucket:    pgtype.Timestamp{Time: day, Valid: true},
+		Created:   10,
+		Detected:  7,
+		Confirmed: 6,
+		Expired:   3,
+	}}, rows)
+	mockDB.AssertExpectations(t)
+}
+
+func TestGetPaymentFunnelSQL(t *testing.T) {
+	assert.Contains(t, getPaymentFunnel, "date_trunc($1::STRING, created_at AT TIME ZONE 'UTC')", "buckets are UTC")
+	assert.Contains(t, getPaymentFunnel, "created_at >= $2 AND created_at < $3", "the window is half open")
+	assert.Contains(t, getPaymentFunnel, "($4::UUID IS NULL OR client_id = $4)")
+	assert.Contains(t, getPaymentFunnel, "status = 'CONFIRMED' AND confirmed_at <= expires_at",
+		"only payments confirmed within their window convert")
+	assert.Contains(t, getPaymentFunnel, "GROUP BY bucket", "one grouped query for the whole range")
+}
+
+func TestQueries_ListRecentClientPayments(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	id := uuid.New()
+
+	mockRows := new(MockRows)
+	arg := ListRecentClientPaymentsParams{ClientID: uuid.New(), Limit: 10}
+	mockDB.On("Query", ctx, listRecentClientPayments, []interface{}{arg.ClientID, arg.Limit}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 21)
+		*dest[0].(*uuid.UUID) = id
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	payments, err := queries.ListRecentClientPayments(ctx, arg)
+
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+	assert.Equal(t, id, payments[0].ID)
+	assert.Contains(t, listRecentClientPayments, "ORDER BY created_at DESC, id DESC\nLIMIT $2", "newest first")
+	mockDB.AssertExpectations(t)
+}
+
+func TestSearchPaymentsSQL(t *testing.T) {
+	assert.Contains(t, searchPaymentsByWallet, "unique_wallet = $2::STRING\n       OR id IN (SELECT payment_id FROM payment_attempts WHERE generated_wallet = $2::STRING)",
+		"any attempt's wallet matches")
+	assert.Contains(t, searchPaymentsByTxHash, "WHERE tx_hash = $2::STRING AND status != 'ORPHANED'")
+	for _, sql := range []string{searchPaymentsByWallet, searchPaymentsByTxHash} {
+		assert.Contains(t, sql, "WHERE client_id = $1\n")
+		assert.Contains(t, sql, "ORDER BY created_at DESC, id DESC\nLIMIT $3")
+	}
+}
+
+func TestQueries_SearchPaymentsByWallet(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	id := uuid.New()
+
+	mockRows := new(MockRows)
+	arg := SearchPaymentsByWalletParams{ClientID: uuid.New(), Wallet: "TWallet1", Limit: 20}
+	mockDB.On("Query", ctx, searchPaymentsByWallet, []interface{}{arg.ClientID, arg.Wallet, arg.Limit}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 21)
+		*dest[0].(*uuid.UUID) = id
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	payments, err := queries.SearchPaymentsByWallet(ctx, arg)
+
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+	assert.Equal(t, id, payments[0].ID)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_SearchPaymentsByTxHash_QueryError(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+
+	arg := SearchPaymentsByTxHashParams{ClientID: uuid.New(), TxHash: "abc", Limit: 20}
+	mockDB.On("Query", ctx, searchPaymentsByTxHash, []interface{}{arg.ClientID, arg.TxHash, arg.Limit}).Return(nil, errors.New("db down"))
+
+	_, err := queries.SearchPaymentsByTxHash(ctx, arg)
+
+	require.Error(t, err)
+	mockDB.AssertExpectations(t)
+}
+
+func TestGetOpenPaymentByWalletAndAmountSQL(t *testing.T) {
+	assert.Contains(t, getOpenPaymentByWalletAndAmount, "WHERE unique_wallet = $1 AND amount = $2 AND mode = $3")
+	assert.Contains(t, getOpenPaymentByWalletAndAmount, "AND wallet_index IS NULL",
+		"only payments sharing a deposit wallet are told apart by amount")
+	assert.Contains(t, getOpenPaymentByWalletAndAmount, "status IN ('PENDING', 'DETECTED', 'UNDERPAID')")
+}
@@ -8,15 +8,249 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
-	CreateAccount(ctx context.Context, arg CreateAccountParams) error
-	CreateClient(ctx context.Context, arg CreateClientParams) error
+	AcquireLease(ctx context.Context, arg AcquireLeaseParams) (Lease, error)
+	// Takes count consecutive indexes from the counter at once, for the address
+	// pool, returning the first of them.
+	AllocateWalletIndexes(ctx context.Context, arg AllocateWalletIndexesParams) (int64, error)
+	CancelPayment(ctx context.Context, arg CancelPaymentParams) (Payment, error)
+	// Claims the key for a request. A key held by the same request whose claim
+	// has not been renewed for stale_after was left IN_PROGRESS by a process that
+	// died mid-request and is claimed again; any other held key is not.
+	ClaimIdempotencyKey(ctx context.Context, arg ClaimIdempotencyKeyParams) (IdempotencyKey, error)
+	ClaimOutboxEvents(ctx context.Context, limit int32) ([]Outbox, error)
+	// Claims up to limit due deliveries, oldest first, by moving their
+	// next_attempt_at to claimed_until. Workers polling at once skip each other's
+	// rows, so each delivery is sent by one of them. Recording the attempt sets
+	// next_attempt_at again; if the worker dies first, the claim lapses at
+	// claimed_until and the delivery is sent again. Each row carries its
+	// claimed_until, which the queries recording the attempt match, so a worker
+	// whose claim lapsed and was taken over cannot overwrite the new one.
+	ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]ClaimWebhookDeliveriesRow, error)
+	// Drops the secrets replaced at or before rotated_before, whose overlap
+	// window has ended.
+	ClearRotatedWebhookSecrets(ctx context.Context, rotatedBefore pgtype.Timestamptz) (int64, error)
+	// Reports whether any client, active or not, holds the API key.
+	ClientExistsByAPIKey(ctx context.Context, apiKey string) (bool, error)
+	CompleteIdempotencyKey(ctx context.Context, arg CompleteIdempotencyKeyParams) error
+	// Refuses a payment whose live deposits fall short of its amount by more
+	// than tolerance_bps, whatever status it was left in.
+	ConfirmPayment(ctx context.Context, arg ConfirmPaymentParams) (Payment, error)
+	// Counts the accounts of the client that are not deleted.
+	CountAccountsByClientID(ctx context.Context, clientID uuid.UUID) (int64, error)
+	// Counts the client's payments created since month_from by status, and how
+	// many of them were created since week_from and today_from.
+	CountClientPaymentsByStatus(ctx context.Context, arg CountClientPaymentsByStatusParams) ([]CountClientPaymentsByStatusRow, error)
+	CountLogsOlderThan(ctx context.Context, arg CountLogsOlderThanParams) (int64, error)
+	// Counts the payments of the account still waiting for funds.
+	CountPendingPaymentsByAccountID(ctx context.Context, accountID uuid.UUID) (int64, error)
+	// Creates an account of the client; a nil address_strategy gives it a
+	// wallet per payment.
+	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateClient(ctx context.Context, arg CreateClientParams) (Client, error)
+	// Returns no row when the client's invoice for the month already exists.
+	CreateInvoice(ctx context.Context, arg CreateInvoiceParams) (Invoice, error)
+	CreateInvoiceLine(ctx context.Context, arg CreateInvoiceLineParams) error
+	CreateLog(ctx context.Context, arg CreateLogParams) error
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) error
+	// A payment whose wallet comes from the address pool is created with the id
+	// the pool reserved the wallet for; any other gets a random one.
+	CreatePayment(ctx context.Context, arg CreatePaymentParams) (Payment, error)
+	// The attempt count is bumped without touching the version: a new attempt
+	// is only ever recorded with CreatePayment or UpdatePaymentWallet, which
+	// set the version.
+	CreatePaymentAttempt(ctx context.Context, arg CreatePaymentAttemptParams) (PaymentAttempt, error)
+	// Adds derived addresses to the account's pool, address_indexes[i] being
+	// the index addresses[i] was derived at.
+	CreatePoolAddresses(ctx context.Context, arg CreatePoolAddressesParams) (int64, error)
+	CreateReconciliationMismatch(ctx context.Context, arg CreateReconciliationMismatchParams) error
+	CreateReconciliationReport(ctx context.Context, arg CreateReconciliationReportParams) (ReconciliationReport, error)
+	CreateSweep(ctx context.Context, arg CreateSweepParams) (Sweep, error)
+	CreateSweepTransaction(ctx context.Context, arg CreateSweepTransactionParams) (Transaction, error)
+	CreateTransaction(ctx context.Context, arg CreateTransactionParams) (Transaction, error)
+	CreateWebhookDeliveries(ctx context.Context, arg CreateWebhookDeliveriesParams) error
+	DeactivateClient(ctx context.Context, id uuid.UUID) (Client, error)
+	// Leaves the wallet out of the sweep candidates until next_check_at.
+	DeferSweepCandidate(ctx context.Context, arg DeferSweepCandidateParams) error
+	// Postpones a delivery without counting an attempt, as when its endpoint's
+	// host is being held back after failing.
+	DeferWebhookDelivery(ctx context.Context, arg DeferWebhookDeliveryParams) error
+	// Deletes the oldest logs of an event type created before a cutoff, at
+	// most limit of them, so each delete stays a small transaction.
+	DeleteLogsBatch(ctx context.Context, arg DeleteLogsBatchParams) (int64, error)
+	// Marks a key whose request succeeded but whose response could not be
+	// stored, so it is never claimed again.
+	FailIdempotencyKey(ctx context.Context, arg FailIdempotencyKeyParams) error
+	FailWebhookDelivery(ctx context.Context, arg FailWebhookDeliveryParams) error
+	// Returns an account of the client; a soft deleted one only when
+	// include_deleted is set.
 	GetAccountByIDAndClientID(ctx context.Context, arg GetAccountByIDAndClientIDParams) (Account, error)
-	GetAccountsByClientID(ctx context.Context, clientID uuid.UUID) ([]GetAccountsByClientIDRow, error)
+	// Lists the accounts of the client, leaving out soft deleted ones unless
+	// include_deleted is set.
+	GetAccountsByClientID(ctx context.Context, arg GetAccountsByClientIDParams) ([]GetAccountsByClientIDRow, error)
+	GetActiveWebhookEndpoint(ctx context.Context, arg GetActiveWebhookEndpointParams) (WebhookEndpoint, error)
 	GetClientByAPIKey(ctx context.Context, apiKey string) (Client, error)
 	GetClientByID(ctx context.Context, id uuid.UUID) (Client, error)
+	// The client's confirmed volume per UTC day and token since confirmed_from.
+	GetDailyConfirmedVolume(ctx context.Context, arg GetDailyConfirmedVolumeParams) ([]GetDailyConfirmedVolumeRow, error)
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	GetInvoiceByClientMonth(ctx context.Context, arg GetInvoiceByClientMonthParams) (Invoice, error)
+	GetLatestReconciliationReport(ctx context.Context) (ReconciliationReport, error)
+	// The client's live payments confirmed in [month_start, month_end) per token
+	// of the fee schedule given by tokens, percents and minimums, with how many
+	// of them, and how much of their amount, had a percentage fee under the
+	// token's minimum. Tokens left out of the schedule are left out of the sums.
+	GetMonthlyFeeBasis(ctx context.Context, arg GetMonthlyFeeBasisParams) ([]GetMonthlyFeeBasisRow, error)
+	// The payment awaiting funds of exactly amount among those sharing wallet,
+	// the deposit wallet of an amount_suffix account, in mode. Their amounts
+	// differ by their suffixes, so at most one matches.
+	GetOpenPaymentByWalletAndAmount(ctx context.Context, arg GetOpenPaymentByWalletAndAmountParams) (Payment, error)
+	GetPayment(ctx context.Context, id uuid.UUID) (Payment, error)
+	// The client's payment created with the given order reference.
+	GetPaymentByOrderReference(ctx context.Context, arg GetPaymentByOrderReferenceParams) (Payment, error)
+	GetPaymentByUniqueWallet(ctx context.Context, uniqueWallet string) (Payment, error)
+	GetPaymentByWallet(ctx context.Context, arg GetPaymentByWalletParams) (Payment, error)
+	// The payments created in [created_from, created_to) per UTC hour or day,
+	// as bucket is 'hour' or 'day', with how many of them a transfer was seen
+	// for, were confirmed before they expired, and expired. Every client's
+	// payments unless client_id is set.
+	GetPaymentFunnel(ctx context.Context, arg GetPaymentFunnelParams) ([]GetPaymentFunnelRow, error)
+	// The payment awaiting funds that was given wallet by any of its attempts,
+	// the current one included; settled payments are left to GetPaymentByWallet.
+	GetPendingPaymentByAnyWallet(ctx context.Context, wallet string) (Payment, error)
+	GetReconciliationReport(ctx context.Context, id uuid.UUID) (ReconciliationReport, error)
+	// What was deposited to and swept from a wallet in token, across all of the
+	// payments it was recorded for.
+	GetWalletFlows(ctx context.Context, arg GetWalletFlowsParams) (GetWalletFlowsRow, error)
+	GetWatcherHeight(ctx context.Context, name string) (int64, error)
+	GetWatcherState(ctx context.Context, name string) (GetWatcherStateRow, error)
+	// A delivery to one of the client's endpoints.
+	GetWebhookDelivery(ctx context.Context, arg GetWebhookDeliveryParams) (GetWebhookDeliveryRow, error)
+	// Accounts of active clients, deleted ones aside, with fewer than low_water
+	// unreserved pooled addresses, and the mode their addresses derive in.
+	ListAccountsBelowLowWater(ctx context.Context, lowWater int64) ([]ListAccountsBelowLowWaterRow, error)
+	// Payments whose attempt_count is not the number of their attempts. One
+	// with neither predates attempt tracking and is left out.
+	ListAttemptCountDrift(ctx context.Context) ([]ListAttemptCountDriftRow, error)
+	// A page of the logs of a client's payments, newest first, filtered as in
+	// ListLogs. A log of no payment belongs to no client and is never listed.
+	ListClientLogs(ctx context.Context, arg ListClientLogsParams) ([]Log, error)
+	ListClientPayments(ctx context.Context, arg ListClientPaymentsParams) ([]Payment, error)
+	ListClients(ctx context.Context, arg ListClientsParams) ([]Client, error)
+	// Confirmed payments without a single recorded deposit.
+	ListConfirmedWithoutDeposit(ctx context.Context) ([]ListConfirmedWithoutDepositRow, error)
+	// The wallets among wallets that are the deposit wallet of an account,
+	// shared by its amount_suffix payments.
+	ListDepositWallets(ctx context.Context, wallets []string) ([]string, error)
+	ListDetectedTransactions(ctx context.Context, mode string) ([]Transaction, error)
+	ListExpiredPayments(ctx context.Context, arg ListExpiredPaymentsParams) ([]Payment, error)
+	ListInvoiceLines(ctx context.Context, invoiceIds []uuid.UUID) ([]InvoiceLine, error)
+	// A page of the invoices, newest month first, of client_id and month; a NULL
+	// filter matches every invoice.
+	ListInvoices(ctx context.Context, arg ListInvoicesParams) ([]Invoice, error)
+	ListLogEventTypes(ctx context.Context) ([]string, error)
+	// A page of the logs, newest first, of event_type and payment_id and created
+	// in [from, to); a NULL filter matches every log. idx_logs_event_type_created_at
+	// serves a filter on the event type.
+	ListLogs(ctx context.Context, arg ListLogsParams) ([]Log, error)
+	ListOpenSweeps(ctx context.Context) ([]Sweep, error)
+	ListPaymentAttempts(ctx context.Context, paymentIds []uuid.UUID) ([]PaymentAttempt, error)
+	// A page of the payments a client created in [created_from, created_to),
+	// after the (created_at, id) key of the previous page.
+	ListPaymentExport(ctx context.Context, arg ListPaymentExportParams) ([]ListPaymentExportRow, error)
+	ListPaymentStatuses(ctx context.Context, ids []uuid.UUID) ([]ListPaymentStatusesRow, error)
+	ListPayments(ctx context.Context, arg ListPaymentsParams) ([]Payment, error)
+	ListPaymentTransactions(ctx context.Context, paymentID uuid.UUID) ([]Transaction, error)
+	// The newest attempts that recorded the index their wallet was derived at,
+	// with the account and mode of their payment.
+	ListRecentAttemptWallets(ctx context.Context, limit int32) ([]ListRecentAttemptWalletsRow, error)
+	ListReconciliationMismatches(ctx context.Context, reportID uuid.UUID) ([]ReconciliationMismatch, error)
+	// Confirmed live payments whose confirmation falls in [since, until).
+	// shared_wallet marks those paid to their account's deposit wallet, whose
+	// balance belongs to all of the account's payments.
+	ListReconciliationPayments(ctx context.Context, arg ListReconciliationPaymentsParams) ([]ListReconciliationPaymentsRow, error)
+	// The client's newest payments.
+	ListRecentClientPayments(ctx context.Context, arg ListRecentClientPaymentsParams) ([]Payment, error)
+	ListRecentPendingPayments(ctx context.Context, arg ListRecentPendingPaymentsParams) ([]Payment, error)
+	ListSweepCandidates(ctx context.Context, limit int32) ([]ListSweepCandidatesRow, error)
+	ListTransactionsByBlockHashes(ctx context.Context, blockHashes []string) ([]Transaction, error)
+	// Payments whose wallet is not the one their latest attempt generated.
+	ListWalletDrift(ctx context.Context) ([]ListWalletDriftRow, error)
+	// A page of the deliveries to the client's endpoints, newest first, after
+	// before_id, the delivery the previous page ended with.
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]ListWebhookDeliveriesRow, error)
+	MarkOutboxEventsProcessed(ctx context.Context, ids []uuid.UUID) error
+	MarkWebhookDelivered(ctx context.Context, arg MarkWebhookDeliveredParams) error
+	// Each mode derives its wallets from its own mnemonic, so each has its own
+	// counter, created by its first payment.
+	NextWalletIndex(ctx context.Context, name string) (int64, error)
+	PaymentExists(ctx context.Context, arg PaymentExistsParams) (bool, error)
+	ReleaseIdempotencyKey(ctx context.Context, arg ReleaseIdempotencyKeyParams) error
+	ReleaseLease(ctx context.Context, arg ReleaseLeaseParams) error
+	// Renews the claim of a request still running, so it does not go stale.
+	RenewIdempotencyKey(ctx context.Context, arg RenewIdempotencyKeyParams) error
+	RenewLease(ctx context.Context, arg RenewLeaseParams) (Lease, error)
+	// Replaces the name and settings of an account of the client. A nil
+	// address_strategy gives it a wallet per payment.
+	ReplaceAccount(ctx context.Context, arg ReplaceAccountParams) (Account, error)
+	// Claims the account's lowest unreserved pooled address for payment_id.
+	// Rows another transaction is claiming are skipped rather than waited on,
+	// so concurrent claims never return the same address.
+	ReserveAddress(ctx context.Context, arg ReserveAddressParams) (AddressPool, error)
+	// Reserves the account's lowest suffix no unexpired reservation holds for
+	// payment_id until expires_at, overwriting the expired reservation of it if
+	// there is one. It returns no row when every suffix is held.
+	ReserveAmountSuffix(ctx context.Context, arg ReserveAmountSuffixParams) (int32, error)
+	// Queues a copy of a delivery to one of the client's active endpoints as a
+	// new PENDING delivery. The original is left as it is.
+	ReplayWebhookDelivery(ctx context.Context, arg ReplayWebhookDeliveryParams) (WebhookDelivery, error)
+	RescheduleWebhookDelivery(ctx context.Context, arg RescheduleWebhookDeliveryParams) error
+	// Sets a watcher's position unconditionally and forgets its recent blocks,
+	// for an operator recovering a stuck watcher.
+	ResetWatcherState(ctx context.Context, arg ResetWatcherStateParams) error
+	RevertPaymentConfirmation(ctx context.Context, arg RevertPaymentConfirmationParams) (Payment, error)
+	RotateClientAPIKey(ctx context.Context, arg RotateClientAPIKeyParams) (Client, error)
+	// Makes secret the signing secret of one of the client's active endpoints,
+	// keeping the one it replaces as secondary_secret.
+	RotateWebhookEndpointSecret(ctx context.Context, arg RotateWebhookEndpointSecretParams) (WebhookEndpoint, error)
+	// The client's payments with a transfer, deposit or sweep, in the
+	// transaction. Orphaned transfers are left out.
+	SearchPaymentsByTxHash(ctx context.Context, arg SearchPaymentsByTxHashParams) ([]Payment, error)
+	// The client's payments that handed out the wallet, as their current wallet
+	// or in an earlier attempt, newest first.
+	SearchPaymentsByWallet(ctx context.Context, arg SearchPaymentsByWalletParams) ([]Payment, error)
+	// Gives the account the deposit wallet its amount_suffix payments pay into
+	// unless it has one already, and returns the one it has.
+	SetAccountDepositWallet(ctx context.Context, arg SetAccountDepositWalletParams) (SetAccountDepositWalletRow, error)
+	// Marks an account of the client deleted unless one of its payments is still
+	// PENDING or DETECTED.
+	SoftDeleteAccount(ctx context.Context, arg SoftDeleteAccountParams) (Account, error)
+	SumPaymentTransfers(ctx context.Context, paymentID uuid.UUID) (pgtype.Numeric, error)
+	// Sets the payment's attempt_count to the number of its attempts.
+	SyncAttemptCount(ctx context.Context, id uuid.UUID) (*int32, error)
+	// Points the payment at the wallet and index of its latest attempt.
+	SyncPaymentWallet(ctx context.Context, id uuid.UUID) (SyncPaymentWalletRow, error)
+	// Changes the fields given of an account of the client, keeping the others.
+	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdatePaymentStatus(ctx context.Context, arg UpdatePaymentStatusParams) (Payment, error)
+	UpdatePaymentWallet(ctx context.Context, arg UpdatePaymentWalletParams) (Payment, error)
+	UpdateSweepStatus(ctx context.Context, arg UpdateSweepStatusParams) (Sweep, error)
+	UpdateTransactionBlock(ctx context.Context, arg UpdateTransactionBlockParams) error
+	UpdateTransactionConfirmations(ctx context.Context, arg UpdateTransactionConfirmationsParams) error
+	UpsertAccount(ctx context.Context, arg UpsertAccountParams) (Account, error)
+	UpsertClient(ctx context.Context, arg UpsertClientParams) (Client, error)
+	UpsertLog(ctx context.Context, arg UpsertLogParams) error
+	UpsertPayment(ctx context.Context, arg UpsertPaymentParams) (Payment, error)
+	UpsertPaymentAttempt(ctx context.Context, arg UpsertPaymentAttemptParams) (PaymentAttempt, error)
+	// Only overwrites a row still at expected_height, or inserts one when
+	// expected_height is NULL, so two replicas of a watcher cannot interleave
+	// their writes. No row is returned when the check fails.
+	UpsertWatcherState(ctx context.Context, arg UpsertWatcherStateParams) (int64, error)
+	// Referencing tables come before the tables they reference.
+	WipeData(ctx context.Context) error
 }
 
 var _ Querier = (*Queries)(nil)
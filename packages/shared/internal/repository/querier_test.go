@@ -6,47 +6,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
 )
 
-// MockQuerier is a mock implementation of Querier interface
-type MockQuerier struct {
-	mock.Mock
-}
-
-func (m *MockQuerier) CreateAccount(ctx context.Context, arg CreateAccountParams) error {
-	args := m.Called(ctx, arg)
-	return args.Error(0)
-}
-
-func (m *MockQuerier) CreateClient(ctx context.Context, arg CreateClientParams) error {
-	args := m.Called(ctx, arg)
-	return args.Error(0)
-}
-
-func (m *MockQuerier) GetAccountByIDAndClientID(ctx context.Context, arg GetAccountByIDAndClientIDParams) (GetAccountByIDAndClientIDRow, error) {
-	args := m.Called(ctx, arg)
-	return args.Get(0).(GetAccountByIDAndClientIDRow), args.Error(1)
-}
-
-func (m *MockQuerier) GetAccountsByClientID(ctx context.Context, clientID uuid.UUID) ([]GetAccountsByClientIDRow, error) {
-	args := m.Called(ctx, clientID)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).([]GetAccountsByClientIDRow), args.Error(1)
-}
-
-func (m *MockQuerier) GetClientByAPIKey(ctx context.Context, apiKey string) (Client, error) {
-	args := m.Called(ctx, apiKey)
-	return args.Get(0).(Client), args.Error(1)
-}
-
-func (m *MockQuerier) GetClientByID(ctx context.Context, id uuid.UUID) (Client, error) {
-	args := m.Called(ctx, id)
-	return args.Get(0).(Client), args.Error(1)
-}
-
 func TestQuerier_Interface(t *testing.T) {
 	// Test that MockQuerier implements Querier interface
 	var _ Querier = (*MockQuerier)(nil)
@@ -65,11 +26,13 @@ func TestMockQuerier_CreateAccount(t *testing.T) {
 		Name:     "Test Account",
 	}
 
-	mockQuerier.On("CreateAccount", ctx, params).Return(nil)
+	expectedAccount := Account{ID: uuid.New(), ClientID: params.ClientID, Name: params.Name}
+	mockQuerier.On("CreateAccount", ctx, params).Return(expectedAccount, nil)
 
-	err := mockQuerier.CreateAccount(ctx, params)
+	account, err := mockQuerier.CreateAccount(ctx, params)
 
 	assert.NoError(t, err)
+	assert.Equal(t, expectedAccount, account)
 	mockQuerier.AssertExpectations(t)
 }
 
@@ -81,11 +44,13 @@ func TestMockQuerier_CreateClient(t *testing.T) {
 		ApiKey: "test-key",
 	}
 
-	mockQuerier.On("CreateClient", ctx, params).Return(nil)
+	expectedClient := Client{ID: uuid.New(), Name: params.Name, ApiKey: params.ApiKey}
+	mockQuerier.On("CreateClient", ctx, params).Return(expectedClient, nil)
 
-	err := mockQuerier.CreateClient(ctx, params)
+	client, err := mockQuerier.CreateClient(ctx, params)
 
 	assert.NoError(t, err)
+	assert.Equal(t, expectedClient, client)
 	mockQuerier.AssertExpectations(t)
 }
 
@@ -100,7 +65,7 @@ func TestMockQuerier_GetAccountByIDAndClientID(t *testing.T) {
 		ClientID: clientID,
 	}
 
-	expectedAccount := GetAccountByIDAndClientIDRow{
+	expectedAccount := Account{
 		ID:       id,
 		ClientID: clientID,
 		Name:     "Test Account",
@@ -133,9 +98,9 @@ func TestMockQuerier_GetAccountsByClientID(t *testing.T) {
 		},
 	}
 
-	mockQuerier.On("GetAccountsByClientID", ctx, clientID).Return(expectedAccounts, nil)
+	mockQuerier.On("GetAccountsByClientID", ctx, GetAccountsByClientIDParams{ClientID: clientID}).Return(expectedAccounts, nil)
 
-	accounts, err := mockQuerier.GetAccountsByClientID(ctx, clientID)
+	accounts, err := mockQuerier.GetAccountsByClientID(ctx, GetAccountsByClientIDParams{ClientID: clientID})
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedAccounts, accounts)
@@ -204,19 +169,20 @@ func TestMockQuerier_MultipleMethodCalls(t *testing.T) {
 		Name:     "Test Account",
 	}
 
-	mockQuerier.On("CreateClient", ctx, clientParams).Return(nil)
+	mockQuerier.On("CreateClient", ctx, clientParams).Return(client, nil)
 	mockQuerier.On("GetClientByAPIKey", ctx, clientParams.ApiKey).Return(client, nil)
-	mockQuerier.On("CreateAccount", ctx, accountParams).Return(nil)
+	mockQuerier.On("CreateAccount", ctx, accountParams).Return(Account{ClientID: clientID, Name: accountParams.Name}, nil)
 
 	// Execute
-	err := mockQuerier.CreateClient(ctx, clientParams)
+	created, err := mockQuerier.CreateClient(ctx, clientParams)
 	assert.NoError(t, err)
+	assert.Equal(t, client, created)
 
 	retrievedClient, err := mockQuerier.GetClientByAPIKey(ctx, clientParams.ApiKey)
 	assert.NoError(t, err)
 	assert.Equal(t, client, retrievedClient)
 
-	err = mockQuerier.CreateAccount(ctx, accountParams)
+	_, err = mockQuerier.CreateAccount(ctx, accountParams)
 	assert.NoError(t, err)
 
 	mockQuerier.AssertExpectations(t)
@@ -237,9 +203,9 @@ func TestMockQuerier_GetAccountsByClientID_EmptyResult(t *testing.T) {
 	ctx := context.Background()
 	clientID := uuid.New()
 
-	mockQuerier.On("GetAccountsByClientID", ctx, clientID).Return([]GetAccountsByClientIDRow{}, nil)
+	mockQuerier.On("GetAccountsByClientID", ctx, GetAccountsByClientIDParams{ClientID: clientID}).Return([]GetAccountsByClientIDRow{}, nil)
 
-	accounts, err := mockQuerier.GetAccountsByClientID(ctx, clientID)
+	accounts, err := mockQuerier.GetAccountsByClientID(ctx, GetAccountsByClientIDParams{ClientID: clientID})
 
 	assert.NoError(t, err)
 	assert.Empty(t, accounts)
@@ -251,9 +217,9 @@ func TestMockQuerier_GetAccountsByClientID_NilResult(t *testing.T) {
 	ctx := context.Background()
 	clientID := uuid.New()
 
-	mockQuerier.On("GetAccountsByClientID", ctx, clientID).Return(nil, nil)
+	mockQuerier.On("GetAccountsByClientID", ctx, GetAccountsByClientIDParams{ClientID: clientID}).Return(nil, nil)
 
-	accounts, err := mockQuerier.GetAccountsByClientID(ctx, clientID)
+	accounts, err := mockQuerier.GetAccountsByClientID(ctx, GetAccountsByClientIDParams{ClientID: clientID})
 
 	assert.NoError(t, err)
 	assert.Nil(t, accounts)
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueries_CreateReconciliationReport(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	now := time.Now()
+	params := CreateReconciliationReportParams{
+		WindowStart:     pgtype.Timestamptz{Time: now.Add(-24 * time.Hour), Valid: true},
+		WindowEnd:       pgtype.Timestamptz{Time: now, Valid: true},
+		PaymentsChecked: 40,
+		Mismatches:      3,
+	}
+	reportID := uuid.New()
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createReconciliationReport, []interface{}{
+		params.WindowStart, params.WindowEnd, params.PaymentsChecked, params.Mismatches,
+	}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 6)
+		*dest[0].(*uuid.UUID) = reportID
+		*dest[3].(*int32) = params.PaymentsChecked
+		*dest[4].(*int32) = params.Mismatches
+	})
+
+	report, err := queries.CreateReconciliationReport(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, reportID, report.ID)
+	assert.Equal(t, int32(3), report.Mismatches)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_CreateReconciliationMismatch(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	txHash := "f00d"
+	params := CreateReconciliationMismatchParams{
+		ReportID:  uuid.New(),
+		PaymentID: uuid.New(),
+		Kind:      "MISSING_TRANSFER",
+		Wallet:    "TDeposit",
+		Token:     "USDT",
+		TxHash:    &txHash,
+		Expected:  pgtype.Numeric{Valid: true},
+		Actual:    pgtype.Numeric{Valid: true},
+		Detail:    "transaction not found on chain",
+	}
+	mockDB.On("Exec", ctx, createReconciliationMismatch, []interface{}{
+		params.ReportID, params.PaymentID, params.Kind, params.Wallet, params.Token,
+		params.TxHash, params.Expected, params.Actual, params.Detail,
+	}).Return(nil, nil)
+
+	err := queries.CreateReconciliationMismatch(ctx, params)
+
+	require.NoError(t, err)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_GetLatestReconciliationReport_None(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, getLatestReconciliationReport, []interface{}(nil)).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+	_, err := queries.GetLatestReconciliationReport(ctx)
+
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+	assert.Contains(t, getLatestReconciliationReport, "ORDER BY created_at DESC")
+}
+
+func TestQueries_ListReconciliationMismatches(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	reportID := uuid.New()
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, listReconciliationMismatches, []interface{}{reportID}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 11)
+		*dest[1].(*uuid.UUID) = reportID
+		*dest[3].(*string) = "SWEEP_SHORTFALL"
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	mismatches, err := queries.ListReconciliationMismatches(ctx, reportID)
+
+	require.NoError(t, err)
+	require.Len(t, mismatches, 1)
+	assert.Equal(t, "SWEEP_SHORTFALL", mismatches[0].Kind)
+	assert.Nil(t, mismatches[0].TxHash)
+	mockRows.AssertExpectations(t)
+}
+
+func TestQueries_ListReconciliationPayments(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	now := time.Now()
+	params := ListReconciliationPaymentsParams{
+		Since: pgtype.Timestamptz{Time: now.Add(-time.Hour), Valid: true},
+		Until: pgtype.Timestamptz{Time: now, Valid: true},
+	}
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, listReconciliationPayments, []interface{}{params.Since, params.Until}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 6)
+		*dest[1].(*string) = "USDT"
+		*dest[2].(*string) = "TDeposit"
+		*dest[5].(*bool) = true
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	payments, err := queries.ListReconciliationPayments(ctx, params)
+
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+	assert.Equal(t, "TDeposit", payments[0].UniqueWallet)
+	assert.True(t, payments[0].SharedWallet)
+	assert.Contains(t, listReconciliationPayments, "p.status = 'CONFIRMED' AND p.confirmed_at >= $1 AND p.confirmed_at < $2")
+	assert.Contains(t, listReconciliationPayments, "a.deposit_wallet = p.unique_wallet")
+	mockRows.AssertExpectations(t)
+}
+
+func TestQueries_GetWalletFlows(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	params := GetWalletFlowsParams{Wallet: "TDeposit", Token: "USDT"}
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, getWalletFlows, []interface{}{params.Wallet, params.Token}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 2)
+		*dest[0].(*pgtype.Numeric) = pgtype.Numeric{Int: big.NewInt(30003), Exp: -3, Valid: true}
+	})
+
+	flows, err := queries.GetWalletFlows(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(30003), flows.Deposited.Int.Int64())
+	assert.Contains(t, getWalletFlows, "kind = 'DEPOSIT' AND to_address = $1")
+	assert.Contains(t, getWalletFlows, "kind = 'SWEEP' AND from_address = $1")
+	assert.Contains(t, getWalletFlows, "status != 'ORPHANED'")
+}
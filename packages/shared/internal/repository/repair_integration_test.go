@@ -0,0 +1,134 @@
+//go:build integration
+
+package repository
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// attempts records n attempts of payment, the last generating its current
+// wallet, and returns their wallets.
+func (f *fixture) attempts(payment Payment, n int32) []string {
+	f.t.Helper()
+	wallets := make([]string, n)
+	for i := range n {
+		wallets[i] = f.wallet()
+		if i == n-1 {
+			wallets[i] = payment.UniqueWallet
+		}
+		_, err := f.store.CreatePaymentAttempt(f.ctx, CreatePaymentAttemptParams{AttemptNumber: i + 1, PaymentID: payment.ID, GeneratedWallet: wallets[i]})
+		require.NoError(f.t, err)
+	}
+	return wallets
+}
+
+func containsPayment[T any](rows []T, id uuid.UUID, idOf func(T) uuid.UUID) (T, bool) {
+	for _, r := range rows {
+		if idOf(r) == id {
+			return r, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+func TestIntegration_AttemptCountDrift(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	account := f.account(f.client().ID)
+	consistent := f.payment(account)
+	f.attempts(consistent, 2)
+	drifted := f.payment(account)
+	f.attempts(drifted, 2)
+	legacy := f.payment(account)
+	_, err := f.pool.Exec(ctx, "UPDATE payments SET attempt_count = 1 WHERE id = $1", drifted.ID)
+	require.NoError(t, err)
+	_, err = f.pool.Exec(ctx, "UPDATE payments SET attempt_count = NULL WHERE id = $1", legacy.ID)
+	require.NoError(t, err)
+
+	rows, err := f.store.ListAttemptCountDrift(ctx)
+	require.NoError(t, err)
+	id := func(r ListAttemptCountDriftRow) uuid.UUID { return r.ID }
+	row, ok := containsPayment(rows, drifted.ID, id)
+	require.True(t, ok, "the seeded drift is found")
+	require.NotNil(t, row.AttemptCount)
+	assert.Equal(t, int32(1), *row.AttemptCount)
+	assert.Equal(t, int64(2), row.Attempts)
+	_, ok = containsPayment(rows, consistent.ID, id)
+	assert.False(t, ok)
+	_, ok = containsPayment(rows, legacy.ID, id)
+	assert.False(t, ok, "a payment from before attempts were tracked")
+
+	count, err := f.store.SyncAttemptCount(ctx, drifted.ID)
+	require.NoError(t, err)
+	require.NotNil(t, count)
+	assert.Equal(t, int32(2), *count)
+	rows, err = f.store.ListAttemptCountDrift(ctx)
+	require.NoError(t, err)
+	_, ok = containsPayment(rows, drifted.ID, id)
+	assert.False(t, ok, "repaired")
+}
+
+func TestIntegration_WalletDrift(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	account := f.account(f.client().ID)
+	payment := f.payment(account)
+	wallets := f.attempts(payment, 2)
+	index, err := f.store.NextWalletIndex(ctx, "deposit")
+	require.NoError(t, err)
+	_, err = f.pool.Exec(ctx, "UPDATE payment_attempts SET wallet_index = $1 WHERE payment_id = $2 AND attempt_number = 2", index, payment.ID)
+	require.NoError(t, err)
+	// The payment went back to its first wallet, as a lost regeneration
+	// could leave it.
+	_, err = f.pool.Exec(ctx, "UPDATE payments SET unique_wallet = $1 WHERE id = $2", wallets[0], payment.ID)
+	require.NoError(t, err)
+
+	rows, err := f.store.ListWalletDrift(ctx)
+	require.NoError(t, err)
+	id := func(r ListWalletDriftRow) uuid.UUID { return r.ID }
+	row, ok := containsPayment(rows, payment.ID, id)
+	require.True(t, ok)
+	assert.Equal(t, ListWalletDriftRow{ID: payment.ID, UniqueWallet: wallets[0], AttemptNumber: 2, LatestWallet: wallets[1]}, row)
+
+	synced, err := f.store.SyncPaymentWallet(ctx, payment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, SyncPaymentWalletRow{UniqueWallet: wallets[1], WalletIndex: &index}, synced)
+	rows, err = f.store.ListWalletDrift(ctx)
+	require.NoError(t, err)
+	_, ok = containsPayment(rows, payment.ID, id)
+	assert.False(t, ok, "repaired")
+}
+
+func TestIntegration_ConfirmedWithoutDeposit(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	account := f.account(f.client().ID)
+	missing := f.payment(account)
+	// ConfirmPayment refuses it; releases that did not check deposits could
+	// leave it so.
+	_, err := f.pool.Exec(ctx, "UPDATE payments SET status = 'CONFIRMED', confirmed_at = now() WHERE id = $1", missing.ID)
+	require.NoError(t, err)
+	deposited := f.payment(account)
+	f.deposit(deposited)
+	detected, err := f.store.UpdatePaymentStatus(ctx, UpdatePaymentStatusParams{ID: deposited.ID, FromStatus: PaymentPending, ToStatus: PaymentDetected, Version: deposited.Version})
+	require.NoError(t, err)
+	_, err = f.store.ConfirmPayment(ctx, ConfirmPaymentParams{ID: deposited.ID, Version: detected.Version})
+	require.NoError(t, err)
+	pending := f.payment(account)
+
+	rows, err := f.store.ListConfirmedWithoutDeposit(ctx)
+	require.NoError(t, err)
+	id := func(r ListConfirmedWithoutDepositRow) uuid.UUID { return r.ID }
+	row, ok := containsPayment(rows, missing.ID, id)
+	require.True(t, ok)
+	assert.Equal(t, missing.UniqueWallet, row.UniqueWallet)
+	_, ok = containsPayment(rows, deposited.ID, id)
+	assert.False(t, ok)
+	_, ok = containsPayment(rows, pending.ID, id)
+	assert.False(t, ok, "only confirmed payments need a deposit")
+}
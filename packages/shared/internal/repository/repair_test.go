@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueries_ListAttemptCountDrift(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	id := uuid.New()
+	count := int32(1)
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, listAttemptCountDrift, []interface{}(nil)).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 3)
+		*dest[0].(*uuid.UUID) = id
+		*dest[1].(**int32) = &count
+		*dest[2].(*int64) = 3
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	rows, err := queries.ListAttemptCountDrift(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, []ListAttemptCountDriftRow{{ID: id, AttemptCount: &count, Attempts: 3}}, rows)
+	assert.Contains(t, listAttemptCountDrift, "HAVING p.attempt_count IS DISTINCT FROM count(pa.id)", "a NULL count is drift too")
+	assert.Contains(t, listAttemptCountDrift, "NOT (p.attempt_count IS NULL AND count(pa.id) = 0)", "payments from before attempts were tracked are left out")
+}
+
+func TestQueries_ListWalletDrift(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, listWalletDrift, []interface{}(nil)).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 4)
+		*dest[1].(*string) = "TFirst"
+		*dest[2].(*int32) = 2
+		*dest[3].(*string) = "TSecond"
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	rows, err := queries.ListWalletDrift(ctx)
+
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "TFirst", rows[0].UniqueWallet)
+	assert.Equal(t, int32(2), rows[0].AttemptNumber)
+	assert.Equal(t, "TSecond", rows[0].LatestWallet)
+	assert.Contains(t, listWalletDrift, "ORDER BY payment_id, attempt_number DESC", "the latest attempt is the one compared")
+}
+
+func TestQueries_ListConfirmedWithoutDeposit(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, listConfirmedWithoutDeposit, []interface{}(nil)).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 3)
+		*dest[1].(*string) = "TWallet"
+		*dest[2].(*string) = "USDT"
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	rows, err := queries.ListConfirmedWithoutDeposit(ctx)
+
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "TWallet", rows[0].UniqueWallet)
+	assert.Contains(t, listConfirmedWithoutDeposit, "t.kind = 'DEPOSIT'", "a sweep is not a deposit")
+}
+
+func TestQueries_SyncAttemptCount(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	id := uuid.New()
+	count := int32(2)
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, syncAttemptCount, []interface{}{id}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 1)
+		*dest[0].(**int32) = &count
+	})
+
+	got, err := queries.SyncAttemptCount(ctx, id)
+
+	require.NoError(t, err)
+	assert.Equal(t, &count, got)
+}
+
+func TestQueries_SyncPaymentWallet(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	id := uuid.New()
+	index := int64(9)
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, syncPaymentWallet, []interface{}{id}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 2)
+		*dest[0].(*string) = "TSecond"
+		*dest[1].(**int64) = &index
+	})
+
+	got, err := queries.SyncPaymentWallet(ctx, id)
+
+	require.NoError(t, err)
+	assert.Equal(t, SyncPaymentWalletRow{UniqueWallet: "TSecond", WalletIndex: &index}, got)
+	assert.Contains(t, syncPaymentWallet, "ORDER BY attempt_number DESC")
+}
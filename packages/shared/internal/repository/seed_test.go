@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeedSQL(t *testing.T) {
+	for name, query := range map[string]string{
+		"UpsertClient":         upsertClient,
+		"UpsertAccount":        upsertAccount,
+		"UpsertPayment":        upsertPayment,
+		"UpsertPaymentAttempt": upsertPaymentAttempt,
+		"UpsertLog":            upsertLog,
+	} {
+		assert.Contains(t, query, "ON CONFLICT (id) DO UPDATE", name)
+	}
+
+	// Referencing tables are listed before the tables they reference.
+	tables := []string{"reconciliation_mismatches", "reconciliation_reports", "webhook_deliveries", "webhook_endpoints", "outbox", "logs", "transactions", "sweeps",
+		"payment_attempts", "idempotency_keys", "payments", "accounts", "clients"}
+	last := -1
+	for _, table := range tables {
+		i := strings.Index(wipeData, " "+table+",")
+		if table == "clients" {
+			i = strings.Index(wipeData, " clients\n")
+		}
+		require.GreaterOrEqual(t, i, 0, table)
+		assert.Greater(t, i, last, "%s is out of order", table)
+		last = i
+	}
+	assert.NotContains(t, wipeData, "wallet_index_counters", "indexes are never handed out twice")
+}
+
+func TestQueries_UpsertClient(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	params := UpsertClientParams{ID: uuid.New(), Name: "Demo Shop", ApiKey: "sk_dev_1"}
+	active := true
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, upsertClient, []interface{}{params.ID, params.Name, params.ApiKey}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 6)
+		*dest[0].(*uuid.UUID) = params.ID
+		*dest[1].(*string) = params.Name
+		*dest[2].(*string) = params.ApiKey
+		*dest[3].(**bool) = &active
+	})
+
+	client, err := queries.UpsertClient(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, Client{ID: params.ID, Name: params.Name, ApiKey: params.ApiKey, IsActive: &active}, client)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_UpsertAccount(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	params := UpsertAccountParams{ID: uuid.New(), ClientID: uuid.New(), Name: "main"}
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, upsertAccount, []interface{}{params.ID, params.ClientID, params.Name}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 5)
+		*dest[0].(*uuid.UUID) = params.ID
+		*dest[1].(*uuid.UUID) = params.ClientID
+		*dest[2].(*string) = params.Name
+	})
+
+	account, err := queries.UpsertAccount(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, Account{ID: params.ID, ClientID: params.ClientID, Name: params.Name}, account)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_UpsertPayment(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	index := int64(12)
+	attempts := int32(2)
+	params := UpsertPaymentParams{
+		ID:           uuid.New(),
+		ClientID:     uuid.New(),
+		AccountID:    uuid.New(),
+		Amount:       pgtype.Numeric{Int: big.NewInt(1999), Exp: -2, Valid: true},
+		UniqueWallet: "TWallet12",
+		Status:       PaymentConfirmed,
+		ExpiresAt:    pgtype.Timestamptz{Time: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+		ConfirmedAt:  pgtype.Timestamptz{Time: time.Date(2025, 12, 31, 23, 50, 0, 0, time.UTC), Valid: true},
+		AttemptCount: &attempts,
+		WalletIndex:  &index,
+		Token:        "USDT",
+	}
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, upsertPayment, []interface{}{
+		params.ID, params.ClientID, params.AccountID, params.Amount, params.UniqueWallet, params.Status,
+		params.ExpiresAt, params.ConfirmedAt, params.AttemptCount, params.WalletIndex, params.Token,
+	}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 21)
+		*dest[0].(*uuid.UUID) = params.ID
+		*dest[5].(*string) = params.Status
+		*dest[10].(**int64) = &index
+		*dest[15].(*string) = params.Token
+	})
+
+	payment, err := queries.UpsertPayment(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, params.ID, payment.ID)
+	assert.Equal(t, PaymentConfirmed, payment.Status)
+	assert.Equal(t, &index, payment.WalletIndex)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_UpsertPaymentAttempt(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	index := int64(13)
+	params := UpsertPaymentAttemptParams{ID: uuid.New(), PaymentID: uuid.New(), AttemptNumber: 1, GeneratedWallet: "TWallet13", WalletIndex: &index}
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, upsertPaymentAttempt, []interface{}{params.ID, params.PaymentID, params.AttemptNumber, params.GeneratedWallet, params.WalletIndex}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 6)
+		*dest[0].(*uuid.UUID) = params.ID
+		*dest[1].(*uuid.UUID) = params.PaymentID
+		*dest[2].(*int32) = params.AttemptNumber
+		*dest[3].(*string) = params.GeneratedWallet
+		*dest[5].(**int64) = &index
+	})
+
+	attempt, err := queries.UpsertPaymentAttempt(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, PaymentAttempt{ID: params.ID, PaymentID: params.PaymentID, AttemptNumber: 1, GeneratedWallet: "TWallet13", WalletIndex: &index}, attempt)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_UpsertLog(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	msg := "payment created"
+	actor := "seed"
+	params := UpsertLogParams{
+		ID:        uuid.New(),
+		PaymentID: UUIDPtr(uuid.New()),
+		EventType: "PAYMENT_CREATED",
+		Message:   &msg,
+		RawData:   []byte(`{}`),
+		Actor:     &actor,
+	}
+
+	mockDB.On("Exec", ctx, upsertLog, []interface{}{params.ID, params.PaymentID, params.EventType, params.Message, params.RawData, params.Actor}).
+		Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+
+	require.NoError(t, queries.UpsertLog(ctx, params))
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_WipeData(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+
+	mockDB.On("Exec", ctx, wipeData, []interface{}(nil)).Return(pgconn.NewCommandTag("TRUNCATE"), nil)
+
+	require.NoError(t, queries.WipeData(ctx))
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,50 @@
+package repository
+
+import "slices"
+
+// Payment statuses, as stored in payments.status.
+const (
+	PaymentPending   = "PENDING"
+	PaymentDetected  = "DETECTED"
+	PaymentUnderpaid = "UNDERPAID"
+	PaymentConfirmed = "CONFIRMED"
+	PaymentExpired   = "EXPIRED"
+	PaymentReview    = "REVIEW"
+	PaymentCancelled = "CANCELLED"
+)
+
+// paymentTransitions lists the statuses each status may move to. A status
+// without an entry is terminal.
+//
+// DETECTED means a transfer was seen in the pending pool. It is never final:
+// the transfer may land short, never land at all, or land and confirm.
+// CANCELLED is set by the merchant, and only before any transfer landed.
+var paymentTransitions = map[string][]string{
+	PaymentPending:   {PaymentDetected, PaymentUnderpaid, PaymentConfirmed, PaymentExpired, PaymentCancelled},
+	PaymentDetected:  {PaymentUnderpaid, PaymentConfirmed, PaymentExpired, PaymentCancelled},
+	PaymentUnderpaid: {PaymentPending, PaymentExpired},
+}
+
+// confirmationReverts lists the statuses a CONFIRMED payment may be moved back
+// to when a reorg orphans the transfers it was settled with: PENDING while it
+// can still be paid, REVIEW for an operator once it has expired. Nothing else
+// leaves CONFIRMED, and REVIEW is only left by hand.
+var confirmationReverts = []string{PaymentPending, PaymentReview}
+
+// CanTransitionPayment reports whether a payment may move from one status to
+// another.
+func CanTransitionPayment(from, to string) bool {
+	return slices.Contains(paymentTransitions[from], to)
+}
+
+// IsTerminalPaymentStatus reports whether a payment in status can no longer
+// change.
+func IsTerminalPaymentStatus(status string) bool {
+	return len(paymentTransitions[status]) == 0
+}
+
+// CanRevertConfirmation reports whether a CONFIRMED payment may be moved back
+// to status after a reorg.
+func CanRevertConfirmation(status string) bool {
+	return slices.Contains(confirmationReverts, status)
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransitionPayment(t *testing.T) {
+	testCases := []struct {
+		from, to string
+		want     bool
+	}{
+		{PaymentPending, PaymentDetected, true},
+		{PaymentPending, PaymentConfirmed, true},
+		{PaymentDetected, PaymentConfirmed, true},
+		{PaymentDetected, PaymentUnderpaid, true},
+		{PaymentDetected, PaymentExpired, true},
+		{PaymentUnderpaid, PaymentPending, true},
+		{PaymentPending, PaymentCancelled, true},
+		{PaymentDetected, PaymentCancelled, true},
+		{PaymentUnderpaid, PaymentCancelled, false},
+		{PaymentConfirmed, PaymentCancelled, false},
+		{PaymentCancelled, PaymentPending, false},
+		{PaymentDetected, PaymentPending, false},
+		{PaymentUnderpaid, PaymentDetected, false},
+		{PaymentConfirmed, PaymentExpired, false},
+		{PaymentExpired, PaymentPending, false},
+		{PaymentPending, PaymentPending, false},
+		{"BOGUS", PaymentPending, false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, CanTransitionPayment(tc.from, tc.to), "%s -> %s", tc.from, tc.to)
+	}
+}
+
+func TestIsTerminalPaymentStatus(t *testing.T) {
+	assert.False(t, IsTerminalPaymentStatus(PaymentPending))
+	assert.False(t, IsTerminalPaymentStatus(PaymentDetected), "DETECTED must never be terminal")
+	assert.False(t, IsTerminalPaymentStatus(PaymentUnderpaid))
+	assert.True(t, IsTerminalPaymentStatus(PaymentConfirmed))
+	assert.True(t, IsTerminalPaymentStatus(PaymentExpired))
+	assert.True(t, IsTerminalPaymentStatus(PaymentReview), "only an operator moves a payment out of REVIEW")
+	assert.True(t, IsTerminalPaymentStatus(PaymentCancelled))
+}
+
+func TestCanRevertConfirmation(t *testing.T) {
+	assert.True(t, CanRevertConfirmation(PaymentPending))
+	assert.True(t, CanRevertConfirmation(PaymentReview))
+	assert.False(t, CanRevertConfirmation(PaymentDetected))
+	assert.False(t, CanRevertConfirmation(PaymentExpired))
+	assert.False(t, CanRevertConfirmation(PaymentConfirmed))
+}
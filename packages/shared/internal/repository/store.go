@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Store wraps the generated Queries with behaviour sqlc cannot express, such
+// as translating constraint violations into repository errors. Methods not
+// overridden here fall through to the embedded Querier: the Queries, or an
+// InstrumentedQuerier around them when the Store records metrics.
+type Store struct {
+	Querier
+	db       DBTX
+	tracer   trace.Tracer
+	timeout  time.Duration
+	recorder MetricsRecorder
+}
+
+func NewStore(db DBTX, opts ...StoreOption) *Store {
+	s := &Store{db: db, tracer: noop.NewTracerProvider().Tracer(tracerName)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.Querier = s.bind(db, nil, trace.SpanContext{})
+	return s
+}
+
+// bind returns the Queries running on db, bounded by the query timeout,
+// traced when the Store is and instrumented when it has a recorder. txSpan
+// and outer are as in tracedDB.
+func (s *Store) bind(db DBTX, txSpan trace.Span, outer trace.SpanContext) Querier {
+	if s.timeout > 0 {
+		db = timeoutDB{db: db, timeout: s.timeout}
+	}
+	if s.isTraced() {
+		db = tracedDB{db: db, tracer: s.tracer, txSpan: txSpan, outer: outer}
+	}
+	if s.recorder != nil {
+		return NewInstrumentedQuerier(New(db), s.recorder)
+	}
+	return New(db)
+}
+
+func (s *Store) isTraced() bool {
+	_, noopTracer := s.tracer.(noop.Tracer)
+	return !noopTracer
+}
+
+// txBeginner is a DBTX that can start a transaction, such as *pgxpool.Pool
+// or a pgx.Tx, which starts a savepoint.
+type txBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// ExecTx runs fn with a Querier bound to one transaction, committing when fn
+// returns nil and rolling back otherwise. The Querier is a Store, so its
+// errors are translated the same way.
+func (s *Store) ExecTx(ctx context.Context, fn func(Querier) error) (err error) {
+	b, ok := s.db.(txBeginner)
+	if !ok {
+		return errors.New("store cannot begin transactions")
+	}
+	outer := trace.SpanContextFromContext(ctx)
+	ctx, span := s.tracer.Start(ctx, "db.transaction", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { endSpan(span, err) }()
+
+	tx, err := b.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// Rollback after Commit is a no-op.
+	defer func() { _ = tx.Rollback(context.WithoutCancel(ctx)) }()
+
+	queries := s.bind(tx, span, outer)
+	if err := fn(&Store{Querier: queries, db: tx, tracer: s.tracer, timeout: s.timeout, recorder: s.recorder}); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// maxTxAttempts bounds how often RetryTx runs a transaction that keeps
+// losing version races.
+const maxTxAttempts = 3
+
+// TxRunner runs fn in a transaction, as Store and FakeStore do.
+type TxRunner interface {
+	ExecTx(ctx context.Context, fn func(Querier) error) error
+}
+
+// RetryTx runs fn in a transaction on tx, running it again in a new one when
+// it fails with ErrVersionConflict, up to maxTxAttempts times in all. fn
+// should read the rows it updates inside the transaction, so each attempt
+// starts from what the writer that beat the last one left. Any other error
+// is returned at once, and the last conflict once the attempts run out.
+func RetryTx(ctx context.Context, tx TxRunner, fn func(Querier) error) error {
+	var err error
+	for range maxTxAttempts {
+		if err = tx.ExecTx(ctx, fn); !errors.Is(err, ErrVersionConflict) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// CreatePayment inserts a payment, returning ErrDuplicateWallet when the
+// wallet or its index already backs another payment that is still open, and
+// ErrDuplicateOrderReference when the client already used the order
+// reference.
+func (s *Store) CreatePayment(ctx context.Context, arg CreatePaymentParams) (Payment, error) {
+	p, err := s.Querier.CreatePayment(ctx, arg)
+	if isUniqueViolation(err, "idx_payments_unique_wallet_open") || isUniqueViolation(err, "idx_payments_wallet_index") {
+		return Payment{}, ErrDuplicateWallet
+	}
+	if isUniqueViolation(err, "idx_payments_client_order_reference") {
+		return Payment{}, ErrDuplicateOrderReference
+	}
+	return p, err
+}
+
+// ConfirmPayment moves a PENDING or DETECTED payment at Version to
+// CONFIRMED once its deposits cover its amount within ToleranceBps. It
+// returns ErrVersionConflict when the payment was updated since Version was
+// read, and ErrPaymentNotPending when it is missing, in any other status or
+// short of its amount, so concurrent confirmations settle a payment exactly
+// once and an underpaid one never.
+func (s *Store) ConfirmPayment(ctx context.Context, arg ConfirmPaymentParams) (Payment, error) {
+	p, err := s.Querier.ConfirmPayment(ctx, arg)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Payment{}, s.paymentMissed(ctx, arg.ID, arg.Version, ErrPaymentNotPending)
+	}
+	return p, err
+}
+
+// UpdatePaymentStatus moves a payment at Version from FromStatus to
+// ToStatus. It returns ErrInvalidPaymentTransition without touching the
+// database when the move is not allowed, ErrVersionConflict when the payment
+// was updated since Version was read, and ErrPaymentStatusChanged when it is
+// missing or not in FromStatus.
+func (s *Store) UpdatePaymentStatus(ctx context.Context, arg UpdatePaymentStatusParams) (Payment, error) {
+	if !CanTransitionPayment(arg.FromStatus, arg.ToStatus) {
+		return Payment{}, fmt.Errorf("%w: %s to %s", ErrInvalidPaymentTransition, arg.FromStatus, arg.ToStatus)
+	}
+	p, err := s.Querier.UpdatePaymentStatus(ctx, arg)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Payment{}, s.paymentMissed(ctx, arg.ID, arg.Version, ErrPaymentStatusChanged)
+	}
+	return p, err
+}
+
+// CancelPayment moves a payment at Version from FromStatus to CANCELLED
+// unless a transfer to it has been recorded. It returns
+// ErrInvalidPaymentTransition without touching the database when FromStatus
+// cannot be cancelled, ErrVersionConflict when the payment was updated since
+// Version was read, and ErrPaymentStatusChanged when it is missing, not in
+// FromStatus or has a transfer.
+func (s *Store) CancelPayment(ctx context.Context, arg CancelPaymentParams) (Payment, error) {
+	if !CanTransitionPayment(arg.FromStatus, PaymentCancelled) {
+		return Payment{}, fmt.Errorf("%w: %s to %s", ErrInvalidPaymentTransition, arg.FromStatus, PaymentCancelled)
+	}
+	p, err := s.Querier.CancelPayment(ctx, arg)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Payment{}, s.paymentMissed(ctx, arg.ID, arg.Version, ErrPaymentStatusChanged)
+	}
+	return p, err
+}
+
+// UpdatePaymentWallet points a PENDING payment at Version at a new deposit
+// wallet. It returns ErrVersionConflict when the payment was updated since
+// Version was read, ErrPaymentStatusChanged when it is missing, not PENDING
+// or was given another wallet since AttemptCount was read, and
+// ErrDuplicateWallet when the wallet or its index already backs a payment.
+func (s *Store) UpdatePaymentWallet(ctx context.Context, arg UpdatePaymentWalletParams) (Payment, error) {
+	p, err := s.Querier.UpdatePaymentWallet(ctx, arg)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Payment{}, s.paymentMissed(ctx, arg.ID, arg.Version, ErrPaymentStatusChanged)
+	}
+	if isUniqueViolation(err, "idx_payments_unique_wallet_open") || isUniqueViolation(err, "idx_payments_wallet_index") {
+		return Payment{}, ErrDuplicateWallet
+	}
+	return p, err
+}
+
+// RevertPaymentConfirmation moves a CONFIRMED payment at Version back to
+// ToStatus after a reorg orphaned its transfers. It returns
+// ErrInvalidPaymentTransition for any status but PENDING and REVIEW,
+// ErrVersionConflict when the payment was updated since Version was read,
+// and ErrPaymentStatusChanged when it is missing or not CONFIRMED.
+func (s *Store) RevertPaymentConfirmation(ctx context.Context, arg RevertPaymentConfirmationParams) (Payment, error) {
+	if !CanRevertConfirmation(arg.ToStatus) {
+		return Payment{}, fmt.Errorf("%w: %s to %s", ErrInvalidPaymentTransition, PaymentConfirmed, arg.ToStatus)
+	}
+	p, err := s.Querier.RevertPaymentConfirmation(ctx, arg)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Payment{}, s.paymentMissed(ctx, arg.ID, arg.Version, ErrPaymentStatusChanged)
+	}
+	return p, err
+}
+
+// paymentMissed tells why an update of payment id at version matched no
+// row: ErrVersionConflict when the payment has been updated since version
+// was read, and missed when it is missing or its status rules the update
+// out.
+func (s *Store) paymentMissed(ctx context.Context, id uuid.UUID, version int32, missed error) error {
+	p, err := s.Querier.GetPayment(ctx, id)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return missed
+	case err != nil:
+		return fmt.Errorf("failed to reread payment: %w", err)
+	case p.Version != version:
+		return ErrVersionConflict
+	}
+	return missed
+}
+
+// UpdateAccount changes the fields given of an account, returning ErrNotFound
+// when the client has no such account or it is deleted.
+func (s *Store) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
+	a, err := s.Querier.UpdateAccount(ctx, arg)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Account{}, ErrNotFound
+	}
+	return a, err
+}
+
+// SoftDeleteAccount marks an account deleted. It returns
+// ErrAccountHasOpenPayments when the account still has a PENDING or DETECTED
+// payment, and pgx.ErrNoRows when the client has no such account or it is
+// already deleted.
+func (s *Store) SoftDeleteAccount(ctx context.Context, arg SoftDeleteAccountParams) (Account, error) {
+	a, err := s.Querier.SoftDeleteAccount(ctx, arg)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return a, err
+	}
+	// No row either way; the account still being there means its payments
+	// held it back.
+	if _, err := s.Querier.GetAccountByIDAndClientID(ctx, GetAccountByIDAndClientIDParams{ID: arg.ID, ClientID: arg.ClientID}); err != nil {
+		return Account{}, err
+	}
+	return Account{}, ErrAccountHasOpenPayments
+}
+
+// CreateTransaction records an on-chain transfer, returning
+// ErrDuplicateTransaction when the same transfer was already recorded and
+// not orphaned since.
+func (s *Store) CreateTransaction(ctx context.Context, arg CreateTransactionParams) (Transaction, error) {
+	tx, err := s.Querier.CreateTransaction(ctx, arg)
+	if isUniqueViolation(err, "idx_transactions_tx_hash_transfer_index") {
+		return Transaction{}, ErrDuplicateTransaction
+	}
+	return tx, err
+}
+
+// CreateSweepTransaction records a confirmed sweep, returning
+// ErrDuplicateTransaction when it was already recorded.
+func (s *Store) CreateSweepTransaction(ctx context.Context, arg CreateSweepTransactionParams) (Transaction, error) {
+	tx, err := s.Querier.CreateSweepTransaction(ctx, arg)
+	if isUniqueViolation(err, "idx_transactions_tx_hash_transfer_index") {
+		return Transaction{}, ErrDuplicateTransaction
+	}
+	return tx, err
+}
+
+// CreateSweep records a signed sweep before it is broadcast, returning
+// ErrSweepInProgress when the wallet already has an unsettled sweep of the
+// same token.
+func (s *Store) CreateSweep(ctx context.Context, arg CreateSweepParams) (Sweep, error) {
+	sw, err := s.Querier.CreateSweep(ctx, arg)
+	if isUniqueViolation(err, "idx_sweeps_open") {
+		return Sweep{}, ErrSweepInProgress
+	}
+	return sw, err
+}
+
+// UpdateSweepStatus moves a sweep from FromStatus to ToStatus. It returns
+// ErrSweepStatusChanged when the sweep is missing or no longer in
+// FromStatus.
+func (s *Store) UpdateSweepStatus(ctx context.Context, arg UpdateSweepStatusParams) (Sweep, error) {
+	sw, err := s.Querier.UpdateSweepStatus(ctx, arg)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Sweep{}, ErrSweepStatusChanged
+	}
+	return sw, err
+}
+
+// UpsertWatcherState saves a watcher's position over the one it read,
+// ExpectedHeight, or as its first when ExpectedHeight is nil. It returns
+// ErrStateConflict when the row has moved since, so a second replica of the
+// watcher fails instead of interleaving its writes with the first.
+func (s *Store) UpsertWatcherState(ctx context.Context, arg UpsertWatcherStateParams) (int64, error) {
+	h, err := s.Querier.UpsertWatcherState(ctx, arg)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrStateConflict
+	}
+	return h, err
+}
+
+var _ Querier = (*Store)(nil)
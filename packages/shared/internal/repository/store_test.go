@@ -0,0 +1,689 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStore(t *testing.T) {
+	mockDB := new(MockDBTX)
+
+	store := NewStore(mockDB)
+
+	require.NotNil(t, store)
+	assert.Equal(t, mockDB, store.db)
+	assert.Equal(t, mockDB, store.Querier.(*Queries).db)
+}
+
+func TestStore_CreatePayment_DuplicateWallet(t *testing.T) {
+	mockDB := new(MockDBTX)
+	store := NewStore(mockDB)
+
+	ctx := context.Background()
+	params := CreatePaymentParams{ClientID: uuid.New(), AccountID: uuid.New(), UniqueWallet: "TDup"}
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createPayment, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(&pgconn.PgError{
+		Code:           "23505",
+		ConstraintName: "idx_payments_unique_wallet_open",
+		Message:        `duplicate key value violates unique constraint "idx_payments_unique_wallet_open"`,
+	})
+
+	_, err := store.CreatePayment(ctx, params)
+
+	assert.ErrorIs(t, err, ErrDuplicateWallet)
+}
+
+func TestStore_CreatePayment_OtherUniqueViolationPassesThrough(t *testing.T) {
+	mockDB := new(MockDBTX)
+	store := NewStore(mockDB)
+
+	ctx := context.Background()
+	pgErr := &pgconn.PgError{Code: "23505", ConstraintName: "payments_pkey"}
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createPayment, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(pgErr)
+
+	_, err := store.CreatePayment(ctx, CreatePaymentParams{})
+
+	assert.NotErrorIs(t, err, ErrDuplicateWallet)
+	assert.Equal(t, pgErr, err)
+}
+
+func TestStore_CreatePayment_DuplicateWalletIndex(t *testing.T) {
+	mockDB := new(MockDBTX)
+	store := NewStore(mockDB)
+
+	ctx := context.Background()
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createPayment, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(&pgconn.PgError{Code: "23505", ConstraintName: "idx_payments_wallet_index"})
+
+	_, err := store.CreatePayment(ctx, CreatePaymentParams{})
+
+	assert.ErrorIs(t, err, ErrDuplicateWallet)
+}
+
+func TestStore_CreatePayment_DuplicateOrderReference(t *testing.T) {
+	mockDB := new(MockDBTX)
+	store := NewStore(mockDB)
+
+	ctx := context.Background()
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createPayment, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(&pgconn.PgError{Code: "23505", ConstraintName: "idx_payments_client_order_reference"})
+
+	_, err := store.CreatePayment(ctx, CreatePaymentParams{})
+
+	assert.ErrorIs(t, err, ErrDuplicateOrderReference)
+	assert.NotErrorIs(t, err, ErrDuplicateWallet)
+}
+
+// beginnerDB is a MockDBTX that starts tx.
+type beginnerDB struct {
+	MockDBTX
+	tx       *recordingTx
+	beginErr error
+}
+
+func (b *beginnerDB) Begin(context.Context) (pgx.Tx, error) {
+	if b.beginErr != nil {
+		return nil, b.beginErr
+	}
+	return b.tx, nil
+}
+
+// recordingTx records whether it was committed or rolled back.
+type recordingTx struct {
+	MockTx
+	committed  bool
+	rolledBack bool
+	commitErr  error
+}
+
+func (t *recordingTx) Commit(context.Context) error {
+	if t.commitErr != nil {
+		return t.commitErr
+	}
+	t.committed = true
+	return nil
+}
+
+func (t *recordingTx) Rollback(context.Context) error {
+	if !t.committed {
+		t.rolledBack = true
+	}
+	return nil
+}
+
+func TestStore_ExecTx_Commits(t *testing.T) {
+	ctx := context.Background()
+	tx := &recordingTx{}
+	store := NewStore(&beginnerDB{tx: tx})
+	mockRow := new(MockRow)
+	tx.On("QueryRow", ctx, nextWalletIndex, []interface{}{"deposit"}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil)
+
+	err := store.ExecTx(ctx, func(q Querier) error {
+		_, err := q.NextWalletIndex(ctx, "deposit")
+		return err
+	})
+
+	require.NoError(t, err)
+	tx.AssertExpectations(t)
+	assert.True(t, tx.committed)
+	assert.False(t, tx.rolledBack)
+}
+
+func TestStore_ExecTx_RollsBackOnError(t *testing.T) {
+	tx := &recordingTx{}
+	store := NewStore(&beginnerDB{tx: tx})
+	boom := errors.New("boom")
+
+	err := store.ExecTx(context.Background(), func(Querier) error { return boom })
+
+	assert.Equal(t, boom, err, "fn's error is returned as is")
+	assert.False(t, tx.committed)
+	assert.True(t, tx.rolledBack)
+}
+
+func TestStore_ExecTx_TranslatesErrors(t *testing.T) {
+	ctx := context.Background()
+	tx := &recordingTx{}
+	store := NewStore(&beginnerDB{tx: tx})
+	mockRow := new(MockRow)
+	tx.On("QueryRow", ctx, createPayment, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(&pgconn.PgError{Code: "23505", ConstraintName: "idx_payments_unique_wallet_open"})
+
+	err := store.ExecTx(ctx, func(q Querier) error {
+		_, err := q.CreatePayment(ctx, CreatePaymentParams{})
+		return err
+	})
+
+	assert.ErrorIs(t, err, ErrDuplicateWallet)
+	assert.True(t, tx.rolledBack)
+}
+
+func TestStore_ExecTx_Errors(t *testing.T) {
+	err := NewStore(new(MockDBTX)).ExecTx(context.Background(), func(Querier) error { return nil })
+	assert.ErrorContains(t, err, "cannot begin transactions")
+
+	err = NewStore(&beginnerDB{beginErr: errors.New("no conn")}).ExecTx(context.Background(), func(Querier) error { return nil })
+	assert.ErrorContains(t, err, "failed to begin transaction: no conn")
+
+	tx := &recordingTx{commitErr: errors.New("retry txn")}
+	err = NewStore(&beginnerDB{tx: tx}).ExecTx(context.Background(), func(Querier) error { return nil })
+	assert.ErrorContains(t, err, "failed to commit transaction: retry txn")
+	assert.True(t, tx.rolledBack)
+}
+
+func TestStore_CreateTransaction_Duplicate(t *testing.T) {
+	mockDB := new(MockDBTX)
+	store := NewStore(mockDB)
+
+	ctx := context.Background()
+	params := CreateTransactionParams{PaymentID: uuid.New(), TxHash: "abc", Token: "TRX"}
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createTransaction, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(&pgconn.PgError{
+		Code:           "23505",
+		ConstraintName: "idx_transactions_tx_hash_transfer_index",
+	})
+
+	_, err := store.CreateTransaction(ctx, params)
+
+	assert.ErrorIs(t, err, ErrDuplicateTransaction)
+}
+
+// expectReread sets mockDB up for the GetPayment a Store makes after an
+// update of payment id matched no row, finding it at version or failing with
+// err.
+func expectReread(mockDB *MockDBTX, ctx context.Context, id uuid.UUID, version int32, err error) {
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, getPayment, []interface{}{id}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(err).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		*dest[0].(*uuid.UUID) = id
+		*dest[20].(*int32) = version
+	})
+}
+
+func TestStore_ConfirmPayment(t *testing.T) {
+	ctx := context.Background()
+	id := uuid.New()
+	arg := ConfirmPaymentParams{ID: id, Version: 3, ToleranceBps: 50}
+
+	t.Run("pending payment", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, confirmPayment, []interface{}{id, arg.Version, arg.ToleranceBps}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			dest := args.Get(0).([]interface{})
+			*dest[0].(*uuid.UUID) = id
+			*dest[5].(*string) = "CONFIRMED"
+		})
+
+		p, err := NewStore(mockDB).ConfirmPayment(ctx, arg)
+
+		require.NoError(t, err)
+		assert.Equal(t, id, p.ID)
+		assert.Equal(t, "CONFIRMED", p.Status)
+	})
+
+	t.Run("lost the race", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, confirmPayment, []interface{}{id, arg.Version, arg.ToleranceBps}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+		expectReread(mockDB, ctx, id, arg.Version, nil)
+
+		_, err := NewStore(mockDB).ConfirmPayment(ctx, arg)
+
+		assert.ErrorIs(t, err, ErrPaymentNotPending)
+	})
+
+	t.Run("updated meanwhile", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, confirmPayment, []interface{}{id, arg.Version, arg.ToleranceBps}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+		expectReread(mockDB, ctx, id, arg.Version+1, nil)
+
+		_, err := NewStore(mockDB).ConfirmPayment(ctx, arg)
+
+		assert.ErrorIs(t, err, ErrVersionConflict)
+	})
+}
+
+func TestStore_UpdateAccount_NotFound(t *testing.T) {
+	ctx := context.Background()
+	arg := UpdateAccountParams{ID: uuid.New(), ClientID: uuid.New()}
+	mockDB := new(MockDBTX)
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, updateAccount, []interface{}{arg.Name, arg.AddressStrategy, arg.ID, arg.ClientID}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+	_, err := NewStore(mockDB).UpdateAccount(ctx, arg)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStore_SoftDeleteAccount(t *testing.T) {
+	ctx := context.Background()
+	arg := SoftDeleteAccountParams{ID: uuid.New(), ClientID: uuid.New()}
+	lookup := []interface{}{arg.ID, arg.ClientID, false}
+
+	t.Run("deleted", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, softDeleteAccount, []interface{}{arg.ID, arg.ClientID}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			*args.Get(0).([]interface{})[0].(*uuid.UUID) = arg.ID
+		})
+
+		a, err := NewStore(mockDB).SoftDeleteAccount(ctx, arg)
+
+		require.NoError(t, err)
+		assert.Equal(t, arg.ID, a.ID)
+		mockDB.AssertNotCalled(t, "QueryRow", ctx, getAccountByIDAndClientID, lookup)
+	})
+
+	t.Run("open payments", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		deleteRow, lookupRow := new(MockRow), new(MockRow)
+		mockDB.On("QueryRow", ctx, softDeleteAccount, []interface{}{arg.ID, arg.ClientID}).Return(deleteRow)
+		deleteRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+		mockDB.On("QueryRow", ctx, getAccountByIDAndClientID, lookup).Return(lookupRow)
+		lookupRow.On("Scan", mock.Anything).Return(nil)
+
+		_, err := NewStore(mockDB).SoftDeleteAccount(ctx, arg)
+
+		assert.ErrorIs(t, err, ErrAccountHasOpenPayments)
+	})
+
+	t.Run("missing or already deleted", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		deleteRow, lookupRow := new(MockRow), new(MockRow)
+		mockDB.On("QueryRow", ctx, softDeleteAccount, []interface{}{arg.ID, arg.ClientID}).Return(deleteRow)
+		deleteRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+		mockDB.On("QueryRow", ctx, getAccountByIDAndClientID, lookup).Return(lookupRow)
+		lookupRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+		_, err := NewStore(mockDB).SoftDeleteAccount(ctx, arg)
+
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+		assert.NotErrorIs(t, err, ErrAccountHasOpenPayments)
+	})
+}
+
+func TestStore_UpdatePaymentStatus(t *testing.T) {
+	ctx := context.Background()
+	arg := UpdatePaymentStatusParams{ToStatus: "UNDERPAID", ID: uuid.New(), FromStatus: "PENDING", Version: 2}
+	queryArgs := []interface{}{arg.ToStatus, arg.ID, arg.FromStatus, arg.Version}
+
+	t.Run("expected status", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, updatePaymentStatus, queryArgs).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			dest := args.Get(0).([]interface{})
+			*dest[0].(*uuid.UUID) = arg.ID
+			*dest[5].(*string) = arg.ToStatus
+		})
+
+		p, err := NewStore(mockDB).UpdatePaymentStatus(ctx, arg)
+
+		require.NoError(t, err)
+		assert.Equal(t, "UNDERPAID", p.Status)
+	})
+
+	t.Run("status changed", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, updatePaymentStatus, queryArgs).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+		expectReread(mockDB, ctx, arg.ID, arg.Version, nil)
+
+		_, err := NewStore(mockDB).UpdatePaymentStatus(ctx, arg)
+
+		assert.ErrorIs(t, err, ErrPaymentStatusChanged)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, updatePaymentStatus, queryArgs).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+		expectReread(mockDB, ctx, arg.ID, 0, pgx.ErrNoRows)
+
+		_, err := NewStore(mockDB).UpdatePaymentStatus(ctx, arg)
+
+		assert.ErrorIs(t, err, ErrPaymentStatusChanged)
+	})
+
+	t.Run("updated meanwhile", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, updatePaymentStatus, queryArgs).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+		expectReread(mockDB, ctx, arg.ID, arg.Version+1, nil)
+
+		_, err := NewStore(mockDB).UpdatePaymentStatus(ctx, arg)
+
+		assert.ErrorIs(t, err, ErrVersionConflict)
+	})
+
+	t.Run("reread fails", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, updatePaymentStatus, queryArgs).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+		expectReread(mockDB, ctx, arg.ID, 0, errors.New("connection reset"))
+
+		_, err := NewStore(mockDB).UpdatePaymentStatus(ctx, arg)
+
+		assert.ErrorContains(t, err, "connection reset")
+		assert.NotErrorIs(t, err, ErrPaymentStatusChanged)
+	})
+
+	t.Run("invalid transition", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		invalid := UpdatePaymentStatusParams{ToStatus: PaymentPending, ID: arg.ID, FromStatus: PaymentExpired}
+
+		_, err := NewStore(mockDB).UpdatePaymentStatus(ctx, invalid)
+
+		assert.ErrorIs(t, err, ErrInvalidPaymentTransition)
+		mockDB.AssertNotCalled(t, "QueryRow", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestStore_CancelPayment(t *testing.T) {
+	ctx := context.Background()
+	arg := CancelPaymentParams{ID: uuid.New(), FromStatus: PaymentPending, Version: 1}
+	queryArgs := []interface{}{arg.ID, arg.FromStatus, arg.Version}
+
+	t.Run("cancelled", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, cancelPayment, queryArgs).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			dest := args.Get(0).([]interface{})
+			*dest[0].(*uuid.UUID) = arg.ID
+			*dest[5].(*string) = PaymentCancelled
+		})
+
+		p, err := NewStore(mockDB).CancelPayment(ctx, arg)
+
+		require.NoError(t, err)
+		assert.Equal(t, PaymentCancelled, p.Status)
+	})
+
+	t.Run("status changed or transfer recorded", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, cancelPayment, queryArgs).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+		expectReread(mockDB, ctx, arg.ID, arg.Version, nil)
+
+		_, err := NewStore(mockDB).CancelPayment(ctx, arg)
+
+		assert.ErrorIs(t, err, ErrPaymentStatusChanged)
+	})
+
+	t.Run("updated meanwhile", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, cancelPayment, queryArgs).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+		expectReread(mockDB, ctx, arg.ID, arg.Version+1, nil)
+
+		_, err := NewStore(mockDB).CancelPayment(ctx, arg)
+
+		assert.ErrorIs(t, err, ErrVersionConflict)
+	})
+
+	t.Run("terminal status", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+
+		_, err := NewStore(mockDB).CancelPayment(ctx, CancelPaymentParams{ID: arg.ID, FromStatus: PaymentConfirmed})
+
+		assert.ErrorIs(t, err, ErrInvalidPaymentTransition)
+		mockDB.AssertNotCalled(t, "QueryRow", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestStore_UpdatePaymentWallet(t *testing.T) {
+	ctx := context.Background()
+	index := int64(12)
+	arg := UpdatePaymentWalletParams{UniqueWallet: "TNew", WalletIndex: &index, ID: uuid.New(), AttemptCount: 1, Version: 2}
+	queryArgs := []interface{}{arg.UniqueWallet, arg.WalletIndex, arg.ID, arg.AttemptCount, arg.Version}
+
+	t.Run("updated", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, updatePaymentWallet, queryArgs).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			dest := args.Get(0).([]interface{})
+			*dest[0].(*uuid.UUID) = arg.ID
+			*dest[4].(*string) = arg.UniqueWallet
+		})
+
+		p, err := NewStore(mockDB).UpdatePaymentWallet(ctx, arg)
+
+		require.NoError(t, err)
+		assert.Equal(t, "TNew", p.UniqueWallet)
+	})
+
+	t.Run("regenerated or settled meanwhile", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, updatePaymentWallet, queryArgs).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+		expectReread(mockDB, ctx, arg.ID, arg.Version, nil)
+
+		_, err := NewStore(mockDB).UpdatePaymentWallet(ctx, arg)
+
+		assert.ErrorIs(t, err, ErrPaymentStatusChanged)
+	})
+
+	t.Run("updated meanwhile", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, updatePaymentWallet, queryArgs).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+		expectReread(mockDB, ctx, arg.ID, arg.Version+1, nil)
+
+		_, err := NewStore(mockDB).UpdatePaymentWallet(ctx, arg)
+
+		assert.ErrorIs(t, err, ErrVersionConflict)
+	})
+
+	t.Run("wallet index taken", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, updatePaymentWallet, queryArgs).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(&pgconn.PgError{Code: uniqueViolation, ConstraintName: "idx_payments_wallet_index"})
+
+		_, err := NewStore(mockDB).UpdatePaymentWallet(ctx, arg)
+
+		assert.ErrorIs(t, err, ErrDuplicateWallet)
+	})
+}
+
+func TestStore_RevertPaymentConfirmation(t *testing.T) {
+	ctx := context.Background()
+	arg := RevertPaymentConfirmationParams{ToStatus: PaymentPending, ID: uuid.New(), Version: 4}
+	queryArgs := []interface{}{arg.ToStatus, arg.ID, arg.Version}
+
+	t.Run("confirmed", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, revertPaymentConfirmation, queryArgs).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			dest := args.Get(0).([]interface{})
+			*dest[0].(*uuid.UUID) = arg.ID
+			*dest[5].(*string) = arg.ToStatus
+		})
+
+		p, err := NewStore(mockDB).RevertPaymentConfirmation(ctx, arg)
+
+		require.NoError(t, err)
+		assert.Equal(t, PaymentPending, p.Status)
+	})
+
+	t.Run("not confirmed", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, revertPaymentConfirmation, queryArgs).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+		expectReread(mockDB, ctx, arg.ID, arg.Version, nil)
+
+		_, err := NewStore(mockDB).RevertPaymentConfirmation(ctx, arg)
+
+		assert.ErrorIs(t, err, ErrPaymentStatusChanged)
+	})
+
+	t.Run("updated meanwhile", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, revertPaymentConfirmation, queryArgs).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+		expectReread(mockDB, ctx, arg.ID, arg.Version+1, nil)
+
+		_, err := NewStore(mockDB).RevertPaymentConfirmation(ctx, arg)
+
+		assert.ErrorIs(t, err, ErrVersionConflict)
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+
+		_, err := NewStore(mockDB).RevertPaymentConfirmation(ctx, RevertPaymentConfirmationParams{ToStatus: PaymentExpired, ID: arg.ID})
+
+		assert.ErrorIs(t, err, ErrInvalidPaymentTransition)
+		mockDB.AssertNotCalled(t, "QueryRow", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestStore_UpsertWatcherState(t *testing.T) {
+	ctx := context.Background()
+	expected := int64(99)
+	arg := UpsertWatcherStateParams{Name: "tron", LastHeight: 100, RecentBlocks: []byte("[]"), ExpectedHeight: &expected}
+	params := []interface{}{arg.Name, arg.LastHeight, arg.RecentBlocks, arg.ExpectedHeight}
+
+	t.Run("saved", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, upsertWatcherState, params).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			*args.Get(0).([]interface{})[0].(*int64) = 100
+		})
+
+		height, err := NewStore(mockDB).UpsertWatcherState(ctx, arg)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(100), height)
+	})
+
+	t.Run("moved since read", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, upsertWatcherState, params).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+		_, err := NewStore(mockDB).UpsertWatcherState(ctx, arg)
+
+		assert.ErrorIs(t, err, ErrStateConflict)
+	})
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain error", errors.New("boom"), false},
+		{"other code", &pgconn.PgError{Code: "23503", ConstraintName: "idx"}, false},
+		{"matching constraint", &pgconn.PgError{Code: "23505", ConstraintName: "idx"}, true},
+		{"matching message", &pgconn.PgError{Code: "23505", Message: `violates unique constraint "idx"`}, true},
+		{"wrapped", fmt.Errorf("insert: %w", &pgconn.PgError{Code: "23505", ConstraintName: "idx"}), true},
+		{"different constraint", &pgconn.PgError{Code: "23505", ConstraintName: "other"}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, isUniqueViolation(tc.err, "idx"))
+		})
+	}
+}
+
+// countingTx runs each transaction with no Querier, counting them.
+type countingTx struct {
+	attempts int
+}
+
+func (c *countingTx) ExecTx(_ context.Context, fn func(Querier) error) error {
+	c.attempts++
+	return fn(nil)
+}
+
+func TestRetryTx(t *testing.T) {
+	ctx := context.Background()
+	boom := errors.New("boom")
+	testCases := []struct {
+		name     string
+		results  []error
+		want     error
+		attempts int
+	}{
+		{"first attempt wins", []error{nil}, nil, 1},
+		{"wins after a conflict", []error{ErrVersionConflict, nil}, nil, 2},
+		{"keeps losing", []error{ErrVersionConflict, ErrVersionConflict, ErrVersionConflict, nil}, ErrVersionConflict, maxTxAttempts},
+		{"other error", []error{boom, nil}, boom, 1},
+		{"wrapped conflict", []error{fmt.Errorf("failed to confirm: %w", ErrVersionConflict), nil}, nil, 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tx := &countingTx{}
+
+			err := RetryTx(ctx, tx, func(Querier) error {
+				return tc.results[tx.attempts-1]
+			})
+
+			if tc.want == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.want)
+			}
+			assert.Equal(t, tc.attempts, tx.attempts)
+		})
+	}
+}
+
+func TestRetryTx_StopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tx := &countingTx{}
+
+	err := RetryTx(ctx, tx, func(Querier) error {
+		cancel()
+		return ErrVersionConflict
+	})
+
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	assert.Equal(t, 1, tx.attempts)
+}
@@ -0,0 +1,125 @@
+//go:build integration
+
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// confirmWithDeposit records a deposit of amount to p's wallet and confirms p.
+func (f *fixture) confirmWithDeposit(p Payment, amount int64) {
+	f.t.Helper()
+	_, err := f.store.CreateTransaction(f.ctx, CreateTransactionParams{
+		PaymentID:   p.ID,
+		TxHash:      uuid.NewString(),
+		Token:       p.Token,
+		FromAddress: f.wallet(),
+		ToAddress:   p.UniqueWallet,
+		Amount:      numeric(amount, -6),
+		BlockNumber: 1,
+		BlockHash:   "hash",
+	})
+	require.NoError(f.t, err)
+	detected, err := f.store.UpdatePaymentStatus(f.ctx, UpdatePaymentStatusParams{ID: p.ID, FromStatus: PaymentPending, ToStatus: PaymentDetected, Version: p.Version})
+	require.NoError(f.t, err)
+	_, err = f.store.ConfirmPayment(f.ctx, ConfirmPaymentParams{ID: p.ID, Version: detected.Version})
+	require.NoError(f.t, err)
+}
+
+// sweepCandidates returns the candidates on wallet.
+func (f *fixture) sweepCandidates(wallet string) []ListSweepCandidatesRow {
+	f.t.Helper()
+	rows, err := f.store.ListSweepCandidates(f.ctx, 1000)
+	require.NoError(f.t, err)
+	var out []ListSweepCandidatesRow
+	for _, r := range rows {
+		if r.UniqueWallet == wallet {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func TestIntegration_SweepCandidates_SharedDepositWallet(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	account := f.account(f.client().ID)
+	wallet := f.wallet()
+	_, err := f.store.SetAccountDepositWallet(ctx, SetAccountDepositWalletParams{DepositWallet: wallet, DepositWalletIndex: 4, ID: account.ID})
+	require.NoError(t, err)
+	shares := func(amount int64) func(*CreatePaymentParams) {
+		return func(arg *CreatePaymentParams) {
+			arg.UniqueWallet, arg.WalletIndex, arg.Amount = wallet, nil, numeric(amount, -6)
+		}
+	}
+	first := f.payment(account, shares(25500001))
+	f.confirmWithDeposit(first, 25500001)
+	second := f.payment(account, shares(25500002))
+	f.confirmWithDeposit(second, 25500002)
+
+	candidates := f.sweepCandidates(wallet)
+	require.Len(t, candidates, 1, "the shared wallet is one candidate, not one per payment")
+	index := int64(4)
+	assert.Equal(t, ListSweepCandidatesRow{PaymentID: second.ID, UniqueWallet: wallet, WalletIndex: &index, Token: "USDT"}, candidates[0],
+		"signed for with the account's index, for the latest payment confirmed")
+
+	sw, err := f.store.CreateSweep(ctx, CreateSweepParams{
+		PaymentID:   second.ID,
+		Token:       "USDT",
+		FromAddress: wallet,
+		ToAddress:   f.wallet(),
+		Amount:      numeric(51000003, -6),
+		TxID:        uuid.NewString(),
+		SignedTx:    []byte(`{}`),
+		ExpiresAt:   timestamptz(time.Now().Add(time.Minute)),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, f.sweepCandidates(wallet), "swept for the latest payment")
+	_, err = f.store.CreateSweepTransaction(ctx, CreateSweepTransactionParams{
+		PaymentID:   second.ID,
+		TxHash:      sw.TxID,
+		Token:       "USDT",
+		FromAddress: wallet,
+		ToAddress:   sw.ToAddress,
+		Amount:      sw.Amount,
+		BlockNumber: 2,
+		BlockHash:   "hash",
+	})
+	require.NoError(t, err)
+
+	third := f.payment(account, shares(12000000))
+	f.confirmWithDeposit(third, 12000000)
+	candidates = f.sweepCandidates(wallet)
+	require.Len(t, candidates, 1, "a later payment makes the wallet a candidate again")
+	assert.Equal(t, third.ID, candidates[0].PaymentID)
+
+	flows, err := f.store.GetWalletFlows(ctx, GetWalletFlowsParams{Wallet: wallet, Token: "USDT"})
+	require.NoError(t, err)
+	assertNumeric(t, 63.000003, flows.Deposited)
+	assertNumeric(t, 51.000003, flows.Swept)
+}
+
+func TestIntegration_SweepCandidates_Deferred(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	p := f.payment(f.account(f.client().ID))
+	f.confirmWithDeposit(p, 25500000)
+	require.Len(t, f.sweepCandidates(p.UniqueWallet), 1)
+
+	err := f.store.DeferSweepCandidate(ctx, DeferSweepCandidateParams{
+		Address: p.UniqueWallet, Token: "USDT", NextCheckAt: timestamptz(time.Now().Add(time.Hour)),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, f.sweepCandidates(p.UniqueWallet), "deferred until its next check")
+
+	err = f.store.DeferSweepCandidate(ctx, DeferSweepCandidateParams{
+		Address: p.UniqueWallet, Token: "USDT", NextCheckAt: timestamptz(time.Now().Add(-time.Second)),
+	})
+	require.NoError(t, err)
+	assert.Len(t, f.sweepCandidates(p.UniqueWallet), 1, "a candidate again once the check is due")
+}
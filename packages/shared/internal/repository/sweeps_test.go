@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueries_CreateSweep(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	params := CreateSweepParams{
+		PaymentID:   uuid.New(),
+		Token:       "TRX",
+		FromAddress: "TDeposit",
+		ToAddress:   "TCold",
+		Amount:      pgtype.Numeric{Valid: true},
+		TxID:        "5ee9",
+		SignedTx:    []byte(`{"txID":"5ee9"}`),
+		ExpiresAt:   pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true},
+	}
+	sweepID := uuid.New()
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createSweep, []interface{}{
+		params.PaymentID, params.Token, params.ContractAddress, params.FromAddress, params.ToAddress,
+		params.Amount, params.TxID, params.SignedTx, params.ExpiresAt,
+	}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 13)
+		*dest[0].(*uuid.UUID) = sweepID
+		*dest[7].(*string) = params.TxID
+		*dest[10].(*string) = "SIGNED"
+	})
+
+	sw, err := queries.CreateSweep(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, sweepID, sw.ID)
+	assert.Equal(t, "5ee9", sw.TxID)
+	assert.Equal(t, "SIGNED", sw.Status)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_ListOpenSweeps(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, listOpenSweeps, []interface{}(nil)).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 13)
+		*dest[10].(*string) = "SENT"
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	sweeps, err := queries.ListOpenSweeps(ctx)
+
+	require.NoError(t, err)
+	require.Len(t, sweeps, 1)
+	assert.Equal(t, "SENT", sweeps[0].Status)
+	assert.Contains(t, listOpenSweeps, "WHERE status IN ('SIGNED', 'SENT')")
+}
+
+func TestQueries_ListSweepCandidates(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	index := int64(3)
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, listSweepCandidates, []interface{}{int32(50)}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 4)
+		*dest[1].(*string) = "TDeposit"
+		*dest[2].(**int64) = &index
+		*dest[3].(*string) = "USDT"
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	rows, err := queries.ListSweepCandidates(ctx, 50)
+
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, ListSweepCandidatesRow{UniqueWallet: "TDeposit", WalletIndex: &index, Token: "USDT"}, rows[0])
+}
+
+func TestListSweepCandidatesSQL(t *testing.T) {
+	assert.Contains(t, listSweepCandidates, "p.status = 'CONFIRMED'")
+	assert.Contains(t, listSweepCandidates, "c.wallet_index IS NOT NULL", "a wallet without an index has no key to sign with")
+	assert.Contains(t, listSweepCandidates, "d.to_address AS unique_wallet", "every wallet a payment was paid to is swept")
+	assert.Contains(t, listSweepCandidates, "CASE WHEN d.to_address = p.unique_wallet THEN p.wallet_index ELSE a.wallet_index END",
+		"an earlier wallet is signed for with the index recorded on its attempt")
+	assert.Contains(t, listSweepCandidates, "s.from_address = c.unique_wallet")
+	assert.Contains(t, listSweepCandidates, "kind = 'DEPOSIT'")
+	assert.Contains(t, listSweepCandidates, "s.status NOT IN ('REJECTED', 'EXPIRED')", "only sweeps that never reached the chain may be retried")
+	assert.Contains(t, listSweepCandidates, "k.address = c.unique_wallet AND k.token = c.token AND k.next_check_at > now()",
+		"a deferred wallet stays out of the batch until its next check")
+	assert.Contains(t, listSweepCandidates, "ac.deposit_wallet, d.token, p.confirmed_at, ac.deposit_wallet_index",
+		"an account's shared deposit wallet is signed for with its own index")
+	assert.Contains(t, listSweepCandidates, "ORDER BY ac.id, d.token, p.confirmed_at DESC",
+		"the shared wallet is swept for the latest payment confirmed")
+}
+
+func TestQueries_DeferSweepCandidate(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	params := DeferSweepCandidateParams{
+		Address:     "TDeposit",
+		Token:       "USDT",
+		NextCheckAt: pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+	mockDB.On("Exec", ctx, deferSweepCandidate, []interface{}{params.Address, params.Token, params.NextCheckAt}).Return(nil, nil)
+
+	err := queries.DeferSweepCandidate(ctx, params)
+
+	require.NoError(t, err)
+	assert.Contains(t, deferSweepCandidate, "ON CONFLICT (address, token) DO UPDATE SET next_check_at = excluded.next_check_at")
+	mockDB.AssertExpectations(t)
+}
+
+func TestUpdateSweepStatusSQL(t *testing.T) {
+	assert.Contains(t, updateSweepStatus, "WHERE id = $2 AND status = $3", "UpdateSweepStatus must compare-and-set on the old status")
+}
+
+func TestStore_CreateSweep_InProgress(t *testing.T) {
+	mockDB := new(MockDBTX)
+	ctx := context.Background()
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createSweep, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(&pgconn.PgError{Code: "23505", ConstraintName: "idx_sweeps_open"})
+
+	_, err := NewStore(mockDB).CreateSweep(ctx, CreateSweepParams{PaymentID: uuid.New(), Token: "TRX"})
+
+	assert.ErrorIs(t, err, ErrSweepInProgress)
+}
+
+func TestStore_CreateSweepTransaction_Duplicate(t *testing.T) {
+	mockDB := new(MockDBTX)
+	ctx := context.Background()
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createSweepTransaction, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(&pgconn.PgError{Code: "23505", ConstraintName: "idx_transactions_tx_hash_transfer_index"})
+
+	_, err := NewStore(mockDB).CreateSweepTransaction(ctx, CreateSweepTransactionParams{PaymentID: uuid.New(), TxHash: "5ee9"})
+
+	assert.ErrorIs(t, err, ErrDuplicateTransaction)
+}
+
+func TestStore_UpdateSweepStatus(t *testing.T) {
+	ctx := context.Background()
+	arg := UpdateSweepStatusParams{ToStatus: "SENT", ID: uuid.New(), FromStatus: "SIGNED"}
+
+	t.Run("expected status", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, updateSweepStatus, []interface{}{arg.ToStatus, arg.ID, arg.FromStatus}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			dest := args.Get(0).([]interface{})
+			*dest[10].(*string) = arg.ToStatus
+		})
+
+		sw, err := NewStore(mockDB).UpdateSweepStatus(ctx, arg)
+
+		require.NoError(t, err)
+		assert.Equal(t, "SENT", sw.Status)
+	})
+
+	t.Run("status changed", func(t *testing.T) {
+		mockDB := new(MockDBTX)
+		mockRow := new(MockRow)
+		mockDB.On("QueryRow", ctx, updateSweepStatus, []interface{}{arg.ToStatus, arg.ID, arg.FromStatus}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+		_, err := NewStore(mockDB).UpdateSweepStatus(ctx, arg)
+
+		assert.ErrorIs(t, err, ErrSweepStatusChanged)
+	})
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// TenantQuerier is the part of Querier a TenantQueries reads through.
+// Querier and Store implement it.
+type TenantQuerier interface {
+	GetPayment(ctx context.Context, id uuid.UUID) (Payment, error)
+	GetPaymentByOrderReference(ctx context.Context, arg GetPaymentByOrderReferenceParams) (Payment, error)
+	SearchPaymentsByWallet(ctx context.Context, arg SearchPaymentsByWalletParams) ([]Payment, error)
+	SearchPaymentsByTxHash(ctx context.Context, arg SearchPaymentsByTxHashParams) ([]Payment, error)
+	ListRecentClientPayments(ctx context.Context, arg ListRecentClientPaymentsParams) ([]Payment, error)
+	CountClientPaymentsByStatus(ctx context.Context, arg CountClientPaymentsByStatusParams) ([]CountClientPaymentsByStatusRow, error)
+	GetDailyConfirmedVolume(ctx context.Context, arg GetDailyConfirmedVolumeParams) ([]GetDailyConfirmedVolumeRow, error)
+	GetPaymentFunnel(ctx context.Context, arg GetPaymentFunnelParams) ([]GetPaymentFunnelRow, error)
+	ListPaymentExport(ctx context.Context, arg ListPaymentExportParams) ([]ListPaymentExportRow, error)
+	GetAccountByIDAndClientID(ctx context.Context, arg GetAccountByIDAndClientIDParams) (Account, error)
+	ListClientLogs(ctx context.Context, arg ListClientLogsParams) ([]Log, error)
+	GetActiveWebhookEndpoint(ctx context.Context, arg GetActiveWebhookEndpointParams) (WebhookEndpoint, error)
+	GetWebhookDelivery(ctx context.Context, arg GetWebhookDeliveryParams) (GetWebhookDeliveryRow, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]ListWebhookDeliveriesRow, error)
+	ReplayWebhookDelivery(ctx context.Context, arg ReplayWebhookDeliveryParams) (WebhookDelivery, error)
+}
+
+// TenantQueries is what one client may read: its payments, accounts, logs
+// and webhook deliveries. Every query is scoped to the client it was made for,
+// whatever ClientID the arguments carry, so a handler holding one cannot
+// leak another client's data by forgetting the client_id predicate.
+type TenantQueries struct {
+	q        TenantQuerier
+	clientID uuid.UUID
+}
+
+// NewTenantQueries scopes q to clientID.
+func NewTenantQueries(q TenantQuerier, clientID uuid.UUID) *TenantQueries {
+	return &TenantQueries{q: q, clientID: clientID}
+}
+
+// ClientID is the client the queries are scoped to.
+func (t *TenantQueries) ClientID() uuid.UUID {
+	return t.clientID
+}
+
+// GetPayment returns the payment with id, or pgx.ErrNoRows when it belongs
+// to another client, so the two cannot be told apart.
+func (t *TenantQueries) GetPayment(ctx context.Context, id uuid.UUID) (Payment, error) {
+	p, err := t.q.GetPayment(ctx, id)
+	if err != nil {
+		return Payment{}, err
+	}
+	if p.ClientID != t.clientID {
+		return Payment{}, pgx.ErrNoRows
+	}
+	return p, nil
+}
+
+func (t *TenantQueries) GetPaymentByOrderReference(ctx context.Context, orderReference string) (Payment, error) {
+	return t.q.GetPaymentByOrderReference(ctx, GetPaymentByOrderReferenceParams{
+		ClientID:       t.clientID,
+		OrderReference: orderReference,
+	})
+}
+
+func (t *TenantQueries) SearchPaymentsByWallet(ctx context.Context, arg SearchPaymentsByWalletParams) ([]Payment, error) {
+	arg.ClientID = t.clientID
+	return t.q.SearchPaymentsByWallet(ctx, arg)
+}
+
+func (t *TenantQueries) SearchPaymentsByTxHash(ctx context.Context, arg SearchPaymentsByTxHashParams) ([]Payment, error) {
+	arg.ClientID = t.clientID
+	return t.q.SearchPaymentsByTxHash(ctx, arg)
+}
+
+func (t *TenantQueries) ListRecentClientPayments(ctx context.Context, arg ListRecentClientPaymentsParams) ([]Payment, error) {
+	arg.ClientID = t.clientID
+	return t.q.ListRecentClientPayments(ctx, arg)
+}
+
+func (t *TenantQueries) CountClientPaymentsByStatus(ctx context.Context, arg CountClientPaymentsByStatusParams) ([]CountClientPaymentsByStatusRow, error) {
+	arg.ClientID = t.clientID
+	return t.q.CountClientPaymentsByStatus(ctx, arg)
+}
+
+func (t *TenantQueries) GetDailyConfirmedVolume(ctx context.Context, arg GetDailyConfirmedVolumeParams) ([]GetDailyConfirmedVolumeRow, error) {
+	arg.ClientID = t.clientID
+	return t.q.GetDailyConfirmedVolume(ctx, arg)
+}
+
+func (t *TenantQueries) GetPaymentFunnel(ctx context.Context, arg GetPaymentFunnelParams) ([]GetPaymentFunnelRow, error) {
+	arg.ClientID = &t.clientID
+	return t.q.GetPaymentFunnel(ctx, arg)
+}
+
+func (t *TenantQueries) ListPaymentExport(ctx context.Context, arg ListPaymentExportParams) ([]ListPaymentExportRow, error) {
+	arg.ClientID = t.clientID
+	return t.q.ListPaymentExport(ctx, arg)
+}
+
+func (t *TenantQueries) GetAccountByIDAndClientID(ctx context.Context, arg GetAccountByIDAndClientIDParams) (Account, error) {
+	arg.ClientID = t.clientID
+	return t.q.GetAccountByIDAndClientID(ctx, arg)
+}
+
+func (t *TenantQueries) ListClientLogs(ctx context.Context, arg ListClientLogsParams) ([]Log, error) {
+	arg.ClientID = t.clientID
+	return t.q.ListClientLogs(ctx, arg)
+}
+
+func (t *TenantQueries) GetActiveWebhookEndpoint(ctx context.Context, id uuid.UUID) (WebhookEndpoint, error) {
+	return t.q.GetActiveWebhookEndpoint(ctx, GetActiveWebhookEndpointParams{ID: id, ClientID: t.clientID})
+}
+
+func (t *TenantQueries) GetWebhookDelivery(ctx context.Context, id uuid.UUID) (GetWebhookDeliveryRow, error) {
+	return t.q.GetWebhookDelivery(ctx, GetWebhookDeliveryParams{ID: id, ClientID: t.clientID})
+}
+
+func (t *TenantQueries) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]ListWebhookDeliveriesRow, error) {
+	arg.ClientID = t.clientID
+	return t.q.ListWebhookDeliveries(ctx, arg)
+}
+
+func (t *TenantQueries) ReplayWebhookDelivery(ctx context.Context, arg ReplayWebhookDeliveryParams) (WebhookDelivery, error) {
+	arg.ClientID = t.clientID
+	return t.q.ReplayWebhookDelivery(ctx, arg)
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ TenantQuerier = Querier(nil)
+	_ TenantQuerier = (*Store)(nil)
+)
+
+func TestTenantQueries_GetPayment(t *testing.T) {
+	ctx := context.Background()
+	clientID := uuid.New()
+	own := Payment{ID: uuid.New(), ClientID: clientID}
+	other := Payment{ID: uuid.New(), ClientID: uuid.New()}
+	q := NewMockQuerier(t)
+	q.On("GetPayment", ctx, own.ID).Return(own, nil)
+	q.On("GetPayment", ctx, other.ID).Return(other, nil)
+	tenant := NewTenantQueries(q, clientID)
+
+	got, err := tenant.GetPayment(ctx, own.ID)
+	require.NoError(t, err)
+	assert.Equal(t, own, got)
+
+	_, err = tenant.GetPayment(ctx, other.ID)
+	assert.ErrorIs(t, err, pgx.ErrNoRows, "another client's payment is not found")
+}
+
+func TestTenantQueries_ScopesArguments(t *testing.T) {
+	ctx := context.Background()
+	clientID, intruder := uuid.New(), uuid.New()
+	q := NewMockQuerier(t)
+	tenant := NewTenantQueries(q, clientID)
+	assert.Equal(t, clientID, tenant.ClientID())
+
+	q.On("SearchPaymentsByWallet", ctx, SearchPaymentsByWalletParams{ClientID: clientID, Wallet: "TWallet", Limit: 20}).
+		Return([]Payment{}, nil)
+	_, err := tenant.SearchPaymentsByWallet(ctx, SearchPaymentsByWalletParams{ClientID: intruder, Wallet: "TWallet", Limit: 20})
+	require.NoError(t, err, "a ClientID passed in is replaced")
+
+	q.On("GetPaymentByOrderReference", ctx, GetPaymentByOrderReferenceParams{ClientID: clientID, OrderReference: "order-1"}).
+		Return(Payment{}, nil)
+	_, err = tenant.GetPaymentByOrderReference(ctx, "order-1")
+	require.NoError(t, err)
+
+	id := uuid.New()
+	q.On("GetAccountByIDAndClientID", ctx, GetAccountByIDAndClientIDParams{ID: id, ClientID: clientID, IncludeDeleted: true}).
+		Return(Account{}, nil)
+	_, err = tenant.GetAccountByIDAndClientID(ctx, GetAccountByIDAndClientIDParams{ID: id, IncludeDeleted: true})
+	require.NoError(t, err)
+
+	q.On("GetWebhookDelivery", ctx, GetWebhookDeliveryParams{ID: id, ClientID: clientID}).Return(GetWebhookDeliveryRow{}, nil)
+	_, err = tenant.GetWebhookDelivery(ctx, id)
+	require.NoError(t, err)
+
+	q.On("GetPaymentFunnel", ctx, mock.MatchedBy(func(arg GetPaymentFunnelParams) bool {
+		return arg.ClientID != nil && *arg.ClientID == clientID
+	})).Return([]GetPaymentFunnelRow{}, nil)
+	_, err = tenant.GetPaymentFunnel(ctx, GetPaymentFunnelParams{Bucket: "day"})
+	require.NoError(t, err, "a funnel without a client is scoped all the same")
+
+	q.On("ListWebhookDeliveries", ctx, mock.MatchedBy(func(arg ListWebhookDeliveriesParams) bool {
+		return arg.ClientID == clientID
+	})).Return([]ListWebhookDeliveriesRow{}, nil)
+	_, err = tenant.ListWebhookDeliveries(ctx, ListWebhookDeliveriesParams{ClientID: intruder, Limit: 10})
+	require.NoError(t, err)
+}
+
+func TestTenantQueries_ListClientLogs(t *testing.T) {
+	ctx := context.Background()
+	clientID, other := uuid.New(), uuid.New()
+	logs := map[uuid.UUID][]Log{
+		clientID: {{ID: uuid.New(), EventType: "PAYMENT_CREATED"}},
+		other:    {{ID: uuid.New(), EventType: "PAYMENT_CREATED"}},
+	}
+	q := NewMockQuerier(t)
+	q.On("ListClientLogs", ctx, mock.Anything).Return(func(_ context.Context, arg ListClientLogsParams) ([]Log, error) {
+		return logs[arg.ClientID], nil
+	})
+	tenant := NewTenantQueries(q, clientID)
+
+	got, err := tenant.ListClientLogs(ctx, ListClientLogsParams{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, logs[clientID], got)
+
+	got, err = tenant.ListClientLogs(ctx, ListClientLogsParams{ClientID: other, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, logs[clientID], got, "another client's logs cannot be read")
+}
+
+// internalOnly are the payment, account and log queries that take no client
+// id. They serve the watcher, sweeper, reconciler and other background jobs,
+// the admin API, or act on a row already loaded through a client-scoped query. The API reaches
+// client data through TenantQueries; add a query here only if no client ever
+// reaches it directly.
+var internalOnly = map[string]bool{
+	// Background jobs.
+	"ConfirmPayment":                  true,
+	"CountPendingPaymentsByAccountID": true,
+	"GetOpenPaymentByWalletAndAmount": true,
+	"GetPaymentByUniqueWallet":        true,
+	"GetPaymentByWallet":              true,
+	"GetPendingPaymentByAnyWallet":    true,
+	"ListAccountsBelowLowWater":       true,
+	"ListExpiredPayments":             true,
+	"ListPaymentStatuses":             true,
+	"ListPaymentTransactions":         true,
+	"ListPayments":                    true,
+	"ListReconciliationPayments":      true,
+	"ListRecentPendingPayments":       true,
+	"RevertPaymentConfirmation":       true,
+	"SumPaymentTransfers":             true,
+	"SyncPaymentWallet":               true,
+	"UpdatePaymentStatus":             true,
+	"UpsertPaymentAttempt":            true,
+	// Loaded by id, then checked against the client by TenantQueries.
+	"GetPayment": true,
+	// Writes to a payment already loaded through TenantQueries.
+	"CancelPayment":        true,
+	"CreatePaymentAttempt": true,
+	"ListPaymentAttempts":  true,
+	"UpdatePaymentWallet":  true,
+	// Writes to an account already loaded through TenantQueries.
+	"SetAccountDepositWallet": true,
+	// Written by every service and read by the admin API and log retention.
+	"CountLogsOlderThan": true,
+	"CreateLog":          true,
+	"DeleteLogsBatch":    true,
+	"ListLogEventTypes":  true,
+	"ListLogs":           true,
+	"UpsertLog":          true,
+}
+
+// takesClientID reports whether m, a Querier method, is scoped to a client:
+// its argument struct has a ClientID, or it takes the client id alone.
+func takesClientID(m reflect.Method) bool {
+	if m.Type.NumIn() < 2 {
+		return false
+	}
+	arg := m.Type.In(1)
+	if arg.Kind() == reflect.Struct {
+		_, ok := arg.FieldByName("ClientID")
+		return ok
+	}
+	return arg == reflect.TypeOf(uuid.UUID{}) && strings.HasSuffix(m.Name, "ByClientID")
+}
+
+func TestQuerier_PaymentAccountAndLogQueriesAreClientScoped(t *testing.T) {
+	querier := reflect.TypeOf((*Querier)(nil)).Elem()
+	for i := range querier.NumMethod() {
+		m := querier.Method(i)
+		if !strings.Contains(m.Name, "Payment") && !strings.Contains(m.Name, "Account") && !strings.Contains(m.Name, "Log") {
+			continue
+		}
+		scoped := takesClientID(m)
+		if internalOnly[m.Name] {
+			assert.False(t, scoped, "%s takes a client id; drop it from internalOnly", m.Name)
+			continue
+		}
+		assert.True(t, scoped, "%s takes no client id: scope it, or list it in internalOnly if no client reaches it", m.Name)
+	}
+
+	for name := range internalOnly {
+		_, ok := querier.MethodByName(name)
+		assert.True(t, ok, "internalOnly lists %s, which Querier does not have", name)
+	}
+}
@@ -34,6 +34,60 @@ func (m *MockDBTX) QueryRow(ctx context.Context, query string, args ...interface
 	return argsMock.Get(0).(pgx.Row)
 }
 
+// MockRow is a mock implementation of pgx.Row
+type MockRow struct {
+	mock.Mock
+}
+
+func (m *MockRow) Scan(dest ...interface{}) error {
+	args := m.Called(dest)
+	return args.Error(0)
+}
+
+// MockRows is a mock implementation of pgx.Rows
+type MockRows struct {
+	mock.Mock
+}
+
+func (m *MockRows) Close() {
+	m.Called()
+}
+
+func (m *MockRows) Err() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockRows) CommandTag() pgconn.CommandTag {
+	return pgconn.CommandTag{}
+}
+
+func (m *MockRows) FieldDescriptions() []pgconn.FieldDescription {
+	return nil
+}
+
+func (m *MockRows) Next() bool {
+	args := m.Called()
+	return args.Bool(0)
+}
+
+func (m *MockRows) Scan(dest ...interface{}) error {
+	args := m.Called(dest)
+	return args.Error(0)
+}
+
+func (m *MockRows) Values() ([]interface{}, error) {
+	return nil, nil
+}
+
+func (m *MockRows) RawValues() [][]byte {
+	return nil
+}
+
+func (m *MockRows) Conn() *pgx.Conn {
+	return nil
+}
+
 // MockTx is a mock implementation of pgx.Tx interface
 type MockTx struct {
 	mock.Mock
@@ -97,4 +151,3 @@ func (m *MockTx) Conn() *pgx.Conn {
 func boolPtr(b bool) *bool {
 	return &b
 }
-$ r .sed-tmp
\ No newline at end of file
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// queryCanceled is the SQLSTATE for query_canceled, which the server
+// reports when a statement runs past statement_timeout.
+const queryCanceled = "57014"
+
+// WithQueryTimeout bounds every query the Store makes to d, so a slow query
+// fails with ErrQueryTimeout instead of holding its connection for as long
+// as the caller is willing to wait. Zero leaves queries unbounded.
+func WithQueryTimeout(d time.Duration) StoreOption {
+	return func(s *Store) {
+		if d > 0 {
+			s.timeout = d
+		}
+	}
+}
+
+type queryTimeoutKey struct{}
+
+// ContextWithQueryTimeout returns a context whose queries are bounded to d
+// instead of the timeout the Store was built with, e.g. for a report known
+// to scan more rows than a request should. It has no effect on a Store built
+// without WithQueryTimeout.
+func ContextWithQueryTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, queryTimeoutKey{}, d)
+}
+
+// timeoutDB is a DBTX bounding each query to timeout, or to the timeout in
+// the query's context.
+type timeoutDB struct {
+	db      DBTX
+	timeout time.Duration
+}
+
+func (t timeoutDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	qctx, cancel := t.withTimeout(ctx)
+	defer cancel()
+	tag, err := t.db.Exec(qctx, sql, args...)
+	return tag, queryError(ctx, qctx, err)
+}
+
+func (t timeoutDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	qctx, cancel := t.withTimeout(ctx)
+	rows, err := t.db.Query(qctx, sql, args...)
+	if err != nil {
+		cancel()
+		return nil, queryError(ctx, qctx, err)
+	}
+	return &timeoutRows{Rows: rows, ctx: ctx, qctx: qctx, cancel: cancel}, nil
+}
+
+func (t timeoutDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	qctx, cancel := t.withTimeout(ctx)
+	return &timeoutRow{row: t.db.QueryRow(qctx, sql, args...), ctx: ctx, qctx: qctx, cancel: cancel}
+}
+
+func (t timeoutDB) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	d := t.timeout
+	if v, ok := ctx.Value(queryTimeoutKey{}).(time.Duration); ok && v > 0 {
+		d = v
+	}
+	return context.WithTimeoutCause(ctx, d, ErrQueryTimeout)
+}
+
+// timeoutRow releases its timer once the row is scanned, which is when pgx
+// reports the query's error.
+type timeoutRow struct {
+	row       pgx.Row
+	ctx, qctx context.Context
+	cancel    context.CancelFunc
+}
+
+func (r *timeoutRow) Scan(dest ...any) error {
+	defer r.cancel()
+	return queryError(r.ctx, r.qctx, r.row.Scan(dest...))
+}
+
+// timeoutRows releases its timer when the rows are closed.
+type timeoutRows struct {
+	pgx.Rows
+	ctx, qctx context.Context
+	cancel    context.CancelFunc
+}
+
+func (r *timeoutRows) Close() {
+	r.Rows.Close()
+	r.cancel()
+}
+
+func (r *timeoutRows) Err() error {
+	return queryError(r.ctx, r.qctx, r.Rows.Err())
+}
+
+// queryError wraps err with ErrQueryTimeout when the query ran out of time:
+// either the timer of qctx fired or the server cancelled the statement,
+// while the caller's ctx was still live. A caller giving up is not a
+// timeout of ours, so its error is returned as is.
+func queryError(ctx, qctx context.Context, err error) error {
+	if err == nil || errors.Is(err, ErrQueryTimeout) || ctx.Err() != nil {
+		return err
+	}
+	var pgErr *pgconn.PgError
+	if errors.Is(context.Cause(qctx), ErrQueryTimeout) || (errors.As(err, &pgErr) && pgErr.Code == queryCanceled) {
+		return fmt.Errorf("%w: %w", ErrQueryTimeout, err)
+	}
+	return err
+}
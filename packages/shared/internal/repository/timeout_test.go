@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// blockingDB is a DBTX whose queries hang until their context is done, then
+// fail the way pgx does, wrapping the context's error.
+type blockingDB struct {
+	MockDBTX
+}
+
+func (b *blockingDB) wait(ctx context.Context) error {
+	<-ctx.Done()
+	return fmt.Errorf("timeout: %w", ctx.Err())
+}
+
+func (b *blockingDB) Exec(ctx context.Context, _ string, _ ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, b.wait(ctx)
+}
+
+func (b *blockingDB) Query(ctx context.Context, _ string, _ ...interface{}) (pgx.Rows, error) {
+	return nil, b.wait(ctx)
+}
+
+func (b *blockingDB) QueryRow(ctx context.Context, _ string, _ ...interface{}) pgx.Row {
+	return errRow{b.wait(ctx)}
+}
+
+type errRow struct{ err error }
+
+func (r errRow) Scan(...any) error { return r.err }
+
+func TestStore_QueryTimeout(t *testing.T) {
+	store := NewStore(&blockingDB{}, WithQueryTimeout(10*time.Millisecond))
+	ctx := context.Background()
+
+	testCases := []struct {
+		name string
+		call func() error
+	}{
+		{"exec", func() error { return store.MarkOutboxEventsProcessed(ctx, []uuid.UUID{uuid.New()}) }},
+		{"query", func() error { _, err := store.ClaimOutboxEvents(ctx, 10); return err }},
+		{"query row", func() error { _, err := store.GetPayment(ctx, uuid.New()); return err }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			start := time.Now()
+			err := tc.call()
+
+			assert.ErrorIs(t, err, ErrQueryTimeout)
+			assert.ErrorIs(t, err, context.DeadlineExceeded, "the driver's error is kept")
+			assert.Less(t, time.Since(start), time.Second)
+		})
+	}
+}
+
+func TestStore_QueryTimeout_ContextOverride(t *testing.T) {
+	store := NewStore(&blockingDB{}, WithQueryTimeout(time.Hour))
+	ctx := ContextWithQueryTimeout(context.Background(), 10*time.Millisecond)
+
+	_, err := store.GetPayment(ctx, uuid.New())
+
+	assert.ErrorIs(t, err, ErrQueryTimeout)
+}
+
+func TestStore_QueryTimeout_CallerDeadline(t *testing.T) {
+	store := NewStore(&blockingDB{}, WithQueryTimeout(time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := store.GetPayment(ctx, uuid.New())
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, ErrQueryTimeout, "the caller giving up is not a query timeout")
+}
+
+func TestStore_QueryTimeout_StatementTimeout(t *testing.T) {
+	mockDB := new(MockDBTX)
+	store := NewStore(mockDB, WithQueryTimeout(time.Minute))
+	mockDB.On("Exec", mock.Anything, markOutboxEventsProcessed, mock.Anything).
+		Return(pgconn.CommandTag{}, &pgconn.PgError{Code: "57014", Message: "query execution canceled due to statement timeout"})
+
+	err := store.MarkOutboxEventsProcessed(context.Background(), []uuid.UUID{uuid.New()})
+
+	assert.ErrorIs(t, err, ErrQueryTimeout)
+}
+
+func TestStore_QueryTimeout_OtherErrorsPassThrough(t *testing.T) {
+	mockDB := new(MockDBTX)
+	store := NewStore(mockDB, WithQueryTimeout(time.Minute))
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", mock.Anything, getPayment, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+	_, err := store.GetPayment(context.Background(), uuid.New())
+
+	assert.Equal(t, pgx.ErrNoRows, err)
+}
+
+func TestStore_QueryTimeout_ReleasesTimerOnClose(t *testing.T) {
+	mockDB := new(MockDBTX)
+	store := NewStore(mockDB, WithQueryTimeout(time.Minute))
+	mockRows := new(MockRows)
+	var queryCtx context.Context
+	mockDB.On("Query", mock.Anything, claimOutboxEvents, mock.Anything).
+		Run(func(args mock.Arguments) { queryCtx = args.Get(0).(context.Context) }).
+		Return(mockRows, nil)
+	mockRows.On("Next").Return(false)
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	_, err := store.ClaimOutboxEvents(context.Background(), 10)
+
+	require.NoError(t, err)
+	require.NotNil(t, queryCtx)
+	_, hasDeadline := queryCtx.Deadline()
+	assert.True(t, hasDeadline)
+	assert.ErrorIs(t, queryCtx.Err(), context.Canceled, "closing the rows cancels the query context")
+}
+
+func TestStore_QueryTimeout_InTransaction(t *testing.T) {
+	tx := &recordingTx{}
+	store := NewStore(&beginnerDB{tx: tx}, WithQueryTimeout(10*time.Millisecond))
+	mockRow := new(MockRow)
+	tx.On("QueryRow", mock.Anything, nextWalletIndex, []interface{}{"deposit"}).
+		Run(func(args mock.Arguments) { <-args.Get(0).(context.Context).Done() }).
+		Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(errors.New("timeout: context deadline exceeded"))
+
+	err := store.ExecTx(context.Background(), func(q Querier) error {
+		_, err := q.NextWalletIndex(context.Background(), "deposit")
+		return err
+	})
+
+	assert.ErrorIs(t, err, ErrQueryTimeout)
+	assert.True(t, tx.rolledBack)
+}
+
+func TestStore_QueryTimeout_Traced(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	store := NewStore(&blockingDB{}, WithTracerProvider(tp), WithQueryTimeout(10*time.Millisecond))
+
+	_, err := store.GetPayment(context.Background(), uuid.New())
+
+	require.ErrorIs(t, err, ErrQueryTimeout)
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Contains(t, spans[0].Status().Description, "query timed out")
+}
+
+func TestNewStore_WithoutQueryTimeout(t *testing.T) {
+	mockDB := new(MockDBTX)
+
+	store := NewStore(mockDB, WithQueryTimeout(0))
+
+	assert.Equal(t, mockDB, store.Querier.(*Queries).db, "queries are unbounded")
+}
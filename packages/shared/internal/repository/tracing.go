@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans the Store records.
+const tracerName = "github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+
+// StoreOption customises a Store.
+type StoreOption func(*Store)
+
+// WithTracerProvider records a client span for every query, named after
+// the query as in "db.GetPayment", and one for every transaction ExecTx
+// runs, parenting the queries inside it. Spans hang off the span in the
+// caller's context. Argument values are never recorded.
+func WithTracerProvider(tp trace.TracerProvider) StoreOption {
+	return func(s *Store) {
+		if tp != nil {
+			s.tracer = tp.Tracer(tracerName)
+		}
+	}
+}
+
+// tracedDB is a DBTX recording a span per query.
+type tracedDB struct {
+	db     DBTX
+	tracer trace.Tracer
+
+	// txSpan, when set, is the span of the transaction db is bound to.
+	// Queries made with the context ExecTx was called with, outer, become
+	// its children; fn has no other context to make them with.
+	txSpan trace.Span
+	outer  trace.SpanContext
+}
+
+func (t tracedDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, span := t.start(ctx, sql)
+	tag, err := t.db.Exec(ctx, sql, args...)
+	if err == nil {
+		span.SetAttributes(attribute.Int64("db.rows_affected", tag.RowsAffected()))
+	}
+	endSpan(span, err)
+	return tag, err
+}
+
+func (t tracedDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	ctx, span := t.start(ctx, sql)
+	rows, err := t.db.Query(ctx, sql, args...)
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func (t tracedDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	ctx, span := t.start(ctx, sql)
+	return &tracedRow{row: t.db.QueryRow(ctx, sql, args...), span: span}
+}
+
+func (t tracedDB) start(ctx context.Context, sql string) (context.Context, trace.Span) {
+	if t.txSpan != nil && trace.SpanContextFromContext(ctx).Equal(t.outer) {
+		ctx = trace.ContextWithSpan(ctx, t.txSpan)
+	}
+	name := queryName(sql)
+	spanName := "db.query"
+	if name != "" {
+		spanName = "db." + name
+	}
+	return t.tracer.Start(ctx, spanName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation.name", name),
+		))
+}
+
+// tracedRow ends its span once the row is scanned, which is when pgx
+// reports the query's error.
+type tracedRow struct {
+	row  pgx.Row
+	span trace.Span
+}
+
+func (r *tracedRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// No row is an answer, not a failure.
+		endSpan(r.span, nil)
+		return err
+	}
+	endSpan(r.span, err)
+	return err
+}
+
+// tracedRows ends its span when the rows are closed.
+type tracedRows struct {
+	pgx.Rows
+	span  trace.Span
+	close sync.Once
+}
+
+func (r *tracedRows) Close() {
+	r.Rows.Close()
+	r.close.Do(func() {
+		if r.Rows.Err() == nil {
+			r.span.SetAttributes(attribute.Int64("db.rows_affected", r.Rows.CommandTag().RowsAffected()))
+		}
+		endSpan(r.span, r.Rows.Err())
+	})
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// queryName extracts the sqlc query name from a "-- name: X :kind" header.
+func queryName(sql string) string {
+	rest, ok := strings.CutPrefix(sql, "-- name: ")
+	if !ok {
+		return ""
+	}
+	name, _, _ := strings.Cut(rest, " ")
+	return name
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newRecordedStore(db DBTX) (*Store, *tracetest.SpanRecorder) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	return NewStore(db, WithTracerProvider(tp)), rec
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := make([]string, len(spans))
+	for i, s := range spans {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+func TestStore_TracesQueries(t *testing.T) {
+	mockDB := new(MockDBTX)
+	store, rec := newRecordedStore(mockDB)
+	paymentID := uuid.New()
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", mock.Anything, getPayment, []interface{}{paymentID}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+	mockDB.On("Exec", mock.Anything, markOutboxEventsProcessed, mock.Anything).
+		Return(pgconn.NewCommandTag("UPDATE 2"), nil)
+
+	_, err := store.GetPayment(context.Background(), paymentID)
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+	require.NoError(t, store.MarkOutboxEventsProcessed(context.Background(), []uuid.UUID{paymentID}))
+
+	spans := rec.Ended()
+	require.Equal(t, []string{"db.GetPayment", "db.MarkOutboxEventsProcessed"}, spanNames(spans))
+	assert.Equal(t, codes.Unset, spans[0].Status().Code, "no rows is not an error")
+	assert.Contains(t, spans[1].Attributes(), attribute.Int64("db.rows_affected", 2))
+}
+
+func TestStore_TracesQueryErrors(t *testing.T) {
+	mockDB := new(MockDBTX)
+	store, rec := newRecordedStore(mockDB)
+	mockDB.On("Query", mock.Anything, claimOutboxEvents, []interface{}{int32(10)}).
+		Return(nil, errors.New("connection reset"))
+
+	_, err := store.ClaimOutboxEvents(context.Background(), 10)
+	require.Error(t, err)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "db.ClaimOutboxEvents", spans[0].Name())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
+
+func TestStore_ExecTx_ParentsQuerySpans(t *testing.T) {
+	tx := &recordingTx{}
+	store, rec := newRecordedStore(&beginnerDB{tx: tx})
+	mockRow := new(MockRow)
+	tx.On("QueryRow", mock.Anything, nextWalletIndex, []interface{}{"deposit"}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil)
+
+	parentCtx, parent := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "request")
+	err := store.ExecTx(parentCtx, func(q Querier) error {
+		_, err := q.NextWalletIndex(parentCtx, "deposit")
+		return err
+	})
+	parent.End()
+	require.NoError(t, err)
+
+	spans := rec.Ended()
+	require.Equal(t, []string{"db.NextWalletIndex", "db.transaction"}, spanNames(spans))
+	query, txSpan := spans[0], spans[1]
+	assert.Equal(t, parent.SpanContext().SpanID(), txSpan.Parent().SpanID())
+	assert.Equal(t, txSpan.SpanContext().SpanID(), query.Parent().SpanID(),
+		"queries made with the caller's context nest under the transaction")
+	assert.Equal(t, parent.SpanContext().TraceID(), query.SpanContext().TraceID())
+}
+
+func TestStore_ExecTx_RecordsRollback(t *testing.T) {
+	store, rec := newRecordedStore(&beginnerDB{tx: &recordingTx{}})
+	boom := errors.New("boom")
+
+	err := store.ExecTx(context.Background(), func(Querier) error { return boom })
+	require.ErrorIs(t, err, boom)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
+
+func TestNewStore_UntracedByDefault(t *testing.T) {
+	mockDB := new(MockDBTX)
+	store := NewStore(mockDB)
+	assert.Same(t, mockDB, store.Querier.(*Queries).db)
+}
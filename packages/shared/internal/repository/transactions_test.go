@@ -0,0 +1,251 @@
+package repository
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateTransactionSQL(t *testing.T) {
+	assert.Contains(t, createTransaction, "-- name: CreateTransaction :one\nINSERT INTO transactions")
+	assert.Contains(t, createTransaction, "VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)")
+}
+
+func TestQueries_CreateTransaction_Success(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	contract := "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+	params := CreateTransactionParams{
+		PaymentID:       uuid.New(),
+		TxHash:          "f00d",
+		TransferIndex:   1,
+		Token:           "USDT",
+		ContractAddress: &contract,
+		FromAddress:     "TFrom",
+		ToAddress:       "TTo",
+		Amount:          pgtype.Numeric{Valid: true},
+		BlockNumber:     42,
+		BlockHash:       "beef",
+	}
+	txID := uuid.New()
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createTransaction, []interface{}{
+		params.PaymentID, params.TxHash, params.TransferIndex, params.Token, params.ContractAddress,
+		params.FromAddress, params.ToAddress, params.Amount, params.BlockNumber, params.BlockHash, params.ExcessAmount,
+	}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 16)
+		*dest[0].(*uuid.UUID) = txID
+		*dest[9].(*int64) = params.BlockNumber
+		*dest[12].(*string) = "DETECTED"
+	})
+
+	tx, err := queries.CreateTransaction(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, txID, tx.ID)
+	assert.Equal(t, int64(42), tx.BlockNumber)
+	assert.Equal(t, "DETECTED", tx.Status)
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_CreateLog(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	msg := "transfer detected"
+	params := CreateLogParams{
+		PaymentID: UUIDPtr(uuid.New()),
+		EventType: "TX_DETECTED",
+		Message:   &msg,
+		RawData:   []byte(`{"tx_hash":"f00d"}`),
+	}
+
+	mockDB.On("Exec", ctx, createLog, []interface{}{params.PaymentID, params.EventType, params.Message, params.RawData, params.RequestID, params.Actor}).Return(nil, nil)
+
+	require.NoError(t, queries.CreateLog(ctx, params))
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_ListDetectedTransactions(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, listDetectedTransactions, []interface{}{"live"}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 16)
+		*dest[2].(*string) = "f00d"
+		*dest[12].(*string) = "DETECTED"
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	txs, err := queries.ListDetectedTransactions(ctx, "live")
+
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	assert.Equal(t, "f00d", txs[0].TxHash)
+	mockRows.AssertExpectations(t)
+}
+
+func TestQueries_UpdateTransactionConfirmations(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	arg := UpdateTransactionConfirmationsParams{ID: uuid.New(), Confirmations: 19, Status: "CONFIRMED"}
+	mockDB.On("Exec", ctx, updateTransactionConfirmations, []interface{}{arg.ID, arg.Confirmations, arg.Status}).Return(nil, nil)
+
+	require.NoError(t, queries.UpdateTransactionConfirmations(ctx, arg))
+	assert.Contains(t, updateTransactionConfirmations, "status != 'ORPHANED'", "an orphaned transfer stays orphaned")
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_UpdateTransactionBlock(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	arg := UpdateTransactionBlockParams{ID: uuid.New(), BlockNumber: 1004, BlockHash: "cafe", Confirmations: 1}
+	mockDB.On("Exec", ctx, updateTransactionBlock, []interface{}{arg.ID, arg.BlockNumber, arg.BlockHash, arg.Confirmations}).Return(nil, nil)
+
+	require.NoError(t, queries.UpdateTransactionBlock(ctx, arg))
+	assert.Contains(t, updateTransactionBlock, "status != 'ORPHANED'", "an orphaned transfer stays orphaned")
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_SumPaymentTransfers(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	paymentID := uuid.New()
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, sumPaymentTransfers, []interface{}{paymentID}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		*dest[0].(*pgtype.Numeric) = pgtype.Numeric{Int: big.NewInt(99900000), Exp: -6, Valid: true}
+	})
+
+	total, err := queries.SumPaymentTransfers(ctx, paymentID)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(99900000), total.Int.Int64())
+	assert.Contains(t, sumPaymentTransfers, "COALESCE(SUM(amount), 0)", "a payment without transfers sums to zero")
+	assert.Contains(t, sumPaymentTransfers, "kind = 'DEPOSIT'", "sweeps must not count as received")
+	assert.Contains(t, sumPaymentTransfers, "status != 'ORPHANED'", "orphaned transfers must not count as received")
+}
+
+func TestQueries_ListPaymentTransactions(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	paymentID := uuid.New()
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, listPaymentTransactions, []interface{}{paymentID}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Twice()
+	mockRows.On("Next").Return(false).Once()
+	kinds := []string{"DEPOSIT", "SWEEP"}
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 16)
+		*dest[1].(*uuid.UUID) = paymentID
+		*dest[15].(*string) = kinds[0]
+		kinds = kinds[1:]
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	txs, err := queries.ListPaymentTransactions(ctx, paymentID)
+
+	require.NoError(t, err)
+	require.Len(t, txs, 2)
+	assert.Equal(t, "DEPOSIT", txs[0].Kind)
+	assert.Equal(t, "SWEEP", txs[1].Kind)
+	assert.Contains(t, listPaymentTransactions, "status != 'ORPHANED'")
+	mockRows.AssertExpectations(t)
+}
+
+func TestQueries_ListTransactionsByBlockHashes(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	hashes := []string{"aa", "bb"}
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, listTransactionsByBlockHashes, []interface{}{hashes}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 16)
+		*dest[2].(*string) = "f00d"
+		*dest[10].(*string) = "bb"
+		*dest[12].(*string) = "CONFIRMED"
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	txs, err := queries.ListTransactionsByBlockHashes(ctx, hashes)
+
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	assert.Equal(t, "bb", txs[0].BlockHash)
+	assert.Contains(t, listTransactionsByBlockHashes, "kind = 'DEPOSIT'", "sweeps are reconciled by the sweeper")
+	mockRows.AssertExpectations(t)
+}
+
+func TestQueries_CreateSweepTransaction(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	params := CreateSweepTransactionParams{
+		PaymentID:     uuid.New(),
+		TxHash:        "5ee9",
+		Token:         "TRX",
+		FromAddress:   "TDeposit",
+		ToAddress:     "TCold",
+		Amount:        pgtype.Numeric{Valid: true},
+		BlockNumber:   1200,
+		BlockHash:     "cafe",
+		Confirmations: 19,
+	}
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, createSweepTransaction, []interface{}{
+		params.PaymentID, params.TxHash, params.Token, params.ContractAddress, params.FromAddress,
+		params.ToAddress, params.Amount, params.BlockNumber, params.BlockHash, params.Confirmations,
+	}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 16)
+		*dest[12].(*string) = "CONFIRMED"
+		*dest[15].(*string) = "SWEEP"
+	})
+
+	tx, err := queries.CreateSweepTransaction(ctx, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, "CONFIRMED", tx.Status)
+	assert.Equal(t, "SWEEP", tx.Kind)
+	assert.Contains(t, createSweepTransaction, "'CONFIRMED', 'SWEEP'")
+	mockDB.AssertExpectations(t)
+}
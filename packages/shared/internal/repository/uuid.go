@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Nullable UUID columns are *uuid.UUID, nil standing for NULL; non-null ones
+// are uuid.UUID. The helpers below cover the few places a pgtype.UUID still
+// crosses into the repository, e.g. a column scanned from a raw query.
+
+// UUIDPtr returns a pointer to a copy of id, for setting a nullable column.
+func UUIDPtr(id uuid.UUID) *uuid.UUID {
+	return &id
+}
+
+// UUIDFromPgtype returns id as a nullable column value, nil when it is not
+// valid.
+func UUIDFromPgtype(id pgtype.UUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return UUIDPtr(id.Bytes)
+}
+
+// PgtypeUUID returns id as a pgtype.UUID, invalid when id is nil.
+func PgtypeUUID(id *uuid.UUID) pgtype.UUID {
+	if id == nil {
+		return pgtype.UUID{}
+	}
+	return pgtype.UUID{Bytes: *id, Valid: true}
+}
+
+// SameUUID reports whether two nullable UUIDs hold the same value, both nil
+// counting as equal.
+func SameUUID(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
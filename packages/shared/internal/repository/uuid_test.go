@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUUIDPtr(t *testing.T) {
+	id := uuid.New()
+	p := UUIDPtr(id)
+
+	require.NotNil(t, p)
+	assert.Equal(t, id, *p)
+
+	*p = uuid.New()
+	assert.NotEqual(t, id, *p, "UUIDPtr must copy its argument")
+}
+
+func TestUUIDPgtypeConversion(t *testing.T) {
+	id := uuid.New()
+
+	assert.Nil(t, UUIDFromPgtype(pgtype.UUID{}))
+	assert.Equal(t, pgtype.UUID{}, PgtypeUUID(nil))
+
+	got := UUIDFromPgtype(pgtype.UUID{Bytes: id, Valid: true})
+	require.NotNil(t, got)
+	assert.Equal(t, id, *got)
+	assert.Equal(t, pgtype.UUID{Bytes: id, Valid: true}, PgtypeUUID(&id))
+}
+
+func TestSameUUID(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+
+	testCases := []struct {
+		name string
+		x, y *uuid.UUID
+		want bool
+	}{
+		{"both nil", nil, nil, true},
+		{"one nil", UUIDPtr(a), nil, false},
+		{"other nil", nil, UUIDPtr(a), false},
+		{"same value", UUIDPtr(a), UUIDPtr(a), true},
+		{"different value", UUIDPtr(a), UUIDPtr(b), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, SameUUID(tc.x, tc.y))
+		})
+	}
+}
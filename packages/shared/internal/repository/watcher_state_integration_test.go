@@ -0,0 +1,60 @@
+//go:build integration
+
+package repository
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegration_WatcherStateConflict(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	name := "test-" + uuid.NewString()
+
+	// Two replicas start together; only the first creates the row.
+	_, err := f.store.UpsertWatcherState(ctx, UpsertWatcherStateParams{Name: name, LastHeight: 100, RecentBlocks: []byte("[]")})
+	require.NoError(t, err)
+	_, err = f.store.UpsertWatcherState(ctx, UpsertWatcherStateParams{Name: name, LastHeight: 100, RecentBlocks: []byte("[]")})
+	assert.ErrorIs(t, err, ErrStateConflict)
+
+	// Both read height 100; the second to save loses.
+	height, err := f.store.UpsertWatcherState(ctx, UpsertWatcherStateParams{
+		Name:           name,
+		LastHeight:     101,
+		RecentBlocks:   []byte(`[{"height":101,"hash":"a"}]`),
+		ExpectedHeight: ptr(int64(100)),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(101), height)
+	_, err = f.store.UpsertWatcherState(ctx, UpsertWatcherStateParams{
+		Name:           name,
+		LastHeight:     101,
+		RecentBlocks:   []byte(`[{"height":101,"hash":"b"}]`),
+		ExpectedHeight: ptr(int64(100)),
+	})
+	assert.ErrorIs(t, err, ErrStateConflict)
+
+	state, err := f.store.GetWatcherState(ctx, name)
+	require.NoError(t, err)
+	assert.Equal(t, int64(101), state.LastHeight)
+	assert.JSONEq(t, `[{"height":101,"hash":"a"}]`, string(state.RecentBlocks), "the losing write left no trace")
+
+	// A reset moves the row under the running replica, which then conflicts
+	// and has to read the new position.
+	require.NoError(t, f.store.ResetWatcherState(ctx, ResetWatcherStateParams{Name: name, LastHeight: 50}))
+	_, err = f.store.UpsertWatcherState(ctx, UpsertWatcherStateParams{
+		Name:           name,
+		LastHeight:     102,
+		RecentBlocks:   []byte("[]"),
+		ExpectedHeight: ptr(int64(101)),
+	})
+	assert.ErrorIs(t, err, ErrStateConflict)
+	state, err = f.store.GetWatcherState(ctx, name)
+	require.NoError(t, err)
+	assert.Equal(t, int64(50), state.LastHeight)
+	assert.JSONEq(t, `[]`, string(state.RecentBlocks))
+}
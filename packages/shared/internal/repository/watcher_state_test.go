@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueries_GetWatcherHeight(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, getWatcherHeight, []interface{}{"tron"}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 1)
+		*dest[0].(*int64) = 1234
+	})
+
+	height, err := queries.GetWatcherHeight(ctx, "tron")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1234), height)
+}
+
+func TestQueries_GetWatcherHeight_NotFound(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, getWatcherHeight, []interface{}{"tron"}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+	_, err := queries.GetWatcherHeight(ctx, "tron")
+
+	assert.True(t, errors.Is(err, pgx.ErrNoRows))
+}
+
+func TestQueries_GetWatcherState(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, getWatcherState, []interface{}{"tron"}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 2)
+		*dest[0].(*int64) = 1234
+		*dest[1].(*[]byte) = []byte(`[{"height":1234,"hash":"ab"}]`)
+	})
+
+	state, err := queries.GetWatcherState(ctx, "tron")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1234), state.LastHeight)
+	assert.JSONEq(t, `[{"height":1234,"hash":"ab"}]`, string(state.RecentBlocks))
+}
+
+func TestQueries_UpsertWatcherState(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	blocks := []byte(`[{"height":99,"hash":"ab"}]`)
+	expected := int64(98)
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, upsertWatcherState, []interface{}{"tron", int64(99), blocks, &expected}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 1)
+		*dest[0].(*int64) = 99
+	})
+
+	height, err := queries.UpsertWatcherState(ctx, UpsertWatcherStateParams{
+		Name:           "tron",
+		LastHeight:     99,
+		RecentBlocks:   blocks,
+		ExpectedHeight: &expected,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(99), height)
+	assert.Contains(t, upsertWatcherState, "ON CONFLICT (name) DO UPDATE")
+	assert.Contains(t, upsertWatcherState, "recent_blocks = excluded.recent_blocks", "height and blocks are saved together")
+	assert.Contains(t, upsertWatcherState, "WHERE watcher_state.last_height = $4", "only a row still at the expected height is overwritten")
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_UpsertWatcherState_Conflict(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, upsertWatcherState, []interface{}{"tron", int64(99), []byte("[]"), (*int64)(nil)}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+	_, err := queries.UpsertWatcherState(ctx, UpsertWatcherStateParams{Name: "tron", LastHeight: 99, RecentBlocks: []byte("[]")})
+
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestQueries_ResetWatcherState(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+
+	ctx := context.Background()
+	mockDB.On("Exec", ctx, resetWatcherState, []interface{}{"tron", int64(500)}).Return(nil, nil)
+
+	err := queries.ResetWatcherState(ctx, ResetWatcherStateParams{Name: "tron", LastHeight: 500})
+
+	require.NoError(t, err)
+	assert.Contains(t, resetWatcherState, "recent_blocks = '[]'", "a reset forgets the recent blocks")
+	assert.NotContains(t, resetWatcherState, "WHERE", "a reset is unconditional")
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,259 @@
+//go:build integration
+
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// endpoint creates an active webhook endpoint of client.
+func (f *fixture) endpoint(clientID uuid.UUID, secret string) uuid.UUID {
+	f.t.Helper()
+	var id uuid.UUID
+	err := f.pool.QueryRow(f.ctx, "INSERT INTO webhook_endpoints (client_id, url, secret) VALUES ($1, $2, $3) RETURNING id",
+		clientID, "https://shop.example/hooks", secret).Scan(&id)
+	require.NoError(f.t, err)
+	return id
+}
+
+// delivery queues a payment.confirmed delivery of payment to every endpoint
+// of its client and returns the first.
+func (f *fixture) delivery(payment Payment) ListWebhookDeliveriesRow {
+	f.t.Helper()
+	require.NoError(f.t, f.store.CreateWebhookDeliveries(f.ctx, CreateWebhookDeliveriesParams{
+		EventType: "payment.confirmed",
+		Payload:   []byte(`{"type":"payment.confirmed"}`),
+		PaymentID: payment.ID,
+	}))
+	rows, err := f.store.ListWebhookDeliveries(f.ctx, ListWebhookDeliveriesParams{
+		ClientID:  payment.ClientID,
+		PaymentID: UUIDPtr(payment.ID),
+		Limit:     1,
+	})
+	require.NoError(f.t, err)
+	require.Len(f.t, rows, 1)
+	return rows[0]
+}
+
+func TestIntegration_ReplayWebhookDelivery(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	client := f.client()
+	f.endpoint(client.ID, "whsec_old")
+	payment := f.payment(f.account(client.ID))
+	original := f.delivery(payment)
+
+	code, msg, body, latency := int32(502), "endpoint returned 502", "bad gateway", int32(184)
+	require.NoError(t, f.store.FailWebhookDelivery(ctx, FailWebhookDeliveryParams{
+		ID: original.ID, LastStatusCode: &code, LastError: &msg, LastResponseBody: &body,
+		LastResponseHeaders: []byte(`{"Retry-After":"120"}`),
+		LastLatencyMs:       &latency,
+		AttemptHistory:      []byte(`[{"attempt":1,"status_code":502}]`),
+	}))
+
+	requestID := "req-replay"
+	replay, err := f.store.ReplayWebhookDelivery(ctx, ReplayWebhookDeliveryParams{
+		RequestID: &requestID,
+		ID:        original.ID,
+		ClientID:  client.ID,
+	})
+	require.NoError(t, err)
+
+	// The copy starts over...
+	assert.NotEqual(t, original.ID, replay.ID)
+	assert.Equal(t, "PENDING", replay.Status)
+	assert.Zero(t, replay.Attempts)
+	assert.Nil(t, replay.LastStatusCode)
+	assert.Nil(t, replay.LastError)
+	assert.Nil(t, replay.LastResponseBody)
+	assert.Nil(t, replay.LastResponseHeaders)
+	assert.Nil(t, replay.LastLatencyMs)
+	assert.JSONEq(t, `[]`, string(replay.AttemptHistory))
+	assert.Equal(t, requestID, *replay.RequestID)
+	assert.Equal(t, UUIDPtr(original.ID), replay.ReplayOf)
+	// ...with what the original was about.
+	assert.Equal(t, original.EndpointID, replay.EndpointID)
+	assert.Equal(t, original.PaymentID, replay.PaymentID)
+	assert.Equal(t, original.EventType, replay.EventType)
+	assert.JSONEq(t, `{"type":"payment.confirmed"}`, string(replay.Payload))
+
+	// The original is left as it is.
+	got, err := f.store.GetWebhookDelivery(ctx, GetWebhookDeliveryParams{ID: original.ID, ClientID: client.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "FAILED", got.Status)
+	assert.Equal(t, int32(1), got.Attempts)
+	assert.Equal(t, "bad gateway", *got.LastResponseBody)
+	assert.JSONEq(t, `{"Retry-After":"120"}`, string(got.LastResponseHeaders))
+	assert.Equal(t, int32(184), *got.LastLatencyMs)
+	assert.JSONEq(t, `[{"attempt":1,"status_code":502}]`, string(got.AttemptHistory))
+
+	// The worker picks the copy up with the endpoint's secret as it is now.
+	_, err = f.pool.Exec(ctx, "UPDATE webhook_endpoints SET secret = 'whsec_new' WHERE id = $1", original.EndpointID)
+	require.NoError(t, err)
+	due, err := f.store.ClaimWebhookDeliveries(ctx, ClaimWebhookDeliveriesParams{Limit: 1000, ClaimedUntil: claimLease()})
+	require.NoError(t, err)
+	var found bool
+	for _, d := range due {
+		if d.ID == replay.ID {
+			found = true
+			assert.Equal(t, "whsec_new", d.Secret)
+		}
+		assert.NotEqual(t, original.ID, d.ID)
+	}
+	assert.True(t, found, "the replay is due")
+
+	// Once claimed, it is not handed to another worker.
+	again, err := f.store.ClaimWebhookDeliveries(ctx, ClaimWebhookDeliveriesParams{Limit: 1000, ClaimedUntil: claimLease()})
+	require.NoError(t, err)
+	for _, d := range again {
+		assert.NotEqual(t, replay.ID, d.ID)
+	}
+
+	// Newest first, so the replay heads the payment's deliveries.
+	rows, err := f.store.ListWebhookDeliveries(ctx, ListWebhookDeliveriesParams{
+		ClientID:  client.ID,
+		PaymentID: UUIDPtr(payment.ID),
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, replay.ID, rows[0].ID)
+	assert.Equal(t, original.ID, rows[1].ID)
+
+	next, err := f.store.ListWebhookDeliveries(ctx, ListWebhookDeliveriesParams{
+		ClientID:  client.ID,
+		PaymentID: UUIDPtr(payment.ID),
+		BeforeID:  UUIDPtr(replay.ID),
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, next, 1)
+	assert.Equal(t, original.ID, next[0].ID)
+}
+
+func TestIntegration_ReplayWebhookDeliveryRefused(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	client := f.client()
+	endpoint := f.endpoint(client.ID, "whsec")
+	original := f.delivery(f.payment(f.account(client.ID)))
+
+	// Another client can neither see nor replay it.
+	other := f.client()
+	_, err := f.store.GetWebhookDelivery(ctx, GetWebhookDeliveryParams{ID: original.ID, ClientID: other.ID})
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+	_, err = f.store.ReplayWebhookDelivery(ctx, ReplayWebhookDeliveryParams{ID: original.ID, ClientID: other.ID})
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+
+	// Nor is anything sent to a deactivated endpoint.
+	_, err = f.pool.Exec(ctx, "UPDATE webhook_endpoints SET is_active = false WHERE id = $1", endpoint)
+	require.NoError(t, err)
+	_, err = f.store.ReplayWebhookDelivery(ctx, ReplayWebhookDeliveryParams{ID: original.ID, ClientID: client.ID})
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestIntegration_RotateWebhookEndpointSecret(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	client := f.client()
+	endpoint := f.endpoint(client.ID, "whsec_old")
+	f.delivery(f.payment(f.account(client.ID)))
+
+	// Another client cannot rotate it.
+	_, err := f.store.RotateWebhookEndpointSecret(ctx, RotateWebhookEndpointSecretParams{
+		Secret: "whsec_stolen", ID: endpoint, ClientID: f.client().ID,
+	})
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+
+	rotated, err := f.store.RotateWebhookEndpointSecret(ctx, RotateWebhookEndpointSecretParams{
+		Secret: "whsec_new", ID: endpoint, ClientID: client.ID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "whsec_new", rotated.Secret)
+	require.NotNil(t, rotated.SecondarySecret)
+	assert.Equal(t, "whsec_old", *rotated.SecondarySecret)
+	require.True(t, rotated.RotatedAt.Valid)
+
+	// The worker gets both secrets with the pending delivery.
+	due, err := f.store.ClaimWebhookDeliveries(ctx, ClaimWebhookDeliveriesParams{Limit: 1000, ClaimedUntil: claimLease()})
+	require.NoError(t, err)
+	var found bool
+	for _, d := range due {
+		if d.EndpointID == endpoint {
+			found = true
+			assert.Equal(t, "whsec_new", d.Secret)
+			assert.Equal(t, "whsec_old", *d.SecondarySecret)
+			assert.Equal(t, rotated.RotatedAt.Time, d.RotatedAt.Time)
+		}
+	}
+	assert.True(t, found, "the delivery is due")
+
+	// Cleanup keeps the old secret until the overlap window has ended.
+	_, err = f.store.ClearRotatedWebhookSecrets(ctx, pgtype.Timestamptz{Time: rotated.RotatedAt.Time.Add(-time.Second), Valid: true})
+	require.NoError(t, err)
+	got, err := f.store.GetActiveWebhookEndpoint(ctx, GetActiveWebhookEndpointParams{ID: endpoint, ClientID: client.ID})
+	require.NoError(t, err)
+	assert.NotNil(t, got.SecondarySecret)
+
+	_, err = f.store.ClearRotatedWebhookSecrets(ctx, rotated.RotatedAt)
+	require.NoError(t, err)
+	got, err = f.store.GetActiveWebhookEndpoint(ctx, GetActiveWebhookEndpointParams{ID: endpoint, ClientID: client.ID})
+	require.NoError(t, err)
+	assert.Nil(t, got.SecondarySecret)
+	assert.Equal(t, "whsec_new", got.Secret)
+}
+
+func TestIntegration_WebhookAttemptNeedsItsClaim(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	client := f.client()
+	f.endpoint(client.ID, "whsec")
+	queued := f.delivery(f.payment(f.account(client.ID)))
+	claim := func() ClaimWebhookDeliveriesRow {
+		t.Helper()
+		due, err := f.store.ClaimWebhookDeliveries(ctx, ClaimWebhookDeliveriesParams{Limit: 1000, ClaimedUntil: claimLease()})
+		require.NoError(t, err)
+		for _, d := range due {
+			if d.ID == queued.ID {
+				return d
+			}
+		}
+		t.Fatal("the delivery is not due")
+		return ClaimWebhookDeliveriesRow{}
+	}
+
+	stale := claim()
+	assert.True(t, stale.ClaimedUntil.Valid)
+	// The first worker overran its lease, and another took the delivery.
+	_, err := f.pool.Exec(ctx, "UPDATE webhook_deliveries SET next_attempt_at = now() WHERE id = $1", queued.ID)
+	require.NoError(t, err)
+	current := claim()
+
+	require.NoError(t, f.store.RescheduleWebhookDelivery(ctx, RescheduleWebhookDeliveryParams{
+		ID:            queued.ID,
+		NextAttemptAt: pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+		ClaimedUntil:  stale.ClaimedUntil,
+	}))
+	got, err := f.store.GetWebhookDelivery(ctx, GetWebhookDeliveryParams{ID: queued.ID, ClientID: client.ID})
+	require.NoError(t, err)
+	assert.Zero(t, got.Attempts, "the stale worker's attempt is not recorded")
+	assert.Equal(t, current.ClaimedUntil.Time, got.NextAttemptAt.Time)
+
+	require.NoError(t, f.store.MarkWebhookDelivered(ctx, MarkWebhookDeliveredParams{ID: queued.ID, ClaimedUntil: current.ClaimedUntil}))
+	got, err = f.store.GetWebhookDelivery(ctx, GetWebhookDeliveryParams{ID: queued.ID, ClientID: client.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "DELIVERED", got.Status)
+	assert.Equal(t, int32(1), got.Attempts)
+}
+
+// claimLease is the claimed_until of deliveries claimed by a test.
+func claimLease() pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true}
+}
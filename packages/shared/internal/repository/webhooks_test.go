@@ -0,0 +1,372 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueries_CreateWebhookDeliveries(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	requestID := "req-1"
+	params := CreateWebhookDeliveriesParams{
+		EventType: "payment.confirmed",
+		Payload:   []byte(`{}`),
+		RequestID: &requestID,
+		PaymentID: uuid.New(),
+	}
+	mockDB.On("Exec", ctx, createWebhookDeliveries,
+		[]interface{}{params.EventType, params.Payload, params.RequestID, params.PaymentID}).Return(nil, nil)
+
+	err := queries.CreateWebhookDeliveries(ctx, params)
+
+	require.NoError(t, err)
+	assert.Contains(t, createWebhookDeliveries, "JOIN webhook_endpoints e ON e.client_id = p.client_id")
+	assert.Contains(t, createWebhookDeliveries, "WHERE p.id = $4 AND e.is_active", "one delivery per active endpoint")
+	assert.Contains(t, createWebhookDeliveries, "AND (p.webhook_endpoint_id IS NULL OR e.id = p.webhook_endpoint_id)",
+		"a payment naming an endpoint only notifies that one")
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_GetActiveWebhookEndpoint(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	arg := GetActiveWebhookEndpointParams{ID: uuid.New(), ClientID: uuid.New()}
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, getActiveWebhookEndpoint, []interface{}{arg.ID, arg.ClientID}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 9)
+		*dest[0].(*uuid.UUID) = arg.ID
+		*dest[2].(*string) = "https://shop.example/hooks"
+		*dest[6].(*string) = "2026-03-01"
+	})
+
+	endpoint, err := queries.GetActiveWebhookEndpoint(ctx, arg)
+
+	require.NoError(t, err)
+	assert.Equal(t, arg.ID, endpoint.ID)
+	assert.Equal(t, "https://shop.example/hooks", endpoint.Url)
+	assert.Equal(t, "2026-03-01", endpoint.ApiVersion)
+	assert.Contains(t, getActiveWebhookEndpoint, "WHERE id = $1 AND client_id = $2 AND is_active")
+}
+
+func TestQueries_ClaimWebhookDeliveries(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	arg := ClaimWebhookDeliveriesParams{Limit: 50, ClaimedUntil: pgtype.Timestamptz{Time: time.Unix(1767225900, 0), Valid: true}}
+
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, claimWebhookDeliveries, []interface{}{int32(50), arg.ClaimedUntil}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 14)
+		*dest[3].(*string) = "payment.confirmed"
+		*dest[5].(*int32) = 2
+		requestID := "req-1"
+		*dest[6].(**string) = &requestID
+		*dest[7].(*[]byte) = []byte(`[{"attempt":1}]`)
+		*dest[8].(*string) = "https://shop.example/hooks"
+		*dest[9].(*string) = "whsec"
+		*dest[10].(*string) = "2026-03-01"
+		old := "whsec_old"
+		*dest[11].(**string) = &old
+		*dest[12].(*pgtype.Timestamptz) = pgtype.Timestamptz{Time: time.Unix(1767225600, 0), Valid: true}
+		*dest[13].(*pgtype.Timestamptz) = arg.ClaimedUntil
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	rows, err := queries.ClaimWebhookDeliveries(ctx, arg)
+
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "payment.confirmed", rows[0].EventType)
+	assert.Equal(t, int32(2), rows[0].Attempts)
+	assert.Equal(t, "https://shop.example/hooks", rows[0].Url)
+	assert.Equal(t, "whsec", rows[0].Secret)
+	assert.Equal(t, "req-1", *rows[0].RequestID)
+	assert.JSONEq(t, `[{"attempt":1}]`, string(rows[0].AttemptHistory))
+	assert.Equal(t, "2026-03-01", rows[0].ApiVersion)
+	assert.Equal(t, "whsec_old", *rows[0].SecondarySecret)
+	assert.True(t, rows[0].RotatedAt.Valid)
+	assert.Equal(t, arg.ClaimedUntil, rows[0].ClaimedUntil)
+}
+
+func TestClaimWebhookDeliveriesSQL(t *testing.T) {
+	assert.Contains(t, claimWebhookDeliveries, "d.status = 'PENDING' AND d.next_attempt_at <= now()")
+	assert.Contains(t, claimWebhookDeliveries, "e.is_active", "deactivated endpoints are not called")
+	assert.Contains(t, claimWebhookDeliveries, "FOR UPDATE OF d SKIP LOCKED", "concurrent workers skip each other's rows")
+	assert.Contains(t, claimWebhookDeliveries, "SET next_attempt_at = $2", "claimed rows are not due again until the claim lapses")
+	assert.Contains(t, claimWebhookDeliveries, "ORDER BY c.due_at")
+	assert.Contains(t, claimWebhookDeliveries, "d.next_attempt_at AS claimed_until", "each row carries the claim it was taken under")
+}
+
+func TestQueries_MarkWebhookDelivered(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	code, body, latency := int32(204), "ok", int32(87)
+	params := MarkWebhookDeliveredParams{
+		ID:                  uuid.New(),
+		LastStatusCode:      &code,
+		LastResponseBody:    &body,
+		LastResponseHeaders: []byte(`{"Content-Type":"text/plain"}`),
+		LastLatencyMs:       &latency,
+		AttemptHistory:      []byte(`[{"attempt":1}]`),
+		ClaimedUntil:        pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true},
+	}
+	mockDB.On("Exec", ctx, markWebhookDelivered, []interface{}{
+		params.ID, params.LastStatusCode, params.LastResponseBody,
+		params.LastResponseHeaders, params.LastLatencyMs, params.AttemptHistory, params.ClaimedUntil,
+	}).Return(nil, nil)
+
+	require.NoError(t, queries.MarkWebhookDelivered(ctx, params))
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_RescheduleWebhookDelivery(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	code, msg := int32(500), "endpoint returned 500"
+	params := RescheduleWebhookDeliveryParams{
+		ID:             uuid.New(),
+		NextAttemptAt:  pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true},
+		LastStatusCode: &code,
+		LastError:      &msg,
+	}
+	mockDB.On("Exec", ctx, rescheduleWebhookDelivery,
+		[]interface{}{params.ID, params.NextAttemptAt, params.LastStatusCode, params.LastError, params.LastResponseBody,
+			params.LastResponseHeaders, params.LastLatencyMs, params.AttemptHistory, params.ClaimedUntil}).Return(nil, nil)
+
+	require.NoError(t, queries.RescheduleWebhookDelivery(ctx, params))
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_FailWebhookDelivery(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	msg := "timeout"
+	params := FailWebhookDeliveryParams{ID: uuid.New(), LastError: &msg}
+	mockDB.On("Exec", ctx, failWebhookDelivery,
+		[]interface{}{params.ID, params.LastStatusCode, params.LastError, params.LastResponseBody,
+			params.LastResponseHeaders, params.LastLatencyMs, params.AttemptHistory, params.ClaimedUntil}).Return(nil, nil)
+
+	require.NoError(t, queries.FailWebhookDelivery(ctx, params))
+	mockDB.AssertExpectations(t)
+}
+
+func TestQueries_DeferWebhookDelivery(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	params := DeferWebhookDeliveryParams{
+		ID:            uuid.New(),
+		NextAttemptAt: pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true},
+	}
+	mockDB.On("Exec", ctx, deferWebhookDelivery, []interface{}{params.ID, params.NextAttemptAt, params.ClaimedUntil}).Return(nil, nil)
+
+	require.NoError(t, queries.DeferWebhookDelivery(ctx, params))
+	mockDB.AssertExpectations(t)
+}
+
+func TestDeferWebhookDeliverySQL(t *testing.T) {
+	assert.Contains(t, deferWebhookDelivery, "status = 'PENDING'", "settled deliveries are not touched")
+	assert.Contains(t, deferWebhookDelivery, "next_attempt_at = $3", "a lapsed claim does not move the delivery")
+	assert.NotContains(t, deferWebhookDelivery, "attempts", "deferring does not count an attempt")
+}
+
+func TestWebhookDeliveryUpdatesSQL(t *testing.T) {
+	for _, query := range []string{markWebhookDelivered, rescheduleWebhookDelivery, failWebhookDelivery} {
+		assert.Contains(t, query, "attempts = attempts + 1")
+		assert.Contains(t, query, "status = 'PENDING'", "settled deliveries are not touched")
+		assert.Contains(t, query, "last_response_body = $")
+		assert.Contains(t, query, "last_response_headers = $")
+		assert.Contains(t, query, "last_latency_ms = $")
+		assert.Contains(t, query, "attempt_history = $")
+		assert.Regexp(t, `AND next_attempt_at = \$\d+\n$`, query, "a worker whose claim lapsed does not overwrite the next one")
+	}
+}
+
+func TestQueries_GetWebhookDelivery(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	arg := GetWebhookDeliveryParams{ID: uuid.New(), ClientID: uuid.New()}
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, getWebhookDelivery, []interface{}{arg.ID, arg.ClientID}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 20)
+		*dest[0].(*uuid.UUID) = arg.ID
+		*dest[4].(*[]byte) = []byte(`{"type":"payment.confirmed"}`)
+		body := "bad gateway"
+		*dest[13].(**string) = &body
+		*dest[15].(*[]byte) = []byte(`{"Retry-After":"120"}`)
+		latency := int32(184)
+		*dest[16].(**int32) = &latency
+		*dest[17].(*[]byte) = []byte(`[{"attempt":1}]`)
+		*dest[18].(*string) = "https://shop.example/hooks"
+		*dest[19].(*bool) = true
+	})
+
+	d, err := queries.GetWebhookDelivery(ctx, arg)
+
+	require.NoError(t, err)
+	assert.Equal(t, arg.ID, d.ID)
+	assert.JSONEq(t, `{"type":"payment.confirmed"}`, string(d.Payload))
+	assert.Equal(t, "bad gateway", *d.LastResponseBody)
+	assert.JSONEq(t, `{"Retry-After":"120"}`, string(d.LastResponseHeaders))
+	assert.Equal(t, int32(184), *d.LastLatencyMs)
+	assert.JSONEq(t, `[{"attempt":1}]`, string(d.AttemptHistory))
+	assert.Equal(t, "https://shop.example/hooks", d.Url)
+	assert.True(t, d.IsActive)
+	assert.Contains(t, getWebhookDelivery, "WHERE d.id = $1 AND e.client_id = $2", "clients only see their own deliveries")
+}
+
+func TestQueries_ListWebhookDeliveries(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	status := "FAILED"
+	arg := ListWebhookDeliveriesParams{
+		ClientID:  uuid.New(),
+		PaymentID: UUIDPtr(uuid.New()),
+		Status:    &status,
+		Limit:     51,
+	}
+
+	mockRows := new(MockRows)
+	mockDB.On("Query", ctx, listWebhookDeliveries,
+		[]interface{}{arg.ClientID, arg.PaymentID, arg.Status, arg.BeforeID, arg.Limit}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 15)
+		*dest[4].(*string) = "FAILED"
+		*dest[5].(*int32) = 8
+		latency := int32(184)
+		*dest[13].(**int32) = &latency
+		*dest[14].(*string) = "https://shop.example/hooks"
+	})
+	mockRows.On("Close").Return()
+	mockRows.On("Err").Return(nil)
+
+	rows, err := queries.ListWebhookDeliveries(ctx, arg)
+
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "FAILED", rows[0].Status)
+	assert.Equal(t, int32(8), rows[0].Attempts)
+	assert.Equal(t, int32(184), *rows[0].LastLatencyMs)
+	assert.Equal(t, "https://shop.example/hooks", rows[0].Url)
+}
+
+func TestListWebhookDeliveriesSQL(t *testing.T) {
+	assert.Contains(t, listWebhookDeliveries, "WHERE e.client_id = $1")
+	assert.Contains(t, listWebhookDeliveries, "($2::UUID IS NULL OR d.payment_id = $2)")
+	assert.Contains(t, listWebhookDeliveries, "($3::STRING IS NULL OR d.status = $3)")
+	assert.Contains(t, listWebhookDeliveries, "(d.created_at, d.id) < (")
+	assert.Contains(t, listWebhookDeliveries, "ORDER BY d.created_at DESC, d.id DESC")
+}
+
+func TestQueries_ReplayWebhookDelivery(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	requestID := "req-replay"
+	arg := ReplayWebhookDeliveryParams{RequestID: &requestID, ID: uuid.New(), ClientID: uuid.New()}
+	replayID := uuid.New()
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, replayWebhookDelivery, []interface{}{arg.RequestID, arg.ID, arg.ClientID}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 18)
+		*dest[0].(*uuid.UUID) = replayID
+		*dest[5].(*string) = "PENDING"
+		*dest[14].(**uuid.UUID) = UUIDPtr(arg.ID)
+	})
+
+	d, err := queries.ReplayWebhookDelivery(ctx, arg)
+
+	require.NoError(t, err)
+	assert.Equal(t, replayID, d.ID)
+	assert.Equal(t, "PENDING", d.Status)
+	assert.Equal(t, arg.ID, uuid.UUID(*d.ReplayOf))
+}
+
+func TestReplayWebhookDeliverySQL(t *testing.T) {
+	assert.Contains(t, replayWebhookDelivery,
+		"INSERT INTO webhook_deliveries (endpoint_id, payment_id, event_type, payload, request_id, replay_of)")
+	assert.Contains(t, replayWebhookDelivery, "SELECT d.endpoint_id, d.payment_id, d.event_type, d.payload, $1, d.id",
+		"the copy starts over: PENDING, no attempts, due now")
+	assert.Contains(t, replayWebhookDelivery, "WHERE d.id = $2 AND e.client_id = $3 AND e.is_active")
+}
+
+func TestQueries_RotateWebhookEndpointSecret(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	arg := RotateWebhookEndpointSecretParams{Secret: "whsec_new", ID: uuid.New(), ClientID: uuid.New()}
+	rotatedAt := time.Now()
+
+	mockRow := new(MockRow)
+	mockDB.On("QueryRow", ctx, rotateWebhookEndpointSecret, []interface{}{arg.Secret, arg.ID, arg.ClientID}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		require.Len(t, dest, 9)
+		*dest[0].(*uuid.UUID) = arg.ID
+		*dest[3].(*string) = arg.Secret
+		old := "whsec_old"
+		*dest[7].(**string) = &old
+		*dest[8].(*pgtype.Timestamptz) = pgtype.Timestamptz{Time: rotatedAt, Valid: true}
+	})
+
+	endpoint, err := queries.RotateWebhookEndpointSecret(ctx, arg)
+
+	require.NoError(t, err)
+	assert.Equal(t, "whsec_new", endpoint.Secret)
+	assert.Equal(t, "whsec_old", *endpoint.SecondarySecret)
+	assert.Equal(t, rotatedAt, endpoint.RotatedAt.Time)
+}
+
+func TestRotateWebhookEndpointSecretSQL(t *testing.T) {
+	assert.Contains(t, rotateWebhookEndpointSecret, "SET secondary_secret = secret, secret = $1, rotated_at = now()",
+		"the replaced secret is kept for the overlap window")
+	assert.Contains(t, rotateWebhookEndpointSecret, "WHERE id = $2 AND client_id = $3 AND is_active")
+}
+
+func TestQueries_ClearRotatedWebhookSecrets(t *testing.T) {
+	mockDB := new(MockDBTX)
+	queries := New(mockDB)
+	ctx := context.Background()
+	cutoff := pgtype.Timestamptz{Time: time.Now().Add(-24 * time.Hour), Valid: true}
+	mockDB.On("Exec", ctx, clearRotatedWebhookSecrets, []interface{}{cutoff}).
+		Return(pgconn.NewCommandTag("UPDATE 2"), nil)
+
+	n, err := queries.ClearRotatedWebhookSecrets(ctx, cutoff)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.Contains(t, clearRotatedWebhookSecrets, "WHERE secondary_secret IS NOT NULL AND rotated_at <= $1")
+	mockDB.AssertExpectations(t)
+}
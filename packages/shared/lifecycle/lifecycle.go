@@ -0,0 +1,15 @@
+// Package lifecycle holds the shutdown plumbing shared by the cmd/ binaries.
+package lifecycle
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// SignalContext returns a context that is cancelled on SIGINT or SIGTERM.
+// Call stop once shutdown has begun so a second signal kills the process.
+func SignalContext(parent context.Context) (ctx context.Context, stop context.CancelFunc) {
+	return signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+}
@@ -0,0 +1,23 @@
+package lifecycle
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignalContext_CancelledOnSIGTERM(t *testing.T) {
+	ctx, stop := SignalContext(context.Background())
+	defer stop()
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("context not cancelled by SIGTERM")
+	}
+}
@@ -0,0 +1,247 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultStopTimeout bounds a component's Stop unless AddWithTimeout says
+// otherwise.
+const DefaultStopTimeout = 10 * time.Second
+
+// Component is a part of a binary the Runner starts and stops. Start must not
+// block: long-running work belongs in a goroutine that Stop ends. A component
+// that fails after starting reports it with Fail on the context Start got.
+type Component interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+type component struct {
+	name    string
+	c       Component
+	timeout time.Duration
+}
+
+// Runner starts a binary's components in the order they were added and, on
+// SIGINT or SIGTERM, stops them in reverse order. Add the database first and
+// the servers last, so requests drain before the workers stop and the workers
+// stop before the pool closes.
+type Runner struct {
+	logger      *slog.Logger
+	stopTimeout time.Duration
+	components  []component
+}
+
+// RunnerOption configures a Runner.
+type RunnerOption func(*Runner)
+
+// WithLogger sets the logger for start and stop events.
+func WithLogger(logger *slog.Logger) RunnerOption {
+	return func(r *Runner) {
+		r.logger = logger
+	}
+}
+
+// WithStopTimeout sets the stop timeout of components added with Add.
+func WithStopTimeout(d time.Duration) RunnerOption {
+	return func(r *Runner) {
+		r.stopTimeout = d
+	}
+}
+
+// NewRunner returns a Runner without components.
+func NewRunner(opts ...RunnerOption) *Runner {
+	r := &Runner{
+		logger:      slog.Default(),
+		stopTimeout: DefaultStopTimeout,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Add registers c under name with the runner's stop timeout.
+func (r *Runner) Add(name string, c Component) {
+	r.AddWithTimeout(name, c, r.stopTimeout)
+}
+
+// AddWithTimeout registers c under name. Its Stop gets timeout to return.
+func (r *Runner) AddWithTimeout(name string, c Component, timeout time.Duration) {
+	r.components = append(r.components, component{name: name, c: c, timeout: timeout})
+}
+
+type failKey struct{}
+
+// Fail tells the Runner that started the component owning ctx that it has
+// failed, which shuts the binary down. Outside a Runner it does nothing.
+func Fail(ctx context.Context, err error) {
+	if fail, ok := ctx.Value(failKey{}).(func(error)); ok {
+		fail(err)
+	}
+}
+
+// Run starts every component and blocks until ctx is done, SIGINT or SIGTERM
+// arrives, or a component fails. It then stops the started components in
+// reverse order. A component that does not stop within its timeout is logged
+// and left behind, so it cannot hold up the ones after it.
+//
+// Run returns the error that failed a component, if any, joined with the
+// errors the components returned from Stop.
+func (r *Runner) Run(ctx context.Context) error {
+	ctx, stop := SignalContext(ctx)
+	defer stop()
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var failOnce sync.Once
+	var failure error
+	ctx = context.WithValue(ctx, failKey{}, func(err error) {
+		failOnce.Do(func() {
+			failure = err
+			cancel(err)
+		})
+	})
+
+	started := 0
+	for _, c := range r.components {
+		if err := c.c.Start(ctx); err != nil {
+			Fail(ctx, fmt.Errorf("failed to start %s: %w", c.name, err))
+			break
+		}
+		r.logger.Info("component started", "component", c.name)
+		started++
+	}
+
+	<-ctx.Done()
+	// A second signal kills the process outright.
+	stop()
+	failOnce.Do(func() {})
+	if failure != nil {
+		r.logger.ErrorContext(ctx, "shutting down after a failure", "error", failure)
+	} else {
+		r.logger.Info("shutting down")
+	}
+
+	errs := []error{failure}
+	for i := started - 1; i >= 0; i-- {
+		if err := r.stop(ctx, r.components[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stop stops c, giving up on it after its timeout.
+func (r *Runner) stop(ctx context.Context, c component) error {
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- c.c.Stop(stopCtx) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			r.logger.ErrorContext(ctx, "component failed to stop", "component", c.name, "error", err)
+			return fmt.Errorf("failed to stop %s: %w", c.name, err)
+		}
+		r.logger.Info("component stopped", "component", c.name, "took", time.Since(start))
+		return nil
+	case <-stopCtx.Done():
+		r.logger.ErrorContext(ctx, "component did not stop in time", "component", c.name, "timeout", c.timeout)
+		return fmt.Errorf("%s did not stop within %s", c.name, c.timeout)
+	}
+}
+
+// Loop adapts a function that runs until its context is done, such as a
+// worker's Run, to a Component. The function gets its own context, cancelled
+// by Stop rather than by the signal, so it keeps going until the components
+// added after it have stopped. Returning early, with or without an error,
+// fails the Runner.
+func Loop(run func(ctx context.Context) error) Component {
+	return &loop{run: run}
+}
+
+type loop struct {
+	run    func(ctx context.Context) error
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (l *loop) Start(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	l.cancel = cancel
+	l.done = make(chan struct{})
+	go func() {
+		defer close(l.done)
+		err := l.run(runCtx)
+		if runCtx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = errors.New("stopped unexpectedly")
+		}
+		Fail(ctx, err)
+	}()
+	return nil
+}
+
+func (l *loop) Stop(ctx context.Context) error {
+	l.cancel()
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HTTPServer adapts srv to a Component listening on addr. Start binds the
+// port, so a port in use fails startup, and Stop drains in-flight requests
+// with http.Server.Shutdown.
+func HTTPServer(srv *http.Server, addr string) Component {
+	return &httpServer{srv: srv, addr: addr}
+}
+
+type httpServer struct {
+	srv  *http.Server
+	addr string
+}
+
+func (s *httpServer) Start(ctx context.Context) error {
+	l, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.addr, err)
+	}
+	go func() {
+		if err := s.srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+			Fail(ctx, fmt.Errorf("http server failed: %w", err))
+		}
+	}()
+	return nil
+}
+
+func (s *httpServer) Stop(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+// OnStop adapts a cleanup function, such as closing the database pool, to a
+// Component that does nothing on Start.
+func OnStop(fn func(ctx context.Context) error) Component {
+	return onStop(fn)
+}
+
+type onStop func(ctx context.Context) error
+
+func (onStop) Start(context.Context) error { return nil }
+
+func (fn onStop) Stop(ctx context.Context) error { return fn(ctx) }
@@ -0,0 +1,292 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// events records what the fake components did, in order.
+type events struct {
+	mu  sync.Mutex
+	log []string
+}
+
+func (e *events) add(s string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.log = append(e.log, s)
+}
+
+func (e *events) list() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.log...)
+}
+
+// fake is a Component that records its calls and runs stop, if set, as its
+// Stop.
+type fake struct {
+	name     string
+	events   *events
+	startErr error
+	stop     func(ctx context.Context) error
+}
+
+func (f *fake) Start(context.Context) error {
+	f.events.add("start " + f.name)
+	return f.startErr
+}
+
+func (f *fake) Stop(ctx context.Context) error {
+	f.events.add("stop " + f.name)
+	if f.stop != nil {
+		return f.stop(ctx)
+	}
+	return nil
+}
+
+func newTestRunner() *Runner {
+	return NewRunner(WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))), WithStopTimeout(time.Second))
+}
+
+// runAsync runs r until the returned cancel is called, and returns Run's
+// result on the channel.
+func runAsync(r *Runner) (context.CancelFunc, <-chan error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Run(ctx) }()
+	return cancel, done
+}
+
+func wait(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return")
+		return nil
+	}
+}
+
+func TestRunner_StartsInOrderStopsInReverse(t *testing.T) {
+	ev := &events{}
+	r := newTestRunner()
+	for _, name := range []string{"database", "worker", "server"} {
+		r.Add(name, &fake{name: name, events: ev})
+	}
+
+	cancel, done := runAsync(r)
+	require.Eventually(t, func() bool { return len(ev.list()) == 3 }, time.Second, time.Millisecond)
+	cancel()
+
+	require.NoError(t, wait(t, done))
+	assert.Equal(t, []string{
+		"start database", "start worker", "start server",
+		"stop server", "stop worker", "stop database",
+	}, ev.list())
+}
+
+func TestRunner_StopsOnSIGTERM(t *testing.T) {
+	ev := &events{}
+	r := newTestRunner()
+	r.Add("worker", &fake{name: "worker", events: ev})
+
+	done := make(chan error, 1)
+	go func() { done <- r.Run(context.Background()) }()
+	require.Eventually(t, func() bool { return len(ev.list()) == 1 }, time.Second, time.Millisecond)
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+	require.NoError(t, wait(t, done))
+	assert.Equal(t, []string{"start worker", "stop worker"}, ev.list())
+}
+
+func TestRunner_HangingComponentDoesNotBlockOthers(t *testing.T) {
+	ev := &events{}
+	r := newTestRunner()
+	var dbStopped time.Time
+	r.Add("database", &fake{name: "database", events: ev, stop: func(context.Context) error {
+		dbStopped = time.Now()
+		return nil
+	}})
+	hang := make(chan struct{})
+	defer close(hang)
+	r.AddWithTimeout("stuck", &fake{name: "stuck", events: ev, stop: func(context.Context) error {
+		<-hang // ignores its context
+		return nil
+	}}, 50*time.Millisecond)
+	r.Add("server", &fake{name: "server", events: ev})
+
+	cancel, done := runAsync(r)
+	require.Eventually(t, func() bool { return len(ev.list()) == 3 }, time.Second, time.Millisecond)
+	stopping := time.Now()
+	cancel()
+
+	err := wait(t, done)
+	assert.EqualError(t, err, "stuck did not stop within 50ms")
+	assert.Equal(t, []string{"stop server", "stop stuck", "stop database"}, ev.list()[3:])
+	assert.Less(t, dbStopped.Sub(stopping), 500*time.Millisecond, "database waited only for the stuck component's deadline")
+}
+
+func TestRunner_StopGetsItsTimeout(t *testing.T) {
+	r := newTestRunner()
+	var deadline time.Time
+	r.AddWithTimeout("worker", &fake{name: "worker", events: &events{}, stop: func(ctx context.Context) error {
+		deadline, _ = ctx.Deadline()
+		return nil
+	}}, 3*time.Second)
+
+	cancel, done := runAsync(r)
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	require.NoError(t, wait(t, done))
+	assert.WithinDuration(t, time.Now().Add(3*time.Second), deadline, 500*time.Millisecond)
+}
+
+func TestRunner_StopErrorsReturned(t *testing.T) {
+	ev := &events{}
+	r := newTestRunner()
+	r.Add("database", &fake{name: "database", events: ev, stop: func(context.Context) error {
+		return errors.New("3 connections still acquired")
+	}})
+	r.Add("server", &fake{name: "server", events: ev})
+
+	cancel, done := runAsync(r)
+	require.Eventually(t, func() bool { return len(ev.list()) == 2 }, time.Second, time.Millisecond)
+	cancel()
+
+	assert.EqualError(t, wait(t, done), "failed to stop database: 3 connections still acquired")
+	assert.Equal(t, "stop database", ev.list()[3], "later components are still stopped")
+}
+
+func TestRunner_StartFailureStopsStarted(t *testing.T) {
+	ev := &events{}
+	r := newTestRunner()
+	r.Add("database", &fake{name: "database", events: ev})
+	r.Add("server", &fake{name: "server", events: ev, startErr: errors.New("address in use")})
+	r.Add("never", &fake{name: "never", events: ev})
+
+	err := r.Run(context.Background())
+
+	assert.EqualError(t, err, "failed to start server: address in use")
+	assert.Equal(t, []string{"start database", "start server", "stop database"}, ev.list())
+}
+
+func TestRunner_LoopFailureShutsDown(t *testing.T) {
+	ev := &events{}
+	r := newTestRunner()
+	r.Add("database", &fake{name: "database", events: ev})
+	r.Add("worker", Loop(func(context.Context) error { return errors.New("boom") }))
+
+	err := wait(t, func() <-chan error {
+		done := make(chan error, 1)
+		go func() { done <- r.Run(context.Background()) }()
+		return done
+	}())
+
+	assert.EqualError(t, err, "boom")
+	assert.Equal(t, []string{"start database", "stop database"}, ev.list())
+}
+
+func TestLoop_RunsUntilStopped(t *testing.T) {
+	ev := &events{}
+	r := newTestRunner()
+	r.Add("worker", Loop(func(ctx context.Context) error {
+		<-ctx.Done()
+		ev.add("worker returned")
+		return nil
+	}))
+	r.Add("server", &fake{name: "server", events: ev})
+
+	cancel, done := runAsync(r)
+	require.Eventually(t, func() bool { return len(ev.list()) == 1 }, time.Second, time.Millisecond)
+	cancel()
+
+	require.NoError(t, wait(t, done))
+	assert.Equal(t, []string{"start server", "stop server", "worker returned"}, ev.list(),
+		"the loop outlives the signal until its own Stop")
+}
+
+func TestHTTPServer_DrainsInFlightRequests(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	entered := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(entered)
+		time.Sleep(100 * time.Millisecond)
+		_, _ = fmt.Fprint(w, "done")
+	})}
+	r := newTestRunner()
+	r.Add("server", HTTPServer(srv, addr))
+
+	cancel, done := runAsync(r)
+	require.Eventually(t, func() bool {
+		c, err := net.Dial("tcp", addr)
+		if err == nil {
+			_ = c.Close()
+		}
+		return err == nil
+	}, time.Second, 5*time.Millisecond)
+
+	var body string
+	var reqErr error
+	got := make(chan struct{})
+	go func() {
+		defer close(got)
+		resp, err := http.Get("http://" + addr)
+		if reqErr = err; err != nil {
+			close(entered)
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body = string(b)
+	}()
+	<-entered
+	cancel()
+
+	require.NoError(t, wait(t, done))
+	<-got
+	require.NoError(t, reqErr)
+	assert.Equal(t, "done", body)
+}
+
+func TestHTTPServer_PortInUse(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	r := newTestRunner()
+	r.Add("server", HTTPServer(&http.Server{}, l.Addr().String()))
+
+	assert.ErrorContains(t, r.Run(context.Background()), "failed to start server: failed to listen on")
+}
+
+func TestOnStop(t *testing.T) {
+	called := false
+	c := OnStop(func(context.Context) error {
+		called = true
+		return nil
+	})
+
+	require.NoError(t, c.Start(context.Background()))
+	assert.False(t, called)
+	require.NoError(t, c.Stop(context.Background()))
+	assert.True(t, called)
+}
@@ -0,0 +1,270 @@
+// Package locking elects one leader among the replicas of a singleton
+// worker, such as the sweeper, through leases in the database.
+//
+// A lease is held for a TTL and renewed by a heartbeat every third of it. A
+// leader that cannot renew gives up once the TTL it last secured has run
+// out, which is no later than the database lets another replica take over.
+// Every new holder gets a higher fencing token, so work tagged with a token
+// can be told apart from a deposed leader's.
+package locking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// ErrNotLeader is returned by AcquireLeader while another replica holds the
+// lease.
+var ErrNotLeader = errors.New("lease is held by another replica")
+
+// ErrLeaseLost is the cause of a lease ending because another replica took
+// it over.
+var ErrLeaseLost = errors.New("lease was taken over")
+
+// ErrLeaseExpired is the cause of a lease ending because it could not be
+// renewed before its TTL ran out. It wraps the last renewal error, if any.
+var ErrLeaseExpired = errors.New("lease expired before it could be renewed")
+
+// releaseTimeout bounds how long RunAsLeader waits to hand a lease back.
+const releaseTimeout = 5 * time.Second
+
+// Store is the subset of repository.Querier leases are kept through.
+type Store interface {
+	AcquireLease(ctx context.Context, arg repository.AcquireLeaseParams) (repository.Lease, error)
+	RenewLease(ctx context.Context, arg repository.RenewLeaseParams) (repository.Lease, error)
+	ReleaseLease(ctx context.Context, arg repository.ReleaseLeaseParams) error
+}
+
+// Locker acquires leases on behalf of one replica.
+type Locker struct {
+	store  Store
+	holder string
+	logger *slog.Logger
+}
+
+// Option customises a Locker.
+type Option func(*Locker)
+
+// WithHolder names the replica in the leases it holds, e.g. a pod name. By
+// default it is the hostname followed by a random suffix.
+func WithHolder(holder string) Option {
+	return func(l *Locker) { l.holder = holder }
+}
+
+// WithLogger replaces slog.Default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(l *Locker) { l.logger = logger }
+}
+
+// New returns a Locker keeping its leases in store.
+func New(store Store, opts ...Option) *Locker {
+	l := &Locker{store: store, logger: slog.Default()}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.holder == "" {
+		host, _ := os.Hostname()
+		l.holder = host + "-" + uuid.NewString()[:8]
+	}
+	return l
+}
+
+// AcquireLeader takes the lease called name for ttl, provided it is free or
+// has lapsed, and keeps renewing it until it is released or lost. It returns
+// ErrNotLeader while another replica holds it.
+func (l *Locker) AcquireLeader(ctx context.Context, name string, ttl time.Duration) (*Lease, error) {
+	start := time.Now()
+	row, err := l.store.AcquireLease(ctx, repository.AcquireLeaseParams{
+		Name:   name,
+		Holder: l.holder,
+		Ttl:    interval(ttl),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotLeader
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lease %s: %w", name, err)
+	}
+	lease := &Lease{
+		locker: l,
+		name:   name,
+		token:  row.Token,
+		ttl:    ttl,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go lease.heartbeat(start.Add(ttl))
+	return lease, nil
+}
+
+// RunAsLeader campaigns for the lease called name and runs fn while this
+// replica holds it. fn's context is cancelled when leadership is lost, after
+// which the replica campaigns again. It returns nil once ctx is done, or
+// whatever fn returns while still the leader.
+func (l *Locker) RunAsLeader(ctx context.Context, name string, ttl time.Duration, fn func(context.Context) error) error {
+	retry := time.NewTicker(ttl / 3)
+	defer retry.Stop()
+
+	for {
+		lease, err := l.AcquireLeader(ctx, name, ttl)
+		switch {
+		case err == nil:
+			lost, err := l.lead(ctx, lease, fn)
+			if !lost && ctx.Err() == nil {
+				return err
+			}
+		case errors.Is(err, ErrNotLeader):
+		case ctx.Err() == nil:
+			l.logger.ErrorContext(ctx, "leader election failed", "lease", name, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-retry.C:
+		}
+	}
+}
+
+// lead runs fn under lease and releases it afterwards. It reports whether fn
+// was stopped because the lease was lost.
+func (l *Locker) lead(ctx context.Context, lease *Lease, fn func(context.Context) error) (bool, error) {
+	l.logger.Info("leadership acquired", "lease", lease.name, "holder", l.holder, "token", lease.token)
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-lease.Done():
+			cancel()
+		case <-runCtx.Done():
+		}
+	}()
+
+	err := fn(runCtx)
+	if lostErr := lease.Err(); lostErr != nil {
+		l.logger.Warn("leadership lost", "lease", lease.name, "token", lease.token, "error", lostErr)
+		return true, err
+	}
+	releaseCtx, cancelRelease := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+	defer cancelRelease()
+	if err := lease.Release(releaseCtx); err != nil {
+		l.logger.Warn("failed to release lease", "lease", lease.name, "error", err)
+	}
+	return false, err
+}
+
+// Lease is leadership under a name, held until Release or until it is lost.
+type Lease struct {
+	locker *Locker
+	name   string
+	token  int64
+	ttl    time.Duration
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// Token is the fencing token of this tenure. It is higher than that of any
+// earlier holder of the lease.
+func (l *Lease) Token() int64 {
+	return l.token
+}
+
+// Done is closed when the lease ends, by Release or because it was lost.
+func (l *Lease) Done() <-chan struct{} {
+	return l.done
+}
+
+// Err returns why the lease was lost once Done is closed, and nil while it
+// is held or after Release.
+func (l *Lease) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// Release stops renewing the lease and hands it back, so another replica
+// can take over without waiting out the TTL.
+func (l *Lease) Release(ctx context.Context) error {
+	l.stopOnce.Do(func() { close(l.stop) })
+	<-l.done
+	if l.Err() != nil {
+		return nil
+	}
+	err := l.locker.store.ReleaseLease(ctx, repository.ReleaseLeaseParams{Name: l.name, Token: l.token})
+	if err != nil {
+		return fmt.Errorf("failed to release lease %s: %w", l.name, err)
+	}
+	return nil
+}
+
+// heartbeat renews the lease every third of its TTL until it is released.
+// The lease is lost when a renewal finds it taken over, or when renewals
+// keep failing until deadline, the end of the TTL last secured.
+func (l *Lease) heartbeat(deadline time.Time) {
+	defer close(l.done)
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	expiry := time.NewTimer(time.Until(deadline))
+	defer expiry.Stop()
+
+	var lastErr error
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-expiry.C:
+			if lastErr != nil {
+				l.lose(fmt.Errorf("%w: %w", ErrLeaseExpired, lastErr))
+			} else {
+				l.lose(ErrLeaseExpired)
+			}
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
+		_, err := l.locker.store.RenewLease(ctx, repository.RenewLeaseParams{
+			Ttl:   interval(l.ttl),
+			Name:  l.name,
+			Token: l.token,
+		})
+		cancel()
+		switch {
+		case err == nil:
+			deadline = start.Add(l.ttl)
+			expiry.Reset(time.Until(deadline))
+			lastErr = nil
+		case errors.Is(err, pgx.ErrNoRows):
+			l.lose(ErrLeaseLost)
+			return
+		default:
+			l.locker.logger.Warn("failed to renew lease", "lease", l.name, "token", l.token, "error", err)
+			lastErr = err
+		}
+	}
+}
+
+func (l *Lease) lose(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.err = err
+}
+
+func interval(d time.Duration) pgtype.Interval {
+	return pgtype.Interval{Microseconds: d.Microseconds(), Valid: true}
+}
@@ -0,0 +1,307 @@
+package locking
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+const testTTL = 60 * time.Millisecond
+
+// memStore keeps leases in memory with the semantics of the lease queries.
+type memStore struct {
+	mu       sync.Mutex
+	leases   map[string]repository.Lease
+	until    map[string]time.Time
+	renewErr error
+	renews   int
+}
+
+func newMemStore() *memStore {
+	return &memStore{leases: map[string]repository.Lease{}, until: map[string]time.Time{}}
+}
+
+func (s *memStore) AcquireLease(_ context.Context, arg repository.AcquireLeaseParams) (repository.Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	lease, ok := s.leases[arg.Name]
+	if ok && now.Before(s.until[arg.Name]) {
+		return repository.Lease{}, pgx.ErrNoRows
+	}
+	lease.Name = arg.Name
+	lease.Holder = arg.Holder
+	lease.Token++
+	s.leases[arg.Name] = lease
+	s.until[arg.Name] = now.Add(time.Duration(arg.Ttl.Microseconds) * time.Microsecond)
+	return lease, nil
+}
+
+func (s *memStore) RenewLease(_ context.Context, arg repository.RenewLeaseParams) (repository.Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.renews++
+	if s.renewErr != nil {
+		return repository.Lease{}, s.renewErr
+	}
+	now := time.Now()
+	lease, ok := s.leases[arg.Name]
+	if !ok || lease.Token != arg.Token || !now.Before(s.until[arg.Name]) {
+		return repository.Lease{}, pgx.ErrNoRows
+	}
+	s.until[arg.Name] = now.Add(time.Duration(arg.Ttl.Microseconds) * time.Microsecond)
+	return lease, nil
+}
+
+func (s *memStore) ReleaseLease(_ context.Context, arg repository.ReleaseLeaseParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if lease, ok := s.leases[arg.Name]; ok && lease.Token == arg.Token {
+		s.until[arg.Name] = time.Now()
+	}
+	return nil
+}
+
+func (s *memStore) setRenewErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.renewErr = err
+}
+
+func (s *memStore) renewCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.renews
+}
+
+// steal hands the lease to another holder as if it had lapsed.
+func (s *memStore) steal(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lease := s.leases[name]
+	lease.Holder = "thief"
+	lease.Token++
+	s.leases[name] = lease
+	s.until[name] = time.Now().Add(time.Hour)
+}
+
+func isDone(lease *Lease) bool {
+	select {
+	case <-lease.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+func TestAcquireLeader_CompetingAcquirers(t *testing.T) {
+	store := newMemStore()
+	a := New(store, WithHolder("a"))
+	b := New(store, WithHolder("b"))
+
+	lease, err := a.AcquireLeader(context.Background(), "sweeper", testTTL)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), lease.Token())
+
+	_, err = b.AcquireLeader(context.Background(), "sweeper", testTTL)
+	assert.ErrorIs(t, err, ErrNotLeader)
+
+	other, err := b.AcquireLeader(context.Background(), "relay", testTTL)
+	require.NoError(t, err, "leases are independent by name")
+	require.NoError(t, other.Release(context.Background()))
+
+	require.NoError(t, lease.Release(context.Background()))
+	assert.True(t, isDone(lease))
+	assert.NoError(t, lease.Err())
+
+	next, err := b.AcquireLeader(context.Background(), "sweeper", testTTL)
+	require.NoError(t, err, "a released lease is free at once")
+	assert.Equal(t, int64(2), next.Token())
+	require.NoError(t, next.Release(context.Background()))
+}
+
+func TestAcquireLeader_StoreError(t *testing.T) {
+	store := &failingStore{err: errors.New("connection refused")}
+
+	_, err := New(store).AcquireLeader(context.Background(), "sweeper", testTTL)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotLeader)
+	assert.ErrorIs(t, err, store.err)
+}
+
+func TestLease_RenewalKeepsLeadership(t *testing.T) {
+	store := newMemStore()
+	lease, err := New(store).AcquireLeader(context.Background(), "sweeper", testTTL)
+	require.NoError(t, err)
+
+	time.Sleep(4 * testTTL)
+	assert.False(t, isDone(lease), "lease must outlive its TTL while renewed")
+	assert.GreaterOrEqual(t, store.renewCount(), 3)
+
+	_, err = New(store).AcquireLeader(context.Background(), "sweeper", testTTL)
+	assert.ErrorIs(t, err, ErrNotLeader)
+	require.NoError(t, lease.Release(context.Background()))
+}
+
+func TestLease_ExpiresWhenRenewalFails(t *testing.T) {
+	store := newMemStore()
+	lease, err := New(store).AcquireLeader(context.Background(), "sweeper", testTTL)
+	require.NoError(t, err)
+
+	renewErr := errors.New("connection reset")
+	store.setRenewErr(renewErr)
+
+	select {
+	case <-lease.Done():
+	case <-time.After(time.Second):
+		t.Fatal("lease was not given up after renewals failed")
+	}
+	assert.ErrorIs(t, lease.Err(), ErrLeaseExpired)
+	assert.ErrorIs(t, lease.Err(), renewErr)
+
+	// The database lets another replica in once the TTL has run out, with a
+	// token that fences off the old leader.
+	store.setRenewErr(nil)
+	next, err := New(store).AcquireLeader(context.Background(), "sweeper", testTTL)
+	require.NoError(t, err)
+	assert.Greater(t, next.Token(), lease.Token())
+	require.NoError(t, next.Release(context.Background()))
+
+	assert.NoError(t, lease.Release(context.Background()), "releasing a lost lease is a no-op")
+}
+
+func TestLease_LostWhenTakenOver(t *testing.T) {
+	store := newMemStore()
+	lease, err := New(store).AcquireLeader(context.Background(), "sweeper", testTTL)
+	require.NoError(t, err)
+
+	store.steal("sweeper")
+
+	select {
+	case <-lease.Done():
+	case <-time.After(time.Second):
+		t.Fatal("lease was not given up after it was taken over")
+	}
+	assert.ErrorIs(t, lease.Err(), ErrLeaseLost)
+}
+
+func TestRunAsLeader_OneReplicaAtATime(t *testing.T) {
+	store := newMemStore()
+	var running, maxRunning atomic.Int32
+	var ran [2]atomic.Bool
+	work := func(i int) func(context.Context) error {
+		return func(ctx context.Context) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				if cur := maxRunning.Load(); n <= cur || maxRunning.CompareAndSwap(cur, n) {
+					break
+				}
+			}
+			ran[i].Store(true)
+			<-ctx.Done()
+			return ctx.Err()
+		}
+	}
+
+	ctxs := make([]context.Context, 2)
+	cancels := make([]context.CancelFunc, 2)
+	results := make([]chan error, 2)
+	for i := range 2 {
+		ctxs[i], cancels[i] = context.WithCancel(context.Background())
+		defer cancels[i]()
+		results[i] = make(chan error, 1)
+		locker := New(store, WithHolder([]string{"a", "b"}[i]))
+		go func() { results[i] <- locker.RunAsLeader(ctxs[i], "sweeper", testTTL, work(i)) }()
+	}
+
+	require.Eventually(t, func() bool { return ran[0].Load() || ran[1].Load() }, time.Second, 5*time.Millisecond)
+	leader := 0
+	if ran[1].Load() {
+		leader = 1
+	}
+	follower := 1 - leader
+
+	time.Sleep(3 * testTTL)
+	assert.False(t, ran[follower].Load(), "follower must not run while the leader renews")
+
+	cancels[leader]()
+	require.NoError(t, <-results[leader])
+	require.Eventually(t, ran[follower].Load, time.Second, 5*time.Millisecond, "follower must take over")
+	assert.Equal(t, int32(1), maxRunning.Load())
+
+	cancels[follower]()
+	require.NoError(t, <-results[follower])
+}
+
+func TestRunAsLeader_StopsWorkWhenLeadershipIsLost(t *testing.T) {
+	store := newMemStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var runs atomic.Int32
+	stopped := make(chan error, 1)
+	result := make(chan error, 1)
+	go func() {
+		result <- New(store).RunAsLeader(ctx, "sweeper", testTTL, func(ctx context.Context) error {
+			if runs.Add(1) == 1 {
+				store.setRenewErr(errors.New("connection reset"))
+				<-ctx.Done()
+				stopped <- ctx.Err()
+				store.setRenewErr(nil)
+				return ctx.Err()
+			}
+			<-ctx.Done()
+			return nil
+		})
+	}()
+
+	select {
+	case err := <-stopped:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("work was not stopped after leadership was lost")
+	}
+	require.Eventually(t, func() bool { return runs.Load() == 2 }, time.Second, 5*time.Millisecond, "replica must campaign again")
+
+	cancel()
+	require.NoError(t, <-result)
+}
+
+func TestRunAsLeader_ReturnsWorkError(t *testing.T) {
+	store := newMemStore()
+	boom := errors.New("boom")
+
+	err := New(store).RunAsLeader(context.Background(), "sweeper", testTTL, func(context.Context) error {
+		return boom
+	})
+	assert.ErrorIs(t, err, boom)
+
+	lease, err := New(store).AcquireLeader(context.Background(), "sweeper", testTTL)
+	require.NoError(t, err, "lease must be released when the work fails")
+	require.NoError(t, lease.Release(context.Background()))
+}
+
+type failingStore struct {
+	err error
+}
+
+func (s *failingStore) AcquireLease(context.Context, repository.AcquireLeaseParams) (repository.Lease, error) {
+	return repository.Lease{}, s.err
+}
+
+func (s *failingStore) RenewLease(context.Context, repository.RenewLeaseParams) (repository.Lease, error) {
+	return repository.Lease{}, s.err
+}
+
+func (s *failingStore) ReleaseLease(context.Context, repository.ReleaseLeaseParams) error {
+	return s.err
+}
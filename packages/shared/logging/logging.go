@@ -0,0 +1,157 @@
+// Package logging builds the logger every binary installs at startup: level
+// and format from config, correlating IDs from the context and secrets
+// redacted from every record.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/requestid"
+)
+
+// RedactedValue replaces the value of a secret attribute.
+const RedactedValue = "[REDACTED]"
+
+// New returns a logger writing records of at least cfg.Level to w in
+// cfg.Format. Records logged with a context get the request, payment and
+// client IDs it carries, and secret attributes are redacted.
+func New(w io.Writer, cfg config.LoggingConfig) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level:       level(cfg.Level),
+		AddSource:   cfg.AddSource,
+		ReplaceAttr: Redact,
+	}
+	var h slog.Handler
+	if cfg.Format == config.LogFormatText {
+		h = slog.NewTextHandler(w, opts)
+	} else {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(NewHandler(h))
+}
+
+// Setup builds the logger for cfg writing to stderr, installs it as
+// slog.Default and returns it. Components built afterwards log through it.
+func Setup(cfg config.LoggingConfig) *slog.Logger {
+	logger := New(os.Stderr, cfg)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// FollowReloads reinstalls the default logger whenever w reloads a changed
+// logging section, so the level and format apply without a restart.
+func FollowReloads(w *config.Watcher) {
+	w.OnChange(config.SectionLogging, func(_, updated *config.Config) {
+		Setup(updated.Logging)
+	})
+	// Debug only feeds the default log level, reloaded with logging above.
+	w.OnChange(config.SectionDebug, func(_, _ *config.Config) {})
+}
+
+func level(name string) slog.Level {
+	switch name {
+	case config.LogLevelDebug:
+		return slog.LevelDebug
+	case config.LogLevelWarn:
+		return slog.LevelWarn
+	case config.LogLevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+type paymentIDKey struct{}
+
+type clientIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return requestid.NewContext(ctx, id)
+}
+
+// WithPaymentID returns a copy of ctx carrying the ID of the payment the
+// work is about.
+func WithPaymentID(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, paymentIDKey{}, id)
+}
+
+// WithClientID returns a copy of ctx carrying the ID of the client the work
+// is for.
+func WithClientID(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, clientIDKey{}, id)
+}
+
+// NewHandler wraps h so that records logged with a context get request_id,
+// payment_id and client_id attributes for the IDs it carries. A handler
+// already wrapped, e.g. that of the default logger, is returned as is.
+func NewHandler(h slog.Handler) slog.Handler {
+	if _, ok := h.(contextHandler); ok {
+		return h
+	}
+	return contextHandler{requestid.NewLogHandler(h)}
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id, ok := ctx.Value(paymentIDKey{}).(uuid.UUID); ok {
+		r.AddAttrs(slog.String("payment_id", id.String()))
+	}
+	if id, ok := ctx.Value(clientIDKey{}).(uuid.UUID); ok {
+		r.AddAttrs(slog.String("client_id", id.String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// secretKeys are the normalized attribute keys, or key suffixes, whose
+// values are never logged.
+var secretKeys = []string{"apikey", "mnemonic", "signature", "secret", "password", "privatekey", "authorization"}
+
+// Redact is a slog.HandlerOptions.ReplaceAttr hook replacing the value of
+// attributes named like a secret, e.g. api_key, X-Api-Key, webhook_secret,
+// mnemonic or signature, with RedactedValue. Keys ending in token are
+// secrets too, except token itself, which names the currency of a payment.
+func Redact(_ []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() == slog.KindGroup {
+		return a
+	}
+	if isSecret(a.Key) {
+		a.Value = slog.StringValue(RedactedValue)
+	}
+	return a
+}
+
+func isSecret(key string) bool {
+	k := strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || r == '.' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(key))
+	if k != "token" && strings.HasSuffix(k, "token") {
+		return true
+	}
+	for _, s := range secretKeys {
+		if strings.HasSuffix(k, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+// decode returns the JSON records written to buf.
+func decode(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var r map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &r))
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestNew_Level(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, config.LoggingConfig{Level: config.LogLevelWarn, Format: config.LogFormatJSON})
+
+	logger.Info("dropped")
+	logger.Warn("kept")
+
+	records := decode(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "kept", records[0]["msg"])
+	assert.NotContains(t, records[0], "source")
+}
+
+func TestNew_TextWithSource(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, config.LoggingConfig{Level: config.LogLevelDebug, Format: config.LogFormatText, AddSource: true})
+
+	logger.Debug("hello", "api_key", "sk_live_123")
+
+	line := buf.String()
+	assert.Contains(t, line, "msg=hello")
+	assert.Contains(t, line, "source=")
+	assert.Contains(t, line, "logging_test.go")
+	assert.Contains(t, line, "api_key="+RedactedValue)
+	assert.NotContains(t, line, "sk_live_123")
+}
+
+func TestNew_ContextFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, config.LoggingConfig{}).With("component", "watcher")
+	paymentID, clientID := uuid.New(), uuid.New()
+	ctx := WithClientID(WithPaymentID(WithRequestID(context.Background(), "req-1"), paymentID), clientID)
+
+	logger.ErrorContext(ctx, "with ids")
+	logger.ErrorContext(context.Background(), "without ids")
+
+	records := decode(t, &buf)
+	require.Len(t, records, 2)
+	assert.Equal(t, "req-1", records[0]["request_id"])
+	assert.Equal(t, paymentID.String(), records[0]["payment_id"])
+	assert.Equal(t, clientID.String(), records[0]["client_id"])
+	assert.Equal(t, "watcher", records[0]["component"], "attrs survive With")
+	for _, key := range []string{"request_id", "payment_id", "client_id"} {
+		assert.NotContains(t, records[1], key)
+	}
+}
+
+func TestNewHandler_WrapsOnce(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, config.LoggingConfig{})
+	ctx := WithPaymentID(WithRequestID(context.Background(), "req-1"), uuid.New())
+
+	slog.New(NewHandler(logger.With("component", "api").Handler())).InfoContext(ctx, "wrapped twice")
+
+	assert.Equal(t, 1, strings.Count(buf.String(), `"request_id"`))
+	assert.Equal(t, 1, strings.Count(buf.String(), `"payment_id"`))
+}
+
+func TestRedact(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, config.LoggingConfig{})
+
+	logger.Info("secrets",
+		"api_key", "sk_live_123",
+		"X-Api-Key", "sk_live_456",
+		"mnemonic", "abandon abandon about",
+		"signature", "t=1,v1=abc",
+		"webhook_secret", "whsec_1",
+		"Authorization", "Bearer sk_live_789",
+		"status_token", "st_1",
+		slog.Group("endpoint", "secret", "whsec_2", "url", "https://shop.example/hook"),
+		"token", "USDT",
+		"payment_id", "p-1",
+	)
+
+	records := decode(t, &buf)
+	require.Len(t, records, 1)
+	r := records[0]
+	for _, key := range []string{"api_key", "X-Api-Key", "mnemonic", "signature", "webhook_secret", "Authorization", "status_token"} {
+		assert.Equal(t, RedactedValue, r[key], key)
+	}
+	assert.Equal(t, map[string]any{"secret": RedactedValue, "url": "https://shop.example/hook"}, r["endpoint"], "grouped secrets are redacted")
+	assert.Equal(t, "USDT", r["token"], "the currency of a payment is not a secret")
+	assert.Equal(t, "p-1", r["payment_id"])
+	for _, secret := range []string{"sk_live", "abandon", "v1=abc", "whsec", "st_1"} {
+		assert.NotContains(t, buf.String(), secret)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	logger := Setup(config.LoggingConfig{Level: config.LogLevelError})
+
+	assert.Same(t, logger, slog.Default())
+	assert.False(t, logger.Enabled(context.Background(), slog.LevelWarn))
+}
+
+func TestFollowReloads(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	base := "appPort: 8080\ndatabase:\n  user: root\n  host: localhost\n  database: tpg\n  maxConnections: 10\n"
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(base), 0644))
+	var cfg config.Config
+	require.NoError(t, cfg.LoadConfig(path))
+	Setup(cfg.Logging)
+	w := config.NewWatcher(path, &cfg)
+	FollowReloads(w)
+	require.False(t, slog.Default().Enabled(context.Background(), slog.LevelDebug))
+
+	require.NoError(t, os.WriteFile(path, []byte("debug: true\n"+base), 0644))
+	_, err := w.Reload()
+
+	require.NoError(t, err)
+	assert.True(t, slog.Default().Enabled(context.Background(), slog.LevelDebug))
+}
@@ -0,0 +1,384 @@
+// Package metrics exports the gateway's Prometheus metrics. The packages it
+// measures each declare the small interface they report through, which
+// *Metrics implements, so their tests can record into a fake instead.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/db"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+)
+
+// namespace prefixes every metric name.
+const namespace = "tpg"
+
+// PoolStatsInterval is how often ReportPoolStats samples the pool.
+const PoolStatsInterval = 15 * time.Second
+
+// TronUsageInterval is how often ReportTronUsage samples the client.
+const TronUsageInterval = 15 * time.Second
+
+const (
+	readHeaderTimeout = 5 * time.Second
+	shutdownTimeout   = 5 * time.Second
+)
+
+// Metrics holds the gateway's collectors.
+type Metrics struct {
+	httpRequests       *prometheus.HistogramVec
+	grpcRequests       *prometheus.HistogramVec
+	paymentTransitions *prometheus.CounterVec
+	chainLag           *prometheus.GaugeVec
+	webhookDeliveries  *prometheus.CounterVec
+	webhookAttempts    *prometheus.HistogramVec
+	webhookInFlight    prometheus.Gauge
+	webhookQueueDepth  prometheus.Gauge
+	webhookBreaker     *prometheus.GaugeVec
+	tronRequests       *prometheus.CounterVec
+	tronUsage          *prometheus.GaugeVec
+	tronToday          prometheus.Gauge
+	tronBudget         prometheus.Gauge
+	reconcileMismatch  *prometheus.GaugeVec
+	logsPruned         *prometheus.CounterVec
+	eventsDropped      *prometheus.CounterVec
+	dbQueries          *prometheus.HistogramVec
+
+	dbTotalConns      prometheus.Gauge
+	dbIdleConns       prometheus.Gauge
+	dbAcquiredConns   prometheus.Gauge
+	dbMaxConns        prometheus.Gauge
+	dbAcquireCount    prometheus.Gauge
+	dbAcquireDuration prometheus.Gauge
+}
+
+// NewRegistry returns a registry holding the Go runtime and process
+// collectors, for New.
+func NewRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return reg
+}
+
+// New registers the gateway's collectors with reg. It panics if they are
+// already registered there.
+func New(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		httpRequests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "API request latency by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		grpcRequests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "request_duration_seconds",
+			Help:      "Internal gRPC call latency by method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		paymentTransitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "payment_transitions_total",
+			Help:      "Payments moved to a status, by the status they moved to.",
+		}, []string{"status"}),
+		chainLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "watcher",
+			Name:      "chain_lag_blocks",
+			Help:      "Blocks between the chain head and the last block the watcher processed.",
+		}, []string{"watcher"}),
+		webhookDeliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "webhook",
+			Name:      "deliveries_total",
+			Help:      "Webhook delivery attempts by result: delivered, retried or failed for good.",
+		}, []string{"result"}),
+		webhookAttempts: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "webhook",
+			Name:      "delivery_attempts",
+			Help:      "The attempt number of each webhook delivery attempt, by result.",
+			Buckets:   []float64{1, 2, 3, 4, 5, 6, 8, 10},
+		}, []string{"result"}),
+		webhookInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "webhook",
+			Name:      "in_flight",
+			Help:      "Webhook deliveries being sent.",
+		}),
+		webhookQueueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "webhook",
+			Name:      "queue_depth",
+			Help:      "Due webhook deliveries claimed by the worker and waiting to be sent.",
+		}),
+		webhookBreaker: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "webhook",
+			Name:      "breaker_state",
+			Help:      "The circuit breaker of each failing endpoint host: 0 closed, 1 half open, 2 open.",
+		}, []string{"host"}),
+		tronRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "tron",
+			Name:      "requests_total",
+			Help:      "TRON node requests by API path and HTTP status, or \"error\" when no response came back.",
+		}, []string{"endpoint", "status"}),
+		tronUsage: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "tron",
+			Name:      "window_requests",
+			Help:      "TRON node requests sent in the current minute or hour, by API path and window.",
+		}, []string{"endpoint", "window"}),
+		tronToday: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "tron",
+			Name:      "requests_today",
+			Help:      "TRON node requests sent since UTC midnight, which count against the daily budget.",
+		}),
+		tronBudget: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "tron",
+			Name:      "daily_budget",
+			Help:      "The daily TRON request budget, 0 when there is none.",
+		}),
+		reconcileMismatch: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "reconciler",
+			Name:      "mismatches",
+			Help:      "Mismatches between the database and the chain found by the last reconciliation, by kind.",
+		}, []string{"kind"}),
+		logsPruned: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "retention",
+			Name:      "logs_pruned_total",
+			Help:      "Logs deleted for outliving their retention period, by event type.",
+		}, []string{"event_type"}),
+		eventsDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "events",
+			Name:      "dropped_total",
+			Help:      "In-process payment events dropped for subscribers that fell behind, by event type.",
+		}, []string{"event_type"}),
+		dbQueries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "Database query latency by repository method and result: success or error.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"method", "result"}),
+		dbTotalConns:      dbGauge("total_conns", "Connections open in the pool."),
+		dbIdleConns:       dbGauge("idle_conns", "Idle connections in the pool."),
+		dbAcquiredConns:   dbGauge("acquired_conns", "Connections in use."),
+		dbMaxConns:        dbGauge("max_conns", "The pool's connection limit."),
+		dbAcquireCount:    dbGauge("acquire_count", "Connections acquired since the pool was opened."),
+		dbAcquireDuration: dbGauge("acquire_duration_seconds", "Time spent waiting to acquire connections since the pool was opened."),
+	}
+	reg.MustRegister(
+		m.httpRequests,
+		m.grpcRequests,
+		m.paymentTransitions,
+		m.chainLag,
+		m.webhookDeliveries,
+		m.webhookAttempts,
+		m.webhookInFlight,
+		m.webhookQueueDepth,
+		m.webhookBreaker,
+		m.tronRequests,
+		m.tronUsage,
+		m.tronToday,
+		m.tronBudget,
+		m.reconcileMismatch,
+		m.logsPruned,
+		m.eventsDropped,
+		m.dbQueries,
+		m.dbTotalConns,
+		m.dbIdleConns,
+		m.dbAcquiredConns,
+		m.dbMaxConns,
+		m.dbAcquireCount,
+		m.dbAcquireDuration,
+	)
+	return m
+}
+
+func dbGauge(name, help string) prometheus.Gauge {
+	return prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "db_pool",
+		Name:      name,
+		Help:      help,
+	})
+}
+
+// Handler serves the metrics gathered by g at /metrics.
+func Handler(g prometheus.Gatherer) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.HandlerFor(g, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// Serve serves the metrics gathered by g at /metrics on l until ctx is done.
+func Serve(ctx context.Context, l net.Listener, g prometheus.Gatherer) error {
+	srv := &http.Server{Handler: Handler(g), ReadHeaderTimeout: readHeaderTimeout}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(l) }()
+
+	select {
+	case err := <-errc:
+		return fmt.Errorf("metrics server failed: %w", err)
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down metrics server: %w", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("metrics server failed: %w", err)
+	}
+	return nil
+}
+
+// ListenAndServe serves the metrics gathered by g on port until ctx is done.
+func ListenAndServe(ctx context.Context, port int, g prometheus.Gatherer) error {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d: %w", port, err)
+	}
+	return Serve(ctx, l, g)
+}
+
+// ReportPoolStats samples pool into m every PoolStatsInterval until ctx is
+// done.
+func (m *Metrics) ReportPoolStats(ctx context.Context, pool *pgxpool.Pool) {
+	db.NewPoolStatsReporter(pool, PoolStatsInterval, m.SetPoolStats).Run(ctx)
+}
+
+// ReportTronUsage samples the usage of client, e.g. a *tron.Client, into m
+// every TronUsageInterval until ctx is done.
+func (m *Metrics) ReportTronUsage(ctx context.Context, client interface{ Usage() tron.Usage }) {
+	ticker := time.NewTicker(TronUsageInterval)
+	defer ticker.Stop()
+	for {
+		m.SetTronUsage(client.Usage())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ObserveRequest records an API request. route is the pattern that matched
+// it, never the raw path, to keep the label set small.
+func (m *Metrics) ObserveRequest(route, method string, status int, d time.Duration) {
+	m.httpRequests.WithLabelValues(route, method, strconv.Itoa(status)).Observe(d.Seconds())
+}
+
+// ObserveRPC records a gRPC call. method is its full method name and code
+// the name of its status code, e.g. "NotFound".
+func (m *Metrics) ObserveRPC(method, code string, d time.Duration) {
+	m.grpcRequests.WithLabelValues(method, code).Observe(d.Seconds())
+}
+
+// PaymentTransition records a payment moving to status.
+func (m *Metrics) PaymentTransition(status string) {
+	m.paymentTransitions.WithLabelValues(status).Inc()
+}
+
+// ChainLag records how many blocks the watcher named watcher is behind.
+func (m *Metrics) ChainLag(watcher string, blocks int64) {
+	m.chainLag.WithLabelValues(watcher).Set(float64(blocks))
+}
+
+// WebhookDelivery records the result of a delivery's attempt-th attempt.
+func (m *Metrics) WebhookDelivery(result string, attempt int) {
+	m.webhookDeliveries.WithLabelValues(result).Inc()
+	m.webhookAttempts.WithLabelValues(result).Observe(float64(attempt))
+}
+
+// WebhookInFlight adds delta to the webhook deliveries being sent.
+func (m *Metrics) WebhookInFlight(delta int) {
+	m.webhookInFlight.Add(float64(delta))
+}
+
+// WebhookQueueDepth adds delta to the webhook deliveries waiting to be sent.
+func (m *Metrics) WebhookQueueDepth(delta int) {
+	m.webhookQueueDepth.Add(float64(delta))
+}
+
+// WebhookBreakerState records the state of an endpoint host's circuit
+// breaker: 0 closed, 1 half open, 2 open.
+func (m *Metrics) WebhookBreakerState(host string, state int) {
+	m.webhookBreaker.WithLabelValues(host).Set(float64(state))
+}
+
+// TronRequest records a request to a TRON node API path. status is the HTTP
+// status code, or 0 when the request got no response.
+func (m *Metrics) TronRequest(endpoint string, status int) {
+	label := "error"
+	if status != 0 {
+		label = strconv.Itoa(status)
+	}
+	m.tronRequests.WithLabelValues(endpoint, label).Inc()
+}
+
+// SetTronUsage records a snapshot of the requests a TRON client has sent.
+func (m *Metrics) SetTronUsage(u tron.Usage) {
+	m.tronToday.Set(float64(u.Today))
+	m.tronBudget.Set(float64(u.Budget))
+	for _, p := range u.Paths {
+		m.tronUsage.WithLabelValues(p.Path, "minute").Set(float64(p.Minute))
+		m.tronUsage.WithLabelValues(p.Path, "hour").Set(float64(p.Hour))
+	}
+}
+
+// ReconcileMismatches records how many mismatches of kind the last
+// reconciliation found.
+func (m *Metrics) ReconcileMismatches(kind string, n int) {
+	m.reconcileMismatch.WithLabelValues(kind).Set(float64(n))
+}
+
+// LogsPruned counts n logs of eventType deleted by the retention job.
+func (m *Metrics) LogsPruned(eventType string, n int) {
+	m.logsPruned.WithLabelValues(eventType).Add(float64(n))
+}
+
+// EventDropped counts an in-process event of eventType dropped for a
+// subscriber that fell behind.
+func (m *Metrics) EventDropped(eventType string) {
+	m.eventsDropped.WithLabelValues(eventType).Inc()
+}
+
+// ObserveQuery records a call of the repository method named method, e.g.
+// "CreatePayment", and whether it failed.
+func (m *Metrics) ObserveQuery(method, result string, d time.Duration) {
+	m.dbQueries.WithLabelValues(method, result).Observe(d.Seconds())
+}
+
+// SetPoolStats records a database pool snapshot, e.g. as the report callback
+// of a db.StatsReporter.
+func (m *Metrics) SetPoolStats(s db.Stats) {
+	m.dbTotalConns.Set(float64(s.TotalConns))
+	m.dbIdleConns.Set(float64(s.IdleConns))
+	m.dbAcquiredConns.Set(float64(s.AcquiredConns))
+	m.dbMaxConns.Set(float64(s.MaxConns))
+	m.dbAcquireCount.Set(float64(s.AcquireCount))
+	m.dbAcquireDuration.Set(s.AcquireDuration.Seconds())
+}
@@ -0,0 +1,275 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/api"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/db"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/events"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/reconciler"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/retention"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/watcher"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/webhooks"
+)
+
+var (
+	_ api.Metrics        = (*Metrics)(nil)
+	_ watcher.Metrics    = (*Metrics)(nil)
+	_ webhooks.Metrics   = (*Metrics)(nil)
+	_ tron.Metrics       = (*Metrics)(nil)
+	_ reconciler.Metrics = (*Metrics)(nil)
+	_ retention.Metrics  = (*Metrics)(nil)
+	_ events.DropMetrics = (*Metrics)(nil)
+
+	_ repository.MetricsRecorder = (*Metrics)(nil)
+)
+
+// scrape returns the text exposition served by Handler for reg.
+func scrape(t *testing.T, reg prometheus.Gatherer) string {
+	t.Helper()
+	srv := httptest.NewServer(Handler(reg))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestObserveRequest(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := New(reg)
+
+	m.ObserveRequest("/v1/payments", "POST", 201, 30*time.Millisecond)
+	m.ObserveRequest("/v1/payments", "POST", 201, 2*time.Second)
+	m.ObserveRequest("/v1/payments/{id}", "GET", 404, time.Millisecond)
+
+	out := scrape(t, reg)
+	assert.Contains(t, out, `tpg_http_request_duration_seconds_count{method="POST",route="/v1/payments",status="201"} 2`)
+	assert.Contains(t, out, `tpg_http_request_duration_seconds_sum{method="POST",route="/v1/payments",status="201"} 2.03`)
+	assert.Contains(t, out, `tpg_http_request_duration_seconds_bucket{method="POST",route="/v1/payments",status="201",le="0.05"} 1`)
+	assert.Contains(t, out, `tpg_http_request_duration_seconds_count{method="GET",route="/v1/payments/{id}",status="404"} 1`)
+}
+
+func TestObserveRPC(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := New(reg)
+
+	m.ObserveRPC("/payments.v1.PaymentService/GetPayment", "OK", 20*time.Millisecond)
+	m.ObserveRPC("/payments.v1.PaymentService/GetPayment", "NotFound", time.Millisecond)
+
+	out := scrape(t, reg)
+	assert.Contains(t, out, `tpg_grpc_request_duration_seconds_count{code="OK",method="/payments.v1.PaymentService/GetPayment"} 1`)
+	assert.Contains(t, out, `tpg_grpc_request_duration_seconds_count{code="NotFound",method="/payments.v1.PaymentService/GetPayment"} 1`)
+}
+
+func TestPaymentTransition(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+
+	m.PaymentTransition("CONFIRMED")
+	m.PaymentTransition("CONFIRMED")
+	m.PaymentTransition("EXPIRED")
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.paymentTransitions.WithLabelValues("CONFIRMED")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.paymentTransitions.WithLabelValues("EXPIRED")))
+}
+
+func TestChainLag(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+
+	m.ChainLag("default", 12)
+	m.ChainLag("default", 3)
+
+	assert.Equal(t, 3.0, testutil.ToFloat64(m.chainLag.WithLabelValues("default")), "a gauge keeps the latest value")
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := New(reg)
+
+	m.WebhookDelivery(webhooks.ResultRetried, 1)
+	m.WebhookDelivery(webhooks.ResultRetried, 2)
+	m.WebhookDelivery(webhooks.ResultDelivered, 3)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.webhookDeliveries.WithLabelValues("retried")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.webhookDeliveries.WithLabelValues("delivered")))
+	out := scrape(t, reg)
+	assert.Contains(t, out, `tpg_webhook_delivery_attempts_bucket{result="retried",le="1"} 1`)
+	assert.Contains(t, out, `tpg_webhook_delivery_attempts_bucket{result="retried",le="2"} 2`)
+	assert.Contains(t, out, `tpg_webhook_delivery_attempts_bucket{result="delivered",le="2"} 0`)
+	assert.Contains(t, out, `tpg_webhook_delivery_attempts_bucket{result="delivered",le="3"} 1`)
+}
+
+func TestWebhookDispatch(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := New(reg)
+
+	m.WebhookQueueDepth(5)
+	m.WebhookQueueDepth(-2)
+	m.WebhookInFlight(2)
+	m.WebhookInFlight(-1)
+	m.WebhookBreakerState("hooks.example.com", int(webhooks.BreakerOpen))
+	m.WebhookBreakerState("shop.example.com:8443", int(webhooks.BreakerHalfOpen))
+
+	assert.Equal(t, 3.0, testutil.ToFloat64(m.webhookQueueDepth))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.webhookInFlight))
+	out := scrape(t, reg)
+	assert.Contains(t, out, `tpg_webhook_breaker_state{host="hooks.example.com"} 2`)
+	assert.Contains(t, out, `tpg_webhook_breaker_state{host="shop.example.com:8443"} 1`)
+}
+
+func TestReconcileMismatches(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+
+	m.ReconcileMismatches(reconciler.KindMissingTransfer, 4)
+	m.ReconcileMismatches(reconciler.KindMissingTransfer, 0)
+	m.ReconcileMismatches(reconciler.KindSweepShortfall, 2)
+
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.reconcileMismatch.WithLabelValues("MISSING_TRANSFER")), "a resolved mismatch clears")
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.reconcileMismatch.WithLabelValues("SWEEP_SHORTFALL")))
+}
+
+func TestLogsPruned(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+
+	m.LogsPruned("TX_DETECTED", 1000)
+	m.LogsPruned("TX_DETECTED", 250)
+	m.LogsPruned("WEBHOOK_SENT", 3)
+
+	assert.Equal(t, 1250.0, testutil.ToFloat64(m.logsPruned.WithLabelValues("TX_DETECTED")))
+	assert.Equal(t, 3.0, testutil.ToFloat64(m.logsPruned.WithLabelValues("WEBHOOK_SENT")))
+}
+
+func TestEventDropped(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+
+	m.EventDropped("payment.confirmed")
+	m.EventDropped("payment.confirmed")
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.eventsDropped.WithLabelValues("payment.confirmed")))
+}
+
+func TestTronRequest(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+
+	m.TronRequest("/wallet/getnowblock", 200)
+	m.TronRequest("/wallet/getnowblock", 429)
+	m.TronRequest("/wallet/getnowblock", 0)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.tronRequests.WithLabelValues("/wallet/getnowblock", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.tronRequests.WithLabelValues("/wallet/getnowblock", "429")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.tronRequests.WithLabelValues("/wallet/getnowblock", "error")))
+}
+
+func TestSetTronUsage(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+
+	m.SetTronUsage(tron.Usage{
+		Today:  420,
+		Budget: 10000,
+		Paths:  []tron.PathUsage{{Path: "/wallet/getnowblock", Minute: 12, Hour: 300}},
+	})
+
+	assert.Equal(t, 420.0, testutil.ToFloat64(m.tronToday))
+	assert.Equal(t, 10000.0, testutil.ToFloat64(m.tronBudget))
+	assert.Equal(t, 12.0, testutil.ToFloat64(m.tronUsage.WithLabelValues("/wallet/getnowblock", "minute")))
+	assert.Equal(t, 300.0, testutil.ToFloat64(m.tronUsage.WithLabelValues("/wallet/getnowblock", "hour")))
+}
+
+func TestObserveQuery(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := New(reg)
+
+	m.ObserveQuery("CreatePayment", repository.QueryResultSuccess, 3*time.Millisecond)
+	m.ObserveQuery("CreatePayment", repository.QueryResultError, 20*time.Millisecond)
+	m.ObserveQuery("GetClientByAPIKey", repository.QueryResultSuccess, time.Millisecond)
+
+	out := scrape(t, reg)
+	assert.Contains(t, out, `tpg_db_query_duration_seconds_count{method="CreatePayment",result="success"} 1`)
+	assert.Contains(t, out, `tpg_db_query_duration_seconds_bucket{method="CreatePayment",result="success",le="0.005"} 1`)
+	assert.Contains(t, out, `tpg_db_query_duration_seconds_count{method="CreatePayment",result="error"} 1`)
+	assert.Contains(t, out, `tpg_db_query_duration_seconds_count{method="GetClientByAPIKey",result="success"} 1`)
+}
+
+func TestSetPoolStats(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+
+	m.SetPoolStats(db.Stats{
+		TotalConns:      8,
+		IdleConns:       5,
+		AcquiredConns:   3,
+		MaxConns:        10,
+		AcquireCount:    1234,
+		AcquireDuration: 1500 * time.Millisecond,
+	})
+
+	assert.Equal(t, 8.0, testutil.ToFloat64(m.dbTotalConns))
+	assert.Equal(t, 5.0, testutil.ToFloat64(m.dbIdleConns))
+	assert.Equal(t, 3.0, testutil.ToFloat64(m.dbAcquiredConns))
+	assert.Equal(t, 10.0, testutil.ToFloat64(m.dbMaxConns))
+	assert.Equal(t, 1234.0, testutil.ToFloat64(m.dbAcquireCount))
+	assert.Equal(t, 1.5, testutil.ToFloat64(m.dbAcquireDuration))
+}
+
+func TestNew_RegistersOnce(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	New(reg)
+
+	assert.Panics(t, func() { New(reg) })
+}
+
+func TestNewRegistry_IncludesRuntime(t *testing.T) {
+	reg := NewRegistry()
+	New(reg)
+
+	out := scrape(t, reg)
+	assert.Contains(t, out, "go_goroutines")
+	assert.Contains(t, out, "tpg_db_pool_max_conns 0")
+}
+
+func TestHandler_OnlyServesMetrics(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	Handler(prometheus.NewRegistry()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServe_StopsWithContext(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	reg := prometheus.NewRegistry()
+	New(reg).PaymentTransition("CONFIRMED")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Serve(ctx, l, reg) }()
+
+	resp, err := http.Get("http://" + l.Addr().String() + "/metrics")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Contains(t, string(body), `tpg_payment_transitions_total{status="CONFIRMED"} 1`)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after cancel")
+	}
+}
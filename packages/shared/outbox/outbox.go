@@ -0,0 +1,87 @@
+// Package outbox hands payment events on exactly once.
+//
+// An event is written to the outbox table in the transaction that changes
+// the payment, so the change and its event commit or roll back together.
+// The Relay later claims committed events, hands each to its handlers, e.g.
+// as webhook deliveries or to a Kafka topic, and marks them processed, all in
+// one transaction of its own: a relay that crashes midway leaves the batch to
+// be claimed again, and one that commits never sees it again.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/events"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/requestid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tracing"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/webhooks"
+)
+
+// Store is the subset of repository.Querier Record writes through.
+type Store interface {
+	CreateOutboxEvent(ctx context.Context, arg repository.CreateOutboxEventParams) error
+}
+
+// TxRunner runs fn in a transaction. *repository.Store implements it.
+type TxRunner interface {
+	ExecTx(ctx context.Context, fn func(repository.Querier) error) error
+}
+
+// Record writes event about payment to the outbox through q, which should be
+// bound to the transaction that changed the payment. The payload is the
+// webhook body, created at at; the request ID and trace context in ctx, if
+// any, go with it.
+func Record(ctx context.Context, q Store, event string, payment repository.Payment, at time.Time) error {
+	id := uuid.New()
+	payload, err := webhooks.NewPayload(id, event, payment, at)
+	if err != nil {
+		return err
+	}
+	err = q.CreateOutboxEvent(ctx, repository.CreateOutboxEventParams{
+		ID:          id,
+		EventType:   event,
+		AggregateID: payment.ID,
+		Payload:     payload,
+		RequestID:   requestid.Ptr(ctx),
+		TraceParent: tracing.TraceParent(ctx),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record %s event: %w", event, err)
+	}
+	return nil
+}
+
+// Transition is how a payment changes status. It runs change, which moves
+// the payment and writes whatever else belongs with the move, such as a log,
+// and records event for the payment change returns, all in one transaction
+// on store. A change that loses a version race is run again in a new
+// transaction, as repository.RetryTx does, so change should read the
+// payment's version through q. When change or the outbox write fails,
+// nothing is kept and the error is returned as is.
+//
+// Once the transaction commits, the change is published to bus, if not nil,
+// for what reacts to it in this process. The outbox is what other processes
+// rely on: a publish is not retried and its failure is not returned, as the
+// change stands either way.
+func Transition(ctx context.Context, store TxRunner, bus events.EventPublisher, event string, at time.Time,
+	change func(repository.Querier) (repository.Payment, error)) (repository.Payment, error) {
+	var payment repository.Payment
+	err := repository.RetryTx(ctx, store, func(q repository.Querier) error {
+		var err error
+		if payment, err = change(q); err != nil {
+			return err
+		}
+		return Record(ctx, q, event, payment, at)
+	})
+	if err != nil {
+		return repository.Payment{}, err
+	}
+	if bus != nil {
+		_ = bus.Publish(ctx, events.Event{PaymentID: payment.ID, Type: event, Status: payment.Status, At: at.UTC()})
+	}
+	return payment, nil
+}
@@ -0,0 +1,518 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/events"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/requestid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/webhooks"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	t0         = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	errCrashed = errors.New("process crashed")
+)
+
+// memStore keeps payments, the outbox and webhook deliveries in memory.
+// ExecTx runs one transaction at a time and restores the state it found when
+// fn fails, as a rollback would. Methods it does not implement panic through
+// the nil Querier.
+type memStore struct {
+	repository.Querier
+
+	tx         sync.Mutex
+	mu         sync.Mutex
+	payments   map[uuid.UUID]repository.Payment
+	events     []repository.Outbox
+	deliveries []repository.CreateWebhookDeliveriesParams
+	// crash fails the next call of the named method, once.
+	crash map[string]bool
+}
+
+func newMemStore() *memStore {
+	return &memStore{payments: make(map[uuid.UUID]repository.Payment), crash: make(map[string]bool)}
+}
+
+func (s *memStore) ExecTx(_ context.Context, fn func(repository.Querier) error) error {
+	s.tx.Lock()
+	defer s.tx.Unlock()
+
+	s.mu.Lock()
+	payments, events, deliveries := maps.Clone(s.payments), slices.Clone(s.events), slices.Clone(s.deliveries)
+	s.mu.Unlock()
+
+	if err := fn(s); err != nil {
+		s.mu.Lock()
+		s.payments, s.events, s.deliveries = payments, events, deliveries
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (s *memStore) crashed(method string) bool {
+	if s.crash[method] {
+		delete(s.crash, method)
+		return true
+	}
+	return false
+}
+
+func (s *memStore) UpdatePaymentStatus(_ context.Context, arg repository.UpdatePaymentStatusParams) (repository.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.payments[arg.ID]
+	if !ok || p.Status != arg.FromStatus {
+		return repository.Payment{}, repository.ErrPaymentStatusChanged
+	}
+	p.Status = arg.ToStatus
+	s.payments[p.ID] = p
+	return p, nil
+}
+
+func (s *memStore) CreateOutboxEvent(_ context.Context, arg repository.CreateOutboxEventParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.crashed("CreateOutboxEvent") {
+		return errCrashed
+	}
+	s.events = append(s.events, repository.Outbox{
+		ID:          arg.ID,
+		EventType:   arg.EventType,
+		AggregateID: arg.AggregateID,
+		Payload:     arg.Payload,
+		RequestID:   arg.RequestID,
+		TraceParent: arg.TraceParent,
+	})
+	return nil
+}
+
+func (s *memStore) ClaimOutboxEvents(_ context.Context, limit int32) ([]repository.Outbox, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []repository.Outbox
+	for _, e := range s.events {
+		if !e.ProcessedAt.Valid && len(claimed) < int(limit) {
+			claimed = append(claimed, e)
+		}
+	}
+	return claimed, nil
+}
+
+func (s *memStore) MarkOutboxEventsProcessed(_ context.Context, ids []uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.crashed("MarkOutboxEventsProcessed") {
+		return errCrashed
+	}
+	for i, e := range s.events {
+		if slices.Contains(ids, e.ID) {
+			s.events[i].ProcessedAt.Time, s.events[i].ProcessedAt.Valid = t0, true
+		}
+	}
+	return nil
+}
+
+func (s *memStore) CreateWebhookDeliveries(_ context.Context, arg repository.CreateWebhookDeliveriesParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, arg)
+	return nil
+}
+
+func (s *memStore) addPayment(status string) repository.Payment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := repository.Payment{ID: uuid.New(), ClientID: uuid.New(), Status: status, Token: "USDT"}
+	s.payments[p.ID] = p
+	return p
+}
+
+func (s *memStore) payment(id uuid.UUID) repository.Payment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.payments[id]
+}
+
+func (s *memStore) outbox() []repository.Outbox {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.events)
+}
+
+func (s *memStore) queued() []repository.CreateWebhookDeliveriesParams {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.deliveries)
+}
+
+// expire moves payment from PENDING to EXPIRED through Transition, without
+// a bus.
+func expire(ctx context.Context, store *memStore, payment repository.Payment) (repository.Payment, error) {
+	return expireOn(ctx, store, nil, payment)
+}
+
+func expireOn(ctx context.Context, store *memStore, bus events.EventPublisher, payment repository.Payment) (repository.Payment, error) {
+	return Transition(ctx, store, bus, "payment.expired", t0, func(q repository.Querier) (repository.Payment, error) {
+		return q.UpdatePaymentStatus(ctx, repository.UpdatePaymentStatusParams{
+			ToStatus:   repository.PaymentExpired,
+			ID:         payment.ID,
+			FromStatus: repository.PaymentPending,
+		})
+	})
+}
+
+func testConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Outbox = config.OutboxConfig{PollInterval: config.Duration(time.Millisecond), BatchSize: 2}
+	return cfg
+}
+
+func TestRecord(t *testing.T) {
+	store := newMemStore()
+	payment := store.addPayment(repository.PaymentConfirmed)
+	ctx := requestid.NewContext(context.Background(), "req-abc123")
+
+	require.NoError(t, Record(ctx, store, "payment.confirmed", payment, t0))
+
+	events := store.outbox()
+	require.Len(t, events, 1)
+	e := events[0]
+	assert.Equal(t, "payment.confirmed", e.EventType)
+	assert.Equal(t, payment.ID, e.AggregateID)
+	require.NotNil(t, e.RequestID)
+	assert.Equal(t, "req-abc123", *e.RequestID)
+
+	var body webhooks.Event
+	require.NoError(t, json.Unmarshal(e.Payload, &body))
+	assert.Equal(t, e.ID, body.ID, "the event keeps its id through every delivery")
+	assert.Equal(t, "payment.confirmed", body.Type)
+	assert.Equal(t, t0, body.Created)
+	assert.Equal(t, repository.PaymentConfirmed, body.Data.Status)
+}
+
+func TestRecord_TraceParent(t *testing.T) {
+	store := newMemStore()
+	payment := store.addPayment(repository.PaymentConfirmed)
+	require.NoError(t, Record(context.Background(), store, "payment.confirmed", payment, t0))
+	assert.Nil(t, store.outbox()[0].TraceParent, "no span, no trace context")
+
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "confirm")
+	defer span.End()
+	require.NoError(t, Record(ctx, store, "payment.confirmed", payment, t0))
+
+	stored := store.outbox()[1].TraceParent
+	require.NotNil(t, stored)
+	assert.Contains(t, *stored, span.SpanContext().TraceID().String())
+}
+
+func TestRecord_Error(t *testing.T) {
+	store := newMemStore()
+	store.crash["CreateOutboxEvent"] = true
+
+	err := Record(context.Background(), store, "payment.confirmed", repository.Payment{}, t0)
+
+	assert.ErrorIs(t, err, errCrashed)
+	assert.ErrorContains(t, err, "failed to record payment.confirmed event")
+}
+
+func TestTransition(t *testing.T) {
+	store := newMemStore()
+	payment := store.addPayment(repository.PaymentPending)
+
+	expired, err := expire(context.Background(), store, payment)
+
+	require.NoError(t, err)
+	assert.Equal(t, repository.PaymentExpired, expired.Status)
+	assert.Equal(t, repository.PaymentExpired, store.payment(payment.ID).Status)
+	events := store.outbox()
+	require.Len(t, events, 1)
+	assert.Equal(t, payment.ID, events[0].AggregateID)
+	assert.Contains(t, string(events[0].Payload), `"status":"EXPIRED"`, "the event carries the changed payment")
+}
+
+func TestTransition_PublishesAfterCommit(t *testing.T) {
+	store := newMemStore()
+	payment := store.addPayment(repository.PaymentPending)
+	bus := events.NewTypedBus()
+	ch, cancel := bus.Subscribe("payment.expired")
+	defer cancel()
+
+	_, err := expireOn(context.Background(), store, bus, payment)
+
+	require.NoError(t, err)
+	require.Len(t, ch, 1)
+	assert.Equal(t, events.Event{PaymentID: payment.ID, Type: "payment.expired", Status: repository.PaymentExpired, At: t0}, <-ch)
+}
+
+func TestTransition_NoPublishOnRollback(t *testing.T) {
+	store := newMemStore()
+	payment := store.addPayment(repository.PaymentPending)
+	store.crash["CreateOutboxEvent"] = true
+	bus := events.NewTypedBus()
+	ch, cancel := bus.Subscribe()
+	defer cancel()
+
+	_, err := expireOn(context.Background(), store, bus, payment)
+
+	require.ErrorIs(t, err, errCrashed)
+	assert.Empty(t, ch, "a rolled back change is not published")
+}
+
+func TestTransition_RollbackBeforeCommit(t *testing.T) {
+	store := newMemStore()
+	payment := store.addPayment(repository.PaymentPending)
+	store.crash["CreateOutboxEvent"] = true
+
+	_, err := expire(context.Background(), store, payment)
+
+	assert.ErrorIs(t, err, errCrashed)
+	assert.Equal(t, repository.PaymentPending, store.payment(payment.ID).Status, "the change is rolled back with its event")
+	assert.Empty(t, store.outbox())
+}
+
+func TestTransition_ChangeFails(t *testing.T) {
+	store := newMemStore()
+	payment := store.addPayment(repository.PaymentConfirmed)
+
+	_, err := expire(context.Background(), store, payment)
+
+	assert.ErrorIs(t, err, repository.ErrPaymentStatusChanged, "the change's error is returned as is")
+	assert.Empty(t, store.outbox(), "no change, no event")
+}
+
+func TestRelay_RelaysEachEventOnce(t *testing.T) {
+	store := newMemStore()
+	relay := NewRelay(store, testConfig())
+	var payments []repository.Payment
+	for range 3 {
+		payment := store.addPayment(repository.PaymentPending)
+		_, err := expire(context.Background(), store, payment)
+		require.NoError(t, err)
+		payments = append(payments, payment)
+	}
+
+	n, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n, "one batch")
+	n, err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	queued := store.queued()
+	require.Len(t, queued, 3)
+	for i, d := range queued {
+		assert.Equal(t, payments[i].ID, d.PaymentID, "in the order the events were written")
+		assert.Equal(t, "payment.expired", d.EventType)
+		assert.Equal(t, store.outbox()[i].Payload, d.Payload)
+	}
+	for _, e := range store.outbox() {
+		assert.True(t, e.ProcessedAt.Valid)
+	}
+}
+
+func TestRelay_LinksEventSpans(t *testing.T) {
+	store := newMemStore()
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	relay := NewRelay(store, testConfig(), WithTracerProvider(tp))
+
+	ctx, origin := tp.Tracer("test").Start(context.Background(), "expire")
+	_, err := expire(ctx, store, store.addPayment(repository.PaymentPending))
+	require.NoError(t, err)
+	origin.End()
+	_, err = expire(context.Background(), store, store.addPayment(repository.PaymentPending))
+	require.NoError(t, err)
+
+	n, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 3)
+	linked, unlinked := spans[1], spans[2]
+	assert.Equal(t, "outbox.relay payment.expired", linked.Name())
+	assert.Equal(t, trace.SpanKindConsumer, linked.SpanKind())
+	require.Len(t, linked.Links(), 1)
+	assert.Equal(t, origin.SpanContext().TraceID(), linked.Links()[0].SpanContext.TraceID())
+	assert.Equal(t, origin.SpanContext().SpanID(), linked.Links()[0].SpanContext.SpanID())
+	assert.NotEqual(t, origin.SpanContext().TraceID(), linked.SpanContext().TraceID(), "the relay starts a trace of its own")
+	assert.Empty(t, unlinked.Links(), "an event recorded without a span has nothing to link")
+}
+
+func TestRelay_CrashBeforeCommit(t *testing.T) {
+	store := newMemStore()
+	_, err := expire(context.Background(), store, store.addPayment(repository.PaymentPending))
+	require.NoError(t, err)
+	store.crash["MarkOutboxEventsProcessed"] = true
+
+	n, err := NewRelay(store, testConfig()).RelayOnce(context.Background())
+
+	assert.ErrorIs(t, err, errCrashed)
+	assert.Zero(t, n)
+	assert.Empty(t, store.queued(), "the deliveries are rolled back with the batch")
+	assert.False(t, store.outbox()[0].ProcessedAt.Valid)
+
+	n, err = NewRelay(store, testConfig()).RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Len(t, store.queued(), 1, "the retry delivers exactly once")
+}
+
+func TestRelay_CrashAfterCommitBeforeRelay(t *testing.T) {
+	store := newMemStore()
+	payment := store.addPayment(repository.PaymentPending)
+	// The watcher commits the transition and dies before any relay runs.
+	_, err := expire(context.Background(), store, payment)
+	require.NoError(t, err)
+
+	// A relay started after the restart finds the event.
+	relay := NewRelay(store, testConfig())
+	for range 2 {
+		_, err := relay.RelayOnce(context.Background())
+		require.NoError(t, err)
+	}
+
+	queued := store.queued()
+	require.Len(t, queued, 1)
+	assert.Equal(t, payment.ID, queued[0].PaymentID)
+}
+
+func TestRelay_HandlerErrorRetriesBatch(t *testing.T) {
+	store := newMemStore()
+	for range 2 {
+		_, err := expire(context.Background(), store, store.addPayment(repository.PaymentPending))
+		require.NoError(t, err)
+	}
+	var published []uuid.UUID
+	fail := true
+	relay := NewRelay(store, testConfig(), WithHandler(func(_ context.Context, _ repository.Querier, e repository.Outbox) error {
+		if fail && len(published) == 1 {
+			fail = false
+			return errors.New("broker unavailable")
+		}
+		published = append(published, e.ID)
+		return nil
+	}))
+
+	_, err := relay.RelayOnce(context.Background())
+	require.ErrorContains(t, err, "broker unavailable")
+	assert.Empty(t, store.queued(), "the first event's deliveries are rolled back too")
+	published = nil
+
+	n, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Len(t, store.queued(), 2)
+	assert.Equal(t, []uuid.UUID{store.outbox()[0].ID, store.outbox()[1].ID}, published, "handlers run after the webhooks, in order")
+}
+
+func TestRelay_RunDrainsFullBatches(t *testing.T) {
+	store := newMemStore()
+	for range 5 {
+		_, err := expire(context.Background(), store, store.addPayment(repository.PaymentPending))
+		require.NoError(t, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- NewRelay(store, testConfig()).Run(ctx) }()
+
+	require.Eventually(t, func() bool { return len(store.queued()) == 5 }, time.Second, time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+}
+
+// fakePublisher records the envelopes it publishes and fails with err.
+type fakePublisher struct {
+	mu        sync.Mutex
+	published []events.Envelope
+	err       error
+}
+
+func (p *fakePublisher) Publish(_ context.Context, e events.Envelope) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, e)
+	return nil
+}
+
+func (p *fakePublisher) Close() {}
+
+func TestRelay_Publish(t *testing.T) {
+	store := newMemStore()
+	payment := store.addPayment(repository.PaymentPending)
+	_, err := expire(context.Background(), store, payment)
+	require.NoError(t, err)
+	publisher := &fakePublisher{}
+
+	n, err := NewRelay(store, testConfig(), WithHandler(Publish(publisher))).RelayOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.Len(t, publisher.published, 1)
+	env := publisher.published[0]
+	assert.Equal(t, events.EnvelopeVersion, env.SchemaVersion)
+	assert.Equal(t, store.outbox()[0].ID, env.EventID)
+	assert.Equal(t, "payment.expired", env.EventType)
+	assert.Equal(t, t0, env.OccurredAt)
+	assert.Equal(t, payment.ID, env.Payment.ID)
+	assert.Equal(t, repository.PaymentExpired, env.Payment.Status)
+}
+
+func TestRelay_PublishErrorRetriesBatch(t *testing.T) {
+	store := newMemStore()
+	_, err := expire(context.Background(), store, store.addPayment(repository.PaymentPending))
+	require.NoError(t, err)
+	publisher := &fakePublisher{err: errors.New("not enough replicas")}
+	relay := NewRelay(store, testConfig(), WithHandler(Publish(publisher)))
+
+	_, err = relay.RelayOnce(context.Background())
+	require.ErrorContains(t, err, "not enough replicas")
+	assert.Empty(t, store.queued())
+	assert.False(t, store.outbox()[0].ProcessedAt.Valid)
+
+	publisher.err = nil
+	n, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Len(t, publisher.published, 1)
+}
+
+func TestRelay_CrashAfterPublish(t *testing.T) {
+	store := newMemStore()
+	_, err := expire(context.Background(), store, store.addPayment(repository.PaymentPending))
+	require.NoError(t, err)
+	store.crash["MarkOutboxEventsProcessed"] = true
+	publisher := &fakePublisher{}
+	relay := NewRelay(store, testConfig(), WithHandler(Publish(publisher)))
+
+	_, err = relay.RelayOnce(context.Background())
+	require.ErrorIs(t, err, errCrashed)
+	_, err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, publisher.published, 2, "a publish is not rolled back")
+	assert.Equal(t, publisher.published[0].EventID, publisher.published[1].EventID, "consumers drop the repeat by event ID")
+	assert.Len(t, store.queued(), 1, "webhooks are still queued once")
+}
@@ -0,0 +1,178 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/events"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tracing"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/webhooks"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Handler passes one event on, writing through q, which is bound to the
+// relay's transaction. An error rolls the whole batch back to be retried.
+type Handler func(ctx context.Context, q repository.Querier, e repository.Outbox) error
+
+// enqueueWebhooks is the Handler every relay starts with.
+func enqueueWebhooks(ctx context.Context, q repository.Querier, e repository.Outbox) error {
+	return webhooks.Enqueue(ctx, q, e)
+}
+
+// Publish returns a Handler publishing every event through p. A publish is
+// not part of the relay's transaction: one whose batch then fails to commit
+// is repeated with the batch, so p's consumers see each event at least once
+// and drop repeats by event ID.
+func Publish(p events.Publisher) Handler {
+	return func(ctx context.Context, _ repository.Querier, e repository.Outbox) error {
+		env, err := events.NewEnvelope(e)
+		if err != nil {
+			return err
+		}
+		return p.Publish(ctx, env)
+	}
+}
+
+// Relay moves committed outbox events to their handlers. Replicas may run
+// side by side: each claims its batch with SKIP LOCKED, so no event is
+// handed to two of them.
+type Relay struct {
+	store    TxRunner
+	handlers []Handler
+	logger   *slog.Logger
+	tracer   trace.Tracer
+
+	interval  time.Duration
+	batchSize int32
+}
+
+// Option customises a Relay.
+type Option func(*Relay)
+
+// WithLogger replaces slog.Default.
+func WithLogger(l *slog.Logger) Option {
+	return func(r *Relay) { r.logger = l }
+}
+
+// WithTracerProvider records a span for every event relayed, linked to the
+// span that recorded the event, so a trace of the request that changed a
+// payment leads on to the delivery of its event.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(r *Relay) {
+		r.tracer = tp.Tracer("github.com/yaninyzwitty/tron-payment-gateway/packages/shared/outbox")
+	}
+}
+
+// WithHandler passes every event to h too, after the webhook deliveries are
+// queued.
+func WithHandler(h Handler) Option {
+	return func(r *Relay) { r.handlers = append(r.handlers, h) }
+}
+
+// NewRelay relays up to outbox.batchSize events per transaction, polling
+// every outbox.pollInterval.
+func NewRelay(store TxRunner, cfg *config.Config, opts ...Option) *Relay {
+	r := &Relay{
+		store:     store,
+		handlers:  []Handler{enqueueWebhooks},
+		logger:    slog.Default(),
+		tracer:    noop.NewTracerProvider().Tracer(""),
+		interval:  cfg.Outbox.PollInterval.Std(),
+		batchSize: int32(cfg.Outbox.BatchSize),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run relays events until ctx is done. A full batch is followed by the next
+// one without waiting.
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := r.RelayOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logger.ErrorContext(ctx, "outbox relay failed", "error", err)
+		}
+		if err == nil && n == int(r.batchSize) {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOnce hands one batch of events, oldest first, to the handlers and
+// marks them processed, in one transaction. It returns how many events were
+// relayed; on error none were.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	var relayed int
+	err := r.store.ExecTx(ctx, func(q repository.Querier) error {
+		events, err := q.ClaimOutboxEvents(ctx, r.batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to claim outbox events: %w", err)
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		ids := make([]uuid.UUID, 0, len(events))
+		for _, e := range events {
+			if err := r.handle(ctx, q, e); err != nil {
+				return fmt.Errorf("event %s: %w", e.ID, err)
+			}
+			ids = append(ids, e.ID)
+		}
+		if err := q.MarkOutboxEventsProcessed(ctx, ids); err != nil {
+			return fmt.Errorf("failed to mark outbox events processed: %w", err)
+		}
+		relayed = len(ids)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if relayed > 0 {
+		r.logger.Debug("outbox events relayed", "count", relayed)
+	}
+	return relayed, nil
+}
+
+// handle passes e to every handler under a span of its own.
+func (r *Relay) handle(ctx context.Context, q repository.Querier, e repository.Outbox) error {
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("event.id", e.ID.String()),
+			attribute.String("event.type", e.EventType),
+			attribute.String("payment.id", e.AggregateID.String()),
+		),
+	}
+	if link, ok := tracing.Link(e.TraceParent); ok {
+		opts = append(opts, trace.WithLinks(link))
+	}
+	ctx, span := r.tracer.Start(ctx, "outbox.relay "+e.EventType, opts...)
+	defer span.End()
+
+	for _, h := range r.handlers {
+		if err := h(ctx, q, e); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+	}
+	return nil
+}
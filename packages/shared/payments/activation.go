@@ -0,0 +1,56 @@
+// Package payments holds the payment creation logic shared by the services
+// that accept payments.
+package payments
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+)
+
+// ActivationChecker is the subset of *tron.Client that reports whether an
+// address is activated.
+type ActivationChecker interface {
+	IsAddressActivated(ctx context.Context, address string) (bool, error)
+}
+
+// WalletActivation is the activation status of a new payment's deposit
+// wallet, returned with the payment so integrators can warn the payer about
+// the extra fee.
+type WalletActivation struct {
+	// Activated is nil when the node could not be asked.
+	Activated *bool  `json:"activated"`
+	Warning   string `json:"warning,omitempty"`
+}
+
+// CheckWalletActivation looks up whether wallet, the deposit address of a
+// payment in token, is activated. A payment should not fail to be created
+// because the node is unreachable, so on error the status is returned as
+// unknown along with the error for the caller to log.
+func CheckWalletActivation(ctx context.Context, checker ActivationChecker, wallet, token string) (WalletActivation, error) {
+	activated, err := checker.IsAddressActivated(ctx, wallet)
+	if err != nil {
+		return WalletActivation{}, fmt.Errorf("failed to check activation of %s: %w", wallet, err)
+	}
+	a := WalletActivation{Activated: &activated}
+	if !activated {
+		a.Warning = activationWarning(token)
+	}
+	return a, nil
+}
+
+func activationWarning(token string) string {
+	if strings.EqualFold(token, config.TokenTRX) {
+		return fmt.Sprintf("deposit address is not activated; the sender pays an extra %s TRX to activate it",
+			sunToTRX(tron.ActivationFeeSun))
+	}
+	return fmt.Sprintf("deposit address is not activated; a %s transfer to it costs the sender about twice the usual energy",
+		strings.ToUpper(token))
+}
+
+func sunToTRX(sun int64) string {
+	return fmt.Sprintf("%d.%d", sun/1_000_000, sun%1_000_000/100_000)
+}
@@ -0,0 +1,74 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWallet = "TMVQGm1qAQYVdetCeGRRkTWYYrLXuHK2HC"
+
+type stubChecker struct {
+	activated bool
+	err       error
+	calls     []string
+}
+
+func (s *stubChecker) IsAddressActivated(_ context.Context, address string) (bool, error) {
+	s.calls = append(s.calls, address)
+	return s.activated, s.err
+}
+
+func TestCheckWalletActivation(t *testing.T) {
+	testCases := []struct {
+		name        string
+		activated   bool
+		token       string
+		wantWarning string
+	}{
+		{"activated", true, "USDT", ""},
+		{"unactivated USDT", false, "USDT",
+			"deposit address is not activated; a USDT transfer to it costs the sender about twice the usual energy"},
+		{"unactivated TRX", false, "trx",
+			"deposit address is not activated; the sender pays an extra 1.1 TRX to activate it"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker := &stubChecker{activated: tc.activated}
+
+			a, err := CheckWalletActivation(context.Background(), checker, testWallet, tc.token)
+
+			require.NoError(t, err)
+			require.NotNil(t, a.Activated)
+			assert.Equal(t, tc.activated, *a.Activated)
+			assert.Equal(t, tc.wantWarning, a.Warning)
+			assert.Equal(t, []string{testWallet}, checker.calls)
+		})
+	}
+}
+
+func TestCheckWalletActivation_Unknown(t *testing.T) {
+	checker := &stubChecker{err: errors.New("connection refused")}
+
+	a, err := CheckWalletActivation(context.Background(), checker, testWallet, "USDT")
+
+	assert.ErrorContains(t, err, "connection refused")
+	assert.Nil(t, a.Activated, "status is unknown, not false")
+	assert.Empty(t, a.Warning)
+}
+
+func TestWalletActivation_JSON(t *testing.T) {
+	activated := false
+	data, err := json.Marshal(WalletActivation{Activated: &activated, Warning: "w"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"activated":false,"warning":"w"}`, string(data))
+
+	data, err = json.Marshal(WalletActivation{})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"activated":null}`, string(data))
+}
@@ -0,0 +1,425 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/requestid"
+)
+
+const (
+	// MinExpiry and MaxExpiry bound the expiry of a new payment.
+	MinExpiry = time.Minute
+	MaxExpiry = 24 * time.Hour
+
+	// AmountDecimals is the precision every supported token shares; it is
+	// used for amounts whose token is not known.
+	AmountDecimals = 6
+	// amountIntegerDigits is how many digits the amount column, a
+	// DECIMAL(18,6), holds before the decimal point.
+	amountIntegerDigits = 12
+	// maxAmountLength bounds an amount string before it is parsed.
+	maxAmountLength = 32
+
+	// MaxOrderReferenceLength bounds the order reference of a payment, in
+	// bytes, as the payments_order_reference_length constraint does.
+	MaxOrderReferenceLength = 200
+	// MaxMetadataBytes bounds the metadata of a payment or an account,
+	// measured compacted.
+	MaxMetadataBytes = 8 << 10
+
+	// EventAddressGenerated is logged when a payment is given its deposit
+	// wallet.
+	EventAddressGenerated = "ADDRESS_GENERATED"
+)
+
+// Request is a payment as a merchant asks for it, before validation.
+type Request struct {
+	AccountID string
+	// Token is one of the configured supported tokens; empty means the
+	// first of them.
+	Token string
+	// Amount is a decimal string in Token units.
+	Amount string
+	// ExpiresIn is in seconds; nil means the account's default, or the
+	// configured one.
+	ExpiresIn *int64
+	// WebhookEndpointID is the one endpoint the payment's webhooks go to;
+	// empty means the account's default, or every endpoint of the client.
+	WebhookEndpointID string
+	// OrderReference is the merchant's own id for the payment, unique among
+	// the payments of the client; empty means none.
+	OrderReference string
+	// Metadata is a JSON object stored and echoed with the payment.
+	Metadata json.RawMessage
+}
+
+// New is a validated Request.
+type New struct {
+	AccountID uuid.UUID
+	Token     string
+	Amount    decimal.Decimal
+	Expiry    time.Duration
+	// Mode is the mode of the paying client; Validate leaves it live.
+	Mode string
+	// WebhookEndpointID is nil when the webhooks go to every endpoint.
+	WebhookEndpointID *uuid.UUID
+	// MaxActive bounds the PENDING and DETECTED payments of the account,
+	// counting this one; zero means no bound.
+	MaxActive int
+	// OrderReference is nil when the payment has none.
+	OrderReference *string
+	// Metadata is compacted, or nil when the payment has none.
+	Metadata []byte
+	// FromPool makes Create claim the wallet from the account's address
+	// pool, deriving one only when the pool is empty.
+	FromPool bool
+	// SuffixHold is how long the amount suffix of a payment of an
+	// amount_suffix account stays reserved after the payment expires.
+	SuffixHold time.Duration
+}
+
+// ErrAccountNotFound is returned by Create when the client has no such
+// account or it was deleted.
+var ErrAccountNotFound = errors.New("account not found")
+
+// ErrTooManyOpenPayments is returned by Create when the account already has
+// New.MaxActive payments waiting for funds.
+var ErrTooManyOpenPayments = errors.New("too many open payments")
+
+// ErrDerivationHalted is returned by a WalletDeriver that refuses to derive
+// because the startup check found stored wallets it no longer derives.
+var ErrDerivationHalted = errors.New("wallet derivation is halted")
+
+// AddressGeneratedLog is the raw data of an ADDRESS_GENERATED log.
+type AddressGeneratedLog struct {
+	Wallet      string `json:"wallet"`
+	WalletIndex int64  `json:"wallet_index"`
+	Attempt     int32  `json:"attempt"`
+	// Pooled is set when the wallet was taken from the address pool.
+	Pooled bool `json:"pooled,omitempty"`
+	// Suffix is the amount suffix of a payment sharing the deposit wallet
+	// of an amount_suffix account.
+	Suffix int32 `json:"amount_suffix,omitempty"`
+}
+
+// WalletDeriver derives the address of the deposit wallet at index.
+type WalletDeriver interface {
+	DeriveWallet(index uint32) (string, error)
+}
+
+// TxRunner runs fn in a transaction; *repository.Store implements it.
+type TxRunner interface {
+	ExecTx(ctx context.Context, fn func(repository.Querier) error) error
+}
+
+// Validate checks every field, so a client sees all of its mistakes at once.
+// The returned map names each invalid field with what is wrong with it.
+func (req Request) Validate(cfg config.PaymentsConfig) (New, map[string]string) {
+	p := New{
+		Expiry:    cfg.DefaultExpiry.Std(),
+		Mode:      config.ModeLive,
+		MaxActive: cfg.MaxActivePerAccount,
+		FromPool:  cfg.AddressPool.Enabled,
+
+		SuffixHold: cfg.AmountSuffix.Hold.Std(),
+	}
+	fields := make(map[string]string)
+
+	var err error
+	if req.AccountID == "" {
+		fields["account_id"] = "is required"
+	} else if p.AccountID, err = uuid.Parse(req.AccountID); err != nil {
+		fields["account_id"] = "must be a UUID"
+	}
+
+	p.Token = strings.ToUpper(req.Token)
+	if p.Token == "" {
+		p.Token = cfg.DefaultToken()
+	}
+	decimals, ok := config.TokenDecimals(p.Token)
+	if !ok || !cfg.SupportsToken(p.Token) {
+		fields["token"] = "must be one of " + strings.Join(SupportedTokens(cfg), ", ")
+		// The amount is still checked, at the precision every token shares.
+		decimals = AmountDecimals
+	}
+
+	if msg := validateAmount(req.Amount, decimals, cfg, &p.Amount); msg != "" {
+		fields["amount"] = msg
+	}
+
+	if req.ExpiresIn != nil {
+		expiry := time.Duration(*req.ExpiresIn) * time.Second
+		if *req.ExpiresIn < int64(MinExpiry/time.Second) || *req.ExpiresIn > int64(MaxExpiry/time.Second) {
+			fields["expires_in"] = fmt.Sprintf("must be between %d and %d seconds",
+				int64(MinExpiry/time.Second), int64(MaxExpiry/time.Second))
+		} else {
+			p.Expiry = expiry
+		}
+	}
+
+	if req.WebhookEndpointID != "" {
+		if id, err := uuid.Parse(req.WebhookEndpointID); err != nil {
+			fields["webhook_endpoint_id"] = "must be a UUID"
+		} else {
+			p.WebhookEndpointID = repository.UUIDPtr(id)
+		}
+	}
+
+	if req.OrderReference != "" {
+		switch {
+		case strings.TrimSpace(req.OrderReference) != req.OrderReference:
+			fields["order_reference"] = "must not start or end with whitespace"
+		case len(req.OrderReference) > MaxOrderReferenceLength:
+			fields["order_reference"] = fmt.Sprintf("must be at most %d bytes", MaxOrderReferenceLength)
+		default:
+			ref := req.OrderReference
+			p.OrderReference = &ref
+		}
+	}
+
+	var msg string
+	if p.Metadata, msg = CompactMetadata(req.Metadata); msg != "" {
+		fields["metadata"] = msg
+	}
+	return p, fields
+}
+
+// WithAccountDefaults returns p with the settings req left out taken from
+// account. A request overrides its account, which overrides the config
+// Validate applied.
+func (req Request) WithAccountDefaults(p New, account repository.Account) New {
+	if req.ExpiresIn == nil && account.DefaultExpirySeconds != nil {
+		p.Expiry = time.Duration(*account.DefaultExpirySeconds) * time.Second
+	}
+	if req.WebhookEndpointID == "" {
+		p.WebhookEndpointID = account.DefaultWebhookEndpointID
+	}
+	return p
+}
+
+// CompactMetadata returns raw, a JSON value already checked to be valid, as
+// a compacted object, or what is wrong with it. null and a missing value
+// leave the metadata unset.
+func CompactMetadata(raw json.RawMessage) ([]byte, string) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, ""
+	}
+	if raw[0] != '{' {
+		return nil, "must be an object"
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return nil, "must be an object"
+	}
+	if buf.Len() > MaxMetadataBytes {
+		return nil, fmt.Sprintf("must be at most %d bytes", MaxMetadataBytes)
+	}
+	return buf.Bytes(), ""
+}
+
+// SupportedTokens lists the tokens a payment may be requested in.
+func SupportedTokens(cfg config.PaymentsConfig) []string {
+	tokens := make([]string, len(cfg.SupportedTokens))
+	for i, t := range cfg.SupportedTokens {
+		tokens[i] = strings.ToUpper(t)
+	}
+	return tokens
+}
+
+// validateAmount parses s into amount, an amount of a token with decimals
+// places, returning what is wrong with it.
+func validateAmount(s string, decimals int32, cfg config.PaymentsConfig, amount *decimal.Decimal) string {
+	if s == "" {
+		return "is required"
+	}
+	if len(s) > maxAmountLength {
+		return fmt.Sprintf("must be at most %d characters", maxAmountLength)
+	}
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return "must be a decimal string"
+	}
+	if !d.IsPositive() {
+		return "must be positive"
+	}
+	if -d.Exponent() > decimals {
+		return fmt.Sprintf("must have at most %d decimal places", decimals)
+	}
+	if limit := decimal.New(1, amountIntegerDigits); d.GreaterThanOrEqual(limit) {
+		return "must be less than " + limit.String()
+	}
+	// Limits are checked when the config loads.
+	minAmount, maxAmount, _ := cfg.AmountLimits()
+	if minAmount != nil && d.LessThan(*minAmount) {
+		return "must be at least " + minAmount.String()
+	}
+	if maxAmount != nil && d.GreaterThan(*maxAmount) {
+		return "must be at most " + maxAmount.String()
+	}
+	*amount = d
+	return ""
+}
+
+// Create gives p a deposit wallet and its amount with the AddressStrategy
+// the account names, and records the payment, its first attempt and an
+// ADDRESS_GENERATED log in one transaction. The payment expires p.Expiry
+// after now. It returns ErrAccountNotFound when the account is not an
+// undeleted account of clientID, ErrTooManyOpenPayments when it already has
+// p.MaxActive open payments, ErrAmountTooPrecise and ErrSuffixesExhausted
+// when an amount_suffix account cannot tell the payment apart, and
+// repository.ErrDuplicateOrderReference when the client already used
+// p.OrderReference.
+func Create(ctx context.Context, store TxRunner, wallets WalletDeriver, clientID uuid.UUID, p New, now time.Time) (repository.Payment, error) {
+	var payment repository.Payment
+	err := store.ExecTx(ctx, func(q repository.Querier) error {
+		// Read again in the transaction, so a concurrent SoftDeleteAccount
+		// either sees this payment or makes the insert retry.
+		account, err := q.GetAccountByIDAndClientID(ctx, repository.GetAccountByIDAndClientIDParams{ID: p.AccountID, ClientID: clientID})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAccountNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load account: %w", err)
+		}
+		if p.MaxActive > 0 {
+			open, err := q.CountPendingPaymentsByAccountID(ctx, p.AccountID)
+			if err != nil {
+				return fmt.Errorf("failed to count open payments: %w", err)
+			}
+			if open >= int64(p.MaxActive) {
+				return ErrTooManyOpenPayments
+			}
+		}
+
+		strategy, ok := StrategyFor(account.AddressStrategy, p.SuffixHold)
+		if !ok {
+			return fmt.Errorf("account has unknown address strategy %q", account.AddressStrategy)
+		}
+		a, err := strategy.Allocate(ctx, q, wallets, account, p, now)
+		if err != nil {
+			return err
+		}
+		// A shared wallet is not the payment's own, so it records no index.
+		var index *int64
+		if !a.Shared {
+			index = &a.Index
+		}
+
+		payment, err = q.CreatePayment(ctx, repository.CreatePaymentParams{
+			ClientID:     clientID,
+			AccountID:    p.AccountID,
+			Amount:       repository.DecimalToNumeric(a.Amount),
+			UniqueWallet: a.Wallet,
+			ExpiresAt:    pgtype.Timestamptz{Time: now.Add(p.Expiry), Valid: true},
+			WalletIndex:  index,
+			Token:        p.Token,
+			Mode:         p.Mode,
+
+			WebhookEndpointID: p.WebhookEndpointID,
+			OrderReference:    p.OrderReference,
+			Metadata:          p.Metadata,
+			ID:                a.PaymentID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to insert payment: %w", err)
+		}
+
+		const attempt = 1
+		if _, err := q.CreatePaymentAttempt(ctx, repository.CreatePaymentAttemptParams{
+			AttemptNumber:   attempt,
+			PaymentID:       payment.ID,
+			GeneratedWallet: a.Wallet,
+			WalletIndex:     index,
+		}); err != nil {
+			return fmt.Errorf("failed to insert payment attempt: %w", err)
+		}
+
+		raw, err := json.Marshal(AddressGeneratedLog{Wallet: a.Wallet, WalletIndex: a.Index, Attempt: attempt, Pooled: a.Pooled, Suffix: a.Suffix})
+		if err != nil {
+			return fmt.Errorf("failed to encode log data: %w", err)
+		}
+		msg := fmt.Sprintf("deposit wallet %s generated at index %d", a.Wallet, a.Index)
+		if a.Shared {
+			msg = fmt.Sprintf("deposit wallet %s shared with amount suffix %d", a.Wallet, a.Suffix)
+		}
+		if err := q.CreateLog(ctx, repository.CreateLogParams{
+			PaymentID: repository.UUIDPtr(payment.ID),
+			EventType: EventAddressGenerated,
+			Message:   &msg,
+			RawData:   raw,
+			RequestID: requestid.Ptr(ctx),
+		}); err != nil {
+			return fmt.Errorf("failed to log address generation: %w", err)
+		}
+		return nil
+	})
+	return payment, err
+}
+
+// allocateWallet returns the wallet of a new payment p. A wallet claimed
+// from the pool is reserved for a payment id chosen here, which the payment
+// must then be created with; paymentID is nil for a wallet derived on
+// demand.
+func allocateWallet(ctx context.Context, q repository.Querier, wallets WalletDeriver, p New) (wallet string, index int64, paymentID *uuid.UUID, err error) {
+	if p.FromPool {
+		id := uuid.New()
+		pooled, err := q.ReserveAddress(ctx, repository.ReserveAddressParams{PaymentID: id, AccountID: p.AccountID})
+		switch {
+		case err == nil:
+			return pooled.Address, pooled.AddressIndex, repository.UUIDPtr(id), nil
+		case !errors.Is(err, pgx.ErrNoRows):
+			return "", 0, nil, fmt.Errorf("failed to reserve pooled address: %w", err)
+		}
+	}
+	wallet, index, err = AllocateWallet(ctx, q, wallets, p.Mode)
+	return wallet, index, nil, err
+}
+
+// AllocateWallet derives the wallet at the next unused address index of
+// mode. Each mode has its own mnemonic, so wallets must derive from the one
+// of mode.
+func AllocateWallet(ctx context.Context, q repository.Querier, wallets WalletDeriver, mode string) (string, int64, error) {
+	index, err := q.NextWalletIndex(ctx, WalletCounter(mode))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to allocate wallet index: %w", err)
+	}
+	if index < 0 || index > math.MaxUint32 {
+		return "", 0, fmt.Errorf("wallet index %d is out of range", index)
+	}
+	wallet, err := wallets.DeriveWallet(uint32(index))
+	if err != nil {
+		return "", 0, err
+	}
+	return wallet, index, nil
+}
+
+// WalletCounter names the wallet index counter of mode.
+func WalletCounter(mode string) string {
+	if mode == config.ModeTest {
+		return "deposit_test"
+	}
+	return "deposit"
+}
+
+// FormatAmount renders amount with the precision of token.
+func FormatAmount(amount decimal.Decimal, token string) string {
+	decimals, ok := config.TokenDecimals(token)
+	if !ok {
+		decimals = AmountDecimals
+	}
+	return amount.StringFixed(decimals)
+}
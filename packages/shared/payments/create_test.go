@@ -0,0 +1,283 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+func testPaymentsConfig() config.PaymentsConfig {
+	return config.PaymentsConfig{
+		DefaultExpiry:   config.Duration(30 * time.Minute),
+		MinAmount:       "1",
+		MaxAmount:       "10000",
+		SupportedTokens: []string{"usdt", "trx"},
+	}
+}
+
+func TestRequest_Validate(t *testing.T) {
+	accountID := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	expiresIn := int64(120)
+
+	ref := "order-1001"
+
+	p, fields := Request{
+		AccountID:      accountID.String(),
+		Token:          "trx",
+		Amount:         "12.5",
+		ExpiresIn:      &expiresIn,
+		OrderReference: ref,
+		Metadata:       json.RawMessage(`{ "sku": "A-1" }`),
+	}.Validate(testPaymentsConfig())
+
+	assert.Empty(t, fields)
+	assert.Equal(t, New{
+		AccountID:      accountID,
+		Token:          "TRX",
+		Amount:         decimal.RequireFromString("12.5"),
+		Expiry:         2 * time.Minute,
+		Mode:           config.ModeLive,
+		OrderReference: &ref,
+		Metadata:       []byte(`{"sku":"A-1"}`),
+	}, p)
+}
+
+func TestRequest_ValidateDefaults(t *testing.T) {
+	p, fields := Request{AccountID: uuid.NewString(), Amount: "5"}.Validate(testPaymentsConfig())
+
+	assert.Empty(t, fields)
+	assert.Equal(t, "USDT", p.Token, "the first supported token")
+	assert.Equal(t, 30*time.Minute, p.Expiry)
+}
+
+func TestRequest_WithAccountDefaults(t *testing.T) {
+	requestEndpoint := uuid.MustParse("44444444-4444-4444-4444-444444444444")
+	accountEndpoint := repository.UUIDPtr(uuid.MustParse("55555555-5555-5555-5555-555555555555"))
+	expiresIn, accountExpiry := int64(120), int64(600)
+	withDefaults := repository.Account{DefaultExpirySeconds: &accountExpiry, DefaultWebhookEndpointID: accountEndpoint}
+
+	testCases := []struct {
+		name         string
+		req          Request
+		account      repository.Account
+		wantExpiry   time.Duration
+		wantEndpoint *uuid.UUID
+	}{
+		{"config", Request{}, repository.Account{}, 30 * time.Minute, nil},
+		{"account", Request{}, withDefaults, 10 * time.Minute, accountEndpoint},
+		{"request", Request{ExpiresIn: &expiresIn, WebhookEndpointID: requestEndpoint.String()}, withDefaults,
+			2 * time.Minute, repository.UUIDPtr(requestEndpoint)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := tc.req
+			req.AccountID, req.Amount = uuid.NewString(), "5"
+			p, fields := req.Validate(testPaymentsConfig())
+			assert.Empty(t, fields)
+
+			p = req.WithAccountDefaults(p, tc.account)
+
+			assert.Equal(t, tc.wantExpiry, p.Expiry)
+			assert.Equal(t, tc.wantEndpoint, p.WebhookEndpointID)
+		})
+	}
+}
+
+func TestRequest_ValidateFields(t *testing.T) {
+	testCases := []struct {
+		name   string
+		req    Request
+		fields map[string]string
+	}{
+		{"missing", Request{}, map[string]string{
+			"account_id": "is required",
+			"amount":     "is required",
+		}},
+		{"malformed", Request{AccountID: "acct", Amount: "ten", WebhookEndpointID: "hook"}, map[string]string{
+			"account_id":          "must be a UUID",
+			"amount":              "must be a decimal string",
+			"webhook_endpoint_id": "must be a UUID",
+		}},
+		{"unsupported token", Request{AccountID: uuid.NewString(), Token: "BTC", Amount: "5"}, map[string]string{
+			"token": "must be one of USDT, TRX",
+		}},
+		{"too precise", Request{AccountID: uuid.NewString(), Amount: "1.0000001"}, map[string]string{
+			"amount": "must have at most 6 decimal places",
+		}},
+		{"below minimum", Request{AccountID: uuid.NewString(), Amount: "0.5"}, map[string]string{
+			"amount": "must be at least 1",
+		}},
+		{"above maximum", Request{AccountID: uuid.NewString(), Amount: "10000.01"}, map[string]string{
+			"amount": "must be at most 10000",
+		}},
+		{"order reference and metadata", Request{AccountID: uuid.NewString(), Amount: "5", OrderReference: strings.Repeat("x", 201), Metadata: json.RawMessage(`"note"`)}, map[string]string{
+			"order_reference": "must be at most 200 bytes",
+			"metadata":        "must be an object",
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, fields := tc.req.Validate(testPaymentsConfig())
+
+			assert.Equal(t, tc.fields, fields)
+		})
+	}
+}
+
+func TestRequest_ValidateAmountFitsColumn(t *testing.T) {
+	cfg := testPaymentsConfig()
+	cfg.MaxAmount = ""
+
+	for amount, want := range map[string]string{
+		"999999999999.999999":          "",
+		"1000000000000":                "must be less than 1000000000000",
+		"1e40":                         "must be less than 1000000000000",
+		strings.Repeat("1", 33):        "must be at most 32 characters",
+		"0." + strings.Repeat("0", 31): "must be at most 32 characters",
+	} {
+		_, fields := Request{AccountID: uuid.NewString(), Amount: amount}.Validate(cfg)
+
+		assert.Equal(t, want, fields["amount"], amount)
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	assert.Equal(t, "12.500000", FormatAmount(decimal.RequireFromString("12.5"), "TRX"))
+	assert.Equal(t, "3.000000", FormatAmount(decimal.NewFromInt(3), ""), "an unknown token gets the shared precision")
+}
+
+// derivedWallets derives "TWallet" followed by the index.
+type derivedWallets struct{}
+
+func (derivedWallets) DeriveWallet(index uint32) (string, error) {
+	return fmt.Sprintf("TWallet%d", index), nil
+}
+
+// newPoolStore returns a FakeStore holding one account, whose pool has
+// pooled addresses taken from the deposit counter.
+func newPoolStore(t *testing.T, pooled int) (*repository.FakeStore, repository.Account) {
+	t.Helper()
+	ctx := context.Background()
+	fallback := repository.NewMockQuerier(t)
+	fallback.On("CreatePaymentAttempt", mock.Anything, mock.Anything).Return(repository.PaymentAttempt{}, nil).Maybe()
+	store := repository.NewFakeStore(repository.WithFallback(fallback))
+	client, err := store.CreateClient(ctx, repository.CreateClientParams{Name: "merchant", ApiKey: "key", Mode: config.ModeLive})
+	require.NoError(t, err)
+	account, err := store.CreateAccount(ctx, repository.CreateAccountParams{ClientID: client.ID, Name: "main"})
+	require.NoError(t, err)
+	if pooled > 0 {
+		first, err := store.AllocateWalletIndexes(ctx, repository.AllocateWalletIndexesParams{Name: "deposit", Count: int64(pooled)})
+		require.NoError(t, err)
+		arg := repository.CreatePoolAddressesParams{AccountID: account.ID}
+		for i := range int64(pooled) {
+			arg.AddressIndexes = append(arg.AddressIndexes, first+i)
+			arg.Addresses = append(arg.Addresses, fmt.Sprintf("TPooled%d", first+i))
+		}
+		_, err = store.CreatePoolAddresses(ctx, arg)
+		require.NoError(t, err)
+	}
+	return store, account
+}
+
+func newPayment(account repository.Account, fromPool bool) New {
+	return New{
+		AccountID: account.ID,
+		Token:     "USDT",
+		Amount:    decimal.NewFromInt(5),
+		Expiry:    time.Hour,
+		Mode:      config.ModeLive,
+		FromPool:  fromPool,
+	}
+}
+
+func TestCreate_FromPool(t *testing.T) {
+	ctx := context.Background()
+	store, account := newPoolStore(t, 1)
+
+	payment, err := Create(ctx, store, derivedWallets{}, account.ClientID, newPayment(account, true), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "TPooled0", payment.UniqueWallet)
+	assert.Equal(t, int64(0), *payment.WalletIndex)
+
+	pool := store.AddressPool()
+	require.Len(t, pool, 1)
+	assert.Equal(t, repository.UUIDPtr(payment.ID), pool[0].ReservedByPayment,
+		"the address is reserved for the payment holding it")
+
+	logs := store.Logs()
+	require.Len(t, logs, 1)
+	assert.JSONEq(t, `{"wallet":"TPooled0","wallet_index":0,"attempt":1,"pooled":true}`, string(logs[0].RawData))
+
+	payment, err = Create(ctx, store, derivedWallets{}, account.ClientID, newPayment(account, true), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "TWallet1", payment.UniqueWallet, "an empty pool falls back to deriving the next index")
+	assert.JSONEq(t, `{"wallet":"TWallet1","wallet_index":1,"attempt":1}`, string(store.Logs()[1].RawData))
+}
+
+func TestCreate_PoolDisabled(t *testing.T) {
+	store, account := newPoolStore(t, 3)
+
+	payment, err := Create(context.Background(), store, derivedWallets{}, account.ClientID, newPayment(account, false), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "TWallet3", payment.UniqueWallet)
+	for _, a := range store.AddressPool() {
+		assert.False(t, a.ReservedByPayment != nil, "the pool is not touched")
+	}
+}
+
+func TestCreate_FromPoolReleasedOnFailure(t *testing.T) {
+	store, account := newPoolStore(t, 1)
+	p := newPayment(account, true)
+	p.Token = "BTC"
+
+	_, err := Create(context.Background(), store, derivedWallets{}, account.ClientID, p, time.Now())
+	require.Error(t, err)
+
+	assert.False(t, store.AddressPool()[0].ReservedByPayment != nil, "a failed payment gives its address back")
+}
+
+func TestCreate_FromPoolConcurrent(t *testing.T) {
+	const pooled, workers = 10, 30
+	store, account := newPoolStore(t, pooled)
+
+	var wg sync.WaitGroup
+	created := make([]repository.Payment, workers)
+	errs := make([]error, workers)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			created[i], errs[i] = Create(context.Background(), store, derivedWallets{}, account.ClientID, newPayment(account, true), time.Now())
+		}()
+	}
+	wg.Wait()
+
+	wallets := make(map[string]bool, workers)
+	fromPool := 0
+	for i, payment := range created {
+		require.NoError(t, errs[i])
+		assert.False(t, wallets[payment.UniqueWallet], "wallet %s handed to two payments", payment.UniqueWallet)
+		wallets[payment.UniqueWallet] = true
+		if strings.HasPrefix(payment.UniqueWallet, "TPooled") {
+			fromPool++
+		}
+	}
+	assert.Equal(t, pooled, fromPool, "the pool is drained before any wallet is derived")
+	for _, a := range store.AddressPool() {
+		assert.True(t, a.ReservedByPayment != nil)
+	}
+}
@@ -0,0 +1,115 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// Funnel buckets, the UTC periods a funnel counts payments by.
+const (
+	FunnelHour = "hour"
+	FunnelDay  = "day"
+)
+
+// MaxFunnelBuckets bounds how many buckets one funnel spans.
+const MaxFunnelBuckets = 1000
+
+// FunnelQuerier is the query a funnel is read with. repository.Querier and
+// *repository.TenantQueries implement it.
+type FunnelQuerier interface {
+	GetPaymentFunnel(ctx context.Context, arg repository.GetPaymentFunnelParams) ([]repository.GetPaymentFunnelRow, error)
+}
+
+// FunnelCounts is what became of a set of payments: how many were created,
+// how many a transfer was seen for, how many were confirmed before they
+// expired and how many expired.
+type FunnelCounts struct {
+	Created   int64 `json:"created"`
+	Detected  int64 `json:"detected"`
+	Confirmed int64 `json:"confirmed"`
+	Expired   int64 `json:"expired"`
+}
+
+// FunnelBucket counts the payments created in the bucket starting at Start.
+type FunnelBucket struct {
+	Start time.Time
+	FunnelCounts
+}
+
+// Funnel counts the payments created in a range, bucket by bucket.
+type Funnel struct {
+	Buckets []FunnelBucket
+	Total   FunnelCounts
+}
+
+// FunnelWidth returns the length of bucket, or false unless it is
+// FunnelHour or FunnelDay.
+func FunnelWidth(bucket string) (time.Duration, bool) {
+	switch bucket {
+	case FunnelHour:
+		return time.Hour, true
+	case FunnelDay:
+		return 24 * time.Hour, true
+	}
+	return 0, false
+}
+
+// CheckFunnelRange returns an error unless [from, to) is a range of at most
+// MaxFunnelBuckets buckets of width.
+func CheckFunnelRange(width time.Duration, from, to time.Time) error {
+	if !to.After(from) {
+		return errors.New("to must be after from")
+	}
+	// The last bucket may end after to.
+	if n := (to.Sub(from.UTC().Truncate(width)) + width - 1) / width; n > MaxFunnelBuckets {
+		return fmt.Errorf("the range spans more than %d buckets", MaxFunnelBuckets)
+	}
+	return nil
+}
+
+// GetFunnel counts the payments created in [from, to) per bucket with one
+// query. Every bucket of the range is returned, those no payment was created
+// in too, the first starting at from truncated to its bucket. clientID nil
+// counts every client's payments. The bucket and range must have passed
+// FunnelWidth and CheckFunnelRange.
+func GetFunnel(ctx context.Context, q FunnelQuerier, clientID *uuid.UUID, bucket string, from, to time.Time) (Funnel, error) {
+	width, ok := FunnelWidth(bucket)
+	if !ok {
+		return Funnel{}, fmt.Errorf("invalid funnel bucket %q", bucket)
+	}
+	rows, err := q.GetPaymentFunnel(ctx, repository.GetPaymentFunnelParams{
+		Bucket:      bucket,
+		CreatedFrom: pgtype.Timestamptz{Time: from, Valid: true},
+		CreatedTo:   pgtype.Timestamptz{Time: to, Valid: true},
+		ClientID:    clientID,
+	})
+	if err != nil {
+		return Funnel{}, fmt.Errorf("failed to read payment funnel: %w", err)
+	}
+	counts := make(map[int64]FunnelCounts, len(rows))
+	for _, r := range rows {
+		counts[r.Bucket.Time.Unix()] = FunnelCounts{
+			Created:   r.Created,
+			Detected:  r.Detected,
+			Confirmed: r.Confirmed,
+			Expired:   r.Expired,
+		}
+	}
+
+	var f Funnel
+	for start := from.UTC().Truncate(width); start.Before(to); start = start.Add(width) {
+		c := counts[start.Unix()]
+		f.Buckets = append(f.Buckets, FunnelBucket{Start: start, FunnelCounts: c})
+		f.Total.Created += c.Created
+		f.Total.Detected += c.Detected
+		f.Total.Confirmed += c.Confirmed
+		f.Total.Expired += c.Expired
+	}
+	return f, nil
+}
@@ -0,0 +1,84 @@
+package payments
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+func TestGetFunnel_FillsEmptyBuckets(t *testing.T) {
+	ctx := context.Background()
+	clientID := uuid.New()
+	from := time.Date(2026, 2, 1, 10, 30, 0, 0, time.UTC)
+	to := time.Date(2026, 2, 1, 14, 0, 0, 0, time.UTC)
+	q := repository.NewMockQuerier(t)
+	q.On("GetPaymentFunnel", ctx, repository.GetPaymentFunnelParams{
+		Bucket:      FunnelHour,
+		CreatedFrom: pgtype.Timestamptz{Time: from, Valid: true},
+		CreatedTo:   pgtype.Timestamptz{Time: to, Valid: true},
+		ClientID:    &clientID,
+	}).Return([]repository.GetPaymentFunnelRow{
+		{Bucket: pgtype.Timestamp{Time: time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC), Valid: true}, Created: 4, Detected: 3, Confirmed: 2, Expired: 1},
+		{Bucket: pgtype.Timestamp{Time: time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC), Valid: true}, Created: 2, Detected: 2, Confirmed: 2},
+	}, nil)
+
+	f, err := GetFunnel(ctx, q, &clientID, FunnelHour, from, to)
+
+	require.NoError(t, err)
+	require.Len(t, f.Buckets, 4, "10:00 to 13:00, the first starting before from")
+	assert.Equal(t, from.Truncate(time.Hour), f.Buckets[0].Start)
+	assert.Equal(t, FunnelCounts{Created: 4, Detected: 3, Confirmed: 2, Expired: 1}, f.Buckets[0].FunnelCounts)
+	assert.Equal(t, FunnelCounts{}, f.Buckets[1].FunnelCounts, "an hour without payments is still a bucket")
+	assert.Equal(t, int64(2), f.Buckets[2].Created)
+	assert.Equal(t, time.Date(2026, 2, 1, 13, 0, 0, 0, time.UTC), f.Buckets[3].Start)
+	assert.Equal(t, FunnelCounts{Created: 6, Detected: 5, Confirmed: 4, Expired: 1}, f.Total)
+}
+
+func TestGetFunnel_InvalidBucket(t *testing.T) {
+	_, err := GetFunnel(context.Background(), repository.NewMockQuerier(t), nil, "week", time.Now(), time.Now())
+	assert.ErrorContains(t, err, "invalid funnel bucket")
+}
+
+func TestCheckFunnelRange(t *testing.T) {
+	from := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name    string
+		width   time.Duration
+		to      time.Time
+		wantErr string
+	}{
+		{"one day", 24 * time.Hour, from.Add(time.Hour), ""},
+		{"as many hours as allowed", time.Hour, from.Add(MaxFunnelBuckets * time.Hour), ""},
+		{"one hour too many", time.Hour, from.Add((MaxFunnelBuckets + 1) * time.Hour), "more than 1000 buckets"},
+		{"empty", time.Hour, from, "to must be after from"},
+		{"backwards", 24 * time.Hour, from.Add(-time.Hour), "to must be after from"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckFunnelRange(tc.width, from, tc.to)
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestFunnelWidth(t *testing.T) {
+	width, ok := FunnelWidth(FunnelHour)
+	assert.True(t, ok)
+	assert.Equal(t, time.Hour, width)
+	width, ok = FunnelWidth(FunnelDay)
+	assert.True(t, ok)
+	assert.Equal(t, 24*time.Hour, width)
+	_, ok = FunnelWidth("week")
+	assert.False(t, ok)
+}
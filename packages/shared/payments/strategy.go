@@ -0,0 +1,166 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// Address strategies, the values of an account's address_strategy.
+const (
+	// StrategyUniqueWallet gives every payment a wallet of its own.
+	StrategyUniqueWallet = "unique_wallet"
+	// StrategyAmountSuffix sends every payment of the account to its one
+	// deposit wallet and tells them apart by their amounts.
+	StrategyAmountSuffix = "amount_suffix"
+)
+
+// AddressStrategies lists the address strategies an account may use.
+var AddressStrategies = []string{StrategyUniqueWallet, StrategyAmountSuffix}
+
+// SuffixDigits is how many trailing decimals of an amount_suffix payment's
+// amount its suffix takes; the amount requested may use the others.
+const SuffixDigits = 3
+
+// MaxAmountSuffix is the highest suffix, and so the most payments an
+// amount_suffix account may have holding one at a time.
+const MaxAmountSuffix = 999
+
+// ErrSuffixesExhausted is returned by Create when every amount suffix of an
+// amount_suffix account is held by a payment that is open or expired too
+// recently to give its suffix away.
+var ErrSuffixesExhausted = errors.New("every amount suffix of the account is in use")
+
+// ErrAmountTooPrecise is returned by Create when an amount_suffix payment's
+// amount uses the decimals its suffix takes.
+var ErrAmountTooPrecise = errors.New("amount is too precise to add a suffix to")
+
+// Allocation is what an AddressStrategy gives a new payment.
+type Allocation struct {
+	// Wallet is the wallet the payment is paid into, derived at Index.
+	Wallet string
+	Index  int64
+	// Shared is set when other payments are paid into Wallet too. The
+	// payment is then recorded without a wallet index, and told apart from
+	// the others by its amount.
+	Shared bool
+	// Amount is what the payment asks for.
+	Amount decimal.Decimal
+	// PaymentID is the id the payment must be created with, or nil for any.
+	PaymentID *uuid.UUID
+	// Pooled is set when Wallet was taken from the address pool.
+	Pooled bool
+	// Suffix is the amount suffix of a Shared wallet's payment.
+	Suffix int32
+}
+
+// AddressStrategy gives a new payment the wallet it is paid into and the
+// amount it asks for. It runs in the transaction Create records the payment
+// in, with the account it is created for; the payment is created at now.
+type AddressStrategy interface {
+	Allocate(ctx context.Context, q repository.Querier, wallets WalletDeriver, account repository.Account, p New, now time.Time) (Allocation, error)
+}
+
+// StrategyFor returns the strategy named by an account's address_strategy,
+// or false for an unknown name; an empty name is the default, unique_wallet.
+// hold is how long an amount suffix stays reserved after its payment expires.
+func StrategyFor(name string, hold time.Duration) (AddressStrategy, bool) {
+	switch name {
+	case "", StrategyUniqueWallet:
+		return UniqueWallet{}, true
+	case StrategyAmountSuffix:
+		return AmountSuffix{Hold: hold}, true
+	}
+	return nil, false
+}
+
+// UniqueWallet gives a payment a wallet of its own: from the account's
+// address pool when New.FromPool is set and the pool is not empty, or else
+// the next one derived. The amount is the one requested.
+type UniqueWallet struct{}
+
+// Allocate implements AddressStrategy.
+func (UniqueWallet) Allocate(ctx context.Context, q repository.Querier, wallets WalletDeriver, _ repository.Account, p New, _ time.Time) (Allocation, error) {
+	wallet, index, paymentID, err := allocateWallet(ctx, q, wallets, p)
+	if err != nil {
+		return Allocation{}, err
+	}
+	return Allocation{Wallet: wallet, Index: index, Amount: p.Amount, PaymentID: paymentID, Pooled: paymentID != nil}, nil
+}
+
+// AmountSuffix sends a payment to its account's deposit wallet, derived the
+// first time one is needed, and adds to its amount the account's lowest free
+// suffix in units of the last of the token's decimals, e.g. 100 USDT with
+// suffix 123 asks for 100.000123. The suffix is reserved until Hold after
+// the payment expires, so the watcher can credit a transfer by its wallet
+// and exact amount.
+type AmountSuffix struct {
+	Hold time.Duration
+}
+
+// Allocate implements AddressStrategy.
+func (s AmountSuffix) Allocate(ctx context.Context, q repository.Querier, wallets WalletDeriver, account repository.Account, p New, now time.Time) (Allocation, error) {
+	decimals, ok := config.TokenDecimals(p.Token)
+	if !ok {
+		decimals = AmountDecimals
+	}
+	if -p.Amount.Exponent() > decimals-SuffixDigits {
+		return Allocation{}, ErrAmountTooPrecise
+	}
+
+	wallet, index, err := depositWallet(ctx, q, wallets, account, p.Mode)
+	if err != nil {
+		return Allocation{}, err
+	}
+
+	id := uuid.New()
+	suffix, err := q.ReserveAmountSuffix(ctx, repository.ReserveAmountSuffixParams{
+		AccountID: account.ID,
+		PaymentID: id,
+		ExpiresAt: pgtype.Timestamptz{Time: now.Add(p.Expiry + s.Hold), Valid: true},
+		Now:       pgtype.Timestamptz{Time: now, Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Allocation{}, ErrSuffixesExhausted
+	}
+	if err != nil {
+		return Allocation{}, fmt.Errorf("failed to reserve amount suffix: %w", err)
+	}
+	return Allocation{
+		Wallet:    wallet,
+		Index:     index,
+		Shared:    true,
+		Amount:    p.Amount.Add(decimal.New(int64(suffix), -decimals)),
+		PaymentID: &id,
+		Suffix:    suffix,
+	}, nil
+}
+
+// depositWallet returns the deposit wallet of account, deriving it at the
+// next unused index of mode when the account has none yet.
+func depositWallet(ctx context.Context, q repository.Querier, wallets WalletDeriver, account repository.Account, mode string) (string, int64, error) {
+	if account.DepositWallet != nil && account.DepositWalletIndex != nil {
+		return *account.DepositWallet, *account.DepositWalletIndex, nil
+	}
+	wallet, index, err := AllocateWallet(ctx, q, wallets, mode)
+	if err != nil {
+		return "", 0, err
+	}
+	row, err := q.SetAccountDepositWallet(ctx, repository.SetAccountDepositWalletParams{
+		DepositWallet:      wallet,
+		DepositWalletIndex: index,
+		ID:                 account.ID,
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to set account deposit wallet: %w", err)
+	}
+	return row.DepositWallet, row.DepositWalletIndex, nil
+}
@@ -0,0 +1,139 @@
+package payments
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// newSuffixStore returns a FakeStore holding one amount_suffix account.
+func newSuffixStore(t *testing.T) (*repository.FakeStore, repository.Account) {
+	t.Helper()
+	store, account := newPoolStore(t, 0)
+	strategy := StrategyAmountSuffix
+	account, err := store.ReplaceAccount(context.Background(), repository.ReplaceAccountParams{
+		ID:              account.ID,
+		ClientID:        account.ClientID,
+		Name:            account.Name,
+		AddressStrategy: &strategy,
+	})
+	require.NoError(t, err)
+	return store, account
+}
+
+func newSuffixPayment(account repository.Account) New {
+	p := newPayment(account, false)
+	p.SuffixHold = 10 * time.Minute
+	return p
+}
+
+func TestStrategyFor(t *testing.T) {
+	s, ok := StrategyFor(StrategyUniqueWallet, time.Minute)
+	require.True(t, ok)
+	assert.Equal(t, UniqueWallet{}, s)
+
+	s, ok = StrategyFor(StrategyAmountSuffix, time.Minute)
+	require.True(t, ok)
+	assert.Equal(t, AmountSuffix{Hold: time.Minute}, s)
+
+	s, ok = StrategyFor("", time.Minute)
+	require.True(t, ok)
+	assert.Equal(t, UniqueWallet{}, s, "the default")
+
+	_, ok = StrategyFor("round_robin", time.Minute)
+	assert.False(t, ok)
+}
+
+func TestCreate_AmountSuffix(t *testing.T) {
+	ctx := context.Background()
+	store, account := newSuffixStore(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	first, err := Create(ctx, store, derivedWallets{}, account.ClientID, newSuffixPayment(account), now)
+	require.NoError(t, err)
+	second, err := Create(ctx, store, derivedWallets{}, account.ClientID, newSuffixPayment(account), now)
+	require.NoError(t, err)
+
+	assert.Equal(t, "TWallet0", first.UniqueWallet)
+	assert.Equal(t, "TWallet0", second.UniqueWallet, "payments of the account share its deposit wallet")
+	assert.Nil(t, first.WalletIndex, "a shared wallet is not the payment's own")
+	assert.Equal(t, "5.000001", FormatAmount(repository.NumericToDecimal(first.Amount), "USDT"))
+	assert.Equal(t, "5.000002", FormatAmount(repository.NumericToDecimal(second.Amount), "USDT"))
+
+	reservations := store.AmountSuffixReservations()
+	require.Len(t, reservations, 2)
+	assert.Equal(t, first.ID, reservations[0].PaymentID)
+	assert.Equal(t, int32(1), reservations[0].Suffix)
+	assert.Equal(t, now.Add(time.Hour+10*time.Minute), reservations[0].ExpiresAt.Time,
+		"the suffix is held for the hold after the payment expires")
+
+	updated, err := store.GetAccountByIDAndClientID(ctx, repository.GetAccountByIDAndClientIDParams{ID: account.ID, ClientID: account.ClientID})
+	require.NoError(t, err)
+	assert.Equal(t, "TWallet0", *updated.DepositWallet)
+	assert.Equal(t, int64(0), *updated.DepositWalletIndex)
+
+	assert.JSONEq(t, `{"wallet":"TWallet0","wallet_index":0,"attempt":1,"amount_suffix":2}`, string(store.Logs()[1].RawData))
+
+	third, err := Create(ctx, store, derivedWallets{}, account.ClientID, newPayment(account, false), now)
+	require.NoError(t, err)
+	assert.Equal(t, "TWallet0", third.UniqueWallet, "the deposit wallet is derived once")
+}
+
+func TestCreate_AmountSuffixTooPrecise(t *testing.T) {
+	store, account := newSuffixStore(t)
+	p := newSuffixPayment(account)
+	p.Amount = decimal.RequireFromString("5.0001")
+
+	_, err := Create(context.Background(), store, derivedWallets{}, account.ClientID, p, time.Now())
+	require.ErrorIs(t, err, ErrAmountTooPrecise)
+	assert.Empty(t, store.AmountSuffixReservations())
+
+	p.Amount = decimal.RequireFromString("5.001")
+	payment, err := Create(context.Background(), store, derivedWallets{}, account.ClientID, p, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "5.001001", FormatAmount(repository.NumericToDecimal(payment.Amount), "USDT"))
+}
+
+func TestCreate_AmountSuffixExhaustedAndReleasedOnExpiry(t *testing.T) {
+	ctx := context.Background()
+	store, account := newSuffixStore(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	p := newSuffixPayment(account)
+
+	for range MaxAmountSuffix {
+		_, err := Create(ctx, store, derivedWallets{}, account.ClientID, p, now)
+		require.NoError(t, err)
+	}
+	_, err := Create(ctx, store, derivedWallets{}, account.ClientID, p, now)
+	require.ErrorIs(t, err, ErrSuffixesExhausted)
+	assert.Len(t, store.AmountSuffixReservations(), MaxAmountSuffix)
+
+	expired := now.Add(p.Expiry)
+	_, err = Create(ctx, store, derivedWallets{}, account.ClientID, p, expired)
+	require.ErrorIs(t, err, ErrSuffixesExhausted, "suffixes are held for the hold after their payments expire")
+
+	released := expired.Add(p.SuffixHold)
+	payment, err := Create(ctx, store, derivedWallets{}, account.ClientID, p, released)
+	require.NoError(t, err)
+	assert.Equal(t, "5.000001", FormatAmount(repository.NumericToDecimal(payment.Amount), "USDT"),
+		"the lowest released suffix is reused")
+
+	reservations := store.AmountSuffixReservations()
+	assert.Len(t, reservations, MaxAmountSuffix, "a released suffix is reserved again in place")
+	assert.Equal(t, payment.ID, reservations[0].PaymentID)
+}
+
+func TestCreate_UniqueWalletIgnoresSuffixes(t *testing.T) {
+	store, account := newPoolStore(t, 0)
+
+	payment, err := Create(context.Background(), store, derivedWallets{}, account.ClientID, newSuffixPayment(account), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "5.000000", FormatAmount(repository.NumericToDecimal(payment.Amount), "USDT"))
+	assert.NotNil(t, payment.WalletIndex)
+	assert.Empty(t, store.AmountSuffixReservations())
+}
@@ -0,0 +1,103 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+// Cache is a RateProvider that serves rates from another provider, fetching
+// each pair at most once per TTL.
+//
+// When a fetch fails, the cached rate is served for as long as it is younger
+// than the max age; after that GetRate fails with ErrStaleRate, so invoices
+// are never priced at a rate older than the max age. Age is measured from
+// when the rate was quoted, not when it was fetched.
+type Cache struct {
+	provider RateProvider
+	ttl      time.Duration
+	maxAge   time.Duration
+	logger   *slog.Logger
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[pair]entry
+}
+
+type pair struct{ base, quote string }
+
+type entry struct {
+	rate    decimal.Decimal
+	at      time.Time
+	fetched time.Time
+}
+
+// CacheOption customises a Cache.
+type CacheOption func(*Cache)
+
+// WithLogger replaces slog.Default.
+func WithLogger(l *slog.Logger) CacheOption {
+	return func(c *Cache) { c.logger = l }
+}
+
+// NewCache wraps provider with the cacheTTL and maxAge of cfg.
+func NewCache(provider RateProvider, cfg config.RatesConfig, opts ...CacheOption) *Cache {
+	c := &Cache{
+		provider: provider,
+		ttl:      cfg.CacheTTL.Std(),
+		maxAge:   cfg.MaxAge.Std(),
+		logger:   slog.Default(),
+		now:      time.Now,
+		entries:  make(map[pair]entry),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// GetRate returns the cached rate while it is fresh and fetches it otherwise.
+// Concurrent callers that miss the cache may each fetch; the last answer
+// wins.
+func (c *Cache) GetRate(ctx context.Context, base, quote string) (decimal.Decimal, time.Time, error) {
+	key := pair{strings.ToUpper(base), strings.ToUpper(quote)}
+	now := c.now()
+
+	c.mu.Lock()
+	cached, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && now.Sub(cached.fetched) < c.ttl {
+		return cached.rate, cached.at, nil
+	}
+
+	rate, at, err := c.provider.GetRate(ctx, key.base, key.quote)
+	if err == nil && now.Sub(at) > c.maxAge {
+		err = fmt.Errorf("%w: %s/%s quoted at %s", ErrStaleRate, key.base, key.quote, at.Format(time.RFC3339))
+	}
+	if err == nil {
+		c.mu.Lock()
+		c.entries[key] = entry{rate: rate, at: at, fetched: now}
+		c.mu.Unlock()
+		return rate, at, nil
+	}
+	if errors.Is(err, ErrUnsupportedPair) || ctx.Err() != nil {
+		return decimal.Decimal{}, time.Time{}, err
+	}
+
+	if ok && now.Sub(cached.at) <= c.maxAge {
+		c.logger.Warn("rate fetch failed, using cached rate",
+			"base", key.base, "quote", key.quote, "age", now.Sub(cached.at), "error", err)
+		return cached.rate, cached.at, nil
+	}
+	if !errors.Is(err, ErrStaleRate) {
+		err = fmt.Errorf("%w: no %s/%s rate within %s: %w", ErrStaleRate, key.base, key.quote, c.maxAge, err)
+	}
+	return decimal.Decimal{}, time.Time{}, err
+}
@@ -0,0 +1,168 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+var t0 = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// stubProvider answers every GetRate with rate quoted at at, or err.
+type stubProvider struct {
+	rate  decimal.Decimal
+	at    time.Time
+	err   error
+	calls []string
+}
+
+func (s *stubProvider) GetRate(_ context.Context, base, quote string) (decimal.Decimal, time.Time, error) {
+	s.calls = append(s.calls, base+"/"+quote)
+	if s.err != nil {
+		return decimal.Decimal{}, time.Time{}, s.err
+	}
+	return s.rate, s.at, nil
+}
+
+// newTestCache caches stub for a minute with a ten minute max age, on a
+// clock the test moves through *now.
+func newTestCache(stub *stubProvider) (*Cache, *time.Time) {
+	now := t0
+	c := NewCache(stub, config.RatesConfig{
+		CacheTTL: config.Duration(time.Minute),
+		MaxAge:   config.Duration(10 * time.Minute),
+	}, WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestCache_ServesWithinTTL(t *testing.T) {
+	stub := &stubProvider{rate: decimal.RequireFromString("0.25"), at: t0}
+	c, now := newTestCache(stub)
+
+	for range 3 {
+		rate, at, err := c.GetRate(context.Background(), "trx", "usd")
+		require.NoError(t, err)
+		assert.Equal(t, "0.25", rate.String())
+		assert.Equal(t, t0, at)
+		*now = now.Add(20 * time.Second)
+	}
+
+	assert.Equal(t, []string{"TRX/USD"}, stub.calls, "symbols are case-insensitive and fetched once")
+}
+
+func TestCache_RefetchesAfterTTL(t *testing.T) {
+	stub := &stubProvider{rate: decimal.RequireFromString("0.25"), at: t0}
+	c, now := newTestCache(stub)
+	_, _, err := c.GetRate(context.Background(), "TRX", "USD")
+	require.NoError(t, err)
+
+	*now = t0.Add(time.Minute)
+	stub.rate, stub.at = decimal.RequireFromString("0.26"), *now
+	rate, at, err := c.GetRate(context.Background(), "TRX", "USD")
+
+	require.NoError(t, err)
+	assert.Equal(t, "0.26", rate.String())
+	assert.Equal(t, *now, at)
+	assert.Len(t, stub.calls, 2)
+}
+
+func TestCache_PairsCachedSeparately(t *testing.T) {
+	stub := &stubProvider{rate: decimal.RequireFromString("0.25"), at: t0}
+	c, _ := newTestCache(stub)
+
+	for _, quote := range []string{"USD", "EUR", "USD"} {
+		_, _, err := c.GetRate(context.Background(), "TRX", quote)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{"TRX/USD", "TRX/EUR"}, stub.calls)
+}
+
+func TestCache_FallsBackWithinMaxAge(t *testing.T) {
+	stub := &stubProvider{rate: decimal.RequireFromString("0.25"), at: t0}
+	c, now := newTestCache(stub)
+	_, _, err := c.GetRate(context.Background(), "TRX", "USD")
+	require.NoError(t, err)
+
+	stub.err = errors.New("coingecko returned 503")
+	*now = t0.Add(10 * time.Minute)
+	rate, at, err := c.GetRate(context.Background(), "TRX", "USD")
+
+	require.NoError(t, err)
+	assert.Equal(t, "0.25", rate.String())
+	assert.Equal(t, t0, at, "the fallback keeps its original quote time")
+	assert.Len(t, stub.calls, 2, "a failing provider is still asked every time")
+}
+
+func TestCache_FailsPastMaxAge(t *testing.T) {
+	stub := &stubProvider{rate: decimal.RequireFromString("0.25"), at: t0}
+	c, now := newTestCache(stub)
+	_, _, err := c.GetRate(context.Background(), "TRX", "USD")
+	require.NoError(t, err)
+
+	stub.err = errors.New("coingecko returned 503")
+	*now = t0.Add(10*time.Minute + time.Second)
+	_, _, err = c.GetRate(context.Background(), "TRX", "USD")
+
+	assert.ErrorIs(t, err, ErrStaleRate)
+	assert.ErrorContains(t, err, "returned 503")
+}
+
+func TestCache_FailsWithoutCachedRate(t *testing.T) {
+	stub := &stubProvider{err: errors.New("connection refused")}
+	c, _ := newTestCache(stub)
+
+	_, _, err := c.GetRate(context.Background(), "TRX", "USD")
+
+	assert.ErrorIs(t, err, ErrStaleRate)
+	assert.ErrorContains(t, err, "connection refused")
+}
+
+func TestCache_RejectsStaleQuote(t *testing.T) {
+	// The provider answers, but with a price it has not updated in an hour.
+	stub := &stubProvider{rate: decimal.RequireFromString("0.25"), at: t0.Add(-time.Hour)}
+	c, _ := newTestCache(stub)
+
+	_, _, err := c.GetRate(context.Background(), "TRX", "USD")
+
+	assert.ErrorIs(t, err, ErrStaleRate)
+	assert.Empty(t, c.entries, "a stale quote is not cached")
+}
+
+func TestCache_StaleQuoteFallsBackToFresherCache(t *testing.T) {
+	stub := &stubProvider{rate: decimal.RequireFromString("0.25"), at: t0}
+	c, now := newTestCache(stub)
+	_, _, err := c.GetRate(context.Background(), "TRX", "USD")
+	require.NoError(t, err)
+
+	*now = t0.Add(5 * time.Minute)
+	stub.rate, stub.at = decimal.RequireFromString("0.20"), t0.Add(-time.Hour)
+	rate, at, err := c.GetRate(context.Background(), "TRX", "USD")
+
+	require.NoError(t, err)
+	assert.Equal(t, "0.25", rate.String())
+	assert.Equal(t, t0, at)
+}
+
+func TestCache_UnsupportedPairNotMasked(t *testing.T) {
+	stub := &stubProvider{rate: decimal.RequireFromString("0.25"), at: t0}
+	c, now := newTestCache(stub)
+	_, _, err := c.GetRate(context.Background(), "TRX", "USD")
+	require.NoError(t, err)
+
+	stub.err = ErrUnsupportedPair
+	*now = t0.Add(2 * time.Minute)
+	_, _, err = c.GetRate(context.Background(), "TRX", "USD")
+
+	assert.ErrorIs(t, err, ErrUnsupportedPair)
+	assert.NotErrorIs(t, err, ErrStaleRate)
+}
@@ -0,0 +1,126 @@
+package rates
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+// CoinGecko takes a demo key and a pro key in different headers; pro keys
+// only work against the pro-api host.
+const (
+	demoKeyHeader = "x-cg-demo-api-key"
+	proKeyHeader  = "x-cg-pro-api-key"
+)
+
+// coinIDs maps the gateway's token symbols to CoinGecko coin ids.
+var coinIDs = map[string]string{
+	"TRX":  "tron",
+	"USDT": "tether",
+}
+
+// CoinGecko is a RateProvider backed by the CoinGecko simple price API. It
+// does not cache; wrap it in a Cache.
+type CoinGecko struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+}
+
+// CoinGeckoOption customises a CoinGecko provider.
+type CoinGeckoOption func(*CoinGecko)
+
+// WithHTTPClient replaces the default HTTP client, e.g. in tests.
+func WithHTTPClient(hc *http.Client) CoinGeckoOption {
+	return func(c *CoinGecko) { c.httpClient = hc }
+}
+
+// NewCoinGecko builds a provider for the API at cfg.BaseURL. apiKey may be
+// empty.
+func NewCoinGecko(cfg config.RatesConfig, apiKey string, opts ...CoinGeckoOption) *CoinGecko {
+	c := &CoinGecko{
+		httpClient: &http.Client{Timeout: cfg.RequestTimeout.Std()},
+		baseURL:    strings.TrimRight(cfg.BaseURL, "/"),
+		apiKey:     apiKey,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// GetRate returns CoinGecko's price of base in quote and the time CoinGecko
+// last updated it.
+func (c *CoinGecko) GetRate(ctx context.Context, base, quote string) (decimal.Decimal, time.Time, error) {
+	id, ok := coinIDs[strings.ToUpper(base)]
+	if !ok {
+		return decimal.Decimal{}, time.Time{}, fmt.Errorf("%w: %s/%s", ErrUnsupportedPair, base, quote)
+	}
+	vs := strings.ToLower(quote)
+
+	q := url.Values{}
+	q.Set("ids", id)
+	q.Set("vs_currencies", vs)
+	q.Set("include_last_updated_at", "true")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/simple/price?"+q.Encode(), nil)
+	if err != nil {
+		return decimal.Decimal{}, time.Time{}, fmt.Errorf("failed to build rate request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set(c.keyHeader(), c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return decimal.Decimal{}, time.Time{}, fmt.Errorf("failed to fetch %s/%s rate: %w", base, quote, err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return decimal.Decimal{}, time.Time{}, fmt.Errorf("failed to read rate response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return decimal.Decimal{}, time.Time{}, fmt.Errorf("coingecko returned %d: %s", resp.StatusCode, bytes.TrimSpace(raw))
+	}
+
+	// Numbers are decoded as json.Number so the rate keeps every digit
+	// CoinGecko sent.
+	var prices map[string]map[string]json.Number
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&prices); err != nil {
+		return decimal.Decimal{}, time.Time{}, fmt.Errorf("failed to decode rate response: %w", err)
+	}
+	price, ok := prices[id][vs]
+	if !ok {
+		// CoinGecko leaves out currencies it does not know.
+		return decimal.Decimal{}, time.Time{}, fmt.Errorf("%w: %s/%s", ErrUnsupportedPair, base, quote)
+	}
+	rate, err := decimal.NewFromString(price.String())
+	if err != nil {
+		return decimal.Decimal{}, time.Time{}, fmt.Errorf("failed to parse %s/%s rate %q: %w", base, quote, price, err)
+	}
+	updated, err := prices[id]["last_updated_at"].Int64()
+	if err != nil {
+		return decimal.Decimal{}, time.Time{}, fmt.Errorf("failed to parse %s/%s rate timestamp: %w", base, quote, err)
+	}
+	return rate, time.Unix(updated, 0).UTC(), nil
+}
+
+func (c *CoinGecko) keyHeader() string {
+	if u, err := url.Parse(c.baseURL); err == nil && strings.HasPrefix(u.Host, "pro-api.") {
+		return proKeyHeader
+	}
+	return demoKeyHeader
+}
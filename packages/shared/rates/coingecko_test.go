@@ -0,0 +1,99 @@
+package rates
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+// newCoinGecko starts a fake CoinGecko answering status and body, and returns
+// a provider for it and the last request it received.
+func newCoinGecko(t *testing.T, apiKey string, status int, body string) (*CoinGecko, **http.Request) {
+	t.Helper()
+	var last *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last = r
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := config.RatesConfig{BaseURL: srv.URL + "/", RequestTimeout: config.Duration(time.Second)}
+	return NewCoinGecko(cfg, apiKey, WithHTTPClient(srv.Client())), &last
+}
+
+func TestCoinGecko_GetRate(t *testing.T) {
+	cg, last := newCoinGecko(t, "demo-key", http.StatusOK,
+		`{"tron":{"usd":0.24351234567890123456,"last_updated_at":1772366400}}`)
+
+	rate, at, err := cg.GetRate(context.Background(), "TRX", "USD")
+
+	require.NoError(t, err)
+	assert.Equal(t, "0.24351234567890123456", rate.String(), "no float rounding")
+	assert.Equal(t, time.Unix(1772366400, 0).UTC(), at)
+
+	req := *last
+	assert.Equal(t, "/simple/price", req.URL.Path)
+	assert.Equal(t, "tron", req.URL.Query().Get("ids"))
+	assert.Equal(t, "usd", req.URL.Query().Get("vs_currencies"))
+	assert.Equal(t, "true", req.URL.Query().Get("include_last_updated_at"))
+	assert.Equal(t, "demo-key", req.Header.Get(demoKeyHeader))
+}
+
+func TestCoinGecko_USDT(t *testing.T) {
+	cg, last := newCoinGecko(t, "", http.StatusOK, `{"tether":{"eur":0.92,"last_updated_at":1772366400}}`)
+
+	rate, _, err := cg.GetRate(context.Background(), "usdt", "eur")
+
+	require.NoError(t, err)
+	assert.Equal(t, "0.92", rate.String())
+	assert.Equal(t, "tether", (*last).URL.Query().Get("ids"))
+	assert.Empty(t, (*last).Header.Get(demoKeyHeader), "no key, no header")
+}
+
+func TestCoinGecko_UnsupportedToken(t *testing.T) {
+	cg, last := newCoinGecko(t, "", http.StatusOK, `{}`)
+
+	_, _, err := cg.GetRate(context.Background(), "BTC", "USD")
+
+	assert.ErrorIs(t, err, ErrUnsupportedPair)
+	assert.Nil(t, *last, "nothing is requested")
+}
+
+func TestCoinGecko_UnsupportedCurrency(t *testing.T) {
+	cg, _ := newCoinGecko(t, "", http.StatusOK, `{"tron":{"last_updated_at":1772366400}}`)
+
+	_, _, err := cg.GetRate(context.Background(), "TRX", "XYZ")
+
+	assert.ErrorIs(t, err, ErrUnsupportedPair)
+}
+
+func TestCoinGecko_HTTPError(t *testing.T) {
+	cg, _ := newCoinGecko(t, "", http.StatusTooManyRequests, `{"status":{"error_code":429}}`)
+
+	_, _, err := cg.GetRate(context.Background(), "TRX", "USD")
+
+	assert.ErrorContains(t, err, "coingecko returned 429")
+}
+
+func TestCoinGecko_MalformedResponse(t *testing.T) {
+	cg, _ := newCoinGecko(t, "", http.StatusOK, `{"tron":{"usd":"n/a","last_updated_at":1772366400}}`)
+
+	_, _, err := cg.GetRate(context.Background(), "TRX", "USD")
+
+	assert.Error(t, err)
+}
+
+func TestCoinGecko_ProKeyHeader(t *testing.T) {
+	cg := NewCoinGecko(config.RatesConfig{BaseURL: "https://pro-api.coingecko.com/api/v3"}, "pro-key")
+	assert.Equal(t, proKeyHeader, cg.keyHeader())
+
+	cg = NewCoinGecko(config.RatesConfig{BaseURL: config.DefaultCoinGeckoURL}, "demo-key")
+	assert.Equal(t, demoKeyHeader, cg.keyHeader())
+}
@@ -0,0 +1,92 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// AmountDecimals is the precision invoice amounts are rounded to. TRX (sun)
+// and TRC-20 USDT both have 6 decimals.
+const AmountDecimals = 6
+
+// ErrInvalidAmount is returned for a fiat amount that cannot be invoiced.
+var ErrInvalidAmount = errors.New("invalid invoice amount")
+
+// Conversion is a fiat invoice amount priced in a token. Every field is
+// stored on the payment, so the amount can be audited later.
+type Conversion struct {
+	FiatAmount decimal.Decimal
+	Currency   string
+	Token      string
+	// Amount is the token amount the payer is asked for.
+	Amount decimal.Decimal
+	// Rate is the price of one Token in Currency, quoted at RateAt.
+	Rate   decimal.Decimal
+	RateAt time.Time
+}
+
+// ConvertInvoiceAmount prices fiatAmount of currency in token at the rate
+// provider returns.
+//
+// The token amount is fiatAmount / rate rounded to AmountDecimals with
+// bankers rounding (round half to even): an exact half goes to the even
+// neighbour, so over many invoices the rounding favours neither the merchant
+// nor the payer. The quotient is rounded exactly, not from a truncated
+// intermediate.
+//
+// A fiat amount that is not positive, or too small to be worth a single
+// unit of the token, fails with ErrInvalidAmount. A rate error, including
+// ErrStaleRate, is returned wrapped and must fail invoice creation.
+func ConvertInvoiceAmount(ctx context.Context, provider RateProvider, fiatAmount decimal.Decimal, currency, token string) (Conversion, error) {
+	currency, token = strings.ToUpper(currency), strings.ToUpper(token)
+	if !fiatAmount.IsPositive() {
+		return Conversion{}, fmt.Errorf("%w: %s %s is not positive", ErrInvalidAmount, fiatAmount, currency)
+	}
+
+	rate, at, err := provider.GetRate(ctx, token, currency)
+	if err != nil {
+		return Conversion{}, fmt.Errorf("failed to get %s/%s rate: %w", token, currency, err)
+	}
+	if !rate.IsPositive() {
+		return Conversion{}, fmt.Errorf("failed to get %s/%s rate: provider returned %s", token, currency, rate)
+	}
+
+	amount := divRoundBank(fiatAmount, rate, AmountDecimals)
+	if !amount.IsPositive() {
+		return Conversion{}, fmt.Errorf("%w: %s %s is less than the smallest %s amount", ErrInvalidAmount, fiatAmount, currency, token)
+	}
+
+	return Conversion{
+		FiatAmount: fiatAmount,
+		Currency:   currency,
+		Token:      token,
+		Amount:     amount,
+		Rate:       rate,
+		RateAt:     at,
+	}, nil
+}
+
+// divRoundBank returns n / d rounded half to even at places decimals, for
+// positive n and d. decimal.Div rounds its quotient half up at a fixed
+// precision first, which can turn a value just above or below a half into an
+// exact half; the remainder decides here instead.
+func divRoundBank(n, d decimal.Decimal, places int32) decimal.Decimal {
+	q, r := n.QuoRem(d, places)
+	unit := decimal.New(1, -places)
+	// The discarded fraction is r/d units of the last place; compare 2r to
+	// d·unit to tell whether it is below, at or above a half.
+	switch r.Mul(decimal.NewFromInt(2)).Cmp(d.Mul(unit)) {
+	case 1:
+		return q.Add(unit)
+	case 0:
+		if q.Shift(places).BigInt().Bit(0) == 1 {
+			return q.Add(unit)
+		}
+	}
+	return q
+}
@@ -0,0 +1,108 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertInvoiceAmount(t *testing.T) {
+	stub := &stubProvider{rate: decimal.RequireFromString("0.2435"), at: t0}
+
+	conv, err := ConvertInvoiceAmount(context.Background(), stub, decimal.RequireFromString("25.00"), "usd", "trx")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"TRX/USD"}, stub.calls)
+	assert.Equal(t, Conversion{
+		FiatAmount: decimal.RequireFromString("25.00"),
+		Currency:   "USD",
+		Token:      "TRX",
+		Amount:     decimal.RequireFromString("102.669405"),
+		Rate:       decimal.RequireFromString("0.2435"),
+		RateAt:     t0,
+	}, conv)
+}
+
+func TestConvertInvoiceAmount_BankersRounding(t *testing.T) {
+	testCases := []struct {
+		name string
+		fiat string
+		rate string
+		want string
+	}{
+		{"exact", "10", "0.5", "20"},
+		{"half rounds down to even", "0.0000025", "1", "0.000002"},
+		{"half rounds up to even", "0.0000035", "1", "0.000004"},
+		{"just above half rounds up", "0.00000250000001", "1", "0.000003"},
+		{"just below half rounds down", "0.00000349999999", "1", "0.000003"},
+		// 1/3 = 0.333333|333..., below half.
+		{"repeating quotient", "1", "3", "0.333333"},
+		// 2/3 = 0.666666|666..., above half.
+		{"repeating quotient above half", "2", "3", "0.666667"},
+		// 0.0000005 + 1e-22 is above half, though a quotient rounded to 16
+		// places first would look like an exact half and round to 0.
+		{"half only after intermediate rounding", "0.0000010000000000000002", "2", "0.000001"},
+		{"half at a carry", "0.9999995", "1", "1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := &stubProvider{rate: decimal.RequireFromString(tc.rate), at: t0}
+
+			conv, err := ConvertInvoiceAmount(context.Background(), stub, decimal.RequireFromString(tc.fiat), "USD", "USDT")
+
+			require.NoError(t, err)
+			assert.True(t, decimal.RequireFromString(tc.want).Equal(conv.Amount), "got %s, want %s", conv.Amount, tc.want)
+			assert.LessOrEqual(t, -conv.Amount.Exponent(), int32(AmountDecimals))
+		})
+	}
+}
+
+func TestConvertInvoiceAmount_InvalidAmount(t *testing.T) {
+	testCases := []struct {
+		name string
+		fiat string
+	}{
+		{"zero", "0"},
+		{"negative", "-5"},
+		// 0.0000005 / 1 is an exact half and rounds to the even 0.
+		{"rounds to zero", "0.0000005"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := &stubProvider{rate: decimal.NewFromInt(1), at: t0}
+
+			_, err := ConvertInvoiceAmount(context.Background(), stub, decimal.RequireFromString(tc.fiat), "USD", "USDT")
+
+			assert.ErrorIs(t, err, ErrInvalidAmount)
+		})
+	}
+}
+
+func TestConvertInvoiceAmount_RateErrors(t *testing.T) {
+	stale := &stubProvider{err: ErrStaleRate}
+	_, err := ConvertInvoiceAmount(context.Background(), stale, decimal.NewFromInt(10), "USD", "TRX")
+	assert.ErrorIs(t, err, ErrStaleRate)
+
+	failing := &stubProvider{err: errors.New("boom")}
+	_, err = ConvertInvoiceAmount(context.Background(), failing, decimal.NewFromInt(10), "USD", "TRX")
+	assert.ErrorContains(t, err, "failed to get TRX/USD rate: boom")
+
+	zero := &stubProvider{rate: decimal.Zero, at: t0}
+	_, err = ConvertInvoiceAmount(context.Background(), zero, decimal.NewFromInt(10), "USD", "TRX")
+	assert.ErrorContains(t, err, "provider returned 0")
+}
+
+func TestConvertInvoiceAmount_NotCalledForInvalidAmount(t *testing.T) {
+	stub := &stubProvider{rate: decimal.NewFromInt(1), at: t0}
+
+	_, err := ConvertInvoiceAmount(context.Background(), stub, decimal.Zero, "USD", "TRX")
+
+	require.Error(t, err)
+	assert.Empty(t, stub.calls)
+}
@@ -0,0 +1,27 @@
+// Package rates prices the gateway's tokens in fiat currencies, so an invoice
+// can be issued for a fiat amount and paid in TRX or USDT.
+package rates
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrUnsupportedPair is returned for a token or currency the provider
+	// does not price.
+	ErrUnsupportedPair = errors.New("unsupported currency pair")
+	// ErrStaleRate is returned when no rate younger than the configured max
+	// age is available.
+	ErrStaleRate = errors.New("exchange rate is stale")
+)
+
+// RateProvider prices one unit of base, a token symbol such as TRX, in the
+// fiat currency quote, such as USD. It returns the rate and when the rate was
+// quoted.
+type RateProvider interface {
+	GetRate(ctx context.Context, base, quote string) (decimal.Decimal, time.Time, error)
+}
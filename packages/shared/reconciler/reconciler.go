@@ -0,0 +1,444 @@
+// Package reconciler checks what the database says confirmed payments
+// received and swept against the chain, and reports where the two disagree.
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+)
+
+// Mismatch kinds.
+const (
+	// KindMissingTransfer is a recorded deposit the chain does not show as
+	// recorded, or a confirmed payment without any deposit.
+	KindMissingTransfer = "MISSING_TRANSFER"
+	// KindUnexplainedBalance is a deposit wallet holding more than was
+	// deposited and not swept.
+	KindUnexplainedBalance = "UNEXPLAINED_BALANCE"
+	// KindMissingBalance is a deposit wallet that was never swept holding
+	// less than was deposited.
+	KindMissingBalance = "MISSING_BALANCE"
+	// KindSweepShortfall is a recorded sweep the chain does not show as
+	// recorded, or a swept wallet that lost more than its sweeps moved.
+	KindSweepShortfall = "SWEEP_SHORTFALL"
+)
+
+// Kinds lists every mismatch kind.
+var Kinds = []string{KindMissingTransfer, KindUnexplainedBalance, KindMissingBalance, KindSweepShortfall}
+
+// Transaction kinds, as stored in transactions.kind.
+const (
+	kindDeposit = "DEPOSIT"
+	kindSweep   = "SWEEP"
+)
+
+// tokenDecimals is shared by TRX (sun) and USDT.
+const tokenDecimals = 6
+
+// Chain is the subset of *tron.Client the reconciler reads balances and
+// transactions through.
+type Chain interface {
+	GetTRXBalance(ctx context.Context, address string) (int64, error)
+	GetTRC20Balance(ctx context.Context, contractAddress, holderAddress string) (*big.Int, error)
+	GetTransactionByID(ctx context.Context, txID string) (*tron.Transaction, error)
+	GetTransactionInfoByID(ctx context.Context, txID string) (*tron.TransactionInfo, error)
+}
+
+// Store is the subset of *repository.Store the reconciler reads and writes
+// its reports through.
+type Store interface {
+	ListReconciliationPayments(ctx context.Context, arg repository.ListReconciliationPaymentsParams) ([]repository.ListReconciliationPaymentsRow, error)
+	ListPaymentTransactions(ctx context.Context, paymentID uuid.UUID) ([]repository.Transaction, error)
+	ListOpenSweeps(ctx context.Context) ([]repository.Sweep, error)
+	GetWalletFlows(ctx context.Context, arg repository.GetWalletFlowsParams) (repository.GetWalletFlowsRow, error)
+	ExecTx(ctx context.Context, fn func(repository.Querier) error) error
+}
+
+// Metrics records what reconciliation finds. *metrics.Metrics implements it.
+type Metrics interface {
+	// ReconcileMismatches records how many mismatches of kind the last
+	// reconciliation found.
+	ReconcileMismatches(kind string, n int)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ReconcileMismatches(string, int) {}
+
+// Reconciler checks every payment confirmed within a window:
+//
+//   - each deposit recorded for it must be on chain, successful, between the
+//     recorded addresses and for the recorded amount;
+//   - each sweep recorded for it must be too;
+//   - each deposit wallet must hold what was deposited less what was swept,
+//     give or take the token's tolerance. The deposit wallet an
+//     amount_suffix account's payments share holds what was deposited to and
+//     swept from it for all of them, and is checked once per run.
+//
+// Wallets with a sweep still in flight are left for a later run, since their
+// balance is already gone while the sweep is not yet recorded.
+type Reconciler struct {
+	chain   Chain
+	store   Store
+	metrics Metrics
+	logger  *slog.Logger
+
+	interval     time.Duration
+	window       time.Duration
+	tolerance    func(token string) decimal.Decimal
+	usdtContract string
+	now          func() time.Time
+}
+
+// Option customises a Reconciler.
+type Option func(*Reconciler)
+
+// WithLogger replaces slog.Default.
+func WithLogger(l *slog.Logger) Option {
+	return func(r *Reconciler) { r.logger = l }
+}
+
+// WithMetrics records the mismatches every run finds in m.
+func WithMetrics(m Metrics) Option {
+	return func(r *Reconciler) { r.metrics = m }
+}
+
+// New builds a reconciler using the reconciler and tron sections of cfg.
+func New(chain Chain, store Store, cfg *config.Config, opts ...Option) *Reconciler {
+	r := &Reconciler{
+		chain:        chain,
+		store:        store,
+		metrics:      nopMetrics{},
+		logger:       slog.Default(),
+		interval:     cfg.Reconciler.Interval.Std(),
+		window:       cfg.Reconciler.Window.Std(),
+		tolerance:    cfg.Reconciler.ReconcileTolerance,
+		usdtContract: cfg.Tron.USDTContract,
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run reconciles the configured window every interval until ctx is done.
+func (r *Reconciler) Run(ctx context.Context) error {
+	r.logger.Info("reconciler started", "interval", r.interval, "window", r.window)
+	for {
+		if _, err := r.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			r.logger.ErrorContext(ctx, "reconciliation failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			r.logger.Info("reconciler stopped")
+			return nil
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+// RunOnce reconciles the payments confirmed within the configured window
+// before now.
+func (r *Reconciler) RunOnce(ctx context.Context) (*Report, error) {
+	until := r.now().UTC()
+	return r.Reconcile(ctx, until.Add(-r.window), until)
+}
+
+// Reconcile checks the payments confirmed in [since, until), saves the
+// report and records its mismatch counts. A payment that cannot be checked
+// fails the whole run rather than leave a report claiming it was.
+//
+// Its chain calls are non-critical, so a run fails with
+// tron.ErrBudgetExhausted once the daily request budget is spent.
+func (r *Reconciler) Reconcile(ctx context.Context, since, until time.Time) (*Report, error) {
+	ctx = tron.NonCritical(ctx)
+	payments, err := r.store.ListReconciliationPayments(ctx, repository.ListReconciliationPaymentsParams{
+		Since: pgtype.Timestamptz{Time: since, Valid: true},
+		Until: pgtype.Timestamptz{Time: until, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list confirmed payments: %w", err)
+	}
+	sweeps, err := r.store.ListOpenSweeps(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open sweeps: %w", err)
+	}
+	inFlight := make(map[holding]bool, len(sweeps))
+	for _, sw := range sweeps {
+		inFlight[holding{wallet: sw.FromAddress, token: sw.Token}] = true
+	}
+
+	report := &Report{WindowStart: since, WindowEnd: until, PaymentsChecked: len(payments)}
+	shared := make(map[holding]bool)
+	for _, p := range payments {
+		found, err := r.payment(ctx, p, inFlight, shared)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reconcile payment %s: %w", p.ID, err)
+		}
+		report.Mismatches = append(report.Mismatches, found...)
+	}
+
+	if err := r.save(ctx, report); err != nil {
+		return nil, err
+	}
+	for _, kind := range Kinds {
+		r.metrics.ReconcileMismatches(kind, report.Count(kind))
+	}
+	for _, m := range report.Mismatches {
+		r.logger.Warn("reconciliation mismatch", "report_id", report.ID, "payment_id", m.PaymentID, "kind", m.Kind,
+			"wallet", m.Wallet, "token", m.Token, "tx_hash", m.TxHash, "expected", m.Expected.StringFixed(tokenDecimals),
+			"actual", m.Actual.StringFixed(tokenDecimals), "detail", m.Detail)
+	}
+	r.logger.Info("reconciliation finished", "report_id", report.ID, "since", since, "until", until,
+		"payments", report.PaymentsChecked, "mismatches", len(report.Mismatches))
+	return report, nil
+}
+
+// holding is a token balance of a deposit wallet.
+type holding struct {
+	wallet string
+	token  string
+}
+
+// flows is what the database says moved through a holding.
+type flows struct {
+	deposited decimal.Decimal
+	swept     decimal.Decimal
+}
+
+// payment checks the transactions and wallets of p. shared holds the shared
+// deposit wallets already checked this run.
+func (r *Reconciler) payment(ctx context.Context, p repository.ListReconciliationPaymentsRow, inFlight, shared map[holding]bool) ([]Mismatch, error) {
+	txs, err := r.store.ListPaymentTransactions(ctx, p.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+
+	var found []Mismatch
+	held := make(map[holding]flows)
+	for _, tx := range txs {
+		amount := repository.NumericToDecimal(tx.Amount)
+		var kind string
+		var h holding
+		switch tx.Kind {
+		case kindDeposit:
+			kind, h = KindMissingTransfer, holding{wallet: tx.ToAddress, token: tx.Token}
+			f := held[h]
+			f.deposited = f.deposited.Add(amount)
+			held[h] = f
+		case kindSweep:
+			kind, h = KindSweepShortfall, holding{wallet: tx.FromAddress, token: tx.Token}
+			f := held[h]
+			f.swept = f.swept.Add(amount)
+			held[h] = f
+		default:
+			continue
+		}
+
+		actual, problem, err := r.verify(ctx, tx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify %s: %w", tx.TxHash, err)
+		}
+		if problem != "" {
+			found = append(found, Mismatch{
+				PaymentID: p.ID, Kind: kind, Wallet: h.wallet, Token: tx.Token, TxHash: tx.TxHash,
+				Expected: amount, Actual: actual, Detail: problem,
+			})
+		}
+	}
+	if len(held) == 0 {
+		found = append(found, Mismatch{
+			PaymentID: p.ID, Kind: KindMissingTransfer, Wallet: p.UniqueWallet, Token: p.Token,
+			Expected: repository.NumericToDecimal(p.Amount), Actual: decimal.Zero,
+			Detail: "confirmed payment has no recorded deposit",
+		})
+	}
+
+	holdings := make([]holding, 0, len(held))
+	for h := range held {
+		holdings = append(holdings, h)
+	}
+	sort.Slice(holdings, func(i, j int) bool {
+		return holdings[i].wallet < holdings[j].wallet || holdings[i].wallet == holdings[j].wallet && holdings[i].token < holdings[j].token
+	})
+	for _, h := range holdings {
+		if inFlight[h] {
+			r.logger.Debug("sweep in flight, balance left for the next run", "payment_id", p.ID, "wallet", h.wallet, "token", h.token)
+			continue
+		}
+		f := held[h]
+		if p.SharedWallet && h.wallet == p.UniqueWallet {
+			if shared[h] {
+				continue
+			}
+			shared[h] = true
+			row, err := r.store.GetWalletFlows(ctx, repository.GetWalletFlowsParams{Wallet: h.wallet, Token: h.token})
+			if err != nil {
+				return nil, fmt.Errorf("failed to sum the transactions of %s: %w", h.wallet, err)
+			}
+			f = flows{deposited: repository.NumericToDecimal(row.Deposited), swept: repository.NumericToDecimal(row.Swept)}
+		}
+		m, err := r.balance(ctx, h, f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check the balance of %s: %w", h.wallet, err)
+		}
+		if m != nil {
+			m.PaymentID = p.ID
+			found = append(found, *m)
+		}
+	}
+	return found, nil
+}
+
+// balance compares the on-chain balance of h with what the database says is
+// left in it.
+func (r *Reconciler) balance(ctx context.Context, h holding, f flows) (*Mismatch, error) {
+	var actual decimal.Decimal
+	switch h.token {
+	case config.TokenTRX:
+		sun, err := r.chain.GetTRXBalance(ctx, h.wallet)
+		if err != nil {
+			return nil, err
+		}
+		actual = decimal.New(sun, -tokenDecimals)
+	case config.TokenUSDT:
+		units, err := r.chain.GetTRC20Balance(ctx, r.usdtContract, h.wallet)
+		if err != nil {
+			return nil, err
+		}
+		actual = decimal.NewFromBigInt(units, -tokenDecimals)
+	default:
+		return nil, fmt.Errorf("unsupported token %q", h.token)
+	}
+
+	expected := f.deposited.Sub(f.swept)
+	diff := actual.Sub(expected)
+	tolerance := r.tolerance(h.token)
+	m := &Mismatch{Wallet: h.wallet, Token: h.token, Expected: expected, Actual: actual}
+	switch {
+	case diff.GreaterThan(tolerance):
+		m.Kind = KindUnexplainedBalance
+		m.Detail = fmt.Sprintf("wallet holds %s %s more than was deposited and not swept", diff.StringFixed(tokenDecimals), h.token)
+	case diff.Neg().GreaterThan(tolerance) && f.swept.IsPositive():
+		m.Kind = KindSweepShortfall
+		m.Detail = fmt.Sprintf("%s %s left the wallet without a recorded sweep", diff.Neg().StringFixed(tokenDecimals), h.token)
+	case diff.Neg().GreaterThan(tolerance):
+		m.Kind = KindMissingBalance
+		m.Detail = fmt.Sprintf("wallet holds %s %s less than was deposited", diff.Neg().StringFixed(tokenDecimals), h.token)
+	default:
+		return nil, nil
+	}
+	return m, nil
+}
+
+// verify looks tx up on chain. It returns the amount the chain shows and,
+// when that is not tx as recorded, what differs.
+func (r *Reconciler) verify(ctx context.Context, tx repository.Transaction) (decimal.Decimal, string, error) {
+	var transfers []tron.Transfer
+	if tx.ContractAddress == nil {
+		chainTx, err := r.chain.GetTransactionByID(ctx, tx.TxHash)
+		if errors.Is(err, tron.ErrTransactionNotFound) {
+			return decimal.Zero, "transaction not found on chain", nil
+		}
+		if err != nil {
+			return decimal.Zero, "", err
+		}
+		if !chainTx.Succeeded() {
+			return decimal.Zero, "transaction failed on chain", nil
+		}
+		for _, t := range chainTx.Transfers() {
+			if t.Contract == "" {
+				transfers = append(transfers, t)
+			}
+		}
+	} else {
+		info, err := r.chain.GetTransactionInfoByID(ctx, tx.TxHash)
+		if errors.Is(err, tron.ErrTransactionNotFound) {
+			return decimal.Zero, "transaction not found on chain", nil
+		}
+		if err != nil {
+			return decimal.Zero, "", err
+		}
+		if info.Failed() {
+			return decimal.Zero, "transaction failed on chain", nil
+		}
+		events, err := tron.ParseTRC20Transfers(*info)
+		if err != nil {
+			return decimal.Zero, "", err
+		}
+		for _, e := range events {
+			if e.Contract == *tx.ContractAddress {
+				transfers = append(transfers, tron.Transfer{TxID: e.TxID, Index: e.LogIndex, From: e.From, To: e.To, Contract: e.Contract, Amount: e.Amount})
+			}
+		}
+	}
+
+	recorded := repository.NumericToDecimal(tx.Amount)
+	for _, t := range transfers {
+		if t.Index != int(tx.TransferIndex) {
+			continue
+		}
+		if t.From != tx.FromAddress || t.To != tx.ToAddress {
+			return decimal.Zero, fmt.Sprintf("transfer %d is from %s to %s, not from %s to %s",
+				t.Index, t.From, t.To, tx.FromAddress, tx.ToAddress), nil
+		}
+		actual := decimal.NewFromBigInt(t.Amount, -tokenDecimals)
+		if !actual.Equal(recorded) {
+			return actual, fmt.Sprintf("transferred %s %s on chain, %s recorded",
+				actual.StringFixed(tokenDecimals), tx.Token, recorded.StringFixed(tokenDecimals)), nil
+		}
+		return actual, "", nil
+	}
+	return decimal.Zero, fmt.Sprintf("transaction has no %s transfer %d", tx.Token, tx.TransferIndex), nil
+}
+
+// save writes the report and its mismatches in one transaction.
+func (r *Reconciler) save(ctx context.Context, report *Report) error {
+	return r.store.ExecTx(ctx, func(q repository.Querier) error {
+		saved, err := q.CreateReconciliationReport(ctx, repository.CreateReconciliationReportParams{
+			WindowStart:     pgtype.Timestamptz{Time: report.WindowStart, Valid: true},
+			WindowEnd:       pgtype.Timestamptz{Time: report.WindowEnd, Valid: true},
+			PaymentsChecked: int32(report.PaymentsChecked),
+			Mismatches:      int32(len(report.Mismatches)),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save reconciliation report: %w", err)
+		}
+		for _, m := range report.Mismatches {
+			var txHash *string
+			if m.TxHash != "" {
+				txHash = &m.TxHash
+			}
+			if err := q.CreateReconciliationMismatch(ctx, repository.CreateReconciliationMismatchParams{
+				ReportID:  saved.ID,
+				PaymentID: m.PaymentID,
+				Kind:      m.Kind,
+				Wallet:    m.Wallet,
+				Token:     m.Token,
+				TxHash:    txHash,
+				Expected:  repository.DecimalToNumeric(m.Expected),
+				Actual:    repository.DecimalToNumeric(m.Actual),
+				Detail:    m.Detail,
+			}); err != nil {
+				return fmt.Errorf("failed to save reconciliation mismatch: %w", err)
+			}
+		}
+		report.ID = saved.ID
+		report.CreatedAt = saved.CreatedAt.Time
+		return nil
+	})
+}
@@ -0,0 +1,511 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+const (
+	testMnemonic     = "flash couple heart script ramp april average caution plunge alter elite author"
+	testColdWallet   = "TKTX96CBxr5kvhjsDHcqoiPWZageGxoTW3"
+	testUSDTContract = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+)
+
+var errNetwork = errors.New("connection reset")
+
+// fakeChain answers from the balances, transactions and receipts it holds.
+// Anything else is not on chain.
+type fakeChain struct {
+	trx      map[string]int64
+	usdt     map[string]*big.Int
+	txs      map[string]*tron.Transaction
+	receipts map[string]*tron.TransactionInfo
+	// err fails every call when set.
+	err error
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{
+		trx:      map[string]int64{},
+		usdt:     map[string]*big.Int{},
+		txs:      map[string]*tron.Transaction{},
+		receipts: map[string]*tron.TransactionInfo{},
+	}
+}
+
+func (c *fakeChain) GetTRXBalance(_ context.Context, address string) (int64, error) {
+	return c.trx[address], c.err
+}
+
+func (c *fakeChain) GetTRC20Balance(_ context.Context, contract, holder string) (*big.Int, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if contract != testUSDTContract {
+		return nil, fmt.Errorf("unexpected contract %s", contract)
+	}
+	if b, ok := c.usdt[holder]; ok {
+		return b, nil
+	}
+	return new(big.Int), nil
+}
+
+func (c *fakeChain) GetTransactionByID(_ context.Context, txID string) (*tron.Transaction, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if tx, ok := c.txs[txID]; ok {
+		return tx, nil
+	}
+	return nil, tron.ErrTransactionNotFound
+}
+
+func (c *fakeChain) GetTransactionInfoByID(_ context.Context, txID string) (*tron.TransactionInfo, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if info, ok := c.receipts[txID]; ok {
+		return info, nil
+	}
+	return nil, tron.ErrTransactionNotFound
+}
+
+// memStore holds the rows a run reads and keeps the report it saves.
+type memStore struct {
+	payments []repository.ListReconciliationPaymentsRow
+	txs      []repository.Transaction
+	open     []repository.Sweep
+
+	listed     []repository.ListReconciliationPaymentsParams
+	reports    []repository.CreateReconciliationReportParams
+	mismatches []repository.CreateReconciliationMismatchParams
+}
+
+func (s *memStore) ListReconciliationPayments(_ context.Context, arg repository.ListReconciliationPaymentsParams) ([]repository.ListReconciliationPaymentsRow, error) {
+	s.listed = append(s.listed, arg)
+	return s.payments, nil
+}
+
+func (s *memStore) ListPaymentTransactions(_ context.Context, paymentID uuid.UUID) ([]repository.Transaction, error) {
+	var out []repository.Transaction
+	for _, tx := range s.txs {
+		if tx.PaymentID == paymentID {
+			out = append(out, tx)
+		}
+	}
+	return out, nil
+}
+
+func (s *memStore) ListOpenSweeps(context.Context) ([]repository.Sweep, error) {
+	return s.open, nil
+}
+
+func (s *memStore) GetWalletFlows(_ context.Context, arg repository.GetWalletFlowsParams) (repository.GetWalletFlowsRow, error) {
+	deposited, swept := decimal.Zero, decimal.Zero
+	for _, tx := range s.txs {
+		if tx.Token != arg.Token {
+			continue
+		}
+		switch {
+		case tx.Kind == "DEPOSIT" && tx.ToAddress == arg.Wallet:
+			deposited = deposited.Add(repository.NumericToDecimal(tx.Amount))
+		case tx.Kind == "SWEEP" && tx.FromAddress == arg.Wallet:
+			swept = swept.Add(repository.NumericToDecimal(tx.Amount))
+		}
+	}
+	return repository.GetWalletFlowsRow{Deposited: repository.DecimalToNumeric(deposited), Swept: repository.DecimalToNumeric(swept)}, nil
+}
+
+func (s *memStore) ExecTx(_ context.Context, fn func(repository.Querier) error) error {
+	return fn(reportWriter{s: s})
+}
+
+// reportWriter is the transaction a report is saved in. Queries it does not
+// implement panic through the nil Querier.
+type reportWriter struct {
+	repository.Querier
+	s *memStore
+}
+
+func (w reportWriter) CreateReconciliationReport(_ context.Context, arg repository.CreateReconciliationReportParams) (repository.ReconciliationReport, error) {
+	w.s.reports = append(w.s.reports, arg)
+	return repository.ReconciliationReport{
+		ID:              uuid.New(),
+		WindowStart:     arg.WindowStart,
+		WindowEnd:       arg.WindowEnd,
+		PaymentsChecked: arg.PaymentsChecked,
+		Mismatches:      arg.Mismatches,
+		CreatedAt:       pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}, nil
+}
+
+func (w reportWriter) CreateReconciliationMismatch(_ context.Context, arg repository.CreateReconciliationMismatchParams) error {
+	w.s.mismatches = append(w.s.mismatches, arg)
+	return nil
+}
+
+type recordedMetrics map[string]int
+
+func (m recordedMetrics) ReconcileMismatches(kind string, n int) { m[kind] = n }
+
+// ledger builds consistent or deliberately inconsistent fixtures: what the
+// database records on one side, what the chain shows on the other.
+type ledger struct {
+	t     *testing.T
+	chain *fakeChain
+	store *memStore
+	payer string
+	next  uint32
+	txNum int
+}
+
+func newLedger(t *testing.T) *ledger {
+	l := &ledger{t: t, chain: newFakeChain(), store: &memStore{}}
+	l.payer = l.address()
+	return l
+}
+
+func (l *ledger) address() string {
+	l.t.Helper()
+	address, _, err := wallet.DeriveTronAddressFromMnemonic(testMnemonic, l.next)
+	require.NoError(l.t, err)
+	l.next++
+	return address
+}
+
+// payment records a confirmed payment of amount token to a fresh wallet.
+func (l *ledger) payment(token, amount string) repository.ListReconciliationPaymentsRow {
+	p := repository.ListReconciliationPaymentsRow{
+		ID:           uuid.New(),
+		Token:        token,
+		UniqueWallet: l.address(),
+		Amount:       numeric(amount),
+		ConfirmedAt:  pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	l.store.payments = append(l.store.payments, p)
+	return p
+}
+
+// sharedPayment records a confirmed payment of amount token to the deposit
+// wallet of an amount_suffix account, shared with its other payments.
+func (l *ledger) sharedPayment(wallet, token, amount string) repository.ListReconciliationPaymentsRow {
+	p := l.payment(token, amount)
+	p.UniqueWallet, p.SharedWallet = wallet, true
+	l.store.payments[len(l.store.payments)-1] = p
+	return p
+}
+
+// deposit records a deposit of recorded to p's wallet and puts a transfer of
+// onChain on the chain; an empty onChain leaves it off.
+func (l *ledger) deposit(p repository.ListReconciliationPaymentsRow, recorded, onChain string) string {
+	return l.transfer(p, "DEPOSIT", l.payer, p.UniqueWallet, recorded, onChain)
+}
+
+// sweep is deposit for a sweep from p's wallet to the cold wallet.
+func (l *ledger) sweep(p repository.ListReconciliationPaymentsRow, recorded, onChain string) string {
+	return l.transfer(p, "SWEEP", p.UniqueWallet, testColdWallet, recorded, onChain)
+}
+
+func (l *ledger) transfer(p repository.ListReconciliationPaymentsRow, kind, from, to, recorded, onChain string) string {
+	l.t.Helper()
+	l.txNum++
+	txID := fmt.Sprintf("%064x", l.txNum)
+	tx := repository.Transaction{
+		ID:          uuid.New(),
+		PaymentID:   p.ID,
+		TxHash:      txID,
+		Token:       p.Token,
+		FromAddress: from,
+		ToAddress:   to,
+		Amount:      numeric(recorded),
+		Status:      "CONFIRMED",
+		Kind:        kind,
+	}
+	if p.Token == config.TokenUSDT {
+		contract := testUSDTContract
+		tx.ContractAddress = &contract
+		if onChain != "" {
+			l.chain.receipts[txID] = transferReceipt(l.t, txID, from, to, units(onChain))
+		}
+	} else if onChain != "" {
+		chainTx, err := tron.BuildTRXTransfer(from, to, units(onChain).Int64(),
+			tron.BlockRef{Number: 1, ID: strings.Repeat("ab", 32), Timestamp: time.Now().UnixMilli()}, time.Minute)
+		require.NoError(l.t, err)
+		chainTx.TxID = txID
+		chainTx.Ret = []tron.TransactionResult{{ContractRet: "SUCCESS"}}
+		l.chain.txs[txID] = chainTx
+	}
+	l.store.txs = append(l.store.txs, tx)
+	return txID
+}
+
+// holds sets the on-chain balance of p's wallet.
+func (l *ledger) holds(p repository.ListReconciliationPaymentsRow, amount string) {
+	if p.Token == config.TokenUSDT {
+		l.chain.usdt[p.UniqueWallet] = units(amount)
+		return
+	}
+	l.chain.trx[p.UniqueWallet] = units(amount).Int64()
+}
+
+func transferReceipt(t *testing.T, txID, from, to string, amount *big.Int) *tron.TransactionInfo {
+	t.Helper()
+	topic := func(address string) string {
+		h, err := wallet.AddressToHex(address)
+		require.NoError(t, err)
+		return strings.Repeat("0", 24) + h[2:]
+	}
+	contract, err := wallet.AddressToHex(testUSDTContract)
+	require.NoError(t, err)
+	return &tron.TransactionInfo{
+		ID: txID,
+		Log: []tron.Log{{
+			Address: contract[2:],
+			Topics:  []string{tron.TransferEventTopic, topic(from), topic(to)},
+			Data:    fmt.Sprintf("%064x", amount),
+		}},
+	}
+}
+
+func numeric(amount string) pgtype.Numeric {
+	return repository.DecimalToNumeric(decimal.RequireFromString(amount))
+}
+
+func units(amount string) *big.Int {
+	return decimal.RequireFromString(amount).Shift(tokenDecimals).BigInt()
+}
+
+func testConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.ApplyDefaults()
+	cfg.Tron.USDTContract = testUSDTContract
+	return cfg
+}
+
+func newTestReconciler(l *ledger, opts ...Option) *Reconciler {
+	opts = append([]Option{WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, opts...)
+	return New(l.chain, l.store, testConfig(), opts...)
+}
+
+// found is a mismatch without its free-text detail, for comparing.
+type found struct {
+	payment  uuid.UUID
+	kind     string
+	txHash   string
+	expected string
+	actual   string
+}
+
+func summarize(ms []Mismatch) []found {
+	out := make([]found, len(ms))
+	for i, m := range ms {
+		out[i] = found{m.PaymentID, m.Kind, m.TxHash, m.Expected.StringFixed(2), m.Actual.StringFixed(2)}
+	}
+	return out
+}
+
+func TestReconcile_Consistent(t *testing.T) {
+	l := newLedger(t)
+	held := l.payment(config.TokenUSDT, "25")
+	l.deposit(held, "25", "25")
+	l.holds(held, "25")
+
+	// Paid in two transfers and swept in one.
+	swept := l.payment(config.TokenUSDT, "40")
+	l.deposit(swept, "15", "15")
+	l.deposit(swept, "25", "25")
+	l.sweep(swept, "40", "40")
+
+	// The bandwidth a TRX sweep burns is within the default tolerance.
+	trx := l.payment(config.TokenTRX, "100")
+	l.deposit(trx, "100", "100")
+	l.sweep(trx, "99.7", "99.7")
+
+	m := recordedMetrics{}
+	report, err := newTestReconciler(l, WithMetrics(m)).Reconcile(context.Background(), time.Now().Add(-time.Hour), time.Now())
+
+	require.NoError(t, err)
+	assert.Empty(t, report.Mismatches)
+	assert.Equal(t, 3, report.PaymentsChecked)
+	require.Len(t, l.store.reports, 1)
+	assert.EqualValues(t, 3, l.store.reports[0].PaymentsChecked)
+	assert.NotEqual(t, uuid.Nil, report.ID)
+	assert.Equal(t, recordedMetrics{KindMissingTransfer: 0, KindUnexplainedBalance: 0, KindMissingBalance: 0, KindSweepShortfall: 0}, m,
+		"every kind is reported, so a cleared mismatch drops to zero")
+}
+
+func TestReconcile_Mismatches(t *testing.T) {
+	l := newLedger(t)
+
+	ghost := l.payment(config.TokenUSDT, "10")
+	ghostTx := l.deposit(ghost, "10", "")
+
+	short := l.payment(config.TokenTRX, "100")
+	shortTx := l.deposit(short, "100", "90")
+	l.holds(short, "90")
+
+	extra := l.payment(config.TokenTRX, "50")
+	l.deposit(extra, "50", "50")
+	l.holds(extra, "75")
+
+	leaked := l.payment(config.TokenUSDT, "60")
+	l.deposit(leaked, "60", "60")
+	l.sweep(leaked, "50", "50")
+
+	overstated := l.payment(config.TokenUSDT, "30")
+	l.deposit(overstated, "30", "30")
+	overstatedTx := l.sweep(overstated, "30", "20")
+	l.holds(overstated, "10")
+
+	unpaid := l.payment(config.TokenUSDT, "12.5")
+
+	// The balance is gone but the sweep moving it is not recorded yet.
+	inFlight := l.payment(config.TokenUSDT, "80")
+	l.deposit(inFlight, "80", "80")
+	l.store.open = append(l.store.open, repository.Sweep{FromAddress: inFlight.UniqueWallet, Token: config.TokenUSDT, Status: "SENT"})
+
+	m := recordedMetrics{}
+	report, err := newTestReconciler(l, WithMetrics(m)).Reconcile(context.Background(), time.Now().Add(-time.Hour), time.Now())
+	require.NoError(t, err)
+
+	assert.Equal(t, []found{
+		{ghost.ID, KindMissingTransfer, ghostTx, "10.00", "0.00"},
+		{ghost.ID, KindMissingBalance, "", "10.00", "0.00"},
+		{short.ID, KindMissingTransfer, shortTx, "100.00", "90.00"},
+		{short.ID, KindMissingBalance, "", "100.00", "90.00"},
+		{extra.ID, KindUnexplainedBalance, "", "50.00", "75.00"},
+		{leaked.ID, KindSweepShortfall, "", "10.00", "0.00"},
+		{overstated.ID, KindSweepShortfall, overstatedTx, "30.00", "20.00"},
+		{overstated.ID, KindUnexplainedBalance, "", "0.00", "10.00"},
+		{unpaid.ID, KindMissingTransfer, "", "12.50", "0.00"},
+	}, summarize(report.Mismatches))
+	assert.Equal(t, "transaction not found on chain", report.Mismatches[0].Detail)
+	assert.Equal(t, "transferred 90.000000 TRX on chain, 100.000000 recorded", report.Mismatches[2].Detail)
+	assert.Equal(t, "confirmed payment has no recorded deposit", report.Mismatches[8].Detail)
+	assert.Equal(t, overstated.UniqueWallet, report.Mismatches[6].Wallet, "a sweep is filed under the wallet it emptied")
+
+	assert.Equal(t, recordedMetrics{KindMissingTransfer: 3, KindUnexplainedBalance: 2, KindMissingBalance: 2, KindSweepShortfall: 2}, m)
+	require.Len(t, l.store.reports, 1)
+	assert.EqualValues(t, 7, l.store.reports[0].PaymentsChecked)
+	assert.EqualValues(t, 9, l.store.reports[0].Mismatches)
+	require.Len(t, l.store.mismatches, 9)
+	assert.Equal(t, &ghostTx, l.store.mismatches[0].TxHash)
+	assert.Nil(t, l.store.mismatches[1].TxHash, "a balance mismatch has no transaction")
+	assert.Equal(t, numeric("90.000000"), l.store.mismatches[2].Actual)
+}
+
+func TestReconcile_SharedDepositWallet(t *testing.T) {
+	testCases := []struct {
+		name  string
+		holds string
+		want  []string
+	}{
+		{"consistent", "12", nil},
+		{"unexplained balance", "20", []string{KindUnexplainedBalance}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := newLedger(t)
+			wallet := l.address()
+			first := l.sharedPayment(wallet, config.TokenUSDT, "10.001")
+			l.deposit(first, "10.001", "10.001")
+			second := l.sharedPayment(wallet, config.TokenUSDT, "20.002")
+			l.deposit(second, "20.002", "20.002")
+			// The wallet is swept whole for the latest payment confirmed.
+			l.sweep(second, "30.003", "30.003")
+			third := l.sharedPayment(wallet, config.TokenUSDT, "12")
+			l.deposit(third, "12", "12")
+			l.holds(third, tc.holds)
+
+			report, err := newTestReconciler(l).Reconcile(context.Background(), time.Now().Add(-time.Hour), time.Now())
+
+			require.NoError(t, err)
+			var kinds []string
+			for _, m := range report.Mismatches {
+				kinds = append(kinds, m.Kind)
+				assert.Equal(t, wallet, m.Wallet)
+				assert.Equal(t, "12.00", m.Expected.StringFixed(2), "what all the payments deposited less the sweep")
+				assert.Equal(t, tc.holds, m.Actual.String())
+			}
+			assert.Equal(t, tc.want, kinds, "the shared wallet is checked once, not per payment")
+		})
+	}
+}
+
+func TestReconcile_WrongRecipient(t *testing.T) {
+	l := newLedger(t)
+	p := l.payment(config.TokenUSDT, "5")
+	txID := l.deposit(p, "5", "5")
+	l.holds(p, "5")
+	// The transfer on chain paid someone else.
+	l.chain.receipts[txID] = transferReceipt(t, txID, l.payer, testColdWallet, units("5"))
+
+	report, err := newTestReconciler(l).Reconcile(context.Background(), time.Now().Add(-time.Hour), time.Now())
+
+	require.NoError(t, err)
+	require.Len(t, report.Mismatches, 1)
+	assert.Equal(t, KindMissingTransfer, report.Mismatches[0].Kind)
+	assert.Contains(t, report.Mismatches[0].Detail, "not from "+l.payer+" to "+p.UniqueWallet)
+}
+
+func TestReconcile_FailedTransaction(t *testing.T) {
+	l := newLedger(t)
+	p := l.payment(config.TokenTRX, "5")
+	txID := l.deposit(p, "5", "5")
+	l.holds(p, "5")
+	l.chain.txs[txID].Ret[0].ContractRet = "OUT_OF_ENERGY"
+
+	report, err := newTestReconciler(l).Reconcile(context.Background(), time.Now().Add(-time.Hour), time.Now())
+
+	require.NoError(t, err)
+	require.Len(t, report.Mismatches, 1)
+	assert.Equal(t, "transaction failed on chain", report.Mismatches[0].Detail)
+}
+
+func TestReconcile_ChainErrorFailsRun(t *testing.T) {
+	l := newLedger(t)
+	p := l.payment(config.TokenUSDT, "25")
+	l.deposit(p, "25", "25")
+	l.chain.err = errNetwork
+	m := recordedMetrics{}
+
+	_, err := newTestReconciler(l, WithMetrics(m)).Reconcile(context.Background(), time.Now().Add(-time.Hour), time.Now())
+
+	require.ErrorIs(t, err, errNetwork)
+	assert.Contains(t, err.Error(), p.ID.String())
+	assert.Empty(t, l.store.reports, "a partial report must not be saved")
+	assert.Empty(t, m)
+}
+
+func TestRunOnce_Window(t *testing.T) {
+	l := newLedger(t)
+	r := newTestReconciler(l)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	report, err := r.RunOnce(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, l.store.listed, 1)
+	assert.Equal(t, now.Add(-24*time.Hour), l.store.listed[0].Since.Time, "the default window is a day")
+	assert.Equal(t, now, l.store.listed[0].Until.Time)
+	assert.Equal(t, now.Add(-24*time.Hour), report.WindowStart)
+}
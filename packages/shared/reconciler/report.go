@@ -0,0 +1,112 @@
+package reconciler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// Mismatch is a place where the chain disagrees with the database.
+type Mismatch struct {
+	PaymentID uuid.UUID
+	Kind      string
+	Wallet    string
+	Token     string
+	// TxHash is the transaction at fault, empty for a balance mismatch.
+	TxHash string
+	// Expected is what the database accounts for and Actual what the chain
+	// shows, in token units.
+	Expected decimal.Decimal
+	Actual   decimal.Decimal
+	Detail   string
+}
+
+// Report is the outcome of one reconciliation.
+type Report struct {
+	ID uuid.UUID
+	// Payments confirmed in [WindowStart, WindowEnd) were checked.
+	WindowStart     time.Time
+	WindowEnd       time.Time
+	PaymentsChecked int
+	Mismatches      []Mismatch
+	CreatedAt       time.Time
+}
+
+// Count returns the number of mismatches of kind.
+func (r *Report) Count(kind string) int {
+	n := 0
+	for _, m := range r.Mismatches {
+		if m.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// ReportFromRecords rebuilds a saved report from its rows.
+func ReportFromRecords(report repository.ReconciliationReport, mismatches []repository.ReconciliationMismatch) *Report {
+	r := &Report{
+		ID:              report.ID,
+		WindowStart:     report.WindowStart.Time,
+		WindowEnd:       report.WindowEnd.Time,
+		PaymentsChecked: int(report.PaymentsChecked),
+		CreatedAt:       report.CreatedAt.Time,
+	}
+	for _, m := range mismatches {
+		var txHash string
+		if m.TxHash != nil {
+			txHash = *m.TxHash
+		}
+		r.Mismatches = append(r.Mismatches, Mismatch{
+			PaymentID: m.PaymentID,
+			Kind:      m.Kind,
+			Wallet:    m.Wallet,
+			Token:     m.Token,
+			TxHash:    txHash,
+			Expected:  repository.NumericToDecimal(m.Expected),
+			Actual:    repository.NumericToDecimal(m.Actual),
+			Detail:    m.Detail,
+		})
+	}
+	return r
+}
+
+// csvHeader names the columns WriteCSV writes.
+var csvHeader = []string{"report_id", "window_start", "window_end", "payment_id", "kind", "wallet", "token", "tx_hash", "expected", "actual", "difference", "detail"}
+
+// WriteCSV writes one row per mismatch, under a header row. Amounts are in
+// token units with six decimals, and difference is actual less expected.
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	for _, m := range r.Mismatches {
+		if err := cw.Write([]string{
+			r.ID.String(),
+			r.WindowStart.UTC().Format(time.RFC3339),
+			r.WindowEnd.UTC().Format(time.RFC3339),
+			m.PaymentID.String(),
+			m.Kind,
+			m.Wallet,
+			m.Token,
+			m.TxHash,
+			m.Expected.StringFixed(tokenDecimals),
+			m.Actual.StringFixed(tokenDecimals),
+			m.Actual.Sub(m.Expected).StringFixed(tokenDecimals),
+			m.Detail,
+		}); err != nil {
+			return fmt.Errorf("failed to write csv: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package reconciler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+func TestReportFromRecords(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	txHash := "abc123"
+	record := repository.ReconciliationReport{
+		ID:              uuid.New(),
+		WindowStart:     pgtype.Timestamptz{Time: start, Valid: true},
+		WindowEnd:       pgtype.Timestamptz{Time: start.Add(24 * time.Hour), Valid: true},
+		PaymentsChecked: 12,
+		Mismatches:      2,
+		CreatedAt:       pgtype.Timestamptz{Time: start.Add(25 * time.Hour), Valid: true},
+	}
+	paymentID := uuid.New()
+
+	r := ReportFromRecords(record, []repository.ReconciliationMismatch{
+		{PaymentID: paymentID, Kind: KindMissingTransfer, Wallet: "TWallet", Token: "USDT", TxHash: &txHash,
+			Expected: numeric("10"), Actual: numeric("0"), Detail: "transaction not found on chain"},
+		{PaymentID: paymentID, Kind: KindMissingBalance, Wallet: "TWallet", Token: "USDT",
+			Expected: numeric("10"), Actual: numeric("0")},
+	})
+
+	assert.Equal(t, record.ID, r.ID)
+	assert.Equal(t, start, r.WindowStart)
+	assert.Equal(t, start.Add(24*time.Hour), r.WindowEnd)
+	assert.Equal(t, 12, r.PaymentsChecked)
+	require.Len(t, r.Mismatches, 2)
+	assert.Equal(t, txHash, r.Mismatches[0].TxHash)
+	assert.Empty(t, r.Mismatches[1].TxHash)
+	assert.True(t, decimal.NewFromInt(10).Equal(r.Mismatches[0].Expected))
+	assert.Equal(t, 1, r.Count(KindMissingBalance))
+}
+
+func TestReport_WriteCSV(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.FixedZone("EAT", 3*60*60))
+	r := &Report{
+		ID:          uuid.New(),
+		WindowStart: start,
+		WindowEnd:   start.Add(24 * time.Hour),
+		Mismatches: []Mismatch{{
+			PaymentID: uuid.New(),
+			Kind:      KindUnexplainedBalance,
+			Wallet:    "TWallet",
+			Token:     "TRX",
+			Expected:  decimal.RequireFromString("50"),
+			Actual:    decimal.RequireFromString("75.5"),
+			Detail:    "holds more, than recorded",
+		}},
+	}
+	var buf bytes.Buffer
+
+	require.NoError(t, r.WriteCSV(&buf))
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, csvHeader, rows[0])
+	assert.Equal(t, []string{
+		r.ID.String(), "2026-02-28T21:00:00Z", "2026-03-01T21:00:00Z", r.Mismatches[0].PaymentID.String(),
+		KindUnexplainedBalance, "TWallet", "TRX", "", "50.000000", "75.500000", "25.500000", "holds more, than recorded",
+	}, rows[1])
+}
+
+func TestReport_WriteCSVEmpty(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, (&Report{}).WriteCSV(&buf))
+
+	assert.Equal(t, "report_id,window_start,window_end,payment_id,kind,wallet,token,tx_hash,expected,actual,difference,detail\n", buf.String())
+}
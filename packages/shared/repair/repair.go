@@ -0,0 +1,239 @@
+// Package repair finds payments whose stored state contradicts their
+// attempts or transactions and, where the right value can be read off the
+// database, corrects them.
+package repair
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// Issue kinds, one per consistency rule.
+const (
+	// KindAttemptCount is a payment whose attempt_count is not the number of
+	// its attempts.
+	KindAttemptCount = "ATTEMPT_COUNT"
+	// KindWallet is a payment whose wallet is not the one its latest attempt
+	// generated.
+	KindWallet = "WALLET"
+	// KindMissingDeposit is a confirmed payment without a recorded deposit.
+	// Only the chain knows the transfer, so it is reported, never fixed;
+	// tpg reconcile run looks it up.
+	KindMissingDeposit = "MISSING_DEPOSIT"
+)
+
+// EventPaymentRepaired is the log event type of a repair.
+const EventPaymentRepaired = "PAYMENT_REPAIRED"
+
+// Store is the subset of *repository.Store the repairer reads the
+// inconsistencies through and fixes them in.
+type Store interface {
+	ListAttemptCountDrift(ctx context.Context) ([]repository.ListAttemptCountDriftRow, error)
+	ListWalletDrift(ctx context.Context) ([]repository.ListWalletDriftRow, error)
+	ListConfirmedWithoutDeposit(ctx context.Context) ([]repository.ListConfirmedWithoutDepositRow, error)
+	ExecTx(ctx context.Context, fn func(repository.Querier) error) error
+}
+
+// Issue is one broken rule of one payment.
+type Issue struct {
+	PaymentID uuid.UUID `json:"payment_id"`
+	Kind      string    `json:"kind"`
+	// Stored is what the payment holds and Expected what the rule says it
+	// should; both empty for a missing deposit.
+	Stored   string `json:"stored,omitempty"`
+	Expected string `json:"expected,omitempty"`
+	Detail   string `json:"detail"`
+	// Fixable is set when Fix can correct the issue.
+	Fixable bool `json:"fixable"`
+	// Repaired is set once Fix corrected the issue; Error says why it
+	// could not.
+	Repaired bool   `json:"repaired"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Report lists the issues a scan found, in rule and then payment order.
+type Report struct {
+	Issues []Issue `json:"issues"`
+}
+
+// Counts returns how many issues were repaired, failed to be, and are left
+// for an operator because Fix cannot correct them.
+func (r *Report) Counts() (repaired, failed, manual int) {
+	for _, issue := range r.Issues {
+		switch {
+		case issue.Repaired:
+			repaired++
+		case issue.Error != "":
+			failed++
+		case !issue.Fixable:
+			manual++
+		}
+	}
+	return repaired, failed, manual
+}
+
+// repairLog is the raw data of a PAYMENT_REPAIRED log.
+type repairLog struct {
+	Kind     string `json:"kind"`
+	Previous string `json:"previous"`
+	Value    string `json:"value"`
+}
+
+// Repairer scans for and fixes inconsistent payments.
+type Repairer struct {
+	store  Store
+	actor  string
+	logger *slog.Logger
+}
+
+// Option customises a Repairer.
+type Option func(*Repairer)
+
+// WithLogger replaces slog.Default.
+func WithLogger(l *slog.Logger) Option {
+	return func(r *Repairer) { r.logger = l }
+}
+
+// WithActor sets the actor of the repair logs, "tpg" by default.
+func WithActor(actor string) Option {
+	return func(r *Repairer) { r.actor = actor }
+}
+
+// New builds a repairer over store.
+func New(store Store, opts ...Option) *Repairer {
+	r := &Repairer{
+		store:  store,
+		actor:  "tpg",
+		logger: slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Scan checks every rule and reports what breaks them, changing nothing.
+func (r *Repairer) Scan(ctx context.Context) (*Report, error) {
+	report := &Report{Issues: []Issue{}}
+
+	counts, err := r.store.ListAttemptCountDrift(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attempt count drift: %w", err)
+	}
+	for _, row := range counts {
+		report.Issues = append(report.Issues, Issue{
+			PaymentID: row.ID,
+			Kind:      KindAttemptCount,
+			Stored:    formatCount(row.AttemptCount),
+			Expected:  strconv.FormatInt(row.Attempts, 10),
+			Detail:    fmt.Sprintf("attempt_count is %s but the payment has %d attempts", formatCount(row.AttemptCount), row.Attempts),
+			Fixable:   true,
+		})
+	}
+
+	wallets, err := r.store.ListWalletDrift(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wallet drift: %w", err)
+	}
+	for _, row := range wallets {
+		report.Issues = append(report.Issues, Issue{
+			PaymentID: row.ID,
+			Kind:      KindWallet,
+			Stored:    row.UniqueWallet,
+			Expected:  row.LatestWallet,
+			Detail:    fmt.Sprintf("wallet is not the one attempt %d generated", row.AttemptNumber),
+			Fixable:   true,
+		})
+	}
+
+	deposits, err := r.store.ListConfirmedWithoutDeposit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list confirmed payments without deposits: %w", err)
+	}
+	for _, row := range deposits {
+		report.Issues = append(report.Issues, Issue{
+			PaymentID: row.ID,
+			Kind:      KindMissingDeposit,
+			Detail:    fmt.Sprintf("confirmed %s payment to %s has no recorded deposit", row.Token, row.UniqueWallet),
+		})
+	}
+	return report, nil
+}
+
+// Fix corrects every fixable issue of report, each in a transaction of its
+// own that also writes a PAYMENT_REPAIRED log. An issue that fails is
+// marked with its error and does not stop the others; only ctx ending
+// does.
+func (r *Repairer) Fix(ctx context.Context, report *Report) error {
+	for i := range report.Issues {
+		issue := &report.Issues[i]
+		if !issue.Fixable || issue.Repaired {
+			continue
+		}
+		if err := r.fix(ctx, issue); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			issue.Error = err.Error()
+			r.logger.ErrorContext(ctx, "payment repair failed", "payment_id", issue.PaymentID, "kind", issue.Kind, "error", err)
+			continue
+		}
+		issue.Repaired = true
+		r.logger.InfoContext(ctx, "payment repaired", "payment_id", issue.PaymentID, "kind", issue.Kind,
+			"previous", issue.Stored, "value", issue.Expected)
+	}
+	return nil
+}
+
+func (r *Repairer) fix(ctx context.Context, issue *Issue) error {
+	return r.store.ExecTx(ctx, func(q repository.Querier) error {
+		// The stored value is re-read from the attempts, so a payment that
+		// moved on since the scan gets what is right now.
+		switch issue.Kind {
+		case KindAttemptCount:
+			count, err := q.SyncAttemptCount(ctx, issue.PaymentID)
+			if err != nil {
+				return fmt.Errorf("failed to update attempt count: %w", err)
+			}
+			issue.Expected = formatCount(count)
+		case KindWallet:
+			wallet, err := q.SyncPaymentWallet(ctx, issue.PaymentID)
+			if err != nil {
+				return fmt.Errorf("failed to update wallet: %w", err)
+			}
+			issue.Expected = wallet.UniqueWallet
+		default:
+			return fmt.Errorf("%s issues cannot be fixed", issue.Kind)
+		}
+
+		raw, err := json.Marshal(repairLog{Kind: issue.Kind, Previous: issue.Stored, Value: issue.Expected})
+		if err != nil {
+			return fmt.Errorf("failed to encode log data: %w", err)
+		}
+		msg := fmt.Sprintf("%s repaired: %s replaced by %s", issue.Kind, issue.Stored, issue.Expected)
+		if err := q.CreateLog(ctx, repository.CreateLogParams{
+			PaymentID: repository.UUIDPtr(issue.PaymentID),
+			EventType: EventPaymentRepaired,
+			Message:   &msg,
+			RawData:   raw,
+			Actor:     &r.actor,
+		}); err != nil {
+			return fmt.Errorf("failed to write %s log: %w", EventPaymentRepaired, err)
+		}
+		return nil
+	})
+}
+
+// formatCount renders a nullable attempt count, NULL when unset.
+func formatCount(n *int32) string {
+	if n == nil {
+		return "NULL"
+	}
+	return strconv.Itoa(int(*n))
+}
@@ -0,0 +1,160 @@
+package repair
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// mockStore is a Store whose transactions run directly on its MockQuerier.
+type mockStore struct {
+	*repository.MockQuerier
+}
+
+func (m *mockStore) ExecTx(_ context.Context, fn func(repository.Querier) error) error {
+	return fn(m.MockQuerier)
+}
+
+var (
+	countPayment   = uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	legacyPayment  = uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	walletPayment  = uuid.MustParse("33333333-3333-3333-3333-333333333333")
+	depositPayment = uuid.MustParse("44444444-4444-4444-4444-444444444444")
+)
+
+// seeded is a store holding one payment breaking each rule, and a legacy
+// payment whose attempt_count was never set.
+func seeded(t *testing.T) *mockStore {
+	t.Helper()
+	store := &mockStore{MockQuerier: repository.NewMockQuerier(t)}
+	one := int32(1)
+	store.On("ListAttemptCountDrift", mock.Anything).Return([]repository.ListAttemptCountDriftRow{
+		{ID: countPayment, AttemptCount: &one, Attempts: 3},
+		{ID: legacyPayment, Attempts: 1},
+	}, nil)
+	store.On("ListWalletDrift", mock.Anything).Return([]repository.ListWalletDriftRow{
+		{ID: walletPayment, UniqueWallet: "TFirst", AttemptNumber: 2, LatestWallet: "TSecond"},
+	}, nil)
+	store.On("ListConfirmedWithoutDeposit", mock.Anything).Return([]repository.ListConfirmedWithoutDepositRow{
+		{ID: depositPayment, UniqueWallet: "TPaid", Token: "USDT"},
+	}, nil)
+	return store
+}
+
+func newRepairer(store Store) *Repairer {
+	return New(store, WithActor("tpg:alice"), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+}
+
+func TestScan(t *testing.T) {
+	store := seeded(t)
+
+	report, err := newRepairer(store).Scan(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []Issue{
+		{PaymentID: countPayment, Kind: KindAttemptCount, Stored: "1", Expected: "3",
+			Detail: "attempt_count is 1 but the payment has 3 attempts", Fixable: true},
+		{PaymentID: legacyPayment, Kind: KindAttemptCount, Stored: "NULL", Expected: "1",
+			Detail: "attempt_count is NULL but the payment has 1 attempts", Fixable: true},
+		{PaymentID: walletPayment, Kind: KindWallet, Stored: "TFirst", Expected: "TSecond",
+			Detail: "wallet is not the one attempt 2 generated", Fixable: true},
+		{PaymentID: depositPayment, Kind: KindMissingDeposit,
+			Detail: "confirmed USDT payment to TPaid has no recorded deposit"},
+	}, report.Issues)
+	store.AssertNotCalled(t, "SyncAttemptCount", mock.Anything, mock.Anything)
+	store.AssertNotCalled(t, "CreateLog", mock.Anything, mock.Anything)
+
+	repaired, failed, manual := report.Counts()
+	assert.Equal(t, []int{0, 0, 1}, []int{repaired, failed, manual})
+}
+
+func TestScan_Consistent(t *testing.T) {
+	store := &mockStore{MockQuerier: repository.NewMockQuerier(t)}
+	store.On("ListAttemptCountDrift", mock.Anything).Return(nil, nil)
+	store.On("ListWalletDrift", mock.Anything).Return(nil, nil)
+	store.On("ListConfirmedWithoutDeposit", mock.Anything).Return(nil, nil)
+
+	report, err := newRepairer(store).Scan(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []Issue{}, report.Issues)
+}
+
+func TestScan_StoreError(t *testing.T) {
+	store := &mockStore{MockQuerier: repository.NewMockQuerier(t)}
+	store.On("ListAttemptCountDrift", mock.Anything).Return(nil, nil)
+	store.On("ListWalletDrift", mock.Anything).Return(nil, errors.New("connection reset"))
+
+	_, err := newRepairer(store).Scan(context.Background())
+
+	require.ErrorContains(t, err, "failed to list wallet drift: connection reset")
+}
+
+func TestFix(t *testing.T) {
+	store := seeded(t)
+	three, one := int32(3), int32(1)
+	index := int64(12)
+	store.On("SyncAttemptCount", mock.Anything, countPayment).Return(&three, nil).Once()
+	store.On("SyncAttemptCount", mock.Anything, legacyPayment).Return(&one, nil).Once()
+	store.On("SyncPaymentWallet", mock.Anything, walletPayment).
+		Return(repository.SyncPaymentWalletRow{UniqueWallet: "TSecond", WalletIndex: &index}, nil).Once()
+	var logs []repository.CreateLogParams
+	store.On("CreateLog", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		logs = append(logs, args.Get(1).(repository.CreateLogParams))
+	})
+	r := newRepairer(store)
+	report, err := r.Scan(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, r.Fix(context.Background(), report))
+
+	repaired, failed, manual := report.Counts()
+	assert.Equal(t, []int{3, 0, 1}, []int{repaired, failed, manual})
+	assert.False(t, report.Issues[3].Repaired, "a missing deposit is left for reconciliation")
+
+	require.Len(t, logs, 3, "one log per repair")
+	for i, id := range []uuid.UUID{countPayment, legacyPayment, walletPayment} {
+		assert.Equal(t, id, *logs[i].PaymentID)
+		assert.Equal(t, EventPaymentRepaired, logs[i].EventType)
+		assert.Equal(t, "tpg:alice", *logs[i].Actor)
+	}
+	var data repairLog
+	require.NoError(t, json.Unmarshal(logs[2].RawData, &data))
+	assert.Equal(t, repairLog{Kind: KindWallet, Previous: "TFirst", Value: "TSecond"}, data)
+	assert.Equal(t, "WALLET repaired: TFirst replaced by TSecond", *logs[2].Message)
+}
+
+func TestFix_FailureDoesNotStopOthers(t *testing.T) {
+	store := seeded(t)
+	three, one := int32(3), int32(1)
+	store.On("SyncAttemptCount", mock.Anything, countPayment).Return(&three, nil)
+	store.On("SyncAttemptCount", mock.Anything, legacyPayment).Return(&one, nil)
+	store.On("SyncPaymentWallet", mock.Anything, walletPayment).
+		Return(repository.SyncPaymentWalletRow{}, repository.ErrDuplicateWallet)
+	store.On("CreateLog", mock.Anything, mock.MatchedBy(func(arg repository.CreateLogParams) bool {
+		return *arg.PaymentID == countPayment
+	})).Return(errors.New("connection reset"))
+	store.On("CreateLog", mock.Anything, mock.Anything).Return(nil)
+	r := newRepairer(store)
+	report, err := r.Scan(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, r.Fix(context.Background(), report))
+
+	assert.False(t, report.Issues[0].Repaired)
+	assert.Equal(t, "failed to write PAYMENT_REPAIRED log: connection reset", report.Issues[0].Error)
+	assert.True(t, report.Issues[1].Repaired)
+	assert.False(t, report.Issues[2].Repaired)
+	assert.Contains(t, report.Issues[2].Error, "failed to update wallet")
+	repaired, failed, manual := report.Counts()
+	assert.Equal(t, []int{1, 2, 1}, []int{repaired, failed, manual})
+}
@@ -0,0 +1,91 @@
+// Package requestid carries the ID of the API request that started a piece
+// of work, so its logs, log rows and webhook deliveries can be tied back to
+// it.
+package requestid
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+)
+
+// Header is the HTTP header a request ID is read from and echoed in.
+const Header = "X-Request-ID"
+
+// maxLen bounds a caller-supplied ID, which ends up in logs and the database.
+const maxLen = 128
+
+type key struct{}
+
+// NewContext returns a copy of ctx carrying id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// FromContext returns the request ID in ctx, or "" if there is none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(key{}).(string)
+	return id
+}
+
+// Ptr returns the request ID in ctx for a nullable column, or nil if there
+// is none.
+func Ptr(ctx context.Context) *string {
+	if id := FromContext(ctx); id != "" {
+		return &id
+	}
+	return nil
+}
+
+// New returns a fresh request ID.
+func New() string {
+	return uuid.NewString()
+}
+
+// Valid reports whether id, e.g. from a client or proxy, is safe to adopt:
+// non-empty, at most 128 bytes, and made of letters, digits and -_.:
+// only.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+	for _, c := range []byte(id) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// NewLogHandler wraps h so that records logged with a context carrying a
+// request ID get a request_id attribute. A handler already wrapped is
+// returned as is, so the attribute is never added twice.
+func NewLogHandler(h slog.Handler) slog.Handler {
+	if _, ok := h.(logHandler); ok {
+		return h
+	}
+	return logHandler{h}
+}
+
+type logHandler struct {
+	slog.Handler
+}
+
+func (h logHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := FromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return logHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h logHandler) WithGroup(name string) slog.Handler {
+	return logHandler{h.Handler.WithGroup(name)}
+}
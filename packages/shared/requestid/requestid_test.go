@@ -0,0 +1,79 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, FromContext(ctx))
+	assert.Nil(t, Ptr(ctx))
+
+	ctx = NewContext(ctx, "req-1")
+
+	assert.Equal(t, "req-1", FromContext(ctx))
+	require.NotNil(t, Ptr(ctx))
+	assert.Equal(t, "req-1", *Ptr(ctx))
+}
+
+func TestNew(t *testing.T) {
+	id := New()
+
+	assert.True(t, Valid(id))
+	assert.NotEqual(t, id, New())
+}
+
+func TestValid(t *testing.T) {
+	testCases := []struct {
+		id    string
+		valid bool
+	}{
+		{"3f2c1e7a-0c1b-4a57-9a5e-2b1f0d8c9e11", true},
+		{"lb:abc_123.4", true},
+		{strings.Repeat("a", 128), true},
+		{"", false},
+		{strings.Repeat("a", 129), false},
+		{"has space", false},
+		{"line\nbreak", false},
+		{`quote"`, false},
+		{"ünïcode", false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.valid, Valid(tc.id), "%q", tc.id)
+	}
+}
+
+func TestNewLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewLogHandler(slog.NewJSONHandler(&buf, nil))).With("component", "api")
+
+	logger.InfoContext(NewContext(context.Background(), "req-1"), "with id")
+	logger.InfoContext(context.Background(), "without id")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	var with, without map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &with))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &without))
+	assert.Equal(t, "req-1", with["request_id"])
+	assert.Equal(t, "api", with["component"], "attrs survive With")
+	assert.NotContains(t, without, "request_id")
+}
+
+func TestNewLogHandler_WrapsOnce(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewLogHandler(slog.NewJSONHandler(&buf, nil))
+
+	slog.New(NewLogHandler(h)).InfoContext(NewContext(context.Background(), "req-1"), "wrapped twice")
+
+	assert.Equal(t, 1, strings.Count(buf.String(), `"request_id"`))
+}
@@ -0,0 +1,222 @@
+// Package retention deletes logs that have outlived the retention period of
+// their event type.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// Store is the subset of *repository.Store the pruner reads and deletes logs
+// through.
+type Store interface {
+	ListLogEventTypes(ctx context.Context) ([]string, error)
+	CountLogsOlderThan(ctx context.Context, arg repository.CountLogsOlderThanParams) (int64, error)
+	DeleteLogsBatch(ctx context.Context, arg repository.DeleteLogsBatchParams) (int64, error)
+}
+
+// Metrics records what the pruner deletes. *metrics.Metrics implements it.
+type Metrics interface {
+	// LogsPruned counts n logs of eventType deleted.
+	LogsPruned(eventType string, n int)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) LogsPruned(string, int) {}
+
+// Pruned is what a run deleted, or in dry-run mode would delete, of one
+// event type.
+type Pruned struct {
+	EventType string
+	// Cutoff is the time logs older than were pruned.
+	Cutoff time.Time
+	Logs   int64
+}
+
+// Summary is the outcome of a run.
+type Summary struct {
+	DryRun bool
+	// Pruned lists the event types with a retention period, by name.
+	Pruned []Pruned
+}
+
+// Total returns how many logs the run pruned.
+func (s *Summary) Total() int64 {
+	var n int64
+	for _, p := range s.Pruned {
+		n += p.Logs
+	}
+	return n
+}
+
+// Pruner deletes the logs of each event type older than its retention period.
+// Logs go at most BatchSize at a time, oldest first, with BatchPause between
+// deletes, so no run holds a large transaction open on the cluster.
+type Pruner struct {
+	store   Store
+	metrics Metrics
+	logger  *slog.Logger
+
+	cfg   config.RetentionConfig
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Option customises a Pruner.
+type Option func(*Pruner)
+
+// WithLogger replaces slog.Default.
+func WithLogger(l *slog.Logger) Option {
+	return func(p *Pruner) { p.logger = l }
+}
+
+// WithMetrics counts the logs every run deletes in m.
+func WithMetrics(m Metrics) Option {
+	return func(p *Pruner) { p.metrics = m }
+}
+
+// WithDryRun overrides retention.dryRun.
+func WithDryRun(dryRun bool) Option {
+	return func(p *Pruner) { p.cfg.DryRun = dryRun }
+}
+
+// New builds a pruner using the retention section of cfg.
+func New(store Store, cfg *config.Config, opts ...Option) *Pruner {
+	p := &Pruner{
+		store:   store,
+		metrics: nopMetrics{},
+		logger:  slog.Default(),
+		cfg:     cfg.Retention,
+		now:     time.Now,
+		sleep:   sleep,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Run prunes every interval until ctx is done.
+func (p *Pruner) Run(ctx context.Context) error {
+	p.logger.Info("log pruner started", "interval", p.cfg.Interval.Std(), "dry_run", p.cfg.DryRun)
+	for {
+		if _, err := p.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			p.logger.ErrorContext(ctx, "log pruning failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			p.logger.Info("log pruner stopped")
+			return nil
+		case <-time.After(p.cfg.Interval.Std()):
+		}
+	}
+}
+
+// RunOnce prunes the logs that are past their retention period now. In
+// dry-run mode it only counts them. A failure stops the run; the summary
+// still holds what was pruned before it.
+func (p *Pruner) RunOnce(ctx context.Context) (*Summary, error) {
+	summary := &Summary{DryRun: p.cfg.DryRun}
+	defer p.logSummary(ctx, summary)
+
+	eventTypes, err := p.eventTypes(ctx)
+	if err != nil {
+		return summary, err
+	}
+	now := p.now().UTC()
+	deleting := false
+	for _, eventType := range eventTypes {
+		period := p.cfg.Period(eventType)
+		if period <= 0 {
+			continue
+		}
+		summary.Pruned = append(summary.Pruned, Pruned{EventType: eventType, Cutoff: now.Add(-period)})
+		pruned := &summary.Pruned[len(summary.Pruned)-1]
+		cutoff := pgtype.Timestamptz{Time: pruned.Cutoff, Valid: true}
+
+		if p.cfg.DryRun {
+			n, err := p.store.CountLogsOlderThan(ctx, repository.CountLogsOlderThanParams{EventType: eventType, CreatedAt: cutoff})
+			if err != nil {
+				return summary, fmt.Errorf("failed to count %s logs: %w", eventType, err)
+			}
+			pruned.Logs = n
+			continue
+		}
+		for {
+			if deleting {
+				if err := p.sleep(ctx, p.cfg.BatchPause.Std()); err != nil {
+					return summary, err
+				}
+			}
+			deleting = true
+			n, err := p.store.DeleteLogsBatch(ctx, repository.DeleteLogsBatchParams{
+				EventType:     eventType,
+				CreatedBefore: cutoff,
+				Limit:         int32(p.cfg.BatchSize),
+			})
+			if err != nil {
+				return summary, fmt.Errorf("failed to delete %s logs: %w", eventType, err)
+			}
+			pruned.Logs += n
+			p.metrics.LogsPruned(eventType, int(n))
+			if n < int64(p.cfg.BatchSize) {
+				break
+			}
+		}
+	}
+	return summary, nil
+}
+
+// eventTypes returns the event types to consider, by name. Only the
+// configured ones have a period unless there is a default, which saves
+// listing every type in the table.
+func (p *Pruner) eventTypes(ctx context.Context) ([]string, error) {
+	if p.cfg.Default <= 0 {
+		types := make([]string, 0, len(p.cfg.Periods))
+		for eventType := range p.cfg.Periods {
+			types = append(types, eventType)
+		}
+		sort.Strings(types)
+		return types, nil
+	}
+	types, err := p.store.ListLogEventTypes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list log event types: %w", err)
+	}
+	return types, nil
+}
+
+func (p *Pruner) logSummary(ctx context.Context, s *Summary) {
+	msg := "logs pruned"
+	if s.DryRun {
+		msg = "logs due for pruning"
+	}
+	for _, pruned := range s.Pruned {
+		if pruned.Logs > 0 {
+			p.logger.InfoContext(ctx, msg, "event_type", pruned.EventType, "cutoff", pruned.Cutoff,
+				"logs", pruned.Logs, "dry_run", s.DryRun)
+		}
+	}
+	p.logger.InfoContext(ctx, "log pruning finished", "event_types", len(s.Pruned), "logs", s.Total(), "dry_run", s.DryRun)
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,200 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+var testNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+const day = 24 * time.Hour
+
+// memStore keeps the creation times of logs by event type.
+type memStore struct {
+	logs map[string][]time.Time
+	// deletes records the limit of every delete.
+	deletes []int32
+	listed  bool
+	// failDelete fails the delete after this many succeeded, when positive.
+	failDelete int
+}
+
+func newMemStore() *memStore {
+	return &memStore{logs: map[string][]time.Time{}}
+}
+
+// add stores n logs of eventType created age before testNow.
+func (s *memStore) add(eventType string, n int, age time.Duration) {
+	for i := 0; i < n; i++ {
+		s.logs[eventType] = append(s.logs[eventType], testNow.Add(-age))
+	}
+	sort.Slice(s.logs[eventType], func(i, j int) bool { return s.logs[eventType][i].Before(s.logs[eventType][j]) })
+}
+
+func (s *memStore) ListLogEventTypes(context.Context) ([]string, error) {
+	s.listed = true
+	types := make([]string, 0, len(s.logs))
+	for t := range s.logs {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types, nil
+}
+
+func (s *memStore) older(eventType string, cutoff time.Time) int {
+	n := 0
+	for n < len(s.logs[eventType]) && s.logs[eventType][n].Before(cutoff) {
+		n++
+	}
+	return n
+}
+
+func (s *memStore) CountLogsOlderThan(_ context.Context, arg repository.CountLogsOlderThanParams) (int64, error) {
+	return int64(s.older(arg.EventType, arg.CreatedAt.Time)), nil
+}
+
+func (s *memStore) DeleteLogsBatch(_ context.Context, arg repository.DeleteLogsBatchParams) (int64, error) {
+	if s.failDelete > 0 && len(s.deletes) == s.failDelete {
+		return 0, errors.New("restart transaction")
+	}
+	s.deletes = append(s.deletes, arg.Limit)
+	n := min(s.older(arg.EventType, arg.CreatedBefore.Time), int(arg.Limit))
+	s.logs[arg.EventType] = s.logs[arg.EventType][n:]
+	return int64(n), nil
+}
+
+type recordedMetrics map[string]int
+
+func (m recordedMetrics) LogsPruned(eventType string, n int) { m[eventType] += n }
+
+// newTestPruner returns a pruner over store that records its pauses.
+func newTestPruner(store Store, edit func(*config.RetentionConfig), opts ...Option) (*Pruner, recordedMetrics, *[]time.Duration) {
+	cfg := &config.Config{Retention: config.RetentionConfig{
+		Periods: map[string]config.Duration{
+			"TX_DETECTED":  config.Duration(30 * day),
+			"WEBHOOK_SENT": config.Duration(90 * day),
+			"ERROR":        0,
+		},
+	}}
+	cfg.ApplyDefaults()
+	if edit != nil {
+		edit(&cfg.Retention)
+	}
+	m := recordedMetrics{}
+	opts = append([]Option{WithMetrics(m), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, opts...)
+	p := New(store, cfg, opts...)
+	p.now = func() time.Time { return testNow }
+	var pauses []time.Duration
+	p.sleep = func(ctx context.Context, d time.Duration) error {
+		pauses = append(pauses, d)
+		return ctx.Err()
+	}
+	return p, m, &pauses
+}
+
+func TestRunOnce_PrunesInBatches(t *testing.T) {
+	store := newMemStore()
+	store.add("TX_DETECTED", 2500, 31*day)
+	store.add("TX_DETECTED", 5, 29*day)
+	store.add("WEBHOOK_SENT", 3, 91*day)
+	store.add("WEBHOOK_SENT", 7, 31*day)
+	store.add("ERROR", 10, 1000*day)
+	store.add("ADDRESS_GENERATED", 10, 1000*day)
+	p, m, pauses := newTestPruner(store, nil)
+
+	summary, err := p.RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.False(t, summary.DryRun)
+	assert.Equal(t, []Pruned{
+		{EventType: "TX_DETECTED", Cutoff: testNow.Add(-30 * day), Logs: 2500},
+		{EventType: "WEBHOOK_SENT", Cutoff: testNow.Add(-90 * day), Logs: 3},
+	}, summary.Pruned, "ERROR is kept forever and unlisted types without a default are left alone")
+	assert.Equal(t, int64(2503), summary.Total())
+	assert.Equal(t, []int32{1000, 1000, 1000, 1000}, store.deletes, "the third TX_DETECTED batch falls short and ends the type")
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond}, *pauses,
+		"every delete after the first waits")
+	assert.Equal(t, recordedMetrics{"TX_DETECTED": 2500, "WEBHOOK_SENT": 3}, m)
+	assert.Len(t, store.logs["TX_DETECTED"], 5)
+	assert.Len(t, store.logs["WEBHOOK_SENT"], 7)
+	assert.Len(t, store.logs["ERROR"], 10)
+	assert.Len(t, store.logs["ADDRESS_GENERATED"], 10)
+	assert.False(t, store.listed, "without a default only the configured types are pruned")
+}
+
+func TestRunOnce_Default(t *testing.T) {
+	store := newMemStore()
+	store.add("ADDRESS_GENERATED", 4, 400*day)
+	store.add("ERROR", 4, 400*day)
+	p, m, _ := newTestPruner(store, func(r *config.RetentionConfig) { r.Default = config.Duration(365 * day) })
+
+	summary, err := p.RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.True(t, store.listed)
+	assert.Equal(t, []Pruned{{EventType: "ADDRESS_GENERATED", Cutoff: testNow.Add(-365 * day), Logs: 4}}, summary.Pruned,
+		"a zero period overrides the default")
+	assert.Equal(t, recordedMetrics{"ADDRESS_GENERATED": 4}, m)
+	assert.Len(t, store.logs["ERROR"], 4)
+}
+
+func TestRunOnce_DryRun(t *testing.T) {
+	store := newMemStore()
+	store.add("TX_DETECTED", 1500, 31*day)
+	store.add("WEBHOOK_SENT", 2, 1*day)
+	p, m, pauses := newTestPruner(store, nil, WithDryRun(true))
+
+	summary, err := p.RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.True(t, summary.DryRun)
+	assert.Equal(t, int64(1500), summary.Total())
+	assert.Empty(t, store.deletes)
+	assert.Empty(t, m)
+	assert.Empty(t, *pauses)
+	assert.Len(t, store.logs["TX_DETECTED"], 1500)
+}
+
+func TestRunOnce_DeleteFails(t *testing.T) {
+	store := newMemStore()
+	store.add("TX_DETECTED", 2500, 31*day)
+	store.failDelete = 2
+	p, m, _ := newTestPruner(store, nil)
+
+	summary, err := p.RunOnce(context.Background())
+
+	require.ErrorContains(t, err, "failed to delete TX_DETECTED logs: restart transaction")
+	assert.Equal(t, int64(2000), summary.Total(), "the summary keeps what was pruned")
+	assert.Equal(t, recordedMetrics{"TX_DETECTED": 2000}, m)
+}
+
+func TestRunOnce_StopsWhilePausing(t *testing.T) {
+	store := newMemStore()
+	store.add("TX_DETECTED", 2500, 31*day)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p, _, _ := newTestPruner(store, nil)
+
+	_, err := p.RunOnce(ctx)
+
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, store.deletes, 1, "the first delete goes ahead; the pause before the next sees the cancellation")
+}
+
+func TestSleep(t *testing.T) {
+	require.NoError(t, sleep(context.Background(), time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, sleep(ctx, time.Hour), context.Canceled)
+}
@@ -0,0 +1,115 @@
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// ServerTLS loads the server side of cfg: the server's certificate and, when
+// cfg names a CA, the requirement that callers present a certificate it
+// signed.
+func ServerTLS(cfg config.GRPCTLSConfig) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load grpc server certificate: %w", err)
+	}
+	c := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if cfg.CAFile != "" {
+		if c.ClientCAs, err = loadCA(cfg.CAFile); err != nil {
+			return nil, err
+		}
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return c, nil
+}
+
+// ClientTLS loads the client side of cfg: the CA the server is verified
+// against, the system roots when there is none, and the client's own
+// certificate if cfg has one.
+func ClientTLS(cfg config.GRPCTLSConfig) (*tls.Config, error) {
+	c := &tls.Config{MinVersion: tls.VersionTLS12}
+	var err error
+	if cfg.CAFile != "" {
+		if c.RootCAs, err = loadCA(cfg.CAFile); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load grpc client certificate: %w", err)
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+	return c, nil
+}
+
+func loadCA(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read grpc CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates in grpc CA %s", path)
+	}
+	return pool, nil
+}
+
+// Dial connects to the gRPC API at the grpc.target of cfg, over TLS when
+// grpc.tls is set. Every call carries the shared secret when there is one;
+// without it the connection must present a client certificate.
+func Dial(cfg *config.Config, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	if cfg.GRPC.Target == "" {
+		return nil, errors.New("grpc.target is required to dial the payment service")
+	}
+	secret, secretErr := cfg.GRPCSecret()
+	if secretErr != nil && !cfg.GRPC.TLS.Mutual() {
+		return nil, fmt.Errorf("no grpc credentials: %w", secretErr)
+	}
+
+	creds := insecure.NewCredentials()
+	if cfg.GRPC.TLS.Enabled() {
+		tlsConfig, err := ClientTLS(cfg.GRPC.TLS)
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, opts...)
+	if secretErr == nil {
+		opts = append(opts, grpc.WithPerRPCCredentials(secretCredentials{
+			secret: secret,
+			secure: cfg.GRPC.TLS.Enabled(),
+		}))
+	}
+	conn, err := grpc.NewClient(cfg.GRPC.Target, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", cfg.GRPC.Target, err)
+	}
+	return conn, nil
+}
+
+// secretCredentials sends the shared secret the way authorize reads it.
+type secretCredentials struct {
+	secret string
+	// secure refuses to send the secret over a plaintext connection.
+	secure bool
+}
+
+func (c secretCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + c.secret}, nil
+}
+
+func (c secretCredentials) RequireTransportSecurity() bool {
+	return c.secure
+}
@@ -0,0 +1,144 @@
+package rpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// requestIDKey is requestid.Header as gRPC metadata keys are written.
+var requestIDKey = strings.ToLower(requestid.Header)
+
+// logUnary gives every call a request ID, taken from x-request-id metadata
+// when the caller sent a usable one, puts it in the context and the response
+// headers, and traces, logs and measures the call once it completes.
+// Messages are never logged.
+func (s *Server) logUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	ctx, span := s.startSpan(ctx, info.FullMethod)
+	ctx = s.withRequestID(ctx)
+	resp, err := handler(ctx, req)
+	s.endSpan(ctx, span, err)
+	s.observe(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+// logStream is logUnary for streaming calls. The call is logged when the
+// stream ends.
+func (s *Server) logStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx, span := s.startSpan(ss.Context(), info.FullMethod)
+	ctx = s.withRequestID(ctx)
+	err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	s.endSpan(ctx, span, err)
+	s.observe(ctx, info.FullMethod, start, err)
+	return err
+}
+
+func (s *Server) withRequestID(ctx context.Context) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(requestIDKey); len(v) > 0 {
+			id = v[0]
+		}
+	}
+	if !requestid.Valid(id) {
+		id = requestid.New()
+	}
+	// The header only fails to send once the call has ended.
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id))
+	return requestid.NewContext(ctx, id)
+}
+
+func (s *Server) observe(ctx context.Context, method string, start time.Time, err error) {
+	latency := time.Since(start)
+	code := status.Code(err)
+	s.metrics.ObserveRPC(method, code.String(), latency)
+
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("latency", latency),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		attrs = append(attrs, slog.String("remote_addr", p.Addr.String()))
+	}
+	level := slog.LevelInfo
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		level = slog.LevelError
+	}
+	s.logger.LogAttrs(ctx, level, "rpc completed", attrs...)
+}
+
+// authUnary refuses calls authorize does not admit.
+func (s *Server) authUnary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// authStream is authUnary for streaming calls.
+func (s *Server) authStream(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.authorize(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// authorize admits calls made with a client certificate the TLS config
+// verified, and calls bearing the shared secret.
+func (s *Server) authorize(ctx context.Context) error {
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 {
+			return nil
+		}
+	}
+	if s.secret != nil {
+		secret := bearer(ctx)
+		got := sha256.Sum256([]byte(secret))
+		if secret != "" && subtle.ConstantTimeCompare(got[:], s.secret[:]) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid credentials")
+}
+
+// bearer reads the secret from "authorization: Bearer <secret>" metadata.
+func bearer(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	v := md.Get("authorization")
+	if len(v) == 0 {
+		return ""
+	}
+	scheme, secret, ok := strings.Cut(v[0], " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(secret)
+}
+
+// serverStream replaces the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,241 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	paymentsv1 "github.com/yaninyzwitty/tron-payment-gateway/gen/payments/v1"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Page sizes of ListPayments.
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+var (
+	errPaymentNotFound = status.Error(codes.NotFound, "payment not found")
+	errInternal        = status.Error(codes.Internal, "internal error")
+)
+
+// CreatePayment validates the request like POST /v1/payments does, applies
+// the account's defaults and creates the payment with payments.Create.
+func (s *Server) CreatePayment(ctx context.Context, req *paymentsv1.CreatePaymentRequest) (*paymentsv1.CreatePaymentResponse, error) {
+	fields := make(map[string]string)
+	clientID, err := uuid.Parse(req.GetClientId())
+	if err != nil {
+		fields["client_id"] = "must be a UUID"
+	}
+	preq := payments.Request{
+		AccountID: req.GetAccountId(),
+		Token:     req.GetToken(),
+		Amount:    req.GetAmount(),
+		ExpiresIn: req.ExpiresIn,
+	}
+	p, invalid := preq.Validate(s.payments)
+	for field, msg := range invalid {
+		fields[field] = msg
+	}
+	if len(fields) > 0 {
+		return nil, invalidArgument(fields)
+	}
+
+	account, err := s.store.GetAccountByIDAndClientID(ctx, repository.GetAccountByIDAndClientIDParams{
+		ID:       p.AccountID,
+		ClientID: clientID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, status.Error(codes.NotFound, "account not found")
+	}
+	if err != nil {
+		return nil, s.internalError(ctx, "failed to load account", err, "account_id", p.AccountID)
+	}
+	p = preq.WithAccountDefaults(p, account)
+
+	client, err := s.store.GetClientByID(ctx, clientID)
+	if err != nil {
+		return nil, s.internalError(ctx, "failed to load client", err, "client_id", clientID)
+	}
+	wallets := s.wallets
+	if client.Mode == config.ModeTest {
+		if s.testWallets == nil {
+			return nil, status.Error(codes.FailedPrecondition, "test mode is not enabled")
+		}
+		wallets = s.testWallets
+		p.Mode = config.ModeTest
+	}
+
+	payment, err := payments.Create(ctx, s.store, wallets, clientID, p, s.now())
+	if errors.Is(err, payments.ErrAccountNotFound) {
+		return nil, status.Error(codes.NotFound, "account not found")
+	}
+	if errors.Is(err, payments.ErrTooManyOpenPayments) {
+		return nil, status.Errorf(codes.ResourceExhausted, "account has %d open payments, the most allowed", p.MaxActive)
+	}
+	if errors.Is(err, payments.ErrSuffixesExhausted) {
+		return nil, status.Error(codes.ResourceExhausted, "every amount suffix of the account is held by a recent payment")
+	}
+	if errors.Is(err, payments.ErrAmountTooPrecise) {
+		return nil, invalidArgument(map[string]string{"amount": fmt.Sprintf("must have at most %d decimal places for an %s account",
+			payments.AmountDecimals-payments.SuffixDigits, payments.StrategyAmountSuffix)})
+	}
+	if errors.Is(err, payments.ErrDerivationHalted) {
+		return nil, status.Error(codes.Unavailable, "new deposit wallets cannot be generated right now")
+	}
+	if err != nil {
+		return nil, s.internalError(ctx, "failed to create payment", err, "account_id", p.AccountID)
+	}
+	s.logger.InfoContext(ctx, "payment created", "payment_id", payment.ID, "client_id", clientID,
+		"account_id", p.AccountID, "wallet", payment.UniqueWallet)
+	return &paymentsv1.CreatePaymentResponse{Payment: paymentMessage(payment)}, nil
+}
+
+// GetPayment returns a payment of the client. Payments of other clients are
+// reported as not found.
+func (s *Server) GetPayment(ctx context.Context, req *paymentsv1.GetPaymentRequest) (*paymentsv1.GetPaymentResponse, error) {
+	clientID, id, err := parseIDs(req.GetClientId(), req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	payment, err := s.clientPayment(ctx, clientID, id)
+	if err != nil {
+		return nil, err
+	}
+	return &paymentsv1.GetPaymentResponse{Payment: paymentMessage(payment)}, nil
+}
+
+// ListPayments pages through the client's payments in id order. The page
+// token is the id of the last payment of the previous page.
+func (s *Server) ListPayments(ctx context.Context, req *paymentsv1.ListPaymentsRequest) (*paymentsv1.ListPaymentsResponse, error) {
+	fields := make(map[string]string)
+	clientID, err := uuid.Parse(req.GetClientId())
+	if err != nil {
+		fields["client_id"] = "must be a UUID"
+	}
+	limit := int(req.GetPageSize())
+	if limit == 0 {
+		limit = defaultPageSize
+	} else if limit < 0 || limit > maxPageSize {
+		fields["page_size"] = fmt.Sprintf("must be between 1 and %d", maxPageSize)
+	}
+	var cursor uuid.UUID
+	if token := req.GetPageToken(); token != "" {
+		if cursor, err = uuid.Parse(token); err != nil {
+			fields["page_token"] = "must be a next_page_token from an earlier page"
+		}
+	}
+	if len(fields) > 0 {
+		return nil, invalidArgument(fields)
+	}
+
+	// One extra row tells whether there is a next page.
+	rows, err := s.store.ListClientPayments(ctx, repository.ListClientPaymentsParams{
+		ClientID: clientID,
+		AfterID:  cursor,
+		Limit:    int32(limit + 1),
+	})
+	if err != nil {
+		return nil, s.internalError(ctx, "failed to list payments", err, "client_id", clientID)
+	}
+
+	resp := &paymentsv1.ListPaymentsResponse{Payments: make([]*paymentsv1.Payment, 0, min(len(rows), limit))}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		resp.NextPageToken = rows[limit-1].ID.String()
+	}
+	for _, payment := range rows {
+		resp.Payments = append(resp.Payments, paymentMessage(payment))
+	}
+	return resp, nil
+}
+
+// parseIDs parses the client and payment ids of a request. A malformed
+// payment id is reported as not found, like an unknown one.
+func parseIDs(clientID, id string) (uuid.UUID, uuid.UUID, error) {
+	client, err := uuid.Parse(clientID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, invalidArgument(map[string]string{"client_id": "must be a UUID"})
+	}
+	paymentID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, errPaymentNotFound
+	}
+	return client, paymentID, nil
+}
+
+// clientPayment loads payment id, reporting an unknown payment and another
+// client's payment alike as not found.
+func (s *Server) clientPayment(ctx context.Context, clientID, id uuid.UUID) (repository.Payment, error) {
+	payment, err := s.store.GetPayment(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return repository.Payment{}, errPaymentNotFound
+	}
+	if err != nil {
+		return repository.Payment{}, s.internalError(ctx, "failed to load payment", err, "payment_id", id)
+	}
+	if payment.ClientID != clientID {
+		return repository.Payment{}, errPaymentNotFound
+	}
+	return payment, nil
+}
+
+func (s *Server) internalError(ctx context.Context, msg string, err error, args ...any) error {
+	s.logger.ErrorContext(ctx, msg, append(args, "error", err)...)
+	return errInternal
+}
+
+// invalidArgument reports fields, each with what is wrong with it, as a
+// BadRequest detail.
+func invalidArgument(fields map[string]string) error {
+	br := &errdetails.BadRequest{}
+	for _, field := range slices.Sorted(maps.Keys(fields)) {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: fields[field],
+		})
+	}
+	st, err := status.New(codes.InvalidArgument, "request has invalid fields").WithDetails(br)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "request has invalid fields")
+	}
+	return st.Err()
+}
+
+// paymentMessage converts payment to its protobuf form.
+func paymentMessage(payment repository.Payment) *paymentsv1.Payment {
+	msg := &paymentsv1.Payment{
+		Id:        payment.ID.String(),
+		ClientId:  payment.ClientID.String(),
+		AccountId: payment.AccountID.String(),
+		Wallet:    payment.UniqueWallet,
+		Token:     payment.Token,
+		Amount:    payments.FormatAmount(repository.NumericToDecimal(payment.Amount), payment.Token),
+		Status:    payment.Status,
+		ExpiresAt: timestamp(payment.ExpiresAt),
+		CreatedAt: timestamp(payment.CreatedAt),
+	}
+	if payment.ConfirmedAt.Valid {
+		msg.ConfirmedAt = timestamp(payment.ConfirmedAt)
+	}
+	if payment.AttemptCount != nil {
+		msg.AttemptCount = *payment.AttemptCount
+	}
+	return msg
+}
+
+func timestamp(t pgtype.Timestamptz) *timestamppb.Timestamp {
+	return timestamppb.New(t.Time)
+}
@@ -0,0 +1,464 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	paymentsv1 "github.com/yaninyzwitty/tron-payment-gateway/gen/payments/v1"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/walletcheck"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func storedPayment() repository.Payment {
+	attempts := int32(1)
+	return repository.Payment{
+		ID:           testPaymentID,
+		ClientID:     testClientID,
+		AccountID:    testAccountID,
+		Amount:       repository.DecimalToNumeric(decimal.RequireFromString("25.5")),
+		UniqueWallet: "TWallet7",
+		Token:        "USDT",
+		Status:       repository.PaymentPending,
+		ExpiresAt:    pgtype.Timestamptz{Time: t0.Add(30 * time.Minute), Valid: true},
+		AttemptCount: &attempts,
+		CreatedAt:    pgtype.Timestamptz{Time: t0, Valid: true},
+	}
+}
+
+// fieldViolations returns the BadRequest details of err by field.
+func fieldViolations(t *testing.T, err error) map[string]string {
+	t.Helper()
+	st := status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	fields := make(map[string]string)
+	for _, d := range st.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			for _, v := range br.GetFieldViolations() {
+				fields[v.GetField()] = v.GetDescription()
+			}
+		}
+	}
+	return fields
+}
+
+// expectClient expects the client to be loaded in mode.
+func expectClient(store *mockStore, mode string) {
+	store.On("GetClientByID", mock.Anything, testClientID).Return(repository.Client{
+		ID:   testClientID,
+		Name: "shop",
+		Mode: mode,
+	}, nil)
+}
+
+func expectCreate(store *mockStore, token, amount string, expiresAt time.Time) {
+	index := int64(7)
+	store.On("GetAccountByIDAndClientID", mock.Anything, repository.GetAccountByIDAndClientIDParams{
+		ID:       testAccountID,
+		ClientID: testClientID,
+	}).Return(repository.Account{ID: testAccountID, ClientID: testClientID}, nil)
+	expectClient(store, config.ModeLive)
+	store.On("NextWalletIndex", mock.Anything, "deposit").Return(index, nil)
+	store.On("CreatePayment", mock.Anything, mock.MatchedBy(func(arg repository.CreatePaymentParams) bool {
+		return arg.ClientID == testClientID &&
+			arg.AccountID == testAccountID &&
+			decimal.NewFromBigInt(arg.Amount.Int, arg.Amount.Exp).String() == amount &&
+			arg.UniqueWallet == "TWallet7" &&
+			arg.ExpiresAt.Time.Equal(expiresAt) &&
+			arg.Token == token &&
+			arg.Mode == config.ModeLive
+	})).Return(repository.Payment{
+		ID:           testPaymentID,
+		ClientID:     testClientID,
+		AccountID:    testAccountID,
+		Amount:       repository.DecimalToNumeric(decimal.RequireFromString(amount)),
+		UniqueWallet: "TWallet7",
+		Token:        token,
+		Status:       repository.PaymentPending,
+		ExpiresAt:    pgtype.Timestamptz{Time: expiresAt, Valid: true},
+		CreatedAt:    pgtype.Timestamptz{Time: t0, Valid: true},
+	}, nil)
+	store.On("CreatePaymentAttempt", mock.Anything, repository.CreatePaymentAttemptParams{
+		AttemptNumber:   1,
+		PaymentID:       testPaymentID,
+		GeneratedWallet: "TWallet7",
+		WalletIndex:     &index,
+	}).Return(repository.PaymentAttempt{}, nil)
+	store.On("CreateLog", mock.Anything, mock.MatchedBy(func(arg repository.CreateLogParams) bool {
+		return *arg.PaymentID == testPaymentID && arg.EventType == payments.EventAddressGenerated
+	})).Return(nil)
+}
+
+func TestCreatePayment(t *testing.T) {
+	client, store := newTestClient(t)
+	expectCreate(store, "TRX", "12.5", t0.Add(10*time.Minute))
+	expiresIn := int64(600)
+
+	resp, err := client.CreatePayment(context.Background(), &paymentsv1.CreatePaymentRequest{
+		ClientId:  testClientID.String(),
+		AccountId: testAccountID.String(),
+		Token:     "trx",
+		Amount:    "12.5",
+		ExpiresIn: &expiresIn,
+	})
+
+	require.NoError(t, err)
+	p := resp.GetPayment()
+	assert.Equal(t, testPaymentID.String(), p.GetId())
+	assert.Equal(t, testClientID.String(), p.GetClientId())
+	assert.Equal(t, testAccountID.String(), p.GetAccountId())
+	assert.Equal(t, "TWallet7", p.GetWallet())
+	assert.Equal(t, "TRX", p.GetToken())
+	assert.Equal(t, "12.500000", p.GetAmount())
+	assert.Equal(t, repository.PaymentPending, p.GetStatus())
+	assert.True(t, p.GetExpiresAt().AsTime().Equal(t0.Add(10*time.Minute)))
+	assert.Nil(t, p.GetConfirmedAt())
+}
+
+func TestCreatePayment_Defaults(t *testing.T) {
+	client, store := newTestClient(t)
+	expectCreate(store, "USDT", "100", t0.Add(30*time.Minute))
+
+	resp, err := client.CreatePayment(context.Background(), &paymentsv1.CreatePaymentRequest{
+		ClientId:  testClientID.String(),
+		AccountId: testAccountID.String(),
+		Amount:    "100",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "USDT", resp.GetPayment().GetToken(), "the first supported token")
+}
+
+func TestCreatePayment_TestClient(t *testing.T) {
+	client, store := newTestClient(t, WithTestWallets(stubTestWallets{}))
+	index := int64(3)
+	store.On("GetAccountByIDAndClientID", mock.Anything, mock.Anything).Return(repository.Account{ID: testAccountID}, nil)
+	expectClient(store, config.ModeTest)
+	store.On("NextWalletIndex", mock.Anything, "deposit_test").Return(index, nil)
+	store.On("CreatePayment", mock.Anything, mock.MatchedBy(func(arg repository.CreatePaymentParams) bool {
+		return arg.UniqueWallet == "TTest3" && arg.Mode == config.ModeTest
+	})).Return(repository.Payment{
+		ID:           testPaymentID,
+		ClientID:     testClientID,
+		AccountID:    testAccountID,
+		Amount:       repository.DecimalToNumeric(decimal.RequireFromString("10")),
+		UniqueWallet: "TTest3",
+		Token:        "USDT",
+		Status:       repository.PaymentPending,
+		Mode:         config.ModeTest,
+		ExpiresAt:    pgtype.Timestamptz{Time: t0.Add(30 * time.Minute), Valid: true},
+		CreatedAt:    pgtype.Timestamptz{Time: t0, Valid: true},
+	}, nil)
+	store.On("CreatePaymentAttempt", mock.Anything, mock.Anything).Return(repository.PaymentAttempt{}, nil)
+	store.On("CreateLog", mock.Anything, mock.Anything).Return(nil)
+
+	resp, err := client.CreatePayment(context.Background(), &paymentsv1.CreatePaymentRequest{
+		ClientId:  testClientID.String(),
+		AccountId: testAccountID.String(),
+		Amount:    "10",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "TTest3", resp.GetPayment().GetWallet(), "the wallet comes from the test mnemonic")
+}
+
+func TestCreatePayment_TestModeDisabled(t *testing.T) {
+	client, store := newTestClient(t)
+	store.On("GetAccountByIDAndClientID", mock.Anything, mock.Anything).Return(repository.Account{ID: testAccountID}, nil)
+	expectClient(store, config.ModeTest)
+
+	_, err := client.CreatePayment(context.Background(), &paymentsv1.CreatePaymentRequest{
+		ClientId:  testClientID.String(),
+		AccountId: testAccountID.String(),
+		Amount:    "10",
+	})
+
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, "test mode is not enabled", status.Convert(err).Message())
+	store.AssertNotCalled(t, "NextWalletIndex", mock.Anything, mock.Anything)
+}
+
+func TestCreatePayment_InvalidFields(t *testing.T) {
+	client, _ := newTestClient(t)
+	expiresIn := int64(5)
+
+	_, err := client.CreatePayment(context.Background(), &paymentsv1.CreatePaymentRequest{
+		ClientId:  "shop",
+		Token:     "BTC",
+		Amount:    "-1",
+		ExpiresIn: &expiresIn,
+	})
+
+	assert.Equal(t, map[string]string{
+		"client_id":  "must be a UUID",
+		"account_id": "is required",
+		"token":      "must be one of USDT, TRX",
+		"amount":     "must be positive",
+		"expires_in": "must be between 60 and 86400 seconds",
+	}, fieldViolations(t, err), "every mistake is reported at once")
+}
+
+func TestCreatePayment_AccountNotFound(t *testing.T) {
+	client, store := newTestClient(t)
+	store.On("GetAccountByIDAndClientID", mock.Anything, mock.Anything).Return(repository.Account{}, pgx.ErrNoRows)
+
+	_, err := client.CreatePayment(context.Background(), &paymentsv1.CreatePaymentRequest{
+		ClientId:  testClientID.String(),
+		AccountId: testAccountID.String(),
+		Amount:    "10",
+	})
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, "account not found", status.Convert(err).Message())
+}
+
+func TestCreatePayment_TooManyOpenPayments(t *testing.T) {
+	s, store := newTestServer(t)
+	s.payments.MaxActivePerAccount = 3
+	lis, _ := start(t, s)
+	client := dial(t, lis, withSecret(testSecret))
+	store.On("GetAccountByIDAndClientID", mock.Anything, mock.Anything).Return(repository.Account{ID: testAccountID}, nil)
+	expectClient(store, config.ModeLive)
+	store.On("CountPendingPaymentsByAccountID", mock.Anything, testAccountID).Return(int64(3), nil)
+
+	_, err := client.CreatePayment(context.Background(), &paymentsv1.CreatePaymentRequest{
+		ClientId:  testClientID.String(),
+		AccountId: testAccountID.String(),
+		Amount:    "10",
+	})
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, "account has 3 open payments, the most allowed", status.Convert(err).Message())
+	store.AssertNotCalled(t, "NextWalletIndex", mock.Anything, mock.Anything)
+}
+
+func TestCreatePayment_StoreError(t *testing.T) {
+	client, store := newTestClient(t)
+	store.On("GetAccountByIDAndClientID", mock.Anything, mock.Anything).Return(repository.Account{ID: testAccountID}, nil)
+	expectClient(store, config.ModeLive)
+	store.On("NextWalletIndex", mock.Anything, "deposit").Return(int64(0), errors.New("db down"))
+
+	_, err := client.CreatePayment(context.Background(), &paymentsv1.CreatePaymentRequest{
+		ClientId:  testClientID.String(),
+		AccountId: testAccountID.String(),
+		Amount:    "10",
+	})
+
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, "internal error", status.Convert(err).Message(), "the cause is logged, not returned")
+}
+
+func TestCreatePayment_DerivationHalted(t *testing.T) {
+	s, store := newTestServer(t)
+	s.wallets = walletcheck.Halted()
+	lis, _ := start(t, s)
+	client := dial(t, lis, withSecret(testSecret))
+	store.On("GetAccountByIDAndClientID", mock.Anything, mock.Anything).Return(repository.Account{ID: testAccountID}, nil)
+	expectClient(store, config.ModeLive)
+	store.On("NextWalletIndex", mock.Anything, "deposit").Return(int64(7), nil)
+
+	_, err := client.CreatePayment(context.Background(), &paymentsv1.CreatePaymentRequest{
+		ClientId:  testClientID.String(),
+		AccountId: testAccountID.String(),
+		Amount:    "10",
+	})
+
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	store.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything)
+}
+
+func TestCreatePayment_AmountSuffix(t *testing.T) {
+	account := repository.Account{ID: testAccountID, AddressStrategy: payments.StrategyAmountSuffix}
+
+	t.Run("too precise", func(t *testing.T) {
+		client, store := newTestClient(t)
+		store.On("GetAccountByIDAndClientID", mock.Anything, mock.Anything).Return(account, nil)
+		expectClient(store, config.ModeLive)
+
+		_, err := client.CreatePayment(context.Background(), &paymentsv1.CreatePaymentRequest{
+			ClientId:  testClientID.String(),
+			AccountId: testAccountID.String(),
+			Amount:    "10.0001",
+		})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Equal(t, map[string]string{"amount": "must have at most 3 decimal places for an amount_suffix account"}, fieldViolations(t, err))
+		store.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything)
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		client, store := newTestClient(t)
+		wallet, index := "TWallet1", int64(1)
+		suffixed := account
+		suffixed.DepositWallet, suffixed.DepositWalletIndex = &wallet, &index
+		store.On("GetAccountByIDAndClientID", mock.Anything, mock.Anything).Return(suffixed, nil)
+		expectClient(store, config.ModeLive)
+		store.On("ReserveAmountSuffix", mock.Anything, mock.Anything).Return(int32(0), pgx.ErrNoRows)
+
+		_, err := client.CreatePayment(context.Background(), &paymentsv1.CreatePaymentRequest{
+			ClientId:  testClientID.String(),
+			AccountId: testAccountID.String(),
+			Amount:    "10",
+		})
+
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		store.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetPayment(t *testing.T) {
+	client, store := newTestClient(t)
+	payment := storedPayment()
+	payment.Status = repository.PaymentConfirmed
+	payment.ConfirmedAt = pgtype.Timestamptz{Time: t0.Add(5 * time.Minute), Valid: true}
+	expectPayment(store, payment)
+
+	resp, err := client.GetPayment(context.Background(), &paymentsv1.GetPaymentRequest{
+		ClientId: testClientID.String(),
+		Id:       testPaymentID.String(),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, &paymentsv1.Payment{
+		Id:           testPaymentID.String(),
+		ClientId:     testClientID.String(),
+		AccountId:    testAccountID.String(),
+		Wallet:       "TWallet7",
+		Token:        "USDT",
+		Amount:       "25.500000",
+		Status:       repository.PaymentConfirmed,
+		ExpiresAt:    timestamppb.New(t0.Add(30 * time.Minute)),
+		ConfirmedAt:  timestamppb.New(t0.Add(5 * time.Minute)),
+		AttemptCount: 1,
+		CreatedAt:    timestamppb.New(t0),
+	}, resp.GetPayment())
+}
+
+func TestGetPayment_NotFound(t *testing.T) {
+	testCases := []struct {
+		name   string
+		id     string
+		expect func(*mockStore)
+	}{
+		{"unknown", testPaymentID.String(), func(store *mockStore) {
+			store.On("GetPayment", mock.Anything, testPaymentID).Return(repository.Payment{}, pgx.ErrNoRows)
+		}},
+		{"another client's", testPaymentID.String(), func(store *mockStore) {
+			payment := storedPayment()
+			payment.ClientID = uuid.New()
+			expectPayment(store, payment)
+		}},
+		{"malformed id", "pay_1", func(*mockStore) {}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client, store := newTestClient(t)
+			tc.expect(store)
+
+			_, err := client.GetPayment(context.Background(), &paymentsv1.GetPaymentRequest{
+				ClientId: testClientID.String(),
+				Id:       tc.id,
+			})
+
+			assert.Equal(t, codes.NotFound, status.Code(err))
+		})
+	}
+}
+
+func TestGetPayment_InvalidClient(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	_, err := client.GetPayment(context.Background(), &paymentsv1.GetPaymentRequest{Id: testPaymentID.String()})
+
+	assert.Equal(t, map[string]string{"client_id": "must be a UUID"}, fieldViolations(t, err))
+}
+
+func TestListPayments(t *testing.T) {
+	client, store := newTestClient(t)
+	rows := make([]repository.Payment, 3)
+	for i := range rows {
+		rows[i] = storedPayment()
+		rows[i].ID = uuid.MustParse("44444444-4444-4444-4444-44444444444" + string(rune('1'+i)))
+	}
+	store.On("ListClientPayments", mock.Anything, repository.ListClientPaymentsParams{
+		ClientID: testClientID,
+		Limit:    3,
+	}).Return(rows, nil)
+	store.On("ListClientPayments", mock.Anything, repository.ListClientPaymentsParams{
+		ClientID: testClientID,
+		AfterID:  rows[1].ID,
+		Limit:    3,
+	}).Return(rows[2:], nil)
+
+	first, err := client.ListPayments(context.Background(), &paymentsv1.ListPaymentsRequest{
+		ClientId: testClientID.String(),
+		PageSize: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, first.GetPayments(), 2)
+	assert.Equal(t, rows[0].ID.String(), first.GetPayments()[0].GetId())
+	assert.Equal(t, rows[1].ID.String(), first.GetNextPageToken())
+
+	last, err := client.ListPayments(context.Background(), &paymentsv1.ListPaymentsRequest{
+		ClientId:  testClientID.String(),
+		PageSize:  2,
+		PageToken: first.GetNextPageToken(),
+	})
+	require.NoError(t, err)
+	require.Len(t, last.GetPayments(), 1)
+	assert.Equal(t, rows[2].ID.String(), last.GetPayments()[0].GetId())
+	assert.Empty(t, last.GetNextPageToken(), "no token on the last page")
+}
+
+func TestListPayments_DefaultPageSize(t *testing.T) {
+	client, store := newTestClient(t)
+	store.On("ListClientPayments", mock.Anything, repository.ListClientPaymentsParams{
+		ClientID: testClientID,
+		Limit:    defaultPageSize + 1,
+	}).Return(nil, nil)
+
+	resp, err := client.ListPayments(context.Background(), &paymentsv1.ListPaymentsRequest{ClientId: testClientID.String()})
+
+	require.NoError(t, err)
+	assert.Empty(t, resp.GetPayments())
+}
+
+func TestListPayments_InvalidFields(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	_, err := client.ListPayments(context.Background(), &paymentsv1.ListPaymentsRequest{
+		ClientId:  "shop",
+		PageSize:  maxPageSize + 1,
+		PageToken: "page-2",
+	})
+
+	assert.Equal(t, map[string]string{
+		"client_id":  "must be a UUID",
+		"page_size":  "must be between 1 and 200",
+		"page_token": "must be a next_page_token from an earlier page",
+	}, fieldViolations(t, err))
+}
+
+func TestListPayments_StoreError(t *testing.T) {
+	client, store := newTestClient(t)
+	store.On("ListClientPayments", mock.Anything, mock.Anything).Return(nil, errors.New("db down"))
+
+	_, err := client.ListPayments(context.Background(), &paymentsv1.ListPaymentsRequest{ClientId: testClientID.String()})
+
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
@@ -0,0 +1,188 @@
+// Package rpc serves the payment API to the gateway's internal services over
+// gRPC. Merchants keep using the HTTP API in package api; callers here are
+// trusted to act for any client, so every request names the client it is
+// for.
+package rpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+	paymentsv1 "github.com/yaninyzwitty/tron-payment-gateway/gen/payments/v1"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/events"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/logging"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// shutdownTimeout bounds how long Serve waits for in-flight calls on exit.
+const shutdownTimeout = 10 * time.Second
+
+// Store is the subset of *repository.Store the server uses.
+type Store interface {
+	GetAccountByIDAndClientID(ctx context.Context, arg repository.GetAccountByIDAndClientIDParams) (repository.Account, error)
+	GetClientByID(ctx context.Context, id uuid.UUID) (repository.Client, error)
+	GetPayment(ctx context.Context, id uuid.UUID) (repository.Payment, error)
+	ListClientPayments(ctx context.Context, arg repository.ListClientPaymentsParams) ([]repository.Payment, error)
+	ExecTx(ctx context.Context, fn func(repository.Querier) error) error
+}
+
+// Metrics records gRPC calls. *metrics.Metrics implements it.
+type Metrics interface {
+	ObserveRPC(method, code string, d time.Duration)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveRPC(string, string, time.Duration) {}
+
+// Server implements paymentsv1.PaymentServiceServer.
+type Server struct {
+	paymentsv1.UnimplementedPaymentServiceServer
+
+	store    Store
+	wallets  payments.WalletDeriver
+	bus      events.Bus
+	secret   *[sha256.Size]byte
+	tls      *tls.Config
+	logger   *slog.Logger
+	metrics  Metrics
+	tracer   trace.Tracer
+	payments config.PaymentsConfig
+	now      func() time.Time
+	grpc     *grpc.Server
+
+	// testWallets derives the wallets of test clients; nil leaves test
+	// mode off.
+	testWallets payments.WalletDeriver
+
+	// streams is cancelled when Serve returns, ending the open watches.
+	streams      context.Context
+	closeStreams context.CancelFunc
+}
+
+// Option customises a Server.
+type Option func(*Server)
+
+// WithLogger replaces slog.Default. Records logged while serving a call get
+// its request_id.
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Server) { s.logger = l }
+}
+
+// WithMetrics records every call in m.
+func WithMetrics(m Metrics) Option {
+	return func(s *Server) { s.metrics = m }
+}
+
+// WithTestWallets derives the deposit wallets of test clients with w, which
+// must use the test mnemonic. Without it the payments of test clients are
+// refused.
+func WithTestWallets(w payments.WalletDeriver) Option {
+	return func(s *Server) { s.testWallets = w }
+}
+
+// WithEventBus serves WatchPayment, streaming the status changes published
+// to bus. Without it WatchPayment is unimplemented.
+func WithEventBus(bus events.Bus) Option {
+	return func(s *Server) { s.bus = bus }
+}
+
+// WithSharedSecret admits calls bearing secret as "authorization: Bearer
+// <secret>" metadata. An empty secret is ignored.
+func WithSharedSecret(secret string) Option {
+	return func(s *Server) {
+		if secret == "" {
+			return
+		}
+		sum := sha256.Sum256([]byte(secret))
+		s.secret = &sum
+	}
+}
+
+// WithTLS serves over TLS with cfg. When cfg verifies client certificates,
+// calls presenting a verified one need no shared secret.
+func WithTLS(cfg *tls.Config) Option {
+	return func(s *Server) { s.tls = cfg }
+}
+
+// New builds a server using the payments section of cfg. A server given
+// neither WithSharedSecret nor a TLS config verifying client certificates
+// refuses every call.
+func New(store Store, wallets payments.WalletDeriver, cfg *config.Config, opts ...Option) *Server {
+	s := &Server{
+		store:    store,
+		wallets:  wallets,
+		logger:   slog.Default(),
+		metrics:  nopMetrics{},
+		tracer:   noop.NewTracerProvider().Tracer(tracerName),
+		payments: cfg.Payments,
+		now:      time.Now,
+	}
+	s.streams, s.closeStreams = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.logger = slog.New(logging.NewHandler(s.logger.Handler()))
+
+	serverOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.logUnary, s.authUnary),
+		grpc.ChainStreamInterceptor(s.logStream, s.authStream),
+	}
+	if s.tls != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(s.tls)))
+	}
+	s.grpc = grpc.NewServer(serverOpts...)
+	paymentsv1.RegisterPaymentServiceServer(s.grpc, s)
+	return s
+}
+
+// Serve serves on l until ctx is done, then ends the open watches and gives
+// in-flight calls shutdownTimeout to finish.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	errc := make(chan error, 1)
+	go func() { errc <- s.grpc.Serve(l) }()
+
+	select {
+	case err := <-errc:
+		s.closeStreams()
+		return fmt.Errorf("grpc server failed: %w", err)
+	case <-ctx.Done():
+	}
+	s.closeStreams()
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		s.grpc.Stop()
+		<-stopped
+	}
+	if err := <-errc; err != nil {
+		return fmt.Errorf("grpc server failed: %w", err)
+	}
+	return nil
+}
+
+// ListenAndServe serves on port until ctx is done.
+func (s *Server) ListenAndServe(ctx context.Context, port int) error {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d: %w", port, err)
+	}
+	return s.Serve(ctx, l)
+}
@@ -0,0 +1,373 @@
+package rpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	paymentsv1 "github.com/yaninyzwitty/tron-payment-gateway/gen/payments/v1"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testSecret = "internal-secret-0123456789abcdef"
+
+var (
+	t0            = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	testClientID  = uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	testAccountID = uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	testPaymentID = uuid.MustParse("33333333-3333-3333-3333-333333333333")
+)
+
+// mockStore is a Store whose transactions run directly on its MockQuerier.
+type mockStore struct {
+	*repository.MockQuerier
+}
+
+func (m *mockStore) ExecTx(_ context.Context, fn func(repository.Querier) error) error {
+	return fn(m.MockQuerier)
+}
+
+// stubWallets derives "TWallet<index>".
+type stubWallets struct{}
+
+func (stubWallets) DeriveWallet(index uint32) (string, error) {
+	return fmt.Sprintf("TWallet%d", index), nil
+}
+
+// stubTestWallets derives "TTest<index>".
+type stubTestWallets struct{}
+
+func (stubTestWallets) DeriveWallet(index uint32) (string, error) {
+	return fmt.Sprintf("TTest%d", index), nil
+}
+
+// recordingMetrics remembers the calls it observed as "method code".
+type recordingMetrics struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (m *recordingMetrics) ObserveRPC(method, code string, _ time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, method+" "+code)
+}
+
+func (m *recordingMetrics) observed() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.calls...)
+}
+
+func testConfig() *config.Config {
+	return &config.Config{Payments: config.PaymentsConfig{
+		DefaultExpiry:   config.Duration(30 * time.Minute),
+		MinAmount:       "1",
+		MaxAmount:       "10000",
+		SupportedTokens: []string{config.TokenUSDT, config.TokenTRX},
+	}}
+}
+
+func newTestServer(t *testing.T, opts ...Option) (*Server, *mockStore) {
+	t.Helper()
+	store := &mockStore{MockQuerier: repository.NewMockQuerier(t)}
+	opts = append([]Option{
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithSharedSecret(testSecret),
+	}, opts...)
+	s := New(store, stubWallets{}, testConfig(), opts...)
+	s.now = func() time.Time { return t0 }
+	return s, store
+}
+
+// start serves s on an in-memory listener until the test ends, or until
+// the returned stop is called.
+func start(t *testing.T, s *Server) (*bufconn.Listener, func()) {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, lis) }()
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			cancel()
+			assert.NoError(t, <-done)
+		})
+	}
+	t.Cleanup(stop)
+	return lis, stop
+}
+
+// dial connects to lis in plaintext with opts.
+func dial(t *testing.T, lis *bufconn.Listener, opts ...grpc.DialOption) paymentsv1.PaymentServiceClient {
+	t.Helper()
+	opts = append([]grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}, opts...)
+	conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return paymentsv1.NewPaymentServiceClient(conn)
+}
+
+// withSecret sends secret with every call.
+func withSecret(secret string) grpc.DialOption {
+	return grpc.WithPerRPCCredentials(secretCredentials{secret: secret})
+}
+
+// newTestClient serves a test server and returns a client authenticated with
+// the shared secret.
+func newTestClient(t *testing.T, opts ...Option) (paymentsv1.PaymentServiceClient, *mockStore) {
+	t.Helper()
+	s, store := newTestServer(t, opts...)
+	lis, _ := start(t, s)
+	return dial(t, lis, withSecret(testSecret)), store
+}
+
+func expectPayment(store *mockStore, payment repository.Payment) {
+	store.On("GetPayment", mock.Anything, payment.ID).Return(payment, nil)
+}
+
+func TestAuth_SharedSecret(t *testing.T) {
+	testCases := []struct {
+		name   string
+		opts   []grpc.DialOption
+		wantOK bool
+	}{
+		{"secret", []grpc.DialOption{withSecret(testSecret)}, true},
+		{"no credentials", nil, false},
+		{"wrong secret", []grpc.DialOption{withSecret(strings.Repeat("x", len(testSecret)))}, false},
+		{"other scheme", []grpc.DialOption{grpc.WithPerRPCCredentials(rawCredentials("Basic " + testSecret))}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store := newTestServer(t)
+			lis, _ := start(t, s)
+			client := dial(t, lis, tc.opts...)
+			if tc.wantOK {
+				expectPayment(store, storedPayment())
+			}
+
+			_, err := client.GetPayment(context.Background(), &paymentsv1.GetPaymentRequest{
+				ClientId: testClientID.String(),
+				Id:       testPaymentID.String(),
+			})
+
+			if tc.wantOK {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		})
+	}
+}
+
+// rawCredentials sends its value as the authorization metadata.
+type rawCredentials string
+
+func (c rawCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": string(c)}, nil
+}
+
+func (rawCredentials) RequireTransportSecurity() bool { return false }
+
+func TestAuth_NothingConfiguredRefusesEveryCall(t *testing.T) {
+	store := &mockStore{MockQuerier: repository.NewMockQuerier(t)}
+	s := New(store, stubWallets{}, testConfig(), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	lis, _ := start(t, s)
+	client := dial(t, lis, withSecret(testSecret))
+
+	_, err := client.GetPayment(context.Background(), &paymentsv1.GetPaymentRequest{})
+
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestAuth_ClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	tlsFiles := writeTestPKI(t, dir)
+	serverTLS, err := ServerTLS(config.GRPCTLSConfig{CertFile: tlsFiles.serverCert, KeyFile: tlsFiles.serverKey, CAFile: tlsFiles.ca})
+	require.NoError(t, err)
+
+	s, store := newTestServer(t, WithTLS(serverTLS))
+	lis, _ := start(t, s)
+
+	t.Run("verified certificate needs no secret", func(t *testing.T) {
+		clientTLS, err := ClientTLS(config.GRPCTLSConfig{CertFile: tlsFiles.clientCert, KeyFile: tlsFiles.clientKey, CAFile: tlsFiles.ca})
+		require.NoError(t, err)
+		clientTLS.ServerName = "payments.internal"
+		client := dial(t, lis, grpc.WithTransportCredentials(credentials.NewTLS(clientTLS)))
+		expectPayment(store, storedPayment())
+
+		_, err = client.GetPayment(context.Background(), &paymentsv1.GetPaymentRequest{
+			ClientId: testClientID.String(),
+			Id:       testPaymentID.String(),
+		})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("without a certificate the handshake fails", func(t *testing.T) {
+		clientTLS, err := ClientTLS(config.GRPCTLSConfig{CAFile: tlsFiles.ca})
+		require.NoError(t, err)
+		clientTLS.ServerName = "payments.internal"
+		client := dial(t, lis, grpc.WithTransportCredentials(credentials.NewTLS(clientTLS)), withSecret(testSecret))
+
+		_, err = client.GetPayment(context.Background(), &paymentsv1.GetPaymentRequest{
+			ClientId: testClientID.String(),
+			Id:       testPaymentID.String(),
+		})
+
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+}
+
+func TestLogging_RequestIDAndMetrics(t *testing.T) {
+	m := &recordingMetrics{}
+	client, store := newTestClient(t, WithMetrics(m))
+	store.On("GetPayment", mock.Anything, testPaymentID).Return(repository.Payment{}, errors.New("db down"))
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-123")
+	_, err := client.GetPayment(ctx, &paymentsv1.GetPaymentRequest{
+		ClientId: testClientID.String(),
+		Id:       testPaymentID.String(),
+	}, grpc.Header(&header))
+
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, []string{"req-123"}, header.Get("x-request-id"), "the caller's request ID is echoed")
+	assert.Equal(t, []string{"/payments.v1.PaymentService/GetPayment Internal"}, m.observed())
+}
+
+func TestLogging_GeneratesRequestID(t *testing.T) {
+	client, store := newTestClient(t)
+	expectPayment(store, storedPayment())
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "not valid!")
+	_, err := client.GetPayment(ctx, &paymentsv1.GetPaymentRequest{
+		ClientId: testClientID.String(),
+		Id:       testPaymentID.String(),
+	}, grpc.Header(&header))
+
+	require.NoError(t, err)
+	require.Len(t, header.Get("x-request-id"), 1)
+	assert.NoError(t, uuid.Validate(header.Get("x-request-id")[0]), "an unusable ID is replaced")
+}
+
+func TestDial(t *testing.T) {
+	t.Setenv(config.DefaultGRPCSecretEnv, testSecret)
+	s, store := newTestServer(t)
+	lis, _ := start(t, s)
+	expectPayment(store, storedPayment())
+
+	cfg := &config.Config{GRPC: config.GRPCConfig{Target: "passthrough:///bufnet"}}
+	cfg.Hydrate()
+	conn, err := Dial(cfg, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = paymentsv1.NewPaymentServiceClient(conn).GetPayment(context.Background(), &paymentsv1.GetPaymentRequest{
+		ClientId: testClientID.String(),
+		Id:       testPaymentID.String(),
+	})
+
+	assert.NoError(t, err, "the configured secret is sent")
+}
+
+func TestDial_Errors(t *testing.T) {
+	_, err := Dial(&config.Config{})
+	assert.EqualError(t, err, "grpc.target is required to dial the payment service")
+
+	_, err = Dial(&config.Config{GRPC: config.GRPCConfig{Target: "api:9443"}})
+	assert.ErrorContains(t, err, "no grpc credentials: grpc shared secret is empty")
+}
+
+type testPKI struct {
+	ca, serverCert, serverKey, clientCert, clientKey string
+}
+
+// writeTestPKI writes a CA and a server and client certificate it signed to
+// dir.
+func writeTestPKI(t *testing.T, dir string) testPKI {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	files := testPKI{ca: filepath.Join(dir, "ca.pem")}
+	writePEM(t, files.ca, "CERTIFICATE", caDER)
+	issue := func(name string, serial int64, usage x509.ExtKeyUsage) (string, string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			DNSNames:     []string{name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+		certPath, keyPath := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+		writePEM(t, certPath, "CERTIFICATE", der)
+		writePEM(t, keyPath, "PRIVATE KEY", keyDER)
+		return certPath, keyPath
+	}
+	files.serverCert, files.serverKey = issue("payments.internal", 2, x509.ExtKeyUsageServerAuth)
+	files.clientCert, files.clientKey = issue("watcher.internal", 3, x509.ExtKeyUsageClientAuth)
+	return files
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+}
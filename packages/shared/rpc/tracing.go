@@ -0,0 +1,79 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/requestid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// tracerName identifies the spans the server records.
+const tracerName = "github.com/yaninyzwitty/tron-payment-gateway/packages/shared/rpc"
+
+// WithTracerProvider records a server span for every call, continuing the
+// trace of a caller that sent traceparent metadata.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(s *Server) { s.tracer = tp.Tracer(tracerName) }
+}
+
+// startSpan starts the server span of a call to method, a full method name
+// such as "/payments.v1.PaymentService/CreatePayment".
+func (s *Server) startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = tracing.Propagator.Extract(ctx, metadataCarrier(md))
+	service, name, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	return s.tracer.Start(ctx, strings.TrimPrefix(method, "/"),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.service", service),
+			attribute.String("rpc.method", name),
+		))
+}
+
+// endSpan records the status the call ended with. Only the codes logged as
+// errors mark the span failed; the rest are the caller's doing.
+func (s *Server) endSpan(ctx context.Context, span trace.Span, err error) {
+	defer span.End()
+	if !span.IsRecording() {
+		return
+	}
+	code := status.Code(err)
+	span.SetAttributes(
+		attribute.Int("rpc.grpc.status_code", int(code)),
+		attribute.String("request_id", requestid.FromContext(ctx)),
+	)
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+	}
+}
+
+// metadataCarrier reads trace context from incoming gRPC metadata.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,84 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	paymentsv1 "github.com/yaninyzwitty/tron-payment-gateway/gen/payments/v1"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestTracing_ServerSpan(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	client, store := newTestClient(t, WithTracerProvider(tp))
+	expectPayment(store, storedPayment())
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(),
+		"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"x-request-id", "req-trace-1")
+	_, err := client.GetPayment(ctx, &paymentsv1.GetPaymentRequest{
+		ClientId: testClientID.String(),
+		Id:       testPaymentID.String(),
+	})
+	require.NoError(t, err)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "payments.v1.PaymentService/GetPayment", span.Name())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String(), "the caller's trace continues")
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.Equal(t, otelcodes.Unset, span.Status().Code)
+	attrs := span.Attributes()
+	assert.Contains(t, attrs, attribute.String("rpc.system", "grpc"))
+	assert.Contains(t, attrs, attribute.String("rpc.service", "payments.v1.PaymentService"))
+	assert.Contains(t, attrs, attribute.String("rpc.method", "GetPayment"))
+	assert.Contains(t, attrs, attribute.Int("rpc.grpc.status_code", int(codes.OK)))
+	assert.Contains(t, attrs, attribute.String("request_id", "req-trace-1"))
+}
+
+func TestTracing_ServerSpanStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		code   codes.Code
+		status otelcodes.Code
+	}{
+		{name: "internal", err: errors.New("db down"), code: codes.Internal, status: otelcodes.Error},
+		{name: "not found", err: pgx.ErrNoRows, code: codes.NotFound, status: otelcodes.Unset},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+			client, store := newTestClient(t, WithTracerProvider(tp))
+			store.On("GetPayment", mock.Anything, testPaymentID).Return(repository.Payment{}, tt.err)
+
+			_, err := client.GetPayment(context.Background(), &paymentsv1.GetPaymentRequest{
+				ClientId: testClientID.String(),
+				Id:       testPaymentID.String(),
+			})
+			require.Equal(t, tt.code, status.Code(err))
+
+			spans := rec.Ended()
+			require.Len(t, spans, 1)
+			assert.Equal(t, tt.status, spans[0].Status().Code)
+			assert.Contains(t, spans[0].Attributes(), attribute.Int("rpc.grpc.status_code", int(tt.code)))
+		})
+	}
+}
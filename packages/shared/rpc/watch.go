@@ -0,0 +1,61 @@
+package rpc
+
+import (
+	"context"
+
+	paymentsv1 "github.com/yaninyzwitty/tron-payment-gateway/gen/payments/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WatchPayment sends the payment's current status, then every change to it
+// published on the event bus, until the caller cancels or Serve returns. It
+// checks ownership like GetPayment.
+func (s *Server) WatchPayment(req *paymentsv1.WatchPaymentRequest, stream grpc.ServerStreamingServer[paymentsv1.WatchPaymentResponse]) error {
+	if s.bus == nil {
+		return status.Error(codes.Unimplemented, "payment events are not enabled")
+	}
+	clientID, id, err := parseIDs(req.GetClientId(), req.GetId())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	stopOnClose := context.AfterFunc(s.streams, cancel)
+	defer stopOnClose()
+
+	// Subscribed before the payment is read, so no change falls between the
+	// two.
+	events, unsubscribe, err := s.bus.Subscribe(ctx, id)
+	if err != nil {
+		return s.internalError(ctx, "failed to follow payment", err, "payment_id", id)
+	}
+	defer unsubscribe()
+	payment, err := s.clientPayment(ctx, clientID, id)
+	if err != nil {
+		return err
+	}
+
+	last := payment.Status
+	if err := stream.Send(&paymentsv1.WatchPaymentResponse{PaymentId: payment.ID.String(), Status: last}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if e.Status == last {
+				continue
+			}
+			last = e.Status
+			if err := stream.Send(&paymentsv1.WatchPaymentResponse{PaymentId: payment.ID.String(), Status: last}); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	paymentsv1 "github.com/yaninyzwitty/tron-payment-gateway/gen/payments/v1"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/events"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func watch(t *testing.T, client paymentsv1.PaymentServiceClient) (grpc.ServerStreamingClient[paymentsv1.WatchPaymentResponse], context.CancelFunc) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	stream, err := client.WatchPayment(ctx, &paymentsv1.WatchPaymentRequest{
+		ClientId: testClientID.String(),
+		Id:       testPaymentID.String(),
+	})
+	require.NoError(t, err)
+	return stream, cancel
+}
+
+func recvStatus(t *testing.T, stream grpc.ServerStreamingClient[paymentsv1.WatchPaymentResponse]) string {
+	t.Helper()
+	msg, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, testPaymentID.String(), msg.GetPaymentId())
+	return msg.GetStatus()
+}
+
+// waitWatched waits until the payment has a subscriber on bus.
+func waitWatched(t *testing.T, bus *events.MemoryBus) {
+	t.Helper()
+	require.Eventually(t, func() bool { return len(bus.Watched()) == 1 }, time.Second, time.Millisecond)
+}
+
+func TestWatchPayment(t *testing.T) {
+	bus := events.NewMemoryBus()
+	client, store := newTestClient(t, WithEventBus(bus))
+	expectPayment(store, storedPayment())
+
+	stream, cancel := watch(t, client)
+	assert.Equal(t, repository.PaymentPending, recvStatus(t, stream), "the current status comes first")
+	waitWatched(t, bus)
+
+	for _, s := range []string{repository.PaymentDetected, repository.PaymentDetected, repository.PaymentConfirmed} {
+		require.NoError(t, bus.Publish(context.Background(), events.Event{PaymentID: testPaymentID, Status: s, At: t0}))
+	}
+	assert.Equal(t, repository.PaymentDetected, recvStatus(t, stream))
+	assert.Equal(t, repository.PaymentConfirmed, recvStatus(t, stream), "repeats are dropped")
+
+	cancel()
+	require.Eventually(t, func() bool { return len(bus.Watched()) == 0 }, time.Second, time.Millisecond,
+		"unsubscribed once the caller left")
+}
+
+func TestWatchPayment_ServerStopEndsStream(t *testing.T) {
+	bus := events.NewMemoryBus()
+	s, store := newTestServer(t, WithEventBus(bus))
+	lis, stop := start(t, s)
+	client := dial(t, lis, withSecret(testSecret))
+	expectPayment(store, storedPayment())
+	stream, _ := watch(t, client)
+	recvStatus(t, stream)
+
+	stop()
+
+	_, err := stream.Recv()
+	assert.True(t, errors.Is(err, io.EOF), "the stream ends cleanly, got %v", err)
+	assert.Empty(t, bus.Watched())
+}
+
+func TestWatchPayment_NotFound(t *testing.T) {
+	bus := events.NewMemoryBus()
+	client, store := newTestClient(t, WithEventBus(bus))
+	payment := storedPayment()
+	payment.ClientID = uuid.New()
+	expectPayment(store, payment)
+
+	stream, _ := watch(t, client)
+	_, err := stream.Recv()
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Empty(t, bus.Watched())
+}
+
+func TestWatchPayment_WithoutBus(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	stream, _ := watch(t, client)
+	_, err := stream.Recv()
+
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
@@ -0,0 +1,587 @@
+// Package sweeper moves the funds of confirmed payments from their deposit
+// wallets to the cold wallet.
+package sweeper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/logging"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+// Log events written against the swept payment.
+const (
+	EventSweepSent      = "SWEEP_SENT"
+	EventSweepConfirmed = "SWEEP_CONFIRMED"
+	EventSweepFailed    = "SWEEP_FAILED"
+	EventSweepRejected  = "SWEEP_REJECTED"
+	EventSweepExpired   = "SWEEP_EXPIRED"
+)
+
+// Sweep statuses. SIGNED and SENT sweeps are open: at most one per wallet and
+// token exists, and it is followed until it settles.
+const (
+	statusSigned    = "SIGNED"
+	statusSent      = "SENT"
+	statusConfirmed = "CONFIRMED"
+	statusFailed    = "FAILED"
+	statusRejected  = "REJECTED"
+	statusExpired   = "EXPIRED"
+)
+
+// tokenDecimals is shared by TRX (sun) and USDT.
+const tokenDecimals = 6
+
+// expiryGrace is how long past its expiration a sweep without a receipt is
+// still waited for, so a node that lags the head is not mistaken for a
+// dropped transaction.
+const expiryGrace = time.Minute
+
+// Chain is the subset of *tron.Client the sweeper reads balances and sends
+// transactions through.
+type Chain interface {
+	GetNowBlock(ctx context.Context) (*tron.Block, error)
+	GetBlockByNum(ctx context.Context, num int64) (*tron.Block, error)
+	GetTRXBalance(ctx context.Context, address string) (int64, error)
+	GetTRC20Balance(ctx context.Context, contractAddress, holderAddress string) (*big.Int, error)
+	GetAccountResources(ctx context.Context, address string) (*tron.AccountResources, error)
+	IsAddressActivated(ctx context.Context, address string) (bool, error)
+	EstimateResources(ctx context.Context, tx *tron.Transaction) (tron.ResourceEstimate, error)
+	BroadcastTransaction(ctx context.Context, signedTx tron.Transaction) (string, error)
+	GetTransactionInfoByID(ctx context.Context, txID string) (*tron.TransactionInfo, error)
+}
+
+// Store is the subset of repository.Querier the sweeper writes through.
+type Store interface {
+	ListSweepCandidates(ctx context.Context, limit int32) ([]repository.ListSweepCandidatesRow, error)
+	DeferSweepCandidate(ctx context.Context, arg repository.DeferSweepCandidateParams) error
+	ListOpenSweeps(ctx context.Context) ([]repository.Sweep, error)
+	CreateSweep(ctx context.Context, arg repository.CreateSweepParams) (repository.Sweep, error)
+	UpdateSweepStatus(ctx context.Context, arg repository.UpdateSweepStatusParams) (repository.Sweep, error)
+	CreateSweepTransaction(ctx context.Context, arg repository.CreateSweepTransactionParams) (repository.Transaction, error)
+	CreateLog(ctx context.Context, arg repository.CreateLogParams) error
+}
+
+// Keys returns the signer of the deposit wallet at index.
+type Keys func(index uint32) (tron.Signer, error)
+
+// MnemonicKeys derives deposit wallet keys from mnemonic, the same way
+// payments derive their addresses.
+func MnemonicKeys(mnemonic string) Keys {
+	return func(index uint32) (tron.Signer, error) {
+		_, privateKey, err := wallet.DeriveTronAddressFromMnemonic(mnemonic, index)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive wallet %d: %w", index, err)
+		}
+		return tron.NewKeySigner(privateKey)
+	}
+}
+
+// Transfer is a sweep a run sent, or would have sent in dry-run mode.
+type Transfer struct {
+	PaymentID uuid.UUID
+	Token     string
+	From      string
+	To        string
+	Amount    decimal.Decimal
+	// FeeLimitSun is the fee limit of a TRC20 sweep, zero for TRX.
+	FeeLimitSun int64
+	// TxID is empty in dry-run mode.
+	TxID string
+}
+
+// Sweeper moves the balance of every confirmed payment's deposit wallet to
+// the cold wallet.
+//
+// A sweep is signed and saved before it is broadcast, and only one sweep per
+// wallet and token can be open at a time. After a crash the saved
+// transaction is looked up and broadcast again rather than signed anew, so
+// the same funds are never sent twice. A sweep that never makes it into a
+// block is marked EXPIRED once its expiration passes and the payment becomes
+// a candidate again.
+type Sweeper struct {
+	chain  Chain
+	store  Store
+	keys   Keys
+	logger *slog.Logger
+
+	coldWallet     string
+	interval       time.Duration
+	batchSize      int
+	recheck        time.Duration
+	minAmount      func(token string) decimal.Decimal
+	maxFeeLimitSun int64
+	energyPriceSun int64
+	dryRun         bool
+	required       int64
+	usdtContract   string
+
+	// failures tracks the consecutive failed sweeps of a wallet and token,
+	// so their retries back off. Only RunOnce touches it.
+	failures map[string]sweepFailure
+}
+
+type sweepFailure struct {
+	count int
+	last  time.Time
+}
+
+// Option customises a Sweeper.
+type Option func(*Sweeper)
+
+// WithLogger replaces slog.Default.
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Sweeper) { s.logger = l }
+}
+
+// New builds a sweeper using the sweeper and tron sections of cfg.
+func New(chain Chain, store Store, keys Keys, cfg *config.Config, opts ...Option) *Sweeper {
+	s := &Sweeper{
+		chain:          chain,
+		store:          store,
+		keys:           keys,
+		logger:         slog.Default(),
+		coldWallet:     cfg.Sweeper.ColdWallet,
+		interval:       cfg.Sweeper.Interval.Std(),
+		batchSize:      cfg.Sweeper.BatchSize,
+		recheck:        cfg.Sweeper.RecheckInterval.Std(),
+		minAmount:      cfg.Sweeper.MinSweepAmount,
+		maxFeeLimitSun: cfg.Sweeper.MaxFeeLimitSun,
+		energyPriceSun: cfg.Sweeper.EnergyPriceSun,
+		dryRun:         cfg.Sweeper.DryRun,
+		required:       int64(cfg.Tron.ConfirmationsRequired),
+		usdtContract:   cfg.Tron.USDTContract,
+		failures:       make(map[string]sweepFailure),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.logger = slog.New(logging.NewHandler(s.logger.Handler()))
+	return s
+}
+
+// Run sweeps every interval until ctx is done.
+func (s *Sweeper) Run(ctx context.Context) error {
+	s.logger.Info("sweeper started", "interval", s.interval, "batch_size", s.batchSize, "dry_run", s.dryRun)
+	for {
+		if _, err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			s.logger.ErrorContext(ctx, "sweep run failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			s.logger.Info("sweeper stopped")
+			return nil
+		case <-time.After(s.interval):
+		}
+	}
+}
+
+// RunOnce settles the open sweeps and then sweeps at most batchSize new
+// candidates, returning the transfers it sent. In dry-run mode nothing is
+// signed or written and the returned transfers are the ones it would send.
+//
+// A candidate that cannot be swept is logged and deferred, so one wallet does
+// not hold up the rest: for twice the interval, doubling with every failure
+// in a row up to the recheck interval. One left alone because its balance is
+// below the threshold or cannot pay the fees is deferred for the recheck
+// interval. Either way such wallets do not fill every batch ahead of the ones
+// that can be swept.
+func (s *Sweeper) RunOnce(ctx context.Context) ([]Transfer, error) {
+	head, err := s.chain.GetNowBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !s.dryRun {
+		if err := s.settle(ctx, head); err != nil {
+			return nil, err
+		}
+	}
+
+	candidates, err := s.store.ListSweepCandidates(ctx, int32(s.batchSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sweep candidates: %w", err)
+	}
+
+	// A wallet not retried well past its longest backoff is no longer a
+	// candidate.
+	for key, f := range s.failures {
+		if time.Since(f.last) > 2*s.recheck {
+			delete(s.failures, key)
+		}
+	}
+
+	var transfers []Transfer
+	for _, c := range candidates {
+		t, err := s.sweep(ctx, head, c)
+		if err != nil {
+			if ctx.Err() != nil {
+				return transfers, ctx.Err()
+			}
+			retryIn := s.backoff(c)
+			s.logger.ErrorContext(logging.WithPaymentID(ctx, c.PaymentID), "sweep failed",
+				"wallet", c.UniqueWallet, "token", c.Token, "retry_in", retryIn, "error", err)
+			s.deferCandidate(ctx, c, retryIn)
+			continue
+		}
+		delete(s.failures, failureKey(c))
+		if t == nil {
+			s.deferCandidate(ctx, c, s.recheck)
+			continue
+		}
+		transfers = append(transfers, *t)
+	}
+	return transfers, nil
+}
+
+func failureKey(c repository.ListSweepCandidatesRow) string {
+	return c.UniqueWallet + "/" + c.Token
+}
+
+// backoff records a failed sweep of c and returns how long its wallet waits
+// before the next attempt: twice the interval, doubled for every earlier
+// failure in a row, and at most the recheck interval.
+func (s *Sweeper) backoff(c repository.ListSweepCandidatesRow) time.Duration {
+	key := failureKey(c)
+	f := s.failures[key]
+	f.count++
+	f.last = time.Now()
+	s.failures[key] = f
+	delay := s.interval
+	for range f.count {
+		delay *= 2
+		if delay >= s.recheck {
+			return s.recheck
+		}
+	}
+	return delay
+}
+
+// deferCandidate keeps the wallet of a candidate out of the batches for
+// delay. Nothing is written in dry-run mode.
+func (s *Sweeper) deferCandidate(ctx context.Context, c repository.ListSweepCandidatesRow, delay time.Duration) {
+	if s.dryRun {
+		return
+	}
+	err := s.store.DeferSweepCandidate(ctx, repository.DeferSweepCandidateParams{
+		Address:     c.UniqueWallet,
+		Token:       c.Token,
+		NextCheckAt: pgtype.Timestamptz{Time: time.Now().Add(delay), Valid: true},
+	})
+	if err != nil {
+		s.logger.ErrorContext(logging.WithPaymentID(ctx, c.PaymentID), "failed to defer sweep candidate",
+			"wallet", c.UniqueWallet, "token", c.Token, "error", err)
+	}
+}
+
+// settle follows every open sweep one step: confirm, fail or expire it once
+// its fate is known, and broadcast a SIGNED one again if the node has not
+// seen it.
+func (s *Sweeper) settle(ctx context.Context, head *tron.Block) error {
+	sweeps, err := s.store.ListOpenSweeps(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list open sweeps: %w", err)
+	}
+	for _, sw := range sweeps {
+		if err := s.settleOne(ctx, head, sw); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.logger.ErrorContext(ctx, "failed to settle sweep", "sweep_id", sw.ID, "tx_id", sw.TxID, "error", err)
+		}
+	}
+	return nil
+}
+
+func (s *Sweeper) settleOne(ctx context.Context, head *tron.Block, sw repository.Sweep) error {
+	info, err := s.chain.GetTransactionInfoByID(ctx, sw.TxID)
+	switch {
+	case errors.Is(err, tron.ErrTransactionNotFound):
+		if head.Time().After(sw.ExpiresAt.Time.Add(expiryGrace)) {
+			return s.finish(ctx, sw, statusExpired, EventSweepExpired, "sweep expired before it was included", nil)
+		}
+		if sw.Status != statusSigned {
+			return nil
+		}
+		var tx tron.Transaction
+		if err := json.Unmarshal(sw.SignedTx, &tx); err != nil {
+			return fmt.Errorf("failed to decode signed transaction: %w", err)
+		}
+		return s.broadcast(ctx, sw, &tx, false)
+	case err != nil:
+		return err
+	}
+
+	if info.Failed() {
+		return s.finish(ctx, sw, statusFailed, EventSweepFailed,
+			fmt.Sprintf("sweep failed on chain: %s", info.ResMessage), info)
+	}
+	// The block holding the sweep counts as its first confirmation, as in
+	// the confirmation tracker.
+	confirmations := head.Number() - info.BlockNumber + 1
+	if confirmations < s.required {
+		if sw.Status == statusSigned {
+			// Broadcast before a crash and already in a block.
+			_, err := s.store.UpdateSweepStatus(ctx, repository.UpdateSweepStatusParams{
+				ToStatus: statusSent, ID: sw.ID, FromStatus: statusSigned,
+			})
+			return err
+		}
+		return nil
+	}
+	return s.confirm(ctx, sw, info, confirmations)
+}
+
+// confirm records the sweep as an outgoing transaction of its payment and
+// closes it.
+func (s *Sweeper) confirm(ctx context.Context, sw repository.Sweep, info *tron.TransactionInfo, confirmations int64) error {
+	block, err := s.chain.GetBlockByNum(ctx, info.BlockNumber)
+	if err != nil {
+		return err
+	}
+	_, err = s.store.CreateSweepTransaction(ctx, repository.CreateSweepTransactionParams{
+		PaymentID:       sw.PaymentID,
+		TxHash:          sw.TxID,
+		Token:           sw.Token,
+		ContractAddress: sw.ContractAddress,
+		FromAddress:     sw.FromAddress,
+		ToAddress:       sw.ToAddress,
+		Amount:          sw.Amount,
+		BlockNumber:     info.BlockNumber,
+		BlockHash:       block.BlockID,
+		Confirmations:   int32(min(confirmations, math.MaxInt32)),
+	})
+	// A duplicate means an earlier pass recorded it and stopped short of
+	// closing the sweep.
+	if err != nil && !errors.Is(err, repository.ErrDuplicateTransaction) {
+		return fmt.Errorf("failed to record sweep transaction: %w", err)
+	}
+	amount := repository.NumericToDecimal(sw.Amount).StringFixed(tokenDecimals)
+	return s.finish(ctx, sw, statusConfirmed, EventSweepConfirmed,
+		fmt.Sprintf("swept %s %s to %s", amount, sw.Token, sw.ToAddress), info)
+}
+
+// finish moves an open sweep to a final status and logs it.
+func (s *Sweeper) finish(ctx context.Context, sw repository.Sweep, status, event, msg string, data any) error {
+	if _, err := s.store.UpdateSweepStatus(ctx, repository.UpdateSweepStatusParams{
+		ToStatus: status, ID: sw.ID, FromStatus: sw.Status,
+	}); err != nil {
+		return fmt.Errorf("failed to mark sweep %s: %w", status, err)
+	}
+	s.logger.Info("sweep settled", "sweep_id", sw.ID, "tx_id", sw.TxID, "status", status)
+	if data == nil {
+		data = map[string]string{"tx_id": sw.TxID}
+	}
+	return s.log(ctx, sw.PaymentID, event, msg, data)
+}
+
+// sweep sends the balance of one candidate's wallet to the cold wallet. It
+// returns nil without error when the balance is below the threshold or the
+// wallet cannot pay the fees.
+func (s *Sweeper) sweep(ctx context.Context, head *tron.Block, c repository.ListSweepCandidatesRow) (*Transfer, error) {
+	token := strings.ToUpper(c.Token)
+	if token != config.TokenTRX && token != config.TokenUSDT {
+		return nil, fmt.Errorf("unsupported token %q", c.Token)
+	}
+	if c.WalletIndex == nil || *c.WalletIndex < 0 || *c.WalletIndex > math.MaxUint32 {
+		return nil, errors.New("payment has no valid wallet index")
+	}
+	signer, err := s.keys(uint32(*c.WalletIndex))
+	if err != nil {
+		return nil, err
+	}
+	if signer.Address() != c.UniqueWallet {
+		return nil, fmt.Errorf("wallet %d derives to %s, not %s", *c.WalletIndex, signer.Address(), c.UniqueWallet)
+	}
+
+	trxBalance, err := s.chain.GetTRXBalance(ctx, c.UniqueWallet)
+	if err != nil {
+		return nil, err
+	}
+	balance := big.NewInt(trxBalance)
+	if token == config.TokenUSDT {
+		if balance, err = s.chain.GetTRC20Balance(ctx, s.usdtContract, c.UniqueWallet); err != nil {
+			return nil, err
+		}
+	}
+	if toDecimal(balance).LessThan(s.minAmount(token)) {
+		s.logger.Debug("balance below sweep threshold", "wallet", c.UniqueWallet, "token", token, "balance", toDecimal(balance))
+		return nil, nil
+	}
+
+	resources, err := s.chain.GetAccountResources(ctx, c.UniqueWallet)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.build(token, c.UniqueWallet, balance, head.Ref())
+	if err != nil {
+		return nil, err
+	}
+	estimate, err := s.chain.EstimateResources(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	afford := tron.CanAfford(resources, estimate, trxBalance, s.energyPriceSun)
+	if afford.Action == tron.ActionTopUp {
+		// A deposit wallet that only ever received TRC20 is not activated,
+		// and whoever sends it TRX for the fees pays to activate it too.
+		activated, err := s.chain.IsAddressActivated(ctx, c.UniqueWallet)
+		if err != nil {
+			return nil, err
+		}
+		var activationFee int64
+		if !activated {
+			activationFee = tron.ActivationFeeSun
+		}
+		s.logger.Warn("wallet cannot pay sweep fees", "payment_id", c.PaymentID, "wallet", c.UniqueWallet,
+			"token", token, "burn_sun", afford.BurnSun, "trx_balance", trxBalance,
+			"top_up_sun", afford.BurnSun-trxBalance, "activated", activated, "activation_fee_sun", activationFee)
+		return nil, nil
+	}
+
+	var feeLimit int64
+	if token == config.TokenTRX {
+		// Whatever bandwidth is burned comes out of the swept balance.
+		balance = new(big.Int).Sub(balance, big.NewInt(afford.BurnSun))
+		if toDecimal(balance).LessThan(s.minAmount(token)) {
+			return nil, nil
+		}
+		tx, err = tron.BuildTRXTransfer(c.UniqueWallet, s.coldWallet, balance.Int64(), head.Ref(), tron.DefaultExpiration)
+	} else {
+		feeLimit = estimate.FeeLimit(s.energyPriceSun)
+		if feeLimit > s.maxFeeLimitSun {
+			return nil, fmt.Errorf("sweep needs a fee limit of %d sun, above the cap of %d", feeLimit, s.maxFeeLimitSun)
+		}
+		tx, err = tron.BuildTRC20Transfer(c.UniqueWallet, s.usdtContract, s.coldWallet, balance, feeLimit, head.Ref(),
+			tron.WithFeeLimitCap(s.maxFeeLimitSun))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Transfer{
+		PaymentID:   c.PaymentID,
+		Token:       token,
+		From:        c.UniqueWallet,
+		To:          s.coldWallet,
+		Amount:      toDecimal(balance),
+		FeeLimitSun: feeLimit,
+	}
+	if s.dryRun {
+		s.logger.Info("dry run: would sweep", "payment_id", t.PaymentID, "from", t.From, "to", t.To,
+			"token", t.Token, "amount", t.Amount.StringFixed(tokenDecimals), "fee_limit_sun", t.FeeLimitSun)
+		return t, nil
+	}
+
+	if err := signer.Sign(tx); err != nil {
+		return nil, fmt.Errorf("failed to sign sweep: %w", err)
+	}
+	signed, err := json.Marshal(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode sweep: %w", err)
+	}
+	var contract *string
+	if token == config.TokenUSDT {
+		contract = &s.usdtContract
+	}
+	sw, err := s.store.CreateSweep(ctx, repository.CreateSweepParams{
+		PaymentID:       c.PaymentID,
+		Token:           token,
+		ContractAddress: contract,
+		FromAddress:     c.UniqueWallet,
+		ToAddress:       s.coldWallet,
+		Amount:          repository.DecimalToNumeric(t.Amount),
+		TxID:            tx.TxID,
+		SignedTx:        signed,
+		ExpiresAt:       pgtype.Timestamptz{Time: time.UnixMilli(tx.RawData.Expiration), Valid: true},
+	})
+	if errors.Is(err, repository.ErrSweepInProgress) {
+		// Another payment to the same wallet is being swept; its transfer
+		// takes this balance with it.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save sweep: %w", err)
+	}
+	if err := s.broadcast(ctx, sw, tx, true); err != nil {
+		return nil, err
+	}
+	t.TxID = tx.TxID
+	return t, nil
+}
+
+// build drafts the transfer of balance to the cold wallet for a resource
+// estimate.
+func (s *Sweeper) build(token, from string, balance *big.Int, ref tron.BlockRef) (*tron.Transaction, error) {
+	if token == config.TokenTRX {
+		return tron.BuildTRXTransfer(from, s.coldWallet, balance.Int64(), ref, tron.DefaultExpiration)
+	}
+	return tron.BuildTRC20Transfer(from, s.usdtContract, s.coldWallet, balance, s.maxFeeLimitSun, ref,
+		tron.WithFeeLimitCap(s.maxFeeLimitSun))
+}
+
+// broadcast sends a SIGNED sweep and marks it SENT. A sweep broadcast for
+// the first time that the node refuses is marked REJECTED so it is built
+// again next run; one that may have been sent before a crash stays SIGNED
+// until it is found or expires. Network errors always leave it SIGNED.
+func (s *Sweeper) broadcast(ctx context.Context, sw repository.Sweep, tx *tron.Transaction, first bool) error {
+	_, err := s.chain.BroadcastTransaction(ctx, *tx)
+	var berr *tron.BroadcastError
+	switch {
+	case err == nil, errors.Is(err, tron.ErrDuplicateTransaction):
+	case errors.As(err, &berr) && first && !errors.Is(err, tron.ErrNodeBusy):
+		if err := s.finish(ctx, sw, statusRejected, EventSweepRejected, berr.Error(), berr); err != nil {
+			return err
+		}
+		return berr
+	default:
+		return err
+	}
+
+	if _, err := s.store.UpdateSweepStatus(ctx, repository.UpdateSweepStatusParams{
+		ToStatus: statusSent, ID: sw.ID, FromStatus: statusSigned,
+	}); err != nil {
+		return fmt.Errorf("failed to mark sweep sent: %w", err)
+	}
+	amount := repository.NumericToDecimal(sw.Amount).StringFixed(tokenDecimals)
+	s.logger.Info("sweep sent", "payment_id", sw.PaymentID, "tx_id", sw.TxID, "token", sw.Token, "amount", amount)
+	return s.log(ctx, sw.PaymentID, EventSweepSent,
+		fmt.Sprintf("sent %s %s to %s", amount, sw.Token, sw.ToAddress),
+		map[string]any{"tx_id": sw.TxID, "from": sw.FromAddress, "to": sw.ToAddress, "amount": amount, "fee_limit": tx.RawData.FeeLimit})
+}
+
+func (s *Sweeper) log(ctx context.Context, paymentID uuid.UUID, event, msg string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode log: %w", err)
+	}
+	err = s.store.CreateLog(ctx, repository.CreateLogParams{
+		PaymentID: repository.UUIDPtr(paymentID),
+		EventType: event,
+		Message:   &msg,
+		RawData:   raw,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %s log: %w", event, err)
+	}
+	return nil
+}
+
+// toDecimal converts a base-unit amount to token units.
+func toDecimal(amount *big.Int) decimal.Decimal {
+	return decimal.NewFromBigInt(amount, -tokenDecimals)
+}
@@ -0,0 +1,836 @@
+package sweeper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/big"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+const (
+	testMnemonic   = "flash couple heart script ramp april average caution plunge alter elite author"
+	testColdWallet = "TKTX96CBxr5kvhjsDHcqoiPWZageGxoTW3"
+	testHead       = 1000
+	// usdtEnergy is what a USDT transfer to an address holding USDT costs.
+	usdtEnergy = 31895
+)
+
+var errNetwork = errors.New("connection reset")
+
+type fakeChain struct {
+	mu         sync.Mutex
+	head       int64
+	headTime   time.Time
+	trx        map[string]int64
+	usdt       map[string]*big.Int
+	resources  map[string]*tron.AccountResources
+	activated  map[string]bool
+	receipts   map[string]*tron.TransactionInfo
+	broadcasts []tron.Transaction
+	// broadcastErr is returned by the next broadcast only.
+	broadcastErr error
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{
+		head:      testHead,
+		headTime:  time.UnixMilli(1727000001000),
+		trx:       map[string]int64{},
+		usdt:      map[string]*big.Int{},
+		resources: map[string]*tron.AccountResources{},
+		activated: map[string]bool{},
+		receipts:  map[string]*tron.TransactionInfo{},
+	}
+}
+
+func block(num int64, at time.Time) *tron.Block {
+	b := &tron.Block{BlockID: fmt.Sprintf("%016x%048x", num, num)}
+	b.BlockHeader.RawData.Number = num
+	b.BlockHeader.RawData.Timestamp = at.UnixMilli()
+	return b
+}
+
+func (c *fakeChain) advance(blocks int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.head += blocks
+	c.headTime = c.headTime.Add(time.Duration(blocks) * 3 * time.Second)
+}
+
+func (c *fakeChain) GetNowBlock(context.Context) (*tron.Block, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return block(c.head, c.headTime), nil
+}
+
+func (c *fakeChain) GetBlockByNum(_ context.Context, num int64) (*tron.Block, error) {
+	return block(num, time.Time{}), nil
+}
+
+func (c *fakeChain) GetTRXBalance(_ context.Context, address string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.trx[address], nil
+}
+
+func (c *fakeChain) GetTRC20Balance(_ context.Context, _, holder string) (*big.Int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if b, ok := c.usdt[holder]; ok {
+		return new(big.Int).Set(b), nil
+	}
+	return new(big.Int), nil
+}
+
+func (c *fakeChain) GetAccountResources(_ context.Context, address string) (*tron.AccountResources, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if r, ok := c.resources[address]; ok {
+		return r, nil
+	}
+	return &tron.AccountResources{FreeBandwidthLimit: 600}, nil
+}
+
+func (c *fakeChain) IsAddressActivated(_ context.Context, address string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.activated[address], nil
+}
+
+func (c *fakeChain) EstimateResources(_ context.Context, tx *tron.Transaction) (tron.ResourceEstimate, error) {
+	if tx.RawData.Contract[0].Type == "TriggerSmartContract" {
+		return tron.ResourceEstimate{Energy: usdtEnergy, Bandwidth: 345}, nil
+	}
+	return tron.ResourceEstimate{Bandwidth: 268}, nil
+}
+
+func (c *fakeChain) BroadcastTransaction(_ context.Context, tx tron.Transaction) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.broadcastErr; err != nil {
+		c.broadcastErr = nil
+		return "", err
+	}
+	c.broadcasts = append(c.broadcasts, tx)
+	return tx.TxID, nil
+}
+
+func (c *fakeChain) GetTransactionInfoByID(_ context.Context, txID string) (*tron.TransactionInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if info, ok := c.receipts[txID]; ok {
+		return info, nil
+	}
+	return nil, tron.ErrTransactionNotFound
+}
+
+// include puts txID in the current head block.
+func (c *fakeChain) include(txID string, failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	info := &tron.TransactionInfo{ID: txID, BlockNumber: c.head}
+	if failed {
+		info.Result, info.ResMessage = "FAILED", "REVERT opcode executed"
+	}
+	c.receipts[txID] = info
+}
+
+// memStore keeps candidates, sweeps and their side effects in memory with
+// the same constraints as the database.
+type memStore struct {
+	mu         sync.Mutex
+	candidates []repository.ListSweepCandidatesRow
+	sweeps     []repository.Sweep
+	txs        []repository.Transaction
+	logs       []repository.CreateLogParams
+	// deferred maps a wallet and token to their next check.
+	deferred map[string]time.Time
+}
+
+func (s *memStore) addCandidate(t *testing.T, index int64, token string) repository.ListSweepCandidatesRow {
+	t.Helper()
+	address, _, err := wallet.DeriveTronAddressFromMnemonic(testMnemonic, uint32(index))
+	require.NoError(t, err)
+	c := repository.ListSweepCandidatesRow{PaymentID: uuid.New(), UniqueWallet: address, WalletIndex: &index, Token: token}
+	s.candidates = append(s.candidates, c)
+	return c
+}
+
+func (s *memStore) ListSweepCandidates(_ context.Context, limit int32) ([]repository.ListSweepCandidatesRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []repository.ListSweepCandidatesRow
+	for _, c := range s.candidates {
+		swept := slices.ContainsFunc(s.sweeps, func(sw repository.Sweep) bool {
+			return sw.PaymentID == c.PaymentID && sw.Token == c.Token &&
+				sw.Status != statusRejected && sw.Status != statusExpired
+		})
+		if next, ok := s.deferred[c.UniqueWallet+"/"+c.Token]; ok && next.After(time.Now()) {
+			continue
+		}
+		if !swept && len(out) < int(limit) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (s *memStore) DeferSweepCandidate(_ context.Context, arg repository.DeferSweepCandidateParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.deferred == nil {
+		s.deferred = map[string]time.Time{}
+	}
+	s.deferred[arg.Address+"/"+arg.Token] = arg.NextCheckAt.Time
+	return nil
+}
+
+func (s *memStore) ListOpenSweeps(context.Context) ([]repository.Sweep, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []repository.Sweep
+	for _, sw := range s.sweeps {
+		if sw.Status == statusSigned || sw.Status == statusSent {
+			out = append(out, sw)
+		}
+	}
+	return out, nil
+}
+
+func (s *memStore) CreateSweep(_ context.Context, arg repository.CreateSweepParams) (repository.Sweep, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sw := range s.sweeps {
+		if sw.FromAddress == arg.FromAddress && sw.Token == arg.Token &&
+			(sw.Status == statusSigned || sw.Status == statusSent) {
+			return repository.Sweep{}, repository.ErrSweepInProgress
+		}
+	}
+	sw := repository.Sweep{
+		ID:              uuid.New(),
+		PaymentID:       arg.PaymentID,
+		Token:           arg.Token,
+		ContractAddress: arg.ContractAddress,
+		FromAddress:     arg.FromAddress,
+		ToAddress:       arg.ToAddress,
+		Amount:          arg.Amount,
+		TxID:            arg.TxID,
+		SignedTx:        arg.SignedTx,
+		ExpiresAt:       arg.ExpiresAt,
+		Status:          statusSigned,
+	}
+	s.sweeps = append(s.sweeps, sw)
+	return sw, nil
+}
+
+func (s *memStore) UpdateSweepStatus(_ context.Context, arg repository.UpdateSweepStatusParams) (repository.Sweep, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.sweeps {
+		if s.sweeps[i].ID == arg.ID && s.sweeps[i].Status == arg.FromStatus {
+			s.sweeps[i].Status = arg.ToStatus
+			return s.sweeps[i], nil
+		}
+	}
+	return repository.Sweep{}, repository.ErrSweepStatusChanged
+}
+
+func (s *memStore) CreateSweepTransaction(_ context.Context, arg repository.CreateSweepTransactionParams) (repository.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tx := range s.txs {
+		if tx.TxHash == arg.TxHash {
+			return repository.Transaction{}, repository.ErrDuplicateTransaction
+		}
+	}
+	tx := repository.Transaction{
+		ID:            uuid.New(),
+		PaymentID:     arg.PaymentID,
+		TxHash:        arg.TxHash,
+		Token:         arg.Token,
+		FromAddress:   arg.FromAddress,
+		ToAddress:     arg.ToAddress,
+		Amount:        arg.Amount,
+		BlockNumber:   arg.BlockNumber,
+		BlockHash:     arg.BlockHash,
+		Confirmations: arg.Confirmations,
+		Status:        statusConfirmed,
+		Kind:          "SWEEP",
+	}
+	s.txs = append(s.txs, tx)
+	return tx, nil
+}
+
+func (s *memStore) CreateLog(_ context.Context, arg repository.CreateLogParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logs = append(s.logs, arg)
+	return nil
+}
+
+func (s *memStore) eventTypes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []string
+	for _, l := range s.logs {
+		out = append(out, l.EventType)
+	}
+	return out
+}
+
+func (s *memStore) statuses() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []string
+	for _, sw := range s.sweeps {
+		out = append(out, sw.Status)
+	}
+	return out
+}
+
+func testConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Sweeper.Enabled = true
+	cfg.Sweeper.ColdWallet = testColdWallet
+	cfg.ApplyDefaults()
+	return cfg
+}
+
+func newTestSweeper(chain *fakeChain, store *memStore, mutate func(*config.Config)) *Sweeper {
+	cfg := testConfig()
+	if mutate != nil {
+		mutate(cfg)
+	}
+	return New(chain, store, MnemonicKeys(testMnemonic), cfg)
+}
+
+func trx(units int64) int64 { return units * 1_000_000 }
+
+func usdt(units int64) *big.Int { return big.NewInt(units * 1_000_000) }
+
+func TestSweeper_TRX(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	c := store.addCandidate(t, 0, config.TokenTRX)
+	chain.trx[c.UniqueWallet] = trx(50)
+
+	transfers, err := newTestSweeper(chain, store, nil).RunOnce(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	assert.Equal(t, c.PaymentID, transfers[0].PaymentID)
+	assert.Equal(t, testColdWallet, transfers[0].To)
+	assert.True(t, decimal.NewFromInt(50).Equal(transfers[0].Amount))
+
+	require.Len(t, chain.broadcasts, 1)
+	tx := chain.broadcasts[0]
+	assert.Equal(t, transfers[0].TxID, tx.TxID)
+	assert.Len(t, tx.Signature, 1)
+	var value struct {
+		Amount int64 `json:"amount"`
+	}
+	require.NoError(t, json.Unmarshal(tx.RawData.Contract[0].Parameter.Value, &value))
+	assert.Equal(t, trx(50), value.Amount, "free bandwidth leaves the whole balance to sweep")
+
+	assert.Equal(t, []string{statusSent}, store.statuses())
+	assert.Equal(t, []string{EventSweepSent}, store.eventTypes())
+}
+
+func TestSweeper_TRXBurnsBandwidthFromBalance(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	c := store.addCandidate(t, 0, config.TokenTRX)
+	chain.trx[c.UniqueWallet] = trx(50)
+	chain.resources[c.UniqueWallet] = &tron.AccountResources{}
+
+	transfers, err := newTestSweeper(chain, store, nil).RunOnce(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	assert.Equal(t, "49.732000", transfers[0].Amount.StringFixed(6))
+}
+
+func TestSweeper_USDT(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	c := store.addCandidate(t, 1, config.TokenUSDT)
+	chain.usdt[c.UniqueWallet] = usdt(120)
+	chain.resources[c.UniqueWallet] = &tron.AccountResources{FreeBandwidthLimit: 600, EnergyLimit: 65000}
+
+	transfers, err := newTestSweeper(chain, store, nil).RunOnce(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	feeLimit := tron.ResourceEstimate{Energy: usdtEnergy}.FeeLimit(config.DefaultEnergyPriceSun)
+	assert.Equal(t, feeLimit, transfers[0].FeeLimitSun)
+	require.Len(t, chain.broadcasts, 1)
+	assert.Equal(t, feeLimit, chain.broadcasts[0].RawData.FeeLimit)
+	require.Len(t, store.sweeps, 1)
+	require.NotNil(t, store.sweeps[0].ContractAddress)
+	assert.Equal(t, testConfig().Tron.USDTContract, *store.sweeps[0].ContractAddress)
+	assert.True(t, decimal.NewFromInt(120).Equal(repository.NumericToDecimal(store.sweeps[0].Amount)))
+}
+
+func TestSweeper_SkipsWalletThatCannotPayFees(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	c := store.addCandidate(t, 1, config.TokenUSDT)
+	chain.usdt[c.UniqueWallet] = usdt(120)
+
+	transfers, err := newTestSweeper(chain, store, nil).RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, transfers)
+	assert.Empty(t, chain.broadcasts)
+	assert.Empty(t, store.sweeps)
+}
+
+func TestSweeper_ReportsActivationFeeForTopUp(t *testing.T) {
+	testCases := []struct {
+		name      string
+		activated bool
+		wantFee   float64
+	}{
+		{"unactivated", false, tron.ActivationFeeSun},
+		{"activated", true, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			chain := newFakeChain()
+			store := &memStore{}
+			c := store.addCandidate(t, 1, config.TokenUSDT)
+			chain.usdt[c.UniqueWallet] = usdt(120)
+			chain.activated[c.UniqueWallet] = tc.activated
+			var logs bytes.Buffer
+			s := New(chain, store, MnemonicKeys(testMnemonic), testConfig(),
+				WithLogger(slog.New(slog.NewJSONHandler(&logs, nil))))
+
+			_, err := s.RunOnce(context.Background())
+
+			require.NoError(t, err)
+			var entry map[string]any
+			require.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+			assert.Equal(t, "wallet cannot pay sweep fees", entry["msg"])
+			assert.Equal(t, tc.activated, entry["activated"])
+			assert.Equal(t, tc.wantFee, entry["activation_fee_sun"])
+			assert.Equal(t, entry["burn_sun"], entry["top_up_sun"], "the wallet holds no TRX")
+		})
+	}
+}
+
+func TestSweeper_FeeLimitAboveCap(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	c := store.addCandidate(t, 1, config.TokenUSDT)
+	chain.usdt[c.UniqueWallet] = usdt(120)
+	chain.trx[c.UniqueWallet] = trx(100)
+
+	transfers, err := newTestSweeper(chain, store, func(cfg *config.Config) {
+		cfg.Sweeper.MaxFeeLimitSun = trx(1)
+	}).RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, transfers)
+	assert.Empty(t, store.sweeps)
+}
+
+func TestSweeper_BelowThreshold(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	c := store.addCandidate(t, 0, config.TokenTRX)
+	chain.trx[c.UniqueWallet] = trx(5)
+
+	transfers, err := newTestSweeper(chain, store, func(cfg *config.Config) {
+		cfg.Sweeper.MinAmount = map[string]string{"TRX": "5.000001"}
+	}).RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, transfers)
+	assert.Empty(t, chain.broadcasts)
+}
+
+func TestSweeper_DefersWalletsLeftAlone(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	// The oldest candidates fill the first batch and none can be swept.
+	low := store.addCandidate(t, 0, config.TokenTRX)
+	chain.trx[low.UniqueWallet] = trx(5)
+	unfunded := store.addCandidate(t, 1, config.TokenUSDT)
+	chain.usdt[unfunded.UniqueWallet] = usdt(120)
+	ready := store.addCandidate(t, 2, config.TokenTRX)
+	chain.trx[ready.UniqueWallet] = trx(50)
+	s := newTestSweeper(chain, store, func(cfg *config.Config) { cfg.Sweeper.BatchSize = 2 })
+
+	transfers, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, transfers)
+	assert.Len(t, store.deferred, 2)
+	next := store.deferred[low.UniqueWallet+"/"+config.TokenTRX]
+	assert.WithinDuration(t, time.Now().Add(config.DefaultSweepRecheckInterval.Std()), next, time.Minute)
+
+	transfers, err = s.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Len(t, transfers, 1, "the wallets left alone no longer fill the batch")
+	assert.Equal(t, ready.PaymentID, transfers[0].PaymentID)
+}
+
+func TestSweeper_DeferredWalletIsCheckedAgain(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	c := store.addCandidate(t, 0, config.TokenTRX)
+	chain.trx[c.UniqueWallet] = trx(5)
+	s := newTestSweeper(chain, store, nil)
+
+	_, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	chain.trx[c.UniqueWallet] = trx(50)
+	store.deferred[c.UniqueWallet+"/"+c.Token] = time.Now().Add(-time.Second)
+
+	transfers, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, transfers, 1, "a wallet is swept once its next check is due")
+}
+
+func TestSweeper_FailingWalletsDoNotFillTheBatch(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	// The oldest candidate derives to another address and always fails.
+	broken := store.addCandidate(t, 0, config.TokenTRX)
+	store.candidates[0].UniqueWallet = testColdWallet
+	chain.trx[testColdWallet] = trx(50)
+	ready := store.addCandidate(t, 1, config.TokenTRX)
+	chain.trx[ready.UniqueWallet] = trx(50)
+	s := newTestSweeper(chain, store, func(cfg *config.Config) { cfg.Sweeper.BatchSize = 1 })
+
+	transfers, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, transfers)
+	next := store.deferred[testColdWallet+"/"+broken.Token]
+	assert.WithinDuration(t, time.Now().Add(2*config.DefaultSweepInterval.Std()), next, time.Minute)
+
+	transfers, err = s.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Len(t, transfers, 1, "the failing wallet no longer fills the batch")
+	assert.Equal(t, ready.PaymentID, transfers[0].PaymentID)
+}
+
+func TestSweeper_FailureBackoff(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{deferred: map[string]time.Time{}}
+	c := store.addCandidate(t, 0, config.TokenTRX)
+	chain.trx[c.UniqueWallet] = trx(50)
+	s := newTestSweeper(chain, store, func(cfg *config.Config) {
+		cfg.Sweeper.Interval = config.Duration(10 * time.Minute)
+		cfg.Sweeper.RecheckInterval = config.Duration(time.Hour)
+	})
+	key := c.UniqueWallet + "/" + c.Token
+
+	var delays []time.Duration
+	for range 4 {
+		store.deferred[key] = time.Now().Add(-time.Second)
+		chain.broadcastErr = &tron.BroadcastError{Code: "TAPOS_ERROR", Message: "ref block not found"}
+		_, err := s.RunOnce(context.Background())
+		require.NoError(t, err)
+		delays = append(delays, time.Until(store.deferred[key]).Round(time.Minute))
+	}
+	assert.Equal(t, []time.Duration{20 * time.Minute, 40 * time.Minute, time.Hour, time.Hour}, delays)
+
+	// A sweep that goes through resets the backoff.
+	store.deferred[key] = time.Now().Add(-time.Second)
+	transfers, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	assert.Empty(t, s.failures)
+}
+
+func TestSweeper_ForgetsFailuresOfWalletsGone(t *testing.T) {
+	store := &memStore{}
+	s := newTestSweeper(newFakeChain(), store, nil)
+	s.failures["gone/TRX"] = sweepFailure{count: 3, last: time.Now().Add(-3 * config.DefaultSweepRecheckInterval.Std())}
+	s.failures["recent/TRX"] = sweepFailure{count: 1, last: time.Now()}
+
+	_, err := s.RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"recent/TRX"}, slices.Collect(maps.Keys(s.failures)))
+}
+
+func TestSweeper_BatchSize(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	for i := range int64(3) {
+		c := store.addCandidate(t, i, config.TokenTRX)
+		chain.trx[c.UniqueWallet] = trx(20)
+	}
+	s := newTestSweeper(chain, store, func(cfg *config.Config) { cfg.Sweeper.BatchSize = 2 })
+
+	transfers, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, transfers, 2)
+
+	transfers, err = s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, transfers, 1, "the next run picks up the rest")
+}
+
+func TestSweeper_DryRun(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	c := store.addCandidate(t, 0, config.TokenTRX)
+	chain.trx[c.UniqueWallet] = trx(50)
+
+	transfers, err := newTestSweeper(chain, store, func(cfg *config.Config) {
+		cfg.Sweeper.DryRun = true
+	}).RunOnce(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	assert.Empty(t, transfers[0].TxID)
+	assert.True(t, decimal.NewFromInt(50).Equal(transfers[0].Amount))
+	assert.Empty(t, chain.broadcasts)
+	assert.Empty(t, store.sweeps)
+	assert.Empty(t, store.logs)
+	assert.Empty(t, store.deferred)
+}
+
+func TestSweeper_WrongDerivedAddress(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	c := store.addCandidate(t, 0, config.TokenTRX)
+	store.candidates[0].UniqueWallet = testColdWallet
+	chain.trx[c.UniqueWallet] = trx(50)
+	chain.trx[testColdWallet] = trx(50)
+
+	transfers, err := newTestSweeper(chain, store, nil).RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, transfers)
+	assert.Empty(t, chain.broadcasts)
+}
+
+func TestSweeper_ResumesAfterCrash(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	c := store.addCandidate(t, 0, config.TokenTRX)
+	chain.trx[c.UniqueWallet] = trx(50)
+	chain.broadcastErr = errNetwork
+	s := newTestSweeper(chain, store, nil)
+
+	transfers, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, transfers)
+	require.Equal(t, []string{statusSigned}, store.statuses(), "the signed sweep is kept")
+	txID := store.sweeps[0].TxID
+
+	transfers, err = s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, transfers, "no second sweep is signed")
+	require.Len(t, chain.broadcasts, 1)
+	assert.Equal(t, txID, chain.broadcasts[0].TxID, "the saved transaction is broadcast again")
+	assert.Equal(t, []string{statusSent}, store.statuses())
+}
+
+func TestSweeper_ResumeDuplicateCountsAsSent(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	c := store.addCandidate(t, 0, config.TokenTRX)
+	chain.trx[c.UniqueWallet] = trx(50)
+	chain.broadcastErr = errNetwork
+	s := newTestSweeper(chain, store, nil)
+	_, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+
+	chain.broadcastErr = &tron.BroadcastError{Code: "DUP_TRANSACTION_ERROR"}
+	_, err = s.RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{statusSent}, store.statuses())
+}
+
+func TestSweeper_ResumeFindsIncludedTransaction(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	c := store.addCandidate(t, 0, config.TokenTRX)
+	chain.trx[c.UniqueWallet] = trx(50)
+	chain.broadcastErr = errNetwork
+	s := newTestSweeper(chain, store, nil)
+	_, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+
+	// The broadcast reached the network before the error.
+	chain.include(store.sweeps[0].TxID, false)
+	_, err = s.RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, chain.broadcasts)
+	assert.Equal(t, []string{statusSent}, store.statuses())
+}
+
+func TestSweeper_Confirms(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	c := store.addCandidate(t, 0, config.TokenTRX)
+	chain.trx[c.UniqueWallet] = trx(50)
+	s := newTestSweeper(chain, store, nil)
+	_, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	chain.include(store.sweeps[0].TxID, false)
+
+	// The block holding the sweep is its first confirmation.
+	chain.advance(int64(testConfig().Tron.ConfirmationsRequired) - 2)
+	_, err = s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{statusSent}, store.statuses())
+	assert.Empty(t, store.txs)
+
+	chain.advance(1)
+	transfers, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, transfers, "a confirmed payment is not swept again")
+	assert.Equal(t, []string{statusConfirmed}, store.statuses())
+	assert.Equal(t, []string{EventSweepSent, EventSweepConfirmed}, store.eventTypes())
+
+	require.Len(t, store.txs, 1)
+	tx := store.txs[0]
+	assert.Equal(t, c.PaymentID, tx.PaymentID)
+	assert.Equal(t, store.sweeps[0].TxID, tx.TxHash)
+	assert.Equal(t, "SWEEP", tx.Kind)
+	assert.Equal(t, int64(testHead), tx.BlockNumber)
+	assert.Equal(t, block(testHead, time.Time{}).BlockID, tx.BlockHash)
+	assert.Equal(t, int32(testConfig().Tron.ConfirmationsRequired), tx.Confirmations)
+}
+
+func TestSweeper_FailedOnChain(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	c := store.addCandidate(t, 1, config.TokenUSDT)
+	chain.usdt[c.UniqueWallet] = usdt(120)
+	chain.trx[c.UniqueWallet] = trx(30)
+	s := newTestSweeper(chain, store, nil)
+	_, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	chain.include(store.sweeps[0].TxID, true)
+
+	transfers, err := s.RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, transfers, "a failed sweep needs a look before it is retried")
+	assert.Equal(t, []string{statusFailed}, store.statuses())
+	assert.Equal(t, []string{EventSweepSent, EventSweepFailed}, store.eventTypes())
+	assert.Empty(t, store.txs)
+}
+
+func TestSweeper_ExpiresAndRetries(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	c := store.addCandidate(t, 0, config.TokenTRX)
+	chain.trx[c.UniqueWallet] = trx(50)
+	s := newTestSweeper(chain, store, nil)
+	_, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	first := store.sweeps[0].TxID
+
+	// Still within the expiration and grace: keep waiting.
+	chain.advance(20)
+	transfers, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, transfers)
+	assert.Equal(t, []string{statusSent}, store.statuses())
+
+	chain.advance(30)
+	transfers, err = s.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	assert.NotEqual(t, first, transfers[0].TxID)
+	assert.Equal(t, []string{statusExpired, statusSent}, store.statuses())
+	assert.Equal(t, []string{EventSweepSent, EventSweepExpired, EventSweepSent}, store.eventTypes())
+}
+
+func TestSweeper_Rejected(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	c := store.addCandidate(t, 0, config.TokenTRX)
+	chain.trx[c.UniqueWallet] = trx(50)
+	chain.broadcastErr = &tron.BroadcastError{Code: "TAPOS_ERROR", Message: "ref block not found"}
+	s := newTestSweeper(chain, store, nil)
+
+	transfers, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, transfers)
+	assert.Equal(t, []string{statusRejected}, store.statuses())
+	assert.Equal(t, []string{EventSweepRejected}, store.eventTypes())
+
+	// The failed wallet waits out its backoff first.
+	transfers, err = s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, transfers)
+	store.deferred[c.UniqueWallet+"/"+c.Token] = time.Now().Add(-time.Second)
+
+	transfers, err = s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, transfers, 1, "a rejected sweep is built again")
+}
+
+func TestSweeper_OneOpenSweepPerWallet(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	c := store.addCandidate(t, 0, config.TokenTRX)
+	// A second payment reusing the same deposit wallet.
+	index := *c.WalletIndex
+	store.candidates = append(store.candidates, repository.ListSweepCandidatesRow{
+		PaymentID: uuid.New(), UniqueWallet: c.UniqueWallet, WalletIndex: &index, Token: config.TokenTRX,
+	})
+	chain.trx[c.UniqueWallet] = trx(50)
+
+	transfers, err := newTestSweeper(chain, store, nil).RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Len(t, transfers, 1)
+	assert.Len(t, chain.broadcasts, 1)
+}
+
+func TestSweeper_SettleSkippedInDryRun(t *testing.T) {
+	chain := newFakeChain()
+	store := &memStore{}
+	store.sweeps = append(store.sweeps, repository.Sweep{
+		ID:        uuid.New(),
+		TxID:      "abc",
+		Status:    statusSent,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Unix(0, 0), Valid: true},
+	})
+
+	_, err := newTestSweeper(chain, store, func(cfg *config.Config) {
+		cfg.Sweeper.DryRun = true
+	}).RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{statusSent}, store.statuses())
+}
+
+func TestSweeper_RunStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := newTestSweeper(newFakeChain(), &memStore{}, nil).Run(ctx)
+
+	assert.NoError(t, err)
+}
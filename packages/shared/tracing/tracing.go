@@ -0,0 +1,105 @@
+// Package tracing sets up OpenTelemetry tracing for the gateway's binaries
+// and carries trace context across the places it cannot travel in a
+// context.Context, such as outbox rows.
+//
+// Components take a trace.TracerProvider through an option and default to a
+// no-op one, so nothing here is global: a binary calls Setup once and hands
+// the provider to whatever it wires up.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracesPath is where an OTLP/HTTP collector receives traces.
+const tracesPath = "/v1/traces"
+
+// Propagator reads and writes the W3C traceparent header, in HTTP requests
+// and gRPC metadata alike.
+var Propagator propagation.TextMapPropagator = propagation.TraceContext{}
+
+// Setup returns the tracer provider described by cfg, naming the process
+// service unless cfg names it, and the function that flushes its spans on
+// shutdown. With tracing disabled the provider is a no-op.
+func Setup(ctx context.Context, cfg config.TracingConfig, service string) (trace.TracerProvider, func(context.Context) error, error) {
+	if !cfg.Enabled() {
+		return noop.NewTracerProvider(), func(context.Context) error { return nil }, nil
+	}
+	opts, err := exporterOptions(cfg.Endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	if cfg.ServiceName != "" {
+		service = cfg.ServiceName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", service)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	return tp, tp.Shutdown, nil
+}
+
+// exporterOptions points the exporter at the collector at endpoint, a base
+// URL the traces path is appended to.
+func exporterOptions(endpoint string) ([]otlptracehttp.Option, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tracing endpoint: %w", err)
+	}
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(u.Host),
+		otlptracehttp.WithURLPath(strings.TrimSuffix(u.Path, "/") + tracesPath),
+	}
+	if u.Scheme == "http" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	return opts, nil
+}
+
+// TraceParent returns the traceparent of the span in ctx, for storing next
+// to work that is picked up later, or nil when ctx carries no span.
+func TraceParent(ctx context.Context) *string {
+	carrier := propagation.MapCarrier{}
+	Propagator.Inject(ctx, carrier)
+	v, ok := carrier["traceparent"]
+	if !ok {
+		return nil
+	}
+	return &v
+}
+
+// Link links to the span traceparent names, as stored by TraceParent. It
+// reports false when traceparent is nil or malformed.
+func Link(traceparent *string) (trace.Link, bool) {
+	if traceparent == nil {
+		return trace.Link{}, false
+	}
+	ctx := Propagator.Extract(context.Background(), propagation.MapCarrier{"traceparent": *traceparent})
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return trace.Link{}, false
+	}
+	return trace.Link{SpanContext: sc}, true
+}
@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestSetup_Disabled(t *testing.T) {
+	tp, shutdown, err := Setup(context.Background(), config.TracingConfig{}, "api")
+	require.NoError(t, err)
+	assert.IsType(t, noop.TracerProvider{}, tp)
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestSetup_Enabled(t *testing.T) {
+	cfg := config.TracingConfig{Endpoint: "http://localhost:4318", SampleRatio: 1}
+	tp, shutdown, err := Setup(context.Background(), cfg, "api")
+	require.NoError(t, err)
+	assert.IsType(t, &sdktrace.TracerProvider{}, tp)
+
+	_, span := tp.Tracer("test").Start(context.Background(), "op")
+	assert.True(t, span.IsRecording())
+	span.End()
+
+	// Nothing listens on the endpoint; shutdown gives up on the export
+	// once its context is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = shutdown(ctx)
+}
+
+func TestExporterOptions(t *testing.T) {
+	for _, endpoint := range []string{"http://otel:4318", "https://otel.example.com/collector/"} {
+		opts, err := exporterOptions(endpoint)
+		require.NoError(t, err, endpoint)
+		assert.NotEmpty(t, opts)
+	}
+}
+
+func TestTraceParentRoundTrip(t *testing.T) {
+	assert.Nil(t, TraceParent(context.Background()), "no span, nothing to store")
+	_, ok := Link(nil)
+	assert.False(t, ok)
+	bad := "not-a-traceparent"
+	_, ok = Link(&bad)
+	assert.False(t, ok)
+
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "op")
+	defer span.End()
+
+	stored := TraceParent(ctx)
+	require.NotNil(t, stored)
+	assert.Regexp(t, `^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`, *stored)
+
+	link, ok := Link(stored)
+	require.True(t, ok)
+	assert.Equal(t, span.SpanContext().TraceID(), link.SpanContext.TraceID())
+	assert.Equal(t, span.SpanContext().SpanID(), link.SpanContext.SpanID())
+	assert.True(t, link.SpanContext.IsRemote())
+	assert.Equal(t, trace.FlagsSampled, link.SpanContext.TraceFlags())
+}
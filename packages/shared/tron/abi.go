@@ -0,0 +1,76 @@
+package tron
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+	"golang.org/x/crypto/sha3"
+)
+
+// wordSize is the width of an ABI-encoded argument or return value.
+const wordSize = 32
+
+// methodSelector returns the 4-byte function selector for sig, e.g.
+// "balanceOf(address)" -> 70a08231.
+func methodSelector(sig string) []byte {
+	return keccak256([]byte(sig))[:4]
+}
+
+func keccak256(b []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(b)
+	return h.Sum(nil)
+}
+
+// encodeAddressParam ABI-encodes a base58 address as a 32-byte word: the
+// 20-byte account hash without the 0x41 prefix, left-padded with zeros.
+func encodeAddressParam(address string) (string, error) {
+	h, err := wallet.AddressToHex(address)
+	if err != nil {
+		return "", err
+	}
+	return strings.Repeat("0", 2*wordSize-40) + h[2:], nil
+}
+
+// decodeUint256 decodes the first 32-byte word of hex-encoded data.
+func decodeUint256(data string) (*big.Int, error) {
+	raw, err := hex.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex result: %w", err)
+	}
+	if len(raw) < wordSize {
+		return nil, fmt.Errorf("result is %d bytes, want at least %d", len(raw), wordSize)
+	}
+	return new(big.Int).SetBytes(raw[:wordSize]), nil
+}
+
+// decodeString decodes an ABI string return value. Some older tokens return
+// a bytes32 instead, which is accepted with trailing zeros trimmed.
+func decodeString(data string) (string, error) {
+	raw, err := hex.DecodeString(data)
+	if err != nil {
+		return "", fmt.Errorf("invalid hex result: %w", err)
+	}
+	if len(raw) == wordSize {
+		return strings.TrimRight(string(raw), "\x00"), nil
+	}
+	if len(raw) < 2*wordSize {
+		return "", errors.New("string result too short")
+	}
+
+	offset := new(big.Int).SetBytes(raw[:wordSize])
+	if !offset.IsInt64() || offset.Int64()+wordSize > int64(len(raw)) {
+		return "", errors.New("string result has out of range offset")
+	}
+	start := int(offset.Int64())
+	length := new(big.Int).SetBytes(raw[start : start+wordSize])
+	if !length.IsInt64() || int64(start+wordSize)+length.Int64() > int64(len(raw)) {
+		return "", errors.New("string result has out of range length")
+	}
+	begin := start + wordSize
+	return string(raw[begin : begin+int(length.Int64())]), nil
+}
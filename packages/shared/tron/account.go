@@ -0,0 +1,130 @@
+package tron
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+type getAccountRequest struct {
+	Address string `json:"address"`
+	Visible bool   `json:"visible"`
+}
+
+// ActivationFeeSun is what the sender of the first TRX transfer to an
+// address pays to activate it: 1 TRX to create the account plus 0.1 TRX of
+// bandwidth, which account creation cannot take from free bandwidth.
+const ActivationFeeSun = 1_100_000
+
+// account is the subset of wallet/getaccount the gateway reads. The node
+// returns {} for an address that has never been activated.
+type account struct {
+	Address string    `json:"address"`
+	Balance jsonInt64 `json:"balance"`
+}
+
+// IsAddressActivated reports whether address exists on chain. A fresh
+// address is not activated until it receives TRX (or TRC10); until then a
+// TRC20 transfer to it costs the sender about twice the energy, and a TRX
+// transfer to it costs ActivationFeeSun on top.
+//
+// Activated addresses are cached for the life of the client. Unactivated
+// ones are cached only briefly, see WithInactiveAddressTTL.
+func (c *Client) IsAddressActivated(ctx context.Context, address string) (bool, error) {
+	if err := wallet.ValidateAddress(address); err != nil {
+		return false, err
+	}
+
+	c.activationMu.Lock()
+	activated := c.activated[address]
+	inactive := c.clock.Now().Before(c.inactiveUntil[address])
+	c.activationMu.Unlock()
+	if activated || inactive {
+		return activated, nil
+	}
+
+	var acc account
+	if err := c.post(ctx, c.fullNode, "/wallet/getaccount", getAccountRequest{Address: address, Visible: true}, &acc); err != nil {
+		return false, fmt.Errorf("failed to get account %s: %w", address, err)
+	}
+	activated = acc.Address != ""
+
+	c.activationMu.Lock()
+	defer c.activationMu.Unlock()
+	if activated {
+		c.activated[address] = true
+		delete(c.inactiveUntil, address)
+	} else {
+		c.inactiveUntil[address] = c.clock.Now().Add(c.inactiveTTL)
+	}
+	return activated, nil
+}
+
+// GetTRXBalance returns the balance of address in sun (1 TRX = 1e6 sun). An
+// address that has never received funds has a balance of 0.
+func (c *Client) GetTRXBalance(ctx context.Context, address string) (int64, error) {
+	return c.getTRXBalance(ctx, c.fullNode, "/wallet/getaccount", address)
+}
+
+func (c *Client) getTRXBalance(ctx context.Context, nodes *pool, path, address string) (int64, error) {
+	if err := wallet.ValidateAddress(address); err != nil {
+		return 0, err
+	}
+
+	var acc account
+	if err := c.post(ctx, nodes, path, getAccountRequest{Address: address, Visible: true}, &acc); err != nil {
+		return 0, fmt.Errorf("failed to get balance of %s: %w", address, err)
+	}
+	return int64(acc.Balance), nil
+}
+
+// GetTRXBalances looks up every address with at most maxConcurrency requests
+// in flight. Successful lookups land in balances; failures land in errs keyed
+// by address, so one bad address does not hide the rest.
+func (c *Client) GetTRXBalances(ctx context.Context, addresses []string) (balances map[string]int64, errs map[string]error) {
+	balances = make(map[string]int64, len(addresses))
+	errs = make(map[string]error)
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, c.maxConcurrency)
+		seen = make(map[string]bool, len(addresses))
+	)
+	for _, addr := range addresses {
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			mu.Lock()
+			errs[addr] = ctx.Err()
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			balance, err := c.GetTRXBalance(ctx, addr)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[addr] = err
+				return
+			}
+			balances[addr] = balance
+		}(addr)
+	}
+	wg.Wait()
+
+	return balances, errs
+}
@@ -0,0 +1,230 @@
+package tron
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+const (
+	activatedAddr   = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+	unactivatedAddr = "TMVQGm1qAQYVdetCeGRRkTWYYrLXuHK2HC"
+)
+
+func TestGetTRXBalance_Activated(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/getaccount", http.StatusOK, fixture(t, "getaccount_activated.json"))
+
+	balance, err := client.GetTRXBalance(context.Background(), activatedAddr)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1523000000), balance)
+
+	reqs := node.recorded()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, activatedAddr, reqs[0].Body["address"])
+	assert.Equal(t, true, reqs[0].Body["visible"])
+	assert.Equal(t, "test-key", reqs[0].Header.Get(apiKeyHeader))
+}
+
+func TestGetTRXBalance_Unactivated(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/getaccount", http.StatusOK, fixture(t, "getaccount_unactivated.json"))
+
+	balance, err := client.GetTRXBalance(context.Background(), unactivatedAddr)
+
+	require.NoError(t, err)
+	assert.Zero(t, balance)
+}
+
+func TestGetTRXBalance_InvalidAddress(t *testing.T) {
+	node, client := newFakeNode(t)
+
+	_, err := client.GetTRXBalance(context.Background(), "TNotAnAddress")
+
+	assert.ErrorIs(t, err, wallet.ErrInvalidAddress)
+	assert.Empty(t, node.recorded(), "no request should be sent for an invalid address")
+}
+
+func TestGetTRXBalance_NodeErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"http error", http.StatusServiceUnavailable, "overloaded", "returned 503"},
+		{"node error body", http.StatusOK, `{"Error":"class org.tron.core.exception.BadItemException"}`, "BadItemException"},
+		{"bad json", http.StatusOK, `{"balance":`, "failed to decode"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			node, client := newFakeNode(t)
+			node.respond("/wallet/getaccount", tc.status, []byte(tc.body))
+
+			_, err := client.GetTRXBalance(context.Background(), activatedAddr)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
+
+func TestGetTRXBalances(t *testing.T) {
+	node, client := newFakeNode(t)
+	client.maxConcurrency = 2
+
+	var inFlight, peak atomic.Int32
+	node.handle("/wallet/getaccount", func(body map[string]any) (int, []byte) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		switch body["address"] {
+		case activatedAddr:
+			return http.StatusOK, fixture(t, "getaccount_activated.json")
+		case unactivatedAddr:
+			return http.StatusOK, []byte(`{}`)
+		default:
+			return http.StatusInternalServerError, []byte("boom")
+		}
+	})
+
+	other, err := wallet.HexToAddress("41" + "00112233445566778899aabbccddeeff00112233")
+	require.NoError(t, err)
+
+	addresses := []string{activatedAddr, unactivatedAddr, other, "bogus", activatedAddr}
+	balances, errs := client.GetTRXBalances(context.Background(), addresses)
+
+	assert.Equal(t, map[string]int64{activatedAddr: 1523000000, unactivatedAddr: 0}, balances)
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[other].Error(), "returned 500")
+	assert.True(t, errors.Is(errs["bogus"], wallet.ErrInvalidAddress))
+	assert.LessOrEqual(t, peak.Load(), int32(2))
+	assert.Len(t, node.recorded(), 3, "duplicates and invalid addresses must not hit the node")
+}
+
+func TestGetTRXBalances_CancelledContext(t *testing.T) {
+	_, client := newFakeNode(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	balances, errs := client.GetTRXBalances(ctx, []string{activatedAddr, unactivatedAddr})
+
+	assert.Empty(t, balances)
+	assert.Len(t, errs, 2)
+}
+
+func TestIsAddressActivated(t *testing.T) {
+	testCases := []struct {
+		name    string
+		fixture string
+		address string
+		want    bool
+	}{
+		{"activated", "getaccount_activated.json", activatedAddr, true},
+		{"account not found", "getaccount_unactivated.json", unactivatedAddr, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			node, client := newFakeNode(t)
+			node.respond("/wallet/getaccount", http.StatusOK, fixture(t, tc.fixture))
+
+			activated, err := client.IsAddressActivated(context.Background(), tc.address)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, activated)
+			reqs := node.recorded()
+			require.Len(t, reqs, 1)
+			assert.Equal(t, tc.address, reqs[0].Body["address"])
+			assert.Equal(t, true, reqs[0].Body["visible"])
+		})
+	}
+}
+
+func TestIsAddressActivated_CachesActivatedForGood(t *testing.T) {
+	node, client := newFakeNode(t)
+	clock := newFakeClock()
+	client.clock = clock
+	node.respond("/wallet/getaccount", http.StatusOK, fixture(t, "getaccount_activated.json"))
+
+	for range 3 {
+		activated, err := client.IsAddressActivated(context.Background(), activatedAddr)
+		require.NoError(t, err)
+		assert.True(t, activated)
+		clock.Advance(24 * time.Hour)
+	}
+
+	assert.Len(t, node.recorded(), 1)
+}
+
+func TestIsAddressActivated_CachesUnactivatedBriefly(t *testing.T) {
+	node, client := newFakeNode(t)
+	clock := newFakeClock()
+	client.clock = clock
+	node.respond("/wallet/getaccount", http.StatusOK, fixture(t, "getaccount_unactivated.json"))
+
+	for range 2 {
+		activated, err := client.IsAddressActivated(context.Background(), unactivatedAddr)
+		require.NoError(t, err)
+		assert.False(t, activated)
+	}
+	assert.Len(t, node.recorded(), 1, "cached within the TTL")
+
+	// The first deposit activates the address.
+	clock.Advance(DefaultInactiveAddressTTL)
+	node.respond("/wallet/getaccount", http.StatusOK,
+		[]byte(`{"address":"`+unactivatedAddr+`","balance":1000000,"create_time":1727000000000}`))
+	activated, err := client.IsAddressActivated(context.Background(), unactivatedAddr)
+
+	require.NoError(t, err)
+	assert.True(t, activated)
+	assert.Len(t, node.recorded(), 2)
+}
+
+func TestIsAddressActivated_InactiveTTLOption(t *testing.T) {
+	client := NewClient(config.TronConfig{FullNodeURL: "http://localhost"}, "", WithInactiveAddressTTL(time.Minute))
+	assert.Equal(t, time.Minute, client.inactiveTTL)
+
+	client = NewClient(config.TronConfig{FullNodeURL: "http://localhost"}, "", WithInactiveAddressTTL(0))
+	assert.Equal(t, DefaultInactiveAddressTTL, client.inactiveTTL, "non-positive keeps the default")
+}
+
+func TestIsAddressActivated_ErrorsNotCached(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/getaccount", http.StatusBadRequest, []byte("bad request"))
+
+	_, err := client.IsAddressActivated(context.Background(), unactivatedAddr)
+	require.ErrorContains(t, err, "failed to get account "+unactivatedAddr)
+
+	node.respond("/wallet/getaccount", http.StatusOK, fixture(t, "getaccount_activated.json"))
+	activated, err := client.IsAddressActivated(context.Background(), unactivatedAddr)
+
+	require.NoError(t, err)
+	assert.True(t, activated)
+}
+
+func TestIsAddressActivated_InvalidAddress(t *testing.T) {
+	node, client := newFakeNode(t)
+
+	_, err := client.IsAddressActivated(context.Background(), "TNotAnAddress")
+
+	assert.ErrorIs(t, err, wallet.ErrInvalidAddress)
+	assert.Empty(t, node.recorded())
+}
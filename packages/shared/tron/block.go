@@ -0,0 +1,288 @@
+package tron
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+// ErrBlockNotFound is returned for a block number above the chain head.
+var ErrBlockNotFound = errors.New("block not found")
+
+// Contract types that move value to another address.
+const (
+	ContractTransfer             = "TransferContract"
+	ContractTriggerSmartContract = "TriggerSmartContract"
+)
+
+// contractRetSuccess marks a transaction whose contract executed successfully.
+const contractRetSuccess = "SUCCESS"
+
+// trc20TransferSelector is transfer(address,uint256).
+var trc20TransferSelector = hex.EncodeToString(methodSelector("transfer(address,uint256)"))
+
+// Block is a block as returned by wallet/getblockbynum with visible=true.
+type Block struct {
+	BlockID     string `json:"blockID"`
+	BlockHeader struct {
+		RawData BlockRawData `json:"raw_data"`
+	} `json:"block_header"`
+	// Transactions is empty for a block without transactions.
+	Transactions []Transaction `json:"transactions"`
+}
+
+// BlockRawData is the header of a block. A header without a number fails to
+// decode with ErrMissingField.
+type BlockRawData struct {
+	Number     int64  `json:"number"`
+	ParentHash string `json:"parentHash"`
+	Timestamp  int64  `json:"timestamp"`
+}
+
+func (r *BlockRawData) UnmarshalJSON(data []byte) error {
+	type plain BlockRawData
+	v := struct {
+		*plain
+		Number    *jsonInt64 `json:"number"`
+		Timestamp *jsonInt64 `json:"timestamp"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Number == nil {
+		return missingField("block_header.raw_data.number")
+	}
+	r.Number, r.Timestamp = int64(*v.Number), int64Value(v.Timestamp)
+	return nil
+}
+
+// Number returns the block height.
+func (b *Block) Number() int64 { return b.BlockHeader.RawData.Number }
+
+// Time returns the block timestamp.
+func (b *Block) Time() time.Time { return time.UnixMilli(b.BlockHeader.RawData.Timestamp) }
+
+// ParentHash returns the block ID of the previous block.
+func (b *Block) ParentHash() string { return b.BlockHeader.RawData.ParentHash }
+
+// Transaction is a transaction in the node's JSON encoding.
+type Transaction struct {
+	TxID       string              `json:"txID"`
+	RawData    TransactionRawData  `json:"raw_data"`
+	RawDataHex string              `json:"raw_data_hex,omitempty"`
+	Signature  []string            `json:"signature,omitempty"`
+	Ret        []TransactionResult `json:"ret,omitempty"`
+	// Visible reports whether the addresses in RawData are base58 rather
+	// than hex. The node needs it to parse a transaction it is sent.
+	Visible bool `json:"visible"`
+}
+
+// TransactionRawData is the signed part of a transaction.
+type TransactionRawData struct {
+	Contract      []Contract `json:"contract"`
+	RefBlockBytes string     `json:"ref_block_bytes"`
+	RefBlockHash  string     `json:"ref_block_hash"`
+	Expiration    int64      `json:"expiration"`
+	Timestamp     int64      `json:"timestamp"`
+	FeeLimit      int64      `json:"fee_limit,omitempty"`
+}
+
+func (r *TransactionRawData) UnmarshalJSON(data []byte) error {
+	type plain TransactionRawData
+	v := struct {
+		*plain
+		Expiration *jsonInt64 `json:"expiration"`
+		Timestamp  *jsonInt64 `json:"timestamp"`
+		FeeLimit   *jsonInt64 `json:"fee_limit"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	r.Expiration, r.Timestamp, r.FeeLimit = int64Value(v.Expiration), int64Value(v.Timestamp), int64Value(v.FeeLimit)
+	return nil
+}
+
+// Contract is one call in a transaction. Value is decoded according to Type.
+type Contract struct {
+	Type      string `json:"type"`
+	Parameter struct {
+		Value   json.RawMessage `json:"value"`
+		TypeURL string          `json:"type_url"`
+	} `json:"parameter"`
+}
+
+// TransactionResult is the execution result the node attaches to a
+// transaction in a block.
+type TransactionResult struct {
+	ContractRet string `json:"contractRet"`
+}
+
+// Succeeded reports whether the node recorded the transaction as successful.
+func (tx *Transaction) Succeeded() bool {
+	return len(tx.Ret) > 0 && tx.Ret[0].ContractRet == contractRetSuccess
+}
+
+type transferContractValue struct {
+	OwnerAddress string    `json:"owner_address"`
+	ToAddress    string    `json:"to_address"`
+	Amount       jsonInt64 `json:"amount"`
+}
+
+type triggerSmartContractValue struct {
+	OwnerAddress    string `json:"owner_address"`
+	ContractAddress string `json:"contract_address"`
+	Data            string `json:"data"`
+}
+
+// Transfer is a movement of TRX or TRC20 tokens found in a block. Addresses
+// are base58.
+type Transfer struct {
+	TxID string
+	// Index is the position of the contract within the transaction, or of the
+	// event in its logs for a transfer decoded from a receipt.
+	Index int
+	From  string
+	To    string
+	// Contract is the TRC20 token contract, empty for TRX.
+	Contract string
+	// Amount is in the token's base unit (sun for TRX).
+	Amount *big.Int
+}
+
+// Transfers extracts TRX transfers and direct TRC20 transfer calls from the
+// block's successful transactions. Contracts that cannot be decoded are
+// skipped, so a single odd transaction does not stall the caller.
+func (b *Block) Transfers() []Transfer {
+	var transfers []Transfer
+	for _, tx := range b.Transactions {
+		if tx.Succeeded() {
+			transfers = append(transfers, tx.Transfers()...)
+		}
+	}
+	return transfers
+}
+
+// Transfers extracts the TRX transfers and direct TRC20 transfer calls of
+// tx, whether or not it succeeded.
+func (tx *Transaction) Transfers() []Transfer {
+	var transfers []Transfer
+	for i, c := range tx.RawData.Contract {
+		t, ok := decodeTransfer(c)
+		if !ok {
+			continue
+		}
+		t.TxID = tx.TxID
+		t.Index = i
+		transfers = append(transfers, t)
+	}
+	return transfers
+}
+
+func decodeTransfer(c Contract) (Transfer, bool) {
+	switch c.Type {
+	case ContractTransfer:
+		var v transferContractValue
+		if json.Unmarshal(c.Parameter.Value, &v) != nil || v.Amount <= 0 {
+			return Transfer{}, false
+		}
+		from, err1 := normalizeAddress(v.OwnerAddress)
+		to, err2 := normalizeAddress(v.ToAddress)
+		if err1 != nil || err2 != nil {
+			return Transfer{}, false
+		}
+		return Transfer{From: from, To: to, Amount: big.NewInt(int64(v.Amount))}, true
+
+	case ContractTriggerSmartContract:
+		var v triggerSmartContractValue
+		if json.Unmarshal(c.Parameter.Value, &v) != nil {
+			return Transfer{}, false
+		}
+		to, amount, ok := decodeTRC20TransferCall(v.Data)
+		if !ok {
+			return Transfer{}, false
+		}
+		from, err1 := normalizeAddress(v.OwnerAddress)
+		contract, err2 := normalizeAddress(v.ContractAddress)
+		if err1 != nil || err2 != nil {
+			return Transfer{}, false
+		}
+		return Transfer{From: from, To: to, Contract: contract, Amount: amount}, true
+	}
+	return Transfer{}, false
+}
+
+// decodeTRC20TransferCall decodes transfer(address,uint256) calldata.
+func decodeTRC20TransferCall(data string) (to string, amount *big.Int, ok bool) {
+	raw, err := hex.DecodeString(data)
+	if err != nil || len(raw) != 4+2*wordSize || hex.EncodeToString(raw[:4]) != trc20TransferSelector {
+		return "", nil, false
+	}
+	to, err = wallet.HexToAddress(hex.EncodeToString(raw[4+wordSize-20 : 4+wordSize]))
+	if err != nil {
+		return "", nil, false
+	}
+	amount = new(big.Int).SetBytes(raw[4+wordSize:])
+	if amount.Sign() <= 0 {
+		return "", nil, false
+	}
+	return to, amount, true
+}
+
+// normalizeAddress returns address in base58, accepting the hex form nodes
+// return when visible=false.
+func normalizeAddress(address string) (string, error) {
+	if wallet.ValidateAddress(address) == nil {
+		return address, nil
+	}
+	return wallet.HexToAddress(address)
+}
+
+type getBlockByNumRequest struct {
+	Num     int64 `json:"num"`
+	Visible bool  `json:"visible"`
+}
+
+type getNowBlockRequest struct {
+	Visible bool `json:"visible"`
+}
+
+// GetBlockByNum fetches the block at height num, or ErrBlockNotFound if the
+// chain has not reached it yet.
+func (c *Client) GetBlockByNum(ctx context.Context, num int64) (*Block, error) {
+	var b Block
+	if err := c.post(ctx, c.fullNode, "/wallet/getblockbynum", getBlockByNumRequest{Num: num, Visible: true}, &b); err != nil {
+		return nil, fmt.Errorf("failed to get block %d: %w", num, err)
+	}
+	// The node answers {} for a block it does not have.
+	if b.BlockID == "" {
+		return nil, fmt.Errorf("failed to get block %d: %w", num, ErrBlockNotFound)
+	}
+	return &b, nil
+}
+
+// GetChainHeight returns the number of the latest block.
+func (c *Client) GetChainHeight(ctx context.Context) (int64, error) {
+	b, err := c.GetNowBlock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return b.Number(), nil
+}
+
+// GetNowBlock fetches the latest block.
+func (c *Client) GetNowBlock(ctx context.Context) (*Block, error) {
+	var b Block
+	if err := c.post(ctx, c.fullNode, "/wallet/getnowblock", getNowBlockRequest{Visible: true}, &b); err != nil {
+		return nil, fmt.Errorf("failed to get latest block: %w", err)
+	}
+	if b.BlockID == "" {
+		return nil, fmt.Errorf("failed to get latest block: %w", ErrBlockNotFound)
+	}
+	return &b, nil
+}
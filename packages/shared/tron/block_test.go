@@ -0,0 +1,160 @@
+package tron
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBlockByNum(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/getblockbynum", http.StatusOK, fixture(t, "getblockbynum_transfers.json"))
+
+	block, err := client.GetBlockByNum(context.Background(), 1000)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), block.Number())
+	assert.Equal(t, time.UnixMilli(1700000004000), block.Time())
+	assert.Equal(t, "00000000000003e7"+"b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2", block.ParentHash())
+	assert.Len(t, block.Transactions, 4)
+
+	reqs := node.recorded()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, float64(1000), reqs[0].Body["num"])
+	assert.Equal(t, true, reqs[0].Body["visible"])
+}
+
+func TestGetBlockByNum_NotFound(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/getblockbynum", http.StatusOK, []byte(`{}`))
+
+	_, err := client.GetBlockByNum(context.Background(), 99999999)
+
+	assert.ErrorIs(t, err, ErrBlockNotFound)
+}
+
+func TestGetBlockByNum_StringNumbers(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/getblockbynum", http.StatusOK, fixture(t, "getblockbynum_string_numbers.json"))
+
+	block, err := client.GetBlockByNum(context.Background(), 1000)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), block.Number())
+	assert.Equal(t, time.UnixMilli(1700000004000), block.Time())
+	require.Len(t, block.Transactions, 1)
+	raw := block.Transactions[0].RawData
+	assert.Equal(t, int64(1700000063000), raw.Expiration)
+	assert.Equal(t, int64(1700000003000), raw.Timestamp)
+	assert.Zero(t, raw.FeeLimit)
+
+	transfers := block.Transfers()
+	require.Len(t, transfers, 1)
+	assert.Equal(t, big.NewInt(2500000), transfers[0].Amount)
+}
+
+func TestGetBlockByNum_MissingNumber(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/getblockbynum", http.StatusOK, fixture(t, "getblockbynum_missing_number.json"))
+
+	_, err := client.GetBlockByNum(context.Background(), 1000)
+
+	require.ErrorIs(t, err, ErrMissingField)
+	assert.ErrorContains(t, err, "/wallet/getblockbynum")
+	assert.ErrorContains(t, err, "block_header.raw_data.number")
+	assert.Len(t, node.recorded(), 1, "a malformed response is not retried")
+}
+
+func TestGetNowBlock(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/getnowblock", http.StatusOK, fixture(t, "getnowblock.json"))
+
+	block, err := client.GetNowBlock(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1005), block.Number())
+	assert.Empty(t, block.Transactions)
+}
+
+func TestGetChainHeight(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/getnowblock", http.StatusOK, fixture(t, "getnowblock.json"))
+
+	height, err := client.GetChainHeight(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1005), height)
+
+	node.respond("/wallet/getnowblock", http.StatusOK, []byte(`{}`))
+	_, err = client.GetChainHeight(context.Background())
+	assert.ErrorIs(t, err, ErrBlockNotFound)
+}
+
+func decodeBlock(t *testing.T, name string) *Block {
+	t.Helper()
+	var b Block
+	require.NoError(t, json.Unmarshal(fixture(t, name), &b))
+	return &b
+}
+
+func TestBlock_Transfers(t *testing.T) {
+	transfers := decodeBlock(t, "getblockbynum_transfers.json").Transfers()
+
+	// The reverted transfer and the approve call are skipped.
+	require.Len(t, transfers, 2)
+
+	trx := transfers[0]
+	assert.Equal(t, "1111111111111111111111111111111111111111111111111111111111111111", trx.TxID)
+	assert.Equal(t, "TKTX96CBxr5kvhjsDHcqoiPWZageGxoTW3", trx.From)
+	assert.Equal(t, "TDvSsdrNM5eeXNL3czpa6AxLDHZA9nwe9K", trx.To)
+	assert.Empty(t, trx.Contract)
+	assert.Equal(t, big.NewInt(2500000), trx.Amount)
+
+	usdt := transfers[1]
+	assert.Equal(t, "2222222222222222222222222222222222222222222222222222222222222222", usdt.TxID)
+	assert.Equal(t, "TMVQGm1qAQYVdetCeGRRkTWYYrLXuHK2HC", usdt.To)
+	assert.Equal(t, "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", usdt.Contract)
+	assert.Equal(t, big.NewInt(100000000), usdt.Amount)
+}
+
+func TestBlock_Transfers_EmptyBlock(t *testing.T) {
+	b := decodeBlock(t, "getblockbynum_empty.json")
+
+	assert.Equal(t, int64(1001), b.Number())
+	assert.Empty(t, b.Transfers())
+}
+
+func TestBlock_Transfers_HexAddresses(t *testing.T) {
+	transfers := decodeBlock(t, "getblockbynum_hex_addresses.json").Transfers()
+
+	require.Len(t, transfers, 1)
+	assert.Equal(t, "TMVQGm1qAQYVdetCeGRRkTWYYrLXuHK2HC", transfers[0].To)
+	assert.Equal(t, byte('T'), transfers[0].From[0])
+}
+
+func TestDecodeTRC20TransferCall(t *testing.T) {
+	word := func(h string) string { return strings.Repeat("0", 64-len(h)) + h }
+	valid := "a9059cbb" + word("7e5f4552091a69125d5dfcb7b8c2659029395bdf") + word("5f5e100")
+
+	to, amount, ok := decodeTRC20TransferCall(valid)
+	require.True(t, ok)
+	assert.Equal(t, "TMVQGm1qAQYVdetCeGRRkTWYYrLXuHK2HC", to)
+	assert.Equal(t, big.NewInt(100000000), amount)
+
+	for name, data := range map[string]string{
+		"wrong selector": "095ea7b3" + valid[8:],
+		"truncated":      valid[:len(valid)-2],
+		"zero amount":    "a9059cbb" + word("7e5f4552091a69125d5dfcb7b8c2659029395bdf") + word("0"),
+		"not hex":        "zz",
+	} {
+		_, _, ok := decodeTRC20TransferCall(data)
+		assert.False(t, ok, name)
+	}
+}
@@ -0,0 +1,126 @@
+package tron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultReceiptPollInterval is one block; polling faster cannot find a
+// receipt sooner.
+const DefaultReceiptPollInterval = 3 * time.Second
+
+// Reasons the node rejects a broadcast. A BroadcastError wraps the one
+// matching its code, so callers can errors.Is to decide what to do next.
+var (
+	// ErrSignature means the transaction must be signed again.
+	ErrSignature = errors.New("invalid signature")
+	// ErrBandwidth means the sender lacks bandwidth or TRX to pay for it;
+	// wait for bandwidth to recover or fund the account.
+	ErrBandwidth = errors.New("insufficient bandwidth")
+	// ErrDuplicateTransaction means the node already has the transaction,
+	// e.g. from an earlier attempt; wait for its receipt instead.
+	ErrDuplicateTransaction = errors.New("transaction already broadcast")
+	// ErrTapos means the reference block is not on the node's chain; build
+	// the transaction again from a recent block.
+	ErrTapos = errors.New("reference block not found")
+	// ErrTransactionExpired means the expiration passed; build again.
+	ErrTransactionExpired = errors.New("transaction expired")
+	// ErrTransactionTooBig means the transaction exceeds the size limit.
+	ErrTransactionTooBig = errors.New("transaction too big")
+	// ErrContractValidation means the contract cannot run, e.g. because the
+	// balance is too low; retrying will not help.
+	ErrContractValidation = errors.New("contract validation failed")
+	// ErrContractExecution means the contract failed while executing.
+	ErrContractExecution = errors.New("contract execution failed")
+	// ErrNodeBusy means the node could not relay the transaction right now;
+	// broadcasting again later may succeed.
+	ErrNodeBusy = errors.New("node unavailable")
+)
+
+// broadcastCodes maps the node's response codes to the errors above.
+var broadcastCodes = map[string]error{
+	"SIGERROR":                        ErrSignature,
+	"BANDWITH_ERROR":                  ErrBandwidth,
+	"DUP_TRANSACTION_ERROR":           ErrDuplicateTransaction,
+	"TAPOS_ERROR":                     ErrTapos,
+	"TRANSACTION_EXPIRATION_ERROR":    ErrTransactionExpired,
+	"TOO_BIG_TRANSACTION_ERROR":       ErrTransactionTooBig,
+	"CONTRACT_VALIDATE_ERROR":         ErrContractValidation,
+	"CONTRACT_EXE_ERROR":              ErrContractExecution,
+	"SERVER_BUSY":                     ErrNodeBusy,
+	"NO_CONNECTION":                   ErrNodeBusy,
+	"NOT_ENOUGH_EFFECTIVE_CONNECTION": ErrNodeBusy,
+}
+
+// BroadcastError is returned when the node rejects a transaction. It
+// unwraps to one of the Err values above, or to nothing for OTHER_ERROR and
+// codes this package does not know.
+type BroadcastError struct {
+	TxID    string
+	Code    string
+	Message string
+}
+
+func (e *BroadcastError) Error() string {
+	return fmt.Sprintf("broadcast of %s rejected: %s: %s", e.TxID, e.Code, e.Message)
+}
+
+func (e *BroadcastError) Unwrap() error { return broadcastCodes[e.Code] }
+
+type broadcastResponse struct {
+	Result  bool   `json:"result"`
+	Code    string `json:"code"`
+	TxID    string `json:"txid"`
+	Message string `json:"message"`
+}
+
+// BroadcastTransaction submits a signed transaction and returns its ID. The
+// transaction is only in the mempool afterwards; see WaitForTransaction.
+func (c *Client) BroadcastTransaction(ctx context.Context, signedTx Transaction) (string, error) {
+	var resp broadcastResponse
+	if err := c.post(ctx, c.fullNode, "/wallet/broadcasttransaction", signedTx, &resp); err != nil {
+		return "", fmt.Errorf("failed to broadcast %s: %w", signedTx.TxID, err)
+	}
+	if !resp.Result {
+		code := resp.Code
+		if code == "" {
+			code = "OTHER_ERROR"
+		}
+		return "", &BroadcastError{TxID: signedTx.TxID, Code: code, Message: decodeNodeMessage(resp.Message)}
+	}
+	if resp.TxID == "" {
+		return signedTx.TxID, nil
+	}
+	return resp.TxID, nil
+}
+
+// WaitForTransaction polls for the receipt of txID until it is in a block,
+// timeout passes or ctx is done. A receipt is returned even if the
+// transaction failed; check TransactionInfo.Failed.
+func (c *Client) WaitForTransaction(ctx context.Context, txID string, timeout time.Duration) (*TransactionInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(c.receiptPollInterval)
+	defer ticker.Stop()
+	for {
+		info, err := c.GetTransactionInfoByID(ctx, txID)
+		if err == nil {
+			return info, nil
+		}
+		if !errors.Is(err, ErrTransactionNotFound) && ctx.Err() == nil {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("no receipt for %s after %s: %w", txID, timeout, ErrTransactionNotFound)
+			}
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package tron
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signedTransfer(t *testing.T) Transaction {
+	t.Helper()
+	tx := decodeBlock(t, "getblockbynum_transfers.json").Transactions[0]
+	tx.Ret = nil
+	tx.Visible = true
+	return tx
+}
+
+func TestBroadcastTransaction(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/broadcasttransaction", http.StatusOK, fixture(t, "broadcast_success.json"))
+	tx := signedTransfer(t)
+
+	txID, err := client.BroadcastTransaction(context.Background(), tx)
+
+	require.NoError(t, err)
+	assert.Equal(t, tx.TxID, txID)
+
+	reqs := node.recorded()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, tx.TxID, reqs[0].Body["txID"])
+	assert.Equal(t, true, reqs[0].Body["visible"])
+	assert.NotEmpty(t, reqs[0].Body["signature"])
+	assert.NotContains(t, reqs[0].Body, "ret")
+}
+
+func TestBroadcastTransaction_Errors(t *testing.T) {
+	var responses map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(fixture(t, "broadcast_errors.json"), &responses))
+
+	want := map[string]error{
+		"SIGERROR":                        ErrSignature,
+		"BANDWITH_ERROR":                  ErrBandwidth,
+		"DUP_TRANSACTION_ERROR":           ErrDuplicateTransaction,
+		"TAPOS_ERROR":                     ErrTapos,
+		"TRANSACTION_EXPIRATION_ERROR":    ErrTransactionExpired,
+		"TOO_BIG_TRANSACTION_ERROR":       ErrTransactionTooBig,
+		"CONTRACT_VALIDATE_ERROR":         ErrContractValidation,
+		"CONTRACT_EXE_ERROR":              ErrContractExecution,
+		"SERVER_BUSY":                     ErrNodeBusy,
+		"NO_CONNECTION":                   ErrNodeBusy,
+		"NOT_ENOUGH_EFFECTIVE_CONNECTION": ErrNodeBusy,
+		"OTHER_ERROR":                     nil,
+	}
+	require.Len(t, responses, len(want))
+
+	for code, body := range responses {
+		t.Run(code, func(t *testing.T) {
+			node, client := newFakeNode(t)
+			node.respond("/wallet/broadcasttransaction", http.StatusOK, body)
+
+			txID, err := client.BroadcastTransaction(context.Background(), signedTransfer(t))
+
+			assert.Empty(t, txID)
+			var berr *BroadcastError
+			require.ErrorAs(t, err, &berr)
+			assert.Equal(t, code, berr.Code)
+			assert.NotEmpty(t, berr.Message)
+			assert.NotRegexp(t, "^[0-9a-f]+$", berr.Message, "message is decoded from hex")
+			if want[code] != nil {
+				assert.ErrorIs(t, err, want[code])
+			} else {
+				assert.Nil(t, errors.Unwrap(err))
+			}
+		})
+	}
+}
+
+func TestBroadcastTransaction_DecodedMessage(t *testing.T) {
+	node, client := newFakeNode(t)
+	var responses map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(fixture(t, "broadcast_errors.json"), &responses))
+	node.respond("/wallet/broadcasttransaction", http.StatusOK, responses["CONTRACT_VALIDATE_ERROR"])
+
+	_, err := client.BroadcastTransaction(context.Background(), signedTransfer(t))
+
+	assert.EqualError(t, err, "broadcast of 1111111111111111111111111111111111111111111111111111111111111111 rejected: "+
+		"CONTRACT_VALIDATE_ERROR: Contract validate error : Validate TransferContract error, balance is not sufficient.")
+}
+
+func TestBroadcastTransaction_NoCode(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/broadcasttransaction", http.StatusOK, []byte(`{"result":false}`))
+
+	_, err := client.BroadcastTransaction(context.Background(), signedTransfer(t))
+
+	var berr *BroadcastError
+	require.ErrorAs(t, err, &berr)
+	assert.Equal(t, "OTHER_ERROR", berr.Code)
+}
+
+func newPollingNode(t *testing.T) (*fakeNode, *Client) {
+	t.Helper()
+	node, client := newFakeNode(t)
+	WithReceiptPollInterval(5 * time.Millisecond)(client)
+	return node, client
+}
+
+func TestWaitForTransaction_PendingThenFound(t *testing.T) {
+	node, client := newPollingNode(t)
+	var calls atomic.Int32
+	receipt := fixture(t, "gettransactioninfobyid_trx.json")
+	node.handle("/wallet/gettransactioninfobyid", func(map[string]any) (int, []byte) {
+		if calls.Add(1) < 3 {
+			return http.StatusOK, []byte(`{}`)
+		}
+		return http.StatusOK, receipt
+	})
+
+	info, err := client.WaitForTransaction(context.Background(),
+		"1111111111111111111111111111111111111111111111111111111111111111", time.Second)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), info.BlockNumber)
+	assert.False(t, info.Failed())
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestWaitForTransaction_Failed(t *testing.T) {
+	node, client := newPollingNode(t)
+	node.respond("/wallet/gettransactioninfobyid", http.StatusOK,
+		[]byte(`{"id":"3333","blockNumber":1000,"result":"FAILED","receipt":{"result":"REVERT"}}`))
+
+	info, err := client.WaitForTransaction(context.Background(), "3333", time.Second)
+
+	require.NoError(t, err)
+	assert.True(t, info.Failed())
+}
+
+func TestWaitForTransaction_Timeout(t *testing.T) {
+	node, client := newPollingNode(t)
+	node.respond("/wallet/gettransactioninfobyid", http.StatusOK, []byte(`{}`))
+
+	start := time.Now()
+	_, err := client.WaitForTransaction(context.Background(), "dead", 30*time.Millisecond)
+
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+	assert.ErrorContains(t, err, "after 30ms")
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Greater(t, len(node.recorded()), 1, "polls more than once")
+}
+
+func TestWaitForTransaction_Canceled(t *testing.T) {
+	node, client := newPollingNode(t)
+	node.respond("/wallet/gettransactioninfobyid", http.StatusOK, []byte(`{}`))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.WaitForTransaction(ctx, "dead", time.Second)
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestWaitForTransaction_NodeError(t *testing.T) {
+	node, client := newPollingNode(t)
+	node.respond("/wallet/gettransactioninfobyid", http.StatusInternalServerError, []byte(`boom`))
+
+	_, err := client.WaitForTransaction(context.Background(), "dead", time.Second)
+
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrTransactionNotFound)
+	assert.Len(t, node.recorded(), 1, "does not keep polling a failing node")
+}
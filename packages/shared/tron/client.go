@@ -0,0 +1,277 @@
+// Package tron is a small HTTP client for the TRON full node and solidity
+// node APIs (TronGrid compatible).
+package tron
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// apiKeyHeader carries the TronGrid API key.
+const apiKeyHeader = "TRON-PRO-API-KEY"
+
+// DefaultMaxConcurrency bounds the fan-out of batch calls such as
+// GetTRXBalances.
+const DefaultMaxConcurrency = 8
+
+// DefaultInactiveAddressTTL is how long IsAddressActivated trusts a "not
+// activated" answer; the next transfer to the address may activate it.
+const DefaultInactiveAddressTTL = 30 * time.Second
+
+// Metrics records node requests. *metrics.Metrics implements it.
+type Metrics interface {
+	// TronRequest records a request to an API path and the HTTP status it
+	// got, or 0 if no response came back.
+	TronRequest(endpoint string, status int)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) TronRequest(string, int) {}
+
+// Client talks to a TRON node over its HTTP API.
+type Client struct {
+	httpClient *http.Client
+	// fullNode spreads full node calls across the configured endpoints.
+	fullNode *pool
+	// solidity serves the Confirmed view.
+	solidity       *pool
+	maxConcurrency int
+	// receiptPollInterval paces WaitForTransaction.
+	receiptPollInterval time.Duration
+	retry               retryPolicy
+	clock               clock
+	metrics             Metrics
+	tracer              trace.Tracer
+	// usage counts requests and enforces the daily budget.
+	usage *usage
+
+	// Token metadata never changes per contract, so it is cached.
+	tokenMu  sync.Mutex
+	decimals map[string]uint8
+	symbols  map[string]string
+
+	// Activation cannot be undone, so activated addresses are cached for
+	// good and unactivated ones until their entry in inactiveUntil.
+	activationMu  sync.Mutex
+	activated     map[string]bool
+	inactiveUntil map[string]time.Time
+	inactiveTTL   time.Duration
+}
+
+// ClientOption customises a Client.
+type ClientOption func(*Client)
+
+// WithHTTPClient replaces the default HTTP client, e.g. in tests.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithMetrics records every request made to a node in m, retries included.
+func WithMetrics(m Metrics) ClientOption {
+	return func(c *Client) { c.metrics = m }
+}
+
+// WithMaxConcurrency bounds how many requests batch calls run at once.
+func WithMaxConcurrency(n int) ClientOption {
+	return func(c *Client) {
+		if n > 0 {
+			c.maxConcurrency = n
+		}
+	}
+}
+
+// WithReceiptPollInterval changes how often WaitForTransaction polls.
+func WithReceiptPollInterval(d time.Duration) ClientOption {
+	return func(c *Client) {
+		if d > 0 {
+			c.receiptPollInterval = d
+		}
+	}
+}
+
+// WithInactiveAddressTTL changes how long IsAddressActivated caches an
+// address that is not activated.
+func WithInactiveAddressTTL(d time.Duration) ClientOption {
+	return func(c *Client) {
+		if d > 0 {
+			c.inactiveTTL = d
+		}
+	}
+}
+
+// NewClient builds a client for the node endpoints in cfg. apiKey may be
+// empty; it is sent to every endpoint that has no key of its own.
+func NewClient(cfg config.TronConfig, apiKey string, opts ...ClientOption) *Client {
+	c := &Client{
+		httpClient:          &http.Client{Timeout: cfg.RequestTimeout.Std()},
+		fullNode:            newPool(cfg.FullNodeEndpoints(), apiKey, cfg.RateLimit),
+		maxConcurrency:      DefaultMaxConcurrency,
+		receiptPollInterval: DefaultReceiptPollInterval,
+		retry: retryPolicy{
+			maxAttempts: max(cfg.Retry.MaxAttempts, 1),
+			baseDelay:   cfg.Retry.BaseDelay.Std(),
+			maxDelay:    cfg.Retry.MaxDelay.Std(),
+		},
+		clock:         realClock{},
+		metrics:       nopMetrics{},
+		tracer:        noop.NewTracerProvider().Tracer(tracerName),
+		usage:         newUsage(cfg.DailyBudget),
+		decimals:      make(map[string]uint8),
+		symbols:       make(map[string]string),
+		activated:     make(map[string]bool),
+		inactiveUntil: make(map[string]time.Time),
+		inactiveTTL:   DefaultInactiveAddressTTL,
+	}
+	c.solidity = c.fullNode.share(config.TronEndpoint{URL: cfg.SolidityNodeURL}, apiKey, cfg.RateLimit)
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// nodeError is the {"Error": "..."} body some endpoints return with a 200.
+type nodeError struct {
+	Error string `json:"Error"`
+}
+
+// notRetried lists the calls that change state. Every other call only reads
+// and is retried on failure.
+var notRetried = map[string]bool{
+	"/wallet/broadcasttransaction": true,
+}
+
+// post sends body as JSON to path on the best endpoint of nodes and decodes
+// the response into out, waiting for the endpoint's rate limiter first.
+//
+// A non-critical call fails with ErrBudgetExhausted once the daily budget is
+// spent; see NonCritical.
+//
+// A read that fails because of the endpoint, e.g. a timeout, 429 or 5xx, is
+// retried up to the retry policy's attempt count. A retry goes straight to
+// the next best endpoint when there is one; otherwise it backs off
+// exponentially, or for as long as the node's Retry-After asks.
+func (c *Client) post(ctx context.Context, nodes *pool, path string, body, out any) (err error) {
+	ctx, span := c.startSpan(ctx, path)
+	defer func() { endSpan(span, out, err) }()
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", path, err)
+	}
+
+	attempts := 1
+	if !notRetried[path] {
+		attempts = c.retry.maxAttempts
+	}
+	var last *endpoint
+	for attempt := 1; ; attempt++ {
+		ep := nodes.pick(c.clock.Now(), last)
+		tagAttempt(span, ep, attempt)
+		if err := ep.limiter.wait(ctx, c.clock); err != nil {
+			return err
+		}
+		if err := c.usage.admit(path, c.clock.Now(), isCritical(ctx)); err != nil {
+			return err
+		}
+		err := c.send(ctx, ep, path, payload, out)
+		rerr, retry := isRetryable(err)
+		if !retry || attempt >= attempts || ctx.Err() != nil {
+			return err
+		}
+		if next := nodes.pick(c.clock.Now(), ep); next == ep {
+			delay, ok := c.retry.delay(attempt, rerr.retryAfter)
+			if !ok {
+				return err
+			}
+			if err := sleep(ctx, c.clock, delay); err != nil {
+				return err
+			}
+		}
+		last = ep
+	}
+}
+
+// send makes one request to ep and records how ep fared. Failures that are
+// the endpoint's fault rather than the request's are a *retryableError.
+func (c *Client) send(ctx context.Context, ep *endpoint, path string, payload []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.url+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build %s request: %w", path, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if ep.apiKey != "" {
+		req.Header.Set(apiKeyHeader, ep.apiKey)
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.metrics.TronRequest(path, 0)
+		// A cancelled caller says nothing about the endpoint.
+		if ctx.Err() != nil {
+			return fmt.Errorf("failed to call %s: %w", path, err)
+		}
+		ep.failed(c.clock.Now())
+		return &retryableError{err: fmt.Errorf("failed to call %s: %w", path, err)}
+	}
+	defer resp.Body.Close()
+	c.metrics.TronRequest(path, resp.StatusCode)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("failed to read %s response: %w", path, err)
+		}
+		ep.failed(c.clock.Now())
+		return &retryableError{err: fmt.Errorf("failed to read %s response: %w", path, err)}
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		now := c.clock.Now()
+		after := retryAfter(resp.Header, now)
+		cooldown := after
+		if cooldown == 0 {
+			cooldown = DefaultRateLimitCooldown
+		}
+		ep.throttled(now, cooldown)
+		return &retryableError{err: fmt.Errorf("%s returned %d: %w", path, resp.StatusCode, ErrRateLimited), retryAfter: after}
+	case resp.StatusCode >= http.StatusInternalServerError:
+		ep.failed(c.clock.Now())
+		return &retryableError{err: fmt.Errorf("%s returned %d: %s", path, resp.StatusCode, truncate(data, 200))}
+	}
+	ep.answered(time.Since(start))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d: %s", path, resp.StatusCode, truncate(data, 200))
+	}
+
+	var nerr nodeError
+	if json.Unmarshal(data, &nerr) == nil && nerr.Error != "" {
+		return fmt.Errorf("%s failed: %s", path, nerr.Error)
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", path, err)
+	}
+	return nil
+}
+
+func truncate(b []byte, n int) string {
+	if len(b) <= n {
+		return string(b)
+	}
+	return string(b[:n]) + "…"
+}
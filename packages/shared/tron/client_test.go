@@ -0,0 +1,88 @@
+package tron
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+// fakeNode serves canned responses per API path and records requests.
+type fakeNode struct {
+	t        *testing.T
+	mu       sync.Mutex
+	handlers map[string]func(body map[string]any) (int, []byte)
+	requests []recordedRequest
+	// retryAfter is sent with every 429.
+	retryAfter string
+}
+
+type recordedRequest struct {
+	Path   string
+	Header http.Header
+	Body   map[string]any
+}
+
+func newFakeNode(t *testing.T) (*fakeNode, *Client) {
+	t.Helper()
+	n := &fakeNode{t: t, handlers: make(map[string]func(map[string]any) (int, []byte))}
+	srv := httptest.NewServer(n)
+	t.Cleanup(srv.Close)
+
+	cfg := config.TronConfig{FullNodeURL: srv.URL, SolidityNodeURL: srv.URL + "/solidity"}
+	return n, NewClient(cfg, "test-key", WithHTTPClient(srv.Client()))
+}
+
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]any
+	data, _ := io.ReadAll(r.Body)
+	_ = json.Unmarshal(data, &body)
+
+	n.mu.Lock()
+	n.requests = append(n.requests, recordedRequest{Path: r.URL.Path, Header: r.Header.Clone(), Body: body})
+	h, ok := n.handlers[r.URL.Path]
+	retryAfter := n.retryAfter
+	n.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	status, resp := h(body)
+	if status == http.StatusTooManyRequests && retryAfter != "" {
+		w.Header().Set("Retry-After", retryAfter)
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(resp)
+}
+
+func (n *fakeNode) handle(path string, h func(body map[string]any) (int, []byte)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.handlers[path] = h
+}
+
+// respond always answers path with the given status and body.
+func (n *fakeNode) respond(path string, status int, body []byte) {
+	n.handle(path, func(map[string]any) (int, []byte) { return status, body })
+}
+
+func (n *fakeNode) recorded() []recordedRequest {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]recordedRequest(nil), n.requests...)
+}
+
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return data
+}
@@ -0,0 +1,69 @@
+package tron
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrMissingField is returned for a response that lacks a field the client
+// cannot do without, e.g. the number of a block. The error from the call
+// names the field and the endpoint.
+var ErrMissingField = errors.New("missing required field")
+
+// Nodes and proxies do not agree on how they encode numbers: most send JSON
+// numbers, some send the same value as a string, and fields the node has
+// nothing to say about come back null or not at all. Responses are decoded
+// leniently so that such drift does not stop the gateway: unknown fields are
+// ignored, numbers are read from either form and absent optional fields are
+// zero. Only the few fields a response is useless without are required.
+
+// jsonInt64 is an int64 that decodes from a JSON number, a string holding
+// one, or null. null and "" decode to 0.
+type jsonInt64 int64
+
+func (n *jsonInt64) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*n = 0
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		if s == "" {
+			*n = 0
+			return nil
+		}
+		data = []byte(s)
+	}
+	v, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		// Some encoders write integers as 1.7e+12.
+		f, ferr := strconv.ParseFloat(string(data), 64)
+		if ferr != nil || f != float64(int64(f)) {
+			return fmt.Errorf("invalid integer %s", data)
+		}
+		v = int64(f)
+	}
+	*n = jsonInt64(v)
+	return nil
+}
+
+// int64Value returns the value of a field decoded into p, 0 if it was absent
+// or null.
+func int64Value(p *jsonInt64) int64 {
+	if p == nil {
+		return 0
+	}
+	return int64(*p)
+}
+
+// missingField returns the error for a required field that is absent.
+func missingField(name string) error {
+	return fmt.Errorf("%w %s", ErrMissingField, name)
+}
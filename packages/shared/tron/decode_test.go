@@ -0,0 +1,52 @@
+package tron
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONInt64(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{`1000`, 1000},
+		{`"1000"`, 1000},
+		{`-5`, -5},
+		{`"-5"`, -5},
+		{`1.7e+12`, 1700000000000},
+		{`"1.7e+12"`, 1700000000000},
+		{`null`, 0},
+		{`""`, 0},
+		{`9223372036854775807`, 9223372036854775807},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			n := jsonInt64(42)
+			require.NoError(t, json.Unmarshal([]byte(tt.in), &n))
+			assert.Equal(t, tt.want, int64(n))
+		})
+	}
+}
+
+func TestJSONInt64_Invalid(t *testing.T) {
+	for _, in := range []string{`"abc"`, `1.5`, `"0x3e8"`, `true`, `{}`} {
+		var n jsonInt64
+		assert.Error(t, json.Unmarshal([]byte(in), &n), in)
+	}
+}
+
+func TestJSONInt64_Absent(t *testing.T) {
+	var v struct {
+		N *jsonInt64 `json:"n"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"n": null}`), &v))
+	assert.Nil(t, v.N)
+	assert.Zero(t, int64Value(v.N))
+
+	require.NoError(t, json.Unmarshal([]byte(`{"n": "7"}`), &v))
+	assert.Equal(t, int64(7), int64Value(v.N))
+}
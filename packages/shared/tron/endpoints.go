@@ -0,0 +1,220 @@
+package tron
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+// ErrRateLimited is returned when a node answers 429 Too Many Requests.
+var ErrRateLimited = errors.New("rate limited")
+
+// Endpoint health tuning.
+const (
+	// MaxConsecutiveFailures takes an endpoint out of rotation for
+	// FailureCooldown once it fails this many requests in a row.
+	MaxConsecutiveFailures = 3
+	FailureCooldown        = 30 * time.Second
+	// DefaultRateLimitCooldown rests a rate-limited endpoint whose 429 has no
+	// Retry-After header.
+	DefaultRateLimitCooldown = 30 * time.Second
+	// latencyWeight is the share of the newest sample in the latency EWMA.
+	latencyWeight = 0.3
+)
+
+// EndpointHealth is a snapshot of one node endpoint, e.g. for metrics.
+type EndpointHealth struct {
+	URL string
+	// Healthy is false while the endpoint is cooling down after repeated
+	// failures or a rate limit.
+	Healthy     bool
+	RateLimited bool
+	// CooldownUntil is when a cooling endpoint is tried again.
+	CooldownUntil       time.Time
+	ConsecutiveFailures int
+	// Latency is a moving average over answered requests, zero until the
+	// first answer.
+	Latency  time.Duration
+	Requests int64
+	Failures int64
+}
+
+// endpoint is one node and its health.
+type endpoint struct {
+	url     string
+	apiKey  string
+	limiter *limiter
+
+	mu            sync.Mutex
+	failures      int
+	latency       time.Duration
+	sampled       bool
+	rateLimited   bool
+	cooldownUntil time.Time
+	requests      int64
+	errors        int64
+}
+
+// answered records a response that says nothing bad about the node, even if
+// the request itself failed.
+func (e *endpoint) answered(d time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests++
+	e.failures = 0
+	e.rateLimited = false
+	if !e.sampled {
+		e.latency, e.sampled = d, true
+		return
+	}
+	e.latency = time.Duration(latencyWeight*float64(d) + (1-latencyWeight)*float64(e.latency))
+}
+
+// failed records a transport error or server fault.
+func (e *endpoint) failed(now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests++
+	e.errors++
+	e.failures++
+	if e.failures >= MaxConsecutiveFailures {
+		e.cooldownUntil = now.Add(FailureCooldown)
+	}
+}
+
+// throttled records a 429 and rests the endpoint for cooldown.
+func (e *endpoint) throttled(now time.Time, cooldown time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests++
+	e.errors++
+	e.rateLimited = true
+	e.cooldownUntil = now.Add(cooldown)
+}
+
+func (e *endpoint) health(now time.Time) EndpointHealth {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	cooling := now.Before(e.cooldownUntil)
+	h := EndpointHealth{
+		URL:                 e.url,
+		Healthy:             !cooling,
+		RateLimited:         e.rateLimited && cooling,
+		ConsecutiveFailures: e.failures,
+		Latency:             e.latency,
+		Requests:            e.requests,
+		Failures:            e.errors,
+	}
+	if cooling {
+		h.CooldownUntil = e.cooldownUntil
+	}
+	return h
+}
+
+// pool routes requests across the endpoints serving one API.
+type pool struct {
+	endpoints []*endpoint
+}
+
+// newPool builds a pool for endpoints, each paced by its own limiter.
+// Endpoints without a key use apiKey.
+func newPool(endpoints []config.TronEndpoint, apiKey string, rl config.TronRateLimitConfig) *pool {
+	p := &pool{}
+	for _, e := range endpoints {
+		key := e.Key()
+		if key == "" {
+			key = apiKey
+		}
+		p.endpoints = append(p.endpoints, &endpoint{
+			url:     strings.TrimRight(e.URL, "/"),
+			apiKey:  key,
+			limiter: newLimiter(rl.RequestsPerSecond, rl.Burst),
+		})
+	}
+	return p
+}
+
+// share returns a pool for e that reuses this pool's endpoint of the same
+// URL, so both pools draw on one rate limit and one health record. TronGrid
+// serves the full node and solidity APIs from one host under one quota.
+func (p *pool) share(e config.TronEndpoint, apiKey string, rl config.TronRateLimitConfig) *pool {
+	url := strings.TrimRight(e.URL, "/")
+	for _, ep := range p.endpoints {
+		if ep.url == url {
+			return &pool{endpoints: []*endpoint{ep}}
+		}
+	}
+	return newPool([]config.TronEndpoint{e}, apiKey, rl)
+}
+
+// pick returns the best endpoint other than skip. Endpoints cooling down are
+// passed over; among the rest, fewer consecutive failures win, then a
+// measured endpoint beats an unmeasured one, then lower latency, then
+// configuration order. If every endpoint is cooling down the one that
+// recovers first is returned.
+func (p *pool) pick(now time.Time, skip *endpoint) *endpoint {
+	var best, soonest *endpoint
+	var bestH, soonestH EndpointHealth
+	for _, e := range p.endpoints {
+		if e == skip && len(p.endpoints) > 1 {
+			continue
+		}
+		h := e.health(now)
+		if !h.Healthy {
+			if soonest == nil || h.CooldownUntil.Before(soonestH.CooldownUntil) {
+				soonest, soonestH = e, h
+			}
+			continue
+		}
+		if best == nil || better(h, bestH) {
+			best, bestH = e, h
+		}
+	}
+	if best == nil {
+		return soonest
+	}
+	return best
+}
+
+// better reports whether a ranks above b; ties keep configuration order.
+func better(a, b EndpointHealth) bool {
+	if a.ConsecutiveFailures != b.ConsecutiveFailures {
+		return a.ConsecutiveFailures < b.ConsecutiveFailures
+	}
+	if measured := a.Latency > 0; measured != (b.Latency > 0) {
+		return measured
+	}
+	return a.Latency < b.Latency
+}
+
+func (p *pool) health(now time.Time) []EndpointHealth {
+	out := make([]EndpointHealth, len(p.endpoints))
+	for i, e := range p.endpoints {
+		out[i] = e.health(now)
+	}
+	return out
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP
+// date. It returns zero when the header is missing or unusable.
+func retryAfter(h http.Header, now time.Time) time.Duration {
+	v := h.Get("Retry-After")
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(v); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// EndpointHealth returns a snapshot of every full node endpoint in
+// configuration order.
+func (c *Client) EndpointHealth() []EndpointHealth {
+	return c.fullNode.health(c.clock.Now())
+}
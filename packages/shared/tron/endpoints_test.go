@@ -0,0 +1,328 @@
+package tron
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+// endpointTimeout is the client timeout in failover tests; slowNode sleeps
+// past it.
+const endpointTimeout = 50 * time.Millisecond
+
+// newFakeNodes starts n nodes and a client routing across them in order.
+// The returned clock controls cooldowns.
+func newFakeNodes(t *testing.T, n int, keys ...string) ([]*fakeNode, *Client, *fakeClock) {
+	t.Helper()
+	var nodes []*fakeNode
+	var endpoints []config.TronEndpoint
+	for i := range n {
+		node := &fakeNode{t: t, handlers: make(map[string]func(map[string]any) (int, []byte))}
+		srv := httptest.NewServer(node)
+		t.Cleanup(srv.Close)
+		nodes = append(nodes, node)
+		e := config.TronEndpoint{URL: srv.URL + "/"}
+		if i < len(keys) {
+			e.APIKey = keys[i]
+		}
+		endpoints = append(endpoints, e)
+	}
+
+	cfg := config.TronConfig{Endpoints: endpoints, Retry: config.TronRetryConfig{MaxAttempts: 2}}
+	client := NewClient(cfg, "shared-key", WithHTTPClient(&http.Client{Timeout: endpointTimeout}))
+	clock := newFakeClock()
+	client.clock = clock
+	return nodes, client, clock
+}
+
+// fakeClock only moves when told to. After moves it forward by d at once, so
+// waits take no real time, and records d.
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits []time.Duration
+	// hold makes After never fire, to test cancellation.
+	hold bool
+}
+
+func newFakeClock() *fakeClock { return &fakeClock{now: time.Unix(1727000000, 0)} }
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waits = append(c.waits, d)
+	if c.hold {
+		return nil
+	}
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func (c *fakeClock) recordedWaits() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Duration(nil), c.waits...)
+}
+
+// rateLimit answers every call with 429 and the given Retry-After.
+func rateLimit(n *fakeNode, path, retryAfter string) {
+	n.handle(path, func(map[string]any) (int, []byte) { return http.StatusTooManyRequests, []byte("slow down") })
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.retryAfter = retryAfter
+}
+
+// slowNode answers path only after the client has given up.
+func slowNode(t *testing.T, n *fakeNode, path string) {
+	n.handle(path, func(map[string]any) (int, []byte) {
+		time.Sleep(3 * endpointTimeout)
+		return http.StatusOK, fixture(t, "getnowblock.json")
+	})
+}
+
+func TestClient_FailoverOnRateLimit(t *testing.T) {
+	nodes, client, clock := newFakeNodes(t, 2)
+	rateLimit(nodes[0], "/wallet/getnowblock", "60")
+	nodes[1].respond("/wallet/getnowblock", http.StatusOK, fixture(t, "getnowblock.json"))
+
+	_, err := client.GetNowBlock(context.Background())
+	require.NoError(t, err, "the read is retried on the second endpoint")
+	_, err = client.GetNowBlock(context.Background())
+	require.NoError(t, err)
+
+	assert.Len(t, nodes[0].recorded(), 1, "a rate-limited endpoint rests")
+	assert.Len(t, nodes[1].recorded(), 2)
+
+	health := client.EndpointHealth()
+	require.Len(t, health, 2)
+	assert.False(t, health[0].Healthy)
+	assert.True(t, health[0].RateLimited)
+	assert.Equal(t, clock.Now().Add(60*time.Second), health[0].CooldownUntil)
+	assert.True(t, health[1].Healthy)
+	assert.Equal(t, int64(2), health[1].Requests)
+	assert.Positive(t, health[1].Latency)
+
+	clock.Advance(61 * time.Second)
+	health = client.EndpointHealth()
+	assert.True(t, health[0].Healthy)
+	assert.False(t, health[0].RateLimited)
+}
+
+func TestClient_FailoverOnTimeout(t *testing.T) {
+	nodes, client, _ := newFakeNodes(t, 2)
+	slowNode(t, nodes[0], "/wallet/getnowblock")
+	nodes[1].respond("/wallet/getnowblock", http.StatusOK, fixture(t, "getnowblock.json"))
+
+	for range 3 {
+		_, err := client.GetNowBlock(context.Background())
+		require.NoError(t, err)
+	}
+
+	assert.Len(t, nodes[0].recorded(), 1, "traffic stays on the endpoint that answers")
+	assert.Len(t, nodes[1].recorded(), 3)
+	health := client.EndpointHealth()
+	assert.Equal(t, 1, health[0].ConsecutiveFailures)
+	assert.Equal(t, int64(1), health[0].Failures)
+	assert.Zero(t, health[1].ConsecutiveFailures)
+}
+
+func TestClient_TrafficShiftsPastRateLimitAndTimeout(t *testing.T) {
+	nodes, client, _ := newFakeNodes(t, 3)
+	rateLimit(nodes[0], "/wallet/getnowblock", "")
+	slowNode(t, nodes[1], "/wallet/getnowblock")
+	nodes[2].respond("/wallet/getnowblock", http.StatusOK, fixture(t, "getnowblock.json"))
+
+	_, err := client.GetNowBlock(context.Background())
+	require.Error(t, err, "a read is retried only once")
+	assert.Empty(t, nodes[2].recorded())
+
+	for range 2 {
+		_, err = client.GetNowBlock(context.Background())
+		require.NoError(t, err)
+	}
+	assert.Len(t, nodes[0].recorded(), 1)
+	assert.Len(t, nodes[1].recorded(), 1)
+	assert.Len(t, nodes[2].recorded(), 2)
+
+	health := client.EndpointHealth()
+	assert.True(t, health[0].RateLimited)
+	assert.Equal(t, 1, health[1].ConsecutiveFailures)
+	assert.True(t, health[2].Healthy)
+}
+
+func TestClient_RateLimitedError(t *testing.T) {
+	nodes, client, _ := newFakeNodes(t, 1)
+	rateLimit(nodes[0], "/wallet/getnowblock", "")
+
+	_, err := client.GetNowBlock(context.Background())
+
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Len(t, nodes[0].recorded(), 2, "a lone endpoint is retried after a backoff")
+}
+
+func TestClient_BroadcastNotRetried(t *testing.T) {
+	nodes, client, _ := newFakeNodes(t, 2)
+	nodes[0].respond("/wallet/broadcasttransaction", http.StatusBadGateway, []byte("bad gateway"))
+	nodes[1].respond("/wallet/broadcasttransaction", http.StatusOK, fixture(t, "broadcast_success.json"))
+
+	_, err := client.BroadcastTransaction(context.Background(), Transaction{TxID: "abc"})
+
+	require.Error(t, err)
+	assert.Empty(t, nodes[1].recorded())
+}
+
+func TestClient_ApplicationErrorsKeepEndpointHealthy(t *testing.T) {
+	nodes, client, _ := newFakeNodes(t, 2)
+	nodes[0].respond("/wallet/getnowblock", http.StatusOK, []byte(`{"Error":"class java.lang.NullPointerException : null"}`))
+
+	_, err := client.GetNowBlock(context.Background())
+
+	require.Error(t, err)
+	assert.Empty(t, nodes[1].recorded(), "the node answered, so the request is not retried")
+	assert.Zero(t, client.EndpointHealth()[0].ConsecutiveFailures)
+}
+
+func TestClient_UnhealthyAfterConsecutiveFailures(t *testing.T) {
+	nodes, client, clock := newFakeNodes(t, 1)
+	client.retry.maxAttempts = 1
+	nodes[0].respond("/wallet/getnowblock", http.StatusServiceUnavailable, nil)
+
+	for range MaxConsecutiveFailures {
+		_, err := client.GetNowBlock(context.Background())
+		require.Error(t, err)
+	}
+
+	health := client.EndpointHealth()[0]
+	assert.False(t, health.Healthy)
+	assert.False(t, health.RateLimited)
+	assert.Equal(t, clock.Now().Add(FailureCooldown), health.CooldownUntil)
+
+	// With nowhere else to go the endpoint is still used.
+	nodes[0].respond("/wallet/getnowblock", http.StatusOK, fixture(t, "getnowblock.json"))
+	_, err := client.GetNowBlock(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, client.EndpointHealth()[0].ConsecutiveFailures)
+}
+
+func TestClient_EndpointAPIKeys(t *testing.T) {
+	nodes, client, _ := newFakeNodes(t, 2, "", "own-key")
+	nodes[0].respond("/wallet/getnowblock", http.StatusInternalServerError, nil)
+	nodes[1].respond("/wallet/getnowblock", http.StatusOK, fixture(t, "getnowblock.json"))
+
+	_, err := client.GetNowBlock(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "shared-key", nodes[0].recorded()[0].Header.Get(apiKeyHeader))
+	assert.Equal(t, "own-key", nodes[1].recorded()[0].Header.Get(apiKeyHeader))
+}
+
+func TestPool_Pick(t *testing.T) {
+	now := time.Unix(1727000000, 0)
+	testCases := []struct {
+		name      string
+		endpoints []*endpoint
+		skip      int
+		want      int
+	}{
+		{
+			name:      "configuration order breaks ties",
+			endpoints: []*endpoint{{url: "a"}, {url: "b"}},
+			skip:      -1,
+			want:      0,
+		},
+		{
+			name:      "lower latency wins",
+			endpoints: []*endpoint{{url: "a", latency: 300 * time.Millisecond, sampled: true}, {url: "b", latency: 80 * time.Millisecond, sampled: true}},
+			skip:      -1,
+			want:      1,
+		},
+		{
+			name:      "measured beats unmeasured",
+			endpoints: []*endpoint{{url: "a"}, {url: "b", latency: time.Second, sampled: true}},
+			skip:      -1,
+			want:      1,
+		},
+		{
+			name:      "fewer failures beat latency",
+			endpoints: []*endpoint{{url: "a", failures: 1, latency: time.Millisecond, sampled: true}, {url: "b", latency: time.Second, sampled: true}},
+			skip:      -1,
+			want:      1,
+		},
+		{
+			name:      "cooling endpoints are passed over",
+			endpoints: []*endpoint{{url: "a", cooldownUntil: now.Add(time.Second)}, {url: "b", failures: 2}},
+			skip:      -1,
+			want:      1,
+		},
+		{
+			name:      "skip is passed over",
+			endpoints: []*endpoint{{url: "a"}, {url: "b", failures: 2}},
+			skip:      0,
+			want:      1,
+		},
+		{
+			name:      "all cooling picks the first to recover",
+			endpoints: []*endpoint{{url: "a", cooldownUntil: now.Add(time.Minute)}, {url: "b", cooldownUntil: now.Add(time.Second)}},
+			skip:      -1,
+			want:      1,
+		},
+		{
+			name:      "a lone endpoint is never skipped",
+			endpoints: []*endpoint{{url: "a"}},
+			skip:      0,
+			want:      0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &pool{endpoints: tc.endpoints}
+			var skip *endpoint
+			if tc.skip >= 0 {
+				skip = tc.endpoints[tc.skip]
+			}
+
+			assert.Same(t, tc.endpoints[tc.want], p.pick(now, skip))
+		})
+	}
+}
+
+func TestEndpoint_LatencyAverage(t *testing.T) {
+	e := &endpoint{}
+
+	e.answered(100 * time.Millisecond)
+	assert.Equal(t, 100*time.Millisecond, e.latency)
+	e.answered(200 * time.Millisecond)
+	assert.Equal(t, 130*time.Millisecond, e.latency)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 9, 22, 10, 0, 0, 0, time.UTC)
+	header := func(v string) http.Header { return http.Header{"Retry-After": []string{v}} }
+
+	assert.Equal(t, 5*time.Second, retryAfter(header("5"), now))
+	assert.Equal(t, 90*time.Second, retryAfter(header("Sun, 22 Sep 2024 10:01:30 GMT"), now))
+	assert.Zero(t, retryAfter(header(""), now))
+	assert.Zero(t, retryAfter(header("-1"), now))
+}
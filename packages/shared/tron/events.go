@@ -0,0 +1,88 @@
+package tron
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+// TransferEventTopic is the first topic of a Transfer(address,address,uint256)
+// event.
+var TransferEventTopic = hex.EncodeToString(keccak256([]byte("Transfer(address,address,uint256)")))
+
+// txResultFailed marks a transaction whose execution failed.
+const txResultFailed = "FAILED"
+
+// TRC20Transfer is a TRC20 Transfer event. Addresses are base58.
+type TRC20Transfer struct {
+	TxID string
+	// LogIndex is the position of the event in the transaction's logs.
+	LogIndex int
+	// Contract is the token contract that emitted the event.
+	Contract string
+	From     string
+	To       string
+	// Amount is in the token's base unit.
+	Amount *big.Int
+}
+
+// ParseTRC20Transfers decodes the Transfer events in a transaction receipt.
+// Unlike the transfer calls in Block.Transfers, it also sees tokens moved by
+// transferFrom or by another contract, and one transaction can pay several
+// addresses. Other events, and Transfer events with an indexed amount such
+// as TRC721's, are skipped. A failed transaction has no transfers.
+func ParseTRC20Transfers(info TransactionInfo) ([]TRC20Transfer, error) {
+	if info.Result == txResultFailed {
+		return nil, nil
+	}
+
+	var transfers []TRC20Transfer
+	for i, l := range info.Log {
+		if len(l.Topics) != 3 || l.Topics[0] != TransferEventTopic {
+			continue
+		}
+		t, err := decodeTransferEvent(l)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode transfer event %d of %s: %w", i, info.ID, err)
+		}
+		t.TxID = info.ID
+		t.LogIndex = i
+		transfers = append(transfers, t)
+	}
+	return transfers, nil
+}
+
+func decodeTransferEvent(l Log) (TRC20Transfer, error) {
+	contract, err := wallet.HexToAddress(l.Address)
+	if err != nil {
+		return TRC20Transfer{}, fmt.Errorf("invalid contract address: %w", err)
+	}
+	from, err := decodeAddressTopic(l.Topics[1])
+	if err != nil {
+		return TRC20Transfer{}, fmt.Errorf("invalid from topic: %w", err)
+	}
+	to, err := decodeAddressTopic(l.Topics[2])
+	if err != nil {
+		return TRC20Transfer{}, fmt.Errorf("invalid to topic: %w", err)
+	}
+	amount, err := decodeUint256(l.Data)
+	if err != nil {
+		return TRC20Transfer{}, fmt.Errorf("invalid amount: %w", err)
+	}
+	return TRC20Transfer{Contract: contract, From: from, To: to, Amount: amount}, nil
+}
+
+// decodeAddressTopic decodes an indexed address, which is left-padded to 32
+// bytes like an ABI argument.
+func decodeAddressTopic(topic string) (string, error) {
+	raw, err := hex.DecodeString(topic)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) != wordSize {
+		return "", fmt.Errorf("topic is %d bytes, want %d", len(raw), wordSize)
+	}
+	return wallet.HexToAddress(hex.EncodeToString(raw[wordSize-20:]))
+}
@@ -0,0 +1,112 @@
+package tron
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeTransactionInfo(t *testing.T, name string) TransactionInfo {
+	t.Helper()
+	var info TransactionInfo
+	require.NoError(t, json.Unmarshal(fixture(t, name), &info))
+	return info
+}
+
+func TestTransferEventTopic(t *testing.T) {
+	assert.Equal(t, "ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef", TransferEventTopic)
+}
+
+func TestParseTRC20Transfers_Single(t *testing.T) {
+	transfers, err := ParseTRC20Transfers(decodeTransactionInfo(t, "gettransactioninfobyid_usdt_transfer.json"))
+
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	assert.Equal(t, TRC20Transfer{
+		TxID:     "2222222222222222222222222222222222222222222222222222222222222222",
+		LogIndex: 0,
+		Contract: "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t",
+		From:     "TKTX96CBxr5kvhjsDHcqoiPWZageGxoTW3",
+		To:       "TMVQGm1qAQYVdetCeGRRkTWYYrLXuHK2HC",
+		Amount:   big.NewInt(100000000),
+	}, transfers[0])
+}
+
+func TestParseTRC20Transfers_MultiTransfer(t *testing.T) {
+	info := decodeTransactionInfo(t, "gettransactioninfobyid_usdt_multi_transfer.json")
+
+	transfers, err := ParseTRC20Transfers(info)
+
+	require.NoError(t, err)
+	require.Len(t, transfers, 2)
+	// The token is tagged by the emitting contract, not the called one.
+	assert.Equal(t, "41eca9bc828a3005b9a3b909f2cc5c2a54794de05f", info.ContractAddress)
+	for _, tr := range transfers {
+		assert.Equal(t, "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", tr.Contract)
+	}
+	assert.Equal(t, 0, transfers[0].LogIndex)
+	assert.Equal(t, "TMVQGm1qAQYVdetCeGRRkTWYYrLXuHK2HC", transfers[0].To)
+	assert.Equal(t, big.NewInt(10000000), transfers[0].Amount)
+	// The Approval event in between is skipped but keeps its index.
+	assert.Equal(t, 2, transfers[1].LogIndex)
+	assert.Equal(t, "TDvSsdrNM5eeXNL3czpa6AxLDHZA9nwe9K", transfers[1].To)
+	assert.Equal(t, big.NewInt(20000000), transfers[1].Amount)
+}
+
+func TestParseTRC20Transfers_NoTransferEvents(t *testing.T) {
+	for _, name := range []string{"gettransactioninfobyid_approve.json", "gettransactioninfobyid_trx.json"} {
+		transfers, err := ParseTRC20Transfers(decodeTransactionInfo(t, name))
+
+		require.NoError(t, err, name)
+		assert.Empty(t, transfers, name)
+	}
+}
+
+func TestParseTRC20Transfers_FailedTransaction(t *testing.T) {
+	info := decodeTransactionInfo(t, "gettransactioninfobyid_usdt_transfer.json")
+	info.Result = "FAILED"
+
+	transfers, err := ParseTRC20Transfers(info)
+
+	require.NoError(t, err)
+	assert.Empty(t, transfers)
+}
+
+func TestParseTRC20Transfers_SkipsIndexedAmount(t *testing.T) {
+	info := decodeTransactionInfo(t, "gettransactioninfobyid_usdt_transfer.json")
+	// A TRC721 Transfer indexes the token ID as a fourth topic.
+	info.Log[0].Topics = append(info.Log[0].Topics, info.Log[0].Data)
+	info.Log[0].Data = ""
+
+	transfers, err := ParseTRC20Transfers(info)
+
+	require.NoError(t, err)
+	assert.Empty(t, transfers)
+}
+
+func TestParseTRC20Transfers_Malformed(t *testing.T) {
+	testCases := []struct {
+		name   string
+		mutate func(*Log)
+	}{
+		{"bad contract", func(l *Log) { l.Address = "zz" }},
+		{"short topic", func(l *Log) { l.Topics[1] = l.Topics[1][2:] }},
+		{"non-hex topic", func(l *Log) { l.Topics[2] = strings.Repeat("z", 64) }},
+		{"short data", func(l *Log) { l.Data = "05" }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := decodeTransactionInfo(t, "gettransactioninfobyid_usdt_transfer.json")
+			tc.mutate(&info.Log[0])
+
+			_, err := ParseTRC20Transfers(info)
+
+			assert.ErrorContains(t, err, "failed to decode transfer event 0")
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+)
+
+// FixtureClient replays a Scenario through the read methods of *tron.Client
+// that the watcher, detector, confirmation tracker and reconciler use.
+//
+// The chain ends at the head, the last recorded block unless SetHead moves
+// it back, so a test can step through the scenario one block at a time.
+// Blocks above the head are not found and their transactions are not
+// included yet; those of the block right after the head sit in the pending
+// pool.
+type FixtureClient struct {
+	scenario *Scenario
+
+	mu   sync.Mutex
+	head int64
+	// included maps a recorded transaction to the height of its block.
+	included map[string]int64
+}
+
+// NewFixtureClient replays s with its last block as the head.
+func NewFixtureClient(s *Scenario) *FixtureClient {
+	c := &FixtureClient{
+		scenario: s,
+		head:     s.Last(),
+		included: make(map[string]int64),
+	}
+	for _, b := range s.Blocks {
+		for _, tx := range b.Transactions {
+			c.included[tx.TxID] = b.Number()
+		}
+	}
+	return c
+}
+
+// SetHead moves the head to num, which must be within the scenario.
+func (c *FixtureClient) SetHead(num int64) {
+	if num < c.scenario.First() || num > c.scenario.Last() {
+		panic(fmt.Sprintf("fixtures: head %d is outside scenario %s (%d-%d)", num, c.scenario.Name, c.scenario.First(), c.scenario.Last()))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.head = num
+}
+
+// GetNowBlock returns the head block.
+func (c *FixtureClient) GetNowBlock(context.Context) (*tron.Block, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b := c.block(c.head).Block
+	return &b, nil
+}
+
+// GetChainHeight returns the head.
+func (c *FixtureClient) GetChainHeight(context.Context) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.head, nil
+}
+
+// GetBlockByNum returns the recorded block num, or tron.ErrBlockNotFound
+// for one above the head or outside the scenario.
+func (c *FixtureClient) GetBlockByNum(_ context.Context, num int64) (*tron.Block, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if num > c.head || !c.recorded(num) {
+		return nil, fmt.Errorf("failed to get block %d: %w", num, tron.ErrBlockNotFound)
+	}
+	b := c.block(num).Block
+	return &b, nil
+}
+
+// GetTransactionInfoByBlockNum returns the recorded receipts of block num,
+// empty like the node's answer for a block above the head.
+func (c *FixtureClient) GetTransactionInfoByBlockNum(_ context.Context, num int64) ([]tron.TransactionInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if num > c.head || !c.recorded(num) {
+		return nil, nil
+	}
+	return append([]tron.TransactionInfo(nil), c.block(num).TransactionInfos...), nil
+}
+
+// GetTransactionInfoByID returns the receipt of txID, or
+// tron.ErrTransactionNotFound if its block is above the head.
+func (c *FixtureClient) GetTransactionInfoByID(_ context.Context, txID string) (*tron.TransactionInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	num, ok := c.included[txID]
+	if ok && num <= c.head {
+		for _, info := range c.block(num).TransactionInfos {
+			if info.ID == txID {
+				return &info, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("failed to get transaction info %s: %w", txID, tron.ErrTransactionNotFound)
+}
+
+// GetTransactionByID returns txID, or tron.ErrTransactionNotFound if its
+// block is above the head.
+func (c *FixtureClient) GetTransactionByID(_ context.Context, txID string) (*tron.Transaction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	num, ok := c.included[txID]
+	if !ok || num > c.head {
+		return nil, fmt.Errorf("failed to get transaction %s: %w", txID, tron.ErrTransactionNotFound)
+	}
+	return c.transaction(num, txID), nil
+}
+
+// GetPendingTransactionIDs lists the transactions of the block after the
+// head.
+func (c *FixtureClient) GetPendingTransactionIDs(context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.recorded(c.head + 1) {
+		return nil, nil
+	}
+	var ids []string
+	for _, tx := range c.block(c.head + 1).Transactions {
+		ids = append(ids, tx.TxID)
+	}
+	return ids, nil
+}
+
+// GetPendingTransaction returns txID while it is in the pending pool.
+func (c *FixtureClient) GetPendingTransaction(_ context.Context, txID string) (*tron.Transaction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	num, ok := c.included[txID]
+	if !ok || num != c.head+1 {
+		return nil, fmt.Errorf("failed to get pending transaction %s: %w", txID, tron.ErrTransactionNotFound)
+	}
+	return c.transaction(num, txID), nil
+}
+
+func (c *FixtureClient) recorded(num int64) bool {
+	return num >= c.scenario.First() && num <= c.scenario.Last()
+}
+
+func (c *FixtureClient) block(num int64) Block {
+	return c.scenario.Blocks[num-c.scenario.First()]
+}
+
+func (c *FixtureClient) transaction(num int64, txID string) *tron.Transaction {
+	for _, tx := range c.block(num).Transactions {
+		if tx.TxID == txID {
+			return &tx
+		}
+	}
+	return nil
+}
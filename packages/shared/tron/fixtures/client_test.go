@@ -0,0 +1,71 @@
+package fixtures
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+)
+
+func TestFixtureClient_Blocks(t *testing.T) {
+	ctx := context.Background()
+	s := loadScenario(t, ScenarioTRXPayment)
+	c := NewFixtureClient(s)
+
+	height, err := c.GetChainHeight(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1002), height)
+	now, err := c.GetNowBlock(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, s.Blocks[2].BlockID, now.BlockID)
+
+	b, err := c.GetBlockByNum(ctx, 1001)
+	require.NoError(t, err)
+	assert.Equal(t, s.Blocks[0].BlockID, b.ParentHash())
+	require.Len(t, b.Transfers(), 1)
+	assert.Equal(t, trxWallet, b.Transfers()[0].To)
+
+	for _, num := range []int64{999, 1003} {
+		_, err = c.GetBlockByNum(ctx, num)
+		assert.ErrorIs(t, err, tron.ErrBlockNotFound)
+	}
+}
+
+func TestFixtureClient_SetHead(t *testing.T) {
+	ctx := context.Background()
+	c := NewFixtureClient(loadScenario(t, ScenarioUSDTPayment))
+	c.SetHead(2000)
+	txID := "2222222222222222222222222222222222222222222222222222222222222222"
+
+	_, err := c.GetBlockByNum(ctx, 2001)
+	assert.ErrorIs(t, err, tron.ErrBlockNotFound)
+	infos, err := c.GetTransactionInfoByBlockNum(ctx, 2001)
+	require.NoError(t, err)
+	assert.Empty(t, infos)
+	_, err = c.GetTransactionInfoByID(ctx, txID)
+	assert.ErrorIs(t, err, tron.ErrTransactionNotFound)
+	_, err = c.GetTransactionByID(ctx, txID)
+	assert.ErrorIs(t, err, tron.ErrTransactionNotFound)
+
+	// The next block's transactions wait in the pending pool.
+	ids, err := c.GetPendingTransactionIDs(ctx)
+	require.NoError(t, err)
+	assert.Len(t, ids, 2)
+	pending, err := c.GetPendingTransaction(ctx, txID)
+	require.NoError(t, err)
+	assert.Equal(t, txID, pending.TxID)
+
+	c.SetHead(2001)
+	info, err := c.GetTransactionInfoByID(ctx, txID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2001), info.BlockNumber)
+	tx, err := c.GetTransactionByID(ctx, txID)
+	require.NoError(t, err)
+	assert.True(t, tx.Succeeded())
+	_, err = c.GetPendingTransaction(ctx, txID)
+	assert.ErrorIs(t, err, tron.ErrTransactionNotFound)
+
+	assert.Panics(t, func() { c.SetHead(2003) })
+}
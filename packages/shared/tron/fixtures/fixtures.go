@@ -0,0 +1,132 @@
+// Package fixtures records ranges of TRON blocks into scenario files and
+// replays them through FixtureClient, so chain-related tests run against
+// what a node actually returns rather than hand-written JSON.
+//
+// Scenarios are recorded with go run ./tools/record-fixtures and checked in
+// under scenarios/.
+package fixtures
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+)
+
+// Recorded scenarios.
+const (
+	// ScenarioTRXPayment pays 2.5 TRX to one address.
+	ScenarioTRXPayment = "trx_payment"
+	// ScenarioUSDTPayment pays 100 USDT to one address through a transfer
+	// call, next to a reverted transfer call to the same address.
+	ScenarioUSDTPayment = "usdt_payment"
+	// ScenarioMultiTransfer pays two addresses in USDT from a single
+	// contract call, and one of them in TRX in the same block.
+	ScenarioMultiTransfer = "multi_transfer"
+)
+
+//go:embed scenarios/*.json
+var scenarioFiles embed.FS
+
+// Scenario is a recorded range of consecutive blocks, scrubbed down to the
+// transactions that move value from or to one of Addresses.
+type Scenario struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Node is the full node the blocks were recorded from.
+	Node string `json:"node"`
+	// Addresses are the base58 addresses the scenario was recorded for,
+	// sorted.
+	Addresses []string `json:"addresses"`
+	// Blocks ascend by height without gaps.
+	Blocks []Block `json:"blocks"`
+}
+
+// Block is a recorded block with the receipts of the transactions kept in
+// it.
+type Block struct {
+	tron.Block
+	TransactionInfos []tron.TransactionInfo `json:"transaction_infos,omitempty"`
+}
+
+// First returns the height of the first recorded block.
+func (s *Scenario) First() int64 { return s.Blocks[0].Number() }
+
+// Last returns the height of the last recorded block.
+func (s *Scenario) Last() int64 { return s.Blocks[len(s.Blocks)-1].Number() }
+
+// Names lists the scenarios checked in under scenarios/.
+func Names() []string {
+	entries, err := scenarioFiles.ReadDir("scenarios")
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	return names
+}
+
+// Load decodes the checked-in scenario name.
+func Load(name string) (*Scenario, error) {
+	data, err := scenarioFiles.ReadFile(path.Join("scenarios", name+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario %s: %w", name, err)
+	}
+	return Decode(bytes.NewReader(data))
+}
+
+// Decode reads a scenario written by Encode.
+func Decode(r io.Reader) (*Scenario, error) {
+	var s Scenario
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("failed to decode scenario: %w", err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", s.Name, err)
+	}
+	return &s, nil
+}
+
+// Encode writes s as indented JSON. Recording the same blocks twice writes
+// the same bytes, so a re-recorded fixture only shows up in a diff if the
+// chain data changed.
+func (s *Scenario) Encode(w io.Writer) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode scenario: %w", err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func (s *Scenario) validate() error {
+	if s.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(s.Blocks) == 0 {
+		return errors.New("no blocks")
+	}
+	if !slices.IsSorted(s.Addresses) {
+		return errors.New("addresses are not sorted")
+	}
+	for i, b := range s.Blocks {
+		if want := s.First() + int64(i); b.Number() != want {
+			return fmt.Errorf("block %d follows block %d", b.Number(), want-1)
+		}
+		if b.BlockID == "" {
+			return fmt.Errorf("block %d has no ID", b.Number())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package fixtures
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_Scenarios(t *testing.T) {
+	assert.ElementsMatch(t, []string{ScenarioTRXPayment, ScenarioUSDTPayment, ScenarioMultiTransfer}, Names())
+
+	for _, name := range Names() {
+		t.Run(name, func(t *testing.T) {
+			s, err := Load(name)
+			require.NoError(t, err)
+			assert.Equal(t, name, s.Name)
+			assert.NotEmpty(t, s.Description)
+			assert.Len(t, s.Blocks, 3)
+
+			// Re-encoding writes the file back byte for byte.
+			var buf bytes.Buffer
+			require.NoError(t, s.Encode(&buf))
+			file, err := os.ReadFile(filepath.Join("scenarios", name+".json"))
+			require.NoError(t, err)
+			assert.Equal(t, string(file), buf.String())
+		})
+	}
+}
+
+func TestLoad_Unknown(t *testing.T) {
+	_, err := Load("missing")
+	assert.ErrorContains(t, err, "failed to read scenario missing")
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	block := func(num string) string {
+		return `{"blockID":"` + num + `","block_header":{"raw_data":{"number":` + num + `}}}`
+	}
+	testCases := []struct {
+		name string
+		json string
+		err  string
+	}{
+		{"no name", `{"blocks":[` + block("1") + `]}`, "name is required"},
+		{"no blocks", `{"name":"x"}`, "no blocks"},
+		{"gap", `{"name":"x","blocks":[` + block("1") + `,` + block("3") + `]}`, "block 3 follows block 1"},
+		{"unsorted addresses", `{"name":"x","addresses":["b","a"],"blocks":[` + block("1") + `]}`, "addresses are not sorted"},
+		{"unknown field", `{"name":"x","extra":1}`, "unknown field"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Decode(strings.NewReader(tc.json))
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+// MaxBlocks bounds the range a scenario is recorded from, keeping fixture
+// files small enough to review.
+const MaxBlocks = 100
+
+// Source is the subset of *tron.Client a scenario is recorded from.
+type Source interface {
+	GetBlockByNum(ctx context.Context, num int64) (*tron.Block, error)
+	GetTransactionInfoByBlockNum(ctx context.Context, num int64) ([]tron.TransactionInfo, error)
+}
+
+// Record fetches blocks from to to of src and scrubs them with Scrub. The
+// returned scenario has no name, description or node; the caller sets them.
+func Record(ctx context.Context, src Source, from, to int64, addresses []string) (*Scenario, error) {
+	if from <= 0 || to < from {
+		return nil, fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	if to-from >= MaxBlocks {
+		return nil, fmt.Errorf("block range %d-%d is longer than %d blocks", from, to, MaxBlocks)
+	}
+	if len(addresses) == 0 {
+		return nil, errors.New("at least one address is required")
+	}
+	for _, a := range addresses {
+		if err := wallet.ValidateAddress(a); err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", a, err)
+		}
+	}
+	watched := slices.Sorted(slices.Values(addresses))
+	watched = slices.Compact(watched)
+
+	s := &Scenario{Addresses: watched}
+	for num := from; num <= to; num++ {
+		block, err := src.GetBlockByNum(ctx, num)
+		if err != nil {
+			return nil, err
+		}
+		infos, err := src.GetTransactionInfoByBlockNum(ctx, num)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transaction infos of block %d: %w", num, err)
+		}
+		s.Blocks = append(s.Blocks, Scrub(*block, infos, watched))
+	}
+	return s, nil
+}
+
+// Scrub keeps the transactions of block that move TRX or TRC20 tokens from
+// or to one of addresses, whether they succeeded or not, and the receipts of
+// those transactions. A transfer is found either in the transaction itself
+// or in the Transfer events of its receipt, so tokens moved by another
+// contract are kept too. Signatures and raw_data_hex are dropped: nothing
+// replays them and they are most of a transaction's bytes.
+func Scrub(block tron.Block, infos []tron.TransactionInfo, addresses []string) Block {
+	watched := make(map[string]bool, len(addresses))
+	for _, a := range addresses {
+		watched[a] = true
+	}
+	receipts := make(map[string]tron.TransactionInfo, len(infos))
+	for _, info := range infos {
+		receipts[info.ID] = info
+	}
+
+	kept := make(map[string]bool)
+	txs := block.Transactions
+	block.Transactions = nil
+	for _, tx := range txs {
+		if !touches(tx, receipts[tx.TxID], watched) {
+			continue
+		}
+		tx.Signature, tx.RawDataHex = nil, ""
+		block.Transactions = append(block.Transactions, tx)
+		kept[tx.TxID] = true
+	}
+
+	out := Block{Block: block}
+	for _, info := range infos {
+		if kept[info.ID] {
+			out.TransactionInfos = append(out.TransactionInfos, info)
+		}
+	}
+	return out
+}
+
+// touches reports whether tx or its receipt moves value from or to a watched
+// address.
+func touches(tx tron.Transaction, info tron.TransactionInfo, watched map[string]bool) bool {
+	for _, t := range tx.Transfers() {
+		if watched[t.From] || watched[t.To] {
+			return true
+		}
+	}
+	// A receipt whose events cannot be decoded keeps nothing; Record is run
+	// by hand, so the missing transaction is noticed in the fixture.
+	events, _ := tron.ParseTRC20Transfers(info)
+	for _, e := range events {
+		if watched[e.From] || watched[e.To] {
+			return true
+		}
+	}
+	return false
+}
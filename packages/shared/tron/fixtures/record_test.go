@@ -0,0 +1,145 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+)
+
+const (
+	trxWallet  = "TDvSsdrNM5eeXNL3czpa6AxLDHZA9nwe9K"
+	usdtWallet = "TMVQGm1qAQYVdetCeGRRkTWYYrLXuHK2HC"
+)
+
+// scenarioSource serves the blocks of a loaded scenario as a node would.
+type scenarioSource struct {
+	s        *Scenario
+	requests []int64
+	err      error
+}
+
+func (src *scenarioSource) GetBlockByNum(_ context.Context, num int64) (*tron.Block, error) {
+	src.requests = append(src.requests, num)
+	if src.err != nil {
+		return nil, src.err
+	}
+	b := src.s.Blocks[num-src.s.First()].Block
+	return &b, nil
+}
+
+func (src *scenarioSource) GetTransactionInfoByBlockNum(_ context.Context, num int64) ([]tron.TransactionInfo, error) {
+	return src.s.Blocks[num-src.s.First()].TransactionInfos, nil
+}
+
+func loadScenario(t *testing.T, name string) *Scenario {
+	t.Helper()
+	s, err := Load(name)
+	require.NoError(t, err)
+	return s
+}
+
+func txIDs(b Block) []string {
+	var ids []string
+	for _, tx := range b.Transactions {
+		ids = append(ids, tx.TxID)
+	}
+	return ids
+}
+
+func infoIDs(b Block) []string {
+	var ids []string
+	for _, info := range b.TransactionInfos {
+		ids = append(ids, info.ID)
+	}
+	return ids
+}
+
+func TestScrub(t *testing.T) {
+	block := loadScenario(t, ScenarioMultiTransfer).Blocks[1]
+	call, trx := block.Transactions[0].TxID, block.Transactions[1].TxID
+	block.Transactions[0].Signature = []string{"00"}
+	block.Transactions[0].RawDataHex = "0a02"
+
+	testCases := []struct {
+		name      string
+		addresses []string
+		want      []string
+	}{
+		{"transfer call or receipt", []string{trxWallet}, []string{call, trx}},
+		{"receipt only", []string{usdtWallet}, []string{call}},
+		{"sender", []string{"TKTX96CBxr5kvhjsDHcqoiPWZageGxoTW3"}, []string{call, trx}},
+		{"unrelated", []string{"TLyqzVGLV1srkB7dToTAEqgDSfPtXRJZYH"}, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Scrub(block.Block, block.TransactionInfos, tc.addresses)
+
+			assert.Equal(t, tc.want, txIDs(got))
+			assert.Equal(t, tc.want, infoIDs(got))
+			assert.Equal(t, block.BlockID, got.BlockID)
+			for _, tx := range got.Transactions {
+				assert.Nil(t, tx.Signature)
+				assert.Empty(t, tx.RawDataHex)
+			}
+		})
+	}
+}
+
+func TestScrub_KeepsFailedTransactions(t *testing.T) {
+	block := loadScenario(t, ScenarioUSDTPayment).Blocks[1]
+
+	got := Scrub(block.Block, block.TransactionInfos, []string{usdtWallet})
+
+	require.Len(t, got.Transactions, 2)
+	assert.False(t, got.Transactions[1].Succeeded())
+	require.Len(t, got.TransactionInfos, 2)
+	assert.True(t, got.TransactionInfos[1].Failed())
+}
+
+func TestRecord(t *testing.T) {
+	s := loadScenario(t, ScenarioTRXPayment)
+	src := &scenarioSource{s: s}
+
+	got, err := Record(context.Background(), src, s.First(), s.Last(), []string{trxWallet, trxWallet})
+
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1000, 1001, 1002}, src.requests)
+	assert.Equal(t, []string{trxWallet}, got.Addresses)
+	assert.Equal(t, s.Blocks, got.Blocks)
+}
+
+func TestRecord_Invalid(t *testing.T) {
+	src := &scenarioSource{s: loadScenario(t, ScenarioTRXPayment)}
+	testCases := []struct {
+		name      string
+		from, to  int64
+		addresses []string
+		err       string
+	}{
+		{"reversed range", 1002, 1000, []string{trxWallet}, "invalid block range 1002-1000"},
+		{"too long", 1, MaxBlocks + 1, []string{trxWallet}, fmt.Sprintf("longer than %d blocks", MaxBlocks)},
+		{"no addresses", 1000, 1000, nil, "at least one address is required"},
+		{"bad address", 1000, 1000, []string{"TNotAnAddress"}, `invalid address "TNotAnAddress"`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Record(context.Background(), src, tc.from, tc.to, tc.addresses)
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+	assert.Empty(t, src.requests)
+}
+
+func TestRecord_SourceError(t *testing.T) {
+	boom := errors.New("boom")
+	src := &scenarioSource{s: loadScenario(t, ScenarioTRXPayment), err: boom}
+
+	_, err := Record(context.Background(), src, 1000, 1001, []string{trxWallet})
+
+	assert.ErrorIs(t, err, boom)
+}
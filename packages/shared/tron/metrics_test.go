@@ -0,0 +1,66 @@
+package tron
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+type request struct {
+	endpoint string
+	status   int
+}
+
+type recordingMetrics struct {
+	mu       sync.Mutex
+	requests []request
+}
+
+func (m *recordingMetrics) TronRequest(endpoint string, status int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, request{endpoint, status})
+}
+
+func TestClient_Metrics(t *testing.T) {
+	block := fixture(t, "getblockbynum_empty.json")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/wallet/getnowblock" {
+			_, _ = w.Write(block)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	m := &recordingMetrics{}
+	client := NewClient(config.TronConfig{FullNodeURL: srv.URL}, "", WithHTTPClient(srv.Client()), WithMetrics(m))
+
+	_, err := client.GetNowBlock(context.Background())
+	require.NoError(t, err)
+	_, err = client.GetBlockByNum(context.Background(), 1000)
+	require.Error(t, err)
+
+	assert.Equal(t, []request{
+		{"/wallet/getnowblock", http.StatusOK},
+		{"/wallet/getblockbynum", http.StatusInternalServerError},
+	}, m.requests)
+}
+
+func TestClient_MetricsWithoutResponse(t *testing.T) {
+	m := &recordingMetrics{}
+	// Nothing listens on port 1.
+	cfg := config.TronConfig{FullNodeURL: "http://127.0.0.1:1", RequestTimeout: config.Duration(time.Second)}
+	client := NewClient(cfg, "", WithMetrics(m))
+
+	_, err := client.GetNowBlock(context.Background())
+
+	require.Error(t, err)
+	assert.Equal(t, []request{{"/wallet/getnowblock", 0}}, m.requests)
+}
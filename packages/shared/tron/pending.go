@@ -0,0 +1,35 @@
+package tron
+
+import (
+	"context"
+	"fmt"
+)
+
+type pendingListResponse struct {
+	TxID []string `json:"txId"`
+}
+
+// GetPendingTransactionIDs lists the transactions in the node's pending pool,
+// i.e. broadcast but not yet in a block. Each node has its own pool, so two
+// endpoints may disagree.
+func (c *Client) GetPendingTransactionIDs(ctx context.Context) ([]string, error) {
+	var resp pendingListResponse
+	if err := c.post(ctx, c.fullNode, "/wallet/gettransactionlistfrompending", struct{}{}, &resp); err != nil {
+		return nil, fmt.Errorf("failed to list pending transactions: %w", err)
+	}
+	return resp.TxID, nil
+}
+
+// GetPendingTransaction fetches txID from the pending pool, or
+// ErrTransactionNotFound once it has left it, e.g. because it was included in
+// a block or dropped.
+func (c *Client) GetPendingTransaction(ctx context.Context, txID string) (*Transaction, error) {
+	var tx Transaction
+	if err := c.post(ctx, c.fullNode, "/wallet/gettransactionfrompending", getTransactionByIDRequest{Value: txID, Visible: true}, &tx); err != nil {
+		return nil, fmt.Errorf("failed to get pending transaction %s: %w", txID, err)
+	}
+	if tx.TxID == "" {
+		return nil, fmt.Errorf("failed to get pending transaction %s: %w", txID, ErrTransactionNotFound)
+	}
+	return &tx, nil
+}
@@ -0,0 +1,64 @@
+package tron
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPendingTransactionIDs(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/gettransactionlistfrompending", http.StatusOK, fixture(t, "gettransactionlistfrompending.json"))
+
+	ids, err := client.GetPendingTransactionIDs(context.Background())
+
+	require.NoError(t, err)
+	assert.Len(t, ids, 2)
+	assert.Equal(t, "1111111111111111111111111111111111111111111111111111111111111111", ids[0])
+}
+
+func TestGetPendingTransactionIDs_Empty(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/gettransactionlistfrompending", http.StatusOK, []byte(`{}`))
+
+	ids, err := client.GetPendingTransactionIDs(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestGetPendingTransaction(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/gettransactionfrompending", http.StatusOK, fixture(t, "gettransactionfrompending_trx.json"))
+	txID := "1111111111111111111111111111111111111111111111111111111111111111"
+
+	tx, err := client.GetPendingTransaction(context.Background(), txID)
+
+	require.NoError(t, err)
+	assert.Empty(t, tx.Ret, "a pending transaction has no result yet")
+	transfers := tx.Transfers()
+	require.Len(t, transfers, 1)
+	assert.Equal(t, Transfer{
+		TxID:   txID,
+		From:   "TKTX96CBxr5kvhjsDHcqoiPWZageGxoTW3",
+		To:     "TDvSsdrNM5eeXNL3czpa6AxLDHZA9nwe9K",
+		Amount: big.NewInt(2500000),
+	}, transfers[0])
+
+	reqs := node.recorded()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, txID, reqs[0].Body["value"])
+}
+
+func TestGetPendingTransaction_Gone(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/gettransactionfrompending", http.StatusOK, []byte(`{}`))
+
+	_, err := client.GetPendingTransaction(context.Background(), "dead")
+
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+}
@@ -0,0 +1,124 @@
+package tron
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// clock is time as the client sees it, swapped out in tests.
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// sleep waits d or until ctx is done, whichever comes first.
+func sleep(ctx context.Context, clk clock, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-clk.After(d):
+		return nil
+	}
+}
+
+// limiter is a token bucket holding up to burst tokens, refilled at rate
+// tokens per second. A nil limiter lets everything through.
+type limiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newLimiter returns nil when rps is not positive.
+func newLimiter(rps float64, burst int) *limiter {
+	if rps <= 0 {
+		return nil
+	}
+	b := float64(max(burst, 1))
+	return &limiter{rate: rps, burst: b, tokens: b}
+}
+
+// reserve takes a token and returns how long to wait before using it. The
+// bucket may go negative, which queues later callers behind this one.
+func (l *limiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// refund returns a token reserved by a caller that gave up waiting.
+func (l *limiter) refund() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = min(l.burst, l.tokens+1)
+}
+
+// wait blocks until a token is available or ctx is done.
+func (l *limiter) wait(ctx context.Context, clk clock) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	if err := sleep(ctx, clk, l.reserve(clk.Now())); err != nil {
+		l.refund()
+		return err
+	}
+	return nil
+}
+
+// retryPolicy spaces out the retries of a read.
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+// delay returns the wait before retry number n (1 for the first retry), or
+// false if the node asked for a longer pause than maxDelay.
+func (p retryPolicy) delay(n int, retryAfter time.Duration) (time.Duration, bool) {
+	if retryAfter > 0 {
+		return retryAfter, retryAfter <= p.maxDelay
+	}
+	d := p.baseDelay
+	for i := 1; i < n && d < p.maxDelay; i++ {
+		d *= 2
+	}
+	return min(d, p.maxDelay), true
+}
+
+// retryableError marks a failure that was the endpoint's fault, e.g. a
+// timeout, 429 or 5xx, so the request may be tried again.
+type retryableError struct {
+	err error
+	// retryAfter is the pause the node asked for, zero if it named none.
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+func isRetryable(err error) (*retryableError, bool) {
+	var rerr *retryableError
+	ok := errors.As(err, &rerr)
+	return rerr, ok
+}
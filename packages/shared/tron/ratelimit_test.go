@@ -0,0 +1,225 @@
+package tron
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+// newPacedNode starts one node behind a client using the rateLimit and
+// retry settings of cfg and a fake clock.
+func newPacedNode(t *testing.T, cfg config.TronConfig) (*fakeNode, *Client, *fakeClock) {
+	t.Helper()
+	node := &fakeNode{t: t, handlers: make(map[string]func(map[string]any) (int, []byte))}
+	srv := httptest.NewServer(node)
+	t.Cleanup(srv.Close)
+
+	cfg.FullNodeURL = srv.URL
+	client := NewClient(cfg, "", WithHTTPClient(srv.Client()))
+	clock := newFakeClock()
+	client.clock = clock
+	return node, client, clock
+}
+
+func retryConfig(attempts int, base, maxDelay time.Duration) config.TronConfig {
+	return config.TronConfig{Retry: config.TronRetryConfig{
+		MaxAttempts: attempts,
+		BaseDelay:   config.Duration(base),
+		MaxDelay:    config.Duration(maxDelay),
+	}}
+}
+
+// failFirst answers path with status n times and with the now block after.
+func failFirst(t *testing.T, node *fakeNode, path string, n int32, status int) {
+	var calls atomic.Int32
+	node.handle(path, func(map[string]any) (int, []byte) {
+		if calls.Add(1) <= n {
+			return status, []byte("try again")
+		}
+		return http.StatusOK, fixture(t, "getnowblock.json")
+	})
+}
+
+func TestClient_RateLimitPacesRequests(t *testing.T) {
+	node, client, clock := newPacedNode(t, config.TronConfig{
+		RateLimit: config.TronRateLimitConfig{RequestsPerSecond: 2, Burst: 1},
+	})
+	node.respond("/wallet/getnowblock", http.StatusOK, fixture(t, "getnowblock.json"))
+
+	for range 4 {
+		_, err := client.GetNowBlock(context.Background())
+		require.NoError(t, err)
+	}
+
+	assert.Len(t, node.recorded(), 4)
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond},
+		clock.recordedWaits(), "the first request spends the burst, the rest wait 1/rps")
+}
+
+func TestClient_RateLimitBurst(t *testing.T) {
+	node, client, clock := newPacedNode(t, config.TronConfig{
+		RateLimit: config.TronRateLimitConfig{RequestsPerSecond: 1, Burst: 3},
+	})
+	node.respond("/wallet/getnowblock", http.StatusOK, fixture(t, "getnowblock.json"))
+
+	for range 4 {
+		_, err := client.GetNowBlock(context.Background())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []time.Duration{time.Second}, clock.recordedWaits())
+
+	// An idle spell refills the bucket, but never beyond the burst.
+	clock.Advance(time.Minute)
+	for range 4 {
+		_, err := client.GetNowBlock(context.Background())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []time.Duration{time.Second, time.Second}, clock.recordedWaits())
+}
+
+func TestClient_RetryBacksOffExponentially(t *testing.T) {
+	node, client, clock := newPacedNode(t, retryConfig(4, 100*time.Millisecond, time.Second))
+	failFirst(t, node, "/wallet/getnowblock", 3, http.StatusServiceUnavailable)
+
+	_, err := client.GetNowBlock(context.Background())
+
+	require.NoError(t, err)
+	assert.Len(t, node.recorded(), 4)
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond}, clock.recordedWaits())
+}
+
+func TestClient_RetryHonorsRetryAfter(t *testing.T) {
+	node, client, clock := newPacedNode(t, retryConfig(3, 100*time.Millisecond, 5*time.Second))
+	failFirst(t, node, "/wallet/getnowblock", 1, http.StatusTooManyRequests)
+	node.retryAfter = "2"
+
+	_, err := client.GetNowBlock(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{2 * time.Second}, clock.recordedWaits())
+}
+
+func TestClient_RetryAfterBeyondMaxDelayGivesUp(t *testing.T) {
+	node, client, clock := newPacedNode(t, retryConfig(3, 100*time.Millisecond, 5*time.Second))
+	failFirst(t, node, "/wallet/getnowblock", 1, http.StatusTooManyRequests)
+	node.retryAfter = "60"
+
+	_, err := client.GetNowBlock(context.Background())
+
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Len(t, node.recorded(), 1)
+	assert.Empty(t, clock.recordedWaits())
+}
+
+func TestClient_RetryCappedAtMaxAttempts(t *testing.T) {
+	node, client, clock := newPacedNode(t, retryConfig(3, 100*time.Millisecond, 150*time.Millisecond))
+	node.respond("/wallet/getnowblock", http.StatusBadGateway, []byte("bad gateway"))
+
+	_, err := client.GetNowBlock(context.Background())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "returned 502")
+	assert.Len(t, node.recorded(), 3)
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 150 * time.Millisecond}, clock.recordedWaits())
+}
+
+func TestClient_ClientErrorsNotRetried(t *testing.T) {
+	node, client, _ := newPacedNode(t, retryConfig(3, 100*time.Millisecond, time.Second))
+	node.respond("/wallet/getnowblock", http.StatusBadRequest, []byte("bad request"))
+
+	_, err := client.GetNowBlock(context.Background())
+
+	require.Error(t, err)
+	assert.Len(t, node.recorded(), 1)
+}
+
+func TestClient_BroadcastNeverAutoRetried(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		node, client, clock := newPacedNode(t, retryConfig(5, 100*time.Millisecond, time.Second))
+		failFirst(t, node, "/wallet/broadcasttransaction", 1, status)
+
+		_, err := client.BroadcastTransaction(context.Background(), Transaction{TxID: "abc"})
+
+		require.Error(t, err, "status %d", status)
+		assert.Len(t, node.recorded(), 1, "status %d", status)
+		assert.Empty(t, clock.recordedWaits(), "status %d", status)
+	}
+}
+
+func TestClient_CancelAbortsRateLimitWait(t *testing.T) {
+	node, client, clock := newPacedNode(t, config.TronConfig{
+		RateLimit: config.TronRateLimitConfig{RequestsPerSecond: 0.1, Burst: 1},
+	})
+	node.respond("/wallet/getnowblock", http.StatusOK, fixture(t, "getnowblock.json"))
+	_, err := client.GetNowBlock(context.Background())
+	require.NoError(t, err)
+	clock.hold = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = client.GetNowBlock(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Len(t, node.recorded(), 1)
+
+	// The abandoned token went back, so the next caller waits no longer.
+	lim := client.fullNode.endpoints[0].limiter
+	assert.Equal(t, 10*time.Second, lim.reserve(clock.Now()))
+}
+
+func TestClient_CancelAbortsBackoff(t *testing.T) {
+	node, client, clock := newPacedNode(t, retryConfig(3, time.Minute, time.Hour))
+	node.respond("/wallet/getnowblock", http.StatusServiceUnavailable, nil)
+	clock.hold = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := client.GetNowBlock(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Len(t, node.recorded(), 1)
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := retryPolicy{maxAttempts: 5, baseDelay: 250 * time.Millisecond, maxDelay: 2 * time.Second}
+
+	testCases := []struct {
+		retry      int
+		retryAfter time.Duration
+		want       time.Duration
+		wantOK     bool
+	}{
+		{1, 0, 250 * time.Millisecond, true},
+		{2, 0, 500 * time.Millisecond, true},
+		{3, 0, time.Second, true},
+		{4, 0, 2 * time.Second, true},
+		{10, 0, 2 * time.Second, true},
+		{1, 1500 * time.Millisecond, 1500 * time.Millisecond, true},
+		{1, 2 * time.Second, 2 * time.Second, true},
+		{1, 3 * time.Second, 3 * time.Second, false},
+	}
+
+	for _, tc := range testCases {
+		got, ok := p.delay(tc.retry, tc.retryAfter)
+		assert.Equal(t, tc.want, got, "retry %d after %s", tc.retry, tc.retryAfter)
+		assert.Equal(t, tc.wantOK, ok, "retry %d after %s", tc.retry, tc.retryAfter)
+	}
+}
+
+func TestLimiter_Nil(t *testing.T) {
+	assert.Nil(t, newLimiter(0, 10))
+	var l *limiter
+
+	assert.NoError(t, l.wait(context.Background(), newFakeClock()))
+}
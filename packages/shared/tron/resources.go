@@ -0,0 +1,219 @@
+package tron
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+const (
+	// BandwidthPriceSun is the TRX burned per byte of bandwidth the sender
+	// does not have.
+	BandwidthPriceSun = 1000
+	// maxResultSize is the space the node reserves for a transaction's
+	// result when charging bandwidth.
+	maxResultSize = 64
+	// signatureFieldSize is one 65-byte signature with its protobuf tag and
+	// length.
+	signatureFieldSize = 67
+	// feeLimitHeadroomPct is added to the estimated energy cost when
+	// choosing a fee limit, since energy use varies with contract state.
+	feeLimitHeadroomPct = 20
+)
+
+// ResourceEstimate is what a transaction is expected to consume.
+type ResourceEstimate struct {
+	Energy    int64
+	Bandwidth int64
+}
+
+// FeeLimit returns the fee limit for a transaction with this estimate when
+// energy costs energyPrice sun: the cost of all its energy with 20%
+// headroom. It assumes none of the sender's energy is used; the node only
+// burns TRX for energy the sender lacks, so a generous limit costs nothing
+// extra. Energy use of a transfer depends on the recipient's balance, which
+// can change between estimate and broadcast, hence the headroom. Pass the
+// result to BuildTRC20Transfer, whose fee limit cap still applies.
+func (e ResourceEstimate) FeeLimit(energyPrice int64) int64 {
+	return e.Energy * energyPrice * (100 + feeLimitHeadroomPct) / 100
+}
+
+type estimateEnergyRequest struct {
+	OwnerAddress    string `json:"owner_address"`
+	ContractAddress string `json:"contract_address"`
+	Data            string `json:"data"`
+	Visible         bool   `json:"visible"`
+}
+
+// EstimateResources estimates the energy and bandwidth of tx. Energy comes
+// from executing the contract call on the node without broadcasting; a TRX
+// transfer uses none. Bandwidth is the size of tx once signed, which does
+// not need the node. A call that would revert returns ErrContractReverted.
+func (c *Client) EstimateResources(ctx context.Context, tx *Transaction) (ResourceEstimate, error) {
+	raw, err := hex.DecodeString(tx.RawDataHex)
+	if err != nil || len(raw) == 0 {
+		return ResourceEstimate{}, fmt.Errorf("transaction %s has no raw data", tx.TxID)
+	}
+	signatures := max(len(tx.Signature), 1)
+	est := ResourceEstimate{
+		Bandwidth: int64(1+uvarintLen(uint64(len(raw)))+len(raw)+signatures*signatureFieldSize) + maxResultSize,
+	}
+
+	for _, contract := range tx.RawData.Contract {
+		if contract.Type != ContractTriggerSmartContract {
+			continue
+		}
+		var v triggerSmartContractValue
+		if err := json.Unmarshal(contract.Parameter.Value, &v); err != nil {
+			return ResourceEstimate{}, fmt.Errorf("failed to decode contract call of %s: %w", tx.TxID, err)
+		}
+		req := estimateEnergyRequest{
+			OwnerAddress:    v.OwnerAddress,
+			ContractAddress: v.ContractAddress,
+			Data:            v.Data,
+			Visible:         tx.Visible,
+		}
+		var resp triggerConstantResponse
+		if err := c.post(ctx, c.fullNode, "/wallet/triggerconstantcontract", req, &resp); err != nil {
+			return ResourceEstimate{}, fmt.Errorf("failed to estimate energy of %s: %w", tx.TxID, err)
+		}
+		if err := resp.err(); err != nil {
+			return ResourceEstimate{}, fmt.Errorf("failed to estimate energy of %s: %w", tx.TxID, err)
+		}
+		est.Energy += int64(resp.EnergyUsed)
+	}
+	return est, nil
+}
+
+func uvarintLen(v uint64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], v)
+}
+
+// AccountResources is an account's bandwidth and energy. Every account gets
+// free bandwidth each day; staked bandwidth and energy come from freezing
+// TRX. Usage recovers over 24 hours.
+type AccountResources struct {
+	FreeBandwidthLimit int64 `json:"freeNetLimit"`
+	FreeBandwidthUsed  int64 `json:"freeNetUsed"`
+	BandwidthLimit     int64 `json:"NetLimit"`
+	BandwidthUsed      int64 `json:"NetUsed"`
+	EnergyLimit        int64 `json:"EnergyLimit"`
+	EnergyUsed         int64 `json:"EnergyUsed"`
+}
+
+func (r *AccountResources) UnmarshalJSON(data []byte) error {
+	var v struct {
+		FreeBandwidthLimit jsonInt64 `json:"freeNetLimit"`
+		FreeBandwidthUsed  jsonInt64 `json:"freeNetUsed"`
+		BandwidthLimit     jsonInt64 `json:"NetLimit"`
+		BandwidthUsed      jsonInt64 `json:"NetUsed"`
+		EnergyLimit        jsonInt64 `json:"EnergyLimit"`
+		EnergyUsed         jsonInt64 `json:"EnergyUsed"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*r = AccountResources{
+		FreeBandwidthLimit: int64(v.FreeBandwidthLimit),
+		FreeBandwidthUsed:  int64(v.FreeBandwidthUsed),
+		BandwidthLimit:     int64(v.BandwidthLimit),
+		BandwidthUsed:      int64(v.BandwidthUsed),
+		EnergyLimit:        int64(v.EnergyLimit),
+		EnergyUsed:         int64(v.EnergyUsed),
+	}
+	return nil
+}
+
+// FreeBandwidth returns the free bandwidth left today.
+func (r *AccountResources) FreeBandwidth() int64 {
+	return max(r.FreeBandwidthLimit-r.FreeBandwidthUsed, 0)
+}
+
+// StakedBandwidth returns the bandwidth left from staked TRX.
+func (r *AccountResources) StakedBandwidth() int64 {
+	return max(r.BandwidthLimit-r.BandwidthUsed, 0)
+}
+
+// Energy returns the energy left from staked TRX.
+func (r *AccountResources) Energy() int64 {
+	return max(r.EnergyLimit-r.EnergyUsed, 0)
+}
+
+// GetAccountResources returns the bandwidth and energy of address. An
+// address that has never been activated has no free bandwidth either.
+func (c *Client) GetAccountResources(ctx context.Context, address string) (*AccountResources, error) {
+	if err := wallet.ValidateAddress(address); err != nil {
+		return nil, err
+	}
+
+	var res AccountResources
+	if err := c.post(ctx, c.fullNode, "/wallet/getaccountresource", getAccountRequest{Address: address, Visible: true}, &res); err != nil {
+		return nil, fmt.Errorf("failed to get resources of %s: %w", address, err)
+	}
+	return &res, nil
+}
+
+// Action is what the sender should do before broadcasting a transaction.
+type Action int
+
+const (
+	// ActionProceed means the sender's energy covers the transaction and its
+	// balance covers any bandwidth it lacks.
+	ActionProceed Action = iota
+	// ActionStake means the sender lacks energy. The balance can burn TRX
+	// for it, but staking for energy is usually cheaper for repeated sweeps.
+	ActionStake
+	// ActionTopUp means the balance cannot pay for the missing resources;
+	// the transaction would fail.
+	ActionTopUp
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionProceed:
+		return "proceed"
+	case ActionStake:
+		return "stake"
+	case ActionTopUp:
+		return "top up"
+	}
+	return fmt.Sprintf("Action(%d)", int(a))
+}
+
+// Affordability is the outcome of CanAfford.
+type Affordability struct {
+	Action Action
+	// EnergyShortfall is the energy the sender lacks.
+	EnergyShortfall int64
+	// BurnSun is the TRX burned for missing energy and bandwidth if the
+	// transaction is sent as is.
+	BurnSun int64
+}
+
+// CanAfford decides whether a sender with resources and trxBalance sun can
+// send a transaction with estimate, given the chain's energy price in sun.
+// Bandwidth is taken from staked bandwidth first, then free, then burned; a
+// transaction cannot split its bandwidth between free and staked.
+func CanAfford(resources *AccountResources, estimate ResourceEstimate, trxBalance, energyPrice int64) Affordability {
+	var a Affordability
+	if estimate.Bandwidth > resources.FreeBandwidth() && estimate.Bandwidth > resources.StakedBandwidth() {
+		a.BurnSun += estimate.Bandwidth * BandwidthPriceSun
+	}
+	a.EnergyShortfall = max(estimate.Energy-resources.Energy(), 0)
+	a.BurnSun += a.EnergyShortfall * energyPrice
+
+	switch {
+	case a.BurnSun > trxBalance:
+		a.Action = ActionTopUp
+	case a.EnergyShortfall > 0:
+		a.Action = ActionStake
+	default:
+		a.Action = ActionProceed
+	}
+	return a
+}
@@ -0,0 +1,233 @@
+package tron
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+// energyPrice is the mainnet energy price in sun.
+const energyPrice = 420
+
+func TestEstimateResources_TRC20(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/triggerconstantcontract", http.StatusOK, fixture(t, "triggerconstantcontract_estimate_usdt_transfer.json"))
+	tx, err := BuildTRC20Transfer(builderSender, builderToken, builderTokenRecipient,
+		big.NewInt(builderTRC20AmountUSDT), builderTRC20FeeLimit, fixtureRef)
+	require.NoError(t, err)
+
+	est, err := client.EstimateResources(context.Background(), tx)
+
+	require.NoError(t, err)
+	assert.Equal(t, ResourceEstimate{Energy: 31895, Bandwidth: 345}, est)
+
+	reqs := node.recorded()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "416813eb9362372eef6200f3b1dbc3f819671cba69", reqs[0].Body["owner_address"])
+	assert.Equal(t, "41a614f803b6fd780986a42c78ec9c7f77e6ded13c", reqs[0].Body["contract_address"])
+	assert.Equal(t, false, reqs[0].Body["visible"])
+	assert.Contains(t, reqs[0].Body["data"], "a9059cbb")
+}
+
+func TestEstimateResources_TRX(t *testing.T) {
+	node, client := newFakeNode(t)
+	tx, err := BuildTRXTransfer(builderSender, builderRecipient, 2500000, fixtureRef, time.Minute)
+	require.NoError(t, err)
+
+	est, err := client.EstimateResources(context.Background(), tx)
+
+	require.NoError(t, err)
+	assert.Equal(t, ResourceEstimate{Energy: 0, Bandwidth: 268}, est)
+	assert.Empty(t, node.recorded(), "a TRX transfer needs no energy estimate")
+}
+
+func TestEstimateResources_Signed(t *testing.T) {
+	_, client := newFakeNode(t)
+	tx, err := BuildTRXTransfer(builderSender, builderRecipient, 2500000, fixtureRef, time.Minute)
+	require.NoError(t, err)
+	tx.Signature = []string{"00", "00"}
+
+	est, err := client.EstimateResources(context.Background(), tx)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(268+signatureFieldSize), est.Bandwidth)
+}
+
+func TestEstimateResources_Errors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		fixture string
+		check   func(t *testing.T, err error)
+	}{
+		{"revert", "trc20_revert.json", func(t *testing.T, err error) {
+			assert.ErrorIs(t, err, ErrContractReverted)
+		}},
+		{"validation", "trc20_contract_validate_error.json", func(t *testing.T, err error) {
+			var cerr *ContractError
+			require.True(t, errors.As(err, &cerr))
+			assert.Equal(t, "CONTRACT_VALIDATE_ERROR", cerr.Code)
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			node, client := newFakeNode(t)
+			node.respond("/wallet/triggerconstantcontract", http.StatusOK, fixture(t, tc.fixture))
+			tx, err := BuildTRC20Transfer(builderSender, builderToken, builderTokenRecipient,
+				big.NewInt(1), builderTRC20FeeLimit, fixtureRef)
+			require.NoError(t, err)
+
+			_, err = client.EstimateResources(context.Background(), tx)
+
+			require.Error(t, err)
+			tc.check(t, err)
+		})
+	}
+}
+
+func TestEstimateResources_NoRawData(t *testing.T) {
+	_, client := newFakeNode(t)
+
+	_, err := client.EstimateResources(context.Background(), &Transaction{TxID: "abc"})
+
+	assert.Error(t, err)
+}
+
+func TestResourceEstimate_FeeLimit(t *testing.T) {
+	est := ResourceEstimate{Energy: 31895, Bandwidth: 345}
+
+	assert.Equal(t, int64(16075080), est.FeeLimit(energyPrice))
+	assert.Zero(t, ResourceEstimate{Bandwidth: 268}.FeeLimit(energyPrice))
+}
+
+func TestGetAccountResources(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/getaccountresource", http.StatusOK, fixture(t, "getaccountresource_staked.json"))
+
+	res, err := client.GetAccountResources(context.Background(), activatedAddr)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(480), res.FreeBandwidth())
+	assert.Equal(t, int64(4700), res.StakedBandwidth())
+	assert.Equal(t, int64(50000), res.Energy())
+
+	reqs := node.recorded()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, activatedAddr, reqs[0].Body["address"])
+	assert.Equal(t, true, reqs[0].Body["visible"])
+}
+
+func TestGetAccountResources_Unactivated(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/getaccountresource", http.StatusOK, fixture(t, "getaccountresource_unactivated.json"))
+
+	res, err := client.GetAccountResources(context.Background(), unactivatedAddr)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(600), res.FreeBandwidth())
+	assert.Zero(t, res.StakedBandwidth())
+	assert.Zero(t, res.Energy())
+}
+
+func TestGetAccountResources_NullEnergy(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/getaccountresource", http.StatusOK, fixture(t, "getaccountresource_null_energy.json"))
+
+	res, err := client.GetAccountResources(context.Background(), activatedAddr)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(480), res.FreeBandwidth())
+	assert.Zero(t, res.StakedBandwidth())
+	assert.Zero(t, res.Energy())
+}
+
+func TestGetAccountResources_InvalidAddress(t *testing.T) {
+	node, client := newFakeNode(t)
+
+	_, err := client.GetAccountResources(context.Background(), "TNotAnAddress")
+
+	assert.ErrorIs(t, err, wallet.ErrInvalidAddress)
+	assert.Empty(t, node.recorded())
+}
+
+func TestCanAfford(t *testing.T) {
+	usdt := ResourceEstimate{Energy: 31895, Bandwidth: 345}
+	trx := ResourceEstimate{Bandwidth: 268}
+
+	testCases := []struct {
+		name      string
+		resources AccountResources
+		estimate  ResourceEstimate
+		balance   int64
+		want      Affordability
+	}{
+		{
+			name:      "staked energy and bandwidth",
+			resources: AccountResources{BandwidthLimit: 5000, EnergyLimit: 65000},
+			estimate:  usdt,
+			want:      Affordability{Action: ActionProceed},
+		},
+		{
+			name:      "free bandwidth covers TRX transfer",
+			resources: AccountResources{FreeBandwidthLimit: 600},
+			estimate:  trx,
+			want:      Affordability{Action: ActionProceed},
+		},
+		{
+			name:      "bandwidth burned from balance",
+			resources: AccountResources{FreeBandwidthLimit: 600, FreeBandwidthUsed: 500, EnergyLimit: 65000},
+			estimate:  usdt,
+			balance:   1000000,
+			want:      Affordability{Action: ActionProceed, BurnSun: 345000},
+		},
+		{
+			name:      "free and staked bandwidth are not combined",
+			resources: AccountResources{FreeBandwidthLimit: 600, FreeBandwidthUsed: 400, BandwidthLimit: 200, EnergyLimit: 65000},
+			estimate:  usdt,
+			balance:   1000000,
+			want:      Affordability{Action: ActionProceed, BurnSun: 345000},
+		},
+		{
+			name:      "energy short",
+			resources: AccountResources{FreeBandwidthLimit: 600, EnergyLimit: 20000},
+			estimate:  usdt,
+			balance:   100000000,
+			want:      Affordability{Action: ActionStake, EnergyShortfall: 11895, BurnSun: 11895 * energyPrice},
+		},
+		{
+			name:      "no energy, cannot burn",
+			resources: AccountResources{FreeBandwidthLimit: 600},
+			estimate:  usdt,
+			balance:   1000000,
+			want:      Affordability{Action: ActionTopUp, EnergyShortfall: 31895, BurnSun: 31895 * energyPrice},
+		},
+		{
+			name:      "no bandwidth, no balance",
+			resources: AccountResources{},
+			estimate:  trx,
+			want:      Affordability{Action: ActionTopUp, BurnSun: 268000},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := CanAfford(&tc.resources, tc.estimate, tc.balance, energyPrice)
+
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestAction_String(t *testing.T) {
+	assert.Equal(t, "proceed", ActionProceed.String())
+	assert.Equal(t, "stake", ActionStake.String())
+	assert.Equal(t, "top up", ActionTopUp.String())
+	assert.Equal(t, "Action(7)", Action(7).String())
+}
@@ -0,0 +1,57 @@
+package tron
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+// Signer signs transactions for one address. Keeping the key behind an
+// interface lets it live in an HSM or KMS instead of process memory.
+type Signer interface {
+	// Address is the base58 address whose key signs.
+	Address() string
+	// Sign appends a signature over tx.TxID to tx.Signature.
+	Sign(tx *Transaction) error
+}
+
+// KeySigner signs with a secp256k1 private key held in memory.
+type KeySigner struct {
+	key     *secp256k1.PrivateKey
+	address string
+}
+
+// NewKeySigner returns a signer for the hex-encoded 32-byte private key, as
+// returned by wallet.DeriveTronAddressFromMnemonic.
+func NewKeySigner(privateKeyHex string) (*KeySigner, error) {
+	raw, err := hex.DecodeString(privateKeyHex)
+	if err != nil || len(raw) != 32 {
+		return nil, errors.New("private key must be 32 hex-encoded bytes")
+	}
+	address, err := wallet.PrivateKeyToTronAddress(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &KeySigner{key: secp256k1.PrivKeyFromBytes(raw), address: address}, nil
+}
+
+// Address returns the base58 address of the key.
+func (s *KeySigner) Address() string { return s.address }
+
+// Sign signs the transaction ID, the SHA-256 of its raw data. The signature
+// is r || s || v with v 27 or 28, the layout the node expects.
+func (s *KeySigner) Sign(tx *Transaction) error {
+	hash, err := hex.DecodeString(tx.TxID)
+	if err != nil || len(hash) != 32 {
+		return fmt.Errorf("invalid transaction ID %q", tx.TxID)
+	}
+	// SignCompact returns v || r || s.
+	compact := ecdsa.SignCompact(s.key, hash, false)
+	sig := append(compact[1:], compact[0])
+	tx.Signature = append(tx.Signature, hex.EncodeToString(sig))
+	return nil
+}
@@ -0,0 +1,73 @@
+package tron
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+// testPrivateKey is the secp256k1 key 1, whose address is well known.
+const (
+	testPrivateKey = "0000000000000000000000000000000000000000000000000000000000000001"
+	testKeyAddress = "TMVQGm1qAQYVdetCeGRRkTWYYrLXuHK2HC"
+)
+
+func TestNewKeySigner(t *testing.T) {
+	signer, err := NewKeySigner(testPrivateKey)
+
+	require.NoError(t, err)
+	assert.Equal(t, testKeyAddress, signer.Address())
+}
+
+func TestNewKeySigner_Invalid(t *testing.T) {
+	for _, key := range []string{"", "zz", "0001"} {
+		_, err := NewKeySigner(key)
+		assert.Error(t, err, "key %q", key)
+	}
+}
+
+func TestKeySigner_Sign(t *testing.T) {
+	signer, err := NewKeySigner(testPrivateKey)
+	require.NoError(t, err)
+	tx, err := BuildTRXTransfer(signer.Address(), builderRecipient, 1000000, fixtureRef, time.Minute)
+	require.NoError(t, err)
+
+	require.NoError(t, signer.Sign(tx))
+
+	require.Len(t, tx.Signature, 1)
+	sig, err := hex.DecodeString(tx.Signature[0])
+	require.NoError(t, err)
+	require.Len(t, sig, 65)
+	assert.Contains(t, []byte{27, 28}, sig[64])
+
+	// Recover the public key the way the node does and check it is the
+	// sender's.
+	hash, err := hex.DecodeString(tx.TxID)
+	require.NoError(t, err)
+	compact := append([]byte{sig[64]}, sig[:64]...)
+	pub, _, err := ecdsa.RecoverCompact(compact, hash)
+	require.NoError(t, err)
+	assert.Equal(t, testKeyAddress, publicKeyAddress(t, pub.SerializeUncompressed()))
+}
+
+func TestKeySigner_SignInvalidTxID(t *testing.T) {
+	signer, err := NewKeySigner(testPrivateKey)
+	require.NoError(t, err)
+
+	err = signer.Sign(&Transaction{TxID: "abc"})
+
+	assert.Error(t, err)
+}
+
+func publicKeyAddress(t *testing.T, uncompressed []byte) string {
+	t.Helper()
+	h := keccak256(uncompressed[1:])
+	addr, err := wallet.HexToAddress("41" + hex.EncodeToString(h[12:]))
+	require.NoError(t, err)
+	return addr
+}
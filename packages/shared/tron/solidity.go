@@ -0,0 +1,35 @@
+package tron
+
+import "context"
+
+// ConfirmedClient is a view of a Client that answers from the solidity node,
+// which only knows solidified blocks: a transaction it returns can no longer
+// be reverted. Its answers lag the full node's by about a minute, so use it
+// for final checks and the Client for everything a user waits on.
+//
+// The view shares the client's limiters, endpoint health and retry policy.
+type ConfirmedClient struct {
+	c *Client
+}
+
+// Confirmed returns the solidity view of c.
+func (c *Client) Confirmed() *ConfirmedClient {
+	return &ConfirmedClient{c: c}
+}
+
+// GetTRXBalance returns the confirmed balance of address in sun.
+func (v *ConfirmedClient) GetTRXBalance(ctx context.Context, address string) (int64, error) {
+	return v.c.getTRXBalance(ctx, v.c.solidity, "/walletsolidity/getaccount", address)
+}
+
+// GetTransactionByID fetches the transaction txID if it is in a solidified
+// block, or ErrTransactionNotFound.
+func (v *ConfirmedClient) GetTransactionByID(ctx context.Context, txID string) (*Transaction, error) {
+	return v.c.getTransactionByID(ctx, v.c.solidity, "/walletsolidity/gettransactionbyid", txID)
+}
+
+// GetTransactionInfoByID fetches the receipt of txID if it is in a
+// solidified block, or ErrTransactionNotFound.
+func (v *ConfirmedClient) GetTransactionInfoByID(ctx context.Context, txID string) (*TransactionInfo, error) {
+	return v.c.getTransactionInfoByID(ctx, v.c.solidity, "/walletsolidity/gettransactioninfobyid", txID)
+}
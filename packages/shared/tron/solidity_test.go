@@ -0,0 +1,126 @@
+package tron
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+const solidityTxID = "1111111111111111111111111111111111111111111111111111111111111111"
+
+func TestConfirmed_RoutesToSolidityNode(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/getaccount", http.StatusOK, fixture(t, "getaccount_activated.json"))
+	node.respond("/solidity/walletsolidity/getaccount", http.StatusOK, fixture(t, "getaccount_activated.json"))
+	node.respond("/solidity/walletsolidity/gettransactionbyid", http.StatusOK, fixture(t, "gettransactionbyid_trx.json"))
+	node.respond("/solidity/walletsolidity/gettransactioninfobyid", http.StatusOK, fixture(t, "gettransactioninfobyid_trx.json"))
+	ctx := context.Background()
+
+	_, err := client.GetTRXBalance(ctx, activatedAddr)
+	require.NoError(t, err)
+	balance, err := client.Confirmed().GetTRXBalance(ctx, activatedAddr)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1523000000), balance)
+	tx, err := client.Confirmed().GetTransactionByID(ctx, solidityTxID)
+	require.NoError(t, err)
+	assert.Equal(t, solidityTxID, tx.TxID)
+	info, err := client.Confirmed().GetTransactionInfoByID(ctx, solidityTxID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), info.BlockNumber)
+
+	var paths []string
+	for _, r := range node.recorded() {
+		paths = append(paths, r.Path)
+		assert.Equal(t, "test-key", r.Header.Get(apiKeyHeader), r.Path)
+	}
+	assert.Equal(t, []string{
+		"/wallet/getaccount",
+		"/solidity/walletsolidity/getaccount",
+		"/solidity/walletsolidity/gettransactionbyid",
+		"/solidity/walletsolidity/gettransactioninfobyid",
+	}, paths)
+}
+
+func TestConfirmed_NotYetSolidified(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/solidity/walletsolidity/gettransactionbyid", http.StatusOK, []byte(`{}`))
+	node.respond("/solidity/walletsolidity/gettransactioninfobyid", http.StatusOK, []byte(`{}`))
+
+	_, err := client.Confirmed().GetTransactionByID(context.Background(), solidityTxID)
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+	_, err = client.Confirmed().GetTransactionInfoByID(context.Background(), solidityTxID)
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+}
+
+// newSharedNode starts one node serving both APIs, as TronGrid does.
+func newSharedNode(t *testing.T, cfg config.TronConfig) (*fakeNode, *Client, *fakeClock) {
+	t.Helper()
+	node := &fakeNode{t: t, handlers: make(map[string]func(map[string]any) (int, []byte))}
+	srv := httptest.NewServer(node)
+	t.Cleanup(srv.Close)
+
+	cfg.FullNodeURL = srv.URL
+	cfg.SolidityNodeURL = srv.URL + "/"
+	client := NewClient(cfg, "", WithHTTPClient(srv.Client()))
+	clock := newFakeClock()
+	client.clock = clock
+	return node, client, clock
+}
+
+func TestConfirmed_SharesLimiterWithFullNode(t *testing.T) {
+	node, client, clock := newSharedNode(t, config.TronConfig{
+		RateLimit: config.TronRateLimitConfig{RequestsPerSecond: 1, Burst: 1},
+	})
+	node.respond("/wallet/getaccount", http.StatusOK, fixture(t, "getaccount_activated.json"))
+	node.respond("/walletsolidity/getaccount", http.StatusOK, fixture(t, "getaccount_activated.json"))
+
+	_, err := client.GetTRXBalance(context.Background(), activatedAddr)
+	require.NoError(t, err)
+	_, err = client.Confirmed().GetTRXBalance(context.Background(), activatedAddr)
+	require.NoError(t, err)
+
+	assert.Same(t, client.fullNode.endpoints[0], client.solidity.endpoints[0])
+	assert.Equal(t, []time.Duration{time.Second}, clock.recordedWaits(),
+		"the solidity call waits for the token the full node call spent")
+}
+
+func TestConfirmed_SharesHealthWithFullNode(t *testing.T) {
+	node, client, _ := newSharedNode(t, config.TronConfig{})
+	node.respond("/walletsolidity/gettransactioninfobyid", http.StatusTooManyRequests, []byte("slow down"))
+
+	_, err := client.Confirmed().GetTransactionInfoByID(context.Background(), solidityTxID)
+
+	assert.ErrorIs(t, err, ErrRateLimited)
+	health := client.EndpointHealth()
+	require.Len(t, health, 1)
+	assert.True(t, health[0].RateLimited, "a 429 on the solidity API rests the shared endpoint")
+}
+
+func TestConfirmed_RetriesAndFailsOver(t *testing.T) {
+	nodes, client, clock := newFakeNodes(t, 2)
+	// Route the solidity view across the same two nodes.
+	client.solidity = client.fullNode
+	nodes[0].respond("/walletsolidity/gettransactioninfobyid", http.StatusServiceUnavailable, nil)
+	nodes[1].respond("/walletsolidity/gettransactioninfobyid", http.StatusOK, fixture(t, "gettransactioninfobyid_trx.json"))
+
+	info, err := client.Confirmed().GetTransactionInfoByID(context.Background(), solidityTxID)
+
+	require.NoError(t, err)
+	assert.Equal(t, solidityTxID, info.ID)
+	assert.Len(t, nodes[0].recorded(), 1)
+	assert.Len(t, nodes[1].recorded(), 1)
+	assert.Empty(t, clock.recordedWaits(), "failing over to another node needs no backoff")
+}
+
+func TestConfirmed_SeparateSolidityNode(t *testing.T) {
+	_, client := newFakeNode(t)
+
+	assert.NotSame(t, client.fullNode.endpoints[0], client.solidity.endpoints[0])
+	assert.Equal(t, client.fullNode.endpoints[0].apiKey, client.solidity.endpoints[0].apiKey)
+}
@@ -0,0 +1,69 @@
+package tron
+
+import (
+	"context"
+	"net/url"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans the client records.
+const tracerName = "github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+
+// WithTracerProvider records a client span for every API call, retries
+// included, tagged with the endpoint that served it and the TRON result
+// code the node answered with.
+func WithTracerProvider(tp trace.TracerProvider) ClientOption {
+	return func(c *Client) { c.tracer = tp.Tracer(tracerName) }
+}
+
+// resultCoder is a response that carries a TRON result code, such as the
+// SIGERROR a rejected broadcast gets.
+type resultCoder interface {
+	resultCode() string
+}
+
+func (r *broadcastResponse) resultCode() string { return r.Code }
+
+func (r *triggerConstantResponse) resultCode() string { return r.Result.Code }
+
+func (i *TransactionInfo) resultCode() string { return i.Receipt.Result }
+
+// startSpan starts the span of a call to path.
+func (c *Client) startSpan(ctx context.Context, path string) (context.Context, trace.Span) {
+	return c.tracer.Start(ctx, "tron "+path,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("tron.path", path)))
+}
+
+// tagAttempt records the endpoint attempt n of a call went to. Only its
+// host is kept: some providers put the credential in the URL path.
+func tagAttempt(span trace.Span, ep *endpoint, n int) {
+	if !span.IsRecording() {
+		return
+	}
+	host := ep.url
+	if u, err := url.Parse(ep.url); err == nil {
+		host = u.Host
+	}
+	span.SetAttributes(
+		attribute.String("tron.endpoint", host),
+		attribute.Int("tron.attempts", n),
+	)
+}
+
+// endSpan records how the call ended: its error, or the result code out
+// holds once decoded.
+func endSpan(span trace.Span, out any, err error) {
+	defer span.End()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	if rc, ok := out.(resultCoder); ok && rc.resultCode() != "" {
+		span.SetAttributes(attribute.String("tron.result_code", rc.resultCode()))
+	}
+}
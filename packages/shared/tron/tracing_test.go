@@ -0,0 +1,81 @@
+package tron
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func recordSpans(c *Client) *tracetest.SpanRecorder {
+	rec := tracetest.NewSpanRecorder()
+	WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))(c)
+	return rec
+}
+
+func TestTracing_ResultCode(t *testing.T) {
+	var responses map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(fixture(t, "broadcast_errors.json"), &responses))
+	node, client := newFakeNode(t)
+	rec := recordSpans(client)
+	node.respond("/wallet/broadcasttransaction", http.StatusOK, responses["SIGERROR"])
+
+	_, err := client.BroadcastTransaction(context.Background(), signedTransfer(t))
+	require.ErrorIs(t, err, ErrSignature)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "tron /wallet/broadcasttransaction", span.Name())
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+	attrs := span.Attributes()
+	assert.Contains(t, attrs, attribute.String("tron.result_code", "SIGERROR"))
+	assert.Contains(t, attrs, attribute.Int("tron.attempts", 1))
+	assert.Contains(t, attrs, attribute.Int("http.response.status_code", http.StatusOK))
+	u, err := url.Parse(client.fullNode.endpoints[0].url)
+	require.NoError(t, err)
+	assert.Contains(t, attrs, attribute.String("tron.endpoint", u.Host))
+}
+
+func TestTracing_Retries(t *testing.T) {
+	nodes, client, _ := newFakeNodes(t, 2)
+	rec := recordSpans(client)
+	nodes[0].respond("/wallet/getnowblock", http.StatusInternalServerError, nil)
+	nodes[1].respond("/wallet/getnowblock", http.StatusOK, fixture(t, "getblockbynum_empty.json"))
+
+	_, err := client.GetNowBlock(context.Background())
+	require.NoError(t, err)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	attrs := spans[0].Attributes()
+	assert.Contains(t, attrs, attribute.Int("tron.attempts", 2))
+	assert.Contains(t, attrs, attribute.Int("http.response.status_code", http.StatusOK))
+	u, err := url.Parse(client.fullNode.endpoints[1].url)
+	require.NoError(t, err)
+	assert.Contains(t, attrs, attribute.String("tron.endpoint", u.Host), "the endpoint that answered")
+}
+
+func TestTracing_Failure(t *testing.T) {
+	node, client := newFakeNode(t)
+	rec := recordSpans(client)
+	node.respond("/wallet/getnowblock", http.StatusBadRequest, []byte("bad request"))
+
+	_, err := client.GetNowBlock(context.Background())
+	require.Error(t, err)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Contains(t, spans[0].Attributes(), attribute.Int("http.response.status_code", http.StatusBadRequest))
+}
@@ -0,0 +1,171 @@
+package tron
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+var (
+	// ErrContractReverted is returned when a constant call reverts.
+	ErrContractReverted = errors.New("contract call reverted")
+	// ErrEmptyResult is returned when a constant call succeeds but returns
+	// nothing, e.g. the address is not a contract.
+	ErrEmptyResult = errors.New("contract call returned no result")
+)
+
+// ContractError is returned when the node rejects a contract call, e.g.
+// CONTRACT_VALIDATE_ERROR for an unknown contract.
+type ContractError struct {
+	Code    string
+	Message string
+}
+
+func (e *ContractError) Error() string {
+	return fmt.Sprintf("contract call failed: %s: %s", e.Code, e.Message)
+}
+
+type triggerConstantRequest struct {
+	OwnerAddress     string `json:"owner_address"`
+	ContractAddress  string `json:"contract_address"`
+	FunctionSelector string `json:"function_selector"`
+	Parameter        string `json:"parameter,omitempty"`
+	Visible          bool   `json:"visible"`
+}
+
+type triggerConstantResponse struct {
+	Result struct {
+		Result  bool   `json:"result"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"result"`
+	ConstantResult []string  `json:"constant_result"`
+	EnergyUsed     jsonInt64 `json:"energy_used"`
+	Transaction    struct {
+		Ret []struct {
+			Ret string `json:"ret"`
+		} `json:"ret"`
+	} `json:"transaction"`
+}
+
+// triggerConstant calls a read-only contract method and returns the hex
+// encoded return data.
+func (c *Client) triggerConstant(ctx context.Context, owner, contract, selector, parameter string) (string, error) {
+	if err := wallet.ValidateAddress(contract); err != nil {
+		return "", err
+	}
+
+	req := triggerConstantRequest{
+		OwnerAddress:     owner,
+		ContractAddress:  contract,
+		FunctionSelector: selector,
+		Parameter:        parameter,
+		Visible:          true,
+	}
+	var resp triggerConstantResponse
+	if err := c.post(ctx, c.fullNode, "/wallet/triggerconstantcontract", req, &resp); err != nil {
+		return "", fmt.Errorf("failed to call %s on %s: %w", selector, contract, err)
+	}
+
+	if err := resp.err(); err != nil {
+		return "", fmt.Errorf("%s on %s: %w", selector, contract, err)
+	}
+	if len(resp.ConstantResult) == 0 || resp.ConstantResult[0] == "" {
+		return "", fmt.Errorf("%s on %s: %w", selector, contract, ErrEmptyResult)
+	}
+	return resp.ConstantResult[0], nil
+}
+
+// err returns the node's rejection of the call or ErrContractReverted.
+func (r *triggerConstantResponse) err() error {
+	if r.Result.Code != "" {
+		return &ContractError{Code: r.Result.Code, Message: decodeNodeMessage(r.Result.Message)}
+	}
+	for _, ret := range r.Transaction.Ret {
+		if ret.Ret == "REVERT" {
+			return ErrContractReverted
+		}
+	}
+	return nil
+}
+
+// decodeNodeMessage decodes the hex-encoded message field of node errors,
+// falling back to the raw value.
+func decodeNodeMessage(msg string) string {
+	if b, err := hex.DecodeString(msg); err == nil {
+		return strings.TrimSpace(string(b))
+	}
+	return msg
+}
+
+// GetTRC20Balance returns holder's balance of the TRC20 token at contract in
+// the token's smallest unit.
+func (c *Client) GetTRC20Balance(ctx context.Context, contractAddress, holderAddress string) (*big.Int, error) {
+	param, err := encodeAddressParam(holderAddress)
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.triggerConstant(ctx, holderAddress, contractAddress, "balanceOf(address)", param)
+	if err != nil {
+		return nil, err
+	}
+	return decodeUint256(result)
+}
+
+// GetTRC20Decimals returns the token's decimals. Results are cached per
+// contract since they never change.
+func (c *Client) GetTRC20Decimals(ctx context.Context, contractAddress string) (uint8, error) {
+	c.tokenMu.Lock()
+	d, ok := c.decimals[contractAddress]
+	c.tokenMu.Unlock()
+	if ok {
+		return d, nil
+	}
+
+	result, err := c.triggerConstant(ctx, contractAddress, contractAddress, "decimals()", "")
+	if err != nil {
+		return 0, err
+	}
+	v, err := decodeUint256(result)
+	if err != nil {
+		return 0, err
+	}
+	if !v.IsUint64() || v.Uint64() > 255 {
+		return 0, fmt.Errorf("decimals of %s out of range: %s", contractAddress, v)
+	}
+	d = uint8(v.Uint64())
+
+	c.tokenMu.Lock()
+	c.decimals[contractAddress] = d
+	c.tokenMu.Unlock()
+	return d, nil
+}
+
+// GetTRC20Symbol returns the token's symbol, e.g. USDT. Results are cached
+// per contract.
+func (c *Client) GetTRC20Symbol(ctx context.Context, contractAddress string) (string, error) {
+	c.tokenMu.Lock()
+	s, ok := c.symbols[contractAddress]
+	c.tokenMu.Unlock()
+	if ok {
+		return s, nil
+	}
+
+	result, err := c.triggerConstant(ctx, contractAddress, contractAddress, "symbol()", "")
+	if err != nil {
+		return "", err
+	}
+	if s, err = decodeString(result); err != nil {
+		return "", fmt.Errorf("failed to decode symbol of %s: %w", contractAddress, err)
+	}
+
+	c.tokenMu.Lock()
+	c.symbols[contractAddress] = s
+	c.tokenMu.Unlock()
+	return s, nil
+}
@@ -0,0 +1,167 @@
+package tron
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+const usdtContract = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+
+func TestMethodSelector(t *testing.T) {
+	assert.Equal(t, "70a08231", hex.EncodeToString(methodSelector("balanceOf(address)")))
+	assert.Equal(t, "313ce567", hex.EncodeToString(methodSelector("decimals()")))
+	assert.Equal(t, "95d89b41", hex.EncodeToString(methodSelector("symbol()")))
+	assert.Equal(t, "a9059cbb", hex.EncodeToString(methodSelector("transfer(address,uint256)")))
+}
+
+func TestEncodeAddressParam(t *testing.T) {
+	got, err := encodeAddressParam(usdtContract)
+
+	require.NoError(t, err)
+	assert.Equal(t, "000000000000000000000000a614f803b6fd780986a42c78ec9c7f77e6ded13c", got)
+
+	_, err = encodeAddressParam("bogus")
+	assert.ErrorIs(t, err, wallet.ErrInvalidAddress)
+}
+
+func TestDecodeString(t *testing.T) {
+	testCases := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{"abi string", "0000000000000000000000000000000000000000000000000000000000000020" +
+			"0000000000000000000000000000000000000000000000000000000000000004" +
+			"5553445400000000000000000000000000000000000000000000000000000000", "USDT", false},
+		{"bytes32", "5553445400000000000000000000000000000000000000000000000000000000", "USDT", false},
+		{"bad hex", "zz", "", true},
+		{"too short", "00", "", true},
+		{"offset out of range", "00000000000000000000000000000000000000000000000000000000000000ff" +
+			"0000000000000000000000000000000000000000000000000000000000000004", "", true},
+		{"length out of range", "0000000000000000000000000000000000000000000000000000000000000020" +
+			"00000000000000000000000000000000000000000000000000000000000000ff", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := decodeString(tc.data)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestGetTRC20Balance(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/triggerconstantcontract", http.StatusOK, fixture(t, "trc20_balanceof_usdt.json"))
+
+	balance, err := client.GetTRC20Balance(context.Background(), usdtContract, activatedAddr)
+
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(10_000_000_000), balance)
+
+	reqs := node.recorded()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "balanceOf(address)", reqs[0].Body["function_selector"])
+	assert.Equal(t, "000000000000000000000000a614f803b6fd780986a42c78ec9c7f77e6ded13c", reqs[0].Body["parameter"])
+	assert.Equal(t, usdtContract, reqs[0].Body["contract_address"])
+}
+
+func TestGetTRC20Balance_Errors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		fixture string
+		check   func(t *testing.T, err error)
+	}{
+		{"revert", "trc20_revert.json", func(t *testing.T, err error) {
+			assert.ErrorIs(t, err, ErrContractReverted)
+		}},
+		{"empty result", "trc20_empty.json", func(t *testing.T, err error) {
+			assert.ErrorIs(t, err, ErrEmptyResult)
+		}},
+		{"unknown contract", "trc20_contract_validate_error.json", func(t *testing.T, err error) {
+			var cerr *ContractError
+			require.True(t, errors.As(err, &cerr))
+			assert.Equal(t, "CONTRACT_VALIDATE_ERROR", cerr.Code)
+			assert.Equal(t, "Smart contract is not exist.", cerr.Message)
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			node, client := newFakeNode(t)
+			node.respond("/wallet/triggerconstantcontract", http.StatusOK, fixture(t, tc.fixture))
+
+			_, err := client.GetTRC20Balance(context.Background(), usdtContract, activatedAddr)
+
+			require.Error(t, err)
+			tc.check(t, err)
+		})
+	}
+}
+
+func TestGetTRC20Balance_InvalidAddresses(t *testing.T) {
+	node, client := newFakeNode(t)
+
+	_, err := client.GetTRC20Balance(context.Background(), usdtContract, "bogus")
+	assert.ErrorIs(t, err, wallet.ErrInvalidAddress)
+
+	_, err = client.GetTRC20Balance(context.Background(), "bogus", activatedAddr)
+	assert.ErrorIs(t, err, wallet.ErrInvalidAddress)
+
+	assert.Empty(t, node.recorded())
+}
+
+func TestGetTRC20Decimals_Cached(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/triggerconstantcontract", http.StatusOK, fixture(t, "trc20_decimals_usdt.json"))
+
+	for i := 0; i < 3; i++ {
+		d, err := client.GetTRC20Decimals(context.Background(), usdtContract)
+		require.NoError(t, err)
+		assert.Equal(t, uint8(6), d)
+	}
+
+	reqs := node.recorded()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "decimals()", reqs[0].Body["function_selector"])
+}
+
+func TestGetTRC20Symbol_Cached(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/triggerconstantcontract", http.StatusOK, fixture(t, "trc20_symbol_usdt.json"))
+
+	for i := 0; i < 3; i++ {
+		s, err := client.GetTRC20Symbol(context.Background(), usdtContract)
+		require.NoError(t, err)
+		assert.Equal(t, "USDT", s)
+	}
+
+	assert.Len(t, node.recorded(), 1)
+}
+
+func TestGetTRC20Symbol_ErrorsNotCached(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/triggerconstantcontract", http.StatusOK, fixture(t, "trc20_revert.json"))
+
+	_, err := client.GetTRC20Symbol(context.Background(), usdtContract)
+	require.ErrorIs(t, err, ErrContractReverted)
+
+	node.respond("/wallet/triggerconstantcontract", http.StatusOK, fixture(t, "trc20_symbol_usdt.json"))
+	s, err := client.GetTRC20Symbol(context.Background(), usdtContract)
+	require.NoError(t, err)
+	assert.Equal(t, "USDT", s)
+}
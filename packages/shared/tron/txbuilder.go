@@ -0,0 +1,263 @@
+package tron
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+const (
+	// MaxExpiration is the furthest past its reference block a transaction
+	// may expire; the node rejects anything later.
+	MaxExpiration = 24 * time.Hour
+	// DefaultExpiration is the expiration of a TRC20 transfer unless
+	// WithExpiration is given, the same as the node's own default.
+	DefaultExpiration = time.Minute
+	// DefaultFeeLimitCap is the highest fee limit BuildTRC20Transfer accepts
+	// unless WithFeeLimitCap is given: 100 TRX, several times the cost of a
+	// USDT transfer paid entirely by burning TRX.
+	DefaultFeeLimitCap int64 = 100_000_000
+)
+
+// maxUint256 is the largest amount a TRC20 transfer can encode.
+var maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// typeURLPrefix prefixes the protobuf message name of a contract parameter.
+const typeURLPrefix = "type.googleapis.com/protocol."
+
+// Contract type numbers in the protocol's ContractType enum.
+var contractTypeNumbers = map[string]uint64{
+	ContractTransfer:             1,
+	ContractTriggerSmartContract: 31,
+}
+
+// BlockRef is the recent block a transaction is built against. The node
+// only accepts the transaction while that block is on its chain, which
+// keeps it from being replayed on a fork.
+type BlockRef struct {
+	Number int64
+	// ID is the hex block ID.
+	ID string
+	// Timestamp is the block time in milliseconds.
+	Timestamp int64
+}
+
+// Ref returns the block as a reference for building transactions.
+func (b *Block) Ref() BlockRef {
+	return BlockRef{Number: b.Number(), ID: b.BlockID, Timestamp: b.BlockHeader.RawData.Timestamp}
+}
+
+// refBytes returns ref_block_bytes and ref_block_hash: the low two bytes of
+// the block number and bytes 8-16 of the block ID.
+func (r BlockRef) refBytes() ([]byte, []byte, error) {
+	id, err := hex.DecodeString(r.ID)
+	if err != nil || len(id) != 32 {
+		return nil, nil, fmt.Errorf("invalid reference block ID %q", r.ID)
+	}
+	var num [8]byte
+	binary.BigEndian.PutUint64(num[:], uint64(r.Number))
+	return num[6:8], id[8:16], nil
+}
+
+// BuildOption customises BuildTRC20Transfer.
+type BuildOption func(*buildOptions)
+
+type buildOptions struct {
+	expiration  time.Duration
+	feeLimitCap int64
+}
+
+// WithExpiration sets how long after the reference block the transaction
+// expires.
+func WithExpiration(d time.Duration) BuildOption {
+	return func(o *buildOptions) { o.expiration = d }
+}
+
+// WithFeeLimitCap sets the highest fee limit accepted, in sun.
+func WithFeeLimitCap(sun int64) BuildOption {
+	return func(o *buildOptions) { o.feeLimitCap = sun }
+}
+
+// BuildTRXTransfer builds an unsigned transaction sending amountSun from one
+// base58 address to another. It expires expiration after the reference
+// block. The transaction is encoded locally, so only ref needs the node;
+// its TxID is the one the node computes.
+func BuildTRXTransfer(from, to string, amountSun int64, ref BlockRef, expiration time.Duration) (*Transaction, error) {
+	owner, err := addressBytes(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender: %w", err)
+	}
+	recipient, err := addressBytes(to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+	if from == to {
+		return nil, errors.New("sender and recipient are the same address")
+	}
+	if amountSun <= 0 {
+		return nil, fmt.Errorf("amount must be positive, got %d", amountSun)
+	}
+
+	var value []byte
+	value = appendBytesField(value, 1, owner)
+	value = appendBytesField(value, 2, recipient)
+	value = appendVarintField(value, 3, uint64(amountSun))
+
+	params, err := json.Marshal(transferContractValue{
+		OwnerAddress: hex.EncodeToString(owner),
+		ToAddress:    hex.EncodeToString(recipient),
+		Amount:       jsonInt64(amountSun),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transfer: %w", err)
+	}
+	return buildTransaction(ContractTransfer, value, params, 0, ref, expiration)
+}
+
+// BuildTRC20Transfer builds an unsigned transaction calling
+// transfer(to, amount) on a TRC20 token contract. Addresses are base58 and
+// amount is in the token's base unit. feeLimitSun is the most TRX the sender
+// burns for energy; it must be positive and at most the fee limit cap.
+func BuildTRC20Transfer(from, contract, to string, amount *big.Int, feeLimitSun int64, ref BlockRef, opts ...BuildOption) (*Transaction, error) {
+	o := buildOptions{expiration: DefaultExpiration, feeLimitCap: DefaultFeeLimitCap}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	owner, err := addressBytes(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender: %w", err)
+	}
+	token, err := addressBytes(contract)
+	if err != nil {
+		return nil, fmt.Errorf("invalid contract: %w", err)
+	}
+	if amount == nil || amount.Sign() <= 0 || amount.Cmp(maxUint256) > 0 {
+		return nil, fmt.Errorf("amount must be positive and fit in 256 bits, got %v", amount)
+	}
+	if feeLimitSun <= 0 || feeLimitSun > o.feeLimitCap {
+		return nil, fmt.Errorf("fee limit must be between 0 and %d sun, got %d", o.feeLimitCap, feeLimitSun)
+	}
+	data, err := encodeTransferCall(to, amount)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+
+	var value []byte
+	value = appendBytesField(value, 1, owner)
+	value = appendBytesField(value, 2, token)
+	value = appendBytesField(value, 4, data)
+
+	params, err := json.Marshal(triggerSmartContractValue{
+		OwnerAddress:    hex.EncodeToString(owner),
+		ContractAddress: hex.EncodeToString(token),
+		Data:            hex.EncodeToString(data),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode contract call: %w", err)
+	}
+	return buildTransaction(ContractTriggerSmartContract, value, params, feeLimitSun, ref, o.expiration)
+}
+
+// encodeTransferCall ABI-encodes transfer(to, amount).
+func encodeTransferCall(to string, amount *big.Int) ([]byte, error) {
+	recipient, err := encodeAddressParam(to)
+	if err != nil {
+		return nil, err
+	}
+	data, err := hex.DecodeString(trc20TransferSelector + recipient)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, amount.FillBytes(make([]byte, wordSize))...), nil
+}
+
+// buildTransaction wraps one contract, given as its protobuf encoding and
+// the node's JSON for it, in a transaction and computes its ID.
+func buildTransaction(contractType string, value []byte, params json.RawMessage, feeLimit int64, ref BlockRef, expiration time.Duration) (*Transaction, error) {
+	if expiration <= 0 || expiration > MaxExpiration {
+		return nil, fmt.Errorf("expiration must be between 0 and %s, got %s", MaxExpiration, expiration)
+	}
+	refBytes, refHash, err := ref.refBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	contract := Contract{Type: contractType}
+	contract.Parameter.TypeURL = typeURLPrefix + contractType
+	contract.Parameter.Value = params
+	tx := &Transaction{
+		RawData: TransactionRawData{
+			Contract:      []Contract{contract},
+			RefBlockBytes: hex.EncodeToString(refBytes),
+			RefBlockHash:  hex.EncodeToString(refHash),
+			Expiration:    ref.Timestamp + expiration.Milliseconds(),
+			Timestamp:     ref.Timestamp,
+			FeeLimit:      feeLimit,
+		},
+	}
+
+	var param []byte
+	param = appendBytesField(param, 1, []byte(contract.Parameter.TypeURL))
+	param = appendBytesField(param, 2, value)
+	var c []byte
+	c = appendVarintField(c, 1, contractTypeNumbers[contractType])
+	c = appendBytesField(c, 2, param)
+
+	// Fields in field-number order, as the node serializes them.
+	var raw []byte
+	raw = appendBytesField(raw, 1, refBytes)
+	raw = appendBytesField(raw, 4, refHash)
+	raw = appendVarintField(raw, 8, uint64(tx.RawData.Expiration))
+	raw = appendBytesField(raw, 11, c)
+	raw = appendVarintField(raw, 14, uint64(tx.RawData.Timestamp))
+	raw = appendVarintField(raw, 18, uint64(feeLimit))
+
+	id := sha256.Sum256(raw)
+	tx.RawDataHex = hex.EncodeToString(raw)
+	tx.TxID = hex.EncodeToString(id[:])
+	return tx, nil
+}
+
+// addressBytes decodes a base58 address to its 21 bytes, 0x41 first.
+func addressBytes(address string) ([]byte, error) {
+	h, err := wallet.AddressToHex(address)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(h)
+}
+
+// Protobuf wire types.
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// appendVarintField appends a protobuf varint field. Zero is the default and
+// is omitted, as protobuf does.
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = binary.AppendUvarint(b, uint64(field)<<3|wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+// appendBytesField appends a length-delimited protobuf field, omitting it
+// when empty.
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = binary.AppendUvarint(b, uint64(field)<<3|wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
@@ -0,0 +1,188 @@
+package tron
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixtureRef is the reference block of the createtransaction fixtures.
+var fixtureRef = BlockRef{
+	Number:    65000000,
+	ID:        "0000000003dfd2405e1f0c3a9b7d2e48c6a1f09d3b2e7c5a4f8e1d0b9c6a3f2e",
+	Timestamp: 1727000001000,
+}
+
+const (
+	builderSender    = "TKTX96CBxr5kvhjsDHcqoiPWZageGxoTW3"
+	builderRecipient = "TDvSsdrNM5eeXNL3czpa6AxLDHZA9nwe9K"
+)
+
+func decodeTransaction(t *testing.T, name string) Transaction {
+	t.Helper()
+	var tx Transaction
+	require.NoError(t, json.Unmarshal(fixture(t, name), &tx))
+	return tx
+}
+
+func TestBuildTRXTransfer(t *testing.T) {
+	want := decodeTransaction(t, "createtransaction_trx.json")
+
+	tx, err := BuildTRXTransfer(builderSender, builderRecipient, 2500000, fixtureRef, time.Minute)
+
+	require.NoError(t, err)
+	assert.Equal(t, want.RawDataHex, tx.RawDataHex)
+	assert.Equal(t, want.TxID, tx.TxID)
+	assert.False(t, tx.Visible)
+	assert.Empty(t, tx.Signature)
+
+	wantRaw, err := json.Marshal(want.RawData)
+	require.NoError(t, err)
+	gotRaw, err := json.Marshal(tx.RawData)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(wantRaw), string(gotRaw))
+}
+
+func TestBuildTRXTransfer_Expiration(t *testing.T) {
+	tx, err := BuildTRXTransfer(builderSender, builderRecipient, 1, fixtureRef, 10*time.Minute)
+
+	require.NoError(t, err)
+	assert.Equal(t, fixtureRef.Timestamp, tx.RawData.Timestamp)
+	assert.Equal(t, fixtureRef.Timestamp+600000, tx.RawData.Expiration)
+}
+
+func TestBuildTRXTransfer_Invalid(t *testing.T) {
+	testCases := []struct {
+		name       string
+		from, to   string
+		amount     int64
+		ref        BlockRef
+		expiration time.Duration
+	}{
+		{"invalid sender", "TInvalid", builderRecipient, 1, fixtureRef, time.Minute},
+		{"invalid recipient", builderSender, "not-an-address", 1, fixtureRef, time.Minute},
+		{"same address", builderSender, builderSender, 1, fixtureRef, time.Minute},
+		{"zero amount", builderSender, builderRecipient, 0, fixtureRef, time.Minute},
+		{"negative amount", builderSender, builderRecipient, -5, fixtureRef, time.Minute},
+		{"no expiration", builderSender, builderRecipient, 1, fixtureRef, 0},
+		{"expiration too far", builderSender, builderRecipient, 1, fixtureRef, MaxExpiration + time.Second},
+		{"no reference block", builderSender, builderRecipient, 1, BlockRef{}, time.Minute},
+		{"short block ID", builderSender, builderRecipient, 1, BlockRef{Number: 1, ID: "00000001"}, time.Minute},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tx, err := BuildTRXTransfer(tc.from, tc.to, tc.amount, tc.ref, tc.expiration)
+
+			assert.Error(t, err)
+			assert.Nil(t, tx)
+		})
+	}
+}
+
+func TestBlock_Ref(t *testing.T) {
+	block := decodeBlock(t, "getblockbynum_transfers.json")
+
+	ref := block.Ref()
+
+	assert.Equal(t, block.Number(), ref.Number)
+	assert.Equal(t, block.BlockID, ref.ID)
+	assert.Equal(t, block.Time().UnixMilli(), ref.Timestamp)
+
+	refBytes, refHash, err := ref.refBytes()
+	require.NoError(t, err)
+	assert.Len(t, refBytes, 2)
+	assert.Len(t, refHash, 8)
+}
+
+const (
+	builderToken           = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+	builderTokenRecipient  = "TMVQGm1qAQYVdetCeGRRkTWYYrLXuHK2HC"
+	builderTRC20FeeLimit   = 30000000
+	builderTRC20AmountUSDT = 100000000
+)
+
+func TestEncodeTransferCall(t *testing.T) {
+	data, err := encodeTransferCall(builderTokenRecipient, big.NewInt(builderTRC20AmountUSDT))
+
+	require.NoError(t, err)
+	assert.Equal(t,
+		"a9059cbb"+
+			"0000000000000000000000007e5f4552091a69125d5dfcb7b8c2659029395bdf"+
+			"0000000000000000000000000000000000000000000000000000000005f5e100",
+		hex.EncodeToString(data))
+
+	data, err = encodeTransferCall(builderTokenRecipient, maxUint256)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("ff", wordSize), hex.EncodeToString(data[4+wordSize:]))
+}
+
+func TestBuildTRC20Transfer(t *testing.T) {
+	var resp struct {
+		Transaction Transaction `json:"transaction"`
+	}
+	require.NoError(t, json.Unmarshal(fixture(t, "triggersmartcontract_usdt_transfer.json"), &resp))
+	want := resp.Transaction
+
+	tx, err := BuildTRC20Transfer(builderSender, builderToken, builderTokenRecipient,
+		big.NewInt(builderTRC20AmountUSDT), builderTRC20FeeLimit, fixtureRef)
+
+	require.NoError(t, err)
+	assert.Equal(t, want.RawDataHex, tx.RawDataHex)
+	assert.Equal(t, want.TxID, tx.TxID)
+	assert.Equal(t, int64(builderTRC20FeeLimit), tx.RawData.FeeLimit)
+
+	wantRaw, err := json.Marshal(want.RawData)
+	require.NoError(t, err)
+	gotRaw, err := json.Marshal(tx.RawData)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(wantRaw), string(gotRaw))
+}
+
+func TestBuildTRC20Transfer_Options(t *testing.T) {
+	tx, err := BuildTRC20Transfer(builderSender, builderToken, builderTokenRecipient, big.NewInt(1),
+		200000000, fixtureRef, WithFeeLimitCap(200000000), WithExpiration(5*time.Minute))
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(200000000), tx.RawData.FeeLimit)
+	assert.Equal(t, fixtureRef.Timestamp+300000, tx.RawData.Expiration)
+}
+
+func TestBuildTRC20Transfer_Invalid(t *testing.T) {
+	tooBig := new(big.Int).Add(maxUint256, big.NewInt(1))
+	testCases := []struct {
+		name               string
+		from, contract, to string
+		amount             *big.Int
+		feeLimit           int64
+		opts               []BuildOption
+	}{
+		{"invalid sender", "TInvalid", builderToken, builderTokenRecipient, big.NewInt(1), builderTRC20FeeLimit, nil},
+		{"invalid contract", builderSender, "TInvalid", builderTokenRecipient, big.NewInt(1), builderTRC20FeeLimit, nil},
+		{"invalid recipient", builderSender, builderToken, "TInvalid", big.NewInt(1), builderTRC20FeeLimit, nil},
+		{"nil amount", builderSender, builderToken, builderTokenRecipient, nil, builderTRC20FeeLimit, nil},
+		{"zero amount", builderSender, builderToken, builderTokenRecipient, big.NewInt(0), builderTRC20FeeLimit, nil},
+		{"amount over 256 bits", builderSender, builderToken, builderTokenRecipient, tooBig, builderTRC20FeeLimit, nil},
+		{"zero fee limit", builderSender, builderToken, builderTokenRecipient, big.NewInt(1), 0, nil},
+		{"fee limit over default cap", builderSender, builderToken, builderTokenRecipient, big.NewInt(1), DefaultFeeLimitCap + 1, nil},
+		{"fee limit over cap", builderSender, builderToken, builderTokenRecipient, big.NewInt(1), builderTRC20FeeLimit,
+			[]BuildOption{WithFeeLimitCap(builderTRC20FeeLimit - 1)}},
+		{"expiration too far", builderSender, builderToken, builderTokenRecipient, big.NewInt(1), builderTRC20FeeLimit,
+			[]BuildOption{WithExpiration(MaxExpiration + time.Second)}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tx, err := BuildTRC20Transfer(tc.from, tc.contract, tc.to, tc.amount, tc.feeLimit, fixtureRef, tc.opts...)
+
+			assert.Error(t, err)
+			assert.Nil(t, tx)
+		})
+	}
+}
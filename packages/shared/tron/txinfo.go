@@ -0,0 +1,120 @@
+package tron
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrTransactionNotFound is returned for a transaction the node has not
+// included in a block, either because it is still pending or because it was
+// dropped.
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// TransactionInfo is the receipt returned by wallet/gettransactioninfobyid.
+type TransactionInfo struct {
+	ID             string `json:"id"`
+	BlockNumber    int64  `json:"blockNumber"`
+	BlockTimeStamp int64  `json:"blockTimeStamp"`
+	// ContractAddress is the called contract in hex, empty for transactions
+	// that call none.
+	ContractAddress string `json:"contract_address"`
+	// Result is "FAILED" for a failed transaction and empty otherwise.
+	Result     string `json:"result"`
+	ResMessage string `json:"resMessage"`
+	Receipt    struct {
+		Result string `json:"result"`
+	} `json:"receipt"`
+	// Log holds the events emitted while the transaction executed.
+	Log []Log `json:"log"`
+}
+
+// UnmarshalJSON decodes a receipt. A receipt with an ID but without a block
+// number fails with ErrMissingField; {} is left for the caller to treat as
+// not found.
+func (i *TransactionInfo) UnmarshalJSON(data []byte) error {
+	type plain TransactionInfo
+	v := struct {
+		*plain
+		BlockNumber    *jsonInt64 `json:"blockNumber"`
+		BlockTimeStamp *jsonInt64 `json:"blockTimeStamp"`
+	}{plain: (*plain)(i)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if i.ID != "" && v.BlockNumber == nil {
+		return missingField("blockNumber")
+	}
+	i.BlockNumber, i.BlockTimeStamp = int64Value(v.BlockNumber), int64Value(v.BlockTimeStamp)
+	return nil
+}
+
+// Log is an event emitted by a contract. Address, topics and data are hex
+// without a 0x prefix; Address also lacks the 0x41 prefix.
+type Log struct {
+	Address string   `json:"address"`
+	Topics  []string `json:"topics"`
+	Data    string   `json:"data"`
+}
+
+// Failed reports whether the transaction was included but did not execute
+// successfully, e.g. a reverted contract call.
+func (i *TransactionInfo) Failed() bool { return i.Result == txResultFailed }
+
+type getTransactionInfoByBlockNumRequest struct {
+	Num int64 `json:"num"`
+}
+
+type getTransactionByIDRequest struct {
+	Value   string `json:"value"`
+	Visible bool   `json:"visible,omitempty"`
+}
+
+// GetTransactionInfoByID fetches the receipt of txID, or
+// ErrTransactionNotFound if it is not in a block.
+func (c *Client) GetTransactionInfoByID(ctx context.Context, txID string) (*TransactionInfo, error) {
+	return c.getTransactionInfoByID(ctx, c.fullNode, "/wallet/gettransactioninfobyid", txID)
+}
+
+func (c *Client) getTransactionInfoByID(ctx context.Context, nodes *pool, path, txID string) (*TransactionInfo, error) {
+	var info TransactionInfo
+	if err := c.post(ctx, nodes, path, getTransactionByIDRequest{Value: txID}, &info); err != nil {
+		return nil, fmt.Errorf("failed to get transaction info %s: %w", txID, err)
+	}
+	// The node answers {} for a transaction it has no receipt for.
+	if info.ID == "" {
+		return nil, fmt.Errorf("failed to get transaction info %s: %w", txID, ErrTransactionNotFound)
+	}
+	return &info, nil
+}
+
+// GetTransactionByID fetches the transaction txID, or ErrTransactionNotFound
+// if the node does not know it. A pending transaction is found too; use
+// GetTransactionInfoByID to tell whether it is in a block.
+func (c *Client) GetTransactionByID(ctx context.Context, txID string) (*Transaction, error) {
+	return c.getTransactionByID(ctx, c.fullNode, "/wallet/gettransactionbyid", txID)
+}
+
+func (c *Client) getTransactionByID(ctx context.Context, nodes *pool, path, txID string) (*Transaction, error) {
+	var tx Transaction
+	if err := c.post(ctx, nodes, path, getTransactionByIDRequest{Value: txID, Visible: true}, &tx); err != nil {
+		return nil, fmt.Errorf("failed to get transaction %s: %w", txID, err)
+	}
+	// As with receipts, an unknown transaction is {}.
+	if tx.TxID == "" {
+		return nil, fmt.Errorf("failed to get transaction %s: %w", txID, ErrTransactionNotFound)
+	}
+	return &tx, nil
+}
+
+// GetTransactionInfoByBlockNum fetches the receipts of every transaction in
+// block num. A block without transactions, or one the node does not have
+// yet, returns an empty slice.
+func (c *Client) GetTransactionInfoByBlockNum(ctx context.Context, num int64) ([]TransactionInfo, error) {
+	var infos []TransactionInfo
+	if err := c.post(ctx, c.fullNode, "/wallet/gettransactioninfobyblocknum", getTransactionInfoByBlockNumRequest{Num: num}, &infos); err != nil {
+		return nil, fmt.Errorf("failed to get transaction info for block %d: %w", num, err)
+	}
+	return infos, nil
+}
@@ -0,0 +1,111 @@
+package tron
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTransactionInfoByID(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/gettransactioninfobyid", http.StatusOK, fixture(t, "gettransactioninfobyid_trx.json"))
+	txID := "1111111111111111111111111111111111111111111111111111111111111111"
+
+	info, err := client.GetTransactionInfoByID(context.Background(), txID)
+
+	require.NoError(t, err)
+	assert.Equal(t, txID, info.ID)
+	assert.Equal(t, int64(1000), info.BlockNumber)
+	assert.Empty(t, info.Result)
+
+	reqs := node.recorded()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, txID, reqs[0].Body["value"])
+}
+
+func TestGetTransactionInfoByID_NotFound(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/gettransactioninfobyid", http.StatusOK, []byte(`{}`))
+
+	_, err := client.GetTransactionInfoByID(context.Background(), "dead")
+
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+}
+
+func TestGetTransactionInfoByID_StringNumbers(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/gettransactioninfobyid", http.StatusOK, fixture(t, "gettransactioninfobyid_string_numbers.json"))
+
+	info, err := client.GetTransactionInfoByID(context.Background(), "1111111111111111111111111111111111111111111111111111111111111111")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), info.BlockNumber)
+	assert.Equal(t, int64(1700000004000), info.BlockTimeStamp)
+}
+
+func TestGetTransactionInfoByID_MissingBlockNumber(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/gettransactioninfobyid", http.StatusOK, fixture(t, "gettransactioninfobyid_missing_block.json"))
+
+	_, err := client.GetTransactionInfoByID(context.Background(), "1111111111111111111111111111111111111111111111111111111111111111")
+
+	require.ErrorIs(t, err, ErrMissingField)
+	assert.ErrorContains(t, err, "failed to decode /wallet/gettransactioninfobyid response: missing required field blockNumber")
+}
+
+func TestGetTransactionByID(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/gettransactionbyid", http.StatusOK, fixture(t, "gettransactionbyid_trx.json"))
+	txID := "1111111111111111111111111111111111111111111111111111111111111111"
+
+	tx, err := client.GetTransactionByID(context.Background(), txID)
+
+	require.NoError(t, err)
+	assert.Equal(t, txID, tx.TxID)
+	require.Len(t, tx.Ret, 1)
+	assert.Equal(t, "SUCCESS", tx.Ret[0].ContractRet)
+
+	reqs := node.recorded()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, txID, reqs[0].Body["value"])
+	assert.Equal(t, true, reqs[0].Body["visible"])
+}
+
+func TestGetTransactionByID_NotFound(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/gettransactionbyid", http.StatusOK, []byte(`{}`))
+
+	_, err := client.GetTransactionByID(context.Background(), "dead")
+
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+}
+
+func TestGetTransactionInfoByBlockNum(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/gettransactioninfobyblocknum", http.StatusOK, fixture(t, "gettransactioninfobyblocknum_transfers.json"))
+
+	infos, err := client.GetTransactionInfoByBlockNum(context.Background(), 1000)
+
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	assert.Empty(t, infos[0].Log)
+	assert.Len(t, infos[1].Log, 1)
+	assert.Equal(t, "41a614f803b6fd780986a42c78ec9c7f77e6ded13c", infos[1].ContractAddress)
+
+	reqs := node.recorded()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, float64(1000), reqs[0].Body["num"])
+}
+
+func TestGetTransactionInfoByBlockNum_Empty(t *testing.T) {
+	node, client := newFakeNode(t)
+	node.respond("/wallet/gettransactioninfobyblocknum", http.StatusOK, []byte(`[]`))
+
+	infos, err := client.GetTransactionInfoByBlockNum(context.Background(), 1001)
+
+	require.NoError(t, err)
+	assert.Empty(t, infos)
+}
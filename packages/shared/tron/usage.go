@@ -0,0 +1,152 @@
+package tron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBudgetExhausted is returned for a non-critical call once the day's
+// request budget is spent.
+var ErrBudgetExhausted = errors.New("daily tron request budget exhausted")
+
+type nonCriticalKey struct{}
+
+// NonCritical marks the calls made with the returned context as ones that
+// can wait for tomorrow, e.g. balance audits and reconciliation: once the
+// daily budget is spent they fail with ErrBudgetExhausted instead of going
+// out. Calls are critical by default, so block polling and broadcasts never
+// stop.
+func NonCritical(ctx context.Context) context.Context {
+	return context.WithValue(ctx, nonCriticalKey{}, true)
+}
+
+func isCritical(ctx context.Context) bool {
+	nonCritical, _ := ctx.Value(nonCriticalKey{}).(bool)
+	return !nonCritical
+}
+
+// Usage is a snapshot of the requests a Client has sent, e.g. for metrics.
+// Windows are aligned to the clock: Minute counts from the start of the
+// current minute, Hour from the start of the hour and Today from UTC
+// midnight.
+type Usage struct {
+	Today int64
+	// Budget is the daily budget, zero when there is none.
+	Budget int64
+	// Paths lists the API paths called so far, sorted.
+	Paths []PathUsage
+}
+
+// PathUsage counts the requests sent to one API path.
+type PathUsage struct {
+	Path   string
+	Minute int64
+	Hour   int64
+}
+
+// Usage returns how many requests the client has sent, retries included.
+func (c *Client) Usage() Usage {
+	return c.usage.snapshot(c.clock.Now())
+}
+
+// usage accounts for the requests a Client sends. Counters are in memory,
+// so the budget is per process.
+type usage struct {
+	budget uint64
+	today  window
+	// paths maps an API path to its *pathWindows.
+	paths sync.Map
+}
+
+type pathWindows struct {
+	minute, hour window
+}
+
+func newUsage(budget int64) *usage {
+	return &usage{budget: uint64(max(budget, 0)), today: window{width: 24 * time.Hour}}
+}
+
+// admit counts a request to path, or refuses it when it is not critical
+// and the day's budget is spent.
+func (u *usage) admit(path string, now time.Time, critical bool) error {
+	limit := u.budget
+	if critical {
+		limit = 0
+	}
+	if !u.today.add(now, limit) {
+		return fmt.Errorf("%s not sent: %w", path, ErrBudgetExhausted)
+	}
+	w, ok := u.paths.Load(path)
+	if !ok {
+		w, _ = u.paths.LoadOrStore(path, &pathWindows{
+			minute: window{width: time.Minute},
+			hour:   window{width: time.Hour},
+		})
+	}
+	pw := w.(*pathWindows)
+	pw.minute.add(now, 0)
+	pw.hour.add(now, 0)
+	return nil
+}
+
+func (u *usage) snapshot(now time.Time) Usage {
+	s := Usage{Today: int64(u.today.count(now)), Budget: int64(u.budget)}
+	u.paths.Range(func(path, w any) bool {
+		pw := w.(*pathWindows)
+		s.Paths = append(s.Paths, PathUsage{
+			Path:   path.(string),
+			Minute: int64(pw.minute.count(now)),
+			Hour:   int64(pw.hour.count(now)),
+		})
+		return true
+	})
+	sort.Slice(s.Paths, func(i, j int) bool { return s.Paths[i].Path < s.Paths[j].Path })
+	return s
+}
+
+// window counts events in consecutive windows of width since the Unix
+// epoch. The window number and its count share one word, so a count is
+// reset exactly when its window ends, without a lock.
+type window struct {
+	width time.Duration
+	// v holds the window number in its high 32 bits and the count in its
+	// low 32.
+	v atomic.Uint64
+}
+
+func (w *window) number(now time.Time) uint64 {
+	return uint64(now.Unix() / int64(w.width/time.Second))
+}
+
+// add counts an event unless the current window already holds limit; a
+// zero limit sets none. It reports whether the event was counted.
+func (w *window) add(now time.Time, limit uint64) bool {
+	n := w.number(now)
+	for {
+		old := w.v.Load()
+		count := old & 0xffffffff
+		if old>>32 != n {
+			count = 0
+		}
+		if limit > 0 && count >= limit {
+			return false
+		}
+		if w.v.CompareAndSwap(old, n<<32|(count+1)) {
+			return true
+		}
+	}
+}
+
+// count returns the events of the current window.
+func (w *window) count(now time.Time) uint64 {
+	v := w.v.Load()
+	if v>>32 != w.number(now) {
+		return 0
+	}
+	return v & 0xffffffff
+}
@@ -0,0 +1,140 @@
+package tron
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+func TestWindow_ResetsAtEdge(t *testing.T) {
+	w := window{width: time.Minute}
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	require.True(t, w.add(start, 0))
+	require.True(t, w.add(start.Add(59*time.Second), 0))
+	assert.Equal(t, uint64(2), w.count(start.Add(59*time.Second)))
+
+	assert.Zero(t, w.count(start.Add(time.Minute)), "the next window starts empty")
+	require.True(t, w.add(start.Add(time.Minute), 0))
+	assert.Equal(t, uint64(1), w.count(start.Add(time.Minute+30*time.Second)))
+}
+
+func TestWindow_Limit(t *testing.T) {
+	w := window{width: time.Hour}
+	now := time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	assert.True(t, w.add(now, 2))
+	assert.True(t, w.add(now, 2))
+	assert.False(t, w.add(now, 2))
+	assert.Equal(t, uint64(2), w.count(now), "a refused event is not counted")
+	assert.True(t, w.add(now, 0), "no limit")
+}
+
+func TestWindow_ConcurrentLimit(t *testing.T) {
+	w := window{width: time.Hour}
+	now := time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	admitted := 0
+
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				if w.add(now, 600) {
+					mu.Lock()
+					admitted++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 600, admitted)
+	assert.Equal(t, uint64(600), w.count(now))
+}
+
+func TestClient_DailyBudget(t *testing.T) {
+	node, client, clock := newPacedNode(t, config.TronConfig{DailyBudget: 2})
+	node.respond("/wallet/getnowblock", http.StatusOK, fixture(t, "getnowblock.json"))
+	audit := NonCritical(context.Background())
+
+	for range 2 {
+		_, err := client.GetNowBlock(audit)
+		require.NoError(t, err)
+	}
+	_, err := client.GetNowBlock(audit)
+	require.ErrorIs(t, err, ErrBudgetExhausted)
+	assert.Len(t, node.recorded(), 2, "a refused call is not sent")
+
+	_, err = client.GetNowBlock(context.Background())
+	require.NoError(t, err, "critical calls go past the budget")
+	assert.Len(t, node.recorded(), 3)
+	assert.Equal(t, int64(3), client.Usage().Today)
+
+	clock.Advance(24 * time.Hour)
+	_, err = client.GetNowBlock(audit)
+	require.NoError(t, err, "the budget renews at UTC midnight")
+	assert.Equal(t, int64(1), client.Usage().Today)
+}
+
+func TestClient_CriticalCallsSpendBudget(t *testing.T) {
+	node, client, _ := newPacedNode(t, config.TronConfig{DailyBudget: 1})
+	node.respond("/wallet/getnowblock", http.StatusOK, fixture(t, "getnowblock.json"))
+
+	_, err := client.GetNowBlock(context.Background())
+	require.NoError(t, err)
+	_, err = client.GetNowBlock(NonCritical(context.Background()))
+
+	require.ErrorIs(t, err, ErrBudgetExhausted)
+}
+
+func TestClient_NoBudget(t *testing.T) {
+	node, client, _ := newPacedNode(t, config.TronConfig{})
+	node.respond("/wallet/getnowblock", http.StatusOK, fixture(t, "getnowblock.json"))
+
+	for range 5 {
+		_, err := client.GetNowBlock(NonCritical(context.Background()))
+		require.NoError(t, err)
+	}
+	assert.Zero(t, client.Usage().Budget)
+}
+
+func TestClient_Usage(t *testing.T) {
+	node, client, clock := newPacedNode(t, config.TronConfig{DailyBudget: 100})
+	node.respond("/wallet/getnowblock", http.StatusOK, fixture(t, "getnowblock.json"))
+	node.respond("/wallet/getblockbynum", http.StatusOK, []byte(`{}`))
+	// Start on the hour so the minute ends before the hour.
+	now := clock.Now()
+	clock.Advance(now.Truncate(time.Hour).Add(time.Hour).Sub(now))
+
+	_, err := client.GetNowBlock(context.Background())
+	require.NoError(t, err)
+	clock.Advance(time.Minute)
+	_, err = client.GetNowBlock(context.Background())
+	require.NoError(t, err)
+	_, _ = client.GetBlockByNum(context.Background(), 1)
+
+	assert.Equal(t, Usage{
+		Today:  3,
+		Budget: 100,
+		Paths: []PathUsage{
+			{Path: "/wallet/getblockbynum", Minute: 1, Hour: 1},
+			{Path: "/wallet/getnowblock", Minute: 1, Hour: 2},
+		},
+	}, client.Usage())
+
+	clock.Advance(time.Hour)
+	assert.Equal(t, []PathUsage{
+		{Path: "/wallet/getblockbynum"},
+		{Path: "/wallet/getnowblock"},
+	}, client.Usage().Paths, "paths stay listed with empty windows")
+}
@@ -0,0 +1,165 @@
+// Package walletcheck re-derives deposit wallets already handed out and
+// compares them with the stored addresses. A build whose key derivation
+// changed would otherwise give out wallets nobody holds the keys of.
+package walletcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+)
+
+// ErrMismatch is returned by Result.Err when a stored wallet is not the one
+// its index derives to.
+var ErrMismatch = errors.New("derived wallet does not match the stored one")
+
+// Store is the subset of *repository.Store the checker reads sampled
+// attempts through.
+type Store interface {
+	ListRecentAttemptWallets(ctx context.Context, limit int32) ([]repository.ListRecentAttemptWalletsRow, error)
+}
+
+// Mismatch is a sampled attempt whose wallet did not re-derive.
+type Mismatch struct {
+	PaymentID uuid.UUID `json:"payment_id"`
+	AccountID uuid.UUID `json:"account_id"`
+	Attempt   int32     `json:"attempt"`
+	Mode      string    `json:"mode"`
+	Index     int64     `json:"wallet_index"`
+	Stored    string    `json:"stored_wallet"`
+	// Derived is empty when the index could not be derived at all; Error
+	// then says why.
+	Derived string `json:"derived_wallet,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Result is the outcome of a check.
+type Result struct {
+	// Checked counts the attempts re-derived, mismatches included.
+	Checked int `json:"checked"`
+	// Skipped counts test-mode attempts, left out when no test mnemonic is
+	// configured.
+	Skipped    int        `json:"skipped"`
+	Mismatches []Mismatch `json:"mismatches"`
+}
+
+// Err returns nil when every checked wallet matched, and otherwise an
+// ErrMismatch naming the first index that diverged.
+func (r Result) Err() error {
+	if len(r.Mismatches) == 0 {
+		return nil
+	}
+	m := r.Mismatches[0]
+	return fmt.Errorf("%w: %s wallet index %d of payment %s (%d of %d checked wallets differ)",
+		ErrMismatch, m.Mode, m.Index, m.PaymentID, len(r.Mismatches), r.Checked)
+}
+
+// Checker re-derives the wallets of the newest payment attempts.
+type Checker struct {
+	store       Store
+	wallets     payments.WalletDeriver
+	testWallets payments.WalletDeriver
+	logger      *slog.Logger
+}
+
+// Option customises a Checker.
+type Option func(*Checker)
+
+// WithLogger replaces slog.Default.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Checker) { c.logger = l }
+}
+
+// WithTestWallets checks the wallets of test payments against w. Without it
+// they are skipped.
+func WithTestWallets(w payments.WalletDeriver) Option {
+	return func(c *Checker) { c.testWallets = w }
+}
+
+// New builds a checker; wallets derives the wallets of live payments.
+func New(store Store, wallets payments.WalletDeriver, opts ...Option) *Checker {
+	c := &Checker{
+		store:   store,
+		wallets: wallets,
+		logger:  slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Check re-derives the wallets of the samples newest attempts that recorded
+// their index. The error is only set when the attempts could not be read;
+// mismatches are in the result, each one logged.
+func (c *Checker) Check(ctx context.Context, samples int) (Result, error) {
+	rows, err := c.store.ListRecentAttemptWallets(ctx, int32(min(samples, math.MaxInt32)))
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to list recent payment attempts: %w", err)
+	}
+
+	var res Result
+	for _, row := range rows {
+		wallets := c.wallets
+		if row.Mode == config.ModeTest {
+			if wallets = c.testWallets; wallets == nil {
+				res.Skipped++
+				continue
+			}
+		}
+		res.Checked++
+		m, ok := check(wallets, row)
+		if ok {
+			continue
+		}
+		res.Mismatches = append(res.Mismatches, m)
+		c.logger.ErrorContext(ctx, "stored wallet does not match its derivation",
+			"payment_id", m.PaymentID, "account_id", m.AccountID, "attempt", m.Attempt, "mode", m.Mode,
+			"wallet_index", m.Index, "stored_wallet", m.Stored, "derived_wallet", m.Derived, "error", m.Error)
+	}
+	c.logger.InfoContext(ctx, "wallet derivation checked", "checked", res.Checked, "skipped", res.Skipped,
+		"mismatches", len(res.Mismatches))
+	return res, nil
+}
+
+// check re-derives the wallet of row and reports whether it matched.
+func check(wallets payments.WalletDeriver, row repository.ListRecentAttemptWalletsRow) (Mismatch, bool) {
+	m := Mismatch{
+		PaymentID: row.PaymentID,
+		AccountID: row.AccountID,
+		Attempt:   row.AttemptNumber,
+		Mode:      row.Mode,
+		Index:     row.WalletIndex,
+		Stored:    row.GeneratedWallet,
+	}
+	if row.WalletIndex < 0 || row.WalletIndex > math.MaxUint32 {
+		m.Error = fmt.Sprintf("wallet index %d is out of range", row.WalletIndex)
+		return m, false
+	}
+	derived, err := wallets.DeriveWallet(uint32(row.WalletIndex))
+	if err != nil {
+		m.Error = err.Error()
+		return m, false
+	}
+	m.Derived = derived
+	return m, derived == row.GeneratedWallet
+}
+
+// Halted returns a deriver refusing every derivation with
+// payments.ErrDerivationHalted, for serving read-only after a failed check.
+func Halted() payments.WalletDeriver {
+	return halted{}
+}
+
+type halted struct{}
+
+func (halted) DeriveWallet(uint32) (string, error) {
+	return "", payments.ErrDerivationHalted
+}
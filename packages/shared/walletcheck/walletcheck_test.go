@@ -0,0 +1,176 @@
+package walletcheck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/payments"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+const (
+	liveMnemonic  = "flash couple heart script ramp april average caution plunge alter elite author"
+	testMnemonic  = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	otherMnemonic = "legal winner thank year wave sausage worth useful legal winner thank yellow"
+)
+
+// mnemonicWallets derives with the real wallet package, as the services do.
+type mnemonicWallets string
+
+func (m mnemonicWallets) DeriveWallet(index uint32) (string, error) {
+	address, _, err := wallet.DeriveTronAddressFromMnemonic(string(m), index)
+	return address, err
+}
+
+// failingWallets fails every derivation.
+type failingWallets struct{}
+
+func (failingWallets) DeriveWallet(uint32) (string, error) {
+	return "", errors.New("seed unavailable")
+}
+
+func derive(t *testing.T, mnemonic string, index int64) string {
+	t.Helper()
+	address, _, err := wallet.DeriveTronAddressFromMnemonic(mnemonic, uint32(index))
+	require.NoError(t, err)
+	return address
+}
+
+// attempt is a stored attempt of mode whose wallet was derived from
+// mnemonic at index.
+func attempt(t *testing.T, mode, mnemonic string, index int64) repository.ListRecentAttemptWalletsRow {
+	return repository.ListRecentAttemptWalletsRow{
+		PaymentID:       uuid.New(),
+		AttemptNumber:   1,
+		GeneratedWallet: derive(t, mnemonic, index),
+		WalletIndex:     index,
+		AccountID:       uuid.New(),
+		Mode:            mode,
+	}
+}
+
+func newChecker(t *testing.T, rows []repository.ListRecentAttemptWalletsRow, opts ...Option) (*Checker, *bytes.Buffer) {
+	t.Helper()
+	store := repository.NewMockQuerier(t)
+	store.On("ListRecentAttemptWallets", mock.Anything, int32(20)).Return(rows, nil)
+	var logs bytes.Buffer
+	opts = append([]Option{WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))}, opts...)
+	return New(store, mnemonicWallets(liveMnemonic), opts...), &logs
+}
+
+func TestCheck_AllMatch(t *testing.T) {
+	rows := []repository.ListRecentAttemptWalletsRow{
+		attempt(t, config.ModeLive, liveMnemonic, 3),
+		attempt(t, config.ModeLive, liveMnemonic, 2),
+		attempt(t, config.ModeTest, testMnemonic, 0),
+	}
+	c, _ := newChecker(t, rows, WithTestWallets(mnemonicWallets(testMnemonic)))
+
+	res, err := c.Check(context.Background(), 20)
+
+	require.NoError(t, err)
+	assert.Equal(t, Result{Checked: 3}, res)
+	assert.NoError(t, res.Err())
+}
+
+func TestCheck_CorruptedFixtures(t *testing.T) {
+	good := attempt(t, config.ModeLive, liveMnemonic, 5)
+	// Derived by a build whose derivation changed: a valid address, but
+	// not the one the configured mnemonic gives.
+	diverged := attempt(t, config.ModeLive, otherMnemonic, 6)
+	// A stored address with its checksum character altered.
+	tampered := attempt(t, config.ModeLive, liveMnemonic, 7)
+	last := tampered.GeneratedWallet[len(tampered.GeneratedWallet)-1]
+	replacement := byte('A')
+	if last == replacement {
+		replacement = 'B'
+	}
+	tampered.GeneratedWallet = tampered.GeneratedWallet[:len(tampered.GeneratedWallet)-1] + string(replacement)
+
+	c, logs := newChecker(t, []repository.ListRecentAttemptWalletsRow{good, diverged, tampered})
+
+	res, err := c.Check(context.Background(), 20)
+
+	require.NoError(t, err, "mismatches are reported in the result")
+	assert.Equal(t, 3, res.Checked)
+	require.Len(t, res.Mismatches, 2)
+	assert.Equal(t, Mismatch{
+		PaymentID: diverged.PaymentID,
+		AccountID: diverged.AccountID,
+		Attempt:   1,
+		Mode:      config.ModeLive,
+		Index:     6,
+		Stored:    diverged.GeneratedWallet,
+		Derived:   derive(t, liveMnemonic, 6),
+	}, res.Mismatches[0])
+	assert.Equal(t, int64(7), res.Mismatches[1].Index)
+	assert.Equal(t, derive(t, liveMnemonic, 7), res.Mismatches[1].Derived)
+
+	err = res.Err()
+	require.ErrorIs(t, err, ErrMismatch)
+	assert.Contains(t, err.Error(), "live wallet index 6 of payment "+diverged.PaymentID.String())
+	assert.Contains(t, err.Error(), "2 of 3 checked wallets differ")
+	assert.Contains(t, logs.String(), "wallet_index=6")
+	assert.Contains(t, logs.String(), "wallet_index=7")
+	assert.NotContains(t, logs.String(), "wallet_index=5")
+}
+
+func TestCheck_TestModeNeedsTestWallets(t *testing.T) {
+	// Test payments derive from their own mnemonic; checked against the
+	// live one they would all mismatch.
+	rows := []repository.ListRecentAttemptWalletsRow{
+		attempt(t, config.ModeTest, testMnemonic, 0),
+		attempt(t, config.ModeLive, liveMnemonic, 0),
+	}
+
+	c, _ := newChecker(t, rows)
+	res, err := c.Check(context.Background(), 20)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Checked: 1, Skipped: 1}, res)
+
+	c, _ = newChecker(t, rows, WithTestWallets(mnemonicWallets(liveMnemonic)))
+	res, err = c.Check(context.Background(), 20)
+	require.NoError(t, err)
+	require.Len(t, res.Mismatches, 1, "a test wallet checked against the wrong mnemonic")
+	assert.Equal(t, config.ModeTest, res.Mismatches[0].Mode)
+}
+
+func TestCheck_DerivationError(t *testing.T) {
+	row := attempt(t, config.ModeLive, liveMnemonic, 1)
+	outOfRange := attempt(t, config.ModeLive, liveMnemonic, 2)
+	outOfRange.WalletIndex = math.MaxUint32 + 1
+	store := repository.NewMockQuerier(t)
+	store.On("ListRecentAttemptWallets", mock.Anything, int32(20)).Return([]repository.ListRecentAttemptWalletsRow{row, outOfRange}, nil)
+
+	res, err := New(store, failingWallets{}, WithLogger(slog.New(slog.DiscardHandler))).Check(context.Background(), 20)
+
+	require.NoError(t, err)
+	require.Len(t, res.Mismatches, 2)
+	assert.Empty(t, res.Mismatches[0].Derived)
+	assert.Equal(t, "seed unavailable", res.Mismatches[0].Error)
+	assert.Equal(t, "wallet index 4294967296 is out of range", res.Mismatches[1].Error)
+}
+
+func TestCheck_StoreError(t *testing.T) {
+	store := repository.NewMockQuerier(t)
+	store.On("ListRecentAttemptWallets", mock.Anything, int32(5)).Return(nil, errors.New("connection reset"))
+
+	_, err := New(store, mnemonicWallets(liveMnemonic)).Check(context.Background(), 5)
+
+	require.ErrorContains(t, err, "failed to list recent payment attempts: connection reset")
+}
+
+func TestHalted(t *testing.T) {
+	_, err := Halted().DeriveWallet(0)
+	assert.ErrorIs(t, err, payments.ErrDerivationHalted)
+}
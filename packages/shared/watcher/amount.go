@@ -0,0 +1,35 @@
+package watcher
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// bpsScale is 100% in basis points.
+const bpsScale = 10000
+
+// match is the outcome of comparing a payment's transfers, including a new
+// one, with the requested amount.
+type match struct {
+	// received is the sum of all transfers so far.
+	received decimal.Decimal
+	// excess is the part of the new transfer beyond the requested amount.
+	excess decimal.Decimal
+	// funded is set once received is within toleranceBps of the requested
+	// amount.
+	funded bool
+}
+
+// matchAmount adds amount to the earlier transfers of a payment. Tolerance
+// only applies to underpayment: anything above requested is excess.
+func matchAmount(requested, earlier, amount decimal.Decimal, toleranceBps int64) match {
+	received := earlier.Add(amount)
+	m := match{
+		received: received,
+		funded: received.Mul(decimal.NewFromInt(bpsScale)).
+			GreaterThanOrEqual(requested.Mul(decimal.NewFromInt(bpsScale - toleranceBps))),
+	}
+	if over := received.Sub(requested); over.IsPositive() {
+		m.excess = decimal.Min(over, amount)
+	}
+	return m
+}
@@ -0,0 +1,58 @@
+package watcher
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+func TestMatchAmount(t *testing.T) {
+	testCases := []struct {
+		name         string
+		earlier      string
+		amount       string
+		toleranceBps int64
+		funded       bool
+		excess       string
+	}{
+		{"exact", "0", "100", 0, true, "0"},
+		{"one unit short", "0", "99.999999", 0, false, "0"},
+		{"at tolerance", "0", "99.5", 50, true, "0"},
+		{"just below tolerance", "0", "99.499999", 50, false, "0"},
+		{"one unit over", "0", "100.000001", 0, true, "0.000001"},
+		{"partial", "40", "59.9", 0, false, "0"},
+		{"top-up completes", "60", "40", 0, true, "0"},
+		{"top-up overshoots", "60", "50", 0, true, "10"},
+		{"already funded", "120", "5", 0, true, "5"},
+	}
+
+	requested := decimal.RequireFromString("100")
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			earlier := decimal.RequireFromString(tc.earlier)
+			amount := decimal.RequireFromString(tc.amount)
+
+			m := matchAmount(requested, earlier, amount, tc.toleranceBps)
+
+			assert.Equal(t, tc.funded, m.funded)
+			assert.True(t, earlier.Add(amount).Equal(m.received), "received %s", m.received)
+			assert.True(t, decimal.RequireFromString(tc.excess).Equal(m.excess), "excess %s", m.excess)
+		})
+	}
+}
+
+func TestNumericToDecimal(t *testing.T) {
+	assert.True(t, repository.NumericToDecimal(pgtype.Numeric{}).IsZero(), "NULL is zero")
+
+	n := pgtype.Numeric{Int: big.NewInt(99900000), Exp: -6, Valid: true}
+	d := repository.NumericToDecimal(n)
+	assert.Equal(t, "99.900000", d.StringFixed(6))
+
+	back := repository.DecimalToNumeric(d)
+	assert.True(t, back.Valid)
+	assert.Equal(t, "99.900000", repository.NumericToDecimal(back).StringFixed(6))
+}
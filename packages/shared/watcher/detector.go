@@ -0,0 +1,307 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/events"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/outbox"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/webhooks"
+)
+
+// EventPaymentDetected notifies the merchant that a transfer to the payment
+// is on its way. It is a hint for the checkout page, not a settlement.
+const EventPaymentDetected = webhooks.EventPaymentDetected
+
+// PendingChain is the subset of *tron.Client the detector reads the pending
+// pool through.
+type PendingChain interface {
+	GetPendingTransactionIDs(ctx context.Context) ([]string, error)
+	GetPendingTransaction(ctx context.Context, txID string) (*tron.Transaction, error)
+}
+
+// DetectorStore is the subset of *repository.Store the detector writes
+// through.
+type DetectorStore interface {
+	ListRecentPendingPayments(ctx context.Context, arg repository.ListRecentPendingPaymentsParams) ([]repository.Payment, error)
+	ListPaymentAttempts(ctx context.Context, paymentIds []uuid.UUID) ([]repository.PaymentAttempt, error)
+	ListDepositWallets(ctx context.Context, wallets []string) ([]string, error)
+	ExecTx(ctx context.Context, fn func(repository.Querier) error) error
+}
+
+// Detector watches the pending pool for transfers to the wallets of recently
+// created PENDING payments and marks those payments DETECTED, so a checkout
+// page can show the transfer seconds after it was sent.
+//
+// It records nothing else: the Watcher credits the transfer once it is in a
+// block and the ConfirmationTracker confirms it from there. A detected
+// transfer that is never mined leaves the payment DETECTED until it expires.
+type Detector struct {
+	chain   PendingChain
+	store   DetectorStore
+	metrics Metrics
+	bus     events.EventPublisher
+	logger  *slog.Logger
+	tokens  tokenFilter
+	mode    string
+
+	window       time.Duration
+	pollInterval time.Duration
+	now          func() time.Time
+
+	// seen holds the pending transactions already inspected. It is pruned to
+	// the pool's contents on every poll, so only new arrivals are fetched.
+	seen map[string]bool
+}
+
+// DetectorOption customises a Detector.
+type DetectorOption func(*Detector)
+
+// WithDetectorLogger replaces slog.Default.
+func WithDetectorLogger(l *slog.Logger) DetectorOption {
+	return func(d *Detector) { d.logger = l }
+}
+
+// NewDetector reads its window and interval from blockWatcher.zeroConf and
+// the accepted tokens from the tron and payments sections of cfg. It only
+// watches the payments of cfg's mode.
+func NewDetector(chain PendingChain, store DetectorStore, cfg *config.Config, opts ...DetectorOption) *Detector {
+	d := &Detector{
+		chain:        chain,
+		store:        store,
+		metrics:      nopMetrics{},
+		logger:       slog.Default(),
+		tokens:       newTokenFilter(cfg),
+		mode:         cfg.Mode(),
+		window:       cfg.BlockWatcher.ZeroConf.Window.Std(),
+		pollInterval: cfg.BlockWatcher.ZeroConf.PollInterval.Std(),
+		now:          time.Now,
+		seen:         make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Run polls the pending pool until ctx is done.
+func (d *Detector) Run(ctx context.Context) error {
+	d.logger.Info("pending pool detector started", "window", d.window, "poll_interval", d.pollInterval)
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Poll(ctx); err != nil && ctx.Err() == nil {
+			d.logger.ErrorContext(ctx, "pending pool poll failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			d.logger.Info("pending pool detector stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll inspects the transactions that entered the pending pool since the
+// last poll. The pool is not read at all while no payment is waiting. A
+// transaction that fails to process is retried on the next poll; all
+// failures are returned joined.
+func (d *Detector) Poll(ctx context.Context) error {
+	payments, err := d.store.ListRecentPendingPayments(ctx, repository.ListRecentPendingPaymentsParams{
+		CreatedAfter: pgtype.Timestamptz{Time: d.now().Add(-d.window), Valid: true},
+		Mode:         d.mode,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list pending payments: %w", err)
+	}
+	if len(payments) == 0 {
+		return nil
+	}
+	wallets, err := d.watchedWallets(ctx, payments)
+	if err != nil {
+		return err
+	}
+
+	ids, err := d.chain.GetPendingTransactionIDs(ctx)
+	if err != nil {
+		return err
+	}
+	inPool := make(map[string]bool, len(ids))
+	var errs []error
+	for _, id := range ids {
+		inPool[id] = true
+		if d.seen[id] {
+			continue
+		}
+		if err := d.inspect(ctx, id, wallets); err != nil {
+			errs = append(errs, fmt.Errorf("pending transaction %s: %w", id, err))
+			continue
+		}
+		d.seen[id] = true
+	}
+	for id := range d.seen {
+		if !inPool[id] {
+			delete(d.seen, id)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// watchedWallets maps the wallets of payments to their payment. A payment
+// whose wallet was regenerated is watched on its earlier wallets too. The
+// payments sharing the deposit wallet of an amount_suffix account are keyed
+// by wallet and amount, see sharedKey.
+func (d *Detector) watchedWallets(ctx context.Context, payments []repository.Payment) (map[string]repository.Payment, error) {
+	unique := make([]string, 0, len(payments))
+	for _, p := range payments {
+		unique = append(unique, p.UniqueWallet)
+	}
+	deposit, err := d.store.ListDepositWallets(ctx, unique)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deposit wallets: %w", err)
+	}
+	shared := make(map[string]bool, len(deposit))
+	for _, w := range deposit {
+		shared[w] = true
+	}
+
+	wallets := make(map[string]repository.Payment, len(payments))
+	byID := make(map[uuid.UUID]repository.Payment)
+	for _, p := range payments {
+		if shared[p.UniqueWallet] {
+			decimals := tokenDecimals(p.Token)
+			wallets[sharedKey(p.UniqueWallet, repository.NumericToDecimal(p.Amount).StringFixed(decimals))] = p
+			continue
+		}
+		wallets[p.UniqueWallet] = p
+		if p.AttemptCount != nil && *p.AttemptCount > 1 {
+			byID[p.ID] = p
+		}
+	}
+	if len(byID) == 0 {
+		return wallets, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	attempts, err := d.store.ListPaymentAttempts(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payment attempts: %w", err)
+	}
+	for _, a := range attempts {
+		wallets[a.GeneratedWallet] = byID[a.PaymentID]
+	}
+	return wallets, nil
+}
+
+// inspect marks the payments that the pending transaction id pays. Matched
+// wallets are removed from wallets so a payment is detected once per poll.
+func (d *Detector) inspect(ctx context.Context, id string, wallets map[string]repository.Payment) error {
+	tx, err := d.chain.GetPendingTransaction(ctx, id)
+	if errors.Is(err, tron.ErrTransactionNotFound) {
+		// Mined or dropped since it was listed.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, t := range tx.Transfers() {
+		token, ok := d.tokens.token(t)
+		if !ok {
+			continue
+		}
+		key := t.To
+		payment, ok := wallets[key]
+		if !ok {
+			key = sharedKey(t.To, formatAmount(t.Amount, token))
+			payment, ok = wallets[key]
+		}
+		if !ok || token != payment.Token {
+			continue
+		}
+		if err := d.detect(ctx, payment, token, t); err != nil {
+			return err
+		}
+		delete(wallets, key)
+	}
+	return nil
+}
+
+// sharedKey keys a payment paid into a shared deposit wallet by the wallet
+// and its amount, formatted to the token's decimals.
+func sharedKey(wallet, amount string) string {
+	return wallet + " " + amount
+}
+
+// detect moves payment from PENDING to DETECTED, writing its TX_DETECTED
+// log and its payment.detected event in the same transaction. A payment that
+// moved on meanwhile, e.g. because the transfer was already mined and
+// credited, is left alone.
+func (d *Detector) detect(ctx context.Context, payment repository.Payment, token string, t tron.Transfer) error {
+	amount := formatAmount(t.Amount, token)
+	_, err := outbox.Transition(ctx, d.store, d.bus, EventPaymentDetected, d.now(), func(q repository.Querier) (repository.Payment, error) {
+		current, err := q.GetPayment(ctx, payment.ID)
+		if err != nil {
+			return current, fmt.Errorf("failed to load payment: %w", err)
+		}
+		updated, err := q.UpdatePaymentStatus(ctx, repository.UpdatePaymentStatusParams{
+			ToStatus:   statusDetected,
+			ID:         payment.ID,
+			FromStatus: statusPending,
+			Version:    current.Version,
+		})
+		if err != nil {
+			return updated, err
+		}
+		return updated, d.log(ctx, q, payment, EventTxDetected,
+			fmt.Sprintf("%s %s seen in the pending pool", amount, token),
+			pendingLog{TxHash: t.TxID, Token: token, Amount: amount, From: t.From, To: t.To, Pending: true})
+	})
+	if errors.Is(err, repository.ErrPaymentStatusChanged) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to mark payment detected: %w", err)
+	}
+	d.metrics.PaymentTransition(statusDetected)
+	d.logger.Info("pending payment transfer detected", "payment_id", payment.ID, "tx_hash", t.TxID, "token", token)
+	return nil
+}
+
+type pendingLog struct {
+	TxHash  string `json:"tx_hash"`
+	Token   string `json:"token"`
+	Amount  string `json:"amount"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Pending bool   `json:"pending"`
+}
+
+func (d *Detector) log(ctx context.Context, w logWriter, payment repository.Payment, event, msg string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode log: %w", err)
+	}
+	err = w.CreateLog(ctx, repository.CreateLogParams{
+		PaymentID: repository.UUIDPtr(payment.ID),
+		EventType: event,
+		Message:   &msg,
+		RawData:   raw,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %s log: %w", event, err)
+	}
+	return nil
+}
@@ -0,0 +1,386 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron/fixtures"
+)
+
+const pendingSender = "TKTX96CBxr5kvhjsDHcqoiPWZageGxoTW3"
+
+func (s *memStore) ListRecentPendingPayments(_ context.Context, arg repository.ListRecentPendingPaymentsParams) ([]repository.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []repository.Payment
+	for _, p := range s.payments {
+		if p.Status == statusPending && !p.CreatedAt.Time.Before(arg.CreatedAfter.Time) && paymentMode(p) == arg.Mode {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (s *memStore) ListPaymentAttempts(_ context.Context, paymentIds []uuid.UUID) ([]repository.PaymentAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []repository.PaymentAttempt
+	for _, a := range s.attempts {
+		if slices.Contains(paymentIds, a.PaymentID) {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+// fakePool is a pending pool.
+type fakePool struct {
+	mu      sync.Mutex
+	txs     map[string]*tron.Transaction
+	order   []string
+	fetches map[string]int
+	failTx  error
+}
+
+func newFakePool() *fakePool {
+	return &fakePool{txs: make(map[string]*tron.Transaction), fetches: make(map[string]int)}
+}
+
+func (p *fakePool) add(tx *tron.Transaction) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.txs[tx.TxID] = tx
+	p.order = append(p.order, tx.TxID)
+}
+
+// mine removes txID from the pool.
+func (p *fakePool) mine(txID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.txs, txID)
+}
+
+func (p *fakePool) GetPendingTransactionIDs(context.Context) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var ids []string
+	for _, id := range p.order {
+		if _, ok := p.txs[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (p *fakePool) GetPendingTransaction(_ context.Context, txID string) (*tron.Transaction, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fetches[txID]++
+	if p.failTx != nil {
+		return nil, p.failTx
+	}
+	tx, ok := p.txs[txID]
+	if !ok {
+		return nil, tron.ErrTransactionNotFound
+	}
+	return tx, nil
+}
+
+var pendingRef = tron.BlockRef{Number: 1000, ID: strings.Repeat("ab", 32), Timestamp: 1700000000000}
+
+func pendingTRX(t *testing.T, to string, sun int64) *tron.Transaction {
+	t.Helper()
+	tx, err := tron.BuildTRXTransfer(pendingSender, to, sun, pendingRef, time.Minute)
+	require.NoError(t, err)
+	return tx
+}
+
+func newTestDetector(pool *fakePool, store *memStore) *Detector {
+	cfg := testConfig()
+	cfg.BlockWatcher.ZeroConf.Enabled = true
+	return NewDetector(pool, store, cfg)
+}
+
+// recentPayment adds a PENDING payment for wallet created just now.
+func recentPayment(store *memStore, wallet string) repository.Payment {
+	p := store.addPayment(wallet, statusPending)
+	p.CreatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	store.payments[wallet] = p
+	return p
+}
+
+func TestDetector_MarksPaymentDetected(t *testing.T) {
+	pool := newFakePool()
+	store := newMemStore()
+	payment := recentPayment(store, trxWallet)
+	tx := pendingTRX(t, trxWallet, 2500000)
+	pool.add(tx)
+
+	require.NoError(t, newTestDetector(pool, store).Poll(context.Background()))
+
+	assert.Equal(t, statusDetected, store.payment(trxWallet).Status)
+	assert.Equal(t, []string{EventPaymentDetected}, store.outboxTypes())
+	require.Len(t, store.logs, 1)
+	assert.Equal(t, EventTxDetected, store.logs[0].EventType)
+	assert.Equal(t, payment.ID, *store.logs[0].PaymentID)
+	var data pendingLog
+	require.NoError(t, json.Unmarshal(store.logs[0].RawData, &data))
+	assert.Equal(t, pendingLog{TxHash: tx.TxID, Token: "TRX", Amount: "2.500000", From: pendingSender, To: trxWallet, Pending: true}, data)
+	assert.Empty(t, store.txs, "the transfer is only recorded once mined")
+}
+
+func TestDetector_RecordedScenario(t *testing.T) {
+	scenario, err := fixtures.Load(fixtures.ScenarioTRXPayment)
+	require.NoError(t, err)
+	chain := fixtures.NewFixtureClient(scenario)
+	// The TRX transfer is pending while the chain is one block short of it.
+	chain.SetHead(scenario.First())
+	store := newMemStore()
+	recentPayment(store, trxWallet)
+	cfg := testConfig()
+	cfg.BlockWatcher.ZeroConf.Enabled = true
+
+	require.NoError(t, NewDetector(chain, store, cfg).Poll(context.Background()))
+
+	assert.Equal(t, statusDetected, store.payment(trxWallet).Status)
+	assert.Equal(t, []string{EventPaymentDetected}, store.outboxTypes())
+}
+
+func TestDetector_RegeneratedWallet(t *testing.T) {
+	testCases := []struct {
+		name, to string
+	}{
+		{"old wallet", trxWallet},
+		{"new wallet", regeneratedWallet},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pool := newFakePool()
+			store := newMemStore()
+			recentPayment(store, trxWallet)
+			store.regenerate(trxWallet, regeneratedWallet)
+			pool.add(pendingTRX(t, tc.to, 2500000))
+
+			require.NoError(t, newTestDetector(pool, store).Poll(context.Background()))
+
+			assert.Equal(t, statusDetected, store.payment(regeneratedWallet).Status)
+			require.Len(t, store.logs, 1)
+		})
+	}
+}
+
+func TestDetector_USDT(t *testing.T) {
+	pool := newFakePool()
+	store := newMemStore()
+	recentPayment(store, usdtWallet)
+	cfg := testConfig()
+	tx, err := tron.BuildTRC20Transfer(pendingSender, cfg.Tron.USDTContract, usdtWallet, big.NewInt(100_000000), 10_000000, pendingRef)
+	require.NoError(t, err)
+	pool.add(tx)
+
+	require.NoError(t, NewDetector(pool, store, cfg).Poll(context.Background()))
+
+	assert.Equal(t, statusDetected, store.payment(usdtWallet).Status)
+}
+
+func TestDetector_IgnoresOtherTransfers(t *testing.T) {
+	pool := newFakePool()
+	store := newMemStore()
+	recentPayment(store, trxWallet)
+	pool.add(pendingTRX(t, usdtWallet, 2500000))
+	cfg := testConfig()
+	unknown, err := tron.BuildTRC20Transfer(pendingSender, "TXYZopYRdj2D9XRtbG411XZZ3kM5VkAeBf", trxWallet, big.NewInt(1), 10_000000, pendingRef)
+	require.NoError(t, err)
+	pool.add(unknown)
+
+	require.NoError(t, NewDetector(pool, store, cfg).Poll(context.Background()))
+
+	assert.Equal(t, statusPending, store.payment(trxWallet).Status)
+	assert.Empty(t, store.logs)
+}
+
+func TestDetector_IgnoresOtherToken(t *testing.T) {
+	pool := newFakePool()
+	store := newMemStore()
+	p := recentPayment(store, trxWallet)
+	p.Token = "USDT"
+	store.payments[trxWallet] = p
+	pool.add(pendingTRX(t, trxWallet, 2500000))
+
+	require.NoError(t, newTestDetector(pool, store).Poll(context.Background()))
+
+	assert.Equal(t, statusPending, store.payment(trxWallet).Status, "a TRX transfer does not pay a USDT payment")
+	assert.Empty(t, store.logs)
+}
+
+func TestDetector_OnlyRecentPayments(t *testing.T) {
+	pool := newFakePool()
+	store := newMemStore()
+	p := store.addPayment(trxWallet, statusPending)
+	p.CreatedAt = pgtype.Timestamptz{Time: time.Now().Add(-2 * time.Hour), Valid: true}
+	store.payments[trxWallet] = p
+	pool.add(pendingTRX(t, trxWallet, 2500000))
+
+	require.NoError(t, newTestDetector(pool, store).Poll(context.Background()))
+
+	assert.Equal(t, statusPending, store.payment(trxWallet).Status)
+	assert.Empty(t, pool.fetches, "the pool is not read without a waiting payment")
+}
+
+func TestDetector_FetchesEachTransactionOnce(t *testing.T) {
+	pool := newFakePool()
+	store := newMemStore()
+	recentPayment(store, trxWallet)
+	other := pendingTRX(t, usdtWallet, 1)
+	pool.add(other)
+	detector := newTestDetector(pool, store)
+	ctx := context.Background()
+
+	require.NoError(t, detector.Poll(ctx))
+	require.NoError(t, detector.Poll(ctx))
+	assert.Equal(t, 1, pool.fetches[other.TxID])
+
+	// Once it leaves the pool it is forgotten.
+	pool.mine(other.TxID)
+	require.NoError(t, detector.Poll(ctx))
+	assert.Empty(t, detector.seen)
+}
+
+func TestDetector_LeavesMovedOnPaymentsAlone(t *testing.T) {
+	pool := newFakePool()
+	store := newMemStore()
+	recentPayment(store, trxWallet)
+	pool.add(pendingTRX(t, trxWallet, 2500000))
+	detector := newTestDetector(pool, store)
+	// The block watcher credits the transfer between the list and the update.
+	detector.store = &racingStore{memStore: store, wallet: trxWallet, status: statusUnderpaid}
+
+	require.NoError(t, detector.Poll(context.Background()))
+
+	assert.Equal(t, statusUnderpaid, store.payment(trxWallet).Status)
+	assert.Empty(t, store.logs)
+}
+
+func (r *racingStore) ListRecentPendingPayments(ctx context.Context, arg repository.ListRecentPendingPaymentsParams) ([]repository.Payment, error) {
+	out, err := r.memStore.ListRecentPendingPayments(ctx, arg)
+	r.mu.Lock()
+	p := r.payments[r.wallet]
+	p.Status = r.status
+	r.payments[r.wallet] = p
+	r.mu.Unlock()
+	return out, err
+}
+
+func TestDetector_FailedFetchIsRetried(t *testing.T) {
+	pool := newFakePool()
+	store := newMemStore()
+	recentPayment(store, trxWallet)
+	tx := pendingTRX(t, trxWallet, 2500000)
+	pool.add(tx)
+	pool.failTx = errors.New("node unavailable")
+	detector := newTestDetector(pool, store)
+	ctx := context.Background()
+
+	err := detector.Poll(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "node unavailable")
+
+	pool.failTx = nil
+	require.NoError(t, detector.Poll(ctx))
+	assert.Equal(t, statusDetected, store.payment(trxWallet).Status)
+}
+
+func TestDetector_OutboxFailureIsRetried(t *testing.T) {
+	pool := newFakePool()
+	store := newMemStore()
+	recentPayment(store, trxWallet)
+	tx := pendingTRX(t, trxWallet, 2500000)
+	pool.add(tx)
+	store.failOutbox = errors.New("connection reset")
+	detector := newTestDetector(pool, store)
+	ctx := context.Background()
+
+	err := detector.Poll(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "connection reset")
+	assert.Equal(t, statusPending, store.payment(trxWallet).Status, "the detection is rolled back")
+	assert.Empty(t, store.logs)
+
+	store.failOutbox = nil
+	require.NoError(t, detector.Poll(ctx))
+	assert.Equal(t, statusDetected, store.payment(trxWallet).Status)
+	assert.Equal(t, []string{EventPaymentDetected}, store.outboxTypes())
+}
+
+func TestDetector_DetectedPaymentIsCreditedAndConfirmed(t *testing.T) {
+	pool := newFakePool()
+	store := newMemStore()
+	recentPayment(store, trxWallet)
+	pool.add(pendingTRX(t, trxWallet, 2500000))
+	require.NoError(t, newTestDetector(pool, store).Poll(context.Background()))
+	require.Equal(t, statusDetected, store.payment(trxWallet).Status)
+
+	// The transfer is mined: the block flow takes over from DETECTED.
+	chain := newFakeChain(1000)
+	chain.blocks[1000] = loadBlock(t, "block_transfers.json", 1000)
+	chain.receipts[1000] = loadReceipts(t, "txinfo_transfers.json", 1000)
+	store.heights[DefaultName] = 999
+	_, err := New(chain, store, testConfig()).Poll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, store.txs, 1)
+	assert.Equal(t, statusDetected, store.payment(trxWallet).Status, "DETECTED is kept until confirmation")
+
+	chain.setHead(1010)
+	require.NoError(t, newTestTracker(chain, store, 3).Advance(context.Background(), 1010))
+	assert.Equal(t, "CONFIRMED", store.payment(trxWallet).Status)
+	assert.Equal(t, []string{EventPaymentDetected, EventPaymentConfirmed}, store.outboxTypes())
+}
+
+func TestDetector_DetectedPaymentCanBeUnderpaid(t *testing.T) {
+	chain := newFakeChain(1000)
+	chain.blocks[1000] = loadBlock(t, "block_transfers.json", 1000)
+	store := newMemStore()
+	store.addPaymentFor(trxWallet, statusDetected, "10")
+	store.heights[DefaultName] = 999
+
+	_, err := New(chain, store, testConfig()).Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, statusUnderpaid, store.payment(trxWallet).Status)
+}
+
+func TestDetector_SharedWalletMatchesAmount(t *testing.T) {
+	pool := newFakePool()
+	store := newMemStore()
+	var payments []repository.Payment
+	for _, amount := range []string{"2.500001", "2.500002"} {
+		p := store.addSharedPayment(trxWallet, amount)
+		p.CreatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+		store.payments[trxWallet+" "+amount] = p
+		payments = append(payments, p)
+	}
+	pool.add(pendingTRX(t, trxWallet, 2500002))
+	pool.add(pendingTRX(t, trxWallet, 2500003))
+
+	require.NoError(t, newTestDetector(pool, store).Poll(context.Background()))
+
+	assert.Equal(t, statusPending, store.payment(trxWallet+" 2.500001").Status)
+	assert.Equal(t, statusDetected, store.payment(trxWallet+" 2.500002").Status,
+		"only the payment asking for exactly the amount is detected")
+	require.Len(t, store.logs, 1)
+	assert.Equal(t, payments[1].ID, *store.logs[0].PaymentID)
+}
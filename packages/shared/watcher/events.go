@@ -0,0 +1,19 @@
+package watcher
+
+import "github.com/yaninyzwitty/tron-payment-gateway/packages/shared/events"
+
+// WithTrackerEvents publishes the payments the tracker confirms to bus.
+func WithTrackerEvents(bus events.EventPublisher) TrackerOption {
+	return func(t *ConfirmationTracker) { t.bus = bus }
+}
+
+// WithDetectorEvents publishes the payments the detector marks DETECTED to
+// bus.
+func WithDetectorEvents(bus events.EventPublisher) DetectorOption {
+	return func(d *Detector) { d.bus = bus }
+}
+
+// WithExpirerEvents publishes the payments the expirer expires to bus.
+func WithExpirerEvents(bus events.EventPublisher) ExpirerOption {
+	return func(e *Expirer) { e.bus = bus }
+}
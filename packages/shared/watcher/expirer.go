@@ -0,0 +1,175 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/events"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/outbox"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/webhooks"
+)
+
+// EventExpired is logged when a payment expires.
+const EventExpired = "PAYMENT_EXPIRED"
+
+// EventPaymentExpired notifies the merchant that a payment expired unpaid.
+const EventPaymentExpired = webhooks.EventPaymentExpired
+
+const statusExpired = "EXPIRED"
+
+// expiryGrace holds a payment open past its deadline for about 20 blocks, so
+// a watcher running slightly behind still credits a transfer mined just
+// before it.
+const expiryGrace = time.Minute
+
+// ExpirerStore is the subset of *repository.Store the expirer writes
+// through.
+type ExpirerStore interface {
+	ListExpiredPayments(ctx context.Context, arg repository.ListExpiredPaymentsParams) ([]repository.Payment, error)
+	ExecTx(ctx context.Context, fn func(repository.Querier) error) error
+}
+
+// Expirer moves PENDING, DETECTED and UNDERPAID payments past their deadline
+// to EXPIRED. The status they expired from is kept in the PAYMENT_EXPIRED
+// log, so a payment whose transfer was seen in the pending pool but never
+// mined stays recognisable.
+type Expirer struct {
+	store   ExpirerStore
+	metrics Metrics
+	bus     events.EventPublisher
+	logger  *slog.Logger
+
+	interval  time.Duration
+	batchSize int32
+	mode      string
+	now       func() time.Time
+}
+
+// ExpirerOption customises an Expirer.
+type ExpirerOption func(*Expirer)
+
+// WithExpirerLogger replaces slog.Default.
+func WithExpirerLogger(l *slog.Logger) ExpirerOption {
+	return func(e *Expirer) { e.logger = l }
+}
+
+// NewExpirer runs at the blockWatcher poll interval and expires at most
+// blockWatcher.batchSize payments per run, all of them of cfg's mode.
+func NewExpirer(store ExpirerStore, cfg *config.Config, opts ...ExpirerOption) *Expirer {
+	e := &Expirer{
+		store:     store,
+		metrics:   nopMetrics{},
+		logger:    slog.Default(),
+		interval:  cfg.BlockWatcher.PollInterval.Std(),
+		batchSize: int32(cfg.BlockWatcher.BatchSize),
+		mode:      cfg.Mode(),
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Run expires payments until ctx is done.
+func (e *Expirer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := e.ExpireOnce(ctx); err != nil && ctx.Err() == nil {
+			e.logger.ErrorContext(ctx, "payment expiry failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExpireOnce expires one batch of overdue payments and returns how many it
+// expired. A payment that changed status meanwhile is skipped.
+func (e *Expirer) ExpireOnce(ctx context.Context) (int, error) {
+	payments, err := e.store.ListExpiredPayments(ctx, repository.ListExpiredPaymentsParams{
+		ExpiredBefore: pgtype.Timestamptz{Time: e.now().Add(-expiryGrace), Valid: true},
+		Mode:          e.mode,
+		Limit:         e.batchSize,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired payments: %w", err)
+	}
+
+	expired := 0
+	var errs []error
+	for _, p := range payments {
+		ok, err := e.expire(ctx, p)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("payment %s: %w", p.ID, err))
+		}
+		if ok {
+			expired++
+		}
+	}
+	return expired, errors.Join(errs...)
+}
+
+type expiredLog struct {
+	PreviousStatus string    `json:"previous_status"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// expire moves payment to EXPIRED, writing its PAYMENT_EXPIRED log and its
+// payment.expired event in the same transaction.
+func (e *Expirer) expire(ctx context.Context, payment repository.Payment) (bool, error) {
+	msg := fmt.Sprintf("expired while %s", payment.Status)
+	if payment.Status == statusDetected {
+		msg += "; a transfer was seen in the pending pool but never mined"
+	}
+	raw, err := json.Marshal(expiredLog{PreviousStatus: payment.Status, ExpiresAt: payment.ExpiresAt.Time})
+	if err != nil {
+		return false, fmt.Errorf("failed to encode log: %w", err)
+	}
+
+	_, err = outbox.Transition(ctx, e.store, e.bus, EventPaymentExpired, e.now(), func(q repository.Querier) (repository.Payment, error) {
+		current, err := q.GetPayment(ctx, payment.ID)
+		if err != nil {
+			return current, fmt.Errorf("failed to load payment: %w", err)
+		}
+		updated, err := q.UpdatePaymentStatus(ctx, repository.UpdatePaymentStatusParams{
+			ToStatus:   statusExpired,
+			ID:         payment.ID,
+			FromStatus: payment.Status,
+			Version:    current.Version,
+		})
+		if err != nil {
+			return updated, err
+		}
+		err = q.CreateLog(ctx, repository.CreateLogParams{
+			PaymentID: repository.UUIDPtr(payment.ID),
+			EventType: EventExpired,
+			Message:   &msg,
+			RawData:   raw,
+		})
+		if err != nil {
+			return updated, fmt.Errorf("failed to write %s log: %w", EventExpired, err)
+		}
+		return updated, nil
+	})
+	if errors.Is(err, repository.ErrPaymentStatusChanged) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to expire payment: %w", err)
+	}
+	e.metrics.PaymentTransition(statusExpired)
+	e.logger.Info("payment expired", "payment_id", payment.ID, "previous_status", payment.Status)
+	return true, nil
+}
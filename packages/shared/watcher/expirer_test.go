@@ -0,0 +1,173 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/events"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+func (s *memStore) ListExpiredPayments(_ context.Context, arg repository.ListExpiredPaymentsParams) ([]repository.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []repository.Payment
+	for _, p := range s.payments {
+		if repository.IsTerminalPaymentStatus(p.Status) || p.ExpiresAt.Time.After(arg.ExpiredBefore.Time) || paymentMode(p) != arg.Mode {
+			continue
+		}
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ExpiresAt.Time.Before(out[j].ExpiresAt.Time) })
+	if len(out) > int(arg.Limit) {
+		out = out[:arg.Limit]
+	}
+	return out, nil
+}
+
+func expiringPayment(store *memStore, wallet, status string, expiresAt time.Time) repository.Payment {
+	p := store.addPaymentFor(wallet, status, "1")
+	p.ExpiresAt = pgtype.Timestamptz{Time: expiresAt, Valid: true}
+	store.payments[wallet] = p
+	return p
+}
+
+func newTestExpirer(store *memStore, now time.Time) *Expirer {
+	e := NewExpirer(store, testConfig())
+	e.now = func() time.Time { return now }
+	return e
+}
+
+func TestExpirer_ExpiresDetectedPayment(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := newMemStore()
+	expiresAt := now.Add(-2 * time.Minute)
+	payment := expiringPayment(store, trxWallet, statusDetected, expiresAt)
+
+	n, err := newTestExpirer(store, now).ExpireOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, statusExpired, store.payment(trxWallet).Status)
+	assert.Equal(t, []string{EventPaymentExpired}, store.outboxTypes())
+	require.Len(t, store.logs, 1)
+	assert.Equal(t, EventExpired, store.logs[0].EventType)
+	assert.Equal(t, payment.ID, *store.logs[0].PaymentID)
+	assert.Contains(t, *store.logs[0].Message, "seen in the pending pool")
+	var data expiredLog
+	require.NoError(t, json.Unmarshal(store.logs[0].RawData, &data))
+	assert.Equal(t, statusDetected, data.PreviousStatus, "the detection is recorded")
+	assert.True(t, expiresAt.Equal(data.ExpiresAt))
+}
+
+func TestExpirer_PublishesExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := newMemStore()
+	payment := expiringPayment(store, trxWallet, statusPending, now.Add(-time.Hour))
+	bus := events.NewTypedBus()
+	ch, cancel := bus.Subscribe(EventPaymentExpired)
+	defer cancel()
+	e := newTestExpirer(store, now)
+	WithExpirerEvents(bus)(e)
+
+	_, err := e.ExpireOnce(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, ch, 1)
+	assert.Equal(t, events.Event{PaymentID: payment.ID, Type: EventPaymentExpired, Status: statusExpired, At: now.UTC()}, <-ch)
+}
+
+func TestExpirer_OpenStatuses(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := newMemStore()
+	expiringPayment(store, trxWallet, statusPending, now.Add(-2*time.Hour))
+	expiringPayment(store, usdtWallet, statusUnderpaid, now.Add(-time.Hour))
+	expiringPayment(store, "TConfirmedWallet", "CONFIRMED", now.Add(-time.Hour))
+
+	n, err := newTestExpirer(store, now).ExpireOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, statusExpired, store.payment(trxWallet).Status)
+	assert.Equal(t, statusExpired, store.payment(usdtWallet).Status)
+	assert.Equal(t, "CONFIRMED", store.payment("TConfirmedWallet").Status)
+	assert.Equal(t, "expired while PENDING", *store.logs[0].Message)
+}
+
+func TestExpirer_KeepsToItsMode(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := newMemStore()
+	expiringPayment(store, trxWallet, statusPending, now.Add(-time.Hour))
+	test := expiringPayment(store, usdtWallet, statusPending, now.Add(-time.Hour))
+	test.Mode = config.ModeTest
+	store.payments[usdtWallet] = test
+	e := NewExpirer(store, testConfig().ForTestMode())
+	e.now = func() time.Time { return now }
+
+	n, err := e.ExpireOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, statusExpired, store.payment(usdtWallet).Status)
+	assert.Equal(t, statusPending, store.payment(trxWallet).Status)
+}
+
+func TestExpirer_Grace(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := newMemStore()
+	expiringPayment(store, trxWallet, statusDetected, now.Add(-expiryGrace/2))
+
+	n, err := newTestExpirer(store, now).ExpireOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Equal(t, statusDetected, store.payment(trxWallet).Status, "a late block may still credit it")
+}
+
+func TestExpirer_SkipsPaymentsThatMovedOn(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := newMemStore()
+	p := expiringPayment(store, trxWallet, statusDetected, now.Add(-time.Hour))
+	// Confirmed after it was listed.
+	stale := p
+	p.Status = "CONFIRMED"
+	store.payments[trxWallet] = p
+	e := newTestExpirer(store, now)
+
+	ok, err := e.expire(context.Background(), stale)
+
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "CONFIRMED", store.payment(trxWallet).Status)
+	assert.Empty(t, store.logs)
+}
+
+func TestExpirer_OutboxFailureRollsBack(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := newMemStore()
+	expiringPayment(store, trxWallet, statusPending, now.Add(-time.Hour))
+	store.failOutbox = errors.New("connection reset")
+	e := newTestExpirer(store, now)
+
+	n, err := e.ExpireOnce(context.Background())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "connection reset")
+	assert.Zero(t, n)
+	assert.Equal(t, statusPending, store.payment(trxWallet).Status)
+	assert.Empty(t, store.logs)
+
+	store.failOutbox = nil
+	n, err = e.ExpireOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{EventPaymentExpired}, store.outboxTypes())
+}
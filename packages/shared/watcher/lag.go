@@ -0,0 +1,67 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrLagging is returned by Ready when the watcher has fallen too far behind
+// the chain head.
+var ErrLagging = errors.New("watcher is lagging behind the chain")
+
+// LagStatus is how far the watcher is behind the chain, as reported by the
+// readiness probe.
+type LagStatus struct {
+	ChainHeight     int64 `json:"chain_height"`
+	ProcessedHeight int64 `json:"processed_height"`
+	Lag             int64 `json:"lag"`
+	MaxLag          int64 `json:"max_lag"`
+}
+
+// Lag returns how many blocks the chain head is ahead of the last block the
+// watcher processed.
+func (w *Watcher) Lag(ctx context.Context) (int64, error) {
+	status, err := w.lag(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return status.Lag, nil
+}
+
+// Ready reports the watcher's lag and fails with ErrLagging once it exceeds
+// blockWatcher.maxLag, so a readiness probe shifts traffic to a replica that
+// is keeping up. A watcher that has not processed a block yet is not ready.
+func (w *Watcher) Ready(ctx context.Context) (any, error) {
+	status, err := w.lag(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if status.Lag > status.MaxLag {
+		return status, fmt.Errorf("%w: %d blocks behind, at most %d allowed", ErrLagging, status.Lag, status.MaxLag)
+	}
+	return status, nil
+}
+
+func (w *Watcher) lag(ctx context.Context) (LagStatus, error) {
+	row, err := w.store.GetWatcherState(ctx, w.name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return LagStatus{}, errors.New("watcher has not processed a block yet")
+	}
+	if err != nil {
+		return LagStatus{}, fmt.Errorf("failed to load watcher state: %w", err)
+	}
+	head, err := w.chain.GetChainHeight(ctx)
+	if err != nil {
+		return LagStatus{}, err
+	}
+	return LagStatus{
+		ChainHeight:     head,
+		ProcessedHeight: row.LastHeight,
+		// A node behind the one the watcher read from last is not lag.
+		Lag:    max(head-row.LastHeight, 0),
+		MaxLag: w.maxLag,
+	}, nil
+}
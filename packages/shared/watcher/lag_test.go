@@ -0,0 +1,99 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher_Lag(t *testing.T) {
+	testCases := []struct {
+		name      string
+		head      int64
+		processed int64
+		want      int64
+	}{
+		{"caught up", 1000, 1000, 0},
+		{"behind", 1250, 1000, 250},
+		// The node answering GetChainHeight trails the one blocks came from.
+		{"node behind the watcher", 995, 1000, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newMemStore()
+			store.heights[DefaultName] = tc.processed
+			w := New(newFakeChain(tc.head), store, testConfig())
+
+			lag, err := w.Lag(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, lag)
+		})
+	}
+}
+
+func TestWatcher_Ready(t *testing.T) {
+	testCases := []struct {
+		name    string
+		head    int64
+		wantErr bool
+	}{
+		{"within max lag", 1050, false},
+		{"at max lag", 1100, false},
+		{"past max lag", 1101, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newMemStore()
+			store.heights[DefaultName] = 1000
+			w := New(newFakeChain(tc.head), store, testConfig())
+
+			details, err := w.Ready(context.Background())
+
+			assert.Equal(t, LagStatus{ChainHeight: tc.head, ProcessedHeight: 1000, Lag: tc.head - 1000, MaxLag: 100}, details,
+				"the status is reported either way")
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrLagging)
+				assert.ErrorContains(t, err, "101 blocks behind, at most 100 allowed")
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestWatcher_ReadyMaxLagFromConfig(t *testing.T) {
+	store := newMemStore()
+	store.heights[DefaultName] = 1000
+	cfg := testConfig()
+	cfg.BlockWatcher.MaxLag = 5
+
+	_, err := New(newFakeChain(1006), store, cfg).Ready(context.Background())
+
+	assert.ErrorIs(t, err, ErrLagging)
+}
+
+func TestWatcher_ReadyBeforeFirstBlock(t *testing.T) {
+	w := New(newFakeChain(1000), newMemStore(), testConfig())
+
+	_, err := w.Ready(context.Background())
+
+	assert.ErrorContains(t, err, "has not processed a block yet")
+}
+
+func TestWatcher_ReadyChainError(t *testing.T) {
+	chain := newFakeChain(1000)
+	chain.headErr = errors.New("connection refused")
+	store := newMemStore()
+	store.heights[DefaultName] = 1000
+
+	_, err := New(chain, store, testConfig()).Ready(context.Background())
+
+	assert.ErrorContains(t, err, "connection refused")
+	assert.NotErrorIs(t, err, ErrLagging)
+}
@@ -0,0 +1,37 @@
+package watcher
+
+// Metrics records what the watcher and its workers do. *metrics.Metrics
+// implements it.
+type Metrics interface {
+	// PaymentTransition records a payment moving to status.
+	PaymentTransition(status string)
+	// ChainLag records how many blocks the watcher named name is behind the
+	// chain head.
+	ChainLag(name string, blocks int64)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) PaymentTransition(string) {}
+func (nopMetrics) ChainLag(string, int64)   {}
+
+// WithMetrics records the watcher's lag and the payment statuses it sets
+// in m.
+func WithMetrics(m Metrics) Option {
+	return func(w *Watcher) { w.metrics = m }
+}
+
+// WithTrackerMetrics records the payments the tracker confirms in m.
+func WithTrackerMetrics(m Metrics) TrackerOption {
+	return func(t *ConfirmationTracker) { t.metrics = m }
+}
+
+// WithDetectorMetrics records the payments the detector marks DETECTED in m.
+func WithDetectorMetrics(m Metrics) DetectorOption {
+	return func(d *Detector) { d.metrics = m }
+}
+
+// WithExpirerMetrics records the payments the expirer expires in m.
+func WithExpirerMetrics(m Metrics) ExpirerOption {
+	return func(e *Expirer) { e.metrics = m }
+}
@@ -0,0 +1,90 @@
+package watcher
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingMetrics struct {
+	transitions []string
+	lag         map[string]int64
+}
+
+func (m *recordingMetrics) PaymentTransition(status string) {
+	m.transitions = append(m.transitions, status)
+}
+
+func (m *recordingMetrics) ChainLag(name string, blocks int64) {
+	if m.lag == nil {
+		m.lag = make(map[string]int64)
+	}
+	m.lag[name] = blocks
+}
+
+func TestWatcher_MetricsChainLag(t *testing.T) {
+	chain := newFakeChain(1500)
+	store := newMemStore()
+	store.heights[DefaultName] = 1000
+	cfg := testConfig()
+	cfg.BlockWatcher.BatchSize = 25
+	m := &recordingMetrics{}
+	w := New(chain, store, cfg, WithMetrics(m))
+
+	_, err := w.Poll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(475), m.lag[DefaultName], "measured after the batch")
+
+	chain.setHead(1030)
+	_, err = w.Poll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), m.lag[DefaultName])
+}
+
+func TestWatcher_MetricsTransitions(t *testing.T) {
+	chain := newFakeChain(1000)
+	payUSDT(t, chain, 1000, strings.Repeat("a", 64), usdtWallet, "99.9")
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	store.addPaymentFor(usdtWallet, statusPending, "100")
+	m := &recordingMetrics{}
+
+	_, err := New(chain, store, testConfig(), WithMetrics(m)).Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"UNDERPAID"}, m.transitions)
+}
+
+func TestConfirmationTracker_MetricsTransitions(t *testing.T) {
+	chain := newFakeChain(1003)
+	store := newMemStore()
+	payment := store.addPayment(trxWallet, statusPending)
+	addDetected(t, chain, store, payment, strings.Repeat("1", 64), 1000, pgtype.Numeric{})
+	m := &recordingMetrics{}
+	cfg := testConfig()
+	cfg.Tron.ConfirmationsRequired = 3
+	tracker := NewConfirmationTracker(chain, store, cfg, WithTrackerMetrics(m))
+
+	require.NoError(t, tracker.Advance(context.Background(), 1003))
+
+	assert.Equal(t, []string{statusConfirmed}, m.transitions)
+}
+
+func TestExpirer_MetricsTransitions(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := newMemStore()
+	expiringPayment(store, trxWallet, statusPending, now.Add(-time.Hour))
+	m := &recordingMetrics{}
+	e := NewExpirer(store, testConfig(), WithExpirerMetrics(m))
+	e.now = func() time.Time { return now }
+
+	_, err := e.ExpireOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{statusExpired}, m.transitions)
+}
@@ -0,0 +1,295 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/logging"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+)
+
+// EventPaymentReverted is logged when a reorg takes back the confirmation of
+// a payment.
+const EventPaymentReverted = "PAYMENT_REVERTED"
+
+const (
+	statusConfirmed = "CONFIRMED"
+	statusReview    = "REVIEW"
+)
+
+// blockRef is a scanned block as remembered in watcher_state.recent_blocks.
+type blockRef struct {
+	Height int64  `json:"height"`
+	Hash   string `json:"hash"`
+}
+
+// chainState is the watcher's position: the last height scanned and the
+// blocks it scanned last, oldest first. saved is the height persisted in
+// watcher_state, nil before the first save.
+type chainState struct {
+	height int64
+	blocks []blockRef
+	saved  *int64
+}
+
+// extends reports whether block follows on from the remembered blocks.
+// Without a remembered parent, e.g. on first start, every block does.
+func (s chainState) extends(block *tron.Block) bool {
+	if len(s.blocks) == 0 {
+		return true
+	}
+	tip := s.blocks[len(s.blocks)-1]
+	return tip.Height != block.Number()-1 || tip.Hash == block.ParentHash()
+}
+
+// push records block as scanned, remembering at most depth blocks.
+func (s *chainState) push(block *tron.Block, depth int) {
+	s.height = block.Number()
+	s.blocks = append(s.blocks, blockRef{Height: block.Number(), Hash: block.BlockID})
+	if n := len(s.blocks) - depth; n > 0 {
+		s.blocks = s.blocks[n:]
+	}
+}
+
+// Stats counts the reorgs a Watcher has handled since it started, e.g. for
+// metrics.
+type Stats struct {
+	// Reorgs counts forks the watcher rewound past.
+	Reorgs int64
+	// OrphanedTransactions counts recorded transfers whose block was
+	// orphaned.
+	OrphanedTransactions int64
+	// RevertedPayments counts confirmed payments reopened or sent to review.
+	RevertedPayments int64
+}
+
+type stats struct {
+	reorgs   atomic.Int64
+	orphaned atomic.Int64
+	reverted atomic.Int64
+}
+
+// Stats returns the watcher's counters.
+func (w *Watcher) Stats() Stats {
+	return Stats{
+		Reorgs:               w.stats.reorgs.Load(),
+		OrphanedTransactions: w.stats.orphaned.Load(),
+		RevertedPayments:     w.stats.reverted.Load(),
+	}
+}
+
+// loadState returns the persisted position. Without one the watcher starts
+// at startHeight, or at the chain head when that is unset.
+func (w *Watcher) loadState(ctx context.Context, head int64) (chainState, error) {
+	row, err := w.store.GetWatcherState(ctx, w.name)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		if w.startHeight > 0 {
+			return chainState{height: w.startHeight - 1}, nil
+		}
+		return chainState{height: head - 1}, nil
+	case err != nil:
+		return chainState{}, fmt.Errorf("failed to load watcher state: %w", err)
+	}
+	state := chainState{height: row.LastHeight, saved: &row.LastHeight}
+	if len(row.RecentBlocks) > 0 {
+		if err := json.Unmarshal(row.RecentBlocks, &state.blocks); err != nil {
+			return chainState{}, fmt.Errorf("failed to decode recent blocks: %w", err)
+		}
+	}
+	return state, nil
+}
+
+// saveState persists state over the height it was loaded or last saved at.
+// It returns repository.ErrStateConflict when the row has moved since, e.g.
+// because another replica of the watcher is running or an operator reset it;
+// the next poll then starts over from the row.
+func (w *Watcher) saveState(ctx context.Context, state *chainState) error {
+	blocks, err := json.Marshal(state.blocks)
+	if err != nil {
+		return fmt.Errorf("failed to encode recent blocks: %w", err)
+	}
+	if state.blocks == nil {
+		blocks = []byte("[]")
+	}
+	height, err := w.store.UpsertWatcherState(ctx, repository.UpsertWatcherStateParams{
+		Name:           w.name,
+		LastHeight:     state.height,
+		RecentBlocks:   blocks,
+		ExpectedHeight: state.saved,
+	})
+	if errors.Is(err, repository.ErrStateConflict) {
+		w.logger.ErrorContext(ctx, "watcher state changed under the watcher; is another replica running?", "name", w.name, "height", state.height)
+	}
+	if err != nil {
+		return err
+	}
+	state.saved = &height
+	return nil
+}
+
+// rewind handles a reorg found below the remembered blocks of state. It
+// walks back to the newest remembered block still on the canonical chain,
+// orphans the transfers recorded from the blocks above it and saves that
+// block as the last one scanned, so the next poll scans the new branch.
+//
+// A reorg deeper than the remembered blocks rewinds past all of them;
+// transfers recorded below them are left for the ConfirmationTracker.
+func (w *Watcher) rewind(ctx context.Context, state chainState) error {
+	fork := len(state.blocks)
+	for ; fork > 0; fork-- {
+		ref := state.blocks[fork-1]
+		block, err := w.chain.GetBlockByNum(ctx, ref.Height)
+		if err != nil {
+			// Blocks below the one that revealed the reorg exist, so
+			// ErrBlockNotFound is a lagging node; retry next poll.
+			return fmt.Errorf("failed to get block %d: %w", ref.Height, err)
+		}
+		if block.BlockID == ref.Hash {
+			break
+		}
+	}
+	orphaned := state.blocks[fork:]
+	forkHeight := orphaned[0].Height - 1
+	if fork == 0 {
+		w.logger.ErrorContext(ctx, "reorg deeper than the remembered blocks",
+			"name", w.name, "depth", len(orphaned), "rewound_to", forkHeight)
+	}
+	w.logger.Warn("chain reorg detected", "name", w.name, "fork_height", forkHeight,
+		"orphaned_blocks", len(orphaned), "old_tip", state.height)
+
+	if err := w.orphan(ctx, orphaned); err != nil {
+		return err
+	}
+	rewound := chainState{height: forkHeight, blocks: state.blocks[:fork], saved: state.saved}
+	if err := w.saveState(ctx, &rewound); err != nil {
+		return fmt.Errorf("failed to save watcher height %d: %w", forkHeight, err)
+	}
+	w.stats.reorgs.Add(1)
+	return nil
+}
+
+// orphan marks the transfers recorded from blocks as ORPHANED and reverts the
+// payments they settled. Transfers already orphaned by an earlier, interrupted
+// rewind are not logged again, but their payments are checked again.
+func (w *Watcher) orphan(ctx context.Context, blocks []blockRef) error {
+	hashes := make([]string, len(blocks))
+	for i, b := range blocks {
+		hashes[i] = b.Hash
+	}
+	txs, err := w.store.ListTransactionsByBlockHashes(ctx, hashes)
+	if err != nil {
+		return fmt.Errorf("failed to list transactions in orphaned blocks: %w", err)
+	}
+
+	var payments []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, tx := range txs {
+		if !seen[tx.PaymentID] {
+			seen[tx.PaymentID] = true
+			payments = append(payments, tx.PaymentID)
+		}
+		if tx.Status == txStatusOrphaned {
+			continue
+		}
+		err := w.store.UpdateTransactionConfirmations(ctx, repository.UpdateTransactionConfirmationsParams{
+			ID:     tx.ID,
+			Status: txStatusOrphaned,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to orphan transaction %s: %w", tx.TxHash, err)
+		}
+		w.stats.orphaned.Add(1)
+		w.logger.Warn("transaction orphaned", "payment_id", tx.PaymentID, "tx_hash", tx.TxHash,
+			"block", tx.BlockNumber, "status", tx.Status)
+		err = w.log(ctx, w.store, repository.Payment{ID: tx.PaymentID}, EventTxReorged,
+			fmt.Sprintf("block %d was orphaned; transfer no longer counts unless it is mined again", tx.BlockNumber),
+			reorgLog{TxHash: tx.TxHash, OldBlock: tx.BlockNumber, OldBlockHash: tx.BlockHash})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, id := range payments {
+		if err := w.revert(ctx, id); err != nil {
+			return fmt.Errorf("payment %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+type revertedLog struct {
+	Requested string `json:"requested"`
+	Received  string `json:"received"`
+	Status    string `json:"status"`
+}
+
+// revert takes back the confirmation of a payment that its remaining
+// transfers no longer fund. While the payment has not expired it is reopened
+// as PENDING, so the transfer is credited again if the new chain includes
+// it. An expired payment can no longer take a transfer and goes to REVIEW.
+func (w *Watcher) revert(ctx context.Context, id uuid.UUID) error {
+	ctx = logging.WithPaymentID(ctx, id)
+	payment, err := w.store.GetPayment(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to load payment: %w", err)
+	}
+	if payment.Status != statusConfirmed {
+		return nil
+	}
+	live, err := w.store.SumPaymentTransfers(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to sum transfers: %w", err)
+	}
+	requested := repository.NumericToDecimal(payment.Amount)
+	m := matchAmount(requested, repository.NumericToDecimal(live), decimal.Zero, w.toleranceBps)
+	if m.funded {
+		return nil
+	}
+
+	status := statusPending
+	if payment.ExpiresAt.Valid && !w.now().Before(payment.ExpiresAt.Time) {
+		status = statusReview
+	}
+	err = repository.RetryTx(ctx, w.store, func(q repository.Querier) error {
+		current, err := q.GetPayment(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to load payment: %w", err)
+		}
+		_, err = q.RevertPaymentConfirmation(ctx, repository.RevertPaymentConfirmationParams{
+			ToStatus: status,
+			ID:       id,
+			Version:  current.Version,
+		})
+		return err
+	})
+	if errors.Is(err, repository.ErrPaymentStatusChanged) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to revert payment confirmation: %w", err)
+	}
+	w.stats.reverted.Add(1)
+	w.metrics.PaymentTransition(status)
+
+	data := revertedLog{
+		Requested: requested.StringFixed(tokenDecimals(payment.Token)),
+		Received:  m.received.StringFixed(tokenDecimals(payment.Token)),
+		Status:    status,
+	}
+	msg := fmt.Sprintf("confirmation reverted by a reorg; %s of %s still on chain, reopened", data.Received, data.Requested)
+	if status == statusReview {
+		msg = fmt.Sprintf("confirmation reverted by a reorg after expiry; %s of %s still on chain, needs review", data.Received, data.Requested)
+		w.logger.ErrorContext(ctx, "confirmed payment reorged after expiry, needs review")
+	} else {
+		w.logger.WarnContext(ctx, "confirmed payment reorged, reopened")
+	}
+	return w.log(ctx, w.store, payment, EventPaymentReverted, msg, data)
+}
@@ -0,0 +1,300 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+)
+
+// fork replaces the blocks from..to with blocks of a new branch: same
+// contents, new IDs. The block after to links to the new branch.
+func (c *fakeChain) fork(from, to int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for h := from; h <= to; h++ {
+		b := *c.block(h)
+		b.BlockID += "f"
+		c.blocks[h] = &b
+	}
+}
+
+// clear empties the blocks from..to, e.g. before building a new branch.
+func (c *fakeChain) clear(from, to int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for h := from; h <= to; h++ {
+		delete(c.blocks, h)
+		delete(c.receipts, h)
+	}
+}
+
+// remembered decodes the blocks the watcher saved for name.
+func (s *memStore) remembered(t *testing.T, name string) []blockRef {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var refs []blockRef
+	require.NoError(t, json.Unmarshal(s.recent[name], &refs))
+	return refs
+}
+
+// recordTx adds a transfer of amount to payment as recorded from block.
+func (s *memStore) recordTx(payment repository.Payment, txHash string, block *tron.Block, amount, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.txs = append(s.txs, repository.Transaction{
+		ID:          uuid.New(),
+		PaymentID:   payment.ID,
+		TxHash:      txHash,
+		Token:       "USDT",
+		Amount:      repository.DecimalToNumeric(decimal.RequireFromString(amount)),
+		BlockNumber: block.Number(),
+		BlockHash:   block.BlockID,
+		Status:      status,
+	})
+}
+
+// scanned returns a watcher that has scanned chain up to its head from the
+// height saved in store.
+func scanned(t *testing.T, chain *fakeChain, store Store) *Watcher {
+	t.Helper()
+	w := New(chain, store, testConfig())
+	caughtUp, err := w.Poll(context.Background())
+	require.NoError(t, err)
+	require.True(t, caughtUp)
+	return w
+}
+
+func TestChainState(t *testing.T) {
+	var s chainState
+	assert.True(t, s.extends(emptyBlock(5)), "nothing remembered yet")
+
+	for h := int64(1); h <= 4; h++ {
+		s.push(emptyBlock(h), 3)
+	}
+	assert.Equal(t, int64(4), s.height)
+	assert.Equal(t, []blockRef{{2, emptyBlock(2).BlockID}, {3, emptyBlock(3).BlockID}, {4, emptyBlock(4).BlockID}}, s.blocks)
+
+	next := emptyBlock(5)
+	next.BlockHeader.RawData.ParentHash = emptyBlock(4).BlockID
+	assert.True(t, s.extends(next))
+	next.BlockHeader.RawData.ParentHash = "other"
+	assert.False(t, s.extends(next))
+}
+
+func TestWatcher_RemembersRecentBlocks(t *testing.T) {
+	chain := newFakeChain(1030)
+	store := newMemStore()
+	store.heights[DefaultName] = 1000
+	cfg := testConfig()
+	cfg.BlockWatcher.BatchSize = 100
+
+	_, err := New(chain, store, cfg).Poll(context.Background())
+
+	require.NoError(t, err)
+	refs := store.remembered(t, DefaultName)
+	require.Len(t, refs, 20, "reorgDepth defaults to 20")
+	assert.Equal(t, blockRef{Height: 1011, Hash: emptyBlock(1011).BlockID}, refs[0])
+	assert.Equal(t, blockRef{Height: 1030, Hash: emptyBlock(1030).BlockID}, refs[19])
+}
+
+func TestWatcher_ReorgOrphansAndRescans(t *testing.T) {
+	txID := strings.Repeat("a", 64)
+	chain := newFakeChain(1003)
+	payUSDT(t, chain, 1001, txID, usdtWallet, "100")
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	store.addPaymentFor(usdtWallet, "PENDING", "100")
+	w := scanned(t, chain, store)
+	require.Len(t, store.txs, 1)
+	oldHash := store.txs[0].BlockHash
+
+	// A new branch from 1001 includes the transfer one block later.
+	chain.clear(1001, 1003)
+	payUSDT(t, chain, 1002, txID, usdtWallet, "100")
+	chain.fork(1001, 1003)
+	chain.setHead(1004)
+
+	caughtUp, err := w.Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.False(t, caughtUp, "the new branch is scanned on the next poll")
+	assert.Equal(t, int64(1000), store.heights[DefaultName], "rewound to the fork point")
+	assert.Equal(t, "ORPHANED", store.txs[0].Status)
+	assert.Equal(t, []string{EventTxDetected, EventTxReorged}, store.eventTypes())
+	assert.JSONEq(t, `{"tx_hash":"`+txID+`","old_block":1001,"old_block_hash":"`+oldHash+`","new_block":0}`,
+		string(store.logs[1].RawData))
+
+	_, err = w.Poll(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, store.txs, 2, "the re-included transfer is recorded again")
+	assert.Equal(t, int64(1002), store.txs[1].BlockNumber)
+	assert.Equal(t, chain.blocks[1002].BlockID, store.txs[1].BlockHash)
+	assert.Equal(t, "DETECTED", store.txs[1].Status)
+	assert.Equal(t, "PENDING", store.payment(usdtWallet).Status)
+	assert.Equal(t, []string{EventTxDetected, EventTxReorged, EventTxDetected}, store.eventTypes())
+	assert.Equal(t, int64(1004), store.heights[DefaultName])
+	assert.Equal(t, Stats{Reorgs: 1, OrphanedTransactions: 1}, w.Stats())
+}
+
+func TestWatcher_ReorgRevertsConfirmedPayment(t *testing.T) {
+	chain := newFakeChain(1003)
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	p := store.addPaymentFor(usdtWallet, "CONFIRMED", "100")
+	p.ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true}
+	store.payments[usdtWallet] = p
+	w := scanned(t, chain, store)
+	store.recordTx(p, strings.Repeat("a", 64), chain.block(1002), "100", "CONFIRMED")
+
+	chain.fork(1002, 1003)
+	chain.setHead(1004)
+	_, err := w.Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, "PENDING", store.payment(usdtWallet).Status, "reopened while it can still be paid")
+	assert.Equal(t, "ORPHANED", store.txs[0].Status)
+	assert.Equal(t, []string{EventTxReorged, EventPaymentReverted}, store.eventTypes())
+	assert.JSONEq(t, `{"requested":"100.000000","received":"0.000000","status":"PENDING"}`, string(store.logs[1].RawData))
+	assert.Equal(t, Stats{Reorgs: 1, OrphanedTransactions: 1, RevertedPayments: 1}, w.Stats())
+}
+
+func TestWatcher_ReorgAfterExpiryNeedsReview(t *testing.T) {
+	chain := newFakeChain(1003)
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	p := store.addPaymentFor(usdtWallet, "CONFIRMED", "100")
+	p.ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true}
+	store.payments[usdtWallet] = p
+	w := scanned(t, chain, store)
+	store.recordTx(p, strings.Repeat("a", 64), chain.block(1002), "100", "CONFIRMED")
+
+	chain.fork(1002, 1003)
+	chain.setHead(1004)
+	_, err := w.Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, "REVIEW", store.payment(usdtWallet).Status)
+	assert.Contains(t, *store.logs[1].Message, "needs review")
+}
+
+func TestWatcher_ReorgKeepsPaymentStillFunded(t *testing.T) {
+	chain := newFakeChain(1003)
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	p := store.addPaymentFor(usdtWallet, "CONFIRMED", "100")
+	w := scanned(t, chain, store)
+	// Funded in block 1000, which survives; a later top-up in 1002 does not.
+	store.recordTx(p, strings.Repeat("a", 64), chain.block(1000), "100", "CONFIRMED")
+	store.recordTx(p, strings.Repeat("b", 64), chain.block(1002), "5", "CONFIRMED")
+
+	chain.fork(1002, 1003)
+	chain.setHead(1004)
+	_, err := w.Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, "CONFIRMED", store.payment(usdtWallet).Status)
+	assert.Equal(t, "CONFIRMED", store.txs[0].Status)
+	assert.Equal(t, "ORPHANED", store.txs[1].Status)
+	assert.Equal(t, []string{EventTxReorged}, store.eventTypes())
+}
+
+func TestWatcher_ReorgDeeperThanWindow(t *testing.T) {
+	chain := newFakeChain(1010)
+	store := newMemStore()
+	store.heights[DefaultName] = 1000
+	cfg := testConfig()
+	cfg.BlockWatcher.ReorgDepth = 3
+	w := New(chain, store, cfg)
+	_, err := w.Poll(context.Background())
+	require.NoError(t, err)
+
+	chain.fork(1005, 1010)
+	chain.setHead(1011)
+	_, err = w.Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1007), store.heights[DefaultName], "rewound past every remembered block")
+	assert.Empty(t, store.remembered(t, DefaultName))
+
+	// The next block after the rewind has no remembered parent to check;
+	// scanning resumes on the new branch.
+	_, err = w.Poll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1011), store.heights[DefaultName])
+	assert.Equal(t, int64(1), w.Stats().Reorgs)
+}
+
+func TestWatcher_ReorgNodeLagRetried(t *testing.T) {
+	chain := newFakeChain(1003)
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	w := scanned(t, chain, store)
+
+	chain.fork(1003, 1003)
+	chain.setHead(1004)
+	chain.missing[1003] = true
+	_, err := w.Poll(context.Background())
+
+	require.ErrorIs(t, err, tron.ErrBlockNotFound)
+	assert.Equal(t, int64(1003), store.heights[DefaultName], "nothing rewound")
+
+	delete(chain.missing, 1003)
+	_, err = w.Poll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1002), store.heights[DefaultName])
+}
+
+// failingStateStore fails the first n UpsertWatcherState calls.
+type failingStateStore struct {
+	*memStore
+	n int
+}
+
+func (f *failingStateStore) UpsertWatcherState(ctx context.Context, arg repository.UpsertWatcherStateParams) (int64, error) {
+	if f.n > 0 {
+		f.n--
+		return 0, errors.New("connection reset")
+	}
+	return f.memStore.UpsertWatcherState(ctx, arg)
+}
+
+func TestWatcher_InterruptedRewindResumes(t *testing.T) {
+	chain := newFakeChain(1003)
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	p := store.addPaymentFor(usdtWallet, "CONFIRMED", "100")
+	p.ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true}
+	store.payments[usdtWallet] = p
+	failing := &failingStateStore{memStore: store}
+	w := scanned(t, chain, failing)
+	store.recordTx(p, strings.Repeat("a", 64), chain.block(1002), "100", "CONFIRMED")
+	chain.fork(1002, 1003)
+	chain.setHead(1004)
+
+	// The rewind orphans the transfer and reverts the payment, but dies
+	// before saving the fork point.
+	failing.n = 1
+	_, err := w.Poll(context.Background())
+	require.ErrorContains(t, err, "connection reset")
+	assert.Equal(t, int64(1003), store.heights[DefaultName])
+
+	_, err = w.Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1001), store.heights[DefaultName])
+	assert.Equal(t, "PENDING", store.payment(usdtWallet).Status)
+	assert.Equal(t, []string{EventTxReorged, EventPaymentReverted}, store.eventTypes(), "nothing is logged twice")
+}
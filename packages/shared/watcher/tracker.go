@@ -0,0 +1,447 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/events"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/outbox"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/webhooks"
+)
+
+// Log events written by the confirmation tracker.
+const (
+	EventTxConfirmed = "TX_CONFIRMED"
+	EventTxReorged   = "TX_REORGED"
+)
+
+// Merchant notifications sent once a payment is final. An overpaid payment
+// gets EventPaymentOverpaid instead of EventPaymentConfirmed.
+const (
+	EventPaymentConfirmed = webhooks.EventPaymentConfirmed
+	EventPaymentOverpaid  = webhooks.EventPaymentOverpaid
+)
+
+// Transaction statuses.
+const (
+	txStatusDetected  = "DETECTED"
+	txStatusConfirmed = "CONFIRMED"
+	txStatusOrphaned  = "ORPHANED"
+)
+
+// TrackerChain is the subset of *tron.Client the tracker needs to follow a
+// transaction after it was detected.
+type TrackerChain interface {
+	Chain
+	GetTransactionInfoByID(ctx context.Context, txID string) (*tron.TransactionInfo, error)
+}
+
+// SolidityChain answers from solidified blocks only, e.g.
+// (*tron.Client).Confirmed().
+type SolidityChain interface {
+	GetTransactionInfoByID(ctx context.Context, txID string) (*tron.TransactionInfo, error)
+}
+
+// TrackerStore is the subset of *repository.Store the tracker writes through.
+type TrackerStore interface {
+	ListDetectedTransactions(ctx context.Context, mode string) ([]repository.Transaction, error)
+	UpdateTransactionConfirmations(ctx context.Context, arg repository.UpdateTransactionConfirmationsParams) error
+	UpdateTransactionBlock(ctx context.Context, arg repository.UpdateTransactionBlockParams) error
+	CreateLog(ctx context.Context, arg repository.CreateLogParams) error
+	ExecTx(ctx context.Context, fn func(repository.Querier) error) error
+}
+
+// logWriter is what the log helpers write through: the store itself, or a
+// Querier bound to the transaction of a payment transition.
+type logWriter interface {
+	CreateLog(ctx context.Context, arg repository.CreateLogParams) error
+}
+
+// ConfirmationTracker follows detected transactions until they are buried
+// under ConfirmationsRequired blocks and then confirms their payment. A
+// payment paid in several transfers is confirmed with its last one.
+//
+// A transaction's block is checked against the canonical chain on every
+// pass. If the block was replaced by a reorg, the transaction is looked up
+// again: if it was re-included it moves to its new block, otherwise it is
+// reset to zero confirmations with an empty block hash until it reappears.
+// Both cases write a TX_REORGED log.
+//
+// With a SolidityChain, a payment is only settled once its last transaction
+// is also in a solidified block at the height the tracker counted from.
+type ConfirmationTracker struct {
+	chain    TrackerChain
+	solidity SolidityChain
+	store    TrackerStore
+	metrics  Metrics
+	bus      events.EventPublisher
+	logger   *slog.Logger
+
+	required     int64
+	toleranceBps int64
+	pollInterval time.Duration
+	mode         string
+	now          func() time.Time
+
+	wake       chan struct{}
+	lastHeight int64
+}
+
+// TrackerOption customises a ConfirmationTracker.
+type TrackerOption func(*ConfirmationTracker)
+
+// WithSolidity makes the final check before a payment is confirmed against
+// c.
+func WithSolidity(c SolidityChain) TrackerOption {
+	return func(t *ConfirmationTracker) { t.solidity = c }
+}
+
+// WithTrackerLogger replaces slog.Default.
+func WithTrackerLogger(l *slog.Logger) TrackerOption {
+	return func(t *ConfirmationTracker) { t.logger = l }
+}
+
+// NewConfirmationTracker reads the required confirmations from the tron
+// section of cfg, the underpayment tolerance from the payments section, and
+// polls at the blockWatcher interval. It only tracks the
+// transactions of payments of cfg's mode.
+func NewConfirmationTracker(chain TrackerChain, store TrackerStore, cfg *config.Config, opts ...TrackerOption) *ConfirmationTracker {
+	t := &ConfirmationTracker{
+		chain:        chain,
+		store:        store,
+		metrics:      nopMetrics{},
+		logger:       slog.Default(),
+		required:     int64(cfg.Tron.ConfirmationsRequired),
+		toleranceBps: cfg.Payments.UnderpaymentToleranceBps(),
+		pollInterval: cfg.BlockWatcher.PollInterval.Std(),
+		mode:         cfg.Mode(),
+		now:          time.Now,
+		wake:         make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Track implements Tracker. The transaction is already persisted, so Track
+// only wakes Run to count its first confirmation without waiting a tick.
+func (t *ConfirmationTracker) Track(context.Context, repository.Payment, repository.Transaction) error {
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run advances confirmations whenever the chain head moves, until ctx is
+// done.
+func (t *ConfirmationTracker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(t.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		woken := false
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-t.wake:
+			woken = true
+		}
+
+		head, err := t.chain.GetNowBlock(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				t.logger.ErrorContext(ctx, "failed to get chain head", "error", err)
+			}
+			continue
+		}
+		if head.Number() == t.lastHeight && !woken {
+			continue
+		}
+		if err := t.Advance(ctx, head.Number()); err != nil && ctx.Err() == nil {
+			t.logger.ErrorContext(ctx, "confirmation tracking failed", "height", head.Number(), "error", err)
+		}
+	}
+}
+
+// Advance recomputes the confirmations of every detected transaction against
+// height. A failure on one transaction does not stop the others; all
+// failures are returned joined.
+func (t *ConfirmationTracker) Advance(ctx context.Context, height int64) error {
+	txs, err := t.store.ListDetectedTransactions(ctx, t.mode)
+	if err != nil {
+		return fmt.Errorf("failed to list detected transactions: %w", err)
+	}
+
+	p := pass{height: height, hashes: make(map[int64]string), payments: make(map[uuid.UUID]*paymentPass)}
+	for _, tx := range txs {
+		pp := p.payments[tx.PaymentID]
+		if pp == nil {
+			pp = &paymentPass{}
+			p.payments[tx.PaymentID] = pp
+		}
+		pp.open++
+		pp.overpaid = pp.overpaid || tx.ExcessAmount.Valid
+	}
+
+	var errs []error
+	for _, tx := range txs {
+		if err := t.advance(ctx, tx, &p); err != nil {
+			errs = append(errs, fmt.Errorf("transaction %s: %w", tx.TxHash, err))
+		}
+	}
+	t.lastHeight = height
+	return errors.Join(errs...)
+}
+
+// pass is the state of one Advance call.
+type pass struct {
+	height int64
+	// hashes caches canonical block IDs by height.
+	hashes   map[int64]string
+	payments map[uuid.UUID]*paymentPass
+}
+
+// paymentPass tracks the detected transactions of one payment.
+type paymentPass struct {
+	// open counts transactions not yet confirmed in this pass.
+	open     int
+	overpaid bool
+}
+
+func (t *ConfirmationTracker) advance(ctx context.Context, tx repository.Transaction, p *pass) error {
+	canonical := false
+	if tx.BlockHash != "" {
+		hash, err := t.blockHash(ctx, tx.BlockNumber, p.hashes)
+		if err != nil {
+			return err
+		}
+		canonical = hash == tx.BlockHash
+	}
+	if !canonical {
+		moved, err := t.relocate(ctx, &tx, p.hashes)
+		if err != nil || !moved {
+			return err
+		}
+	}
+
+	confirmations := max(p.height-tx.BlockNumber+1, 0)
+	if confirmations >= t.required {
+		return t.confirm(ctx, tx, confirmations, p.payments[tx.PaymentID])
+	}
+	if int32(confirmations) == tx.Confirmations {
+		return nil
+	}
+	return t.store.UpdateTransactionConfirmations(ctx, repository.UpdateTransactionConfirmationsParams{
+		ID:            tx.ID,
+		Confirmations: int32(confirmations),
+		Status:        txStatusDetected,
+	})
+}
+
+// relocate finds the block tx is in now. It reports false when tx is not in
+// any block, after resetting it so later passes keep looking.
+func (t *ConfirmationTracker) relocate(ctx context.Context, tx *repository.Transaction, hashes map[int64]string) (bool, error) {
+	info, err := t.chain.GetTransactionInfoByID(ctx, tx.TxHash)
+	if errors.Is(err, tron.ErrTransactionNotFound) {
+		if tx.BlockHash == "" {
+			return false, nil
+		}
+		if err := t.store.UpdateTransactionBlock(ctx, repository.UpdateTransactionBlockParams{ID: tx.ID}); err != nil {
+			return false, fmt.Errorf("failed to reset reorged transaction: %w", err)
+		}
+		return false, t.logReorg(ctx, *tx, 0)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	hash, err := t.blockHash(ctx, info.BlockNumber, hashes)
+	if err != nil {
+		return false, err
+	}
+	if hash == "" || (hash == tx.BlockHash && info.BlockNumber == tx.BlockNumber) {
+		// The node serving blocks lags the one serving receipts; retry
+		// next pass.
+		return false, nil
+	}
+	if tx.BlockHash != "" {
+		if err := t.logReorg(ctx, *tx, info.BlockNumber); err != nil {
+			return false, err
+		}
+	}
+
+	tx.BlockNumber, tx.BlockHash, tx.Confirmations = info.BlockNumber, hash, 0
+	err = t.store.UpdateTransactionBlock(ctx, repository.UpdateTransactionBlockParams{
+		ID:          tx.ID,
+		BlockNumber: tx.BlockNumber,
+		BlockHash:   tx.BlockHash,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to move transaction to block %d: %w", tx.BlockNumber, err)
+	}
+	return true, nil
+}
+
+func (t *ConfirmationTracker) blockHash(ctx context.Context, height int64, hashes map[int64]string) (string, error) {
+	if hash, ok := hashes[height]; ok {
+		return hash, nil
+	}
+	block, err := t.chain.GetBlockByNum(ctx, height)
+	if errors.Is(err, tron.ErrBlockNotFound) {
+		// Shortened chain after a reorg.
+		hashes[height] = ""
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	hashes[height] = block.BlockID
+	return block.BlockID, nil
+}
+
+// confirm settles the payment before marking the transaction, so a crash in
+// between is retried on the next pass instead of leaving the payment
+// PENDING behind a CONFIRMED transaction. The payment, its TX_CONFIRMED log
+// and its outbox event commit together. The payment is only settled by the
+// last of its open transactions.
+func (t *ConfirmationTracker) confirm(ctx context.Context, tx repository.Transaction, confirmations int64, pp *paymentPass) error {
+	if pp.open > 1 {
+		pp.open--
+		return t.markConfirmed(ctx, tx, confirmations)
+	}
+
+	solid, err := t.solidified(ctx, tx)
+	if err != nil || !solid {
+		if err != nil || int32(confirmations) == tx.Confirmations {
+			return err
+		}
+		return t.store.UpdateTransactionConfirmations(ctx, repository.UpdateTransactionConfirmationsParams{
+			ID:            tx.ID,
+			Confirmations: int32(confirmations),
+			Status:        txStatusDetected,
+		})
+	}
+
+	event := EventPaymentConfirmed
+	if pp.overpaid {
+		event = EventPaymentOverpaid
+	}
+	payment, err := outbox.Transition(ctx, t.store, t.bus, event, t.now(), func(q repository.Querier) (repository.Payment, error) {
+		current, err := q.GetPayment(ctx, tx.PaymentID)
+		if err != nil {
+			return current, fmt.Errorf("failed to load payment: %w", err)
+		}
+		payment, err := q.ConfirmPayment(ctx, repository.ConfirmPaymentParams{
+			ID:           current.ID,
+			Version:      current.Version,
+			ToleranceBps: t.toleranceBps,
+		})
+		if err != nil {
+			return payment, err
+		}
+		return payment, t.log(ctx, q, tx, EventTxConfirmed, fmt.Sprintf("confirmed after %d blocks", confirmations), nil)
+	})
+	switch {
+	case errors.Is(err, repository.ErrPaymentNotPending):
+		// E.g. UNDERPAID, or left PENDING short of its amount, to be
+		// confirmed by a later top-up.
+		t.logger.Info("confirmed transfer did not settle its payment",
+			"payment_id", tx.PaymentID, "tx_hash", tx.TxHash)
+	case err != nil:
+		return fmt.Errorf("failed to confirm payment: %w", err)
+	default:
+		t.metrics.PaymentTransition(statusConfirmed)
+		t.logger.Info("payment confirmed", "payment_id", payment.ID, "tx_hash", tx.TxHash, "confirmations", confirmations)
+	}
+	return t.markConfirmed(ctx, tx, confirmations)
+}
+
+// solidified reports whether the solidity node has tx in the block it was
+// counted from. Without a SolidityChain every transaction passes.
+func (t *ConfirmationTracker) solidified(ctx context.Context, tx repository.Transaction) (bool, error) {
+	if t.solidity == nil {
+		return true, nil
+	}
+	info, err := t.solidity.GetTransactionInfoByID(ctx, tx.TxHash)
+	if errors.Is(err, tron.ErrTransactionNotFound) {
+		t.logger.Debug("transaction not solidified yet", "payment_id", tx.PaymentID, "tx_hash", tx.TxHash)
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check solidified transaction: %w", err)
+	}
+	switch {
+	case info.Failed():
+		t.logger.Warn("solidified transaction failed", "payment_id", tx.PaymentID, "tx_hash", tx.TxHash,
+			"result", info.ResMessage)
+		return false, nil
+	case info.BlockNumber != tx.BlockNumber:
+		// A reorg the tracker has not seen yet; relocate picks it up.
+		t.logger.Warn("solidified transaction is in another block", "payment_id", tx.PaymentID,
+			"tx_hash", tx.TxHash, "block", tx.BlockNumber, "solidified_block", info.BlockNumber)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (t *ConfirmationTracker) markConfirmed(ctx context.Context, tx repository.Transaction, confirmations int64) error {
+	return t.store.UpdateTransactionConfirmations(ctx, repository.UpdateTransactionConfirmationsParams{
+		ID:            tx.ID,
+		Confirmations: int32(confirmations),
+		Status:        txStatusConfirmed,
+	})
+}
+
+type reorgLog struct {
+	TxHash       string `json:"tx_hash"`
+	OldBlock     int64  `json:"old_block"`
+	OldBlockHash string `json:"old_block_hash"`
+	// NewBlock is 0 while the transaction is not in any block.
+	NewBlock int64 `json:"new_block"`
+}
+
+func (t *ConfirmationTracker) logReorg(ctx context.Context, tx repository.Transaction, newBlock int64) error {
+	msg := fmt.Sprintf("block %d was reorged; transaction is not in any block", tx.BlockNumber)
+	if newBlock != 0 {
+		msg = fmt.Sprintf("block %d was reorged; transaction moved to block %d", tx.BlockNumber, newBlock)
+	}
+	t.logger.Warn("transaction reorged", "payment_id", tx.PaymentID, "tx_hash", tx.TxHash,
+		"old_block", tx.BlockNumber, "new_block", newBlock)
+	return t.log(ctx, t.store, tx, EventTxReorged, msg, reorgLog{
+		TxHash:       tx.TxHash,
+		OldBlock:     tx.BlockNumber,
+		OldBlockHash: tx.BlockHash,
+		NewBlock:     newBlock,
+	})
+}
+
+func (t *ConfirmationTracker) log(ctx context.Context, w logWriter, tx repository.Transaction, event, msg string, data any) error {
+	if data == nil {
+		data = map[string]any{"tx_hash": tx.TxHash, "block_number": tx.BlockNumber}
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode log: %w", err)
+	}
+	err = w.CreateLog(ctx, repository.CreateLogParams{
+		PaymentID: repository.UUIDPtr(tx.PaymentID),
+		EventType: event,
+		Message:   &msg,
+		RawData:   raw,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %s log: %w", event, err)
+	}
+	return nil
+}
@@ -0,0 +1,503 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/webhooks"
+)
+
+func (s *memStore) ListDetectedTransactions(_ context.Context, mode string) ([]repository.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	modes := make(map[uuid.UUID]string)
+	for _, p := range s.payments {
+		modes[p.ID] = paymentMode(p)
+	}
+	var out []repository.Transaction
+	for _, tx := range s.txs {
+		if tx.Status == txStatusDetected && modes[tx.PaymentID] == mode {
+			out = append(out, tx)
+		}
+	}
+	return out, nil
+}
+
+func (s *memStore) UpdateTransactionConfirmations(_ context.Context, arg repository.UpdateTransactionConfirmationsParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.txs {
+		if s.txs[i].ID == arg.ID && s.txs[i].Status != txStatusOrphaned {
+			s.txs[i].Confirmations, s.txs[i].Status = arg.Confirmations, arg.Status
+		}
+	}
+	return nil
+}
+
+func (s *memStore) UpdateTransactionBlock(_ context.Context, arg repository.UpdateTransactionBlockParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.txs {
+		if s.txs[i].ID == arg.ID && s.txs[i].Status != txStatusOrphaned {
+			s.txs[i].BlockNumber, s.txs[i].BlockHash, s.txs[i].Confirmations = arg.BlockNumber, arg.BlockHash, arg.Confirmations
+		}
+	}
+	return nil
+}
+
+func (s *memStore) ConfirmPayment(_ context.Context, arg repository.ConfirmPaymentParams) (repository.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for wallet, p := range s.payments {
+		if p.ID != arg.ID {
+			continue
+		}
+		if p.Version != arg.Version {
+			return repository.Payment{}, repository.ErrVersionConflict
+		}
+		if p.Status != statusPending && p.Status != statusDetected {
+			return repository.Payment{}, repository.ErrPaymentNotPending
+		}
+		received := decimal.Zero
+		for _, tx := range s.txs {
+			if tx.PaymentID == p.ID && tx.Status != txStatusOrphaned {
+				received = received.Add(repository.NumericToDecimal(tx.Amount))
+			}
+		}
+		if !matchAmount(repository.NumericToDecimal(p.Amount), received, decimal.Zero, arg.ToleranceBps).funded {
+			return repository.Payment{}, repository.ErrPaymentNotPending
+		}
+		p.Status = "CONFIRMED"
+		p.Version++
+		s.payments[wallet] = p
+		return p, nil
+	}
+	return repository.Payment{}, repository.ErrPaymentNotPending
+}
+
+func (s *memStore) eventTypes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []string
+	for _, l := range s.logs {
+		out = append(out, l.EventType)
+	}
+	return out
+}
+
+// fakeSolidity serves receipts of solidified transactions.
+type fakeSolidity struct {
+	mu       sync.Mutex
+	receipts map[string]tron.TransactionInfo
+	err      error
+	calls    int
+}
+
+func newFakeSolidity() *fakeSolidity {
+	return &fakeSolidity{receipts: make(map[string]tron.TransactionInfo)}
+}
+
+func (s *fakeSolidity) GetTransactionInfoByID(_ context.Context, txID string) (*tron.TransactionInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	info, ok := s.receipts[txID]
+	if !ok {
+		return nil, tron.ErrTransactionNotFound
+	}
+	return &info, nil
+}
+
+func (s *fakeSolidity) solidify(txID string, num int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.receipts[txID] = tron.TransactionInfo{ID: txID, BlockNumber: num}
+}
+
+// detectedTx records a transfer to a pending payment in block num, as the
+// watcher would.
+func detectedTx(t *testing.T, chain *fakeChain, store *memStore, num int64) (repository.Payment, repository.Transaction) {
+	t.Helper()
+	payment := store.addPayment(trxWallet, statusPending)
+	return payment, addDetected(t, chain, store, payment, strings.Repeat("1", 64), num, pgtype.Numeric{})
+}
+
+func addDetected(t *testing.T, chain *fakeChain, store *memStore, payment repository.Payment, txHash string, num int64, excess pgtype.Numeric) repository.Transaction {
+	t.Helper()
+	tx, err := store.CreateTransaction(context.Background(), repository.CreateTransactionParams{
+		PaymentID:    payment.ID,
+		TxHash:       txHash,
+		Token:        "TRX",
+		BlockNumber:  num,
+		Amount:       payment.Amount,
+		BlockHash:    emptyBlock(num).BlockID,
+		ExcessAmount: excess,
+	})
+	require.NoError(t, err)
+	chain.included[txHash] = num
+	return tx
+}
+
+func newTestTracker(chain *fakeChain, store *memStore, required int) *ConfirmationTracker {
+	cfg := testConfig()
+	cfg.Tron.ConfirmationsRequired = required
+	return NewConfirmationTracker(chain, store, cfg)
+}
+
+func TestConfirmationTracker_ShortPaymentIsNotConfirmed(t *testing.T) {
+	chain := newFakeChain(1000)
+	store := newMemStore()
+	payment := store.addPaymentFor(usdtWallet, statusPending, "100")
+	// Left PENDING with half its amount, e.g. by a crash before it was marked
+	// UNDERPAID.
+	half := payment
+	half.Amount = repository.DecimalToNumeric(decimal.RequireFromString("50"))
+	addDetected(t, chain, store, half, strings.Repeat("1", 64), 1000, pgtype.Numeric{})
+	tracker := newTestTracker(chain, store, 1)
+
+	require.NoError(t, tracker.Advance(context.Background(), 1000))
+
+	assert.Equal(t, statusPending, store.payment(usdtWallet).Status)
+	assert.Empty(t, store.outbox)
+	assert.Equal(t, txStatusConfirmed, store.txs[0].Status)
+}
+
+func TestConfirmationTracker_HeightProgression(t *testing.T) {
+	chain := newFakeChain(1000)
+	store := newMemStore()
+	payment, _ := detectedTx(t, chain, store, 1000)
+	tracker := newTestTracker(chain, store, 3)
+	ctx := context.Background()
+
+	require.NoError(t, tracker.Advance(ctx, 1000))
+	assert.Equal(t, int32(1), store.txs[0].Confirmations)
+	assert.Equal(t, txStatusDetected, store.txs[0].Status)
+
+	require.NoError(t, tracker.Advance(ctx, 1001))
+	assert.Equal(t, int32(2), store.txs[0].Confirmations)
+	assert.Equal(t, statusPending, store.payments[trxWallet].Status)
+	assert.Empty(t, store.outbox)
+
+	require.NoError(t, tracker.Advance(ctx, 1002))
+	assert.Equal(t, int32(3), store.txs[0].Confirmations)
+	assert.Equal(t, txStatusConfirmed, store.txs[0].Status)
+	assert.Equal(t, "CONFIRMED", store.payments[trxWallet].Status)
+	assert.Equal(t, []string{EventPaymentConfirmed}, store.outboxTypes())
+	assert.Equal(t, []string{EventTxConfirmed}, store.eventTypes())
+	assert.Equal(t, payment.ID, *store.logs[0].PaymentID)
+
+	// Confirmed transactions are no longer tracked.
+	require.NoError(t, tracker.Advance(ctx, 1003))
+	assert.Len(t, store.outboxTypes(), 1)
+}
+
+func TestConfirmationTracker_CatchUpConfirmsAtOnce(t *testing.T) {
+	chain := newFakeChain(1050)
+	store := newMemStore()
+	detectedTx(t, chain, store, 1000)
+
+	require.NoError(t, newTestTracker(chain, store, 19).Advance(context.Background(), 1050))
+
+	assert.Equal(t, int32(51), store.txs[0].Confirmations)
+	assert.Equal(t, txStatusConfirmed, store.txs[0].Status)
+	assert.Len(t, store.outboxTypes(), 1)
+}
+
+func TestConfirmationTracker_ReorgDropsTransaction(t *testing.T) {
+	chain := newFakeChain(1001)
+	store := newMemStore()
+	_, tx := detectedTx(t, chain, store, 1000)
+	tracker := newTestTracker(chain, store, 3)
+	ctx := context.Background()
+
+	require.NoError(t, tracker.Advance(ctx, 1001))
+	assert.Equal(t, int32(2), store.txs[0].Confirmations)
+
+	// Block 1000 is replaced and the transfer is no longer in any block.
+	forked := emptyBlock(1000)
+	forked.BlockID = "fork-1000"
+	chain.blocks[1000] = forked
+	delete(chain.included, tx.TxHash)
+
+	require.NoError(t, tracker.Advance(ctx, 1002))
+	assert.Equal(t, txStatusDetected, store.txs[0].Status)
+	assert.Zero(t, store.txs[0].Confirmations)
+	assert.Empty(t, store.txs[0].BlockHash)
+	assert.Equal(t, []string{EventTxReorged}, store.eventTypes())
+	assert.JSONEq(t, `{"tx_hash":"`+tx.TxHash+`","old_block":1000,"old_block_hash":"`+tx.BlockHash+`","new_block":0}`,
+		string(store.logs[0].RawData))
+	assert.Equal(t, statusPending, store.payments[trxWallet].Status)
+
+	// Still missing: no duplicate reorg log.
+	require.NoError(t, tracker.Advance(ctx, 1003))
+	assert.Len(t, store.logs, 1)
+
+	// Re-included in block 1004, it counts confirmations from there.
+	chain.setHead(1006)
+	chain.included[tx.TxHash] = 1004
+	require.NoError(t, tracker.Advance(ctx, 1004))
+	assert.Equal(t, int64(1004), store.txs[0].BlockNumber)
+	assert.Equal(t, emptyBlock(1004).BlockID, store.txs[0].BlockHash)
+	assert.Equal(t, int32(1), store.txs[0].Confirmations)
+	assert.Len(t, store.logs, 1)
+
+	require.NoError(t, tracker.Advance(ctx, 1006))
+	assert.Equal(t, txStatusConfirmed, store.txs[0].Status)
+	assert.Equal(t, []string{EventPaymentConfirmed}, store.outboxTypes())
+}
+
+func TestConfirmationTracker_ReorgMovesTransaction(t *testing.T) {
+	chain := newFakeChain(1001)
+	store := newMemStore()
+	_, tx := detectedTx(t, chain, store, 1000)
+	tracker := newTestTracker(chain, store, 19)
+	ctx := context.Background()
+
+	forked := emptyBlock(1000)
+	forked.BlockID = "fork-1000"
+	chain.blocks[1000] = forked
+	chain.included[tx.TxHash] = 1001
+
+	require.NoError(t, tracker.Advance(ctx, 1002))
+
+	assert.Equal(t, int64(1001), store.txs[0].BlockNumber)
+	assert.Equal(t, int32(2), store.txs[0].Confirmations)
+	assert.Equal(t, []string{EventTxReorged}, store.eventTypes())
+	assert.Contains(t, *store.logs[0].Message, "moved to block 1001")
+}
+
+func TestConfirmationTracker_PaymentNoLongerPending(t *testing.T) {
+	chain := newFakeChain(1010)
+	store := newMemStore()
+	detectedTx(t, chain, store, 1000)
+	p := store.payments[trxWallet]
+	p.Status = "EXPIRED"
+	store.payments[trxWallet] = p
+
+	require.NoError(t, newTestTracker(chain, store, 3).Advance(context.Background(), 1010))
+
+	assert.Equal(t, txStatusConfirmed, store.txs[0].Status)
+	assert.Equal(t, "EXPIRED", store.payments[trxWallet].Status)
+	assert.Empty(t, store.outbox)
+	assert.Empty(t, store.logs)
+}
+
+func TestConfirmationTracker_ConfirmsWithLastTransfer(t *testing.T) {
+	chain := newFakeChain(1007)
+	store := newMemStore()
+	payment := store.addPayment(trxWallet, statusPending)
+	first := addDetected(t, chain, store, payment, strings.Repeat("1", 64), 1000, pgtype.Numeric{})
+	addDetected(t, chain, store, payment, strings.Repeat("2", 64), 1005, pgtype.Numeric{})
+	tracker := newTestTracker(chain, store, 3)
+	ctx := context.Background()
+
+	require.NoError(t, tracker.Advance(ctx, 1003))
+	assert.Equal(t, first.ID, store.txs[0].ID)
+	assert.Equal(t, txStatusConfirmed, store.txs[0].Status)
+	assert.Equal(t, txStatusDetected, store.txs[1].Status)
+	assert.Equal(t, statusPending, store.payment(trxWallet).Status, "the top-up is not final yet")
+	assert.Empty(t, store.outbox)
+
+	require.NoError(t, tracker.Advance(ctx, 1007))
+	assert.Equal(t, txStatusConfirmed, store.txs[1].Status)
+	assert.Equal(t, "CONFIRMED", store.payment(trxWallet).Status)
+	assert.Equal(t, []string{EventPaymentConfirmed}, store.outboxTypes())
+}
+
+func TestConfirmationTracker_Overpaid(t *testing.T) {
+	chain := newFakeChain(1010)
+	store := newMemStore()
+	payment := store.addPayment(trxWallet, statusPending)
+	addDetected(t, chain, store, payment, strings.Repeat("1", 64), 1000,
+		pgtype.Numeric{Int: big.NewInt(500000), Exp: -6, Valid: true})
+
+	require.NoError(t, newTestTracker(chain, store, 3).Advance(context.Background(), 1010))
+
+	assert.Equal(t, "CONFIRMED", store.payment(trxWallet).Status)
+	assert.Equal(t, []string{EventPaymentOverpaid}, store.outboxTypes())
+}
+
+func TestConfirmationTracker_UnderpaidIsNotConfirmed(t *testing.T) {
+	chain := newFakeChain(1010)
+	store := newMemStore()
+	payment := store.addPayment(trxWallet, statusUnderpaid)
+	addDetected(t, chain, store, payment, strings.Repeat("1", 64), 1000, pgtype.Numeric{})
+
+	require.NoError(t, newTestTracker(chain, store, 3).Advance(context.Background(), 1010))
+
+	assert.Equal(t, txStatusConfirmed, store.txs[0].Status)
+	assert.Equal(t, statusUnderpaid, store.payment(trxWallet).Status)
+	assert.Empty(t, store.outbox)
+}
+
+func TestConfirmationTracker_OutboxFailureIsRetried(t *testing.T) {
+	chain := newFakeChain(1010)
+	store := newMemStore()
+	detectedTx(t, chain, store, 1000)
+	store.failOutbox = errors.New("connection reset")
+	tracker := newTestTracker(chain, store, 3)
+	ctx := context.Background()
+
+	err := tracker.Advance(ctx, 1010)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "connection reset")
+	assert.Equal(t, txStatusDetected, store.txs[0].Status, "transaction stays tracked")
+	assert.Equal(t, statusPending, store.payment(trxWallet).Status, "the confirmation is rolled back")
+	assert.Empty(t, store.logs)
+
+	store.failOutbox = nil
+	require.NoError(t, tracker.Advance(ctx, 1011))
+	assert.Equal(t, "CONFIRMED", store.payment(trxWallet).Status)
+	assert.Equal(t, []string{EventPaymentConfirmed}, store.outboxTypes())
+	assert.Equal(t, []string{EventTxConfirmed}, store.eventTypes())
+}
+
+func TestConfirmationTracker_OutboxEvent(t *testing.T) {
+	chain := newFakeChain(1010)
+	store := newMemStore()
+	payment, _ := detectedTx(t, chain, store, 1000)
+	tracker := newTestTracker(chain, store, 3)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	require.NoError(t, tracker.Advance(context.Background(), 1010))
+
+	require.Len(t, store.outbox, 1)
+	e := store.outbox[0]
+	assert.Equal(t, EventPaymentConfirmed, e.EventType)
+	assert.Equal(t, payment.ID, e.AggregateID)
+	var body webhooks.Event
+	require.NoError(t, json.Unmarshal(e.Payload, &body))
+	assert.Equal(t, e.ID, body.ID, "the webhook event id is the outbox id")
+	assert.Equal(t, now, body.Created)
+	assert.Equal(t, "CONFIRMED", body.Data.Status)
+}
+
+func newSolidityTracker(chain *fakeChain, store *memStore, solidity *fakeSolidity) *ConfirmationTracker {
+	cfg := testConfig()
+	cfg.Tron.ConfirmationsRequired = 3
+	return NewConfirmationTracker(chain, store, cfg, WithSolidity(solidity))
+}
+
+func TestConfirmationTracker_WaitsForSolidity(t *testing.T) {
+	chain := newFakeChain(1010)
+	store := newMemStore()
+	_, tx := detectedTx(t, chain, store, 1000)
+	solidity := newFakeSolidity()
+	tracker := newSolidityTracker(chain, store, solidity)
+	ctx := context.Background()
+
+	require.NoError(t, tracker.Advance(ctx, 1010))
+	assert.Equal(t, txStatusDetected, store.txs[0].Status)
+	assert.Equal(t, int32(11), store.txs[0].Confirmations, "confirmations still advance")
+	assert.Equal(t, statusPending, store.payment(trxWallet).Status)
+	assert.Empty(t, store.outbox)
+
+	solidity.solidify(tx.TxHash, 1000)
+	require.NoError(t, tracker.Advance(ctx, 1011))
+	assert.Equal(t, txStatusConfirmed, store.txs[0].Status)
+	assert.Equal(t, "CONFIRMED", store.payment(trxWallet).Status)
+	assert.Equal(t, []string{EventPaymentConfirmed}, store.outboxTypes())
+}
+
+func TestConfirmationTracker_SolidityOnlyChecksFinalTransfer(t *testing.T) {
+	chain := newFakeChain(1010)
+	store := newMemStore()
+	payment := store.addPayment(trxWallet, statusPending)
+	addDetected(t, chain, store, payment, strings.Repeat("1", 64), 1000, pgtype.Numeric{})
+	last := addDetected(t, chain, store, payment, strings.Repeat("2", 64), 1001, pgtype.Numeric{})
+	solidity := newFakeSolidity()
+	solidity.solidify(last.TxHash, 1001)
+
+	require.NoError(t, newSolidityTracker(chain, store, solidity).Advance(context.Background(), 1010))
+
+	assert.Equal(t, 1, solidity.calls)
+	assert.Equal(t, "CONFIRMED", store.payment(trxWallet).Status)
+}
+
+func TestConfirmationTracker_SolidityRejects(t *testing.T) {
+	testCases := []struct {
+		name string
+		info tron.TransactionInfo
+	}{
+		{"failed", tron.TransactionInfo{BlockNumber: 1000, Result: "FAILED"}},
+		{"other block", tron.TransactionInfo{BlockNumber: 999}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			chain := newFakeChain(1010)
+			store := newMemStore()
+			_, tx := detectedTx(t, chain, store, 1000)
+			solidity := newFakeSolidity()
+			tc.info.ID = tx.TxHash
+			solidity.receipts[tx.TxHash] = tc.info
+
+			require.NoError(t, newSolidityTracker(chain, store, solidity).Advance(context.Background(), 1010))
+
+			assert.Equal(t, txStatusDetected, store.txs[0].Status)
+			assert.Equal(t, statusPending, store.payment(trxWallet).Status)
+			assert.Empty(t, store.outbox)
+		})
+	}
+}
+
+func TestConfirmationTracker_SolidityErrorIsRetried(t *testing.T) {
+	chain := newFakeChain(1010)
+	store := newMemStore()
+	detectedTx(t, chain, store, 1000)
+	solidity := newFakeSolidity()
+	solidity.err = errors.New("solidity node down")
+
+	err := newSolidityTracker(chain, store, solidity).Advance(context.Background(), 1010)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "solidity node down")
+	assert.Equal(t, txStatusDetected, store.txs[0].Status)
+	assert.Equal(t, statusPending, store.payment(trxWallet).Status)
+}
+
+func TestConfirmationTracker_RunWakesOnTrack(t *testing.T) {
+	chain := newFakeChain(1000)
+	store := newMemStore()
+	detectedTx(t, chain, store, 1000)
+	cfg := testConfig()
+	cfg.BlockWatcher.PollInterval = config.Duration(time.Hour)
+	tracker := NewConfirmationTracker(chain, store, cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- tracker.Run(ctx) }()
+
+	require.NoError(t, tracker.Track(ctx, repository.Payment{}, repository.Transaction{}))
+	require.Eventually(t, func() bool {
+		txs, _ := store.ListDetectedTransactions(ctx, config.ModeLive)
+		return len(txs) == 1 && txs[0].Confirmations == 1
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
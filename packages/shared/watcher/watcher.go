@@ -0,0 +1,664 @@
+// Package watcher scans new TRON blocks for transfers to payment wallets and
+// records them against the matching pending payment.
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/logging"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+)
+
+// DefaultName keys the watcher's row in watcher_state.
+const DefaultName = "tron"
+
+// Log events written when a transfer is matched.
+const (
+	EventTxDetected = "TX_DETECTED"
+	EventUnderpaid  = "PAYMENT_UNDERPAID"
+	EventOverpaid   = "PAYMENT_OVERPAID"
+)
+
+// Payment statuses a transfer is credited to. A DETECTED payment had a
+// transfer spotted in the pending pool; an UNDERPAID payment keeps accepting
+// transfers until it expires.
+const (
+	statusPending   = "PENDING"
+	statusDetected  = "DETECTED"
+	statusUnderpaid = "UNDERPAID"
+)
+
+// tokenDecimals returns the precision of token's amounts. Tokens come from
+// the token filter or a payment, so they are always known to config.
+func tokenDecimals(token string) int32 {
+	d, _ := config.TokenDecimals(token)
+	return d
+}
+
+// Chain is the subset of *tron.Client the watcher reads blocks through.
+type Chain interface {
+	GetNowBlock(ctx context.Context) (*tron.Block, error)
+	GetChainHeight(ctx context.Context) (int64, error)
+	GetBlockByNum(ctx context.Context, num int64) (*tron.Block, error)
+	GetTransactionInfoByBlockNum(ctx context.Context, num int64) ([]tron.TransactionInfo, error)
+}
+
+// Store is the subset of repository.Querier the watcher writes through.
+type Store interface {
+	GetPendingPaymentByAnyWallet(ctx context.Context, wallet string) (repository.Payment, error)
+	GetPaymentByWallet(ctx context.Context, arg repository.GetPaymentByWalletParams) (repository.Payment, error)
+	GetOpenPaymentByWalletAndAmount(ctx context.Context, arg repository.GetOpenPaymentByWalletAndAmountParams) (repository.Payment, error)
+	ListDepositWallets(ctx context.Context, wallets []string) ([]string, error)
+	ListPaymentAttempts(ctx context.Context, paymentIds []uuid.UUID) ([]repository.PaymentAttempt, error)
+	CreateTransaction(ctx context.Context, arg repository.CreateTransactionParams) (repository.Transaction, error)
+	CreateLog(ctx context.Context, arg repository.CreateLogParams) error
+	GetWatcherState(ctx context.Context, name string) (repository.GetWatcherStateRow, error)
+	UpsertWatcherState(ctx context.Context, arg repository.UpsertWatcherStateParams) (int64, error)
+	SumPaymentTransfers(ctx context.Context, paymentID uuid.UUID) (pgtype.Numeric, error)
+	UpdatePaymentStatus(ctx context.Context, arg repository.UpdatePaymentStatusParams) (repository.Payment, error)
+	ExecTx(ctx context.Context, fn func(repository.Querier) error) error
+
+	// Reorg handling.
+	ListTransactionsByBlockHashes(ctx context.Context, blockHashes []string) ([]repository.Transaction, error)
+	UpdateTransactionConfirmations(ctx context.Context, arg repository.UpdateTransactionConfirmationsParams) error
+	GetPayment(ctx context.Context, id uuid.UUID) (repository.Payment, error)
+	RevertPaymentConfirmation(ctx context.Context, arg repository.RevertPaymentConfirmationParams) (repository.Payment, error)
+}
+
+// Tracker takes over a transfer once it has been recorded, e.g. to follow it
+// until it has enough confirmations.
+type Tracker interface {
+	Track(ctx context.Context, payment repository.Payment, tx repository.Transaction) error
+}
+
+type nopTracker struct{}
+
+func (nopTracker) Track(context.Context, repository.Payment, repository.Transaction) error {
+	return nil
+}
+
+// Watcher polls the chain from the last persisted height. Progress is saved
+// after every block, so a restart resumes where it stopped; a block that is
+// scanned twice is harmless because recorded transfers are unique. A save
+// only overwrites the height the watcher read, so a second replica running
+// by accident fails its polls instead of interleaving its progress.
+//
+// The hashes of the last reorgDepth blocks are saved with the height. When
+// the chain reorganises below them, the watcher rewinds to the fork point,
+// marks the transfers recorded from orphaned blocks ORPHANED with a
+// TX_REORGED log, reverts the payments they confirmed and scans the new
+// branch, crediting the transfers it includes again.
+type Watcher struct {
+	name    string
+	chain   Chain
+	store   Store
+	tracker Tracker
+	logger  *slog.Logger
+	metrics Metrics
+
+	pollInterval time.Duration
+	batchSize    int
+	startHeight  int64
+	reorgDepth   int
+	maxLag       int64
+	toleranceBps int64
+	tokens       tokenFilter
+	mode         string
+	now          func() time.Time
+
+	stats stats
+}
+
+// Option customises a Watcher.
+type Option func(*Watcher)
+
+// WithName keys the persisted height, so several watchers can share a
+// database.
+func WithName(name string) Option {
+	return func(w *Watcher) { w.name = name }
+}
+
+// WithTracker hands every recorded transfer to t.
+func WithTracker(t Tracker) Option {
+	return func(w *Watcher) { w.tracker = t }
+}
+
+// WithLogger replaces slog.Default.
+func WithLogger(l *slog.Logger) Option {
+	return func(w *Watcher) { w.logger = l }
+}
+
+// New builds a watcher using the blockWatcher, tron and payments sections of
+// cfg. It only credits the payments of cfg's mode, so a testnet watcher never
+// touches live payments.
+func New(chain Chain, store Store, cfg *config.Config, opts ...Option) *Watcher {
+	w := &Watcher{
+		name:         DefaultName,
+		chain:        chain,
+		store:        store,
+		tracker:      nopTracker{},
+		logger:       slog.Default(),
+		metrics:      nopMetrics{},
+		pollInterval: cfg.BlockWatcher.PollInterval.Std(),
+		batchSize:    cfg.BlockWatcher.BatchSize,
+		startHeight:  cfg.BlockWatcher.StartHeight,
+		reorgDepth:   cfg.BlockWatcher.ReorgDepth,
+		maxLag:       cfg.BlockWatcher.MaxLag,
+		toleranceBps: cfg.Payments.UnderpaymentToleranceBps(),
+		tokens:       newTokenFilter(cfg),
+		mode:         cfg.Mode(),
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.logger = slog.New(logging.NewHandler(w.logger.Handler()))
+	return w
+}
+
+// Run polls until ctx is done. It keeps polling without pause while behind
+// the chain head and waits pollInterval once caught up or after an error.
+func (w *Watcher) Run(ctx context.Context) error {
+	w.logger.Info("block watcher started", "name", w.name, "poll_interval", w.pollInterval, "batch_size", w.batchSize)
+	for {
+		caughtUp, err := w.Poll(ctx)
+		if ctx.Err() != nil {
+			w.logger.Info("block watcher stopped", "name", w.name)
+			return nil
+		}
+		if err != nil {
+			w.logger.ErrorContext(ctx, "block poll failed", "name", w.name, "error", err)
+		}
+		if err == nil && !caughtUp {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info("block watcher stopped", "name", w.name)
+			return nil
+		case <-time.After(w.pollInterval):
+		}
+	}
+}
+
+// Poll processes at most batchSize blocks past the persisted height and
+// reports whether it reached the chain head. A block whose parent is not the
+// last block scanned reveals a reorg: Poll then rewinds to the fork point and
+// returns, and the next poll scans the new branch.
+func (w *Watcher) Poll(ctx context.Context) (caughtUp bool, err error) {
+	head, err := w.chain.GetNowBlock(ctx)
+	if err != nil {
+		return false, err
+	}
+	state, err := w.loadState(ctx, head.Number())
+	if err != nil {
+		return false, err
+	}
+	defer func() { w.metrics.ChainLag(w.name, max(head.Number()-state.height, 0)) }()
+
+	end := min(head.Number(), state.height+int64(w.batchSize))
+	for height := state.height + 1; height <= end; height++ {
+		block, err := w.chain.GetBlockByNum(ctx, height)
+		if errors.Is(err, tron.ErrBlockNotFound) {
+			// The node serving this request lags the one that reported head.
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if !state.extends(block) {
+			if err := w.rewind(ctx, state); err != nil {
+				return false, fmt.Errorf("failed to rewind reorg at block %d: %w", height, err)
+			}
+			return false, nil
+		}
+		if err := w.processBlock(ctx, block); err != nil {
+			return false, fmt.Errorf("failed to process block %d: %w", height, err)
+		}
+		state.push(block, w.reorgDepth)
+		if err := w.saveState(ctx, &state); err != nil {
+			return false, fmt.Errorf("failed to save watcher height %d: %w", height, err)
+		}
+	}
+	return end >= head.Number(), nil
+}
+
+func (w *Watcher) processBlock(ctx context.Context, block *tron.Block) error {
+	transfers, err := w.transfers(ctx, block)
+	if err != nil {
+		return err
+	}
+	for _, t := range transfers {
+		token, ok := w.tokens.token(t)
+		if !ok {
+			continue
+		}
+		if err := w.processTransfer(ctx, block, token, t); err != nil {
+			return fmt.Errorf("transfer %s:%d: %w", t.TxID, t.Index, err)
+		}
+	}
+	return nil
+}
+
+// transfers returns the TRX transfers in block and the TRC20 transfers in its
+// receipts. TRC20 transfers are taken from Transfer events rather than from
+// the transfer calls in the block, which miss tokens moved by transferFrom or
+// by another contract. Receipts are only fetched for blocks that call a
+// contract.
+func (w *Watcher) transfers(ctx context.Context, block *tron.Block) ([]tron.Transfer, error) {
+	var transfers []tron.Transfer
+	for _, t := range block.Transfers() {
+		if t.Contract == "" {
+			transfers = append(transfers, t)
+		}
+	}
+	if !callsContract(block) {
+		return transfers, nil
+	}
+
+	infos, err := w.chain.GetTransactionInfoByBlockNum(ctx, block.Number())
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		events, err := tron.ParseTRC20Transfers(info)
+		if err != nil {
+			// One malformed receipt must not stall the watcher.
+			w.logger.Warn("skipping undecodable receipt", "tx_hash", info.ID, "block", block.Number(), "error", err)
+			continue
+		}
+		for _, e := range events {
+			transfers = append(transfers, tron.Transfer{
+				TxID:     e.TxID,
+				Index:    e.LogIndex,
+				From:     e.From,
+				To:       e.To,
+				Contract: e.Contract,
+				Amount:   e.Amount,
+			})
+		}
+	}
+	return transfers, nil
+}
+
+func callsContract(block *tron.Block) bool {
+	for _, tx := range block.Transactions {
+		if !tx.Succeeded() {
+			continue
+		}
+		for _, c := range tx.RawData.Contract {
+			if c.Type == tron.ContractTriggerSmartContract {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tokenFilter maps transfers to the tokens the gateway accepts.
+type tokenFilter struct {
+	// contracts maps a TRC20 contract to the token it credits.
+	contracts map[string]string
+	supported func(token string) bool
+}
+
+func newTokenFilter(cfg *config.Config) tokenFilter {
+	return tokenFilter{
+		contracts: map[string]string{cfg.Tron.USDTContract: config.TokenUSDT},
+		supported: cfg.Payments.SupportsToken,
+	}
+}
+
+// token returns the token t credits, or false for tokens the gateway does
+// not accept.
+func (f tokenFilter) token(t tron.Transfer) (string, bool) {
+	token := config.TokenTRX
+	if t.Contract != "" {
+		var ok bool
+		if token, ok = f.contracts[t.Contract]; !ok {
+			return "", false
+		}
+	}
+	return token, f.supported(token)
+}
+
+func (w *Watcher) processTransfer(ctx context.Context, block *tron.Block, token string, t tron.Transfer) error {
+	decimals := tokenDecimals(token)
+	amount := pgtype.Numeric{Int: t.Amount, Exp: -decimals, Valid: true}
+	payment, err := w.paymentByWallet(ctx, t.To, amount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up payment for %s: %w", t.To, err)
+	}
+	ctx = logging.WithPaymentID(ctx, payment.ID)
+	if payment.Token != token {
+		// A TRX payment is only paid by TransferContracts and a USDT one by
+		// Transfer events of the USDT contract.
+		w.logger.WarnContext(ctx, "transfer in another token than the payment's",
+			"wallet", t.To, "token", token, "payment_token", payment.Token, "tx_hash", t.TxID)
+		return nil
+	}
+	if payment.Status != statusPending && payment.Status != statusDetected && payment.Status != statusUnderpaid {
+		w.logger.WarnContext(ctx, "transfer to wallet without a pending payment",
+			"wallet", t.To, "status", payment.Status, "tx_hash", t.TxID)
+		return nil
+	}
+	if payment.ExpiresAt.Valid && block.Time().After(payment.ExpiresAt.Time) {
+		w.logger.WarnContext(ctx, "transfer after payment expiry",
+			"wallet", t.To, "expires_at", payment.ExpiresAt.Time, "tx_hash", t.TxID)
+		return nil
+	}
+
+	earlier, err := w.store.SumPaymentTransfers(ctx, payment.ID)
+	if err != nil {
+		return fmt.Errorf("failed to sum transfers of payment %s: %w", payment.ID, err)
+	}
+	m := matchAmount(repository.NumericToDecimal(payment.Amount), repository.NumericToDecimal(earlier),
+		decimal.NewFromBigInt(t.Amount, -decimals), w.toleranceBps)
+
+	params := repository.CreateTransactionParams{
+		PaymentID:     payment.ID,
+		TxHash:        t.TxID,
+		TransferIndex: int32(t.Index),
+		Token:         token,
+		FromAddress:   t.From,
+		ToAddress:     t.To,
+		Amount:        amount,
+		BlockNumber:   block.Number(),
+		BlockHash:     block.BlockID,
+	}
+	if t.Contract != "" {
+		params.ContractAddress = &t.Contract
+	}
+	if m.excess.IsPositive() {
+		params.ExcessAmount = repository.DecimalToNumeric(m.excess)
+	}
+	attempt, err := w.walletAttempt(ctx, payment, t.To)
+	if err != nil {
+		return err
+	}
+
+	// The transfer and the status it settles the payment in commit together,
+	// so a restart never finds one without the other.
+	var (
+		tx      repository.Transaction
+		settled string
+	)
+	err = repository.RetryTx(ctx, w.store, func(q repository.Querier) error {
+		var err error
+		if tx, err = q.CreateTransaction(ctx, params); err != nil {
+			return fmt.Errorf("failed to record transaction: %w", err)
+		}
+		if err := w.logDetected(ctx, q, payment, tx, t.Amount, attempt); err != nil {
+			return err
+		}
+		settled, err = w.settle(ctx, q, payment, tx, m)
+		return err
+	})
+	if errors.Is(err, repository.ErrDuplicateTransaction) {
+		// Recorded before a restart, possibly by a release that settled the
+		// payment apart from recording its transfers and stopped in between.
+		// The tracker finds the transfer by itself.
+		return w.resettle(ctx, payment, t.TxID)
+	}
+	if err != nil {
+		return err
+	}
+	if settled != "" {
+		w.metrics.PaymentTransition(settled)
+	}
+	w.logger.InfoContext(ctx, "payment transfer detected",
+		"tx_hash", t.TxID, "token", token, "block", block.Number())
+
+	if err := w.tracker.Track(ctx, payment, tx); err != nil {
+		return fmt.Errorf("failed to hand off transaction: %w", err)
+	}
+	return nil
+}
+
+// paymentByWallet returns the payment wallet was given to. A regenerated
+// payment is still found by its earlier wallets, so a late transfer to one of
+// them is credited until the payment expires. The pending payment holding
+// wallet in any attempt comes first; failing that, the latest payment of the
+// watched mode given wallet, so transfers to settled payments are reported.
+//
+// A wallet shared by the payments of an amount_suffix account only pays the
+// open payment asking for exactly amount; a transfer of any other amount is
+// reported and credited to none.
+func (w *Watcher) paymentByWallet(ctx context.Context, wallet string, amount pgtype.Numeric) (repository.Payment, error) {
+	payment, err := w.store.GetPendingPaymentByAnyWallet(ctx, wallet)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return repository.Payment{}, err
+	}
+	if err != nil || payment.Mode != w.mode {
+		payment, err = w.store.GetPaymentByWallet(ctx, repository.GetPaymentByWalletParams{Wallet: wallet, Mode: w.mode})
+		if err != nil {
+			return repository.Payment{}, err
+		}
+	}
+
+	shared, err := w.store.ListDepositWallets(ctx, []string{wallet})
+	if err != nil {
+		return repository.Payment{}, fmt.Errorf("failed to look up deposit wallet: %w", err)
+	}
+	if len(shared) == 0 {
+		return payment, nil
+	}
+	payment, err = w.store.GetOpenPaymentByWalletAndAmount(ctx, repository.GetOpenPaymentByWalletAndAmountParams{
+		Wallet: wallet,
+		Amount: amount,
+		Mode:   w.mode,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		w.logger.WarnContext(ctx, "transfer to a shared deposit wallet matches no payment amount",
+			"wallet", wallet, "amount", repository.NumericToDecimal(amount).String())
+	}
+	return payment, err
+}
+
+// walletAttempt returns the number of the attempt of payment that generated
+// wallet, or 0 when no attempt records it.
+func (w *Watcher) walletAttempt(ctx context.Context, payment repository.Payment, wallet string) (int32, error) {
+	if wallet == payment.UniqueWallet && payment.AttemptCount != nil {
+		return *payment.AttemptCount, nil
+	}
+	attempts, err := w.store.ListPaymentAttempts(ctx, []uuid.UUID{payment.ID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list attempts of payment %s: %w", payment.ID, err)
+	}
+	for _, a := range attempts {
+		if a.GeneratedWallet == wallet {
+			return a.AttemptNumber, nil
+		}
+	}
+	return 0, nil
+}
+
+// settle moves the payment between PENDING and UNDERPAID to match what it has
+// received, and logs under- and overpayments, through q. A DETECTED payment
+// that is funded stays DETECTED. Confirmation stays with the tracker. It
+// returns the status the payment moved to, if any.
+func (w *Watcher) settle(ctx context.Context, q repository.Querier, payment repository.Payment, tx repository.Transaction, m match) (string, error) {
+	status := settledStatus(payment, m)
+	moved, err := w.setStatus(ctx, q, payment, status)
+	if err != nil {
+		return "", err
+	}
+	if !moved {
+		status = ""
+	}
+
+	data := w.amountLog(payment, tx.TxHash, m)
+	if !m.funded {
+		w.logger.InfoContext(ctx, "payment underpaid", "received", data.Received, "requested", data.Requested)
+		return status, w.log(ctx, q, payment, EventUnderpaid,
+			fmt.Sprintf("received %s of %s", data.Received, data.Requested), data)
+	}
+	if m.excess.IsPositive() {
+		w.logger.InfoContext(ctx, "payment overpaid", "excess", data.Excess)
+		return status, w.log(ctx, q, payment, EventOverpaid,
+			fmt.Sprintf("received %s of %s, %s in excess", data.Received, data.Requested, data.Excess), data)
+	}
+	return status, nil
+}
+
+// resettle settles payment again from the transfers it has received, after
+// one of them turned out to be recorded already. It only logs an
+// underpayment when it has to move the payment, i.e. when the run that
+// recorded the transfer did not settle it.
+func (w *Watcher) resettle(ctx context.Context, payment repository.Payment, txHash string) error {
+	received, err := w.store.SumPaymentTransfers(ctx, payment.ID)
+	if err != nil {
+		return fmt.Errorf("failed to sum transfers of payment %s: %w", payment.ID, err)
+	}
+	m := matchAmount(repository.NumericToDecimal(payment.Amount), repository.NumericToDecimal(received),
+		decimal.Zero, w.toleranceBps)
+	status := settledStatus(payment, m)
+
+	var moved bool
+	err = repository.RetryTx(ctx, w.store, func(q repository.Querier) error {
+		var err error
+		if moved, err = w.setStatus(ctx, q, payment, status); err != nil || !moved || m.funded {
+			return err
+		}
+		data := w.amountLog(payment, txHash, m)
+		return w.log(ctx, q, payment, EventUnderpaid,
+			fmt.Sprintf("received %s of %s", data.Received, data.Requested), data)
+	})
+	if err != nil {
+		return err
+	}
+	if moved {
+		w.metrics.PaymentTransition(status)
+		w.logger.WarnContext(ctx, "settled payment of a transfer recorded before a restart", "status", status)
+	}
+	return nil
+}
+
+// settledStatus is the status payment takes once it has received m: UNDERPAID
+// while it is short, PENDING again once an UNDERPAID payment is funded, and
+// its current status otherwise.
+func settledStatus(payment repository.Payment, m match) string {
+	switch {
+	case !m.funded:
+		return statusUnderpaid
+	case payment.Status == statusUnderpaid:
+		return statusPending
+	}
+	return payment.Status
+}
+
+// setStatus moves payment to status through q unless it is already there,
+// and reports whether it did. A payment that changed status concurrently,
+// e.g. because it expired, is left alone.
+func (w *Watcher) setStatus(ctx context.Context, q repository.Querier, payment repository.Payment, status string) (bool, error) {
+	if payment.Status == status {
+		return false, nil
+	}
+	current, err := q.GetPayment(ctx, payment.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to load payment: %w", err)
+	}
+	_, err = q.UpdatePaymentStatus(ctx, repository.UpdatePaymentStatusParams{
+		ToStatus:   status,
+		ID:         payment.ID,
+		FromStatus: payment.Status,
+		Version:    current.Version,
+	})
+	if errors.Is(err, repository.ErrPaymentStatusChanged) {
+		w.logger.WarnContext(ctx, "payment status changed while crediting a transfer",
+			"from", payment.Status, "to", status)
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to mark payment %s: %w", status, err)
+	}
+	return true, nil
+}
+
+type amountLog struct {
+	TxHash    string `json:"tx_hash"`
+	Requested string `json:"requested"`
+	Received  string `json:"received"`
+	Excess    string `json:"excess,omitempty"`
+}
+
+// amountLog describes what payment has received in m, up to the transfer
+// txHash.
+func (w *Watcher) amountLog(payment repository.Payment, txHash string, m match) amountLog {
+	decimals := tokenDecimals(payment.Token)
+	data := amountLog{
+		TxHash:    txHash,
+		Requested: repository.NumericToDecimal(payment.Amount).StringFixed(decimals),
+		Received:  m.received.StringFixed(decimals),
+	}
+	if m.excess.IsPositive() {
+		data.Excess = m.excess.StringFixed(decimals)
+	}
+	return data
+}
+
+type detectedLog struct {
+	TxHash      string `json:"tx_hash"`
+	Token       string `json:"token"`
+	Amount      string `json:"amount"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	BlockNumber int64  `json:"block_number"`
+	// Attempt is the attempt of the payment whose wallet received the
+	// transfer, when one records it.
+	Attempt int32 `json:"attempt,omitempty"`
+}
+
+func (w *Watcher) logDetected(ctx context.Context, lw logWriter, payment repository.Payment, tx repository.Transaction, amount *big.Int, attempt int32) error {
+	return w.log(ctx, lw, payment, EventTxDetected,
+		fmt.Sprintf("%s %s received in block %d", formatAmount(amount, tx.Token), tx.Token, tx.BlockNumber),
+		detectedLog{
+			TxHash:      tx.TxHash,
+			Token:       tx.Token,
+			Amount:      formatAmount(amount, tx.Token),
+			From:        tx.FromAddress,
+			To:          tx.ToAddress,
+			BlockNumber: tx.BlockNumber,
+			Attempt:     attempt,
+		})
+}
+
+func (w *Watcher) log(ctx context.Context, lw logWriter, payment repository.Payment, event, msg string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode log: %w", err)
+	}
+	err = lw.CreateLog(ctx, repository.CreateLogParams{
+		PaymentID: repository.UUIDPtr(payment.ID),
+		EventType: event,
+		Message:   &msg,
+		RawData:   raw,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %s log: %w", event, err)
+	}
+	return nil
+}
+
+// formatAmount renders a base-unit amount in units of token, e.g. 2500000
+// sun -> "2.500000".
+func formatAmount(amount *big.Int, token string) string {
+	decimals := tokenDecimals(token)
+	return decimal.NewFromBigInt(amount, -decimals).StringFixed(decimals)
+}
@@ -0,0 +1,1269 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/tron/fixtures"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+// Wallets paid in testdata/block_transfers.json.
+const (
+	trxWallet  = "TDvSsdrNM5eeXNL3czpa6AxLDHZA9nwe9K"
+	usdtWallet = "TMVQGm1qAQYVdetCeGRRkTWYYrLXuHK2HC"
+)
+
+// regeneratedWallet replaces a payment's wallet in regeneration tests.
+const regeneratedWallet = "TXYZopYRdj2D9XRtbG411XZZ3kM5VkAeBf"
+
+// fakeChain serves canned blocks; heights without a fixture are empty blocks.
+type fakeChain struct {
+	mu       sync.Mutex
+	head     int64
+	blocks   map[int64]*tron.Block
+	missing  map[int64]bool
+	requests []int64
+	// included maps a transaction to the block it is in.
+	included map[string]int64
+	// receipts holds the transaction infos of a block.
+	receipts map[int64][]tron.TransactionInfo
+	// headErr fails GetChainHeight.
+	headErr error
+}
+
+func newFakeChain(head int64) *fakeChain {
+	return &fakeChain{
+		head:     head,
+		blocks:   make(map[int64]*tron.Block),
+		missing:  make(map[int64]bool),
+		included: make(map[string]int64),
+		receipts: make(map[int64][]tron.TransactionInfo),
+	}
+}
+
+func (c *fakeChain) GetNowBlock(context.Context) (*tron.Block, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return emptyBlock(c.head), nil
+}
+
+func (c *fakeChain) GetChainHeight(context.Context) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.head, c.headErr
+}
+
+func (c *fakeChain) GetBlockByNum(_ context.Context, num int64) (*tron.Block, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, num)
+	if num > c.head || c.missing[num] {
+		return nil, tron.ErrBlockNotFound
+	}
+	// Link the block to whatever is at the height below, so replacing a
+	// block forks the chain from there.
+	b := *c.block(num)
+	b.BlockHeader.RawData.ParentHash = c.block(num - 1).BlockID
+	return &b, nil
+}
+
+func (c *fakeChain) block(num int64) *tron.Block {
+	if b, ok := c.blocks[num]; ok {
+		return b
+	}
+	return emptyBlock(num)
+}
+
+func (c *fakeChain) GetTransactionInfoByID(_ context.Context, txID string) (*tron.TransactionInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	num, ok := c.included[txID]
+	if !ok {
+		return nil, tron.ErrTransactionNotFound
+	}
+	return &tron.TransactionInfo{ID: txID, BlockNumber: num}, nil
+}
+
+func (c *fakeChain) GetTransactionInfoByBlockNum(_ context.Context, num int64) ([]tron.TransactionInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.receipts[num], nil
+}
+
+func (c *fakeChain) setHead(head int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.head = head
+}
+
+func emptyBlock(num int64) *tron.Block {
+	var b tron.Block
+	b.BlockID = fmt.Sprintf("%016x", num)
+	b.BlockHeader.RawData.Number = num
+	return &b
+}
+
+// loadBlock decodes a fixture from testdata and moves it to height num.
+func loadBlock(t *testing.T, name string, num int64) *tron.Block {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	var b tron.Block
+	require.NoError(t, json.Unmarshal(data, &b))
+	b.BlockHeader.RawData.Number = num
+	return &b
+}
+
+// loadReceipts decodes transaction infos from testdata and moves them to
+// height num.
+func loadReceipts(t *testing.T, name string, num int64) []tron.TransactionInfo {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	var infos []tron.TransactionInfo
+	require.NoError(t, json.Unmarshal(data, &infos))
+	for i := range infos {
+		infos[i].BlockNumber = num
+	}
+	return infos
+}
+
+// memStore is an in-memory Store with unique transfers, like the database.
+// The embedded Querier is nil: it only fills the methods ExecTx's Querier
+// has that no test calls.
+type memStore struct {
+	repository.Querier
+
+	mu       sync.Mutex
+	payments map[string]repository.Payment
+	attempts []repository.PaymentAttempt
+	txs      []repository.Transaction
+	logs     []repository.CreateLogParams
+	outbox   []repository.CreateOutboxEventParams
+	heights  map[string]int64
+	// deposit holds the deposit wallets of amount_suffix accounts.
+	deposit map[string]bool
+	// recent holds the remembered blocks saved with each height.
+	recent     map[string][]byte
+	failTx     error
+	failStatus error
+	failOutbox error
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		payments: make(map[string]repository.Payment),
+		heights:  make(map[string]int64),
+		deposit:  make(map[string]bool),
+		recent:   make(map[string][]byte),
+	}
+}
+
+// fixtureAmounts is what the transfers in block_transfers.json pay each
+// wallet.
+var fixtureAmounts = map[string]string{trxWallet: "2.5", usdtWallet: "100"}
+
+// walletTokens is the token a test payment on a wallet is requested in;
+// wallets not listed take USDT.
+var walletTokens = map[string]string{trxWallet: config.TokenTRX}
+
+// addPayment adds a payment for exactly what block_transfers.json pays to
+// wallet.
+func (s *memStore) addPayment(wallet, status string) repository.Payment {
+	return s.addPaymentFor(wallet, status, fixtureAmounts[wallet])
+}
+
+func (s *memStore) addPaymentFor(wallet, status, amount string) repository.Payment {
+	token, ok := walletTokens[wallet]
+	if !ok {
+		token = config.TokenUSDT
+	}
+	p := repository.Payment{
+		ID:           uuid.New(),
+		UniqueWallet: wallet,
+		Token:        token,
+		Status:       status,
+		Amount:       repository.DecimalToNumeric(decimal.RequireFromString(amount)),
+	}
+	s.payments[wallet] = p
+	return p
+}
+
+// addSharedPayment adds a payment of amount paid into the deposit wallet
+// wallet, keyed by wallet and amount.
+func (s *memStore) addSharedPayment(wallet, amount string) repository.Payment {
+	s.deposit[wallet] = true
+	p := s.addPaymentFor(wallet, statusPending, amount)
+	delete(s.payments, wallet)
+	s.payments[wallet+" "+amount] = p
+	s.attempts = append(s.attempts, repository.PaymentAttempt{PaymentID: p.ID, AttemptNumber: 1, GeneratedWallet: wallet})
+	return p
+}
+
+func (s *memStore) payment(wallet string) repository.Payment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.payments[wallet]
+}
+
+// regenerate moves the payment on wallet to next, recording both wallets as
+// its attempts like the regenerate endpoint does.
+func (s *memStore) regenerate(wallet, next string) repository.Payment {
+	p := s.payments[wallet]
+	delete(s.payments, wallet)
+	attempts := int32(2)
+	p.UniqueWallet, p.AttemptCount = next, &attempts
+	s.payments[next] = p
+	s.attempts = append(s.attempts,
+		repository.PaymentAttempt{PaymentID: p.ID, AttemptNumber: 1, GeneratedWallet: wallet},
+		repository.PaymentAttempt{PaymentID: p.ID, AttemptNumber: 2, GeneratedWallet: next},
+	)
+	return p
+}
+
+func (s *memStore) GetPaymentByWallet(_ context.Context, arg repository.GetPaymentByWalletParams) (repository.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.payments[arg.Wallet]; ok && paymentMode(p) == arg.Mode {
+		return p, nil
+	}
+	for _, a := range s.attempts {
+		if a.GeneratedWallet != arg.Wallet {
+			continue
+		}
+		for _, p := range s.payments {
+			if p.ID == a.PaymentID && paymentMode(p) == arg.Mode {
+				return p, nil
+			}
+		}
+	}
+	return repository.Payment{}, pgx.ErrNoRows
+}
+
+func (s *memStore) GetPendingPaymentByAnyWallet(_ context.Context, wallet string) (repository.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range s.attempts {
+		if a.GeneratedWallet != wallet {
+			continue
+		}
+		for _, p := range s.payments {
+			if p.ID == a.PaymentID && (p.Status == statusPending || p.Status == statusDetected || p.Status == statusUnderpaid) {
+				p.Mode = paymentMode(p)
+				return p, nil
+			}
+		}
+	}
+	return repository.Payment{}, pgx.ErrNoRows
+}
+
+func (s *memStore) ListDepositWallets(_ context.Context, wallets []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []string
+	for _, w := range wallets {
+		if s.deposit[w] {
+			out = append(out, w)
+		}
+	}
+	return out, nil
+}
+
+func (s *memStore) GetOpenPaymentByWalletAndAmount(_ context.Context, arg repository.GetOpenPaymentByWalletAndAmountParams) (repository.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.payments {
+		open := p.Status == statusPending || p.Status == statusDetected || p.Status == statusUnderpaid
+		if p.UniqueWallet == arg.Wallet && open && paymentMode(p) == arg.Mode &&
+			repository.NumericToDecimal(p.Amount).Equal(repository.NumericToDecimal(arg.Amount)) {
+			return p, nil
+		}
+	}
+	return repository.Payment{}, pgx.ErrNoRows
+}
+
+// paymentMode is the mode of p; payments added without one are live.
+func paymentMode(p repository.Payment) string {
+	if p.Mode == "" {
+		return config.ModeLive
+	}
+	return p.Mode
+}
+
+func (s *memStore) CreateTransaction(_ context.Context, arg repository.CreateTransactionParams) (repository.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failTx != nil {
+		return repository.Transaction{}, s.failTx
+	}
+	for _, tx := range s.txs {
+		if tx.TxHash == arg.TxHash && tx.TransferIndex == arg.TransferIndex && tx.Status != txStatusOrphaned {
+			return repository.Transaction{}, repository.ErrDuplicateTransaction
+		}
+	}
+	tx := repository.Transaction{
+		ID:              uuid.New(),
+		PaymentID:       arg.PaymentID,
+		TxHash:          arg.TxHash,
+		TransferIndex:   arg.TransferIndex,
+		Token:           arg.Token,
+		ContractAddress: arg.ContractAddress,
+		FromAddress:     arg.FromAddress,
+		ToAddress:       arg.ToAddress,
+		Amount:          arg.Amount,
+		BlockNumber:     arg.BlockNumber,
+		BlockHash:       arg.BlockHash,
+		Status:          "DETECTED",
+		ExcessAmount:    arg.ExcessAmount,
+	}
+	s.txs = append(s.txs, tx)
+	return tx, nil
+}
+
+func (s *memStore) SumPaymentTransfers(_ context.Context, paymentID uuid.UUID) (pgtype.Numeric, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	total := decimal.Zero
+	for _, tx := range s.txs {
+		if tx.PaymentID == paymentID && tx.Status != txStatusOrphaned {
+			total = total.Add(repository.NumericToDecimal(tx.Amount))
+		}
+	}
+	return repository.DecimalToNumeric(total), nil
+}
+
+func (s *memStore) UpdatePaymentStatus(_ context.Context, arg repository.UpdatePaymentStatusParams) (repository.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !repository.CanTransitionPayment(arg.FromStatus, arg.ToStatus) {
+		return repository.Payment{}, repository.ErrInvalidPaymentTransition
+	}
+	if s.failStatus != nil {
+		return repository.Payment{}, s.failStatus
+	}
+	for wallet, p := range s.payments {
+		if p.ID != arg.ID {
+			continue
+		}
+		if p.Version != arg.Version {
+			return repository.Payment{}, repository.ErrVersionConflict
+		}
+		if p.Status != arg.FromStatus {
+			break
+		}
+		p.Status = arg.ToStatus
+		p.Version++
+		s.payments[wallet] = p
+		return p, nil
+	}
+	return repository.Payment{}, repository.ErrPaymentStatusChanged
+}
+
+func (s *memStore) CreateLog(_ context.Context, arg repository.CreateLogParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logs = append(s.logs, arg)
+	return nil
+}
+
+// ExecTx runs fn against the store itself and, like a rolled back
+// transaction, undoes its payment, transaction, log and outbox writes when
+// it fails.
+func (s *memStore) ExecTx(_ context.Context, fn func(repository.Querier) error) error {
+	s.mu.Lock()
+	payments := maps.Clone(s.payments)
+	txs, logs, outbox := slices.Clone(s.txs), slices.Clone(s.logs), slices.Clone(s.outbox)
+	s.mu.Unlock()
+
+	err := fn(s)
+	if err != nil {
+		s.mu.Lock()
+		s.payments, s.txs, s.logs, s.outbox = payments, txs, logs, outbox
+		s.mu.Unlock()
+	}
+	return err
+}
+
+func (s *memStore) CreateOutboxEvent(_ context.Context, arg repository.CreateOutboxEventParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failOutbox != nil {
+		return s.failOutbox
+	}
+	s.outbox = append(s.outbox, arg)
+	return nil
+}
+
+// outboxTypes lists the event types recorded in the outbox, oldest first.
+func (s *memStore) outboxTypes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []string
+	for _, e := range s.outbox {
+		out = append(out, e.EventType)
+	}
+	return out
+}
+
+func (s *memStore) height(name string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.heights[name]
+}
+
+func (s *memStore) GetWatcherState(_ context.Context, name string) (repository.GetWatcherStateRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.heights[name]
+	if !ok {
+		return repository.GetWatcherStateRow{}, pgx.ErrNoRows
+	}
+	return repository.GetWatcherStateRow{LastHeight: h, RecentBlocks: s.recent[name]}, nil
+}
+
+func (s *memStore) UpsertWatcherState(_ context.Context, arg repository.UpsertWatcherStateParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.heights[arg.Name]
+	if ok && (arg.ExpectedHeight == nil || *arg.ExpectedHeight != h) {
+		return 0, repository.ErrStateConflict
+	}
+	s.heights[arg.Name] = arg.LastHeight
+	s.recent[arg.Name] = arg.RecentBlocks
+	return arg.LastHeight, nil
+}
+
+func (s *memStore) ListTransactionsByBlockHashes(_ context.Context, hashes []string) ([]repository.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []repository.Transaction
+	for _, tx := range s.txs {
+		if slices.Contains(hashes, tx.BlockHash) {
+			out = append(out, tx)
+		}
+	}
+	return out, nil
+}
+
+func (s *memStore) GetPayment(_ context.Context, id uuid.UUID) (repository.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.payments {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return repository.Payment{}, pgx.ErrNoRows
+}
+
+func (s *memStore) RevertPaymentConfirmation(_ context.Context, arg repository.RevertPaymentConfirmationParams) (repository.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !repository.CanRevertConfirmation(arg.ToStatus) {
+		return repository.Payment{}, repository.ErrInvalidPaymentTransition
+	}
+	for wallet, p := range s.payments {
+		if p.ID != arg.ID {
+			continue
+		}
+		if p.Version != arg.Version {
+			return repository.Payment{}, repository.ErrVersionConflict
+		}
+		if p.Status != statusConfirmed {
+			break
+		}
+		p.Status, p.ConfirmedAt = arg.ToStatus, pgtype.Timestamptz{}
+		p.Version++
+		s.payments[wallet] = p
+		return p, nil
+	}
+	return repository.Payment{}, repository.ErrPaymentStatusChanged
+}
+
+type recordingTracker struct {
+	mu  sync.Mutex
+	txs []repository.Transaction
+}
+
+func (r *recordingTracker) Track(_ context.Context, _ repository.Payment, tx repository.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.txs = append(r.txs, tx)
+	return nil
+}
+
+func testConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Payments.SupportedTokens = []string{config.TokenTRX, config.TokenUSDT}
+	cfg.BlockWatcher.BatchSize = 10
+	cfg.BlockWatcher.PollInterval = config.Duration(10 * time.Millisecond)
+	cfg.ApplyDefaults()
+	return cfg
+}
+
+func TestWatcher_MatchesTransfers(t *testing.T) {
+	chain := newFakeChain(1000)
+	chain.blocks[1000] = loadBlock(t, "block_transfers.json", 1000)
+	chain.receipts[1000] = loadReceipts(t, "txinfo_transfers.json", 1000)
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	trxPayment := store.addPayment(trxWallet, "PENDING")
+	usdtPayment := store.addPayment(usdtWallet, "PENDING")
+	tracker := &recordingTracker{}
+
+	w := New(chain, store, testConfig(), WithTracker(tracker))
+	caughtUp, err := w.Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.True(t, caughtUp)
+	assert.Equal(t, int64(1000), store.heights[DefaultName])
+
+	require.Len(t, store.txs, 2)
+	trx, usdt := store.txs[0], store.txs[1]
+
+	assert.Equal(t, trxPayment.ID, trx.PaymentID)
+	assert.Equal(t, config.TokenTRX, trx.Token)
+	assert.Nil(t, trx.ContractAddress)
+	assert.Equal(t, int64(2500000), trx.Amount.Int.Int64())
+	assert.Equal(t, int32(-6), trx.Amount.Exp)
+	assert.Equal(t, int64(1000), trx.BlockNumber)
+
+	assert.Equal(t, usdtPayment.ID, usdt.PaymentID)
+	assert.Equal(t, config.TokenUSDT, usdt.Token)
+	require.NotNil(t, usdt.ContractAddress)
+	assert.Equal(t, "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", *usdt.ContractAddress)
+	assert.Equal(t, int64(100000000), usdt.Amount.Int.Int64())
+
+	require.Len(t, store.logs, 2)
+	assert.Equal(t, EventTxDetected, store.logs[0].EventType)
+	assert.Equal(t, "2.500000 TRX received in block 1000", *store.logs[0].Message)
+	assert.JSONEq(t, `{"tx_hash":"1111111111111111111111111111111111111111111111111111111111111111","token":"TRX","amount":"2.500000",
+		"from":"TKTX96CBxr5kvhjsDHcqoiPWZageGxoTW3","to":"TDvSsdrNM5eeXNL3czpa6AxLDHZA9nwe9K","block_number":1000}`, string(store.logs[0].RawData))
+	assert.Equal(t, usdtPayment.ID, *store.logs[1].PaymentID)
+
+	assert.Len(t, tracker.txs, 2)
+}
+
+func TestWatcher_TestMode(t *testing.T) {
+	chain := newFakeChain(1000)
+	chain.blocks[1000] = loadBlock(t, "block_transfers.json", 1000)
+	chain.receipts[1000] = loadReceipts(t, "txinfo_transfers.json", 1000)
+	store := newMemStore()
+	store.heights["tron_test"] = 999
+	test := store.addPayment(trxWallet, "PENDING")
+	test.Mode = config.ModeTest
+	store.payments[trxWallet] = test
+	store.addPayment(usdtWallet, "PENDING")
+
+	w := New(chain, store, testConfig().ForTestMode(), WithName("tron_test"))
+	_, err := w.Poll(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, store.txs, 1, "the live payment is left to the live watcher")
+	assert.Equal(t, test.ID, store.txs[0].PaymentID)
+	assert.Equal(t, int64(1000), store.heights["tron_test"])
+	assert.NotContains(t, store.heights, DefaultName)
+}
+
+// contractCallBlock is a block with one successful contract call, whose
+// transfers are only visible in its receipt.
+func contractCallBlock(num int64, txID string) *tron.Block {
+	b := emptyBlock(num)
+	b.Transactions = []tron.Transaction{{
+		TxID:    txID,
+		RawData: tron.TransactionRawData{Contract: []tron.Contract{{Type: tron.ContractTriggerSmartContract}}},
+		Ret:     []tron.TransactionResult{{ContractRet: "SUCCESS"}},
+	}}
+	return b
+}
+
+func TestWatcher_MultiTransferReceipt(t *testing.T) {
+	chain := newFakeChain(1000)
+	receipts := loadReceipts(t, "txinfo_multi_transfer.json", 1000)
+	chain.blocks[1000] = contractCallBlock(1000, receipts[0].ID)
+	chain.receipts[1000] = receipts
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	store.addPaymentFor(usdtWallet, "PENDING", "10")
+	// The receipt pays both wallets in USDT.
+	p := store.addPaymentFor(trxWallet, "PENDING", "20")
+	p.Token = config.TokenUSDT
+	store.payments[trxWallet] = p
+
+	_, err := New(chain, store, testConfig()).Poll(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, store.txs, 2)
+	for _, tx := range store.txs {
+		assert.Equal(t, receipts[0].ID, tx.TxHash)
+		assert.Equal(t, config.TokenUSDT, tx.Token)
+	}
+	assert.Equal(t, usdtWallet, store.txs[0].ToAddress)
+	assert.Equal(t, int32(0), store.txs[0].TransferIndex)
+	assert.Equal(t, int64(10000000), store.txs[0].Amount.Int.Int64())
+	assert.Equal(t, trxWallet, store.txs[1].ToAddress)
+	assert.Equal(t, int32(2), store.txs[1].TransferIndex)
+	assert.Equal(t, int64(20000000), store.txs[1].Amount.Int.Int64())
+}
+
+// The fixture client replays recorded chain data to every chain consumer.
+var (
+	_ TrackerChain = (*fixtures.FixtureClient)(nil)
+	_ PendingChain = (*fixtures.FixtureClient)(nil)
+)
+
+func TestWatcher_RecordedScenarios(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		// payments maps a wallet to the token and amount it is paid in.
+		payments map[string][2]string
+		// want lists the credited wallets with the amount credited, in
+		// block order.
+		want []string
+	}{
+		{fixtures.ScenarioTRXPayment, map[string][2]string{trxWallet: {config.TokenTRX, "2.5"}}, []string{trxWallet + " 2500000"}},
+		// The reverted transfer call in the same block credits nothing.
+		{fixtures.ScenarioUSDTPayment, map[string][2]string{usdtWallet: {config.TokenUSDT, "100"}}, []string{usdtWallet + " 100000000"}},
+		// The TRX transfer is not in the token trxWallet's payment asks for.
+		{fixtures.ScenarioMultiTransfer, map[string][2]string{
+			usdtWallet: {config.TokenUSDT, "10"},
+			trxWallet:  {config.TokenUSDT, "20"},
+		}, []string{usdtWallet + " 10000000", trxWallet + " 20000000"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			scenario, err := fixtures.Load(tc.scenario)
+			require.NoError(t, err)
+			store := newMemStore()
+			store.heights[DefaultName] = scenario.First() - 1
+			for wallet, p := range tc.payments {
+				payment := store.addPaymentFor(wallet, statusPending, p[1])
+				payment.Token = p[0]
+				store.payments[wallet] = payment
+			}
+
+			caughtUp, err := New(fixtures.NewFixtureClient(scenario), store, testConfig()).Poll(context.Background())
+
+			require.NoError(t, err)
+			assert.True(t, caughtUp)
+			assert.Equal(t, scenario.Last(), store.heights[DefaultName])
+			var got []string
+			for _, tx := range store.txs {
+				assert.Equal(t, store.payment(tx.ToAddress).ID, tx.PaymentID)
+				got = append(got, fmt.Sprintf("%s %s", tx.ToAddress, tx.Amount.Int))
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestWatcher_TRC20RequiresReceipt(t *testing.T) {
+	chain := newFakeChain(1000)
+	// The USDT transfer call is in the block but no receipt has a Transfer
+	// event for it, e.g. because the token contract swallowed the call.
+	chain.blocks[1000] = loadBlock(t, "block_transfers.json", 1000)
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	store.addPayment(usdtWallet, "PENDING")
+
+	_, err := New(chain, store, testConfig()).Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, store.txs)
+}
+
+func TestWatcher_SkipsReceiptsWithoutContractCalls(t *testing.T) {
+	chain := newFakeChain(1001)
+	chain.blocks[1001] = loadBlock(t, "block_empty.json", 1001)
+	chain.receipts[1001] = loadReceipts(t, "txinfo_transfers.json", 1001)
+	store := newMemStore()
+	store.heights[DefaultName] = 1000
+	store.addPayment(usdtWallet, "PENDING")
+
+	_, err := New(chain, store, testConfig()).Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, store.txs)
+}
+
+func TestWatcher_SkipsUnmatchedTransfers(t *testing.T) {
+	testCases := []struct {
+		name  string
+		setup func(*memStore, *config.Config)
+	}{
+		{"unknown wallets", func(*memStore, *config.Config) {}},
+		{"non-pending payments", func(s *memStore, _ *config.Config) {
+			s.addPayment(trxWallet, "CONFIRMED")
+			s.addPayment(usdtWallet, "EXPIRED")
+		}},
+		{"unsupported tokens", func(s *memStore, cfg *config.Config) {
+			s.addPayment(trxWallet, "PENDING")
+			cfg.Payments.SupportedTokens = []string{config.TokenUSDT}
+			cfg.Tron.USDTContract = "TXYZopYRdj2D9XRtbG411XZZ3kM5VkAeBf"
+		}},
+		{"other token than the payment's", func(s *memStore, _ *config.Config) {
+			// The fixture pays trxWallet in TRX and usdtWallet in USDT.
+			for wallet, token := range map[string]string{trxWallet: config.TokenUSDT, usdtWallet: config.TokenTRX} {
+				p := s.addPayment(wallet, "PENDING")
+				p.Token = token
+				s.payments[wallet] = p
+			}
+		}},
+		{"test payments", func(s *memStore, _ *config.Config) {
+			for _, wallet := range []string{trxWallet, usdtWallet} {
+				p := s.addPayment(wallet, "PENDING")
+				p.Mode = config.ModeTest
+				s.payments[wallet] = p
+			}
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			chain := newFakeChain(1000)
+			chain.blocks[1000] = loadBlock(t, "block_transfers.json", 1000)
+			chain.receipts[1000] = loadReceipts(t, "txinfo_transfers.json", 1000)
+			store := newMemStore()
+			store.heights[DefaultName] = 999
+			cfg := testConfig()
+			tc.setup(store, cfg)
+
+			_, err := New(chain, store, cfg).Poll(context.Background())
+
+			require.NoError(t, err)
+			assert.Empty(t, store.txs)
+			assert.Empty(t, store.logs)
+			assert.Equal(t, int64(1000), store.heights[DefaultName])
+		})
+	}
+}
+
+func TestWatcher_EmptyBlocksAdvanceHeight(t *testing.T) {
+	chain := newFakeChain(1003)
+	chain.blocks[1001] = loadBlock(t, "block_empty.json", 1001)
+	store := newMemStore()
+	store.heights[DefaultName] = 1000
+
+	caughtUp, err := New(chain, store, testConfig()).Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.True(t, caughtUp)
+	assert.Equal(t, []int64{1001, 1002, 1003}, chain.requests)
+	assert.Equal(t, int64(1003), store.heights[DefaultName])
+}
+
+func TestWatcher_CapsCatchUpBatch(t *testing.T) {
+	chain := newFakeChain(1500)
+	store := newMemStore()
+	store.heights[DefaultName] = 1000
+	cfg := testConfig()
+	cfg.BlockWatcher.BatchSize = 25
+	w := New(chain, store, cfg)
+
+	caughtUp, err := w.Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.False(t, caughtUp)
+	assert.Len(t, chain.requests, 25)
+	assert.Equal(t, int64(1025), store.heights[DefaultName])
+
+	_, err = w.Poll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1026), chain.requests[25])
+	assert.Equal(t, int64(1050), store.heights[DefaultName])
+}
+
+func TestWatcher_ResumesAfterRestart(t *testing.T) {
+	chain := newFakeChain(1000)
+	chain.blocks[1000] = loadBlock(t, "block_transfers.json", 1000)
+	chain.receipts[1000] = loadReceipts(t, "txinfo_transfers.json", 1000)
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	store.addPayment(trxWallet, "PENDING")
+	store.addPayment(usdtWallet, "PENDING")
+
+	// The first run records the transfers but dies before saving the height.
+	first := New(chain, store, testConfig())
+	require.NoError(t, first.processBlock(context.Background(), chain.blocks[1000]))
+	require.Len(t, store.txs, 2)
+	require.Len(t, store.logs, 2)
+
+	tracker := &recordingTracker{}
+	second := New(chain, store, testConfig(), WithTracker(tracker))
+	_, err := second.Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1000}, chain.requests, "resumes from the persisted height")
+	assert.Len(t, store.txs, 2, "re-scanned transfers are not recorded twice")
+	assert.Len(t, store.logs, 2)
+	assert.Empty(t, tracker.txs)
+	assert.Equal(t, int64(1000), store.heights[DefaultName])
+}
+
+func TestWatcher_StartHeight(t *testing.T) {
+	t.Run("chain head when unset", func(t *testing.T) {
+		chain := newFakeChain(5000)
+		store := newMemStore()
+
+		_, err := New(chain, store, testConfig()).Poll(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{5000}, chain.requests)
+		assert.Equal(t, int64(5000), store.heights[DefaultName])
+	})
+
+	t.Run("configured height", func(t *testing.T) {
+		chain := newFakeChain(5000)
+		store := newMemStore()
+		cfg := testConfig()
+		cfg.BlockWatcher.StartHeight = 4995
+
+		_, err := New(chain, store, cfg, WithName("tron-nile")).Poll(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{4995, 4996, 4997, 4998, 4999, 5000}, chain.requests)
+		assert.Equal(t, int64(5000), store.heights["tron-nile"])
+	})
+}
+
+func TestWatcher_StoreErrorDoesNotAdvance(t *testing.T) {
+	chain := newFakeChain(1000)
+	chain.blocks[1000] = loadBlock(t, "block_transfers.json", 1000)
+	chain.receipts[1000] = loadReceipts(t, "txinfo_transfers.json", 1000)
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	store.addPayment(trxWallet, "PENDING")
+	store.failTx = errors.New("connection reset")
+
+	_, err := New(chain, store, testConfig()).Poll(context.Background())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to process block 1000")
+	assert.Contains(t, err.Error(), "connection reset")
+	assert.Equal(t, int64(999), store.heights[DefaultName])
+}
+
+// contendedStore runs race once, right after the next watcher state is read.
+type contendedStore struct {
+	*memStore
+	race func()
+}
+
+func (s *contendedStore) GetWatcherState(ctx context.Context, name string) (repository.GetWatcherStateRow, error) {
+	row, err := s.memStore.GetWatcherState(ctx, name)
+	if race := s.race; race != nil {
+		s.race = nil
+		race()
+	}
+	return row, err
+}
+
+func TestWatcher_SecondReplicaConflicts(t *testing.T) {
+	chain := newFakeChain(1002)
+	store := newMemStore()
+	store.heights[DefaultName] = 1000
+	contended := &contendedStore{memStore: store}
+	first := New(chain, contended, testConfig())
+	second := New(chain, store, testConfig())
+
+	// The second replica scans the same blocks between the first reading
+	// the height and saving its progress.
+	contended.race = func() {
+		_, err := second.Poll(context.Background())
+		require.NoError(t, err)
+	}
+	_, err := first.Poll(context.Background())
+
+	require.ErrorIs(t, err, repository.ErrStateConflict)
+	assert.Equal(t, int64(1002), store.heights[DefaultName])
+
+	caughtUp, err := first.Poll(context.Background())
+	require.NoError(t, err, "the next poll starts from the saved height")
+	assert.True(t, caughtUp)
+}
+
+func TestWatcher_NodeBehindHead(t *testing.T) {
+	chain := newFakeChain(1005)
+	chain.missing[1003] = true
+	store := newMemStore()
+	store.heights[DefaultName] = 1000
+
+	caughtUp, err := New(chain, store, testConfig()).Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.True(t, caughtUp)
+	assert.Equal(t, int64(1002), store.heights[DefaultName])
+}
+
+func TestWatcher_RunStopsOnCancel(t *testing.T) {
+	chain := newFakeChain(1000)
+	store := newMemStore()
+	store.heights[DefaultName] = 990
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- New(chain, store, testConfig()).Run(ctx) }()
+
+	require.Eventually(t, func() bool {
+		return store.height(DefaultName) == 1000
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	assert.Equal(t, "2.500000", formatAmount(big.NewInt(2500000), config.TokenTRX))
+	assert.Equal(t, "0.000001", formatAmount(big.NewInt(1), config.TokenTRX))
+	assert.Equal(t, "100.000000", formatAmount(big.NewInt(100000000), config.TokenUSDT))
+}
+
+// payUSDT puts a USDT transfer of amount (in token units) to wallet in block
+// num.
+func payUSDT(t *testing.T, chain *fakeChain, num int64, txID, to, amount string) {
+	t.Helper()
+	toHex, err := wallet.AddressToHex(to)
+	require.NoError(t, err)
+	units := decimal.RequireFromString(amount).Shift(tokenDecimals(config.TokenUSDT)).BigInt()
+	chain.blocks[num] = contractCallBlock(num, txID)
+	chain.receipts[num] = []tron.TransactionInfo{{
+		ID:          txID,
+		BlockNumber: num,
+		Log: []tron.Log{{
+			Address: "a614f803b6fd780986a42c78ec9c7f77e6ded13c",
+			Topics: []string{
+				tron.TransferEventTopic,
+				"0000000000000000000000006813eb9362372eef6200f3b1dbc3f819671cba69",
+				"000000000000000000000000" + toHex[2:],
+			},
+			Data: fmt.Sprintf("%064x", units),
+		}},
+	}}
+}
+
+func TestWatcher_Underpaid(t *testing.T) {
+	chain := newFakeChain(1000)
+	payUSDT(t, chain, 1000, strings.Repeat("a", 64), usdtWallet, "99.9")
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	store.addPaymentFor(usdtWallet, "PENDING", "100")
+	tracker := &recordingTracker{}
+
+	_, err := New(chain, store, testConfig(), WithTracker(tracker)).Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, "UNDERPAID", store.payment(usdtWallet).Status)
+	require.Len(t, store.txs, 1)
+	assert.False(t, store.txs[0].ExcessAmount.Valid)
+	assert.Equal(t, []string{EventTxDetected, EventUnderpaid}, store.eventTypes())
+	assert.Equal(t, "received 99.900000 of 100.000000", *store.logs[1].Message)
+	assert.JSONEq(t, `{"tx_hash":"`+strings.Repeat("a", 64)+`","requested":"100.000000","received":"99.900000"}`,
+		string(store.logs[1].RawData))
+	assert.Len(t, tracker.txs, 1, "underpaid transfers are still tracked")
+}
+
+func TestWatcher_RecordsTransferWithSettlement(t *testing.T) {
+	chain := newFakeChain(1000)
+	payUSDT(t, chain, 1000, strings.Repeat("a", 64), usdtWallet, "99.9")
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	store.addPaymentFor(usdtWallet, "PENDING", "100")
+	store.failStatus = errors.New("connection reset")
+	w := New(chain, store, testConfig())
+
+	_, err := w.Poll(context.Background())
+
+	require.Error(t, err)
+	assert.Empty(t, store.txs, "the transfer is not recorded without its settlement")
+	assert.Empty(t, store.logs)
+	assert.Equal(t, "PENDING", store.payment(usdtWallet).Status)
+
+	store.failStatus = nil
+	_, err = w.Poll(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, store.txs, 1)
+	assert.Equal(t, "UNDERPAID", store.payment(usdtWallet).Status)
+}
+
+func TestWatcher_SettlesTransferRecordedBeforeRestart(t *testing.T) {
+	chain := newFakeChain(1000)
+	txID := strings.Repeat("a", 64)
+	payUSDT(t, chain, 1000, txID, usdtWallet, "99.9")
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	p := store.addPaymentFor(usdtWallet, "PENDING", "100")
+	// Recorded by a release that settled the payment apart, and stopped in
+	// between.
+	_, err := store.CreateTransaction(context.Background(), repository.CreateTransactionParams{
+		PaymentID: p.ID,
+		TxHash:    txID,
+		Token:     config.TokenUSDT,
+		Amount:    repository.DecimalToNumeric(decimal.RequireFromString("99.9")),
+	})
+	require.NoError(t, err)
+
+	_, err = New(chain, store, testConfig()).Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.Len(t, store.txs, 1)
+	assert.Equal(t, "UNDERPAID", store.payment(usdtWallet).Status, "not left PENDING to be confirmed")
+	assert.Equal(t, []string{EventUnderpaid}, store.eventTypes())
+	assert.JSONEq(t, `{"tx_hash":"`+txID+`","requested":"100.000000","received":"99.900000"}`,
+		string(store.logs[0].RawData))
+
+	store.heights[DefaultName] = 999
+	_, err = New(chain, store, testConfig()).Poll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{EventUnderpaid}, store.eventTypes(), "a settled payment is not logged again")
+}
+
+func TestWatcher_LogsPaymentID(t *testing.T) {
+	chain := newFakeChain(1000)
+	payUSDT(t, chain, 1000, strings.Repeat("a", 64), usdtWallet, "99.9")
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	store.addPaymentFor(usdtWallet, "PENDING", "100")
+	var buf strings.Builder
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	_, err := New(chain, store, testConfig(), WithLogger(logger)).Poll(context.Background())
+
+	require.NoError(t, err)
+	var underpaid map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		if record["msg"] == "payment underpaid" {
+			underpaid = record
+		}
+	}
+	require.NotNil(t, underpaid)
+	assert.Equal(t, store.payment(usdtWallet).ID.String(), underpaid["payment_id"])
+}
+
+func TestWatcher_UnderpaidTopUp(t *testing.T) {
+	chain := newFakeChain(1002)
+	payUSDT(t, chain, 1000, strings.Repeat("a", 64), usdtWallet, "60")
+	payUSDT(t, chain, 1002, strings.Repeat("b", 64), usdtWallet, "39.9")
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	store.addPaymentFor(usdtWallet, "PENDING", "100")
+	cfg := testConfig()
+	cfg.Payments.UnderpaymentTolerancePercent = 0.5
+
+	_, err := New(chain, store, cfg).Poll(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, store.txs, 2)
+	// 99.9 of 100 is within 0.5%, so the top-up reopens the payment for
+	// confirmation.
+	assert.Equal(t, "PENDING", store.payment(usdtWallet).Status)
+	assert.Equal(t, []string{EventTxDetected, EventUnderpaid, EventTxDetected}, store.eventTypes())
+}
+
+func TestWatcher_WithinTolerance(t *testing.T) {
+	chain := newFakeChain(1000)
+	payUSDT(t, chain, 1000, strings.Repeat("a", 64), usdtWallet, "99.5")
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	store.addPaymentFor(usdtWallet, "PENDING", "100")
+	cfg := testConfig()
+	cfg.Payments.UnderpaymentTolerancePercent = 0.5
+
+	_, err := New(chain, store, cfg).Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, "PENDING", store.payment(usdtWallet).Status)
+	assert.Equal(t, []string{EventTxDetected}, store.eventTypes())
+}
+
+func TestWatcher_Overpaid(t *testing.T) {
+	chain := newFakeChain(1000)
+	payUSDT(t, chain, 1000, strings.Repeat("a", 64), usdtWallet, "100.5")
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	store.addPaymentFor(usdtWallet, "PENDING", "100")
+
+	_, err := New(chain, store, testConfig()).Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, "PENDING", store.payment(usdtWallet).Status, "confirmation stays with the tracker")
+	require.Len(t, store.txs, 1)
+	require.True(t, store.txs[0].ExcessAmount.Valid)
+	assert.Equal(t, "0.500000", repository.NumericToDecimal(store.txs[0].ExcessAmount).StringFixed(6))
+	assert.Equal(t, []string{EventTxDetected, EventOverpaid}, store.eventTypes())
+	assert.Equal(t, "received 100.500000 of 100.000000, 0.500000 in excess", *store.logs[1].Message)
+}
+
+func TestWatcher_TransferAfterExpiry(t *testing.T) {
+	chain := newFakeChain(1000)
+	chain.blocks[1000] = loadBlock(t, "block_transfers.json", 1000)
+	chain.receipts[1000] = loadReceipts(t, "txinfo_transfers.json", 1000)
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	p := store.addPayment(usdtWallet, "PENDING")
+	// The block was produced one second after the payment expired.
+	p.ExpiresAt = pgtype.Timestamptz{Time: time.UnixMilli(1700000003000), Valid: true}
+	store.payments[usdtWallet] = p
+
+	_, err := New(chain, store, testConfig()).Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, store.txs)
+	assert.Equal(t, "PENDING", store.payment(usdtWallet).Status)
+}
+
+func TestWatcher_RegeneratedWallet(t *testing.T) {
+	chain := newFakeChain(1001)
+	payUSDT(t, chain, 1000, strings.Repeat("a", 64), usdtWallet, "60")
+	payUSDT(t, chain, 1001, strings.Repeat("b", 64), regeneratedWallet, "40")
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	store.addPaymentFor(usdtWallet, "PENDING", "100")
+	p := store.regenerate(usdtWallet, regeneratedWallet)
+
+	_, err := New(chain, store, testConfig()).Poll(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, store.txs, 2, "transfers to the old and the new wallet are both credited")
+	assert.Equal(t, p.ID, store.txs[0].PaymentID)
+	assert.Equal(t, usdtWallet, store.txs[0].ToAddress)
+	assert.Equal(t, p.ID, store.txs[1].PaymentID)
+	assert.Equal(t, regeneratedWallet, store.txs[1].ToAddress)
+	assert.Equal(t, "PENDING", store.payment(regeneratedWallet).Status, "60 and 40 together pay in full")
+	assert.Equal(t, []string{EventTxDetected, EventUnderpaid, EventTxDetected}, store.eventTypes())
+
+	var first, second detectedLog
+	require.NoError(t, json.Unmarshal(store.logs[0].RawData, &first))
+	require.NoError(t, json.Unmarshal(store.logs[2].RawData, &second))
+	assert.Equal(t, detectedLog{To: usdtWallet, Attempt: 1}, detectedLog{To: first.To, Attempt: first.Attempt},
+		"the log names the attempt whose wallet was paid")
+	assert.Equal(t, detectedLog{To: regeneratedWallet, Attempt: 2}, detectedLog{To: second.To, Attempt: second.Attempt})
+}
+
+func TestWatcher_RegeneratedWalletOfOtherMode(t *testing.T) {
+	chain := newFakeChain(1000)
+	payUSDT(t, chain, 1000, strings.Repeat("a", 64), usdtWallet, "100")
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	store.addPaymentFor(usdtWallet, "PENDING", "100")
+	p := store.regenerate(usdtWallet, regeneratedWallet)
+	p.Mode = config.ModeTest
+	store.payments[regeneratedWallet] = p
+
+	_, err := New(chain, store, testConfig()).Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, store.txs, "a live watcher never credits a test payment")
+}
+
+func TestWatcher_OldWalletAfterExpiry(t *testing.T) {
+	chain := newFakeChain(1000)
+	chain.blocks[1000] = loadBlock(t, "block_transfers.json", 1000)
+	chain.receipts[1000] = loadReceipts(t, "txinfo_transfers.json", 1000)
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	store.addPayment(usdtWallet, "PENDING")
+	p := store.regenerate(usdtWallet, regeneratedWallet)
+	p.ExpiresAt = pgtype.Timestamptz{Time: time.UnixMilli(1700000003000), Valid: true}
+	store.payments[regeneratedWallet] = p
+
+	_, err := New(chain, store, testConfig()).Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, store.txs, "the old wallet is only watched until the payment expires")
+	assert.Equal(t, "PENDING", store.payment(regeneratedWallet).Status)
+}
+
+func TestWatcher_UnderpaidStatusRace(t *testing.T) {
+	chain := newFakeChain(1000)
+	payUSDT(t, chain, 1000, strings.Repeat("a", 64), usdtWallet, "50")
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	p := store.addPaymentFor(usdtWallet, "PENDING", "100")
+	// The watcher reads PENDING, but the payment expires before the update.
+	racing := &racingStore{memStore: store, wallet: usdtWallet, status: "EXPIRED"}
+
+	_, err := New(chain, racing, testConfig()).Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, "EXPIRED", store.payment(usdtWallet).Status)
+	assert.Equal(t, p.ID, store.txs[0].PaymentID)
+}
+
+// racingStore changes a payment's status right after it is read.
+type racingStore struct {
+	*memStore
+	wallet, status string
+}
+
+func (r *racingStore) GetPaymentByWallet(ctx context.Context, arg repository.GetPaymentByWalletParams) (repository.Payment, error) {
+	p, err := r.memStore.GetPaymentByWallet(ctx, arg)
+	if err == nil && arg.Wallet == r.wallet {
+		r.mu.Lock()
+		changed := p
+		changed.Status = r.status
+		r.payments[arg.Wallet] = changed
+		r.mu.Unlock()
+	}
+	return p, err
+}
+
+func TestWatcher_SharedWalletMatchesAmount(t *testing.T) {
+	chain := newFakeChain(1000)
+	payUSDT(t, chain, 1000, strings.Repeat("a", 64), usdtWallet, "100.000002")
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	store.addSharedPayment(usdtWallet, "100.000001")
+	p := store.addSharedPayment(usdtWallet, "100.000002")
+
+	_, err := New(chain, store, testConfig()).Poll(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, store.txs, 1)
+	assert.Equal(t, p.ID, store.txs[0].PaymentID, "the payment asking for exactly the amount is credited")
+	assert.Equal(t, []string{EventTxDetected}, store.eventTypes())
+}
+
+func TestWatcher_SharedWalletUnmatchedAmount(t *testing.T) {
+	chain := newFakeChain(1000)
+	payUSDT(t, chain, 1000, strings.Repeat("a", 64), usdtWallet, "100.000003")
+	store := newMemStore()
+	store.heights[DefaultName] = 999
+	store.addSharedPayment(usdtWallet, "100.000001")
+	var buf strings.Builder
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	_, err := New(chain, store, testConfig(), WithLogger(logger)).Poll(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, store.txs, "a shared wallet credits no payment by wallet alone")
+	assert.Contains(t, buf.String(), "transfer to a shared deposit wallet matches no payment amount")
+}
@@ -0,0 +1,122 @@
+package webhooks
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of an endpoint host's circuit breaker, as
+// reported to Metrics.
+type BreakerState int
+
+const (
+	// BreakerClosed lets deliveries to the host through.
+	BreakerClosed BreakerState = iota
+	// BreakerHalfOpen lets one delivery through to probe the host once the
+	// cool-down has passed; the others wait for its outcome.
+	BreakerHalfOpen
+	// BreakerOpen holds every delivery to the host back until the cool-down
+	// has passed.
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerHalfOpen:
+		return "half_open"
+	case BreakerOpen:
+		return "open"
+	}
+	return "unknown"
+}
+
+// breaker tracks consecutive failed attempts per endpoint host. After
+// threshold of them the host's breaker opens and its deliveries are held
+// back for cooldown; then one is let through, and its outcome closes the
+// breaker or opens it again.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostBreaker
+}
+
+type hostBreaker struct {
+	state     BreakerState
+	failures  int
+	openUntil time.Time
+	// probing is set while the half-open probe is in flight.
+	probing bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{threshold: max(threshold, 1), cooldown: cooldown, hosts: make(map[string]*hostBreaker)}
+}
+
+// allow reports whether a delivery to host may be sent at now. A delivery
+// let through while the breaker is half open is the probe, and the state it
+// moved to is returned with changed set. A delivery held back is given the
+// time its host's breaker reopens, or the zero time while a probe is in
+// flight.
+func (b *breaker) allow(host string, now time.Time) (ok bool, until time.Time, state BreakerState, changed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h := b.hosts[host]
+	if h == nil {
+		return true, time.Time{}, BreakerClosed, false
+	}
+	switch h.state {
+	case BreakerOpen:
+		if now.Before(h.openUntil) {
+			return false, h.openUntil, h.state, false
+		}
+		h.state, h.probing = BreakerHalfOpen, true
+		return true, time.Time{}, h.state, true
+	case BreakerHalfOpen:
+		if h.probing {
+			return false, time.Time{}, h.state, false
+		}
+		h.probing = true
+	}
+	return true, time.Time{}, h.state, false
+}
+
+// record counts the outcome of an attempt sent to host at now and returns
+// the state it leaves the host's breaker in, with changed set if it moved.
+func (b *breaker) record(host string, now time.Time, succeeded bool) (state BreakerState, changed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h := b.hosts[host]
+	if succeeded {
+		if h == nil {
+			return BreakerClosed, false
+		}
+		// A healthy host needs no entry, so the map only holds failing ones.
+		delete(b.hosts, host)
+		return BreakerClosed, h.state != BreakerClosed
+	}
+	if h == nil {
+		h = &hostBreaker{}
+		b.hosts[host] = h
+	}
+	h.failures++
+	if h.state == BreakerHalfOpen || (h.state == BreakerClosed && h.failures >= b.threshold) {
+		h.state, h.probing = BreakerOpen, false
+		h.openUntil = now.Add(b.cooldown)
+		return h.state, true
+	}
+	return h.state, false
+}
+
+// release ends a probe that was never sent, e.g. because its payload did
+// not render, so another delivery may probe the host.
+func (b *breaker) release(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if h := b.hosts[host]; h != nil {
+		h.probing = false
+	}
+}
@@ -0,0 +1,84 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker_Transitions(t *testing.T) {
+	b := newBreaker(2, time.Minute)
+	host := "hooks.example.com"
+
+	ok, _, state, changed := b.allow(host, t0)
+	assert.True(t, ok)
+	assert.Equal(t, BreakerClosed, state)
+	assert.False(t, changed)
+
+	state, changed = b.record(host, t0, false)
+	assert.Equal(t, BreakerClosed, state, "one failure is below the threshold")
+	assert.False(t, changed)
+	state, changed = b.record(host, t0, false)
+	assert.Equal(t, BreakerOpen, state)
+	assert.True(t, changed)
+
+	ok, until, _, _ := b.allow(host, t0.Add(30*time.Second))
+	assert.False(t, ok, "held back during the cool-down")
+	assert.Equal(t, t0.Add(time.Minute), until)
+	ok, _, _, _ = b.allow("other.example.com", t0)
+	assert.True(t, ok, "other hosts are not held back")
+
+	ok, _, state, changed = b.allow(host, t0.Add(time.Minute))
+	assert.True(t, ok, "the probe is let through")
+	assert.Equal(t, BreakerHalfOpen, state)
+	assert.True(t, changed)
+	ok, until, _, _ = b.allow(host, t0.Add(time.Minute))
+	assert.False(t, ok, "one probe at a time")
+	assert.True(t, until.IsZero())
+
+	state, changed = b.record(host, t0.Add(time.Minute), false)
+	assert.Equal(t, BreakerOpen, state, "a failed probe reopens the breaker")
+	assert.True(t, changed)
+	ok, until, _, _ = b.allow(host, t0.Add(90*time.Second))
+	assert.False(t, ok)
+	assert.Equal(t, t0.Add(2*time.Minute), until)
+
+	ok, _, _, _ = b.allow(host, t0.Add(2*time.Minute))
+	assert.True(t, ok)
+	state, changed = b.record(host, t0.Add(2*time.Minute), true)
+	assert.Equal(t, BreakerClosed, state, "a successful probe closes the breaker")
+	assert.True(t, changed)
+	assert.Empty(t, b.hosts, "healthy hosts are forgotten")
+}
+
+func TestBreaker_SuccessResetsFailures(t *testing.T) {
+	b := newBreaker(2, time.Minute)
+	host := "hooks.example.com"
+
+	b.record(host, t0, false)
+	b.record(host, t0, true)
+	state, _ := b.record(host, t0, false)
+
+	assert.Equal(t, BreakerClosed, state, "only failures in a row count")
+}
+
+func TestBreaker_ReleaseUnsentProbe(t *testing.T) {
+	b := newBreaker(1, time.Minute)
+	host := "hooks.example.com"
+	b.record(host, t0, false)
+
+	ok, _, _, _ := b.allow(host, t0.Add(time.Minute))
+	assert.True(t, ok)
+	b.release(host)
+
+	ok, _, state, _ := b.allow(host, t0.Add(time.Minute))
+	assert.True(t, ok, "another delivery probes in place of one never sent")
+	assert.Equal(t, BreakerHalfOpen, state)
+}
+
+func TestHost(t *testing.T) {
+	assert.Equal(t, "hooks.example.com", host("https://Hooks.Example.com/a?b=c"))
+	assert.Equal(t, "hooks.example.com:8443", host("https://hooks.example.com:8443/a"))
+	assert.Equal(t, "://not a url", host("://not a url"))
+}
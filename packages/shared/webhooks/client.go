@@ -0,0 +1,91 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+// ErrBlockedAddress is returned when a webhook URL resolves to an address
+// merchants may not reach through the gateway.
+var ErrBlockedAddress = errors.New("webhook address is not publicly routable")
+
+// blockedPrefixes are special-purpose ranges netip has no predicate for.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+}
+
+// NewHTTPClient returns the client deliveries are sent with. Unless
+// AllowPrivateAddresses is set, it refuses to connect to loopback, private,
+// link-local and other non-public addresses. The check runs on the address
+// actually dialled, after DNS resolution and on every redirect, so a
+// hostname cannot be pointed at the internal network after it was saved.
+func NewHTTPClient(cfg config.WebhooksConfig) *http.Client {
+	dialer := &net.Dialer{Timeout: cfg.RequestTimeout.Std()}
+	if !cfg.AllowPrivateAddresses {
+		dialer.Control = refusePrivate
+	}
+	transport := &http.Transport{
+		// No proxy: it would dial on our behalf and skip the address check.
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   cfg.RequestTimeout.Std(),
+		ResponseHeaderTimeout: cfg.RequestTimeout.Std(),
+		ForceAttemptHTTP2:     true,
+	}
+	maxRedirects := cfg.MaxRedirects
+	return &http.Client{
+		Transport: transport,
+		Timeout:   cfg.RequestTimeout.Std(),
+		CheckRedirect: func(_ *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				// Hand back the redirect itself, which counts as a failure.
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+}
+
+func refusePrivate(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("failed to parse dial address %q: %w", address, err)
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("failed to parse dial address %q: %w", address, err)
+	}
+	if isBlocked(ip) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, ip)
+	}
+	return nil
+}
+
+func isBlocked(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
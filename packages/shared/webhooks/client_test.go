@@ -0,0 +1,73 @@
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+)
+
+func TestIsBlocked(t *testing.T) {
+	testCases := []struct {
+		addr    string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true}, // cloud metadata
+		{"0.0.0.0", true},
+		{"100.64.0.1", true},
+		{"198.18.0.1", true},
+		{"224.0.0.1", true},
+		{"::1", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"8.8.8.8", false},
+		{"104.16.0.1", false},
+		{"2606:4700::1111", false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.blocked, isBlocked(netip.MustParseAddr(tc.addr)), tc.addr)
+	}
+}
+
+func TestNewHTTPClient_RefusesPrivateAddresses(t *testing.T) {
+	var called bool
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true }))
+	defer srv.Close()
+	client := NewHTTPClient(config.WebhooksConfig{RequestTimeout: config.Duration(time.Second)})
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, srv.URL, nil)
+	_, err := client.Do(req)
+
+	assert.ErrorIs(t, err, ErrBlockedAddress)
+	assert.False(t, called)
+}
+
+func TestNewHTTPClient_AllowPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	client := NewHTTPClient(config.WebhooksConfig{
+		RequestTimeout:        config.Duration(time.Second),
+		AllowPrivateAddresses: true,
+	})
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, srv.URL, nil)
+	resp, err := client.Do(req)
+
+	if assert.NoError(t, err) {
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	}
+}
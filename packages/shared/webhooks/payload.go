@@ -0,0 +1,159 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// Event types a delivery can carry, in the order of a payment's life.
+const (
+	EventPaymentCreated   = "payment.created"
+	EventPaymentDetected  = "payment.detected"
+	EventPaymentUnderpaid = "payment.underpaid"
+	EventPaymentConfirmed = "payment.confirmed"
+	EventPaymentOverpaid  = "payment.overpaid"
+	EventPaymentExpired   = "payment.expired"
+	EventPaymentCancelled = "payment.cancelled"
+)
+
+// EventTypes is the event catalog: every type NewPayload accepts.
+var EventTypes = []string{
+	EventPaymentCreated,
+	EventPaymentDetected,
+	EventPaymentUnderpaid,
+	EventPaymentConfirmed,
+	EventPaymentOverpaid,
+	EventPaymentExpired,
+	EventPaymentCancelled,
+}
+
+// API versions of the payload. An endpoint keeps the version it was created
+// with, so a change that renames, removes or retypes a field ships as a new
+// version; adding a field does not need one.
+const (
+	APIVersion20260301 = "2026-03-01"
+	// LatestAPIVersion is the version new endpoints get.
+	LatestAPIVersion = APIVersion20260301
+)
+
+// versions maps each API version to how it renders an event. Event is the
+// shape of LatestAPIVersion; when it changes, older versions get a renderer
+// returning the struct they were released with.
+var versions = map[string]func(Event) any{
+	APIVersion20260301: func(e Event) any { return e },
+}
+
+// SupportsAPIVersion reports whether version can be rendered.
+func SupportsAPIVersion(version string) bool {
+	_, ok := versions[version]
+	return ok
+}
+
+// amountDecimals formats the amount of a payment whose token is not known,
+// which only happens for payments written without one.
+const amountDecimals = 6
+
+// Event is the body of a delivery.
+type Event struct {
+	ID         uuid.UUID   `json:"id"`
+	Type       string      `json:"type"`
+	APIVersion string      `json:"api_version"`
+	Created    time.Time   `json:"created"`
+	Data       PaymentData `json:"data"`
+}
+
+// PaymentData is the payment an event is about. It holds what a merchant
+// may rely on; the client, attempts and derivation index stay internal.
+type PaymentData struct {
+	ID          uuid.UUID  `json:"id"`
+	AccountID   uuid.UUID  `json:"account_id"`
+	Wallet      string     `json:"wallet"`
+	Token       string     `json:"token"`
+	Amount      string     `json:"amount"`
+	Status      string     `json:"status"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+	// Livemode is false for payments of test clients.
+	Livemode bool `json:"livemode"`
+	// OrderReference and Metadata are as the merchant created the payment
+	// with them, null when it did not.
+	OrderReference *string         `json:"order_reference"`
+	Metadata       json.RawMessage `json:"metadata"`
+}
+
+// NewPayload returns the body of a delivery of event about payment, in the
+// shape of LatestAPIVersion. id identifies the event across every endpoint
+// and retry, so merchants can drop duplicates.
+func NewPayload(id uuid.UUID, event string, payment repository.Payment, created time.Time) ([]byte, error) {
+	if !slices.Contains(EventTypes, event) {
+		return nil, fmt.Errorf("unknown webhook event %q", event)
+	}
+	payload, err := json.Marshal(Event{
+		ID:         id,
+		Type:       event,
+		APIVersion: LatestAPIVersion,
+		Created:    created.UTC(),
+		Data: PaymentData{
+			ID:          payment.ID,
+			AccountID:   payment.AccountID,
+			Wallet:      payment.UniqueWallet,
+			Token:       payment.Token,
+			Amount:      formatAmount(payment.Amount, payment.Token),
+			Status:      payment.Status,
+			ExpiresAt:   optionalTime(payment.ExpiresAt),
+			ConfirmedAt: optionalTime(payment.ConfirmedAt),
+			Livemode:    payment.Mode != config.ModeTest,
+
+			OrderReference: payment.OrderReference,
+			Metadata:       payment.Metadata,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s payload: %w", event, err)
+	}
+	return payload, nil
+}
+
+// Render returns payload, a body made by NewPayload, in the shape of
+// version. Payloads queued before versioning carry no api_version and render
+// like any other.
+func Render(payload []byte, version string) ([]byte, error) {
+	render, ok := versions[version]
+	if !ok {
+		return nil, fmt.Errorf("unsupported webhook api version %q", version)
+	}
+	var e Event
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, fmt.Errorf("failed to decode payload: %w", err)
+	}
+	e.APIVersion = version
+	body, err := json.Marshal(render(e))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s payload for %s: %w", e.Type, version, err)
+	}
+	return body, nil
+}
+
+func optionalTime(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	utc := t.Time.UTC()
+	return &utc
+}
+
+// formatAmount renders amount with the precision of token.
+func formatAmount(amount pgtype.Numeric, token string) string {
+	decimals, ok := config.TokenDecimals(token)
+	if !ok {
+		decimals = amountDecimals
+	}
+	return repository.NumericToDecimal(amount).StringFixed(decimals)
+}
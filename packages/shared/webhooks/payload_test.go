@@ -0,0 +1,192 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"flag"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden payloads in testdata")
+
+func TestNewPayload(t *testing.T) {
+	id := uuid.New()
+	payment := repository.Payment{
+		ID:           uuid.New(),
+		AccountID:    uuid.New(),
+		ClientID:     uuid.New(),
+		UniqueWallet: "TWallet7",
+		Token:        "TRX",
+		Amount:       pgtype.Numeric{Int: big.NewInt(2500), Exp: -2, Valid: true},
+		Status:       "CONFIRMED",
+		ExpiresAt:    pgtype.Timestamptz{Time: t0.Add(time.Hour), Valid: true},
+		ConfirmedAt:  pgtype.Timestamptz{Time: t0, Valid: true},
+	}
+
+	payload, err := NewPayload(id, "payment.confirmed", payment, t0.In(time.FixedZone("EAT", 3*3600)))
+	require.NoError(t, err)
+
+	var event Event
+	require.NoError(t, json.Unmarshal(payload, &event))
+	assert.Equal(t, id, event.ID)
+	assert.Equal(t, "payment.confirmed", event.Type)
+	assert.Equal(t, t0, event.Created)
+	assert.Contains(t, string(payload), `"created":"2026-03-01T12:00:00Z"`, "created is in UTC")
+	assert.Equal(t, payment.ID, event.Data.ID)
+	assert.Equal(t, payment.AccountID, event.Data.AccountID)
+	assert.Equal(t, "TWallet7", event.Data.Wallet)
+	assert.Equal(t, "TRX", event.Data.Token)
+	assert.Equal(t, "25.000000", event.Data.Amount)
+	assert.Equal(t, "CONFIRMED", event.Data.Status)
+	assert.Equal(t, t0, *event.Data.ConfirmedAt)
+	assert.True(t, event.Data.Livemode)
+}
+
+func TestNewPayloadTestMode(t *testing.T) {
+	payload, err := NewPayload(uuid.New(), "payment.confirmed", repository.Payment{Status: "CONFIRMED", Mode: config.ModeTest}, t0)
+
+	require.NoError(t, err)
+	assert.Contains(t, string(payload), `"livemode":false`)
+}
+
+func TestNewPayloadUnconfirmed(t *testing.T) {
+	payload, err := NewPayload(uuid.New(), "payment.expired", repository.Payment{Status: "EXPIRED"}, t0)
+
+	require.NoError(t, err)
+	assert.Contains(t, string(payload), `"confirmed_at":null`)
+}
+
+func TestNewPayloadOrderReference(t *testing.T) {
+	payload, err := NewPayload(uuid.New(), "payment.created", goldenPayment("PENDING"), t0)
+	require.NoError(t, err)
+
+	var event Event
+	require.NoError(t, json.Unmarshal(payload, &event))
+	require.NotNil(t, event.Data.OrderReference)
+	assert.Equal(t, "order-1001", *event.Data.OrderReference)
+	assert.JSONEq(t, `{"sku":"A-1"}`, string(event.Data.Metadata))
+
+	payload, err = NewPayload(uuid.New(), "payment.created", repository.Payment{Status: "PENDING"}, t0)
+	require.NoError(t, err)
+	assert.Contains(t, string(payload), `"order_reference":null,"metadata":null`)
+}
+
+func TestNewPayloadUnknownEvent(t *testing.T) {
+	_, err := NewPayload(uuid.New(), "payment.paid", repository.Payment{}, t0)
+
+	assert.ErrorContains(t, err, `unknown webhook event "payment.paid"`)
+}
+
+// goldenPayment is the payment every golden payload is about, in the status
+// its event leaves it in.
+func goldenPayment(status string) repository.Payment {
+	p := repository.Payment{
+		ID:           uuid.MustParse("7d0c7c6e-3f4a-4a51-9b52-2f6f0d3a1c01"),
+		AccountID:    uuid.MustParse("0b8e5f57-91a2-4c1e-8d55-6a7b1e9c2d02"),
+		ClientID:     uuid.MustParse("c1c1c1c1-0000-4000-8000-000000000003"),
+		UniqueWallet: "TQn9Y2khEsLJW1ChVWFMSMeRDow5KcbLSE",
+		WalletIndex:  ptr(int64(42)),
+		AttemptCount: ptr(int32(2)),
+		Token:        "USDT",
+		Amount:       pgtype.Numeric{Int: big.NewInt(2500), Exp: -2, Valid: true},
+		Status:       status,
+		ExpiresAt:    pgtype.Timestamptz{Time: t0.Add(time.Hour), Valid: true},
+		Mode:         config.ModeLive,
+
+		OrderReference: ptr("order-1001"),
+		Metadata:       []byte(`{"sku":"A-1"}`),
+	}
+	if status == "CONFIRMED" {
+		p.ConfirmedAt = pgtype.Timestamptz{Time: t0.Add(10 * time.Minute), Valid: true}
+	}
+	return p
+}
+
+var goldenStatus = map[string]string{
+	EventPaymentCreated:   "PENDING",
+	EventPaymentDetected:  "DETECTED",
+	EventPaymentUnderpaid: "DETECTED",
+	EventPaymentConfirmed: "CONFIRMED",
+	EventPaymentOverpaid:  "CONFIRMED",
+	EventPaymentExpired:   "EXPIRED",
+	EventPaymentCancelled: "CANCELLED",
+}
+
+// TestRender_Golden locks the JSON of every event in every API version. A
+// diff here breaks merchants on that version: ship the change as a new
+// version instead. Run with -update to write the files of a new version.
+func TestRender_Golden(t *testing.T) {
+	for version := range versions {
+		for _, event := range EventTypes {
+			t.Run(version+"/"+event, func(t *testing.T) {
+				payload, err := NewPayload(uuid.MustParse("e7e7e7e7-0000-4000-8000-000000000004"), event,
+					goldenPayment(goldenStatus[event]), t0.Add(10*time.Minute))
+				require.NoError(t, err)
+				body, err := Render(payload, version)
+				require.NoError(t, err)
+
+				path := filepath.Join("testdata", version, event+".json")
+				if *update {
+					var indented []byte
+					indented, err = json.MarshalIndent(json.RawMessage(body), "", "  ")
+					require.NoError(t, err)
+					require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+					require.NoError(t, os.WriteFile(path, append(indented, '\n'), 0o644))
+				}
+				want, err := os.ReadFile(path)
+				require.NoError(t, err)
+				assert.JSONEq(t, string(want), string(body))
+			})
+		}
+	}
+}
+
+func TestRender_OmitsInternalFields(t *testing.T) {
+	payload, err := NewPayload(uuid.New(), EventPaymentConfirmed, goldenPayment("CONFIRMED"), t0)
+	require.NoError(t, err)
+
+	body, err := Render(payload, LatestAPIVersion)
+
+	require.NoError(t, err)
+	for _, field := range []string{"client_id", "attempt_count", "wallet_index", "mode", "webhook_endpoint_id"} {
+		assert.NotContains(t, string(body), `"`+field+`"`)
+	}
+}
+
+func TestRender_SetsVersion(t *testing.T) {
+	// Queued before versioning: no api_version in the stored payload.
+	body, err := Render([]byte(`{"type":"payment.confirmed","data":{"status":"CONFIRMED"}}`), APIVersion20260301)
+
+	require.NoError(t, err)
+	var e Event
+	require.NoError(t, json.Unmarshal(body, &e))
+	assert.Equal(t, APIVersion20260301, e.APIVersion)
+	assert.Equal(t, "CONFIRMED", e.Data.Status)
+}
+
+func TestRender_UnsupportedVersion(t *testing.T) {
+	_, err := Render([]byte(`{}`), "1999-01-01")
+
+	assert.ErrorContains(t, err, `unsupported webhook api version "1999-01-01"`)
+	assert.False(t, SupportsAPIVersion("1999-01-01"))
+	assert.True(t, SupportsAPIVersion(LatestAPIVersion))
+}
+
+func TestEventTypes(t *testing.T) {
+	assert.Len(t, goldenStatus, len(EventTypes), "every event has a golden payload")
+	for _, event := range EventTypes {
+		assert.Contains(t, goldenStatus, event)
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,30 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+// QueueStore is the subset of repository.Querier Enqueue writes through.
+type QueueStore interface {
+	CreateWebhookDeliveries(ctx context.Context, arg repository.CreateWebhookDeliveriesParams) error
+}
+
+// Enqueue queues a delivery of e, an outbox event about a payment, to every
+// active endpoint of the payment's client, tagged with the request ID of the
+// API request that caused it. The outbox relay calls it in the transaction
+// that marks e processed.
+func Enqueue(ctx context.Context, store QueueStore, e repository.Outbox) error {
+	err := store.CreateWebhookDeliveries(ctx, repository.CreateWebhookDeliveriesParams{
+		EventType: e.EventType,
+		Payload:   e.Payload,
+		RequestID: e.RequestID,
+		PaymentID: e.AggregateID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to queue %s webhook: %w", e.EventType, err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+)
+
+type recordingQueue struct {
+	queued []repository.CreateWebhookDeliveriesParams
+	err    error
+}
+
+func (r *recordingQueue) CreateWebhookDeliveries(_ context.Context, arg repository.CreateWebhookDeliveriesParams) error {
+	r.queued = append(r.queued, arg)
+	return r.err
+}
+
+func TestEnqueue(t *testing.T) {
+	store := &recordingQueue{}
+	requestID := "req-abc123"
+	e := repository.Outbox{
+		ID:          uuid.New(),
+		EventType:   "payment.cancelled",
+		AggregateID: uuid.New(),
+		Payload:     []byte(`{"type":"payment.cancelled"}`),
+		RequestID:   &requestID,
+	}
+
+	require.NoError(t, Enqueue(context.Background(), store, e))
+
+	require.Len(t, store.queued, 1)
+	assert.Equal(t, repository.CreateWebhookDeliveriesParams{
+		EventType: "payment.cancelled",
+		Payload:   e.Payload,
+		RequestID: &requestID,
+		PaymentID: e.AggregateID,
+	}, store.queued[0])
+}
+
+func TestEnqueueError(t *testing.T) {
+	store := &recordingQueue{err: errors.New("connection reset")}
+
+	err := Enqueue(context.Background(), store, repository.Outbox{EventType: "payment.confirmed"})
+
+	assert.ErrorContains(t, err, "failed to queue payment.confirmed webhook: connection reset")
+}
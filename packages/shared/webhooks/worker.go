@@ -0,0 +1,637 @@
+// Package webhooks delivers payment notifications to merchant endpoints.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/logging"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/requestid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/webhooksig"
+)
+
+// Headers sent with every delivery.
+const (
+	// SignatureHeader signs the timestamp and raw body with the endpoint
+	// secret, and with the one it replaced while a rotation overlaps; see
+	// package webhooksig.
+	SignatureHeader  = webhooksig.Header
+	EventHeader      = "X-TPG-Event"
+	DeliveryIDHeader = "X-TPG-Delivery-ID"
+	// APIVersionHeader is the payload version, the endpoint's api_version.
+	APIVersionHeader = "X-TPG-API-Version"
+)
+
+// Log events written for deliveries.
+const (
+	EventWebhookSent   = "WEBHOOK_SENT"
+	EventWebhookFailed = "WEBHOOK_FAILED"
+)
+
+const (
+	userAgent = "tron-payment-gateway-webhooks/1"
+	// maxResponseBytes is how much of a response is read, so the connection
+	// can be reused, before it is dropped.
+	maxResponseBytes = 64 << 10
+	// maxErrorLength bounds last_error.
+	maxErrorLength = 500
+	// maxSnippetLength bounds last_response_body, the start of the response
+	// kept for merchants debugging their endpoint.
+	maxSnippetLength = 4 << 10
+)
+
+// responseHeaders are the response headers kept with an attempt. Anything
+// else an endpoint sends back, cookies included, is dropped.
+var responseHeaders = []string{
+	"Content-Length",
+	"Content-Type",
+	"Date",
+	"Location",
+	"Retry-After",
+	"Server",
+	"X-Request-Id",
+}
+
+// RetrySchedule is the wait after each failed attempt; attempts past its end
+// wait as long as the last entry.
+var RetrySchedule = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	30 * time.Minute,
+	2 * time.Hour,
+	12 * time.Hour,
+}
+
+// retryDelay returns the wait after the attempt-th failed attempt.
+func retryDelay(attempt int) time.Duration {
+	if attempt > len(RetrySchedule) {
+		attempt = len(RetrySchedule)
+	}
+	return RetrySchedule[max(attempt, 1)-1]
+}
+
+// Store is the subset of repository.Querier the worker writes through.
+type Store interface {
+	ClaimWebhookDeliveries(ctx context.Context, arg repository.ClaimWebhookDeliveriesParams) ([]repository.ClaimWebhookDeliveriesRow, error)
+	MarkWebhookDelivered(ctx context.Context, arg repository.MarkWebhookDeliveredParams) error
+	RescheduleWebhookDelivery(ctx context.Context, arg repository.RescheduleWebhookDeliveryParams) error
+	DeferWebhookDelivery(ctx context.Context, arg repository.DeferWebhookDeliveryParams) error
+	FailWebhookDelivery(ctx context.Context, arg repository.FailWebhookDeliveryParams) error
+	ClearRotatedWebhookSecrets(ctx context.Context, rotatedBefore pgtype.Timestamptz) (int64, error)
+	CreateLog(ctx context.Context, arg repository.CreateLogParams) error
+}
+
+// Delivery attempt results, as reported to Metrics.
+const (
+	ResultDelivered = "delivered"
+	ResultRetried   = "retried"
+	ResultFailed    = "failed"
+)
+
+// Metrics records delivery attempts and the dispatch of each batch.
+// *metrics.Metrics implements it.
+type Metrics interface {
+	WebhookDelivery(result string, attempt int)
+	// WebhookInFlight and WebhookQueueDepth add delta to the deliveries
+	// being sent and to those claimed but not yet dispatched.
+	WebhookInFlight(delta int)
+	WebhookQueueDepth(delta int)
+	// WebhookBreakerState records the BreakerState of host's breaker.
+	WebhookBreakerState(host string, state int)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) WebhookDelivery(string, int)     {}
+func (nopMetrics) WebhookInFlight(int)             {}
+func (nopMetrics) WebhookQueueDepth(int)           {}
+func (nopMetrics) WebhookBreakerState(string, int) {}
+
+// Worker drains due webhook deliveries. Each batch is claimed for
+// webhooks.claimLease first, so replicas running side by side never send the
+// same delivery. A delivery is not sent once its claim would lapse before
+// the attempt could time out, and an attempt is only recorded while the claim
+// it was sent under holds. A delivery answered with a 2xx is
+// DELIVERED; any other answer, a timeout or a refused connection is a failed
+// attempt, retried after RetrySchedule until webhooks.maxAttempts attempts
+// have failed, when it is marked FAILED and a WEBHOOK_FAILED log is written.
+//
+// For webhooks.rotationOverlap after an endpoint's secret is rotated,
+// deliveries are signed with the old secret as well as the new one; once the
+// overlap has passed the worker clears the old secret.
+//
+// A batch is sent webhooks.concurrency deliveries at a time, and at most
+// webhooks.maxConnsPerHost to any one endpoint host. Once
+// webhooks.breakerThreshold attempts in a row have failed against a host,
+// its remaining deliveries are deferred, without counting an attempt, for
+// webhooks.breakerCooldown; then one is sent to probe the host, and the
+// rest follow if it succeeds.
+type Worker struct {
+	store   Store
+	client  *http.Client
+	logger  *slog.Logger
+	metrics Metrics
+	breaker *breaker
+
+	interval    time.Duration
+	batchSize   int32
+	maxAttempts int
+	concurrency int
+	perHost     int
+	overlap     time.Duration
+	lease       time.Duration
+	timeout     time.Duration
+	now         func() time.Time
+}
+
+// Option customises a Worker.
+type Option func(*Worker)
+
+// WithHTTPClient replaces the client built by NewHTTPClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(w *Worker) { w.client = c }
+}
+
+// WithLogger replaces slog.Default.
+func WithLogger(l *slog.Logger) Option {
+	return func(w *Worker) { w.logger = l }
+}
+
+// WithMetrics records every delivery attempt in m.
+func WithMetrics(m Metrics) Option {
+	return func(w *Worker) { w.metrics = m }
+}
+
+// NewWorker delivers up to webhooks.batchSize deliveries every
+// webhooks.pollInterval.
+func NewWorker(store Store, cfg *config.Config, opts ...Option) *Worker {
+	w := &Worker{
+		store:       store,
+		client:      NewHTTPClient(cfg.Webhooks),
+		logger:      slog.Default(),
+		metrics:     nopMetrics{},
+		interval:    cfg.Webhooks.PollInterval.Std(),
+		batchSize:   int32(cfg.Webhooks.BatchSize),
+		maxAttempts: cfg.Webhooks.MaxAttempts,
+		concurrency: max(cfg.Webhooks.Concurrency, 1),
+		perHost:     max(cfg.Webhooks.MaxConnsPerHost, 1),
+		overlap:     cfg.Webhooks.RotationOverlap.Std(),
+		lease:       cfg.Webhooks.ClaimLease.Std(),
+		timeout:     cfg.Webhooks.RequestTimeout.Std(),
+		now:         time.Now,
+	}
+	w.breaker = newBreaker(cfg.Webhooks.BreakerThreshold, cfg.Webhooks.BreakerCooldown.Std())
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.logger = slog.New(logging.NewHandler(w.logger.Handler()))
+	return w
+}
+
+// Run delivers webhooks until ctx is done.
+func (w *Worker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.DeliverOnce(ctx); err != nil && ctx.Err() == nil {
+			w.logger.ErrorContext(ctx, "webhook delivery failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverOnce clears the secrets whose rotation overlap has passed, then
+// claims and attempts one batch of due deliveries and returns how many were
+// delivered.
+// The error reports a failed cleanup and deliveries whose outcome could not
+// be saved; failed attempts are not errors.
+func (w *Worker) DeliverOnce(ctx context.Context) (int, error) {
+	var errs []error
+	if err := w.clearRotatedSecrets(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	due, err := w.store.ClaimWebhookDeliveries(ctx, repository.ClaimWebhookDeliveriesParams{
+		Limit:        w.batchSize,
+		ClaimedUntil: pgtype.Timestamptz{Time: w.now().Add(w.lease), Valid: true},
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to claim due webhook deliveries: %w", err))
+		return 0, errors.Join(errs...)
+	}
+
+	var (
+		delivered atomic.Int64
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+	)
+	sem := make(chan struct{}, w.concurrency)
+	w.metrics.WebhookQueueDepth(len(due))
+	for host, queue := range byHost(due) {
+		// Each lane sends one delivery at a time, so the lanes of a host
+		// bound how many it is sent at once.
+		for range min(w.perHost, len(queue)) {
+			wg.Go(func() {
+				for d := range queue {
+					ok, err := w.dispatch(ctx, sem, host, d)
+					if err != nil {
+						mu.Lock()
+						errs = append(errs, fmt.Errorf("delivery %s: %w", d.ID, err))
+						mu.Unlock()
+					}
+					if ok {
+						delivered.Add(1)
+					}
+				}
+			})
+		}
+	}
+	wg.Wait()
+	return int(delivered.Load()), errors.Join(errs...)
+}
+
+// byHost queues due deliveries by the host of their endpoint, oldest first.
+func byHost(due []repository.ClaimWebhookDeliveriesRow) map[string]chan repository.ClaimWebhookDeliveriesRow {
+	grouped := make(map[string][]repository.ClaimWebhookDeliveriesRow)
+	for _, d := range due {
+		h := host(d.Url)
+		grouped[h] = append(grouped[h], d)
+	}
+	queues := make(map[string]chan repository.ClaimWebhookDeliveriesRow, len(grouped))
+	for h, ds := range grouped {
+		q := make(chan repository.ClaimWebhookDeliveriesRow, len(ds))
+		for _, d := range ds {
+			q <- d
+		}
+		close(q)
+		queues[h] = q
+	}
+	return queues
+}
+
+// host is the host and port deliveries to rawURL are sent to. A URL that
+// does not parse is its own host; sending to it fails anyway.
+func host(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return strings.ToLower(u.Host)
+}
+
+// dispatch sends d unless host's breaker holds it back, in which case d is
+// deferred. sem bounds the deliveries of the batch sent at once.
+func (w *Worker) dispatch(ctx context.Context, sem chan struct{}, host string, d repository.ClaimWebhookDeliveriesRow) (bool, error) {
+	sem <- struct{}{}
+	defer func() { <-sem }()
+	w.metrics.WebhookQueueDepth(-1)
+
+	if w.now().Add(w.timeout).After(d.ClaimedUntil.Time) {
+		// Another worker may claim d before the attempt finished; leave it
+		// to that claim.
+		w.logger.WarnContext(ctx, "webhook claim lapsing, delivery left for the next poll", "delivery_id", d.ID,
+			"claimed_until", d.ClaimedUntil.Time)
+		return false, nil
+	}
+
+	ok, until, state, changed := w.breaker.allow(host, w.now())
+	if changed {
+		w.breakerChanged(ctx, host, state)
+	}
+	if !ok {
+		if until.IsZero() {
+			// The host is being probed; look again next poll.
+			until = w.now().Add(w.interval)
+		}
+		return false, w.deferDelivery(ctx, d, until)
+	}
+
+	w.metrics.WebhookInFlight(1)
+	defer w.metrics.WebhookInFlight(-1)
+	return w.deliver(ctx, host, d)
+}
+
+// deferDelivery postpones d to until without counting an attempt.
+func (w *Worker) deferDelivery(ctx context.Context, d repository.ClaimWebhookDeliveriesRow, until time.Time) error {
+	if err := w.store.DeferWebhookDelivery(ctx, repository.DeferWebhookDeliveryParams{
+		ID:            d.ID,
+		NextAttemptAt: pgtype.Timestamptz{Time: until, Valid: true},
+		ClaimedUntil:  d.ClaimedUntil,
+	}); err != nil {
+		return fmt.Errorf("failed to defer: %w", err)
+	}
+	w.logger.DebugContext(ctx, "webhook delivery deferred", "delivery_id", d.ID, "url", d.Url, "next_attempt_at", until)
+	return nil
+}
+
+// observe counts an attempt against host's breaker: one that was sent
+// towards its threshold, one that never left towards nothing.
+func (w *Worker) observe(ctx context.Context, host string, resp response, sendErr error) {
+	if resp.Latency == nil {
+		w.breaker.release(host)
+		return
+	}
+	if state, changed := w.breaker.record(host, w.now(), sendErr == nil); changed {
+		w.breakerChanged(ctx, host, state)
+	}
+}
+
+func (w *Worker) breakerChanged(ctx context.Context, host string, state BreakerState) {
+	w.metrics.WebhookBreakerState(host, int(state))
+	if state == BreakerOpen {
+		w.logger.WarnContext(ctx, "webhook host held back", "host", host, "cooldown", w.breaker.cooldown)
+		return
+	}
+	w.logger.InfoContext(ctx, "webhook host breaker changed", "host", host, "state", state.String())
+}
+
+func (w *Worker) clearRotatedSecrets(ctx context.Context) error {
+	cutoff := w.now().Add(-w.overlap)
+	n, err := w.store.ClearRotatedWebhookSecrets(ctx, pgtype.Timestamptz{Time: cutoff, Valid: true})
+	if err != nil {
+		return fmt.Errorf("failed to clear rotated webhook secrets: %w", err)
+	}
+	if n > 0 {
+		w.logger.InfoContext(ctx, "rotated webhook secrets cleared", "endpoints", n)
+	}
+	return nil
+}
+
+// secrets returns the secrets d is signed with: the endpoint's secret, then
+// the one it replaced while the rotation overlap lasts.
+func (w *Worker) secrets(d repository.ClaimWebhookDeliveriesRow) []string {
+	secrets := []string{d.Secret}
+	if d.SecondarySecret != nil && d.RotatedAt.Valid && w.now().Before(d.RotatedAt.Time.Add(w.overlap)) {
+		secrets = append(secrets, *d.SecondarySecret)
+	}
+	return secrets
+}
+
+// response is how an endpoint answered one attempt. StatusCode, Body and
+// Headers are nil when no response came back, and Latency is nil when
+// nothing was sent.
+type response struct {
+	StatusCode *int32
+	Body       *string
+	Headers    map[string]string
+	Latency    *time.Duration
+}
+
+// latencyMs is r.Latency in whole milliseconds.
+func (r response) latencyMs() *int32 {
+	if r.Latency == nil {
+		return nil
+	}
+	ms := int32(min(r.Latency.Milliseconds(), math.MaxInt32))
+	return &ms
+}
+
+// attemptRecord is an entry of attempt_history.
+type attemptRecord struct {
+	Attempt         int               `json:"attempt"`
+	At              time.Time         `json:"at"`
+	StatusCode      *int32            `json:"status_code"`
+	LatencyMs       *int32            `json:"latency_ms"`
+	Error           string            `json:"error,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    *string           `json:"response_body,omitempty"`
+}
+
+// appendAttempt adds rec to the history, dropping the oldest entries past
+// limit. A history that does not decode is started afresh rather than
+// holding up the delivery.
+func appendAttempt(history []byte, rec attemptRecord, limit int) ([]byte, error) {
+	var entries []json.RawMessage
+	if len(history) > 0 && json.Unmarshal(history, &entries) != nil {
+		entries = nil
+	}
+	raw, err := json.Marshal(rec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode attempt: %w", err)
+	}
+	entries = append(entries, raw)
+	if len(entries) > limit {
+		entries = entries[len(entries)-max(limit, 1):]
+	}
+	return json.Marshal(entries)
+}
+
+// keptHeaders returns the allow-listed headers of h, each with its values
+// joined.
+func keptHeaders(h http.Header) map[string]string {
+	kept := make(map[string]string)
+	for _, name := range responseHeaders {
+		if v := h.Values(name); len(v) > 0 {
+			kept[name] = truncate(strings.Join(v, ", "), maxErrorLength)
+		}
+	}
+	return kept
+}
+
+type deliveryLog struct {
+	DeliveryID string `json:"delivery_id"`
+	EventType  string `json:"event_type"`
+	URL        string `json:"url"`
+	Attempts   int    `json:"attempts"`
+	StatusCode *int32 `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+func (w *Worker) deliver(ctx context.Context, host string, d repository.ClaimWebhookDeliveriesRow) (bool, error) {
+	if d.RequestID != nil {
+		// Trace the delivery back to the API request that queued it.
+		ctx = requestid.NewContext(ctx, *d.RequestID)
+	}
+	if d.PaymentID != nil {
+		ctx = logging.WithPaymentID(ctx, *d.PaymentID)
+	}
+	attempt := int(d.Attempts) + 1
+	at := w.now()
+	resp, sendErr := w.send(ctx, d)
+	w.observe(ctx, host, resp, sendErr)
+	code := resp.StatusCode
+	entry := deliveryLog{
+		DeliveryID: d.ID.String(),
+		EventType:  d.EventType,
+		URL:        d.Url,
+		Attempts:   attempt,
+		StatusCode: code,
+	}
+
+	var msg string
+	if sendErr != nil {
+		msg = truncate(sendErr.Error(), maxErrorLength)
+		entry.Error = msg
+	}
+	var headers []byte
+	if resp.Headers != nil {
+		var err error
+		if headers, err = json.Marshal(resp.Headers); err != nil {
+			return false, fmt.Errorf("failed to encode response headers: %w", err)
+		}
+	}
+	history, err := appendAttempt(d.AttemptHistory, attemptRecord{
+		Attempt:         attempt,
+		At:              at,
+		StatusCode:      code,
+		LatencyMs:       resp.latencyMs(),
+		Error:           msg,
+		ResponseHeaders: resp.Headers,
+		ResponseBody:    resp.Body,
+	}, w.maxAttempts)
+	if err != nil {
+		return false, fmt.Errorf("failed to record attempt: %w", err)
+	}
+
+	if sendErr == nil {
+		if err := w.store.MarkWebhookDelivered(ctx, repository.MarkWebhookDeliveredParams{
+			ID:                  d.ID,
+			LastStatusCode:      code,
+			LastResponseBody:    resp.Body,
+			LastResponseHeaders: headers,
+			LastLatencyMs:       resp.latencyMs(),
+			AttemptHistory:      history,
+			ClaimedUntil:        d.ClaimedUntil,
+		}); err != nil {
+			return false, fmt.Errorf("failed to mark delivered: %w", err)
+		}
+		w.metrics.WebhookDelivery(ResultDelivered, attempt)
+		w.logger.InfoContext(ctx, "webhook delivered", "delivery_id", d.ID, "event", d.EventType, "status_code", *code, "attempt", attempt)
+		return true, w.log(ctx, d, EventWebhookSent, fmt.Sprintf("%s delivered to %s", d.EventType, d.Url), entry)
+	}
+
+	if attempt >= w.maxAttempts {
+		err := w.store.FailWebhookDelivery(ctx, repository.FailWebhookDeliveryParams{
+			ID:                  d.ID,
+			LastStatusCode:      code,
+			LastError:           &msg,
+			LastResponseBody:    resp.Body,
+			LastResponseHeaders: headers,
+			LastLatencyMs:       resp.latencyMs(),
+			AttemptHistory:      history,
+			ClaimedUntil:        d.ClaimedUntil,
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to mark failed: %w", err)
+		}
+		w.metrics.WebhookDelivery(ResultFailed, attempt)
+		w.logger.ErrorContext(ctx, "webhook delivery gave up", "delivery_id", d.ID, "event", d.EventType, "url", d.Url,
+			"attempts", attempt, "error", msg)
+		return false, w.log(ctx, d, EventWebhookFailed,
+			fmt.Sprintf("%s to %s failed after %d attempts: %s", d.EventType, d.Url, attempt, msg), entry)
+	}
+
+	next := w.now().Add(retryDelay(attempt))
+	err = w.store.RescheduleWebhookDelivery(ctx, repository.RescheduleWebhookDeliveryParams{
+		ID:                  d.ID,
+		NextAttemptAt:       pgtype.Timestamptz{Time: next, Valid: true},
+		LastStatusCode:      code,
+		LastError:           &msg,
+		LastResponseBody:    resp.Body,
+		LastResponseHeaders: headers,
+		LastLatencyMs:       resp.latencyMs(),
+		AttemptHistory:      history,
+		ClaimedUntil:        d.ClaimedUntil,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to reschedule: %w", err)
+	}
+	w.metrics.WebhookDelivery(ResultRetried, attempt)
+	w.logger.WarnContext(ctx, "webhook attempt failed", "delivery_id", d.ID, "event", d.EventType, "attempt", attempt,
+		"next_attempt_at", next, "error", msg)
+	return false, nil
+}
+
+// send POSTs the delivery, rendered in the endpoint's API version, and
+// returns the response, if one came back, and an error unless it was a 2xx.
+// It signs with the endpoint's secrets as they are now, so a replayed
+// delivery is signed with the secret the merchant has now.
+func (w *Worker) send(ctx context.Context, d repository.ClaimWebhookDeliveriesRow) (response, error) {
+	body, err := Render(d.Payload, d.ApiVersion)
+	if err != nil {
+		return response{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Url, bytes.NewReader(body))
+	if err != nil {
+		return response{}, fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(SignatureHeader, webhooksig.SignAll(w.secrets(d), w.now(), body))
+	req.Header.Set(EventHeader, d.EventType)
+	req.Header.Set(APIVersionHeader, d.ApiVersion)
+	req.Header.Set(DeliveryIDHeader, d.ID.String())
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set(requestid.Header, id)
+	}
+
+	start := w.now()
+	resp, err := w.client.Do(req)
+	latency := w.now().Sub(start)
+	if err != nil {
+		return response{Latency: &latency}, err
+	}
+	defer resp.Body.Close()
+	head, _ := io.ReadAll(io.LimitReader(resp.Body, maxSnippetLength))
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes-maxSnippetLength))
+	// A rune cut off by the limit is replaced, and the replacement may
+	// itself need cutting.
+	snippet := truncate(strings.ToValidUTF8(string(head), "\uFFFD"), maxSnippetLength)
+
+	code := int32(resp.StatusCode)
+	r := response{StatusCode: &code, Body: &snippet, Headers: keptHeaders(resp.Header), Latency: &latency}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return r, fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	}
+	return r, nil
+}
+
+func (w *Worker) log(ctx context.Context, d repository.ClaimWebhookDeliveriesRow, event, msg string, data deliveryLog) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode log data: %w", err)
+	}
+	err = w.store.CreateLog(ctx, repository.CreateLogParams{
+		PaymentID: d.PaymentID,
+		EventType: event,
+		Message:   &msg,
+		RawData:   raw,
+		RequestID: requestid.Ptr(ctx),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %s log: %w", event, err)
+	}
+	return nil
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,948 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/config"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/internal/repository"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/shared/requestid"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/webhooksig"
+)
+
+var t0 = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// memStore records what the worker writes.
+type memStore struct {
+	mu          sync.Mutex
+	due         []repository.ClaimWebhookDeliveriesRow
+	delivered   []repository.MarkWebhookDeliveredParams
+	rescheduled []repository.RescheduleWebhookDeliveryParams
+	failed      []repository.FailWebhookDeliveryParams
+	deferred    []repository.DeferWebhookDeliveryParams
+	logs        []repository.CreateLogParams
+	cleared     []time.Time
+	// claimed holds the claimed_until of each claimed delivery.
+	claimed  map[uuid.UUID]time.Time
+	listErr  error
+	clearErr error
+}
+
+// ClaimWebhookDeliveries hands out each due delivery once, as the query
+// does until the claim lapses.
+func (m *memStore) ClaimWebhookDeliveries(_ context.Context, arg repository.ClaimWebhookDeliveriesParams) ([]repository.ClaimWebhookDeliveriesRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.listErr != nil {
+		return nil, m.listErr
+	}
+	if m.claimed == nil {
+		m.claimed = make(map[uuid.UUID]time.Time)
+	}
+	var claimed []repository.ClaimWebhookDeliveriesRow
+	for _, d := range m.due {
+		if len(claimed) == int(arg.Limit) {
+			break
+		}
+		if _, ok := m.claimed[d.ID]; ok {
+			continue
+		}
+		m.claimed[d.ID] = arg.ClaimedUntil.Time
+		d.ClaimedUntil = arg.ClaimedUntil
+		claimed = append(claimed, d)
+	}
+	return claimed, nil
+}
+
+func (m *memStore) MarkWebhookDelivered(_ context.Context, arg repository.MarkWebhookDeliveredParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.delivered = append(m.delivered, arg)
+	return nil
+}
+
+func (m *memStore) RescheduleWebhookDelivery(_ context.Context, arg repository.RescheduleWebhookDeliveryParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rescheduled = append(m.rescheduled, arg)
+	return nil
+}
+
+func (m *memStore) DeferWebhookDelivery(_ context.Context, arg repository.DeferWebhookDeliveryParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deferred = append(m.deferred, arg)
+	return nil
+}
+
+func (m *memStore) FailWebhookDelivery(_ context.Context, arg repository.FailWebhookDeliveryParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failed = append(m.failed, arg)
+	return nil
+}
+
+// ClearRotatedWebhookSecrets clears the secondary secrets of the due
+// deliveries, as the query does for their endpoints.
+func (m *memStore) ClearRotatedWebhookSecrets(_ context.Context, rotatedBefore pgtype.Timestamptz) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.clearErr != nil {
+		return 0, m.clearErr
+	}
+	m.cleared = append(m.cleared, rotatedBefore.Time)
+	var n int64
+	for i, d := range m.due {
+		if d.SecondarySecret != nil && !d.RotatedAt.Time.After(rotatedBefore.Time) {
+			m.due[i].SecondarySecret = nil
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memStore) CreateLog(_ context.Context, arg repository.CreateLogParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logs = append(m.logs, arg)
+	return nil
+}
+
+func testConfig() *config.Config {
+	return &config.Config{Webhooks: config.WebhooksConfig{
+		PollInterval:          config.Duration(time.Second),
+		BatchSize:             10,
+		RequestTimeout:        config.Duration(200 * time.Millisecond),
+		MaxAttempts:           3,
+		Concurrency:           4,
+		MaxConnsPerHost:       2,
+		BreakerThreshold:      5,
+		BreakerCooldown:       config.Duration(time.Minute),
+		RotationOverlap:       config.Duration(24 * time.Hour),
+		ClaimLease:            config.Duration(time.Minute),
+		AllowPrivateAddresses: true,
+	}}
+}
+
+func newTestWorker(store *memStore, cfg *config.Config) *Worker {
+	w := NewWorker(store, cfg, WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	w.now = func() time.Time { return t0 }
+	return w
+}
+
+func delivery(url string, attempts int32) repository.ClaimWebhookDeliveriesRow {
+	return repository.ClaimWebhookDeliveriesRow{
+		ID:         uuid.New(),
+		EndpointID: uuid.New(),
+		PaymentID:  repository.UUIDPtr(uuid.New()),
+		EventType:  "payment.confirmed",
+		Payload:    []byte(`{"type":"payment.confirmed","data":{"status":"CONFIRMED"}}`),
+		Attempts:   attempts,
+		Url:        url,
+		Secret:     "whsec_test",
+		ApiVersion: LatestAPIVersion,
+	}
+}
+
+func endpoint(t *testing.T, h http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWorker_Delivers(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	srv := endpoint(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	d := delivery(srv.URL+"/hooks", 0)
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{d}}
+
+	n, err := newTestWorker(store, testConfig()).DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.NotNil(t, got)
+	assert.Equal(t, http.MethodPost, got.Method)
+	assert.Equal(t, "/hooks", got.URL.Path)
+	want, err := Render(d.Payload, LatestAPIVersion)
+	require.NoError(t, err)
+	assert.Equal(t, want, body, "the payload is sent in the endpoint's version")
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	assert.Equal(t, webhooksig.Sign("whsec_test", t0, body), got.Header.Get(SignatureHeader))
+	assert.NoError(t, webhooksig.Verify("whsec_test", got.Header.Get(SignatureHeader), body, 0))
+	assert.Equal(t, "payment.confirmed", got.Header.Get(EventHeader))
+	assert.Equal(t, d.ID.String(), got.Header.Get(DeliveryIDHeader))
+	assert.Equal(t, LatestAPIVersion, got.Header.Get(APIVersionHeader))
+
+	require.Len(t, store.delivered, 1)
+	assert.Equal(t, d.ID, store.delivered[0].ID)
+	assert.Equal(t, int32(204), *store.delivered[0].LastStatusCode)
+	assert.Empty(t, store.rescheduled)
+	require.Len(t, store.logs, 1)
+	assert.Equal(t, EventWebhookSent, store.logs[0].EventType)
+	assert.Equal(t, d.PaymentID, store.logs[0].PaymentID)
+}
+
+func TestWorker_ClaimsBatch(t *testing.T) {
+	srv := endpoint(t, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{delivery(srv.URL, 0)}}
+
+	_, err := newTestWorker(store, testConfig()).DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, t0.Add(time.Minute), store.claimed[store.due[0].ID])
+}
+
+func TestWorker_ReplicasSendEachDeliveryOnce(t *testing.T) {
+	var mu sync.Mutex
+	sent := make(map[string]int)
+	srv := endpoint(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sent[r.Header.Get(DeliveryIDHeader)]++
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	})
+	store := &memStore{}
+	for range 40 {
+		store.due = append(store.due, delivery(srv.URL, 0))
+	}
+	cfg := testConfig()
+	cfg.Webhooks.BatchSize = 7
+
+	// Two replicas poll the same store until the queue is drained.
+	var wg sync.WaitGroup
+	for range 2 {
+		w := newTestWorker(store, cfg)
+		wg.Go(func() {
+			for {
+				n, err := w.DeliverOnce(context.Background())
+				assert.NoError(t, err)
+				if n == 0 {
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	assert.Len(t, sent, len(store.due))
+	for id, n := range sent {
+		assert.Equalf(t, 1, n, "delivery %s was sent %d times", id, n)
+	}
+	assert.Len(t, store.delivered, len(store.due))
+}
+
+func TestWorker_SecretRotation(t *testing.T) {
+	rotatedAt := t0.Add(-time.Hour)
+	testCases := []struct {
+		name string
+		// clean is whether the cleanup has already run, so the row the worker
+		// gets no longer has the old secret.
+		clean     bool
+		now       time.Time
+		oldSigned bool
+	}{
+		{"within the overlap", false, t0, true},
+		{"just before the overlap ends", false, rotatedAt.Add(24*time.Hour - time.Second), true},
+		{"after the overlap", false, rotatedAt.Add(24 * time.Hour), false},
+		{"after the overlap, not yet cleaned up", true, rotatedAt.Add(25 * time.Hour), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var header string
+			var body []byte
+			srv := endpoint(t, func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header.Get(SignatureHeader)
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(http.StatusOK)
+			})
+			// Queued before the rotation; the merchant still verifies with
+			// the old secret.
+			d := delivery(srv.URL, 0)
+			d.Secret = "whsec_new"
+			d.SecondarySecret = ptr("whsec_old")
+			d.RotatedAt = pgtype.Timestamptz{Time: rotatedAt, Valid: true}
+			store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{d}}
+			if tc.clean {
+				store.clearErr = errors.New("connection refused")
+			}
+			w := newTestWorker(store, testConfig())
+			w.now = func() time.Time { return tc.now }
+
+			_, err := w.DeliverOnce(context.Background())
+
+			if !tc.clean {
+				require.NoError(t, err)
+				assert.Equal(t, []time.Time{tc.now.Add(-24 * time.Hour)}, store.cleared)
+			}
+			require.NotEmpty(t, header)
+			assert.NoError(t, webhooksig.Verify("whsec_new", header, body, 0), "the new secret always verifies")
+			oldErr := webhooksig.Verify("whsec_old", header, body, 0)
+			if tc.oldSigned {
+				assert.NoError(t, oldErr)
+				assert.Equal(t, webhooksig.SignAll([]string{"whsec_new", "whsec_old"}, tc.now, body), header)
+			} else {
+				assert.ErrorIs(t, oldErr, webhooksig.ErrSignatureMismatch)
+				assert.Equal(t, webhooksig.Sign("whsec_new", tc.now, body), header)
+			}
+		})
+	}
+}
+
+func TestWorker_CleanupFailureStillDelivers(t *testing.T) {
+	srv := endpoint(t, func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{delivery(srv.URL, 0)}, clearErr: errors.New("connection refused")}
+
+	n, err := newTestWorker(store, testConfig()).DeliverOnce(context.Background())
+
+	assert.ErrorContains(t, err, "failed to clear rotated webhook secrets")
+	assert.Equal(t, 1, n)
+	assert.Len(t, store.delivered, 1)
+}
+
+func TestWorker_PropagatesRequestID(t *testing.T) {
+	srv := endpoint(t, func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	d := delivery(srv.URL, 0)
+	id := "req-abc123"
+	d.RequestID = &id
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{d, delivery(srv.URL, 0)}}
+
+	_, err := newTestWorker(store, testConfig()).DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, store.logs, 2)
+	for _, l := range store.logs {
+		if *l.PaymentID == *d.PaymentID {
+			require.NotNil(t, l.RequestID)
+			assert.Equal(t, id, *l.RequestID)
+		} else {
+			assert.Nil(t, l.RequestID, "deliveries not caused by a request have no ID")
+		}
+	}
+}
+
+func TestWorker_SendsRequestID(t *testing.T) {
+	var got string
+	srv := endpoint(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(requestid.Header)
+		w.WriteHeader(http.StatusOK)
+	})
+	d := delivery(srv.URL, 0)
+	id := "req-abc123"
+	d.RequestID = &id
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{d}}
+
+	_, err := newTestWorker(store, testConfig()).DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, id, got)
+}
+
+func TestWorker_LogsCorrelatingIDs(t *testing.T) {
+	srv := endpoint(t, func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	d := delivery(srv.URL, 0)
+	id := "req-abc123"
+	d.RequestID = &id
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{d}}
+	var buf strings.Builder
+	w := NewWorker(store, testConfig(), WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+
+	_, err := w.DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	var record map[string]any
+	require.NoError(t, json.Unmarshal([]byte(buf.String()), &record))
+	assert.Equal(t, "webhook delivered", record["msg"])
+	assert.Equal(t, id, record["request_id"])
+	assert.Equal(t, uuid.UUID(*d.PaymentID).String(), record["payment_id"])
+}
+
+func TestWorker_UnsupportedAPIVersion(t *testing.T) {
+	called := false
+	srv := endpoint(t, func(w http.ResponseWriter, _ *http.Request) { called = true })
+	d := delivery(srv.URL, 0)
+	d.ApiVersion = "1999-01-01"
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{d}}
+
+	n, err := newTestWorker(store, testConfig()).DeliverOnce(context.Background())
+
+	require.NoError(t, err, "a failed attempt is not an error")
+	assert.Equal(t, 0, n)
+	assert.False(t, called, "nothing is sent in a version the endpoint did not ask for")
+	require.Len(t, store.rescheduled, 1)
+	assert.Contains(t, *store.rescheduled[0].LastError, "unsupported webhook api version")
+}
+
+func TestWorker_KeepsResponseSnippet(t *testing.T) {
+	srv := endpoint(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = io.WriteString(w, "upstream down: "+strings.Repeat("x", 2*maxSnippetLength))
+	})
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{delivery(srv.URL, 0)}}
+
+	_, err := newTestWorker(store, testConfig()).DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, store.rescheduled, 1)
+	snippet := store.rescheduled[0].LastResponseBody
+	require.NotNil(t, snippet)
+	assert.Len(t, *snippet, maxSnippetLength)
+	assert.True(t, strings.HasPrefix(*snippet, "upstream down: "))
+}
+
+func TestWorker_SnippetKeepsRunesWhole(t *testing.T) {
+	srv := endpoint(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		// The limit falls in the middle of the last "é".
+		_, _ = io.WriteString(w, "x"+strings.Repeat("é", maxSnippetLength/2))
+	})
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{delivery(srv.URL, 0)}}
+
+	_, err := newTestWorker(store, testConfig()).DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, store.rescheduled, 1)
+	snippet := *store.rescheduled[0].LastResponseBody
+	assert.LessOrEqual(t, len(snippet), maxSnippetLength)
+	assert.True(t, utf8.ValidString(snippet))
+	assert.True(t, strings.HasSuffix(snippet, "é"), "the cut-off rune is dropped, not mangled")
+}
+
+func TestWorker_KeepsAllowListedHeaders(t *testing.T) {
+	srv := endpoint(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Retry-After", "120")
+		w.Header().Set("X-Request-Id", "upstream-7")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("X-Internal-Token", "t0k3n")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{delivery(srv.URL, 0)}}
+
+	_, err := newTestWorker(store, testConfig()).DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, store.rescheduled, 1)
+	var headers map[string]string
+	require.NoError(t, json.Unmarshal(store.rescheduled[0].LastResponseHeaders, &headers))
+	assert.Equal(t, "text/plain", headers["Content-Type"])
+	assert.Equal(t, "120", headers["Retry-After"])
+	assert.Equal(t, "upstream-7", headers["X-Request-Id"])
+	assert.NotContains(t, headers, "Set-Cookie")
+	assert.NotContains(t, headers, "X-Internal-Token")
+	assert.NotNil(t, store.rescheduled[0].LastLatencyMs)
+}
+
+func TestWorker_RecordsAttemptHistory(t *testing.T) {
+	srv := endpoint(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = io.WriteString(w, "bad gateway")
+	})
+	d := delivery(srv.URL, 1)
+	d.AttemptHistory = []byte(`[{"attempt":1,"at":"2026-03-01T11:59:00Z","status_code":null,"latency_ms":200,"error":"timeout"}]`)
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{d}}
+
+	_, err := newTestWorker(store, testConfig()).DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, store.rescheduled, 1)
+	var history []attemptRecord
+	require.NoError(t, json.Unmarshal(store.rescheduled[0].AttemptHistory, &history))
+	require.Len(t, history, 2)
+	assert.Equal(t, "timeout", history[0].Error)
+	assert.Equal(t, 2, history[1].Attempt)
+	assert.Equal(t, t0, history[1].At)
+	assert.Equal(t, int32(502), *history[1].StatusCode)
+	assert.Equal(t, "endpoint returned 502", history[1].Error)
+	assert.Equal(t, "bad gateway", *history[1].ResponseBody)
+}
+
+func TestAppendAttempt(t *testing.T) {
+	var history []byte
+	for i := 1; i <= 5; i++ {
+		var err error
+		history, err = appendAttempt(history, attemptRecord{Attempt: i, At: t0}, 3)
+		require.NoError(t, err)
+	}
+
+	var got []attemptRecord
+	require.NoError(t, json.Unmarshal(history, &got))
+	require.Len(t, got, 3, "capped at the attempt limit")
+	assert.Equal(t, 3, got[0].Attempt, "the oldest are dropped")
+	assert.Equal(t, 5, got[2].Attempt)
+
+	history, err := appendAttempt([]byte("not json"), attemptRecord{Attempt: 6, At: t0}, 3)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(history, &got))
+	require.Len(t, got, 1, "a corrupt history is started afresh")
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", truncate("short", 10))
+	assert.Equal(t, "abc", truncate("abcdef", 3))
+	assert.Equal(t, "a", truncate("aé", 2), "a rune is never split")
+	assert.Equal(t, "aé", truncate("aéb", 3))
+}
+
+func TestWorker_SignsWithCurrentSecret(t *testing.T) {
+	var body []byte
+	var signature string
+	srv := endpoint(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+	})
+	// A replay of a delivery first sent before the secret was rotated.
+	d := delivery(srv.URL, 0)
+	d.Secret = "whsec_rotated"
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{d}}
+
+	_, err := newTestWorker(store, testConfig()).DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.NoError(t, webhooksig.Verify("whsec_rotated", signature, body, 0))
+	require.Len(t, store.delivered, 1)
+	assert.Equal(t, "", *store.delivered[0].LastResponseBody)
+}
+
+func TestWorker_ServerErrorIsRetried(t *testing.T) {
+	srv := endpoint(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	d := delivery(srv.URL, 1)
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{d}}
+
+	n, err := newTestWorker(store, testConfig()).DeliverOnce(context.Background())
+
+	require.NoError(t, err, "a failed attempt is not a worker error")
+	assert.Zero(t, n)
+	require.Len(t, store.rescheduled, 1)
+	r := store.rescheduled[0]
+	assert.Equal(t, d.ID, r.ID)
+	assert.Equal(t, t0.Add(5*time.Minute), r.NextAttemptAt.Time, "second failure waits 5m")
+	assert.Equal(t, int32(500), *r.LastStatusCode)
+	assert.Equal(t, "endpoint returned 500", *r.LastError)
+	assert.Empty(t, store.delivered)
+	assert.Empty(t, store.logs)
+}
+
+func TestWorker_SlowEndpointTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	srv := endpoint(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	defer close(release)
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{delivery(srv.URL, 0)}}
+
+	start := time.Now()
+	_, err := newTestWorker(store, testConfig()).DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second, "bounded by requestTimeout")
+	require.Len(t, store.rescheduled, 1)
+	assert.Nil(t, store.rescheduled[0].LastStatusCode, "no response came back")
+	assert.Contains(t, *store.rescheduled[0].LastError, "Timeout")
+	assert.Equal(t, t0.Add(time.Minute), store.rescheduled[0].NextAttemptAt.Time)
+}
+
+func TestWorker_GivesUpAfterMaxAttempts(t *testing.T) {
+	srv := endpoint(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	d := delivery(srv.URL, 2)
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{d}}
+
+	_, err := newTestWorker(store, testConfig()).DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, store.rescheduled)
+	require.Len(t, store.failed, 1)
+	assert.Equal(t, d.ID, store.failed[0].ID)
+	assert.Equal(t, int32(502), *store.failed[0].LastStatusCode)
+
+	require.Len(t, store.logs, 1)
+	log := store.logs[0]
+	assert.Equal(t, EventWebhookFailed, log.EventType)
+	assert.Equal(t, d.PaymentID, log.PaymentID)
+	var data deliveryLog
+	require.NoError(t, json.Unmarshal(log.RawData, &data))
+	assert.Equal(t, 3, data.Attempts)
+	assert.Equal(t, "endpoint returned 502", data.Error)
+}
+
+func TestWorker_RedirectIsAFailure(t *testing.T) {
+	var followed bool
+	target := endpoint(t, func(http.ResponseWriter, *http.Request) { followed = true })
+	srv := endpoint(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusFound)
+	})
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{delivery(srv.URL, 0)}}
+
+	_, err := newTestWorker(store, testConfig()).DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.False(t, followed)
+	require.Len(t, store.rescheduled, 1)
+	assert.Equal(t, int32(302), *store.rescheduled[0].LastStatusCode)
+}
+
+func TestWorker_FollowsConfiguredRedirects(t *testing.T) {
+	target := endpoint(t, func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	srv := endpoint(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	})
+	cfg := testConfig()
+	cfg.Webhooks.MaxRedirects = 1
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{delivery(srv.URL, 0)}}
+
+	n, err := newTestWorker(store, cfg).DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestWorker_BlocksPrivateAddresses(t *testing.T) {
+	var called bool
+	srv := endpoint(t, func(http.ResponseWriter, *http.Request) { called = true })
+	cfg := testConfig()
+	cfg.Webhooks.AllowPrivateAddresses = false
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{delivery(srv.URL, 0)}}
+
+	_, err := newTestWorker(store, cfg).DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.False(t, called, "the loopback endpoint is never reached")
+	require.Len(t, store.rescheduled, 1)
+	assert.Contains(t, *store.rescheduled[0].LastError, ErrBlockedAddress.Error())
+}
+
+func TestWorker_ContinuesPastFailedDelivery(t *testing.T) {
+	ok := endpoint(t, func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	failing := endpoint(t, func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusInternalServerError) })
+	invalid := delivery("://not a url", 0)
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{
+		delivery(failing.URL, 0),
+		delivery(ok.URL, 0),
+		invalid,
+	}}
+
+	n, err := newTestWorker(store, testConfig()).DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.Len(t, store.rescheduled, 2)
+	for _, r := range store.rescheduled {
+		if r.ID == invalid.ID {
+			assert.Contains(t, *r.LastError, "invalid webhook URL")
+		}
+	}
+}
+
+func TestWorker_RecordsAttemptUnderItsClaim(t *testing.T) {
+	srv := endpoint(t, func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	failing := endpoint(t, func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusBadGateway) })
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{delivery(srv.URL, 0), delivery(failing.URL, 0)}}
+
+	_, err := newTestWorker(store, testConfig()).DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	claimedUntil := t0.Add(time.Minute)
+	require.Len(t, store.delivered, 1)
+	assert.Equal(t, claimedUntil, store.delivered[0].ClaimedUntil.Time)
+	require.Len(t, store.rescheduled, 1)
+	assert.Equal(t, claimedUntil, store.rescheduled[0].ClaimedUntil.Time)
+}
+
+func TestWorker_LeavesDeliveryWhoseClaimIsLapsing(t *testing.T) {
+	cfg := testConfig()
+	cfg.Webhooks.MaxConnsPerHost = 1
+	var now atomic.Pointer[time.Time]
+	now.Store(&t0)
+	var calls atomic.Int32
+	srv := endpoint(t, func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		// The first delivery took most of the lease.
+		late := t0.Add(cfg.Webhooks.ClaimLease.Std() - cfg.Webhooks.RequestTimeout.Std()/2)
+		now.Store(&late)
+		w.WriteHeader(http.StatusOK)
+	})
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{delivery(srv.URL, 0), delivery(srv.URL, 0)}}
+	w := newTestWorker(store, cfg)
+	w.now = func() time.Time { return *now.Load() }
+
+	n, err := w.DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, int32(1), calls.Load(), "the second delivery is not sent under a lapsing claim")
+	assert.Len(t, store.delivered, 1)
+	assert.Empty(t, store.rescheduled)
+	assert.Empty(t, store.deferred)
+}
+
+func TestWorker_ListError(t *testing.T) {
+	store := &memStore{listErr: errors.New("connection refused")}
+
+	_, err := newTestWorker(store, testConfig()).DeliverOnce(context.Background())
+
+	assert.ErrorContains(t, err, "failed to claim due webhook deliveries")
+}
+
+type result struct {
+	result  string
+	attempt int
+}
+
+type recordingMetrics struct {
+	mu          sync.Mutex
+	results     []result
+	inFlight    int
+	maxInFlight int
+	queued      int
+	breakers    map[string][]BreakerState
+}
+
+func (m *recordingMetrics) WebhookDelivery(r string, attempt int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.results = append(m.results, result{r, attempt})
+}
+
+func (m *recordingMetrics) WebhookInFlight(delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight += delta
+	m.maxInFlight = max(m.maxInFlight, m.inFlight)
+}
+
+func (m *recordingMetrics) WebhookQueueDepth(delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queued += delta
+}
+
+func (m *recordingMetrics) WebhookBreakerState(host string, state int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.breakers == nil {
+		m.breakers = make(map[string][]BreakerState)
+	}
+	m.breakers[host] = append(m.breakers[host], BreakerState(state))
+}
+
+func TestWorker_Metrics(t *testing.T) {
+	ok := endpoint(t, func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	failing := endpoint(t, func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusInternalServerError) })
+	store := &memStore{due: []repository.ClaimWebhookDeliveriesRow{
+		delivery(ok.URL, 1),
+		delivery(failing.URL, 0),
+		delivery(failing.URL, 2),
+	}}
+	m := &recordingMetrics{}
+	w := newTestWorker(store, testConfig())
+	w.metrics = m
+
+	_, err := w.DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []result{
+		{ResultDelivered, 2},
+		{ResultRetried, 1},
+		{ResultFailed, 3},
+	}, m.results)
+	assert.Zero(t, m.queued, "every claimed delivery was dispatched")
+	assert.Zero(t, m.inFlight)
+	assert.Positive(t, m.maxInFlight)
+}
+
+// concurrency counts the requests a handler is serving at once.
+type concurrency struct {
+	mu       sync.Mutex
+	current  int
+	max      int
+	requests int
+}
+
+// handler answers with status after delay.
+func (c *concurrency) handler(delay time.Duration, status func() int) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		c.mu.Lock()
+		c.current++
+		c.requests++
+		c.max = max(c.max, c.current)
+		c.mu.Unlock()
+		time.Sleep(delay)
+		c.mu.Lock()
+		c.current--
+		c.mu.Unlock()
+		w.WriteHeader(status())
+	}
+}
+
+func statusOK() int { return http.StatusOK }
+
+func TestWorker_CapsConcurrencyPerHost(t *testing.T) {
+	var slow, fast concurrency
+	slowSrv := endpoint(t, slow.handler(50*time.Millisecond, statusOK))
+	fastSrv := endpoint(t, fast.handler(time.Millisecond, statusOK))
+	store := &memStore{}
+	for range 6 {
+		store.due = append(store.due, delivery(slowSrv.URL, 0), delivery(fastSrv.URL, 0))
+	}
+	cfg := testConfig()
+	cfg.Webhooks.BatchSize = len(store.due)
+	cfg.Webhooks.Concurrency = 8
+	cfg.Webhooks.MaxConnsPerHost = 2
+	m := &recordingMetrics{}
+	w := newTestWorker(store, cfg)
+	w.metrics = m
+
+	n, err := w.DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 12, n)
+	assert.Equal(t, 2, slow.max, "a slow host is sent no more than maxConnsPerHost at once")
+	assert.Equal(t, 6, slow.requests)
+	assert.Equal(t, 6, fast.requests, "a slow host does not hold up the others")
+	assert.LessOrEqual(t, m.maxInFlight, 4)
+	assert.Greater(t, m.maxInFlight, 2)
+	assert.Zero(t, m.queued)
+}
+
+func TestWorker_CapsConcurrency(t *testing.T) {
+	var all concurrency
+	store := &memStore{}
+	for range 3 {
+		srv := endpoint(t, all.handler(30*time.Millisecond, statusOK))
+		for range 4 {
+			store.due = append(store.due, delivery(srv.URL, 0))
+		}
+	}
+	cfg := testConfig()
+	cfg.Webhooks.BatchSize = len(store.due)
+	cfg.Webhooks.Concurrency = 3
+	cfg.Webhooks.MaxConnsPerHost = 4
+
+	n, err := newTestWorker(store, cfg).DeliverOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 12, n)
+	assert.Equal(t, 3, all.max, "no more than concurrency deliveries are sent at once")
+}
+
+func TestWorker_BreakerTransitions(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	var flaky, healthy concurrency
+	flakySrv := endpoint(t, flaky.handler(0, func() int {
+		if failing.Load() {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	}))
+	healthySrv := endpoint(t, healthy.handler(0, statusOK))
+	flakyHost := strings.TrimPrefix(flakySrv.URL, "http://")
+	cfg := testConfig()
+	cfg.Webhooks.MaxAttempts = 10
+	cfg.Webhooks.MaxConnsPerHost = 1
+	cfg.Webhooks.BreakerThreshold = 2
+	cfg.Webhooks.BreakerCooldown = config.Duration(time.Minute)
+	store := &memStore{}
+	m := &recordingMetrics{}
+	w := newTestWorker(store, cfg)
+	w.metrics = m
+	now := t0
+	w.now = func() time.Time { return now }
+	batch := func(n int) {
+		t.Helper()
+		store.due, store.delivered, store.rescheduled, store.deferred = nil, nil, nil, nil
+		for range n {
+			store.due = append(store.due, delivery(flakySrv.URL, 0))
+		}
+		store.due = append(store.due, delivery(healthySrv.URL, 0))
+		_, err := w.DeliverOnce(context.Background())
+		require.NoError(t, err)
+	}
+
+	// Closed: attempts fail until the threshold opens the breaker, and the
+	// rest of the batch is deferred without counting an attempt.
+	batch(4)
+	assert.Equal(t, 2, flaky.requests)
+	assert.Len(t, store.rescheduled, 2)
+	require.Len(t, store.deferred, 2)
+	for _, d := range store.deferred {
+		assert.Equal(t, t0.Add(time.Minute), d.NextAttemptAt.Time)
+	}
+	assert.Len(t, store.delivered, 1, "other hosts are still delivered to")
+	assert.Equal(t, []BreakerState{BreakerOpen}, m.breakers[flakyHost])
+
+	// Open: nothing is sent to the host during the cool-down.
+	now = t0.Add(30 * time.Second)
+	batch(2)
+	assert.Equal(t, 2, flaky.requests)
+	assert.Len(t, store.deferred, 2)
+
+	// Half open: one probe is sent, and its failure reopens the breaker.
+	now = t0.Add(time.Minute)
+	batch(3)
+	assert.Equal(t, 3, flaky.requests)
+	assert.Len(t, store.rescheduled, 1)
+	require.Len(t, store.deferred, 2)
+	for _, d := range store.deferred {
+		assert.Equal(t, now.Add(time.Minute), d.NextAttemptAt.Time)
+	}
+	assert.Equal(t, []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerOpen}, m.breakers[flakyHost])
+
+	// A successful probe closes the breaker and the rest follow.
+	failing.Store(false)
+	now = t0.Add(2 * time.Minute)
+	batch(3)
+	assert.Equal(t, 6, flaky.requests)
+	assert.Empty(t, store.deferred)
+	assert.Len(t, store.delivered, 4)
+	assert.Equal(t, []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerOpen, BreakerHalfOpen, BreakerClosed},
+		m.breakers[flakyHost])
+	assert.Equal(t, 4, healthy.requests)
+	assert.Empty(t, m.breakers[strings.TrimPrefix(healthySrv.URL, "http://")])
+}
+
+func TestRetryDelay(t *testing.T) {
+	testCases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Minute},
+		{2, 5 * time.Minute},
+		{3, 30 * time.Minute},
+		{4, 2 * time.Hour},
+		{5, 12 * time.Hour},
+		{9, 12 * time.Hour},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, retryDelay(tc.attempt), "attempt %d", tc.attempt)
+	}
+}
@@ -0,0 +1,68 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+)
+
+// AddressPrefix is the first byte of every TRON address.
+const AddressPrefix = 0x41
+
+// addressLen is the prefix byte plus the 20-byte account hash.
+const addressLen = 21
+
+// ErrInvalidAddress is returned for strings that are not valid TRON addresses.
+var ErrInvalidAddress = errors.New("invalid tron address")
+
+// ValidateAddress checks that address is a base58check TRON address: 25
+// bytes once decoded, starting with 0x41, with a valid checksum.
+func ValidateAddress(address string) error {
+	_, err := decodeAddress(address)
+	return err
+}
+
+// AddressToHex converts a base58 address to the 21-byte hex form used by the
+// node API, e.g. 41a614f8...
+func AddressToHex(address string) (string, error) {
+	payload, err := decodeAddress(address)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(payload), nil
+}
+
+// HexToAddress converts a 21-byte hex address (41...) or a 20-byte EVM-style
+// hex address (optionally 0x-prefixed) to base58.
+func HexToAddress(hexAddress string) (string, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(hexAddress, "0x"), "0X"))
+	if err != nil {
+		return "", fmt.Errorf("%w: %q is not hex", ErrInvalidAddress, hexAddress)
+	}
+	switch {
+	case len(raw) == addressLen-1:
+		raw = append([]byte{AddressPrefix}, raw...)
+	case len(raw) != addressLen || raw[0] != AddressPrefix:
+		return "", fmt.Errorf("%w: %q has wrong length or prefix", ErrInvalidAddress, hexAddress)
+	}
+	return encodeCheck(raw), nil
+}
+
+func decodeAddress(address string) ([]byte, error) {
+	decoded := base58.Decode(address)
+	if len(decoded) != addressLen+4 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAddress, address)
+	}
+	payload, checksum := decoded[:addressLen], decoded[addressLen:]
+	if payload[0] != AddressPrefix {
+		return nil, fmt.Errorf("%w: %q does not start with 0x41", ErrInvalidAddress, address)
+	}
+	if !bytes.Equal(doubleSHA256(payload)[:4], checksum) {
+		return nil, fmt.Errorf("%w: %q has a bad checksum", ErrInvalidAddress, address)
+	}
+	return payload, nil
+}
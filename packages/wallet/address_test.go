@@ -0,0 +1,147 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+const (
+	usdtContract    = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+	usdtContractHex = "41a614f803b6fd780986a42c78ec9c7f77e6ded13c"
+)
+
+// Test PrivateKeyToTronAddress against the well-known secp256k1 vector: the
+// key 1 maps to EVM address 0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf.
+func TestPrivateKeyToTronAddress_KnownVector(t *testing.T) {
+	key := make([]byte, 32)
+	key[31] = 0x01
+
+	address, err := PrivateKeyToTronAddress(key)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	got, err := AddressToHex(address)
+	if err != nil {
+		t.Fatalf("Derived address should validate, got: %v", err)
+	}
+	if got != "417e5f4552091a69125d5dfcb7b8c2659029395bdf" {
+		t.Errorf("Expected hex 417e5f45..., got: %s", got)
+	}
+}
+
+// Test derived addresses pass the validator
+func TestDeriveTronAddressFromMnemonic_Validates(t *testing.T) {
+	mnemonic := "flash couple heart script ramp april average caution plunge alter elite author"
+
+	for i := uint32(0); i < 5; i++ {
+		address, _, err := DeriveTronAddressFromMnemonic(mnemonic, i)
+		if err != nil {
+			t.Fatalf("Error at index %d: %v", i, err)
+		}
+		if err := ValidateAddress(address); err != nil {
+			t.Errorf("Derived address %s at index %d should validate: %v", address, i, err)
+		}
+	}
+}
+
+func TestValidateAddress(t *testing.T) {
+	testCases := []struct {
+		name    string
+		address string
+		valid   bool
+	}{
+		{"USDT contract", usdtContract, true},
+		{"empty", "", false},
+		{"bad checksum", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6u", false},
+		{"too short", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzg", false},
+		{"not base58", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj60", false},
+		{"bitcoin address", "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", false},
+		{"hex form", usdtContractHex, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateAddress(tc.address)
+			if tc.valid && err != nil {
+				t.Errorf("Expected %q to be valid, got: %v", tc.address, err)
+			}
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("Expected %q to be invalid", tc.address)
+				} else if !errors.Is(err, ErrInvalidAddress) {
+					t.Errorf("Expected ErrInvalidAddress, got: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestAddressToHex(t *testing.T) {
+	got, err := AddressToHex(usdtContract)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got != usdtContractHex {
+		t.Errorf("Expected %s, got: %s", usdtContractHex, got)
+	}
+
+	if _, err := AddressToHex("not-an-address"); !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("Expected ErrInvalidAddress, got: %v", err)
+	}
+}
+
+func TestHexToAddress(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		want  string
+		valid bool
+	}{
+		{"21-byte hex", usdtContractHex, usdtContract, true},
+		{"20-byte hex", usdtContractHex[2:], usdtContract, true},
+		{"0x-prefixed 20-byte hex", "0x" + usdtContractHex[2:], usdtContract, true},
+		{"wrong prefix", "42" + usdtContractHex[2:], "", false},
+		{"wrong length", usdtContractHex[:20], "", false},
+		{"not hex", "zz", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := HexToAddress(tc.input)
+			if !tc.valid {
+				if !errors.Is(err, ErrInvalidAddress) {
+					t.Errorf("Expected ErrInvalidAddress, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Expected %s, got: %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestHexRoundTrip(t *testing.T) {
+	raw := make([]byte, 21)
+	raw[0] = AddressPrefix
+	for i := 1; i < len(raw); i++ {
+		raw[i] = byte(i * 7)
+	}
+
+	address, err := HexToAddress(hex.EncodeToString(raw))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	back, err := AddressToHex(address)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if back != hex.EncodeToString(raw) {
+		t.Errorf("Round trip mismatch: %s != %x", back, raw)
+	}
+}
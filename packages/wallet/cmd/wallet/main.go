@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+var (
+	mnemonicSecret = `flash couple heart script ramp april average caution plunge alter elite author`
+	index          = uint32(0)
+)
+
+// main derives a TRON address and its private key from the package-level mnemonicSecret and index,
+// prints the address and private key to standard output, and panics on error.
+func main() {
+	address, privKey, err := wallet.DeriveTronAddressFromMnemonic(mnemonicSecret, index)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Address:", address)
+	fmt.Println("PrivKey:", privKey)
+
+}
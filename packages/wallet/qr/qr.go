@@ -0,0 +1,70 @@
+// Package qr renders payment URIs, such as those of wallet.PaymentURI, as QR
+// code images for wallet apps to scan.
+package qr
+
+import (
+	"fmt"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Level is how much of a code may be damaged and still scan; higher levels
+// make denser codes.
+type Level int
+
+// Error correction levels, recovering about 7%, 15%, 25% and 30% of the code.
+const (
+	Low Level = iota
+	Medium
+	High
+	Highest
+)
+
+// DefaultSize is the width and height of an image, in pixels.
+const DefaultSize = 256
+
+var levels = map[Level]qrcode.RecoveryLevel{
+	Low:     qrcode.Low,
+	Medium:  qrcode.Medium,
+	High:    qrcode.High,
+	Highest: qrcode.Highest,
+}
+
+type settings struct {
+	size  int
+	level Level
+}
+
+// Option customises an image.
+type Option func(*settings)
+
+// WithSize replaces DefaultSize. A size too small for the code is raised to
+// one pixel per module.
+func WithSize(px int) Option {
+	return func(s *settings) { s.size = px }
+}
+
+// WithLevel replaces the Medium error correction.
+func WithLevel(l Level) Option {
+	return func(s *settings) { s.level = l }
+}
+
+// PNG encodes content as a black on white QR code with a quiet zone, in PNG.
+func PNG(content string, opts ...Option) ([]byte, error) {
+	s := settings{size: DefaultSize, level: Medium}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	level, ok := levels[s.level]
+	if !ok {
+		return nil, fmt.Errorf("unknown error correction level %d", s.level)
+	}
+	if s.size <= 0 {
+		return nil, fmt.Errorf("size must be positive, got %d", s.size)
+	}
+	png, err := qrcode.Encode(content, level, s.size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	return png, nil
+}
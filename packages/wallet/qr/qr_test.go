@@ -0,0 +1,380 @@
+package qr
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/wallet"
+)
+
+const (
+	depositAddress = "TJRabPrwbZy45sbavfcjinPJC18kjpRTv8"
+	usdtContract   = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+)
+
+func numeric(t *testing.T, s string) pgtype.Numeric {
+	t.Helper()
+	var n pgtype.Numeric
+	if err := n.Scan(s); err != nil {
+		t.Fatalf("bad numeric %q: %v", s, err)
+	}
+	return n
+}
+
+func TestPNG_DecodesToURI(t *testing.T) {
+	trx, err := wallet.PaymentURI(depositAddress, numeric(t, "25.5"), wallet.NativeToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usdt, err := wallet.PaymentURI(depositAddress, numeric(t, "102.669405"), usdtContract)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, uri := range []string{trx, usdt} {
+		for _, level := range []Level{Low, Medium, High, Highest} {
+			img, err := PNG(uri, WithLevel(level))
+			if err != nil {
+				t.Fatalf("PNG(%q): %v", uri, err)
+			}
+			got := decode(t, img)
+			if got.content != uri {
+				t.Errorf("level %d: decoded %q, want %q", level, got.content, uri)
+			}
+			if got.level != level {
+				t.Errorf("decoded level %d, want %d", got.level, level)
+			}
+		}
+	}
+}
+
+func TestPNG_Size(t *testing.T) {
+	for _, size := range []int{DefaultSize, 120, 512} {
+		var opts []Option
+		if size != DefaultSize {
+			opts = append(opts, WithSize(size))
+		}
+		raw, err := PNG("tron:"+depositAddress, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := img.Bounds(); b.Dx() != size || b.Dy() != size {
+			t.Errorf("image is %dx%d, want %dx%d", b.Dx(), b.Dy(), size, size)
+		}
+	}
+}
+
+func TestPNG_Errors(t *testing.T) {
+	if _, err := PNG("tron:"+depositAddress, WithSize(0)); err == nil {
+		t.Error("expected an error for size 0")
+	}
+	if _, err := PNG("tron:"+depositAddress, WithLevel(Level(9))); err == nil || !strings.Contains(err.Error(), "unknown error correction level 9") {
+		t.Errorf("expected an unknown level error, got %v", err)
+	}
+}
+
+// The decoder below reads back the clean, axis-aligned codes PNG draws,
+// versions 1 to 10 only. It skips error correction: nothing is damaged.
+
+type decodedCode struct {
+	level   Level
+	content string
+}
+
+// blockGroup is numBlocks blocks of numCodewords codewords each, the first
+// numData of them data.
+type blockGroup struct{ numBlocks, numCodewords, numData int }
+
+// blockGroups lists the block groups of versions 1 to 10 per level.
+var blockGroups = map[Level][][]blockGroup{
+	Low: {
+		nil, {{1, 26, 19}}, {{1, 44, 34}}, {{1, 70, 55}}, {{1, 100, 80}}, {{1, 134, 108}},
+		{{2, 86, 68}}, {{2, 98, 78}}, {{2, 121, 97}}, {{2, 146, 116}}, {{2, 86, 68}, {2, 87, 69}},
+	},
+	Medium: {
+		nil, {{1, 26, 16}}, {{1, 44, 28}}, {{1, 70, 44}}, {{2, 50, 32}}, {{2, 67, 43}},
+		{{4, 43, 27}}, {{4, 49, 31}}, {{2, 60, 38}, {2, 61, 39}}, {{3, 58, 36}, {2, 59, 37}}, {{4, 69, 43}, {1, 70, 44}},
+	},
+	High: {
+		nil, {{1, 26, 13}}, {{1, 44, 22}}, {{2, 35, 17}}, {{2, 50, 24}}, {{2, 33, 15}, {2, 34, 16}},
+		{{4, 43, 19}}, {{2, 32, 14}, {4, 33, 15}}, {{4, 40, 18}, {2, 41, 19}}, {{4, 36, 16}, {4, 37, 17}}, {{6, 43, 19}, {2, 44, 20}},
+	},
+	Highest: {
+		nil, {{1, 26, 9}}, {{1, 44, 16}}, {{2, 35, 13}}, {{4, 25, 9}}, {{2, 33, 11}, {2, 34, 12}},
+		{{4, 43, 15}}, {{4, 39, 13}, {1, 40, 14}}, {{4, 40, 14}, {2, 41, 15}}, {{4, 36, 12}, {4, 37, 13}}, {{6, 43, 15}, {2, 44, 16}},
+	},
+}
+
+// alignmentCenters are the alignment pattern coordinates of versions 2 to 10.
+var alignmentCenters = [][]int{
+	nil, nil, {6, 18}, {6, 22}, {6, 26}, {6, 30}, {6, 34}, {6, 22, 38}, {6, 24, 42}, {6, 26, 46}, {6, 28, 50},
+}
+
+// formatLevels maps the level bits of the format information to levels.
+var formatLevels = map[uint32]Level{1: Low, 0: Medium, 3: High, 2: Highest}
+
+func decode(t *testing.T, raw []byte) decodedCode {
+	t.Helper()
+	img, err := png.Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("not a PNG: %v", err)
+	}
+	grid, version := readGrid(t, img)
+	n := len(grid)
+
+	// Format information, bit 14 first, around the top left finder.
+	positions := [15][2]int{}
+	for i := 0; i <= 5; i++ {
+		positions[i] = [2]int{i, 8}
+	}
+	positions[6], positions[7], positions[8] = [2]int{7, 8}, [2]int{8, 8}, [2]int{8, 7}
+	for i := 9; i <= 14; i++ {
+		positions[i] = [2]int{8, 14 - i}
+	}
+	var format uint32
+	for i, p := range positions {
+		if grid[p[0]][p[1]] {
+			format |= 1 << i
+		}
+	}
+	format ^= 0x5412
+	level := formatLevels[format>>13]
+	mask := int(format>>10) & 7
+
+	function := functionModules(version)
+	var bits []bool
+	up := true
+	for c := n - 1; c > 0; c -= 2 {
+		if c == 6 {
+			c--
+		}
+		for i := range n {
+			r := i
+			if up {
+				r = n - 1 - i
+			}
+			for _, cc := range []int{c, c - 1} {
+				if !function[r][cc] {
+					bits = append(bits, grid[r][cc] != masked(mask, r, cc))
+				}
+			}
+		}
+		up = !up
+	}
+
+	groups := blockGroups[level][version]
+	var blocks [][]byte
+	var dataLens []int
+	for _, g := range groups {
+		for range g.numBlocks {
+			blocks = append(blocks, nil)
+			dataLens = append(dataLens, g.numData)
+		}
+	}
+	next := 0
+	codeword := func() byte {
+		var b byte
+		for range 8 {
+			b <<= 1
+			if bits[next] {
+				b |= 1
+			}
+			next++
+		}
+		return b
+	}
+	for i := 0; i < dataLens[len(dataLens)-1]; i++ {
+		for b := range blocks {
+			if i < dataLens[b] {
+				blocks[b] = append(blocks[b], codeword())
+			}
+		}
+	}
+	return decodedCode{level: level, content: parseSegments(t, bytes.Join(blocks, nil), version)}
+}
+
+// readGrid samples the modules of img, trying each version until the finder
+// and timing patterns line up.
+func readGrid(t *testing.T, img image.Image) ([][]bool, int) {
+	t.Helper()
+	size := img.Bounds().Dx()
+	for version := 1; version <= 10; version++ {
+		n := 17 + 4*version
+		// The image includes a four module quiet zone on every side.
+		total := n + 8
+		dark := func(r, c int) bool {
+			x := int((float64(c+4) + 0.5) * float64(size) / float64(total))
+			y := int((float64(r+4) + 0.5) * float64(size) / float64(total))
+			red, _, _, _ := img.At(x, y).RGBA()
+			return red < 0x8000
+		}
+		grid := make([][]bool, n)
+		for r := range grid {
+			grid[r] = make([]bool, n)
+			for c := range grid[r] {
+				grid[r][c] = dark(r, c)
+			}
+		}
+		if hasFinders(grid) {
+			return grid, version
+		}
+	}
+	t.Fatal("no QR code of version 1 to 10 found")
+	return nil, 0
+}
+
+func hasFinders(grid [][]bool) bool {
+	n := len(grid)
+	for _, corner := range [][2]int{{0, 0}, {0, n - 7}, {n - 7, 0}} {
+		for r := range 7 {
+			for c := range 7 {
+				ring := max(abs(r-3), abs(c-3))
+				if grid[corner[0]+r][corner[1]+c] != (ring != 2) {
+					return false
+				}
+			}
+		}
+	}
+	for i := 8; i < n-8; i++ {
+		if grid[6][i] != (i%2 == 0) || grid[i][6] != (i%2 == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// functionModules marks the modules that carry no data.
+func functionModules(version int) [][]bool {
+	n := 17 + 4*version
+	f := make([][]bool, n)
+	for r := range f {
+		f[r] = make([]bool, n)
+		for c := range f[r] {
+			f[r][c] = r == 6 || c == 6 ||
+				(r < 9 && c < 9) || (r < 9 && c >= n-8) || (r >= n-8 && c < 9) ||
+				(version >= 7 && ((r < 6 && c >= n-11 && c < n-8) || (c < 6 && r >= n-11 && r < n-8)))
+		}
+	}
+	centers := alignmentCenters[version]
+	for _, cr := range centers {
+		for _, cc := range centers {
+			if f[cr][cc] {
+				continue // under a finder
+			}
+			for r := cr - 2; r <= cr+2; r++ {
+				for c := cc - 2; c <= cc+2; c++ {
+					f[r][c] = true
+				}
+			}
+		}
+	}
+	return f
+}
+
+func masked(mask, r, c int) bool {
+	switch mask {
+	case 0:
+		return (r+c)%2 == 0
+	case 1:
+		return r%2 == 0
+	case 2:
+		return c%3 == 0
+	case 3:
+		return (r+c)%3 == 0
+	case 4:
+		return (r/2+c/3)%2 == 0
+	case 5:
+		return (r*c)%2+(r*c)%3 == 0
+	case 6:
+		return ((r*c)%2+(r*c)%3)%2 == 0
+	default:
+		return ((r+c)%2+(r*c)%3)%2 == 0
+	}
+}
+
+const alphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// parseSegments reads the numeric, alphanumeric and byte segments of data.
+func parseSegments(t *testing.T, data []byte, version int) string {
+	t.Helper()
+	pos := 0
+	read := func(n int) int {
+		v := 0
+		for range n {
+			v <<= 1
+			if data[pos/8]&(0x80>>(pos%8)) != 0 {
+				v |= 1
+			}
+			pos++
+		}
+		return v
+	}
+	wide := version >= 10
+	var out strings.Builder
+	for len(data)*8-pos >= 4 {
+		switch mode := read(4); mode {
+		case 0:
+			return out.String()
+		case 1:
+			count := read(map[bool]int{false: 10, true: 12}[wide])
+			for ; count >= 3; count -= 3 {
+				out.WriteString(pad(read(10), 3))
+			}
+			switch count {
+			case 2:
+				out.WriteString(pad(read(7), 2))
+			case 1:
+				out.WriteString(pad(read(4), 1))
+			}
+		case 2:
+			count := read(map[bool]int{false: 9, true: 11}[wide])
+			for ; count >= 2; count -= 2 {
+				v := read(11)
+				out.WriteByte(alphanumeric[v/45])
+				out.WriteByte(alphanumeric[v%45])
+			}
+			if count == 1 {
+				out.WriteByte(alphanumeric[read(6)])
+			}
+		case 4:
+			count := read(map[bool]int{false: 8, true: 16}[wide])
+			for range count {
+				out.WriteByte(byte(read(8)))
+			}
+		default:
+			t.Fatalf("unsupported segment mode %d", mode)
+		}
+	}
+	return out.String()
+}
+
+func pad(v, digits int) string {
+	s := strings.Repeat("0", digits) + itoa(v)
+	return s[len(s)-digits:]
+}
+
+func itoa(v int) string {
+	if v == 0 {
+		return "0"
+	}
+	var b []byte
+	for ; v > 0; v /= 10 {
+		b = append([]byte{byte('0' + v%10)}, b...)
+	}
+	return string(b)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,82 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// URIScheme is the scheme of the payment URIs TRON wallets scan.
+const URIScheme = "tron"
+
+// NativeToken names TRX, the token with no contract.
+const NativeToken = "TRX"
+
+// ErrInvalidAmount is returned by PaymentURI for an amount that is not a
+// positive number.
+var ErrInvalidAmount = errors.New("invalid payment amount")
+
+// PaymentURI returns the URI a wallet app scans to pay amount of token to
+// address:
+//
+//	tron:<address>?amount=<amount>                     for TRX
+//	tron:<address>?amount=<amount>&token=<contract>    for a TRC20 token
+//
+// token is TRX or the contract address of a TRC20 token; wallets that know
+// the contract preselect the token. amount is in whole tokens, e.g. 12.5
+// USDT, written without exponent or trailing zeros. A NULL amount is left
+// out, for a payer to fill in.
+func PaymentURI(address string, amount pgtype.Numeric, token string) (string, error) {
+	if err := ValidateAddress(address); err != nil {
+		return "", err
+	}
+	var params []string
+	if amount.Valid {
+		s, err := formatAmount(amount)
+		if err != nil {
+			return "", err
+		}
+		params = append(params, "amount="+s)
+	}
+	if token != NativeToken {
+		if err := ValidateAddress(token); err != nil {
+			return "", fmt.Errorf("token is neither TRX nor a contract: %w", err)
+		}
+		params = append(params, "token="+token)
+	}
+	uri := URIScheme + ":" + address
+	if len(params) > 0 {
+		uri += "?" + strings.Join(params, "&")
+	}
+	return uri, nil
+}
+
+// formatAmount writes a positive amount as a plain decimal.
+func formatAmount(n pgtype.Numeric) (string, error) {
+	if n.NaN || n.InfinityModifier != pgtype.Finite || n.Int == nil || n.Int.Sign() <= 0 {
+		return "", ErrInvalidAmount
+	}
+	digits := new(big.Int).Set(n.Int)
+	exp := n.Exp
+	// Trailing zeros of the coefficient only move the exponent.
+	ten := big.NewInt(10)
+	for exp < 0 {
+		q, r := new(big.Int).QuoRem(digits, ten, new(big.Int))
+		if r.Sign() != 0 {
+			break
+		}
+		digits, exp = q, exp+1
+	}
+	s := digits.String()
+	if exp >= 0 {
+		return s + strings.Repeat("0", int(exp)), nil
+	}
+	point := len(s) + int(exp)
+	if point <= 0 {
+		return "0." + strings.Repeat("0", -point) + s, nil
+	}
+	return s[:point] + "." + s[point:], nil
+}
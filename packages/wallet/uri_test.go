@@ -0,0 +1,84 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const depositAddress = "TJRabPrwbZy45sbavfcjinPJC18kjpRTv8"
+
+func numeric(t *testing.T, s string) pgtype.Numeric {
+	t.Helper()
+	var n pgtype.Numeric
+	if err := n.Scan(s); err != nil {
+		t.Fatalf("Bad numeric %q: %v", s, err)
+	}
+	return n
+}
+
+func TestPaymentURI(t *testing.T) {
+	testCases := []struct {
+		name   string
+		amount pgtype.Numeric
+		token  string
+		want   string
+	}{
+		{"TRX", numeric(t, "25.5"), NativeToken,
+			"tron:TJRabPrwbZy45sbavfcjinPJC18kjpRTv8?amount=25.5"},
+		{"USDT", numeric(t, "102.669405"), usdtContract,
+			"tron:TJRabPrwbZy45sbavfcjinPJC18kjpRTv8?amount=102.669405&token=TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"},
+		{"trailing zeros", numeric(t, "12.500000"), usdtContract,
+			"tron:TJRabPrwbZy45sbavfcjinPJC18kjpRTv8?amount=12.5&token=TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"},
+		{"whole amount", numeric(t, "100"), NativeToken,
+			"tron:TJRabPrwbZy45sbavfcjinPJC18kjpRTv8?amount=100"},
+		{"small amount", numeric(t, "0.000001"), NativeToken,
+			"tron:TJRabPrwbZy45sbavfcjinPJC18kjpRTv8?amount=0.000001"},
+		{"no amount", pgtype.Numeric{}, usdtContract,
+			"tron:TJRabPrwbZy45sbavfcjinPJC18kjpRTv8?token=TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"},
+		{"no amount TRX", pgtype.Numeric{}, NativeToken,
+			"tron:TJRabPrwbZy45sbavfcjinPJC18kjpRTv8"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := PaymentURI(depositAddress, tc.amount, tc.token)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Expected %s, got: %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPaymentURI_Invalid(t *testing.T) {
+	testCases := []struct {
+		name    string
+		address string
+		amount  pgtype.Numeric
+		token   string
+		want    error
+	}{
+		{"bad address", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6u", numeric(t, "1"), NativeToken, nil},
+		{"bad token", depositAddress, numeric(t, "1"), "USDT", nil},
+		{"zero amount", depositAddress, numeric(t, "0"), NativeToken, ErrInvalidAmount},
+		{"negative amount", depositAddress, numeric(t, "-1.5"), NativeToken, ErrInvalidAmount},
+		{"NaN amount", depositAddress, pgtype.Numeric{NaN: true, Valid: true}, NativeToken, ErrInvalidAmount},
+		{"infinite amount", depositAddress, pgtype.Numeric{InfinityModifier: pgtype.Infinity, Valid: true}, NativeToken, ErrInvalidAmount},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			uri, err := PaymentURI(tc.address, tc.amount, tc.token)
+			if err == nil {
+				t.Fatalf("Expected an error, got URI: %s", uri)
+			}
+			if tc.want != nil && !errors.Is(err, tc.want) {
+				t.Errorf("Expected %v, got: %v", tc.want, err)
+			}
+		})
+	}
+}
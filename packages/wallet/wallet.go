@@ -1,38 +1,19 @@
-package main
+// Package wallet derives TRON deposit addresses from a BIP39 mnemonic and
+// validates TRON addresses.
+package wallet
 
 import (
-	"crypto/ecdsa"
-	"crypto/elliptic"
+	"crypto/sha256"
 	"encoding/hex"
-	"fmt"
 	"log/slog"
-	"math/big"
-
-	"golang.org/x/crypto/sha3"
 
 	"github.com/btcsuite/btcutil/base58"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"github.com/tyler-smith/go-bip32"
 	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/sha3"
 )
 
-var (
-	mnemonicSecret = `flash couple heart script ramp april average caution plunge alter elite author`
-	index          = uint32(0)
-)
-
-// main derives a TRON address and its private key from the package-level mnemonicSecret and index,
-// prints the address and private key to standard output, and panics on error.
-func main() {
-	address, privKey, err := DeriveTronAddressFromMnemonic(mnemonicSecret, index)
-	if err != nil {
-		panic(err)
-	}
-
-	fmt.Println("Address:", address)
-	fmt.Println("PrivKey:", privKey)
-
-}
-
 // DeriveTronAddressFromMnemonic derives a TRON address and its corresponding private key hex
 // from the provided BIP39 mnemonic at the given BIP32 index using the path m/44'/195'/0'/0/index.
 // It returns the Base58-encoded TRON address, the private key as a hex string, and an error if any step fails.
@@ -72,19 +53,13 @@ func DeriveTronAddressFromMnemonic(mnemonicSecret string, index uint32) (string,
 }
 
 // PrivateKeyToTronAddress converts a 32-byte raw private key into a Base58-encoded TRON address.
-// The input privateKey is expected to be a 32-byte big-endian ECDSA private key.
+// The input privateKey is expected to be a 32-byte big-endian secp256k1 private key.
 // It returns the Base58-encoded TRON address and a nil error on success.
 func PrivateKeyToTronAddress(privateKey []byte) (string, error) {
-	// Convert raw 32-byte private key into ecdsa.PrivateKey
-	d := new(big.Int).SetBytes(privateKey)
-	curve := elliptic.P256() // SECP256K1 alternative in Go (close compatibility)
-	priv := new(ecdsa.PrivateKey)
-	priv.D = d
-	priv.PublicKey.Curve = curve
-	priv.PublicKey.X, priv.PublicKey.Y = curve.ScalarBaseMult(d.Bytes())
+	priv := secp256k1.PrivKeyFromBytes(privateKey)
 
 	// Encode public key (uncompressed, 0x04 + X + Y)
-	pubKey := append([]byte{0x04}, append(priv.PublicKey.X.Bytes(), priv.PublicKey.Y.Bytes()...)...)
+	pubKey := priv.PubKey().SerializeUncompressed()
 
 	// Remove the 0x04 prefix for hashing
 	hash := sha3.NewLegacyKeccak256()
@@ -92,14 +67,20 @@ func PrivateKeyToTronAddress(privateKey []byte) (string, error) {
 	sum := hash.Sum(nil)
 
 	// Tron address: prefix 0x41 + last 20 bytes of keccak hash
-	addressBytes := append([]byte{0x41}, sum[12:]...)
+	addressBytes := append([]byte{AddressPrefix}, sum[12:]...)
 
-	// Checksum
-	checksumHash := sha3.NewLegacyKeccak256()
-	checksumHash.Write(addressBytes)
-	checksum := checksumHash.Sum(nil)[:4]
+	return encodeCheck(addressBytes), nil
+}
 
-	// Final address = base58(address + checksum)
-	fullAddress := append(addressBytes, checksum...)
-	return base58.Encode(fullAddress), nil
-}
\ No newline at end of file
+// encodeCheck appends the first 4 bytes of double SHA-256 as a checksum and
+// base58-encodes the result.
+func encodeCheck(payload []byte) string {
+	checksum := doubleSHA256(payload)[:4]
+	return base58.Encode(append(append([]byte{}, payload...), checksum...))
+}
+
+func doubleSHA256(b []byte) []byte {
+	first := sha256.Sum256(b)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
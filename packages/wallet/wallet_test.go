@@ -1,7 +1,8 @@
-package main
+package wallet
 
 import (
 	"encoding/hex"
+	"strings"
 	"testing"
 
 	"github.com/btcsuite/btcutil/base58"
@@ -225,11 +226,6 @@ func TestPrivateKeyToTronAddress_AllZeros(t *testing.T) {
 	if !strings.HasPrefix(address, "T") {
 		t.Error("Address should start with T")
 	}
-
-	address, err := PrivateKeyToTronAddress(privateKey)
-
-	require.NoError(t, err)
-	assert.NotEmpty(t, address)
 }
 
 // Test PrivateKeyToTronAddress with all ones
@@ -348,6 +344,7 @@ func TestPrivateKeyToTronAddress_ShortKey(t *testing.T) {
 	if address != "" && !strings.HasPrefix(address, "T") {
 		t.Error("If address is generated, it should start with T")
 	}
+}
 
 // Test PrivateKeyToTronAddress validates checksum
 func TestPrivateKeyToTronAddress_ValidChecksum(t *testing.T) {
@@ -0,0 +1,33 @@
+package webhooksig_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/yaninyzwitty/tron-payment-gateway/packages/webhooksig"
+)
+
+func ExampleSign() {
+	fmt.Println(webhooksig.Sign("whsec_test", time.Unix(1767225600, 0), []byte(`{"id":"evt_1"}`)))
+	// Output: t=1767225600,v1=45b40331de0325606dc5400202ade162460fbe48daf9401adfdcd0d7b4f35470
+}
+
+func ExampleVerify() {
+	const secret = "whsec_test" // the endpoint's secret
+	http.HandleFunc("/webhooks/tpg", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "cannot read body", http.StatusBadRequest)
+			return
+		}
+		err = webhooksig.Verify(secret, r.Header.Get(webhooksig.Header), body, webhooksig.DefaultTolerance)
+		if err != nil {
+			http.Error(w, "bad signature", http.StatusBadRequest)
+			return
+		}
+		// Decode body and handle the event.
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,3 @@
+module github.com/yaninyzwitty/tron-payment-gateway/packages/webhooksig
+
+go 1.25.0
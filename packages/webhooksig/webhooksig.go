@@ -0,0 +1,147 @@
+// Package webhooksig signs and verifies the webhooks the gateway sends.
+//
+// Every delivery carries an X-TPG-Signature header:
+//
+//	X-TPG-Signature: t=1767225600,v1=45b40331de0325606dc5400202ade162460fbe48daf9401adfdcd0d7b4f35470
+//
+// t is the Unix time the delivery was signed at and each v1 is the hex
+// HMAC-SHA256, keyed with an endpoint secret, of t, a dot and the raw body:
+//
+//	HMAC-SHA256("whsec_test", `1767225600.{"id":"evt_1"}`)
+//	  = 45b40331de0325606dc5400202ade162460fbe48daf9401adfdcd0d7b4f35470
+//
+// While an endpoint's secret is being rotated the header carries one v1 per
+// secret, so a receiver holding either one verifies it:
+//
+//	HMAC-SHA256("whsec_old", `1767225600.{"id":"evt_1"}`)
+//	  = cc8eec4a38fd1fcbf2239fdfaa2af6db3f6cd83419e90882421c22d9dc23ba8c
+//
+// Receivers should call Verify on the body exactly as it arrived, before
+// decoding it. The timestamp is signed too, so a captured delivery cannot be
+// replayed outside the tolerance passed to Verify.
+//
+// The package depends on nothing outside the standard library, so merchants
+// can import it on its own.
+package webhooksig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header is the HTTP header the signature is sent in.
+const Header = "X-TPG-Signature"
+
+// DefaultTolerance is how far from the receiver's clock a timestamp may be
+// for Verify to accept it; it covers clock skew and retries in flight.
+const DefaultTolerance = 5 * time.Minute
+
+// Errors returned by Verify.
+var (
+	// ErrInvalidHeader is returned for a header that is not a list of
+	// key=value pairs with one t.
+	ErrInvalidHeader = errors.New("webhooksig: invalid signature header")
+	// ErrNoSignature is returned for a header without a v1 signature.
+	ErrNoSignature = errors.New("webhooksig: no v1 signature in header")
+	// ErrTimestampOutOfRange is returned when t is further from now than
+	// the tolerance, e.g. for a replayed delivery.
+	ErrTimestampOutOfRange = errors.New("webhooksig: timestamp outside the tolerance")
+	// ErrSignatureMismatch is returned when no v1 signature matches the
+	// secret and body.
+	ErrSignatureMismatch = errors.New("webhooksig: signature mismatch")
+)
+
+// now is the receiver's clock.
+var now = time.Now
+
+// Sign returns the header value signing body at timestamp with secret.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	return SignAll([]string{secret}, timestamp, body)
+}
+
+// SignAll returns the header value signing body at timestamp with each of
+// secrets, in order, as during a secret rotation.
+func SignAll(secrets []string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	var b strings.Builder
+	b.WriteString("t=" + t)
+	for _, secret := range secrets {
+		b.WriteString(",v1=" + hex.EncodeToString(mac(secret, t, body)))
+	}
+	return b.String()
+}
+
+// Verify checks that header, the X-TPG-Signature of a delivery, signs body
+// with secret at a time within tolerance of now. Any v1 matching is enough.
+// A tolerance of zero or less skips the timestamp check, which leaves the
+// receiver open to replays; use DefaultTolerance unless there is a reason
+// not to.
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	t, signatures, err := parse(header)
+	if err != nil {
+		return err
+	}
+	if tolerance > 0 {
+		ts, _ := strconv.ParseInt(t, 10, 64)
+		if d := now().Sub(time.Unix(ts, 0)); d > tolerance || d < -tolerance {
+			return fmt.Errorf("%w: signed %s ago", ErrTimestampOutOfRange, d.Round(time.Second))
+		}
+	}
+	want := mac(secret, t, body)
+	for _, sig := range signatures {
+		if hmac.Equal(sig, want) {
+			return nil
+		}
+	}
+	return ErrSignatureMismatch
+}
+
+// parse splits header into its timestamp and decoded v1 signatures.
+// Signatures of other schemes, and v1 values that are not hex, are skipped
+// so the header can carry new schemes alongside v1.
+func parse(header string) (string, [][]byte, error) {
+	var t string
+	var signatures [][]byte
+	for _, pair := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return "", nil, ErrInvalidHeader
+		}
+		switch key {
+		case "t":
+			if t != "" {
+				return "", nil, ErrInvalidHeader
+			}
+			if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+				return "", nil, ErrInvalidHeader
+			}
+			t = value
+		case "v1":
+			if sig, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	if t == "" {
+		return "", nil, ErrInvalidHeader
+	}
+	if len(signatures) == 0 {
+		return "", nil, ErrNoSignature
+	}
+	return t, signatures, nil
+}
+
+// mac is the HMAC-SHA256, keyed with secret, of "t.body".
+func mac(secret, t string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(t))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
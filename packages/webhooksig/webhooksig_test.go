@@ -0,0 +1,116 @@
+package webhooksig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	vectorTime = time.Unix(1767225600, 0)
+	vectorBody = []byte(`{"id":"evt_1"}`)
+)
+
+const (
+	vectorSig    = "45b40331de0325606dc5400202ade162460fbe48daf9401adfdcd0d7b4f35470"
+	vectorOldSig = "cc8eec4a38fd1fcbf2239fdfaa2af6db3f6cd83419e90882421c22d9dc23ba8c"
+)
+
+// at sets the receiver's clock to t for the rest of the test.
+func at(t *testing.T, tm time.Time) {
+	t.Helper()
+	prev := now
+	now = func() time.Time { return tm }
+	t.Cleanup(func() { now = prev })
+}
+
+// TestSign_Vectors checks the vectors in the package documentation.
+func TestSign_Vectors(t *testing.T) {
+	if got, want := Sign("whsec_test", vectorTime, vectorBody), "t=1767225600,v1="+vectorSig; got != want {
+		t.Errorf("Sign() = %q, want %q", got, want)
+	}
+	got := SignAll([]string{"whsec_test", "whsec_old"}, vectorTime, vectorBody)
+	if want := "t=1767225600,v1=" + vectorSig + ",v1=" + vectorOldSig; got != want {
+		t.Errorf("SignAll() = %q, want %q", got, want)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	at(t, vectorTime.Add(time.Minute))
+	header := Sign("whsec_test", vectorTime, vectorBody)
+
+	testCases := []struct {
+		name      string
+		secret    string
+		header    string
+		body      []byte
+		tolerance time.Duration
+		want      error
+	}{
+		{"valid", "whsec_test", header, vectorBody, DefaultTolerance, nil},
+		{"wrong secret", "whsec_other", header, vectorBody, DefaultTolerance, ErrSignatureMismatch},
+		{"tampered body", "whsec_test", header, []byte(`{"id":"evt_2"}`), DefaultTolerance, ErrSignatureMismatch},
+		{"re-encoded body", "whsec_test", header, []byte(`{"id": "evt_1"}`), DefaultTolerance, ErrSignatureMismatch},
+		{"timestamp changed", "whsec_test", strings.Replace(header, "t=1767225600", "t=1767225601", 1), vectorBody,
+			DefaultTolerance, ErrSignatureMismatch},
+		{"old timestamp", "whsec_test", header, vectorBody, 30 * time.Second, ErrTimestampOutOfRange},
+		{"future timestamp", "whsec_test", Sign("whsec_test", vectorTime.Add(10*time.Minute), vectorBody), vectorBody,
+			DefaultTolerance, ErrTimestampOutOfRange},
+		{"tolerance off", "whsec_test", Sign("whsec_test", vectorTime.Add(-24*time.Hour), vectorBody), vectorBody,
+			0, nil},
+		{"spaces", "whsec_test", "t=1767225600, v1=" + vectorSig, vectorBody, DefaultTolerance, nil},
+		{"unknown scheme alongside", "whsec_test", header + ",v0=abc", vectorBody, DefaultTolerance, nil},
+		{"uppercase hex", "whsec_test", "t=1767225600,v1=" + strings.ToUpper(vectorSig), vectorBody, DefaultTolerance, nil},
+		{"truncated signature", "whsec_test", "t=1767225600,v1=" + vectorSig[:62], vectorBody, DefaultTolerance,
+			ErrSignatureMismatch},
+		{"empty", "whsec_test", "", vectorBody, DefaultTolerance, ErrInvalidHeader},
+		{"no timestamp", "whsec_test", "v1=" + vectorSig, vectorBody, DefaultTolerance, ErrInvalidHeader},
+		{"two timestamps", "whsec_test", "t=1,t=1767225600,v1=" + vectorSig, vectorBody, DefaultTolerance, ErrInvalidHeader},
+		{"bad timestamp", "whsec_test", "t=yesterday,v1=" + vectorSig, vectorBody, DefaultTolerance, ErrInvalidHeader},
+		{"bare hex", "whsec_test", vectorSig, vectorBody, DefaultTolerance, ErrInvalidHeader},
+		{"no signature", "whsec_test", "t=1767225600", vectorBody, DefaultTolerance, ErrNoSignature},
+		{"signature not hex", "whsec_test", "t=1767225600,v1=zz", vectorBody, DefaultTolerance, ErrNoSignature},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Verify(tc.secret, tc.header, tc.body, tc.tolerance)
+			if !errors.Is(err, tc.want) || (tc.want == nil && err != nil) {
+				t.Errorf("Verify() = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestVerify_DuringRotation(t *testing.T) {
+	at(t, vectorTime)
+	header := SignAll([]string{"whsec_new", "whsec_old"}, vectorTime, vectorBody)
+
+	for _, secret := range []string{"whsec_new", "whsec_old"} {
+		if err := Verify(secret, header, vectorBody, DefaultTolerance); err != nil {
+			t.Errorf("Verify(%q) = %v, want nil", secret, err)
+		}
+	}
+	if err := Verify("whsec_leaked", header, vectorBody, DefaultTolerance); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("Verify(other secret) = %v, want %v", err, ErrSignatureMismatch)
+	}
+	// Once the old secret is dropped, only the new one verifies.
+	header = Sign("whsec_new", vectorTime, vectorBody)
+	if err := Verify("whsec_old", header, vectorBody, DefaultTolerance); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("Verify(old secret) = %v, want %v", err, ErrSignatureMismatch)
+	}
+}
+
+func TestVerify_ToleranceBoundary(t *testing.T) {
+	header := Sign("whsec_test", vectorTime, vectorBody)
+
+	at(t, vectorTime.Add(DefaultTolerance))
+	if err := Verify("whsec_test", header, vectorBody, DefaultTolerance); err != nil {
+		t.Errorf("Verify() at the tolerance = %v, want nil", err)
+	}
+	at(t, vectorTime.Add(DefaultTolerance+time.Second))
+	if err := Verify("whsec_test", header, vectorBody, DefaultTolerance); !errors.Is(err, ErrTimestampOutOfRange) {
+		t.Errorf("Verify() past the tolerance = %v, want %v", err, ErrTimestampOutOfRange)
+	}
+}